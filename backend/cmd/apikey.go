@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/ksred/claude-session-manager/internal/auth"
+	"github.com/ksred/claude-session-manager/internal/config"
+	"github.com/ksred/claude-session-manager/internal/database"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var apiKeyCmd = &cobra.Command{
+	Use:   "apikey",
+	Short: "Manage the API keys accepted when auth is enabled",
+	Long: `API keys are only checked when auth.enabled is set in the config (or
+CSM_AUTH_ENABLED=true). Clients send a key as "Authorization: Bearer <key>",
+or as the api_key query parameter where headers can't be set. Read keys may
+only make GET requests; write keys may make any request. Only a hash of each
+key is stored, so a key is shown once, when it is created.`,
+}
+
+var apiKeyCreateCmd = &cobra.Command{
+	Use:          "create",
+	Short:        "Create an API key for a user",
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		user, _ := cmd.Flags().GetString("user")
+		name, _ := cmd.Flags().GetString("name")
+		scope, _ := cmd.Flags().GetString("scope")
+
+		return withSessionRepository(func(repo *database.SessionRepository) error {
+			plaintext, key, err := auth.Issue(repo, user, name, scope)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Created %s key %s for %s\n", key.Scope, key.ID, key.UserName)
+			fmt.Printf("\n    %s\n\n", plaintext)
+			fmt.Println("Store it now; it can't be shown again.")
+			return nil
+		})
+	},
+}
+
+var apiKeyListCmd = &cobra.Command{
+	Use:          "list",
+	Short:        "List API keys and when they were last used",
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return withSessionRepository(func(repo *database.SessionRepository) error {
+			keys, err := repo.GetAPIKeys()
+			if err != nil {
+				return err
+			}
+			if len(keys) == 0 {
+				fmt.Println("No API keys")
+				return nil
+			}
+
+			fmt.Printf("%-36s %-16s %-16s %-6s %-13s %-17s %s\n", "ID", "USER", "NAME", "SCOPE", "PREFIX", "LAST USED", "STATUS")
+			for _, key := range keys {
+				lastUsed := "never"
+				if key.LastUsedAt != nil {
+					lastUsed = key.LastUsedAt.Local().Format("2006-01-02 15:04")
+				}
+				status := "active"
+				if key.RevokedAt != nil {
+					status = "revoked " + key.RevokedAt.Local().Format("2006-01-02")
+				}
+				fmt.Printf("%-36s %-16s %-16s %-6s %-13s %-17s %s\n", key.ID, key.UserName, key.Name, key.Scope, key.Prefix+"…", lastUsed, status)
+			}
+			return nil
+		})
+	},
+}
+
+var apiKeyRevokeCmd = &cobra.Command{
+	Use:          "revoke <id>",
+	Short:        "Revoke an API key",
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return withSessionRepository(func(repo *database.SessionRepository) error {
+			if err := repo.RevokeAPIKey(args[0]); err != nil {
+				return err
+			}
+			fmt.Printf("Revoked API key %s\n", args[0])
+			return nil
+		})
+	},
+}
+
+// withSessionRepository opens the configured database for the duration of fn
+func withSessionRepository(fn func(repo *database.SessionRepository) error) error {
+	cfg, err := config.LoadConfig(cfgFile)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	logger := logrus.New()
+	logger.SetOutput(os.Stderr)
+	logger.SetLevel(logrus.WarnLevel)
+
+	db, err := database.NewDatabase(database.Config{
+		DatabasePath: filepath.Join(cfg.Claude.HomeDirectory, "sessions.db"),
+		Logger:       logger,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to initialize database: %w", err)
+	}
+	defer db.Close()
+
+	return fn(database.NewSessionRepository(db, logger))
+}
+
+func init() {
+	apiKeyCreateCmd.Flags().String("user", "", "user the key is issued to, created if new (required)")
+	apiKeyCreateCmd.Flags().String("name", "", "label to tell the key apart, e.g. the machine it's used on")
+	apiKeyCreateCmd.Flags().String("scope", database.ScopeRead, "read for GET requests only, write for any request")
+	apiKeyCreateCmd.MarkFlagRequired("user")
+
+	apiKeyCmd.AddCommand(apiKeyCreateCmd)
+	apiKeyCmd.AddCommand(apiKeyListCmd)
+	apiKeyCmd.AddCommand(apiKeyRevokeCmd)
+}
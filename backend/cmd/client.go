@@ -0,0 +1,31 @@
+package main
+
+import (
+	"net/http"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// apiKey returns the key a client command sends to the server: its --api-key
+// flag, or else CSM_API_KEY
+func apiKey(cmd *cobra.Command) string {
+	if key, _ := cmd.Flags().GetString("api-key"); key != "" {
+		return key
+	}
+	return os.Getenv("CSM_API_KEY")
+}
+
+// bearerTransport sends an API key with every request, if there is one
+type bearerTransport struct {
+	key string
+}
+
+func (t bearerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.key == "" {
+		return http.DefaultTransport.RoundTrip(req)
+	}
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+t.key)
+	return http.DefaultTransport.RoundTrip(req)
+}
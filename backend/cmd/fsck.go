@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/ksred/claude-session-manager/internal/config"
+	"github.com/ksred/claude-session-manager/internal/database"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var fsckCmd = &cobra.Command{
+	Use:   "fsck",
+	Short: "Find and fix orphaned and duplicated rows in the database",
+	Long: `Check the database for rows whose session or message no longer exists and for
+token usage recorded more than once for a message, as left behind by writes
+made with foreign keys off or by re-imports. With --fix, orphaned rows are
+deleted (activity log entries are unlinked from their session) and the latest
+token usage of each message is kept. Sessions under an active legal hold are
+left alone. The server repairs these on start as well.`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		fix, _ := cmd.Flags().GetBool("fix")
+
+		cfg, err := config.LoadConfig(cfgFile)
+		if err != nil {
+			return fmt.Errorf("failed to load configuration: %w", err)
+		}
+
+		logger := logrus.New()
+		logger.SetOutput(os.Stderr)
+		logger.SetLevel(logrus.WarnLevel)
+
+		db, err := database.NewDatabase(database.Config{
+			DatabasePath: filepath.Join(cfg.Claude.HomeDirectory, "sessions.db"),
+			Logger:       logger,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to initialize database: %w", err)
+		}
+		defer db.Close()
+
+		findings, err := db.Fsck(fix)
+		if err != nil {
+			return err
+		}
+		if len(findings) == 0 {
+			fmt.Println("No orphaned or duplicate rows found")
+			return nil
+		}
+
+		for _, finding := range findings {
+			status := "found"
+			if finding.Fixed {
+				status = "fixed"
+			}
+			fmt.Printf("%-24s %-30s %8d %s\n", finding.Table, finding.Problem, finding.Rows, status)
+		}
+		if !fix {
+			return fmt.Errorf("database has orphaned or duplicate rows; run with --fix to repair them")
+		}
+		return nil
+	},
+}
+
+func init() {
+	fsckCmd.Flags().Bool("fix", false, "delete or unlink the rows found")
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/ksred/claude-session-manager/internal/config"
+	"github.com/ksred/claude-session-manager/internal/database"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var gitCmd = &cobra.Command{
+	Use:   "git",
+	Short: "Manage the git information recorded for sessions",
+}
+
+var gitBackfillCmd = &cobra.Command{
+	Use:   "backfill",
+	Short: "Detect the git branch, worktree and remote of already imported sessions",
+	Long: `Look up the git repository each session ran in and record its branch, the top
+level of its working tree and its origin remote. The branch recorded in the
+session's transcript is preferred over the one checked out now. Only sessions
+without a worktree are looked at unless --force is given. Sessions whose
+directory no longer exists are left as they are.`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		force, _ := cmd.Flags().GetBool("force")
+
+		cfg, err := config.LoadConfig(cfgFile)
+		if err != nil {
+			return fmt.Errorf("failed to load configuration: %w", err)
+		}
+
+		logger := logrus.New()
+		logger.SetOutput(os.Stderr)
+		logger.SetLevel(logrus.WarnLevel)
+
+		db, err := database.NewDatabase(database.Config{
+			DatabasePath: filepath.Join(cfg.Claude.HomeDirectory, "sessions.db"),
+			Logger:       logger,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to initialize database: %w", err)
+		}
+		defer db.Close()
+
+		updated, err := database.NewSessionRepository(db, logger).BackfillGit(force)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Updated git information of %d sessions\n", updated)
+		return nil
+	},
+}
+
+func init() {
+	gitBackfillCmd.Flags().Bool("force", false, "re-detect sessions that already have git information")
+	gitCmd.AddCommand(gitBackfillCmd)
+}
@@ -15,6 +15,7 @@ func main() {
 		dbPath   = flag.String("db", "", "Path to SQLite database file")
 		filePath = flag.String("file", "", "Path to JSONL file to import")
 		dataDir  = flag.String("data-dir", "", "Claude data directory (alternative to -db)")
+		maxLine  = flag.Int("max-line-size", 10, "Longest line in MB read whole; longer lines are stream decoded")
 	)
 	flag.Parse()
 
@@ -45,6 +46,7 @@ func main() {
 	config := database.Config{
 		DatabasePath: dbFile,
 		Logger:       logger,
+		MaxLineBytes: *maxLine * 1024 * 1024,
 	}
 	db, err := database.NewDatabase(config)
 	if err != nil {
@@ -29,11 +29,15 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"syscall"
 	"time"
 
 	"github.com/ksred/claude-session-manager/internal/api"
 	"github.com/ksred/claude-session-manager/internal/config"
+	"github.com/ksred/claude-session-manager/internal/database"
+	"github.com/ksred/claude-session-manager/internal/demo"
+	"github.com/ksred/claude-session-manager/internal/rpc"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
@@ -43,6 +47,14 @@ var (
 	appConfig *config.Config
 )
 
+// Demo mode generates this many days of history, then keeps this many
+// sessions live, writing a message to one of them about every demoPace
+const (
+	demoHistoryDays  = 30
+	demoLiveSessions = 3
+	demoPace         = 4 * time.Second
+)
+
 var rootCmd = &cobra.Command{
 	Use:   "claude-session-manager",
 	Short: "Claude Session Manager Backend Server",
@@ -79,6 +91,28 @@ var serveCmd = &cobra.Command{
 			logrus.WithField("config_file", cfgFile).Info("Using custom config file")
 		}
 
+		// Demo mode serves generated sessions from a temporary Claude home
+		// instead of the real one
+		var demoGenerator *demo.Generator
+		if demoMode, _ := cmd.Flags().GetBool("demo"); demoMode {
+			home, err := os.MkdirTemp("", "claude-session-manager-demo-")
+			if err != nil {
+				return fmt.Errorf("failed to create demo directory: %w", err)
+			}
+			defer os.RemoveAll(home)
+
+			demo.Configure(appConfig, home)
+			demoGenerator = demo.NewGenerator(home)
+			count, err := demoGenerator.History(demoHistoryDays, time.Now())
+			if err != nil {
+				return fmt.Errorf("failed to generate demo sessions: %w", err)
+			}
+			logrus.WithFields(logrus.Fields{
+				"directory": home,
+				"sessions":  count,
+			}).Info("Demo mode: serving generated sessions")
+		}
+
 		// Create server with configuration (using SQLite)
 		server, err := api.NewSQLiteServer(appConfig)
 		if err != nil {
@@ -86,9 +120,17 @@ var serveCmd = &cobra.Command{
 		}
 
 		// Setup graceful shutdown
-		_, cancel := context.WithCancel(context.Background())
+		ctx, cancel := context.WithCancel(context.Background())
 		defer cancel()
 
+		if demoGenerator != nil {
+			go func() {
+				if err := demoGenerator.Replay(ctx, demoLiveSessions, demoPace); err != nil {
+					logrus.WithError(err).Error("Demo replay stopped")
+				}
+			}()
+		}
+
 		// Handle shutdown signals
 		sigChan := make(chan os.Signal, 1)
 		signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
@@ -145,6 +187,45 @@ var serveCmd = &cobra.Command{
 	},
 }
 
+var rpcCmd = &cobra.Command{
+	Use:   "rpc",
+	Short: "Serve session queries as JSON-RPC over stdio",
+	Long: `Serve the core session queries as JSON-RPC 2.0 over stdin/stdout, framed with
+Content-Length headers like a language server, so editor plugins can embed
+session stats without running the HTTP server. Logs are written to stderr.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.LoadConfig(cfgFile)
+		if err != nil {
+			return fmt.Errorf("failed to load configuration: %w", err)
+		}
+
+		// stdout carries the protocol, so logs go to stderr only
+		logger := logrus.New()
+		logger.SetOutput(os.Stderr)
+		if debug, _ := cmd.Flags().GetBool("debug"); debug || cfg.Features.DebugMode {
+			logger.SetLevel(logrus.DebugLevel)
+		} else {
+			logger.SetLevel(logrus.WarnLevel)
+		}
+
+		db, err := database.NewDatabase(database.Config{
+			DatabasePath: filepath.Join(cfg.Claude.HomeDirectory, "sessions.db"),
+			Logger:       logger,
+			Tuning:       api.DatabaseTuning(cfg.Database),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to initialize database: %w", err)
+		}
+		defer db.Close()
+
+		server := rpc.NewServer(database.NewSessionRepository(db, logger), rpc.Options{
+			DailyBudget: cfg.Statusline.DailyBudget,
+			Currency:    cfg.Pricing.Currency,
+		}, logger)
+		return server.Serve(os.Stdin, os.Stdout)
+	},
+}
+
 func init() {
 	// Global flags
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.config/claude-session-manager/config.yaml)")
@@ -152,9 +233,22 @@ func init() {
 	// Serve command flags
 	serveCmd.Flags().IntP("port", "p", 0, "port to run the server on (overrides config)")
 	serveCmd.Flags().Bool("debug", false, "enable debug logging (overrides config)")
+	serveCmd.Flags().Bool("demo", false, "serve generated sessions with synthetic live activity instead of real session data")
+
+	// RPC command flags
+	rpcCmd.Flags().Bool("debug", false, "enable debug logging to stderr (overrides config)")
 
 	// Add commands
 	rootCmd.AddCommand(serveCmd)
+	rootCmd.AddCommand(rpcCmd)
+	rootCmd.AddCommand(migrateCmd)
+	rootCmd.AddCommand(topCmd)
+	rootCmd.AddCommand(summaryCmd)
+	rootCmd.AddCommand(telemetryCmd)
+	rootCmd.AddCommand(fsckCmd)
+	rootCmd.AddCommand(rebuildSummariesCmd)
+	rootCmd.AddCommand(gitCmd)
+	rootCmd.AddCommand(apiKeyCmd)
 }
 
 // Override config with command line flags after loading
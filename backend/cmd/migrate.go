@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/ksred/claude-session-manager/internal/config"
+	"github.com/ksred/claude-session-manager/internal/database"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Apply, revert or list database migrations",
+	Long: `Manage the versioned database migrations recorded in the schema_migrations table.
+The server applies pending migrations on start; this command applies or reverts
+them without starting it.`,
+}
+
+var migrateUpCmd = &cobra.Command{
+	Use:   "up",
+	Short: "Apply all pending migrations",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		db, err := openMigrationDatabase()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		ran, err := db.MigrateUp()
+		for _, m := range ran {
+			fmt.Printf("Applied %03d %s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(ran) == 0 {
+			fmt.Println("No pending migrations")
+		}
+		return nil
+	},
+}
+
+var migrateDownCmd = &cobra.Command{
+	Use:   "down",
+	Short: "Revert the most recently applied migrations",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		steps, _ := cmd.Flags().GetInt("steps")
+		if steps <= 0 {
+			return fmt.Errorf("steps must be positive, got %d", steps)
+		}
+
+		db, err := openMigrationDatabase()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		reverted, err := db.MigrateDown(steps)
+		for _, m := range reverted {
+			fmt.Printf("Reverted %03d %s\n", m.Version, m.Name)
+		}
+		return err
+	},
+}
+
+var migrateStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "List migrations and whether they have been applied",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		db, err := openMigrationDatabase()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		statuses, err := db.MigrationStatus()
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			applied := "pending"
+			if status.AppliedAt != nil {
+				applied = "applied " + status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			reversible := ""
+			if !status.Reversible {
+				reversible = " (irreversible)"
+			}
+			fmt.Printf("%03d %-32s %s%s\n", status.Version, status.Name, applied, reversible)
+		}
+		return nil
+	},
+}
+
+// openMigrationDatabase opens the configured database without applying
+// pending migrations
+func openMigrationDatabase() (*database.Database, error) {
+	cfg, err := config.LoadConfig(cfgFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	logger := logrus.New()
+	logger.SetOutput(os.Stderr)
+	logger.SetLevel(logrus.WarnLevel)
+
+	db, err := database.NewDatabase(database.Config{
+		DatabasePath:   filepath.Join(cfg.Claude.HomeDirectory, "sessions.db"),
+		Logger:         logger,
+		SkipMigrations: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize database: %w", err)
+	}
+	return db, nil
+}
+
+func init() {
+	migrateDownCmd.Flags().Int("steps", 1, "number of migrations to revert")
+
+	migrateCmd.AddCommand(migrateUpCmd)
+	migrateCmd.AddCommand(migrateDownCmd)
+	migrateCmd.AddCommand(migrateStatusCmd)
+}
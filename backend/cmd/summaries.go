@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/ksred/claude-session-manager/internal/config"
+	"github.com/ksred/claude-session-manager/internal/database"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var rebuildSummariesCmd = &cobra.Command{
+	Use:   "rebuild-summaries",
+	Short: "Recompute the session summaries that session listings read",
+	Long: `Recompute each session's summary, its token totals and the files it modified,
+from its token usage and tool results. Summaries are kept up to date as
+sessions are imported, so this is only needed to repair them, for example
+after editing the database by hand with the triggers that maintain them
+dropped.`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.LoadConfig(cfgFile)
+		if err != nil {
+			return fmt.Errorf("failed to load configuration: %w", err)
+		}
+
+		logger := logrus.New()
+		logger.SetOutput(os.Stderr)
+		logger.SetLevel(logrus.WarnLevel)
+
+		db, err := database.NewDatabase(database.Config{
+			DatabasePath: filepath.Join(cfg.Claude.HomeDirectory, "sessions.db"),
+			Logger:       logger,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to initialize database: %w", err)
+		}
+		defer db.Close()
+
+		count, err := db.RebuildSessionSummaries()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Rebuilt the summaries of %d sessions\n", count)
+		return nil
+	},
+}
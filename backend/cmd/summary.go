@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ksred/claude-session-manager/internal/config"
+	"github.com/ksred/claude-session-manager/internal/database"
+	"github.com/spf13/cobra"
+)
+
+var summaryCmd = &cobra.Command{
+	Use:   "summary [session-id]",
+	Short: "Print a one-shot summary of a session",
+	Long: `Print a session's duration, messages, tokens, cost and files touched from the
+running server. With --latest, summarize the most recent session run in --cwd,
+which makes a shell wrapper print a summary whenever claude exits:
+
+  claude() { command claude "$@"; claude-session-manager summary --latest; }`,
+	Args:         cobra.MaximumNArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		serverURL, _ := cmd.Flags().GetString("url")
+		if serverURL == "" {
+			cfg, err := config.LoadConfig(cfgFile)
+			if err != nil {
+				return fmt.Errorf("failed to load configuration: %w", err)
+			}
+			serverURL = fmt.Sprintf("http://localhost:%d", cfg.Server.Port)
+		}
+		serverURL = strings.TrimSuffix(serverURL, "/")
+		latest, _ := cmd.Flags().GetBool("latest")
+		cwd, _ := cmd.Flags().GetString("cwd")
+		settle, _ := cmd.Flags().GetDuration("settle")
+
+		if (len(args) == 1) == latest {
+			return fmt.Errorf("pass either a session ID or --latest")
+		}
+
+		// Give the file watcher time to import the last messages written
+		// before claude exited
+		time.Sleep(settle)
+
+		client := &http.Client{Timeout: 10 * time.Second, Transport: bearerTransport{key: apiKey(cmd)}}
+		sessionID := ""
+		if len(args) == 1 {
+			sessionID = args[0]
+		} else {
+			if cwd == "" {
+				var err error
+				if cwd, err = os.Getwd(); err != nil {
+					return err
+				}
+			}
+			var err error
+			sessionID, err = latestSessionIn(client, serverURL, cwd)
+			if err != nil {
+				return err
+			}
+		}
+
+		resp, err := client.Get(serverURL + "/api/v1/sessions/" + url.PathEscape(sessionID) + "/wrap-up?format=text")
+		if err != nil {
+			return fmt.Errorf("failed to reach server: %w", err)
+		}
+		defer resp.Body.Close()
+		text, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("%s", strings.TrimSpace(string(text)))
+		}
+		fmt.Print(string(text))
+		return nil
+	},
+}
+
+// latestSessionIn returns the most recently active session run in dir or a
+// directory below it
+func latestSessionIn(client *http.Client, serverURL, dir string) (string, error) {
+	resp, err := client.Get(serverURL + "/api/v1/sessions/recent?limit=100")
+	if err != nil {
+		return "", fmt.Errorf("failed to reach server: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("server returned %s", resp.Status)
+	}
+
+	var body struct {
+		Sessions []database.SessionResponse `json:"sessions"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("failed to decode recent sessions: %w", err)
+	}
+
+	dir = filepath.Clean(dir)
+	var latest *database.SessionResponse
+	for i, session := range body.Sessions {
+		path := filepath.Clean(session.ProjectPath)
+		if path != dir && !strings.HasPrefix(path, dir+string(filepath.Separator)) {
+			continue
+		}
+		if latest == nil || session.UpdatedAt.After(latest.UpdatedAt) {
+			latest = &body.Sessions[i]
+		}
+	}
+	if latest == nil {
+		return "", fmt.Errorf("no recent session in %s", dir)
+	}
+	return latest.ID, nil
+}
+
+func init() {
+	summaryCmd.Flags().String("url", "", "server URL (defaults to http://localhost and the configured port)")
+	summaryCmd.Flags().String("api-key", "", "API key for a server with auth enabled (defaults to $CSM_API_KEY)")
+	summaryCmd.Flags().Bool("latest", false, "summarize the most recent session in --cwd")
+	summaryCmd.Flags().String("cwd", "", "directory --latest looks for sessions in (defaults to the current directory)")
+	summaryCmd.Flags().Duration("settle", 2*time.Second, "how long to wait for the last messages to be imported")
+}
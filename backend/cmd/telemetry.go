@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+
+	"github.com/ksred/claude-session-manager/internal/config"
+	"github.com/ksred/claude-session-manager/internal/telemetry"
+	"github.com/spf13/cobra"
+)
+
+var telemetryCmd = &cobra.Command{
+	Use:   "telemetry",
+	Short: "Show what anonymous usage telemetry is sent",
+	Long: `Telemetry is off unless telemetry.enabled is set in the config (or
+CSM_TELEMETRY_ENABLED=true) along with telemetry.endpoint. When on, the server
+posts its version, platform, a database size bucket and per-route API call
+counts to the endpoint every telemetry.interval hours. Set
+telemetry.enabled: false or DO_NOT_TRACK=1 to turn it off.`,
+}
+
+var telemetryStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show whether telemetry is on and the report it would send",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.LoadConfig(cfgFile)
+		if err != nil {
+			return fmt.Errorf("failed to load configuration: %w", err)
+		}
+
+		if reason := telemetry.Disabled(cfg.Telemetry); reason != "" {
+			fmt.Printf("Telemetry: disabled (%s)\n", reason)
+		} else {
+			fmt.Println("Telemetry: enabled")
+			fmt.Printf("Endpoint:  %s\n", cfg.Telemetry.Endpoint)
+			fmt.Printf("Interval:  every %dh\n", cfg.Telemetry.Interval)
+		}
+
+		// API call counts are only known to the running server, so the
+		// preview shows the shape of the report with none counted
+		report := telemetry.NewReport(filepath.Join(cfg.Claude.HomeDirectory, "sessions.db"))
+		preview, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println("\nReport contents (API calls are counted by the running server):")
+		fmt.Println(string(preview))
+		return nil
+	},
+}
+
+func init() {
+	telemetryCmd.AddCommand(telemetryStatusCmd)
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/ksred/claude-session-manager/internal/config"
+	"github.com/ksred/claude-session-manager/internal/database"
+	"github.com/ksred/claude-session-manager/internal/top"
+	"github.com/spf13/cobra"
+)
+
+var topCmd = &cobra.Command{
+	Use:   "top",
+	Short: "Show the live token burn of active sessions",
+	Long: `Show a continuously refreshing table of active sessions with tokens and cost per
+minute, following the running server's WebSocket stream. Press s to change the
+sort order and q to quit.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		serverURL, _ := cmd.Flags().GetString("url")
+		if serverURL == "" {
+			cfg, err := config.LoadConfig(cfgFile)
+			if err != nil {
+				return fmt.Errorf("failed to load configuration: %w", err)
+			}
+			serverURL = fmt.Sprintf("http://localhost:%d", cfg.Server.Port)
+		}
+		interval, _ := cmd.Flags().GetDuration("interval")
+		window, _ := cmd.Flags().GetDuration("window")
+		if interval <= 0 || window <= 0 {
+			return fmt.Errorf("interval and window must be positive")
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		client := top.NewClient(serverURL, apiKey(cmd))
+		tracker := top.NewTracker(window)
+
+		var (
+			statusMu sync.Mutex
+			status   = "connecting"
+		)
+		setStatus := func(s string) {
+			statusMu.Lock()
+			status = s
+			statusMu.Unlock()
+		}
+
+		// Take a snapshot on every (re)connect so sessions that went quiet
+		// while disconnected still show, then follow the stream
+		go func() {
+			for ctx.Err() == nil {
+				sessions, err := client.ActiveSessions(ctx)
+				if err == nil {
+					for _, session := range sessions {
+						tracker.Observe(session, time.Now())
+					}
+					setStatus("live")
+					err = client.Stream(ctx, func(session database.SessionResponse) {
+						tracker.Observe(session, time.Now())
+					})
+				}
+				if ctx.Err() != nil {
+					return
+				}
+				setStatus(fmt.Sprintf("reconnecting (%v)", err))
+				select {
+				case <-ctx.Done():
+				case <-time.After(2 * time.Second):
+				}
+			}
+		}()
+
+		restore := rawTerminal()
+		defer restore()
+		keys := readKeys()
+
+		order := top.SortTokens
+		draw := func() {
+			statusMu.Lock()
+			current := status
+			statusMu.Unlock()
+			now := time.Now()
+			// Clear the screen and draw from the top left
+			fmt.Print("\033[H\033[2J" + top.Render(tracker.Rows(now, order), now, order, current))
+		}
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			draw()
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-ticker.C:
+			case key := <-keys:
+				switch key {
+				case 'q', 'Q':
+					return nil
+				case 's', 'S':
+					order = top.NextSort(order)
+				}
+			}
+		}
+	},
+}
+
+// rawTerminal switches the terminal to unbuffered input without echo so keys
+// act on their own, returning a function that restores it. Without a terminal
+// or stty, keys are read when Enter is pressed.
+func rawTerminal() func() {
+	saved, err := stty("-g")
+	if err != nil {
+		return func() {}
+	}
+	if _, err := stty("cbreak", "-echo"); err != nil {
+		return func() {}
+	}
+	fmt.Print("\033[?25l") // hide the cursor
+	return func() {
+		fmt.Print("\033[?25h\n")
+		stty(strings.TrimSpace(saved))
+	}
+}
+
+func stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	out, err := cmd.Output()
+	return string(out), err
+}
+
+// readKeys delivers the bytes typed on stdin
+func readKeys() <-chan byte {
+	keys := make(chan byte)
+	go func() {
+		buf := make([]byte, 1)
+		for {
+			if n, err := os.Stdin.Read(buf); err != nil || n == 0 {
+				return
+			}
+			keys <- buf[0]
+		}
+	}()
+	return keys
+}
+
+func init() {
+	topCmd.Flags().String("url", "", "server URL (defaults to http://localhost and the configured port)")
+	topCmd.Flags().String("api-key", "", "API key for a server with auth enabled (defaults to $CSM_API_KEY)")
+	topCmd.Flags().Duration("interval", time.Second, "how often the table is redrawn")
+	topCmd.Flags().Duration("window", 5*time.Minute, "window rates are measured over; sessions idle longer are hidden")
+}
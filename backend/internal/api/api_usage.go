@@ -0,0 +1,146 @@
+package api
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/claude-session-manager/internal/database"
+	"github.com/sirupsen/logrus"
+)
+
+// apiUsageFlushInterval is how often counted requests are written
+const apiUsageFlushInterval = time.Minute
+
+// APIUsageRetention is how long recorded API usage is kept
+const APIUsageRetention = 30 * 24 * time.Hour
+
+// apiUsageKey identifies a route's requests in an hour
+type apiUsageKey struct {
+	hour   time.Time
+	method string
+	route  string
+}
+
+// APIUsageRecorder counts requests by route template and writes them to the
+// api_usage table every minute, so the database isn't written per request
+type APIUsageRecorder struct {
+	repo   *database.SessionRepository
+	logger *logrus.Logger
+	now    func() time.Time
+
+	mu      sync.Mutex
+	pending map[apiUsageKey]*database.APIUsage
+}
+
+// NewAPIUsageRecorder creates a recorder writing to repo
+func NewAPIUsageRecorder(repo *database.SessionRepository, logger *logrus.Logger) *APIUsageRecorder {
+	return &APIUsageRecorder{
+		repo:    repo,
+		logger:  logger,
+		now:     time.Now,
+		pending: make(map[apiUsageKey]*database.APIUsage),
+	}
+}
+
+// Middleware counts each request by its route template, so IDs and query
+// strings are never recorded. Requests matching no route count as "other".
+// WebSocket upgrades are left out, as their latency is the connection's
+// lifetime.
+func (r *APIUsageRecorder) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if strings.EqualFold(c.GetHeader("Upgrade"), "websocket") {
+			c.Next()
+			return
+		}
+
+		start := r.now()
+		c.Next()
+		elapsed := float64(r.now().Sub(start).Microseconds()) / 1000
+
+		route := c.FullPath()
+		if route == "" {
+			route = "other"
+		}
+		key := apiUsageKey{hour: start.UTC().Truncate(time.Hour), method: c.Request.Method, route: route}
+		status := c.Writer.Status()
+
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		usage := r.pending[key]
+		if usage == nil {
+			usage = &database.APIUsage{Hour: key.hour, Method: key.method, Route: key.route}
+			r.pending[key] = usage
+		}
+		usage.Requests++
+		switch {
+		case status >= 500:
+			usage.ServerErrors++
+		case status >= 400:
+			usage.ClientErrors++
+		}
+		usage.TotalMs += elapsed
+		if elapsed > usage.MaxMs {
+			usage.MaxMs = elapsed
+		}
+	}
+}
+
+// Flush writes the requests counted since the last flush. They are kept for
+// the next flush when the write fails.
+func (r *APIUsageRecorder) Flush() error {
+	r.mu.Lock()
+	pending := r.pending
+	r.pending = make(map[apiUsageKey]*database.APIUsage)
+	r.mu.Unlock()
+
+	usage := make([]database.APIUsage, 0, len(pending))
+	for _, u := range pending {
+		usage = append(usage, *u)
+	}
+	if err := r.repo.RecordAPIUsage(usage); err != nil {
+		r.mu.Lock()
+		for key, u := range pending {
+			if current := r.pending[key]; current != nil {
+				current.Requests += u.Requests
+				current.ClientErrors += u.ClientErrors
+				current.ServerErrors += u.ServerErrors
+				current.TotalMs += u.TotalMs
+				if u.MaxMs > current.MaxMs {
+					current.MaxMs = u.MaxMs
+				}
+			} else {
+				r.pending[key] = u
+			}
+		}
+		r.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// Start flushes every minute, deleting usage older than APIUsageRetention,
+// until ctx is cancelled, then flushes what is left
+func (r *APIUsageRecorder) Start(ctx context.Context) {
+	ticker := time.NewTicker(apiUsageFlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			if err := r.Flush(); err != nil {
+				r.logger.WithError(err).Warn("Failed to record API usage")
+			}
+			return
+		case <-ticker.C:
+			if err := r.Flush(); err != nil {
+				r.logger.WithError(err).Warn("Failed to record API usage")
+			}
+			if _, err := r.repo.PruneAPIUsage(r.now().Add(-APIUsageRetention)); err != nil {
+				r.logger.WithError(err).Warn("Failed to prune API usage")
+			}
+		}
+	}
+}
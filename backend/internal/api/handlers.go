@@ -7,6 +7,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/ksred/claude-session-manager/internal/claude"
+	"github.com/ksred/claude-session-manager/internal/pricing"
 )
 
 // This file contains HTTP handlers for the Claude Session Manager API.
@@ -45,7 +46,7 @@ func repoSessionToResponse(session claude.RepositorySession) SessionResponse {
 		CacheCreationInputTokens: session.TotalTokens.CacheCreationInputTokens,
 		CacheReadInputTokens:     session.TotalTokens.CacheReadInputTokens,
 		TotalTokens:              session.TotalTokens.InputTokens + session.TotalTokens.OutputTokens + session.TotalTokens.CacheCreationInputTokens + session.TotalTokens.CacheReadInputTokens,
-		EstimatedCost:            calculateCostFromTokens(session.TotalTokens),
+		EstimatedCost:            calculateCostFromTokens(session.TotalTokens, session.Model),
 	}
 
 	return SessionResponse{
@@ -69,20 +70,13 @@ func repoSessionToResponse(session claude.RepositorySession) SessionResponse {
 }
 
 // Helper to calculate cost from detailed token usage
-func calculateCostFromTokens(tokens claude.RepositoryTokenUsage) float64 {
-	const (
-		inputCostPer1M         = 15.0
-		outputCostPer1M        = 75.0
-		cacheReadCostPer1M     = 1.50
-		cacheCreationCostPer1M = 18.75
-	)
-
-	cost := float64(tokens.InputTokens) * inputCostPer1M / 1000000
-	cost += float64(tokens.OutputTokens) * outputCostPer1M / 1000000
-	cost += float64(tokens.CacheReadInputTokens) * cacheReadCostPer1M / 1000000
-	cost += float64(tokens.CacheCreationInputTokens) * cacheCreationCostPer1M / 1000000
-
-	return cost
+func calculateCostFromTokens(tokens claude.RepositoryTokenUsage, model string) float64 {
+	return pricing.Cost(model, pricing.Usage{
+		InputTokens:              tokens.InputTokens,
+		OutputTokens:             tokens.OutputTokens,
+		CacheCreationInputTokens: tokens.CacheCreationInputTokens,
+		CacheReadInputTokens:     tokens.CacheReadInputTokens,
+	})
 }
 
 // Helper to determine session status from repository data
@@ -581,7 +575,7 @@ func (s *Server) getModelPerformanceHandler(c *gin.Context) {
 		// Calculate total tokens and cost
 		totalTokens := stats.TotalTokens.InputTokens + stats.TotalTokens.OutputTokens +
 			stats.TotalTokens.CacheCreationInputTokens + stats.TotalTokens.CacheReadInputTokens
-		totalCost := s.calculateCostFromTokens(stats.TotalTokens)
+		totalCost := calculateCostFromTokens(stats.TotalTokens, model)
 
 		// Calculate averages
 		avgCost := 0.0
@@ -667,8 +661,8 @@ func (s *Server) getTimeSeriesHandler(c *gin.Context) {
 		models := make(map[string]TimeSeriesModelData)
 
 		for model, usage := range data.Models {
-			// Estimate cost based on token usage (simplified calculation)
-			modelCost := float64(usage.Tokens) * 15.0 / 1000000 // Rough average cost
+			// Only total tokens are kept per period, so price them as input
+			modelCost := pricing.Cost(model, pricing.Usage{InputTokens: usage.Tokens})
 			totalCost += modelCost
 
 			models[model] = TimeSeriesModelData{
@@ -768,19 +762,3 @@ func (s *Server) getCostAnalyticsHandler(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
-// Helper method to calculate cost from detailed token usage
-func (s *Server) calculateCostFromTokens(tokens claude.RepositoryTokenUsage) float64 {
-	const (
-		inputCostPer1M         = 15.0
-		outputCostPer1M        = 75.0
-		cacheReadCostPer1M     = 1.50
-		cacheCreationCostPer1M = 18.75
-	)
-
-	cost := float64(tokens.InputTokens) * inputCostPer1M / 1000000
-	cost += float64(tokens.OutputTokens) * outputCostPer1M / 1000000
-	cost += float64(tokens.CacheReadInputTokens) * cacheReadCostPer1M / 1000000
-	cost += float64(tokens.CacheCreationInputTokens) * cacheCreationCostPer1M / 1000000
-
-	return cost
-}
@@ -0,0 +1,83 @@
+package api
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetAPIUsageHandler returns how often each route of this server was called
+// over the last hours (default 24, at most 720), with latencies and error
+// counts, busiest first up to limit (default 50), and the requests per hour.
+// route and method narrow the hourly series to one route. Requests are
+// written every minute, so the latest ones may be missing.
+func (h *SQLiteHandlers) GetAPIUsageHandler(c *gin.Context) {
+	hours, err := strconv.Atoi(c.DefaultQuery("hours", "24"))
+	if err != nil || hours < 1 || hours > int(APIUsageRetention/time.Hour) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "hours must be between 1 and 720",
+		})
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 {
+		limit = 50
+	}
+	route, method := c.Query("route"), c.DefaultQuery("method", http.MethodGet)
+
+	now := time.Now().UTC()
+	since := now.Truncate(time.Hour).Add(-time.Duration(hours-1) * time.Hour)
+
+	routes, err := h.repo.GetAPIUsage(since)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get API usage")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to get API usage",
+		})
+		return
+	}
+	timeline, err := h.repo.GetAPIUsageByHour(since, method, route)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get API usage by hour")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to get API usage",
+		})
+		return
+	}
+
+	minutes := now.Sub(since).Minutes()
+	var requests, clientErrors, serverErrors int64
+	var totalMs float64
+	for i := range routes {
+		routes[i].RequestsPerMin = math.Round(float64(routes[i].Requests)/minutes*100) / 100
+		routes[i].AvgMs = math.Round(routes[i].AvgMs*100) / 100
+		requests += routes[i].Requests
+		clientErrors += routes[i].ClientErrors
+		serverErrors += routes[i].ServerErrors
+		totalMs += routes[i].TotalMs
+	}
+	var avgMs float64
+	if requests > 0 {
+		avgMs = math.Round(totalMs/float64(requests)*100) / 100
+	}
+	if len(routes) > limit {
+		routes = routes[:limit]
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"since": since,
+		"hours": hours,
+		"totals": gin.H{
+			"requests":            requests,
+			"requests_per_minute": math.Round(float64(requests)/minutes*100) / 100,
+			"client_errors":       clientErrors,
+			"server_errors":       serverErrors,
+			"avg_ms":              avgMs,
+		},
+		"routes":   routes,
+		"timeline": timeline,
+	})
+}
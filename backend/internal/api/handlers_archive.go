@@ -0,0 +1,55 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/claude-session-manager/internal/database"
+)
+
+// ArchiveSessionHandler archives a session, hiding it from session listings
+// and metrics unless include_archived=true is passed. Nothing is deleted.
+// Sessions under legal hold can't be archived until the hold is released.
+func (h *SQLiteHandlers) ArchiveSessionHandler(c *gin.Context) {
+	h.setSessionArchived(c, h.repo.ArchiveSession)
+}
+
+// UnarchiveSessionHandler returns an archived session to listings and metrics
+func (h *SQLiteHandlers) UnarchiveSessionHandler(c *gin.Context) {
+	h.setSessionArchived(c, h.repo.UnarchiveSession)
+}
+
+func (h *SQLiteHandlers) setSessionArchived(c *gin.Context, update func(sessionID string) (*database.SessionSummary, error)) {
+	session, err := update(c.Param("id"))
+	if err != nil {
+		switch {
+		case errors.Is(err, database.ErrLegalHold):
+			c.JSON(http.StatusConflict, gin.H{
+				"error": "Session is under legal hold",
+			})
+		case strings.Contains(err.Error(), "not found"):
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Session not found",
+			})
+		default:
+			h.logger.WithError(err).Error("Failed to update archived state")
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to update session",
+			})
+		}
+		return
+	}
+
+	response, err := h.adapter.SessionSummaryToSessionResponse(session)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to convert session to response")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to process session",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,136 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/claude-session-manager/internal/budget"
+	"github.com/ksred/claude-session-manager/internal/database"
+	"github.com/sirupsen/logrus"
+)
+
+// BudgetHandlers contains handlers for budgets and their spend
+type BudgetHandlers struct {
+	repo    *database.SessionRepository
+	monitor *budget.Monitor
+	logger  *logrus.Logger
+}
+
+// NewBudgetHandlers creates new budget handlers
+func NewBudgetHandlers(repo *database.SessionRepository, monitor *budget.Monitor, logger *logrus.Logger) *BudgetHandlers {
+	return &BudgetHandlers{
+		repo:    repo,
+		monitor: monitor,
+		logger:  logger,
+	}
+}
+
+// CreateBudgetRequest defines a budget. Leaving project_name out covers all
+// projects.
+type CreateBudgetRequest struct {
+	Name        string  `json:"name" binding:"required"`
+	ProjectName string  `json:"project_name"`
+	Period      string  `json:"period" binding:"required"`
+	LimitUSD    float64 `json:"limit_usd" binding:"required"`
+}
+
+// CreateBudgetHandler adds a daily or monthly cost limit for a project or
+// all projects. Spend already over a threshold alerts straight away.
+func (h *BudgetHandlers) CreateBudgetHandler(c *gin.Context) {
+	var req CreateBudgetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "name, period and limit_usd are required",
+		})
+		return
+	}
+	if req.Period != database.BudgetDaily && req.Period != database.BudgetMonthly {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "period must be daily or monthly",
+		})
+		return
+	}
+	if req.LimitUSD <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "limit_usd must be positive",
+		})
+		return
+	}
+
+	b := &database.Budget{
+		Name:     req.Name,
+		Period:   req.Period,
+		LimitUSD: req.LimitUSD,
+	}
+	if projectName := strings.TrimSpace(req.ProjectName); projectName != "" {
+		b.ProjectName = &projectName
+	}
+	if err := h.repo.CreateBudget(b); err != nil {
+		h.logger.WithError(err).Error("Failed to create budget")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to create budget",
+		})
+		return
+	}
+
+	if _, err := h.monitor.Check(); err != nil {
+		h.logger.WithError(err).Error("Failed to check budgets")
+	}
+
+	c.JSON(http.StatusCreated, b)
+}
+
+// GetBudgetsHandler returns every budget
+func (h *BudgetHandlers) GetBudgetsHandler(c *gin.Context) {
+	budgets, err := h.repo.GetBudgets()
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get budgets")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve budgets",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"budgets": budgets,
+		"total":   len(budgets),
+	})
+}
+
+// DeleteBudgetHandler removes a budget and its alerts
+func (h *BudgetHandlers) DeleteBudgetHandler(c *gin.Context) {
+	if err := h.repo.DeleteBudget(c.Param("id")); err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Budget not found",
+			})
+			return
+		}
+		h.logger.WithError(err).Error("Failed to delete budget")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to delete budget",
+		})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// GetBudgetStatusHandler returns each budget's spend, remaining amount and
+// state (ok, warning at 80%, exceeded at 100%) in its current period
+func (h *BudgetHandlers) GetBudgetStatusHandler(c *gin.Context) {
+	statuses, err := h.monitor.Status()
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get budget status")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve budget status",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"budgets": statuses,
+		"total":   len(statuses),
+	})
+}
@@ -0,0 +1,218 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/claude-session-manager/internal/chat"
+	"github.com/ksred/claude-session-manager/internal/database"
+	"github.com/sirupsen/logrus"
+)
+
+// chatPromptTimeout is how long a prompt sent over REST may take, waiting
+// for a process included; the chat queue timeout can end the wait sooner
+const chatPromptTimeout = 10 * time.Minute
+
+// ChatHandlers contains handlers for chatting with a session over REST
+type ChatHandlers struct {
+	repo       *database.SessionRepository
+	chats      *chat.Repository
+	cliManager *chat.CLIManager
+	logger     *logrus.Logger
+
+	mutex   sync.Mutex
+	sending map[string]bool // sessions with a prompt running
+}
+
+// NewChatHandlers creates new chat handlers
+func NewChatHandlers(repo *database.SessionRepository, chats *chat.Repository, cliManager *chat.CLIManager, logger *logrus.Logger) *ChatHandlers {
+	return &ChatHandlers{
+		repo:       repo,
+		chats:      chats,
+		cliManager: cliManager,
+		logger:     logger,
+		sending:    make(map[string]bool),
+	}
+}
+
+// SendChatMessageHandler sends a prompt to a session's chat and waits for
+// Claude's reply. Both are stored in the chat and as messages of the
+// session, the reply with the token usage the CLI reported, and the stored
+// messages are returned. The prompt runs with the permission_mode and
+// allowed_tools in the body, if the chat permission policy allows them.
+func (h *ChatHandlers) SendChatMessageHandler(c *gin.Context) {
+	sessionID := c.Param("sessionId")
+
+	var req struct {
+		Content string `json:"content" binding:"required"`
+		chat.Permissions
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.Content) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "content is required",
+		})
+		return
+	}
+	if err := h.cliManager.CheckPermissions(req.Permissions); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	session, err := h.repo.GetSessionByID(sessionID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Session not found",
+			})
+			return
+		}
+		h.logger.WithError(err).Error("Failed to get session")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve session",
+		})
+		return
+	}
+
+	// Each prompt resumes the Claude session the last one left, so a
+	// session's prompts run one at a time
+	if !h.startSending(sessionID) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "A message is already being sent to this session",
+		})
+		return
+	}
+	defer h.stopSending(sessionID)
+
+	chatSession, err := h.chats.GetChatSessionBySessionID(sessionID)
+	if err == nil && chatSession == nil {
+		chatSession, err = h.chats.CreateChatSession(sessionID, 0)
+	}
+	if err != nil {
+		h.logger.WithError(err).WithField("session_id", sessionID).Error("Failed to get chat session")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to start chat",
+		})
+		return
+	}
+	claudeSessionID := ""
+	if chatSession.ClaudeSessionID != nil {
+		claudeSessionID = *chatSession.ClaudeSessionID
+	}
+
+	sentAt := time.Now()
+	prompt, err := h.chats.CreateChatMessage(chatSession.ID, chat.MessageTypeUser, req.Content, map[string]interface{}{
+		"source": "api",
+	})
+	if err != nil {
+		h.logger.WithError(err).WithField("session_id", sessionID).Error("Failed to store chat message")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to store message",
+		})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), chatPromptTimeout)
+	defer cancel()
+	response, err := h.cliManager.RunPrompt(ctx, session.ProjectPath, claudeSessionID, req.Content, chat.PromptOptions{
+		PermissionMode: req.Mode,
+		AllowedTools:   req.AllowedTools,
+		SessionID:      sessionID,
+	})
+	if err != nil {
+		status := http.StatusBadGateway
+		if errors.Is(err, chat.ErrQueueFull) || errors.Is(err, chat.ErrQueueTimeout) {
+			status = http.StatusServiceUnavailable
+		}
+		h.logger.WithError(err).WithField("session_id", sessionID).Warn("Chat prompt failed")
+		c.JSON(status, gin.H{
+			"error":          err.Error(),
+			"prompt_message": prompt,
+		})
+		return
+	}
+	repliedAt := time.Now()
+
+	if response.SessionID != "" {
+		if err := h.chats.UpdateChatSessionClaudeID(chatSession.ID, response.SessionID); err != nil {
+			h.logger.WithError(err).WithField("session_id", sessionID).Warn("Failed to update chat's Claude session")
+		}
+	}
+	h.chats.UpdateChatSessionActivity(chatSession.ID)
+
+	reply, err := h.chats.CreateChatReply(chatSession.ID, response)
+	if err != nil {
+		h.logger.WithError(err).WithField("session_id", sessionID).Error("Failed to store chat reply")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to store reply",
+		})
+		return
+	}
+
+	turn := &database.ChatTurn{
+		SessionID: sessionID,
+		PromptID:  prompt.ID,
+		ReplyID:   reply.ID,
+		Prompt:    req.Content,
+		Reply:     response.Result,
+		Model:     response.Model(),
+		SentAt:    sentAt,
+		RepliedAt: repliedAt,
+	}
+	if response.Usage != nil {
+		turn.Usage = &database.TokenUsage{
+			InputTokens:              response.Usage.InputTokens,
+			OutputTokens:             response.Usage.OutputTokens,
+			CacheCreationInputTokens: response.Usage.CacheCreationInputTokens,
+			CacheReadInputTokens:     response.Usage.CacheReadInputTokens,
+			ServiceTier:              response.Usage.ServiceTier,
+		}
+	}
+	messages, err := h.repo.RecordChatTurn(turn)
+	if err != nil {
+		h.logger.WithError(err).WithField("session_id", sessionID).Error("Failed to record chat turn")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to store messages",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"session_id":        sessionID,
+		"claude_session_id": response.SessionID,
+		"messages":          messages,
+		"duration_ms":       response.DurationMs,
+		"num_turns":         response.NumTurns,
+		"total_cost_usd":    response.TotalCostUSD,
+	})
+}
+
+// GetChatQueueHandler returns the chat messages and prompts running Claude
+// CLI processes and those waiting for one
+func (h *ChatHandlers) GetChatQueueHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, h.cliManager.QueueStatus())
+}
+
+// startSending marks a session as having a prompt running, reporting false
+// if one already is
+func (h *ChatHandlers) startSending(sessionID string) bool {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if h.sending[sessionID] {
+		return false
+	}
+	h.sending[sessionID] = true
+	return true
+}
+
+func (h *ChatHandlers) stopSending(sessionID string) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	delete(h.sending, sessionID)
+}
@@ -0,0 +1,80 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/claude-session-manager/internal/database"
+)
+
+// GetSessionCommandsHandler returns the shell commands Claude ran in a session
+// with the Bash tool, oldest first, with their exit codes
+func (h *SQLiteHandlers) GetSessionCommandsHandler(c *gin.Context) {
+	sessionID := c.Param("id")
+	if _, err := h.repo.GetSessionByID(sessionID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Session not found",
+		})
+		return
+	}
+
+	if err := h.repo.RefreshSessionToolCalls(sessionID); err != nil {
+		h.logger.WithError(err).WithField("session_id", sessionID).Warn("Failed to refresh session commands")
+	}
+	commands, err := h.repo.GetSessionCommands(sessionID)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get session commands")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve session commands",
+		})
+		return
+	}
+
+	failed := 0
+	for _, command := range commands {
+		if command.ExitCode != nil && *command.ExitCode != 0 {
+			failed++
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"session_id": sessionID,
+		"commands":   commands,
+		"total":      len(commands),
+		"failed":     failed,
+	})
+}
+
+// GetRecentCommandsHandler returns the most recent shell commands Claude ran
+// across sessions (limit, default 50, at most 500). project narrows them to a
+// project, q to commands containing it, and failed=true to those that exited
+// non-zero.
+func (h *SQLiteHandlers) GetRecentCommandsHandler(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 {
+		limit = 50
+	}
+	if limit > 500 {
+		limit = 500
+	}
+
+	filter := database.CommandFilter{
+		Project: c.Query("project"),
+		Query:   c.Query("q"),
+		Failed:  c.Query("failed") == "true",
+	}
+	commands, err := h.repo.GetRecentCommands(filter, limit)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get recent commands")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve recent commands",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"commands": commands,
+		"count":    len(commands),
+	})
+}
@@ -0,0 +1,117 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/claude-session-manager/internal/costcenter"
+	"github.com/ksred/claude-session-manager/internal/database"
+	"github.com/sirupsen/logrus"
+)
+
+// CostCenterHandlers contains handlers for charging usage back to cost centers
+type CostCenterHandlers struct {
+	repo      *database.SessionRepository
+	allocator *costcenter.Allocator
+	currency  string
+	logger    *logrus.Logger
+}
+
+// NewCostCenterHandlers creates new cost center handlers
+func NewCostCenterHandlers(repo *database.SessionRepository, allocator *costcenter.Allocator, currency string, logger *logrus.Logger) *CostCenterHandlers {
+	return &CostCenterHandlers{
+		repo:      repo,
+		allocator: allocator,
+		currency:  currency,
+		logger:    logger,
+	}
+}
+
+// chargebackPeriod parses the reporting period from either month (YYYY-MM)
+// or from and to (YYYY-MM-DD, both inclusive), defaulting to the current
+// month. The returned end is exclusive.
+func chargebackPeriod(c *gin.Context) (time.Time, time.Time, error) {
+	if month := c.Query("month"); month != "" {
+		start, err := time.Parse("2006-01", month)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("month must be YYYY-MM")
+		}
+		return start, start.AddDate(0, 1, 0), nil
+	}
+
+	now := time.Now().UTC()
+	from := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, 0)
+	if value := c.Query("from"); value != "" {
+		parsed, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("from must be YYYY-MM-DD")
+		}
+		from = parsed
+	}
+	if value := c.Query("to"); value != "" {
+		parsed, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("to must be YYYY-MM-DD")
+		}
+		to = parsed.AddDate(0, 0, 1)
+	}
+	if !to.After(from) {
+		return time.Time{}, time.Time{}, fmt.Errorf("to must not be before from")
+	}
+	return from, to, nil
+}
+
+// GetCostsByCostCenterHandler allocates a period's usage to cost centers by
+// the project and tag mappings in the config. format=csv returns the
+// allocation as a chargeback CSV with one row per cost center and project.
+func (h *CostCenterHandlers) GetCostsByCostCenterHandler(c *gin.Context) {
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "csv" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "format must be json or csv",
+		})
+		return
+	}
+
+	from, to, err := chargebackPeriod(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	costs, err := h.repo.GetSessionCosts(from, to)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get session costs")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve costs",
+		})
+		return
+	}
+	tags, err := h.repo.GetSessionTagMap()
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get session tags")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve costs",
+		})
+		return
+	}
+
+	report := h.allocator.Allocate(costs, tags, from, to, h.currency)
+	if format == "json" {
+		c.JSON(http.StatusOK, report)
+		return
+	}
+
+	filename := fmt.Sprintf("chargeback-%s-%s.csv", from.Format("20060102"), to.AddDate(0, 0, -1).Format("20060102"))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Status(http.StatusOK)
+	if err := report.WriteCSV(c.Writer); err != nil {
+		h.logger.WithError(err).Error("Failed to write chargeback export")
+	}
+}
@@ -0,0 +1,102 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/claude-session-manager/internal/database"
+)
+
+// GetCostAnalyticsHandler returns the cost of the last days (default 30, at
+// most 365) grouped by project, model, day or git branch (group_by, default
+// project), most expensive first, with a daily average and a monthly
+// estimate. project narrows it to one project, and archived sessions are
+// left out unless include_archived=true. meta describes the request's locale
+// and currency, and formatted=true adds display strings.
+func (h *SQLiteHandlers) GetCostAnalyticsHandler(c *gin.Context) {
+	groupBy := c.DefaultQuery("group_by", "project")
+	if !database.IsCostGrouping(groupBy) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid group_by parameter. Must be 'project', 'model', 'day' or 'branch'",
+		})
+		return
+	}
+	days, err := strconv.Atoi(c.DefaultQuery("days", "30"))
+	if err != nil || days < 1 || days > 365 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid days parameter. Must be between 1 and 365",
+		})
+		return
+	}
+
+	now := time.Now().UTC()
+	groups, err := h.repo.GetCostBreakdown(groupBy, database.CostBreakdownFilter{
+		From:            now.AddDate(0, 0, -days),
+		To:              now,
+		Project:         c.Query("project"),
+		IncludeArchived: c.Query("include_archived") == "true",
+	})
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get cost analytics")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve cost analytics",
+		})
+		return
+	}
+
+	var totalCost, cacheSavings float64
+	for _, group := range groups {
+		totalCost += group.CostUSD
+		cacheSavings += group.CacheSavings
+	}
+	formatter := requestFormatter(c)
+	formatted := wantsFormatted(c)
+	breakdown := make([]CostBreakdownEntry, len(groups))
+	for i, group := range groups {
+		breakdown[i] = CostBreakdownEntry{
+			Name: group.Name,
+			Cost: group.CostUSD,
+			Tokens: TokenBreakdown{
+				Total:  group.TotalTokens,
+				Cached: group.CachedTokens,
+				Fresh:  group.FreshTokens,
+			},
+			Sessions: group.Sessions,
+		}
+		if totalCost > 0 {
+			breakdown[i].Percentage = group.CostUSD / totalCost
+		}
+		if formatted {
+			breakdown[i].Display = map[string]string{
+				"cost":       formatter.Cost(group.CostUSD),
+				"tokens":     formatter.Tokens(int64(group.TotalTokens)),
+				"percentage": formatter.Number(breakdown[i].Percentage*100, 1) + "%",
+			}
+		}
+	}
+
+	dailyAverage := totalCost / float64(days)
+	response := gin.H{
+		"group_by":      groupBy,
+		"days":          days,
+		"total_cost":    totalCost,
+		"cache_savings": cacheSavings,
+		"breakdown":     breakdown,
+		"projection": CostProjection{
+			DailyAverage:    dailyAverage,
+			MonthlyEstimate: dailyAverage * 30,
+		},
+		"meta": formatter.Metadata(),
+	}
+	if formatted {
+		response["display"] = map[string]string{
+			"total_cost":       formatter.Cost(totalCost),
+			"cache_savings":    formatter.Cost(cacheSavings),
+			"daily_average":    formatter.Cost(dailyAverage),
+			"monthly_estimate": formatter.Cost(dailyAverage * 30),
+		}
+	}
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,218 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/claude-session-manager/internal/database"
+	"github.com/sirupsen/logrus"
+)
+
+// DashboardHandlers contains handlers for saved dashboards
+type DashboardHandlers struct {
+	repo   *database.SessionRepository
+	router *gin.Engine
+	logger *logrus.Logger
+}
+
+// NewDashboardHandlers creates new dashboard handlers. Widget endpoints are
+// checked against the routes registered on router.
+func NewDashboardHandlers(repo *database.SessionRepository, router *gin.Engine, logger *logrus.Logger) *DashboardHandlers {
+	return &DashboardHandlers{
+		repo:   repo,
+		router: router,
+		logger: logger,
+	}
+}
+
+// dashboardRequest is the request body for creating or updating a dashboard
+type dashboardRequest struct {
+	Name        string                     `json:"name" binding:"required"`
+	Description *string                    `json:"description"`
+	Widgets     []database.DashboardWidget `json:"widgets"`
+}
+
+// apply validates the request and copies it onto a dashboard
+func (h *DashboardHandlers) apply(req *dashboardRequest, dashboard *database.Dashboard) error {
+	seen := make(map[string]bool)
+	for i, widget := range req.Widgets {
+		if widget.ID == "" {
+			return fmt.Errorf("widget %d has no id", i)
+		}
+		if seen[widget.ID] {
+			return fmt.Errorf("widget id %q is used more than once", widget.ID)
+		}
+		seen[widget.ID] = true
+
+		if strings.Contains(widget.Endpoint, "?") {
+			return fmt.Errorf("widget %q: put the endpoint's query in params", widget.ID)
+		}
+		if !h.isAPIEndpoint(widget.Endpoint) {
+			return fmt.Errorf("widget %q: %q is not a GET endpoint of this API", widget.ID, widget.Endpoint)
+		}
+		if widget.Layout.X < 0 || widget.Layout.Y < 0 || widget.Layout.W < 0 || widget.Layout.H < 0 {
+			return fmt.Errorf("widget %q: layout can't be negative", widget.ID)
+		}
+	}
+
+	dashboard.Name = req.Name
+	dashboard.Description = req.Description
+	dashboard.Widgets = req.Widgets
+	return nil
+}
+
+// isAPIEndpoint reports whether path, such as /api/v1/analytics/costs or
+// /api/v1/sessions/abc/tokens, is served by a GET route under /api/v1
+func (h *DashboardHandlers) isAPIEndpoint(path string) bool {
+	if !strings.HasPrefix(path, "/api/v1/") {
+		return false
+	}
+	for _, route := range h.router.Routes() {
+		if route.Method == http.MethodGet && strings.HasPrefix(route.Path, "/api/v1/") && matchRoute(route.Path, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchRoute reports whether path matches a gin route pattern, where :name
+// matches one segment and *name the rest of the path
+func matchRoute(pattern, path string) bool {
+	patternParts := strings.Split(strings.Trim(pattern, "/"), "/")
+	pathParts := strings.Split(strings.Trim(path, "/"), "/")
+	for i, part := range patternParts {
+		if strings.HasPrefix(part, "*") {
+			return true
+		}
+		if i >= len(pathParts) {
+			return false
+		}
+		if strings.HasPrefix(part, ":") {
+			if pathParts[i] == "" {
+				return false
+			}
+			continue
+		}
+		if part != pathParts[i] {
+			return false
+		}
+	}
+	return len(patternParts) == len(pathParts)
+}
+
+// GetDashboardsHandler returns every saved dashboard
+func (h *DashboardHandlers) GetDashboardsHandler(c *gin.Context) {
+	dashboards, err := h.repo.GetDashboards()
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get dashboards")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve dashboards",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"dashboards": dashboards,
+		"total":      len(dashboards),
+	})
+}
+
+// GetDashboardHandler returns a dashboard with its widgets
+func (h *DashboardHandlers) GetDashboardHandler(c *gin.Context) {
+	dashboard, err := h.repo.GetDashboard(c.Param("id"))
+	if err != nil {
+		h.notFoundOrError(c, err, "Failed to retrieve dashboard")
+		return
+	}
+
+	c.JSON(http.StatusOK, dashboard)
+}
+
+// CreateDashboardHandler saves a new dashboard
+func (h *DashboardHandlers) CreateDashboardHandler(c *gin.Context) {
+	var req dashboardRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "name is required",
+		})
+		return
+	}
+
+	dashboard := &database.Dashboard{}
+	if err := h.apply(&req, dashboard); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	if err := h.repo.CreateDashboard(dashboard); err != nil {
+		h.notFoundOrError(c, err, "Failed to create dashboard")
+		return
+	}
+
+	c.JSON(http.StatusCreated, dashboard)
+}
+
+// UpdateDashboardHandler replaces a dashboard's name, description and
+// widgets
+func (h *DashboardHandlers) UpdateDashboardHandler(c *gin.Context) {
+	var req dashboardRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "name is required",
+		})
+		return
+	}
+
+	dashboard, err := h.repo.GetDashboard(c.Param("id"))
+	if err != nil {
+		h.notFoundOrError(c, err, "Failed to retrieve dashboard")
+		return
+	}
+	if err := h.apply(&req, dashboard); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	if err := h.repo.UpdateDashboard(dashboard); err != nil {
+		h.notFoundOrError(c, err, "Failed to update dashboard")
+		return
+	}
+
+	c.JSON(http.StatusOK, dashboard)
+}
+
+// DeleteDashboardHandler removes a dashboard
+func (h *DashboardHandlers) DeleteDashboardHandler(c *gin.Context) {
+	if err := h.repo.DeleteDashboard(c.Param("id")); err != nil {
+		h.notFoundOrError(c, err, "Failed to delete dashboard")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// notFoundOrError responds 404 for a missing dashboard, 409 for a name
+// already taken and 500 otherwise
+func (h *DashboardHandlers) notFoundOrError(c *gin.Context, err error, message string) {
+	switch {
+	case strings.Contains(err.Error(), "not found"):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Dashboard not found",
+		})
+	case strings.Contains(err.Error(), "UNIQUE constraint failed"):
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "A dashboard with this name already exists",
+		})
+	default:
+		h.logger.WithError(err).Error(message)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": message,
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/claude-session-manager/internal/database"
+)
+
+// GetDeadLettersHandler returns the JSONL lines imports couldn't read as
+// usual, most recently seen first, optionally of one outcome (decoded or
+// skipped)
+func (h *SQLiteHandlers) GetDeadLettersHandler(c *gin.Context) {
+	outcome := c.Query("outcome")
+	if outcome != "" && outcome != database.DeadLetterDecoded && outcome != database.DeadLetterSkipped {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "outcome must be decoded or skipped",
+		})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit <= 0 {
+		limit = 100
+	}
+	if limit > 1000 {
+		limit = 1000
+	}
+
+	letters, err := h.repo.GetDeadLetters(outcome, limit)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get dead letters")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve dead letters",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"dead_letters": letters,
+		"total":        len(letters),
+		"limit":        limit,
+	})
+}
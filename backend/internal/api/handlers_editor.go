@@ -0,0 +1,225 @@
+package api
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/claude-session-manager/internal/database"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// editorPollInterval is how often a waiting status request checks for a
+	// change
+	editorPollInterval = 250 * time.Millisecond
+	// editorMaxWait caps how long a status request is held open
+	editorMaxWait = 25 * time.Second
+)
+
+// EditorHandlers contains the small, low-latency handlers used by editor
+// extensions
+type EditorHandlers struct {
+	repo    *database.SessionRepository
+	maxWait time.Duration
+	logger  *logrus.Logger
+	mu      sync.Mutex
+	cache   map[string]editorStatusEntry
+}
+
+type editorStatusEntry struct {
+	status    gin.H
+	version   string
+	expiresAt time.Time
+}
+
+// NewEditorHandlers creates new editor handlers. Status requests are held
+// for less than writeTimeout so the server doesn't cut them off; 0 means no
+// write timeout.
+func NewEditorHandlers(repo *database.SessionRepository, writeTimeout time.Duration, logger *logrus.Logger) *EditorHandlers {
+	maxWait := editorMaxWait
+	if writeTimeout > 0 && writeTimeout-2*time.Second < maxWait {
+		maxWait = writeTimeout - 2*time.Second
+	}
+	if maxWait < time.Second {
+		maxWait = time.Second
+	}
+	return &EditorHandlers{
+		repo:    repo,
+		maxWait: maxWait,
+		logger:  logger,
+		cache:   make(map[string]editorStatusEntry),
+	}
+}
+
+// GetFileSessionsHandler returns the sessions whose tool calls touched the
+// file at path, most recently touched first
+func (h *EditorHandlers) GetFileSessionsHandler(c *gin.Context) {
+	path := c.Query("path")
+	if path == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "path is required",
+		})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if err != nil || limit <= 0 {
+		limit = 10
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
+	sessions, err := h.repo.GetFileSessions(path, limit)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get file sessions")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve file sessions",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"path":     path,
+		"sessions": sessions,
+	})
+}
+
+// GetWorkspaceCostHandler returns today's cost and tokens for sessions run in
+// a workspace directory or below it
+func (h *EditorHandlers) GetWorkspaceCostHandler(c *gin.Context) {
+	workspace := c.Query("workspace")
+	if workspace == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "workspace is required",
+		})
+		return
+	}
+
+	usage, err := h.repo.GetWorkspaceUsage(workspace, startOfDay(time.Now()))
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get workspace usage")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve workspace cost",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"workspace":       workspace,
+		"sessions":        usage.TodaySessions,
+		"tokens":          usage.TodayTokens,
+		"cost_usd":        usage.TodayCostUSD,
+		"active_sessions": usage.ActiveSessions,
+	})
+}
+
+// GetStatusHandler returns active sessions, last activity and today's usage,
+// optionally for a workspace, with a version that changes when they do. When
+// since matches the current version the request is held until something
+// changes or wait seconds pass, so an extension can long-poll instead of
+// keeping a WebSocket open.
+func (h *EditorHandlers) GetStatusHandler(c *gin.Context) {
+	workspace := c.Query("workspace")
+	since := c.Query("since")
+
+	wait := h.maxWait
+	if waitStr := c.Query("wait"); waitStr != "" {
+		seconds, err := strconv.Atoi(waitStr)
+		if err != nil || seconds < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "wait must be a number of seconds",
+			})
+			return
+		}
+		if time.Duration(seconds)*time.Second < wait {
+			wait = time.Duration(seconds) * time.Second
+		}
+	}
+
+	deadline := time.Now().Add(wait)
+	ticker := time.NewTicker(editorPollInterval)
+	defer ticker.Stop()
+
+	for {
+		entry, err := h.status(workspace)
+		if err != nil {
+			h.logger.WithError(err).Error("Failed to get editor status")
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to retrieve status",
+			})
+			return
+		}
+
+		changed := entry.version != since
+		if changed || !time.Now().Before(deadline) {
+			status := gin.H{"changed": changed}
+			for key, value := range entry.status {
+				status[key] = value
+			}
+			c.JSON(http.StatusOK, status)
+			return
+		}
+
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// status returns the cached status for a workspace, refreshing it once it
+// expires so concurrent pollers share one query per interval
+func (h *EditorHandlers) status(workspace string) (editorStatusEntry, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	now := time.Now()
+	if entry, ok := h.cache[workspace]; ok && now.Before(entry.expiresAt) {
+		return entry, nil
+	}
+
+	usage, err := h.repo.GetWorkspaceUsage(workspace, startOfDay(now))
+	if err != nil {
+		return editorStatusEntry{}, err
+	}
+
+	var lastActivity int64
+	if usage.LastActivity != nil {
+		lastActivity = usage.LastActivity.UnixNano()
+	}
+	hash := fnv.New64a()
+	fmt.Fprintf(hash, "%d|%d|%d|%.6f", usage.ActiveSessions, lastActivity, usage.TodayTokens, usage.TodayCostUSD)
+	version := strconv.FormatUint(hash.Sum64(), 36)
+
+	// Drop statuses for workspaces no longer polled
+	for key, cached := range h.cache {
+		if now.After(cached.expiresAt) {
+			delete(h.cache, key)
+		}
+	}
+	entry := editorStatusEntry{
+		status: gin.H{
+			"version":         version,
+			"active_sessions": usage.ActiveSessions,
+			"last_activity":   usage.LastActivity,
+			"tokens_today":    usage.TodayTokens,
+			"cost_usd_today":  usage.TodayCostUSD,
+		},
+		version:   version,
+		expiresAt: now.Add(editorPollInterval - 50*time.Millisecond),
+	}
+	h.cache[workspace] = entry
+	return entry, nil
+}
+
+// startOfDay returns midnight at the start of t's day in its location
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
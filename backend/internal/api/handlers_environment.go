@@ -0,0 +1,156 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/claude-session-manager/internal/database"
+	"github.com/ksred/claude-session-manager/internal/gitinfo"
+)
+
+// filterByEnvironment attaches each session's captured environment and keeps
+// only sessions matching the os, terminal, git_remote and client_version
+// query parameters. Sessions without a captured environment are dropped
+// whenever any of those filters is set.
+func (h *SQLiteHandlers) filterByEnvironment(c *gin.Context, responses []database.SessionResponse) []database.SessionResponse {
+	environments, err := h.repo.GetSessionEnvironments()
+	if err != nil {
+		h.logger.WithError(err).Warn("Failed to get session environments")
+		return responses
+	}
+
+	filters := map[string]string{
+		"os":             c.Query("os"),
+		"terminal":       c.Query("terminal"),
+		"git_remote":     c.Query("git_remote"),
+		"client_version": c.Query("client_version"),
+	}
+	if remote := filters["git_remote"]; remote != "" {
+		if sanitized := gitinfo.SanitizeRemote(remote); sanitized != "" {
+			filters["git_remote"] = sanitized
+		}
+	}
+	matches := func(value *string, want string) bool {
+		return want == "" || (value != nil && strings.EqualFold(*value, want))
+	}
+
+	filtered := responses[:0]
+	for _, response := range responses {
+		env := environments[response.ID]
+		response.Environment = env
+		if env == nil {
+			env = &database.SessionEnvironment{}
+		}
+		if matches(env.OS, filters["os"]) && matches(env.Terminal, filters["terminal"]) &&
+			matches(env.GitRemote, filters["git_remote"]) && matches(env.ClientVersion, filters["client_version"]) {
+			filtered = append(filtered, response)
+		}
+	}
+	return filtered
+}
+
+// GetSessionEnvironmentHandler returns the environment a session ran in
+func (h *SQLiteHandlers) GetSessionEnvironmentHandler(c *gin.Context) {
+	env, err := h.repo.GetSessionEnvironment(c.Param("id"))
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "No environment captured for this session",
+			})
+			return
+		}
+		h.logger.WithError(err).Error("Failed to get session environment")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve session environment",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, env)
+}
+
+// ReportSessionEnvironmentHandler records environment details reported by a
+// client, such as a hook script that knows the terminal. Omitted fields keep
+// their detected values; git_remote is sanitized before it is stored.
+func (h *SQLiteHandlers) ReportSessionEnvironmentHandler(c *gin.Context) {
+	var req struct {
+		OS            *string `json:"os"`
+		Terminal      *string `json:"terminal"`
+		GitRemote     *string `json:"git_remote"`
+		CWD           *string `json:"cwd"`
+		ClientVersion *string `json:"client_version"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request body",
+		})
+		return
+	}
+
+	sessionID := c.Param("id")
+	if _, err := h.repo.GetSessionByID(sessionID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Session not found",
+		})
+		return
+	}
+
+	if req.GitRemote != nil {
+		remote := gitinfo.SanitizeRemote(*req.GitRemote)
+		req.GitRemote = nil
+		if remote != "" {
+			req.GitRemote = &remote
+		}
+	}
+	if req.OS != nil {
+		lowered := strings.ToLower(*req.OS)
+		req.OS = &lowered
+	}
+
+	env := &database.SessionEnvironment{
+		SessionID:     sessionID,
+		OS:            req.OS,
+		Terminal:      req.Terminal,
+		GitRemote:     req.GitRemote,
+		CWD:           req.CWD,
+		ClientVersion: req.ClientVersion,
+		Source:        database.EnvironmentSourceReported,
+	}
+	if err := h.repo.SaveSessionEnvironment(env); err != nil {
+		h.logger.WithError(err).Error("Failed to save session environment")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to save session environment",
+		})
+		return
+	}
+
+	saved, err := h.repo.GetSessionEnvironment(sessionID)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get session environment")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve session environment",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, saved)
+}
+
+// GetEnvironmentCountsHandler returns how many sessions share each OS,
+// terminal, git remote and client version, for building session filters
+func (h *SQLiteHandlers) GetEnvironmentCountsHandler(c *gin.Context) {
+	counts, err := h.repo.GetEnvironmentCounts()
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get environment counts")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve environments",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"environments": counts,
+		"total":        len(counts),
+	})
+}
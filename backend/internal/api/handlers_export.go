@@ -0,0 +1,339 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/claude-session-manager/internal/database"
+	"github.com/ksred/claude-session-manager/internal/environment"
+	"github.com/ksred/claude-session-manager/internal/export"
+)
+
+// ExportAnonymizedHandler returns a metrics-only dataset with hashed identifiers
+// and no content, suitable for sharing usage patterns outside the organization.
+// The hash salt is read from the X-Export-Salt header rather than the query
+// string so it is never written to request logs.
+func (h *SQLiteHandlers) ExportAnonymizedHandler(c *gin.Context) {
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "csv" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "format must be json or csv",
+		})
+		return
+	}
+
+	days := 90
+	if daysStr := c.Query("days"); daysStr != "" {
+		parsed, err := strconv.Atoi(daysStr)
+		if err != nil || parsed <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "days must be a positive integer",
+			})
+			return
+		}
+		days = parsed
+	}
+	since := time.Now().UTC().AddDate(0, 0, -days)
+
+	anonymizer, err := export.NewAnonymizer(c.GetHeader("X-Export-Salt"))
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to create anonymizer")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to create export",
+		})
+		return
+	}
+
+	metrics, err := h.repo.GetSessionMetrics(since)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get session metrics")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve session metrics",
+		})
+		return
+	}
+
+	dataset := anonymizer.Dataset(metrics, since)
+	filename := fmt.Sprintf("claude-usage-anonymized-%s.%s", dataset.GeneratedAt.Format("20060102"), format)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	if format == "csv" {
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		c.Status(http.StatusOK)
+		err = dataset.WriteCSV(c.Writer)
+	} else {
+		c.Header("Content-Type", "application/json; charset=utf-8")
+		c.Status(http.StatusOK)
+		err = dataset.WriteJSON(c.Writer)
+	}
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to write anonymized export")
+	}
+}
+
+// ExportAnalyticsHandler streams a raw analytics dataset (token_usage,
+// sessions or tool_results) as CSV or Parquet, for loading into spreadsheets
+// or DuckDB. With days only rows from that many days back are included. Rows
+// are written as they are read, so an error partway through can only be
+// logged; the client sees a truncated file.
+func (h *SQLiteHandlers) ExportAnalyticsHandler(c *gin.Context) {
+	dataset := c.Query("dataset")
+	columns, ok := database.AnalyticsExportColumns(dataset)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "dataset must be token_usage, sessions or tool_results",
+		})
+		return
+	}
+	format := c.DefaultQuery("format", "csv")
+	if !export.IsAnalyticsFormat(format) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "format must be csv or parquet",
+		})
+		return
+	}
+
+	var since time.Time
+	if daysStr := c.Query("days"); daysStr != "" {
+		days, err := strconv.Atoi(daysStr)
+		if err != nil || days <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "days must be a positive integer",
+			})
+			return
+		}
+		since = time.Now().UTC().AddDate(0, 0, -days)
+	}
+
+	filename := fmt.Sprintf("claude-%s-%s.%s", strings.ReplaceAll(dataset, "_", "-"), time.Now().UTC().Format("20060102"), format)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Header("Content-Type", export.AnalyticsContentType(format))
+	c.Status(http.StatusOK)
+
+	writer, err := export.NewAnalyticsWriter(format, c.Writer, columns)
+	if err == nil {
+		err = h.repo.StreamAnalyticsExport(dataset, since, writer.WriteRow)
+		if closeErr := writer.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		h.logger.WithError(err).WithField("dataset", dataset).Error("Failed to write analytics export")
+	}
+}
+
+// ExportComplianceHandler returns a zip bundle of everything stored about a
+// session (transcript, tool results, file diffs, audit log) with SHA-256
+// checksums of each entry, for retention in regulated environments
+func (h *SQLiteHandlers) ExportComplianceHandler(c *gin.Context) {
+	sessionID := c.Param("id")
+
+	records, err := h.repo.GetComplianceRecords(sessionID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Session not found",
+			})
+			return
+		}
+		h.logger.WithError(err).Error("Failed to get compliance records")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve session records",
+		})
+		return
+	}
+
+	filename := fmt.Sprintf("session-%s-compliance-%s.zip", sessionID, time.Now().UTC().Format("20060102"))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Header("Content-Type", "application/zip")
+	c.Status(http.StatusOK)
+
+	if err := export.WriteComplianceBundle(c.Writer, records); err != nil {
+		h.logger.WithError(err).WithField("session_id", sessionID).Error("Failed to write compliance export")
+		return
+	}
+
+	if err := h.repo.LogActivity(&database.ActivityLogEntry{
+		SessionID:    &sessionID,
+		ActivityType: "compliance_export",
+		Details:      fmt.Sprintf("Compliance export of %d messages", len(records.Messages)),
+		Timestamp:    time.Now().UTC(),
+	}); err != nil {
+		h.logger.WithError(err).WithField("session_id", sessionID).Warn("Failed to log compliance export")
+	}
+}
+
+// transcriptFormats maps each transcript export format to its file extension
+// and content type
+var transcriptFormats = map[string][2]string{
+	export.TranscriptJSON:     {"json", "application/json; charset=utf-8"},
+	export.TranscriptMarkdown: {"md", "text/markdown; charset=utf-8"},
+	export.TranscriptHTML:     {"html", "text/html; charset=utf-8"},
+}
+
+// ExportSessionHandler returns a session's full conversation, in
+// conversation order with its tool calls, token usage and cost, as a
+// downloadable JSON, Markdown or HTML document
+func (h *SQLiteHandlers) ExportSessionHandler(c *gin.Context) {
+	sessionID := c.Param("id")
+	format := c.DefaultQuery("format", export.TranscriptJSON)
+	formatInfo, ok := transcriptFormats[format]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "format must be json, markdown or html",
+		})
+		return
+	}
+
+	records, err := h.repo.GetTranscriptRecords(sessionID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Session not found",
+			})
+			return
+		}
+		h.logger.WithError(err).Error("Failed to get transcript records")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve session records",
+		})
+		return
+	}
+
+	transcript := export.BuildTranscript(records)
+	filename := fmt.Sprintf("session-%s.%s", sessionID, formatInfo[0])
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Header("Content-Type", formatInfo[1])
+	c.Status(http.StatusOK)
+
+	switch format {
+	case export.TranscriptMarkdown:
+		err = transcript.WriteMarkdown(c.Writer)
+	case export.TranscriptHTML:
+		err = transcript.WriteHTML(c.Writer)
+	default:
+		err = transcript.WriteJSON(c.Writer)
+	}
+	if err != nil {
+		h.logger.WithError(err).WithField("session_id", sessionID).Error("Failed to write session export")
+	}
+}
+
+// ExportChatHandler returns the chats run against a session from the UI as
+// a downloadable Markdown or JSON document, each reply with the duration,
+// turns and cost the CLI reported and their totals in a cost footer
+func (h *SQLiteHandlers) ExportChatHandler(c *gin.Context) {
+	sessionID := c.Param("sessionId")
+	format := c.DefaultQuery("format", export.TranscriptMarkdown)
+	formatInfo, ok := transcriptFormats[format]
+	if !ok || format == export.TranscriptHTML {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "format must be markdown or json",
+		})
+		return
+	}
+
+	records, err := h.repo.GetChatTranscriptRecords(sessionID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Session not found",
+			})
+			return
+		}
+		h.logger.WithError(err).Error("Failed to get chat transcript records")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve chat messages",
+		})
+		return
+	}
+	if len(records.Messages) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Session has no chat messages",
+		})
+		return
+	}
+
+	transcript := export.BuildChatTranscript(records)
+	filename := fmt.Sprintf("chat-%s.%s", sessionID, formatInfo[0])
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Header("Content-Type", formatInfo[1])
+	c.Status(http.StatusOK)
+
+	if format == export.TranscriptJSON {
+		err = transcript.WriteJSON(c.Writer)
+	} else {
+		err = transcript.WriteMarkdown(c.Writer)
+	}
+	if err != nil {
+		h.logger.WithError(err).WithField("session_id", sessionID).Error("Failed to write chat export")
+	}
+}
+
+// ExportReproBundleHandler returns a zip bundle for reproducing a session's
+// changes: its prompts in order, the files it changed, the commit it started
+// from when the repository is still on this machine, and the model and
+// client metadata
+func (h *SQLiteHandlers) ExportReproBundleHandler(c *gin.Context) {
+	sessionID := c.Param("id")
+
+	records, err := h.repo.GetReproRecords(sessionID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Session not found",
+			})
+			return
+		}
+		h.logger.WithError(err).Error("Failed to get repro records")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve session records",
+		})
+		return
+	}
+
+	git := reproGit(c.Request.Context(), records)
+
+	filename := fmt.Sprintf("session-%s-repro-%s.zip", sessionID, time.Now().UTC().Format("20060102"))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Header("Content-Type", "application/zip")
+	c.Status(http.StatusOK)
+
+	if err := export.WriteReproBundle(c.Writer, records, git); err != nil {
+		h.logger.WithError(err).WithField("session_id", sessionID).Error("Failed to write repro bundle")
+	}
+}
+
+// reproGit looks up the repository a session ran in, if it is still on this
+// machine, for the commits before the session started and ended
+func reproGit(ctx context.Context, records *database.ReproRecords) *export.ReproGit {
+	git := &export.ReproGit{}
+	if records.Environment != nil && records.Environment.GitRemote != nil {
+		git.Remote = *records.Environment.GitRemote
+	}
+
+	dir := records.Session.ProjectPath
+	for _, message := range records.Messages {
+		if message.CWD != "" {
+			dir = message.CWD
+			break
+		}
+	}
+	if git.Root = environment.RepoRoot(ctx, dir); git.Root == "" {
+		return git
+	}
+	if git.Remote == "" {
+		git.Remote = environment.GitRemote(ctx, git.Root)
+	}
+	git.BaseCommit = environment.CommitBefore(ctx, git.Root, records.Session.StartTime)
+	git.EndCommit = environment.CommitBefore(ctx, git.Root, records.Session.LastActivity)
+	if git.BaseCommit != "" {
+		git.Tracked = environment.TrackedFiles(ctx, git.Root, git.BaseCommit)
+	}
+	return git
+}
@@ -0,0 +1,168 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/claude-session-manager/internal/database"
+	"github.com/ksred/claude-session-manager/internal/exportjobs"
+	"github.com/sirupsen/logrus"
+)
+
+// ExportJobHandlers contains handlers for exports generated in the background
+// and downloaded through signed URLs
+type ExportJobHandlers struct {
+	repo   *database.SessionRepository
+	runner *exportjobs.Runner
+	logger *logrus.Logger
+}
+
+// NewExportJobHandlers creates new export job handlers
+func NewExportJobHandlers(repo *database.SessionRepository, runner *exportjobs.Runner, logger *logrus.Logger) *ExportJobHandlers {
+	return &ExportJobHandlers{
+		repo:   repo,
+		runner: runner,
+		logger: logger,
+	}
+}
+
+// CreateExportJobHandler starts generating a backup or analytics dataset,
+// answering 202 with the pending job. Each client may start exports.per_hour
+// exports an hour; beyond that it is told when it may start another.
+func (h *ExportJobHandlers) CreateExportJobHandler(c *gin.Context) {
+	var req exportjobs.Request
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid export",
+		})
+		return
+	}
+
+	client := requestClient(c)
+	job, err := h.runner.Submit(client, req)
+	switch {
+	case errors.Is(err, exportjobs.ErrInvalidRequest):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": strings.TrimPrefix(err.Error(), exportjobs.ErrInvalidRequest.Error()+": "),
+		})
+		return
+	case errors.Is(err, exportjobs.ErrLimitExceeded):
+		usage, usageErr := h.runner.Usage(client)
+		if usageErr == nil && usage.ResetAt != nil {
+			wait := math.Max(1, math.Ceil(time.Until(*usage.ResetAt).Seconds()))
+			c.Header("Retry-After", strconv.Itoa(int(wait)))
+		}
+		c.JSON(http.StatusTooManyRequests, gin.H{
+			"error": "Export limit exceeded",
+			"usage": usage,
+		})
+		return
+	case err != nil:
+		h.logger.WithError(err).Error("Failed to start export")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to start export",
+		})
+		return
+	}
+
+	c.Header("Location", fmt.Sprintf("/api/v1/exports/%s", job.ID))
+	c.JSON(http.StatusAccepted, job)
+}
+
+// GetExportJobsHandler lists the caller's exports, with download URLs for
+// the ones that have succeeded, and how many more it may start this hour
+func (h *ExportJobHandlers) GetExportJobsHandler(c *gin.Context) {
+	client := requestClient(c)
+	jobs, err := h.repo.GetExportJobs(client)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get export jobs")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve exports",
+		})
+		return
+	}
+	usage, err := h.runner.Usage(client)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get export usage")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve exports",
+		})
+		return
+	}
+
+	views := make([]exportjobs.Job, 0, len(jobs))
+	for i := range jobs {
+		views = append(views, h.runner.Job(&jobs[i]))
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"exports": views,
+		"count":   len(views),
+		"usage":   usage,
+	})
+}
+
+// GetExportJobHandler returns one of the caller's exports, with a freshly
+// signed download URL once it has succeeded, for polling until it finishes
+func (h *ExportJobHandlers) GetExportJobHandler(c *gin.Context) {
+	job, err := h.repo.GetExportJob(c.Param("id"))
+	if err == nil && job.Client != requestClient(c) {
+		err = fmt.Errorf("export job not found: %s", job.ID)
+	}
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Export not found",
+			})
+			return
+		}
+		h.logger.WithError(err).Error("Failed to get export job")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve export",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, h.runner.Job(job))
+}
+
+// DownloadExportHandler sends a finished export's file. It needs no API key:
+// the URL's signature and expiry are checked instead, so it can be handed to
+// a browser or curl. The server's write timeout doesn't apply.
+func (h *ExportJobHandlers) DownloadExportHandler(c *gin.Context) {
+	job, path, err := h.runner.Open(c.Param("id"), c.Query("expires"), c.Query("signature"))
+	if err == nil {
+		_, err = os.Stat(path)
+	}
+	switch {
+	case errors.Is(err, exportjobs.ErrInvalidSignature):
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Invalid download signature",
+		})
+	case errors.Is(err, exportjobs.ErrURLExpired):
+		c.JSON(http.StatusGone, gin.H{
+			"error": "Download URL expired; fetch the export again for a new one",
+		})
+	case err != nil && (os.IsNotExist(err) || strings.Contains(err.Error(), "not found")):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Export not found",
+		})
+	case err != nil:
+		h.logger.WithError(err).Error("Failed to open export")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to open export",
+		})
+	default:
+		// Large exports may take longer to send than server.write_timeout
+		if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{}); err != nil {
+			h.logger.WithError(err).Debug("Failed to lift write deadline for export download")
+		}
+		c.FileAttachment(path, *job.FileName)
+	}
+}
@@ -0,0 +1,108 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/claude-session-manager/internal/database"
+	"github.com/ksred/claude-session-manager/internal/federation"
+	"github.com/sirupsen/logrus"
+)
+
+// FederationHandlers contains handlers for sharing usage aggregates with a
+// federation parent and, on the parent, serving the org-wide rollup
+type FederationHandlers struct {
+	repo   *database.SessionRepository
+	poller *federation.Poller // nil unless federation mode is enabled
+	days   int
+	logger *logrus.Logger
+}
+
+// NewFederationHandlers creates new federation handlers
+func NewFederationHandlers(repo *database.SessionRepository, poller *federation.Poller, days int, logger *logrus.Logger) *FederationHandlers {
+	return &FederationHandlers{
+		repo:   repo,
+		poller: poller,
+		days:   days,
+		logger: logger,
+	}
+}
+
+// GetFederationSummaryHandler returns this instance's usage aggregates for a federation parent
+func (h *FederationHandlers) GetFederationSummaryHandler(c *gin.Context) {
+	days := h.days
+	if daysStr := c.Query("days"); daysStr != "" {
+		if parsed, err := strconv.Atoi(daysStr); err == nil && parsed > 0 && parsed <= 365 {
+			days = parsed
+		}
+	}
+
+	summary, err := h.repo.GetUsageSummary(days)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get usage summary")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve usage summary",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, summary)
+}
+
+// GetOrgSummaryHandler returns usage combined across every federated instance
+func (h *FederationHandlers) GetOrgSummaryHandler(c *gin.Context) {
+	instances, ok := h.loadInstances(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, federation.Rollup(instances))
+}
+
+// GetOrgInstancesHandler returns the poll status and latest summary of each federated instance
+func (h *FederationHandlers) GetOrgInstancesHandler(c *gin.Context) {
+	instances, ok := h.loadInstances(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"instances": instances,
+		"total":     len(instances),
+	})
+}
+
+// RefreshOrgHandler polls every federated instance immediately
+func (h *FederationHandlers) RefreshOrgHandler(c *gin.Context) {
+	if h.poller == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": "Federation mode not enabled",
+		})
+		return
+	}
+
+	h.poller.PollAll(c.Request.Context())
+	h.GetOrgInstancesHandler(c)
+}
+
+// loadInstances reads the stored snapshots, writing an error response on failure
+func (h *FederationHandlers) loadInstances(c *gin.Context) ([]federation.InstanceStatus, bool) {
+	if h.poller == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": "Federation mode not enabled",
+		})
+		return nil, false
+	}
+
+	snapshots, err := h.repo.GetFederationSnapshots()
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get federation snapshots")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve federation data",
+		})
+		return nil, false
+	}
+
+	return federation.Instances(snapshots), true
+}
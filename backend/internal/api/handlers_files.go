@@ -0,0 +1,204 @@
+package api
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/claude-session-manager/internal/database"
+)
+
+// FileChangeSession summarizes the changes one session made to a file
+type FileChangeSession struct {
+	SessionID    string    `json:"session_id"`
+	ProjectName  string    `json:"project_name"`
+	GitBranch    *string   `json:"git_branch,omitempty"`
+	Changes      int       `json:"changes"`
+	Tools        []string  `json:"tools"`
+	FirstChanged time.Time `json:"first_changed"`
+	LastChanged  time.Time `json:"last_changed"`
+}
+
+// GetFileChangesHandler resolves a file path to every session and message
+// that modified it. path matches exactly or, unless match=exact, as a suffix
+// at a directory boundary, so a path relative to the repository works.
+func (h *SQLiteHandlers) GetFileChangesHandler(c *gin.Context) {
+	path := strings.TrimSpace(c.Query("path"))
+	if path == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Query parameter 'path' is required",
+		})
+		return
+	}
+
+	match := c.DefaultQuery("match", "suffix")
+	if match != "suffix" && match != "exact" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "match must be exact or suffix",
+		})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "200"))
+	if err != nil || limit <= 0 {
+		limit = 200
+	}
+	if limit > 1000 {
+		limit = 1000
+	}
+
+	changes, total, err := h.repo.GetFileChanges(path, match == "exact", limit)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get file changes")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve file changes",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"path":      path,
+		"match":     match,
+		"files":     changedFiles(changes),
+		"sessions":  fileChangeSessions(changes),
+		"changes":   changes,
+		"total":     total,
+		"truncated": total > len(changes),
+	})
+}
+
+// FileHistorySession lists, oldest first, the edits one session made to a file
+type FileHistorySession struct {
+	SessionID   string              `json:"session_id"`
+	ProjectName string              `json:"project_name"`
+	GitBranch   *string             `json:"git_branch,omitempty"`
+	Edits       []database.FileEdit `json:"edits"`
+}
+
+// GetFileHistoryHandler returns the Edit, Write and MultiEdit tool calls on a
+// file with their diffs, oldest first and grouped by session. path matches as
+// in GetFileChangesHandler; limit keeps the most recent edits.
+func (h *SQLiteHandlers) GetFileHistoryHandler(c *gin.Context) {
+	path := strings.TrimSpace(c.Query("path"))
+	if path == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Query parameter 'path' is required",
+		})
+		return
+	}
+
+	match := c.DefaultQuery("match", "suffix")
+	if match != "suffix" && match != "exact" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "match must be exact or suffix",
+		})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "200"))
+	if err != nil || limit <= 0 {
+		limit = 200
+	}
+	if limit > 1000 {
+		limit = 1000
+	}
+
+	edits, total, err := h.repo.GetFileHistory(path, match == "exact", limit)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get file history")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve file history",
+		})
+		return
+	}
+
+	files := make([]database.FileChange, len(edits))
+	for i, edit := range edits {
+		files[i] = edit.FileChange
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"path":      path,
+		"match":     match,
+		"files":     changedFiles(files),
+		"sessions":  fileHistorySessions(edits),
+		"total":     total,
+		"truncated": total > len(edits),
+	})
+}
+
+// fileHistorySessions groups edits by session, ordered by each session's
+// first edit
+func fileHistorySessions(edits []database.FileEdit) []FileHistorySession {
+	sessions := []FileHistorySession{}
+	index := make(map[string]int)
+	for _, edit := range edits {
+		i, ok := index[edit.SessionID]
+		if !ok {
+			i = len(sessions)
+			index[edit.SessionID] = i
+			sessions = append(sessions, FileHistorySession{
+				SessionID:   edit.SessionID,
+				ProjectName: edit.ProjectName,
+				GitBranch:   edit.GitBranch,
+				Edits:       []database.FileEdit{},
+			})
+		}
+		sessions[i].Edits = append(sessions[i].Edits, edit)
+	}
+	return sessions
+}
+
+// changedFiles returns the distinct paths a lookup matched, which is more
+// than one when a suffix is ambiguous
+func changedFiles(changes []database.FileChange) []string {
+	files := []string{}
+	seen := make(map[string]bool)
+	for _, change := range changes {
+		if !seen[change.FilePath] {
+			seen[change.FilePath] = true
+			files = append(files, change.FilePath)
+		}
+	}
+	sort.Strings(files)
+	return files
+}
+
+// fileChangeSessions groups changes, most recent first, by session
+func fileChangeSessions(changes []database.FileChange) []FileChangeSession {
+	sessions := []FileChangeSession{}
+	index := make(map[string]int)
+	for _, change := range changes {
+		i, ok := index[change.SessionID]
+		if !ok {
+			i = len(sessions)
+			index[change.SessionID] = i
+			sessions = append(sessions, FileChangeSession{
+				SessionID:   change.SessionID,
+				ProjectName: change.ProjectName,
+				GitBranch:   change.GitBranch,
+				Tools:       []string{},
+				LastChanged: change.Timestamp,
+			})
+		}
+
+		session := &sessions[i]
+		session.Changes++
+		session.FirstChanged = change.Timestamp
+		if !containsTool(session.Tools, change.ToolName) {
+			session.Tools = append(session.Tools, change.ToolName)
+		}
+	}
+	return sessions
+}
+
+func containsTool(tools []string, tool string) bool {
+	for _, t := range tools {
+		if t == tool {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,57 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/claude-session-manager/internal/forecast"
+)
+
+// GetForecastHandler projects daily token usage and cost over the next days
+// (default 30, at most 90), overall and for the limit (default 10) projects
+// and models that cost the most, from the history days (default 30, at most
+// 365) before today. Today is left out of the history as it isn't over.
+func (h *SQLiteHandlers) GetForecastHandler(c *gin.Context) {
+	method := c.DefaultQuery("method", forecast.MethodLinear)
+	if !forecast.ValidMethod(method) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "method must be linear or moving_average",
+		})
+		return
+	}
+	horizon, err := strconv.Atoi(c.DefaultQuery("days", "30"))
+	if err != nil || horizon < 1 || horizon > 90 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "days must be between 1 and 90",
+		})
+		return
+	}
+	historyDays, err := strconv.Atoi(c.DefaultQuery("history", "30"))
+	if err != nil || historyDays < 1 || historyDays > 365 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "history must be between 1 and 365",
+		})
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if err != nil || limit < 1 {
+		limit = 10
+	}
+
+	now := time.Now().UTC()
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	from := to.AddDate(0, 0, -historyDays)
+
+	usage, err := h.repo.GetDailyUsage(from, to)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get daily usage")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to forecast usage",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, forecast.Forecast(usage, method, from, to, horizon, limit))
+}
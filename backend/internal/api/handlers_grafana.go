@@ -0,0 +1,259 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/claude-session-manager/internal/database"
+	"github.com/sirupsen/logrus"
+)
+
+// grafanaMetrics are the series a Grafana query can ask for, each read from
+// a token timeline bucket
+var grafanaMetrics = map[string]func(database.TokenTimelineEntry) float64{
+	"tokens":                func(e database.TokenTimelineEntry) float64 { return float64(e.TotalTokens) },
+	"input_tokens":          func(e database.TokenTimelineEntry) float64 { return float64(e.InputTokens) },
+	"output_tokens":         func(e database.TokenTimelineEntry) float64 { return float64(e.OutputTokens) },
+	"cache_creation_tokens": func(e database.TokenTimelineEntry) float64 { return float64(e.CacheCreationTokens) },
+	"cache_read_tokens":     func(e database.TokenTimelineEntry) float64 { return float64(e.CacheReadTokens) },
+	"cost":                  func(e database.TokenTimelineEntry) float64 { return e.EstimatedCost },
+	"messages":              func(e database.TokenTimelineEntry) float64 { return float64(e.MessageCount) },
+}
+
+// grafanaProjectsTarget is the search target that lists projects, for
+// dashboard variables
+const grafanaProjectsTarget = "projects"
+
+// grafanaBuckets are the timeline granularities, finest first
+var grafanaBuckets = []struct {
+	granularity string
+	step        time.Duration
+}{
+	{"minute", time.Minute},
+	{"hour", time.Hour},
+	{"day", 24 * time.Hour},
+}
+
+// GrafanaSearchRequest is the body of a SimpleJSON search
+type GrafanaSearchRequest struct {
+	Target string `json:"target"`
+}
+
+// GrafanaQueryRequest is the body of a SimpleJSON query
+type GrafanaQueryRequest struct {
+	Range struct {
+		From time.Time `json:"from"`
+		To   time.Time `json:"to"`
+	} `json:"range"`
+	IntervalMs    int64           `json:"intervalMs"`
+	MaxDataPoints int             `json:"maxDataPoints"`
+	Targets       []GrafanaTarget `json:"targets"`
+}
+
+// GrafanaTarget is one series of a query: a metric, optionally for one
+// project given as metric:project or in data
+type GrafanaTarget struct {
+	Target string          `json:"target"`
+	RefID  string          `json:"refId"`
+	Type   string          `json:"type"` // timeserie (default) or table
+	Hide   bool            `json:"hide"`
+	Data   json.RawMessage `json:"data"`
+}
+
+// GrafanaTimeSeries is a series as SimpleJSON returns it: [value, unix ms]
+// pairs
+type GrafanaTimeSeries struct {
+	Target     string       `json:"target"`
+	Datapoints [][2]float64 `json:"datapoints"`
+}
+
+// GrafanaTable is a table as SimpleJSON returns it
+type GrafanaTable struct {
+	Type    string          `json:"type"`
+	Columns []GrafanaColumn `json:"columns"`
+	Rows    [][]interface{} `json:"rows"`
+}
+
+// GrafanaColumn is a column of a GrafanaTable
+type GrafanaColumn struct {
+	Text string `json:"text"`
+	Type string `json:"type"`
+}
+
+// GrafanaHandlers answers Grafana's SimpleJSON datasource, and the Infinity
+// datasource pointed at the same endpoints, from the token timeline
+type GrafanaHandlers struct {
+	repo          *database.SessionRepository
+	readOptimized *database.ReadOptimizedRepository
+	logger        *logrus.Logger
+}
+
+// NewGrafanaHandlers creates new Grafana handlers
+func NewGrafanaHandlers(repo *database.SessionRepository, logger *logrus.Logger) *GrafanaHandlers {
+	return &GrafanaHandlers{
+		repo:          repo,
+		readOptimized: database.NewReadOptimizedRepository(repo.GetDB()),
+		logger:        logger,
+	}
+}
+
+// TestConnectionHandler answers the datasource's connection test
+func (h *GrafanaHandlers) TestConnectionHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"status": "ok",
+	})
+}
+
+// SearchHandler returns the metrics a query can ask for, or the projects
+// when the target is "projects"
+func (h *GrafanaHandlers) SearchHandler(c *gin.Context) {
+	var req GrafanaSearchRequest
+	// Grafana sends an empty body from the query editor
+	_ = c.ShouldBindJSON(&req)
+
+	if strings.TrimSpace(req.Target) == grafanaProjectsTarget {
+		projects, err := h.repo.GetProjects(false)
+		if err != nil {
+			h.logger.WithError(err).Error("Failed to get projects")
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to retrieve projects",
+			})
+			return
+		}
+		names := []string{}
+		for _, project := range projects {
+			names = append(names, project.DisplayName)
+		}
+		c.JSON(http.StatusOK, names)
+		return
+	}
+
+	metrics := make([]string, 0, len(grafanaMetrics))
+	for name := range grafanaMetrics {
+		metrics = append(metrics, name)
+	}
+	sort.Strings(metrics)
+	c.JSON(http.StatusOK, metrics)
+}
+
+// QueryHandler returns each target over the query's time range, bucketed by
+// minute, hour or day: the finest that is at least the query's interval and
+// keeps within its maxDataPoints
+func (h *GrafanaHandlers) QueryHandler(c *gin.Context) {
+	var req GrafanaQueryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid query: range.from and range.to must be RFC 3339 times",
+		})
+		return
+	}
+	if req.Range.From.IsZero() || !req.Range.To.After(req.Range.From) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "range.to must be after range.from",
+		})
+		return
+	}
+	granularity, step := grafanaGranularity(req.Range.To.Sub(req.Range.From), time.Duration(req.IntervalMs)*time.Millisecond, req.MaxDataPoints)
+
+	response := []interface{}{}
+	timelines := make(map[string][]database.TokenTimelineEntry)
+	for _, target := range req.Targets {
+		if target.Hide || target.Target == "" {
+			continue
+		}
+		metric, project := parseGrafanaTarget(target)
+		value, ok := grafanaMetrics[metric]
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": fmt.Sprintf("unknown metric %q", metric),
+			})
+			return
+		}
+
+		timeline, ok := timelines[project]
+		if !ok {
+			var err error
+			timeline, err = h.readOptimized.GetTokenTimelineBetween(req.Range.From.Truncate(step), req.Range.To, granularity, project)
+			if err != nil {
+				h.logger.WithError(err).Error("Failed to get token timeline")
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"error": "Failed to retrieve token timeline",
+				})
+				return
+			}
+			timelines[project] = timeline
+		}
+
+		name := target.Target
+		if target.Type == "table" {
+			table := GrafanaTable{
+				Type:    "table",
+				Columns: []GrafanaColumn{{Text: "Time", Type: "time"}, {Text: name, Type: "number"}},
+				Rows:    [][]interface{}{},
+			}
+			for _, entry := range timeline {
+				if at, ok := grafanaTime(entry.Timestamp); ok {
+					table.Rows = append(table.Rows, []interface{}{at, value(entry)})
+				}
+			}
+			response = append(response, table)
+			continue
+		}
+
+		series := GrafanaTimeSeries{Target: name, Datapoints: [][2]float64{}}
+		for _, entry := range timeline {
+			if at, ok := grafanaTime(entry.Timestamp); ok {
+				series.Datapoints = append(series.Datapoints, [2]float64{value(entry), float64(at)})
+			}
+		}
+		response = append(response, series)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// grafanaGranularity picks the finest bucket at least interval long that
+// splits span into no more than maxDataPoints buckets
+func grafanaGranularity(span, interval time.Duration, maxDataPoints int) (string, time.Duration) {
+	for _, bucket := range grafanaBuckets {
+		if bucket.step < interval {
+			continue
+		}
+		if maxDataPoints > 0 && span/bucket.step > time.Duration(maxDataPoints) {
+			continue
+		}
+		return bucket.granularity, bucket.step
+	}
+	last := grafanaBuckets[len(grafanaBuckets)-1]
+	return last.granularity, last.step
+}
+
+// parseGrafanaTarget splits a target into its metric and project. The
+// project comes from metric:project or from data's project field, which
+// dashboard variables can fill in.
+func parseGrafanaTarget(target GrafanaTarget) (string, string) {
+	metric, project, _ := strings.Cut(strings.TrimSpace(target.Target), ":")
+	if project == "" && len(target.Data) > 0 {
+		var data struct {
+			Project string `json:"project"`
+		}
+		if err := json.Unmarshal(target.Data, &data); err == nil {
+			project = data.Project
+		}
+	}
+	return strings.TrimSpace(metric), strings.TrimSpace(project)
+}
+
+// grafanaTime converts a timeline bucket to Unix milliseconds
+func grafanaTime(timestamp string) (int64, bool) {
+	at, err := time.Parse("2006-01-02 15:04:05", timestamp)
+	if err != nil {
+		return 0, false
+	}
+	return at.UnixMilli(), true
+}
@@ -0,0 +1,91 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/claude-session-manager/internal/handoff"
+	"github.com/sirupsen/logrus"
+)
+
+// HandoffHandlers contains handlers for moving sessions between machines
+type HandoffHandlers struct {
+	handoff *handoff.Handoff
+	logger  *logrus.Logger
+}
+
+// NewHandoffHandlers creates new handoff handlers
+func NewHandoffHandlers(h *handoff.Handoff, logger *logrus.Logger) *HandoffHandlers {
+	return &HandoffHandlers{
+		handoff: h,
+		logger:  logger,
+	}
+}
+
+// ExportHandoffHandler downloads a session's handoff bundle: its transcript,
+// metadata and the Claude session its chat resumes
+func (h *HandoffHandlers) ExportHandoffHandler(c *gin.Context) {
+	sessionID := c.Param("id")
+
+	bundle, err := h.handoff.Export(sessionID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		h.logger.WithError(err).WithField("session_id", sessionID).Error("Failed to export handoff bundle")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to export handoff bundle",
+		})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fmt.Sprintf("session-%s.handoff.json", sessionID)))
+	c.JSON(http.StatusOK, bundle)
+}
+
+// ImportHandoffHandler imports a handoff bundle so the session can be
+// continued on this machine. The project_path query parameter sets where
+// the project is checked out here, and force=true replaces transcripts that
+// have diverged from this machine's copies instead of returning a conflict.
+func (h *HandoffHandlers) ImportHandoffHandler(c *gin.Context) {
+	var bundle handoff.Bundle
+	if err := c.ShouldBindJSON(&bundle); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid handoff bundle",
+		})
+		return
+	}
+
+	result, err := h.handoff.Import(&bundle, handoff.ImportOptions{
+		ProjectPath: c.Query("project_path"),
+		Force:       c.Query("force") == "true",
+	})
+	if err != nil {
+		var conflict *handoff.ConflictError
+		switch {
+		case errors.As(err, &conflict):
+			c.JSON(http.StatusConflict, gin.H{
+				"error":       err.Error(),
+				"transcripts": conflict.Transcripts,
+			})
+		case errors.Is(err, handoff.ErrInvalidBundle):
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+		default:
+			h.logger.WithError(err).WithField("session_id", bundle.Session.ID).Error("Failed to import handoff bundle")
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to import handoff bundle",
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,189 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/claude-session-manager/internal/database"
+	"github.com/ksred/claude-session-manager/internal/hooks"
+	"github.com/sirupsen/logrus"
+)
+
+// maxHookPayloadSize bounds a hook's input; PostToolUse carries the tool's
+// full response, which can be a large file read
+const maxHookPayloadSize = 10 << 20
+
+// HookHandlers contains handlers for events sent by Claude Code hooks
+type HookHandlers struct {
+	repo   *database.SessionRepository
+	wsHub  *WebSocketHub
+	logger *logrus.Logger
+}
+
+// NewHookHandlers creates new hook handlers. wsHub may be nil when
+// WebSockets are disabled.
+func NewHookHandlers(repo *database.SessionRepository, wsHub *WebSocketHub, logger *logrus.Logger) *HookHandlers {
+	return &HookHandlers{
+		repo:   repo,
+		wsHub:  wsHub,
+		logger: logger,
+	}
+}
+
+// ReceiveHookEventHandler stores the input of a Claude Code hook as received
+// and broadcasts it to WebSocket clients
+func (h *HookHandlers) ReceiveHookEventHandler(c *gin.Context) {
+	payload, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxHookPayloadSize))
+	if err != nil {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+			"error": "Hook input is too large",
+		})
+		return
+	}
+
+	event, err := hooks.Parse(payload, time.Now())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	if err := h.repo.SaveHookEvent(event); err != nil {
+		h.logger.WithError(err).Error("Failed to save hook event")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to save hook event",
+		})
+		return
+	}
+
+	if h.wsHub != nil {
+		h.wsHub.BroadcastUpdate("hook_event", event)
+	}
+	h.updateLiveness(event)
+	if event.Event == "SessionEnd" {
+		h.broadcastWrapUp(event.SessionID)
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"id": event.ID,
+	})
+}
+
+// updateLiveness marks the event's session active or inactive as the event
+// shows and broadcasts the transition if its state changed
+func (h *HookHandlers) updateLiveness(event *database.HookEvent) {
+	active, ok := hooks.Liveness(event.Event)
+	if !ok {
+		return
+	}
+
+	liveness := &database.SessionLiveness{
+		SessionID: event.SessionID,
+		IsActive:  active,
+		Event:     event.Event,
+		ChangedAt: event.ReceivedAt,
+	}
+	changed, err := h.repo.SetSessionLiveness(liveness)
+	if err != nil {
+		h.logger.WithError(err).WithField("session_id", event.SessionID).Error("Failed to update session liveness")
+		return
+	}
+	if changed && h.wsHub != nil {
+		h.wsHub.BroadcastUpdate("session_liveness", liveness)
+	}
+}
+
+// broadcastWrapUp pushes the summary of a session that just ended to
+// WebSocket clients
+func (h *HookHandlers) broadcastWrapUp(sessionID string) {
+	if h.wsHub == nil {
+		return
+	}
+	session, err := h.repo.GetSessionByID(sessionID)
+	if err != nil {
+		// The session may end before its transcript was ever imported
+		h.logger.WithError(err).WithField("session_id", sessionID).Debug("No summary for ended session")
+		return
+	}
+	h.wsHub.BroadcastUpdate("session_wrap_up", newSessionWrapUp(session))
+}
+
+// GetHookEventsHandler returns received hook events, most recent first,
+// optionally for one session (session_id) and of one kind (event)
+func (h *HookHandlers) GetHookEventsHandler(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit <= 0 {
+		limit = 100
+	}
+	if limit > 1000 {
+		limit = 1000
+	}
+
+	events, err := h.repo.GetHookEvents(c.Query("session_id"), c.Query("event"), limit)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get hook events")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve hook events",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"events": events,
+		"total":  len(events),
+		"limit":  limit,
+	})
+}
+
+// hookEndpoint returns the URL hooks should POST to: url if given, otherwise
+// this server as the request reached it
+func hookEndpoint(c *gin.Context) string {
+	if url := c.Query("url"); url != "" {
+		return url
+	}
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + c.Request.Host + "/api/v1/hooks/events"
+}
+
+// GetHookScriptHandler returns a shell script that forwards hook input to
+// this server. With summary=true the script also prints a session's wrap-up
+// to the terminal when the session ends.
+func (h *HookHandlers) GetHookScriptHandler(c *gin.Context) {
+	endpoint := hookEndpoint(c)
+	script := hooks.Script(endpoint)
+	if summary, _ := strconv.ParseBool(c.Query("summary")); summary {
+		sessionsURL := strings.TrimSuffix(endpoint, "/hooks/events") + "/sessions/"
+		script = hooks.SummaryScript(endpoint, sessionsURL)
+	}
+	c.Header("Content-Disposition", `attachment; filename="session-manager-hook.sh"`)
+	c.Data(http.StatusOK, "text/x-shellscript; charset=utf-8", []byte(script))
+}
+
+// GetHookSettingsHandler returns the hooks section to merge into a Claude
+// settings file so the hook script (command) runs on each of events
+func (h *HookHandlers) GetHookSettingsHandler(c *gin.Context) {
+	events := hooks.DefaultEvents
+	if value := c.Query("events"); value != "" {
+		events = strings.Split(value, ",")
+		for _, event := range events {
+			if !hooks.IsEvent(event) {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"error":  "Unknown hook event: " + event,
+					"events": hooks.Events,
+				})
+				return
+			}
+		}
+	}
+
+	command := c.DefaultQuery("command", "~/.claude/hooks/session-manager-hook.sh")
+	c.JSON(http.StatusOK, hooks.Settings(command, events))
+}
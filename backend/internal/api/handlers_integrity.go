@@ -0,0 +1,91 @@
+package api
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/claude-session-manager/internal/database"
+	"github.com/ksred/claude-session-manager/internal/integrity"
+	"github.com/sirupsen/logrus"
+)
+
+// maxTranscriptLineBytes bounds a single message line in an uploaded transcript
+const maxTranscriptLineBytes = 16 * 1024 * 1024
+
+// IntegrityHandlers contains handlers for verifying transcripts against their hash chains
+type IntegrityHandlers struct {
+	sealer *integrity.Sealer
+	logger *logrus.Logger
+}
+
+// NewIntegrityHandlers creates new integrity handlers
+func NewIntegrityHandlers(sealer *integrity.Sealer, logger *logrus.Logger) *IntegrityHandlers {
+	return &IntegrityHandlers{
+		sealer: sealer,
+		logger: logger,
+	}
+}
+
+// VerifySessionHandler checks a session's stored messages against its hash chain
+func (h *IntegrityHandlers) VerifySessionHandler(c *gin.Context) {
+	report, err := h.sealer.VerifySession(c.Param("id"))
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Session not found",
+			})
+			return
+		}
+		h.logger.WithError(err).Error("Failed to verify session integrity")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to verify session integrity",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// VerifyTranscriptHandler checks an exported transcript, sent as the request
+// body in the JSONL form of a compliance export's transcript.jsonl, against
+// the session's stored hash chain
+func (h *IntegrityHandlers) VerifyTranscriptHandler(c *gin.Context) {
+	var messages []database.Message
+	scanner := bufio.NewScanner(c.Request.Body)
+	scanner.Buffer(make([]byte, 64*1024), maxTranscriptLineBytes)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var message database.Message
+		if err := json.Unmarshal([]byte(text), &message); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": fmt.Sprintf("invalid message on line %d", line),
+			})
+			return
+		}
+		messages = append(messages, message)
+	}
+	if err := scanner.Err(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Failed to read transcript",
+		})
+		return
+	}
+
+	report, err := h.sealer.VerifyTranscript(c.Param("id"), messages)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to verify transcript")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to verify transcript",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,143 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/claude-session-manager/internal/database"
+	"github.com/ksred/claude-session-manager/internal/knowledge"
+	"github.com/sirupsen/logrus"
+)
+
+// KnowledgeHandlers contains handlers for searching knowledge extracted from transcripts
+type KnowledgeHandlers struct {
+	repo      *database.SessionRepository
+	extractor *knowledge.Extractor
+	ctx       context.Context
+	logger    *logrus.Logger
+}
+
+// NewKnowledgeHandlers creates new knowledge handlers. Extraction runs started
+// through the API are bound to ctx so they are cancelled on server shutdown.
+func NewKnowledgeHandlers(ctx context.Context, repo *database.SessionRepository, extractor *knowledge.Extractor, logger *logrus.Logger) *KnowledgeHandlers {
+	return &KnowledgeHandlers{
+		repo:      repo,
+		extractor: extractor,
+		ctx:       ctx,
+		logger:    logger,
+	}
+}
+
+// SearchKnowledgeHandler returns knowledge entries matching the q, kind,
+// project and min_occurrences query parameters, most recurring first
+func (h *KnowledgeHandlers) SearchKnowledgeHandler(c *gin.Context) {
+	kind := c.Query("kind")
+	if kind != "" && kind != database.KnowledgeKindQA && kind != database.KnowledgeKindDecision {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "kind must be qa or decision",
+		})
+		return
+	}
+
+	minOccurrences := 1
+	if minStr := c.Query("min_occurrences"); minStr != "" {
+		if parsed, err := strconv.Atoi(minStr); err == nil && parsed > 0 {
+			minOccurrences = parsed
+		}
+	}
+
+	limit := 50
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if parsed, err := strconv.Atoi(limitStr); err == nil && parsed > 0 && parsed <= 500 {
+			limit = parsed
+		}
+	}
+
+	entries, err := h.repo.SearchKnowledge(c.Query("q"), kind, c.Query("project"), minOccurrences, limit)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to search knowledge")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to search knowledge",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"entries": entries,
+		"total":   len(entries),
+	})
+}
+
+// GetKnowledgeEntryHandler returns a knowledge entry with links to the messages it came from
+func (h *KnowledgeHandlers) GetKnowledgeEntryHandler(c *gin.Context) {
+	entry, sources, err := h.repo.GetKnowledgeEntry(c.Param("id"))
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Knowledge entry not found",
+			})
+			return
+		}
+		h.logger.WithError(err).Error("Failed to get knowledge entry")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve knowledge entry",
+		})
+		return
+	}
+
+	links := make([]gin.H, 0, len(sources))
+	for _, source := range sources {
+		links = append(links, gin.H{
+			"message_id":   source.MessageID,
+			"session_id":   source.SessionID,
+			"project_name": source.ProjectName,
+			"timestamp":    source.Timestamp,
+			"session_url":  "/api/v1/sessions/" + source.SessionID,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"entry":   entry,
+		"sources": links,
+	})
+}
+
+// ExtractKnowledgeHandler starts an extraction run in the background. With
+// full=true every transcript is rescanned rather than only new messages.
+func (h *KnowledgeHandlers) ExtractKnowledgeHandler(c *gin.Context) {
+	full := c.Query("full") == "true"
+
+	go func() {
+		if _, err := h.extractor.Run(h.ctx, full); err != nil && h.ctx.Err() == nil {
+			h.logger.WithError(err).Error("Failed to extract knowledge from transcripts")
+		}
+	}()
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message":   "Knowledge extraction started",
+		"full_scan": full,
+	})
+}
+
+// GetLastExtractionRunHandler returns the most recent extraction run
+func (h *KnowledgeHandlers) GetLastExtractionRunHandler(c *gin.Context) {
+	run, err := h.repo.GetLastKnowledgeExtractionRun()
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get knowledge extraction run")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve knowledge extraction run",
+		})
+		return
+	}
+	if run == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Knowledge extraction has not run yet",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, run)
+}
@@ -0,0 +1,110 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetLegalHoldsHandler returns active legal holds, or all holds with include_released=true
+func (h *SQLiteHandlers) GetLegalHoldsHandler(c *gin.Context) {
+	holds, err := h.repo.GetLegalHolds(c.Query("include_released") == "true")
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get legal holds")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve legal holds",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"holds": holds,
+		"total": len(holds),
+	})
+}
+
+// GetLegalHoldHandler returns a session's active legal hold
+func (h *SQLiteHandlers) GetLegalHoldHandler(c *gin.Context) {
+	hold, err := h.repo.GetLegalHold(c.Param("id"))
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Session is not under legal hold",
+			})
+			return
+		}
+		h.logger.WithError(err).Error("Failed to get legal hold")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve legal hold",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, hold)
+}
+
+// PlaceLegalHoldHandler puts a session under legal hold so it cannot be pruned or archived
+func (h *SQLiteHandlers) PlaceLegalHoldHandler(c *gin.Context) {
+	var req struct {
+		Reason   string `json:"reason" binding:"required"`
+		PlacedBy string `json:"placed_by" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "reason and placed_by are required",
+		})
+		return
+	}
+
+	hold, err := h.repo.PlaceLegalHold(c.Param("id"), req.Reason, req.PlacedBy)
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), "not found"):
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Session not found",
+			})
+		case strings.Contains(err.Error(), "already under legal hold"):
+			c.JSON(http.StatusConflict, gin.H{
+				"error": err.Error(),
+			})
+		default:
+			h.logger.WithError(err).Error("Failed to place legal hold")
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to place legal hold",
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, hold)
+}
+
+// ReleaseLegalHoldHandler releases a session's active legal hold. The
+// released_by query parameter records who released it.
+func (h *SQLiteHandlers) ReleaseLegalHoldHandler(c *gin.Context) {
+	releasedBy := c.Query("released_by")
+	if releasedBy == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "released_by is required",
+		})
+		return
+	}
+
+	hold, err := h.repo.ReleaseLegalHold(c.Param("id"), releasedBy)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Session is not under legal hold",
+			})
+			return
+		}
+		h.logger.WithError(err).Error("Failed to release legal hold")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to release legal hold",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, hold)
+}
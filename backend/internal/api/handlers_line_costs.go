@@ -0,0 +1,105 @@
+package api
+
+import (
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/claude-session-manager/internal/database"
+)
+
+// lineCostCaveats explain what cost per line of code does and doesn't
+// measure; they are returned with every report
+var lineCostCaveats = []string{
+	"Lines are counted from Edit, Write and MultiEdit tool calls, not from commits, so code that was later reverted, rewritten or never committed still counts.",
+	"A Write counts every line of the file it wrote, including files that already existed.",
+	"Changes made through Bash commands, notebooks or by hand aren't counted, while their cost is.",
+	"Cost includes sessions that changed no files, such as questions, reviews and debugging.",
+	"Lines differ in value and difficulty; compare the metric across months of one project rather than across projects.",
+}
+
+// LineCostTotal is the spend and lines changed of one project, or of all
+// projects, over a whole report
+type LineCostTotal struct {
+	ProjectName  string   `json:"project_name,omitempty"`
+	CostUSD      float64  `json:"cost_usd"`
+	Edits        int      `json:"edits"`
+	LinesAdded   int      `json:"lines_added"`
+	LinesRemoved int      `json:"lines_removed"`
+	CostPerKLOC  *float64 `json:"cost_per_kloc"`
+}
+
+// add adds a month of a project to the total
+func (t *LineCostTotal) add(cost database.LineCost) {
+	t.CostUSD += cost.CostUSD
+	t.Edits += cost.Edits
+	t.LinesAdded += cost.LinesAdded
+	t.LinesRemoved += cost.LinesRemoved
+	if t.LinesAdded > 0 {
+		perKLOC := t.CostUSD / float64(t.LinesAdded) * 1000
+		t.CostPerKLOC = &perKLOC
+	}
+}
+
+// GetLineCostsHandler returns the cost per 1,000 lines of code added (the
+// $/kLOC metric) per project and month, with per-project and overall
+// totals. The period is chosen as in GetCostsByCostCenterHandler but
+// defaults to the last six months, including the current one.
+func (h *SQLiteHandlers) GetLineCostsHandler(c *gin.Context) {
+	var from, to time.Time
+	if c.Query("month") == "" && c.Query("from") == "" && c.Query("to") == "" {
+		now := time.Now().UTC()
+		to = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, 1, 0)
+		from = to.AddDate(0, -6, 0)
+	} else {
+		var err error
+		from, to, err = chargebackPeriod(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+	}
+
+	costs, err := h.repo.GetLineCosts(from, to)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get line costs")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve line costs",
+		})
+		return
+	}
+
+	var total LineCostTotal
+	projects := []LineCostTotal{}
+	index := make(map[string]int)
+	for _, cost := range costs {
+		i, ok := index[cost.ProjectName]
+		if !ok {
+			i = len(projects)
+			index[cost.ProjectName] = i
+			projects = append(projects, LineCostTotal{ProjectName: cost.ProjectName})
+		}
+		projects[i].add(cost)
+		total.add(cost)
+	}
+	sort.Slice(projects, func(i, j int) bool {
+		return projects[i].CostUSD > projects[j].CostUSD
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"from":     from,
+		"to":       to,
+		"months":   costs,
+		"projects": projects,
+		"total":    total,
+		"metadata": gin.H{
+			"metric":   "cost_per_kloc",
+			"unit":     "USD per 1,000 lines added",
+			"currency": "USD",
+			"caveats":  lineCostCaveats,
+		},
+	})
+}
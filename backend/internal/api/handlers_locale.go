@@ -0,0 +1,19 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/claude-session-manager/internal/locale"
+)
+
+// GetLocaleHandler returns the currency, locale and units the request's
+// figures are described in, chosen from its Accept-Language header and the
+// display config, and the locales that can be asked for. Clients that format
+// numbers themselves can fetch it once rather than read each response's meta.
+func (h *SQLiteHandlers) GetLocaleHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"meta":      requestFormatter(c).Metadata(),
+		"supported": locale.Supported(),
+	})
+}
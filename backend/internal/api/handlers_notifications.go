@@ -0,0 +1,266 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/claude-session-manager/internal/auth"
+	"github.com/ksred/claude-session-manager/internal/database"
+	"github.com/ksred/claude-session-manager/internal/notifications"
+	"github.com/sirupsen/logrus"
+)
+
+// NotificationHandlers contains handlers for Slack and Discord notifications
+// and each user's notification preferences
+type NotificationHandlers struct {
+	repo     *database.SessionRepository
+	notifier *notifications.Notifier
+	logger   *logrus.Logger
+}
+
+// NewNotificationHandlers creates new notification handlers
+func NewNotificationHandlers(repo *database.SessionRepository, notifier *notifications.Notifier, logger *logrus.Logger) *NotificationHandlers {
+	return &NotificationHandlers{
+		repo:     repo,
+		notifier: notifier,
+		logger:   logger,
+	}
+}
+
+// TestNotificationHandler sends a test notification to the configured Slack
+// and Discord webhooks and returns whether each accepted it
+func (h *NotificationHandlers) TestNotificationHandler(c *gin.Context) {
+	if !h.notifier.Enabled() {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "No Slack or Discord webhook URL is configured",
+		})
+		return
+	}
+
+	if err := h.notifier.Test(c.Request.Context()); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"delivered": false,
+			"error":     err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"delivered": true,
+	})
+}
+
+// notificationPreferencesRequest is the body of a request setting a user's
+// notification preferences
+type notificationPreferencesRequest struct {
+	Channels           []database.NotificationChannel `json:"channels"`
+	Events             []string                       `json:"events"`
+	DailyCostThreshold float64                        `json:"daily_cost_threshold"`
+	IdleMinutes        int                            `json:"idle_minutes"`
+	QuietHoursStart    string                         `json:"quiet_hours_start"`
+	QuietHoursEnd      string                         `json:"quiet_hours_end"`
+	Timezone           string                         `json:"timezone"`
+}
+
+// GetNotificationPreferencesHandler returns every user's notification
+// preferences, or only the API key's user's when auth is enabled, as
+// channels can carry webhook URLs
+func (h *NotificationHandlers) GetNotificationPreferencesHandler(c *gin.Context) {
+	all, err := h.repo.GetNotificationPreferences()
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get notification preferences")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve notification preferences",
+		})
+		return
+	}
+
+	prefs := all
+	if value, ok := c.Get(auth.ContextKey); ok {
+		if key, ok := value.(*database.APIKey); ok {
+			prefs = []database.NotificationPreferences{}
+			for _, p := range all {
+				if p.UserName == key.UserName {
+					prefs = append(prefs, p)
+				}
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"preferences": prefs,
+		"count":       len(prefs),
+	})
+}
+
+// GetUserNotificationPreferencesHandler returns a user's notification
+// preferences, or 404 if they haven't set any
+func (h *NotificationHandlers) GetUserNotificationPreferencesHandler(c *gin.Context) {
+	userName, ok := h.user(c)
+	if !ok {
+		return
+	}
+
+	prefs, err := h.repo.GetUserNotificationPreferences(userName)
+	if err != nil {
+		h.notFoundOrError(c, err, "Failed to retrieve notification preferences")
+		return
+	}
+	if prefs == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "No notification preferences are set",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, prefs)
+}
+
+// SetUserNotificationPreferencesHandler replaces a user's notification
+// preferences once each channel, event, threshold and the quiet hours check
+// out
+func (h *NotificationHandlers) SetUserNotificationPreferencesHandler(c *gin.Context) {
+	userName, ok := h.user(c)
+	if !ok {
+		return
+	}
+
+	var req notificationPreferencesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid notification preferences",
+		})
+		return
+	}
+	prefs := &database.NotificationPreferences{
+		Channels:           req.Channels,
+		Events:             req.Events,
+		DailyCostThreshold: req.DailyCostThreshold,
+		IdleMinutes:        req.IdleMinutes,
+		QuietHoursStart:    req.QuietHoursStart,
+		QuietHoursEnd:      req.QuietHoursEnd,
+		Timezone:           req.Timezone,
+	}
+	if err := h.notifier.ValidatePreferences(prefs); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	if err := h.repo.SetNotificationPreferences(userName, prefs); err != nil {
+		h.notFoundOrError(c, err, "Failed to set notification preferences")
+		return
+	}
+	h.reload()
+
+	c.JSON(http.StatusOK, prefs)
+}
+
+// DeleteUserNotificationPreferencesHandler removes a user's notification
+// preferences, so they are no longer notified
+func (h *NotificationHandlers) DeleteUserNotificationPreferencesHandler(c *gin.Context) {
+	userName, ok := h.user(c)
+	if !ok {
+		return
+	}
+
+	if err := h.repo.DeleteNotificationPreferences(userName); err != nil {
+		h.notFoundOrError(c, err, "Failed to delete notification preferences")
+		return
+	}
+	h.reload()
+
+	c.JSON(http.StatusOK, gin.H{
+		"deleted": true,
+	})
+}
+
+// TestUserNotificationPreferencesHandler sends a test notification to each
+// of a user's channels and returns whether all accepted it
+func (h *NotificationHandlers) TestUserNotificationPreferencesHandler(c *gin.Context) {
+	userName, ok := h.user(c)
+	if !ok {
+		return
+	}
+
+	prefs, err := h.repo.GetUserNotificationPreferences(userName)
+	if err != nil {
+		h.notFoundOrError(c, err, "Failed to retrieve notification preferences")
+		return
+	}
+	if prefs == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "No notification preferences are set",
+		})
+		return
+	}
+
+	if err := h.notifier.TestPreferences(c.Request.Context(), prefs); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"delivered": false,
+			"error":     err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"delivered": true,
+	})
+}
+
+// user returns the name of the user a request is for, with "me" standing
+// for the owner of the request's API key. When auth is enabled a key may only
+// reach its own user's preferences.
+func (h *NotificationHandlers) user(c *gin.Context) (string, bool) {
+	userName := c.Param("user")
+
+	var key *database.APIKey
+	if value, ok := c.Get(auth.ContextKey); ok {
+		key, _ = value.(*database.APIKey)
+	}
+	if userName == "me" {
+		if key == nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "me needs API key authentication; name the user instead",
+			})
+			return "", false
+		}
+		return key.UserName, true
+	}
+	if key != nil && key.UserName != userName {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "API key may only reach its own user's notification preferences",
+		})
+		return "", false
+	}
+	return userName, true
+}
+
+// reload has the notifier pick up changed preferences straight away rather
+// than at its next check
+func (h *NotificationHandlers) reload() {
+	if err := h.notifier.LoadPreferences(); err != nil {
+		h.logger.WithError(err).Warn("Failed to reload notification preferences")
+	}
+}
+
+func (h *NotificationHandlers) notFoundOrError(c *gin.Context, err error, message string) {
+	if strings.Contains(err.Error(), "user not found") {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "User not found",
+		})
+		return
+	}
+	if strings.Contains(err.Error(), "not found") {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "No notification preferences are set",
+		})
+		return
+	}
+	h.logger.WithError(err).Error(message)
+	c.JSON(http.StatusInternalServerError, gin.H{
+		"error": message,
+	})
+}
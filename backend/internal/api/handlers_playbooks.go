@@ -0,0 +1,444 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/claude-session-manager/internal/database"
+	"github.com/ksred/claude-session-manager/internal/playbook"
+	"github.com/sirupsen/logrus"
+)
+
+// PlaybookHandlers contains handlers for listing and running playbooks
+type PlaybookHandlers struct {
+	repo      *database.SessionRepository
+	runner    *playbook.Runner
+	scheduler *playbook.Scheduler
+	directory string
+	ctx       context.Context
+	logger    *logrus.Logger
+}
+
+// NewPlaybookHandlers creates new playbook handlers. Runs started through the
+// API are bound to ctx so they are cancelled on server shutdown.
+func NewPlaybookHandlers(ctx context.Context, repo *database.SessionRepository, runner *playbook.Runner, scheduler *playbook.Scheduler, directory string, logger *logrus.Logger) *PlaybookHandlers {
+	return &PlaybookHandlers{
+		repo:      repo,
+		runner:    runner,
+		scheduler: scheduler,
+		directory: directory,
+		ctx:       ctx,
+		logger:    logger,
+	}
+}
+
+// GetPlaybooksHandler returns all playbooks in the configured directory
+func (h *PlaybookHandlers) GetPlaybooksHandler(c *gin.Context) {
+	playbooks, err := playbook.LoadDir(h.directory)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to load playbooks")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"playbooks": playbooks,
+		"directory": h.directory,
+		"total":     len(playbooks),
+	})
+}
+
+// RunPlaybookHandler starts a run of the named playbook in the background
+func (h *PlaybookHandlers) RunPlaybookHandler(c *gin.Context) {
+	pb, err := playbook.Find(h.directory, c.Param("name"))
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Playbook not found",
+			})
+			return
+		}
+		h.logger.WithError(err).Error("Failed to load playbook")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	// Allow the project to be overridden so one playbook can target several projects
+	var req struct {
+		ProjectPath string `json:"project_path"`
+	}
+	_ = c.ShouldBindJSON(&req)
+	if req.ProjectPath != "" {
+		pb.ProjectPath = req.ProjectPath
+		pb.ProjectName = ""
+		if err := pb.Validate(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+	}
+
+	run, err := h.runner.Start(h.ctx, pb, "manual")
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to start playbook run")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to start playbook run",
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, run)
+}
+
+// GetRunsHandler returns recent playbook runs
+func (h *PlaybookHandlers) GetRunsHandler(c *gin.Context) {
+	limit := 50
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if parsed, err := strconv.Atoi(limitStr); err == nil && parsed > 0 && parsed <= 500 {
+			limit = parsed
+		}
+	}
+
+	runs, err := h.repo.GetPlaybookRuns(c.Query("playbook"), limit)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get playbook runs")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve playbook runs",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"runs":  runs,
+		"total": len(runs),
+		"limit": limit,
+	})
+}
+
+// GetRunHandler returns a playbook run with its steps and linked sessions
+func (h *PlaybookHandlers) GetRunHandler(c *gin.Context) {
+	runID := c.Param("id")
+	run, err := h.repo.GetPlaybookRun(runID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Playbook run not found",
+			})
+			return
+		}
+		h.logger.WithError(err).Error("Failed to get playbook run")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve playbook run",
+		})
+		return
+	}
+
+	steps, err := h.repo.GetPlaybookRunSteps(runID)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get playbook run steps")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve playbook run steps",
+		})
+		return
+	}
+
+	sessionIDs := []string{}
+	if run.GroupID != nil {
+		sessionIDs, err = h.repo.GetSessionGroupSessionIDs(*run.GroupID)
+		if err != nil {
+			h.logger.WithError(err).Error("Failed to get playbook run sessions")
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"run":         run,
+		"steps":       steps,
+		"session_ids": sessionIDs,
+	})
+}
+
+// CancelRunHandler cancels a playbook run that is in progress
+func (h *PlaybookHandlers) CancelRunHandler(c *gin.Context) {
+	if !h.runner.Cancel(c.Param("id")) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "No running playbook run with this ID",
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"id":     c.Param("id"),
+		"status": "cancelling",
+	})
+}
+
+// playbookScheduleRequest is the request body for creating or updating a schedule
+type playbookScheduleRequest struct {
+	PlaybookName string  `json:"playbook_name" binding:"required"`
+	Cron         string  `json:"cron" binding:"required"`
+	ProjectPath  *string `json:"project_path"`
+	WebhookURL   *string `json:"webhook_url"`
+	Enabled      *bool   `json:"enabled"`
+}
+
+// apply validates the request and copies it onto a schedule
+func (req *playbookScheduleRequest) apply(schedule *database.PlaybookSchedule) error {
+	next, err := playbook.NextRun(req.Cron, time.Now())
+	if err != nil {
+		return err
+	}
+
+	schedule.PlaybookName = req.PlaybookName
+	schedule.CronExpr = req.Cron
+	schedule.ProjectPath = req.ProjectPath
+	schedule.WebhookURL = req.WebhookURL
+	schedule.Enabled = req.Enabled == nil || *req.Enabled
+	schedule.NextRunAt = nil
+	if schedule.Enabled {
+		schedule.NextRunAt = next
+	}
+	return nil
+}
+
+// GetSchedulesHandler returns all playbook schedules
+func (h *PlaybookHandlers) GetSchedulesHandler(c *gin.Context) {
+	schedules, err := h.repo.GetPlaybookSchedules()
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get playbook schedules")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve playbook schedules",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"schedules": schedules,
+		"total":     len(schedules),
+	})
+}
+
+// CreateScheduleHandler schedules a playbook to run on a cron expression
+func (h *PlaybookHandlers) CreateScheduleHandler(c *gin.Context) {
+	var req playbookScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request body",
+		})
+		return
+	}
+
+	if _, err := playbook.Find(h.directory, req.PlaybookName); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	schedule := &database.PlaybookSchedule{}
+	if err := req.apply(schedule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	if err := h.repo.CreatePlaybookSchedule(schedule); err != nil {
+		h.logger.WithError(err).Error("Failed to create playbook schedule")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to create playbook schedule",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, schedule)
+}
+
+// UpdateScheduleHandler replaces a playbook schedule
+func (h *PlaybookHandlers) UpdateScheduleHandler(c *gin.Context) {
+	var req playbookScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request body",
+		})
+		return
+	}
+
+	schedule, err := h.repo.GetPlaybookSchedule(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Playbook schedule not found",
+		})
+		return
+	}
+
+	if err := req.apply(schedule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	if err := h.repo.UpdatePlaybookSchedule(schedule); err != nil {
+		h.logger.WithError(err).Error("Failed to update playbook schedule")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to update playbook schedule",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, schedule)
+}
+
+// DeleteScheduleHandler removes a playbook schedule
+func (h *PlaybookHandlers) DeleteScheduleHandler(c *gin.Context) {
+	if err := h.repo.DeletePlaybookSchedule(c.Param("id")); err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Playbook schedule not found",
+			})
+			return
+		}
+		h.logger.WithError(err).Error("Failed to delete playbook schedule")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to delete playbook schedule",
+		})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// TriggerScheduleHandler runs a scheduled playbook immediately
+func (h *PlaybookHandlers) TriggerScheduleHandler(c *gin.Context) {
+	schedule, err := h.repo.GetPlaybookSchedule(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Playbook schedule not found",
+		})
+		return
+	}
+
+	run, err := h.scheduler.Trigger(h.ctx, schedule, "manual")
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to trigger playbook schedule")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, run)
+}
+
+// GetApprovalsHandler returns playbook runs waiting for a human to review a step's proposal
+func (h *PlaybookHandlers) GetApprovalsHandler(c *gin.Context) {
+	runs, err := h.repo.GetPlaybookRunsByStatus(database.RunStatusAwaitingApproval)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get runs awaiting approval")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve approvals",
+		})
+		return
+	}
+
+	approvals := make([]gin.H, 0, len(runs))
+	for i := range runs {
+		_, step, err := h.runner.PendingApproval(runs[i].ID)
+		if err != nil {
+			h.logger.WithError(err).WithField("run_id", runs[i].ID).Warn("Failed to get pending step")
+			continue
+		}
+		approvals = append(approvals, gin.H{
+			"run":  runs[i],
+			"step": step,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"approvals": approvals,
+		"total":     len(approvals),
+	})
+}
+
+// ApproveRunHandler applies the pending proposal of a run and continues its playbook
+func (h *PlaybookHandlers) ApproveRunHandler(c *gin.Context) {
+	var req struct {
+		ApprovedBy string `json:"approved_by"`
+	}
+	_ = c.ShouldBindJSON(&req)
+	if req.ApprovedBy == "" {
+		req.ApprovedBy = "api"
+	}
+
+	run, _, err := h.runner.PendingApproval(c.Param("id"))
+	if err != nil {
+		h.respondApprovalError(c, err)
+		return
+	}
+
+	pb, err := playbook.Find(h.directory, run.PlaybookName)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to load playbook for approval")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	run, err = h.runner.Approve(h.ctx, pb, run.ID, req.ApprovedBy, h.scheduler.CompletionHandler)
+	if err != nil {
+		h.respondApprovalError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, run)
+}
+
+// RejectRunHandler discards the pending proposal of a run and ends it
+func (h *PlaybookHandlers) RejectRunHandler(c *gin.Context) {
+	var req struct {
+		RejectedBy string `json:"rejected_by"`
+		Reason     string `json:"reason"`
+	}
+	_ = c.ShouldBindJSON(&req)
+	if req.RejectedBy == "" {
+		req.RejectedBy = "api"
+	}
+
+	run, err := h.runner.Reject(c.Param("id"), req.RejectedBy, req.Reason)
+	if err != nil {
+		h.respondApprovalError(c, err)
+		return
+	}
+
+	h.scheduler.CompletionHandler(run)
+	c.JSON(http.StatusOK, run)
+}
+
+// respondApprovalError maps errors from approving or rejecting a run to a response
+func (h *PlaybookHandlers) respondApprovalError(c *gin.Context, err error) {
+	switch {
+	case strings.Contains(err.Error(), "not found"):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Playbook run not found",
+		})
+	case strings.Contains(err.Error(), "awaiting approval"):
+		c.JSON(http.StatusConflict, gin.H{
+			"error": err.Error(),
+		})
+	default:
+		h.logger.WithError(err).Error("Failed to review playbook run")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/claude-session-manager/internal/database"
+	"github.com/ksred/claude-session-manager/internal/export"
+	"github.com/ksred/claude-session-manager/internal/plugin"
+	"github.com/ksred/claude-session-manager/internal/rpc"
+	"github.com/sirupsen/logrus"
+)
+
+// PluginHandlers contains handlers for the analytics and exporters that
+// plugins register
+type PluginHandlers struct {
+	repo    *database.SessionRepository
+	manager *plugin.Manager
+	logger  *logrus.Logger
+}
+
+// NewPluginHandlers creates new plugin handlers
+func NewPluginHandlers(repo *database.SessionRepository, manager *plugin.Manager, logger *logrus.Logger) *PluginHandlers {
+	return &PluginHandlers{
+		repo:    repo,
+		manager: manager,
+		logger:  logger,
+	}
+}
+
+// GetPluginsHandler returns the configured plugins, whether each is running
+// and the analytics, exporters and notifiers it registered
+func (h *PluginHandlers) GetPluginsHandler(c *gin.Context) {
+	plugins := h.manager.List()
+	c.JSON(http.StatusOK, gin.H{
+		"plugins": plugins,
+		"total":   len(plugins),
+	})
+}
+
+// RunAnalyticsHandler runs a plugin's analytic, passing it the query
+// parameters, and returns the plugin's result
+func (h *PluginHandlers) RunAnalyticsHandler(c *gin.Context) {
+	params := make(map[string]string)
+	for key, values := range c.Request.URL.Query() {
+		params[key] = values[0]
+	}
+
+	result, err := h.manager.RunAnalytics(c.Request.Context(), c.Param("name"), c.Param("analytic"), params)
+	if err != nil {
+		h.pluginError(c, err, "Failed to run plugin analytic")
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// ExportSessionHandler returns a session's transcript rendered by a plugin's
+// exporter as a download
+func (h *PluginHandlers) ExportSessionHandler(c *gin.Context) {
+	sessionID := c.Param("id")
+	pluginName, exporterName := c.Param("plugin"), c.Param("exporter")
+
+	exporter, err := h.manager.Exporter(pluginName, exporterName)
+	if err != nil {
+		h.pluginError(c, err, "Failed to find plugin exporter")
+		return
+	}
+
+	records, err := h.repo.GetTranscriptRecords(sessionID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Session not found",
+			})
+			return
+		}
+		h.logger.WithError(err).Error("Failed to get transcript records")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve session records",
+		})
+		return
+	}
+
+	content, err := h.manager.Export(c.Request.Context(), pluginName, exporterName, export.BuildTranscript(records))
+	if err != nil {
+		h.pluginError(c, err, "Failed to run plugin exporter")
+		return
+	}
+
+	filename := fmt.Sprintf("session-%s.%s", sessionID, exporter.Extension)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Data(http.StatusOK, exporter.ContentType, []byte(content))
+}
+
+// pluginError responds 404 for unknown plugins, analytics and exporters, and
+// 502 when the plugin fails, passing on the plugin's own error message
+func (h *PluginHandlers) pluginError(c *gin.Context, err error, message string) {
+	if strings.Contains(err.Error(), "not found") {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	h.logger.WithError(err).Error(message)
+	if rpcErr, ok := err.(*rpc.Error); ok {
+		c.JSON(http.StatusBadGateway, gin.H{
+			"error": rpcErr.Message,
+		})
+		return
+	}
+	c.JSON(http.StatusBadGateway, gin.H{
+		"error": message,
+	})
+}
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/claude-session-manager/internal/database"
+	"github.com/ksred/claude-session-manager/internal/pricing"
+)
+
+// GetPricingHandler returns the model prices costs are calculated from, per
+// million tokens, with where each price came from
+func (h *SQLiteHandlers) GetPricingHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, pricing.Default().List())
+}
+
+// UpdatePricingHandler replaces the prices set in the config file or by a
+// previous update until the server restarts. Models not listed keep their
+// built-in or remote price. With recalculate=true, stored token costs are
+// repriced so past sessions reflect the new prices.
+func (h *SQLiteHandlers) UpdatePricingHandler(c *gin.Context) {
+	recalculate, _ := strconv.ParseBool(c.Query("recalculate"))
+
+	var list pricing.PriceList
+	if err := c.ShouldBindJSON(&list); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request body",
+		})
+		return
+	}
+	if err := pricing.Default().SetOverrides(list); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	repriced := 0
+	if recalculate {
+		var err error
+		repriced, err = h.repo.RecalculateTokenCosts()
+		if err != nil {
+			h.logger.WithError(err).Error("Failed to recalculate token costs")
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Prices updated but token costs could not be recalculated",
+			})
+			return
+		}
+	}
+
+	if err := h.repo.LogActivity(&database.ActivityLogEntry{
+		ActivityType: "pricing_updated",
+		Details:      fmt.Sprintf("Pricing updated for %d models, %d token costs recalculated", len(list.Models), repriced),
+		Timestamp:    time.Now().UTC(),
+	}); err != nil {
+		h.logger.WithError(err).Warn("Failed to log pricing update")
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"pricing":  pricing.Default().List(),
+		"repriced": repriced,
+	})
+}
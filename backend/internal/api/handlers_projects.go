@@ -0,0 +1,201 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/claude-session-manager/internal/database"
+	"github.com/sirupsen/logrus"
+)
+
+// ProjectHandlers contains handlers for projects and their aliases
+type ProjectHandlers struct {
+	repo   *database.SessionRepository
+	logger *logrus.Logger
+}
+
+// NewProjectHandlers creates new project handlers
+func NewProjectHandlers(repo *database.SessionRepository, logger *logrus.Logger) *ProjectHandlers {
+	return &ProjectHandlers{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+// CreateProjectRequest defines a project. Sessions imported under any of its
+// aliases, project names, belong to it.
+type CreateProjectRequest struct {
+	DisplayName   string   `json:"display_name" binding:"required"`
+	CanonicalPath string   `json:"canonical_path"`
+	Aliases       []string `json:"aliases"`
+}
+
+// UpdateProjectRequest renames a project or changes its canonical path;
+// fields left out are kept
+type UpdateProjectRequest struct {
+	DisplayName   *string `json:"display_name"`
+	CanonicalPath *string `json:"canonical_path"`
+}
+
+// MergeProjectsRequest names the projects to merge into another
+type MergeProjectsRequest struct {
+	ProjectIDs []int64 `json:"project_ids" binding:"required"`
+}
+
+// GetProjectsHandler returns every project with its aliases and totals.
+// Archived sessions are left out of the totals unless include_archived=true.
+func (h *ProjectHandlers) GetProjectsHandler(c *gin.Context) {
+	projects, err := h.repo.GetProjects(c.Query("include_archived") == "true")
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get projects")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve projects",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"projects": projects,
+		"total":    len(projects),
+	})
+}
+
+// GetProjectHandler returns a project by ID
+func (h *ProjectHandlers) GetProjectHandler(c *gin.Context) {
+	id, ok := projectID(c)
+	if !ok {
+		return
+	}
+	project, err := h.repo.GetProject(id, c.Query("include_archived") == "true")
+	if err != nil {
+		h.projectError(c, err, "Failed to retrieve project")
+		return
+	}
+
+	c.JSON(http.StatusOK, project)
+}
+
+// CreateProjectHandler adds a project claiming the given aliases, so
+// sessions imported under them later are reported together
+func (h *ProjectHandlers) CreateProjectHandler(c *gin.Context) {
+	var req CreateProjectRequest
+	if err := c.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.DisplayName) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "display_name is required",
+		})
+		return
+	}
+
+	project, err := h.repo.CreateProject(strings.TrimSpace(req.DisplayName), strings.TrimSpace(req.CanonicalPath), req.Aliases)
+	if err != nil {
+		h.projectError(c, err, "Failed to create project")
+		return
+	}
+
+	c.JSON(http.StatusCreated, project)
+}
+
+// UpdateProjectHandler renames a project or changes its canonical path
+func (h *ProjectHandlers) UpdateProjectHandler(c *gin.Context) {
+	id, ok := projectID(c)
+	if !ok {
+		return
+	}
+	var req UpdateProjectRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request body",
+		})
+		return
+	}
+	if req.DisplayName != nil {
+		name := strings.TrimSpace(*req.DisplayName)
+		if name == "" {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "display_name must not be empty",
+			})
+			return
+		}
+		req.DisplayName = &name
+	}
+
+	project, err := h.repo.UpdateProject(id, req.DisplayName, req.CanonicalPath)
+	if err != nil {
+		h.projectError(c, err, "Failed to update project")
+		return
+	}
+
+	c.JSON(http.StatusOK, project)
+}
+
+// MergeProjectsHandler moves the aliases of the projects in the body to this
+// one and deletes them, so their sessions are reported under it
+func (h *ProjectHandlers) MergeProjectsHandler(c *gin.Context) {
+	id, ok := projectID(c)
+	if !ok {
+		return
+	}
+	var req MergeProjectsRequest
+	if err := c.ShouldBindJSON(&req); err != nil || len(req.ProjectIDs) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "project_ids is required",
+		})
+		return
+	}
+
+	project, err := h.repo.MergeProjects(id, req.ProjectIDs)
+	if err != nil {
+		h.projectError(c, err, "Failed to merge projects")
+		return
+	}
+
+	c.JSON(http.StatusOK, project)
+}
+
+// DeleteProjectHandler removes a project no session was imported under
+func (h *ProjectHandlers) DeleteProjectHandler(c *gin.Context) {
+	id, ok := projectID(c)
+	if !ok {
+		return
+	}
+	if err := h.repo.DeleteProject(id); err != nil {
+		h.projectError(c, err, "Failed to delete project")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// projectID reads the project ID from the path. The wildcard shares its name
+// with the project name routes beside it, as gin requires.
+func projectID(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("projectName"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Project ID must be a number",
+		})
+		return 0, false
+	}
+	return id, true
+}
+
+func (h *ProjectHandlers) projectError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, database.ErrProjectAliasTaken), errors.Is(err, database.ErrProjectHasSessions):
+		c.JSON(http.StatusConflict, gin.H{
+			"error": err.Error(),
+		})
+	case strings.Contains(err.Error(), "not found"):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Project not found",
+		})
+	default:
+		h.logger.WithError(err).Error(message)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": message,
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/claude-session-manager/internal/similarity"
+	"github.com/sirupsen/logrus"
+)
+
+// PromptHandlers contains handlers for finding past sessions with similar opening prompts
+type PromptHandlers struct {
+	detector *similarity.Detector
+	logger   *logrus.Logger
+}
+
+// NewPromptHandlers creates new prompt handlers
+func NewPromptHandlers(detector *similarity.Detector, logger *logrus.Logger) *PromptHandlers {
+	return &PromptHandlers{
+		detector: detector,
+		logger:   logger,
+	}
+}
+
+// similarityParams reads the threshold (0-1) and limit query parameters
+func similarityParams(c *gin.Context) (float64, int) {
+	threshold := similarity.DefaultThreshold
+	if thresholdStr := c.Query("threshold"); thresholdStr != "" {
+		if parsed, err := strconv.ParseFloat(thresholdStr, 64); err == nil && parsed > 0 && parsed <= 1 {
+			threshold = parsed
+		}
+	}
+
+	limit := 5
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if parsed, err := strconv.Atoi(limitStr); err == nil && parsed > 0 && parsed <= 50 {
+			limit = parsed
+		}
+	}
+
+	return threshold, limit
+}
+
+// GetSimilarSessionsHandler returns earlier sessions whose opening prompt resembles this session's
+func (h *PromptHandlers) GetSimilarSessionsHandler(c *gin.Context) {
+	threshold, limit := similarityParams(c)
+
+	signature, matches, err := h.detector.FindSimilar(c.Param("id"), threshold, limit)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Session or opening prompt not found",
+			})
+			return
+		}
+		h.logger.WithError(err).Error("Failed to find similar sessions")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to find similar sessions",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"session_id": signature.SessionID,
+		"prompt":     signature.Prompt,
+		"threshold":  threshold,
+		"similar":    matches,
+	})
+}
+
+// CheckPromptHandler returns past sessions whose opening prompt resembles a
+// prompt that has not been sent yet
+func (h *PromptHandlers) CheckPromptHandler(c *gin.Context) {
+	var req struct {
+		Prompt string `json:"prompt" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "prompt is required",
+		})
+		return
+	}
+
+	threshold, limit := similarityParams(c)
+	matches, err := h.detector.FindSimilarToPrompt(req.Prompt, threshold, limit)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to find similar prompts")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to find similar prompts",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"threshold": threshold,
+		"similar":   matches,
+	})
+}
@@ -0,0 +1,94 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/claude-session-manager/internal/database"
+)
+
+// parsePruneFilter parses the sessions a retention run selects: those
+// inactive since before (YYYY-MM-DD) or for older_than_days days, optionally
+// limited to a project and to sessions carrying every tag in the tag
+// parameters. A cutoff is required so no run selects every session.
+func parsePruneFilter(c *gin.Context) (database.PruneFilter, error) {
+	filter := database.PruneFilter{
+		Project: c.Query("project"),
+		Tags:    parseTagFilter(c),
+	}
+
+	before, days := c.Query("before"), c.Query("older_than_days")
+	switch {
+	case before != "" && days != "":
+		return filter, fmt.Errorf("set before or older_than_days, not both")
+	case before != "":
+		parsed, err := time.Parse("2006-01-02", before)
+		if err != nil {
+			return filter, fmt.Errorf("before must be YYYY-MM-DD")
+		}
+		filter.Before = parsed
+	case days != "":
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 1 {
+			return filter, fmt.Errorf("older_than_days must be a positive number of days")
+		}
+		filter.Before = time.Now().UTC().AddDate(0, 0, -n)
+	default:
+		return filter, fmt.Errorf("before or older_than_days is required")
+	}
+	return filter, nil
+}
+
+// GetPrunePreviewHandler summarizes the sessions and messages a retention
+// run with the same filters would prune or archive, with their size and cost
+// history, without changing anything
+func (h *SQLiteHandlers) GetPrunePreviewHandler(c *gin.Context) {
+	filter, err := parsePruneFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	preview, err := h.repo.PreviewPrune(filter)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to preview prune")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to preview prune",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, preview)
+}
+
+// DeleteSessionHandler deletes a session and everything stored for it.
+// Sessions under legal hold can't be deleted until the hold is released.
+func (h *SQLiteHandlers) DeleteSessionHandler(c *gin.Context) {
+	if err := h.repo.DeleteSession(c.Param("id")); err != nil {
+		switch {
+		case errors.Is(err, database.ErrLegalHold):
+			c.JSON(http.StatusConflict, gin.H{
+				"error": "Session is under legal hold",
+			})
+		case strings.Contains(err.Error(), "not found"):
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Session not found",
+			})
+		default:
+			h.logger.WithError(err).Error("Failed to delete session")
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to delete session",
+			})
+		}
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
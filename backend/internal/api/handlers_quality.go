@@ -0,0 +1,105 @@
+package api
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/claude-session-manager/internal/database"
+)
+
+// attachQualityScores sets the stored quality score on each session response
+func (h *SQLiteHandlers) attachQualityScores(responses []database.SessionResponse) {
+	scores, err := h.repo.GetSessionQualityScores()
+	if err != nil {
+		h.logger.WithError(err).Warn("Failed to get session quality scores")
+		return
+	}
+	for i := range responses {
+		if score, exists := scores[responses[i].ID]; exists {
+			value := score.Score
+			responses[i].QualityScore = &value
+		}
+	}
+}
+
+// sortSessions attaches quality scores and orders session responses by the
+// sort (last_activity or quality_score) and order (asc or desc) query
+// parameters. Sessions without a score sort last either way.
+func (h *SQLiteHandlers) sortSessions(c *gin.Context, responses []database.SessionResponse) {
+	h.attachQualityScores(responses)
+
+	ascending := strings.EqualFold(c.Query("order"), "asc")
+	switch c.DefaultQuery("sort", "last_activity") {
+	case "quality_score":
+		sort.SliceStable(responses, func(i, j int) bool {
+			a, b := responses[i].QualityScore, responses[j].QualityScore
+			if a == nil || b == nil {
+				return a != nil
+			}
+			if ascending {
+				return *a < *b
+			}
+			return *a > *b
+		})
+	default:
+		sort.SliceStable(responses, func(i, j int) bool {
+			if ascending {
+				return responses[i].UpdatedAt.Before(responses[j].UpdatedAt)
+			}
+			return responses[i].UpdatedAt.After(responses[j].UpdatedAt)
+		})
+	}
+}
+
+// GetSessionScoreHandler returns a session's quality score and the signals behind it
+func (h *SQLiteHandlers) GetSessionScoreHandler(c *gin.Context) {
+	score, err := h.repo.GetSessionQualityScore(c.Param("id"))
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Session has not been scored yet",
+			})
+			return
+		}
+		h.logger.WithError(err).Error("Failed to get session score")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve session score",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, score)
+}
+
+// SetSessionFeedbackHandler records a 1-5 rating of a session and returns its updated score
+func (h *SQLiteHandlers) SetSessionFeedbackHandler(c *gin.Context) {
+	var req struct {
+		Rating int     `json:"rating" binding:"required,min=1,max=5"`
+		Note   *string `json:"note"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "rating must be between 1 and 5",
+		})
+		return
+	}
+
+	score, err := h.repo.SetSessionFeedback(c.Param("id"), req.Rating, req.Note)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Session not found",
+			})
+			return
+		}
+		h.logger.WithError(err).Error("Failed to save session feedback")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to save session feedback",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, score)
+}
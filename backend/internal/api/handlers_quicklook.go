@@ -0,0 +1,180 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/claude-session-manager/internal/database"
+	"github.com/sirupsen/logrus"
+)
+
+// quickLookTitleLength is the longest title shown before it is truncated
+const quickLookTitleLength = 80
+
+// QuickLookHandlers contains the compact search used by launcher
+// integrations such as Raycast and Alfred
+type QuickLookHandlers struct {
+	repo         *database.SessionRepository
+	dashboardURL string
+	logger       *logrus.Logger
+}
+
+// QuickLookItem is a search result with a one-line summary and deep links
+type QuickLookItem struct {
+	ID           string    `json:"id"`
+	Title        string    `json:"title"`
+	Subtitle     string    `json:"subtitle"`
+	URL          string    `json:"url"`
+	APIURL       string    `json:"api_url"`
+	ProjectPath  string    `json:"project_path"`
+	IsActive     bool      `json:"is_active"`
+	LastActivity time.Time `json:"last_activity"`
+}
+
+// NewQuickLookHandlers creates new quick-look handlers. Deep links open
+// dashboardURL, or the server's own address when it is empty.
+func NewQuickLookHandlers(repo *database.SessionRepository, dashboardURL string, logger *logrus.Logger) *QuickLookHandlers {
+	return &QuickLookHandlers{
+		repo:         repo,
+		dashboardURL: strings.TrimRight(dashboardURL, "/"),
+		logger:       logger,
+	}
+}
+
+// QuickLookHandler returns the top sessions matching q with a one-line
+// summary and links to open each in the dashboard. q is optional, returning
+// the most recent sessions; format=alfred returns Alfred Script Filter JSON.
+func (h *QuickLookHandlers) QuickLookHandler(c *gin.Context) {
+	query := c.Query("q")
+	if len(query) > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Query too long (max 100 characters)",
+		})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "5"))
+	if err != nil || limit <= 0 {
+		limit = 5
+	}
+	if limit > 20 {
+		limit = 20
+	}
+
+	sessions, err := h.repo.QuickLookSessions(query, limit)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to search sessions for quick look")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to search sessions",
+		})
+		return
+	}
+
+	serverURL := requestBaseURL(c)
+	dashboardURL := h.dashboardURL
+	if dashboardURL == "" {
+		dashboardURL = serverURL
+	}
+
+	now := time.Now()
+	items := make([]QuickLookItem, 0, len(sessions))
+	for _, session := range sessions {
+		items = append(items, QuickLookItem{
+			ID:           session.ID,
+			Title:        quickLookTitle(session),
+			Subtitle:     quickLookSubtitle(session, now),
+			URL:          dashboardURL + "/?session=" + url.QueryEscape(session.ID),
+			APIURL:       serverURL + "/api/v1/sessions/" + url.PathEscape(session.ID),
+			ProjectPath:  session.ProjectPath,
+			IsActive:     session.IsActive,
+			LastActivity: session.LastActivity,
+		})
+	}
+
+	if c.Query("format") == "alfred" {
+		alfredItems := make([]gin.H, 0, len(items))
+		for _, item := range items {
+			alfredItems = append(alfredItems, gin.H{
+				"uid":          item.ID,
+				"title":        item.Title,
+				"subtitle":     item.Subtitle,
+				"arg":          item.URL,
+				"quicklookurl": item.URL,
+				"text":         gin.H{"copy": item.ID},
+			})
+		}
+		c.JSON(http.StatusOK, gin.H{"items": alfredItems})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"query": query,
+		"items": items,
+	})
+}
+
+// quickLookTitle returns the first line of a session's opening prompt, or its
+// project name when it has none
+func quickLookTitle(session database.QuickLookSession) string {
+	title := strings.TrimSpace(session.Prompt)
+	if i := strings.IndexByte(title, '\n'); i >= 0 {
+		title = strings.TrimSpace(title[:i])
+	}
+	if title == "" {
+		return session.ProjectName
+	}
+	if utf8.RuneCountInString(title) > quickLookTitleLength {
+		title = string([]rune(title)[:quickLookTitleLength-1]) + "…"
+	}
+	return title
+}
+
+// quickLookSubtitle summarizes a session on one line, e.g.
+// "payments · fix/login · 12 messages · $0.42 · 3h ago"
+func quickLookSubtitle(session database.QuickLookSession, now time.Time) string {
+	parts := []string{session.ProjectName}
+	if session.GitBranch != nil && *session.GitBranch != "" {
+		parts = append(parts, *session.GitBranch)
+	}
+	parts = append(parts,
+		fmt.Sprintf("%d messages", session.MessageCount),
+		fmt.Sprintf("$%.2f", session.CostUSD),
+	)
+	if session.IsActive {
+		parts = append(parts, "active")
+	} else {
+		parts = append(parts, formatAgo(now.Sub(session.LastActivity)))
+	}
+	return strings.Join(parts, " · ")
+}
+
+// formatAgo formats an elapsed duration coarsely, e.g. 3h ago
+func formatAgo(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", int(d.Hours()))
+	default:
+		return fmt.Sprintf("%dd ago", int(d.Hours()/24))
+	}
+}
+
+// requestBaseURL returns the scheme and host the request was sent to
+func requestBaseURL(c *gin.Context) string {
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	} else if proto := c.GetHeader("X-Forwarded-Proto"); proto == "https" {
+		scheme = proto
+	}
+	return scheme + "://" + c.Request.Host
+}
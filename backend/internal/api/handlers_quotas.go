@@ -0,0 +1,206 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/claude-session-manager/internal/auth"
+	"github.com/ksred/claude-session-manager/internal/database"
+	"github.com/ksred/claude-session-manager/internal/quota"
+	"github.com/sirupsen/logrus"
+)
+
+// QuotaHandlers contains handlers for team members' soft monthly quotas and
+// the owners their usage is counted by
+type QuotaHandlers struct {
+	repo    *database.SessionRepository
+	monitor *quota.Monitor
+	logger  *logrus.Logger
+}
+
+// NewQuotaHandlers creates new quota handlers
+func NewQuotaHandlers(repo *database.SessionRepository, monitor *quota.Monitor, logger *logrus.Logger) *QuotaHandlers {
+	return &QuotaHandlers{
+		repo:    repo,
+		monitor: monitor,
+		logger:  logger,
+	}
+}
+
+// SetQuotaRequest sets a user's monthly quotas, either of which may be 0 for
+// none, and the projects whose sessions count against them
+type SetQuotaRequest struct {
+	MonthlyTokens  int64    `json:"monthly_tokens"`
+	MonthlyCostUSD float64  `json:"monthly_cost_usd"`
+	Projects       []string `json:"projects"`
+}
+
+// GetQuotasHandler returns each user's usage this month and what remains of
+// their quota
+func (h *QuotaHandlers) GetQuotasHandler(c *gin.Context) {
+	statuses, err := h.monitor.Status()
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get quota status")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve quotas",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"quotas": statuses,
+		"count":  len(statuses),
+	})
+}
+
+// GetUserQuotaHandler returns one user's usage this month and what remains
+// of their quota; "me" stands for the user the API key belongs to
+func (h *QuotaHandlers) GetUserQuotaHandler(c *gin.Context) {
+	userName := c.Param("user")
+	if userName == "me" {
+		value, _ := c.Get(auth.ContextKey)
+		key, ok := value.(*database.APIKey)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "me needs API key authentication; name the user instead",
+			})
+			return
+		}
+		userName = key.UserName
+	}
+
+	statuses, err := h.monitor.Status()
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get quota status")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve quota",
+		})
+		return
+	}
+	for _, status := range statuses {
+		if status.UserName == userName {
+			c.JSON(http.StatusOK, status)
+			return
+		}
+	}
+
+	c.JSON(http.StatusNotFound, gin.H{
+		"error": "User not found",
+	})
+}
+
+// SetUserQuotaHandler replaces a user's quota and projects. Usage already
+// over a threshold warns straight away.
+func (h *QuotaHandlers) SetUserQuotaHandler(c *gin.Context) {
+	var req SetQuotaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid quota",
+		})
+		return
+	}
+	if req.MonthlyTokens < 0 || req.MonthlyCostUSD < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "monthly_tokens and monthly_cost_usd must not be negative",
+		})
+		return
+	}
+
+	q := &database.UserQuota{
+		MonthlyTokens:  req.MonthlyTokens,
+		MonthlyCostUSD: req.MonthlyCostUSD,
+	}
+	seen := make(map[string]bool)
+	for _, project := range req.Projects {
+		if project = strings.TrimSpace(project); project != "" && !seen[project] {
+			seen[project] = true
+			q.Projects = append(q.Projects, project)
+		}
+	}
+	if err := h.repo.SetUserQuota(c.Param("user"), q); err != nil {
+		h.notFoundOrError(c, err, "Failed to set quota")
+		return
+	}
+
+	if _, err := h.monitor.Check(); err != nil {
+		h.logger.WithError(err).Error("Failed to check quotas")
+	}
+
+	c.JSON(http.StatusOK, q)
+}
+
+// DeleteUserQuotaHandler removes a user's quota and projects
+func (h *QuotaHandlers) DeleteUserQuotaHandler(c *gin.Context) {
+	if err := h.repo.DeleteUserQuota(c.Param("user")); err != nil {
+		h.notFoundOrError(c, err, "Failed to delete quota")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"deleted": true,
+	})
+}
+
+// SetSessionOwnerHandler counts a session's usage against a user rather than
+// its project's owner
+func (h *QuotaHandlers) SetSessionOwnerHandler(c *gin.Context) {
+	var req struct {
+		User string `json:"user" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "user is required",
+		})
+		return
+	}
+
+	if err := h.repo.SetSessionOwner(c.Param("id"), req.User); err != nil {
+		h.notFoundOrError(c, err, "Failed to set session owner")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"session_id": c.Param("id"),
+		"user":       req.User,
+	})
+}
+
+// ClearSessionOwnerHandler counts a session's usage against its project's
+// owner again
+func (h *QuotaHandlers) ClearSessionOwnerHandler(c *gin.Context) {
+	if err := h.repo.SetSessionOwner(c.Param("id"), ""); err != nil {
+		h.notFoundOrError(c, err, "Failed to clear session owner")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"deleted": true,
+	})
+}
+
+func (h *QuotaHandlers) notFoundOrError(c *gin.Context, err error, message string) {
+	switch {
+	case strings.Contains(err.Error(), "user not found"):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "User not found",
+		})
+	case strings.Contains(err.Error(), "session not found"):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Session not found",
+		})
+	case strings.Contains(err.Error(), "not found"):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "No quota is set",
+		})
+	case strings.Contains(err.Error(), "already has an owner"):
+		c.JSON(http.StatusConflict, gin.H{
+			"error": err.Error(),
+		})
+	default:
+		h.logger.WithError(err).Error(message)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": message,
+		})
+	}
+}
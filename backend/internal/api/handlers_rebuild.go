@@ -0,0 +1,142 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/claude-session-manager/internal/database"
+	"github.com/sirupsen/logrus"
+)
+
+// RebuildStatus is the state of the latest rebuild
+type RebuildStatus struct {
+	Running    bool                     `json:"running"`
+	StartedAt  *time.Time               `json:"started_at,omitempty"`
+	FinishedAt *time.Time               `json:"finished_at,omitempty"`
+	Wipe       *database.RebuildWipe    `json:"wipe,omitempty"`
+	Progress   *database.ImportProgress `json:"progress,omitempty"`
+	Error      string                   `json:"error,omitempty"`
+}
+
+// RebuildHandlers contains handlers for rebuilding the data imports derive
+// from transcripts
+type RebuildHandlers struct {
+	ctx       context.Context
+	repo      *database.SessionRepository
+	db        *database.Database
+	claudeDir string
+	updates   *WebSocketUpdateAdapter
+	logger    *logrus.Logger
+
+	mu     sync.Mutex
+	status RebuildStatus
+}
+
+// NewRebuildHandlers creates new rebuild handlers. Import progress is
+// broadcast through updates as well as kept for GetRebuildHandler.
+func NewRebuildHandlers(ctx context.Context, repo *database.SessionRepository, db *database.Database, claudeDir string, updates *WebSocketUpdateAdapter, logger *logrus.Logger) *RebuildHandlers {
+	return &RebuildHandlers{
+		ctx:       ctx,
+		repo:      repo,
+		db:        db,
+		claudeDir: claudeDir,
+		updates:   updates,
+		logger:    logger,
+	}
+}
+
+// rebuildProgress records import progress for the rebuild status before
+// broadcasting it like any other import
+type rebuildProgress struct {
+	*WebSocketUpdateAdapter
+	handlers *RebuildHandlers
+}
+
+// OnImportProgress records progress as the latest of the rebuild
+func (p rebuildProgress) OnImportProgress(progress *database.ImportProgress) {
+	p.handlers.mu.Lock()
+	p.handlers.status.Progress = progress
+	p.handlers.mu.Unlock()
+	p.WebSocketUpdateAdapter.OnImportProgress(progress)
+}
+
+// StartRebuildHandler wipes the sessions, messages, token usage, tool results
+// and activity imported from transcripts, then imports every transcript in
+// the Claude directory again in the background. Progress is broadcast as
+// import_progress events and returned by GetRebuildHandler.
+func (h *RebuildHandlers) StartRebuildHandler(c *gin.Context) {
+	h.mu.Lock()
+	if h.status.Running {
+		status := h.status
+		h.mu.Unlock()
+		c.JSON(http.StatusConflict, gin.H{
+			"error":  "A rebuild is already running",
+			"status": status,
+		})
+		return
+	}
+	startedAt := time.Now().UTC()
+	h.status = RebuildStatus{Running: true, StartedAt: &startedAt}
+	h.mu.Unlock()
+
+	wipe, err := h.repo.WipeDerivedData()
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to wipe derived data for rebuild")
+		h.finish(err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to wipe derived data",
+		})
+		return
+	}
+
+	h.mu.Lock()
+	h.status.Wipe = wipe
+	status := h.status
+	h.mu.Unlock()
+
+	h.logger.WithFields(logrus.Fields{
+		"sessions_deleted": wipe.SessionsDeleted,
+		"sessions_kept":    wipe.SessionsKept,
+		"messages_deleted": wipe.MessagesDeleted,
+	}).Info("Wiped derived data, re-importing transcripts")
+
+	go h.reimport()
+
+	c.JSON(http.StatusAccepted, status)
+}
+
+// reimport imports every transcript again and records how the rebuild ended
+func (h *RebuildHandlers) reimport() {
+	importer := database.NewIncrementalImporter(h.ctx, h.repo, h.db, h.logger)
+	importer.SetUpdateCallback(rebuildProgress{WebSocketUpdateAdapter: h.updates, handlers: h})
+	err := importer.ImportClaudeDirectory(h.claudeDir, true)
+	if err != nil {
+		h.logger.WithError(err).Error("Rebuild import failed")
+	} else {
+		h.logger.Info("Rebuild completed")
+	}
+	h.finish(err)
+}
+
+// finish marks the rebuild as no longer running
+func (h *RebuildHandlers) finish(err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	finishedAt := time.Now().UTC()
+	h.status.Running = false
+	h.status.FinishedAt = &finishedAt
+	if err != nil {
+		h.status.Error = err.Error()
+	}
+}
+
+// GetRebuildHandler returns the state of the running or latest rebuild
+func (h *RebuildHandlers) GetRebuildHandler(c *gin.Context) {
+	h.mu.Lock()
+	status := h.status
+	h.mu.Unlock()
+	c.JSON(http.StatusOK, status)
+}
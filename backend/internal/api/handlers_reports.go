@@ -0,0 +1,80 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/claude-session-manager/internal/reports"
+	"github.com/sirupsen/logrus"
+)
+
+// ReportHandlers contains handlers for the scheduled summary reports
+type ReportHandlers struct {
+	scheduler *reports.Scheduler
+	logger    *logrus.Logger
+}
+
+// NewReportHandlers creates new report handlers
+func NewReportHandlers(scheduler *reports.Scheduler, logger *logrus.Logger) *ReportHandlers {
+	return &ReportHandlers{
+		scheduler: scheduler,
+		logger:    logger,
+	}
+}
+
+// RunReportRequest names the report to run and whether to only preview it
+type RunReportRequest struct {
+	Name   string `json:"name"`    // every report when empty
+	DryRun bool   `json:"dry_run"` // render without delivering
+}
+
+// RunReportsNowHandler runs the configured reports straight away for the
+// period they would cover if sent now, delivering them unless dry_run is set,
+// and returns each rendered report with the outcome of each delivery
+func (h *ReportHandlers) RunReportsNowHandler(c *gin.Context) {
+	var req RunReportRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request body",
+		})
+		return
+	}
+
+	results, err := h.scheduler.Run(req.Name, !req.DryRun)
+	if err != nil {
+		if errors.Is(err, reports.ErrUnknownReport) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "No such report is configured",
+			})
+			return
+		}
+		h.logger.WithError(err).Error("Failed to run reports")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to run reports",
+		})
+		return
+	}
+
+	failed := 0
+	for _, result := range results {
+		for _, delivery := range result.Deliveries {
+			if delivery.Error != "" {
+				failed++
+				h.logger.WithFields(logrus.Fields{
+					"report":  result.Report.Name,
+					"channel": delivery.Channel,
+					"error":   delivery.Error,
+				}).Warn("Failed to deliver report")
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"reports":           results,
+		"total":             len(results),
+		"failed_deliveries": failed,
+		"delivered":         !req.DryRun,
+	})
+}
@@ -0,0 +1,153 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/claude-session-manager/internal/database"
+)
+
+// GetSessionReviewHandler returns a session's review state and comments
+func (h *SQLiteHandlers) GetSessionReviewHandler(c *gin.Context) {
+	sessionID := c.Param("id")
+
+	review, err := h.repo.GetSessionReview(sessionID)
+	if err != nil && !strings.Contains(err.Error(), "not found") {
+		h.logger.WithError(err).Error("Failed to get session review")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve session review",
+		})
+		return
+	}
+
+	comments, err := h.repo.GetSessionReviewComments(sessionID)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get review comments")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve review comments",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"session_id": sessionID,
+		"review":     review,
+		"comments":   comments,
+	})
+}
+
+// SetSessionReviewHandler sets a session's review status and optionally assigns a reviewer
+func (h *SQLiteHandlers) SetSessionReviewHandler(c *gin.Context) {
+	var req struct {
+		Status    string  `json:"status" binding:"required"`
+		Reviewer  *string `json:"reviewer"`
+		UpdatedBy *string `json:"updated_by"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || !database.IsValidReviewStatus(req.Status) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "status must be needs-review, reviewed or flagged",
+		})
+		return
+	}
+
+	review, err := h.repo.SetSessionReview(c.Param("id"), req.Status, req.Reviewer, req.UpdatedBy)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Session not found",
+			})
+			return
+		}
+		h.logger.WithError(err).Error("Failed to save session review")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to save session review",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, review)
+}
+
+// AddSessionReviewCommentHandler adds a reviewer comment to a session
+func (h *SQLiteHandlers) AddSessionReviewCommentHandler(c *gin.Context) {
+	var req struct {
+		Author    string  `json:"author" binding:"required"`
+		Body      string  `json:"body" binding:"required"`
+		MessageID *string `json:"message_id"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "author and body are required",
+		})
+		return
+	}
+
+	comment := &database.SessionReviewComment{
+		SessionID: c.Param("id"),
+		Author:    req.Author,
+		Body:      req.Body,
+		MessageID: req.MessageID,
+	}
+	if err := h.repo.AddSessionReviewComment(comment); err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Session not found",
+			})
+			return
+		}
+		h.logger.WithError(err).Error("Failed to add review comment")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to add review comment",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, comment)
+}
+
+// GetReviewQueueHandler returns sessions in a review status (default
+// needs-review), optionally only those assigned to one reviewer. With
+// status=unreviewed it returns sessions that changed files but were never reviewed.
+func (h *SQLiteHandlers) GetReviewQueueHandler(c *gin.Context) {
+	status := c.DefaultQuery("status", database.ReviewStatusNeedsReview)
+	if status != database.ReviewQueueUnreviewed && !database.IsValidReviewStatus(status) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "status must be needs-review, reviewed, flagged or unreviewed",
+		})
+		return
+	}
+
+	limit := 50
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if parsed, err := strconv.Atoi(limitStr); err == nil && parsed > 0 && parsed <= 500 {
+			limit = parsed
+		}
+	}
+
+	items, err := h.repo.GetReviewQueue(status, c.Query("reviewer"), limit)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get review queue")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve review queue",
+		})
+		return
+	}
+
+	counts, err := h.repo.GetReviewCounts()
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get review counts")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve review counts",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":   status,
+		"sessions": items,
+		"total":    len(items),
+		"counts":   counts,
+	})
+}
@@ -0,0 +1,51 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/claude-session-manager/internal/pricing"
+	"github.com/ksred/claude-session-manager/internal/routing"
+)
+
+// GetModelRoutingHandler flags sessions of the last days (default 30) that a
+// cheaper target model (default Haiku) would likely have handled, being
+// short and using no tools, and estimates what running them on it would
+// have saved, per session and per project
+func (h *SQLiteHandlers) GetModelRoutingHandler(c *gin.Context) {
+	days := 30
+	if daysStr := c.Query("days"); daysStr != "" {
+		parsed, err := strconv.Atoi(daysStr)
+		if err != nil || parsed <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "days must be a positive integer",
+			})
+			return
+		}
+		days = parsed
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 {
+		limit = 50
+	}
+	if limit > 500 {
+		limit = 500
+	}
+
+	to := time.Now().UTC()
+	from := to.AddDate(0, 0, -days)
+	usage, err := h.repo.GetSessionModelUsage(from, to)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get session model usage")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to analyze model routing",
+		})
+		return
+	}
+
+	target := c.DefaultQuery("target", routing.DefaultTarget)
+	c.JSON(http.StatusOK, routing.Analyze(usage, pricing.Default(), target, routing.DefaultHeuristics, from, to, limit))
+}
@@ -0,0 +1,80 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/claude-session-manager/internal/database"
+	"github.com/ksred/claude-session-manager/internal/scripting"
+	"github.com/sirupsen/logrus"
+)
+
+// ScriptHandlers contains handlers for the configured scripts and the custom
+// fields they compute
+type ScriptHandlers struct {
+	repo   *database.SessionRepository
+	engine *scripting.Engine
+	logger *logrus.Logger
+}
+
+// NewScriptHandlers creates new script handlers
+func NewScriptHandlers(repo *database.SessionRepository, engine *scripting.Engine, logger *logrus.Logger) *ScriptHandlers {
+	return &ScriptHandlers{
+		repo:   repo,
+		engine: engine,
+		logger: logger,
+	}
+}
+
+// GetScriptsHandler returns the configured scripts with how often each has
+// run, matched and failed since the server started
+func (h *ScriptHandlers) GetScriptsHandler(c *gin.Context) {
+	scripts := []gin.H{}
+	for _, script := range h.engine.Scripts() {
+		entry := gin.H{
+			"name":       script.Name,
+			"on":         script.On,
+			"expression": script.Expression,
+			"action":     script.Action,
+			"metrics":    script.Metrics(),
+		}
+		if script.Field != "" {
+			entry["field"] = script.Field
+		}
+		if script.Tag != "" {
+			entry["tag"] = script.Tag
+		}
+		scripts = append(scripts, entry)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"scripts": scripts,
+		"total":   len(scripts),
+	})
+}
+
+// GetSessionFieldsHandler returns the custom fields scripts computed for a
+// session and its messages
+func (h *ScriptHandlers) GetSessionFieldsHandler(c *gin.Context) {
+	sessionID := c.Param("id")
+	if _, err := h.repo.GetSessionByID(sessionID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Session not found",
+		})
+		return
+	}
+
+	fields, err := h.repo.GetScriptFields(sessionID)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get script fields")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve session fields",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"session_id": sessionID,
+		"fields":     fields,
+	})
+}
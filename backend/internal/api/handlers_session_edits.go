@@ -0,0 +1,122 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/claude-session-manager/internal/database"
+)
+
+// SplitSessionHandler splits a session at a message into two sessions. The
+// message and every later one move to a new session.
+func (h *SQLiteHandlers) SplitSessionHandler(c *gin.Context) {
+	var req struct {
+		MessageID string `json:"message_id" binding:"required"`
+		Reason    string `json:"reason"`
+		EditedBy  string `json:"edited_by"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "message_id is required",
+		})
+		return
+	}
+
+	edit, err := h.repo.SplitSession(c.Param("id"), req.MessageID, req.Reason, req.EditedBy)
+	if err != nil {
+		h.sessionEditError(c, err, "split")
+		return
+	}
+	h.respondSessionEdit(c, edit, edit.SessionID, edit.TargetSessionID)
+}
+
+// MergeSessionHandler merges the session given as session_id into the
+// session in the path, which keeps its ID
+func (h *SQLiteHandlers) MergeSessionHandler(c *gin.Context) {
+	var req struct {
+		SessionID string `json:"session_id" binding:"required"`
+		Reason    string `json:"reason"`
+		EditedBy  string `json:"edited_by"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "session_id is required",
+		})
+		return
+	}
+
+	edit, err := h.repo.MergeSessions(c.Param("id"), req.SessionID, req.Reason, req.EditedBy)
+	if err != nil {
+		h.sessionEditError(c, err, "merge")
+		return
+	}
+	h.respondSessionEdit(c, edit, edit.TargetSessionID)
+}
+
+// GetSessionEditsHandler lists splits and merges, most recent first,
+// optionally only those involving session_id
+func (h *SQLiteHandlers) GetSessionEditsHandler(c *gin.Context) {
+	edits, err := h.repo.GetSessionEdits(c.Query("session_id"))
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get session edits")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve session edits",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"edits": edits,
+		"total": len(edits),
+	})
+}
+
+// respondSessionEdit returns an edit with the sessions it left
+func (h *SQLiteHandlers) respondSessionEdit(c *gin.Context, edit *database.SessionEdit, sessionIDs ...string) {
+	sessions := make([]*database.SessionSummary, 0, len(sessionIDs))
+	for _, sessionID := range sessionIDs {
+		session, err := h.repo.GetSessionByID(sessionID)
+		if err != nil {
+			h.logger.WithError(err).WithField("session_id", sessionID).Error("Failed to get edited session")
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to retrieve edited session",
+			})
+			return
+		}
+		sessions = append(sessions, session)
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"edit":     edit,
+		"sessions": sessions,
+	})
+}
+
+// sessionEditError responds to a split or merge that failed
+func (h *SQLiteHandlers) sessionEditError(c *gin.Context, err error, action string) {
+	switch {
+	case errors.Is(err, database.ErrLegalHold):
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "Session is under legal hold",
+		})
+	case errors.Is(err, database.ErrInvalidSessionEdit):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+	case strings.Contains(err.Error(), "message not found"):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Message not found in session",
+		})
+	case strings.Contains(err.Error(), "not found"):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Session not found",
+		})
+	default:
+		h.logger.WithError(err).Errorf("Failed to %s session", action)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to " + action + " session",
+		})
+	}
+}
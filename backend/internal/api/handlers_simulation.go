@@ -0,0 +1,75 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/claude-session-manager/internal/pricing"
+	"github.com/ksred/claude-session-manager/internal/simulation"
+)
+
+// SimulateCostsRequest is the body of POST /api/v1/analytics/costs/simulate
+type SimulateCostsRequest struct {
+	Scenarios []simulation.Scenario `json:"scenarios"`
+}
+
+// SimulateCostsHandler reprices a period's usage under each scenario in the
+// body and compares it with what it costs at the prices in effect. The
+// period is chosen as in GetCostsByCostCenterHandler but defaults to the
+// last three complete months, so plan fees aren't charged for days yet to
+// come. Nothing is changed.
+func (h *SQLiteHandlers) SimulateCostsHandler(c *gin.Context) {
+	var from, to time.Time
+	if c.Query("month") == "" && c.Query("from") == "" && c.Query("to") == "" {
+		now := time.Now().UTC()
+		to = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+		from = to.AddDate(0, -3, 0)
+	} else {
+		var err error
+		from, to, err = chargebackPeriod(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+	}
+
+	var req SimulateCostsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request body",
+		})
+		return
+	}
+	if len(req.Scenarios) == 0 || len(req.Scenarios) > simulation.MaxScenarios {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("between 1 and %d scenarios are required", simulation.MaxScenarios),
+		})
+		return
+	}
+	for i := range req.Scenarios {
+		if req.Scenarios[i].Name == "" {
+			req.Scenarios[i].Name = fmt.Sprintf("scenario %d", i+1)
+		}
+		if err := req.Scenarios[i].Validate(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": fmt.Sprintf("%s: %v", req.Scenarios[i].Name, err),
+			})
+			return
+		}
+	}
+
+	usage, err := h.repo.GetModelMonthUsage(from, to)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get model usage by month")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to simulate costs",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, simulation.Simulate(usage, pricing.Default(), req.Scenarios, from, to))
+}
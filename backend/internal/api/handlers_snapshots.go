@@ -0,0 +1,108 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/claude-session-manager/internal/database"
+	"github.com/ksred/claude-session-manager/internal/monthclose"
+	"github.com/sirupsen/logrus"
+)
+
+// SnapshotHandlers contains handlers for the frozen monthly close snapshots
+type SnapshotHandlers struct {
+	repo   *database.SessionRepository
+	closer *monthclose.Closer
+	logger *logrus.Logger
+}
+
+// NewSnapshotHandlers creates new snapshot handlers
+func NewSnapshotHandlers(repo *database.SessionRepository, closer *monthclose.Closer, logger *logrus.Logger) *SnapshotHandlers {
+	return &SnapshotHandlers{
+		repo:   repo,
+		closer: closer,
+		logger: logger,
+	}
+}
+
+// GetMonthlySnapshotsHandler lists the closed months and their totals
+func (h *SnapshotHandlers) GetMonthlySnapshotsHandler(c *gin.Context) {
+	snapshots, err := h.repo.GetMonthlySnapshots()
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get monthly snapshots")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve monthly snapshots",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"snapshots": snapshots,
+		"total":     len(snapshots),
+	})
+}
+
+// GetMonthlySnapshotHandler returns the aggregates frozen when a month closed
+func (h *SnapshotHandlers) GetMonthlySnapshotHandler(c *gin.Context) {
+	snapshot, err := h.repo.GetMonthlySnapshot(c.Param("month"))
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Month has not been closed",
+			})
+			return
+		}
+		h.logger.WithError(err).Error("Failed to get monthly snapshot")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve monthly snapshot",
+		})
+		return
+	}
+
+	h.respondWithSnapshot(c, http.StatusOK, snapshot)
+}
+
+// CloseMonthHandler closes a month that has ended without waiting for the
+// automatic close
+func (h *SnapshotHandlers) CloseMonthHandler(c *gin.Context) {
+	snapshot, err := h.closer.CloseMonth(c.Param("month"))
+	if err != nil {
+		switch {
+		case errors.Is(err, monthclose.ErrAlreadyClosed):
+			c.JSON(http.StatusConflict, gin.H{
+				"error": err.Error(),
+			})
+		case errors.Is(err, monthclose.ErrMonthOpen), strings.Contains(err.Error(), "YYYY-MM"):
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+		default:
+			h.logger.WithError(err).Error("Failed to close month")
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to close month",
+			})
+		}
+		return
+	}
+
+	h.respondWithSnapshot(c, http.StatusCreated, snapshot)
+}
+
+func (h *SnapshotHandlers) respondWithSnapshot(c *gin.Context, status int, snapshot *database.MonthlySnapshot) {
+	data, err := monthclose.Decode(snapshot)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to decode monthly snapshot")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to decode monthly snapshot",
+		})
+		return
+	}
+
+	c.JSON(status, gin.H{
+		"snapshot": snapshot,
+		"data":     data,
+		"verified": monthclose.Verify(snapshot),
+	})
+}
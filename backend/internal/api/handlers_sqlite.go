@@ -1,10 +1,12 @@
 package api
 
 import (
+	"fmt"
 	"net/http"
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/ksred/claude-session-manager/internal/database"
@@ -29,9 +31,29 @@ func NewSQLiteHandlers(repo *database.SessionRepository, logger *logrus.Logger)
 	}
 }
 
-// GetSessionsHandler returns all sessions
+// GetSessionsHandler returns all sessions, or a page of them when limit or
+// offset is given. Archived sessions are left out unless include_archived
+// is true.
 func (h *SQLiteHandlers) GetSessionsHandler(c *gin.Context) {
-	sessions, err := h.readOptimized.GetAllSessionsOptimized()
+	limit, offset, paginated := parseSessionsPage(c)
+	includeArchived := c.Query("include_archived") == "true"
+
+	// Environment and tag filters and the quality score order are applied
+	// after loading, so those requests page in memory rather than in SQL
+	inMemory := c.Query("sort") == "quality_score"
+	for _, filter := range []string{"os", "terminal", "git_remote", "client_version", "tag"} {
+		if c.Query(filter) != "" {
+			inMemory = true
+		}
+	}
+
+	queryLimit, queryOffset := limit, offset
+	if !paginated || inMemory {
+		queryLimit, queryOffset = 0, 0
+	}
+	ascending := strings.EqualFold(c.Query("order"), "asc")
+
+	sessions, total, err := h.readOptimized.GetAllSessionsOptimized(queryLimit, queryOffset, ascending, includeArchived)
 	if err != nil {
 		h.logger.WithError(err).Error("Failed to get sessions from database")
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -51,17 +73,82 @@ func (h *SQLiteHandlers) GetSessionsHandler(c *gin.Context) {
 		responses[i] = *response
 	}
 
-	// Sort by last activity (most recent first)
-	sort.Slice(responses, func(i, j int) bool {
-		return responses[i].UpdatedAt.After(responses[j].UpdatedAt)
-	})
+	responses = h.filterByEnvironment(c, responses)
+	if responses, err = h.filterByTags(c, responses); err != nil {
+		h.logger.WithError(err).Error("Failed to filter sessions by tag")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve sessions",
+		})
+		return
+	}
+
+	// Sort by last activity (most recent first) unless another order is requested
+	h.sortSessions(c, responses)
+
+	if !paginated {
+		c.JSON(http.StatusOK, gin.H{
+			"sessions": responses,
+			"total":    len(responses),
+		})
+		return
+	}
+
+	if inMemory {
+		total = len(responses)
+		responses = pageSessions(responses, limit, offset)
+	}
+
+	var nextOffset *int
+	if offset+len(responses) < total {
+		next := offset + len(responses)
+		nextOffset = &next
+	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"sessions": responses,
-		"total":    len(responses),
+		"sessions":    responses,
+		"total":       total,
+		"limit":       limit,
+		"offset":      offset,
+		"has_more":    nextOffset != nil,
+		"next_offset": nextOffset,
 	})
 }
 
+// parseSessionsPage reads the limit (default 50, max 500) and offset query
+// parameters, reporting whether either was given
+func parseSessionsPage(c *gin.Context) (limit, offset int, paginated bool) {
+	limit = 50
+	if l := c.Query("limit"); l != "" {
+		paginated = true
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 {
+			limit = parsed
+		}
+	}
+	if limit > 500 {
+		limit = 500
+	}
+
+	if o := c.Query("offset"); o != "" {
+		paginated = true
+		if parsed, err := strconv.Atoi(o); err == nil && parsed >= 0 {
+			offset = parsed
+		}
+	}
+	return limit, offset, paginated
+}
+
+// pageSessions returns the limit sessions starting at offset
+func pageSessions(responses []database.SessionResponse, limit, offset int) []database.SessionResponse {
+	if offset >= len(responses) {
+		return []database.SessionResponse{}
+	}
+	end := offset + limit
+	if end > len(responses) {
+		end = len(responses)
+	}
+	return responses[offset:end]
+}
+
 // GetSessionHandler returns a specific session by ID
 func (h *SQLiteHandlers) GetSessionHandler(c *gin.Context) {
 	sessionID := c.Param("id")
@@ -84,12 +171,24 @@ func (h *SQLiteHandlers) GetSessionHandler(c *gin.Context) {
 		return
 	}
 
+	if score, err := h.repo.GetSessionQualityScore(sessionID); err == nil {
+		response.QualityScore = &score.Score
+	}
+	if env, err := h.repo.GetSessionEnvironment(sessionID); err == nil {
+		response.Environment = env
+	}
+	if lineage, err := h.repo.GetSessionLineage(sessionID); err != nil {
+		h.logger.WithError(err).Warn("Failed to get session lineage")
+	} else {
+		response.Lineage = lineage
+	}
+
 	c.JSON(http.StatusOK, response)
 }
 
 // GetActiveSessionsHandler returns currently active sessions
 func (h *SQLiteHandlers) GetActiveSessionsHandler(c *gin.Context) {
-	sessions, err := h.readOptimized.GetActiveSessionsOptimized()
+	sessions, err := h.readOptimized.GetActiveSessionsOptimized(c.Query("include_archived") == "true")
 	if err != nil {
 		h.logger.WithError(err).Error("Failed to get active sessions from database")
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -109,10 +208,10 @@ func (h *SQLiteHandlers) GetActiveSessionsHandler(c *gin.Context) {
 		responses[i] = *response
 	}
 
-	// Sort by last activity (most recent first)
-	sort.Slice(responses, func(i, j int) bool {
-		return responses[i].UpdatedAt.After(responses[j].UpdatedAt)
-	})
+	responses = h.filterByEnvironment(c, responses)
+
+	// Sort by last activity (most recent first) unless another order is requested
+	h.sortSessions(c, responses)
 
 	c.JSON(http.StatusOK, gin.H{
 		"sessions": responses,
@@ -131,7 +230,7 @@ func (h *SQLiteHandlers) GetRecentSessionsHandler(c *gin.Context) {
 		limit = 100
 	}
 
-	sessions, err := h.repo.GetRecentSessions(limit)
+	sessions, err := h.repo.GetRecentSessions(limit, c.Query("include_archived") == "true")
 	if err != nil {
 		h.logger.WithError(err).Error("Failed to get recent sessions from database")
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -151,16 +250,77 @@ func (h *SQLiteHandlers) GetRecentSessionsHandler(c *gin.Context) {
 		responses[i] = *response
 	}
 
+	responses = h.filterByEnvironment(c, responses)
+	h.sortSessions(c, responses)
+
 	c.JSON(http.StatusOK, gin.H{
 		"sessions": responses,
 		"limit":    limit,
 	})
 }
 
-// GetMetricsSummaryHandler returns overall metrics summary
+// parseAsOf parses the as_of query parameter of analytics endpoints, which
+// recomputes them from the messages sent before then. A date (YYYY-MM-DD)
+// covers the whole of that UTC day; an RFC 3339 time is taken as is. It
+// returns nil when as_of is not set.
+func parseAsOf(c *gin.Context) (*time.Time, error) {
+	value := c.Query("as_of")
+	if value == "" {
+		return nil, nil
+	}
+	if day, err := time.Parse("2006-01-02", value); err == nil {
+		end := day.AddDate(0, 0, 1)
+		return &end, nil
+	}
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, fmt.Errorf("as_of must be YYYY-MM-DD or an RFC 3339 time")
+	}
+	if parsed.After(time.Now()) {
+		return nil, fmt.Errorf("as_of must not be in the future")
+	}
+	return &parsed, nil
+}
+
+// GetMetricsSummaryHandler returns overall metrics summary, or the summary
+// as it stood at as_of
 func (h *SQLiteHandlers) GetMetricsSummaryHandler(c *gin.Context) {
+	asOf, err := parseAsOf(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if asOf != nil {
+		history, err := h.repo.GetMetricsSummaryAsOf(*asOf)
+		if err != nil {
+			h.logger.WithError(err).Error("Failed to get metrics summary as of a past time")
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to retrieve metrics",
+			})
+			return
+		}
+		summary := MetricsSummary{
+			TotalSessions:          history.TotalSessions,
+			ActiveSessions:         history.ActiveSessions,
+			TotalMessages:          history.TotalMessages,
+			TotalTokensUsed:        history.TotalTokens,
+			TotalEstimatedCost:     history.EstimatedCost,
+			AverageSessionDuration: history.AverageSessionDuration,
+			MostUsedModel:          history.MostUsedModel,
+			ModelUsage:             history.ModelUsage,
+			AsOf:                   asOf,
+		}
+		describeSummary(c, &summary)
+		c.JSON(http.StatusOK, summary)
+		return
+	}
+
+	includeArchived := c.Query("include_archived") == "true"
+
 	// Get total sessions
-	totalSessions, err := h.repo.GetTotalSessions()
+	totalSessions, err := h.repo.GetTotalSessions(includeArchived)
 	if err != nil {
 		h.logger.WithError(err).Error("Failed to get total sessions")
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -170,7 +330,7 @@ func (h *SQLiteHandlers) GetMetricsSummaryHandler(c *gin.Context) {
 	}
 
 	// Get active sessions
-	activeSessions, err := h.repo.GetActiveSessionsCount()
+	activeSessions, err := h.repo.GetActiveSessionsCount(includeArchived)
 	if err != nil {
 		h.logger.WithError(err).Error("Failed to get active sessions")
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -180,7 +340,7 @@ func (h *SQLiteHandlers) GetMetricsSummaryHandler(c *gin.Context) {
 	}
 
 	// Get total messages
-	totalMessages, err := h.repo.GetTotalMessages()
+	totalMessages, err := h.repo.GetTotalMessages(includeArchived)
 	if err != nil {
 		h.logger.WithError(err).Error("Failed to get total messages")
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -190,7 +350,7 @@ func (h *SQLiteHandlers) GetMetricsSummaryHandler(c *gin.Context) {
 	}
 
 	// Get overall token usage
-	tokenUsage, err := h.repo.GetOverallTokenUsage()
+	tokenUsage, err := h.repo.GetOverallTokenUsage(includeArchived)
 	if err != nil {
 		h.logger.WithError(err).Error("Failed to get token usage")
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -200,7 +360,7 @@ func (h *SQLiteHandlers) GetMetricsSummaryHandler(c *gin.Context) {
 	}
 
 	// Get estimated cost
-	totalCost, err := h.repo.GetEstimatedCost()
+	totalCost, err := h.repo.GetEstimatedCost(includeArchived)
 	if err != nil {
 		h.logger.WithError(err).Error("Failed to get estimated cost")
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -210,7 +370,7 @@ func (h *SQLiteHandlers) GetMetricsSummaryHandler(c *gin.Context) {
 	}
 
 	// Get average session duration
-	avgDuration, err := h.repo.GetAverageSessionDuration()
+	avgDuration, err := h.repo.GetAverageSessionDuration(includeArchived)
 	if err != nil {
 		h.logger.WithError(err).Error("Failed to get average duration")
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -220,7 +380,7 @@ func (h *SQLiteHandlers) GetMetricsSummaryHandler(c *gin.Context) {
 	}
 
 	// Get most used model
-	mostUsedModel, err := h.repo.GetMostUsedModel()
+	mostUsedModel, err := h.repo.GetMostUsedModel(includeArchived)
 	if err != nil {
 		h.logger.WithError(err).Error("Failed to get most used model")
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -230,7 +390,7 @@ func (h *SQLiteHandlers) GetMetricsSummaryHandler(c *gin.Context) {
 	}
 
 	// Get model usage
-	modelUsage, err := h.repo.GetModelUsage()
+	modelUsage, err := h.repo.GetModelUsage(includeArchived)
 	if err != nil {
 		h.logger.WithError(err).Error("Failed to get model usage")
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -249,10 +409,29 @@ func (h *SQLiteHandlers) GetMetricsSummaryHandler(c *gin.Context) {
 		MostUsedModel:          mostUsedModel,
 		ModelUsage:             modelUsage,
 	}
+	describeSummary(c, &summary)
 
 	c.JSON(http.StatusOK, summary)
 }
 
+// describeSummary adds the request's locale metadata to a summary and, when
+// asked for, its figures formatted for display
+func describeSummary(c *gin.Context, summary *MetricsSummary) {
+	formatter := requestFormatter(c)
+	meta := formatter.Metadata()
+	summary.Meta = &meta
+	if wantsFormatted(c) {
+		summary.Display = map[string]string{
+			"total_sessions":                   formatter.Number(float64(summary.TotalSessions), 0),
+			"active_sessions":                  formatter.Number(float64(summary.ActiveSessions), 0),
+			"total_messages":                   formatter.Number(float64(summary.TotalMessages), 0),
+			"total_tokens_used":                formatter.Tokens(int64(summary.TotalTokensUsed)),
+			"total_estimated_cost":             formatter.Cost(summary.TotalEstimatedCost),
+			"average_session_duration_minutes": formatter.Number(summary.AverageSessionDuration, 1),
+		}
+	}
+}
+
 // GetActivityHandler returns activity timeline data
 func (h *SQLiteHandlers) GetActivityHandler(c *gin.Context) {
 	limitStr := c.DefaultQuery("limit", "50")
@@ -347,8 +526,53 @@ func (h *SQLiteHandlers) GetProjectActivityHandler(c *gin.Context) {
 	})
 }
 
-// GetUsageStatsHandler returns usage statistics
+// GetProjectSummaryHandler returns the aggregate figures for a project's dashboard
+// @Summary Get project summary
+// @Description Retrieve sessions, messages, token usage by type, estimated cost, active sessions, most used model and first/last activity for a single project
+// @Tags Projects
+// @Accept json
+// @Produce json
+// @Param projectName path string true "Name of the project"
+// @Success 200 {object} database.ProjectSummary "Successfully retrieved project summary"
+// @Failure 404 {object} ErrorResponse "Project not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /projects/{projectName}/summary [get]
+func (h *SQLiteHandlers) GetProjectSummaryHandler(c *gin.Context) {
+	projectName := c.Param("projectName")
+
+	summary, err := h.repo.GetProjectSummary(projectName)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Project not found",
+			})
+			return
+		}
+		h.logger.WithError(err).Error("Failed to get project summary")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve project summary",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, summary)
+}
+
+// GetUsageStatsHandler returns usage statistics, or the statistics as they
+// stood at as_of
 func (h *SQLiteHandlers) GetUsageStatsHandler(c *gin.Context) {
+	asOf, err := parseAsOf(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if asOf != nil {
+		h.getUsageStatsAsOf(c, *asOf)
+		return
+	}
+
 	// Get daily metrics for the last 7 days
 	dailyMetrics, err := h.repo.GetDailyMetrics(7)
 	if err != nil {
@@ -360,7 +584,7 @@ func (h *SQLiteHandlers) GetUsageStatsHandler(c *gin.Context) {
 	}
 
 	// Get model usage
-	modelUsage, err := h.repo.GetModelUsage()
+	modelUsage, err := h.repo.GetModelUsage(c.Query("include_archived") == "true")
 	if err != nil {
 		h.logger.WithError(err).Error("Failed to get model usage")
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -397,6 +621,76 @@ func (h *SQLiteHandlers) GetUsageStatsHandler(c *gin.Context) {
 	c.JSON(http.StatusOK, stats)
 }
 
+// getUsageStatsAsOf returns the usage statistics of the week before asOf
+func (h *SQLiteHandlers) getUsageStatsAsOf(c *gin.Context, asOf time.Time) {
+	dailyMetrics, err := h.repo.GetDailyMetricsAsOf(7, asOf)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get daily metrics")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve usage stats",
+		})
+		return
+	}
+
+	modelUsage, err := h.repo.GetModelUsageAsOf(asOf)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get model usage")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve usage stats",
+		})
+		return
+	}
+
+	peakHours, err := h.repo.GetPeakHoursAsOf(asOf)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get peak hours")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve usage stats",
+		})
+		return
+	}
+
+	dailySessionsList := []gin.H{}
+	for _, daily := range dailyMetrics {
+		dailySessionsList = append(dailySessionsList, gin.H{
+			"date":  daily.Date,
+			"count": daily.SessionCount,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"daily_sessions": dailySessionsList,
+		"model_usage":    modelUsage,
+		"peak_hours":     peakHours,
+		"as_of":          asOf,
+	})
+}
+
+// GetVersionUsageHandler returns usage and error rates by Claude Code client version
+func (h *SQLiteHandlers) GetVersionUsageHandler(c *gin.Context) {
+	days := 30
+	if daysStr := c.Query("days"); daysStr != "" {
+		if parsed, err := strconv.Atoi(daysStr); err == nil && parsed > 0 && parsed <= 365 {
+			days = parsed
+		}
+	}
+
+	versions, err := h.repo.GetVersionUsage(days)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get version usage")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve version usage",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"versions": versions,
+		"days":     days,
+		"total":    len(versions),
+	})
+}
+
 // SearchHandler handles search queries across sessions
 func (h *SQLiteHandlers) SearchHandler(c *gin.Context) {
 	query := c.Query("q")
@@ -435,6 +729,14 @@ func (h *SQLiteHandlers) SearchHandler(c *gin.Context) {
 		results[i] = *response
 	}
 
+	if results, err = h.filterByTags(c, results); err != nil {
+		h.logger.WithError(err).Error("Failed to filter search results by tag")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to search sessions",
+		})
+		return
+	}
+
 	// Sort results by relevance (most recent first)
 	sort.Slice(results, func(i, j int) bool {
 		return results[i].UpdatedAt.After(results[j].UpdatedAt)
@@ -593,7 +895,9 @@ func (h *SQLiteHandlers) GetProjectRecentFilesHandler(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param hours query int false "Number of hours to look back (default: 24, max: 720)"
-// @Param granularity query string false "Time granularity: minute, hour, day (default: hour)"
+// @Param granularity query string false "Time granularity: minute, hour, day, week, month or a window such as 15m, 6h or 2d (default: hour)"
+// @Param as_of query string false "End the timeline at this date (YYYY-MM-DD, inclusive) or RFC 3339 time instead of now"
+// @Param fill query string false "Return every bucket in the range, with empty ones as zero or null" Enums(zero, null)
 // @Success 200 {object} TokenTimelineResponse "Successfully retrieved token timeline"
 // @Failure 400 {object} ErrorResponse "Invalid query parameters"
 // @Failure 500 {object} ErrorResponse "Internal server error"
@@ -608,11 +912,23 @@ func (h *SQLiteHandlers) GetTokenTimelineHandler(c *gin.Context) {
 	}
 
 	granularity := c.DefaultQuery("granularity", "hour")
-	if granularity != "minute" && granularity != "hour" && granularity != "day" {
+	if !database.IsTimelineGranularity(granularity) {
 		granularity = "hour"
 	}
 
-	timeline, err := h.readOptimized.GetTokenTimelineOptimized(hours, granularity)
+	asOf, err := parseAsOf(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	end := time.Now()
+	if asOf != nil {
+		end = *asOf
+	}
+
+	timeline, err := h.readOptimized.GetTokenTimelineAsOf(hours, granularity, end)
 	if err != nil {
 		h.logger.WithError(err).Error("Failed to get token timeline")
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -620,13 +936,21 @@ func (h *SQLiteHandlers) GetTokenTimelineHandler(c *gin.Context) {
 		})
 		return
 	}
+	filled, total, ok := fillTimeline(c, timeline, end.Add(-time.Duration(hours)*time.Hour), end, granularity)
+	if !ok {
+		return
+	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"timeline":    timeline,
+	response := gin.H{
+		"timeline":    filled,
 		"hours":       hours,
 		"granularity": granularity,
-		"total":       len(timeline),
-	})
+		"total":       total,
+	}
+	if asOf != nil {
+		response["as_of"] = asOf
+	}
+	c.JSON(http.StatusOK, response)
 }
 
 // GetSessionTokenTimelineHandler returns token usage timeline for a specific session
@@ -637,7 +961,8 @@ func (h *SQLiteHandlers) GetTokenTimelineHandler(c *gin.Context) {
 // @Produce json
 // @Param id path string true "Session ID"
 // @Param hours query int false "Number of hours to look back (default: 168)"
-// @Param granularity query string false "Time granularity: minute, hour, day (default: minute)"
+// @Param granularity query string false "Time granularity: minute, hour, day, week, month or a window such as 15m, 6h or 2d (default: minute)"
+// @Param fill query string false "Return every bucket in the range, with empty ones as zero or null" Enums(zero, null)
 // @Success 200 {object} TokenTimelineResponse "Successfully retrieved session token timeline"
 // @Failure 400 {object} ErrorResponse "Invalid parameters"
 // @Failure 404 {object} ErrorResponse "Session not found"
@@ -661,7 +986,7 @@ func (h *SQLiteHandlers) GetSessionTokenTimelineHandler(c *gin.Context) {
 	}
 
 	granularity := c.DefaultQuery("granularity", "minute")
-	if granularity != "minute" && granularity != "hour" && granularity != "day" {
+	if !database.IsTimelineGranularity(granularity) {
 		granularity = "minute"
 	}
 
@@ -672,6 +997,7 @@ func (h *SQLiteHandlers) GetSessionTokenTimelineHandler(c *gin.Context) {
 		"granularity": granularity,
 	}).Debug("Getting session token timeline")
 
+	now := time.Now()
 	timeline, err := h.readOptimized.GetSessionTokenTimelineOptimized(sessionID, hours, granularity)
 	if err != nil {
 		h.logger.WithError(err).Error("Failed to get session token timeline")
@@ -701,20 +1027,27 @@ func (h *SQLiteHandlers) GetSessionTokenTimelineHandler(c *gin.Context) {
 		}
 
 		// Session exists but has no token usage data yet - return empty timeline
-		c.JSON(http.StatusOK, gin.H{
-			"session_id":  sessionID,
-			"timeline":    []interface{}{},
-			"granularity": granularity,
-			"total":       0,
-		})
+		if c.Query("fill") == "" {
+			c.JSON(http.StatusOK, gin.H{
+				"session_id":  sessionID,
+				"timeline":    []interface{}{},
+				"granularity": granularity,
+				"total":       0,
+			})
+			return
+		}
+	}
+
+	filled, total, ok := fillTimeline(c, timeline, now.Add(-time.Duration(hours)*time.Hour), now, granularity)
+	if !ok {
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"session_id":  sessionID,
-		"timeline":    timeline,
+		"timeline":    filled,
 		"granularity": granularity,
-		"total":       len(timeline),
+		"total":       total,
 	})
 }
 
@@ -726,7 +1059,8 @@ func (h *SQLiteHandlers) GetSessionTokenTimelineHandler(c *gin.Context) {
 // @Produce json
 // @Param projectName path string true "Name of the project"
 // @Param hours query int false "Number of hours to look back (default: 168/7 days, max: 720)"
-// @Param granularity query string false "Time granularity: minute, hour, day (default: hour)"
+// @Param granularity query string false "Time granularity: minute, hour, day, week, month or a window such as 15m, 6h or 2d (default: hour)"
+// @Param fill query string false "Return every bucket in the range, with empty ones as zero or null" Enums(zero, null)
 // @Success 200 {object} TokenTimelineResponse "Successfully retrieved project token timeline"
 // @Failure 400 {object} ErrorResponse "Invalid parameters"
 // @Failure 404 {object} ErrorResponse "Project not found"
@@ -750,10 +1084,11 @@ func (h *SQLiteHandlers) GetProjectTokenTimelineHandler(c *gin.Context) {
 	}
 
 	granularity := c.DefaultQuery("granularity", "hour")
-	if granularity != "minute" && granularity != "hour" && granularity != "day" {
+	if !database.IsTimelineGranularity(granularity) {
 		granularity = "hour"
 	}
 
+	now := time.Now()
 	timeline, err := h.repo.GetProjectTokenTimeline(projectName, hours, granularity)
 	if err != nil {
 		h.logger.WithError(err).Error("Failed to get project token timeline")
@@ -770,12 +1105,17 @@ func (h *SQLiteHandlers) GetProjectTokenTimelineHandler(c *gin.Context) {
 		return
 	}
 
+	filled, total, ok := fillTimeline(c, timeline, now.Add(-time.Duration(hours)*time.Hour), now, granularity)
+	if !ok {
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"project_name": projectName,
-		"timeline":     timeline,
+		"timeline":     filled,
 		"hours":        hours,
 		"granularity":  granularity,
-		"total":        len(timeline),
+		"total":        total,
 	})
 }
 
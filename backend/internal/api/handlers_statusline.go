@@ -0,0 +1,158 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/claude-session-manager/internal/database"
+	"github.com/sirupsen/logrus"
+)
+
+// statuslineCacheTTL is how long a summary is reused, so several statuslines
+// polling every few seconds cost one query each per interval
+const statuslineCacheTTL = 2 * time.Second
+
+// StatuslineHandlers contains the handler polled by Claude Code statusline
+// scripts
+type StatuslineHandlers struct {
+	repo        *database.SessionRepository
+	dailyBudget float64
+	currency    string
+	logger      *logrus.Logger
+	mu          sync.Mutex
+	cache       map[string]statuslineEntry
+}
+
+type statuslineEntry struct {
+	summary   gin.H
+	line      string
+	expiresAt time.Time
+}
+
+// NewStatuslineHandlers creates new statusline handlers. A dailyBudget of 0
+// leaves the remaining budget out.
+func NewStatuslineHandlers(repo *database.SessionRepository, dailyBudget float64, currency string, logger *logrus.Logger) *StatuslineHandlers {
+	return &StatuslineHandlers{
+		repo:        repo,
+		dailyBudget: dailyBudget,
+		currency:    currency,
+		logger:      logger,
+		cache:       make(map[string]statuslineEntry),
+	}
+}
+
+// GetStatuslineHandler returns a one-line summary of a session's cost and
+// tokens so far, today's cost and the daily budget remaining. session is
+// optional; format=json returns the figures instead of the line.
+func (h *StatuslineHandlers) GetStatuslineHandler(c *gin.Context) {
+	sessionID := c.Query("session")
+
+	entry, err := h.summary(sessionID)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get statusline usage")
+		if c.Query("format") == "json" {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to retrieve usage",
+			})
+			return
+		}
+		// A statusline shows whatever it is sent, so keep the error short
+		c.String(http.StatusInternalServerError, "usage unavailable\n")
+		return
+	}
+
+	if c.Query("format") == "json" {
+		c.JSON(http.StatusOK, entry.summary)
+		return
+	}
+	c.String(http.StatusOK, entry.line+"\n")
+}
+
+// summary returns the cached summary for a session, refreshing it once it
+// expires
+func (h *StatuslineHandlers) summary(sessionID string) (statuslineEntry, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	now := time.Now()
+	if entry, ok := h.cache[sessionID]; ok && now.Before(entry.expiresAt) {
+		return entry, nil
+	}
+
+	dayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	usage, err := h.repo.GetStatuslineUsage(sessionID, dayStart)
+	if err != nil {
+		return statuslineEntry{}, err
+	}
+
+	summary := gin.H{
+		"today_tokens":   usage.TodayTokens,
+		"today_cost_usd": usage.TodayCostUSD,
+		"currency":       h.currency,
+	}
+	var parts []string
+	if sessionID != "" {
+		summary["session_id"] = sessionID
+		summary["session_tokens"] = usage.SessionTokens
+		summary["session_cost_usd"] = usage.SessionCostUSD
+		parts = append(parts,
+			h.formatCost(usage.SessionCostUSD)+" session",
+			formatTokenCount(usage.SessionTokens)+" tokens",
+		)
+	}
+	parts = append(parts, h.formatCost(usage.TodayCostUSD)+" today")
+	if h.dailyBudget > 0 {
+		remaining := h.dailyBudget - usage.TodayCostUSD
+		summary["daily_budget"] = h.dailyBudget
+		summary["budget_remaining"] = remaining
+		if remaining < 0 {
+			parts = append(parts, h.formatCost(-remaining)+" over budget")
+		} else {
+			parts = append(parts, h.formatCost(remaining)+" left")
+		}
+	}
+
+	// Drop summaries for sessions no longer polled
+	for key, cached := range h.cache {
+		if now.After(cached.expiresAt) {
+			delete(h.cache, key)
+		}
+	}
+	entry := statuslineEntry{
+		summary:   summary,
+		line:      strings.Join(parts, " · "),
+		expiresAt: now.Add(statuslineCacheTTL),
+	}
+	h.cache[sessionID] = entry
+	return entry, nil
+}
+
+// formatCost formats a cost for the statusline
+func (h *StatuslineHandlers) formatCost(cost float64) string {
+	return formatCurrency(cost, h.currency)
+}
+
+// formatCurrency formats an amount with a dollar sign for USD and the
+// currency code otherwise
+func formatCurrency(amount float64, currency string) string {
+	if currency == "" || currency == "USD" {
+		return fmt.Sprintf("$%.2f", amount)
+	}
+	return fmt.Sprintf("%.2f %s", amount, currency)
+}
+
+// formatTokenCount abbreviates a token count, e.g. 1234567 as 1.2M
+func formatTokenCount(tokens int) string {
+	switch {
+	case tokens >= 1000000:
+		return fmt.Sprintf("%.1fM", float64(tokens)/1000000)
+	case tokens >= 1000:
+		return fmt.Sprintf("%.1fk", float64(tokens)/1000)
+	default:
+		return fmt.Sprintf("%d", tokens)
+	}
+}
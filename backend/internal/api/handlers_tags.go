@@ -0,0 +1,289 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/claude-session-manager/internal/database"
+	"github.com/ksred/claude-session-manager/internal/tagging"
+	"github.com/sirupsen/logrus"
+)
+
+// TagHandlers contains handlers for session tags and auto-tagging rules
+type TagHandlers struct {
+	repo   *database.SessionRepository
+	tagger *tagging.Tagger
+	logger *logrus.Logger
+}
+
+// NewTagHandlers creates new tag handlers
+func NewTagHandlers(repo *database.SessionRepository, tagger *tagging.Tagger, logger *logrus.Logger) *TagHandlers {
+	return &TagHandlers{
+		repo:   repo,
+		tagger: tagger,
+		logger: logger,
+	}
+}
+
+// GetSessionTagsHandler returns a session's tags and whether each was
+// applied by hand, by a rule or by a script
+func (h *TagHandlers) GetSessionTagsHandler(c *gin.Context) {
+	sessionID := c.Param("id")
+	if _, err := h.repo.GetSessionByID(sessionID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Session not found",
+		})
+		return
+	}
+
+	tags, err := h.repo.GetSessionTags(sessionID)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get session tags")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve session tags",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"session_id": sessionID,
+		"tags":       tags,
+	})
+}
+
+// GetTagRulesHandler returns the configured auto-tagging rules and how many
+// sessions each has tagged
+func (h *TagHandlers) GetTagRulesHandler(c *gin.Context) {
+	counts, err := h.repo.GetRuleTagCounts()
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get rule tag counts")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve tagging rules",
+		})
+		return
+	}
+
+	rules := []gin.H{}
+	for _, rule := range h.tagger.Rules() {
+		rules = append(rules, gin.H{
+			"name":     rule.Name,
+			"tag":      rule.Tag,
+			"patterns": rule.Patterns(),
+			"sessions": counts[rule.Name],
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"rules": rules,
+		"total": len(rules),
+	})
+}
+
+// ApplyTagRulesHandler re-evaluates every session against the tagging rules
+func (h *TagHandlers) ApplyTagRulesHandler(c *gin.Context) {
+	evaluated, err := h.tagger.Reapply()
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to apply tagging rules")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to apply tagging rules",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"sessions_evaluated": evaluated,
+	})
+}
+
+// maxTagLength bounds a tag applied by hand
+const maxTagLength = 64
+
+// SessionTagsRequest lists tags to add to or remove from a session
+type SessionTagsRequest struct {
+	Tags []string `json:"tags" binding:"required"`
+}
+
+// SessionNoteRequest replaces a session's note; empty content removes it
+type SessionNoteRequest struct {
+	Content string `json:"content"`
+}
+
+// AddSessionTagsHandler tags a session by hand, returning all its tags
+func (h *TagHandlers) AddSessionTagsHandler(c *gin.Context) {
+	sessionID := c.Param("id")
+	tags, ok := h.bindTags(c)
+	if !ok {
+		return
+	}
+	if _, err := h.repo.GetSessionByID(sessionID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Session not found",
+		})
+		return
+	}
+
+	if err := h.repo.AddSessionTags(sessionID, tags); err != nil {
+		h.logger.WithError(err).Error("Failed to add session tags")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to add session tags",
+		})
+		return
+	}
+
+	h.GetSessionTagsHandler(c)
+}
+
+// RemoveSessionTagsHandler removes tags from a session, returning the tags
+// it has left. The tags can be given in the body or as tag query parameters.
+func (h *TagHandlers) RemoveSessionTagsHandler(c *gin.Context) {
+	sessionID := c.Param("id")
+	var tags []string
+	if c.Request.ContentLength > 0 {
+		var ok bool
+		if tags, ok = h.bindTags(c); !ok {
+			return
+		}
+	} else if tags = parseTagFilter(c); len(tags) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "tags are required",
+		})
+		return
+	}
+	if _, err := h.repo.GetSessionByID(sessionID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Session not found",
+		})
+		return
+	}
+
+	if _, err := h.repo.RemoveSessionTags(sessionID, tags); err != nil {
+		h.logger.WithError(err).Error("Failed to remove session tags")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to remove session tags",
+		})
+		return
+	}
+
+	h.GetSessionTagsHandler(c)
+}
+
+// bindTags reads and validates the tags in a SessionTagsRequest, responding
+// 400 when they are missing or invalid
+func (h *TagHandlers) bindTags(c *gin.Context) ([]string, bool) {
+	var req SessionTagsRequest
+	if err := c.ShouldBindJSON(&req); err != nil || len(req.Tags) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "tags are required",
+		})
+		return nil, false
+	}
+
+	tags := make([]string, 0, len(req.Tags))
+	for _, tag := range req.Tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || len(tag) > maxTagLength || strings.Contains(tag, ",") {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": fmt.Sprintf("tags must be 1 to %d characters without commas", maxTagLength),
+			})
+			return nil, false
+		}
+		tags = append(tags, tag)
+	}
+	return tags, true
+}
+
+// GetSessionNotesHandler returns the note attached to a session
+func (h *TagHandlers) GetSessionNotesHandler(c *gin.Context) {
+	note, err := h.repo.GetSessionNote(c.Param("id"))
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Session has no note",
+			})
+			return
+		}
+		h.logger.WithError(err).Error("Failed to get session note")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve session note",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, note)
+}
+
+// SetSessionNotesHandler replaces the note attached to a session. Empty
+// content removes the note.
+func (h *TagHandlers) SetSessionNotesHandler(c *gin.Context) {
+	sessionID := c.Param("id")
+	var req SessionNoteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request body",
+		})
+		return
+	}
+	if _, err := h.repo.GetSessionByID(sessionID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Session not found",
+		})
+		return
+	}
+
+	content := strings.TrimSpace(req.Content)
+	if err := h.repo.SetSessionNote(sessionID, content); err != nil {
+		h.logger.WithError(err).Error("Failed to set session note")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to save session note",
+		})
+		return
+	}
+
+	if content == "" {
+		c.Status(http.StatusNoContent)
+		return
+	}
+	h.GetSessionNotesHandler(c)
+}
+
+// parseTagFilter returns the tags in the tag query parameters, which may be
+// repeated or comma-separated
+func parseTagFilter(c *gin.Context) []string {
+	var tags []string
+	for _, value := range c.QueryArray("tag") {
+		for _, tag := range strings.Split(value, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				tags = append(tags, tag)
+			}
+		}
+	}
+	return tags
+}
+
+// filterByTags keeps the sessions carrying every tag in the tag query
+// parameters and fills in each session's tags
+func (h *SQLiteHandlers) filterByTags(c *gin.Context, responses []database.SessionResponse) ([]database.SessionResponse, error) {
+	tagMap, err := h.repo.GetSessionTagMap()
+	if err != nil {
+		return nil, err
+	}
+
+	var matched map[string]bool
+	if tags := parseTagFilter(c); len(tags) > 0 {
+		if matched, err = h.repo.GetSessionIDsWithTags(tags); err != nil {
+			return nil, err
+		}
+	}
+
+	filtered := responses[:0]
+	for _, response := range responses {
+		if matched != nil && !matched[response.ID] {
+			continue
+		}
+		response.Tags = tagMap[response.ID]
+		filtered = append(filtered, response)
+	}
+	return filtered, nil
+}
@@ -0,0 +1,75 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// tailLimit caps how many messages a tail returns at once
+const tailLimit = 500
+
+// GetSessionTailHandler returns a session's messages appended after since, a
+// message ID or an RFC 3339 timestamp, so a live transcript can fetch only
+// what is new. Without since it returns the last limit messages (default
+// 100, at most 500). cursor is the ID to pass as since next time, and
+// has_more is set when more messages are waiting.
+func (h *SQLiteHandlers) GetSessionTailHandler(c *gin.Context) {
+	sessionID := c.Param("id")
+	session, err := h.repo.GetSessionByID(sessionID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Session not found",
+		})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit < 1 {
+		limit = 100
+	}
+	if limit > tailLimit {
+		limit = tailLimit
+	}
+
+	since := strings.TrimSpace(c.Query("since"))
+	var afterID string
+	var after time.Time
+	if since != "" {
+		if t, err := time.Parse(time.RFC3339Nano, since); err == nil {
+			after = t
+		} else {
+			afterID = since
+		}
+	}
+
+	messages, err := h.repo.GetMessagesAfter(sessionID, afterID, after, limit)
+	if err != nil {
+		if afterID != "" && strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "since must be a message of this session or an RFC 3339 timestamp",
+			})
+			return
+		}
+		h.logger.WithError(err).Error("Failed to get session tail")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve session messages",
+		})
+		return
+	}
+
+	cursor := afterID
+	if len(messages) > 0 {
+		cursor = messages[len(messages)-1].ID
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"session_id": sessionID,
+		"is_active":  session.IsActive,
+		"messages":   messages,
+		"cursor":     cursor,
+		"has_more":   since != "" && len(messages) == limit,
+	})
+}
@@ -0,0 +1,183 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// promptTemplateRequest is the request body for creating or updating a prompt template
+type promptTemplateRequest struct {
+	Name        string  `json:"name" binding:"required"`
+	Content     string  `json:"content" binding:"required"`
+	Description *string `json:"description"`
+	Category    *string `json:"category"`
+}
+
+// GetPromptTemplatesHandler returns all saved prompt templates
+func (h *SQLiteHandlers) GetPromptTemplatesHandler(c *gin.Context) {
+	templates, err := h.repo.GetPromptTemplates(c.Query("category"))
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get prompt templates")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve prompt templates",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"templates": templates,
+		"total":     len(templates),
+	})
+}
+
+// GetPromptTemplateHandler returns a single prompt template
+func (h *SQLiteHandlers) GetPromptTemplateHandler(c *gin.Context) {
+	template, err := h.repo.GetPromptTemplateByID(c.Param("id"))
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Prompt template not found",
+			})
+			return
+		}
+		h.logger.WithError(err).Error("Failed to get prompt template")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve prompt template",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, template)
+}
+
+// CreatePromptTemplateHandler stores a new prompt template
+func (h *SQLiteHandlers) CreatePromptTemplateHandler(c *gin.Context) {
+	var req promptTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request body",
+		})
+		return
+	}
+
+	template, err := h.repo.CreatePromptTemplate(req.Name, req.Content, req.Description, req.Category)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			c.JSON(http.StatusConflict, gin.H{
+				"error": "A prompt template with this name already exists",
+			})
+			return
+		}
+		h.logger.WithError(err).Error("Failed to create prompt template")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to create prompt template",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, template)
+}
+
+// UpdatePromptTemplateHandler replaces the contents of a prompt template
+func (h *SQLiteHandlers) UpdatePromptTemplateHandler(c *gin.Context) {
+	var req promptTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request body",
+		})
+		return
+	}
+
+	template, err := h.repo.GetPromptTemplateByID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Prompt template not found",
+		})
+		return
+	}
+
+	template.Name = req.Name
+	template.Content = req.Content
+	template.Description = req.Description
+	template.Category = req.Category
+
+	if err := h.repo.UpdatePromptTemplate(template); err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			c.JSON(http.StatusConflict, gin.H{
+				"error": "A prompt template with this name already exists",
+			})
+			return
+		}
+		h.logger.WithError(err).Error("Failed to update prompt template")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to update prompt template",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, template)
+}
+
+// DeletePromptTemplateHandler removes a prompt template
+func (h *SQLiteHandlers) DeletePromptTemplateHandler(c *gin.Context) {
+	if err := h.repo.DeletePromptTemplate(c.Param("id")); err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Prompt template not found",
+			})
+			return
+		}
+		h.logger.WithError(err).Error("Failed to delete prompt template")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to delete prompt template",
+		})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// UsePromptTemplateHandler records a template being inserted into a chat and returns its content
+func (h *SQLiteHandlers) UsePromptTemplateHandler(c *gin.Context) {
+	var req struct {
+		SessionID string `json:"session_id"`
+	}
+	// Body is optional
+	_ = c.ShouldBindJSON(&req)
+
+	template, err := h.repo.RecordPromptTemplateUsage(c.Param("id"), req.SessionID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Prompt template not found",
+			})
+			return
+		}
+		h.logger.WithError(err).Error("Failed to record prompt template usage")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to record prompt template usage",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, template)
+}
+
+// GetPromptTemplateStatsHandler returns usage statistics for all prompt templates
+func (h *SQLiteHandlers) GetPromptTemplateStatsHandler(c *gin.Context) {
+	stats, err := h.repo.GetPromptTemplateStats()
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get prompt template stats")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve prompt template statistics",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"stats": stats,
+		"total": len(stats),
+	})
+}
@@ -4,11 +4,14 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
+	"unicode/utf8"
 
 	"github.com/gin-gonic/gin"
 	"github.com/ksred/claude-session-manager/internal/claude"
+	"github.com/ksred/claude-session-manager/internal/database"
 )
 
 // Helper function to create test sessions
@@ -589,6 +592,193 @@ func TestErrorScenarios(t *testing.T) {
 	})
 }
 
+func TestQuickLookSummary(t *testing.T) {
+	now := time.Now()
+	branch := "fix/login"
+	session := database.QuickLookSession{
+		ProjectName:  "payments",
+		GitBranch:    &branch,
+		LastActivity: now.Add(-3 * time.Hour),
+		MessageCount: 12,
+		Prompt:       "  Fix the flaky login test\nIt fails on CI",
+		CostUSD:      0.42,
+	}
+
+	if title := quickLookTitle(session); title != "Fix the flaky login test" {
+		t.Errorf("Expected the prompt's first line as title, got %q", title)
+	}
+	if subtitle := quickLookSubtitle(session, now); subtitle != "payments · fix/login · 12 messages · $0.42 · 3h ago" {
+		t.Errorf("Unexpected subtitle: %q", subtitle)
+	}
+
+	session.Prompt = strings.Repeat("é", 100)
+	if title := quickLookTitle(session); utf8.RuneCountInString(title) != quickLookTitleLength || !strings.HasSuffix(title, "…") {
+		t.Errorf("Expected a truncated title, got %q", title)
+	}
+
+	session.Prompt = ""
+	session.IsActive = true
+	if title := quickLookTitle(session); title != "payments" {
+		t.Errorf("Expected the project name without a prompt, got %q", title)
+	}
+	if subtitle := quickLookSubtitle(session, now); !strings.HasSuffix(subtitle, " · active") {
+		t.Errorf("Expected an active session to be marked active, got %q", subtitle)
+	}
+}
+
+func TestFileChangeSessions(t *testing.T) {
+	now := time.Now()
+	// Changes arrive most recent first
+	changes := []database.FileChange{
+		{FilePath: "/a/server.go", SessionID: "s2", ToolName: "Edit", Timestamp: now},
+		{FilePath: "/b/server.go", SessionID: "s1", ToolName: "Write", Timestamp: now.Add(-time.Minute)},
+		{FilePath: "/a/server.go", SessionID: "s2", ToolName: "Write", Timestamp: now.Add(-2 * time.Minute)},
+		{FilePath: "/a/server.go", SessionID: "s2", ToolName: "Edit", Timestamp: now.Add(-3 * time.Minute)},
+	}
+
+	sessions := fileChangeSessions(changes)
+	if len(sessions) != 2 || sessions[0].SessionID != "s2" || sessions[1].SessionID != "s1" {
+		t.Fatalf("Expected s2 then s1, got %+v", sessions)
+	}
+	s2 := sessions[0]
+	if s2.Changes != 3 || len(s2.Tools) != 2 || !s2.LastChanged.Equal(now) || !s2.FirstChanged.Equal(now.Add(-3*time.Minute)) {
+		t.Errorf("Unexpected summary for s2: %+v", s2)
+	}
+
+	if files := changedFiles(changes); len(files) != 2 || files[0] != "/a/server.go" {
+		t.Errorf("Expected both matched files, got %v", files)
+	}
+}
+
+func TestPageSessions(t *testing.T) {
+	responses := make([]database.SessionResponse, 5)
+	for i := range responses {
+		responses[i].ID = string(rune('a' + i))
+	}
+
+	if page := pageSessions(responses, 2, 2); len(page) != 2 || page[0].ID != "c" || page[1].ID != "d" {
+		t.Errorf("Expected c and d, got %+v", page)
+	}
+	if page := pageSessions(responses, 10, 4); len(page) != 1 || page[0].ID != "e" {
+		t.Errorf("Expected only e, got %+v", page)
+	}
+	if page := pageSessions(responses, 2, 5); page == nil || len(page) != 0 {
+		t.Errorf("Expected an empty page past the end, got %+v", page)
+	}
+}
+
+func TestParseTagFilter(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/v1/sessions?tag=prod-incident,%20refactor&tag=experiment&tag=", nil)
+
+	tags := parseTagFilter(c)
+	if strings.Join(tags, "|") != "prod-incident|refactor|experiment" {
+		t.Errorf("Expected repeated and comma-separated tags, got %q", tags)
+	}
+}
+
+func TestParsePruneFilter(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	parse := func(query string) (database.PruneFilter, error) {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodGet, "/api/v1/admin/prune/preview?"+query, nil)
+		return parsePruneFilter(c)
+	}
+
+	filter, err := parse("before=2024-03-01&project=payments&tag=experiment")
+	if err != nil {
+		t.Fatalf("Failed to parse filter: %v", err)
+	}
+	if !filter.Before.Equal(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)) || filter.Project != "payments" || len(filter.Tags) != 1 {
+		t.Errorf("Expected the cutoff, project and tag, got %+v", filter)
+	}
+
+	filter, err = parse("older_than_days=30")
+	if err != nil || time.Since(filter.Before) < 30*24*time.Hour-time.Minute {
+		t.Errorf("Expected a cutoff 30 days ago, got %v, %v", filter.Before, err)
+	}
+
+	for _, query := range []string{"", "project=payments", "older_than_days=0", "before=March", "before=2024-03-01&older_than_days=30"} {
+		if _, err := parse(query); err == nil {
+			t.Errorf("Expected %q to be rejected", query)
+		}
+	}
+}
+
+func TestGrafanaGranularity(t *testing.T) {
+	tests := []struct {
+		span, interval time.Duration
+		maxDataPoints  int
+		want           string
+	}{
+		{6 * time.Hour, 20 * time.Second, 1000, "minute"},
+		{6 * time.Hour, 5 * time.Minute, 1000, "hour"},
+		{7 * 24 * time.Hour, time.Minute, 1000, "hour"},
+		{90 * 24 * time.Hour, time.Hour, 1000, "day"},
+		{365 * 24 * time.Hour, time.Hour, 100, "day"},
+	}
+	for _, tt := range tests {
+		if got, _ := grafanaGranularity(tt.span, tt.interval, tt.maxDataPoints); got != tt.want {
+			t.Errorf("Expected %s for %v at %v, got %s", tt.want, tt.span, tt.interval, got)
+		}
+	}
+}
+
+func TestParseGrafanaTarget(t *testing.T) {
+	tests := []struct {
+		target          GrafanaTarget
+		metric, project string
+	}{
+		{GrafanaTarget{Target: "cost"}, "cost", ""},
+		{GrafanaTarget{Target: "tokens:my-app"}, "tokens", "my-app"},
+		{GrafanaTarget{Target: "cost", Data: []byte(`{"project":"billing"}`)}, "cost", "billing"},
+		{GrafanaTarget{Target: "cost", Data: []byte(`"ignored"`)}, "cost", ""},
+	}
+	for _, tt := range tests {
+		metric, project := parseGrafanaTarget(tt.target)
+		if metric != tt.metric || project != tt.project {
+			t.Errorf("Expected %s for %s, got %s for %s", tt.metric, tt.project, metric, project)
+		}
+	}
+
+	if at, ok := grafanaTime("2026-10-01 12:00:00"); !ok || at != time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC).UnixMilli() {
+		t.Errorf("Expected the bucket in UTC milliseconds, got %d", at)
+	}
+}
+
+func TestFillTimeline(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	from := time.Date(2026, 10, 1, 10, 0, 0, 0, time.UTC)
+	timeline := []database.TokenTimelineEntry{{Timestamp: "2026-10-01 11:00:00", TotalTokens: 30, MessageCount: 2}}
+	fill := func(query string) (*httptest.ResponseRecorder, interface{}, int, bool) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/api/v1/analytics/tokens/timeline?"+query, nil)
+		filled, total, ok := fillTimeline(c, timeline, from, from.Add(3*time.Hour), "hour")
+		return w, filled, total, ok
+	}
+
+	if _, filled, total, ok := fill(""); !ok || total != 1 || len(filled.([]database.TokenTimelineEntry)) != 1 {
+		t.Errorf("Expected the timeline unchanged without fill, got %v", filled)
+	}
+
+	_, filled, total, ok := fill("fill=zero")
+	if !ok || total != 3 || filled.([]database.TokenTimelineEntry)[2].Timestamp != "2026-10-01 12:00:00" {
+		t.Errorf("Expected three hourly buckets, got %v", filled)
+	}
+
+	_, filled, _, ok = fill("fill=null")
+	data, _ := json.Marshal(filled)
+	if !ok || !strings.Contains(string(data), `{"cache_creation_tokens":null,"cache_read_tokens":null,"estimated_cost":null,"input_tokens":null,"message_count":null,"output_tokens":null,"timestamp":"2026-10-01 10:00:00","total_tokens":null}`) {
+		t.Errorf("Expected empty buckets with null figures, got %s", data)
+	}
+
+	if w, _, _, ok := fill("fill=linear"); ok || w.Code != http.StatusBadRequest {
+		t.Errorf("Expected an unknown fill to be rejected, got %d", w.Code)
+	}
+}
+
 // BenchmarkSessionToResponse benchmarks the session conversion
 func BenchmarkSessionToResponse(b *testing.B) {
 	session := createTestSessions()[0]
@@ -608,3 +798,33 @@ func BenchmarkFilterSessions(b *testing.B) {
 		_ = filterSessionsByStatus(sessions, claude.StatusWorking, claude.StatusIdle)
 	}
 }
+
+func TestSessionWrapUpText(t *testing.T) {
+	wrapUp := newSessionWrapUp(&database.SessionSummary{
+		ID:                 "0123456789abcdef",
+		ProjectName:        "app",
+		ProjectPath:        "/work/app",
+		Model:              "claude-sonnet-4",
+		DurationSeconds:    754,
+		MessageCount:       48,
+		TotalTokens:        1_250_000,
+		TotalEstimatedCost: 0.845,
+		FilesModified:      `["/work/app/main.go","/work/app/a.go","/work/app/b.go","/work/app/c.go","/work/app/d.go","/etc/hosts"]`,
+	})
+
+	text := wrapUp.Text()
+	for _, want := range []string{
+		"Session 01234567 · app · claude-sonnet-4",
+		"12m 34s · 48 messages · 1.2M tokens · $0.84",
+		"6 files touched: main.go, a.go, b.go, c.go, d.go, and 1 more",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Expected %q in:\n%s", want, text)
+		}
+	}
+
+	wrapUp.FilesTouched = []string{}
+	if !strings.Contains(wrapUp.Text(), "No files touched") {
+		t.Errorf("Expected no files, got:\n%s", wrapUp.Text())
+	}
+}
@@ -0,0 +1,29 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetThreadHandler returns a session and the sessions resumed from it as one
+// transcript with the cost accumulating across sessions
+func (h *SQLiteHandlers) GetThreadHandler(c *gin.Context) {
+	thread, err := h.repo.GetThread(c.Param("rootSessionId"))
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Session not found",
+			})
+			return
+		}
+		h.logger.WithError(err).Error("Failed to get thread")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve thread",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, thread)
+}
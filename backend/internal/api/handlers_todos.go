@@ -0,0 +1,107 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/claude-session-manager/internal/database"
+)
+
+// todoLimit parses the limit query parameter for todo and settings listings
+func todoLimit(c *gin.Context, defaultLimit, maxLimit int) int {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultLimit)))
+	if err != nil || limit <= 0 {
+		return defaultLimit
+	}
+	if limit > maxLimit {
+		return maxLimit
+	}
+	return limit
+}
+
+// GetTodosHandler returns the todo lists Claude kept during sessions,
+// optionally for one session (session_id) and with one status
+func (h *SQLiteHandlers) GetTodosHandler(c *gin.Context) {
+	status := c.Query("status")
+	switch status {
+	case "", database.TodoStatusPending, database.TodoStatusInProgress, database.TodoStatusCompleted:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "status must be pending, in_progress or completed",
+		})
+		return
+	}
+
+	sessionID := c.Query("session_id")
+	limit := todoLimit(c, 200, 1000)
+	todos, err := h.repo.GetTodos(sessionID, status, limit)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get todos")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve todos",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"todos": todos,
+		"total": len(todos),
+		"limit": limit,
+	})
+}
+
+// GetSessionTodosHandler returns the todo lists Claude kept during a session
+// and its subagents, with a count per status
+func (h *SQLiteHandlers) GetSessionTodosHandler(c *gin.Context) {
+	sessionID := c.Param("id")
+	if _, err := h.repo.GetSessionByID(sessionID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Session not found",
+		})
+		return
+	}
+
+	todos, err := h.repo.GetTodos(sessionID, "", 1000)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get session todos")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve session todos",
+		})
+		return
+	}
+
+	counts := map[string]int{
+		database.TodoStatusPending:    0,
+		database.TodoStatusInProgress: 0,
+		database.TodoStatusCompleted:  0,
+	}
+	for _, todo := range todos {
+		counts[todo.Status]++
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"session_id": sessionID,
+		"todos":      todos,
+		"counts":     counts,
+	})
+}
+
+// GetSettingsHistoryHandler returns the recorded versions of the Claude
+// settings file and which top-level keys each changed
+func (h *SQLiteHandlers) GetSettingsHistoryHandler(c *gin.Context) {
+	limit := todoLimit(c, 50, 500)
+	changes, err := h.repo.GetSettingsChanges(limit)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get settings changes")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve settings history",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"changes": changes,
+		"total":   len(changes),
+	})
+}
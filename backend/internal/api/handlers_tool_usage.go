@@ -0,0 +1,76 @@
+package api
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/claude-session-manager/internal/database"
+)
+
+// GetToolAnalyticsHandler returns how often each tool was called over the
+// last days (default 30, 0 for all time) and how long its results took, with
+// the limit (default 10) projects and sessions that called tools most.
+// project and session_id narrow everything to a project or a session.
+func (h *SQLiteHandlers) GetToolAnalyticsHandler(c *gin.Context) {
+	days, err := strconv.Atoi(c.DefaultQuery("days", "30"))
+	if err != nil || days < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "days must be 0 or more",
+		})
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if err != nil || limit < 1 {
+		limit = 10
+	}
+
+	filter := database.ToolUsageFilter{
+		Project:   c.Query("project"),
+		SessionID: c.Query("session_id"),
+	}
+	if days > 0 {
+		filter.Since = time.Now().UTC().AddDate(0, 0, -days)
+	}
+
+	tools, err := h.repo.GetToolUsage(filter)
+	if err != nil {
+		h.toolAnalyticsFailed(c, err)
+		return
+	}
+	projects, err := h.repo.GetToolUsageByProject(filter, limit)
+	if err != nil {
+		h.toolAnalyticsFailed(c, err)
+		return
+	}
+	sessions, err := h.repo.GetToolUsageBySession(filter, limit)
+	if err != nil {
+		h.toolAnalyticsFailed(c, err)
+		return
+	}
+
+	var calls, errors int64
+	for i := range tools {
+		tools[i].AvgDurationMs = math.Round(tools[i].AvgDurationMs)
+		calls += tools[i].Calls
+		errors += tools[i].Errors
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"days":        days,
+		"total_calls": calls,
+		"errors":      errors,
+		"tools":       tools,
+		"by_project":  projects,
+		"by_session":  sessions,
+	})
+}
+
+func (h *SQLiteHandlers) toolAnalyticsFailed(c *gin.Context, err error) {
+	h.logger.WithError(err).Error("Failed to get tool usage")
+	c.JSON(http.StatusInternalServerError, gin.H{
+		"error": "Failed to get tool usage",
+	})
+}
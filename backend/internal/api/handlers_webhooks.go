@@ -0,0 +1,227 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/claude-session-manager/internal/database"
+	"github.com/ksred/claude-session-manager/internal/webhooks"
+	"github.com/sirupsen/logrus"
+)
+
+// WebhookHandlers contains handlers for outgoing webhooks
+type WebhookHandlers struct {
+	repo       *database.SessionRepository
+	dispatcher *webhooks.Dispatcher
+	logger     *logrus.Logger
+}
+
+// NewWebhookHandlers creates new webhook handlers
+func NewWebhookHandlers(repo *database.SessionRepository, dispatcher *webhooks.Dispatcher, logger *logrus.Logger) *WebhookHandlers {
+	return &WebhookHandlers{
+		repo:       repo,
+		dispatcher: dispatcher,
+		logger:     logger,
+	}
+}
+
+// webhookRequest is the request body for creating or updating a webhook
+type webhookRequest struct {
+	URL         string   `json:"url" binding:"required"`
+	Events      []string `json:"events" binding:"required"`
+	Secret      *string  `json:"secret"` // generated on create and kept on update when left out; empty to stop signing
+	Description *string  `json:"description"`
+	Enabled     *bool    `json:"enabled"`
+}
+
+// apply validates the request and copies it onto a webhook
+func (req *webhookRequest) apply(webhook *database.Webhook) error {
+	parsed, err := url.Parse(req.URL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("url must be an http or https URL")
+	}
+
+	events := []string{}
+	seen := make(map[string]bool)
+	for _, event := range req.Events {
+		if !webhooks.IsEvent(event) {
+			return fmt.Errorf("unknown event %q; events are %s", event, strings.Join(webhooks.Events, ", "))
+		}
+		if !seen[event] {
+			seen[event] = true
+			events = append(events, event)
+		}
+	}
+	if len(events) == 0 {
+		return fmt.Errorf("events must name at least one event")
+	}
+
+	webhook.URL = req.URL
+	webhook.Events = events
+	webhook.Description = req.Description
+	webhook.Enabled = req.Enabled == nil || *req.Enabled
+	if req.Secret != nil {
+		webhook.Secret = *req.Secret
+	}
+	return nil
+}
+
+// createdWebhook is a new webhook with its secret, which is only ever
+// returned here
+type createdWebhook struct {
+	*database.Webhook
+	Secret string `json:"secret,omitempty"`
+}
+
+// GetWebhooksHandler returns every webhook and the events they can
+// subscribe to
+func (h *WebhookHandlers) GetWebhooksHandler(c *gin.Context) {
+	hooks, err := h.repo.GetWebhooks()
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get webhooks")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve webhooks",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"webhooks": hooks,
+		"total":    len(hooks),
+		"events":   webhooks.Events,
+	})
+}
+
+// GetWebhookHandler returns a webhook with the outcome of its last delivery
+func (h *WebhookHandlers) GetWebhookHandler(c *gin.Context) {
+	webhook, err := h.repo.GetWebhook(c.Param("id"))
+	if err != nil {
+		h.notFoundOrError(c, err, "Failed to retrieve webhook")
+		return
+	}
+
+	c.JSON(http.StatusOK, webhook)
+}
+
+// CreateWebhookHandler adds a webhook. Without a secret in the request one
+// is generated, and it is returned only in this response.
+func (h *WebhookHandlers) CreateWebhookHandler(c *gin.Context) {
+	var req webhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "url and events are required",
+		})
+		return
+	}
+
+	webhook := &database.Webhook{}
+	if err := req.apply(webhook); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if req.Secret == nil {
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			h.logger.WithError(err).Error("Failed to generate webhook secret")
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to create webhook",
+			})
+			return
+		}
+		webhook.Secret = hex.EncodeToString(secret)
+	}
+
+	if err := h.repo.CreateWebhook(webhook); err != nil {
+		h.logger.WithError(err).Error("Failed to create webhook")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to create webhook",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, createdWebhook{Webhook: webhook, Secret: webhook.Secret})
+}
+
+// UpdateWebhookHandler replaces a webhook's URL, events, description and
+// whether it is enabled, and its secret if one is given
+func (h *WebhookHandlers) UpdateWebhookHandler(c *gin.Context) {
+	var req webhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "url and events are required",
+		})
+		return
+	}
+
+	webhook, err := h.repo.GetWebhook(c.Param("id"))
+	if err != nil {
+		h.notFoundOrError(c, err, "Failed to retrieve webhook")
+		return
+	}
+	if err := req.apply(webhook); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	if err := h.repo.UpdateWebhook(webhook); err != nil {
+		h.notFoundOrError(c, err, "Failed to update webhook")
+		return
+	}
+
+	c.JSON(http.StatusOK, webhook)
+}
+
+// DeleteWebhookHandler removes a webhook
+func (h *WebhookHandlers) DeleteWebhookHandler(c *gin.Context) {
+	if err := h.repo.DeleteWebhook(c.Param("id")); err != nil {
+		h.notFoundOrError(c, err, "Failed to delete webhook")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// TestWebhookHandler sends a ping event to a webhook, enabled or not, and
+// returns whether it was accepted
+func (h *WebhookHandlers) TestWebhookHandler(c *gin.Context) {
+	webhook, err := h.repo.GetWebhook(c.Param("id"))
+	if err != nil {
+		h.notFoundOrError(c, err, "Failed to retrieve webhook")
+		return
+	}
+
+	if err := h.dispatcher.Test(c.Request.Context(), *webhook); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"delivered": false,
+			"error":     err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"delivered": true,
+	})
+}
+
+// notFoundOrError responds 404 for a missing webhook and 500 otherwise
+func (h *WebhookHandlers) notFoundOrError(c *gin.Context, err error, message string) {
+	if strings.Contains(err.Error(), "not found") {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Webhook not found",
+		})
+		return
+	}
+	h.logger.WithError(err).Error(message)
+	c.JSON(http.StatusInternalServerError, gin.H{
+		"error": message,
+	})
+}
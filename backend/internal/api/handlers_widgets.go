@@ -0,0 +1,253 @@
+package api
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/claude-session-manager/internal/database"
+	"github.com/sirupsen/logrus"
+)
+
+// Widget is a ready-to-render figure for simple dashboards: a value with its
+// display label, how it changed and, for lists, the items to show
+type Widget struct {
+	Widget    string       `json:"widget"`
+	Title     string       `json:"title"`
+	Value     float64      `json:"value"`
+	Label     string       `json:"label"` // the value formatted for display
+	Unit      string       `json:"unit"`
+	Delta     *WidgetDelta `json:"delta,omitempty"`
+	Items     []WidgetItem `json:"items,omitempty"`
+	UpdatedAt time.Time    `json:"updated_at"`
+}
+
+// WidgetDelta is the change in a value since an earlier period
+type WidgetDelta struct {
+	Value      float64  `json:"value"`
+	Percent    *float64 `json:"percent"` // nil when the earlier value was 0
+	Direction  string   `json:"direction"`
+	ComparedTo string   `json:"compared_to"`
+	Label      string   `json:"label"`
+}
+
+// WidgetItem is one row of a list widget
+type WidgetItem struct {
+	Label      string       `json:"label"`
+	Value      float64      `json:"value"`
+	ValueLabel string       `json:"value_label"`
+	Detail     string       `json:"detail,omitempty"`
+	Share      *float64     `json:"share,omitempty"` // fraction of the widget's value
+	Delta      *WidgetDelta `json:"delta,omitempty"`
+}
+
+// Delta directions
+const (
+	DirectionUp   = "up"
+	DirectionDown = "down"
+	DirectionFlat = "flat"
+)
+
+// WidgetHandlers serves pre-shaped figures to dashboards such as Grafana's
+// JSON datasource or Home Assistant, so they need no aggregation of their own
+type WidgetHandlers struct {
+	repo     *database.SessionRepository
+	currency string
+	logger   *logrus.Logger
+}
+
+// NewWidgetHandlers creates new widget handlers
+func NewWidgetHandlers(repo *database.SessionRepository, currency string, logger *logrus.Logger) *WidgetHandlers {
+	return &WidgetHandlers{
+		repo:     repo,
+		currency: currency,
+		logger:   logger,
+	}
+}
+
+// GetCostTodayHandler returns the cost of today's messages, in the server's
+// time zone, compared with yesterday's up to the same time of day
+func (h *WidgetHandlers) GetCostTodayHandler(c *gin.Context) {
+	now := time.Now()
+	dayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	yesterday := dayStart.AddDate(0, 0, -1)
+
+	today, err := h.repo.GetUsageBetween(dayStart, now)
+	if err != nil {
+		h.failed(c, err)
+		return
+	}
+	earlier, err := h.repo.GetUsageBetween(yesterday, yesterday.Add(now.Sub(dayStart)))
+	if err != nil {
+		h.failed(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, Widget{
+		Widget:    "cost-today",
+		Title:     "Cost today",
+		Value:     round2(today.CostUSD),
+		Label:     formatCurrency(today.CostUSD, h.currency),
+		Unit:      h.unit(),
+		Delta:     h.costDelta(today.CostUSD, earlier.CostUSD, "yesterday at this time"),
+		UpdatedAt: now.UTC(),
+	})
+}
+
+// GetActiveNowHandler returns the number of active sessions and lists them,
+// most recently active first. Past liveness isn't recorded, so it has no
+// delta.
+func (h *WidgetHandlers) GetActiveNowHandler(c *gin.Context) {
+	sessions, err := h.repo.GetActiveSessions()
+	if err != nil {
+		h.failed(c, err)
+		return
+	}
+
+	items := []WidgetItem{}
+	for _, session := range sessions {
+		items = append(items, WidgetItem{
+			Label:      session.ProjectName,
+			Value:      round2(session.TotalEstimatedCost),
+			ValueLabel: formatCurrency(session.TotalEstimatedCost, h.currency),
+			Detail:     fmt.Sprintf("%s · %d messages · last active %s", session.Model, session.MessageCount, session.LastActivity.UTC().Format(time.RFC3339)),
+		})
+	}
+
+	label := fmt.Sprintf("%d active", len(sessions))
+	if len(sessions) == 0 {
+		label = "Idle"
+	}
+	c.JSON(http.StatusOK, Widget{
+		Widget:    "active-now",
+		Title:     "Active now",
+		Value:     float64(len(sessions)),
+		Label:     label,
+		Unit:      "sessions",
+		Items:     items,
+		UpdatedAt: time.Now().UTC(),
+	})
+}
+
+// GetTopProjectsHandler returns the projects that cost the most over the last
+// days (default 7), up to limit (default 5, max 20), each compared with the
+// period before
+func (h *WidgetHandlers) GetTopProjectsHandler(c *gin.Context) {
+	days, err := strconv.Atoi(c.DefaultQuery("days", "7"))
+	if err != nil || days <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "days must be a positive integer",
+		})
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "5"))
+	if err != nil || limit <= 0 {
+		limit = 5
+	}
+	if limit > 20 {
+		limit = 20
+	}
+
+	now := time.Now()
+	from := now.AddDate(0, 0, -days)
+	current, err := h.repo.GetUsageByProjectBetween(from, now)
+	if err != nil {
+		h.failed(c, err)
+		return
+	}
+	previous, err := h.repo.GetUsageByProjectBetween(from.AddDate(0, 0, -days), from)
+	if err != nil {
+		h.failed(c, err)
+		return
+	}
+	previousCost := make(map[string]float64, len(previous))
+	var previousTotal float64
+	for _, project := range previous {
+		previousCost[project.Key] = project.CostUSD
+		previousTotal += project.CostUSD
+	}
+
+	var total float64
+	for _, project := range current {
+		total += project.CostUSD
+	}
+
+	comparedTo := fmt.Sprintf("the %d days before", days)
+	items := []WidgetItem{}
+	for _, project := range current {
+		if len(items) == limit {
+			break
+		}
+		item := WidgetItem{
+			Label:      project.Key,
+			Value:      round2(project.CostUSD),
+			ValueLabel: formatCurrency(project.CostUSD, h.currency),
+			Detail:     fmt.Sprintf("%d sessions · %s tokens", project.Sessions, formatTokenCount(project.Tokens)),
+			Delta:      h.costDelta(project.CostUSD, previousCost[project.Key], comparedTo),
+		}
+		if total > 0 {
+			share := math.Round(project.CostUSD/total*1000) / 1000
+			item.Share = &share
+		}
+		items = append(items, item)
+	}
+
+	c.JSON(http.StatusOK, Widget{
+		Widget:    "top-projects",
+		Title:     fmt.Sprintf("Top projects, last %d days", days),
+		Value:     round2(total),
+		Label:     formatCurrency(total, h.currency),
+		Unit:      h.unit(),
+		Delta:     h.costDelta(total, previousTotal, comparedTo),
+		Items:     items,
+		UpdatedAt: now.UTC(),
+	})
+}
+
+// costDelta describes the change from an earlier cost to the current one
+func (h *WidgetHandlers) costDelta(current, earlier float64, comparedTo string) *WidgetDelta {
+	change := round2(current - earlier)
+	delta := &WidgetDelta{
+		Value:      change,
+		Direction:  DirectionFlat,
+		ComparedTo: comparedTo,
+	}
+	sign := ""
+	switch {
+	case change > 0:
+		delta.Direction = DirectionUp
+		sign = "+"
+	case change < 0:
+		delta.Direction = DirectionDown
+		sign = "-"
+	}
+	delta.Label = fmt.Sprintf("%s%s vs %s", sign, formatCurrency(math.Abs(change), h.currency), comparedTo)
+	if earlier > 0 {
+		percent := math.Round((current-earlier)/earlier*1000) / 10
+		delta.Percent = &percent
+		delta.Label = fmt.Sprintf("%s (%s%.1f%%)", delta.Label, sign, math.Abs(percent))
+	}
+	return delta
+}
+
+// unit returns the currency costs are reported in
+func (h *WidgetHandlers) unit() string {
+	if h.currency == "" {
+		return "USD"
+	}
+	return h.currency
+}
+
+func (h *WidgetHandlers) failed(c *gin.Context, err error) {
+	h.logger.WithError(err).Error("Failed to get widget data")
+	c.JSON(http.StatusInternalServerError, gin.H{
+		"error": "Failed to retrieve widget data",
+	})
+}
+
+func round2(value float64) float64 {
+	return math.Round(value*100) / 100
+}
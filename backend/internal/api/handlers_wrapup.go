@@ -0,0 +1,146 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/claude-session-manager/internal/database"
+)
+
+// maxWrapUpFiles is how many touched files the text summary lists by name
+const maxWrapUpFiles = 5
+
+// SessionWrapUp is the one-shot summary shown when a session ends
+type SessionWrapUp struct {
+	SessionID       string    `json:"session_id"`
+	ProjectName     string    `json:"project_name"`
+	ProjectPath     string    `json:"project_path"`
+	Model           string    `json:"model"`
+	StartTime       time.Time `json:"start_time"`
+	EndTime         time.Time `json:"end_time"`
+	DurationSeconds int64     `json:"duration_seconds"`
+	Messages        int       `json:"messages"`
+	TotalTokens     int       `json:"total_tokens"`
+	TotalCost       float64   `json:"total_cost"`
+	FilesTouched    []string  `json:"files_touched"`
+}
+
+// newSessionWrapUp summarizes a session from its summary row
+func newSessionWrapUp(session *database.SessionSummary) *SessionWrapUp {
+	files, _ := session.GetFilesModifiedList()
+	if files == nil {
+		files = []string{}
+	}
+	return &SessionWrapUp{
+		SessionID:       session.ID,
+		ProjectName:     session.ProjectName,
+		ProjectPath:     session.ProjectPath,
+		Model:           session.Model,
+		StartTime:       session.StartTime,
+		EndTime:         session.LastActivity,
+		DurationSeconds: session.DurationSeconds,
+		Messages:        session.MessageCount,
+		TotalTokens:     session.TotalTokens,
+		TotalCost:       session.TotalEstimatedCost,
+		FilesTouched:    files,
+	}
+}
+
+// Text renders the summary as a few lines for a terminal
+func (w *SessionWrapUp) Text() string {
+	var b strings.Builder
+	id := w.SessionID
+	if len(id) > 8 {
+		id = id[:8]
+	}
+	fmt.Fprintf(&b, "Session %s · %s", id, w.ProjectName)
+	if w.Model != "" {
+		fmt.Fprintf(&b, " · %s", w.Model)
+	}
+	fmt.Fprintf(&b, "\n  %s · %d messages · %s tokens · $%.2f\n",
+		formatWrapUpDuration(time.Duration(w.DurationSeconds)*time.Second), w.Messages, formatWrapUpTokens(w.TotalTokens), w.TotalCost)
+
+	if len(w.FilesTouched) == 0 {
+		b.WriteString("  No files touched\n")
+		return b.String()
+	}
+	names := make([]string, 0, maxWrapUpFiles)
+	for i, file := range w.FilesTouched {
+		if i == maxWrapUpFiles {
+			names = append(names, fmt.Sprintf("and %d more", len(w.FilesTouched)-maxWrapUpFiles))
+			break
+		}
+		// Show files in the project relative to it
+		if rel, err := filepath.Rel(w.ProjectPath, file); err == nil && w.ProjectPath != "" && !strings.HasPrefix(rel, "..") {
+			file = rel
+		}
+		names = append(names, file)
+	}
+	fmt.Fprintf(&b, "  %d files touched: %s\n", len(w.FilesTouched), strings.Join(names, ", "))
+	return b.String()
+}
+
+func formatWrapUpDuration(d time.Duration) string {
+	d = d.Round(time.Second)
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm %ds", int(d.Minutes()), int(d.Seconds())%60)
+	default:
+		return fmt.Sprintf("%dh %dm", int(d.Hours()), int(d.Minutes())%60)
+	}
+}
+
+func formatWrapUpTokens(tokens int) string {
+	switch {
+	case tokens >= 1_000_000:
+		return fmt.Sprintf("%.1fM", float64(tokens)/1_000_000)
+	case tokens >= 1_000:
+		return fmt.Sprintf("%.1fk", float64(tokens)/1_000)
+	default:
+		return fmt.Sprintf("%d", tokens)
+	}
+}
+
+// GetSessionWrapUpHandler returns a one-shot summary of a session: duration,
+// messages, tokens, cost and files touched. format=text returns it as lines
+// for a terminal, which the hook script prints when a session ends.
+func (h *SQLiteHandlers) GetSessionWrapUpHandler(c *gin.Context) {
+	sessionID := c.Param("id")
+	text := c.Query("format") == "text"
+
+	session, err := h.repo.GetSessionByID(sessionID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			if text {
+				c.String(http.StatusNotFound, "Session %s not found\n", sessionID)
+				return
+			}
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Session not found",
+			})
+			return
+		}
+		h.logger.WithError(err).Error("Failed to get session")
+		if text {
+			c.String(http.StatusInternalServerError, "Session summary unavailable\n")
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve session",
+		})
+		return
+	}
+
+	wrapUp := newSessionWrapUp(session)
+	if text {
+		c.String(http.StatusOK, wrapUp.Text())
+		return
+	}
+	c.JSON(http.StatusOK, wrapUp)
+}
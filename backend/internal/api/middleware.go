@@ -2,11 +2,19 @@ package api
 
 import (
 	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/ksred/claude-session-manager/internal/auth"
 	"github.com/ksred/claude-session-manager/internal/config"
+	"github.com/ksred/claude-session-manager/internal/database"
+	"github.com/ksred/claude-session-manager/internal/locale"
 	"github.com/sirupsen/logrus"
 )
 
@@ -66,12 +74,66 @@ func CORSMiddleware(cfg *config.Config) gin.HandlerFunc {
 	}
 }
 
-// LoggingMiddleware returns a middleware function that logs requests
+// Context keys under which LocaleMiddleware stores the request's formatter
+// and whether it asked for display strings
+const (
+	localeContextKey    = "locale_formatter"
+	formattedContextKey = "locale_formatted"
+)
+
+// LocaleMiddleware picks the locale each request's figures are described in,
+// from its Accept-Language header when the config allows and the configured
+// locale otherwise, and reports it in Content-Language. Display strings are
+// included as the request's formatted parameter says, or else as configured.
+func LocaleMiddleware(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tag := cfg.Display.Locale
+		if cfg.Display.UseAcceptLanguage {
+			tag = locale.Negotiate(c.GetHeader("Accept-Language"), tag)
+			c.Writer.Header().Add("Vary", "Accept-Language")
+		}
+		formatter := locale.NewFormatter(tag, cfg.Pricing.Currency)
+		c.Writer.Header().Set("Content-Language", formatter.Locale())
+		c.Set(localeContextKey, formatter)
+
+		formatted, err := strconv.ParseBool(c.Query("formatted"))
+		if err != nil {
+			formatted = cfg.Display.FormattedStrings
+		}
+		c.Set(formattedContextKey, formatted)
+		c.Next()
+	}
+}
+
+// requestFormatter returns the formatter LocaleMiddleware chose for the
+// request, or the default locale's when it didn't run
+func requestFormatter(c *gin.Context) *locale.Formatter {
+	if value, ok := c.Get(localeContextKey); ok {
+		if formatter, ok := value.(*locale.Formatter); ok {
+			return formatter
+		}
+	}
+	return locale.NewFormatter(locale.DefaultLocale, "")
+}
+
+// wantsFormatted reports whether a response should include display strings.
+// Without LocaleMiddleware only formatted=true asks for them.
+func wantsFormatted(c *gin.Context) bool {
+	if value, ok := c.Get(formattedContextKey); ok {
+		formatted, _ := value.(bool)
+		return formatted
+	}
+	formatted, _ := strconv.ParseBool(c.Query("formatted"))
+	return formatted
+}
+
+// LoggingMiddleware returns a middleware function that logs requests. API
+// keys sent as the api_key query parameter are redacted.
 func LoggingMiddleware(logger *logrus.Logger) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		start := time.Now()
 		path := c.Request.URL.Path
-		raw := c.Request.URL.RawQuery
+		raw := redactQuery(c.Request.URL.RawQuery)
 
 		// Process request
 		c.Next()
@@ -102,3 +164,140 @@ func LoggingMiddleware(logger *logrus.Logger) gin.HandlerFunc {
 		}
 	}
 }
+
+// redactQuery returns a raw query string with the value of any api_key
+// parameter replaced, so keys never reach the logs. A malformed query is
+// re-encoded from the pairs that could be parsed.
+func redactQuery(raw string) string {
+	if raw == "" {
+		return raw
+	}
+	values, err := url.ParseQuery(raw)
+	if err == nil && !values.Has("api_key") {
+		return raw
+	}
+	if values.Has("api_key") {
+		values.Set("api_key", "REDACTED")
+	}
+	return values.Encode()
+}
+
+// rateLimitIdle is how long a client's bucket is kept after its last request
+const rateLimitIdle = 10 * time.Minute
+
+// tokenBucket holds the requests a client has left
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// RateLimiter limits each client to a steady rate of requests with bursts,
+// using a token bucket per client
+type RateLimiter struct {
+	rate      float64
+	burst     float64
+	paths     []string
+	exempt    map[string]bool
+	now       func() time.Time
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastPrune time.Time
+}
+
+// NewRateLimiter creates a rate limiter from the server's rate_limit
+// settings. Requests for the exempt paths are never limited.
+func NewRateLimiter(cfg config.RateLimitConfig, exempt []string) *RateLimiter {
+	limiter := &RateLimiter{
+		rate:    cfg.RequestsPerSecond,
+		burst:   float64(cfg.Burst),
+		paths:   cfg.Paths,
+		exempt:  make(map[string]bool, len(exempt)),
+		now:     time.Now,
+		buckets: make(map[string]*tokenBucket),
+	}
+	if len(limiter.paths) == 0 {
+		limiter.paths = []string{"/api/v1"}
+	}
+	for _, path := range exempt {
+		limiter.exempt[path] = true
+	}
+	return limiter
+}
+
+// Allow takes a token from the client's bucket, reporting whether there was
+// one and, if not, how long until there is
+func (l *RateLimiter) Allow(client string) (bool, time.Duration) {
+	now := l.now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.lastPrune) > rateLimitIdle {
+		for key, bucket := range l.buckets {
+			if now.Sub(bucket.last) > rateLimitIdle {
+				delete(l.buckets, key)
+			}
+		}
+		l.lastPrune = now
+	}
+
+	bucket, ok := l.buckets[client]
+	if !ok {
+		bucket = &tokenBucket{tokens: l.burst, last: now}
+		l.buckets[client] = bucket
+	}
+	bucket.tokens = math.Min(l.burst, bucket.tokens+now.Sub(bucket.last).Seconds()*l.rate)
+	bucket.last = now
+
+	if bucket.tokens < 1 {
+		return false, time.Duration((1 - bucket.tokens) / l.rate * float64(time.Second))
+	}
+	bucket.tokens--
+	return true, 0
+}
+
+// limits reports whether requests for path are rate limited
+func (l *RateLimiter) limits(path string) bool {
+	if l.exempt[path] {
+		return false
+	}
+	for _, prefix := range l.paths {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// requestClient identifies who made a request: the API key the auth
+// middleware accepted, or else the IP address
+func requestClient(c *gin.Context) string {
+	if value, ok := c.Get(auth.ContextKey); ok {
+		if key, ok := value.(*database.APIKey); ok {
+			return "key:" + key.ID
+		}
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// Middleware rejects requests over a client's limit with 429 and a
+// Retry-After header. Clients are told apart by the API key the auth
+// middleware accepted, so it must run first when auth is enabled, and by IP
+// address otherwise.
+func (l *RateLimiter) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !l.limits(c.Request.URL.Path) {
+			c.Next()
+			return
+		}
+
+		allowed, wait := l.Allow(requestClient(c))
+		if !allowed {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+				"error": "Rate limit exceeded",
+			})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,116 @@
+package api
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/claude-session-manager/internal/config"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimiter_Allow(t *testing.T) {
+	limiter := NewRateLimiter(config.RateLimitConfig{RequestsPerSecond: 2, Burst: 3}, nil)
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	limiter.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		allowed, _ := limiter.Allow("a")
+		assert.True(t, allowed, "requests up to the burst are allowed")
+	}
+	allowed, wait := limiter.Allow("a")
+	assert.False(t, allowed)
+	assert.Equal(t, 500*time.Millisecond, wait)
+
+	allowed, _ = limiter.Allow("b")
+	assert.True(t, allowed, "clients have their own buckets")
+
+	now = now.Add(500 * time.Millisecond)
+	allowed, _ = limiter.Allow("a")
+	assert.True(t, allowed, "the bucket refills at the configured rate")
+}
+
+func TestRateLimiter_Middleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	limiter := NewRateLimiter(config.RateLimitConfig{RequestsPerSecond: 0.5, Burst: 1, Paths: []string{"/api/v1/search"}}, []string{"/api/v1/health"})
+
+	router := gin.New()
+	router.Use(limiter.Middleware())
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	router.GET("/api/v1/search", ok)
+	router.GET("/api/v1/sessions", ok)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	assert.Equal(t, http.StatusOK, get("/api/v1/search").Code)
+	w := get("/api/v1/search")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "2", w.Header().Get("Retry-After"))
+
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusOK, get("/api/v1/sessions").Code, "paths not listed aren't limited")
+	}
+}
+
+func TestLoggingMiddleware_RedactsAPIKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var logs bytes.Buffer
+	logger := logrus.New()
+	logger.SetOutput(&logs)
+
+	router := gin.New()
+	router.Use(LoggingMiddleware(logger))
+	router.GET("/api/v1/sessions", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	for _, path := range []string{
+		"/api/v1/sessions?limit=5&api_key=csm_secret",
+		"/api/v1/sessions?api_key=csm_secret&bad=%zz", // malformed, so not parsed cleanly
+		"/api/v1/missing?api_key=csm_secret",          // logged as a client error
+	} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	assert.NotContains(t, logs.String(), "csm_secret")
+	assert.Contains(t, logs.String(), "limit=5")
+	assert.Contains(t, logs.String(), "api_key=REDACTED")
+}
+
+func TestLocaleMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := config.DefaultConfig()
+	cfg.Display.Locale = "en-GB"
+	cfg.Pricing.Currency = "EUR"
+
+	router := gin.New()
+	router.Use(LocaleMiddleware(cfg))
+	router.GET("/cost", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"cost": requestFormatter(c).Cost(1234.5), "formatted": wantsFormatted(c)})
+	})
+
+	get := func(path, acceptLanguage string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Accept-Language", acceptLanguage)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("/cost", "de-DE,de;q=0.9,en;q=0.8")
+	assert.Equal(t, "de-DE", w.Header().Get("Content-Language"))
+	assert.JSONEq(t, `{"cost":"1.234,50\u00a0€","formatted":false}`, w.Body.String())
+
+	w = get("/cost?formatted=true", "en")
+	assert.Equal(t, "en-GB", w.Header().Get("Content-Language"), "the configured locale is kept for its own language")
+	assert.JSONEq(t, `{"cost":"€1,234.50","formatted":true}`, w.Body.String())
+
+	cfg.Display.UseAcceptLanguage = false
+	assert.Equal(t, "en-GB", get("/cost", "fr-FR").Header().Get("Content-Language"))
+}
@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/ksred/claude-session-manager/internal/claude"
+	"github.com/ksred/claude-session-manager/internal/locale"
 )
 
 // SessionResponse represents the API response for a session
@@ -44,14 +45,17 @@ type SessionsLimitResponse struct {
 // MetricsSummary represents overall metrics
 // @Description Overall system metrics and statistics
 type MetricsSummary struct {
-	TotalSessions          int            `json:"total_sessions" example:"150" description:"Total number of sessions"`
-	ActiveSessions         int            `json:"active_sessions" example:"5" description:"Currently active sessions"`
-	TotalMessages          int            `json:"total_messages" example:"2500" description:"Total messages across all sessions"`
-	TotalTokensUsed        int            `json:"total_tokens_used" example:"125000" description:"Total tokens consumed"`
-	TotalEstimatedCost     float64        `json:"total_estimated_cost" example:"15.75" description:"Estimated total cost in USD"`
-	AverageSessionDuration float64        `json:"average_session_duration_minutes" example:"45.2" description:"Average session duration in minutes"`
-	MostUsedModel          string         `json:"most_used_model" example:"claude-3-opus" description:"Most frequently used model"`
-	ModelUsage             map[string]int `json:"model_usage" description:"Usage count by model"`
+	TotalSessions          int               `json:"total_sessions" example:"150" description:"Total number of sessions"`
+	ActiveSessions         int               `json:"active_sessions" example:"5" description:"Currently active sessions"`
+	TotalMessages          int               `json:"total_messages" example:"2500" description:"Total messages across all sessions"`
+	TotalTokensUsed        int               `json:"total_tokens_used" example:"125000" description:"Total tokens consumed"`
+	TotalEstimatedCost     float64           `json:"total_estimated_cost" example:"15.75" description:"Estimated total cost in USD"`
+	AverageSessionDuration float64           `json:"average_session_duration_minutes" example:"45.2" description:"Average session duration in minutes"`
+	MostUsedModel          string            `json:"most_used_model" example:"claude-3-opus" description:"Most frequently used model"`
+	ModelUsage             map[string]int    `json:"model_usage" description:"Usage count by model"`
+	AsOf                   *time.Time        `json:"as_of,omitempty" description:"Point in time the summary was computed as of, when requested"`
+	Meta                   *locale.Metadata  `json:"meta,omitempty" description:"Currency, locale and units the figures are given in"`
+	Display                map[string]string `json:"display,omitempty" description:"Figures formatted for the locale, keyed by field name, when requested"`
 }
 
 // ActivityEntry represents a single activity in the timeline
@@ -189,11 +193,12 @@ type TokenBreakdown struct {
 // CostBreakdownEntry represents cost data for a group
 // @Description Cost breakdown for a specific group (project, model, or day)
 type CostBreakdownEntry struct {
-	Name       string         `json:"name" example:"my-app" description:"Name of the group (project, model, or date)"`
-	Cost       float64        `json:"cost" example:"45.30" description:"Total cost in USD"`
-	Tokens     TokenBreakdown `json:"tokens" description:"Token usage breakdown"`
-	Sessions   int            `json:"sessions" example:"23" description:"Number of sessions"`
-	Percentage float64        `json:"percentage" example:"0.36" description:"Percentage of total cost"`
+	Name       string            `json:"name" example:"my-app" description:"Name of the group (project, model, or date)"`
+	Cost       float64           `json:"cost" example:"45.30" description:"Total cost in USD"`
+	Tokens     TokenBreakdown    `json:"tokens" description:"Token usage breakdown"`
+	Sessions   int               `json:"sessions" example:"23" description:"Number of sessions"`
+	Percentage float64           `json:"percentage" example:"0.36" description:"Percentage of total cost"`
+	Display    map[string]string `json:"display,omitempty" description:"Figures formatted for the locale, keyed by field name, when requested"`
 }
 
 // CostProjection represents projected costs
@@ -279,7 +284,7 @@ type TokenTimelineEntry struct {
 type TokenTimelineResponse struct {
 	Timeline    []TokenTimelineEntry `json:"timeline" description:"List of timeline data points"`
 	Hours       int                  `json:"hours,omitempty" example:"24" description:"Number of hours included"`
-	Granularity string               `json:"granularity" example:"hour" description:"Time granularity (minute, hour, day)"`
+	Granularity string               `json:"granularity" example:"hour" description:"Time granularity (minute, hour, day, week, month or a window such as 15m)"`
 	Total       int                  `json:"total" example:"24" description:"Total number of data points"`
 	SessionID   string               `json:"session_id,omitempty" example:"session_123456" description:"Session ID (for session-specific timeline)"`
 	ProjectName string               `json:"project_name,omitempty" example:"my-app" description:"Project name (for project-specific timeline)"`
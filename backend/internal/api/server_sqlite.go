@@ -9,9 +9,36 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
+	"github.com/ksred/claude-session-manager/internal/auth"
+	"github.com/ksred/claude-session-manager/internal/budget"
 	"github.com/ksred/claude-session-manager/internal/chat"
 	"github.com/ksred/claude-session-manager/internal/config"
+	"github.com/ksred/claude-session-manager/internal/costcenter"
 	"github.com/ksred/claude-session-manager/internal/database"
+	"github.com/ksred/claude-session-manager/internal/environment"
+	"github.com/ksred/claude-session-manager/internal/exportjobs"
+	"github.com/ksred/claude-session-manager/internal/federation"
+	"github.com/ksred/claude-session-manager/internal/handoff"
+	"github.com/ksred/claude-session-manager/internal/integrity"
+	"github.com/ksred/claude-session-manager/internal/knowledge"
+	"github.com/ksred/claude-session-manager/internal/lifecycle"
+	"github.com/ksred/claude-session-manager/internal/locale"
+	"github.com/ksred/claude-session-manager/internal/metrics"
+	"github.com/ksred/claude-session-manager/internal/monthclose"
+	"github.com/ksred/claude-session-manager/internal/mqtt"
+	"github.com/ksred/claude-session-manager/internal/notifications"
+	"github.com/ksred/claude-session-manager/internal/similarity"
+	"github.com/ksred/claude-session-manager/internal/tagging"
+	"github.com/ksred/claude-session-manager/internal/telemetry"
+	"github.com/ksred/claude-session-manager/internal/webhooks"
+	"github.com/ksred/claude-session-manager/internal/playbook"
+	"github.com/ksred/claude-session-manager/internal/plugin"
+	"github.com/ksred/claude-session-manager/internal/pricing"
+	"github.com/ksred/claude-session-manager/internal/quota"
+	"github.com/ksred/claude-session-manager/internal/reports"
+	"github.com/ksred/claude-session-manager/internal/retention"
+	"github.com/ksred/claude-session-manager/internal/rpc"
+	"github.com/ksred/claude-session-manager/internal/scripting"
 	"github.com/sirupsen/logrus"
 )
 
@@ -34,6 +61,20 @@ func (a *SessionRepositoryAdapter) GetSessionByID(sessionID string) (*chat.Sessi
 	}, nil
 }
 
+// PromptTemplateAdapter adapts database.SessionRepository to chat.TemplateProvider
+type PromptTemplateAdapter struct {
+	sessionRepo *database.SessionRepository
+}
+
+// UsePromptTemplate records template usage and returns the template content
+func (a *PromptTemplateAdapter) UsePromptTemplate(templateID, sessionID string) (string, error) {
+	template, err := a.sessionRepo.RecordPromptTemplateUsage(templateID, sessionID)
+	if err != nil {
+		return "", err
+	}
+	return template.Content, nil
+}
+
 // SQLiteServer represents the API server using SQLite database
 type SQLiteServer struct {
 	config         *config.Config
@@ -45,11 +86,88 @@ type SQLiteServer struct {
 	fileWatcher    *database.ClaudeFileWatcher
 	sqliteHandlers *SQLiteHandlers
 	chatHandler    *chat.WebSocketChatHandler
+	playbooks      *PlaybookHandlers
+	federation     *FederationHandlers
+	knowledge      *KnowledgeHandlers
+	prompts        *PromptHandlers
+	promptDetector *similarity.Detector
+	integrity      *IntegrityHandlers
+	sealer         *integrity.Sealer
+	envCapturer    *environment.Capturer
+	tags           *TagHandlers
+	tagger         *tagging.Tagger
+	scripts        *ScriptHandlers
+	scriptEngine   *scripting.Engine
+	costCenters    *CostCenterHandlers
+	snapshots      *SnapshotHandlers
+	hooks          *HookHandlers
+	statusline     *StatuslineHandlers
+	widgets        *WidgetHandlers
+	projects       *ProjectHandlers
+	grafana        *GrafanaHandlers
+	editor         *EditorHandlers
+	quickLook      *QuickLookHandlers
+	closer         *monthclose.Closer
+	budgets        *BudgetHandlers
+	rebuild        *RebuildHandlers
+	reports        *ReportHandlers
+	reportRunner   *reports.Scheduler
+	webhooks       *WebhookHandlers
+	dispatcher     *webhooks.Dispatcher
+	cliManager     *chat.CLIManager
+	chats          *ChatHandlers
+	notifications  *NotificationHandlers
+	notifier       *notifications.Notifier
+	handoff        *HandoffHandlers
+	dashboards     *DashboardHandlers
+	budgetMonitor  *budget.Monitor
+	quotas         *QuotaHandlers
+	quotaMonitor   *quota.Monitor
+	exportJobs     *ExportJobHandlers
+	exportRunner   *exportjobs.Runner
+	telemetry      *telemetry.Reporter
+	metrics        *metrics.Metrics
+	apiUsage       *APIUsageRecorder
+	plugins        *PluginHandlers
+	pluginManager  *plugin.Manager
 	ctx            context.Context
 	cancel         context.CancelFunc
 	httpServer     *http.Server
 }
 
+// DatabaseTuning converts the database section of the config to the
+// connection settings the database is opened with
+func DatabaseTuning(cfg config.DatabaseConfig) *database.Tuning {
+	return &database.Tuning{
+		BusyTimeout:        time.Duration(cfg.BusyTimeout) * time.Millisecond,
+		ReadConnections:    cfg.ReadConnections,
+		CacheSizeMB:        cfg.CacheSize,
+		MmapSizeMB:         cfg.MmapSize,
+		CheckpointInterval: time.Duration(cfg.CheckpointInterval) * time.Second,
+	}
+}
+
+// ChatLimits converts the chat section of the config to the limits the CLI
+// manager runs Claude CLI processes within
+func ChatLimits(cfg config.ChatConfig) chat.Limits {
+	return chat.Limits{
+		MaxProcesses:    cfg.MaxProcesses,
+		MaxQueued:       cfg.MaxQueued,
+		QueueTimeout:    time.Duration(cfg.QueueTimeout) * time.Second,
+		CommandTimeout:  time.Duration(cfg.CommandTimeout) * time.Second,
+		InactiveTimeout: time.Duration(cfg.InactiveTimeout) * time.Minute,
+	}
+}
+
+// ChatPermissionPolicy converts the chat section of the config to the
+// permissions chats started through the API may ask for
+func ChatPermissionPolicy(cfg config.ChatConfig) chat.PermissionPolicy {
+	return chat.PermissionPolicy{
+		Modes: cfg.PermissionModes,
+		Tools: cfg.AllowedTools,
+	}
+}
+
 // NewSQLiteServer creates a new API server instance using SQLite
 func NewSQLiteServer(cfg *config.Config) (*SQLiteServer, error) {
 	// Set Gin mode based on debug setting
@@ -62,11 +180,16 @@ func NewSQLiteServer(cfg *config.Config) (*SQLiteServer, error) {
 	router := gin.New()
 	logger := logrus.StandardLogger()
 
+	// Apply configured model prices before anything is imported and costed
+	pricing.Default().Configure(cfg.Pricing)
+
 	// Create database in the Claude directory
 	dbPath := filepath.Join(cfg.Claude.HomeDirectory, "sessions.db")
 	db, err := database.NewDatabase(database.Config{
 		DatabasePath: dbPath,
 		Logger:       logger,
+		MaxLineBytes: cfg.Claude.MaxLineSize * 1024 * 1024,
+		Tuning:       DatabaseTuning(cfg.Database),
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize database: %w", err)
@@ -75,10 +198,44 @@ func NewSQLiteServer(cfg *config.Config) (*SQLiteServer, error) {
 	// Create session repository
 	sessionRepo := database.NewSessionRepository(db, logger)
 
+	// Create tagger that applies the configured auto-tagging rules
+	tagger, err := tagging.NewTagger(sessionRepo, cfg.Tagging.Rules, logger)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to load tagging rules: %w", err)
+	}
+
+	// Create engine that runs the configured scripts on imported messages and
+	// sessions
+	scriptEngine, err := scripting.NewEngine(sessionRepo, cfg.Scripting, logger)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to load scripts: %w", err)
+	}
+	if scriptEngine.HasMessageScripts() {
+		sessionRepo.SetMessageHook(scriptEngine.ProcessMessage)
+	}
+
 	// Create WebSocket hub if enabled
 	var wsHub *WebSocketHub
 	if cfg.Features.EnableWebSocket {
 		wsHub = NewWebSocketHub(logger)
+		scriptEngine.SetNotifier(wsHub.BroadcastUpdate)
+		wsHub.SetTailSource(func(sessionID, afterID string, limit int) ([]database.Message, error) {
+			return sessionRepo.GetMessagesAfter(sessionID, afterID, time.Time{}, limit)
+		})
+	}
+
+	// Record Prometheus metrics if enabled, observing the database before
+	// anything else uses it
+	var serverMetrics *metrics.Metrics
+	if cfg.Features.EnableMetrics {
+		serverMetrics = metrics.New(logger)
+		serverMetrics.RegisterUsage(db)
+		db.SetObserver(serverMetrics)
+		if wsHub != nil {
+			serverMetrics.RegisterWebSocketClients(wsHub.ClientCount)
+		}
 	}
 
 	// Clean up any stuck import processes from previous runs
@@ -87,6 +244,20 @@ func NewSQLiteServer(cfg *config.Config) (*SQLiteServer, error) {
 		// Don't fail startup for this, just log the error
 	}
 
+	// Repair rows orphaned or duplicated since the last start, before anything
+	// imports
+	if findings, err := db.Fsck(true); err != nil {
+		logger.WithError(err).Error("Failed to repair orphaned rows")
+	} else {
+		for _, finding := range findings {
+			logger.WithFields(logrus.Fields{
+				"table":   finding.Table,
+				"problem": finding.Problem,
+				"rows":    finding.Rows,
+			}).Warn("Repaired orphaned or duplicate rows")
+		}
+	}
+
 	// Check for files modified while the server was down
 	missedFiles, err := db.CheckForMissedFiles(cfg.Claude.HomeDirectory)
 	if err != nil {
@@ -96,30 +267,105 @@ func NewSQLiteServer(cfg *config.Config) (*SQLiteServer, error) {
 		logger.WithField("missed_files", missedFiles).Info("Found files modified while server was down - will be processed during startup import")
 	}
 
+	// Create runner that generates heavy exports in the background, beside
+	// the database unless exports.directory is set
+	exportRunner, err := exportjobs.NewRunner(cfg.Exports, filepath.Join(filepath.Dir(dbPath), "exports"), sessionRepo, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create export runner: %w", err)
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 
+	// Create chat repository (Database embeds *sqlx.DB, so we pass db directly)
+	chatRepo := chat.NewRepositoryWithWriteOp(db.DB, db.WriteOperation)
+
+	// Create CLI manager, shared by interactive chat and playbook runs
+	cliManager := chat.NewCLIManagerWithLimits(chatRepo, &SessionRepositoryAdapter{sessionRepo: sessionRepo}, ChatLimits(cfg.Chat))
+	cliManager.SetPermissionPolicy(ChatPermissionPolicy(cfg.Chat))
+	if serverMetrics != nil {
+		serverMetrics.RegisterChatProcesses(cliManager.Stats)
+	}
+
 	// Create chat components if WebSocket is enabled
 	var chatHandler *chat.WebSocketChatHandler
 	if cfg.Features.EnableWebSocket && wsHub != nil {
-		// Create chat repository (Database embeds *sqlx.DB, so we pass db directly)
-		chatRepo := chat.NewRepositoryWithWriteOp(db.DB, db.WriteOperation)
-
-		// Create session repository for session data access
-		sessionRepo := database.NewSessionRepository(db, logger)
-
-		// Create session repository adapter for CLI manager
-		sessionRepoAdapter := &SessionRepositoryAdapter{sessionRepo: sessionRepo}
-
-		// Create CLI manager
-		cliManager := chat.NewCLIManager(chatRepo, sessionRepoAdapter)
-
 		// Create chat handler
 		chatHandler = chat.NewWebSocketChatHandler(cliManager, chatRepo, logger)
+		chatHandler.SetTemplateProvider(&PromptTemplateAdapter{sessionRepo: sessionRepo})
 
 		// Set the chat handler on the WebSocket hub
 		wsHub.ChatHandler = chatHandler
 	}
 
+	// Create playbook runner for scripted multi-turn runs
+	playbookRunner := playbook.NewRunner(sessionRepo, cliManager, logger, time.Duration(cfg.Playbooks.StepTimeout)*time.Second)
+	playbookScheduler := playbook.NewScheduler(sessionRepo, playbookRunner, cfg.Playbooks.Directory, logger)
+
+	// Create federation poller when this instance is an org-wide rollup parent
+	var federationPoller *federation.Poller
+	if cfg.Federation.Enabled {
+		federationPoller = federation.NewPoller(sessionRepo, cfg.Federation, logger)
+	}
+
+	// Create knowledge extractor for recurring Q&A and decisions in transcripts
+	knowledgeExtractor := knowledge.NewExtractor(sessionRepo, logger)
+
+	// Create detector for opening prompts that repeat past sessions
+	promptDetector := similarity.NewDetector(sessionRepo, logger)
+
+	// Create sealer that hash-chains imported messages for tamper evidence
+	sealer := integrity.NewSealer(sessionRepo, logger)
+
+	// Create capturer for the OS each session ran on
+	envCapturer := environment.NewCapturer(sessionRepo, logger)
+
+	// Create closer that freezes each month's aggregates once it has ended
+	allocator := costcenter.NewAllocator(cfg.CostCenters)
+	closer := monthclose.NewCloser(sessionRepo, allocator, cfg.Pricing.Currency, logger)
+
+	// Create monitor that alerts when spend crosses a budget's thresholds
+	budgetMonitor := budget.NewMonitor(sessionRepo, logger)
+	if wsHub != nil {
+		budgetMonitor.OnAlert(func(alert budget.Alert) {
+			wsHub.BroadcastUpdate("budget_alert", alert)
+		})
+	}
+
+	// Create monitor that warns when a user nears their monthly quota
+	quotaMonitor := quota.NewMonitor(sessionRepo, logger)
+	if wsHub != nil {
+		quotaMonitor.OnAlert(func(alert quota.Alert) {
+			wsHub.BroadcastUpdate("quota_warning", alert)
+		})
+	}
+
+	// Create scheduler for the configured summary reports
+	reportRunner := reports.NewScheduler(sessionRepo, cfg.Reports, locale.NewFormatter(cfg.Display.Locale, cfg.Pricing.Currency), logger)
+
+	// Create manager for subprocess plugins, which read session data through
+	// the same methods the rpc command serves
+	pluginHost := rpc.NewServer(sessionRepo, rpc.Options{
+		DailyBudget: cfg.Statusline.DailyBudget,
+		Currency:    cfg.Pricing.Currency,
+	}, logger)
+	pluginManager := plugin.NewManager(ctx, cfg.Plugins, pluginHost, logger)
+
+	// Create dispatcher that posts session, budget and error events to the
+	// configured webhooks; like plugins, it is told of every broadcast update
+	dispatcher := webhooks.NewDispatcher(sessionRepo, logger)
+
+	// Create notifier that posts idle sessions, the day's cost and errors to
+	// Slack and Discord, and to the channels users choose
+	notifier := notifications.NewNotifier(cfg.Notifications, sessionRepo, logger)
+	notifier.SetSMTP(cfg.Reports.SMTP)
+	if wsHub != nil {
+		wsHub.SetNotifier(func(updateType string, data interface{}) {
+			pluginManager.Notify(updateType, data)
+			dispatcher.Notify(updateType, data)
+			notifier.Notify(updateType, data)
+		})
+	}
+
 	server := &SQLiteServer{
 		config:         cfg,
 		router:         router,
@@ -129,9 +375,51 @@ func NewSQLiteServer(cfg *config.Config) (*SQLiteServer, error) {
 		sessionRepo:    sessionRepo,
 		sqliteHandlers: NewSQLiteHandlers(sessionRepo, logger),
 		chatHandler:    chatHandler,
+		playbooks:      NewPlaybookHandlers(ctx, sessionRepo, playbookRunner, playbookScheduler, cfg.Playbooks.Directory, logger),
+		federation:     NewFederationHandlers(sessionRepo, federationPoller, cfg.Federation.Days, logger),
+		knowledge:      NewKnowledgeHandlers(ctx, sessionRepo, knowledgeExtractor, logger),
+		prompts:        NewPromptHandlers(promptDetector, logger),
+		promptDetector: promptDetector,
+		integrity:      NewIntegrityHandlers(sealer, logger),
+		sealer:         sealer,
+		envCapturer:    envCapturer,
+		tags:           NewTagHandlers(sessionRepo, tagger, logger),
+		tagger:         tagger,
+		scripts:        NewScriptHandlers(sessionRepo, scriptEngine, logger),
+		scriptEngine:   scriptEngine,
+		costCenters:    NewCostCenterHandlers(sessionRepo, allocator, cfg.Pricing.Currency, logger),
+		snapshots:      NewSnapshotHandlers(sessionRepo, closer, logger),
+		hooks:          NewHookHandlers(sessionRepo, wsHub, logger),
+		statusline:     NewStatuslineHandlers(sessionRepo, cfg.Statusline.DailyBudget, cfg.Pricing.Currency, logger),
+		widgets:        NewWidgetHandlers(sessionRepo, cfg.Pricing.Currency, logger),
+		projects:       NewProjectHandlers(sessionRepo, logger),
+		grafana:        NewGrafanaHandlers(sessionRepo, logger),
+		editor:         NewEditorHandlers(sessionRepo, time.Duration(cfg.Server.WriteTimeout)*time.Second, logger),
+		quickLook:      NewQuickLookHandlers(sessionRepo, cfg.Launcher.DashboardURL, logger),
+		closer:         closer,
+		budgets:        NewBudgetHandlers(sessionRepo, budgetMonitor, logger),
+		budgetMonitor:  budgetMonitor,
+		quotas:         NewQuotaHandlers(sessionRepo, quotaMonitor, logger),
+		quotaMonitor:   quotaMonitor,
+		exportJobs:     NewExportJobHandlers(sessionRepo, exportRunner, logger),
+		exportRunner:   exportRunner,
+		reports:        NewReportHandlers(reportRunner, logger),
+		reportRunner:   reportRunner,
+		webhooks:       NewWebhookHandlers(sessionRepo, dispatcher, logger),
+		dispatcher:     dispatcher,
+		cliManager:     cliManager,
+		notifications:  NewNotificationHandlers(sessionRepo, notifier, logger),
+		chats:          NewChatHandlers(sessionRepo, chatRepo, cliManager, logger),
+		dashboards:     NewDashboardHandlers(sessionRepo, router, logger),
+		handoff:        NewHandoffHandlers(handoff.New(sessionRepo, chatRepo, database.NewImporterWithContext(ctx, sessionRepo, logger), cfg.Claude.HomeDirectory, logger), logger),
+		notifier:       notifier,
+		plugins:        NewPluginHandlers(sessionRepo, pluginManager, logger),
+		pluginManager:  pluginManager,
+		metrics:        serverMetrics,
 		ctx:            ctx,
 		cancel:         cancel,
 	}
+	server.rebuild = NewRebuildHandlers(ctx, sessionRepo, db, cfg.Claude.HomeDirectory, server.newUpdateAdapter(), logger)
 
 	// Start WebSocket hub if enabled
 	if server.wsHub != nil {
@@ -159,6 +447,124 @@ func NewSQLiteServer(cfg *config.Config) (*SQLiteServer, error) {
 			server.wsHub.Run(ctx)
 			logger.Info("WebSocket hub goroutine exited")
 		}()
+
+		// Send live cost tickers their sessions' token totals periodically
+		if cfg.Features.WebSocketTotalsInterval > 0 {
+			server.wsHub.SetTokenTotalsSource(sessionRepo.GetSessionByID)
+			go func() {
+				logger.Info("Token totals goroutine started")
+				server.wsHub.RunTokenTotals(ctx, time.Duration(cfg.Features.WebSocketTotalsInterval)*time.Second)
+				logger.Info("Token totals goroutine exited")
+			}()
+		}
+	}
+
+	// Start the playbook scheduler if enabled
+	if cfg.Playbooks.EnableScheduler {
+		go func() {
+			logger.Info("Playbook scheduler goroutine started")
+			playbookScheduler.Start(ctx)
+			logger.Info("Playbook scheduler goroutine exited")
+		}()
+	}
+
+	// Start polling federated instances if enabled
+	if federationPoller != nil {
+		go func() {
+			logger.Info("Federation poller goroutine started")
+			federationPoller.Start(ctx)
+			logger.Info("Federation poller goroutine exited")
+		}()
+	}
+
+	// Keep base prices in step with the remote price list if one is configured
+	if cfg.Pricing.RemoteURL != "" {
+		fetcher := pricing.NewFetcher(pricing.Default(), cfg.Pricing.RemoteURL, time.Duration(cfg.Pricing.RemoteRefresh)*time.Hour, logger)
+		go func() {
+			logger.Info("Pricing fetcher goroutine started")
+			fetcher.Start(ctx)
+			logger.Info("Pricing fetcher goroutine exited")
+		}()
+	}
+
+	// Record how often each route is called, for /admin/api-usage
+	if cfg.Features.EnableAPIUsage {
+		server.apiUsage = NewAPIUsageRecorder(sessionRepo, logger)
+		go func() {
+			logger.Info("API usage recorder goroutine started")
+			server.apiUsage.Start(ctx)
+			logger.Info("API usage recorder goroutine exited")
+		}()
+	}
+
+	// Send anonymous usage reports only if the user opted in
+	if reason := telemetry.Disabled(cfg.Telemetry); reason == "" {
+		server.telemetry = telemetry.NewReporter(cfg.Telemetry, dbPath, logger)
+		logger.WithField("endpoint", cfg.Telemetry.Endpoint).Info("Anonymous usage telemetry enabled")
+		go func() {
+			logger.Info("Telemetry reporter goroutine started")
+			server.telemetry.Start(ctx)
+			logger.Info("Telemetry reporter goroutine exited")
+		}()
+	} else {
+		logger.WithField("reason", reason).Debug("Telemetry disabled")
+	}
+
+	// Send the configured summary reports when each is due
+	if len(cfg.Reports.Schedules) > 0 {
+		logger.WithField("reports", len(cfg.Reports.Schedules)).Info("Scheduled reports enabled")
+		go func() {
+			logger.Info("Report scheduler goroutine started")
+			server.reportRunner.Start(ctx)
+			logger.Info("Report scheduler goroutine exited")
+		}()
+	}
+
+	// Post events to the configured webhooks
+	go func() {
+		logger.Info("Webhook dispatcher goroutine started")
+		server.dispatcher.Start(ctx)
+		logger.Info("Webhook dispatcher goroutine exited")
+	}()
+
+	// Post to Slack and Discord, and to users' channels; the interval is
+	// only required to be set when a webhook is configured
+	if cfg.Notifications.Interval > 0 {
+		go func() {
+			logger.Info("Notification goroutine started")
+			server.notifier.Start(ctx)
+			logger.Info("Notification goroutine exited")
+		}()
+	}
+
+	// Delete exports once they expire
+	go func() {
+		logger.Info("Export pruning goroutine started")
+		server.exportRunner.Start(ctx)
+		logger.Info("Export pruning goroutine exited")
+	}()
+
+	// Stop chat processes left unused past the inactive timeout
+	go func() {
+		logger.Info("Chat process reaper goroutine started")
+		server.cliManager.Start(ctx)
+		logger.Info("Chat process reaper goroutine exited")
+	}()
+
+	// Publish figures for home dashboards if an MQTT broker is configured
+	if cfg.MQTT.Enabled {
+		publisher := mqtt.NewPublisher(cfg.MQTT, sessionRepo, budgetMonitor, logger)
+		logger.WithField("broker", cfg.MQTT.Broker).Info("MQTT publisher enabled")
+		go func() {
+			logger.Info("MQTT publisher goroutine started")
+			publisher.Start(ctx)
+			logger.Info("MQTT publisher goroutine exited")
+		}()
+	}
+
+	// Start configured plugins
+	if len(cfg.Plugins) > 0 {
+		go pluginManager.Start()
 	}
 
 	// Create completion channel for import process
@@ -181,6 +587,54 @@ func NewSQLiteServer(cfg *config.Config) (*SQLiteServer, error) {
 		logger.Info("Import goroutine exited")
 	}()
 
+	// Keep session quality scores, extracted knowledge, prompt signatures and
+	// message hash chains current once the initial import has finished
+	go func() {
+		select {
+		case <-ctx.Done():
+			return
+		case <-importDone:
+		}
+		server.refreshDerivedData(ctx, knowledgeExtractor)
+	}()
+
+	// Delete sessions past the retention period if a policy is enabled,
+	// starting once the initial import has finished
+	if cfg.Retention.Enabled {
+		janitor := retention.NewJanitor(sessionRepo, cfg.Retention, logger)
+		go func() {
+			select {
+			case <-ctx.Done():
+				return
+			case <-importDone:
+			}
+			logger.Info("Retention janitor goroutine started")
+			janitor.Start(ctx)
+			logger.Info("Retention janitor goroutine exited")
+		}()
+	}
+
+	// Move sessions from active to idle to completed as they go quiet,
+	// starting once the initial import has set their statuses
+	if cfg.SessionStatus.Enabled {
+		statusManager := lifecycle.NewManager(sessionRepo, cfg.SessionStatus, logger)
+		if server.wsHub != nil {
+			statusManager.OnChange(func(change database.SessionStatusChange) {
+				server.wsHub.BroadcastUpdate("session_status", change)
+			})
+		}
+		go func() {
+			select {
+			case <-ctx.Done():
+				return
+			case <-importDone:
+			}
+			logger.Info("Session status goroutine started")
+			statusManager.Start(ctx)
+			logger.Info("Session status goroutine exited")
+		}()
+	}
+
 	// Setup file watcher if enabled - start it after import completes
 	if cfg.Features.EnableFileWatcher {
 		go func() {
@@ -240,6 +694,89 @@ func (s *SQLiteServer) Start() error {
 	return err
 }
 
+// refreshDerivedData seals new messages into their sessions' hash chains,
+// captures new sessions' environments, applies tagging rules, rescores
+// changed sessions and extracts their tool calls, extracts knowledge from
+// new messages, indexes new opening prompts, checks budgets and quotas and
+// closes ended months now and every few minutes until ctx is cancelled
+func (s *SQLiteServer) refreshDerivedData(ctx context.Context, extractor *knowledge.Extractor) {
+	ticker := time.NewTicker(5 * time.Minute)
+	defer ticker.Stop()
+
+	for {
+		if sealed, err := s.sealer.SealPending(1000); err != nil {
+			s.logger.WithError(err).Error("Failed to seal imported messages")
+		} else if sealed > 0 {
+			s.logger.WithField("messages", sealed).Debug("Sealed imported messages")
+		}
+
+		if captured, err := s.envCapturer.CapturePending(1000); err != nil {
+			s.logger.WithError(err).Error("Failed to capture session environments")
+		} else if captured > 0 {
+			s.logger.WithField("sessions", captured).Debug("Captured session environments")
+		}
+
+		if tagged, err := s.tagger.ApplyPending(1000); err != nil {
+			s.logger.WithError(err).Error("Failed to apply tagging rules")
+		} else if tagged > 0 {
+			s.logger.WithField("sessions", tagged).Debug("Applied tagging rules")
+		}
+
+		if evaluated, err := s.scriptEngine.ApplyPending(1000); err != nil {
+			s.logger.WithError(err).Error("Failed to run session scripts")
+		} else if evaluated > 0 {
+			s.logger.WithField("sessions", evaluated).Debug("Ran session scripts")
+		}
+
+		scored, err := s.sessionRepo.RefreshSessionQualityScores()
+		if err != nil {
+			s.logger.WithError(err).Error("Failed to refresh session quality scores")
+		} else if scored > 0 {
+			s.logger.WithField("sessions", scored).Debug("Refreshed session quality scores")
+		}
+
+		if scanned, err := s.sessionRepo.RefreshToolCalls(); err != nil {
+			s.logger.WithError(err).Error("Failed to extract tool calls")
+		} else if scanned > 0 {
+			s.logger.WithField("sessions", scanned).Debug("Extracted tool calls")
+		}
+
+		if _, err := extractor.Run(ctx, false); err != nil && ctx.Err() == nil {
+			s.logger.WithError(err).Error("Failed to extract knowledge from transcripts")
+		}
+
+		if indexed, err := s.promptDetector.IndexPending(1000); err != nil {
+			s.logger.WithError(err).Error("Failed to index opening prompts")
+		} else if indexed > 0 {
+			s.logger.WithField("sessions", indexed).Debug("Indexed opening prompts")
+		}
+
+		if alerts, err := s.budgetMonitor.Check(); err != nil {
+			s.logger.WithError(err).Error("Failed to check budgets")
+		} else if len(alerts) > 0 {
+			s.logger.WithField("alerts", len(alerts)).Debug("Raised budget alerts")
+		}
+
+		if alerts, err := s.quotaMonitor.Check(); err != nil {
+			s.logger.WithError(err).Error("Failed to check quotas")
+		} else if len(alerts) > 0 {
+			s.logger.WithField("alerts", len(alerts)).Debug("Raised quota warnings")
+		}
+
+		if closed, err := s.closer.ClosePending(); err != nil {
+			s.logger.WithError(err).Error("Failed to close ended months")
+		} else if closed > 0 {
+			s.logger.WithField("months", closed).Debug("Closed ended months")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
 // setupMiddleware configures all middleware
 func (s *SQLiteServer) setupMiddleware() {
 	// Recovery middleware
@@ -252,6 +789,40 @@ func (s *SQLiteServer) setupMiddleware() {
 
 	// Logging middleware
 	s.router.Use(LoggingMiddleware(s.logger))
+
+	// Locale the response figures are described in
+	s.router.Use(LocaleMiddleware(s.config))
+
+	// Count API calls for telemetry if enabled
+	if s.telemetry != nil {
+		s.router.Use(s.telemetry.Middleware())
+	}
+
+	// Count requests per route if enabled
+	if s.apiUsage != nil {
+		s.router.Use(s.apiUsage.Middleware())
+	}
+
+	// Time requests for Prometheus if enabled
+	if s.metrics != nil {
+		s.router.Use(s.metrics.Middleware())
+	}
+
+	// Require an API key if auth is enabled, after CORS so preflight requests
+	// are answered and after logging so rejections are logged
+	if s.config.Auth.Enabled {
+		authenticator := auth.NewAuthenticator(s.sessionRepo, []string{"/api/v1/health"}, s.logger)
+		authenticator.AllowQueries("/api/v1/grafana/search", "/api/v1/grafana/query")
+		authenticator.AllowSigned(exportjobs.DownloadPath + "/")
+		s.router.Use(authenticator.Middleware())
+		s.logger.Info("API key authentication enabled")
+	}
+
+	// Limit each client's request rate if enabled, after auth so clients
+	// are told apart by API key
+	if s.config.Server.RateLimit.Enabled {
+		s.router.Use(NewRateLimiter(s.config.Server.RateLimit, []string{"/api/v1/health"}).Middleware())
+	}
 }
 
 // setupRoutes configures all API routes using SQLite handlers
@@ -267,17 +838,155 @@ func (s *SQLiteServer) setupRoutes() {
 		{
 			sessions.GET("", s.sqliteHandlers.GetSessionsHandler)
 			sessions.GET("/:id", s.sqliteHandlers.GetSessionHandler)
+			sessions.DELETE("/:id", s.sqliteHandlers.DeleteSessionHandler)
+			sessions.PATCH("/:id/archive", s.sqliteHandlers.ArchiveSessionHandler)
+			sessions.PATCH("/:id/unarchive", s.sqliteHandlers.UnarchiveSessionHandler)
 			sessions.GET("/active", s.sqliteHandlers.GetActiveSessionsHandler)
 			sessions.GET("/recent", s.sqliteHandlers.GetRecentSessionsHandler)
 			sessions.GET("/:id/tokens/timeline", s.sqliteHandlers.GetSessionTokenTimelineHandler)
 			sessions.GET("/:id/activity", s.sqliteHandlers.GetSessionActivityHandler)
 			sessions.POST("/create", s.sqliteHandlers.CreateSessionHandler)
+			sessions.GET("/:id/score", s.sqliteHandlers.GetSessionScoreHandler)
+			sessions.GET("/:id/similar", s.prompts.GetSimilarSessionsHandler)
+			sessions.PUT("/:id/feedback", s.sqliteHandlers.SetSessionFeedbackHandler)
+			sessions.GET("/:id/review", s.sqliteHandlers.GetSessionReviewHandler)
+			sessions.PUT("/:id/review", s.sqliteHandlers.SetSessionReviewHandler)
+			sessions.POST("/:id/review/comments", s.sqliteHandlers.AddSessionReviewCommentHandler)
+			sessions.GET("/:id/legal-hold", s.sqliteHandlers.GetLegalHoldHandler)
+			sessions.PUT("/:id/legal-hold", s.sqliteHandlers.PlaceLegalHoldHandler)
+			sessions.DELETE("/:id/legal-hold", s.sqliteHandlers.ReleaseLegalHoldHandler)
+			sessions.GET("/:id/wrap-up", s.sqliteHandlers.GetSessionWrapUpHandler)
+			sessions.GET("/:id/export", s.sqliteHandlers.ExportSessionHandler)
+			sessions.GET("/:id/export/compliance", s.sqliteHandlers.ExportComplianceHandler)
+			sessions.GET("/:id/export/plugin/:plugin/:exporter", s.plugins.ExportSessionHandler)
+			sessions.GET("/:id/repro-bundle", s.sqliteHandlers.ExportReproBundleHandler)
+			sessions.GET("/:id/handoff", s.handoff.ExportHandoffHandler)
+			sessions.POST("/handoff", s.handoff.ImportHandoffHandler)
+			sessions.GET("/:id/integrity", s.integrity.VerifySessionHandler)
+			sessions.POST("/:id/integrity/verify", s.integrity.VerifyTranscriptHandler)
+			sessions.GET("/:id/environment", s.sqliteHandlers.GetSessionEnvironmentHandler)
+			sessions.PUT("/:id/environment", s.sqliteHandlers.ReportSessionEnvironmentHandler)
+			sessions.GET("/:id/tags", s.tags.GetSessionTagsHandler)
+			sessions.POST("/:id/tags", s.tags.AddSessionTagsHandler)
+			sessions.DELETE("/:id/tags", s.tags.RemoveSessionTagsHandler)
+			sessions.GET("/:id/notes", s.tags.GetSessionNotesHandler)
+			sessions.PUT("/:id/notes", s.tags.SetSessionNotesHandler)
+			sessions.GET("/:id/fields", s.scripts.GetSessionFieldsHandler)
+			sessions.GET("/:id/todos", s.sqliteHandlers.GetSessionTodosHandler)
+			sessions.GET("/:id/commands", s.sqliteHandlers.GetSessionCommandsHandler)
+			sessions.GET("/:id/tail", s.sqliteHandlers.GetSessionTailHandler)
+			sessions.PUT("/:id/owner", s.quotas.SetSessionOwnerHandler)
+			sessions.DELETE("/:id/owner", s.quotas.ClearSessionOwnerHandler)
+		}
+
+		// Shell commands Claude ran, across sessions
+		v1.GET("/commands/recent", s.sqliteHandlers.GetRecentCommandsHandler)
+
+		// Review queue for auditing sessions' changes
+		v1.GET("/reviews/queue", s.sqliteHandlers.GetReviewQueueHandler)
+
+		// Legal holds keep sessions from being pruned or archived
+		v1.GET("/legal-holds", s.sqliteHandlers.GetLegalHoldsHandler)
+
+		// Administration: what a retention run would prune or archive,
+		// which routes of this server are called most, splitting and
+		// merging sessions by hand, and re-importing every transcript
+		admin := v1.Group("/admin")
+		{
+			admin.GET("/prune/preview", s.sqliteHandlers.GetPrunePreviewHandler)
+			admin.GET("/api-usage", s.sqliteHandlers.GetAPIUsageHandler)
+			admin.POST("/sessions/:id/split", s.sqliteHandlers.SplitSessionHandler)
+			admin.POST("/sessions/:id/merge", s.sqliteHandlers.MergeSessionHandler)
+			admin.GET("/session-edits", s.sqliteHandlers.GetSessionEditsHandler)
+			admin.POST("/rebuild", s.rebuild.StartRebuildHandler)
+			admin.GET("/rebuild", s.rebuild.GetRebuildHandler)
+		}
+
+		// How many sessions share each OS, terminal and git remote, for filtering sessions
+		v1.GET("/environments", s.sqliteHandlers.GetEnvironmentCountsHandler)
+
+		// Transcript lines imports couldn't read as usual, e.g. over the line size limit
+		v1.GET("/import/dead-letters", s.sqliteHandlers.GetDeadLettersHandler)
+
+		// A resumed-session chain stitched into one transcript with cumulative cost
+		v1.GET("/threads/:rootSessionId", s.sqliteHandlers.GetThreadHandler)
+
+		// Daily and monthly cost limits, with alerts at 80% and 100%
+		v1.POST("/budgets", s.budgets.CreateBudgetHandler)
+		v1.GET("/budgets", s.budgets.GetBudgetsHandler)
+		v1.GET("/budgets/status", s.budgets.GetBudgetStatusHandler)
+		v1.DELETE("/budgets/:id", s.budgets.DeleteBudgetHandler)
+
+		// Soft monthly quotas per user, counted by session or project owner
+		v1.GET("/quotas", s.quotas.GetQuotasHandler)
+		v1.GET("/users/:user/quota", s.quotas.GetUserQuotaHandler)
+		v1.PUT("/users/:user/quota", s.quotas.SetUserQuotaHandler)
+		v1.DELETE("/users/:user/quota", s.quotas.DeleteUserQuotaHandler)
+
+		// Analytics endpoints registered by subprocess plugins
+		v1.GET("/plugins", s.plugins.GetPluginsHandler)
+		v1.GET("/plugins/:name/analytics/:analytic", s.plugins.RunAnalyticsHandler)
+
+		// Scripts from the scripting section of the config, with their metrics
+		v1.GET("/scripts", s.scripts.GetScriptsHandler)
+
+		// Model prices every cost is calculated from
+		v1.GET("/pricing", s.sqliteHandlers.GetPricingHandler)
+		v1.PUT("/pricing", s.sqliteHandlers.UpdatePricingHandler)
+
+		// Auto-tagging rules from the tagging section of the config
+		tagRules := v1.Group("/tag-rules")
+		{
+			tagRules.GET("", s.tags.GetTagRulesHandler)
+			tagRules.POST("/apply", s.tags.ApplyTagRulesHandler)
 		}
 
 		// Chat routes
 		chat := v1.Group("/chat")
 		{
 			chat.GET("/sessions/:sessionId/messages", s.sqliteHandlers.GetChatMessagesHandler)
+			chat.GET("/sessions/:sessionId/export", s.sqliteHandlers.ExportChatHandler)
+			chat.GET("/queue", s.chats.GetChatQueueHandler)
+			chat.POST("/:sessionId/messages", s.chats.SendChatMessageHandler)
+		}
+
+		// Prompt template routes
+		templates := v1.Group("/templates")
+		{
+			templates.GET("", s.sqliteHandlers.GetPromptTemplatesHandler)
+			templates.POST("", s.sqliteHandlers.CreatePromptTemplateHandler)
+			templates.GET("/stats", s.sqliteHandlers.GetPromptTemplateStatsHandler)
+			templates.GET("/:id", s.sqliteHandlers.GetPromptTemplateHandler)
+			templates.PUT("/:id", s.sqliteHandlers.UpdatePromptTemplateHandler)
+			templates.DELETE("/:id", s.sqliteHandlers.DeletePromptTemplateHandler)
+			templates.POST("/:id/use", s.sqliteHandlers.UsePromptTemplateHandler)
+		}
+
+		// Playbook routes
+		playbooks := v1.Group("/playbooks")
+		{
+			playbooks.GET("", s.playbooks.GetPlaybooksHandler)
+			playbooks.POST("/:name/run", s.playbooks.RunPlaybookHandler)
+		}
+
+		runs := v1.Group("/runs")
+		{
+			runs.GET("", s.playbooks.GetRunsHandler)
+			runs.GET("/:id", s.playbooks.GetRunHandler)
+			runs.POST("/:id/cancel", s.playbooks.CancelRunHandler)
+			runs.POST("/:id/approve", s.playbooks.ApproveRunHandler)
+			runs.POST("/:id/reject", s.playbooks.RejectRunHandler)
+		}
+
+		v1.GET("/approvals", s.playbooks.GetApprovalsHandler)
+
+		schedules := v1.Group("/schedules")
+		{
+			schedules.GET("", s.playbooks.GetSchedulesHandler)
+			schedules.POST("", s.playbooks.CreateScheduleHandler)
+			schedules.PUT("/:id", s.playbooks.UpdateScheduleHandler)
+			schedules.DELETE("/:id", s.playbooks.DeleteScheduleHandler)
+			schedules.POST("/:id/trigger", s.playbooks.TriggerScheduleHandler)
 		}
 
 		// Metrics routes using SQLite handlers
@@ -286,38 +995,191 @@ func (s *SQLiteServer) setupRoutes() {
 			metrics.GET("/summary", s.sqliteHandlers.GetMetricsSummaryHandler)
 			metrics.GET("/activity", s.sqliteHandlers.GetActivityHandler)
 			metrics.GET("/usage", s.sqliteHandlers.GetUsageStatsHandler)
+			metrics.GET("/versions", s.sqliteHandlers.GetVersionUsageHandler)
 		}
 
+		// Federation routes: every instance shares its aggregates, and a
+		// federation parent serves the org-wide rollup
+		v1.GET("/federation/summary", s.federation.GetFederationSummaryHandler)
+
+		org := v1.Group("/org")
+		{
+			org.GET("/summary", s.federation.GetOrgSummaryHandler)
+			org.GET("/instances", s.federation.GetOrgInstancesHandler)
+			org.POST("/refresh", s.federation.RefreshOrgHandler)
+		}
+
+		// Check a prompt against past sessions' opening prompts before sending it
+		v1.POST("/prompts/similar", s.prompts.CheckPromptHandler)
+
+		// Knowledge extracted from transcripts
+		knowledge := v1.Group("/knowledge")
+		{
+			knowledge.GET("", s.knowledge.SearchKnowledgeHandler)
+			knowledge.GET("/runs/latest", s.knowledge.GetLastExtractionRunHandler)
+			knowledge.POST("/extract", s.knowledge.ExtractKnowledgeHandler)
+			knowledge.GET("/:id", s.knowledge.GetKnowledgeEntryHandler)
+		}
+
+		// Export routes
+		export := v1.Group("/export")
+		{
+			export.GET("/anonymized", s.sqliteHandlers.ExportAnonymizedHandler)
+		}
+
+		// Backups and analytics datasets generated in the background, then
+		// downloaded through signed, expiring URLs that need no API key
+		exports := v1.Group("/exports")
+		{
+			exports.POST("", s.exportJobs.CreateExportJobHandler)
+			exports.GET("", s.exportJobs.GetExportJobsHandler)
+			exports.GET("/:id", s.exportJobs.GetExportJobHandler)
+		}
+		v1.GET("/downloads/exports/:id", s.exportJobs.DownloadExportHandler)
+
 		// Search routes using SQLite handlers
 		v1.GET("/search", s.sqliteHandlers.SearchHandler)
 
 		// Files routes
 		files := v1.Group("/files")
 		{
+			files.GET("", s.sqliteHandlers.GetFileChangesHandler)
 			files.GET("/recent", s.sqliteHandlers.GetRecentFilesHandler)
+			files.GET("/history", s.sqliteHandlers.GetFileHistoryHandler)
 		}
 
 		// Projects routes
 		projects := v1.Group("/projects")
 		{
+			projects.GET("", s.projects.GetProjectsHandler)
+			projects.POST("", s.projects.CreateProjectHandler)
+			projects.GET("/:projectName", s.projects.GetProjectHandler)
+			projects.PATCH("/:projectName", s.projects.UpdateProjectHandler)
+			projects.DELETE("/:projectName", s.projects.DeleteProjectHandler)
+			projects.POST("/:projectName/merge", s.projects.MergeProjectsHandler)
 			projects.GET("/:projectName/files/recent", s.sqliteHandlers.GetProjectRecentFilesHandler)
 			projects.GET("/:projectName/tokens/timeline", s.sqliteHandlers.GetProjectTokenTimelineHandler)
 			projects.GET("/:projectName/activity", s.sqliteHandlers.GetProjectActivityHandler)
+			projects.GET("/:projectName/summary", s.sqliteHandlers.GetProjectSummaryHandler)
 		}
 
 		// Analytics routes
 		analytics := v1.Group("/analytics")
 		{
 			analytics.GET("/tokens/timeline", s.sqliteHandlers.GetTokenTimelineHandler)
+			analytics.GET("/costs", s.sqliteHandlers.GetCostAnalyticsHandler)
+			analytics.GET("/costs/by-cost-center", s.costCenters.GetCostsByCostCenterHandler)
+			analytics.GET("/costs/per-line", s.sqliteHandlers.GetLineCostsHandler)
+			analytics.POST("/costs/simulate", s.sqliteHandlers.SimulateCostsHandler)
+			analytics.GET("/model-routing", s.sqliteHandlers.GetModelRoutingHandler)
+			analytics.GET("/forecast", s.sqliteHandlers.GetForecastHandler)
+			analytics.GET("/tools", s.sqliteHandlers.GetToolAnalyticsHandler)
+			analytics.GET("/export", s.sqliteHandlers.ExportAnalyticsHandler)
+		}
+
+		// Locale and units response figures are described in
+		v1.GET("/locale", s.sqliteHandlers.GetLocaleHandler)
+
+		// Todo lists and settings history from outside the project transcripts
+		v1.GET("/todos", s.sqliteHandlers.GetTodosHandler)
+		v1.GET("/settings/history", s.sqliteHandlers.GetSettingsHistoryHandler)
+
+		// One-line usage summary polled by Claude Code statusline scripts
+		v1.GET("/statusline", s.statusline.GetStatuslineHandler)
+
+		// Grafana SimpleJSON datasource, also usable from the Infinity datasource
+		grafana := v1.Group("/grafana")
+		{
+			grafana.GET("", s.grafana.TestConnectionHandler)
+			grafana.POST("/search", s.grafana.SearchHandler)
+			grafana.POST("/query", s.grafana.QueryHandler)
+		}
+
+		// Pre-shaped figures for simple dashboards such as Grafana and Home Assistant
+		widgets := v1.Group("/widgets")
+		{
+			widgets.GET("/cost-today", s.widgets.GetCostTodayHandler)
+			widgets.GET("/active-now", s.widgets.GetActiveNowHandler)
+			widgets.GET("/top-projects", s.widgets.GetTopProjectsHandler)
+		}
+
+		// Compact search with deep links for launchers such as Raycast and Alfred
+		v1.GET("/quicklook", s.quickLook.QuickLookHandler)
+
+		// Small, low-latency endpoints for editor extensions
+		editor := v1.Group("/editor")
+		{
+			editor.GET("/file-sessions", s.editor.GetFileSessionsHandler)
+			editor.GET("/workspace-cost", s.editor.GetWorkspaceCostHandler)
+			editor.GET("/status", s.editor.GetStatusHandler)
+		}
+
+		// Events POSTed by Claude Code hooks, and the script and settings that send them
+		hookRoutes := v1.Group("/hooks")
+		{
+			hookRoutes.POST("/events", s.hooks.ReceiveHookEventHandler)
+			hookRoutes.GET("/events", s.hooks.GetHookEventsHandler)
+			hookRoutes.GET("/script", s.hooks.GetHookScriptHandler)
+			hookRoutes.GET("/settings", s.hooks.GetHookSettingsHandler)
+		}
+
+		// Scheduled summary reports
+		v1.POST("/reports/run-now", s.reports.RunReportsNowHandler)
+
+		// Outgoing webhook routes
+		webhookRoutes := v1.Group("/webhooks")
+		{
+			webhookRoutes.GET("", s.webhooks.GetWebhooksHandler)
+			webhookRoutes.POST("", s.webhooks.CreateWebhookHandler)
+			webhookRoutes.GET("/:id", s.webhooks.GetWebhookHandler)
+			webhookRoutes.PUT("/:id", s.webhooks.UpdateWebhookHandler)
+			webhookRoutes.DELETE("/:id", s.webhooks.DeleteWebhookHandler)
+			webhookRoutes.POST("/:id/test", s.webhooks.TestWebhookHandler)
+		}
+
+		// Saved dashboards
+		dashboards := v1.Group("/dashboards")
+		{
+			dashboards.GET("", s.dashboards.GetDashboardsHandler)
+			dashboards.POST("", s.dashboards.CreateDashboardHandler)
+			dashboards.GET("/:id", s.dashboards.GetDashboardHandler)
+			dashboards.PUT("/:id", s.dashboards.UpdateDashboardHandler)
+			dashboards.DELETE("/:id", s.dashboards.DeleteDashboardHandler)
+		}
+
+		// Slack and Discord notifications, and each user's preferences
+		v1.POST("/notifications/test", s.notifications.TestNotificationHandler)
+		v1.GET("/notifications/preferences", s.notifications.GetNotificationPreferencesHandler)
+		users := v1.Group("/users/:user/notification-preferences")
+		{
+			users.GET("", s.notifications.GetUserNotificationPreferencesHandler)
+			users.PUT("", s.notifications.SetUserNotificationPreferencesHandler)
+			users.DELETE("", s.notifications.DeleteUserNotificationPreferencesHandler)
+			users.POST("/test", s.notifications.TestUserNotificationPreferencesHandler)
+		}
+
+		// Monthly close snapshots
+		snapshots := v1.Group("/snapshots/monthly")
+		{
+			snapshots.GET("", s.snapshots.GetMonthlySnapshotsHandler)
+			snapshots.GET("/:month", s.snapshots.GetMonthlySnapshotHandler)
+			snapshots.POST("/:month/close", s.snapshots.CloseMonthHandler)
 		}
 
 		// WebSocket endpoint for real-time updates
 		v1.GET("/ws", s.websocketHandler)
+		v1.GET("/ws/schema", s.GetWebSocketSchemaHandler)
+		v1.GET("/presence", s.presenceHandler)
 	}
 
 	// Static files (if needed)
 	s.router.Static("/static", "./static")
 
+	// Prometheus metrics, at the conventional path outside the API
+	if s.metrics != nil {
+		s.router.GET("/metrics", gin.WrapH(s.metrics.Handler()))
+	}
+
 	// Swagger documentation
 	// Note: You'll need to update the swagger imports if using this
 	// s.router.GET("/docs/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
@@ -343,6 +1205,23 @@ func (s *SQLiteServer) healthHandler(c *gin.Context) {
 	})
 }
 
+// presenceHandler returns the viewers who opted in to sharing what they are
+// looking at, optionally only those on one session
+func (s *SQLiteServer) presenceHandler(c *gin.Context) {
+	if s.wsHub == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": "WebSocket not enabled",
+		})
+		return
+	}
+
+	viewers := s.wsHub.presence.Viewers(c.Query("session_id"))
+	c.JSON(http.StatusOK, gin.H{
+		"viewers": viewers,
+		"total":   len(viewers),
+	})
+}
+
 // websocketHandler handles WebSocket connections
 func (s *SQLiteServer) websocketHandler(c *gin.Context) {
 	if s.wsHub == nil {
@@ -352,6 +1231,11 @@ func (s *SQLiteServer) websocketHandler(c *gin.Context) {
 		return
 	}
 
+	version, ok := websocketQueryVersion(c)
+	if !ok {
+		return
+	}
+
 	// Upgrade HTTP connection to WebSocket
 	upgrader := websocket.Upgrader{
 		ReadBufferSize:  1024,
@@ -375,6 +1259,7 @@ func (s *SQLiteServer) websocketHandler(c *gin.Context) {
 		Hub:    s.wsHub,
 		Logger: s.logger,
 	}
+	client.acceptVersion(version)
 
 	// Register client and start pumps
 	s.wsHub.register <- client
@@ -388,6 +1273,9 @@ func (s *SQLiteServer) importExistingData() error {
 
 	// Use incremental importer to avoid re-processing files
 	incrementalImporter := database.NewIncrementalImporter(s.ctx, s.sessionRepo, s.db, s.logger)
+	if s.wsHub != nil {
+		incrementalImporter.SetUpdateCallback(s.newUpdateAdapter())
+	}
 	if err := incrementalImporter.ImportClaudeDirectory(s.config.Claude.HomeDirectory, false); err != nil {
 		if err == context.Canceled {
 			s.logger.Info("Import cancelled by user")
@@ -400,6 +1288,12 @@ func (s *SQLiteServer) importExistingData() error {
 	return nil
 }
 
+// newUpdateAdapter creates the adapter that broadcasts import and watcher
+// updates over WebSocket
+func (s *SQLiteServer) newUpdateAdapter() *WebSocketUpdateAdapter {
+	return NewWebSocketUpdateAdapter(s.wsHub, s.sessionRepo, s.promptDetector, s.tagger, s.scriptEngine, s.budgetMonitor, s.logger)
+}
+
 // setupFileWatcher initializes the file system watcher for session files
 func (s *SQLiteServer) setupFileWatcher() error {
 	var err error
@@ -412,10 +1306,11 @@ func (s *SQLiteServer) setupFileWatcher() error {
 		return fmt.Errorf("failed to create file watcher: %w", err)
 	}
 
+	s.fileWatcher.SetWatchMode(s.config.Claude.WatchMode, time.Duration(s.config.Claude.WatchInterval)*time.Second)
+
 	// Set up WebSocket update callback if WebSocket is enabled
 	if s.wsHub != nil {
-		wsAdapter := NewWebSocketUpdateAdapter(s.wsHub, s.sessionRepo, s.logger)
-		s.fileWatcher.SetUpdateCallback(wsAdapter)
+		s.fileWatcher.SetUpdateCallback(s.newUpdateAdapter())
 		s.logger.Info("WebSocket update adapter connected to file watcher")
 	}
 
@@ -453,6 +1348,17 @@ func (s *SQLiteServer) Stop() error {
 
 	// Cancel context to stop background processes
 	s.logger.Info("Step 2/5: Cancelling background contexts...")
+	if s.pluginManager != nil {
+		// Let plugins shut down before the context kills them
+		s.pluginManager.Close()
+	}
+	if s.cliManager != nil {
+		// Stop chat processes and wait for their message handlers, which
+		// write to the database closed below
+		if err := s.cliManager.Shutdown(shutdownCtx); err != nil {
+			s.logger.WithError(err).Warn("Chat processes did not stop in time")
+		}
+	}
 	if s.cancel != nil {
 		s.cancel()
 		s.logger.Info("Context cancelled - background goroutines should stop")
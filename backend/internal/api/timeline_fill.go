@@ -0,0 +1,65 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/claude-session-manager/internal/database"
+)
+
+// fillTimeline applies the fill query parameter to a timeline covering
+// [from, to). With fill=zero every bucket in the range is returned and empty
+// ones are zero; with fill=null empty buckets have null figures. Without fill
+// the timeline is returned as it is. It responds with 400 and returns false
+// if fill is invalid or the range holds too many buckets.
+func fillTimeline(c *gin.Context, timeline []database.TokenTimelineEntry, from, to time.Time, granularity string) (interface{}, int, bool) {
+	fill := c.Query("fill")
+	if fill == "" {
+		return timeline, len(timeline), true
+	}
+	if fill != "zero" && fill != "null" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "fill must be zero or null",
+		})
+		return nil, 0, false
+	}
+
+	filled, err := database.FillTimeline(timeline, from, to, granularity, granularity)
+	if err != nil {
+		if errors.Is(err, database.ErrTooManyTimelineBuckets) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error() + "; use a coarser granularity or a shorter range",
+			})
+			return nil, 0, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to fill timeline",
+		})
+		return nil, 0, false
+	}
+	if fill == "zero" {
+		return filled, len(filled), true
+	}
+
+	// Every bucket the queries return has at least one message
+	entries := make([]interface{}, len(filled))
+	for i, entry := range filled {
+		if entry.MessageCount > 0 {
+			entries[i] = entry
+			continue
+		}
+		entries[i] = gin.H{
+			"timestamp":             entry.Timestamp,
+			"input_tokens":          nil,
+			"output_tokens":         nil,
+			"cache_creation_tokens": nil,
+			"cache_read_tokens":     nil,
+			"total_tokens":          nil,
+			"estimated_cost":        nil,
+			"message_count":         nil,
+		}
+	}
+	return entries, len(entries), true
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"sync/atomic"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -29,17 +30,50 @@ type WebSocketClient struct {
 	Send   chan []byte
 	Hub    *WebSocketHub
 	Logger *logrus.Logger
+
+	version atomic.Int32 // negotiated contract version, 0 until negotiated
+}
+
+// Version returns the contract version the client is served, the oldest
+// supported until it negotiates one
+func (c *WebSocketClient) Version() int {
+	if v := int(c.version.Load()); v != 0 {
+		return v
+	}
+	return MinWebSocketContractVersion
 }
 
 // WebSocketHub maintains active WebSocket connections
 type WebSocketHub struct {
 	clients     map[*WebSocketClient]bool
-	broadcast   chan []byte
+	broadcast   chan outgoingMessage
 	register    chan *WebSocketClient
 	unregister  chan *WebSocketClient
 	logger      *logrus.Logger
 	ChatHandler ChatMessageHandler
 	batcher     *EventBatcher
+	presence    *PresenceTracker
+	follows     *FollowTracker
+	tail        TailSource
+	tokens      *TokenAccumulator
+	tokenTotals TokenTotalsSource
+	direct      chan directMessage
+	notifier    func(updateType string, data interface{})
+	clientCount atomic.Int64 // len(clients), readable outside Run
+}
+
+// outgoingMessage is a broadcast encoded for the clients on one contract
+// version
+type outgoingMessage struct {
+	version int
+	data    []byte
+}
+
+// directMessage is a message for one client, sent through Run so it is
+// dropped if the client has gone
+type directMessage struct {
+	client *WebSocketClient
+	data   []byte
 }
 
 // ChatMessageHandler interface for handling chat messages
@@ -51,10 +85,14 @@ type ChatMessageHandler interface {
 func NewWebSocketHub(logger *logrus.Logger) *WebSocketHub {
 	return &WebSocketHub{
 		clients:    make(map[*WebSocketClient]bool),
-		broadcast:  make(chan []byte),
+		broadcast:  make(chan outgoingMessage),
 		register:   make(chan *WebSocketClient),
 		unregister: make(chan *WebSocketClient),
 		logger:     logger,
+		presence:   NewPresenceTracker(),
+		follows:    NewFollowTracker(),
+		tokens:     NewTokenAccumulator(),
+		direct:     make(chan directMessage),
 	}
 }
 
@@ -63,6 +101,12 @@ func (h *WebSocketHub) SetBatcher(batcher *EventBatcher) {
 	h.batcher = batcher
 }
 
+// SetNotifier sets a function called with every update broadcast, before
+// batching, such as plugin notifiers
+func (h *WebSocketHub) SetNotifier(fn func(updateType string, data interface{})) {
+	h.notifier = fn
+}
+
 // Run starts the WebSocket hub
 func (h *WebSocketHub) Run(ctx context.Context) {
 	h.logger.Info("WebSocket hub Run() started")
@@ -91,17 +135,33 @@ func (h *WebSocketHub) Run(ctx context.Context) {
 				h.logger.WithField("client_id", client.ID).Info("WebSocket client disconnected")
 			}
 
+		case message := <-h.direct:
+			if _, ok := h.clients[message.client]; !ok {
+				break
+			}
+			select {
+			case message.client.Send <- message.data:
+			default:
+				h.logger.WithField("client_id", message.client.ID).Debug("Failed to send to client (buffer full), closing connection")
+				close(message.client.Send)
+				delete(h.clients, message.client)
+			}
+
 		case message := <-h.broadcast:
 			h.logger.WithFields(logrus.Fields{
-				"message_size": len(message),
+				"message_size": len(message.data),
+				"version":      message.version,
 				"clients":      len(h.clients),
 			}).Debug("Hub received message to broadcast")
 
 			sentCount := 0
 			failedCount := 0
 			for client := range h.clients {
+				if client.Version() != message.version {
+					continue
+				}
 				select {
-				case client.Send <- message:
+				case client.Send <- message.data:
 					sentCount++
 					h.logger.WithFields(logrus.Fields{
 						"client_id": client.ID,
@@ -122,16 +182,24 @@ func (h *WebSocketHub) Run(ctx context.Context) {
 				"total_clients": len(h.clients),
 			}).Debug("Finished broadcasting message")
 		}
+		h.clientCount.Store(int64(len(h.clients)))
 	}
 }
 
-// BroadcastUpdate sends an update to all connected clients
-// Supported update types:
-// - "sessions_updated": Full sessions list has been updated
-// - "session_new": A new session was created
-// - "session_update": An existing session was modified
-// - "session_deleted": A session was deleted
+// ClientCount returns how many clients are connected
+func (h *WebSocketHub) ClientCount() int {
+	return int(h.clientCount.Load())
+}
+
+// BroadcastUpdate sends an update to all connected clients. The update types
+// and their payloads are listed in websocketServerEvents; each client is sent
+// the update encoded for its contract version, and not at all if the type was
+// added in a later version.
 func (h *WebSocketHub) BroadcastUpdate(updateType string, data interface{}) {
+	if h.notifier != nil {
+		h.notifier(updateType, data)
+	}
+
 	// Check if we should batch this event
 	shouldBatch := h.shouldBatchEvent(updateType)
 
@@ -145,35 +213,54 @@ func (h *WebSocketHub) BroadcastUpdate(updateType string, data interface{}) {
 	}
 
 	// Send immediately (no batching)
-	message := gin.H{
-		"type":      updateType,
-		"data":      data,
-		"timestamp": time.Now().Unix(),
-	}
+	timestamp := time.Now().Unix()
 
 	// Log the update being broadcast
 	h.logger.WithFields(logrus.Fields{
 		"update_type":  updateType,
-		"client_count": len(h.clients),
-		"timestamp":    message["timestamp"],
+		"client_count": h.ClientCount(),
+		"timestamp":    timestamp,
 		"batched":      false,
 	}).Debug("Broadcasting WebSocket update to frontend")
 
-	// Convert to JSON
-	jsonData, err := json.Marshal(message)
+	since := websocketEventSince(updateType)
+	for _, version := range supportedWebSocketVersions() {
+		if version < since {
+			continue
+		}
+
+		// Convert to JSON
+		jsonData, err := json.Marshal(gin.H{
+			"type":      updateType,
+			"version":   version,
+			"data":      data,
+			"timestamp": timestamp,
+		})
+		if err != nil {
+			h.logger.WithError(err).Error("Failed to marshal WebSocket message")
+			return
+		}
+
+		// Log the message size
+		h.logger.WithFields(logrus.Fields{
+			"update_type":  updateType,
+			"version":      version,
+			"message_size": len(jsonData),
+		}).Debug("Sending WebSocket message to broadcast channel")
+
+		h.broadcast <- outgoingMessage{version: version, data: jsonData}
+	}
+}
+
+// sendTo sends a message to one client only, if it is still connected
+func (h *WebSocketHub) sendTo(client *WebSocketClient, message gin.H) {
+	message["version"] = client.Version()
+	data, err := json.Marshal(message)
 	if err != nil {
 		h.logger.WithError(err).Error("Failed to marshal WebSocket message")
 		return
 	}
-
-	// Log the message size
-	h.logger.WithFields(logrus.Fields{
-		"update_type":  updateType,
-		"message_size": len(jsonData),
-		"client_count": len(h.clients),
-	}).Debug("Sending WebSocket message to broadcast channel")
-
-	h.broadcast <- jsonData
+	h.direct <- directMessage{client: client, data: data}
 }
 
 // shouldBatchEvent determines if an event type should be batched
@@ -183,7 +270,16 @@ func (h *WebSocketHub) shouldBatchEvent(eventType string) bool {
 	case "session_update", "activity_update", "metrics_update":
 		return true
 	// Don't batch these important events
-	case "session_new", "session_deleted", "sessions_updated":
+	case "session_new", "session_deleted", "sessions_updated", "similar_prompt", "hook_event", "session_liveness":
+		return false
+	// Import progress is already throttled by the importer
+	case "import_progress":
+		return false
+	// Token events drive live cost tickers; token_totals is already periodic
+	case "token_delta", "token_totals":
+		return false
+	// Presence events should not be batched so viewers can follow each other
+	case "presence:update", "presence:leave":
 		return false
 	// Chat events should not be batched for real-time experience
 	case "chat:session:start", "chat:session:end", "chat:message:receive", "chat:message:send", "chat:error", "chat:typing:start", "chat:typing:stop":
@@ -204,6 +300,11 @@ func (h *WebSocketHub) shouldBatchEvent(eventType string) bool {
 // @Failure 400 {object} ErrorResponse "Failed to upgrade connection"
 // @Router /ws [get]
 func (s *Server) websocketHandler(c *gin.Context) {
+	version, ok := websocketQueryVersion(c)
+	if !ok {
+		return
+	}
+
 	// Upgrade HTTP connection to WebSocket
 	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
@@ -219,6 +320,7 @@ func (s *Server) websocketHandler(c *gin.Context) {
 		Hub:    s.wsHub, // Assuming wsHub is added to Server struct
 		Logger: s.logger,
 	}
+	client.acceptVersion(version)
 
 	// Register client
 	client.Hub.register <- client
@@ -231,6 +333,8 @@ func (s *Server) websocketHandler(c *gin.Context) {
 // readPump handles incoming messages from the WebSocket client
 func (c *WebSocketClient) readPump() {
 	defer func() {
+		c.leavePresence()
+		c.Hub.follows.Unfollow(c)
 		c.Hub.unregister <- c
 		c.Conn.Close()
 	}()
@@ -263,12 +367,12 @@ func (c *WebSocketClient) readPump() {
 		// Handle different message types
 		if msgType, ok := msg["type"].(string); ok {
 			switch msgType {
+			case "hello":
+				// Negotiate the contract version messages are encoded for
+				c.handleHello(msg)
 			case "ping":
 				// Respond with pong
-				pong := gin.H{"type": "pong", "timestamp": time.Now().Unix()}
-				if pongData, err := json.Marshal(pong); err == nil {
-					c.Send <- pongData
-				}
+				c.sendJSON(gin.H{"type": "pong", "timestamp": time.Now().Unix()})
 			case "subscribe":
 				// Handle subscription requests
 				c.Logger.WithFields(logrus.Fields{
@@ -276,11 +380,8 @@ func (c *WebSocketClient) readPump() {
 					"subscription": msg,
 				}).Info("Client subscribed to WebSocket updates")
 				// Send acknowledgment
-				ack := gin.H{"type": "subscribed", "timestamp": time.Now().Unix()}
-				if ackData, err := json.Marshal(ack); err == nil {
-					c.Logger.WithField("client_id", c.ID).Debug("Sending subscription acknowledgment")
-					c.Send <- ackData
-				}
+				c.Logger.WithField("client_id", c.ID).Debug("Sending subscription acknowledgment")
+				c.sendJSON(gin.H{"type": "subscribed", "timestamp": time.Now().Unix()})
 			case "chat:session:start", "chat:session:end", "chat:message:send", "chat:typing:start", "chat:typing:stop":
 				// Handle chat messages through the chat handler
 				if c.Hub.ChatHandler != nil {
@@ -294,6 +395,12 @@ func (c *WebSocketClient) readPump() {
 				} else {
 					c.Logger.WithField("type", msgType).Warn("Received chat message but no chat handler configured")
 				}
+			case "presence:join", "presence:update", "presence:leave":
+				// Opt-in sharing of what this viewer is looking at
+				c.handlePresenceMessage(msgType, msg)
+			case "session:follow", "session:unfollow":
+				// Live transcript of one session, fed by the file watcher
+				c.handleFollowMessage(msgType, msg)
 			default:
 				c.Logger.WithField("type", msgType).Debug("Received unknown message type")
 			}
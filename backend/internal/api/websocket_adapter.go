@@ -1,8 +1,11 @@
 package api
 
 import (
-	"github.com/gin-gonic/gin"
+	"github.com/ksred/claude-session-manager/internal/budget"
 	"github.com/ksred/claude-session-manager/internal/database"
+	"github.com/ksred/claude-session-manager/internal/scripting"
+	"github.com/ksred/claude-session-manager/internal/similarity"
+	"github.com/ksred/claude-session-manager/internal/tagging"
 	"github.com/sirupsen/logrus"
 )
 
@@ -10,17 +13,25 @@ import (
 type WebSocketUpdateAdapter struct {
 	wsHub   *WebSocketHub
 	repo    *database.SessionRepository
-	adapter *database.APIAdapter
-	logger  *logrus.Logger
+	adapter  *database.APIAdapter
+	detector *similarity.Detector
+	tagger   *tagging.Tagger
+	scripts  *scripting.Engine
+	budgets  *budget.Monitor
+	logger   *logrus.Logger
 }
 
 // NewWebSocketUpdateAdapter creates a new WebSocket update adapter
-func NewWebSocketUpdateAdapter(wsHub *WebSocketHub, sessionRepo *database.SessionRepository, logger *logrus.Logger) *WebSocketUpdateAdapter {
+func NewWebSocketUpdateAdapter(wsHub *WebSocketHub, sessionRepo *database.SessionRepository, detector *similarity.Detector, tagger *tagging.Tagger, scripts *scripting.Engine, budgets *budget.Monitor, logger *logrus.Logger) *WebSocketUpdateAdapter {
 	return &WebSocketUpdateAdapter{
-		wsHub:   wsHub,
-		repo:    sessionRepo,
-		adapter: database.NewAPIAdapter(sessionRepo),
-		logger:  logger,
+		wsHub:    wsHub,
+		repo:     sessionRepo,
+		adapter:  database.NewAPIAdapter(sessionRepo),
+		detector: detector,
+		tagger:   tagger,
+		scripts:  scripts,
+		budgets:  budgets,
+		logger:   logger,
 	}
 }
 
@@ -53,9 +64,9 @@ func (w *WebSocketUpdateAdapter) OnSessionUpdate(updateType string, sessionID st
 	}
 
 	// Broadcast the update
-	data := gin.H{
-		"session_id": sessionID,
-		"session":    sessionResponse,
+	data := SessionEventData{
+		SessionID: sessionID,
+		Session:   sessionResponse,
 	}
 
 	w.logger.WithFields(logrus.Fields{
@@ -65,6 +76,82 @@ func (w *WebSocketUpdateAdapter) OnSessionUpdate(updateType string, sessionID st
 	}).Info("Sending session update to WebSocket hub for broadcast")
 
 	w.wsHub.BroadcastUpdate(updateType, data)
+	w.wsHub.PushTail(sessionID)
+
+	w.hintSimilarPrompts(sessionID)
+	w.applyTagRules(sessionID)
+	w.applyScripts(sessionID)
+	w.checkBudgets()
+}
+
+// checkBudgets checks budgets against the spend just imported; crossed
+// thresholds are broadcast by the monitor's alert handler
+func (w *WebSocketUpdateAdapter) checkBudgets() {
+	if w.budgets == nil {
+		return
+	}
+	if _, err := w.budgets.Check(); err != nil {
+		w.logger.WithError(err).Warn("Failed to check budgets")
+	}
+}
+
+// applyTagRules tags a session by the configured rules as soon as it is
+// imported rather than waiting for the background refresh
+func (w *WebSocketUpdateAdapter) applyTagRules(sessionID string) {
+	if w.tagger == nil {
+		return
+	}
+	if _, err := w.tagger.ApplySession(sessionID); err != nil {
+		w.logger.WithError(err).WithField("session_id", sessionID).Warn("Failed to apply tagging rules")
+	}
+}
+
+// applyScripts runs the session scripts on a session as soon as it is
+// imported, so notify scripts fire while the session is live
+func (w *WebSocketUpdateAdapter) applyScripts(sessionID string) {
+	if w.scripts == nil {
+		return
+	}
+	if _, err := w.scripts.ApplySession(sessionID); err != nil {
+		w.logger.WithError(err).WithField("session_id", sessionID).Warn("Failed to run session scripts")
+	}
+}
+
+// hintSimilarPrompts indexes a session's opening prompt the first time it is
+// seen and, if past sessions asked something similar, broadcasts a hint
+func (w *WebSocketUpdateAdapter) hintSimilarPrompts(sessionID string) {
+	if w.detector == nil {
+		return
+	}
+
+	signature, created, err := w.detector.Index(sessionID)
+	if err != nil {
+		w.logger.WithError(err).WithField("session_id", sessionID).Warn("Failed to index opening prompt")
+		return
+	}
+	if !created || signature.Signature == "" {
+		return
+	}
+
+	_, matches, err := w.detector.FindSimilar(sessionID, similarity.DefaultThreshold, 3)
+	if err != nil {
+		w.logger.WithError(err).WithField("session_id", sessionID).Warn("Failed to find similar prompts")
+		return
+	}
+	if len(matches) == 0 {
+		return
+	}
+
+	w.logger.WithFields(logrus.Fields{
+		"session_id": sessionID,
+		"matches":    len(matches),
+	}).Info("Sending similar prompt hint to WebSocket hub for broadcast")
+
+	w.wsHub.BroadcastUpdate("similar_prompt", SimilarPromptData{
+		SessionID: sessionID,
+		Prompt:    signature.Prompt,
+		Similar:   matches,
+	})
 }
 
 // OnActivityUpdate handles activity update notifications
@@ -90,8 +177,8 @@ func (w *WebSocketUpdateAdapter) OnActivityUpdate(activity *database.ActivityLog
 	activityEntry := w.adapter.ActivityLogEntryToAPIActivityEntry(activity)
 
 	// Broadcast the update
-	data := gin.H{
-		"activity": activityEntry,
+	data := ActivityUpdateData{
+		Activity: activityEntry,
 	}
 
 	w.logger.WithFields(logrus.Fields{
@@ -130,9 +217,9 @@ func (w *WebSocketUpdateAdapter) OnMetricsUpdate(sessionID string, usage *databa
 	}
 
 	// Broadcast the update
-	data := gin.H{
-		"session_id": sessionID,
-		"usage":      w.adapter.TokenUsageAggregateToClaudeTokenUsage(&tokenUsage),
+	data := MetricsUpdateData{
+		SessionID: sessionID,
+		Usage:     w.adapter.TokenUsageAggregateToClaudeTokenUsage(&tokenUsage),
 	}
 
 	w.logger.WithFields(logrus.Fields{
@@ -142,4 +229,23 @@ func (w *WebSocketUpdateAdapter) OnMetricsUpdate(sessionID string, usage *databa
 	}).Info("Sending metrics update to WebSocket hub for broadcast")
 
 	w.wsHub.BroadcastUpdate("metrics_update", data)
+	w.wsHub.BroadcastTokenDelta(sessionID, usage)
+}
+
+// OnImportProgress handles import progress notifications
+func (w *WebSocketUpdateAdapter) OnImportProgress(progress *database.ImportProgress) {
+	if w.wsHub == nil {
+		w.logger.Debug("WebSocket hub is nil, skipping import progress broadcast")
+		return
+	}
+
+	w.logger.WithFields(logrus.Fields{
+		"type":            "import_progress",
+		"run_id":          progress.RunID,
+		"status":          progress.Status,
+		"files_processed": progress.FilesProcessed,
+		"files_total":     progress.FilesTotal,
+	}).Debug("Sending import progress to WebSocket hub for broadcast")
+
+	w.wsHub.BroadcastUpdate("import_progress", progress)
 }
@@ -35,6 +35,7 @@ type BatchedEvent struct {
 // BatchedMessage represents the batched message sent to clients
 type BatchedMessage struct {
 	Type      string                 `json:"type"`
+	Version   int                    `json:"version"` // contract version the events are encoded for
 	Events    []BatchedEventPayload  `json:"events"`
 	Timestamp int64                  `json:"timestamp"`
 	BatchInfo map[string]interface{} `json:"batch_info"`
@@ -168,49 +169,60 @@ func (b *EventBatcher) flushBatch() {
 	b.events = make(map[string]*BatchedEvent)
 	b.eventsMutex.Unlock()
 
-	// Convert to payload format
-	eventPayloads := make([]BatchedEventPayload, 0, len(eventsToSend))
-	totalCount := 0
-
-	for _, event := range eventsToSend {
-		eventPayloads = append(eventPayloads, BatchedEventPayload{
-			Type:      event.Type,
-			Data:      event.Data,
-			Count:     event.Count,
-			FirstSeen: event.FirstSeen.Unix(),
-			LastSeen:  event.LastSeen.Unix(),
-		})
-		totalCount += event.Count
-	}
+	// Send a batch per contract version, leaving out events added after it
+	for _, version := range supportedWebSocketVersions() {
+		// Convert to payload format
+		eventPayloads := make([]BatchedEventPayload, 0, len(eventsToSend))
+		totalCount := 0
+
+		for _, event := range eventsToSend {
+			if websocketEventSince(event.Type) > version {
+				continue
+			}
+			eventPayloads = append(eventPayloads, BatchedEventPayload{
+				Type:      event.Type,
+				Data:      event.Data,
+				Count:     event.Count,
+				FirstSeen: event.FirstSeen.Unix(),
+				LastSeen:  event.LastSeen.Unix(),
+			})
+			totalCount += event.Count
+		}
+		if len(eventPayloads) == 0 {
+			continue
+		}
 
-	// Create batched message
-	batchedMsg := BatchedMessage{
-		Type:      "batched_updates",
-		Events:    eventPayloads,
-		Timestamp: time.Now().Unix(),
-		BatchInfo: gin.H{
+		// Create batched message
+		batchedMsg := BatchedMessage{
+			Type:      "batched_updates",
+			Version:   version,
+			Events:    eventPayloads,
+			Timestamp: time.Now().Unix(),
+			BatchInfo: gin.H{
+				"event_count":       len(eventPayloads),
+				"total_occurrences": totalCount,
+				"batch_interval":    b.batchInterval.Seconds(),
+			},
+		}
+
+		b.logger.WithFields(logrus.Fields{
 			"event_count":       len(eventPayloads),
 			"total_occurrences": totalCount,
-			"batch_interval":    b.batchInterval.Seconds(),
-		},
-	}
-
-	b.logger.WithFields(logrus.Fields{
-		"event_count":       len(eventPayloads),
-		"total_occurrences": totalCount,
-	}).Info("Flushing event batch")
+			"version":           version,
+		}).Info("Flushing event batch")
+
+		// Send the batched message
+		if b.hub != nil {
+			// Convert to JSON
+			jsonData, err := json.Marshal(batchedMsg)
+			if err != nil {
+				b.logger.WithError(err).Error("Failed to marshal batched message")
+				return
+			}
 
-	// Send the batched message
-	if b.hub != nil {
-		// Convert to JSON
-		jsonData, err := json.Marshal(batchedMsg)
-		if err != nil {
-			b.logger.WithError(err).Error("Failed to marshal batched message")
-			return
+			// Send directly to broadcast channel
+			b.hub.broadcast <- outgoingMessage{version: version, data: jsonData}
 		}
-
-		// Send directly to broadcast channel
-		b.hub.broadcast <- jsonData
 	}
 
 	// Update dedup keys
@@ -228,6 +240,9 @@ func (b *EventBatcher) generateEventKey(eventType string, data interface{}) stri
 	switch eventType {
 	case "session_update", "session_new":
 		// For session events, key by session ID
+		if d, ok := data.(SessionEventData); ok {
+			return eventType + ":" + d.SessionID
+		}
 		if m, ok := data.(gin.H); ok {
 			if sessionID, ok := m["session_id"].(string); ok {
 				return eventType + ":" + sessionID
@@ -235,6 +250,9 @@ func (b *EventBatcher) generateEventKey(eventType string, data interface{}) stri
 		}
 	case "activity_update":
 		// For activity events, key by activity type and session
+		if d, ok := data.(ActivityUpdateData); ok {
+			return eventType + ":" + d.Activity.Type + ":" + d.Activity.SessionID
+		}
 		if m, ok := data.(gin.H); ok {
 			if activity, ok := m["activity"].(map[string]interface{}); ok {
 				activityType := ""
@@ -252,6 +270,9 @@ func (b *EventBatcher) generateEventKey(eventType string, data interface{}) stri
 		}
 	case "metrics_update":
 		// For metrics events, key by session ID
+		if d, ok := data.(MetricsUpdateData); ok {
+			return eventType + ":" + d.SessionID
+		}
 		if m, ok := data.(gin.H); ok {
 			if sessionID, ok := m["session_id"].(string); ok {
 				return eventType + ":" + sessionID
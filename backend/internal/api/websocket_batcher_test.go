@@ -16,7 +16,7 @@ func TestEventBatcher(t *testing.T) {
 
 	// Create a mock hub
 	hub := &WebSocketHub{
-		broadcast: make(chan []byte, 100),
+		broadcast: make(chan outgoingMessage, 100),
 		logger:    logger,
 	}
 
@@ -108,7 +108,7 @@ func TestEventBatcher(t *testing.T) {
 		select {
 		case msg := <-hub.broadcast:
 			assert.NotNil(t, msg, "Should have received a broadcast message")
-			t.Logf("Received broadcast message: %s", string(msg))
+			t.Logf("Received broadcast message: %s", string(msg.data))
 		case <-time.After(1 * time.Second):
 			t.Fatal("No message received on broadcast channel")
 		}
@@ -0,0 +1,498 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"path"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/claude-session-manager/internal/budget"
+	"github.com/ksred/claude-session-manager/internal/chat"
+	"github.com/ksred/claude-session-manager/internal/claude"
+	"github.com/ksred/claude-session-manager/internal/database"
+	"github.com/ksred/claude-session-manager/internal/similarity"
+	"github.com/sirupsen/logrus"
+)
+
+// WebSocket messages follow a versioned data contract. A client picks the
+// version it was written against with the version query parameter when it
+// connects, or a hello message afterwards; clients that do neither are served
+// the oldest version still supported. Every message sent carries the version
+// it was encoded for, and events added in a later version aren't sent to
+// clients on an earlier one. A change to an existing payload that could break
+// a client bumps WebSocketContractVersion.
+const (
+	WebSocketContractVersion    = 1 // newest version the server speaks
+	MinWebSocketContractVersion = 1 // oldest version still served
+)
+
+// websocketEvent describes a type of WebSocket message
+type websocketEvent struct {
+	since       int         // contract version the message was added in
+	flat        bool        // the payload's fields are the message's own rather than under data
+	payload     interface{} // a value of the payload's type; nil when there is none
+	description string
+}
+
+// websocketServerEvents are the messages sent to clients. Adding one here is
+// enough to describe it in the schema; its payload type must be what is
+// passed to BroadcastUpdate.
+var websocketServerEvents = map[string]websocketEvent{
+	"session_created":     {since: 1, payload: SessionEventData{}, description: "A session was imported or started"},
+	"session_update":      {since: 1, payload: SessionEventData{}, description: "A session was modified; batched"},
+	"similar_prompt":      {since: 1, payload: SimilarPromptData{}, description: "A new session's opening prompt resembles past sessions"},
+	"activity_update":     {since: 1, payload: ActivityUpdateData{}, description: "An activity was logged; batched"},
+	"metrics_update":      {since: 1, payload: MetricsUpdateData{}, description: "A message added token usage to a session; batched"},
+	"token_delta":         {since: 1, payload: TokenDelta{}, description: "A message added token usage to a session"},
+	"token_totals":        {since: 1, payload: TokenTotalsData{}, description: "Periodic per-session token sums and totals"},
+	"import_progress":     {since: 1, payload: database.ImportProgress{}, description: "An import of session files advanced, finished or was cancelled"},
+	"hook_event":          {since: 1, payload: database.HookEvent{}, description: "A Claude Code hook reported an event"},
+	"session_liveness":    {since: 1, payload: database.SessionLiveness{}, description: "A hook event started or stopped a session"},
+	"session_wrap_up":     {since: 1, payload: SessionWrapUp{}, description: "A session ended, with what it did"},
+	"session_status":      {since: 1, payload: database.SessionStatusChange{}, description: "A session went idle or completed after going quiet"},
+	"budget_alert":        {since: 1, payload: budget.Alert{}, description: "Spend crossed a budget threshold"},
+	"presence:state":      {since: 1, payload: PresenceStateData{}, description: "Everyone present, sent to a viewer that joins"},
+	"presence:update":     {since: 1, payload: PresenceUpdateData{}, description: "An opted-in viewer joined or moved"},
+	"presence:leave":      {since: 1, payload: PresenceLeaveData{}, description: "An opted-in viewer left or disconnected"},
+	"presence:error":      {since: 1, flat: true, payload: WebSocketError{}, description: "A presence message was rejected"},
+	"session:following":   {since: 1, payload: SessionFollowingData{}, description: "The client now follows a session"},
+	"session:tail":        {since: 1, payload: SessionTailData{}, description: "Messages appended to the followed session"},
+	"session:error":       {since: 1, flat: true, payload: WebSocketError{}, description: "A follow message was rejected"},
+	"batched_updates":     {since: 1, flat: true, payload: BatchedMessage{}, description: "Batched events collected over the batch interval"},
+	"hello:ack":           {since: 1, flat: true, payload: WebSocketHelloAck{}, description: "The contract version agreed with the client, given as the message's version"},
+	"version:unsupported": {since: 1, flat: true, payload: WebSocketHelloAck{}, description: "None of the versions a hello offered is served; the client stays on the message's version"},
+	"pong":                {since: 1, description: "Reply to a ping"},
+	"subscribed":          {since: 1, description: "Reply to a subscribe"},
+
+	chat.WSMsgChatSessionStart: {since: 1, payload: chat.WebSocketMessage{}, description: "A chat session started"},
+	chat.WSMsgChatSessionEnd:   {since: 1, payload: chat.WebSocketMessage{}, description: "A chat session ended"},
+	chat.WSMsgChatMessageSend:  {since: 1, payload: chat.WebSocketMessage{}, description: "A chat message was sent to Claude"},
+	chat.WSMsgChatMessageRecv:  {since: 1, payload: chat.WebSocketMessage{}, description: "Claude replied in a chat"},
+	chat.WSMsgChatTypingStart:  {since: 1, payload: chat.WebSocketMessage{}, description: "A chat participant started typing"},
+	chat.WSMsgChatTypingStop:   {since: 1, payload: chat.WebSocketMessage{}, description: "A chat participant stopped typing"},
+	chat.WSMsgChatError:        {since: 1, payload: chat.WebSocketMessage{}, description: "A chat message failed"},
+}
+
+// websocketClientMessages are the messages clients send
+var websocketClientMessages = map[string]websocketEvent{
+	"hello":              {since: 1, flat: true, payload: WebSocketHello{}, description: "Negotiate the contract version"},
+	"ping":               {since: 1, description: "Check the connection"},
+	"subscribe":          {since: 1, description: "Ask for updates"},
+	"presence:join":      {since: 1, flat: true, payload: PresenceJoinMessage{}, description: "Opt in to presence under a display name"},
+	"presence:update":    {since: 1, flat: true, payload: PresenceUpdateMessage{}, description: "Share what the viewer is looking at"},
+	"presence:leave":     {since: 1, description: "Opt out of presence"},
+	"session:follow":     {since: 1, flat: true, payload: SessionFollowMessage{}, description: "Follow a session's transcript"},
+	"session:unfollow":   {since: 1, description: "Stop following"},
+	"chat:session:start": {since: 1, flat: true, payload: ChatStartMessage{}, description: "Start a chat session"},
+	"chat:session:end":   {since: 1, flat: true, payload: ChatSessionMessage{}, description: "End a chat session"},
+	"chat:message:send":  {since: 1, flat: true, payload: ChatSendMessage{}, description: "Send a chat message to Claude"},
+	"chat:typing:start":  {since: 1, flat: true, payload: ChatSessionMessage{}, description: "Tell other viewers the client is typing"},
+	"chat:typing:stop":   {since: 1, flat: true, payload: ChatSessionMessage{}, description: "Tell other viewers the client stopped typing"},
+}
+
+// SessionEventData is the data of session_created and session_update
+type SessionEventData struct {
+	SessionID string                    `json:"session_id"`
+	Session   *database.SessionResponse `json:"session"`
+}
+
+// SimilarPromptData is the data of similar_prompt
+type SimilarPromptData struct {
+	SessionID string             `json:"session_id"`
+	Prompt    string             `json:"prompt"`
+	Similar   []similarity.Match `json:"similar"`
+}
+
+// ActivityUpdateData is the data of activity_update
+type ActivityUpdateData struct {
+	Activity database.ActivityEntry `json:"activity"`
+}
+
+// MetricsUpdateData is the data of metrics_update
+type MetricsUpdateData struct {
+	SessionID string            `json:"session_id"`
+	Usage     claude.TokenUsage `json:"usage"`
+}
+
+// TokenTotalsData is the data of token_totals
+type TokenTotalsData struct {
+	IntervalSeconds float64              `json:"interval_seconds"`
+	Sessions        []SessionTokenTotals `json:"sessions"`
+}
+
+// PresenceStateData is the data of presence:state
+type PresenceStateData struct {
+	ClientID string           `json:"client_id"`
+	Viewers  []ViewerPresence `json:"viewers"`
+}
+
+// PresenceUpdateData is the data of presence:update
+type PresenceUpdateData struct {
+	Viewer ViewerPresence `json:"viewer"`
+}
+
+// PresenceLeaveData is the data of presence:leave
+type PresenceLeaveData struct {
+	ClientID string `json:"client_id"`
+}
+
+// SessionFollowingData is the data of session:following
+type SessionFollowingData struct {
+	SessionID string `json:"session_id"`
+	Cursor    string `json:"cursor"` // the last message sent, empty until one is
+}
+
+// SessionTailData is the data of session:tail
+type SessionTailData struct {
+	SessionID string             `json:"session_id"`
+	Messages  []database.Message `json:"messages"`
+	Cursor    string             `json:"cursor"`
+}
+
+// WebSocketError is a rejected client message, reported in the message
+// itself rather than under data
+type WebSocketError struct {
+	Error     string `json:"error"`
+	SessionID string `json:"session_id,omitempty"`
+}
+
+// WebSocketHello is a client's offer of the contract versions it can read,
+// as one version or a list
+type WebSocketHello struct {
+	Version  int   `json:"version,omitempty"`
+	Versions []int `json:"versions,omitempty"`
+}
+
+// WebSocketHelloAck reports the contract version a client is served and the
+// versions the server supports
+type WebSocketHelloAck struct {
+	SupportedVersions []int  `json:"supported_versions"`
+	Schema            string `json:"schema"` // where the event schemas are described
+}
+
+// PresenceJoinMessage is the presence:join a client sends
+type PresenceJoinMessage struct {
+	Name string `json:"name"`
+}
+
+// PresenceUpdateMessage is the presence:update a client sends
+type PresenceUpdateMessage struct {
+	SessionID string `json:"session_id,omitempty"`
+	View      string `json:"view,omitempty"`
+	MessageID string `json:"message_id,omitempty"`
+}
+
+// SessionFollowMessage is the session:follow a client sends
+type SessionFollowMessage struct {
+	SessionID string `json:"session_id"`
+	Since     string `json:"since,omitempty"` // a message ID to send the messages after straight away
+}
+
+// ChatSessionMessage is a chat message a client sends about a chat session
+type ChatSessionMessage struct {
+	SessionID string `json:"session_id"`
+}
+
+// ChatStartMessage is the chat:session:start a client sends
+type ChatStartMessage struct {
+	SessionID  string `json:"session_id"`
+	TemplateID string `json:"template_id,omitempty"` // a prompt template to insert into the composer
+}
+
+// ChatSendMessage is the chat:message:send a client sends
+type ChatSendMessage struct {
+	SessionID string `json:"session_id"`
+	Content   string `json:"content"`
+}
+
+// websocketSchemaPath is where GetWebSocketSchemaHandler is served
+const websocketSchemaPath = "/api/v1/ws/schema"
+
+// supportedWebSocketVersions lists the contract versions served, oldest first
+func supportedWebSocketVersions() []int {
+	versions := make([]int, 0, WebSocketContractVersion-MinWebSocketContractVersion+1)
+	for v := MinWebSocketContractVersion; v <= WebSocketContractVersion; v++ {
+		versions = append(versions, v)
+	}
+	return versions
+}
+
+// negotiateWebSocketVersion picks the newest served version of those a client
+// offers. ok is false when none is served.
+func negotiateWebSocketVersion(offered []int) (int, bool) {
+	best := 0
+	for _, v := range offered {
+		if v >= MinWebSocketContractVersion && v <= WebSocketContractVersion && v > best {
+			best = v
+		}
+	}
+	return best, best != 0
+}
+
+// parseWebSocketVersions reads the versions a client offers in the version
+// query parameter, a single version or a comma separated list
+func parseWebSocketVersions(value string) ([]int, bool) {
+	var versions []int
+	for _, part := range strings.Split(value, ",") {
+		v, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			return nil, false
+		}
+		versions = append(versions, v)
+	}
+	return versions, true
+}
+
+// websocketEventSince returns the contract version an event type was added
+// in. Types missing from the contract are treated as always present.
+func websocketEventSince(eventType string) int {
+	if event, ok := websocketServerEvents[eventType]; ok {
+		return event.since
+	}
+	return MinWebSocketContractVersion
+}
+
+// websocketQueryVersion reads the contract version a client asks for in the
+// version query parameter of the WebSocket URL: one version or a list, of
+// which the newest served is picked. It is 0 when none is asked for. When
+// none of those asked for is served the request is answered with 400 and ok
+// is false.
+func websocketQueryVersion(c *gin.Context) (version int, ok bool) {
+	value := c.Query("version")
+	if value == "" {
+		return 0, true
+	}
+	offered, valid := parseWebSocketVersions(value)
+	if valid {
+		version, valid = negotiateWebSocketVersion(offered)
+	}
+	if !valid {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":              "Unsupported WebSocket contract version",
+			"supported_versions": supportedWebSocketVersions(),
+			"schema":             websocketSchemaPath,
+		})
+		return 0, false
+	}
+	return version, true
+}
+
+// acceptVersion serves a client the version it asked for when connecting
+// and acknowledges it. Clients that didn't ask are left on the default.
+func (c *WebSocketClient) acceptVersion(version int) {
+	if version == 0 {
+		return
+	}
+	c.version.Store(int32(version))
+	c.sendJSON(gin.H{
+		"type":               "hello:ack",
+		"supported_versions": supportedWebSocketVersions(),
+		"schema":             websocketSchemaPath,
+		"timestamp":          time.Now().Unix(),
+	})
+}
+
+// handleHello negotiates the contract version from a hello message, which
+// offers a version or a list of versions. A client offering none that is
+// served is told so and stays on its current version.
+func (c *WebSocketClient) handleHello(msg map[string]interface{}) {
+	var offered []int
+	if v, ok := msg["version"].(float64); ok {
+		offered = append(offered, int(v))
+	}
+	if list, ok := msg["versions"].([]interface{}); ok {
+		for _, item := range list {
+			if v, ok := item.(float64); ok {
+				offered = append(offered, int(v))
+			}
+		}
+	}
+
+	version, ok := negotiateWebSocketVersion(offered)
+	if !ok {
+		c.Logger.WithFields(logrus.Fields{
+			"client_id": c.ID,
+			"offered":   offered,
+		}).Warn("Client offered no supported WebSocket contract version")
+		c.sendJSON(gin.H{
+			"type":               "version:unsupported",
+			"supported_versions": supportedWebSocketVersions(),
+			"schema":             websocketSchemaPath,
+			"timestamp":          time.Now().Unix(),
+		})
+		return
+	}
+	c.Logger.WithFields(logrus.Fields{
+		"client_id": c.ID,
+		"version":   version,
+	}).Debug("Negotiated WebSocket contract version")
+	c.acceptVersion(version)
+}
+
+// WebSocketEventSchema describes a message type of the WebSocket contract
+type WebSocketEventSchema struct {
+	Type        string                 `json:"type"`
+	Direction   string                 `json:"direction"` // server or client
+	Since       int                    `json:"since"`
+	Payload     string                 `json:"payload"` // data, message (fields beside type) or none
+	Description string                 `json:"description"`
+	Schema      map[string]interface{} `json:"schema,omitempty"`
+}
+
+// websocketSchema describes the envelope and every message type of the
+// contract, as JSON Schema built from the Go types the payloads are encoded
+// from. Types shared by several payloads are in definitions.
+func websocketSchema() gin.H {
+	definitions := map[string]interface{}{}
+	events := make([]WebSocketEventSchema, 0, len(websocketServerEvents)+len(websocketClientMessages))
+	describe := func(direction string, registry map[string]websocketEvent) {
+		for eventType, event := range registry {
+			schema := WebSocketEventSchema{
+				Type:        eventType,
+				Direction:   direction,
+				Since:       event.since,
+				Payload:     "none",
+				Description: event.description,
+			}
+			if event.payload != nil {
+				schema.Payload = "data"
+				if event.flat {
+					schema.Payload = "message"
+				}
+				schema.Schema = jsonSchemaOf(reflect.TypeOf(event.payload), definitions)
+			}
+			events = append(events, schema)
+		}
+	}
+	describe("server", websocketServerEvents)
+	describe("client", websocketClientMessages)
+	sort.Slice(events, func(i, j int) bool {
+		if events[i].Direction != events[j].Direction {
+			return events[i].Direction > events[j].Direction
+		}
+		return events[i].Type < events[j].Type
+	})
+
+	return gin.H{
+		"version":            WebSocketContractVersion,
+		"min_version":        MinWebSocketContractVersion,
+		"supported_versions": supportedWebSocketVersions(),
+		"envelope": map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"type":      map[string]interface{}{"type": "string"},
+				"version":   map[string]interface{}{"type": "integer"},
+				"timestamp": map[string]interface{}{"type": "integer", "description": "Unix seconds"},
+				"data":      map[string]interface{}{"description": "The payload of events whose payload is data"},
+			},
+			"required": []string{"type", "version"},
+		},
+		"events":      events,
+		"definitions": definitions,
+	}
+}
+
+// GetWebSocketSchemaHandler returns the WebSocket contract: the versions
+// served, the envelope every message shares and a JSON Schema of each
+// message type's payload, so clients can check or generate their types
+func (s *SQLiteServer) GetWebSocketSchemaHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, websocketSchema())
+}
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+// jsonSchemaOf returns the JSON Schema of values of t as encoding/json
+// writes them. Named structs are added to definitions and referenced.
+func jsonSchemaOf(t reflect.Type, definitions map[string]interface{}) map[string]interface{} {
+	switch {
+	case t == timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case t == rawMessageType:
+		return map[string]interface{}{}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return jsonSchemaOf(t.Elem(), definitions)
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": "string", "contentEncoding": "base64"}
+		}
+		return map[string]interface{}{"type": "array", "items": jsonSchemaOf(t.Elem(), definitions)}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": jsonSchemaOf(t.Elem(), definitions)}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return structSchema(t, definitions)
+		}
+		name := path.Base(t.PkgPath()) + "." + t.Name()
+		if _, ok := definitions[name]; !ok {
+			definitions[name] = map[string]interface{}{} // placeholder for recursive types
+			definitions[name] = structSchema(t, definitions)
+		}
+		return map[string]interface{}{"$ref": "#/definitions/" + name}
+	}
+	// interface{} and anything else can hold any value
+	return map[string]interface{}{}
+}
+
+// structSchema returns the object schema of a struct's JSON fields.
+// Fields without omitempty that aren't pointers are required.
+func structSchema(t reflect.Type, definitions map[string]interface{}) map[string]interface{} {
+	properties := map[string]interface{}{}
+	required := []string{}
+	var addFields func(t reflect.Type)
+	addFields = func(t reflect.Type) {
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			tag := field.Tag.Get("json")
+			if tag == "-" {
+				continue
+			}
+			name, options, _ := strings.Cut(tag, ",")
+			if field.Anonymous && name == "" {
+				embedded := field.Type
+				if embedded.Kind() == reflect.Ptr {
+					embedded = embedded.Elem()
+				}
+				if embedded.Kind() == reflect.Struct {
+					addFields(embedded)
+					continue
+				}
+			}
+			if !field.IsExported() {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			properties[name] = jsonSchemaOf(field.Type, definitions)
+			if !strings.Contains(options, "omitempty") && field.Type.Kind() != reflect.Ptr {
+				required = append(required, name)
+			}
+		}
+	}
+	addFields(t)
+	return map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+		"required":   required,
+	}
+}
@@ -0,0 +1,139 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNegotiateWebSocketVersion(t *testing.T) {
+	version, ok := negotiateWebSocketVersion([]int{WebSocketContractVersion + 1, WebSocketContractVersion})
+	assert.True(t, ok)
+	assert.Equal(t, WebSocketContractVersion, version, "the newest served version is picked")
+
+	_, ok = negotiateWebSocketVersion([]int{0, WebSocketContractVersion + 1})
+	assert.False(t, ok)
+	_, ok = negotiateWebSocketVersion(nil)
+	assert.False(t, ok)
+
+	versions, ok := parseWebSocketVersions("1, 2")
+	assert.True(t, ok)
+	assert.Equal(t, []int{1, 2}, versions)
+	_, ok = parseWebSocketVersions("latest")
+	assert.False(t, ok)
+}
+
+func TestWebSocketSchema(t *testing.T) {
+	data, err := json.Marshal(websocketSchema())
+	require.NoError(t, err)
+
+	var schema struct {
+		Version     int                        `json:"version"`
+		Events      []WebSocketEventSchema     `json:"events"`
+		Definitions map[string]json.RawMessage `json:"definitions"`
+	}
+	require.NoError(t, json.Unmarshal(data, &schema))
+	assert.Equal(t, WebSocketContractVersion, schema.Version)
+	assert.Len(t, schema.Events, len(websocketServerEvents)+len(websocketClientMessages))
+
+	// Every reference points at a definition
+	for _, ref := range strings.Split(string(data), `"$ref":"#/definitions/`)[1:] {
+		name := ref[:strings.Index(ref, `"`)]
+		assert.Contains(t, schema.Definitions, name)
+	}
+
+	var session struct {
+		Properties map[string]interface{} `json:"properties"`
+		Required   []string               `json:"required"`
+	}
+	require.Contains(t, schema.Definitions, "database.SessionResponse")
+	require.NoError(t, json.Unmarshal(schema.Definitions["database.SessionResponse"], &session))
+	assert.Equal(t, map[string]interface{}{"type": "string", "format": "date-time"}, session.Properties["created_at"])
+	assert.Contains(t, session.Required, "id")
+	assert.NotContains(t, session.Required, "archived_at", "pointers may be left out")
+	assert.NotContains(t, session.Required, "tags", "omitempty fields may be left out")
+
+	for _, event := range schema.Events {
+		if event.Type == "session_update" {
+			assert.Equal(t, "server", event.Direction)
+			assert.Equal(t, "data", event.Payload)
+			assert.Equal(t, "#/definitions/api.SessionEventData", event.Schema["$ref"])
+		}
+	}
+}
+
+func TestWebSocketHub_BroadcastPerVersion(t *testing.T) {
+	logger := logrus.New()
+	hub := &WebSocketHub{
+		broadcast: make(chan outgoingMessage, 10),
+		logger:    logger,
+	}
+
+	hub.BroadcastUpdate("presence:leave", PresenceLeaveData{ClientID: "client-1"})
+	require.Len(t, hub.broadcast, len(supportedWebSocketVersions()), "the update is encoded once per version")
+	for _, version := range supportedWebSocketVersions() {
+		message := <-hub.broadcast
+		assert.Equal(t, version, message.version)
+
+		var decoded map[string]interface{}
+		require.NoError(t, json.Unmarshal(message.data, &decoded))
+		assert.Equal(t, float64(version), decoded["version"])
+		assert.Equal(t, "client-1", decoded["data"].(map[string]interface{})["client_id"])
+	}
+}
+
+func TestWebSocketClient_Hello(t *testing.T) {
+	logger := logrus.New()
+	client := &WebSocketClient{ID: "client-1", Send: make(chan []byte, 10), Logger: logger}
+	assert.Equal(t, MinWebSocketContractVersion, client.Version(), "clients that don't negotiate get the oldest version")
+
+	decode := func(data []byte) map[string]interface{} {
+		var message map[string]interface{}
+		require.NoError(t, json.Unmarshal(data, &message))
+		return message
+	}
+
+	client.handleHello(map[string]interface{}{"versions": []interface{}{float64(WebSocketContractVersion), float64(WebSocketContractVersion + 1)}})
+	ack := decode(<-client.Send)
+	assert.Equal(t, "hello:ack", ack["type"])
+	assert.Equal(t, float64(WebSocketContractVersion), ack["version"])
+	assert.Equal(t, WebSocketContractVersion, client.Version())
+
+	client.handleHello(map[string]interface{}{"version": float64(WebSocketContractVersion + 1)})
+	rejected := decode(<-client.Send)
+	assert.Equal(t, "version:unsupported", rejected["type"])
+	assert.Equal(t, float64(WebSocketContractVersion), rejected["version"], "the client keeps its version")
+	assert.NotEmpty(t, rejected["supported_versions"])
+}
+
+func TestWebSocketQueryVersion(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	query := func(target string) (int, bool, *httptest.ResponseRecorder) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, target, nil)
+		version, ok := websocketQueryVersion(c)
+		return version, ok, w
+	}
+
+	version, ok, _ := query("/api/v1/ws")
+	assert.True(t, ok)
+	assert.Equal(t, 0, version)
+
+	version, ok, _ = query("/api/v1/ws?version=1")
+	assert.True(t, ok)
+	assert.Equal(t, 1, version)
+
+	_, ok, w := query("/api/v1/ws?version=99")
+	assert.False(t, ok)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "supported_versions")
+}
@@ -0,0 +1,174 @@
+package api
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/claude-session-manager/internal/database"
+	"github.com/sirupsen/logrus"
+)
+
+// TailSource returns up to limit of a session's messages after the message
+// afterID, or its last limit messages when afterID is empty
+type TailSource func(sessionID, afterID string, limit int) ([]database.Message, error)
+
+// followBatchSize is how many messages are sent to a follower at once
+const followBatchSize = 100
+
+// FollowTracker holds which session each following client follows and the
+// last message it was sent
+type FollowTracker struct {
+	mu      sync.Mutex
+	follows map[*WebSocketClient]*follow
+	pushing sync.Mutex // one push at a time, so no message is sent twice
+}
+
+type follow struct {
+	sessionID string
+	cursor    string
+}
+
+// NewFollowTracker creates an empty follow tracker
+func NewFollowTracker() *FollowTracker {
+	return &FollowTracker{
+		follows: make(map[*WebSocketClient]*follow),
+	}
+}
+
+// Follow makes a client follow a session from cursor, replacing any session
+// it followed before
+func (f *FollowTracker) Follow(client *WebSocketClient, sessionID, cursor string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.follows[client] = &follow{sessionID: sessionID, cursor: cursor}
+}
+
+// Unfollow stops a client following and reports whether it was
+func (f *FollowTracker) Unfollow(client *WebSocketClient) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	_, exists := f.follows[client]
+	delete(f.follows, client)
+	return exists
+}
+
+// Followers returns the clients following a session with their cursors
+func (f *FollowTracker) Followers(sessionID string) map[*WebSocketClient]string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	followers := make(map[*WebSocketClient]string)
+	for client, fl := range f.follows {
+		if fl.sessionID == sessionID {
+			followers[client] = fl.cursor
+		}
+	}
+	return followers
+}
+
+// Advance moves a client's cursor if it still follows the session
+func (f *FollowTracker) Advance(client *WebSocketClient, sessionID, cursor string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if fl, ok := f.follows[client]; ok && fl.sessionID == sessionID {
+		fl.cursor = cursor
+	}
+}
+
+// SetTailSource sets where followed sessions' new messages are read from.
+// Following is unavailable until it is set.
+func (h *WebSocketHub) SetTailSource(source TailSource) {
+	h.tail = source
+}
+
+// PushTail sends every client following a session the messages appended
+// since it was last sent any. The file watcher calls it through the update
+// adapter whenever it imports new lines of the session.
+func (h *WebSocketHub) PushTail(sessionID string) {
+	if h.tail == nil {
+		return
+	}
+	h.follows.pushing.Lock()
+	defer h.follows.pushing.Unlock()
+
+	for client, cursor := range h.follows.Followers(sessionID) {
+		for {
+			messages, err := h.tail(sessionID, cursor, followBatchSize)
+			if err != nil {
+				h.logger.WithError(err).WithFields(logrus.Fields{
+					"client_id":  client.ID,
+					"session_id": sessionID,
+				}).Warn("Failed to read followed session")
+				break
+			}
+			if len(messages) == 0 {
+				break
+			}
+			cursor = messages[len(messages)-1].ID
+			h.follows.Advance(client, sessionID, cursor)
+			h.sendTo(client, gin.H{
+				"type":      "session:tail",
+				"data":      SessionTailData{SessionID: sessionID, Messages: messages, Cursor: cursor},
+				"timestamp": time.Now().Unix(),
+			})
+			if len(messages) < followBatchSize {
+				break
+			}
+		}
+	}
+}
+
+// handleFollowMessage applies a session:follow or session:unfollow message
+// from a client. A follow with since (a message ID) is sent the messages
+// after it straight away; without since only messages appended from now on
+// are sent.
+func (c *WebSocketClient) handleFollowMessage(msgType string, msg map[string]interface{}) {
+	hub := c.Hub
+	if msgType == "session:unfollow" {
+		hub.follows.Unfollow(c)
+		return
+	}
+
+	sessionID, _ := msg["session_id"].(string)
+	since, _ := msg["since"].(string)
+	sessionID, since = strings.TrimSpace(sessionID), strings.TrimSpace(since)
+	if hub.tail == nil {
+		c.sendJSON(gin.H{"type": "session:error", "error": "Following sessions is not available"})
+		return
+	}
+	if sessionID == "" {
+		c.sendJSON(gin.H{"type": "session:error", "error": "session_id is required"})
+		return
+	}
+
+	cursor := since
+	if since == "" {
+		// Start from the last message so only new ones are sent
+		last, err := hub.tail(sessionID, "", 1)
+		if err != nil {
+			c.sendJSON(gin.H{"type": "session:error", "session_id": sessionID, "error": "Failed to read session"})
+			return
+		}
+		if len(last) > 0 {
+			cursor = last[0].ID
+		}
+	} else if _, err := hub.tail(sessionID, since, 1); err != nil {
+		c.sendJSON(gin.H{"type": "session:error", "session_id": sessionID, "error": "since must be a message of this session"})
+		return
+	}
+
+	hub.follows.Follow(c, sessionID, cursor)
+	c.Logger.WithFields(logrus.Fields{
+		"client_id":  c.ID,
+		"session_id": sessionID,
+	}).Info("Client following session")
+	c.sendJSON(gin.H{
+		"type":      "session:following",
+		"data":      SessionFollowingData{SessionID: sessionID, Cursor: cursor},
+		"timestamp": time.Now().Unix(),
+	})
+	if since != "" {
+		hub.PushTail(sessionID)
+	}
+}
@@ -0,0 +1,76 @@
+package api
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/ksred/claude-session-manager/internal/database"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebSocketClient_FollowMessages(t *testing.T) {
+	logger := logrus.New()
+	transcript := []database.Message{{ID: "m1", SessionID: "session-1"}, {ID: "m2", SessionID: "session-1"}}
+	hub := &WebSocketHub{
+		logger:  logger,
+		follows: NewFollowTracker(),
+		direct:  make(chan directMessage, 10),
+	}
+	hub.SetTailSource(func(sessionID, afterID string, limit int) ([]database.Message, error) {
+		if afterID == "" {
+			return transcript[len(transcript)-1:], nil
+		}
+		for i, message := range transcript {
+			if message.ID == afterID {
+				return transcript[i+1:], nil
+			}
+		}
+		return nil, assert.AnError
+	})
+	client := &WebSocketClient{ID: "client-1", Send: make(chan []byte, 10), Hub: hub, Logger: logger}
+
+	decode := func(data []byte) map[string]interface{} {
+		var message map[string]interface{}
+		require.NoError(t, json.Unmarshal(data, &message))
+		return message
+	}
+
+	client.handleFollowMessage("session:follow", map[string]interface{}{})
+	assert.Equal(t, "session:error", decode(<-client.Send)["type"])
+	client.handleFollowMessage("session:follow", map[string]interface{}{"session_id": "session-1", "since": "unknown"})
+	assert.Equal(t, "session:error", decode(<-client.Send)["type"])
+
+	// Following without since starts after the last message
+	client.handleFollowMessage("session:follow", map[string]interface{}{"session_id": "session-1"})
+	following := decode(<-client.Send)
+	assert.Equal(t, "session:following", following["type"])
+	assert.Equal(t, "m2", following["data"].(map[string]interface{})["cursor"])
+
+	hub.PushTail("session-1")
+	assert.Empty(t, hub.direct, "nothing new to send")
+
+	transcript = append(transcript, database.Message{ID: "m3", SessionID: "session-1"})
+	hub.PushTail("session-1")
+	require.Len(t, hub.direct, 1)
+	message := <-hub.direct
+	assert.Same(t, client, message.client)
+	tail := decode(message.data)
+	assert.Equal(t, "session:tail", tail["type"])
+	assert.Equal(t, "m3", tail["data"].(map[string]interface{})["cursor"])
+
+	hub.PushTail("session-1")
+	assert.Empty(t, hub.direct, "messages are sent once")
+
+	// Following with since is sent what came after it straight away
+	client.handleFollowMessage("session:follow", map[string]interface{}{"session_id": "session-1", "since": "m1"})
+	assert.Equal(t, "session:following", decode(<-client.Send)["type"])
+	require.Len(t, hub.direct, 1)
+	assert.Len(t, decode((<-hub.direct).data)["data"].(map[string]interface{})["messages"], 2)
+
+	client.handleFollowMessage("session:unfollow", nil)
+	transcript = append(transcript, database.Message{ID: "m4", SessionID: "session-1"})
+	hub.PushTail("session-1")
+	assert.Empty(t, hub.direct)
+}
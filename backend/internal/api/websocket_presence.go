@@ -0,0 +1,164 @@
+package api
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// maxViewerNameLength bounds the display name a viewer can share
+const maxViewerNameLength = 64
+
+// ViewerPresence is what a connected viewer who opted in to presence is looking at
+type ViewerPresence struct {
+	ClientID  string    `json:"client_id"`
+	Name      string    `json:"name"`
+	SessionID string    `json:"session_id,omitempty"`
+	View      string    `json:"view,omitempty"`       // e.g. dashboard, session, transcript
+	MessageID string    `json:"message_id,omitempty"` // transcript position, if any
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// PresenceTracker holds the presence of viewers who opted in. Clients that
+// never send presence:join are not tracked or shown to anyone.
+type PresenceTracker struct {
+	mu      sync.RWMutex
+	viewers map[string]*ViewerPresence
+}
+
+// NewPresenceTracker creates an empty presence tracker
+func NewPresenceTracker() *PresenceTracker {
+	return &PresenceTracker{
+		viewers: make(map[string]*ViewerPresence),
+	}
+}
+
+// Join opts a client in under a display name and returns its presence
+func (p *PresenceTracker) Join(clientID, name string) ViewerPresence {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	viewer, exists := p.viewers[clientID]
+	if !exists {
+		viewer = &ViewerPresence{ClientID: clientID}
+		p.viewers[clientID] = viewer
+	}
+	viewer.Name = name
+	viewer.UpdatedAt = time.Now().UTC()
+	return *viewer
+}
+
+// Update records what an opted-in client is looking at. It reports false if
+// the client has not joined or nothing changed.
+func (p *PresenceTracker) Update(clientID, sessionID, view, messageID string) (ViewerPresence, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	viewer, exists := p.viewers[clientID]
+	if !exists {
+		return ViewerPresence{}, false
+	}
+	if viewer.SessionID == sessionID && viewer.View == view && viewer.MessageID == messageID {
+		return *viewer, false
+	}
+	viewer.SessionID = sessionID
+	viewer.View = view
+	viewer.MessageID = messageID
+	viewer.UpdatedAt = time.Now().UTC()
+	return *viewer, true
+}
+
+// Leave removes a client and reports whether it had opted in
+func (p *PresenceTracker) Leave(clientID string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	_, exists := p.viewers[clientID]
+	delete(p.viewers, clientID)
+	return exists
+}
+
+// Viewers returns every opted-in viewer, optionally only those on one session, by name
+func (p *PresenceTracker) Viewers(sessionID string) []ViewerPresence {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	viewers := make([]ViewerPresence, 0, len(p.viewers))
+	for _, viewer := range p.viewers {
+		if sessionID == "" || viewer.SessionID == sessionID {
+			viewers = append(viewers, *viewer)
+		}
+	}
+	sort.Slice(viewers, func(i, j int) bool {
+		if viewers[i].Name != viewers[j].Name {
+			return viewers[i].Name < viewers[j].Name
+		}
+		return viewers[i].ClientID < viewers[j].ClientID
+	})
+	return viewers
+}
+
+// handlePresenceMessage applies a presence:join, presence:update or
+// presence:leave message from a client and broadcasts the change
+func (c *WebSocketClient) handlePresenceMessage(msgType string, msg map[string]interface{}) {
+	presence := c.Hub.presence
+	if presence == nil {
+		return
+	}
+	field := func(name string) string {
+		value, _ := msg[name].(string)
+		return strings.TrimSpace(value)
+	}
+
+	switch msgType {
+	case "presence:join":
+		name := field("name")
+		if name == "" || len(name) > maxViewerNameLength {
+			c.sendJSON(gin.H{"type": "presence:error", "error": "name is required and must be at most 64 characters"})
+			return
+		}
+		viewer := presence.Join(c.ID, name)
+		c.Logger.WithFields(logrus.Fields{
+			"client_id": c.ID,
+			"name":      name,
+		}).Info("Client joined presence")
+
+		// Send the joining client everyone already present, then announce it
+		c.sendJSON(gin.H{
+			"type":      "presence:state",
+			"data":      PresenceStateData{ClientID: c.ID, Viewers: presence.Viewers("")},
+			"timestamp": time.Now().Unix(),
+		})
+		c.Hub.BroadcastUpdate("presence:update", PresenceUpdateData{Viewer: viewer})
+	case "presence:update":
+		if viewer, changed := presence.Update(c.ID, field("session_id"), field("view"), field("message_id")); changed {
+			c.Hub.BroadcastUpdate("presence:update", PresenceUpdateData{Viewer: viewer})
+		}
+	case "presence:leave":
+		c.leavePresence()
+	}
+}
+
+// leavePresence removes the client from presence and announces it if it had opted in
+func (c *WebSocketClient) leavePresence() {
+	if c.Hub.presence != nil && c.Hub.presence.Leave(c.ID) {
+		c.Hub.BroadcastUpdate("presence:leave", PresenceLeaveData{ClientID: c.ID})
+	}
+}
+
+// sendJSON queues a message for this client only, stamped with its
+// contract version
+func (c *WebSocketClient) sendJSON(message gin.H) {
+	message["version"] = c.Version()
+	data, err := json.Marshal(message)
+	if err != nil {
+		c.Logger.WithError(err).Error("Failed to marshal WebSocket message")
+		return
+	}
+	c.Send <- data
+}
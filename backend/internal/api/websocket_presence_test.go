@@ -0,0 +1,68 @@
+package api
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPresenceTracker(t *testing.T) {
+	tracker := NewPresenceTracker()
+
+	_, changed := tracker.Update("client-1", "session-1", "transcript", "")
+	assert.False(t, changed, "clients that have not joined are not tracked")
+
+	tracker.Join("client-1", "Alice")
+	tracker.Join("client-2", "Bob")
+
+	viewer, changed := tracker.Update("client-1", "session-1", "transcript", "msg-1")
+	assert.True(t, changed)
+	assert.Equal(t, "session-1", viewer.SessionID)
+
+	_, changed = tracker.Update("client-1", "session-1", "transcript", "msg-1")
+	assert.False(t, changed, "repeated updates are ignored")
+
+	viewers := tracker.Viewers("session-1")
+	require.Len(t, viewers, 1)
+	assert.Equal(t, "Alice", viewers[0].Name)
+	assert.Len(t, tracker.Viewers(""), 2)
+
+	assert.True(t, tracker.Leave("client-1"))
+	assert.False(t, tracker.Leave("client-1"))
+	assert.Empty(t, tracker.Viewers("session-1"))
+}
+
+func TestWebSocketClient_PresenceMessages(t *testing.T) {
+	logger := logrus.New()
+	hub := &WebSocketHub{
+		broadcast: make(chan outgoingMessage, 10),
+		logger:    logger,
+		presence:  NewPresenceTracker(),
+	}
+	client := &WebSocketClient{ID: "client-1", Send: make(chan []byte, 10), Hub: hub, Logger: logger}
+
+	decode := func(data []byte) map[string]interface{} {
+		var message map[string]interface{}
+		require.NoError(t, json.Unmarshal(data, &message))
+		return message
+	}
+
+	client.handlePresenceMessage("presence:join", map[string]interface{}{"name": ""})
+	assert.Equal(t, "presence:error", decode(<-client.Send)["type"])
+
+	client.handlePresenceMessage("presence:join", map[string]interface{}{"name": "Alice"})
+	assert.Equal(t, "presence:state", decode(<-client.Send)["type"])
+	assert.Equal(t, "presence:update", decode((<-hub.broadcast).data)["type"])
+
+	client.handlePresenceMessage("presence:update", map[string]interface{}{"session_id": "session-1", "view": "transcript"})
+	update := decode((<-hub.broadcast).data)
+	viewer := update["data"].(map[string]interface{})["viewer"].(map[string]interface{})
+	assert.Equal(t, "session-1", viewer["session_id"])
+
+	client.leavePresence()
+	assert.Equal(t, "presence:leave", decode((<-hub.broadcast).data)["type"])
+	assert.Empty(t, hub.presence.Viewers(""))
+}
@@ -0,0 +1,157 @@
+package api
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ksred/claude-session-manager/internal/database"
+	"github.com/sirupsen/logrus"
+)
+
+// TokenTotalsSource returns a session with its aggregated token usage
+type TokenTotalsSource func(sessionID string) (*database.SessionSummary, error)
+
+// TokenDelta is the usage a single message added to a session, broadcast as
+// token_delta so live tickers can add it without refetching the session
+type TokenDelta struct {
+	SessionID     string  `json:"session_id"`
+	MessageID     string  `json:"message_id"`
+	InputTokens   int     `json:"input_tokens"`
+	OutputTokens  int     `json:"output_tokens"`
+	EstimatedCost float64 `json:"estimated_cost"`
+}
+
+// SessionTokenTotals is what a session added during one totals interval and
+// its usage overall, broadcast in token_totals. Clients replace their running
+// figures with the totals, which also corrects any delta they missed.
+type SessionTokenTotals struct {
+	SessionID          string  `json:"session_id"`
+	InputTokens        int     `json:"input_tokens"`
+	OutputTokens       int     `json:"output_tokens"`
+	EstimatedCost      float64 `json:"estimated_cost"`
+	TotalInputTokens   int     `json:"total_input_tokens"`
+	TotalOutputTokens  int     `json:"total_output_tokens"`
+	TotalEstimatedCost float64 `json:"total_estimated_cost"`
+}
+
+// TokenAccumulator sums token deltas per session between totals broadcasts
+type TokenAccumulator struct {
+	mu      sync.Mutex
+	pending map[string]*SessionTokenTotals
+}
+
+// NewTokenAccumulator creates an empty token accumulator
+func NewTokenAccumulator() *TokenAccumulator {
+	return &TokenAccumulator{
+		pending: make(map[string]*SessionTokenTotals),
+	}
+}
+
+// Add adds a delta to its session's sums
+func (a *TokenAccumulator) Add(delta TokenDelta) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	totals, exists := a.pending[delta.SessionID]
+	if !exists {
+		totals = &SessionTokenTotals{SessionID: delta.SessionID}
+		a.pending[delta.SessionID] = totals
+	}
+	totals.InputTokens += delta.InputTokens
+	totals.OutputTokens += delta.OutputTokens
+	totals.EstimatedCost += delta.EstimatedCost
+}
+
+// Take returns the sums of every session that changed since the last call,
+// ordered by session ID, and starts over
+func (a *TokenAccumulator) Take() []SessionTokenTotals {
+	a.mu.Lock()
+	pending := a.pending
+	a.pending = make(map[string]*SessionTokenTotals)
+	a.mu.Unlock()
+
+	sessions := make([]SessionTokenTotals, 0, len(pending))
+	for _, totals := range pending {
+		sessions = append(sessions, *totals)
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].SessionID < sessions[j].SessionID
+	})
+	return sessions
+}
+
+// SetTokenTotalsSource sets where token_totals reads sessions' overall usage.
+// Without it only the interval's sums are sent.
+func (h *WebSocketHub) SetTokenTotalsSource(source TokenTotalsSource) {
+	h.tokenTotals = source
+}
+
+// BroadcastTokenDelta sends the usage a message added to its session as a
+// token_delta event and counts it towards the next token_totals
+func (h *WebSocketHub) BroadcastTokenDelta(sessionID string, usage *database.TokenUsage) {
+	delta := TokenDelta{
+		SessionID:     sessionID,
+		MessageID:     usage.MessageID,
+		InputTokens:   usage.InputTokens,
+		OutputTokens:  usage.OutputTokens,
+		EstimatedCost: usage.EstimatedCost,
+	}
+	h.tokens.Add(delta)
+	h.BroadcastUpdate("token_delta", delta)
+}
+
+// RunTokenTotals broadcasts token_totals every interval for the sessions
+// whose usage changed during it, until ctx is cancelled
+func (h *WebSocketHub) RunTokenTotals(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			h.broadcastTokenTotals(interval)
+		}
+	}
+}
+
+// broadcastTokenTotals sends the sums collected since the last call, if any
+func (h *WebSocketHub) broadcastTokenTotals(interval time.Duration) {
+	sessions := h.tokens.Take()
+	if len(sessions) == 0 {
+		return
+	}
+
+	if h.tokenTotals != nil {
+		// A session whose totals can't be read is left out rather than sent
+		// with zeros that clients would take as its usage
+		withTotals := sessions[:0]
+		for _, totals := range sessions {
+			summary, err := h.tokenTotals(totals.SessionID)
+			if err != nil {
+				h.logger.WithError(err).WithField("session_id", totals.SessionID).Warn("Failed to read session token totals")
+				continue
+			}
+			totals.TotalInputTokens = summary.TotalInputTokens
+			totals.TotalOutputTokens = summary.TotalOutputTokens
+			totals.TotalEstimatedCost = summary.TotalEstimatedCost
+			withTotals = append(withTotals, totals)
+		}
+		sessions = withTotals
+		if len(sessions) == 0 {
+			return
+		}
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"sessions": len(sessions),
+	}).Debug("Sending token totals to WebSocket hub for broadcast")
+
+	h.BroadcastUpdate("token_totals", TokenTotalsData{
+		IntervalSeconds: interval.Seconds(),
+		Sessions:        sessions,
+	})
+}
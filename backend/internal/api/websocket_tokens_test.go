@@ -0,0 +1,77 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ksred/claude-session-manager/internal/database"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenAccumulator(t *testing.T) {
+	accumulator := NewTokenAccumulator()
+	assert.Empty(t, accumulator.Take())
+
+	accumulator.Add(TokenDelta{SessionID: "session-2", InputTokens: 5, OutputTokens: 1, EstimatedCost: 0.25})
+	accumulator.Add(TokenDelta{SessionID: "session-1", InputTokens: 10, OutputTokens: 2, EstimatedCost: 0.5})
+	accumulator.Add(TokenDelta{SessionID: "session-1", InputTokens: 20, OutputTokens: 3, EstimatedCost: 1})
+
+	sessions := accumulator.Take()
+	require.Len(t, sessions, 2)
+	assert.Equal(t, SessionTokenTotals{SessionID: "session-1", InputTokens: 30, OutputTokens: 5, EstimatedCost: 1.5}, sessions[0])
+	assert.Equal(t, "session-2", sessions[1].SessionID)
+	assert.Empty(t, accumulator.Take(), "sums start over after each take")
+}
+
+func TestWebSocketHub_TokenEvents(t *testing.T) {
+	hub := &WebSocketHub{
+		broadcast: make(chan outgoingMessage, 10),
+		logger:    logrus.New(),
+		tokens:    NewTokenAccumulator(),
+	}
+	hub.SetTokenTotalsSource(func(sessionID string) (*database.SessionSummary, error) {
+		if sessionID == "gone" {
+			return nil, errors.New("session not found: gone")
+		}
+		return &database.SessionSummary{ID: sessionID, TotalInputTokens: 100, TotalOutputTokens: 40, TotalEstimatedCost: 2.5}, nil
+	})
+
+	decode := func(data []byte) map[string]interface{} {
+		var message map[string]interface{}
+		require.NoError(t, json.Unmarshal(data, &message))
+		return message
+	}
+
+	hub.BroadcastTokenDelta("session-1", &database.TokenUsage{MessageID: "msg-1", InputTokens: 10, OutputTokens: 4, TotalTokens: 14, EstimatedCost: 0.5})
+	hub.BroadcastTokenDelta("gone", &database.TokenUsage{MessageID: "msg-2", InputTokens: 1})
+	delta := decode((<-hub.broadcast).data)
+	assert.Equal(t, "token_delta", delta["type"])
+	assert.Equal(t, map[string]interface{}{
+		"session_id":     "session-1",
+		"message_id":     "msg-1",
+		"input_tokens":   float64(10),
+		"output_tokens":  float64(4),
+		"estimated_cost": 0.5,
+	}, delta["data"])
+	<-hub.broadcast
+
+	hub.broadcastTokenTotals(5 * time.Second)
+	totals := decode((<-hub.broadcast).data)
+	assert.Equal(t, "token_totals", totals["type"])
+	data := totals["data"].(map[string]interface{})
+	assert.Equal(t, float64(5), data["interval_seconds"])
+	sessions := data["sessions"].([]interface{})
+	require.Len(t, sessions, 1, "sessions whose totals can't be read are left out")
+	session := sessions[0].(map[string]interface{})
+	assert.Equal(t, "session-1", session["session_id"])
+	assert.Equal(t, float64(10), session["input_tokens"])
+	assert.Equal(t, float64(100), session["total_input_tokens"])
+	assert.Equal(t, 2.5, session["total_estimated_cost"])
+
+	hub.broadcastTokenTotals(5 * time.Second)
+	assert.Empty(t, hub.broadcast, "nothing is sent for an interval without usage")
+}
@@ -0,0 +1,209 @@
+// Package auth issues API keys and checks them on API requests. Keys are
+// only ever stored hashed; a read key may only make GET, HEAD and OPTIONS
+// requests, a write key any request.
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/claude-session-manager/internal/database"
+	"github.com/sirupsen/logrus"
+)
+
+// KeyPrefix starts every key, so leaked keys are easy to search for
+const KeyPrefix = "csm_"
+
+// ContextKey is the gin context key the authenticated *database.APIKey is
+// stored under
+const ContextKey = "api_key"
+
+// touchInterval is how often a key's last use is written to the database
+const touchInterval = time.Minute
+
+// Store is the storage keys are issued to and looked up in
+type Store interface {
+	CreateAPIKey(userName string, key *database.APIKey) error
+	GetActiveAPIKey(keyHash string) (*database.APIKey, error)
+	TouchAPIKey(id string, at time.Time) error
+}
+
+// HashKey returns the hex SHA-256 of a key, which is what's stored. Keys are
+// random, so an unsalted fast hash is enough.
+func HashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// Issue creates a key for the named user with the given scope and returns
+// the key itself, which can't be recovered afterwards
+func Issue(store Store, userName, name, scope string) (string, *database.APIKey, error) {
+	if userName == "" {
+		return "", nil, fmt.Errorf("a user is required")
+	}
+	if scope != database.ScopeRead && scope != database.ScopeWrite {
+		return "", nil, fmt.Errorf("invalid scope %q (expected read or write)", scope)
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+	plaintext := KeyPrefix + hex.EncodeToString(secret)
+
+	key := &database.APIKey{
+		Name:    name,
+		Prefix:  plaintext[:len(KeyPrefix)+8],
+		KeyHash: HashKey(plaintext),
+		Scope:   scope,
+	}
+	if err := store.CreateAPIKey(userName, key); err != nil {
+		return "", nil, err
+	}
+	return plaintext, key, nil
+}
+
+// Allows reports whether a key with the given scope may make a request with
+// the given method
+func Allows(scope, method string) bool {
+	switch scope {
+	case database.ScopeWrite:
+		return true
+	case database.ScopeRead:
+		return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+	default:
+		return false
+	}
+}
+
+// Authenticator checks the API key of each request
+type Authenticator struct {
+	store   Store
+	public  map[string]bool
+	queries map[string]bool // POST paths that only read
+	signed  []string        // path prefixes of URLs that carry their own signature
+	logger  *logrus.Logger
+	now     func() time.Time
+
+	mu      sync.Mutex
+	touched map[string]time.Time // when each key's last use was last written
+}
+
+// NewAuthenticator creates an authenticator that lets requests for the
+// public paths through without a key
+func NewAuthenticator(store Store, public []string, logger *logrus.Logger) *Authenticator {
+	a := &Authenticator{
+		store:   store,
+		public:  make(map[string]bool, len(public)),
+		queries: make(map[string]bool),
+		logger:  logger,
+		now:     time.Now,
+		touched: make(map[string]time.Time),
+	}
+	for _, path := range public {
+		a.public[path] = true
+	}
+	return a
+}
+
+// AllowQueries lets read keys POST to the given paths, for endpoints such as
+// Grafana's that take their query as a body but change nothing
+func (a *Authenticator) AllowQueries(paths ...string) {
+	for _, path := range paths {
+		a.queries[path] = true
+	}
+}
+
+// AllowSigned lets GET requests under the given path prefixes through
+// without a key, for download URLs that carry a signature their handler
+// checks instead
+func (a *Authenticator) AllowSigned(prefixes ...string) {
+	a.signed = append(a.signed, prefixes...)
+}
+
+// Middleware rejects requests without a valid key with 401 and requests the
+// key's scope doesn't allow with 403. The key is read from a Bearer
+// Authorization header, or from the api_key query parameter for clients
+// such as browser WebSockets that can't set headers.
+func (a *Authenticator) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if a.public[c.Request.URL.Path] || a.isSigned(c.Request) {
+			c.Next()
+			return
+		}
+
+		plaintext := requestKey(c.Request)
+		if plaintext == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "API key required"})
+			return
+		}
+
+		key, err := a.store.GetActiveAPIKey(HashKey(plaintext))
+		if err != nil {
+			a.logger.WithError(err).Error("Failed to look up API key")
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to check API key"})
+			return
+		}
+		if key == nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid or revoked API key"})
+			return
+		}
+		query := key.Scope == database.ScopeRead && c.Request.Method == http.MethodPost && a.queries[c.Request.URL.Path]
+		if !query && !Allows(key.Scope, c.Request.Method) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "API key is read-only"})
+			return
+		}
+
+		a.touch(key)
+		c.Set(ContextKey, key)
+		c.Next()
+	}
+}
+
+// isSigned reports whether a request is for a signed URL
+func (a *Authenticator) isSigned(r *http.Request) bool {
+	if r.Method != http.MethodGet {
+		return false
+	}
+	for _, prefix := range a.signed {
+		if strings.HasPrefix(r.URL.Path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// touch records a key's use, at most once per touchInterval so reads don't
+// each become a write
+func (a *Authenticator) touch(key *database.APIKey) {
+	now := a.now()
+	a.mu.Lock()
+	if last, ok := a.touched[key.ID]; ok && now.Sub(last) < touchInterval {
+		a.mu.Unlock()
+		return
+	}
+	a.touched[key.ID] = now
+	a.mu.Unlock()
+
+	if err := a.store.TouchAPIKey(key.ID, now); err != nil {
+		a.logger.WithError(err).Warn("Failed to record API key use")
+	}
+}
+
+// requestKey returns the key a request carries, if any
+func requestKey(r *http.Request) string {
+	if header := r.Header.Get("Authorization"); header != "" {
+		if token, ok := strings.CutPrefix(header, "Bearer "); ok {
+			return strings.TrimSpace(token)
+		}
+		return strings.TrimSpace(header)
+	}
+	return r.URL.Query().Get("api_key")
+}
@@ -0,0 +1,123 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/claude-session-manager/internal/database"
+	"github.com/sirupsen/logrus"
+)
+
+type fakeStore struct {
+	keys    map[string]*database.APIKey // by hash
+	touches int
+}
+
+func (s *fakeStore) CreateAPIKey(userName string, key *database.APIKey) error {
+	key.ID = "key-" + userName
+	key.UserName = userName
+	s.keys[key.KeyHash] = key
+	return nil
+}
+
+func (s *fakeStore) GetActiveAPIKey(keyHash string) (*database.APIKey, error) {
+	key := s.keys[keyHash]
+	if key == nil || key.RevokedAt != nil {
+		return nil, nil
+	}
+	return key, nil
+}
+
+func (s *fakeStore) TouchAPIKey(id string, at time.Time) error {
+	s.touches++
+	return nil
+}
+
+func TestIssue(t *testing.T) {
+	store := &fakeStore{keys: make(map[string]*database.APIKey)}
+
+	plaintext, key, err := Issue(store, "alice", "laptop", database.ScopeRead)
+	if err != nil {
+		t.Fatalf("Failed to issue key: %v", err)
+	}
+	if !strings.HasPrefix(plaintext, KeyPrefix) || !strings.HasPrefix(plaintext, key.Prefix) {
+		t.Errorf("Unexpected key %q with prefix %q", plaintext, key.Prefix)
+	}
+	if key.KeyHash != HashKey(plaintext) || strings.Contains(key.KeyHash, plaintext) {
+		t.Errorf("Expected only the key's hash to be stored, got %q", key.KeyHash)
+	}
+
+	if _, _, err := Issue(store, "alice", "laptop", "admin"); err == nil {
+		t.Error("Expected an invalid scope to be rejected")
+	}
+	if _, _, err := Issue(store, "", "laptop", database.ScopeRead); err == nil {
+		t.Error("Expected a missing user to be rejected")
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := &fakeStore{keys: make(map[string]*database.APIKey)}
+	readKey, _, _ := Issue(store, "reader", "", database.ScopeRead)
+	writeKey, _, _ := Issue(store, "writer", "", database.ScopeWrite)
+	revokedKey, revoked, _ := Issue(store, "gone", "", database.ScopeWrite)
+	now := time.Now()
+	revoked.RevokedAt = &now
+
+	router := gin.New()
+	authenticator := NewAuthenticator(store, []string{"/api/v1/health"}, logrus.New())
+	authenticator.AllowQueries("/api/v1/grafana/query")
+	authenticator.AllowSigned("/api/v1/downloads/")
+	router.Use(authenticator.Middleware())
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	router.GET("/api/v1/health", ok)
+	router.GET("/api/v1/sessions", ok)
+	router.PUT("/api/v1/pricing", ok)
+	router.POST("/api/v1/grafana/query", ok)
+	router.PUT("/api/v1/grafana/query", ok)
+	router.GET("/api/v1/downloads/exports/:id", ok)
+	router.DELETE("/api/v1/downloads/exports/:id", ok)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		header string
+		want   int
+	}{
+		{"public path", http.MethodGet, "/api/v1/health", "", http.StatusOK},
+		{"no key", http.MethodGet, "/api/v1/sessions", "", http.StatusUnauthorized},
+		{"unknown key", http.MethodGet, "/api/v1/sessions", "Bearer csm_nope", http.StatusUnauthorized},
+		{"revoked key", http.MethodGet, "/api/v1/sessions", "Bearer " + revokedKey, http.StatusUnauthorized},
+		{"read key reads", http.MethodGet, "/api/v1/sessions", "Bearer " + readKey, http.StatusOK},
+		{"read key writes", http.MethodPut, "/api/v1/pricing", "Bearer " + readKey, http.StatusForbidden},
+		{"read key queries", http.MethodPost, "/api/v1/grafana/query", "Bearer " + readKey, http.StatusOK},
+		{"read key writes to a query path", http.MethodPut, "/api/v1/grafana/query", "Bearer " + readKey, http.StatusForbidden},
+		{"write key writes", http.MethodPut, "/api/v1/pricing", "Bearer " + writeKey, http.StatusOK},
+		{"query parameter", http.MethodGet, "/api/v1/sessions?api_key=" + readKey, "", http.StatusOK},
+		{"signed download", http.MethodGet, "/api/v1/downloads/exports/1?signature=abc", "", http.StatusOK},
+		{"write to a signed path", http.MethodDelete, "/api/v1/downloads/exports/1", "", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Errorf("Expected %d, got %d: %s", tt.want, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	// Both keys were used several times but their use is only written once
+	if store.touches != 2 {
+		t.Errorf("Expected 2 writes of last use, got %d", store.touches)
+	}
+}
@@ -0,0 +1,199 @@
+// Package budget tracks spend against the configured daily and monthly
+// budgets and raises an alert the first time a budget crosses each of its
+// thresholds in a period.
+package budget
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ksred/claude-session-manager/internal/database"
+	"github.com/sirupsen/logrus"
+)
+
+// Thresholds are the percentages of a budget's limit that raise an alert
+var Thresholds = []int{80, 100}
+
+// Budget states
+const (
+	StateOK       = "ok"
+	StateWarning  = "warning"
+	StateExceeded = "exceeded"
+)
+
+// Status is a budget's spend in its current period
+type Status struct {
+	Budget       database.Budget `json:"budget"`
+	PeriodStart  time.Time       `json:"period_start"`
+	PeriodEnd    time.Time       `json:"period_end"`
+	SpendUSD     float64         `json:"spend_usd"`
+	RemainingUSD float64         `json:"remaining_usd"`
+	Percent      float64         `json:"percent"`
+	State        string          `json:"state"`
+}
+
+// Alert is a budget crossing a threshold, sent to WebSocket clients as
+// budget_alert
+type Alert struct {
+	BudgetID    string    `json:"budget_id"`
+	Name        string    `json:"name"`
+	ProjectName *string   `json:"project_name,omitempty"`
+	Period      string    `json:"period"`
+	PeriodStart time.Time `json:"period_start"`
+	Threshold   int       `json:"threshold"`
+	SpendUSD    float64   `json:"spend_usd"`
+	LimitUSD    float64   `json:"limit_usd"`
+	TriggeredAt time.Time `json:"triggered_at"`
+}
+
+// Bounds returns the start and exclusive end of the period containing now,
+// in now's time zone
+func Bounds(period string, now time.Time) (time.Time, time.Time, error) {
+	switch period {
+	case database.BudgetDaily:
+		start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+		return start, start.AddDate(0, 0, 1), nil
+	case database.BudgetMonthly:
+		start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+		return start, start.AddDate(0, 1, 0), nil
+	default:
+		return time.Time{}, time.Time{}, fmt.Errorf("period must be daily or monthly")
+	}
+}
+
+// Monitor checks budgets against spend and raises alerts
+type Monitor struct {
+	repo   *database.SessionRepository
+	logger *logrus.Logger
+	now    func() time.Time
+
+	mu      sync.Mutex
+	onAlert func(Alert)
+}
+
+// NewMonitor creates a monitor for the budgets stored in repo. Periods start
+// at local midnight.
+func NewMonitor(repo *database.SessionRepository, logger *logrus.Logger) *Monitor {
+	return &Monitor{
+		repo:   repo,
+		logger: logger,
+		now:    time.Now,
+	}
+}
+
+// OnAlert sets the function called for each new alert
+func (m *Monitor) OnAlert(fn func(Alert)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onAlert = fn
+}
+
+// Status returns the spend of every budget in its current period
+func (m *Monitor) Status() ([]Status, error) {
+	budgets, err := m.repo.GetBudgets()
+	if err != nil {
+		return nil, err
+	}
+
+	now := m.now()
+	statuses := make([]Status, 0, len(budgets))
+	for _, b := range budgets {
+		status, err := m.status(b, now)
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, *status)
+	}
+	return statuses, nil
+}
+
+func (m *Monitor) status(b database.Budget, now time.Time) (*Status, error) {
+	start, end, err := Bounds(b.Period, now)
+	if err != nil {
+		return nil, fmt.Errorf("budget %s: %w", b.ID, err)
+	}
+	spend, err := m.repo.GetSpend(b.ProjectName, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &Status{
+		Budget:       b,
+		PeriodStart:  start,
+		PeriodEnd:    end,
+		SpendUSD:     spend,
+		RemainingUSD: b.LimitUSD - spend,
+		State:        StateOK,
+	}
+	if status.RemainingUSD < 0 {
+		status.RemainingUSD = 0
+	}
+	if b.LimitUSD > 0 {
+		status.Percent = spend / b.LimitUSD * 100
+	}
+	switch {
+	case status.Percent >= float64(Thresholds[len(Thresholds)-1]):
+		status.State = StateExceeded
+	case status.Percent >= float64(Thresholds[0]):
+		status.State = StateWarning
+	}
+	return status, nil
+}
+
+// Check raises an alert for each threshold a budget has crossed in its
+// current period that it hadn't already alerted on, returning the new alerts
+func (m *Monitor) Check() ([]Alert, error) {
+	statuses, err := m.Status()
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	onAlert := m.onAlert
+	m.mu.Unlock()
+
+	var alerts []Alert
+	for _, status := range statuses {
+		for _, threshold := range Thresholds {
+			if status.Percent < float64(threshold) {
+				break
+			}
+			alert := Alert{
+				BudgetID:    status.Budget.ID,
+				Name:        status.Budget.Name,
+				ProjectName: status.Budget.ProjectName,
+				Period:      status.Budget.Period,
+				PeriodStart: status.PeriodStart,
+				Threshold:   threshold,
+				SpendUSD:    status.SpendUSD,
+				LimitUSD:    status.Budget.LimitUSD,
+				TriggeredAt: m.now().UTC(),
+			}
+			isNew, err := m.repo.RecordBudgetAlert(&database.BudgetAlert{
+				BudgetID:    alert.BudgetID,
+				PeriodStart: alert.PeriodStart,
+				Threshold:   threshold,
+				SpendUSD:    alert.SpendUSD,
+				TriggeredAt: alert.TriggeredAt,
+			})
+			if err != nil {
+				return alerts, err
+			}
+			if !isNew {
+				continue
+			}
+
+			m.logger.WithFields(logrus.Fields{
+				"budget":    alert.Name,
+				"threshold": threshold,
+				"spend_usd": alert.SpendUSD,
+			}).Warn("Budget threshold crossed")
+			alerts = append(alerts, alert)
+			if onAlert != nil {
+				onAlert(alert)
+			}
+		}
+	}
+	return alerts, nil
+}
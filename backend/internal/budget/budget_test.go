@@ -0,0 +1,127 @@
+package budget
+
+import (
+	"math"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ksred/claude-session-manager/internal/database"
+	"github.com/sirupsen/logrus"
+)
+
+func setupTestRepo(t *testing.T) *database.SessionRepository {
+	tmpFile, err := os.CreateTemp("", "test-budget-*.db")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	tmpFile.Close()
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	db, err := database.NewDatabase(database.Config{DatabasePath: tmpFile.Name(), Logger: logger})
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	t.Cleanup(func() {
+		db.Close()
+		os.Remove(tmpFile.Name())
+	})
+
+	return database.NewSessionRepository(db, logger)
+}
+
+func addSession(t *testing.T, repo *database.SessionRepository, sessionID, project string, at time.Time) {
+	t.Helper()
+	if err := repo.UpsertSession(&database.Session{ID: sessionID, ProjectPath: "/work/" + project, ProjectName: project, StartTime: at, LastActivity: at, Status: "active"}); err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+}
+
+func addSpend(t *testing.T, repo *database.SessionRepository, sessionID, messageID string, at time.Time, cost float64) {
+	t.Helper()
+	if err := repo.UpsertMessage(&database.Message{ID: messageID, SessionID: sessionID, Role: "assistant", Timestamp: at}); err != nil {
+		t.Fatalf("Failed to create message: %v", err)
+	}
+	if err := repo.UpsertTokenUsage(&database.TokenUsage{MessageID: messageID, SessionID: sessionID, TotalTokens: 100, EstimatedCost: cost}); err != nil {
+		t.Fatalf("Failed to create token usage: %v", err)
+	}
+}
+
+func TestBounds(t *testing.T) {
+	now := time.Date(2026, 2, 14, 15, 30, 0, 0, time.UTC)
+	start, end, _ := Bounds(database.BudgetDaily, now)
+	if !start.Equal(time.Date(2026, 2, 14, 0, 0, 0, 0, time.UTC)) || !end.Equal(start.AddDate(0, 0, 1)) {
+		t.Errorf("Unexpected day %v to %v", start, end)
+	}
+	start, end, _ = Bounds(database.BudgetMonthly, now)
+	if !start.Equal(time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)) || !end.Equal(time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected month %v to %v", start, end)
+	}
+	if _, _, err := Bounds("weekly", now); err == nil {
+		t.Error("Expected an unknown period to be rejected")
+	}
+}
+
+func TestMonitor_Check(t *testing.T) {
+	repo := setupTestRepo(t)
+	now := time.Date(2026, 2, 14, 15, 0, 0, 0, time.UTC)
+
+	addSession(t, repo, "s1", "payments", now.AddDate(0, 0, -1))
+	addSession(t, repo, "s2", "search", now.Add(-time.Hour))
+
+	// Yesterday's spend and other projects' spend don't count
+	addSpend(t, repo, "s1", "m1", now.Add(-time.Hour), 8.5)
+	addSpend(t, repo, "s1", "m0", now.AddDate(0, 0, -1), 50)
+	addSpend(t, repo, "s2", "m2", now.Add(-time.Hour), 4)
+
+	project := "payments"
+	daily := &database.Budget{Name: "payments daily", ProjectName: &project, Period: database.BudgetDaily, LimitUSD: 10}
+	monthly := &database.Budget{Name: "everything", Period: database.BudgetMonthly, LimitUSD: 1000}
+	for _, b := range []*database.Budget{daily, monthly} {
+		if err := repo.CreateBudget(b); err != nil {
+			t.Fatalf("Failed to create budget: %v", err)
+		}
+	}
+
+	monitor := NewMonitor(repo, logrus.New())
+	monitor.now = func() time.Time { return now }
+	var notified []Alert
+	monitor.OnAlert(func(alert Alert) { notified = append(notified, alert) })
+
+	statuses, err := monitor.Status()
+	if err != nil {
+		t.Fatalf("Failed to get status: %v", err)
+	}
+	if len(statuses) != 2 || statuses[0].Budget.ID != monthly.ID {
+		t.Fatalf("Expected the global budget first, got %+v", statuses)
+	}
+	if payments := statuses[1]; math.Abs(payments.SpendUSD-8.5) > 1e-9 || payments.State != StateWarning || math.Abs(payments.RemainingUSD-1.5) > 1e-9 {
+		t.Errorf("Expected $8.50 spent and a warning, got %+v", payments)
+	}
+
+	alerts, err := monitor.Check()
+	if err != nil {
+		t.Fatalf("Failed to check budgets: %v", err)
+	}
+	if len(alerts) != 1 || alerts[0].Threshold != 80 || alerts[0].BudgetID != daily.ID || len(notified) != 1 {
+		t.Fatalf("Expected one 80%% alert, got %+v", alerts)
+	}
+
+	// An alert fires once per threshold per period
+	if alerts, _ := monitor.Check(); len(alerts) != 0 {
+		t.Errorf("Expected no repeat alerts, got %+v", alerts)
+	}
+
+	addSpend(t, repo, "s1", "m3", now.Add(-time.Minute), 2)
+	alerts, _ = monitor.Check()
+	if len(alerts) != 1 || alerts[0].Threshold != 100 {
+		t.Errorf("Expected a 100%% alert, got %+v", alerts)
+	}
+
+	// A new day starts over
+	monitor.now = func() time.Time { return now.AddDate(0, 0, 1) }
+	if alerts, _ := monitor.Check(); len(alerts) != 0 {
+		t.Errorf("Expected nothing spent yet today, got %+v", alerts)
+	}
+}
@@ -36,6 +36,58 @@ type ClaudeResponse struct {
 	DurationMs  int     `json:"duration_ms,omitempty"`
 	NumTurns    int     `json:"num_turns,omitempty"`
 	TotalCostUSD float64 `json:"total_cost_usd,omitempty"`
+	Usage       *ClaudeUsage `json:"usage,omitempty"`
+	ModelUsage  map[string]ClaudeModelUsage `json:"modelUsage,omitempty"`
+}
+
+// ClaudeUsage is the tokens a run used across all of its turns
+type ClaudeUsage struct {
+	InputTokens              int    `json:"input_tokens"`
+	OutputTokens             int    `json:"output_tokens"`
+	CacheCreationInputTokens int    `json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int    `json:"cache_read_input_tokens"`
+	ServiceTier              string `json:"service_tier"`
+}
+
+// ClaudeModelUsage is what a run cost on one model
+type ClaudeModelUsage struct {
+	CostUSD float64 `json:"costUSD"`
+}
+
+// Model returns the model a run spent the most on, or "" when the CLI
+// didn't report it
+func (r *ClaudeResponse) Model() string {
+	model, cost := "", -1.0
+	for name, usage := range r.ModelUsage {
+		if usage.CostUSD > cost || (usage.CostUSD == cost && name < model) {
+			model, cost = name, usage.CostUSD
+		}
+	}
+	return model
+}
+
+// CLIOutput is a reply from a chat process with the figures the CLI reported
+// for the run that produced it
+type CLIOutput struct {
+	Content  string
+	Response *ClaudeResponse // nil when the CLI's output wasn't JSON
+}
+
+// Reasons a chat process is stopped
+const (
+	StopReasonEnded    = "ended"    // the chat was ended
+	StopReasonInactive = "inactive" // unused for longer than the inactive timeout
+	StopReasonShutdown = "shutdown" // the server is shutting down
+)
+
+// cleanupInterval is how often Start looks for inactive processes
+const cleanupInterval = time.Minute
+
+// ProcessStats counts a CLI manager's chat processes
+type ProcessStats struct {
+	Live    int              `json:"live"`
+	Started int64            `json:"started"`
+	Stopped map[string]int64 `json:"stopped"` // by reason
 }
 
 // CLIManager manages Claude CLI processes for chat sessions
@@ -45,10 +97,17 @@ type CLIManager struct {
 	processes         map[string]*CLIProcess
 	mutex             sync.RWMutex
 	
-	// Configuration
-	maxProcesses    int
-	processTimeout  time.Duration
-	inactiveTimeout time.Duration
+	// Configuration, and the queue requests wait in for a process
+	limits Limits
+	queue  *queue
+	policy PermissionPolicy
+
+	// Lifecycle: message handlers still running, counts for metrics, and
+	// whether Shutdown has been called
+	handlers sync.WaitGroup
+	started  int64
+	stopped  map[string]int64
+	closed   bool
 }
 
 // CLIProcess represents a Claude chat session
@@ -60,7 +119,7 @@ type CLIProcess struct {
 	
 	// Communication channels
 	InputChan  chan string
-	OutputChan chan string
+	OutputChan chan CLIOutput
 	ErrorChan  chan error
 	StopChan   chan struct{}
 	
@@ -80,26 +139,81 @@ type CLIProcess struct {
 	
 	// Store the project directory for setting working directory
 	projectPath string
+
+	// Tool permissions each message's command runs with
+	permissions Permissions
+}
+
+// Limits bound the Claude CLI processes a CLI manager runs. Chat messages
+// and prompts beyond MaxProcesses wait for one to finish.
+type Limits struct {
+	MaxProcesses    int           // run at once; 0 for no limit
+	MaxQueued       int           // requests waiting before more are refused; 0 for no limit
+	QueueTimeout    time.Duration // a request waits for a process; 0 to wait as long as the request lasts
+	CommandTimeout  time.Duration // a chat message's process may run; 0 for no limit
+	InactiveTimeout time.Duration // before an unused chat is stopped; 0 to keep chats until they end
 }
 
-// NewCLIManager creates a new CLI manager
+// DefaultLimits returns the limits NewCLIManager uses
+func DefaultLimits() Limits {
+	return Limits{
+		MaxProcesses:    10,
+		MaxQueued:       100,
+		QueueTimeout:    5 * time.Minute,
+		CommandTimeout:  5 * time.Minute,
+		InactiveTimeout: 30 * time.Minute,
+	}
+}
+
+// NewCLIManager creates a new CLI manager with the default limits
 func NewCLIManager(repository *Repository, sessionRepository SessionRepository) *CLIManager {
+	return NewCLIManagerWithLimits(repository, sessionRepository, DefaultLimits())
+}
+
+// NewCLIManagerWithLimits creates a new CLI manager with the given limits
+func NewCLIManagerWithLimits(repository *Repository, sessionRepository SessionRepository, limits Limits) *CLIManager {
 	return &CLIManager{
 		repository:        repository,
 		sessionRepository: sessionRepository,
 		processes:         make(map[string]*CLIProcess),
-		maxProcesses:      10, // Configurable limit
-		processTimeout:    5 * time.Minute,
-		inactiveTimeout:   30 * time.Minute,
+		stopped:           make(map[string]int64),
+		limits:            limits,
+		queue:             newQueue(limits.MaxProcesses, limits.MaxQueued, limits.QueueTimeout),
 	}
 }
 
+// SetPermissionPolicy sets the permissions chats started through the API
+// may ask for
+func (m *CLIManager) SetPermissionPolicy(policy PermissionPolicy) {
+	m.policy = policy
+}
+
+// CheckPermissions returns an error if the permission policy doesn't allow
+// a chat to ask for perms
+func (m *CLIManager) CheckPermissions(perms Permissions) error {
+	return m.policy.Check(perms)
+}
+
 // StartChatSession starts a new Claude CLI process for the given session
+// with the CLI's default permissions
 func (m *CLIManager) StartChatSession(sessionID string) (*ChatSession, error) {
+	return m.StartChatSessionWithPermissions(sessionID, Permissions{})
+}
+
+// StartChatSessionWithPermissions starts a new Claude CLI process for the
+// given session whose messages run with perms. If the session already has
+// one, its messages run with perms from then on. perms aren't checked
+// against the permission policy; callers acting for API clients should call
+// CheckPermissions first.
+func (m *CLIManager) StartChatSessionWithPermissions(sessionID string, perms Permissions) (*ChatSession, error) {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
 
 	fmt.Printf("[CLI_MANAGER] StartChatSession called for session: %s\n", sessionID)
+
+	if m.closed {
+		return nil, fmt.Errorf("chat is shutting down")
+	}
 	
 	// Get session data to determine project path
 	sessionData, err := m.sessionRepository.GetSessionByID(sessionID)
@@ -112,9 +226,10 @@ func (m *CLIManager) StartChatSession(sessionID string) (*ChatSession, error) {
 	if existingProcess, exists := m.processes[sessionID]; exists {
 		if existingProcess.Status == StatusActive {
 			fmt.Printf("[CLI_MANAGER] Found existing active process for session: %s\n", sessionID)
-			// Update last used time
+			// Update last used time and permissions
 			existingProcess.mutex.Lock()
 			existingProcess.LastUsed = time.Now()
+			existingProcess.permissions = perms
 			existingProcess.mutex.Unlock()
 			
 			// Return existing chat session
@@ -137,6 +252,7 @@ func (m *CLIManager) StartChatSession(sessionID string) (*ChatSession, error) {
 		// Set the Claude session ID for continuation
 		process.claudeSessionID = *existingChatSession.ClaudeSessionID
 		process.isFirstMessage = false
+		process.permissions = perms
 		
 		// Start the process
 		fmt.Printf("[CLI_MANAGER] Starting process for session: %s with existing Claude session: %s\n", sessionID, process.claudeSessionID)
@@ -155,12 +271,6 @@ func (m *CLIManager) StartChatSession(sessionID string) (*ChatSession, error) {
 		return existingChatSession, nil
 	}
 
-	// Check process limits
-	if len(m.processes) >= m.maxProcesses {
-		fmt.Printf("[CLI_MANAGER] Process limit reached: %d/%d\n", len(m.processes), m.maxProcesses)
-		return nil, fmt.Errorf("maximum number of processes reached: %d", m.maxProcesses)
-	}
-
 	fmt.Printf("[CLI_MANAGER] Creating new CLI process for session: %s\n", sessionID)
 	
 	// Create new CLI process
@@ -169,6 +279,7 @@ func (m *CLIManager) StartChatSession(sessionID string) (*ChatSession, error) {
 		fmt.Printf("[CLI_MANAGER] Failed to create CLI process: %v\n", err)
 		return nil, fmt.Errorf("failed to create CLI process: %w", err)
 	}
+	process.permissions = perms
 
 	// Start the process
 	fmt.Printf("[CLI_MANAGER] Starting process for session: %s\n", sessionID)
@@ -188,8 +299,7 @@ func (m *CLIManager) StartChatSession(sessionID string) (*ChatSession, error) {
 	if err != nil {
 		fmt.Printf("[CLI_MANAGER] Failed to create chat session in DB: %v\n", err)
 		// Cleanup process if database creation fails
-		m.stopProcess(process)
-		delete(m.processes, sessionID)
+		m.removeProcess(sessionID, process, StopReasonEnded)
 		return nil, fmt.Errorf("failed to create chat session: %w", err)
 	}
 
@@ -239,8 +349,7 @@ func (m *CLIManager) StopChatSession(sessionID string) error {
 	}
 
 	// Stop the process
-	err := m.stopProcess(process)
-	delete(m.processes, sessionID)
+	err := m.removeProcess(sessionID, process, StopReasonEnded)
 
 	// Update database
 	chatSession, dbErr := m.repository.GetChatSessionBySessionID(sessionID)
@@ -266,7 +375,7 @@ func (m *CLIManager) createCLIProcess(sessionID, projectPath string) (*CLIProces
 		StartedAt:      time.Now(),
 		LastUsed:       time.Now(),
 		InputChan:      make(chan string, 100),
-		OutputChan:     make(chan string, 100),
+		OutputChan:     make(chan CLIOutput, 100),
 		ErrorChan:      make(chan error, 50),
 		StopChan:       make(chan struct{}),
 		ctx:            ctx,
@@ -284,8 +393,13 @@ func (m *CLIManager) createCLIProcess(sessionID, projectPath string) (*CLIProces
 func (m *CLIManager) startProcess(process *CLIProcess) error {
 	fmt.Printf("[CLI_START] Session %s: Starting Claude chat session\n", process.SessionID)
 
-	// Start message handler goroutine
-	go m.handleMessages(process)
+	// Start message handler goroutine, which Shutdown waits for
+	m.handlers.Add(1)
+	m.started++
+	go func() {
+		defer m.handlers.Done()
+		m.handleMessages(process)
+	}()
 
 	fmt.Printf("[CLI_START] Session %s: Message handler started\n", process.SessionID)
 
@@ -303,37 +417,37 @@ func (m *CLIManager) handleMessages(process *CLIProcess) {
 			func() {
 				// Build command
 				var cmd *exec.Cmd
-				claudePath := "claude" // Try to find claude in PATH first
+				claudePath := findClaudeBinary()
 				
-				// Check if claude is in the expected location
-				if _, err := exec.LookPath("claude"); err != nil {
-					// Try common installation paths
-					homeDir, _ := os.UserHomeDir()
-					possiblePaths := []string{
-						filepath.Join(homeDir, ".npm-global", "bin", "claude"),
-						filepath.Join(homeDir, ".local", "bin", "claude"),
-						"/usr/local/bin/claude",
-						"/opt/homebrew/bin/claude",
-					}
-					
-					for _, path := range possiblePaths {
-						if _, err := os.Stat(path); err == nil {
-							claudePath = path
-							break
-						}
+				fmt.Printf("[CLI_COMMAND] Using claude at: %s\n", claudePath)
+				
+				// Wait for a process slot, taking turns with other sessions
+				release, err := m.queue.acquire(process.ctx, process.SessionID, QueueKindChat)
+				if err != nil {
+					fmt.Printf("[CLI_ERROR] Session %s: No process available: %v\n", process.SessionID, err)
+					select {
+					case process.ErrorChan <- fmt.Errorf("failed to get a chat process: %w", err):
+					default:
 					}
+					return
 				}
+				defer release()
 				
-				fmt.Printf("[CLI_COMMAND] Using claude at: %s\n", claudePath)
-				
-				// Create a timeout context for this specific command (increased timeout for long Claude responses)
-				cmdCtx, cmdCancel := context.WithTimeout(process.ctx, 5*time.Minute)
+				// Create a timeout context for this specific command
+				cmdCtx, cmdCancel := process.ctx, context.CancelFunc(func() {})
+				if m.limits.CommandTimeout > 0 {
+					cmdCtx, cmdCancel = context.WithTimeout(process.ctx, m.limits.CommandTimeout)
+				}
 				defer cmdCancel() // This will be called when the anonymous function returns
 				
+				process.mutex.RLock()
+				permissionArgs := process.permissions.args()
+				process.mutex.RUnlock()
+				
 				if process.isFirstMessage {
 					// First message - start new conversation with JSON output to get session ID
-					cmd = exec.CommandContext(cmdCtx, claudePath, "--print", "--output-format", "json", message)
-					fmt.Printf("[CLI_COMMAND] Session %s: Running first message command: %s --print --output-format json \"%s\"\n", process.SessionID, claudePath, message)
+					cmd = exec.CommandContext(cmdCtx, claudePath, promptArgs(permissionArgs, message)...)
+					fmt.Printf("[CLI_COMMAND] Session %s: Running first message command: %s --print --output-format json -- \"%s\"\n", process.SessionID, claudePath, message)
 					process.isFirstMessage = false
 				} else {
 					// Continue existing conversation using session ID with JSON output
@@ -345,8 +459,8 @@ func (m *CLIManager) handleMessages(process *CLIProcess) {
 						}
 						return
 					}
-					cmd = exec.CommandContext(cmdCtx, claudePath, "--print", "--output-format", "json", "--resume", process.claudeSessionID, message)
-					fmt.Printf("[CLI_COMMAND] Session %s: Running continuation command: %s --print --output-format json --resume %s \"%s\"\n", process.SessionID, claudePath, process.claudeSessionID, message)
+					cmd = exec.CommandContext(cmdCtx, claudePath, promptArgs(append([]string{"--resume", process.claudeSessionID}, permissionArgs...), message)...)
+					fmt.Printf("[CLI_COMMAND] Session %s: Running continuation command: %s --print --output-format json --resume %s -- \"%s\"\n", process.SessionID, claudePath, process.claudeSessionID, message)
 				}
 				
 				// Set working directory if project path is available
@@ -416,11 +530,11 @@ func (m *CLIManager) handleMessages(process *CLIProcess) {
 				response := strings.TrimSpace(string(output))
 				fmt.Printf("[CLI_RESPONSE] Session %s: Got response (%d bytes)\n", process.SessionID, len(response))
 				
-				var finalResponse string
+				var finalResponse CLIOutput
 				var claudeResp ClaudeResponse
 				if err := json.Unmarshal([]byte(response), &claudeResp); err != nil {
 					fmt.Printf("[CLI_ERROR] Session %s: Failed to parse JSON response: %v\n", process.SessionID, err)
-					finalResponse = response // Fall back to raw response
+					finalResponse.Content = response // Fall back to raw response
 				} else {
 					// Always extract and store the Claude session ID (it might change)
 					if claudeResp.SessionID != "" {
@@ -433,8 +547,9 @@ func (m *CLIManager) handleMessages(process *CLIProcess) {
 						}
 					}
 					
-					// Use the actual response text
-					finalResponse = claudeResp.Result
+					// Use the actual response text, keeping the run's figures for the transcript
+					finalResponse.Content = claudeResp.Result
+					finalResponse.Response = &claudeResp
 				}
 				
 				select {
@@ -455,6 +570,18 @@ func (m *CLIManager) handleMessages(process *CLIProcess) {
 	}
 }
 
+// removeProcess stops a process and drops it from the manager, so nothing
+// holds on to its channels once its message handler has exited. The caller
+// must hold m.mutex.
+func (m *CLIManager) removeProcess(sessionID string, process *CLIProcess, reason string) error {
+	err := m.stopProcess(process)
+	if m.processes[sessionID] == process {
+		delete(m.processes, sessionID)
+	}
+	m.stopped[reason]++
+	return err
+}
+
 // stopProcess stops a CLI process and cleans up resources
 func (m *CLIManager) stopProcess(process *CLIProcess) error {
 	process.mutex.Lock()
@@ -475,10 +602,14 @@ func (m *CLIManager) stopProcess(process *CLIProcess) error {
 
 // CleanupInactiveProcesses removes processes that have been inactive
 func (m *CLIManager) CleanupInactiveProcesses() error {
+	if m.limits.InactiveTimeout <= 0 {
+		return nil
+	}
+
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
 
-	cutoffTime := time.Now().Add(-m.inactiveTimeout)
+	cutoffTime := time.Now().Add(-m.limits.InactiveTimeout)
 	var toDelete []string
 
 	for sessionID, process := range m.processes {
@@ -494,8 +625,7 @@ func (m *CLIManager) CleanupInactiveProcesses() error {
 	// Stop and remove inactive processes
 	for _, sessionID := range toDelete {
 		if process, exists := m.processes[sessionID]; exists {
-			m.stopProcess(process)
-			delete(m.processes, sessionID)
+			m.removeProcess(sessionID, process, StopReasonInactive)
 			
 			// Update database
 			if chatSession, err := m.repository.GetChatSessionBySessionID(sessionID); err == nil && chatSession != nil {
@@ -507,8 +637,73 @@ func (m *CLIManager) CleanupInactiveProcesses() error {
 	return nil
 }
 
+// Start stops processes unused for longer than the inactive timeout every
+// minute until ctx is cancelled. Processes still running then are left for
+// Shutdown.
+func (m *CLIManager) Start(ctx context.Context) {
+	ticker := time.NewTicker(cleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.CleanupInactiveProcesses()
+		}
+	}
+}
+
+// Shutdown stops every process, cancelling any CLI command in flight, and
+// waits until their message handlers have exited or ctx is done. Chat
+// sessions stay active in the database so they resume after a restart. No
+// chat can be started afterwards.
+func (m *CLIManager) Shutdown(ctx context.Context) error {
+	m.mutex.Lock()
+	m.closed = true
+	for sessionID, process := range m.processes {
+		m.removeProcess(sessionID, process, StopReasonShutdown)
+	}
+	m.mutex.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		m.handlers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("chat message handlers still running: %w", ctx.Err())
+	}
+}
+
+// Stats returns how many processes are live and how many have been started
+// and stopped
+func (m *CLIManager) Stats() ProcessStats {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	stats := ProcessStats{
+		Live:    len(m.processes),
+		Started: m.started,
+		Stopped: make(map[string]int64, len(m.stopped)),
+	}
+	for reason, count := range m.stopped {
+		stats.Stopped[reason] = count
+	}
+	return stats
+}
+
+// QueueStatus returns the chat messages and prompts running and waiting for
+// a Claude CLI process
+func (m *CLIManager) QueueStatus() QueueStatus {
+	return m.queue.status()
+}
+
 // GetProcessOutput gets output from a specific process
-func (m *CLIManager) GetProcessOutput(sessionID string) ([]string, error) {
+func (m *CLIManager) GetProcessOutput(sessionID string) ([]CLIOutput, error) {
 	m.mutex.RLock()
 	process, exists := m.processes[sessionID]
 	m.mutex.RUnlock()
@@ -517,7 +712,7 @@ func (m *CLIManager) GetProcessOutput(sessionID string) ([]string, error) {
 		return nil, fmt.Errorf("no active process for session %s", sessionID)
 	}
 
-	var outputs []string
+	var outputs []CLIOutput
 	
 	// Non-blocking read of available outputs
 	for {
@@ -551,4 +746,103 @@ func (m *CLIManager) GetProcessErrors(sessionID string) ([]error, error) {
 			return errors, nil
 		}
 	}
-}
\ No newline at end of file
+}
+// findClaudeBinary returns the path to the claude CLI, checking PATH first and
+// then common installation locations
+func findClaudeBinary() string {
+	if _, err := exec.LookPath("claude"); err == nil {
+		return "claude"
+	}
+
+	homeDir, _ := os.UserHomeDir()
+	possiblePaths := []string{
+		filepath.Join(homeDir, ".npm-global", "bin", "claude"),
+		filepath.Join(homeDir, ".local", "bin", "claude"),
+		"/usr/local/bin/claude",
+		"/opt/homebrew/bin/claude",
+	}
+
+	for _, path := range possiblePaths {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+
+	return "claude"
+}
+
+// promptArgs returns the Claude CLI arguments that print the JSON result of
+// prompt, run with flags. The prompt follows -- so one starting with a dash
+// isn't read as a flag.
+func promptArgs(flags []string, prompt string) []string {
+	args := append([]string{"--print", "--output-format", "json"}, flags...)
+	return append(args, "--", prompt)
+}
+
+// PromptOptions controls how RunPrompt invokes the Claude CLI
+type PromptOptions struct {
+	// PermissionMode is passed as --permission-mode when set, e.g. "plan" to
+	// have Claude propose changes without applying them
+	PermissionMode string
+
+	// AllowedTools are passed as --allowedTools when set, allowing the tools
+	// without asking
+	AllowedTools []string
+
+	// SessionID is the session the prompt is run for, which it takes turns
+	// with other sessions under while waiting for a process; the Claude
+	// session or project when empty
+	SessionID string
+}
+
+// RunPrompt runs a single prompt through the Claude CLI and waits for the result.
+// If claudeSessionID is set the existing conversation is resumed. Unlike
+// SendMessage this does not require an active chat process, which makes it
+// suitable for scripted, non-interactive runs.
+func (m *CLIManager) RunPrompt(ctx context.Context, projectPath, claudeSessionID, prompt string, opts PromptOptions) (*ClaudeResponse, error) {
+	var flags []string
+	if claudeSessionID != "" {
+		flags = append(flags, "--resume", claudeSessionID)
+	}
+	flags = append(flags, Permissions{Mode: opts.PermissionMode, AllowedTools: opts.AllowedTools}.args()...)
+	args := promptArgs(flags, prompt)
+
+	queueKey := opts.SessionID
+	if queueKey == "" {
+		queueKey = claudeSessionID
+	}
+	if queueKey == "" {
+		queueKey = projectPath
+	}
+	release, err := m.queue.acquire(ctx, queueKey, QueueKindPrompt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get a chat process: %w", err)
+	}
+	defer release()
+
+	cmd := exec.CommandContext(ctx, findClaudeBinary(), args...)
+	if projectPath != "" && projectPath != "/" {
+		cmd.Dir = projectPath
+	}
+
+	output, err := cmd.Output()
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("claude command failed: %w", err)
+	}
+
+	var response ClaudeResponse
+	if err := json.Unmarshal(output, &response); err != nil {
+		return nil, fmt.Errorf("failed to parse claude response: %w", err)
+	}
+	if response.IsError {
+		if response.Error != "" {
+			return &response, fmt.Errorf("claude returned an error: %s", response.Error)
+		}
+		return &response, fmt.Errorf("claude returned an error: %s", response.Result)
+	}
+
+	return &response, nil
+}
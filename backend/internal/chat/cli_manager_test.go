@@ -0,0 +1,93 @@
+package chat_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ksred/claude-session-manager/internal/chat"
+	"github.com/ksred/claude-session-manager/internal/database"
+	"github.com/sirupsen/logrus"
+)
+
+type fakeSessions struct{}
+
+func (fakeSessions) GetSessionByID(sessionID string) (*chat.SessionData, error) {
+	return &chat.SessionData{ID: sessionID, ProjectPath: os.TempDir(), ProjectName: "app"}, nil
+}
+
+func TestCLIManager_Lifecycle(t *testing.T) {
+	tmpFile, err := os.CreateTemp("", "test-chat-*.db")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	tmpFile.Close()
+	defer os.Remove(tmpFile.Name())
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.WarnLevel)
+	db, err := database.NewDatabase(database.Config{DatabasePath: tmpFile.Name(), Logger: logger})
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer db.Close()
+
+	repo := database.NewSessionRepository(db, logger)
+	now := time.Now()
+	for _, id := range []string{"s1", "s2"} {
+		if err := repo.UpsertSession(&database.Session{ID: id, ProjectPath: "/work/app", ProjectName: "app", StartTime: now, LastActivity: now, Status: "active"}); err != nil {
+			t.Fatalf("Failed to create session: %v", err)
+		}
+	}
+
+	manager := chat.NewCLIManager(chat.NewRepositoryWithWriteOp(db.DB, db.WriteOperation), fakeSessions{})
+	for _, id := range []string{"s1", "s2"} {
+		if _, err := manager.StartChatSession(id); err != nil {
+			t.Fatalf("Failed to start chat: %v", err)
+		}
+	}
+	if stats := manager.Stats(); stats.Live != 2 || stats.Started != 2 {
+		t.Errorf("Expected two live processes, got %+v", stats)
+	}
+
+	if err := manager.StopChatSession("s1"); err != nil {
+		t.Fatalf("Failed to stop chat: %v", err)
+	}
+	if stats := manager.Stats(); stats.Live != 1 || stats.Stopped[chat.StopReasonEnded] != 1 {
+		t.Errorf("Expected the ended process removed, got %+v", stats)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := manager.Shutdown(ctx); err != nil {
+		t.Fatalf("Expected the message handlers to exit, got %v", err)
+	}
+	if stats := manager.Stats(); stats.Live != 0 || stats.Stopped[chat.StopReasonShutdown] != 1 {
+		t.Errorf("Expected the remaining process stopped for shutdown, got %+v", stats)
+	}
+	if _, err := manager.StartChatSession("s1"); err == nil {
+		t.Error("Expected no chat to start after shutdown")
+	}
+}
+
+func TestClaudeResponse_Usage(t *testing.T) {
+	output := `{"type":"result","subtype":"success","result":"Done.","session_id":"c1","num_turns":3,"total_cost_usd":0.12,
+		"usage":{"input_tokens":12,"cache_creation_input_tokens":300,"cache_read_input_tokens":4000,"output_tokens":250,"service_tier":"standard"},
+		"modelUsage":{"claude-3-5-haiku-20241022":{"costUSD":0.01},"claude-sonnet-4-20250514":{"costUSD":0.11}}}`
+
+	var response chat.ClaudeResponse
+	if err := json.Unmarshal([]byte(output), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if response.Usage == nil || response.Usage.CacheReadInputTokens != 4000 || response.Usage.OutputTokens != 250 {
+		t.Errorf("Expected the run's usage, got %+v", response.Usage)
+	}
+	if model := response.Model(); model != "claude-sonnet-4-20250514" {
+		t.Errorf("Expected the model most was spent on, got %q", model)
+	}
+	if model := (&chat.ClaudeResponse{}).Model(); model != "" {
+		t.Errorf("Expected no model without model usage, got %q", model)
+	}
+}
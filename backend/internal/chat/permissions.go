@@ -0,0 +1,77 @@
+package chat
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Claude CLI permission modes
+const (
+	PermissionModeDefault     = "default"
+	PermissionModeAcceptEdits = "acceptEdits"
+	PermissionModePlan        = "plan"
+	PermissionModeBypass      = "bypassPermissions"
+)
+
+// PermissionModes are the permission modes the Claude CLI accepts
+var PermissionModes = []string{PermissionModeDefault, PermissionModeAcceptEdits, PermissionModePlan, PermissionModeBypass}
+
+// Permissions are the tool permissions a chat's Claude CLI processes run
+// with, passed as --permission-mode and --allowedTools as in the terminal
+type Permissions struct {
+	Mode         string   `json:"permission_mode,omitempty"` // the CLI's default when empty
+	AllowedTools []string `json:"allowed_tools,omitempty"`   // tools allowed without asking, such as "Read" or "Bash(git log:*)"
+}
+
+// PermissionPolicy is the permissions chats started through the API may ask
+// for
+type PermissionPolicy struct {
+	Modes []string // permission modes allowed
+	Tools []string // tools that may be allowed; a bare name such as "Bash" also covers "Bash(git log:*)"
+}
+
+// Check returns an error naming the first mode or tool the policy doesn't
+// allow
+func (p PermissionPolicy) Check(perms Permissions) error {
+	if perms.Mode != "" && !contains(p.Modes, perms.Mode) {
+		return fmt.Errorf("permission mode %q is not allowed (allowed: %s)", perms.Mode, list(p.Modes))
+	}
+	for _, tool := range perms.AllowedTools {
+		name, _, _ := strings.Cut(tool, "(")
+		if strings.TrimSpace(tool) == "" || !(contains(p.Tools, tool) || contains(p.Tools, name)) {
+			return fmt.Errorf("tool %q may not be allowed (allowed: %s)", tool, list(p.Tools))
+		}
+	}
+	return nil
+}
+
+// args returns the Claude CLI flags for the permissions. --allowedTools
+// takes every argument up to the next flag, so the prompt must follow a --
+// after them.
+func (perms Permissions) args() []string {
+	var args []string
+	if perms.Mode != "" {
+		args = append(args, "--permission-mode", perms.Mode)
+	}
+	if len(perms.AllowedTools) > 0 {
+		args = append(args, "--allowedTools")
+		args = append(args, perms.AllowedTools...)
+	}
+	return args
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func list(values []string) string {
+	if len(values) == 0 {
+		return "none"
+	}
+	return strings.Join(values, ", ")
+}
@@ -0,0 +1,63 @@
+package chat
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPermissionPolicy_Check(t *testing.T) {
+	policy := PermissionPolicy{
+		Modes: []string{PermissionModeDefault, PermissionModePlan},
+		Tools: []string{"Read", "Bash(git log:*)", "Edit"},
+	}
+
+	for _, perms := range []Permissions{
+		{},
+		{Mode: PermissionModePlan},
+		{Mode: PermissionModeDefault, AllowedTools: []string{"Read", "Bash(git log:*)"}},
+		{AllowedTools: []string{"Edit(src/**)"}}, // a bare name covers its patterns
+	} {
+		if err := policy.Check(perms); err != nil {
+			t.Errorf("Expected %+v allowed, got %v", perms, err)
+		}
+	}
+
+	for _, perms := range []Permissions{
+		{Mode: PermissionModeBypass},
+		{AllowedTools: []string{"Bash"}},
+		{AllowedTools: []string{"Bash(rm:*)"}},
+		{AllowedTools: []string{" "}},
+	} {
+		if err := policy.Check(perms); err == nil {
+			t.Errorf("Expected %+v refused", perms)
+		}
+	}
+
+	if err := (PermissionPolicy{}).Check(Permissions{Mode: PermissionModePlan}); err == nil || !strings.Contains(err.Error(), "allowed: none") {
+		t.Errorf("Expected an empty policy to allow nothing, got %v", err)
+	}
+}
+
+func TestPermissions_Args(t *testing.T) {
+	args := Permissions{Mode: PermissionModeAcceptEdits, AllowedTools: []string{"Read", "Bash(git log:*)"}}.args()
+	if got := strings.Join(args, " "); got != "--permission-mode acceptEdits --allowedTools Read Bash(git log:*)" {
+		t.Errorf("Unexpected args: %s", got)
+	}
+	if args := (Permissions{}).args(); len(args) != 0 {
+		t.Errorf("Expected no args for the default permissions, got %v", args)
+	}
+
+	// The prompt can't be taken as a tool or a flag
+	args = promptArgs(args, "--version?")
+	if got := strings.Join(args, " "); got != "--print --output-format json --permission-mode acceptEdits --allowedTools Read Bash(git log:*) -- --version?" {
+		t.Errorf("Unexpected prompt args: %s", got)
+	}
+
+	perms := permissionsFromMessage(map[string]interface{}{
+		"permission_mode": "plan",
+		"allowed_tools":   []interface{}{"Read", 3, "Edit"},
+	})
+	if perms.Mode != PermissionModePlan || len(perms.AllowedTools) != 2 || perms.AllowedTools[1] != "Edit" {
+		t.Errorf("Unexpected permissions from message: %+v", perms)
+	}
+}
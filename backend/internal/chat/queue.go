@@ -0,0 +1,224 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Errors returned when a request can't get a Claude CLI process
+var (
+	ErrQueueFull    = errors.New("too many chat requests are waiting")
+	ErrQueueTimeout = errors.New("timed out waiting for a chat process")
+)
+
+// Kinds of request that run Claude CLI processes
+const (
+	QueueKindChat   = "chat"   // a message sent in an interactive chat
+	QueueKindPrompt = "prompt" // a prompt run on its own, over REST or by a playbook
+)
+
+// QueueEntry is a request running or waiting for a Claude CLI process
+type QueueEntry struct {
+	SessionID  string     `json:"session_id"`
+	Kind       string     `json:"kind"`
+	EnqueuedAt time.Time  `json:"enqueued_at"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	Position   int        `json:"position,omitempty"` // in the order waiting requests will start, from 1
+}
+
+// QueueStatus is the requests running and waiting for Claude CLI processes
+type QueueStatus struct {
+	MaxProcesses int          `json:"max_processes"` // 0 for no limit
+	MaxQueued    int          `json:"max_queued"`    // 0 for no limit
+	Running      []QueueEntry `json:"running"`
+	Waiting      []QueueEntry `json:"waiting"`
+}
+
+type waiter struct {
+	entry QueueEntry
+	ready chan struct{} // closed once the request may start
+}
+
+// queue hands out Claude CLI process slots. Waiting requests are grouped by
+// session and sessions take turns: a free slot goes to the oldest request of
+// the session at the front, which then goes to the back if it has more
+// waiting, so a session sending many messages can't hold up the others.
+type queue struct {
+	mutex     sync.Mutex
+	limit     int
+	maxQueued int
+	timeout   time.Duration
+	running   map[*waiter]bool
+	sessions  []string // sessions with requests waiting, in turn order
+	waiting   map[string][]*waiter
+	queued    int
+}
+
+func newQueue(limit, maxQueued int, timeout time.Duration) *queue {
+	return &queue{
+		limit:     limit,
+		maxQueued: maxQueued,
+		timeout:   timeout,
+		running:   make(map[*waiter]bool),
+		waiting:   make(map[string][]*waiter),
+	}
+}
+
+// acquire waits for a process slot for a session's request and returns the
+// function that frees it. It fails with ErrQueueFull when too many requests
+// are waiting already, ErrQueueTimeout when the queue timeout passes first,
+// or ctx's error when ctx is done first.
+func (q *queue) acquire(ctx context.Context, sessionID, kind string) (func(), error) {
+	w := &waiter{
+		entry: QueueEntry{SessionID: sessionID, Kind: kind, EnqueuedAt: time.Now()},
+		ready: make(chan struct{}),
+	}
+
+	q.mutex.Lock()
+	if q.free() && q.queued == 0 {
+		q.start(w)
+		q.mutex.Unlock()
+		return q.releaser(w), nil
+	}
+	if q.maxQueued > 0 && q.queued >= q.maxQueued {
+		q.mutex.Unlock()
+		return nil, ErrQueueFull
+	}
+	if len(q.waiting[sessionID]) == 0 {
+		q.sessions = append(q.sessions, sessionID)
+	}
+	q.waiting[sessionID] = append(q.waiting[sessionID], w)
+	q.queued++
+	q.mutex.Unlock()
+
+	waitCtx := ctx
+	if q.timeout > 0 {
+		var cancel context.CancelFunc
+		waitCtx, cancel = context.WithTimeout(ctx, q.timeout)
+		defer cancel()
+	}
+
+	select {
+	case <-w.ready:
+		return q.releaser(w), nil
+	case <-waitCtx.Done():
+	}
+
+	q.mutex.Lock()
+	select {
+	case <-w.ready:
+		// Started while giving up, so hand the slot on
+		delete(q.running, w)
+		q.dispatch()
+	default:
+		q.remove(w)
+	}
+	q.mutex.Unlock()
+
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	return nil, ErrQueueTimeout
+}
+
+// status returns the requests running, oldest first, and those waiting, in
+// the order they will start
+func (q *queue) status() QueueStatus {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	status := QueueStatus{
+		MaxProcesses: q.limit,
+		MaxQueued:    q.maxQueued,
+		Running:      make([]QueueEntry, 0, len(q.running)),
+		Waiting:      make([]QueueEntry, 0, q.queued),
+	}
+	for w := range q.running {
+		status.Running = append(status.Running, w.entry)
+	}
+	sort.Slice(status.Running, func(i, j int) bool {
+		return status.Running[i].StartedAt.Before(*status.Running[j].StartedAt)
+	})
+
+	// Sessions take one turn each per round
+	for round := 0; len(status.Waiting) < q.queued; round++ {
+		for _, sessionID := range q.sessions {
+			if waiters := q.waiting[sessionID]; round < len(waiters) {
+				entry := waiters[round].entry
+				entry.Position = len(status.Waiting) + 1
+				status.Waiting = append(status.Waiting, entry)
+			}
+		}
+	}
+	return status
+}
+
+func (q *queue) free() bool {
+	return q.limit <= 0 || len(q.running) < q.limit
+}
+
+func (q *queue) start(w *waiter) {
+	now := time.Now()
+	w.entry.StartedAt = &now
+	q.running[w] = true
+	close(w.ready)
+}
+
+// dispatch starts waiting requests while there are free slots
+func (q *queue) dispatch() {
+	for q.free() && len(q.sessions) > 0 {
+		sessionID := q.sessions[0]
+		q.sessions = q.sessions[1:]
+
+		waiters := q.waiting[sessionID]
+		if len(waiters) > 1 {
+			q.waiting[sessionID] = waiters[1:]
+			q.sessions = append(q.sessions, sessionID)
+		} else {
+			delete(q.waiting, sessionID)
+		}
+		q.queued--
+		q.start(waiters[0])
+	}
+}
+
+// remove drops a request that gave up waiting
+func (q *queue) remove(w *waiter) {
+	sessionID := w.entry.SessionID
+	waiters := q.waiting[sessionID]
+	for i, other := range waiters {
+		if other == w {
+			waiters = append(waiters[:i:i], waiters[i+1:]...)
+			q.queued--
+			break
+		}
+	}
+	if len(waiters) > 0 {
+		q.waiting[sessionID] = waiters
+		return
+	}
+	delete(q.waiting, sessionID)
+	for i, other := range q.sessions {
+		if other == sessionID {
+			q.sessions = append(q.sessions[:i:i], q.sessions[i+1:]...)
+			break
+		}
+	}
+}
+
+// releaser returns the function that frees a request's slot, which is safe
+// to call more than once
+func (q *queue) releaser(w *waiter) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			q.mutex.Lock()
+			defer q.mutex.Unlock()
+			delete(q.running, w)
+			q.dispatch()
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// enqueue starts a request waiting and returns a channel that receives its
+// release function once it starts
+func enqueue(t *testing.T, q *queue, ctx context.Context, sessionID string) (chan func(), chan error) {
+	t.Helper()
+	started, failed := make(chan func(), 1), make(chan error, 1)
+	waiting := len(q.status().Waiting)
+	go func() {
+		release, err := q.acquire(ctx, sessionID, QueueKindChat)
+		if err != nil {
+			failed <- err
+			return
+		}
+		started <- release
+	}()
+	for deadline := time.Now().Add(time.Second); len(q.status().Waiting) == waiting; {
+		if time.Now().After(deadline) {
+			t.Fatalf("Request for %s never queued", sessionID)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return started, failed
+}
+
+func TestQueue_TakesTurnsBySession(t *testing.T) {
+	q := newQueue(1, 0, 0)
+	release, err := q.acquire(context.Background(), "a", QueueKindChat)
+	if err != nil {
+		t.Fatalf("Failed to acquire: %v", err)
+	}
+
+	a1, _ := enqueue(t, q, context.Background(), "a")
+	a2, _ := enqueue(t, q, context.Background(), "a")
+	b1, _ := enqueue(t, q, context.Background(), "b")
+
+	status := q.status()
+	if len(status.Running) != 1 || len(status.Waiting) != 3 {
+		t.Fatalf("Expected 1 running and 3 waiting, got %+v", status)
+	}
+	order := []string{status.Waiting[0].SessionID, status.Waiting[1].SessionID, status.Waiting[2].SessionID}
+	if order[0] != "a" || order[1] != "b" || order[2] != "a" || status.Waiting[2].Position != 3 {
+		t.Errorf("Expected a, b, a, got %v", order)
+	}
+
+	// Each release starts the next in turn, b before a's second request
+	release()
+	next := <-a1
+	select {
+	case <-b1:
+		t.Fatal("Expected b to wait for a free process")
+	default:
+	}
+	next()
+	next = <-b1
+	next()
+	next = <-a2
+	next()
+	next() // releasing twice is harmless
+
+	if status := q.status(); len(status.Running) != 0 || len(status.Waiting) != 0 {
+		t.Errorf("Expected the queue empty, got %+v", status)
+	}
+}
+
+func TestQueue_Limits(t *testing.T) {
+	q := newQueue(1, 1, 20*time.Millisecond)
+	release, err := q.acquire(context.Background(), "a", QueueKindChat)
+	if err != nil {
+		t.Fatalf("Failed to acquire: %v", err)
+	}
+	defer release()
+
+	_, failed := enqueue(t, q, context.Background(), "b")
+	if _, err := q.acquire(context.Background(), "c", QueueKindPrompt); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("Expected the queue full, got %v", err)
+	}
+	if err := <-failed; !errors.Is(err, ErrQueueTimeout) {
+		t.Errorf("Expected the wait to time out, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	_, failed = enqueue(t, q, ctx, "d")
+	cancel()
+	if err := <-failed; !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the wait cancelled, got %v", err)
+	}
+	if status := q.status(); len(status.Waiting) != 0 {
+		t.Errorf("Expected requests that gave up removed, got %+v", status.Waiting)
+	}
+}
+
+func TestQueue_NoLimit(t *testing.T) {
+	q := newQueue(0, 0, 0)
+	for i := 0; i < 20; i++ {
+		if _, err := q.acquire(context.Background(), "a", QueueKindPrompt); err != nil {
+			t.Fatalf("Failed to acquire without a limit: %v", err)
+		}
+	}
+	if status := q.status(); len(status.Running) != 20 {
+		t.Errorf("Expected 20 running, got %d", len(status.Running))
+	}
+}
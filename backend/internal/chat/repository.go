@@ -150,9 +150,14 @@ func (r *Repository) CreateChatMessage(chatSessionID, msgType, content string, m
 	return message, nil
 }
 
+// CreateChatReply stores Claude's reply to a chat with the figures the CLI
+// reported for the run that produced it
+func (r *Repository) CreateChatReply(chatSessionID string, response *ClaudeResponse) (*ChatMessage, error) {
+	return r.CreateChatMessage(chatSessionID, MessageTypeClaude, response.Result, cliMetadata(response))
+}
+
 // GetChatMessages retrieves messages for a chat session
 func (r *Repository) GetChatMessages(chatSessionID string, limit int, offset int) ([]*ChatMessage, error) {
-	var messages []*ChatMessage
 	query := `
 		SELECT id, chat_session_id, type, content, timestamp, metadata
 		FROM chat_messages 
@@ -167,6 +172,32 @@ func (r *Repository) GetChatMessages(chatSessionID string, limit int, offset int
 	}
 	defer rows.Close()
 
+	return scanChatMessages(rows)
+}
+
+// GetSessionChatMessages retrieves every message of every chat run against a
+// session, whatever the chat's status, in the order they were sent
+func (r *Repository) GetSessionChatMessages(sessionID string) ([]*ChatMessage, error) {
+	query := `
+		SELECT m.id, m.chat_session_id, m.type, m.content, m.timestamp, m.metadata
+		FROM chat_messages m
+		JOIN chat_sessions s ON s.id = m.chat_session_id
+		WHERE s.session_id = ?
+		ORDER BY m.timestamp ASC
+	`
+
+	rows, err := r.db.Query(query, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanChatMessages(rows)
+}
+
+// scanChatMessages reads chat message rows, decoding their metadata
+func scanChatMessages(rows *sql.Rows) ([]*ChatMessage, error) {
+	messages := []*ChatMessage{}
 	for rows.Next() {
 		var message ChatMessage
 		var metadataJSON sql.NullString
@@ -188,7 +219,7 @@ func (r *Repository) GetChatMessages(chatSessionID string, limit int, offset int
 		messages = append(messages, &message)
 	}
 
-	return messages, nil
+	return messages, rows.Err()
 }
 
 // DeleteChatSession deletes a chat session and all its messages
@@ -228,14 +259,16 @@ func (r *Repository) DeleteChatSession(id string) error {
 	return tx.Commit()
 }
 
-// CleanupInactiveSessions removes chat sessions that have been inactive for a specified duration
+// CleanupInactiveSessions removes chat sessions that have been inactive for a
+// specified duration. Sessions under legal hold are never pruned.
 func (r *Repository) CleanupInactiveSessions(inactiveDuration time.Duration) error {
 	cutoffTime := time.Now().Add(-inactiveDuration)
 	
 	operation := func(tx *sqlx.Tx) error {
 		// Get inactive session IDs
 		var sessionIDs []string
-		query := `SELECT id FROM chat_sessions WHERE last_activity < ? AND status = ?`
+		query := `SELECT id FROM chat_sessions WHERE last_activity < ? AND status = ?
+			AND session_id NOT IN (SELECT session_id FROM legal_holds WHERE released_at IS NULL)`
 		err := tx.Select(&sessionIDs, query, cutoffTime, StatusActive)
 		if err != nil {
 			return err
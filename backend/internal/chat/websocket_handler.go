@@ -7,10 +7,16 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
+// TemplateProvider resolves saved prompt templates and records their usage
+type TemplateProvider interface {
+	UsePromptTemplate(templateID, sessionID string) (string, error)
+}
+
 // WebSocketChatHandler handles chat-specific WebSocket messages
 type WebSocketChatHandler struct {
 	cliManager *CLIManager
 	repository *Repository
+	templates  TemplateProvider
 	logger     *logrus.Logger
 }
 
@@ -23,6 +29,11 @@ func NewWebSocketChatHandler(cliManager *CLIManager, repository *Repository, log
 	}
 }
 
+// SetTemplateProvider sets the provider used to resolve prompt templates on session start
+func (h *WebSocketChatHandler) SetTemplateProvider(provider TemplateProvider) {
+	h.templates = provider
+}
+
 // HandleMessage processes incoming chat WebSocket messages
 func (h *WebSocketChatHandler) HandleMessage(clientID string, msgType string, msg map[string]interface{}, broadcastFn func(string, interface{})) error {
 	switch msgType {
@@ -53,8 +64,14 @@ func (h *WebSocketChatHandler) handleSessionStart(clientID string, msg map[strin
 		"session_id": sessionID,
 	}).Info("Starting chat session")
 
-	// Start the CLI process for this session
-	chatSession, err := h.cliManager.StartChatSession(sessionID)
+	// Start the CLI process for this session with the permissions asked for,
+	// if the permission policy allows them
+	perms := permissionsFromMessage(msg)
+	err := h.cliManager.CheckPermissions(perms)
+	var chatSession *ChatSession
+	if err == nil {
+		chatSession, err = h.cliManager.StartChatSessionWithPermissions(sessionID, perms)
+	}
 	if err != nil {
 		h.logger.WithError(err).WithFields(logrus.Fields{
 			"client_id":  clientID,
@@ -97,10 +114,36 @@ func (h *WebSocketChatHandler) handleSessionStart(clientID string, msg map[strin
 		},
 	}
 
+	// Resolve an optional prompt template so the UI can insert it into the composer
+	if templateID, ok := msg["template_id"].(string); ok && templateID != "" && h.templates != nil {
+		content, err := h.templates.UsePromptTemplate(templateID, sessionID)
+		if err != nil {
+			h.logger.WithError(err).WithField("template_id", templateID).Warn("Failed to resolve prompt template")
+		} else {
+			startMsg.Metadata["template_id"] = templateID
+			startMsg.Metadata["template_content"] = content
+		}
+	}
+
 	broadcastFn(WSMsgChatSessionStart, startMsg)
 	return nil
 }
 
+// permissionsFromMessage reads the optional permission_mode and
+// allowed_tools of a chat session start message
+func permissionsFromMessage(msg map[string]interface{}) Permissions {
+	var perms Permissions
+	perms.Mode, _ = msg["permission_mode"].(string)
+	if tools, ok := msg["allowed_tools"].([]interface{}); ok {
+		for _, tool := range tools {
+			if name, ok := tool.(string); ok {
+				perms.AllowedTools = append(perms.AllowedTools, name)
+			}
+		}
+	}
+	return perms
+}
+
 // handleSessionEnd handles ending a chat session
 func (h *WebSocketChatHandler) handleSessionEnd(clientID string, msg map[string]interface{}, broadcastFn func(string, interface{})) error {
 	sessionID, ok := msg["session_id"].(string)
@@ -325,14 +368,14 @@ func (h *WebSocketChatHandler) monitorCLIOutput(sessionID string, broadcastFn fu
 			}
 			
 			for _, output := range outputs {
-				if output == "" {
+				if output.Content == "" {
 					continue
 				}
 
 				h.logger.WithFields(logrus.Fields{
 					"session_id": sessionID,
-					"output":     output[:min(len(output), 100)],
-					"full_len":   len(output),
+					"output":     output.Content[:min(len(output.Content), 100)],
+					"full_len":   len(output.Content),
 				}).Info("Received CLI output")
 
 				// Get chat session for storing message
@@ -342,10 +385,10 @@ func (h *WebSocketChatHandler) monitorCLIOutput(sessionID string, broadcastFn fu
 					continue
 				}
 
-				// Store Claude's response in database
-				claudeMessage, err := h.repository.CreateChatMessage(chatSession.ID, MessageTypeClaude, output, map[string]interface{}{
-					"source": "cli_output",
-				})
+				// Store Claude's response in database with the figures the CLI
+				// reported, which the chat transcript export reads back
+				metadata := cliMetadata(output.Response)
+				claudeMessage, err := h.repository.CreateChatMessage(chatSession.ID, MessageTypeClaude, output.Content, metadata)
 				if err != nil {
 					h.logger.WithError(err).Error("Failed to store Claude message")
 					// Continue processing even if storage fails
@@ -355,14 +398,12 @@ func (h *WebSocketChatHandler) monitorCLIOutput(sessionID string, broadcastFn fu
 				responseMsg := WebSocketMessage{
 					Type:      WSMsgChatMessageRecv,
 					SessionID: sessionID,
-					Content:   output,
+					Content:   output.Content,
 					Timestamp: time.Now(),
-					Metadata: map[string]interface{}{
-						"message_id":   claudeMessage.ID,
-						"message_type": MessageTypeClaude,
-						"source":       "cli_output",
-					},
+					Metadata:  cliMetadata(output.Response),
 				}
+				responseMsg.Metadata["message_id"] = claudeMessage.ID
+				responseMsg.Metadata["message_type"] = MessageTypeClaude
 
 				broadcastFn(WSMsgChatMessageRecv, responseMsg)
 			}
@@ -411,6 +452,23 @@ func (h *WebSocketChatHandler) monitorCLIOutput(sessionID string, broadcastFn fu
 	}
 }
 
+// cliMetadata is the metadata stored with a Claude reply: its source and,
+// when the CLI reported them, the run's duration, turns and cost
+func cliMetadata(response *ClaudeResponse) map[string]interface{} {
+	metadata := map[string]interface{}{
+		"source": "cli_output",
+	}
+	if response != nil {
+		metadata["duration_ms"] = response.DurationMs
+		metadata["num_turns"] = response.NumTurns
+		metadata["total_cost_usd"] = response.TotalCostUSD
+		if response.SessionID != "" {
+			metadata["claude_session_id"] = response.SessionID
+		}
+	}
+	return metadata
+}
+
 // min returns the minimum of two integers
 func min(a, b int) int {
 	if a < b {
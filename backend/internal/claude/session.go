@@ -2,6 +2,8 @@ package claude
 
 import (
 	"time"
+
+	"github.com/ksred/claude-session-manager/internal/pricing"
 )
 
 // SessionStatus represents the current state of a Claude session
@@ -29,85 +31,13 @@ func (s SessionStatus) String() string {
 	}
 }
 
-// ModelPricing represents pricing for a specific model (per 1K tokens)
-type ModelPricing struct {
-	InputTokenPrice              float64
-	OutputTokenPrice             float64
-	CacheCreationInputTokenPrice float64
-	CacheReadInputTokenPrice     float64
-}
-
-// Official Claude pricing as of July 2025 (converted from per million to per 1K tokens)
-// Using 5m cache tier pricing (most common)
-var ModelPricingTable = map[string]ModelPricing{
-	// Claude 4 models  
-	"claude-opus-4": {
-		InputTokenPrice: 0.015, OutputTokenPrice: 0.075, // $15/$75 per million
-		CacheCreationInputTokenPrice: 0.01875, CacheReadInputTokenPrice: 0.0015, // $18.75/$1.50 per million
-	},
-	"claude-opus-4-20250514": {
-		InputTokenPrice: 0.015, OutputTokenPrice: 0.075,
-		CacheCreationInputTokenPrice: 0.01875, CacheReadInputTokenPrice: 0.0015,
-	},
-	"claude-sonnet-4": {
-		InputTokenPrice: 0.003, OutputTokenPrice: 0.015, // $3/$15 per million
-		CacheCreationInputTokenPrice: 0.00375, CacheReadInputTokenPrice: 0.0003, // $3.75/$0.30 per million
-	},
-	"claude-sonnet-4-20250514": {
-		InputTokenPrice: 0.003, OutputTokenPrice: 0.015,
-		CacheCreationInputTokenPrice: 0.00375, CacheReadInputTokenPrice: 0.0003,
-	},
-	
-	// Claude 3.x models
-	"claude-3-opus": {
-		InputTokenPrice: 0.015, OutputTokenPrice: 0.075,
-		CacheCreationInputTokenPrice: 0.01875, CacheReadInputTokenPrice: 0.0015,
-	},
-	"claude-3-sonnet": {
-		InputTokenPrice: 0.003, OutputTokenPrice: 0.015,
-		CacheCreationInputTokenPrice: 0.00375, CacheReadInputTokenPrice: 0.0003,
-	},
-	"claude-3.5-sonnet": {
-		InputTokenPrice: 0.003, OutputTokenPrice: 0.015,
-		CacheCreationInputTokenPrice: 0.00375, CacheReadInputTokenPrice: 0.0003,
-	},
-	"claude-3.7-sonnet": {
-		InputTokenPrice: 0.003, OutputTokenPrice: 0.015,
-		CacheCreationInputTokenPrice: 0.00375, CacheReadInputTokenPrice: 0.0003,
-	},
-	"claude-3-haiku": {
-		InputTokenPrice: 0.00025, OutputTokenPrice: 0.00125, // $0.25/$1.25 per million
-		CacheCreationInputTokenPrice: 0.0003, CacheReadInputTokenPrice: 0.00003, // $0.30/$0.03 per million
-	},
-	"claude-3.5-haiku": {
-		InputTokenPrice: 0.0008, OutputTokenPrice: 0.004, // $0.80/$4 per million
-		CacheCreationInputTokenPrice: 0.001, CacheReadInputTokenPrice: 0.00008, // $1.00/$0.08 per million
-	},
-}
-
-// Default pricing for unknown models (Sonnet pricing)
-var DefaultModelPricing = ModelPricing{
-	InputTokenPrice:              0.003,   // $3.00 per million = $0.003 per 1K
-	OutputTokenPrice:             0.015,   // $15.00 per million = $0.015 per 1K
-	CacheCreationInputTokenPrice: 0.00375, // $3.75 per million = $0.00375 per 1K
-	CacheReadInputTokenPrice:     0.0003,  // $0.30 per million = $0.0003 per 1K
-}
-
 // Token pricing constants (per 1K tokens) - DEPRECATED
-// Deprecated: Use GetModelPricing instead
+// Deprecated: Use CalculateCostWithModel instead
 const (
 	InputTokenPricePerK  = 0.003  // Default to Sonnet pricing
 	OutputTokenPricePerK = 0.015
 )
 
-// GetModelPricing returns pricing for a specific model, with fallback to default
-func GetModelPricing(modelName string) ModelPricing {
-	if pricing, exists := ModelPricingTable[modelName]; exists {
-		return pricing
-	}
-	return DefaultModelPricing
-}
-
 // TokenUsage tracks token consumption and costs for a session
 type TokenUsage struct {
 	InputTokens              int     `json:"input_tokens"`
@@ -135,14 +65,12 @@ func (tu *TokenUsage) CalculateCostWithPricing(inputPricePerK, outputPricePerK f
 
 // CalculateCostWithModel computes the estimated cost using model-specific pricing including cache costs
 func (tu *TokenUsage) CalculateCostWithModel(modelName string) float64 {
-	pricing := GetModelPricing(modelName)
-	
-	inputCost := float64(tu.InputTokens) / 1000.0 * pricing.InputTokenPrice
-	outputCost := float64(tu.OutputTokens) / 1000.0 * pricing.OutputTokenPrice
-	cacheCreationCost := float64(tu.CacheCreationInputTokens) / 1000.0 * pricing.CacheCreationInputTokenPrice
-	cacheReadCost := float64(tu.CacheReadInputTokens) / 1000.0 * pricing.CacheReadInputTokenPrice
-	
-	return inputCost + outputCost + cacheCreationCost + cacheReadCost
+	return pricing.Cost(modelName, pricing.Usage{
+		InputTokens:              tu.InputTokens,
+		OutputTokens:             tu.OutputTokens,
+		CacheCreationInputTokens: tu.CacheCreationInputTokens,
+		CacheReadInputTokens:     tu.CacheReadInputTokens,
+	})
 }
 
 // UpdateTotals recalculates total tokens and estimated cost
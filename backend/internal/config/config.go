@@ -2,10 +2,14 @@ package config
 
 import (
 	"fmt"
+	"net/url"
 	"os"
+	"path"
 	"path/filepath"
+	"regexp"
 	"strings"
 
+	"github.com/ksred/claude-session-manager/internal/chat"
 	"github.com/spf13/viper"
 )
 
@@ -15,6 +19,25 @@ type Config struct {
 	Claude   ClaudeConfig   `mapstructure:"claude"`
 	Pricing  PricingConfig  `mapstructure:"pricing"`
 	Features FeaturesConfig `mapstructure:"features"`
+	Playbooks PlaybooksConfig `mapstructure:"playbooks"`
+	Federation FederationConfig `mapstructure:"federation"`
+	Tagging    TaggingConfig    `mapstructure:"tagging"`
+	CostCenters CostCentersConfig `mapstructure:"cost_centers"`
+	Statusline  StatuslineConfig  `mapstructure:"statusline"`
+	Launcher    LauncherConfig    `mapstructure:"launcher"`
+	Telemetry   TelemetryConfig   `mapstructure:"telemetry"`
+	Plugins     []PluginConfig    `mapstructure:"plugins"`
+	Scripting   ScriptingConfig   `mapstructure:"scripting"`
+	Auth        AuthConfig        `mapstructure:"auth"`
+	Retention   RetentionConfig   `mapstructure:"retention"`
+	MQTT        MQTTConfig        `mapstructure:"mqtt"`
+	Database    DatabaseConfig    `mapstructure:"database"`
+	Display     DisplayConfig     `mapstructure:"display"`
+	Reports     ReportsConfig     `mapstructure:"reports"`
+	Notifications NotificationsConfig `mapstructure:"notifications"`
+	SessionStatus SessionStatusConfig `mapstructure:"session_status"`
+	Chat          ChatConfig          `mapstructure:"chat"`
+	Exports       ExportsConfig       `mapstructure:"exports"`
 }
 
 // ServerConfig contains HTTP server settings
@@ -25,6 +48,7 @@ type ServerConfig struct {
 	WriteTimeout    int      `mapstructure:"write_timeout"`    // seconds
 	ShutdownTimeout int      `mapstructure:"shutdown_timeout"` // seconds
 	CORS            CORSConfig `mapstructure:"cors"`
+	RateLimit       RateLimitConfig `mapstructure:"rate_limit"`
 }
 
 // CORSConfig contains CORS settings
@@ -37,19 +61,49 @@ type CORSConfig struct {
 	MaxAge           int      `mapstructure:"max_age"`
 }
 
+// RateLimitConfig contains settings for the per-client token bucket that
+// limits API requests. Clients are told apart by API key when auth is
+// enabled and by IP address otherwise.
+type RateLimitConfig struct {
+	Enabled           bool     `mapstructure:"enabled"`
+	RequestsPerSecond float64  `mapstructure:"requests_per_second"` // rate the bucket refills at
+	Burst             int      `mapstructure:"burst"`               // requests a client may make at once
+	Paths             []string `mapstructure:"paths"`               // path prefixes limited; empty limits all of /api/v1
+}
+
 // ClaudeConfig contains Claude-specific settings
 type ClaudeConfig struct {
 	HomeDirectory    string `mapstructure:"home_directory"`
 	ProjectsPath     string `mapstructure:"projects_path"`
-	WatchInterval    int    `mapstructure:"watch_interval"`    // seconds
+	WatchInterval    int    `mapstructure:"watch_interval"`    // seconds between scans when polling
+	WatchMode        string `mapstructure:"watch_mode"`        // auto, fsnotify or poll
 	CacheRefreshRate int    `mapstructure:"cache_refresh_rate"` // minutes
+	MaxLineSize      int    `mapstructure:"max_line_size"`      // MB; longer JSONL lines are stream decoded
 }
 
 // PricingConfig contains token pricing information
 type PricingConfig struct {
+	// Deprecated: flat prices are no longer used; set per-model prices in
+	// Models and the price of unknown models in Default
 	InputTokensPerK  float64 `mapstructure:"input_tokens_per_k"`  // Cost per 1K input tokens
 	OutputTokensPerK float64 `mapstructure:"output_tokens_per_k"` // Cost per 1K output tokens
 	Currency         string  `mapstructure:"currency"`
+
+	Models        []ModelPrice `mapstructure:"models"`         // override or add to the built-in prices
+	Default       *ModelPrice  `mapstructure:"default"`        // price of models nothing matches; Sonnet's when unset
+	RemoteURL     string       `mapstructure:"remote_url"`     // optional JSON price list in the GET /api/v1/pricing format
+	RemoteRefresh int          `mapstructure:"remote_refresh"` // hours between fetches of RemoteURL
+}
+
+// ModelPrice is a model's price in USD per million tokens. Model matches the
+// exact model name or, failing that, any model name containing it, so
+// "claude-sonnet-4" also prices "claude-sonnet-4-20250514".
+type ModelPrice struct {
+	Model      string  `mapstructure:"model"`
+	Input      float64 `mapstructure:"input"`
+	Output     float64 `mapstructure:"output"`
+	CacheWrite float64 `mapstructure:"cache_write"`
+	CacheRead  float64 `mapstructure:"cache_read"`
 }
 
 // FeaturesConfig contains feature flags and settings
@@ -57,9 +111,290 @@ type FeaturesConfig struct {
 	EnableWebSocket      bool `mapstructure:"enable_websocket"`
 	EnableFileWatcher    bool `mapstructure:"enable_file_watcher"`
 	EnableMetrics        bool `mapstructure:"enable_metrics"`
+	EnableAPIUsage       bool `mapstructure:"enable_api_usage"` // record request counts and latency per route
 	EnableProfiling      bool `mapstructure:"enable_profiling"`
 	DebugMode            bool `mapstructure:"debug_mode"`
 	WebSocketBatchInterval int  `mapstructure:"websocket_batch_interval"` // seconds
+	WebSocketTotalsInterval int `mapstructure:"websocket_totals_interval"` // seconds between token_totals events, 0 disables
+}
+
+// PlaybooksConfig contains settings for scripted multi-turn runs
+type PlaybooksConfig struct {
+	Directory       string `mapstructure:"directory"`        // where *.yaml playbooks are loaded from
+	StepTimeout     int    `mapstructure:"step_timeout"`     // seconds
+	EnableScheduler bool   `mapstructure:"enable_scheduler"` // launch scheduled playbooks automatically
+}
+
+// FederationConfig contains settings for the org-wide rollup mode, where this
+// instance pulls usage aggregates from other instances
+type FederationConfig struct {
+	Enabled      bool                 `mapstructure:"enabled"`
+	PollInterval int                  `mapstructure:"poll_interval"` // seconds
+	Days         int                  `mapstructure:"days"`          // days of history to pull
+	Instances    []FederationInstance `mapstructure:"instances"`
+}
+
+// FederationInstance is a team instance polled by a federation parent
+type FederationInstance struct {
+	Name   string `mapstructure:"name"`
+	URL    string `mapstructure:"url"`     // base URL, e.g. http://team-a:8080
+	APIKey string `mapstructure:"api_key"` // read key for an instance with auth enabled
+}
+
+// TaggingConfig contains rules that tag sessions automatically as they are imported
+type TaggingConfig struct {
+	Rules []TagRule `mapstructure:"rules"`
+}
+
+// TagRule applies Tag to every session matching all of its patterns. Each
+// pattern is a regular expression; patterns left empty match anything, but a
+// rule must set at least one.
+type TagRule struct {
+	Name         string `mapstructure:"name"`
+	Tag          string `mapstructure:"tag"`
+	Project      string `mapstructure:"project"`       // matched against the project name
+	Path         string `mapstructure:"path"`          // matched against the project path
+	Branch       string `mapstructure:"branch"`        // matched against the git branch
+	FirstMessage string `mapstructure:"first_message"` // matched against the opening prompt
+}
+
+// Patterns returns the rule's patterns keyed by the field they match
+func (r TagRule) Patterns() map[string]string {
+	patterns := make(map[string]string)
+	for field, pattern := range map[string]string{
+		"project":       r.Project,
+		"path":          r.Path,
+		"branch":        r.Branch,
+		"first_message": r.FirstMessage,
+	} {
+		if pattern != "" {
+			patterns[field] = pattern
+		}
+	}
+	return patterns
+}
+
+// CostCentersConfig maps projects and tags to the cost centers their usage is
+// charged back to
+type CostCentersConfig struct {
+	Default string       `mapstructure:"default"` // cost center for sessions no mapping matches
+	Centers []CostCenter `mapstructure:"centers"`
+}
+
+// CostCenter is charged for sessions in any of its projects or carrying any
+// of its tags. A session is charged to the first cost center that matches it.
+type CostCenter struct {
+	Name     string   `mapstructure:"name"`
+	Code     string   `mapstructure:"code"`     // chargeback code, such as a GL account
+	Projects []string `mapstructure:"projects"` // project name globs, e.g. "payments-*"
+	Tags     []string `mapstructure:"tags"`
+}
+
+// StatuslineConfig contains settings for the summary polled by Claude Code
+// statusline scripts
+type StatuslineConfig struct {
+	DailyBudget float64 `mapstructure:"daily_budget"` // daily spend the remaining budget is shown against; 0 hides it
+}
+
+// LauncherConfig contains settings for the quick-look search used by
+// launcher integrations such as Raycast and Alfred
+type LauncherConfig struct {
+	DashboardURL string `mapstructure:"dashboard_url"` // dashboard deep links point at; empty uses the server's own address
+}
+
+// TelemetryConfig contains settings for the opt-in anonymous usage reports
+// sent to the maintainers. Telemetry is off unless Enabled is set, and the
+// DO_NOT_TRACK environment variable turns it off regardless.
+type TelemetryConfig struct {
+	Enabled  bool   `mapstructure:"enabled"`
+	Endpoint string `mapstructure:"endpoint"` // URL reports are posted to
+	Interval int    `mapstructure:"interval"` // hours between reports
+}
+
+// PluginConfig is a subprocess plugin speaking JSON-RPC 2.0 over stdio, which
+// can add analytics endpoints, session exporters and event notifiers
+type PluginConfig struct {
+	Name    string   `mapstructure:"name"`
+	Command string   `mapstructure:"command"`
+	Args    []string `mapstructure:"args"`
+	Env     []string `mapstructure:"env"`     // extra KEY=VALUE variables for the process
+	Timeout int      `mapstructure:"timeout"` // seconds a call may take; 30 when unset
+}
+
+// ScriptingConfig contains CEL scripts run against imported messages and
+// sessions. Scripts cannot reach the file system or network, and each
+// evaluation is stopped once it exceeds CostLimit or Timeout.
+type ScriptingConfig struct {
+	CostLimit uint64   `mapstructure:"cost_limit"` // CEL cost units an evaluation may use
+	Timeout   int      `mapstructure:"timeout"`    // milliseconds an evaluation may take
+	Scripts   []Script `mapstructure:"scripts"`
+}
+
+// Script is a CEL expression evaluated against each imported message or
+// session. Action decides what a result does: "field" stores the result as a
+// custom field named Field unless it is null or empty, "drop" skips storing
+// messages it is true for, "tag" applies Tag to sessions it is true for and
+// "notify" broadcasts a script_match event when it becomes true for a session.
+type Script struct {
+	Name       string `mapstructure:"name"`
+	On         string `mapstructure:"on"` // message or session
+	Expression string `mapstructure:"expression"`
+	Action     string `mapstructure:"action"`
+	Field      string `mapstructure:"field"`
+	Tag        string `mapstructure:"tag"`
+}
+
+// AuthConfig contains settings for API key authentication. Keys are managed
+// with the apikey command; when Enabled is false every request is allowed.
+type AuthConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+}
+
+// RetentionConfig contains the retention policy. When Enabled, sessions
+// inactive for longer than MaxAgeDays are deleted every Interval hours;
+// sessions under legal hold are kept.
+type RetentionConfig struct {
+	Enabled    bool `mapstructure:"enabled"`
+	MaxAgeDays int  `mapstructure:"max_age_days"`
+	Interval   int  `mapstructure:"interval"` // hours between runs
+}
+
+// MQTTConfig contains settings for publishing the active session count,
+// today's cost and budget status to an MQTT broker, for home dashboards such
+// as Home Assistant. Messages are retained so new subscribers see the latest
+// values straight away.
+type MQTTConfig struct {
+	Enabled  bool   `mapstructure:"enabled"`
+	Broker   string `mapstructure:"broker"` // host:port, or tcp://host:port
+	ClientID string `mapstructure:"client_id"`
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
+	Interval int    `mapstructure:"interval"` // seconds between publishes
+
+	ActiveSessionsTopic string `mapstructure:"active_sessions_topic"`
+	CostTodayTopic      string `mapstructure:"cost_today_topic"`
+	BudgetTopic         string `mapstructure:"budget_topic"`
+
+	// DiscoveryPrefix is where Home Assistant discovery configs are
+	// published so the sensors appear without YAML; empty turns it off
+	DiscoveryPrefix string `mapstructure:"discovery_prefix"`
+}
+
+// DatabaseConfig contains SQLite connection settings. Writes share one
+// connection and reads use a pool of read-only connections.
+type DatabaseConfig struct {
+	BusyTimeout        int `mapstructure:"busy_timeout"`        // milliseconds to wait for a lock held by another process
+	ReadConnections    int `mapstructure:"read_connections"`    // size of the read-only pool
+	CacheSize          int `mapstructure:"cache_size"`          // MB of page cache per connection
+	MmapSize           int `mapstructure:"mmap_size"`           // MB memory mapped per connection; 0 turns it off
+	CheckpointInterval int `mapstructure:"checkpoint_interval"` // seconds between WAL checkpoints; 0 leaves them to SQLite
+}
+
+// DisplayConfig contains settings for the locale API responses describe
+// their figures in. Costs are reported in pricing.currency.
+type DisplayConfig struct {
+	Locale            string `mapstructure:"locale"`              // such as en-US or de-DE; used when a request's Accept-Language names no supported locale
+	UseAcceptLanguage bool   `mapstructure:"use_accept_language"` // let each request's Accept-Language header pick the locale
+	FormattedStrings  bool   `mapstructure:"formatted_strings"`   // include display strings unless a request passes formatted=false
+}
+
+// ReportsConfig contains the scheduled summary reports and the mail server
+// they are sent through. No reports are sent unless Schedules lists some.
+type ReportsConfig struct {
+	SMTP      SMTPConfig     `mapstructure:"smtp"`
+	Schedules []ReportConfig `mapstructure:"schedules"`
+}
+
+// SMTPConfig is the mail server reports are emailed through. STARTTLS is
+// used when the server offers it.
+type SMTPConfig struct {
+	Host     string `mapstructure:"host"`
+	Port     int    `mapstructure:"port"`
+	Username string `mapstructure:"username"` // no authentication when empty
+	Password string `mapstructure:"password"`
+	From     string `mapstructure:"from"`
+}
+
+// ReportConfig is a summary of the sessions, costs, top projects and cache
+// savings of the previous day or week, sent at Hour local time to the Email
+// recipients, the WebhookURL or both
+type ReportConfig struct {
+	Name        string   `mapstructure:"name"`
+	Period      string   `mapstructure:"period"`       // daily or weekly
+	Weekday     string   `mapstructure:"weekday"`      // day weekly reports are sent, monday when empty
+	Hour        int      `mapstructure:"hour"`         // 0-23
+	Format      string   `mapstructure:"format"`       // markdown (default) or html
+	TopProjects int      `mapstructure:"top_projects"` // projects listed, 5 when unset
+	Email       []string `mapstructure:"email"`
+	WebhookURL  string   `mapstructure:"webhook_url"`
+}
+
+// NotificationsConfig contains the Slack and Discord incoming webhooks that
+// high-signal events are posted to, and which of those events are sent.
+// Nothing is sent unless a webhook URL is set.
+type NotificationsConfig struct {
+	SlackWebhookURL   string `mapstructure:"slack_webhook_url"`
+	DiscordWebhookURL string `mapstructure:"discord_webhook_url"`
+	Interval          int    `mapstructure:"interval"` // seconds between idle session and daily cost checks
+
+	SessionIdle  IdleNotificationConfig  `mapstructure:"session_idle"`
+	DailyCost    CostNotificationConfig  `mapstructure:"daily_cost"`
+	SessionError ErrorNotificationConfig `mapstructure:"session_error"`
+}
+
+// IdleNotificationConfig notifies when a session has been idle for Minutes
+// and its working tree has uncommitted changes
+type IdleNotificationConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	Minutes int  `mapstructure:"minutes"`
+}
+
+// CostNotificationConfig notifies once a day when the day's cost reaches
+// Threshold, in USD
+type CostNotificationConfig struct {
+	Enabled   bool    `mapstructure:"enabled"`
+	Threshold float64 `mapstructure:"threshold"`
+}
+
+// ErrorNotificationConfig notifies when a session reports a failed tool call
+// or a chat fails
+type ErrorNotificationConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+}
+
+// SessionStatusConfig contains the thresholds sessions move from active to
+// idle and from idle to completed at, counted from their last activity
+type SessionStatusConfig struct {
+	Enabled       bool `mapstructure:"enabled"`
+	IdleAfter     int  `mapstructure:"idle_after"`     // minutes without activity before an active session is idle
+	CompleteAfter int  `mapstructure:"complete_after"` // minutes without activity before a session is completed
+	Interval      int  `mapstructure:"interval"`       // seconds between checks
+}
+
+// ChatConfig contains limits on the Claude CLI processes run for chats,
+// REST prompts and playbooks. Requests beyond max_processes wait their turn.
+// Chats started through the API may only ask for the permission modes and
+// tools listed.
+type ChatConfig struct {
+	MaxProcesses    int      `mapstructure:"max_processes"`    // run at once; 0 for no limit
+	MaxQueued       int      `mapstructure:"max_queued"`       // requests waiting before more are refused; 0 for no limit
+	QueueTimeout    int      `mapstructure:"queue_timeout"`    // seconds a request waits for a process; 0 to wait as long as the request lasts
+	CommandTimeout  int      `mapstructure:"command_timeout"`  // seconds a chat message's process may run; 0 for no limit
+	InactiveTimeout int      `mapstructure:"inactive_timeout"` // minutes before an unused chat is stopped; 0 to keep chats until they end
+	PermissionModes []string `mapstructure:"permission_modes"` // --permission-mode values chats may ask for
+	AllowedTools    []string `mapstructure:"allowed_tools"`    // --allowedTools values chats may ask for; a bare name such as Bash covers Bash(git log:*)
+}
+
+// ExportsConfig contains settings for the heavy exports, such as backups and
+// analytics datasets, generated in the background and downloaded through
+// signed, expiring URLs
+type ExportsConfig struct {
+	Directory  string `mapstructure:"directory"`   // where exports are written; exports beside the database when empty
+	SigningKey string `mapstructure:"signing_key"` // HMAC key for download URLs; random on each start when empty
+	URLTTL     int    `mapstructure:"url_ttl"`     // minutes a download URL is valid; 15 when 0
+	Retention  int    `mapstructure:"retention"`   // hours a finished export is kept; 24 when 0
+	Workers    int    `mapstructure:"workers"`     // exports generated at once; 1 when 0
+	PerHour    int    `mapstructure:"per_hour"`    // exports each client may start an hour; 0 for no limit
 }
 
 // DefaultConfig returns the default configuration
@@ -82,30 +417,130 @@ func DefaultConfig() *Config {
 			CORS: CORSConfig{
 				Enabled:          true,
 				AllowedOrigins:   []string{"*", "http://localhost:5173", "http://localhost:3000", "http://127.0.0.1:5173", "http://127.0.0.1:3000"},
-				AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+				AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
 				AllowedHeaders:   []string{"Origin", "Content-Type", "Accept", "Authorization"},
 				AllowCredentials: true,
 				MaxAge:           86400,
 			},
+			RateLimit: RateLimitConfig{
+				Enabled:           false,
+				RequestsPerSecond: 10,
+				Burst:             20,
+			},
 		},
 		Claude: ClaudeConfig{
 			HomeDirectory:    claudeDir,
 			ProjectsPath:     filepath.Join(claudeDir, "projects"),
 			WatchInterval:    5,
+			WatchMode:        "auto",
 			CacheRefreshRate: 5,
+			MaxLineSize:      10,
 		},
 		Pricing: PricingConfig{
 			InputTokensPerK:  0.003,  // $3.00 per million = $0.003 per 1K
 			OutputTokensPerK: 0.015,  // $15.00 per million = $0.015 per 1K  
 			Currency:         "USD",
+			RemoteRefresh:    24,
 		},
 		Features: FeaturesConfig{
 			EnableWebSocket:   true,
 			EnableFileWatcher: true,
 			EnableMetrics:     false,
+			EnableAPIUsage:    true,
 			EnableProfiling:   false,
 			DebugMode:         false,
 			WebSocketBatchInterval: 20, // 20 seconds default
+			WebSocketTotalsInterval: 5,
+		},
+		Playbooks: PlaybooksConfig{
+			Directory:       filepath.Join(claudeDir, "playbooks"),
+			StepTimeout:     600,
+			EnableScheduler: true,
+		},
+		Federation: FederationConfig{
+			Enabled:      false,
+			PollInterval: 300,
+			Days:         30,
+		},
+		CostCenters: CostCentersConfig{
+			Default: "unallocated",
+		},
+		Telemetry: TelemetryConfig{
+			Enabled:  false,
+			Interval: 24,
+		},
+		Scripting: ScriptingConfig{
+			CostLimit: 100000,
+			Timeout:   50,
+		},
+		Auth: AuthConfig{
+			Enabled: false,
+		},
+		Retention: RetentionConfig{
+			Enabled:    false,
+			MaxAgeDays: 365,
+			Interval:   24,
+		},
+		MQTT: MQTTConfig{
+			Enabled:             false,
+			ClientID:            "claude-session-manager",
+			Interval:            60,
+			ActiveSessionsTopic: "claude-session-manager/active_sessions",
+			CostTodayTopic:      "claude-session-manager/cost_today",
+			BudgetTopic:         "claude-session-manager/budget",
+			DiscoveryPrefix:     "homeassistant",
+		},
+		Database: DatabaseConfig{
+			BusyTimeout:        30000,
+			ReadConnections:    8,
+			CacheSize:          40,
+			MmapSize:           256,
+			CheckpointInterval: 300,
+		},
+		Display: DisplayConfig{
+			Locale:            "en-US",
+			UseAcceptLanguage: true,
+			FormattedStrings:  false,
+		},
+		Reports: ReportsConfig{
+			SMTP: SMTPConfig{
+				Port: 587,
+			},
+		},
+		Notifications: NotificationsConfig{
+			Interval: 60,
+			SessionIdle: IdleNotificationConfig{
+				Enabled: true,
+				Minutes: 30,
+			},
+			DailyCost: CostNotificationConfig{
+				Enabled:   false,
+				Threshold: 50,
+			},
+			SessionError: ErrorNotificationConfig{
+				Enabled: true,
+			},
+		},
+		SessionStatus: SessionStatusConfig{
+			Enabled:       true,
+			IdleAfter:     5,
+			CompleteAfter: 30,
+			Interval:      30,
+		},
+		Chat: ChatConfig{
+			MaxProcesses:    10,
+			MaxQueued:       100,
+			QueueTimeout:    300,
+			CommandTimeout:  300,
+			InactiveTimeout: 30,
+			PermissionModes: []string{"default", "acceptEdits", "plan"},
+			AllowedTools:    []string{"Read", "Grep", "Glob", "LS", "Edit", "MultiEdit", "Write", "NotebookEdit", "WebFetch", "WebSearch", "TodoWrite"},
+		},
+		Exports: ExportsConfig{
+			URLTTL:    15,
+			Retention: 24,
+			Workers:   1,
+			PerHour:   10,
 		},
 	}
 }
@@ -181,27 +616,146 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("server.cors.allowed_headers", defaults.Server.CORS.AllowedHeaders)
 	v.SetDefault("server.cors.allow_credentials", defaults.Server.CORS.AllowCredentials)
 	v.SetDefault("server.cors.max_age", defaults.Server.CORS.MaxAge)
+
+	// Rate limit defaults
+	v.SetDefault("server.rate_limit.enabled", defaults.Server.RateLimit.Enabled)
+	v.SetDefault("server.rate_limit.requests_per_second", defaults.Server.RateLimit.RequestsPerSecond)
+	v.SetDefault("server.rate_limit.burst", defaults.Server.RateLimit.Burst)
 	
 	// Claude defaults
 	v.SetDefault("claude.home_directory", defaults.Claude.HomeDirectory)
 	v.SetDefault("claude.projects_path", defaults.Claude.ProjectsPath)
 	v.SetDefault("claude.watch_interval", defaults.Claude.WatchInterval)
+	v.SetDefault("claude.watch_mode", defaults.Claude.WatchMode)
+	v.SetDefault("claude.max_line_size", defaults.Claude.MaxLineSize)
 	v.SetDefault("claude.cache_refresh_rate", defaults.Claude.CacheRefreshRate)
 	
 	// Pricing defaults
 	v.SetDefault("pricing.input_tokens_per_k", defaults.Pricing.InputTokensPerK)
 	v.SetDefault("pricing.output_tokens_per_k", defaults.Pricing.OutputTokensPerK)
 	v.SetDefault("pricing.currency", defaults.Pricing.Currency)
+	v.SetDefault("pricing.remote_refresh", defaults.Pricing.RemoteRefresh)
 	
 	// Features defaults
 	v.SetDefault("features.enable_websocket", defaults.Features.EnableWebSocket)
 	v.SetDefault("features.enable_file_watcher", defaults.Features.EnableFileWatcher)
 	v.SetDefault("features.enable_metrics", defaults.Features.EnableMetrics)
+	v.SetDefault("features.enable_api_usage", defaults.Features.EnableAPIUsage)
 	v.SetDefault("features.enable_profiling", defaults.Features.EnableProfiling)
 	v.SetDefault("features.debug_mode", defaults.Features.DebugMode)
 	v.SetDefault("features.websocket_batch_interval", defaults.Features.WebSocketBatchInterval)
+	v.SetDefault("features.websocket_totals_interval", defaults.Features.WebSocketTotalsInterval)
+
+	// Playbook defaults
+	v.SetDefault("playbooks.directory", defaults.Playbooks.Directory)
+	v.SetDefault("playbooks.step_timeout", defaults.Playbooks.StepTimeout)
+	v.SetDefault("playbooks.enable_scheduler", defaults.Playbooks.EnableScheduler)
+
+	// Federation defaults
+	v.SetDefault("federation.enabled", defaults.Federation.Enabled)
+	v.SetDefault("federation.poll_interval", defaults.Federation.PollInterval)
+	v.SetDefault("federation.days", defaults.Federation.Days)
+
+	// Cost center defaults
+	v.SetDefault("cost_centers.default", defaults.CostCenters.Default)
+
+	// Statusline defaults
+	v.SetDefault("statusline.daily_budget", defaults.Statusline.DailyBudget)
+
+	// Launcher defaults
+	v.SetDefault("launcher.dashboard_url", defaults.Launcher.DashboardURL)
+
+	// Telemetry defaults
+	v.SetDefault("telemetry.enabled", defaults.Telemetry.Enabled)
+	v.SetDefault("telemetry.endpoint", defaults.Telemetry.Endpoint)
+	v.SetDefault("telemetry.interval", defaults.Telemetry.Interval)
+
+	// Scripting defaults
+	v.SetDefault("scripting.cost_limit", defaults.Scripting.CostLimit)
+	v.SetDefault("scripting.timeout", defaults.Scripting.Timeout)
+
+	// Auth defaults
+	v.SetDefault("auth.enabled", defaults.Auth.Enabled)
+
+	// Retention defaults
+	v.SetDefault("retention.enabled", defaults.Retention.Enabled)
+	v.SetDefault("retention.max_age_days", defaults.Retention.MaxAgeDays)
+	v.SetDefault("retention.interval", defaults.Retention.Interval)
+
+	// MQTT defaults
+	v.SetDefault("mqtt.enabled", defaults.MQTT.Enabled)
+	v.SetDefault("mqtt.broker", defaults.MQTT.Broker)
+	v.SetDefault("mqtt.client_id", defaults.MQTT.ClientID)
+	v.SetDefault("mqtt.username", defaults.MQTT.Username)
+	v.SetDefault("mqtt.password", defaults.MQTT.Password)
+	v.SetDefault("mqtt.interval", defaults.MQTT.Interval)
+	v.SetDefault("mqtt.active_sessions_topic", defaults.MQTT.ActiveSessionsTopic)
+	v.SetDefault("mqtt.cost_today_topic", defaults.MQTT.CostTodayTopic)
+	v.SetDefault("mqtt.budget_topic", defaults.MQTT.BudgetTopic)
+	v.SetDefault("mqtt.discovery_prefix", defaults.MQTT.DiscoveryPrefix)
+
+	// Database defaults
+	v.SetDefault("database.busy_timeout", defaults.Database.BusyTimeout)
+	v.SetDefault("database.read_connections", defaults.Database.ReadConnections)
+	v.SetDefault("database.cache_size", defaults.Database.CacheSize)
+	v.SetDefault("database.mmap_size", defaults.Database.MmapSize)
+	v.SetDefault("database.checkpoint_interval", defaults.Database.CheckpointInterval)
+
+	// Display defaults
+	v.SetDefault("display.locale", defaults.Display.Locale)
+	v.SetDefault("display.use_accept_language", defaults.Display.UseAcceptLanguage)
+	v.SetDefault("display.formatted_strings", defaults.Display.FormattedStrings)
+
+	// Report defaults
+	v.SetDefault("reports.smtp.host", defaults.Reports.SMTP.Host)
+	v.SetDefault("reports.smtp.port", defaults.Reports.SMTP.Port)
+	v.SetDefault("reports.smtp.username", defaults.Reports.SMTP.Username)
+	v.SetDefault("reports.smtp.password", defaults.Reports.SMTP.Password)
+	v.SetDefault("reports.smtp.from", defaults.Reports.SMTP.From)
+
+	// Notification defaults
+	v.SetDefault("notifications.slack_webhook_url", defaults.Notifications.SlackWebhookURL)
+	v.SetDefault("notifications.discord_webhook_url", defaults.Notifications.DiscordWebhookURL)
+	v.SetDefault("notifications.interval", defaults.Notifications.Interval)
+	v.SetDefault("notifications.session_idle.enabled", defaults.Notifications.SessionIdle.Enabled)
+	v.SetDefault("notifications.session_idle.minutes", defaults.Notifications.SessionIdle.Minutes)
+	v.SetDefault("notifications.daily_cost.enabled", defaults.Notifications.DailyCost.Enabled)
+	v.SetDefault("notifications.daily_cost.threshold", defaults.Notifications.DailyCost.Threshold)
+	v.SetDefault("notifications.session_error.enabled", defaults.Notifications.SessionError.Enabled)
+
+	// Session status defaults
+	v.SetDefault("session_status.enabled", defaults.SessionStatus.Enabled)
+	v.SetDefault("session_status.idle_after", defaults.SessionStatus.IdleAfter)
+	v.SetDefault("session_status.complete_after", defaults.SessionStatus.CompleteAfter)
+	v.SetDefault("session_status.interval", defaults.SessionStatus.Interval)
+
+	// Chat defaults
+	v.SetDefault("chat.max_processes", defaults.Chat.MaxProcesses)
+	v.SetDefault("chat.max_queued", defaults.Chat.MaxQueued)
+	v.SetDefault("chat.queue_timeout", defaults.Chat.QueueTimeout)
+	v.SetDefault("chat.command_timeout", defaults.Chat.CommandTimeout)
+	v.SetDefault("chat.inactive_timeout", defaults.Chat.InactiveTimeout)
+	v.SetDefault("chat.permission_modes", defaults.Chat.PermissionModes)
+	v.SetDefault("chat.allowed_tools", defaults.Chat.AllowedTools)
+
+	// Export defaults
+	v.SetDefault("exports.directory", defaults.Exports.Directory)
+	v.SetDefault("exports.signing_key", defaults.Exports.SigningKey)
+	v.SetDefault("exports.url_ttl", defaults.Exports.URLTTL)
+	v.SetDefault("exports.retention", defaults.Exports.Retention)
+	v.SetDefault("exports.workers", defaults.Exports.Workers)
+	v.SetDefault("exports.per_hour", defaults.Exports.PerHour)
 }
 
+// reportWeekdays are the days weekly reports can be sent on
+var reportWeekdays = map[string]bool{
+	"": true, "monday": true, "tuesday": true, "wednesday": true, "thursday": true, "friday": true, "saturday": true, "sunday": true,
+}
+
+// localeTagPattern matches the language and region tags display.locale
+// accepts, such as "en" or "en-US"
+var localeTagPattern = regexp.MustCompile(`^[A-Za-z]{2,3}([-_][A-Za-z]{2})?$`)
+
 // validateConfig validates the configuration
 func validateConfig(config *Config) error {
 	// Validate server port
@@ -219,15 +773,97 @@ func validateConfig(config *Config) error {
 	if config.Server.ShutdownTimeout < 0 {
 		return fmt.Errorf("invalid shutdown timeout: %d", config.Server.ShutdownTimeout)
 	}
+
+	// Validate rate limiting
+	if config.Server.RateLimit.Enabled {
+		if config.Server.RateLimit.RequestsPerSecond <= 0 {
+			return fmt.Errorf("invalid rate limit requests per second: %f", config.Server.RateLimit.RequestsPerSecond)
+		}
+		if config.Server.RateLimit.Burst < 1 {
+			return fmt.Errorf("invalid rate limit burst: %d", config.Server.RateLimit.Burst)
+		}
+	}
 	
+	if config.Features.WebSocketTotalsInterval < 0 {
+		return fmt.Errorf("invalid websocket totals interval: %d", config.Features.WebSocketTotalsInterval)
+	}
+
 	// Validate Claude settings
 	if config.Claude.WatchInterval < 0 {
 		return fmt.Errorf("invalid watch interval: %d", config.Claude.WatchInterval)
 	}
+	switch config.Claude.WatchMode {
+	case "", "auto", "fsnotify", "poll":
+	default:
+		return fmt.Errorf("invalid watch mode: %q (expected auto, fsnotify or poll)", config.Claude.WatchMode)
+	}
 	if config.Claude.CacheRefreshRate < 0 {
 		return fmt.Errorf("invalid cache refresh rate: %d", config.Claude.CacheRefreshRate)
 	}
+	if config.Claude.MaxLineSize < 0 {
+		return fmt.Errorf("invalid max line size: %d", config.Claude.MaxLineSize)
+	}
 	
+	// Validate playbooks
+	if config.Playbooks.StepTimeout < 0 {
+		return fmt.Errorf("invalid playbook step timeout: %d", config.Playbooks.StepTimeout)
+	}
+
+	// Validate federation
+	if config.Federation.Enabled {
+		if config.Federation.PollInterval <= 0 {
+			return fmt.Errorf("invalid federation poll interval: %d", config.Federation.PollInterval)
+		}
+		for _, instance := range config.Federation.Instances {
+			if instance.Name == "" || instance.URL == "" {
+				return fmt.Errorf("federation instances require a name and url")
+			}
+		}
+	}
+
+	// Validate tagging rules
+	names := make(map[string]bool)
+	for i, rule := range config.Tagging.Rules {
+		if rule.Tag == "" {
+			return fmt.Errorf("tagging rule %d has no tag", i+1)
+		}
+		if rule.Name == "" {
+			config.Tagging.Rules[i].Name = rule.Tag
+			rule.Name = rule.Tag
+		}
+		if names[rule.Name] {
+			return fmt.Errorf("duplicate tagging rule name: %s", rule.Name)
+		}
+		names[rule.Name] = true
+
+		patterns := rule.Patterns()
+		if len(patterns) == 0 {
+			return fmt.Errorf("tagging rule %s has no patterns", rule.Name)
+		}
+		for field, pattern := range patterns {
+			if _, err := regexp.Compile(pattern); err != nil {
+				return fmt.Errorf("tagging rule %s has an invalid %s pattern: %w", rule.Name, field, err)
+			}
+		}
+	}
+
+	// Validate cost centers
+	centers := make(map[string]bool)
+	for _, center := range config.CostCenters.Centers {
+		if center.Name == "" {
+			return fmt.Errorf("cost centers require a name")
+		}
+		if centers[center.Name] {
+			return fmt.Errorf("duplicate cost center: %s", center.Name)
+		}
+		centers[center.Name] = true
+		for _, pattern := range center.Projects {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("cost center %s has an invalid project pattern %q", center.Name, pattern)
+			}
+		}
+	}
+
 	// Validate pricing
 	if config.Pricing.InputTokensPerK < 0 {
 		return fmt.Errorf("invalid input token price: %f", config.Pricing.InputTokensPerK)
@@ -235,6 +871,257 @@ func validateConfig(config *Config) error {
 	if config.Pricing.OutputTokensPerK < 0 {
 		return fmt.Errorf("invalid output token price: %f", config.Pricing.OutputTokensPerK)
 	}
+	for _, price := range config.Pricing.Models {
+		if price.Model == "" {
+			return fmt.Errorf("pricing models require a model name")
+		}
+		if price.Input < 0 || price.Output < 0 || price.CacheWrite < 0 || price.CacheRead < 0 {
+			return fmt.Errorf("invalid price for model %s", price.Model)
+		}
+	}
+	if price := config.Pricing.Default; price != nil && (price.Input < 0 || price.Output < 0 || price.CacheWrite < 0 || price.CacheRead < 0) {
+		return fmt.Errorf("invalid default model price")
+	}
+	if remoteURL := config.Pricing.RemoteURL; remoteURL != "" {
+		if u, err := url.Parse(remoteURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid pricing remote URL: %s", remoteURL)
+		}
+		if config.Pricing.RemoteRefresh <= 0 {
+			return fmt.Errorf("invalid pricing remote refresh: %d", config.Pricing.RemoteRefresh)
+		}
+	}
+	if config.Statusline.DailyBudget < 0 {
+		return fmt.Errorf("invalid statusline daily budget: %f", config.Statusline.DailyBudget)
+	}
+	if dashboardURL := config.Launcher.DashboardURL; dashboardURL != "" {
+		if u, err := url.Parse(dashboardURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid launcher dashboard url: %q", dashboardURL)
+		}
+	}
+
+	// Validate plugins
+	plugins := make(map[string]bool)
+	for _, plugin := range config.Plugins {
+		if plugin.Name == "" || plugin.Command == "" {
+			return fmt.Errorf("plugins require a name and command")
+		}
+		if plugins[plugin.Name] {
+			return fmt.Errorf("duplicate plugin: %s", plugin.Name)
+		}
+		plugins[plugin.Name] = true
+		if plugin.Timeout < 0 {
+			return fmt.Errorf("invalid timeout for plugin %s: %d", plugin.Name, plugin.Timeout)
+		}
+		for _, env := range plugin.Env {
+			if !strings.Contains(env, "=") {
+				return fmt.Errorf("plugin %s has an invalid env entry %q (expected KEY=VALUE)", plugin.Name, env)
+			}
+		}
+	}
+
+	// Validate telemetry
+	if config.Telemetry.Enabled {
+		if u, err := url.Parse(config.Telemetry.Endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid telemetry endpoint: %q", config.Telemetry.Endpoint)
+		}
+		if config.Telemetry.Interval <= 0 {
+			return fmt.Errorf("invalid telemetry interval: %d", config.Telemetry.Interval)
+		}
+	}
+
+	// Validate retention
+	if config.Retention.Enabled {
+		if config.Retention.MaxAgeDays <= 0 {
+			return fmt.Errorf("invalid retention max age: %d days", config.Retention.MaxAgeDays)
+		}
+		if config.Retention.Interval <= 0 {
+			return fmt.Errorf("invalid retention interval: %d", config.Retention.Interval)
+		}
+	}
+
+	// Validate MQTT
+	if config.MQTT.Enabled {
+		if config.MQTT.Broker == "" {
+			return fmt.Errorf("mqtt broker is required when mqtt is enabled")
+		}
+		if config.MQTT.ClientID == "" {
+			return fmt.Errorf("mqtt client id is required when mqtt is enabled")
+		}
+		if config.MQTT.Interval <= 0 {
+			return fmt.Errorf("invalid mqtt interval: %d", config.MQTT.Interval)
+		}
+		for _, topic := range []string{config.MQTT.ActiveSessionsTopic, config.MQTT.CostTodayTopic, config.MQTT.BudgetTopic} {
+			if topic == "" || strings.ContainsAny(topic, "+#") {
+				return fmt.Errorf("invalid mqtt topic: %q", topic)
+			}
+		}
+	}
+
+	// Validate database settings
+	if config.Database.BusyTimeout < 0 {
+		return fmt.Errorf("invalid database busy timeout: %d", config.Database.BusyTimeout)
+	}
+	if config.Database.ReadConnections < 0 {
+		return fmt.Errorf("invalid database read connections: %d", config.Database.ReadConnections)
+	}
+	if config.Database.CacheSize < 0 {
+		return fmt.Errorf("invalid database cache size: %d", config.Database.CacheSize)
+	}
+	if config.Database.MmapSize < 0 {
+		return fmt.Errorf("invalid database mmap size: %d", config.Database.MmapSize)
+	}
+	if config.Database.CheckpointInterval < 0 {
+		return fmt.Errorf("invalid database checkpoint interval: %d", config.Database.CheckpointInterval)
+	}
+	if locale := config.Display.Locale; locale != "" && !localeTagPattern.MatchString(locale) {
+		return fmt.Errorf("invalid display locale: %q (expected a tag such as en-US)", locale)
+	}
+
+	// Validate scripts
+	if config.Scripting.Timeout < 0 {
+		return fmt.Errorf("invalid scripting timeout: %d", config.Scripting.Timeout)
+	}
+	scripts := make(map[string]bool)
+	for _, script := range config.Scripting.Scripts {
+		if script.Name == "" || script.Expression == "" {
+			return fmt.Errorf("scripts require a name and expression")
+		}
+		if scripts[script.Name] {
+			return fmt.Errorf("duplicate script: %s", script.Name)
+		}
+		scripts[script.Name] = true
+		if script.On != "message" && script.On != "session" {
+			return fmt.Errorf("script %s must run on message or session, got %q", script.Name, script.On)
+		}
+		switch script.Action {
+		case "field":
+			if script.Field == "" {
+				return fmt.Errorf("script %s sets a field but has no field name", script.Name)
+			}
+		case "tag":
+			if script.Tag == "" {
+				return fmt.Errorf("script %s applies a tag but has no tag", script.Name)
+			}
+		case "drop":
+			if script.On != "message" {
+				return fmt.Errorf("script %s can only drop messages", script.Name)
+			}
+		case "notify":
+			if script.On != "session" {
+				return fmt.Errorf("script %s can only notify on sessions", script.Name)
+			}
+		default:
+			return fmt.Errorf("invalid action for script %s: %q", script.Name, script.Action)
+		}
+	}
+
+	// Validate reports
+	if smtp := config.Reports.SMTP; smtp.Port < 0 || smtp.Port > 65535 {
+		return fmt.Errorf("invalid reports smtp port: %d", smtp.Port)
+	}
+	reports := make(map[string]bool)
+	for _, report := range config.Reports.Schedules {
+		if report.Name == "" {
+			return fmt.Errorf("reports require a name")
+		}
+		if reports[report.Name] {
+			return fmt.Errorf("duplicate report: %s", report.Name)
+		}
+		reports[report.Name] = true
+		if report.Period != "daily" && report.Period != "weekly" {
+			return fmt.Errorf("report %s must be daily or weekly, got %q", report.Name, report.Period)
+		}
+		if !reportWeekdays[strings.ToLower(report.Weekday)] {
+			return fmt.Errorf("invalid weekday for report %s: %q", report.Name, report.Weekday)
+		}
+		if report.Hour < 0 || report.Hour > 23 {
+			return fmt.Errorf("invalid hour for report %s: %d", report.Name, report.Hour)
+		}
+		if report.Format != "" && report.Format != "markdown" && report.Format != "html" {
+			return fmt.Errorf("report %s must be markdown or html, got %q", report.Name, report.Format)
+		}
+		if report.TopProjects < 0 {
+			return fmt.Errorf("invalid top projects for report %s: %d", report.Name, report.TopProjects)
+		}
+		if len(report.Email) == 0 && report.WebhookURL == "" {
+			return fmt.Errorf("report %s needs email recipients or a webhook_url", report.Name)
+		}
+		if len(report.Email) > 0 && (config.Reports.SMTP.Host == "" || config.Reports.SMTP.From == "") {
+			return fmt.Errorf("report %s is emailed but reports.smtp has no host or from address", report.Name)
+		}
+		if report.WebhookURL != "" {
+			parsed, err := url.Parse(report.WebhookURL)
+			if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+				return fmt.Errorf("invalid webhook url for report %s: %q", report.Name, report.WebhookURL)
+			}
+		}
+	}
+
+	// Validate notifications
+	notifications := config.Notifications
+	for name, webhookURL := range map[string]string{"slack": notifications.SlackWebhookURL, "discord": notifications.DiscordWebhookURL} {
+		if webhookURL == "" {
+			continue
+		}
+		parsed, err := url.Parse(webhookURL)
+		if err != nil || parsed.Scheme != "https" || parsed.Host == "" {
+			return fmt.Errorf("invalid %s webhook url: %q", name, webhookURL)
+		}
+	}
+	if notifications.SlackWebhookURL != "" || notifications.DiscordWebhookURL != "" {
+		if notifications.Interval <= 0 {
+			return fmt.Errorf("invalid notifications interval: %d", notifications.Interval)
+		}
+		if notifications.SessionIdle.Enabled && notifications.SessionIdle.Minutes <= 0 {
+			return fmt.Errorf("invalid session idle minutes: %d", notifications.SessionIdle.Minutes)
+		}
+		if notifications.DailyCost.Enabled && notifications.DailyCost.Threshold <= 0 {
+			return fmt.Errorf("invalid daily cost threshold: %v", notifications.DailyCost.Threshold)
+		}
+	}
+
+	// Validate session status transitions
+	if status := config.SessionStatus; status.Enabled {
+		if status.IdleAfter <= 0 {
+			return fmt.Errorf("invalid session idle after: %d minutes", status.IdleAfter)
+		}
+		if status.CompleteAfter <= status.IdleAfter {
+			return fmt.Errorf("session complete after must be longer than idle after: %d minutes", status.CompleteAfter)
+		}
+		if status.Interval <= 0 {
+			return fmt.Errorf("invalid session status interval: %d", status.Interval)
+		}
+	}
+
+	// Validate chat limits
+	if chat := config.Chat; chat.MaxProcesses < 0 || chat.MaxQueued < 0 {
+		return fmt.Errorf("invalid chat limits: max_processes %d, max_queued %d", chat.MaxProcesses, chat.MaxQueued)
+	}
+	if chat := config.Chat; chat.QueueTimeout < 0 || chat.CommandTimeout < 0 || chat.InactiveTimeout < 0 {
+		return fmt.Errorf("invalid chat timeouts: queue_timeout %d, command_timeout %d, inactive_timeout %d", chat.QueueTimeout, chat.CommandTimeout, chat.InactiveTimeout)
+	}
+	for _, mode := range config.Chat.PermissionModes {
+		known := false
+		for _, permissionMode := range chat.PermissionModes {
+			known = known || mode == permissionMode
+		}
+		if !known {
+			return fmt.Errorf("invalid chat permission mode: %q (expected one of %s)", mode, strings.Join(chat.PermissionModes, ", "))
+		}
+	}
+	for _, tool := range config.Chat.AllowedTools {
+		if strings.TrimSpace(tool) == "" {
+			return fmt.Errorf("invalid chat allowed tool: empty name")
+		}
+	}
+
+	// Validate exports
+	if exports := config.Exports; exports.URLTTL < 0 || exports.Retention < 0 {
+		return fmt.Errorf("invalid exports expiry: url_ttl %d minutes, retention %d hours", exports.URLTTL, exports.Retention)
+	}
+	if exports := config.Exports; exports.Workers < 0 || exports.PerHour < 0 {
+		return fmt.Errorf("invalid exports limits: workers %d, per_hour %d", exports.Workers, exports.PerHour)
+	}
 	
 	return nil
 }
@@ -42,7 +42,7 @@ func TestDefaultConfig(t *testing.T) {
 		t.Errorf("Expected default allowed origins ['*'], got %v", config.Server.CORS.AllowedOrigins)
 	}
 	
-	expectedMethods := []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
+	expectedMethods := []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
 	if len(config.Server.CORS.AllowedMethods) != len(expectedMethods) {
 		t.Errorf("Expected %d allowed methods, got %d", len(expectedMethods), len(config.Server.CORS.AllowedMethods))
 	}
@@ -362,6 +362,32 @@ func TestValidateConfig(t *testing.T) {
 			wantErr: true,
 			errMsg:  "invalid watch interval",
 		},
+		{
+			name: "Unknown watch mode",
+			config: &Config{
+				Server: ServerConfig{Port: 8080},
+				Claude: ClaudeConfig{WatchMode: "inotify"},
+			},
+			wantErr: true,
+			errMsg:  "invalid watch mode",
+		},
+		{
+			name: "Invalid max line size",
+			config: &Config{
+				Server: ServerConfig{Port: 8080},
+				Claude: ClaudeConfig{MaxLineSize: -1},
+			},
+			wantErr: true,
+			errMsg:  "invalid max line size",
+		},
+		{
+			name: "Invalid rate limit burst",
+			config: &Config{
+				Server: ServerConfig{Port: 8080, RateLimit: RateLimitConfig{Enabled: true, RequestsPerSecond: 5}},
+			},
+			wantErr: true,
+			errMsg:  "invalid rate limit burst",
+		},
 		{
 			name: "Invalid cache refresh rate",
 			config: &Config{
@@ -391,6 +417,212 @@ func TestValidateConfig(t *testing.T) {
 			wantErr: true,
 			errMsg:  "invalid output token price",
 		},
+		{
+			name: "Negative model price",
+			config: &Config{
+				Server:  ServerConfig{Port: 8080},
+				Pricing: PricingConfig{Models: []ModelPrice{{Model: "claude-sonnet-4", Input: -3}}},
+			},
+			wantErr: true,
+			errMsg:  "invalid price for model claude-sonnet-4",
+		},
+		{
+			name: "Pricing remote URL without a scheme",
+			config: &Config{
+				Server:  ServerConfig{Port: 8080},
+				Pricing: PricingConfig{RemoteURL: "example.com/prices.json", RemoteRefresh: 24},
+			},
+			wantErr: true,
+			errMsg:  "invalid pricing remote URL",
+		},
+		{
+			name: "Tagging rule without a tag",
+			config: &Config{
+				Server:  ServerConfig{Port: 8080},
+				Tagging: TaggingConfig{Rules: []TagRule{{Name: "incidents", FirstMessage: "outage"}}},
+			},
+			wantErr: true,
+			errMsg:  "tagging rule 1 has no tag",
+		},
+		{
+			name: "Tagging rule without patterns",
+			config: &Config{
+				Server:  ServerConfig{Port: 8080},
+				Tagging: TaggingConfig{Rules: []TagRule{{Tag: "prod-incident"}}},
+			},
+			wantErr: true,
+			errMsg:  "tagging rule prod-incident has no patterns",
+		},
+		{
+			name: "Tagging rule with an invalid pattern",
+			config: &Config{
+				Server:  ServerConfig{Port: 8080},
+				Tagging: TaggingConfig{Rules: []TagRule{{Name: "payments", Tag: "payments", Path: "(unclosed"}}},
+			},
+			wantErr: true,
+			errMsg:  "tagging rule payments has an invalid path pattern",
+		},
+		{
+			name: "Negative statusline daily budget",
+			config: &Config{
+				Server:     ServerConfig{Port: 8080},
+				Statusline: StatuslineConfig{DailyBudget: -5},
+			},
+			wantErr: true,
+			errMsg:  "invalid statusline daily budget",
+		},
+		{
+			name: "Relative launcher dashboard url",
+			config: &Config{
+				Server:   ServerConfig{Port: 8080},
+				Launcher: LauncherConfig{DashboardURL: "localhost:5173"},
+			},
+			wantErr: true,
+			errMsg:  "invalid launcher dashboard url",
+		},
+		{
+			name: "Telemetry enabled without an endpoint",
+			config: &Config{
+				Server:    ServerConfig{Port: 8080},
+				Telemetry: TelemetryConfig{Enabled: true, Interval: 24},
+			},
+			wantErr: true,
+			errMsg:  "invalid telemetry endpoint",
+		},
+		{
+			name: "Duplicate plugin",
+			config: &Config{
+				Server:  ServerConfig{Port: 8080},
+				Plugins: []PluginConfig{{Name: "jira", Command: "jira-plugin"}, {Name: "jira", Command: "other"}},
+			},
+			wantErr: true,
+			errMsg:  "duplicate plugin: jira",
+		},
+		{
+			name: "Script dropping sessions",
+			config: &Config{
+				Server: ServerConfig{Port: 8080},
+				Scripting: ScriptingConfig{Scripts: []Script{
+					{Name: "short", On: "session", Expression: "session.message_count < 2", Action: "drop"},
+				}},
+			},
+			wantErr: true,
+			errMsg:  "script short can only drop messages",
+		},
+		{
+			name: "MQTT topic with a wildcard",
+			config: &Config{
+				Server: ServerConfig{Port: 8080},
+				MQTT:   MQTTConfig{Enabled: true, Broker: "localhost:1883", ClientID: "csm", Interval: 60, ActiveSessionsTopic: "csm/#", CostTodayTopic: "csm/cost", BudgetTopic: "csm/budget"},
+			},
+			wantErr: true,
+			errMsg:  "invalid mqtt topic",
+		},
+		{
+			name: "Negative database mmap size",
+			config: &Config{
+				Server:   ServerConfig{Port: 8080},
+				Database: DatabaseConfig{ReadConnections: 4, MmapSize: -1},
+			},
+			wantErr: true,
+			errMsg:  "invalid database mmap size",
+		},
+		{
+			name: "Report without a delivery",
+			config: &Config{
+				Server:  ServerConfig{Port: 8080},
+				Reports: ReportsConfig{Schedules: []ReportConfig{{Name: "weekly", Period: "weekly"}}},
+			},
+			wantErr: true,
+			errMsg:  "report weekly needs email recipients",
+		},
+		{
+			name: "Emailed report without an SMTP server",
+			config: &Config{
+				Server:  ServerConfig{Port: 8080},
+				Reports: ReportsConfig{Schedules: []ReportConfig{{Name: "daily", Period: "daily", Email: []string{"team@example.com"}}}},
+			},
+			wantErr: true,
+			errMsg:  "report daily is emailed",
+		},
+		{
+			name: "Weekly report to a webhook",
+			config: &Config{
+				Server:  ServerConfig{Port: 8080},
+				Reports: ReportsConfig{Schedules: []ReportConfig{{Name: "weekly", Period: "weekly", Weekday: "Friday", Hour: 17, WebhookURL: "https://hooks.example.com/reports"}}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "Slack notifications over plain HTTP",
+			config: &Config{
+				Server:        ServerConfig{Port: 8080},
+				Notifications: NotificationsConfig{SlackWebhookURL: "http://hooks.slack.com/services/T0/B0/x", Interval: 60},
+			},
+			wantErr: true,
+			errMsg:  "invalid slack webhook url",
+		},
+		{
+			name: "Daily cost notifications without a threshold",
+			config: &Config{
+				Server:        ServerConfig{Port: 8080},
+				Notifications: NotificationsConfig{DiscordWebhookURL: "https://discord.com/api/webhooks/1/x", Interval: 60, DailyCost: CostNotificationConfig{Enabled: true}},
+			},
+			wantErr: true,
+			errMsg:  "invalid daily cost threshold",
+		},
+		{
+			name: "Discord notifications",
+			config: &Config{
+				Server:        ServerConfig{Port: 8080},
+				Notifications: NotificationsConfig{DiscordWebhookURL: "https://discord.com/api/webhooks/1/x", Interval: 60, SessionIdle: IdleNotificationConfig{Enabled: true, Minutes: 30}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "Sessions completed before they are idle",
+			config: &Config{
+				Server:        ServerConfig{Port: 8080},
+				SessionStatus: SessionStatusConfig{Enabled: true, IdleAfter: 30, CompleteAfter: 10, Interval: 30},
+			},
+			wantErr: true,
+			errMsg:  "session complete after must be longer",
+		},
+		{
+			name: "Negative chat process limit",
+			config: &Config{
+				Server: ServerConfig{Port: 8080},
+				Chat:   ChatConfig{MaxProcesses: -1},
+			},
+			wantErr: true,
+			errMsg:  "invalid chat limits",
+		},
+		{
+			name: "Unknown chat permission mode",
+			config: &Config{
+				Server: ServerConfig{Port: 8080},
+				Chat:   ChatConfig{PermissionModes: []string{"plan", "yolo"}},
+			},
+			wantErr: true,
+			errMsg:  "invalid chat permission mode",
+		},
+		{
+			name: "Negative exports per hour",
+			config: &Config{
+				Server:  ServerConfig{Port: 8080},
+				Exports: ExportsConfig{PerHour: -1},
+			},
+			wantErr: true,
+			errMsg:  "invalid exports limits",
+		},
+		{
+			name: "Telemetry disabled without an endpoint",
+			config: &Config{
+				Server:    ServerConfig{Port: 8080},
+				Telemetry: TelemetryConfig{Enabled: false},
+			},
+			wantErr: false,
+		},
 	}
 	
 	for _, tt := range tests {
@@ -0,0 +1,204 @@
+// Package costcenter allocates session usage to the cost centers configured
+// for chargeback and formats the allocation for export.
+package costcenter
+
+import (
+	"encoding/csv"
+	"io"
+	"math"
+	"path"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/ksred/claude-session-manager/internal/config"
+	"github.com/ksred/claude-session-manager/internal/database"
+)
+
+// ProjectCost is a project's usage charged to a cost center
+type ProjectCost struct {
+	Project  string  `json:"project"`
+	Sessions int     `json:"sessions"`
+	Messages int     `json:"messages"`
+	Tokens   int     `json:"tokens"`
+	CostUSD  float64 `json:"cost_usd"`
+}
+
+// CenterCost is the usage charged to one cost center
+type CenterCost struct {
+	Name     string        `json:"name"`
+	Code     string        `json:"code,omitempty"`
+	Sessions int           `json:"sessions"`
+	Messages int           `json:"messages"`
+	Tokens   int           `json:"tokens"`
+	CostUSD  float64       `json:"cost_usd"`
+	Share    float64       `json:"share"` // fraction of the period's total cost
+	Projects []ProjectCost `json:"projects"`
+}
+
+// Report is the allocation of a period's usage to cost centers
+type Report struct {
+	From         time.Time    `json:"from"`
+	To           time.Time    `json:"to"`
+	Currency     string       `json:"currency"`
+	TotalCostUSD float64      `json:"total_cost_usd"`
+	CostCenters  []CenterCost `json:"cost_centers"`
+	GeneratedAt  time.Time    `json:"generated_at"`
+}
+
+// Allocator assigns sessions to cost centers by project and tag
+type Allocator struct {
+	centers       []config.CostCenter
+	defaultCenter string
+}
+
+// NewAllocator creates an allocator for the configured cost centers
+func NewAllocator(cfg config.CostCentersConfig) *Allocator {
+	defaultCenter := cfg.Default
+	if defaultCenter == "" {
+		defaultCenter = "unallocated"
+	}
+	return &Allocator{
+		centers:       cfg.Centers,
+		defaultCenter: defaultCenter,
+	}
+}
+
+// CenterFor returns the cost center a session is charged to: the first
+// configured center listing one of its tags or matching its project name,
+// or the default center if none does
+func (a *Allocator) CenterFor(project string, tags []string) config.CostCenter {
+	for _, center := range a.centers {
+		for _, pattern := range center.Projects {
+			if matched, _ := path.Match(pattern, project); matched {
+				return center
+			}
+		}
+		for _, want := range center.Tags {
+			for _, tag := range tags {
+				if tag == want {
+					return center
+				}
+			}
+		}
+	}
+	return config.CostCenter{Name: a.defaultCenter}
+}
+
+// Allocate charges each session's usage to its cost center. Centers are
+// ordered by cost, highest first, and every configured center is listed
+// even if nothing was charged to it.
+func (a *Allocator) Allocate(costs []database.SessionCost, tags map[string][]string, from, to time.Time, currency string) *Report {
+	report := &Report{
+		From:        from.UTC(),
+		To:          to.UTC(),
+		Currency:    currency,
+		GeneratedAt: time.Now().UTC(),
+	}
+
+	centers := make(map[string]*CenterCost)
+	projects := make(map[string]map[string]*ProjectCost)
+	center := func(c config.CostCenter) *CenterCost {
+		if existing, ok := centers[c.Name]; ok {
+			return existing
+		}
+		centers[c.Name] = &CenterCost{Name: c.Name, Code: c.Code, Projects: []ProjectCost{}}
+		projects[c.Name] = make(map[string]*ProjectCost)
+		return centers[c.Name]
+	}
+	for _, c := range a.centers {
+		center(c)
+	}
+
+	for _, cost := range costs {
+		charged := center(a.CenterFor(cost.ProjectName, tags[cost.SessionID]))
+		charged.Sessions++
+		charged.Messages += cost.Messages
+		charged.Tokens += cost.Tokens
+		charged.CostUSD += cost.CostUSD
+		report.TotalCostUSD += cost.CostUSD
+
+		project, ok := projects[charged.Name][cost.ProjectName]
+		if !ok {
+			project = &ProjectCost{Project: cost.ProjectName}
+			projects[charged.Name][cost.ProjectName] = project
+		}
+		project.Sessions++
+		project.Messages += cost.Messages
+		project.Tokens += cost.Tokens
+		project.CostUSD += cost.CostUSD
+	}
+
+	for name, charged := range centers {
+		for _, project := range projects[name] {
+			project.CostUSD = roundCost(project.CostUSD)
+			charged.Projects = append(charged.Projects, *project)
+		}
+		sort.Slice(charged.Projects, func(i, j int) bool {
+			if charged.Projects[i].CostUSD != charged.Projects[j].CostUSD {
+				return charged.Projects[i].CostUSD > charged.Projects[j].CostUSD
+			}
+			return charged.Projects[i].Project < charged.Projects[j].Project
+		})
+		if report.TotalCostUSD > 0 {
+			charged.Share = math.Round(charged.CostUSD/report.TotalCostUSD*10000) / 10000
+		}
+		charged.CostUSD = roundCost(charged.CostUSD)
+		report.CostCenters = append(report.CostCenters, *charged)
+	}
+	sort.Slice(report.CostCenters, func(i, j int) bool {
+		if report.CostCenters[i].CostUSD != report.CostCenters[j].CostUSD {
+			return report.CostCenters[i].CostUSD > report.CostCenters[j].CostUSD
+		}
+		return report.CostCenters[i].Name < report.CostCenters[j].Name
+	})
+	report.TotalCostUSD = roundCost(report.TotalCostUSD)
+
+	return report
+}
+
+// roundCost rounds a cost to six decimal places, as the anonymized export does
+func roundCost(cost float64) float64 {
+	return math.Round(cost*1e6) / 1e6
+}
+
+// chargebackCSVHeader lists the chargeback CSV columns. The period end is
+// inclusive so a calendar month reads as its first and last day.
+var chargebackCSVHeader = []string{
+	"period_start", "period_end", "cost_center", "cost_center_code", "project",
+	"sessions", "messages", "tokens", "amount", "currency",
+}
+
+// WriteCSV writes one row per cost center and project, in the layout used
+// for chargeback. Centers with no usage are omitted.
+func (r *Report) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(chargebackCSVHeader); err != nil {
+		return err
+	}
+
+	start := r.From.Format("2006-01-02")
+	end := r.To.Add(-time.Nanosecond).Format("2006-01-02")
+	for _, center := range r.CostCenters {
+		for _, project := range center.Projects {
+			record := []string{
+				start,
+				end,
+				center.Name,
+				center.Code,
+				project.Project,
+				strconv.Itoa(project.Sessions),
+				strconv.Itoa(project.Messages),
+				strconv.Itoa(project.Tokens),
+				strconv.FormatFloat(project.CostUSD, 'f', 2, 64),
+				r.Currency,
+			}
+			if err := writer.Write(record); err != nil {
+				return err
+			}
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
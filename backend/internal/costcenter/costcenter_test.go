@@ -0,0 +1,78 @@
+package costcenter
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+	"time"
+
+	"github.com/ksred/claude-session-manager/internal/config"
+	"github.com/ksred/claude-session-manager/internal/database"
+)
+
+func TestAllocate(t *testing.T) {
+	allocator := NewAllocator(config.CostCentersConfig{
+		Default: "unallocated",
+		Centers: []config.CostCenter{
+			{Name: "incident-response", Code: "CC-900", Tags: []string{"prod-incident"}},
+			{Name: "payments", Code: "CC-100", Projects: []string{"payments-*", "ledger"}},
+			{Name: "research", Code: "CC-300", Projects: []string{"lab"}},
+		},
+	})
+
+	costs := []database.SessionCost{
+		{SessionID: "s1", ProjectName: "payments-api", Messages: 10, Tokens: 1000, CostUSD: 3.0},
+		{SessionID: "s2", ProjectName: "ledger", Messages: 5, Tokens: 500, CostUSD: 1.0},
+		{SessionID: "s3", ProjectName: "payments-api", Messages: 2, Tokens: 200, CostUSD: 2.0},
+		{SessionID: "s4", ProjectName: "website", Messages: 1, Tokens: 100, CostUSD: 4.0},
+	}
+	tags := map[string][]string{"s3": {"prod-incident"}}
+
+	from := time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)
+	report := allocator.Allocate(costs, tags, from, from.AddDate(0, 1, 0), "USD")
+
+	if report.TotalCostUSD != 10 {
+		t.Errorf("Expected total cost 10, got %v", report.TotalCostUSD)
+	}
+	if len(report.CostCenters) != 4 {
+		t.Fatalf("Expected 4 cost centers, got %+v", report.CostCenters)
+	}
+
+	byName := make(map[string]CenterCost)
+	for _, center := range report.CostCenters {
+		byName[center.Name] = center
+	}
+	if payments := byName["payments"]; payments.Sessions != 2 || payments.CostUSD != 4 || len(payments.Projects) != 2 || payments.Share != 0.4 {
+		t.Errorf("Unexpected payments allocation: %+v", payments)
+	}
+	// Tags are matched in config order, so the incident is charged before its project is
+	if incident := byName["incident-response"]; incident.Sessions != 1 || incident.CostUSD != 2 {
+		t.Errorf("Unexpected incident allocation: %+v", incident)
+	}
+	if unallocated := byName["unallocated"]; unallocated.CostUSD != 4 || unallocated.Projects[0].Project != "website" {
+		t.Errorf("Unexpected unallocated usage: %+v", unallocated)
+	}
+	if research := byName["research"]; research.Sessions != 0 || research.Code != "CC-300" {
+		t.Errorf("Expected configured center with no usage to be listed, got %+v", research)
+	}
+
+	var buf bytes.Buffer
+	if err := report.WriteCSV(&buf); err != nil {
+		t.Fatalf("Failed to write CSV: %v", err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("Failed to read CSV: %v", err)
+	}
+	if len(records) != 5 {
+		t.Fatalf("Expected a header and 4 rows, got %d", len(records))
+	}
+	if got := records[1]; got[0] != "2026-09-01" || got[1] != "2026-09-30" || got[9] != "USD" {
+		t.Errorf("Unexpected period or currency in %v", got)
+	}
+	for _, record := range records[1:] {
+		if record[2] == "payments" && record[4] == "payments-api" && (record[3] != "CC-100" || record[8] != "3.00") {
+			t.Errorf("Unexpected payments-api row: %v", record)
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package database
+
+import (
+	"fmt"
+	"time"
+)
+
+// Kinds of value an analytics export column holds
+const (
+	ExportString = "string"
+	ExportInt    = "int"
+	ExportFloat  = "float"
+	ExportBool   = "bool"
+	ExportTime   = "time"
+)
+
+// AnalyticsExportColumn is a column of an analytics export dataset
+type AnalyticsExportColumn struct {
+	Name string
+	Kind string
+}
+
+// analyticsExportDataset is the query behind a dataset, whose first
+// placeholder is the earliest time to include
+type analyticsExportDataset struct {
+	columns []AnalyticsExportColumn
+	query   string
+}
+
+var analyticsExportDatasets = map[string]analyticsExportDataset{
+	"token_usage": {
+		columns: []AnalyticsExportColumn{
+			{"id", ExportInt},
+			{"message_id", ExportString},
+			{"session_id", ExportString},
+			{"project_name", ExportString},
+			{"model", ExportString},
+			{"timestamp", ExportTime},
+			{"input_tokens", ExportInt},
+			{"output_tokens", ExportInt},
+			{"cache_creation_input_tokens", ExportInt},
+			{"cache_read_input_tokens", ExportInt},
+			{"total_tokens", ExportInt},
+			{"service_tier", ExportString},
+			{"estimated_cost", ExportFloat},
+			{"cache_savings", ExportFloat},
+		},
+		query: `
+			SELECT tu.id, tu.message_id, tu.session_id, s.project_name,
+				s.model, m.timestamp,
+				tu.input_tokens, tu.output_tokens, tu.cache_creation_input_tokens,
+				tu.cache_read_input_tokens, tu.total_tokens, tu.service_tier,
+				tu.estimated_cost, tu.cache_savings
+			FROM token_usage tu
+			JOIN messages m ON m.id = tu.message_id
+			JOIN sessions s ON s.id = tu.session_id
+			WHERE m.timestamp >= ?
+			ORDER BY m.timestamp, tu.id
+		`,
+	},
+	"sessions": {
+		columns: []AnalyticsExportColumn{
+			{"id", ExportString},
+			{"project_name", ExportString},
+			{"project_path", ExportString},
+			{"start_time", ExportTime},
+			{"last_activity", ExportTime},
+			{"status", ExportString},
+			{"model", ExportString},
+			{"message_count", ExportInt},
+			{"duration_seconds", ExportInt},
+			{"git_branch", ExportString},
+			{"archived_at", ExportTime},
+			{"total_input_tokens", ExportInt},
+			{"total_output_tokens", ExportInt},
+			{"total_cache_creation_tokens", ExportInt},
+			{"total_cache_read_tokens", ExportInt},
+			{"total_tokens", ExportInt},
+			{"total_estimated_cost", ExportFloat},
+			{"total_cache_savings", ExportFloat},
+		},
+		query: `
+			SELECT id, project_name, project_path, start_time, last_activity,
+				status, model, message_count, duration_seconds, git_branch,
+				archived_at, total_input_tokens, total_output_tokens,
+				total_cache_creation_tokens, total_cache_read_tokens,
+				total_tokens, total_estimated_cost, total_cache_savings
+			FROM session_summaries
+			WHERE last_activity >= ?
+			ORDER BY start_time, id
+		`,
+	},
+	"tool_results": {
+		columns: []AnalyticsExportColumn{
+			{"id", ExportInt},
+			{"message_id", ExportString},
+			{"session_id", ExportString},
+			{"project_name", ExportString},
+			{"tool_name", ExportString},
+			{"file_path", ExportString},
+			{"absolute_path", ExportString},
+			{"timestamp", ExportTime},
+		},
+		query: `
+			SELECT tr.id, tr.message_id, tr.session_id, s.project_name,
+				tr.tool_name, tr.file_path, tr.absolute_path, tr.timestamp
+			FROM tool_results tr
+			JOIN sessions s ON s.id = tr.session_id
+			WHERE tr.timestamp >= ?
+			ORDER BY tr.timestamp, tr.id
+		`,
+	},
+}
+
+// AnalyticsExportColumns returns the columns of an analytics export dataset:
+// token_usage, sessions or tool_results. ok is false for any other name.
+func AnalyticsExportColumns(dataset string) (columns []AnalyticsExportColumn, ok bool) {
+	d, ok := analyticsExportDatasets[dataset]
+	return d.columns, ok
+}
+
+// StreamAnalyticsExport calls fn with each row of dataset since the given
+// time, oldest first, without loading the dataset into memory. Each value is
+// nil or, by its column's kind, a string, int64, float64, bool or time.Time.
+// The row slice is reused between calls.
+func (r *SessionRepository) StreamAnalyticsExport(dataset string, since time.Time, fn func(row []interface{}) error) error {
+	d, ok := analyticsExportDatasets[dataset]
+	if !ok {
+		return fmt.Errorf("unknown export dataset: %s", dataset)
+	}
+
+	rows, err := r.db.Query(d.query, since.UTC())
+	if err != nil {
+		return fmt.Errorf("failed to query %s export: %w", dataset, err)
+	}
+	defer rows.Close()
+
+	raw := make([]interface{}, len(d.columns))
+	dest := make([]interface{}, len(d.columns))
+	for i := range raw {
+		dest[i] = &raw[i]
+	}
+	row := make([]interface{}, len(d.columns))
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return fmt.Errorf("failed to scan %s export: %w", dataset, err)
+		}
+		for i, column := range d.columns {
+			value, err := exportValue(column.Kind, raw[i])
+			if err != nil {
+				return fmt.Errorf("failed to read %s.%s: %w", dataset, column.Name, err)
+			}
+			row[i] = value
+		}
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// exportTimeLayouts are the layouts SQLite timestamps are read back in when
+// the driver hasn't already parsed them
+var exportTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02 15:04:05",
+}
+
+// exportValue converts a value scanned from SQLite to the Go type of kind
+func exportValue(kind string, value interface{}) (interface{}, error) {
+	if b, ok := value.([]byte); ok {
+		value = string(b)
+	}
+	if value == nil {
+		return nil, nil
+	}
+
+	switch kind {
+	case ExportString:
+		return fmt.Sprint(value), nil
+	case ExportInt:
+		switch v := value.(type) {
+		case int64:
+			return v, nil
+		case float64:
+			return int64(v), nil
+		case bool:
+			if v {
+				return int64(1), nil
+			}
+			return int64(0), nil
+		}
+	case ExportFloat:
+		switch v := value.(type) {
+		case float64:
+			return v, nil
+		case int64:
+			return float64(v), nil
+		}
+	case ExportBool:
+		switch v := value.(type) {
+		case bool:
+			return v, nil
+		case int64:
+			return v != 0, nil
+		}
+	case ExportTime:
+		switch v := value.(type) {
+		case time.Time:
+			return v.UTC(), nil
+		case string:
+			for _, layout := range exportTimeLayouts {
+				if t, err := time.Parse(layout, v); err == nil {
+					return t.UTC(), nil
+				}
+			}
+		}
+	}
+	return nil, fmt.Errorf("unexpected %T value for a %s column", value, kind)
+}
@@ -0,0 +1,82 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSessionRepository_StreamAnalyticsExport(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewSessionRepository(db, logger)
+	start := time.Date(2026, 9, 1, 12, 0, 0, 0, time.UTC)
+	for i, id := range []string{"old", "new"} {
+		at := start.AddDate(0, 0, i*10)
+		if err := repo.UpsertSession(&Session{ID: id, ProjectPath: "/work/app", ProjectName: "app", Model: "claude-sonnet", StartTime: at, LastActivity: at, Status: "completed"}); err != nil {
+			t.Fatalf("Failed to create session: %v", err)
+		}
+		if err := repo.UpsertMessage(&Message{ID: id + "-m1", SessionID: id, Role: "assistant", Timestamp: at}); err != nil {
+			t.Fatalf("Failed to create message: %v", err)
+		}
+		if err := repo.UpsertTokenUsage(&TokenUsage{MessageID: id + "-m1", SessionID: id, InputTokens: 10, OutputTokens: 5, TotalTokens: 15, EstimatedCost: 0.5}); err != nil {
+			t.Fatalf("Failed to create token usage: %v", err)
+		}
+		file := "main.go"
+		if err := repo.UpsertToolResult(&ToolResult{MessageID: id + "-m1", SessionID: id, ToolName: "Edit", FilePath: &file, ResultData: `{"secret":true}`, Timestamp: at}); err != nil {
+			t.Fatalf("Failed to create tool result: %v", err)
+		}
+	}
+
+	collect := func(dataset string, since time.Time) [][]interface{} {
+		var rows [][]interface{}
+		err := repo.StreamAnalyticsExport(dataset, since, func(row []interface{}) error {
+			rows = append(rows, append([]interface{}(nil), row...))
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Failed to stream %s: %v", dataset, err)
+		}
+		return rows
+	}
+
+	for _, dataset := range []string{"token_usage", "sessions", "tool_results"} {
+		columns, ok := AnalyticsExportColumns(dataset)
+		if !ok {
+			t.Fatalf("Expected %s to be an export dataset", dataset)
+		}
+		rows := collect(dataset, time.Time{})
+		if len(rows) != 2 {
+			t.Fatalf("Expected 2 %s rows, got %d", dataset, len(rows))
+		}
+		for _, row := range rows {
+			if len(row) != len(columns) {
+				t.Fatalf("Expected %d %s values, got %d", len(columns), dataset, len(row))
+			}
+		}
+		if rows := collect(dataset, start.AddDate(0, 0, 5)); len(rows) != 1 {
+			t.Errorf("Expected only the newer %s row since the cutoff, got %d", dataset, len(rows))
+		}
+	}
+
+	usage := collect("token_usage", time.Time{})[0]
+	if usage[2] != "old" || usage[3] != "app" || usage[4] != "claude-sonnet" || usage[6] != int64(10) || usage[12] != 0.5 {
+		t.Errorf("Unexpected token usage row: %v", usage)
+	}
+	if at, ok := usage[5].(time.Time); !ok || !at.Equal(start) {
+		t.Errorf("Expected the message timestamp, got %v", usage[5])
+	}
+	sessions := collect("sessions", time.Time{})[0]
+	if sessions[10] != nil || sessions[15] != int64(15) {
+		t.Errorf("Expected an unarchived session with its token total, got %v", sessions)
+	}
+	for _, value := range collect("tool_results", time.Time{})[0] {
+		if value == `{"secret":true}` {
+			t.Error("Expected tool result data to be left out")
+		}
+	}
+
+	if err := repo.StreamAnalyticsExport("messages", time.Time{}, func([]interface{}) error { return nil }); err == nil {
+		t.Error("Expected an unknown dataset to fail")
+	}
+}
@@ -44,19 +44,23 @@ func (a *APIAdapter) SessionSummaryToSessionResponse(summary *SessionSummary) (*
 		Title:         summary.ProjectName,
 		ProjectPath:   summary.ProjectPath,
 		ProjectName:   summary.ProjectName,
-		GitBranch:     "", // Not stored in database yet
-		GitWorktree:   "", // Not stored in database yet
+		GitBranch:     summary.GitBranch,
+		GitWorktree:   summary.GitWorktree,
+		GitRemote:     summary.GitRemote,
 		Status:        summary.Status,
 		CreatedAt:     summary.StartTime,
 		UpdatedAt:     summary.LastActivity,
 		MessageCount:  summary.MessageCount,
 		CurrentTask:   summary.ProjectName,
 		TokensUsed:    tokenUsage,
+		CacheSavings:  summary.TotalCacheSavings,
 		FilesModified: filesModified,
 		Duration:      summary.DurationSeconds,
 		IsActive:      summary.IsActive,
 		Model:         summary.Model,
 		Source:        summary.Source,
+		Archived:      summary.ArchivedAt != nil,
+		ArchivedAt:    summary.ArchivedAt,
 	}, nil
 }
 
@@ -98,24 +102,32 @@ func (a *APIAdapter) ActivityLogEntryToAPIActivityEntry(entry *ActivityLogEntry)
 
 // SessionResponse represents the API response format (matching existing API)
 type SessionResponse struct {
-	ID            string            `json:"id"`
-	Title         string            `json:"title"`
-	ProjectPath   string            `json:"project_path"`
-	ProjectName   string            `json:"project_name"`
-	GitBranch     string            `json:"git_branch,omitempty"`
-	GitWorktree   string            `json:"git_worktree,omitempty"`
-	Status        string            `json:"status"`
-	CreatedAt     time.Time         `json:"created_at"`
-	UpdatedAt     time.Time         `json:"updated_at"`
-	MessageCount  int               `json:"message_count"`
-	CurrentTask   string            `json:"current_task"`
-	TokensUsed    claude.TokenUsage `json:"tokens_used"`
-	FilesModified []string          `json:"files_modified"`
-	Duration      int64             `json:"duration_seconds"`
-	IsActive      bool              `json:"is_active"`
-	Model         string            `json:"model"`
-	Source        string            `json:"source,omitempty"`
-	ChatSessionID string            `json:"chat_session_id,omitempty"`
+	ID            string              `json:"id"`
+	Title         string              `json:"title"`
+	ProjectPath   string              `json:"project_path"`
+	ProjectName   string              `json:"project_name"`
+	GitBranch     string              `json:"git_branch,omitempty"`
+	GitWorktree   string              `json:"git_worktree,omitempty"`
+	GitRemote     string              `json:"git_remote,omitempty"`
+	Status        string              `json:"status"`
+	CreatedAt     time.Time           `json:"created_at"`
+	UpdatedAt     time.Time           `json:"updated_at"`
+	MessageCount  int                 `json:"message_count"`
+	CurrentTask   string              `json:"current_task"`
+	TokensUsed    claude.TokenUsage   `json:"tokens_used"`
+	CacheSavings  float64             `json:"cache_savings"` // fresh input cost of the cached tokens minus their cost
+	FilesModified []string            `json:"files_modified"`
+	Duration      int64               `json:"duration_seconds"`
+	IsActive      bool                `json:"is_active"`
+	Model         string              `json:"model"`
+	Source        string              `json:"source,omitempty"`
+	Archived      bool                `json:"archived"`
+	ArchivedAt    *time.Time          `json:"archived_at,omitempty"`
+	ChatSessionID string              `json:"chat_session_id,omitempty"`
+	QualityScore  *float64            `json:"quality_score,omitempty"`
+	Environment   *SessionEnvironment `json:"environment,omitempty"`
+	Lineage       *SessionLineage     `json:"lineage,omitempty"`
+	Tags          []string            `json:"tags,omitempty"`
 }
 
 // ActivityEntry represents an activity entry for the API
@@ -146,6 +158,7 @@ func (a *APIAdapter) SessionToSessionResponse(session *Session) (*SessionRespons
 		ProjectName:   session.ProjectName,
 		GitBranch:     session.GitBranch,
 		GitWorktree:   session.GitWorktree,
+		GitRemote:     session.GitRemote,
 		Status:        session.Status,
 		CreatedAt:     session.StartTime,
 		UpdatedAt:     session.LastActivity,
@@ -0,0 +1,109 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// CreateAPIKey stores a key for the named user, creating the user if they
+// don't exist yet, and assigns the key's ID and creation time
+func (r *SessionRepository) CreateAPIKey(userName string, key *APIKey) error {
+	now := time.Now().UTC()
+	key.ID = uuid.New().String()
+	key.UserName = userName
+	key.CreatedAt = now
+	err := r.db.WriteOperation(func(tx *sqlx.Tx) error {
+		_, err := tx.Exec(`
+			INSERT INTO users (id, name, created_at) VALUES (?, ?, ?)
+			ON CONFLICT(name) DO NOTHING
+		`, uuid.New().String(), userName, now)
+		if err != nil {
+			return err
+		}
+		if err := tx.Get(&key.UserID, `SELECT id FROM users WHERE name = ?`, userName); err != nil {
+			return err
+		}
+		_, err = tx.NamedExec(`
+			INSERT INTO api_keys (id, user_id, name, prefix, key_hash, scope, created_at)
+			VALUES (:id, :user_id, :name, :prefix, :key_hash, :scope, :created_at)
+		`, key)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create API key: %w", err)
+	}
+	return nil
+}
+
+// GetAPIKeys returns every key, revoked ones included, by user and then
+// newest first
+func (r *SessionRepository) GetAPIKeys() ([]APIKey, error) {
+	keys := []APIKey{}
+	err := r.db.Select(&keys, `
+		SELECT k.id, k.user_id, u.name AS user_name, k.name, k.prefix, k.key_hash, k.scope,
+		       k.created_at, k.last_used_at, k.revoked_at
+		FROM api_keys k
+		JOIN users u ON u.id = k.user_id
+		ORDER BY u.name, k.created_at DESC
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get API keys: %w", err)
+	}
+	return keys, nil
+}
+
+// GetActiveAPIKey returns the unrevoked key with the given hash, or nil if
+// there is none
+func (r *SessionRepository) GetActiveAPIKey(keyHash string) (*APIKey, error) {
+	var key APIKey
+	err := r.db.Get(&key, `
+		SELECT k.id, k.user_id, u.name AS user_name, k.name, k.prefix, k.key_hash, k.scope,
+		       k.created_at, k.last_used_at, k.revoked_at
+		FROM api_keys k
+		JOIN users u ON u.id = k.user_id
+		WHERE k.key_hash = ? AND k.revoked_at IS NULL
+	`, keyHash)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get API key: %w", err)
+	}
+	return &key, nil
+}
+
+// RevokeAPIKey stops a key from being accepted. Revoking a key twice keeps
+// the first revocation time.
+func (r *SessionRepository) RevokeAPIKey(id string) error {
+	var found int64
+	err := r.db.WriteOperation(func(tx *sqlx.Tx) error {
+		if err := tx.Get(&found, `SELECT COUNT(*) FROM api_keys WHERE id = ?`, id); err != nil {
+			return err
+		}
+		_, err := tx.Exec(`UPDATE api_keys SET revoked_at = ? WHERE id = ? AND revoked_at IS NULL`, time.Now().UTC(), id)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to revoke API key: %w", err)
+	}
+	if found == 0 {
+		return fmt.Errorf("API key not found: %s", id)
+	}
+	return nil
+}
+
+// TouchAPIKey records when a key was last used
+func (r *SessionRepository) TouchAPIKey(id string, at time.Time) error {
+	err := r.db.WriteOperation(func(tx *sqlx.Tx) error {
+		_, err := tx.Exec(`UPDATE api_keys SET last_used_at = ? WHERE id = ?`, at.UTC(), id)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record API key use: %w", err)
+	}
+	return nil
+}
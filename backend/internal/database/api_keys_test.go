@@ -0,0 +1,60 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSessionRepository_APIKeys(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewSessionRepository(db, logger)
+
+	laptop := &APIKey{Name: "laptop", Prefix: "csm_aaaa", KeyHash: "hash-laptop", Scope: ScopeWrite}
+	if err := repo.CreateAPIKey("alice", laptop); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	dashboard := &APIKey{Name: "dashboard", Prefix: "csm_bbbb", KeyHash: "hash-dashboard", Scope: ScopeRead}
+	if err := repo.CreateAPIKey("alice", dashboard); err != nil {
+		t.Fatalf("Failed to create second key: %v", err)
+	}
+	if laptop.UserID == "" || laptop.UserID != dashboard.UserID {
+		t.Errorf("Expected both keys to belong to one user, got %q and %q", laptop.UserID, dashboard.UserID)
+	}
+
+	key, err := repo.GetActiveAPIKey("hash-dashboard")
+	if err != nil || key == nil {
+		t.Fatalf("Expected to find the key, got %v (%v)", key, err)
+	}
+	if key.UserName != "alice" || key.Scope != ScopeRead {
+		t.Errorf("Unexpected key: %+v", key)
+	}
+
+	used := time.Now().Add(-time.Minute)
+	if err := repo.TouchAPIKey(key.ID, used); err != nil {
+		t.Fatalf("Failed to touch key: %v", err)
+	}
+	if err := repo.RevokeAPIKey(key.ID); err != nil {
+		t.Fatalf("Failed to revoke key: %v", err)
+	}
+	if key, err := repo.GetActiveAPIKey("hash-dashboard"); err != nil || key != nil {
+		t.Errorf("Expected a revoked key not to be found, got %+v (%v)", key, err)
+	}
+	if err := repo.RevokeAPIKey("missing"); err == nil {
+		t.Error("Expected revoking an unknown key to fail")
+	}
+
+	keys, err := repo.GetAPIKeys()
+	if err != nil {
+		t.Fatalf("Failed to list keys: %v", err)
+	}
+	if len(keys) != 2 {
+		t.Fatalf("Expected 2 keys, got %+v", keys)
+	}
+	for _, k := range keys {
+		if k.ID == dashboard.ID && (k.RevokedAt == nil || k.LastUsedAt == nil) {
+			t.Errorf("Expected the dashboard key to be used and revoked, got %+v", k)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package database
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// RecordAPIUsage adds usage to the hours and routes already recorded
+func (r *SessionRepository) RecordAPIUsage(usage []APIUsage) error {
+	if len(usage) == 0 {
+		return nil
+	}
+	err := r.db.WriteOperation(func(tx *sqlx.Tx) error {
+		for _, u := range usage {
+			u.Hour = u.Hour.UTC().Truncate(time.Hour)
+			_, err := tx.NamedExec(`
+				INSERT INTO api_usage (hour, method, route, requests, client_errors, server_errors, total_ms, max_ms)
+				VALUES (:hour, :method, :route, :requests, :client_errors, :server_errors, :total_ms, :max_ms)
+				ON CONFLICT(hour, method, route) DO UPDATE SET
+					requests = requests + excluded.requests,
+					client_errors = client_errors + excluded.client_errors,
+					server_errors = server_errors + excluded.server_errors,
+					total_ms = total_ms + excluded.total_ms,
+					max_ms = MAX(max_ms, excluded.max_ms)
+			`, u)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record api usage: %w", err)
+	}
+	return nil
+}
+
+// GetAPIUsage returns each route's requests in the hours from since, most
+// requested first
+func (r *SessionRepository) GetAPIUsage(since time.Time) ([]APIRouteUsage, error) {
+	usage := []APIRouteUsage{}
+	err := r.db.Select(&usage, `
+		SELECT
+			method,
+			route,
+			SUM(requests) AS requests,
+			SUM(client_errors) AS client_errors,
+			SUM(server_errors) AS server_errors,
+			SUM(total_ms) AS total_ms,
+			SUM(total_ms) / SUM(requests) AS avg_ms,
+			MAX(max_ms) AS max_ms,
+			MAX(hour) AS last_hour
+		FROM api_usage
+		WHERE hour >= ?
+		GROUP BY method, route
+		ORDER BY requests DESC, total_ms DESC, route, method
+	`, since.UTC().Truncate(time.Hour))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get api usage: %w", err)
+	}
+	return usage, nil
+}
+
+// GetAPIUsageByHour returns the requests in each hour from since, across all
+// routes or for one method and route
+func (r *SessionRepository) GetAPIUsageByHour(since time.Time, method, route string) ([]APIUsage, error) {
+	query := `
+		SELECT
+			hour,
+			'' AS method,
+			'' AS route,
+			SUM(requests) AS requests,
+			SUM(client_errors) AS client_errors,
+			SUM(server_errors) AS server_errors,
+			SUM(total_ms) AS total_ms,
+			MAX(max_ms) AS max_ms
+		FROM api_usage
+		WHERE hour >= ?
+	`
+	args := []interface{}{since.UTC().Truncate(time.Hour)}
+	if route != "" {
+		query += ` AND method = ? AND route = ?`
+		args = append(args, method, route)
+	}
+	query += ` GROUP BY hour ORDER BY hour`
+
+	usage := []APIUsage{}
+	if err := r.db.Select(&usage, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to get api usage by hour: %w", err)
+	}
+	for i := range usage {
+		usage[i].Method, usage[i].Route = method, route
+	}
+	return usage, nil
+}
+
+// PruneAPIUsage deletes the usage recorded for hours before before
+func (r *SessionRepository) PruneAPIUsage(before time.Time) (int64, error) {
+	var deleted int64
+	err := r.db.WriteOperation(func(tx *sqlx.Tx) error {
+		result, err := tx.Exec(`DELETE FROM api_usage WHERE hour < ?`, before.UTC())
+		if err != nil {
+			return err
+		}
+		deleted, err = result.RowsAffected()
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune api usage: %w", err)
+	}
+	return deleted, nil
+}
@@ -0,0 +1,55 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSessionRepository_APIUsage(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewSessionRepository(db, logger)
+	hour := time.Now().UTC().Truncate(time.Hour)
+
+	err := repo.RecordAPIUsage([]APIUsage{
+		{Hour: hour, Method: "GET", Route: "/api/v1/widgets/cost-today", Requests: 10, TotalMs: 50, MaxMs: 12},
+		{Hour: hour, Method: "GET", Route: "/api/v1/sessions/:id", Requests: 2, ClientErrors: 1, TotalMs: 8, MaxMs: 5},
+		{Hour: hour.Add(-time.Hour), Method: "GET", Route: "/api/v1/widgets/cost-today", Requests: 5, TotalMs: 10, MaxMs: 3},
+		{Hour: hour.Add(-48 * time.Hour), Method: "GET", Route: "/api/v1/widgets/cost-today", Requests: 100, TotalMs: 100, MaxMs: 1},
+	})
+	if err != nil {
+		t.Fatalf("Failed to record api usage: %v", err)
+	}
+	// Later flushes add to the same hour
+	if err := repo.RecordAPIUsage([]APIUsage{{Hour: hour.Add(time.Minute), Method: "GET", Route: "/api/v1/widgets/cost-today", Requests: 5, ServerErrors: 1, TotalMs: 40, MaxMs: 30}}); err != nil {
+		t.Fatalf("Failed to record api usage: %v", err)
+	}
+
+	usage, err := repo.GetAPIUsage(hour.Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to get api usage: %v", err)
+	}
+	if len(usage) != 2 || usage[0].Route != "/api/v1/widgets/cost-today" {
+		t.Fatalf("Expected 2 routes, busiest first, got %+v", usage)
+	}
+	if widget := usage[0]; widget.Requests != 20 || widget.ServerErrors != 1 || widget.TotalMs != 100 || widget.AvgMs != 5 || widget.MaxMs != 30 {
+		t.Errorf("Expected the widget's requests over the last 2 hours, got %+v", widget)
+	}
+
+	timeline, err := repo.GetAPIUsageByHour(hour.Add(-time.Hour), "GET", "/api/v1/sessions/:id")
+	if err != nil {
+		t.Fatalf("Failed to get api usage by hour: %v", err)
+	}
+	if len(timeline) != 1 || timeline[0].Requests != 2 || !timeline[0].Hour.Equal(hour) {
+		t.Errorf("Expected one hour for the sessions route, got %+v", timeline)
+	}
+
+	deleted, err := repo.PruneAPIUsage(hour.Add(-24 * time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to prune api usage: %v", err)
+	}
+	if deleted != 1 {
+		t.Errorf("Expected 1 hour pruned, got %d", deleted)
+	}
+}
@@ -0,0 +1,58 @@
+package database
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Listing and metrics queries leave archived sessions out unless asked to
+// include them, with a condition such as (? OR archived_at IS NULL) whose
+// parameter is includeArchived.
+
+// ArchiveSession hides a session from default listings and metrics without
+// deleting anything. Archiving an archived session keeps when it was first
+// archived. Sessions under legal hold are refused with ErrLegalHold.
+func (r *SessionRepository) ArchiveSession(sessionID string) (*SessionSummary, error) {
+	return r.setArchived(sessionID, true)
+}
+
+// UnarchiveSession returns an archived session to listings and metrics
+func (r *SessionRepository) UnarchiveSession(sessionID string) (*SessionSummary, error) {
+	return r.setArchived(sessionID, false)
+}
+
+func (r *SessionRepository) setArchived(sessionID string, archived bool) (*SessionSummary, error) {
+	var archivedAt *time.Time
+	if archived {
+		now := time.Now().UTC()
+		archivedAt = &now
+	}
+
+	var rows int64
+	err := r.db.WriteOperation(func(tx *sqlx.Tx) error {
+		if archived {
+			if err := checkNotOnLegalHold(tx, sessionID); err != nil {
+				return err
+			}
+		}
+		result, err := tx.Exec(`
+			UPDATE sessions
+			SET archived_at = CASE WHEN ? THEN COALESCE(archived_at, ?) ELSE NULL END
+			WHERE id = ?
+		`, archived, archivedAt, sessionID)
+		if err != nil {
+			return fmt.Errorf("failed to update archived state: %w", err)
+		}
+		rows, err = result.RowsAffected()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if rows == 0 {
+		return nil, fmt.Errorf("session not found: %s", sessionID)
+	}
+	return r.GetSessionByID(sessionID)
+}
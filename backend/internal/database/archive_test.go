@@ -0,0 +1,94 @@
+package database
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSessionRepository_ArchiveSession(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewSessionRepository(db, logger)
+	readOptimized := NewReadOptimizedRepository(db)
+
+	now := time.Now().UTC()
+	for _, id := range []string{"kept", "archived"} {
+		session := &Session{ID: id, ProjectPath: "/work/app", ProjectName: "app", StartTime: now.Add(-time.Hour), LastActivity: now, IsActive: true, Status: "active", Model: "claude-sonnet-4"}
+		if err := repo.UpsertSession(session); err != nil {
+			t.Fatalf("Failed to create session: %v", err)
+		}
+		if err := repo.UpsertMessage(&Message{ID: id + "-msg", SessionID: id, Role: "assistant", Content: `"done"`, Timestamp: now}); err != nil {
+			t.Fatalf("Failed to create message: %v", err)
+		}
+		if err := repo.UpsertTokenUsage(&TokenUsage{MessageID: id + "-msg", SessionID: id, TotalTokens: 100, EstimatedCost: 0.5}); err != nil {
+			t.Fatalf("Failed to create token usage: %v", err)
+		}
+	}
+
+	archived, err := repo.ArchiveSession("archived")
+	if err != nil {
+		t.Fatalf("Failed to archive session: %v", err)
+	}
+	if archived.ArchivedAt == nil {
+		t.Fatal("Expected the session to be archived")
+	}
+	if again, err := repo.ArchiveSession("archived"); err != nil || !again.ArchivedAt.Equal(*archived.ArchivedAt) {
+		t.Errorf("Expected archiving again to keep the original time, got %+v (%v)", again, err)
+	}
+
+	// Re-importing an archived session leaves it archived
+	if err := repo.UpsertSession(&Session{ID: "archived", ProjectPath: "/work/app", ProjectName: "app", StartTime: now.Add(-time.Hour), LastActivity: now, Status: "completed"}); err != nil {
+		t.Fatalf("Failed to re-import session: %v", err)
+	}
+
+	sessions, total, err := readOptimized.GetAllSessionsOptimized(0, 0, false, false)
+	if err != nil {
+		t.Fatalf("Failed to get sessions: %v", err)
+	}
+	if total != 1 || len(sessions) != 1 || sessions[0].ID != "kept" {
+		t.Errorf("Expected only the unarchived session, got %d %+v", total, sessions)
+	}
+	if _, total, _ := readOptimized.GetAllSessionsOptimized(0, 0, false, true); total != 2 {
+		t.Errorf("Expected both sessions when including archived, got %d", total)
+	}
+
+	if count, err := repo.GetTotalSessions(false); err != nil || count != 1 {
+		t.Errorf("Expected 1 session, got %d (%v)", count, err)
+	}
+	if count, err := repo.GetTotalMessages(false); err != nil || count != 1 {
+		t.Errorf("Expected 1 message, got %d (%v)", count, err)
+	}
+	if cost, err := repo.GetEstimatedCost(false); err != nil || cost != 0.5 {
+		t.Errorf("Expected the cost of the unarchived session, got %v (%v)", cost, err)
+	}
+	if cost, err := repo.GetEstimatedCost(true); err != nil || cost != 1.0 {
+		t.Errorf("Expected the cost of both sessions, got %v (%v)", cost, err)
+	}
+
+	unarchived, err := repo.UnarchiveSession("archived")
+	if err != nil {
+		t.Fatalf("Failed to unarchive session: %v", err)
+	}
+	if unarchived.ArchivedAt != nil {
+		t.Errorf("Expected the session to be unarchived, got %v", unarchived.ArchivedAt)
+	}
+	if count, err := repo.GetTotalSessions(false); err != nil || count != 2 {
+		t.Errorf("Expected 2 sessions after unarchiving, got %d (%v)", count, err)
+	}
+
+	if _, err := repo.ArchiveSession("missing"); err == nil {
+		t.Error("Expected archiving an unknown session to fail")
+	}
+
+	if _, err := repo.PlaceLegalHold("kept", "Litigation 2025-17", "counsel"); err != nil {
+		t.Fatalf("Failed to place hold: %v", err)
+	}
+	if _, err := repo.ArchiveSession("kept"); !errors.Is(err, ErrLegalHold) {
+		t.Errorf("Expected a held session to be refused, got %v", err)
+	}
+	if session, _ := repo.GetSessionByID("kept"); session.ArchivedAt != nil {
+		t.Errorf("Expected the held session to stay unarchived, got %v", session.ArchivedAt)
+	}
+}
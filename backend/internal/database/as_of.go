@@ -0,0 +1,113 @@
+package database
+
+import (
+	"fmt"
+	"time"
+)
+
+// The AsOf queries recompute analytics from message history, counting only
+// messages sent before asOf and the sessions and token usage they belong to,
+// so numbers shown on an earlier day can be reproduced. Sessions are active
+// as of a time if they sent a message in the two minutes before it, as at
+// import. Data pruned or imported since then, and a session's model, which
+// is stored as of its last import, still reflect the present.
+
+// GetMetricsSummaryAsOf returns the metrics summary as it stood at asOf
+func (r *SessionRepository) GetMetricsSummaryAsOf(asOf time.Time) (*MetricsSummaryAsOf, error) {
+	asOf = asOf.UTC()
+
+	var summary MetricsSummaryAsOf
+	err := r.db.Get(&summary, `
+		WITH history AS (
+			SELECT
+				session_id,
+				MIN(timestamp) AS first_message,
+				MAX(timestamp) AS last_message,
+				COUNT(*) AS messages
+			FROM messages
+			WHERE timestamp < ?
+			GROUP BY session_id
+		)
+		SELECT
+			COUNT(*) AS total_sessions,
+			COALESCE(SUM(CASE WHEN last_message >= ? THEN 1 ELSE 0 END), 0) AS active_sessions,
+			COALESCE(SUM(messages), 0) AS total_messages,
+			COALESCE(AVG(NULLIF(strftime('%s', last_message) - strftime('%s', first_message), 0) / 60.0), 0.0) AS average_session_duration,
+			(SELECT COALESCE(SUM(tu.total_tokens), 0)
+				FROM token_usage tu JOIN messages m ON m.id = tu.message_id
+				WHERE m.timestamp < ?) AS total_tokens,
+			(SELECT COALESCE(SUM(tu.estimated_cost), 0.0)
+				FROM token_usage tu JOIN messages m ON m.id = tu.message_id
+				WHERE m.timestamp < ?) AS estimated_cost
+		FROM history
+	`, asOf, asOf.Add(-2*time.Minute), asOf, asOf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get metrics summary as of %s: %w", asOf.Format(time.RFC3339), err)
+	}
+
+	summary.ModelUsage, err = r.GetModelUsageAsOf(asOf)
+	if err != nil {
+		return nil, err
+	}
+	summary.MostUsedModel = "unknown"
+	for model, count := range summary.ModelUsage {
+		best := summary.ModelUsage[summary.MostUsedModel]
+		if count > best || count == best && model < summary.MostUsedModel {
+			summary.MostUsedModel = model
+		}
+	}
+	return &summary, nil
+}
+
+// GetModelUsageAsOf returns how many sessions that had started by asOf used
+// each model
+func (r *SessionRepository) GetModelUsageAsOf(asOf time.Time) (map[string]int, error) {
+	rows, err := r.db.Query(`
+		SELECT model, COUNT(*) as count
+		FROM sessions
+		WHERE model IS NOT NULL AND model != ''
+			AND id IN (SELECT session_id FROM messages WHERE timestamp < ?)
+		GROUP BY model
+	`, asOf.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get model usage: %w", err)
+	}
+	defer rows.Close()
+
+	usage := make(map[string]int)
+	for rows.Next() {
+		var model string
+		var count int
+		if err := rows.Scan(&model, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan model usage: %w", err)
+		}
+		usage[model] = count
+	}
+	return usage, rows.Err()
+}
+
+// GetDailyMetricsAsOf returns daily metrics for the days days before asOf,
+// computed from messages rather than the daily_metrics table, which only
+// holds current totals
+func (r *SessionRepository) GetDailyMetricsAsOf(days int, asOf time.Time) ([]*DailyMetric, error) {
+	asOf = asOf.UTC()
+
+	metrics := []*DailyMetric{}
+	err := r.db.Select(&metrics, `
+		SELECT
+			DATE(m.timestamp) as date,
+			COUNT(DISTINCT m.session_id) as session_count,
+			COUNT(DISTINCT m.id) as message_count,
+			'all' as model,
+			COALESCE(SUM(tu.total_tokens), 0) as total_tokens
+		FROM messages m
+		LEFT JOIN token_usage tu ON tu.message_id = m.id
+		WHERE m.timestamp >= ? AND m.timestamp < ?
+		GROUP BY DATE(m.timestamp)
+		ORDER BY date DESC
+	`, asOf.AddDate(0, 0, -days), asOf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get daily metrics: %w", err)
+	}
+	return metrics, nil
+}
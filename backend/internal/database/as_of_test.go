@@ -0,0 +1,79 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSessionRepository_AnalyticsAsOf(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewSessionRepository(db, logger)
+
+	may := time.Date(2025, 5, 31, 10, 0, 0, 0, time.UTC)
+	june := time.Date(2025, 6, 10, 9, 0, 0, 0, time.UTC)
+	for _, s := range []struct {
+		id, model string
+		times     []time.Time
+	}{
+		// Spans the as_of date, so only its May messages count
+		{"spanning", "claude-3-5-sonnet-20241022", []time.Time{may, may.Add(30 * time.Minute), june}},
+		{"later", "claude-3-opus-20240229", []time.Time{june}},
+	} {
+		last := s.times[len(s.times)-1]
+		if err := repo.UpsertSession(&Session{ID: s.id, ProjectPath: "/work/app", ProjectName: "app", StartTime: s.times[0], LastActivity: last, Status: "completed", Model: s.model}); err != nil {
+			t.Fatalf("Failed to create session: %v", err)
+		}
+		for i, ts := range s.times {
+			id := s.id + "-" + string(rune('a'+i))
+			if err := repo.UpsertMessage(&Message{ID: id, SessionID: s.id, Role: "assistant", Content: `"ok"`, Timestamp: ts}); err != nil {
+				t.Fatalf("Failed to create message: %v", err)
+			}
+			if err := repo.UpsertTokenUsage(&TokenUsage{MessageID: id, SessionID: s.id, TotalTokens: 100, EstimatedCost: 0.5}); err != nil {
+				t.Fatalf("Failed to create token usage: %v", err)
+			}
+		}
+	}
+
+	asOf := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	summary, err := repo.GetMetricsSummaryAsOf(asOf)
+	if err != nil {
+		t.Fatalf("Failed to get summary: %v", err)
+	}
+	if summary.TotalSessions != 1 || summary.TotalMessages != 2 || summary.TotalTokens != 200 || summary.EstimatedCost != 1.0 {
+		t.Errorf("Expected only May's session, messages and usage, got %+v", summary)
+	}
+	if summary.AverageSessionDuration != 30 || summary.ActiveSessions != 0 {
+		t.Errorf("Expected a 30 minute inactive session, got %+v", summary)
+	}
+	if summary.MostUsedModel != "claude-3-5-sonnet-20241022" || len(summary.ModelUsage) != 1 {
+		t.Errorf("Expected only the May session's model, got %q %v", summary.MostUsedModel, summary.ModelUsage)
+	}
+
+	active, err := repo.GetMetricsSummaryAsOf(may.Add(31 * time.Minute))
+	if err != nil || active.ActiveSessions != 1 {
+		t.Errorf("Expected the session to be active a minute after its message, got %+v (%v)", active, err)
+	}
+
+	daily, err := repo.GetDailyMetricsAsOf(7, asOf)
+	if err != nil {
+		t.Fatalf("Failed to get daily metrics: %v", err)
+	}
+	if len(daily) != 1 || daily[0].Date != "2025-05-31" || daily[0].SessionCount != 1 || daily[0].TotalTokens != 200 {
+		t.Errorf("Expected one day in May, got %+v", daily)
+	}
+
+	timeline, err := NewReadOptimizedRepository(db).GetTokenTimelineAsOf(24, "day", asOf)
+	if err != nil {
+		t.Fatalf("Failed to get timeline: %v", err)
+	}
+	if len(timeline) != 1 || timeline[0].MessageCount != 2 {
+		t.Errorf("Expected May's messages in the timeline, got %+v", timeline)
+	}
+
+	now, err := repo.GetMetricsSummaryAsOf(time.Now())
+	if err != nil || now.TotalSessions != 2 || now.TotalMessages != 4 {
+		t.Errorf("Expected everything as of now, got %+v (%v)", now, err)
+	}
+}
@@ -1,10 +1,10 @@
 package database
 
 import (
-	"bufio"
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
@@ -66,12 +66,9 @@ func (bi *BatchImporter) getSessionIDFromFile(filePath string) (string, error) {
 	}
 	defer file.Close()
 	
-	scanner := bufio.NewScanner(file)
-	if scanner.Scan() {
-		var msg JSONLMessage
-		if err := json.Unmarshal(scanner.Bytes(), &msg); err == nil && msg.SessionID != "" {
-			return msg.SessionID, nil
-		}
+	var msg JSONLMessage
+	if _, err := newJSONLReader(file, bi.repo.db.maxLineBytes).Next(&msg); err == nil && msg.SessionID != "" {
+		return msg.SessionID, nil
 	}
 	
 	return "", fmt.Errorf("could not determine session ID from file")
@@ -105,27 +102,30 @@ func (bi *BatchImporter) importJSONLFileOptimized(filePath string, projectInfo P
 	}
 	defer file.Close()
 
-	scanner := bufio.NewScanner(file)
-	scanner.Buffer(make([]byte, 0, 16*1024*1024), 16*1024*1024) // 16MB buffer
+	reader := newJSONLReader(file, bi.repo.db.maxLineBytes)
 
 	// Collect all data first
 	var sessions []Session
 	var messages []Message
 	var tokenUsages []TokenUsage
 	var toolResults []ToolResult
+	var hookResults []*MessageHookResult
 	sessionMap := make(map[string]*Session)
 
-	lineNum := 0
-	for scanner.Scan() {
-		lineNum++
-		line := scanner.Text()
-		if line == "" {
-			continue
-		}
-
+	for {
 		var msg JSONLMessage
-		if err := json.Unmarshal([]byte(line), &msg); err != nil {
-			bi.logger.WithError(err).WithField("line", lineNum).Warn("Failed to parse line")
+		oversized, err := reader.Next(&msg)
+		if err == io.EOF {
+			break
+		}
+		if err != nil && !isLineError(err) {
+			return 0, 0, fmt.Errorf("failed to read file: %w", err)
+		}
+		if oversized {
+			bi.repo.recordOversizedLine(filePath, reader.offset, reader.size, err)
+		}
+		if err != nil {
+			bi.logger.WithError(err).WithField("line", reader.line).Warn("Failed to parse line")
 			continue
 		}
 
@@ -151,8 +151,7 @@ func (bi *BatchImporter) importJSONLFileOptimized(filePath string, projectInfo P
 				ProjectPath:    actualProjectPath,
 				ProjectName:    actualProjectName,
 				FilePath:       filePath,
-				GitBranch:      "", // Will be populated if available
-				GitWorktree:    "", // Will be populated if available
+				GitBranch:      msg.GitBranch, // Worktree and remote are detected below
 				StartTime:      msg.Timestamp,
 				LastActivity:   msg.Timestamp,
 				IsActive:       false,
@@ -177,6 +176,9 @@ func (bi *BatchImporter) importJSONLFileOptimized(filePath string, projectInfo P
 			if msg.Message.Model != nil {
 				session.Model = *msg.Message.Model
 			}
+			if msg.GitBranch != "" {
+				session.GitBranch = msg.GitBranch
+			}
 		}
 
 		// Skip existing messages in incremental mode
@@ -196,6 +198,13 @@ func (bi *BatchImporter) importJSONLFileOptimized(filePath string, projectInfo P
 		if msg.ParentUUID != nil && *msg.ParentUUID != "" {
 			dbMessage.ParentUUID = msg.ParentUUID
 		}
+		if result := bi.repo.evaluateMessageHook(&dbMessage, msg.Message.Usage != nil); result != nil {
+			// Messages dropped by a script are skipped with their tool results
+			if result.Drop {
+				continue
+			}
+			hookResults = append(hookResults, result)
+		}
 		messages = append(messages, dbMessage)
 
 		// Handle token usage (only if not skipping this message)
@@ -221,7 +230,9 @@ func (bi *BatchImporter) importJSONLFileOptimized(filePath string, projectInfo P
 			if msg.Message.Model != nil {
 				model = *msg.Message.Model
 			}
-			usage.EstimatedCost = bi.calculateTokenCost(&usage, model)
+			usage.Model = model
+			usage.EstimatedCost = estimateTokenCost(&usage, model)
+			usage.CacheSavings = estimateCacheSavings(&usage, model)
 			
 			tokenUsages = append(tokenUsages, usage)
 		}
@@ -265,16 +276,13 @@ func (bi *BatchImporter) importJSONLFileOptimized(filePath string, projectInfo P
 				}
 				resultBytes, _ := json.Marshal(resultData)
 				toolResult.ResultData = string(resultBytes)
+				toolResult.setWorkspacePath(msg.CWD)
 				
 				toolResults = append(toolResults, toolResult)
 			}
 		}
 	}
 
-	if err := scanner.Err(); err != nil {
-		return 0, 0, fmt.Errorf("failed to read file: %w", err)
-	}
-
 	// Convert session map to slice and finalize session data
 	for _, session := range sessionMap {
 		duration := session.LastActivity.Sub(session.StartTime)
@@ -285,6 +293,7 @@ func (bi *BatchImporter) importJSONLFileOptimized(filePath string, projectInfo P
 		} else {
 			session.Status = "completed"
 		}
+		detectGit(session)
 		sessions = append(sessions, *session)
 	}
 
@@ -301,35 +310,20 @@ func (bi *BatchImporter) importJSONLFileOptimized(filePath string, projectInfo P
 		}
 	}
 
-	return len(sessions), len(messages), nil
-}
+	if err := bi.repo.saveMessageHookResults(hookResults); err != nil {
+		return 0, 0, err
+	}
 
-// calculateTokenCost estimates the cost based on token usage and model
-func (bi *BatchImporter) calculateTokenCost(usage *TokenUsage, model string) float64 {
-	var inputCostPer1M, outputCostPer1M, cacheReadCostPer1M, cacheWriteCostPer1M float64
-	
-	switch {
-	case strings.Contains(model, "claude-3-opus"), strings.Contains(model, "claude-opus-4"):
-		inputCostPer1M = 15.0
-		outputCostPer1M = 75.0
-		cacheReadCostPer1M = 1.50
-		cacheWriteCostPer1M = 18.75
-	case strings.Contains(model, "claude-3-5-sonnet"), strings.Contains(model, "claude-3.5-sonnet"):
-		inputCostPer1M = 3.0
-		outputCostPer1M = 15.0
-		cacheReadCostPer1M = 0.30
-		cacheWriteCostPer1M = 3.75
-	default:
-		inputCostPer1M = 10.0
-		outputCostPer1M = 30.0
-		cacheReadCostPer1M = 1.0
-		cacheWriteCostPer1M = 12.5
+	sessionIDs := make([]string, 0, len(sessions))
+	for _, session := range sessions {
+		sessionIDs = append(sessionIDs, session.ID)
 	}
-	
-	cost := float64(usage.InputTokens) * inputCostPer1M / 1_000_000
-	cost += float64(usage.OutputTokens) * outputCostPer1M / 1_000_000
-	cost += float64(usage.CacheReadInputTokens) * cacheReadCostPer1M / 1_000_000
-	cost += float64(usage.CacheCreationInputTokens) * cacheWriteCostPer1M / 1_000_000
-	
-	return cost
-}
\ No newline at end of file
+	if _, err := bi.repo.LinkResumedSessions(sessionIDs); err != nil {
+		bi.logger.WithError(err).WithField("file", filePath).Warn("Failed to link resumed sessions")
+	}
+	if err := bi.repo.ReapplySessionEdits(sessionIDs); err != nil {
+		bi.logger.WithError(err).WithField("file", filePath).Warn("Failed to reapply session edits")
+	}
+
+	return len(sessions), len(messages), nil
+}
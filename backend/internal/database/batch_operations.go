@@ -68,8 +68,8 @@ func (bo *BatchOperations) batchUpsertSessions(tx *sqlx.Tx, sessions []Session)
 
 	// Build batch insert with ON CONFLICT UPDATE
 	query := `
-		INSERT OR REPLACE INTO sessions (id, project_name, project_path, file_path, git_branch, 
-			git_worktree, start_time, last_activity, is_active, status, model, 
+		INSERT INTO sessions (id, project_name, project_path, file_path, git_branch, 
+			git_worktree, git_remote, start_time, last_activity, is_active, status, model, 
 			message_count, duration_seconds) 
 		VALUES `
 	
@@ -77,15 +77,15 @@ func (bo *BatchOperations) batchUpsertSessions(tx *sqlx.Tx, sessions []Session)
 	var args []interface{}
 	
 	for _, session := range sessions {
-		placeholders := "(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
+		placeholders := "(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
 		values = append(values, placeholders)
 		args = append(args, session.ID, session.ProjectName, session.ProjectPath, 
-			session.FilePath, session.GitBranch, session.GitWorktree, session.StartTime,
+			session.FilePath, session.GitBranch, session.GitWorktree, session.GitRemote, session.StartTime,
 			session.LastActivity, session.IsActive, session.Status, session.Model,
 			session.MessageCount, session.DurationSeconds)
 	}
 	
-	query += strings.Join(values, ", ")
+	query += strings.Join(values, ", ") + sessionUpsertSet
 
 	_, err := tx.Exec(query, args...)
 	return err
@@ -157,7 +157,7 @@ func (bo *BatchOperations) batchUpsertTokenUsages(tx *sqlx.Tx, tokenUsages []Tok
 	}
 
 	// SQLite has a limit of 999 parameters, so batch the inserts
-	const batchSize = 100 // 100 records × 8 params = 800 params (safe under 999 limit)
+	const batchSize = 90 // 90 records × 10 params = 900 params (safe under 999 limit)
 	
 	for i := 0; i < len(tokenUsages); i += batchSize {
 		end := i + batchSize
@@ -168,17 +168,17 @@ func (bo *BatchOperations) batchUpsertTokenUsages(tx *sqlx.Tx, tokenUsages []Tok
 		
 		query := `
 			INSERT OR REPLACE INTO token_usage (message_id, session_id, input_tokens, output_tokens, 
-				cache_creation_input_tokens, cache_read_input_tokens, total_tokens, estimated_cost) 
+				cache_creation_input_tokens, cache_read_input_tokens, total_tokens, model, estimated_cost, cache_savings) 
 			VALUES `
 		
 		var values []string
 		var args []interface{}
 		
 		for _, tu := range batch {
-			placeholders := "(?, ?, ?, ?, ?, ?, ?, ?)"
+			placeholders := "(?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
 			values = append(values, placeholders)
 			args = append(args, tu.MessageID, tu.SessionID, tu.InputTokens, tu.OutputTokens,
-				tu.CacheCreationInputTokens, tu.CacheReadInputTokens, tu.TotalTokens, tu.EstimatedCost)
+				tu.CacheCreationInputTokens, tu.CacheReadInputTokens, tu.TotalTokens, tu.Model, tu.EstimatedCost, tu.CacheSavings)
 		}
 		
 		query += strings.Join(values, ", ")
@@ -198,23 +198,27 @@ func (bo *BatchOperations) batchUpsertToolResults(tx *sqlx.Tx, toolResults []Too
 
 	query := `
 		INSERT OR REPLACE INTO tool_results (message_id, session_id, tool_name, result_data, 
-			file_path, timestamp) 
+			file_path, absolute_path, timestamp) 
 		VALUES `
 	
 	var values []string
 	var args []interface{}
 	
 	for _, tr := range toolResults {
-		placeholders := "(?, ?, ?, ?, ?, ?)"
+		placeholders := "(?, ?, ?, ?, ?, ?, ?)"
 		values = append(values, placeholders)
 		
 		var filePath interface{} = sql.NullString{}
 		if tr.FilePath != nil {
 			filePath = *tr.FilePath
 		}
+		var absolutePath interface{} = sql.NullString{}
+		if tr.AbsolutePath != nil {
+			absolutePath = *tr.AbsolutePath
+		}
 		
 		args = append(args, tr.MessageID, tr.SessionID, tr.ToolName,
-			tr.ResultData, filePath, tr.Timestamp)
+			tr.ResultData, filePath, absolutePath, tr.Timestamp)
 	}
 	
 	query += strings.Join(values, ", ")
@@ -225,15 +229,12 @@ func (bo *BatchOperations) batchUpsertToolResults(tx *sqlx.Tx, toolResults []Too
 
 // ExecuteInReadTransaction executes a function within a transaction optimized for reads
 func (bo *BatchOperations) ExecuteInReadTransaction(fn func(*sqlx.Tx) error) error {
-	tx, err := bo.db.Beginx()
+	tx, err := bo.db.BeginRead()
 	if err != nil {
 		return fmt.Errorf("failed to begin read transaction: %w", err)
 	}
 	defer tx.Rollback()
 
-	// Note: We don't use PRAGMA query_only because it affects the entire connection,
-	// not just the transaction, which can cause "readonly database" errors elsewhere
-
 	if err := fn(tx); err != nil {
 		return err
 	}
@@ -290,10 +291,13 @@ func (bo *BatchOperations) batchUpdateSessionsIncremental(tx *sqlx.Tx, sessions
 	for _, session := range sessions {
 		_, err := tx.Exec(`
 			INSERT INTO sessions (id, project_name, project_path, file_path, git_branch, 
-				git_worktree, start_time, last_activity, is_active, status, model, 
+				git_worktree, git_remote, start_time, last_activity, is_active, status, model, 
 				message_count, duration_seconds) 
-			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 			ON CONFLICT(id) DO UPDATE SET
+				git_branch = COALESCE(NULLIF(excluded.git_branch, ''), sessions.git_branch),
+				git_worktree = COALESCE(NULLIF(excluded.git_worktree, ''), sessions.git_worktree),
+				git_remote = COALESCE(NULLIF(excluded.git_remote, ''), sessions.git_remote),
 				last_activity = CASE WHEN excluded.last_activity > sessions.last_activity THEN excluded.last_activity ELSE sessions.last_activity END,
 				is_active = excluded.is_active,
 				status = excluded.status,
@@ -301,7 +305,7 @@ func (bo *BatchOperations) batchUpdateSessionsIncremental(tx *sqlx.Tx, sessions
 				message_count = sessions.message_count + excluded.message_count,
 				duration_seconds = CASE WHEN excluded.last_activity > sessions.last_activity THEN excluded.duration_seconds ELSE sessions.duration_seconds END
 		`, session.ID, session.ProjectName, session.ProjectPath,
-			session.FilePath, session.GitBranch, session.GitWorktree, session.StartTime,
+			session.FilePath, session.GitBranch, session.GitWorktree, session.GitRemote, session.StartTime,
 			session.LastActivity, session.IsActive, session.Status, session.Model,
 			session.MessageCount, session.DurationSeconds)
 		if err != nil {
@@ -372,7 +376,7 @@ func (bo *BatchOperations) batchInsertTokenUsageIncremental(tx *sqlx.Tx, tokenUs
 	}
 
 	// SQLite has a limit of 999 parameters, so batch the inserts
-	const batchSize = 100 // 100 records × 8 params = 800 params (safe under 999 limit)
+	const batchSize = 90 // 90 records × 10 params = 900 params (safe under 999 limit)
 	
 	for i := 0; i < len(tokenUsages); i += batchSize {
 		end := i + batchSize
@@ -383,17 +387,17 @@ func (bo *BatchOperations) batchInsertTokenUsageIncremental(tx *sqlx.Tx, tokenUs
 		
 		query := `
 			INSERT OR IGNORE INTO token_usage (message_id, session_id, input_tokens, output_tokens, 
-				cache_creation_input_tokens, cache_read_input_tokens, total_tokens, estimated_cost) 
+				cache_creation_input_tokens, cache_read_input_tokens, total_tokens, model, estimated_cost, cache_savings) 
 			VALUES `
 		
 		var values []string
 		var args []interface{}
 		
 		for _, tu := range batch {
-			placeholders := "(?, ?, ?, ?, ?, ?, ?, ?)"
+			placeholders := "(?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
 			values = append(values, placeholders)
 			args = append(args, tu.MessageID, tu.SessionID, tu.InputTokens, tu.OutputTokens,
-				tu.CacheCreationInputTokens, tu.CacheReadInputTokens, tu.TotalTokens, tu.EstimatedCost)
+				tu.CacheCreationInputTokens, tu.CacheReadInputTokens, tu.TotalTokens, tu.Model, tu.EstimatedCost, tu.CacheSavings)
 		}
 		
 		query += strings.Join(values, ", ")
@@ -414,23 +418,27 @@ func (bo *BatchOperations) batchInsertToolResultsIncremental(tx *sqlx.Tx, toolRe
 
 	query := `
 		INSERT OR IGNORE INTO tool_results (message_id, session_id, tool_name, result_data, 
-			file_path, timestamp) 
+			file_path, absolute_path, timestamp) 
 		VALUES `
 	
 	var values []string
 	var args []interface{}
 	
 	for _, tr := range toolResults {
-		placeholders := "(?, ?, ?, ?, ?, ?)"
+		placeholders := "(?, ?, ?, ?, ?, ?, ?)"
 		values = append(values, placeholders)
 		
 		var filePath interface{} = sql.NullString{}
 		if tr.FilePath != nil {
 			filePath = *tr.FilePath
 		}
+		var absolutePath interface{} = sql.NullString{}
+		if tr.AbsolutePath != nil {
+			absolutePath = *tr.AbsolutePath
+		}
 		
 		args = append(args, tr.MessageID, tr.SessionID, tr.ToolName,
-			tr.ResultData, filePath, tr.Timestamp)
+			tr.ResultData, filePath, absolutePath, tr.Timestamp)
 	}
 	
 	query += strings.Join(values, ", ")
@@ -0,0 +1,137 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// CreateBudget stores a new budget, assigning its ID and creation time
+func (r *SessionRepository) CreateBudget(budget *Budget) error {
+	budget.ID = uuid.New().String()
+	budget.CreatedAt = time.Now().UTC()
+	err := r.db.WriteOperation(func(tx *sqlx.Tx) error {
+		_, err := tx.NamedExec(`
+			INSERT INTO budgets (id, name, project_name, period, limit_usd, created_at)
+			VALUES (:id, :name, :project_name, :period, :limit_usd, :created_at)
+		`, budget)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create budget: %w", err)
+	}
+	return nil
+}
+
+// GetBudgets returns every budget, global ones first, then by project and name
+func (r *SessionRepository) GetBudgets() ([]Budget, error) {
+	budgets := []Budget{}
+	err := r.db.Select(&budgets, `
+		SELECT id, name, project_name, period, limit_usd, created_at
+		FROM budgets
+		ORDER BY project_name IS NOT NULL, project_name, name
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get budgets: %w", err)
+	}
+	return budgets, nil
+}
+
+// GetBudget returns a budget by ID
+func (r *SessionRepository) GetBudget(id string) (*Budget, error) {
+	var budget Budget
+	err := r.db.Get(&budget, `
+		SELECT id, name, project_name, period, limit_usd, created_at
+		FROM budgets WHERE id = ?
+	`, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("budget not found: %s", id)
+		}
+		return nil, fmt.Errorf("failed to get budget: %w", err)
+	}
+	return &budget, nil
+}
+
+// DeleteBudget removes a budget and its alerts
+func (r *SessionRepository) DeleteBudget(id string) error {
+	var deleted int64
+	err := r.db.WriteOperation(func(tx *sqlx.Tx) error {
+		result, err := tx.Exec(`DELETE FROM budgets WHERE id = ?`, id)
+		if err != nil {
+			return err
+		}
+		deleted, err = result.RowsAffected()
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete budget: %w", err)
+	}
+	if deleted == 0 {
+		return fmt.Errorf("budget not found: %s", id)
+	}
+	return nil
+}
+
+// GetSpend returns the cost of messages sent in [from, to), for one project
+// or all projects when projectName is nil
+func (r *SessionRepository) GetSpend(projectName *string, from, to time.Time) (float64, error) {
+	query := `
+		SELECT COALESCE(SUM(tu.estimated_cost), 0.0)
+		FROM messages m
+		JOIN token_usage tu ON tu.message_id = m.id
+		WHERE m.timestamp >= ? AND m.timestamp < ?
+	`
+	args := []interface{}{from.UTC(), to.UTC()}
+	if projectName != nil {
+		query += ` AND m.session_id IN (SELECT id FROM sessions WHERE project_name = ?)`
+		args = append(args, *projectName)
+	}
+
+	var spend float64
+	if err := r.db.Get(&spend, query, args...); err != nil {
+		return 0, fmt.Errorf("failed to get spend: %w", err)
+	}
+	return spend, nil
+}
+
+// RecordBudgetAlert stores an alert unless the budget already crossed the
+// same threshold in the same period, reporting whether it was new
+func (r *SessionRepository) RecordBudgetAlert(alert *BudgetAlert) (bool, error) {
+	alert.PeriodStart = alert.PeriodStart.UTC()
+	var inserted int64
+	err := r.db.WriteOperation(func(tx *sqlx.Tx) error {
+		result, err := tx.NamedExec(`
+			INSERT OR IGNORE INTO budget_alerts (budget_id, period_start, threshold, spend_usd, triggered_at)
+			VALUES (:budget_id, :period_start, :threshold, :spend_usd, :triggered_at)
+		`, alert)
+		if err != nil {
+			return err
+		}
+		inserted, err = result.RowsAffected()
+		return err
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to record budget alert: %w", err)
+	}
+	return inserted > 0, nil
+}
+
+// GetBudgetAlerts returns the alerts a budget raised in the period starting
+// at periodStart, lowest threshold first
+func (r *SessionRepository) GetBudgetAlerts(budgetID string, periodStart time.Time) ([]BudgetAlert, error) {
+	alerts := []BudgetAlert{}
+	err := r.db.Select(&alerts, `
+		SELECT budget_id, period_start, threshold, spend_usd, triggered_at
+		FROM budget_alerts
+		WHERE budget_id = ? AND period_start = ?
+		ORDER BY threshold
+	`, budgetID, periodStart.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get budget alerts: %w", err)
+	}
+	return alerts, nil
+}
@@ -0,0 +1,132 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ChatTurn is a prompt sent to a session's chat from the manager and
+// Claude's reply, stored as messages of the session like imported ones
+type ChatTurn struct {
+	SessionID string
+	PromptID  string // IDs of the two messages, shared with the chat's records of them
+	ReplyID   string
+	Prompt    string
+	Reply     string
+	Model     string      // the session's model when empty
+	Usage     *TokenUsage // the tokens the reply used; nil when the CLI didn't report them
+	SentAt    time.Time
+	RepliedAt time.Time
+}
+
+// StoredMessage is a message with its token usage, if it has any
+type StoredMessage struct {
+	Message
+	Usage *TokenUsage `json:"usage,omitempty"`
+}
+
+// RecordChatTurn stores a chat turn as a user message following the
+// session's latest message and an assistant message carrying the turn's
+// token usage, priced like imported usage, and returns the messages stored.
+// Either can be dropped by the message hook.
+func (r *SessionRepository) RecordChatTurn(turn *ChatTurn) ([]StoredMessage, error) {
+	stored := []StoredMessage{}
+	err := r.db.WriteOperation(func(tx *sqlx.Tx) error {
+		var session struct {
+			ProjectPath string    `db:"project_path"`
+			Model       string    `db:"model"`
+			StartTime   time.Time `db:"start_time"`
+		}
+		err := tx.Get(&session, `SELECT project_path, COALESCE(model, '') AS model, start_time FROM sessions WHERE id = ?`, turn.SessionID)
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("session not found: %s", turn.SessionID)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get session: %w", err)
+		}
+		model := turn.Model
+		if model == "" {
+			model = session.Model
+		}
+
+		var parentID *string
+		var latest string
+		err = tx.Get(&latest, `SELECT id FROM messages WHERE session_id = ? ORDER BY timestamp DESC LIMIT 1`, turn.SessionID)
+		if err != nil && err != sql.ErrNoRows {
+			return fmt.Errorf("failed to get latest message: %w", err)
+		}
+		if err == nil {
+			parentID = &latest
+		}
+
+		promptContent, _ := json.Marshal(turn.Prompt)
+		replyContent, _ := json.Marshal([]map[string]string{{"type": "text", "text": turn.Reply}})
+		promptID := turn.PromptID
+		messages := []StoredMessage{
+			{Message: Message{
+				ID: turn.PromptID, SessionID: turn.SessionID, ParentUUID: parentID, UserType: "external",
+				CWD: session.ProjectPath, Type: "user", Role: "user", Content: string(promptContent), Timestamp: turn.SentAt,
+			}},
+			{Message: Message{
+				ID: turn.ReplyID, SessionID: turn.SessionID, ParentUUID: &promptID, UserType: "external",
+				CWD: session.ProjectPath, Type: "assistant", Role: "assistant", Content: string(replyContent), Timestamp: turn.RepliedAt,
+			}},
+		}
+		if turn.Usage != nil {
+			usage := *turn.Usage
+			usage.MessageID = turn.ReplyID
+			usage.SessionID = turn.SessionID
+			usage.TotalTokens = usage.InputTokens + usage.OutputTokens +
+				usage.CacheCreationInputTokens + usage.CacheReadInputTokens
+			usage.Model = model
+			usage.EstimatedCost = estimateTokenCost(&usage, model)
+			usage.CacheSavings = estimateCacheSavings(&usage, model)
+			messages[1].Usage = &usage
+		}
+
+		for i := range messages {
+			message := &messages[i]
+			keep, err := r.runMessageHook(tx, &message.Message, message.Usage != nil)
+			if err != nil {
+				return fmt.Errorf("failed to run message hook: %w", err)
+			}
+			if !keep {
+				continue
+			}
+			if err := upsertMessage(tx, &message.Message); err != nil {
+				return fmt.Errorf("failed to store message: %w", err)
+			}
+			if message.Usage != nil {
+				if err := upsertTokenUsage(tx, message.Usage); err != nil {
+					return fmt.Errorf("failed to store token usage: %w", err)
+				}
+			}
+			stored = append(stored, *message)
+		}
+
+		_, err = tx.Exec(`
+			UPDATE sessions SET
+				last_activity = ?,
+				duration_seconds = ?,
+				message_count = message_count + ?,
+				model = COALESCE(NULLIF(?, ''), model),
+				is_active = TRUE,
+				status = ?,
+				updated_at = CURRENT_TIMESTAMP
+			WHERE id = ?
+		`, turn.RepliedAt, int64(turn.RepliedAt.Sub(session.StartTime).Seconds()), len(stored), turn.Model,
+			SessionStatusActive, turn.SessionID)
+		if err != nil {
+			return fmt.Errorf("failed to update session: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return stored, nil
+}
@@ -0,0 +1,67 @@
+package database
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSessionRepository_RecordChatTurn(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewSessionRepository(db, logger)
+	session, err := repo.CreateUISession("/work/app", "app", "claude-sonnet-4-20250514")
+	if err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+
+	sentAt := session.StartTime.Add(time.Minute)
+	turn := &ChatTurn{
+		SessionID: session.ID,
+		PromptID:  "prompt-1",
+		ReplyID:   "reply-1",
+		Prompt:    "Add a test",
+		Reply:     "Added one.",
+		Usage:     &TokenUsage{InputTokens: 1000, OutputTokens: 200, CacheReadInputTokens: 5000},
+		SentAt:    sentAt,
+		RepliedAt: sentAt.Add(30 * time.Second),
+	}
+	messages, err := repo.RecordChatTurn(turn)
+	if err != nil {
+		t.Fatalf("Failed to record chat turn: %v", err)
+	}
+	if len(messages) != 2 || messages[0].ParentUUID != nil || *messages[1].ParentUUID != "prompt-1" {
+		t.Fatalf("Expected the prompt and its reply, got %+v", messages)
+	}
+	if usage := messages[1].Usage; usage == nil || usage.TotalTokens != 6200 || usage.EstimatedCost <= 0 {
+		t.Errorf("Expected the reply's usage priced, got %+v", usage)
+	}
+	if !strings.Contains(messages[1].Content, `"text":"Added one."`) {
+		t.Errorf("Expected the reply as a text block, got %s", messages[1].Content)
+	}
+
+	summary, err := repo.GetSessionByID(session.ID)
+	if err != nil {
+		t.Fatalf("Failed to get session: %v", err)
+	}
+	if summary.MessageCount != 2 || summary.TotalTokens != 6200 {
+		t.Errorf("Expected the session to count the turn, got %d messages and %d tokens", summary.MessageCount, summary.TotalTokens)
+	}
+
+	// The next turn follows on from the last reply
+	turn.PromptID, turn.ReplyID, turn.Usage = "prompt-2", "reply-2", nil
+	turn.SentAt, turn.RepliedAt = turn.RepliedAt.Add(time.Minute), turn.RepliedAt.Add(2*time.Minute)
+	messages, err = repo.RecordChatTurn(turn)
+	if err != nil {
+		t.Fatalf("Failed to record chat turn: %v", err)
+	}
+	if messages[0].ParentUUID == nil || *messages[0].ParentUUID != "reply-1" {
+		t.Errorf("Expected the prompt to follow the last reply, got %v", messages[0].ParentUUID)
+	}
+
+	turn.SessionID = "missing"
+	if _, err := repo.RecordChatTurn(turn); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("Expected a missing session not found, got %v", err)
+	}
+}
@@ -0,0 +1,270 @@
+package database
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Claude keeps todo lists outside the project transcripts, one file per
+// session and agent named <session>-agent-<agent>.json
+const (
+	todosDirName     = "todos"
+	settingsFileName = "settings.json"
+)
+
+// todoItem is an item as written to a Claude todo list file
+type todoItem struct {
+	ID         string `json:"id"`
+	Content    string `json:"content"`
+	Status     string `json:"status"`
+	ActiveForm string `json:"activeForm"`
+	Priority   string `json:"priority"`
+}
+
+// TodosDir returns the directory Claude keeps todo lists in
+func TodosDir(claudeDir string) string {
+	return filepath.Join(claudeDir, todosDirName)
+}
+
+// SettingsFile returns the path of the user-level Claude settings file
+func SettingsFile(claudeDir string) string {
+	return filepath.Join(claudeDir, settingsFileName)
+}
+
+// ParseTodoFileName returns the session and agent a todo list file belongs to
+func ParseTodoFileName(name string) (string, string, bool) {
+	base := filepath.Base(name)
+	if !strings.HasSuffix(base, ".json") {
+		return "", "", false
+	}
+	sessionID, agentID, ok := strings.Cut(strings.TrimSuffix(base, ".json"), "-agent-")
+	if !ok || sessionID == "" || agentID == "" {
+		return "", "", false
+	}
+	return sessionID, agentID, true
+}
+
+// ImportTodoFile replaces the stored todo list for the file's session and
+// agent with the file's contents and returns the number of items
+func (r *SessionRepository) ImportTodoFile(path string) (int, error) {
+	sessionID, agentID, ok := ParseTodoFileName(path)
+	if !ok {
+		return 0, fmt.Errorf("not a todo list file: %s", path)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to stat todo list: %w", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read todo list: %w", err)
+	}
+
+	var items []todoItem
+	if len(strings.TrimSpace(string(data))) > 0 {
+		if err := json.Unmarshal(data, &items); err != nil {
+			return 0, fmt.Errorf("failed to parse todo list: %w", err)
+		}
+	}
+
+	modified := info.ModTime().UTC()
+	err = r.db.WriteOperation(func(tx *sqlx.Tx) error {
+		if _, err := tx.Exec(`
+			DELETE FROM session_todos WHERE session_id = ? AND agent_id = ?
+		`, sessionID, agentID); err != nil {
+			return fmt.Errorf("failed to clear todo list: %w", err)
+		}
+
+		for i, item := range items {
+			if _, err := tx.Exec(`
+				INSERT INTO session_todos (
+					session_id, agent_id, position, todo_id, content, status,
+					active_form, priority, file_modified_at
+				) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+			`, sessionID, agentID, i, nullIfEmpty(item.ID), item.Content, item.Status,
+				nullIfEmpty(item.ActiveForm), nullIfEmpty(item.Priority), modified); err != nil {
+				return fmt.Errorf("failed to store todo: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(items), nil
+}
+
+// RedactSettings parses a settings file and replaces the values of its env
+// section with a short digest, so changes to them are visible without the
+// values (often API keys) being stored
+func RedactSettings(data []byte) (map[string]interface{}, error) {
+	settings := make(map[string]interface{})
+	if len(strings.TrimSpace(string(data))) > 0 {
+		if err := json.Unmarshal(data, &settings); err != nil {
+			return nil, fmt.Errorf("failed to parse settings: %w", err)
+		}
+	}
+
+	if env, ok := settings["env"].(map[string]interface{}); ok {
+		for name, value := range env {
+			raw, _ := json.Marshal(value)
+			sum := sha256.Sum256(raw)
+			env[name] = "[redacted:" + hex.EncodeToString(sum[:4]) + "]"
+		}
+	}
+	return settings, nil
+}
+
+// RecordSettingsFile records the settings file if it differs from the last
+// version recorded for its path and reports whether it was recorded
+func (r *SessionRepository) RecordSettingsFile(path string) (bool, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false, fmt.Errorf("failed to stat settings: %w", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false, fmt.Errorf("failed to read settings: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	checksum := hex.EncodeToString(sum[:])
+
+	settings, err := RedactSettings(data)
+	if err != nil {
+		return false, err
+	}
+	content, err := json.Marshal(settings)
+	if err != nil {
+		return false, fmt.Errorf("failed to encode settings: %w", err)
+	}
+
+	recorded := false
+	err = r.db.WriteOperation(func(tx *sqlx.Tx) error {
+		var previous SettingsChange
+		err := tx.Get(&previous, `
+			SELECT * FROM settings_changes WHERE file_path = ? ORDER BY id DESC LIMIT 1
+		`, path)
+		if err != nil && err != sql.ErrNoRows {
+			return fmt.Errorf("failed to get previous settings: %w", err)
+		}
+		if err == nil && previous.Checksum == checksum {
+			return nil
+		}
+
+		previousSettings := make(map[string]interface{})
+		if err == nil {
+			_ = json.Unmarshal([]byte(previous.Content), &previousSettings)
+		}
+		changed, _ := json.Marshal(changedKeys(previousSettings, settings))
+
+		if _, err := tx.Exec(`
+			INSERT INTO settings_changes (file_path, checksum, content, changed_keys, file_modified_at)
+			VALUES (?, ?, ?, ?, ?)
+		`, path, checksum, string(content), string(changed), info.ModTime().UTC()); err != nil {
+			return fmt.Errorf("failed to record settings: %w", err)
+		}
+		recorded = true
+		return nil
+	})
+	return recorded, err
+}
+
+// changedKeys returns the sorted top-level keys added, removed or changed
+// between two versions of the settings
+func changedKeys(before, after map[string]interface{}) []string {
+	keys := []string{}
+	for key, value := range after {
+		if previous, ok := before[key]; !ok || !reflect.DeepEqual(previous, value) {
+			keys = append(keys, key)
+		}
+	}
+	for key := range before {
+		if _, ok := after[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ImportClaudeData imports every todo list and records the settings file if
+// it changed, returning the number of todo lists imported and whether the
+// settings were recorded. Missing files and directories are skipped.
+func (r *SessionRepository) ImportClaudeData(claudeDir string) (int, bool, error) {
+	lists := 0
+	files, err := filepath.Glob(filepath.Join(TodosDir(claudeDir), "*.json"))
+	if err != nil {
+		return 0, false, err
+	}
+	for _, file := range files {
+		if _, _, ok := ParseTodoFileName(file); !ok {
+			continue
+		}
+		if _, err := r.ImportTodoFile(file); err != nil {
+			r.logger.WithError(err).WithField("file", file).Warn("Failed to import todo list")
+			continue
+		}
+		lists++
+	}
+
+	settingsFile := SettingsFile(claudeDir)
+	if _, err := os.Stat(settingsFile); os.IsNotExist(err) {
+		return lists, false, nil
+	}
+	recorded, err := r.RecordSettingsFile(settingsFile)
+	return lists, recorded, err
+}
+
+// GetTodos returns todo items, optionally for one session and with one
+// status, most recently updated lists first
+func (r *SessionRepository) GetTodos(sessionID, status string, limit int) ([]SessionTodo, error) {
+	todos := []SessionTodo{}
+	err := r.db.Select(&todos, `
+		SELECT t.*, s.project_name
+		FROM session_todos t
+		LEFT JOIN sessions s ON s.id = t.session_id
+		WHERE (? = '' OR t.session_id = ?)
+		AND (? = '' OR t.status = ?)
+		ORDER BY t.file_modified_at DESC, t.session_id, t.agent_id, t.position
+		LIMIT ?
+	`, sessionID, sessionID, status, status, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get todos: %w", err)
+	}
+	return todos, nil
+}
+
+// GetSettingsChanges returns the recorded versions of the settings file,
+// most recent first
+func (r *SessionRepository) GetSettingsChanges(limit int) ([]SettingsChange, error) {
+	changes := []SettingsChange{}
+	err := r.db.Select(&changes, `
+		SELECT * FROM settings_changes ORDER BY id DESC LIMIT ?
+	`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get settings changes: %w", err)
+	}
+	for i := range changes {
+		changes[i].Keys = []string{}
+		_ = json.Unmarshal([]byte(changes[i].ChangedKeys), &changes[i].Keys)
+	}
+	return changes, nil
+}
+
+func nullIfEmpty(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
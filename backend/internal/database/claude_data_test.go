@@ -0,0 +1,98 @@
+package database
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseTodoFileName(t *testing.T) {
+	sessionID, agentID, ok := ParseTodoFileName("/home/me/.claude/todos/abc-123-agent-abc-123.json")
+	if !ok || sessionID != "abc-123" || agentID != "abc-123" {
+		t.Errorf("Unexpected parse: %q %q %v", sessionID, agentID, ok)
+	}
+	if _, _, ok := ParseTodoFileName("abc-123.json"); ok {
+		t.Error("Expected file without an agent to be rejected")
+	}
+}
+
+func TestSessionRepository_ImportClaudeData(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewSessionRepository(db, logger)
+
+	now := time.Now()
+	if err := repo.UpsertSession(&Session{
+		ID:           "s1",
+		ProjectPath:  "/test/project",
+		ProjectName:  "project",
+		StartTime:    now,
+		LastActivity: now,
+		Status:       "active",
+	}); err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+
+	claudeDir := t.TempDir()
+	if err := os.MkdirAll(TodosDir(claudeDir), 0755); err != nil {
+		t.Fatalf("Failed to create todos dir: %v", err)
+	}
+	todoFile := filepath.Join(TodosDir(claudeDir), "s1-agent-s1.json")
+	writeFile := func(path, content string) {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", path, err)
+		}
+	}
+	writeFile(todoFile, `[
+		{"id": "1", "content": "Write the parser", "status": "completed", "activeForm": "Writing the parser"},
+		{"id": "2", "content": "Add tests", "status": "in_progress", "activeForm": "Adding tests"}
+	]`)
+	writeFile(SettingsFile(claudeDir), `{"model": "opus", "env": {"API_KEY": "secret"}}`)
+
+	lists, recorded, err := repo.ImportClaudeData(claudeDir)
+	if err != nil || lists != 1 || !recorded {
+		t.Fatalf("Unexpected import: %d lists, recorded %v (%v)", lists, recorded, err)
+	}
+
+	todos, err := repo.GetTodos("s1", "", 100)
+	if err != nil || len(todos) != 2 {
+		t.Fatalf("Expected 2 todos, got %d (%v)", len(todos), err)
+	}
+	if todos[1].Content != "Add tests" || todos[1].Status != TodoStatusInProgress || *todos[1].ProjectName != "project" {
+		t.Errorf("Unexpected todo: %+v", todos[1])
+	}
+
+	// A rewritten list replaces the previous one
+	writeFile(todoFile, `[{"id": "2", "content": "Add tests", "status": "completed"}]`)
+	if count, err := repo.ImportTodoFile(todoFile); err != nil || count != 1 {
+		t.Fatalf("Expected 1 todo, got %d (%v)", count, err)
+	}
+	if todos, _ := repo.GetTodos("", TodoStatusInProgress, 100); len(todos) != 0 {
+		t.Errorf("Expected no in-progress todos, got %+v", todos)
+	}
+
+	// Unchanged settings are not recorded again
+	if recorded, err := repo.RecordSettingsFile(SettingsFile(claudeDir)); err != nil || recorded {
+		t.Errorf("Expected unchanged settings to be skipped, got %v (%v)", recorded, err)
+	}
+	writeFile(SettingsFile(claudeDir), `{"model": "opus", "env": {"API_KEY": "rotated"}, "permissions": {}}`)
+	if recorded, err := repo.RecordSettingsFile(SettingsFile(claudeDir)); err != nil || !recorded {
+		t.Fatalf("Expected changed settings to be recorded, got %v (%v)", recorded, err)
+	}
+
+	changes, err := repo.GetSettingsChanges(10)
+	if err != nil || len(changes) != 2 {
+		t.Fatalf("Expected 2 settings changes, got %d (%v)", len(changes), err)
+	}
+	if strings.Join(changes[0].Keys, ",") != "env,permissions" {
+		t.Errorf("Unexpected changed keys: %v", changes[0].Keys)
+	}
+	for _, change := range changes {
+		if strings.Contains(change.Content, "secret") || strings.Contains(change.Content, "rotated") {
+			t.Errorf("Expected env values to be redacted, got %s", change.Content)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package database
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// exitCodePattern finds the exit code Claude Code reports in the result of a
+// failed Bash command
+var exitCodePattern = regexp.MustCompile(`(?m)^(?:Error: )?Exit code (\d+)`)
+
+// bashCommand returns the command of a Bash tool call, nil for other tools
+func bashCommand(call ToolCall) *Command {
+	if call.ToolName != "Bash" {
+		return nil
+	}
+	text, _ := call.Parameters["command"].(string)
+	if text == "" {
+		return nil
+	}
+	command := &Command{Command: text}
+	if description, ok := call.Parameters["description"].(string); ok && description != "" {
+		command.Description = &description
+	}
+	return command
+}
+
+// commandExitCode returns the exit code a Bash result reports: the code of a
+// failure, 0 for a success, and nil for a failure that doesn't say, such as
+// an interrupted command
+func commandExitCode(ref ToolResultRef) *int {
+	if match := exitCodePattern.FindStringSubmatch(ref.Content); match != nil {
+		if code, err := strconv.Atoi(match[1]); err == nil {
+			return &code
+		}
+	}
+	if ref.IsError {
+		return nil
+	}
+	code := 0
+	return &code
+}
+
+// CommandFilter selects the commands listed across sessions: those of
+// Project (a display name or any of its aliases), containing Query, or that
+// exited non-zero if Failed. Archived sessions are left out.
+type CommandFilter struct {
+	Project string
+	Query   string
+	Failed  bool
+}
+
+// GetSessionCommands returns the commands run in a session, oldest first
+func (r *SessionRepository) GetSessionCommands(sessionID string) ([]Command, error) {
+	commands := []Command{}
+	err := r.db.Select(&commands, `
+		SELECT id, session_id, message_id, tool_use_id, command, description, cwd, exit_code, timestamp
+		FROM commands
+		WHERE session_id = ?
+		ORDER BY timestamp, id
+	`, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session commands: %w", err)
+	}
+	return commands, nil
+}
+
+// GetRecentCommands returns up to limit commands across sessions, most
+// recent first
+func (r *SessionRepository) GetRecentCommands(filter CommandFilter, limit int) ([]Command, error) {
+	where := `WHERE s.archived_at IS NULL`
+	var args []interface{}
+	if filter.Project != "" {
+		where += ` AND s.project_name IN (
+			SELECT alias FROM project_aliases WHERE project_id IN (
+				SELECT project_id FROM project_aliases WHERE alias = ?
+				UNION SELECT id FROM projects WHERE display_name = ?
+			)
+			UNION SELECT ?
+		)`
+		args = append(args, filter.Project, filter.Project, filter.Project)
+	}
+	if filter.Query != "" {
+		where += ` AND c.command LIKE ?`
+		args = append(args, "%"+filter.Query+"%")
+	}
+	if filter.Failed {
+		where += ` AND c.exit_code != 0`
+	}
+	args = append(args, limit)
+
+	commands := []Command{}
+	err := r.db.Select(&commands, `
+		SELECT
+			c.id, c.session_id, COALESCE(p.display_name, s.project_name) AS project_name,
+			c.message_id, c.tool_use_id, c.command, c.description, c.cwd, c.exit_code, c.timestamp
+		FROM commands c
+		JOIN sessions s ON s.id = c.session_id
+		LEFT JOIN project_aliases pa ON pa.alias = s.project_name
+		LEFT JOIN projects p ON p.id = pa.project_id
+		`+where+`
+		ORDER BY c.timestamp DESC, c.id DESC
+		LIMIT ?
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get recent commands: %w", err)
+	}
+	return commands, nil
+}
@@ -0,0 +1,73 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSessionRepository_Commands(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewSessionRepository(db, logger)
+	start := time.Now().UTC().Add(-time.Hour).Truncate(time.Second)
+
+	messages := []struct {
+		session, id, role, content string
+		at                         time.Duration
+	}{
+		{"s1", "m1", "assistant", `[{"type":"tool_use","id":"tu1","name":"Bash","input":{"command":"go test ./...","description":"Run the tests"}}]`, 0},
+		{"s1", "m2", "user", `[{"type":"tool_result","tool_use_id":"tu1","content":"Exit code 1\nFAIL","is_error":true}]`, 10 * time.Second},
+		{"s1", "m3", "assistant", `[{"type":"tool_use","id":"tu2","name":"Bash","input":{"command":"git status"}},{"type":"tool_use","id":"tu3","name":"Read","input":{"file_path":"/work/app/main.go"}}]`, time.Minute},
+		{"s1", "m4", "user", `[{"type":"tool_result","tool_use_id":"tu2","content":[{"type":"text","text":"nothing to commit"}]}]`, 61 * time.Second},
+		{"s2", "m5", "assistant", `[{"type":"tool_use","id":"tu4","name":"Bash","input":{"command":"npm run build"}}]`, 2 * time.Minute},
+	}
+	counts := map[string]int{"s1": 4, "s2": 1}
+	for _, project := range []struct{ session, name string }{{"s1", "app"}, {"s2", "docs"}} {
+		if err := repo.UpsertSession(&Session{ID: project.session, ProjectPath: "/work/" + project.name, ProjectName: project.name, StartTime: start, LastActivity: start, Status: "completed", MessageCount: counts[project.session]}); err != nil {
+			t.Fatalf("Failed to create session: %v", err)
+		}
+	}
+	for _, m := range messages {
+		if err := repo.UpsertMessage(&Message{ID: m.id, SessionID: m.session, Role: m.role, CWD: "/work/" + m.session, Content: m.content, Timestamp: start.Add(m.at)}); err != nil {
+			t.Fatalf("Failed to create message: %v", err)
+		}
+	}
+
+	if err := repo.RefreshSessionToolCalls("s1"); err != nil {
+		t.Fatalf("Failed to refresh session tool calls: %v", err)
+	}
+	commands, err := repo.GetSessionCommands("s1")
+	if err != nil {
+		t.Fatalf("Failed to get session commands: %v", err)
+	}
+	if len(commands) != 2 {
+		t.Fatalf("Expected 2 commands, got %+v", commands)
+	}
+	if test := commands[0]; test.Command != "go test ./..." || test.ExitCode == nil || *test.ExitCode != 1 || test.Description == nil || *test.Description != "Run the tests" || test.Cwd == nil || *test.Cwd != "/work/s1" {
+		t.Errorf("Expected the failed test run, got %+v", test)
+	}
+	if status := commands[1]; status.Command != "git status" || status.ExitCode == nil || *status.ExitCode != 0 {
+		t.Errorf("Expected git status to succeed, got %+v", status)
+	}
+
+	if _, err := repo.RefreshToolCalls(); err != nil {
+		t.Fatalf("Failed to extract tool calls: %v", err)
+	}
+	recent, err := repo.GetRecentCommands(CommandFilter{}, 10)
+	if err != nil {
+		t.Fatalf("Failed to get recent commands: %v", err)
+	}
+	if len(recent) != 3 || recent[0].Command != "npm run build" || recent[0].ProjectName != "docs" || recent[0].ExitCode != nil {
+		t.Errorf("Expected the running build first, got %+v", recent)
+	}
+
+	failed, _ := repo.GetRecentCommands(CommandFilter{Failed: true}, 10)
+	if len(failed) != 1 || failed[0].Command != "go test ./..." {
+		t.Errorf("Expected only the failed test run, got %+v", failed)
+	}
+	matching, _ := repo.GetRecentCommands(CommandFilter{Project: "app", Query: "git"}, 10)
+	if len(matching) != 1 || matching[0].Command != "git status" {
+		t.Errorf("Expected only git status, got %+v", matching)
+	}
+}
@@ -0,0 +1,161 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/mattn/go-sqlite3"
+	"github.com/sirupsen/logrus"
+)
+
+// Tuning holds the SQLite connection settings. Writes go through a single
+// connection, so they queue in the process rather than failing with
+// "database is locked", and reads use a pool of read-only connections that
+// WAL mode lets run alongside the writer.
+type Tuning struct {
+	BusyTimeout     time.Duration // how long a connection waits for a lock held by another process
+	ReadConnections int           // size of the read-only pool
+	CacheSizeMB     int           // page cache of each connection
+	MmapSizeMB      int           // database memory mapped by each connection; 0 turns it off
+
+	// CheckpointInterval is how often the WAL is checkpointed and truncated;
+	// 0 leaves checkpoints to SQLite, which cannot truncate the WAL while
+	// readers are busy, so it grows under constant load
+	CheckpointInterval time.Duration
+}
+
+// DefaultTuning returns the settings used when Config.Tuning is unset
+func DefaultTuning() Tuning {
+	return Tuning{
+		BusyTimeout:        30 * time.Second,
+		ReadConnections:    8,
+		CacheSizeMB:        40,
+		MmapSizeMB:         256,
+		CheckpointInterval: 5 * time.Minute,
+	}
+}
+
+// WALCheckpoint is the result of a WAL checkpoint
+type WALCheckpoint struct {
+	Busy         bool `db:"busy" json:"busy"`                 // readers kept part of the WAL from being checkpointed
+	Frames       int  `db:"log" json:"frames"`                // frames in the WAL before the checkpoint
+	Checkpointed int  `db:"checkpointed" json:"checkpointed"` // frames copied into the database
+}
+
+// sqliteConnector opens connections to dsn, running pragmas on each one, as
+// pragmas such as mmap_size only apply to the connection they run on
+type sqliteConnector struct {
+	dsn    string
+	driver *sqlite3.SQLiteDriver
+}
+
+func (c *sqliteConnector) Connect(context.Context) (driver.Conn, error) {
+	return c.driver.Open(c.dsn)
+}
+
+func (c *sqliteConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+// openSQLite opens a connection pool to path with the tuning's pragmas
+func openSQLite(path, params string, tuning Tuning) (*sqlx.DB, error) {
+	pragmas := []string{
+		fmt.Sprintf("PRAGMA busy_timeout = %d", tuning.BusyTimeout.Milliseconds()),
+		fmt.Sprintf("PRAGMA cache_size = %d", -tuning.CacheSizeMB*1024), // negative sizes are in KiB
+		fmt.Sprintf("PRAGMA mmap_size = %d", int64(tuning.MmapSizeMB)*1024*1024),
+	}
+	connector := &sqliteConnector{
+		dsn: path + "?" + params,
+		driver: &sqlite3.SQLiteDriver{
+			ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+				for _, pragma := range pragmas {
+					if _, err := conn.Exec(pragma, nil); err != nil {
+						return fmt.Errorf("failed to run %s: %w", pragma, err)
+					}
+				}
+				return nil
+			},
+		},
+	}
+	db := sqlx.NewDb(sql.OpenDB(connector), "sqlite3")
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// openWriter opens the single connection every write goes through. Its
+// transactions take the write lock when they begin, so one never fails
+// part way through because another process started writing.
+func openWriter(path string, tuning Tuning) (*sqlx.DB, error) {
+	db, err := openSQLite(path, "_journal_mode=WAL&_foreign_keys=on&_synchronous=NORMAL&_txlock=immediate", tuning)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(1)
+	db.SetMaxIdleConns(1)
+	db.SetConnMaxLifetime(0) // keep the connection and its page cache
+	return db, nil
+}
+
+// openReader opens the read-only connection pool. openWriter must have run
+// first so the database is in WAL mode.
+func openReader(path string, tuning Tuning) (*sqlx.DB, error) {
+	db, err := openSQLite(path, "_query_only=true&_foreign_keys=on", tuning)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(tuning.ReadConnections)
+	db.SetMaxIdleConns(tuning.ReadConnections)
+	db.SetConnMaxLifetime(time.Hour) // Recycle connections hourly
+	return db, nil
+}
+
+// BeginRead starts a read-only transaction, which sees the database as it was
+// when its first query ran
+func (db *Database) BeginRead() (*sqlx.Tx, error) {
+	return db.reader.Beginx()
+}
+
+// Checkpoint copies the WAL into the database and truncates it. Writes wait
+// for it to finish; readers only delay it.
+func (db *Database) Checkpoint() (*WALCheckpoint, error) {
+	db.writeMutex.Lock()
+	defer db.writeMutex.Unlock()
+
+	var checkpoint WALCheckpoint
+	if err := db.DB.Get(&checkpoint, "PRAGMA wal_checkpoint(TRUNCATE)"); err != nil {
+		return nil, fmt.Errorf("failed to checkpoint WAL: %w", err)
+	}
+	return &checkpoint, nil
+}
+
+// checkpointLoop checkpoints the WAL every interval until Close is called
+func (db *Database) checkpointLoop(interval time.Duration) {
+	defer close(db.checkpointsDone)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-db.stopCheckpoints:
+			return
+		case <-ticker.C:
+			checkpoint, err := db.Checkpoint()
+			if err != nil {
+				db.logger.WithError(err).Warn("WAL checkpoint failed")
+				continue
+			}
+			db.logger.WithFields(logrus.Fields{
+				"frames":       checkpoint.Frames,
+				"checkpointed": checkpoint.Checkpointed,
+				"busy":         checkpoint.Busy,
+			}).Debug("WAL checkpointed")
+		}
+	}
+}
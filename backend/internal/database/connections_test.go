@@ -0,0 +1,96 @@
+package database
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+func TestDatabase_ReadWriteSplit(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	if _, err := db.reader.Exec(`INSERT INTO projects (display_name) VALUES ('app')`); err == nil {
+		t.Error("Expected the read connections to refuse writes")
+	}
+
+	var mmapSize int64
+	if err := db.Get(&mmapSize, "PRAGMA mmap_size"); err != nil {
+		t.Fatalf("Failed to read mmap_size: %v", err)
+	}
+	if want := int64(DefaultTuning().MmapSizeMB) * 1024 * 1024; mmapSize != want {
+		t.Errorf("Expected mmap_size %d, got %d", want, mmapSize)
+	}
+
+	// Reads carry on while a write transaction is open
+	started, release := make(chan struct{}), make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- db.WriteOperation(func(tx *sqlx.Tx) error {
+			if _, err := tx.Exec(`INSERT INTO sessions (id, project_path, project_name, file_path, start_time, last_activity)
+				VALUES ('s1', '/work/app', 'app', '/tmp/s1.jsonl', ?, ?)`, time.Now(), time.Now()); err != nil {
+				return err
+			}
+			close(started)
+			<-release
+			return nil
+		})
+	}()
+	<-started
+	var count int
+	if err := db.Get(&count, "SELECT COUNT(*) FROM sessions"); err != nil {
+		t.Fatalf("Failed to read during a write: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("Expected the uncommitted session to be invisible, got %d", count)
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatalf("Failed to write: %v", err)
+	}
+	if err := db.Get(&count, "SELECT COUNT(*) FROM sessions"); err != nil || count != 1 {
+		t.Errorf("Expected the committed session to be read, got %d (%v)", count, err)
+	}
+}
+
+func TestDatabase_Checkpoint(t *testing.T) {
+	tmpFile, err := os.CreateTemp("", "test-claude-session-*.db")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	tmpFile.Close()
+	defer os.Remove(tmpFile.Name())
+	defer os.Remove(tmpFile.Name() + "-wal")
+	defer os.Remove(tmpFile.Name() + "-shm")
+
+	tuning := DefaultTuning()
+	tuning.CheckpointInterval = 10 * time.Millisecond
+	db, err := NewDatabase(Config{DatabasePath: tmpFile.Name(), Logger: logger, Tuning: &tuning})
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+
+	if _, err := db.Exec(`INSERT INTO projects (display_name) VALUES ('app')`); err != nil {
+		t.Fatalf("Failed to write: %v", err)
+	}
+	checkpoint, err := db.Checkpoint()
+	if err != nil {
+		t.Fatalf("Failed to checkpoint: %v", err)
+	}
+	if checkpoint.Busy || checkpoint.Checkpointed != checkpoint.Frames {
+		t.Errorf("Expected the whole WAL to be checkpointed, got %+v", checkpoint)
+	}
+	if info, err := os.Stat(tmpFile.Name() + "-wal"); err == nil && info.Size() != 0 {
+		t.Errorf("Expected the WAL to be truncated, got %d bytes", info.Size())
+	}
+
+	time.Sleep(30 * time.Millisecond) // let the checkpoint loop run
+	if err := db.Close(); err != nil {
+		t.Errorf("Failed to close: %v", err)
+	}
+	if err := db.Close(); err != nil {
+		t.Errorf("Expected closing twice to succeed, got %v", err)
+	}
+}
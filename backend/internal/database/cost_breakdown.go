@@ -0,0 +1,65 @@
+package database
+
+import (
+	"fmt"
+	"time"
+)
+
+// costGroups maps the groupings GetCostBreakdown accepts to the expression
+// naming each group. Models and branches are those recorded for the session.
+var costGroups = map[string]string{
+	"project": "s.project_name",
+	"model":   "COALESCE(NULLIF(s.model, ''), 'unknown')",
+	"day":     "strftime('%Y-%m-%d', m.timestamp)",
+	"branch":  "COALESCE(NULLIF(s.git_branch, ''), 'unknown')",
+}
+
+// IsCostGrouping reports whether GetCostBreakdown can group costs by groupBy:
+// project, model, day or branch
+func IsCostGrouping(groupBy string) bool {
+	_, ok := costGroups[groupBy]
+	return ok
+}
+
+// CostBreakdownFilter narrows the token usage GetCostBreakdown adds up
+type CostBreakdownFilter struct {
+	From            time.Time
+	To              time.Time
+	Project         string // all projects when empty
+	IncludeArchived bool
+}
+
+// GetCostBreakdown returns the cost of the messages sent in [From, To) per
+// group, most expensive first. Sessions without a recorded git branch are
+// grouped under "unknown" by branch.
+func (r *SessionRepository) GetCostBreakdown(groupBy string, filter CostBreakdownFilter) ([]CostBreakdown, error) {
+	group, ok := costGroups[groupBy]
+	if !ok {
+		return nil, fmt.Errorf("unknown cost grouping: %s", groupBy)
+	}
+
+	breakdown := []CostBreakdown{}
+	err := r.db.Select(&breakdown, fmt.Sprintf(`
+		SELECT
+			%s AS name,
+			COUNT(DISTINCT s.id) AS sessions,
+			COUNT(DISTINCT m.id) AS messages,
+			COALESCE(SUM(tu.total_tokens), 0) AS total_tokens,
+			COALESCE(SUM(tu.cache_read_input_tokens), 0) AS cached_tokens,
+			COALESCE(SUM(tu.input_tokens + tu.output_tokens + tu.cache_creation_input_tokens), 0) AS fresh_tokens,
+			COALESCE(SUM(tu.estimated_cost), 0.0) AS cost_usd,
+			COALESCE(SUM(tu.cache_savings), 0.0) AS cache_savings
+		FROM token_usage tu
+		JOIN messages m ON m.id = tu.message_id
+		JOIN sessions s ON s.id = tu.session_id
+		WHERE m.timestamp >= ? AND m.timestamp < ?
+		AND (? = '' OR s.project_name = ?)
+		AND (? OR s.archived_at IS NULL)
+		GROUP BY name
+		ORDER BY cost_usd DESC, name ASC
+	`, group), filter.From.UTC(), filter.To.UTC(), filter.Project, filter.Project, filter.IncludeArchived)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cost breakdown: %w", err)
+	}
+	return breakdown, nil
+}
@@ -0,0 +1,68 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSessionRepository_GetCostBreakdownByBranch(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewSessionRepository(db, logger)
+	start := time.Date(2026, 9, 1, 12, 0, 0, 0, time.UTC)
+	sessions := []struct {
+		id, project, branch string
+		cost                float64
+	}{
+		{"s1", "app", "feature/login", 1.5},
+		{"s2", "app", "feature/login", 0.5},
+		{"s3", "app", "main", 0.25},
+		{"s4", "api", "", 3},
+		{"s5", "app", "feature/old", 9},
+	}
+	for _, s := range sessions {
+		if err := repo.UpsertSession(&Session{ID: s.id, ProjectPath: "/work/" + s.project, ProjectName: s.project, GitBranch: s.branch, StartTime: start, LastActivity: start, Status: "completed"}); err != nil {
+			t.Fatalf("Failed to create session: %v", err)
+		}
+		if err := repo.UpsertMessage(&Message{ID: s.id + "-m1", SessionID: s.id, Role: "assistant", Timestamp: start}); err != nil {
+			t.Fatalf("Failed to create message: %v", err)
+		}
+		if err := repo.UpsertTokenUsage(&TokenUsage{MessageID: s.id + "-m1", SessionID: s.id, InputTokens: 10, CacheReadInputTokens: 30, TotalTokens: 40, EstimatedCost: s.cost}); err != nil {
+			t.Fatalf("Failed to create token usage: %v", err)
+		}
+	}
+	if _, err := db.Exec(`UPDATE sessions SET archived_at = CURRENT_TIMESTAMP WHERE id = 's5'`); err != nil {
+		t.Fatalf("Failed to archive session: %v", err)
+	}
+
+	filter := CostBreakdownFilter{From: start.Add(-time.Hour), To: start.Add(time.Hour)}
+	breakdown, err := repo.GetCostBreakdown("branch", filter)
+	if err != nil {
+		t.Fatalf("Failed to get cost breakdown: %v", err)
+	}
+	if len(breakdown) != 3 {
+		t.Fatalf("Expected 3 branches without the archived session, got %+v", breakdown)
+	}
+	if breakdown[0].Name != "unknown" || breakdown[0].CostUSD != 3 {
+		t.Errorf("Expected sessions without a branch first as unknown, got %+v", breakdown[0])
+	}
+	login := breakdown[1]
+	if login.Name != "feature/login" || login.CostUSD != 2 || login.Sessions != 2 || login.CachedTokens != 60 || login.FreshTokens != 20 {
+		t.Errorf("Expected both feature/login sessions added up, got %+v", login)
+	}
+
+	filter.Project = "app"
+	filter.IncludeArchived = true
+	breakdown, err = repo.GetCostBreakdown("branch", filter)
+	if err != nil {
+		t.Fatalf("Failed to get cost breakdown: %v", err)
+	}
+	if len(breakdown) != 3 || breakdown[0].Name != "feature/old" {
+		t.Errorf("Expected app's branches including the archived one, got %+v", breakdown)
+	}
+
+	if _, err := repo.GetCostBreakdown("author", filter); err == nil {
+		t.Error("Expected an unknown grouping to fail")
+	}
+}
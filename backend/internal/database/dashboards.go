@@ -0,0 +1,122 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// CreateDashboard stores a new dashboard, assigning its ID and timestamps
+func (r *SessionRepository) CreateDashboard(dashboard *Dashboard) error {
+	now := time.Now().UTC()
+	dashboard.ID = uuid.New().String()
+	dashboard.CreatedAt = now
+	dashboard.UpdatedAt = now
+	if err := dashboard.encodeWidgets(); err != nil {
+		return err
+	}
+
+	err := r.db.WriteOperation(func(tx *sqlx.Tx) error {
+		_, err := tx.NamedExec(`
+			INSERT INTO dashboards (id, name, description, widgets, created_at, updated_at)
+			VALUES (:id, :name, :description, :widgets, :created_at, :updated_at)
+		`, dashboard)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create dashboard: %w", err)
+	}
+	return nil
+}
+
+// UpdateDashboard replaces a dashboard's name, description and widgets
+func (r *SessionRepository) UpdateDashboard(dashboard *Dashboard) error {
+	dashboard.UpdatedAt = time.Now().UTC()
+	if err := dashboard.encodeWidgets(); err != nil {
+		return err
+	}
+
+	return r.db.WriteOperation(func(tx *sqlx.Tx) error {
+		result, err := tx.NamedExec(`
+			UPDATE dashboards
+			SET name = :name, description = :description, widgets = :widgets, updated_at = :updated_at
+			WHERE id = :id
+		`, dashboard)
+		if err != nil {
+			return fmt.Errorf("failed to update dashboard: %w", err)
+		}
+		if rows, _ := result.RowsAffected(); rows == 0 {
+			return fmt.Errorf("dashboard not found: %s", dashboard.ID)
+		}
+		return nil
+	})
+}
+
+// GetDashboards returns every dashboard, by name
+func (r *SessionRepository) GetDashboards() ([]Dashboard, error) {
+	dashboards := []Dashboard{}
+	if err := r.db.Select(&dashboards, `SELECT * FROM dashboards ORDER BY name, id`); err != nil {
+		return nil, fmt.Errorf("failed to get dashboards: %w", err)
+	}
+	for i := range dashboards {
+		if err := dashboards[i].decodeWidgets(); err != nil {
+			return nil, err
+		}
+	}
+	return dashboards, nil
+}
+
+// GetDashboard returns a dashboard by ID
+func (r *SessionRepository) GetDashboard(id string) (*Dashboard, error) {
+	var dashboard Dashboard
+	if err := r.db.Get(&dashboard, `SELECT * FROM dashboards WHERE id = ?`, id); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("dashboard not found: %s", id)
+		}
+		return nil, fmt.Errorf("failed to get dashboard: %w", err)
+	}
+	if err := dashboard.decodeWidgets(); err != nil {
+		return nil, err
+	}
+	return &dashboard, nil
+}
+
+// DeleteDashboard removes a dashboard
+func (r *SessionRepository) DeleteDashboard(id string) error {
+	return r.db.WriteOperation(func(tx *sqlx.Tx) error {
+		result, err := tx.Exec(`DELETE FROM dashboards WHERE id = ?`, id)
+		if err != nil {
+			return fmt.Errorf("failed to delete dashboard: %w", err)
+		}
+		if rows, _ := result.RowsAffected(); rows == 0 {
+			return fmt.Errorf("dashboard not found: %s", id)
+		}
+		return nil
+	})
+}
+
+// encodeWidgets fills the stored widgets column from Widgets
+func (d *Dashboard) encodeWidgets() error {
+	if d.Widgets == nil {
+		d.Widgets = []DashboardWidget{}
+	}
+	data, err := json.Marshal(d.Widgets)
+	if err != nil {
+		return fmt.Errorf("failed to encode dashboard widgets: %w", err)
+	}
+	d.WidgetsJSON = string(data)
+	return nil
+}
+
+// decodeWidgets fills Widgets from the stored widgets column
+func (d *Dashboard) decodeWidgets() error {
+	d.Widgets = []DashboardWidget{}
+	if err := json.Unmarshal([]byte(d.WidgetsJSON), &d.Widgets); err != nil {
+		return fmt.Errorf("failed to decode dashboard widgets: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package database
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSessionRepository_Dashboards(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewSessionRepository(db, logger)
+
+	dashboard := &Dashboard{
+		Name: "Team costs",
+		Widgets: []DashboardWidget{{
+			ID:       "spend",
+			Title:    "Daily spend",
+			Type:     "line",
+			Endpoint: "/api/v1/analytics/costs/daily",
+			Params:   map[string]string{"days": "30"},
+			Layout:   DashboardLayout{W: 6, H: 4},
+			Options:  []byte(`{"color":"#f80"}`),
+		}},
+	}
+	if err := repo.CreateDashboard(dashboard); err != nil {
+		t.Fatalf("Failed to create dashboard: %v", err)
+	}
+
+	got, err := repo.GetDashboard(dashboard.ID)
+	if err != nil {
+		t.Fatalf("Failed to get dashboard: %v", err)
+	}
+	if len(got.Widgets) != 1 || got.Widgets[0].Params["days"] != "30" || got.Widgets[0].Layout.W != 6 || string(got.Widgets[0].Options) != `{"color":"#f80"}` {
+		t.Fatalf("Expected the dashboard with its widget, got %+v", got)
+	}
+
+	if err := repo.CreateDashboard(&Dashboard{Name: "Team costs"}); err == nil || !strings.Contains(err.Error(), "UNIQUE constraint failed") {
+		t.Errorf("Expected a duplicate name to fail, got %v", err)
+	}
+
+	got.Name = "Costs"
+	got.Widgets = nil
+	if err := repo.UpdateDashboard(got); err != nil {
+		t.Fatalf("Failed to update dashboard: %v", err)
+	}
+	dashboards, err := repo.GetDashboards()
+	if err != nil {
+		t.Fatalf("Failed to get dashboards: %v", err)
+	}
+	if len(dashboards) != 1 || dashboards[0].Name != "Costs" || dashboards[0].Widgets == nil || len(dashboards[0].Widgets) != 0 {
+		t.Errorf("Expected the renamed dashboard without widgets, got %+v", dashboards)
+	}
+
+	if err := repo.DeleteDashboard(dashboard.ID); err != nil {
+		t.Fatalf("Failed to delete dashboard: %v", err)
+	}
+	if _, err := repo.GetDashboard(dashboard.ID); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("Expected the deleted dashboard not found, got %v", err)
+	}
+}
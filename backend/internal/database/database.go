@@ -18,17 +18,31 @@ import (
 //go:embed schema.sql
 var schemaFiles embed.FS
 
-// Database represents the SQLite database connection
+// Database represents the SQLite database connection. The embedded DB is the
+// single writer connection; Select, Get and Query read through reader.
 type Database struct {
 	*sqlx.DB
-	logger     *logrus.Logger
-	writeMutex sync.Mutex // Serializes all write operations to prevent database corruption
+	reader       *sqlx.DB // Read-only connection pool
+	logger       *logrus.Logger
+	writeMutex   sync.Mutex // Serializes all write operations to prevent database corruption
+	maxLineBytes int        // Longest JSONL line importers buffer whole
+	observer     Observer   // Told about query timings and imports, if set
+	openedAt     time.Time // Import runs still running from before this belong to a process that is gone
+
+	stopCheckpoints chan struct{}
+	checkpointsDone chan struct{}
+	closeOnce       sync.Once
 }
 
 // Config represents database configuration
 type Config struct {
 	DatabasePath string
 	Logger       *logrus.Logger
+	MaxLineBytes int // Defaults to DefaultMaxLineBytes
+	// SkipMigrations leaves pending migrations for the caller to apply with
+	// MigrateUp, as the migrate command does
+	SkipMigrations bool
+	Tuning         *Tuning // Defaults to DefaultTuning()
 }
 
 // NewDatabase creates a new database connection and runs migrations
@@ -39,22 +53,24 @@ func NewDatabase(config Config) (*Database, error) {
 		return nil, fmt.Errorf("failed to create database directory: %w", err)
 	}
 
-	// Open SQLite database with better concurrency settings
-	dsn := config.DatabasePath + "?_journal_mode=WAL&_timeout=30000&_foreign_keys=on&_busy_timeout=30000&_synchronous=NORMAL&_cache_size=10000"
-	db, err := sqlx.Connect("sqlite3", dsn)
+	tuning := DefaultTuning()
+	if config.Tuning != nil {
+		tuning = *config.Tuning
+	}
+	if tuning.ReadConnections < 1 {
+		tuning.ReadConnections = 1
+	}
+
+	db, err := openWriter(config.DatabasePath, tuning)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
 
-	// Configure connection pool for better concurrency
-	// SQLite with WAL mode can handle multiple readers + 1 writer
-	db.SetMaxOpenConns(10)           // Allow multiple concurrent read operations
-	db.SetMaxIdleConns(5)            // Keep some connections ready
-	db.SetConnMaxLifetime(time.Hour) // Recycle connections hourly
-
 	database := &Database{
-		DB:     db,
-		logger: config.Logger,
+		DB:           db,
+		logger:       config.Logger,
+		maxLineBytes: config.MaxLineBytes,
+		openedAt:     time.Now().UTC(),
 	}
 
 	// Check database integrity
@@ -67,26 +83,68 @@ func NewDatabase(config Config) (*Database, error) {
 		}
 		// Reconnect after repair
 		db.Close()
-		dsn := config.DatabasePath + "?_journal_mode=WAL&_timeout=30000&_foreign_keys=on&_busy_timeout=30000&_synchronous=NORMAL&_cache_size=10000"
-		db, err = sqlx.Connect("sqlite3", dsn)
+		db, err = openWriter(config.DatabasePath, tuning)
 		if err != nil {
 			return nil, fmt.Errorf("failed to reconnect after repair: %w", err)
 		}
 		database.DB = db
 	}
 
+	database.reader, err = openReader(config.DatabasePath, tuning)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to open read connections: %w", err)
+	}
+
+	fresh, err := database.isNewDatabase()
+	if err != nil {
+		database.Close()
+		return nil, err
+	}
+	legacy, err := database.predatesMigrator()
+	if err != nil {
+		database.Close()
+		return nil, err
+	}
+
 	// Run migrations
 	if err := database.migrate(); err != nil {
-		db.Close()
+		database.Close()
 		return nil, fmt.Errorf("failed to run migrations: %w", err)
 	}
 
 	// Apply schema updates for existing tables
 	if err := database.applySchemaUpdates(); err != nil {
-		db.Close()
+		database.Close()
 		return nil, fmt.Errorf("failed to apply schema updates: %w", err)
 	}
 
+	// A new database already has the latest schema, so every migration is
+	// recorded as applied rather than run. One from before the migrator is
+	// recorded as having had the fixes that were run by hand.
+	stamped := manualMigrations()
+	if fresh {
+		stamped = registeredMigrations()
+	}
+	if fresh || legacy {
+		if err := database.stampMigrations(stamped); err != nil {
+			database.Close()
+			return nil, fmt.Errorf("failed to record migrations: %w", err)
+		}
+	}
+	if !fresh && !config.SkipMigrations {
+		if _, err := database.MigrateUp(); err != nil {
+			database.Close()
+			return nil, fmt.Errorf("failed to apply migrations: %w", err)
+		}
+	}
+
+	if tuning.CheckpointInterval > 0 {
+		database.stopCheckpoints = make(chan struct{})
+		database.checkpointsDone = make(chan struct{})
+		go database.checkpointLoop(tuning.CheckpointInterval)
+	}
+
 	database.logger.WithField("path", config.DatabasePath).Info("Database initialized successfully")
 	return database, nil
 }
@@ -109,87 +167,162 @@ func (db *Database) migrate() error {
 
 // applySchemaUpdates applies incremental schema updates for existing tables
 func (db *Database) applySchemaUpdates() error {
-	// Check if file_watchers table exists
-	var tableExists bool
-	err := db.Get(&tableExists, `
-		SELECT COUNT(*) > 0 
-		FROM sqlite_master 
-		WHERE type='table' AND name='file_watchers'
-	`)
-	if err != nil {
-		return fmt.Errorf("failed to check if file_watchers table exists: %w", err)
-	}
-
-	// If table doesn't exist, schema.sql will create it
-	if !tableExists {
-		return nil
-	}
-
-	// List of columns to check and add if missing
+	// List of columns to check and add if missing. schema.sql has already run,
+	// so every table listed here exists.
 	columnsToCheck := []struct {
+		table        string
 		name         string
 		definition   string
 		defaultValue string
 	}{
 		{
+			table:        "file_watchers",
 			name:         "import_status",
 			definition:   "TEXT DEFAULT 'pending'",
 			defaultValue: "'pending'",
 		},
 		{
+			table:        "file_watchers",
 			name:         "sessions_imported",
 			definition:   "INTEGER DEFAULT 0",
 			defaultValue: "0",
 		},
 		{
+			table:        "file_watchers",
 			name:         "messages_imported",
 			definition:   "INTEGER DEFAULT 0",
 			defaultValue: "0",
 		},
 		{
+			table:        "file_watchers",
 			name:         "last_error",
 			definition:   "TEXT",
 			defaultValue: "NULL",
 		},
 		{
+			table:        "file_watchers",
 			name:         "last_processed_position",
 			definition:   "INTEGER DEFAULT 0",
 			defaultValue: "0",
 		},
+		{
+			table:        "import_runs",
+			name:         "resumed_from_run_id",
+			definition:   "INTEGER",
+			defaultValue: "NULL",
+		},
+		{
+			table:        "import_runs",
+			name:         "files_recovered",
+			definition:   "INTEGER DEFAULT 0",
+			defaultValue: "0",
+		},
+		{
+			table:        "sessions",
+			name:         "source",
+			definition:   "TEXT DEFAULT 'import'",
+			defaultValue: "'import'",
+		},
+		{
+			table:        "sessions",
+			name:         "git_remote",
+			definition:   "TEXT DEFAULT ''",
+			defaultValue: "''",
+		},
+		{
+			table:        "sessions",
+			name:         "archived_at",
+			definition:   "DATETIME",
+			defaultValue: "NULL",
+		},
+		{
+			table:        "token_usage",
+			name:         "cache_savings",
+			definition:   "REAL DEFAULT 0.0",
+			defaultValue: "0.0",
+		},
+		{
+			table:        "token_usage",
+			name:         "model",
+			definition:   "TEXT DEFAULT ''",
+			defaultValue: "''",
+		},
+		{
+			table:        "playbook_runs",
+			name:         "diff",
+			definition:   "TEXT",
+			defaultValue: "NULL",
+		},
+		{
+			table:        "playbook_runs",
+			name:         "schedule_id",
+			definition:   "TEXT",
+			defaultValue: "NULL",
+		},
+		{
+			table:        "playbook_run_steps",
+			name:         "proposal",
+			definition:   "TEXT",
+			defaultValue: "NULL",
+		},
+		{
+			table:        "playbook_run_steps",
+			name:         "reviewed_by",
+			definition:   "TEXT",
+			defaultValue: "NULL",
+		},
+		{
+			table:        "playbook_run_steps",
+			name:         "reviewed_at",
+			definition:   "DATETIME",
+			defaultValue: "NULL",
+		},
+		{
+			table:        "chat_sessions",
+			name:         "claude_session_id",
+			definition:   "TEXT",
+			defaultValue: "NULL",
+		},
+		{
+			table:        "tool_results",
+			name:         "absolute_path",
+			definition:   "TEXT",
+			defaultValue: "NULL",
+		},
 	}
 
 	// Check and add each column if it doesn't exist
 	for _, col := range columnsToCheck {
 		var columnExists bool
-		err = db.Get(&columnExists, `
+		err := db.Get(&columnExists, `
 			SELECT COUNT(*) > 0 
-			FROM pragma_table_info('file_watchers') 
+			FROM pragma_table_info(?) 
 			WHERE name = ?
-		`, col.name)
+		`, col.table, col.name)
 		if err != nil {
-			return fmt.Errorf("failed to check for %s column: %w", col.name, err)
+			return fmt.Errorf("failed to check for %s.%s column: %w", col.table, col.name, err)
 		}
 
 		// If column doesn't exist, add it
 		if !columnExists {
-			db.logger.Infof("Adding missing %s column to file_watchers table", col.name)
+			db.logger.Infof("Adding missing %s column to %s table", col.name, col.table)
 
 			_, err = db.Exec(fmt.Sprintf(`
-				ALTER TABLE file_watchers ADD COLUMN %s %s
-			`, col.name, col.definition))
+				ALTER TABLE %s ADD COLUMN %s %s
+			`, col.table, col.name, col.definition))
 			if err != nil {
-				return fmt.Errorf("failed to add %s column: %w", col.name, err)
+				return fmt.Errorf("failed to add %s.%s column: %w", col.table, col.name, err)
 			}
 
 			// Update any existing rows
 			_, err = db.Exec(fmt.Sprintf(`
-				UPDATE file_watchers SET %s = %s WHERE %s IS NULL
-			`, col.name, col.defaultValue, col.name))
+				UPDATE %s SET %s = %s WHERE %s IS NULL
+			`, col.table, col.name, col.defaultValue, col.name))
 			if err != nil {
-				return fmt.Errorf("failed to update %s values: %w", col.name, err)
+				return fmt.Errorf("failed to update %s.%s values: %w", col.table, col.name, err)
 			}
 
-			db.logger.Infof("Successfully added %s column to file_watchers table", col.name)
+			db.logger.Infof("Successfully added %s column to %s table", col.name, col.table)
 		}
 	}
 
@@ -315,9 +448,24 @@ func (db *Database) CheckForMissedFiles(claudeDir string) (int, error) {
 	return missedFiles, nil
 }
 
-// Close closes the database connection
+// Close stops WAL checkpoints, checkpoints the WAL a last time and closes the
+// database connections
 func (db *Database) Close() error {
-	return db.DB.Close()
+	var err error
+	db.closeOnce.Do(func() {
+		if db.stopCheckpoints != nil {
+			close(db.stopCheckpoints)
+			<-db.checkpointsDone
+		}
+		if db.reader != nil {
+			db.reader.Close()
+			if _, checkpointErr := db.Checkpoint(); checkpointErr != nil {
+				db.logger.WithError(checkpointErr).Warn("Final WAL checkpoint failed")
+			}
+		}
+		err = db.DB.Close()
+	})
+	return err
 }
 
 // WriteOperation executes a write operation within a serialized transaction
@@ -332,6 +480,7 @@ func (db *Database) WriteOperation(fn func(*sqlx.Tx) error) error {
 // Transaction executes a function within a database transaction
 // WARNING: For write operations, use WriteOperation() instead to ensure serialization
 func (db *Database) Transaction(fn func(*sqlx.Tx) error) error {
+	defer db.observeQuery("transaction", time.Now())
 	tx, err := db.Beginx()
 	if err != nil {
 		return err
@@ -0,0 +1,74 @@
+package database
+
+import (
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/sirupsen/logrus"
+)
+
+// RecordDeadLetter records a line an import couldn't read as usual, counting
+// another occurrence if the line has been recorded before
+func (r *SessionRepository) RecordDeadLetter(letter *DeadLetter) error {
+	return r.db.WriteOperation(func(tx *sqlx.Tx) error {
+		_, err := tx.Exec(`
+			INSERT INTO import_dead_letters (file_path, byte_offset, byte_size, reason, outcome, error)
+			VALUES (?, ?, ?, ?, ?, ?)
+			ON CONFLICT(file_path, byte_offset) DO UPDATE SET
+				byte_size = excluded.byte_size,
+				reason = excluded.reason,
+				outcome = excluded.outcome,
+				error = excluded.error,
+				occurrences = import_dead_letters.occurrences + 1,
+				last_seen = CURRENT_TIMESTAMP
+		`, letter.FilePath, letter.ByteOffset, letter.ByteSize, letter.Reason, letter.Outcome, letter.Error)
+		if err != nil {
+			return fmt.Errorf("failed to record dead letter: %w", err)
+		}
+		return nil
+	})
+}
+
+// GetDeadLetters returns recorded dead letters, optionally of one outcome,
+// most recently seen first
+func (r *SessionRepository) GetDeadLetters(outcome string, limit int) ([]DeadLetter, error) {
+	letters := []DeadLetter{}
+	err := r.db.Select(&letters, `
+		SELECT * FROM import_dead_letters
+		WHERE (? = '' OR outcome = ?)
+		ORDER BY last_seen DESC, id DESC
+		LIMIT ?
+	`, outcome, outcome, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dead letters: %w", err)
+	}
+	return letters, nil
+}
+
+// recordOversizedLine records a line over the line size limit and whether it
+// could still be decoded
+func (r *SessionRepository) recordOversizedLine(filePath string, offset, size int64, decodeErr error) {
+	letter := &DeadLetter{
+		FilePath:   filePath,
+		ByteOffset: offset,
+		ByteSize:   size,
+		Reason:     DeadLetterOversizedLine,
+		Outcome:    DeadLetterDecoded,
+	}
+	if decodeErr != nil {
+		message := decodeErr.Error()
+		letter.Outcome = DeadLetterSkipped
+		letter.Error = &message
+	}
+
+	r.logger.WithFields(logrus.Fields{
+		"file":    filePath,
+		"offset":  offset,
+		"size":    size,
+		"outcome": letter.Outcome,
+	}).Warn("JSONL line exceeds the line size limit")
+
+	if err := r.RecordDeadLetter(letter); err != nil {
+		r.logger.WithError(err).WithField("file", filePath).Error("Failed to record oversized line")
+	}
+}
@@ -0,0 +1,156 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// GetFileSessions returns the sessions whose tool calls touched a file, most
+// recently touched first. The path matches as in GetFileChanges, so a file
+// is found at its current location after its repository moved.
+func (r *SessionRepository) GetFileSessions(filePath string, limit int) ([]FileSession, error) {
+	condition, args := filePathCondition(filePath, false)
+	sessions := []FileSession{}
+	err := r.db.Select(&sessions, `
+		SELECT
+			s.id AS session_id,
+			s.project_name,
+			s.git_branch,
+			s.is_active,
+			COUNT(*) AS touches,
+			COALESCE(GROUP_CONCAT(DISTINCT tr.tool_name), '') AS tools,
+			latest.timestamp AS last_touched
+		FROM tool_results tr
+		JOIN sessions s ON s.id = tr.session_id
+		JOIN tool_results latest ON latest.id = (
+			SELECT id FROM tool_results
+			WHERE session_id = tr.session_id AND file_path = tr.file_path
+			ORDER BY timestamp DESC
+			LIMIT 1
+		)
+		WHERE `+condition+`
+		GROUP BY s.id
+		ORDER BY latest.timestamp DESC
+		LIMIT ?
+	`, append(args, limit)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get file sessions: %w", err)
+	}
+	return sessions, nil
+}
+
+// GetWorkspaceUsage returns the usage since dayStart of sessions run in a
+// workspace directory or below it, and how many of them are active. An empty
+// workspace covers every session.
+func (r *SessionRepository) GetWorkspaceUsage(workspace string, dayStart time.Time) (*WorkspaceUsage, error) {
+	scope, args := workspaceScope(workspace)
+
+	var usage WorkspaceUsage
+	err := r.db.Get(&usage, `
+		SELECT
+			COUNT(DISTINCT m.session_id) AS today_sessions,
+			COALESCE(SUM(tu.total_tokens), 0) AS today_tokens,
+			COALESCE(SUM(tu.estimated_cost), 0.0) AS today_cost_usd
+		FROM messages m
+		JOIN token_usage tu ON tu.message_id = m.id
+		JOIN sessions s ON s.id = m.session_id
+		WHERE m.timestamp >= ? AND `+scope, append([]interface{}{dayStart}, args...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get workspace usage: %w", err)
+	}
+
+	err = r.db.Get(&usage.ActiveSessions, `
+		SELECT COUNT(*) FROM sessions s WHERE s.is_active = 1 AND `+scope, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count active workspace sessions: %w", err)
+	}
+
+	// Selected directly rather than with MAX so SQLite returns a DATETIME
+	var lastActivity time.Time
+	err = r.db.Get(&lastActivity, `
+		SELECT s.last_activity FROM sessions s WHERE `+scope+`
+		ORDER BY s.last_activity DESC
+		LIMIT 1
+	`, args...)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to get workspace last activity: %w", err)
+	}
+	if err == nil {
+		usage.LastActivity = &lastActivity
+	}
+	return &usage, nil
+}
+
+// workspaceScope returns a condition on sessions aliased s matching a
+// workspace directory and its subdirectories. It compares prefixes rather
+// than using LIKE so paths containing % or _ match literally; substr counts
+// characters, not bytes.
+func workspaceScope(workspace string) (string, []interface{}) {
+	workspace = strings.TrimRight(workspace, "/")
+	if workspace == "" {
+		return "1 = 1", nil
+	}
+	return "(s.project_path = ? OR substr(s.project_path, 1, ?) = ?)",
+		[]interface{}{workspace, utf8.RuneCountInString(workspace) + 1, workspace + "/"}
+}
+
+// filePathCondition matches tool results for filePath, as recorded relative
+// to the working directory or absolute, exactly or, unless exact is set, as
+// a suffix at a directory boundary. A relative path also matches a filePath
+// ending in the session's project directory and the path, so an absolute
+// path finds a file's changes from before its repository moved.
+func filePathCondition(filePath string, exact bool) (string, []interface{}) {
+	if exact {
+		return "(tr.file_path = ? OR tr.absolute_path = ?)", []interface{}{filePath, filePath}
+	}
+	suffix := "/" + strings.TrimLeft(filePath, "/")
+	length := utf8.RuneCountInString(suffix)
+	return `(tr.file_path = ? OR tr.absolute_path = ?
+		OR substr(tr.file_path, -?) = ? OR substr(tr.absolute_path, -?) = ?
+		OR (substr(tr.file_path, 1, 1) != '/' AND EXISTS (
+			SELECT 1 FROM sessions ps
+			WHERE ps.id = tr.session_id
+			AND substr(?, -length(ps.project_name || '/' || tr.file_path) - 1) = '/' || ps.project_name || '/' || tr.file_path
+		)))`,
+		[]interface{}{filePath, filePath, length, suffix, length, suffix, filePath}
+}
+
+// GetFileChanges returns the tool calls that modified a file, most recent
+// first, and how many there are in total. Unless exact is set, path also
+// matches files it is a suffix of at a directory boundary, so a path relative
+// to the repository finds changes recorded with absolute paths.
+func (r *SessionRepository) GetFileChanges(filePath string, exact bool, limit int) ([]FileChange, int, error) {
+	condition, args := filePathCondition(filePath, exact)
+	condition += " AND tr.tool_name IN ('Edit', 'Write', 'MultiEdit', 'NotebookEdit', 'NotebookWrite')"
+
+	var total int
+	err := r.db.Get(&total, `SELECT COUNT(*) FROM tool_results tr WHERE `+condition, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count file changes: %w", err)
+	}
+
+	changes := []FileChange{}
+	err = r.db.Select(&changes, `
+		SELECT
+			tr.file_path,
+			tr.absolute_path,
+			tr.session_id,
+			s.project_name,
+			s.git_branch,
+			tr.message_id,
+			tr.tool_name,
+			tr.timestamp
+		FROM tool_results tr
+		JOIN sessions s ON s.id = tr.session_id
+		WHERE `+condition+`
+		ORDER BY tr.timestamp DESC
+		LIMIT ?
+	`, append(args, limit)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get file changes: %w", err)
+	}
+	return changes, total, nil
+}
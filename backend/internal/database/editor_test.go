@@ -0,0 +1,292 @@
+package database
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSessionRepository_EditorQueries(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewSessionRepository(db, logger)
+
+	now := time.Now()
+	dayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	for _, s := range []struct {
+		id, path string
+		active   bool
+	}{
+		{"s1", "/work/app", true},
+		{"s2", "/work/app/api", false},
+		{"s3", "/work/app_old", false},
+	} {
+		if err := repo.UpsertSession(&Session{
+			ID:           s.id,
+			ProjectPath:  s.path,
+			ProjectName:  "app",
+			StartTime:    dayStart.Add(time.Minute),
+			LastActivity: dayStart.Add(time.Hour),
+			IsActive:     s.active,
+			Status:       "completed",
+		}); err != nil {
+			t.Fatalf("Failed to create session: %v", err)
+		}
+		if err := repo.UpsertMessage(&Message{ID: s.id + "-1", SessionID: s.id, Role: "assistant", Timestamp: dayStart.Add(time.Minute)}); err != nil {
+			t.Fatalf("Failed to create message: %v", err)
+		}
+		if err := repo.UpsertTokenUsage(&TokenUsage{MessageID: s.id + "-1", SessionID: s.id, TotalTokens: 100, EstimatedCost: 1.5}); err != nil {
+			t.Fatalf("Failed to create token usage: %v", err)
+		}
+	}
+
+	file := "/work/app/main.go"
+	for i, tr := range []struct {
+		session, tool string
+		at            time.Duration
+	}{
+		{"s1", "Read", time.Minute},
+		{"s1", "Edit", 2 * time.Minute},
+		{"s2", "Edit", 3 * time.Minute},
+	} {
+		if err := repo.UpsertToolResult(&ToolResult{
+			MessageID: tr.session + "-1",
+			SessionID: tr.session,
+			ToolName:  tr.tool,
+			FilePath:  &file,
+			Timestamp: dayStart.Add(tr.at + time.Duration(i)),
+		}); err != nil {
+			t.Fatalf("Failed to create tool result: %v", err)
+		}
+	}
+
+	sessions, err := repo.GetFileSessions(file, 10)
+	if err != nil {
+		t.Fatalf("Failed to get file sessions: %v", err)
+	}
+	if len(sessions) != 2 || sessions[0].SessionID != "s2" || sessions[1].SessionID != "s1" {
+		t.Fatalf("Expected s2 then s1, got %+v", sessions)
+	}
+	if sessions[1].Touches != 2 || !sessions[1].IsActive || !sessions[1].LastTouched.Equal(dayStart.Add(2*time.Minute+1)) {
+		t.Errorf("Unexpected s1 entry: %+v", sessions[1])
+	}
+	if none, _ := repo.GetFileSessions("/work/other.go", 10); len(none) != 0 {
+		t.Errorf("Expected no sessions for an untouched file, got %+v", none)
+	}
+
+	// The workspace covers /work/app and /work/app/api but not /work/app_old
+	usage, err := repo.GetWorkspaceUsage("/work/app/", dayStart)
+	if err != nil {
+		t.Fatalf("Failed to get workspace usage: %v", err)
+	}
+	if usage.TodaySessions != 2 || usage.TodayTokens != 200 || usage.TodayCostUSD != 3.0 || usage.ActiveSessions != 1 {
+		t.Errorf("Unexpected workspace usage: %+v", usage)
+	}
+	if usage.LastActivity == nil || !usage.LastActivity.Equal(dayStart.Add(time.Hour)) {
+		t.Errorf("Expected last activity %v, got %v", dayStart.Add(time.Hour), usage.LastActivity)
+	}
+
+	if all, _ := repo.GetWorkspaceUsage("", dayStart); all.TodaySessions != 3 {
+		t.Errorf("Expected every session without a workspace, got %+v", all)
+	}
+	empty, err := repo.GetWorkspaceUsage("/elsewhere", dayStart)
+	if err != nil || empty.TodaySessions != 0 || empty.LastActivity != nil {
+		t.Errorf("Expected no usage for an unknown workspace, got %+v (%v)", empty, err)
+	}
+}
+
+func TestSessionRepository_GetFileChanges(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewSessionRepository(db, logger)
+
+	now := time.Now()
+	for _, id := range []string{"s1", "s2"} {
+		if err := repo.UpsertSession(&Session{
+			ID:           id,
+			ProjectPath:  "/work/app",
+			ProjectName:  "app",
+			StartTime:    now,
+			LastActivity: now,
+			Status:       "completed",
+		}); err != nil {
+			t.Fatalf("Failed to create session: %v", err)
+		}
+		if err := repo.UpsertMessage(&Message{ID: id + "-1", SessionID: id, Role: "assistant", Timestamp: now}); err != nil {
+			t.Fatalf("Failed to create message: %v", err)
+		}
+	}
+
+	for i, tr := range []struct {
+		session, tool, path string
+	}{
+		{"s1", "Edit", "/work/app/internal/api/server.go"},
+		{"s1", "Read", "/work/app/internal/api/server.go"},
+		{"s2", "Write", "/work/app/internal/api/server.go"},
+		{"s2", "Edit", "/work/app/cmd/server.go"},
+		{"s2", "Edit", "/work/app/internal/api/myserver.go"},
+	} {
+		path := tr.path
+		if err := repo.UpsertToolResult(&ToolResult{
+			MessageID: tr.session + "-1",
+			SessionID: tr.session,
+			ToolName:  tr.tool,
+			FilePath:  &path,
+			Timestamp: now.Add(time.Duration(i) * time.Minute),
+		}); err != nil {
+			t.Fatalf("Failed to create tool result: %v", err)
+		}
+	}
+
+	changes, total, err := repo.GetFileChanges("/work/app/internal/api/server.go", true, 10)
+	if err != nil {
+		t.Fatalf("Failed to get file changes: %v", err)
+	}
+	if total != 2 || len(changes) != 2 || changes[0].SessionID != "s2" || changes[0].ToolName != "Write" || changes[1].ToolName != "Edit" {
+		t.Fatalf("Expected the Write then the Edit (not the Read), got %d %+v", total, changes)
+	}
+	if changes[0].MessageID != "s2-1" || changes[0].ProjectName != "app" {
+		t.Errorf("Unexpected change: %+v", changes[0])
+	}
+
+	// A relative path matches at a directory boundary, so not myserver.go
+	if _, total, _ := repo.GetFileChanges("internal/api/server.go", false, 10); total != 2 {
+		t.Errorf("Expected 2 changes for a relative path, got %d", total)
+	}
+	if changes, total, _ := repo.GetFileChanges("server.go", false, 1); total != 3 || len(changes) != 1 || changes[0].FilePath != "/work/app/cmd/server.go" {
+		t.Errorf("Expected 3 changes across both server.go files, limited to 1, got %d %+v", total, changes)
+	}
+	if _, total, _ := repo.GetFileChanges("internal/api/server.go", true, 10); total != 0 {
+		t.Errorf("Expected no exact match for a relative path, got %d", total)
+	}
+}
+
+func TestSessionRepository_GetFileHistory(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewSessionRepository(db, logger)
+
+	start := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	for _, id := range []string{"s1", "s2"} {
+		if err := repo.UpsertSession(&Session{ID: id, ProjectPath: "/work/app", ProjectName: "app", StartTime: start, LastActivity: start, Status: "completed"}); err != nil {
+			t.Fatalf("Failed to create session: %v", err)
+		}
+		if err := repo.UpsertMessage(&Message{ID: id + "-1", SessionID: id, Role: "assistant", Timestamp: start}); err != nil {
+			t.Fatalf("Failed to create message: %v", err)
+		}
+	}
+
+	file := "/work/app/main.go"
+	for i, tr := range []struct {
+		session, tool, data string
+	}{
+		{"s1", "Write", `{"tool_name":"Write","parameters":{"file_path":"/work/app/main.go","content":"package main\n"}}`},
+		{"s1", "Read", `{"tool_name":"Read","parameters":{"file_path":"/work/app/main.go"}}`},
+		{"s2", "Edit", `{"tool_name":"Edit","parameters":{"file_path":"/work/app/main.go","old_string":"package main\n","new_string":"package app\n"}}`},
+		{"s2", "MultiEdit", `{"tool_name":"MultiEdit","parameters":{"file_path":"/work/app/main.go","edits":[{"old_string":"a\n","new_string":"b\n"},{"old_string":"c\n","new_string":"d\n"}]}}`},
+	} {
+		if err := repo.UpsertToolResult(&ToolResult{
+			MessageID:  tr.session + "-1",
+			SessionID:  tr.session,
+			ToolName:   tr.tool,
+			FilePath:   &file,
+			ResultData: tr.data,
+			Timestamp:  start.Add(time.Duration(i) * time.Minute),
+		}); err != nil {
+			t.Fatalf("Failed to create tool result: %v", err)
+		}
+	}
+
+	edits, total, err := repo.GetFileHistory("main.go", false, 2)
+	if err != nil {
+		t.Fatalf("Failed to get file history: %v", err)
+	}
+	if total != 3 || len(edits) != 2 {
+		t.Fatalf("Expected the 2 most recent of 3 edits, got %d of %d", len(edits), total)
+	}
+	if edits[0].ToolName != "Edit" || edits[1].ToolName != "MultiEdit" {
+		t.Errorf("Expected edits oldest first, got %s then %s", edits[0].ToolName, edits[1].ToolName)
+	}
+	if !strings.Contains(edits[0].Diff, "-package main\n+package app\n") {
+		t.Errorf("Expected the edit's diff, got %q", edits[0].Diff)
+	}
+	if strings.Count(edits[1].Diff, "@@ ") != 2 || !strings.Contains(edits[1].Diff, "+d\n") {
+		t.Errorf("Expected a hunk per edit, got %q", edits[1].Diff)
+	}
+
+	edits, _, err = repo.GetFileHistory(file, true, 10)
+	if err != nil {
+		t.Fatalf("Failed to get file history: %v", err)
+	}
+	if len(edits) != 3 || !strings.Contains(edits[0].Diff, "+++ b/work/app/main.go") || !strings.Contains(edits[0].Diff, "+package main\n") {
+		t.Errorf("Expected the write to add the whole content, got %+v", edits)
+	}
+}
+
+func TestSessionRepository_GetLineCosts(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewSessionRepository(db, logger)
+
+	jan := time.Date(2025, 1, 10, 10, 0, 0, 0, time.UTC)
+	feb := time.Date(2025, 2, 10, 10, 0, 0, 0, time.UTC)
+	for _, s := range []struct {
+		id, project string
+		at          time.Time
+		cost        float64
+	}{
+		{"s1", "app", jan, 2.0},
+		{"s2", "app", feb, 1.0},
+		{"s3", "docs", feb, 0.5},
+	} {
+		if err := repo.UpsertSession(&Session{ID: s.id, ProjectPath: "/work/" + s.project, ProjectName: s.project, StartTime: s.at, LastActivity: s.at, Status: "completed"}); err != nil {
+			t.Fatalf("Failed to create session: %v", err)
+		}
+		if err := repo.UpsertMessage(&Message{ID: s.id + "-1", SessionID: s.id, Role: "assistant", Timestamp: s.at}); err != nil {
+			t.Fatalf("Failed to create message: %v", err)
+		}
+		if err := repo.UpsertTokenUsage(&TokenUsage{MessageID: s.id + "-1", SessionID: s.id, InputTokens: 100, TotalTokens: 100, EstimatedCost: s.cost}); err != nil {
+			t.Fatalf("Failed to create token usage: %v", err)
+		}
+	}
+
+	file := "/work/app/main.go"
+	for _, tr := range []struct {
+		session, tool, data string
+		at                  time.Time
+	}{
+		{"s1", "Write", `{"parameters":{"content":"package main\n\nfunc main() {}\n"}}`, jan},
+		{"s1", "Read", `{"parameters":{}}`, jan},
+		{"s2", "Edit", `{"parameters":{"old_string":"a\nb\n","new_string":"a\nc\nd\n"}}`, feb},
+	} {
+		if err := repo.UpsertToolResult(&ToolResult{MessageID: tr.session + "-1", SessionID: tr.session, ToolName: tr.tool, FilePath: &file, ResultData: tr.data, Timestamp: tr.at}); err != nil {
+			t.Fatalf("Failed to create tool result: %v", err)
+		}
+	}
+
+	costs, err := repo.GetLineCosts(jan.AddDate(0, 0, -9), feb.AddDate(0, 1, 0))
+	if err != nil {
+		t.Fatalf("Failed to get line costs: %v", err)
+	}
+	if len(costs) != 3 {
+		t.Fatalf("Expected 3 project months, got %+v", costs)
+	}
+
+	// Newest month first, then by project
+	if costs[0].ProjectName != "app" || costs[0].Month != "2025-02" || costs[0].LinesAdded != 2 || costs[0].LinesRemoved != 1 {
+		t.Errorf("Unexpected February line counts: %+v", costs[0])
+	}
+	if costs[0].CostPerKLOC == nil || *costs[0].CostPerKLOC != 500 {
+		t.Errorf("Expected $500/kLOC in February, got %v", costs[0].CostPerKLOC)
+	}
+	if costs[1].ProjectName != "docs" || costs[1].CostPerKLOC != nil {
+		t.Errorf("Expected no metric for a project without edits, got %+v", costs[1])
+	}
+	if costs[2].Month != "2025-01" || costs[2].Edits != 1 || costs[2].LinesAdded != 3 {
+		t.Errorf("Unexpected January line counts: %+v", costs[2])
+	}
+}
@@ -0,0 +1,130 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// environmentColumns selects session environments. Only a git remote a
+// client reported is stored with the environment; otherwise it is the one
+// detected at import, so the two can't disagree.
+const environmentColumns = `
+	SELECT
+		e.session_id, e.os, e.terminal,
+		COALESCE(e.git_remote, NULLIF(s.git_remote, '')) AS git_remote,
+		e.cwd, e.client_version, e.source, e.captured_at, e.updated_at
+	FROM session_environments e
+	LEFT JOIN sessions s ON s.id = e.session_id
+`
+
+// GetSessionsWithoutEnvironment returns up to limit sessions whose
+// environment has not been captured, with the working directory and client
+// version of their first message filled in
+func (r *SessionRepository) GetSessionsWithoutEnvironment(limit int) ([]SessionEnvironment, error) {
+	var environments []SessionEnvironment
+	err := r.db.Select(&environments, `
+		SELECT
+			s.id AS session_id,
+			COALESCE((
+				SELECT cwd FROM messages
+				WHERE session_id = s.id AND cwd IS NOT NULL AND cwd != ''
+				ORDER BY timestamp ASC LIMIT 1
+			), NULLIF(s.project_path, '')) AS cwd,
+			(
+				SELECT version FROM messages
+				WHERE session_id = s.id AND version IS NOT NULL AND version != ''
+				ORDER BY timestamp ASC LIMIT 1
+			) AS client_version,
+			'detected' AS source,
+			s.start_time AS captured_at,
+			s.start_time AS updated_at
+		FROM sessions s
+		LEFT JOIN session_environments e ON e.session_id = s.id
+		WHERE e.session_id IS NULL
+		ORDER BY s.start_time DESC
+		LIMIT ?
+	`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find sessions without environment: %w", err)
+	}
+	return environments, nil
+}
+
+// SaveSessionEnvironment records a session's environment. Fields left nil
+// keep any value already stored, so a client can report only what it knows.
+func (r *SessionRepository) SaveSessionEnvironment(env *SessionEnvironment) error {
+	return r.db.WriteOperation(func(tx *sqlx.Tx) error {
+		_, err := tx.NamedExec(`
+			INSERT INTO session_environments (
+				session_id, os, terminal, git_remote, cwd, client_version, source
+			) VALUES (
+				:session_id, :os, :terminal, :git_remote, :cwd, :client_version, :source
+			)
+			ON CONFLICT(session_id) DO UPDATE SET
+				os = COALESCE(excluded.os, session_environments.os),
+				terminal = COALESCE(excluded.terminal, session_environments.terminal),
+				git_remote = COALESCE(excluded.git_remote, session_environments.git_remote),
+				cwd = COALESCE(excluded.cwd, session_environments.cwd),
+				client_version = COALESCE(excluded.client_version, session_environments.client_version),
+				source = excluded.source,
+				updated_at = CURRENT_TIMESTAMP
+		`, env)
+		return err
+	})
+}
+
+// GetSessionEnvironment returns the environment a session ran in
+func (r *SessionRepository) GetSessionEnvironment(sessionID string) (*SessionEnvironment, error) {
+	var env SessionEnvironment
+	err := r.db.Get(&env, environmentColumns+` WHERE e.session_id = ?`, sessionID)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("session environment not found: %s", sessionID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session environment: %w", err)
+	}
+	return &env, nil
+}
+
+// GetSessionEnvironments returns every captured environment keyed by session ID
+func (r *SessionRepository) GetSessionEnvironments() (map[string]*SessionEnvironment, error) {
+	var environments []SessionEnvironment
+	if err := r.db.Select(&environments, environmentColumns); err != nil {
+		return nil, fmt.Errorf("failed to get session environments: %w", err)
+	}
+
+	result := make(map[string]*SessionEnvironment, len(environments))
+	for i := range environments {
+		result[environments[i].SessionID] = &environments[i]
+	}
+	return result, nil
+}
+
+// GetEnvironmentCounts returns how many sessions share each OS, terminal,
+// git remote and client version, most common first within each field
+func (r *SessionRepository) GetEnvironmentCounts() ([]EnvironmentCount, error) {
+	counts := []EnvironmentCount{}
+	err := r.db.Select(&counts, `
+		WITH environments AS (`+environmentColumns+`)
+		SELECT field, value, sessions FROM (
+			SELECT 'os' AS field, os AS value, COUNT(*) AS sessions
+			FROM environments WHERE os IS NOT NULL GROUP BY os
+			UNION ALL
+			SELECT 'terminal', terminal, COUNT(*)
+			FROM environments WHERE terminal IS NOT NULL GROUP BY terminal
+			UNION ALL
+			SELECT 'git_remote', git_remote, COUNT(*)
+			FROM environments WHERE git_remote IS NOT NULL GROUP BY git_remote
+			UNION ALL
+			SELECT 'client_version', client_version, COUNT(*)
+			FROM environments WHERE client_version IS NOT NULL GROUP BY client_version
+		)
+		ORDER BY field ASC, sessions DESC, value ASC
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get environment counts: %w", err)
+	}
+	return counts, nil
+}
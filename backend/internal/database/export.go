@@ -0,0 +1,52 @@
+package database
+
+import (
+	"fmt"
+	"time"
+)
+
+// GetSessionMetrics returns metrics for every session started at or after since
+func (r *SessionRepository) GetSessionMetrics(since time.Time) ([]SessionMetrics, error) {
+	metrics := []SessionMetrics{}
+	err := r.db.Select(&metrics, `
+		SELECT
+			s.id AS session_id,
+			s.project_name,
+			COALESCE(s.model, '') AS model,
+			s.start_time,
+			COALESCE(s.duration_seconds, 0) AS duration_seconds,
+			COALESCE(s.message_count, 0) AS message_count,
+			COALESCE(tu.input_tokens, 0) AS input_tokens,
+			COALESCE(tu.output_tokens, 0) AS output_tokens,
+			COALESCE(tu.cache_creation_input_tokens, 0) AS cache_creation_input_tokens,
+			COALESCE(tu.cache_read_input_tokens, 0) AS cache_read_input_tokens,
+			COALESCE(tu.total_tokens, 0) AS total_tokens,
+			COALESCE(tu.cost_usd, 0.0) AS cost_usd,
+			COALESCE(tr.tool_calls, 0) AS tool_calls,
+			COALESCE(tr.files_touched, 0) AS files_touched
+		FROM sessions s
+		LEFT JOIN (
+			SELECT
+				session_id,
+				SUM(input_tokens) AS input_tokens,
+				SUM(output_tokens) AS output_tokens,
+				SUM(cache_creation_input_tokens) AS cache_creation_input_tokens,
+				SUM(cache_read_input_tokens) AS cache_read_input_tokens,
+				SUM(total_tokens) AS total_tokens,
+				SUM(estimated_cost) AS cost_usd
+			FROM token_usage
+			GROUP BY session_id
+		) tu ON tu.session_id = s.id
+		LEFT JOIN (
+			SELECT session_id, COUNT(*) AS tool_calls, COUNT(DISTINCT file_path) AS files_touched
+			FROM tool_results
+			GROUP BY session_id
+		) tr ON tr.session_id = s.id
+		WHERE s.start_time >= ?
+		ORDER BY s.start_time ASC
+	`, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session metrics: %w", err)
+	}
+	return metrics, nil
+}
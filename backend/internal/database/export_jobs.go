@@ -0,0 +1,120 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// CreateExportJob stores a new export job as pending
+func (r *SessionRepository) CreateExportJob(job *ExportJob) error {
+	if job.ID == "" {
+		job.ID = uuid.New().String()
+	}
+	job.Status = RunStatusPending
+	job.CreatedAt = time.Now().UTC()
+
+	return r.db.WriteOperation(func(tx *sqlx.Tx) error {
+		_, err := tx.NamedExec(`
+			INSERT INTO export_jobs (id, client, kind, dataset, format, days, status, created_at)
+			VALUES (:id, :client, :kind, :dataset, :format, :days, :status, :created_at)
+		`, job)
+		if err != nil {
+			return fmt.Errorf("failed to create export job: %w", err)
+		}
+		return nil
+	})
+}
+
+// UpdateExportJob persists the mutable fields of an export job
+func (r *SessionRepository) UpdateExportJob(job *ExportJob) error {
+	return r.db.WriteOperation(func(tx *sqlx.Tx) error {
+		_, err := tx.NamedExec(`
+			UPDATE export_jobs
+			SET status = :status, file_name = :file_name, size_bytes = :size_bytes, error = :error,
+				started_at = :started_at, completed_at = :completed_at, expires_at = :expires_at
+			WHERE id = :id
+		`, job)
+		if err != nil {
+			return fmt.Errorf("failed to update export job: %w", err)
+		}
+		return nil
+	})
+}
+
+// GetExportJob returns an export job by ID
+func (r *SessionRepository) GetExportJob(id string) (*ExportJob, error) {
+	var job ExportJob
+	err := r.db.Get(&job, `SELECT * FROM export_jobs WHERE id = ?`, id)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("export job not found: %s", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get export job: %w", err)
+	}
+	return &job, nil
+}
+
+// GetExportJobs returns a client's export jobs, newest first
+func (r *SessionRepository) GetExportJobs(client string) ([]ExportJob, error) {
+	jobs := []ExportJob{}
+	err := r.db.Select(&jobs, `SELECT * FROM export_jobs WHERE client = ? ORDER BY created_at DESC`, client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get export jobs: %w", err)
+	}
+	return jobs, nil
+}
+
+// GetExpiredExportJobs returns the export jobs that expired before now
+func (r *SessionRepository) GetExpiredExportJobs(now time.Time) ([]ExportJob, error) {
+	jobs := []ExportJob{}
+	err := r.db.Select(&jobs, `SELECT * FROM export_jobs WHERE expires_at < ?`, now.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get expired export jobs: %w", err)
+	}
+	return jobs, nil
+}
+
+// DeleteExportJob removes an export job
+func (r *SessionRepository) DeleteExportJob(id string) error {
+	return r.db.WriteOperation(func(tx *sqlx.Tx) error {
+		if _, err := tx.Exec(`DELETE FROM export_jobs WHERE id = ?`, id); err != nil {
+			return fmt.Errorf("failed to delete export job: %w", err)
+		}
+		return nil
+	})
+}
+
+// FailUnfinishedExportJobs marks the export jobs a previous run left pending
+// or running as failed, to expire at expiresAt, returning how many there
+// were
+func (r *SessionRepository) FailUnfinishedExportJobs(reason string, expiresAt time.Time) (int64, error) {
+	var failed int64
+	err := r.db.WriteOperation(func(tx *sqlx.Tx) error {
+		result, err := tx.Exec(`
+			UPDATE export_jobs SET status = ?, error = ?, completed_at = ?, expires_at = ?
+			WHERE status IN (?, ?)
+		`, RunStatusFailed, reason, time.Now().UTC(), expiresAt.UTC(), RunStatusPending, RunStatusRunning)
+		if err != nil {
+			return err
+		}
+		failed, err = result.RowsAffected()
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to fail unfinished export jobs: %w", err)
+	}
+	return failed, nil
+}
+
+// BackupTo writes a consistent copy of the database to path, which must not
+// exist yet
+func (r *SessionRepository) BackupTo(path string) error {
+	if _, err := r.db.Exec(`VACUUM INTO ?`, path); err != nil {
+		return fmt.Errorf("failed to back up database: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package database
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSessionRepository_ExportJobs(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewSessionRepository(db, logger)
+	backup := &ExportJob{Client: "key:1", Kind: "backup", Format: "sqlite"}
+	dataset := "sessions"
+	analytics := &ExportJob{Client: "key:1", Kind: "analytics", Dataset: &dataset, Format: "csv", Days: 7}
+	other := &ExportJob{Client: "ip:10.0.0.1", Kind: "backup", Format: "sqlite"}
+	for _, job := range []*ExportJob{backup, analytics, other} {
+		if err := repo.CreateExportJob(job); err != nil {
+			t.Fatalf("Failed to create export job: %v", err)
+		}
+	}
+
+	now := time.Now().UTC()
+	fileName := "claude-sessions-backup.db"
+	expires := now.Add(-time.Minute)
+	backup.Status, backup.FileName, backup.SizeBytes, backup.CompletedAt, backup.ExpiresAt = RunStatusSucceeded, &fileName, 4096, &now, &expires
+	if err := repo.UpdateExportJob(backup); err != nil {
+		t.Fatalf("Failed to update export job: %v", err)
+	}
+
+	job, err := repo.GetExportJob(backup.ID)
+	if err != nil || job.Status != RunStatusSucceeded || job.SizeBytes != 4096 || *job.FileName != fileName {
+		t.Fatalf("Expected the succeeded backup, got %+v (%v)", job, err)
+	}
+	if _, err := repo.GetExportJob("missing"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("Expected not found, got %v", err)
+	}
+
+	jobs, err := repo.GetExportJobs("key:1")
+	if err != nil || len(jobs) != 2 {
+		t.Fatalf("Expected the client's two jobs, got %+v (%v)", jobs, err)
+	}
+
+	failed, err := repo.FailUnfinishedExportJobs("interrupted", now)
+	if err != nil || failed != 2 {
+		t.Fatalf("Expected two unfinished jobs failed, got %d (%v)", failed, err)
+	}
+	if job, _ := repo.GetExportJob(analytics.ID); job.Status != RunStatusFailed || *job.Error != "interrupted" || job.ExpiresAt == nil {
+		t.Errorf("Expected the analytics job failed, got %+v", job)
+	}
+
+	expired, err := repo.GetExpiredExportJobs(now.Add(time.Second))
+	if err != nil || len(expired) != 3 {
+		t.Fatalf("Expected the expired backup and the failed jobs, got %+v (%v)", expired, err)
+	}
+	if expired, _ := repo.GetExpiredExportJobs(now.Add(-time.Hour)); len(expired) != 0 {
+		t.Errorf("Expected nothing expired an hour ago, got %+v", expired)
+	}
+	if err := repo.DeleteExportJob(backup.ID); err != nil {
+		t.Fatalf("Failed to delete export job: %v", err)
+	}
+	if jobs, _ := repo.GetExportJobs("key:1"); len(jobs) != 1 {
+		t.Errorf("Expected one job left, got %+v", jobs)
+	}
+}
+
+func TestSessionRepository_BackupTo(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewSessionRepository(db, logger)
+	path := filepath.Join(t.TempDir(), "backup.db")
+	if err := repo.BackupTo(path); err != nil {
+		t.Fatalf("Failed to back up: %v", err)
+	}
+	if info, err := os.Stat(path); err != nil || info.Size() == 0 {
+		t.Fatalf("Expected a backup file, got %v", err)
+	}
+
+	backup, err := NewDatabase(Config{DatabasePath: path, Logger: logger})
+	if err != nil {
+		t.Fatalf("Failed to open backup: %v", err)
+	}
+	defer backup.Close()
+	if err := backup.Health(); err != nil {
+		t.Errorf("Expected a usable backup, got %v", err)
+	}
+}
@@ -0,0 +1,135 @@
+package database
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// GetUsageSummary returns this instance's usage aggregates. Totals cover all
+// history, archived sessions included; the project, model and day breakdowns
+// cover the last days days.
+func (r *SessionRepository) GetUsageSummary(days int) (*UsageSummary, error) {
+	summary := &UsageSummary{
+		GeneratedAt: time.Now().UTC(),
+		Days:        days,
+	}
+
+	var err error
+	if summary.TotalSessions, err = r.GetTotalSessions(true); err != nil {
+		return nil, err
+	}
+	if summary.ActiveSessions, err = r.GetActiveSessionsCount(true); err != nil {
+		return nil, err
+	}
+	if summary.TotalMessages, err = r.GetTotalMessages(true); err != nil {
+		return nil, err
+	}
+	tokenUsage, err := r.GetOverallTokenUsage(true)
+	if err != nil {
+		return nil, err
+	}
+	summary.TotalTokens = tokenUsage.TotalTokens
+	if summary.TotalCostUSD, err = r.GetEstimatedCost(true); err != nil {
+		return nil, err
+	}
+
+	since := fmt.Sprintf("-%d days", days)
+
+	// Token usage is pre-aggregated per session so joining it does not
+	// multiply the session's message count
+	sessionUsage := `
+		FROM sessions s
+		LEFT JOIN (
+			SELECT session_id, SUM(total_tokens) AS tokens, SUM(estimated_cost) AS cost
+			FROM token_usage
+			GROUP BY session_id
+		) tu ON tu.session_id = s.id
+		WHERE s.last_activity >= datetime('now', ?)
+	`
+
+	summary.ByProject = []UsageAggregate{}
+	err = r.db.Select(&summary.ByProject, `
+		SELECT
+			s.project_name AS key,
+			COUNT(*) AS sessions,
+			COALESCE(SUM(s.message_count), 0) AS messages,
+			COALESCE(SUM(tu.tokens), 0) AS tokens,
+			COALESCE(SUM(tu.cost), 0.0) AS cost_usd
+	`+sessionUsage+`
+		GROUP BY s.project_name
+		ORDER BY cost_usd DESC
+	`, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get usage by project: %w", err)
+	}
+
+	summary.ByModel = []UsageAggregate{}
+	err = r.db.Select(&summary.ByModel, `
+		SELECT
+			COALESCE(NULLIF(s.model, ''), 'unknown') AS key,
+			COUNT(*) AS sessions,
+			COALESCE(SUM(s.message_count), 0) AS messages,
+			COALESCE(SUM(tu.tokens), 0) AS tokens,
+			COALESCE(SUM(tu.cost), 0.0) AS cost_usd
+	`+sessionUsage+`
+		GROUP BY key
+		ORDER BY cost_usd DESC
+	`, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get usage by model: %w", err)
+	}
+
+	summary.ByDay = []UsageAggregate{}
+	err = r.db.Select(&summary.ByDay, `
+		SELECT
+			DATE(m.timestamp) AS key,
+			COUNT(DISTINCT m.session_id) AS sessions,
+			COUNT(DISTINCT m.id) AS messages,
+			COALESCE(SUM(tu.total_tokens), 0) AS tokens,
+			COALESCE(SUM(tu.estimated_cost), 0.0) AS cost_usd
+		FROM messages m
+		LEFT JOIN token_usage tu ON tu.message_id = m.id
+		WHERE m.timestamp >= datetime('now', ?)
+		GROUP BY DATE(m.timestamp)
+		ORDER BY key ASC
+	`, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get usage by day: %w", err)
+	}
+
+	return summary, nil
+}
+
+// SaveFederationSnapshot records the result of polling a team instance. A
+// failed poll keeps the summary from the last successful one.
+func (r *SessionRepository) SaveFederationSnapshot(snapshot *FederationSnapshot) error {
+	return r.db.WriteOperation(func(tx *sqlx.Tx) error {
+		_, err := tx.NamedExec(`
+			INSERT INTO federation_snapshots (
+				instance_name, url, status, error, summary, last_success_at, polled_at
+			) VALUES (
+				:instance_name, :url, :status, :error, :summary, :last_success_at, :polled_at
+			)
+			ON CONFLICT(instance_name) DO UPDATE SET
+				url = excluded.url,
+				status = excluded.status,
+				error = excluded.error,
+				summary = COALESCE(excluded.summary, federation_snapshots.summary),
+				last_success_at = COALESCE(excluded.last_success_at, federation_snapshots.last_success_at),
+				polled_at = excluded.polled_at
+		`, snapshot)
+		return err
+	})
+}
+
+// GetFederationSnapshots returns the latest snapshot of every polled instance
+func (r *SessionRepository) GetFederationSnapshots() ([]FederationSnapshot, error) {
+	var snapshots []FederationSnapshot
+	err := r.db.Select(&snapshots, `SELECT * FROM federation_snapshots ORDER BY instance_name ASC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get federation snapshots: %w", err)
+	}
+	return snapshots, nil
+}
@@ -0,0 +1,114 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSessionRepository_GetUsageSummary(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewSessionRepository(db, logger)
+
+	now := time.Now().UTC()
+	sessions := []*Session{
+		{ID: "s1", ProjectPath: "/p/api", ProjectName: "api", Model: "claude-opus", MessageCount: 2},
+		{ID: "s2", ProjectPath: "/p/api", ProjectName: "api", Model: "claude-sonnet", MessageCount: 1},
+		{ID: "s3", ProjectPath: "/p/web", ProjectName: "web", Model: "claude-sonnet", MessageCount: 1},
+	}
+	for _, session := range sessions {
+		session.StartTime = now.Add(-time.Hour)
+		session.LastActivity = now
+		session.Status = "completed"
+		if err := repo.UpsertSession(session); err != nil {
+			t.Fatalf("Failed to create session: %v", err)
+		}
+	}
+
+	usage := []struct {
+		messageID, sessionID string
+		tokens               int
+		cost                 float64
+	}{
+		{"m1", "s1", 100, 1.0},
+		{"m2", "s1", 100, 1.0},
+		{"m3", "s2", 50, 0.5},
+		{"m4", "s3", 10, 0.1},
+	}
+	for _, u := range usage {
+		if err := repo.UpsertMessage(&Message{ID: u.messageID, SessionID: u.sessionID, Role: "assistant", Timestamp: now}); err != nil {
+			t.Fatalf("Failed to create message: %v", err)
+		}
+		if err := repo.UpsertTokenUsage(&TokenUsage{MessageID: u.messageID, SessionID: u.sessionID, TotalTokens: u.tokens, EstimatedCost: u.cost}); err != nil {
+			t.Fatalf("Failed to create token usage: %v", err)
+		}
+	}
+
+	summary, err := repo.GetUsageSummary(30)
+	if err != nil {
+		t.Fatalf("Failed to get usage summary: %v", err)
+	}
+
+	if summary.TotalSessions != 3 || summary.TotalTokens != 260 {
+		t.Errorf("Unexpected totals: %+v", summary)
+	}
+	if len(summary.ByProject) != 2 || summary.ByProject[0].Key != "api" {
+		t.Fatalf("Expected api to be the most expensive project, got %+v", summary.ByProject)
+	}
+	// Token usage must not multiply the session message counts
+	if api := summary.ByProject[0]; api.Sessions != 2 || api.Messages != 3 || api.Tokens != 250 {
+		t.Errorf("Unexpected api aggregate: %+v", api)
+	}
+	if len(summary.ByModel) != 2 {
+		t.Errorf("Expected 2 models, got %+v", summary.ByModel)
+	}
+	if len(summary.ByDay) != 1 || summary.ByDay[0].Messages != 4 || summary.ByDay[0].Sessions != 3 {
+		t.Errorf("Unexpected daily aggregates: %+v", summary.ByDay)
+	}
+}
+
+func TestSessionRepository_SaveFederationSnapshot(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewSessionRepository(db, logger)
+
+	polled := time.Now().UTC()
+	if err := repo.SaveFederationSnapshot(&FederationSnapshot{
+		InstanceName:  "team-a",
+		URL:           "http://team-a",
+		Status:        "ok",
+		Summary:       stringPtr(`{"total_sessions": 3}`),
+		LastSuccessAt: &polled,
+		PolledAt:      polled,
+	}); err != nil {
+		t.Fatalf("Failed to save snapshot: %v", err)
+	}
+
+	// A failed poll keeps the last good summary
+	if err := repo.SaveFederationSnapshot(&FederationSnapshot{
+		InstanceName: "team-a",
+		URL:          "http://team-a",
+		Status:       "error",
+		Error:        stringPtr("connection refused"),
+		PolledAt:     polled.Add(time.Minute),
+	}); err != nil {
+		t.Fatalf("Failed to save snapshot: %v", err)
+	}
+
+	snapshots, err := repo.GetFederationSnapshots()
+	if err != nil {
+		t.Fatalf("Failed to get snapshots: %v", err)
+	}
+	if len(snapshots) != 1 {
+		t.Fatalf("Expected 1 snapshot, got %d", len(snapshots))
+	}
+	snapshot := snapshots[0]
+	if snapshot.Status != "error" || snapshot.Error == nil {
+		t.Errorf("Expected error status to be recorded, got %+v", snapshot)
+	}
+	if snapshot.Summary == nil || snapshot.LastSuccessAt == nil {
+		t.Errorf("Expected last successful summary to be kept, got %+v", snapshot)
+	}
+}
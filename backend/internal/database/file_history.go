@@ -0,0 +1,145 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// GetFileHistory returns the most recent limit Edit, Write and MultiEdit tool
+// calls on a file in chronological order, each with its diff, and how many
+// there are in total. path matches as in GetFileChanges.
+func (r *SessionRepository) GetFileHistory(filePath string, exact bool, limit int) ([]FileEdit, int, error) {
+	condition, args := filePathCondition(filePath, exact)
+	condition += " AND tr.tool_name IN ('Edit', 'Write', 'MultiEdit')"
+
+	var total int
+	err := r.db.Get(&total, `SELECT COUNT(*) FROM tool_results tr WHERE `+condition, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count file edits: %w", err)
+	}
+
+	edits := []FileEdit{}
+	err = r.db.Select(&edits, `
+		SELECT
+			tr.file_path,
+			tr.absolute_path,
+			tr.session_id,
+			s.project_name,
+			s.git_branch,
+			tr.message_id,
+			tr.tool_name,
+			tr.timestamp,
+			COALESCE(tr.result_data, '') AS result_data
+		FROM tool_results tr
+		JOIN sessions s ON s.id = tr.session_id
+		WHERE `+condition+`
+		ORDER BY tr.timestamp DESC, tr.id DESC
+		LIMIT ?
+	`, append(args, limit)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get file edits: %w", err)
+	}
+
+	// Oldest first, so the diffs read as the file's history
+	for i, j := 0, len(edits)-1; i < j; i, j = i+1, j-1 {
+		edits[i], edits[j] = edits[j], edits[i]
+	}
+	for i := range edits {
+		edits[i].Diff = editDiff(&edits[i])
+	}
+	return edits, total, nil
+}
+
+// editDiff renders a tool call's parameters as a unified diff, or returns ""
+// when they don't say what changed
+func editDiff(edit *FileEdit) string {
+	var diff strings.Builder
+	for _, replacement := range editReplacements(edit.ToolName, edit.ResultData) {
+		diff.WriteString(unifiedDiff(edit.FilePath, replacement[0], replacement[1]))
+	}
+	return diff.String()
+}
+
+// editReplacements returns the text each change in an Edit, Write or
+// MultiEdit call replaced and what replaced it, read from the recorded tool
+// parameters. A Write replaces nothing.
+func editReplacements(toolName, resultData string) [][2]string {
+	var data struct {
+		Parameters struct {
+			OldString string `json:"old_string"`
+			NewString string `json:"new_string"`
+			Content   string `json:"content"`
+			Edits     []struct {
+				OldString string `json:"old_string"`
+				NewString string `json:"new_string"`
+			} `json:"edits"`
+		} `json:"parameters"`
+	}
+	if err := json.Unmarshal([]byte(resultData), &data); err != nil {
+		return nil
+	}
+	params := data.Parameters
+
+	switch toolName {
+	case "Write":
+		return [][2]string{{"", params.Content}}
+	case "Edit":
+		return [][2]string{{params.OldString, params.NewString}}
+	case "MultiEdit":
+		replacements := make([][2]string, len(params.Edits))
+		for i, e := range params.Edits {
+			replacements[i] = [2]string{e.OldString, e.NewString}
+		}
+		return replacements
+	}
+	return nil
+}
+
+// lineChanges counts the lines added and removed going from before to after
+func lineChanges(before, after string) (added, removed int) {
+	if before == after {
+		return 0, 0
+	}
+	matcher := difflib.NewMatcher(splitLines(before), splitLines(after))
+	for _, op := range matcher.GetOpCodes() {
+		switch op.Tag {
+		case 'r':
+			removed += op.I2 - op.I1
+			added += op.J2 - op.J1
+		case 'd':
+			removed += op.I2 - op.I1
+		case 'i':
+			added += op.J2 - op.J1
+		}
+	}
+	return added, removed
+}
+
+// splitLines splits text into lines for counting. Unlike difflib.SplitLines,
+// empty text has no lines and a trailing newline doesn't start another.
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.SplitAfter(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+func unifiedDiff(path, before, after string) string {
+	if before == after {
+		return ""
+	}
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(before),
+		B:        difflib.SplitLines(after),
+		FromFile: "a" + path,
+		ToFile:   "b" + path,
+		Context:  3,
+	})
+	if err != nil {
+		return ""
+	}
+	return diff
+}
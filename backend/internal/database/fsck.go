@@ -0,0 +1,127 @@
+package database
+
+import (
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// heldSessions excludes rows of sessions under an active legal hold, which
+// are kept even when orphaned
+const heldSessions = `session_id NOT IN (SELECT session_id FROM legal_holds WHERE released_at IS NULL)`
+
+// fsckCheck finds one kind of broken row: where selects the rows in table and
+// fix repairs them, by default by deleting them
+type fsckCheck struct {
+	table   string
+	problem string
+	where   string
+	fix     string
+}
+
+// perSessionTables hold per-session data without a foreign key to sessions,
+// which fsck removes once the session is gone and DeleteSession removes
+// along with it
+var perSessionTables = []string{
+	"session_scores",
+	"session_feedback",
+	"prompt_signatures",
+	"session_reviews",
+	"session_review_comments",
+	"session_tags",
+	"session_notes",
+	"tag_rule_evaluations",
+	"session_links",
+	"script_fields",
+	"script_evaluations",
+	"tool_calls",
+	"tool_call_scans",
+	"commands",
+}
+
+// orphanedBySession checks per-session tables that have no foreign key, so
+// replacing a session row doesn't cascade to them
+func orphanedBySession(tables ...string) []fsckCheck {
+	checks := make([]fsckCheck, len(tables))
+	for i, table := range tables {
+		checks[i] = fsckCheck{
+			table:   table,
+			problem: "session missing",
+			where:   `session_id NOT IN (SELECT id FROM sessions)`,
+		}
+	}
+	return checks
+}
+
+// fsckChecks are run in order, so when fixing, rows of missing messages are
+// removed before duplicates among the rest are counted
+var fsckChecks = append([]fsckCheck{
+	// Enforced by foreign keys, but left behind by writes made with
+	// foreign keys off, such as database repair
+	{table: "messages", problem: "session missing", where: `session_id NOT IN (SELECT id FROM sessions)`},
+	{table: "token_usage", problem: "message missing", where: `message_id NOT IN (SELECT id FROM messages)`},
+	{table: "token_usage", problem: "session missing", where: `session_id NOT IN (SELECT id FROM sessions)`},
+	{table: "tool_results", problem: "message missing", where: `message_id NOT IN (SELECT id FROM messages)`},
+	{table: "tool_results", problem: "session missing", where: `session_id NOT IN (SELECT id FROM sessions)`},
+	{
+		table:   "activity_log",
+		problem: "session missing",
+		where:   `session_id IS NOT NULL AND session_id NOT IN (SELECT id FROM sessions)`,
+		fix:     `UPDATE activity_log SET session_id = NULL WHERE %s`,
+	},
+	// token_usage has no unique key on message_id, so INSERT OR REPLACE
+	// adds a row each time a message is re-imported without its old usage
+	// being cascaded away. The latest row is kept.
+	{
+		table:   "token_usage",
+		problem: "duplicate usage for a message",
+		where:   `id NOT IN (SELECT MAX(id) FROM token_usage GROUP BY message_id)`,
+	},
+}, orphanedBySession(perSessionTables...)...)
+
+// Fsck finds orphaned and duplicated rows, returning a finding for each
+// check with broken rows. With fix, they are deleted, or unlinked for the
+// activity log, in one transaction. Rows of sessions under an active legal
+// hold are never reported or changed; legal holds, integrity hashes, hook
+// events and todos are kept whether or not their session exists.
+func (db *Database) Fsck(fix bool) ([]FsckFinding, error) {
+	findings := []FsckFinding{}
+	run := func(q sqlx.Ext) error {
+		for _, check := range fsckChecks {
+			where := check.where + " AND " + heldSessions
+
+			var count int
+			if err := sqlx.Get(q, &count, fmt.Sprintf(`SELECT COUNT(*) FROM %s WHERE %s`, check.table, where)); err != nil {
+				return fmt.Errorf("failed to check %s: %w", check.table, err)
+			}
+			if count == 0 {
+				continue
+			}
+
+			finding := FsckFinding{Table: check.table, Problem: check.problem, Rows: count}
+			if fix {
+				stmt := check.fix
+				if stmt == "" {
+					stmt = `DELETE FROM ` + check.table + ` WHERE %s`
+				}
+				if _, err := q.Exec(fmt.Sprintf(stmt, where)); err != nil {
+					return fmt.Errorf("failed to fix %s: %w", check.table, err)
+				}
+				finding.Fixed = true
+			}
+			findings = append(findings, finding)
+		}
+		return nil
+	}
+
+	if !fix {
+		if err := run(db.DB); err != nil {
+			return nil, err
+		}
+		return findings, nil
+	}
+	if err := db.WriteOperation(func(tx *sqlx.Tx) error { return run(tx) }); err != nil {
+		return nil, err
+	}
+	return findings, nil
+}
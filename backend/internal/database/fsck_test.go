@@ -0,0 +1,102 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestDatabase_Fsck(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewSessionRepository(db, logger)
+
+	now := time.Now()
+	for _, id := range []string{"kept", "gone", "held"} {
+		if err := repo.UpsertSession(&Session{ID: id, ProjectPath: "/work/app", ProjectName: "app", StartTime: now, LastActivity: now, Status: "completed"}); err != nil {
+			t.Fatalf("Failed to create session: %v", err)
+		}
+		if err := repo.UpsertMessage(&Message{ID: id + "-1", SessionID: id, Role: "assistant", Content: `"done"`, Timestamp: now}); err != nil {
+			t.Fatalf("Failed to create message: %v", err)
+		}
+		if err := repo.UpsertTokenUsage(&TokenUsage{MessageID: id + "-1", SessionID: id, TotalTokens: 100}); err != nil {
+			t.Fatalf("Failed to create token usage: %v", err)
+		}
+	}
+	// A re-import records the kept message's usage again
+	if err := repo.UpsertTokenUsage(&TokenUsage{MessageID: "kept-1", SessionID: "kept", TotalTokens: 100}); err != nil {
+		t.Fatalf("Failed to create token usage: %v", err)
+	}
+	sessionID := "gone"
+	if err := repo.LogActivity(&ActivityLogEntry{SessionID: &sessionID, ActivityType: "session_created", Timestamp: now}); err != nil {
+		t.Fatalf("Failed to log activity: %v", err)
+	}
+	if err := repo.AddSessionTags("gone", []string{"experiment"}); err != nil {
+		t.Fatalf("Failed to tag session: %v", err)
+	}
+	if _, err := repo.PlaceLegalHold("held", "Litigation", "legal@example.com"); err != nil {
+		t.Fatalf("Failed to place legal hold: %v", err)
+	}
+
+	// Delete sessions with foreign keys off, as a repair might
+	conn, err := db.DB.Conn(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to get connection: %v", err)
+	}
+	if _, err := conn.ExecContext(context.Background(), `PRAGMA foreign_keys = OFF`); err != nil {
+		t.Fatalf("Failed to turn off foreign keys: %v", err)
+	}
+	if _, err := conn.ExecContext(context.Background(), `DELETE FROM sessions WHERE id IN ('gone', 'held')`); err != nil {
+		t.Fatalf("Failed to delete sessions: %v", err)
+	}
+	conn.ExecContext(context.Background(), `PRAGMA foreign_keys = ON`)
+	conn.Close()
+
+	findings, err := db.Fsck(false)
+	if err != nil {
+		t.Fatalf("Failed to check database: %v", err)
+	}
+	found := make(map[string]int)
+	for _, finding := range findings {
+		if finding.Fixed {
+			t.Errorf("Expected a check not to fix anything, got %+v", finding)
+		}
+		found[finding.Table+": "+finding.Problem] = finding.Rows
+	}
+	want := map[string]int{
+		"messages: session missing":                  1,
+		"token_usage: session missing":               1,
+		"token_usage: duplicate usage for a message": 1,
+		"activity_log: session missing":              1,
+		"session_tags: session missing":              1,
+	}
+	for key, rows := range want {
+		if found[key] != rows {
+			t.Errorf("Expected %d rows for %q, got %v", rows, key, found)
+		}
+	}
+
+	if _, err := db.Fsck(true); err != nil {
+		t.Fatalf("Failed to fix database: %v", err)
+	}
+	if findings, err := db.Fsck(false); err != nil || len(findings) != 0 {
+		t.Errorf("Expected nothing left to fix, got %+v, %v", findings, err)
+	}
+
+	var messages []string
+	if err := db.Select(&messages, `SELECT id FROM messages ORDER BY id`); err != nil {
+		t.Fatalf("Failed to get messages: %v", err)
+	}
+	if len(messages) != 2 || messages[0] != "held-1" || messages[1] != "kept-1" {
+		t.Errorf("Expected the held session's messages to be kept, got %v", messages)
+	}
+	var usage int
+	if err := db.Get(&usage, `SELECT COUNT(*) FROM token_usage WHERE message_id = 'kept-1'`); err != nil || usage != 1 {
+		t.Errorf("Expected one usage row for the kept message, got %d (%v)", usage, err)
+	}
+	var unlinked int
+	if err := db.Get(&unlinked, `SELECT COUNT(*) FROM activity_log WHERE session_id IS NULL`); err != nil || unlinked != 1 {
+		t.Errorf("Expected the activity to be unlinked rather than deleted, got %d (%v)", unlinked, err)
+	}
+}
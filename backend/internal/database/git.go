@@ -0,0 +1,100 @@
+package database
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/ksred/claude-session-manager/internal/gitinfo"
+)
+
+// detectGit fills in a session's git worktree and remote from the repository
+// at its working directory. The branch the transcript recorded is kept when
+// there is one, since the checkout may have moved on since the session ran.
+func detectGit(session *Session) {
+	info := gitinfo.Detect(session.ProjectPath)
+	session.GitWorktree = info.Worktree
+	session.GitRemote = info.Remote
+	if session.GitBranch == "" {
+		session.GitBranch = info.Branch
+	}
+}
+
+// transcriptBranch returns the last git branch recorded in a session's JSONL
+// file, or "" if it recorded none or can't be read
+func (r *SessionRepository) transcriptBranch(filePath string) string {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	branch := ""
+	reader := newJSONLReader(file, r.db.maxLineBytes)
+	for {
+		var msg JSONLMessage
+		_, err := reader.Next(&msg)
+		if err == io.EOF {
+			break
+		}
+		if err != nil && !isLineError(err) {
+			break
+		}
+		if err == nil && msg.GitBranch != "" {
+			branch = msg.GitBranch
+		}
+	}
+	return branch
+}
+
+// BackfillGit detects the git branch, worktree and remote of sessions imported
+// before they were recorded, returning how many sessions changed. Only
+// sessions without a worktree are looked at unless force is set. Values are
+// only ever added: a directory that has since been removed leaves what is
+// stored alone.
+func (r *SessionRepository) BackfillGit(force bool) (int, error) {
+	query := `SELECT id, project_path, file_path, COALESCE(git_branch, '') AS git_branch,
+		COALESCE(git_worktree, '') AS git_worktree, COALESCE(git_remote, '') AS git_remote FROM sessions`
+	if !force {
+		query += ` WHERE COALESCE(git_worktree, '') = ''`
+	}
+
+	var sessions []Session
+	if err := r.db.Select(&sessions, query); err != nil {
+		return 0, fmt.Errorf("failed to get sessions: %w", err)
+	}
+
+	updated := 0
+	for _, session := range sessions {
+		detected := Session{ProjectPath: session.ProjectPath, GitBranch: r.transcriptBranch(session.FilePath)}
+		detectGit(&detected)
+
+		changed := session
+		if changed.GitBranch == "" || force && detected.GitBranch != "" {
+			changed.GitBranch = detected.GitBranch
+		}
+		if detected.GitWorktree != "" {
+			changed.GitWorktree = detected.GitWorktree
+		}
+		if detected.GitRemote != "" {
+			changed.GitRemote = detected.GitRemote
+		}
+		if changed.GitBranch == session.GitBranch && changed.GitWorktree == session.GitWorktree && changed.GitRemote == session.GitRemote {
+			continue
+		}
+
+		err := r.db.WriteOperation(func(tx *sqlx.Tx) error {
+			_, err := tx.Exec(`UPDATE sessions SET git_branch = ?, git_worktree = ?, git_remote = ? WHERE id = ?`,
+				changed.GitBranch, changed.GitWorktree, changed.GitRemote, session.ID)
+			return err
+		})
+		if err != nil {
+			return updated, fmt.Errorf("failed to update session %s: %w", session.ID, err)
+		}
+		updated++
+	}
+
+	r.logger.WithField("sessions", updated).Info("Backfilled git information")
+	return updated, nil
+}
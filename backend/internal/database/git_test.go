@@ -0,0 +1,66 @@
+package database
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestGitDetection(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewSessionRepository(db, logger)
+
+	work := t.TempDir()
+	gitDir := filepath.Join(work, ".git")
+	if err := os.MkdirAll(gitDir, 0755); err != nil {
+		t.Fatalf("Failed to create git dir: %v", err)
+	}
+	os.WriteFile(filepath.Join(gitDir, "HEAD"), []byte("ref: refs/heads/main\n"), 0644)
+	os.WriteFile(filepath.Join(gitDir, "config"), []byte("[remote \"origin\"]\n\turl = https://token@github.com/acme/app.git\n"), 0644)
+	subdir := filepath.Join(work, "cmd")
+	os.MkdirAll(subdir, 0755)
+
+	// The branch the transcript recorded wins over what is checked out now
+	line := `{"cwd":"` + subdir + `","gitBranch":"feature/login","sessionId":"imported","type":"user","message":{"role":"user","content":"hello"},"uuid":"imported-1","timestamp":"2025-01-01T10:00:00Z"}` + "\n"
+	transcript := filepath.Join(t.TempDir(), "imported.jsonl")
+	if err := os.WriteFile(transcript, []byte(line), 0644); err != nil {
+		t.Fatalf("Failed to write transcript: %v", err)
+	}
+	if _, _, err := NewImporter(repo, logger).ImportJSONLFile(transcript, ProjectInfo{}); err != nil {
+		t.Fatalf("Failed to import: %v", err)
+	}
+	session, err := repo.GetSessionByID("imported")
+	if err != nil {
+		t.Fatalf("Failed to get session: %v", err)
+	}
+	if session.GitBranch != "feature/login" || session.GitWorktree != work || session.GitRemote != "github.com/acme/app" {
+		t.Errorf("Expected branch, worktree and remote to be detected, got %q, %q, %q", session.GitBranch, session.GitWorktree, session.GitRemote)
+	}
+
+	// Sessions imported before detection are filled in by the backfill
+	now := time.Now()
+	if err := repo.UpsertSession(&Session{ID: "old", ProjectPath: subdir, ProjectName: "app", FilePath: "/missing.jsonl", StartTime: now, LastActivity: now, Status: "completed"}); err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	if err := repo.UpsertSession(&Session{ID: "gone", ProjectPath: filepath.Join(work, "removed"), ProjectName: "app", FilePath: "/missing.jsonl", StartTime: now, LastActivity: now, Status: "completed"}); err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	updated, err := repo.BackfillGit(false)
+	if err != nil {
+		t.Fatalf("Failed to backfill: %v", err)
+	}
+	if updated != 1 {
+		t.Errorf("Expected 1 session backfilled, got %d", updated)
+	}
+	old, err := repo.GetSessionByID("old")
+	if err != nil {
+		t.Fatalf("Failed to get session: %v", err)
+	}
+	if old.GitBranch != "main" || old.GitWorktree != work || old.GitRemote != "github.com/acme/app" {
+		t.Errorf("Expected the checked out branch to be backfilled, got %q, %q, %q", old.GitBranch, old.GitWorktree, old.GitRemote)
+	}
+
+}
@@ -0,0 +1,20 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// GetSessionFilePath returns the path of the transcript a session was
+// imported from
+func (r *SessionRepository) GetSessionFilePath(sessionID string) (string, error) {
+	var filePath string
+	err := r.db.Get(&filePath, `SELECT file_path FROM sessions WHERE id = ?`, sessionID)
+	if err == sql.ErrNoRows {
+		return "", fmt.Errorf("session not found: %s", sessionID)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get session file path: %w", err)
+	}
+	return filePath, nil
+}
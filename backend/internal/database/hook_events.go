@@ -0,0 +1,70 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Failed reports whether a hook event reports a failure: a failure event,
+// or a tool response that is an error
+func (e *HookEvent) Failed() bool {
+	if strings.HasSuffix(e.Event, "Failure") {
+		return true
+	}
+	var input struct {
+		Error        string          `json:"error"`
+		ToolResponse json.RawMessage `json:"tool_response"`
+	}
+	if err := json.Unmarshal(e.Data, &input); err != nil {
+		return false
+	}
+	if input.Error != "" {
+		return true
+	}
+	var response struct {
+		IsError bool   `json:"is_error"`
+		Error   string `json:"error"`
+	}
+	json.Unmarshal(input.ToolResponse, &response)
+	return response.IsError || response.Error != ""
+}
+
+// SaveHookEvent stores an event received from a Claude Code hook and sets
+// its ID
+func (r *SessionRepository) SaveHookEvent(event *HookEvent) error {
+	return r.db.WriteOperation(func(tx *sqlx.Tx) error {
+		result, err := tx.Exec(`
+			INSERT INTO hook_events (session_id, event, tool_name, tool_use_id, cwd, transcript_path, payload, received_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		`, event.SessionID, event.Event, event.ToolName, event.ToolUseID, event.CWD,
+			event.TranscriptPath, event.Payload, event.ReceivedAt)
+		if err != nil {
+			return fmt.Errorf("failed to save hook event: %w", err)
+		}
+		event.ID, err = result.LastInsertId()
+		return err
+	})
+}
+
+// GetHookEvents returns hook events, optionally for one session and of one
+// kind, most recent first
+func (r *SessionRepository) GetHookEvents(sessionID, event string, limit int) ([]HookEvent, error) {
+	events := []HookEvent{}
+	err := r.db.Select(&events, `
+		SELECT * FROM hook_events
+		WHERE (? = '' OR session_id = ?)
+		AND (? = '' OR event = ?)
+		ORDER BY id DESC
+		LIMIT ?
+	`, sessionID, sessionID, event, event, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get hook events: %w", err)
+	}
+	for i := range events {
+		events[i].Data = json.RawMessage(events[i].Payload)
+	}
+	return events, nil
+}
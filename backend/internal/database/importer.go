@@ -1,40 +1,52 @@
 package database
 
 import (
-	"bufio"
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
 	"time"
 
+	"github.com/jmoiron/sqlx"
+	"github.com/ksred/claude-session-manager/internal/pricing"
 	"github.com/sirupsen/logrus"
 )
 
+// DefaultImportBatchSize is how many messages ImportJSONLFile holds in
+// memory before writing them in one transaction
+const DefaultImportBatchSize = 500
+
 // Importer handles importing JSONL files into the database
 type Importer struct {
-	repo   *SessionRepository
-	logger *logrus.Logger
-	ctx    context.Context
+	repo      *SessionRepository
+	logger    *logrus.Logger
+	ctx       context.Context
+	batchSize int
 }
 
 // NewImporter creates a new importer
 func NewImporter(repo *SessionRepository, logger *logrus.Logger) *Importer {
-	return &Importer{
-		repo:   repo,
-		logger: logger,
-		ctx:    context.Background(),
-	}
+	return NewImporterWithContext(context.Background(), repo, logger)
 }
 
 // NewImporterWithContext creates a new importer with context
 func NewImporterWithContext(ctx context.Context, repo *SessionRepository, logger *logrus.Logger) *Importer {
 	return &Importer{
-		repo:   repo,
-		logger: logger,
-		ctx:    ctx,
+		repo:      repo,
+		logger:    logger,
+		ctx:       ctx,
+		batchSize: DefaultImportBatchSize,
+	}
+}
+
+// SetBatchSize sets how many messages are written per transaction when
+// importing a file
+func (i *Importer) SetBatchSize(size int) {
+	if size > 0 {
+		i.batchSize = size
 	}
 }
 
@@ -51,7 +63,7 @@ func (i *Importer) ImportClaudeDirectory(claudeDir string) error {
 	totalProjectDirs := 0
 	totalJSONLFiles := 0
 	for _, entry := range entries {
-		if !entry.IsDir() {
+		if !isProjectDir(projectsDir, entry) {
 			continue
 		}
 		totalProjectDirs++
@@ -88,7 +100,7 @@ func (i *Importer) ImportClaudeDirectory(claudeDir string) error {
 		default:
 		}
 		
-		if !entry.IsDir() {
+		if !isProjectDir(projectsDir, entry) {
 			continue
 		}
 
@@ -255,6 +267,7 @@ type JSONLMessage struct {
 	IsSidechain   bool            `json:"isSidechain"`
 	UserType      string          `json:"userType"`
 	CWD           string          `json:"cwd"`
+	GitBranch     string          `json:"gitBranch"`
 	SessionID     string          `json:"sessionId"`
 	Version       string          `json:"version"`
 	Type          string          `json:"type"`
@@ -310,7 +323,10 @@ type JSONLTokenUsage struct {
 	ServiceTier              string `json:"service_tier"`
 }
 
-// ImportJSONLFile imports a single JSONL file and returns counts
+// ImportJSONLFile imports a single JSONL file and returns counts. The file is
+// streamed and written every batch size messages, so memory stays flat
+// however large the file is. A batch that can't be written fails the file, leaving it
+// to be imported again; the batches before it are simply written again.
 func (i *Importer) ImportJSONLFile(filePath string, projectInfo ProjectInfo) (int, int, error) {
 	file, err := os.Open(filePath)
 	if err != nil {
@@ -318,327 +334,370 @@ func (i *Importer) ImportJSONLFile(filePath string, projectInfo ProjectInfo) (in
 	}
 	defer file.Close()
 
-	// Parse all messages first to group by session
-	sessionMessages := make(map[string][]JSONLMessage)
-	
-	// Create scanner with larger buffer to handle long lines
-	scanner := bufio.NewScanner(file)
-	// Set max token size to 10MB (default is 64KB)
-	buf := make([]byte, 0, 64*1024)
-	scanner.Buffer(buf, 10*1024*1024) // 10MB max token size
-	
+	// Session metadata accumulates across batches; the messages don't
+	sessions := make(map[string]*Session)
+	written := make(map[string]bool)
+	batch := make([]JSONLMessage, 0, i.batchSize)
+
+	reader := newJSONLReader(file, i.repo.db.maxLineBytes)
 	messageCount := 0
-	lineNum := 0
 	lastLogTime := time.Now()
-	
-	for scanner.Scan() {
-		lineNum++
-		line := scanner.Text()
-		
-		// Skip empty lines
-		if strings.TrimSpace(line) == "" {
-			continue
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
 		}
-		
+		if err := i.writeBatch(batch, sessions, written, projectInfo); err != nil {
+			return fmt.Errorf("failed to import messages up to line %d: %w", reader.line, err)
+		}
+		batch = batch[:0]
+		return nil
+	}
+
+	for {
 		var msg JSONLMessage
-		if err := json.Unmarshal([]byte(line), &msg); err != nil {
+		oversized, err := reader.Next(&msg)
+		if err == io.EOF {
+			break
+		}
+		if err != nil && !isLineError(err) {
+			return 0, 0, fmt.Errorf("error reading file: %w", err)
+		}
+		if oversized {
+			i.repo.recordOversizedLine(filePath, reader.offset, reader.size, err)
+		}
+		if err != nil {
 			i.logger.WithError(err).WithFields(logrus.Fields{
 				"file": filePath,
-				"line": lineNum,
+				"line": reader.line,
 			}).Debug("Failed to parse message, skipping")
 			continue
 		}
-		
-		sessionMessages[msg.SessionID] = append(sessionMessages[msg.SessionID], msg)
+
+		trackSession(sessions, msg, filePath)
+		batch = append(batch, msg)
 		messageCount++
-		
+
+		if len(batch) >= i.batchSize {
+			if err := flush(); err != nil {
+				return 0, 0, err
+			}
+		}
+
 		// Log progress for large files every 5 seconds or every 1000 messages
 		if messageCount%1000 == 0 || time.Since(lastLogTime) > 5*time.Second {
 			i.logger.WithFields(logrus.Fields{
 				"file":     filepath.Base(filePath),
-				"lines":    lineNum,
+				"lines":    reader.line,
 				"messages": messageCount,
-				"sessions": len(sessionMessages),
+				"sessions": len(sessions),
 			}).Debug("File parsing progress")
 			lastLogTime = time.Now()
 		}
 	}
 
-	if err := scanner.Err(); err != nil {
-		return 0, 0, fmt.Errorf("error reading file: %w", err)
+	if err := flush(); err != nil {
+		return 0, 0, err
 	}
 
-	// Process each session
-	sessionCount := 0
-	for sessionID, messages := range sessionMessages {
-		if err := i.importSession(sessionID, messages, projectInfo, filePath); err != nil {
-			i.logger.WithError(err).WithField("session_id", sessionID).Error("Failed to import session")
-			continue
-		}
-		sessionCount++
+	sessionIDs := make([]string, 0, len(written))
+	for sessionID := range written {
+		sessionIDs = append(sessionIDs, sessionID)
+	}
+	if _, err := i.repo.LinkResumedSessions(sessionIDs); err != nil {
+		i.logger.WithError(err).WithField("file", filePath).Warn("Failed to link resumed sessions")
+	}
+	if err := i.repo.ReapplySessionEdits(sessionIDs); err != nil {
+		i.logger.WithError(err).WithField("file", filePath).Warn("Failed to reapply session edits")
 	}
 
-	return sessionCount, messageCount, nil
+	return len(written), messageCount, nil
 }
 
-// importSession imports a single session with its messages
-func (i *Importer) importSession(sessionID string, messages []JSONLMessage, projectInfo ProjectInfo, filePath string) error {
-	if len(messages) == 0 {
-		return nil
+// trackSession folds a message into its session's running metadata
+func trackSession(sessions map[string]*Session, msg JSONLMessage, filePath string) {
+	session, exists := sessions[msg.SessionID]
+	if !exists {
+		session = &Session{
+			ID:           msg.SessionID,
+			FilePath:     filePath,
+			StartTime:    msg.Timestamp,
+			LastActivity: msg.Timestamp,
+		}
+		sessions[msg.SessionID] = session
 	}
 
-	// Calculate session metadata
-	startTime := messages[0].Timestamp
-	lastActivity := messages[0].Timestamp
-	var model string
-	var actualProjectPath string
-	var actualProjectName string
-	
-	for _, msg := range messages {
-		if msg.Timestamp.Before(startTime) {
-			startTime = msg.Timestamp
-		}
-		if msg.Timestamp.After(lastActivity) {
-			lastActivity = msg.Timestamp
-		}
-		if msg.Message.Model != nil {
-			model = *msg.Message.Model
-		}
-		// Extract the actual project path from CWD field in messages
-		if msg.CWD != "" && actualProjectPath == "" {
-			actualProjectPath = msg.CWD
-			actualProjectName = filepath.Base(actualProjectPath)
-		}
+	if msg.Timestamp.Before(session.StartTime) {
+		session.StartTime = msg.Timestamp
 	}
-	
-	// Fallback to parsed project info if no CWD found in messages
-	if actualProjectPath == "" {
-		actualProjectPath = projectInfo.ProjectPath
-		actualProjectName = projectInfo.ProjectName
+	if msg.Timestamp.After(session.LastActivity) {
+		session.LastActivity = msg.Timestamp
 	}
-
-	// Determine if session is active (activity within last 2 minutes)
-	isActive := time.Since(lastActivity) < 2*time.Minute
-	duration := lastActivity.Sub(startTime)
-
-	// Create session
-	session := &Session{
-		ID:              sessionID,
-		ProjectPath:     actualProjectPath,
-		ProjectName:     actualProjectName,
-		FilePath:        filePath,
-		StartTime:       startTime,
-		LastActivity:    lastActivity,
-		IsActive:        isActive,
-		Status:          "completed",
-		Model:           model,
-		MessageCount:    len(messages),
-		DurationSeconds: int64(duration.Seconds()),
+	if msg.Message.Model != nil {
+		session.Model = *msg.Message.Model
 	}
-
-	if isActive {
-		session.Status = "active"
+	if msg.GitBranch != "" {
+		session.GitBranch = msg.GitBranch
 	}
-
-	// Insert session
-	if err := i.repo.UpsertSession(session); err != nil {
-		return fmt.Errorf("failed to upsert session: %w", err)
+	// Extract the actual project path from CWD field in messages
+	if msg.CWD != "" && session.ProjectPath == "" {
+		session.ProjectPath = msg.CWD
+		session.ProjectName = filepath.Base(msg.CWD)
 	}
+	session.MessageCount++
+}
 
-	// Insert messages and related data
-	for _, msg := range messages {
-		// Convert content to JSON string
-		contentBytes, err := json.Marshal(msg.Message.Content)
-		if err != nil {
-			i.logger.WithError(err).Warn("Failed to marshal message content")
-			contentBytes = []byte("{}")
+// writeBatch writes a batch of messages, and the current metadata of the
+// sessions they belong to, in one transaction
+func (i *Importer) writeBatch(batch []JSONLMessage, sessions map[string]*Session, written map[string]bool, projectInfo ProjectInfo) error {
+	var batchSessions []string
+	inBatch := make(map[string]bool)
+	for _, msg := range batch {
+		if !inBatch[msg.SessionID] {
+			inBatch[msg.SessionID] = true
+			batchSessions = append(batchSessions, msg.SessionID)
 		}
+	}
 
-		// Create message
-		dbMessage := &Message{
-			ID:          msg.UUID,
-			SessionID:   sessionID,
-			ParentUUID:  msg.ParentUUID,
-			IsSidechain: msg.IsSidechain,
-			UserType:    msg.UserType,
-			CWD:         msg.CWD,
-			Version:     msg.Version,
-			Type:        msg.Type,
-			Role:        msg.Message.Role,
-			Content:     string(contentBytes),
-			RequestID:   msg.RequestID,
-			Timestamp:   msg.Timestamp,
-		}
+	// Git is read from disk before taking the write lock
+	batchMetadata := make([]Session, len(batchSessions))
+	for n, sessionID := range batchSessions {
+		session := *sessions[sessionID]
 
-		if err := i.repo.UpsertMessage(dbMessage); err != nil {
-			return fmt.Errorf("failed to upsert message: %w", err)
+		// Fallback to parsed project info if no CWD found in messages
+		if session.ProjectPath == "" {
+			session.ProjectPath = projectInfo.ProjectPath
+			session.ProjectName = projectInfo.ProjectName
 		}
+		detectGit(&session)
+		batchMetadata[n] = session
+	}
 
-		// Handle token usage
-		if msg.Message.Usage != nil {
-			usage := &TokenUsage{
-				MessageID:                msg.UUID,
-				SessionID:                sessionID,
-				InputTokens:              msg.Message.Usage.InputTokens,
-				OutputTokens:             msg.Message.Usage.OutputTokens,
-				CacheCreationInputTokens: msg.Message.Usage.CacheCreationInputTokens,
-				CacheReadInputTokens:     msg.Message.Usage.CacheReadInputTokens,
-				ServiceTier:              msg.Message.Usage.ServiceTier,
+	err := i.repo.db.WriteOperation(func(tx *sqlx.Tx) error {
+		for _, session := range batchMetadata {
+			// Determine if session is active (activity within last 2 minutes)
+			session.IsActive = time.Since(session.LastActivity) < 2*time.Minute
+			session.Status = "completed"
+			if session.IsActive {
+				session.Status = "active"
 			}
-			
-			// Calculate totals and cost
-			usage.TotalTokens = usage.InputTokens + usage.OutputTokens + 
-				usage.CacheCreationInputTokens + usage.CacheReadInputTokens
-			usage.EstimatedCost = i.calculateTokenCost(usage, model)
+			session.DurationSeconds = int64(session.LastActivity.Sub(session.StartTime).Seconds())
 
-			if err := i.repo.UpsertTokenUsage(usage); err != nil {
-				return fmt.Errorf("failed to upsert token usage: %w", err)
+			if err := upsertSession(tx, &session); err != nil {
+				return fmt.Errorf("failed to upsert session: %w", err)
 			}
 		}
 
-		// Extract tool calls from message content (for assistant messages)
-		if msg.Message.Role == "assistant" && msg.Message.Content != nil {
-			// Convert content to string for parsing
-			contentStr := ""
-			switch v := msg.Message.Content.(type) {
-			case string:
-				contentStr = v
-			case []interface{}:
-				// Handle array content by converting to JSON
-				if bytes, err := json.Marshal(v); err == nil {
-					contentStr = string(bytes)
-				}
-			default:
-				// Try to marshal any other type
-				if bytes, err := json.Marshal(v); err == nil {
-					contentStr = string(bytes)
-				}
+		rows := &importRows{messages: make([]Message, 0, len(batch))}
+		for _, msg := range batch {
+			if err := i.importMessage(tx, msg, sessions[msg.SessionID].Model, rows); err != nil {
+				return err
 			}
+		}
+		return rows.write(tx)
+	})
+	if err != nil {
+		return err
+	}
 
-			// Extract tool calls from the content
-			toolCalls := ExtractToolCallsFromMessage(contentStr, msg.Timestamp)
-			for _, toolCall := range toolCalls {
-				// Only save file-modifying tools
-				if !isFileModifyingTool(toolCall.ToolName) {
-					continue
-				}
+	for _, sessionID := range batchSessions {
+		written[sessionID] = true
+	}
+	return nil
+}
 
-				// Create tool result entry
-				resultData := map[string]interface{}{
-					"tool_name": toolCall.ToolName,
-					"parameters": toolCall.Parameters,
-				}
-				resultBytes, _ := json.Marshal(resultData)
+// importRows collects the rows of a batch so each table is written with a
+// few multi-row statements rather than one statement per row
+type importRows struct {
+	messages    []Message
+	usages      []TokenUsage
+	toolResults []ToolResult
+}
 
-				var filePath *string
-				if toolCall.FilePath != "" {
-					filePath = &toolCall.FilePath
-				}
+// write stores the collected rows, messages first as the others reference them
+func (rows *importRows) write(tx *sqlx.Tx) error {
+	if err := upsertMessages(tx, rows.messages); err != nil {
+		return fmt.Errorf("failed to upsert messages: %w", err)
+	}
+	if err := upsertTokenUsages(tx, rows.usages); err != nil {
+		return fmt.Errorf("failed to upsert token usage: %w", err)
+	}
+	if err := upsertToolResults(tx, rows.toolResults); err != nil {
+		return fmt.Errorf("failed to upsert tool results: %w", err)
+	}
+	return nil
+}
 
-				toolResult := &ToolResult{
-					MessageID:  msg.UUID,
-					SessionID:  sessionID,
-					ToolName:   toolCall.ToolName,
-					FilePath:   filePath,
-					ResultData: string(resultBytes),
-					Timestamp:  toolCall.Timestamp,
-				}
+// importMessage adds a message with its token usage and tool results to rows
+func (i *Importer) importMessage(tx *sqlx.Tx, msg JSONLMessage, model string, rows *importRows) error {
+	sessionID := msg.SessionID
+	if msg.Message.Model != nil {
+		model = *msg.Message.Model
+	}
 
-				if err := i.repo.UpsertToolResult(toolResult); err != nil {
-					i.logger.WithError(err).Warn("Failed to upsert tool result from content parsing")
-				}
+	// Convert content to JSON string
+	contentBytes, err := json.Marshal(msg.Message.Content)
+	if err != nil {
+		i.logger.WithError(err).Warn("Failed to marshal message content")
+		contentBytes = []byte("{}")
+	}
+
+	// Create message
+	dbMessage := &Message{
+		ID:          msg.UUID,
+		SessionID:   sessionID,
+		ParentUUID:  msg.ParentUUID,
+		IsSidechain: msg.IsSidechain,
+		UserType:    msg.UserType,
+		CWD:         msg.CWD,
+		Version:     msg.Version,
+		Type:        msg.Type,
+		Role:        msg.Message.Role,
+		Content:     string(contentBytes),
+		RequestID:   msg.RequestID,
+		Timestamp:   msg.Timestamp,
+	}
+
+	keep, err := i.repo.runMessageHook(tx, dbMessage, msg.Message.Usage != nil)
+	if err != nil {
+		return fmt.Errorf("failed to run message hook: %w", err)
+	}
+	if !keep {
+		return nil
+	}
+
+	rows.messages = append(rows.messages, *dbMessage)
+
+	// Handle token usage
+	if msg.Message.Usage != nil {
+		usage := &TokenUsage{
+			MessageID:                msg.UUID,
+			SessionID:                sessionID,
+			InputTokens:              msg.Message.Usage.InputTokens,
+			OutputTokens:             msg.Message.Usage.OutputTokens,
+			CacheCreationInputTokens: msg.Message.Usage.CacheCreationInputTokens,
+			CacheReadInputTokens:     msg.Message.Usage.CacheReadInputTokens,
+			ServiceTier:              msg.Message.Usage.ServiceTier,
+		}
+
+		// Calculate totals and cost
+		usage.TotalTokens = usage.InputTokens + usage.OutputTokens +
+			usage.CacheCreationInputTokens + usage.CacheReadInputTokens
+		usage.Model = model
+		usage.EstimatedCost = estimateTokenCost(usage, model)
+		usage.CacheSavings = estimateCacheSavings(usage, model)
+
+		rows.usages = append(rows.usages, *usage)
+	}
+
+	// Extract tool calls from message content (for assistant messages)
+	if msg.Message.Role == "assistant" && msg.Message.Content != nil {
+		// Convert content to string for parsing
+		contentStr := ""
+		switch v := msg.Message.Content.(type) {
+		case string:
+			contentStr = v
+		case []interface{}:
+			// Handle array content by converting to JSON
+			if bytes, err := json.Marshal(v); err == nil {
+				contentStr = string(bytes)
+			}
+		default:
+			// Try to marshal any other type
+			if bytes, err := json.Marshal(v); err == nil {
+				contentStr = string(bytes)
 			}
 		}
 
-		// Also handle legacy tool use results if present
-		if msg.ToolUseResult != nil && msg.ToolUseResult.Value != nil {
-			resultBytes, err := json.Marshal(msg.ToolUseResult.Value)
-			if err != nil {
-				i.logger.WithError(err).Warn("Failed to marshal tool result")
+		// Extract tool calls from the content
+		toolCalls := ExtractToolCallsFromMessage(contentStr, msg.Timestamp)
+		for _, toolCall := range toolCalls {
+			// Only save file-modifying tools
+			if !isFileModifyingTool(toolCall.ToolName) {
 				continue
 			}
 
-			// Extract file path if available
-			var filePath *string
-			if fp, ok := msg.ToolUseResult.Value["file_path"].(string); ok {
-				filePath = &fp
+			// Create tool result entry
+			resultData := map[string]interface{}{
+				"tool_name":  toolCall.ToolName,
+				"parameters": toolCall.Parameters,
 			}
+			resultBytes, _ := json.Marshal(resultData)
 
-			// Extract tool name if available
-			toolName := "unknown"
-			if tn, ok := msg.ToolUseResult.Value["tool_name"].(string); ok {
-				toolName = tn
+			var filePath *string
+			if toolCall.FilePath != "" {
+				filePath = &toolCall.FilePath
 			}
 
 			toolResult := &ToolResult{
 				MessageID:  msg.UUID,
 				SessionID:  sessionID,
-				ToolName:   toolName,
+				ToolName:   toolCall.ToolName,
 				FilePath:   filePath,
 				ResultData: string(resultBytes),
-				Timestamp:  msg.Timestamp,
+				Timestamp:  toolCall.Timestamp,
 			}
+			toolResult.setWorkspacePath(msg.CWD)
 
-			if err := i.repo.UpsertToolResult(toolResult); err != nil {
-				return fmt.Errorf("failed to upsert tool result: %w", err)
-			}
+			rows.toolResults = append(rows.toolResults, *toolResult)
 		}
 	}
 
+	// Also handle legacy tool use results if present
+	if msg.ToolUseResult != nil && msg.ToolUseResult.Value != nil {
+		resultBytes, err := json.Marshal(msg.ToolUseResult.Value)
+		if err != nil {
+			i.logger.WithError(err).Warn("Failed to marshal tool result")
+			return nil
+		}
+
+		// Extract file path if available
+		var filePath *string
+		if fp, ok := msg.ToolUseResult.Value["file_path"].(string); ok {
+			filePath = &fp
+		}
+
+		// Extract tool name if available
+		toolName := "unknown"
+		if tn, ok := msg.ToolUseResult.Value["tool_name"].(string); ok {
+			toolName = tn
+		}
+
+		toolResult := &ToolResult{
+			MessageID:  msg.UUID,
+			SessionID:  sessionID,
+			ToolName:   toolName,
+			FilePath:   filePath,
+			ResultData: string(resultBytes),
+			Timestamp:  msg.Timestamp,
+		}
+		toolResult.setWorkspacePath(msg.CWD)
+
+		rows.toolResults = append(rows.toolResults, *toolResult)
+	}
+
 	// Don't log import activity - it clutters the activity timeline
 	// Only log real user activities like messages and file modifications
 
 	return nil
 }
 
-// calculateTokenCost estimates the cost based on token usage and model
-func (i *Importer) calculateTokenCost(usage *TokenUsage, model string) float64 {
-	// Pricing per million tokens based on model
-	var inputCostPer1M, outputCostPer1M, cacheReadCostPer1M, cacheWriteCostPer1M float64
-	
-	switch {
-	case strings.Contains(model, "claude-3-opus"):
-		inputCostPer1M = 15.0
-		outputCostPer1M = 75.0
-		cacheReadCostPer1M = 1.50
-		cacheWriteCostPer1M = 18.75
-	case strings.Contains(model, "claude-opus-4"):
-		inputCostPer1M = 15.0
-		outputCostPer1M = 75.0
-		cacheReadCostPer1M = 1.50
-		cacheWriteCostPer1M = 18.75
-	case strings.Contains(model, "claude-3-5-sonnet"), strings.Contains(model, "claude-3.5-sonnet"):
-		inputCostPer1M = 3.0
-		outputCostPer1M = 15.0
-		cacheReadCostPer1M = 0.30
-		cacheWriteCostPer1M = 3.75
-	case strings.Contains(model, "claude-3-sonnet"):
-		inputCostPer1M = 3.0
-		outputCostPer1M = 15.0
-		cacheReadCostPer1M = 0.30
-		cacheWriteCostPer1M = 3.75
-	case strings.Contains(model, "claude-3-5-haiku"), strings.Contains(model, "claude-3.5-haiku"):
-		inputCostPer1M = 0.80
-		outputCostPer1M = 4.0
-		cacheReadCostPer1M = 0.08
-		cacheWriteCostPer1M = 1.0
-	case strings.Contains(model, "claude-3-haiku"):
-		inputCostPer1M = 0.25
-		outputCostPer1M = 1.25
-		cacheReadCostPer1M = 0.03
-		cacheWriteCostPer1M = 0.30
-	default:
-		// Default to Sonnet pricing if model unknown
-		inputCostPer1M = 3.0
-		outputCostPer1M = 15.0
-		cacheReadCostPer1M = 0.30
-		cacheWriteCostPer1M = 3.75
-	}
-
-	cost := float64(usage.InputTokens) * inputCostPer1M / 1000000
-	cost += float64(usage.OutputTokens) * outputCostPer1M / 1000000
-	cost += float64(usage.CacheReadInputTokens) * cacheReadCostPer1M / 1000000
-	cost += float64(usage.CacheCreationInputTokens) * cacheWriteCostPer1M / 1000000
-	
-	return cost
+// estimateTokenCost prices token usage from the shared pricing table
+func estimateTokenCost(usage *TokenUsage, model string) float64 {
+	return pricing.Cost(model, pricingUsage(usage))
+}
+
+// estimateCacheSavings prices what token usage saved by reading and writing
+// the prompt cache rather than sending fresh input
+func estimateCacheSavings(usage *TokenUsage, model string) float64 {
+	return pricing.CacheSavings(model, pricingUsage(usage))
+}
+
+func pricingUsage(usage *TokenUsage) pricing.Usage {
+	return pricing.Usage{
+		InputTokens:              usage.InputTokens,
+		OutputTokens:             usage.OutputTokens,
+		CacheCreationInputTokens: usage.CacheCreationInputTokens,
+		CacheReadInputTokens:     usage.CacheReadInputTokens,
+	}
 }
\ No newline at end of file
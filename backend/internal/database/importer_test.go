@@ -0,0 +1,121 @@
+package database
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestImporter_ImportJSONLFileInBatches(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewSessionRepository(db, logger)
+	importer := NewImporter(repo, logger)
+	importer.SetBatchSize(2)
+
+	start := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	var lines []string
+	for n := 0; n < 7; n++ {
+		lines = append(lines, fmt.Sprintf(`{"cwd":"/work/app","sessionId":"big","type":"assistant","message":{"role":"assistant","model":"claude-3-5-sonnet-20241022","content":"step %d","usage":{"input_tokens":10,"output_tokens":5}},"uuid":"m%d","timestamp":"%s"}`,
+			n, n, start.Add(time.Duration(n)*time.Minute).Format(time.RFC3339)))
+	}
+	path := filepath.Join(t.TempDir(), "big.jsonl")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+		t.Fatalf("Failed to write transcript: %v", err)
+	}
+
+	projectInfo := ProjectInfo{ProjectPath: "/fallback", ProjectName: "fallback"}
+	// Importing twice must not lose the messages written by earlier batches
+	for run := 0; run < 2; run++ {
+		sessions, messages, err := importer.ImportJSONLFile(path, projectInfo)
+		if err != nil {
+			t.Fatalf("Failed to import: %v", err)
+		}
+		if sessions != 1 || messages != 7 {
+			t.Fatalf("Expected 1 session and 7 messages, got %d and %d", sessions, messages)
+		}
+	}
+
+	var stored, usages int
+	if err := db.Get(&stored, `SELECT COUNT(*) FROM messages WHERE session_id = 'big'`); err != nil || stored != 7 {
+		t.Errorf("Expected 7 stored messages, got %d (%v)", stored, err)
+	}
+	if err := db.Get(&usages, `SELECT COUNT(*) FROM token_usage WHERE session_id = 'big'`); err != nil || usages != 7 {
+		t.Errorf("Expected 7 token usage rows, got %d (%v)", usages, err)
+	}
+
+	session, err := repo.GetSessionByID("big")
+	if err != nil {
+		t.Fatalf("Failed to get session: %v", err)
+	}
+	if session.MessageCount != 7 || session.ProjectPath != "/work/app" || session.DurationSeconds != 360 {
+		t.Errorf("Unexpected session metadata: %+v", session)
+	}
+	if !session.StartTime.Equal(start) || !session.LastActivity.Equal(start.Add(6*time.Minute)) {
+		t.Errorf("Expected %v to %v, got %v to %v", start, start.Add(6*time.Minute), session.StartTime, session.LastActivity)
+	}
+
+	// A batch that can't be written fails the file instead of being dropped
+	if _, err := db.Exec(`CREATE TRIGGER refuse_messages BEFORE INSERT ON messages
+		WHEN NEW.id = 'm9' BEGIN SELECT RAISE(ABORT, 'disk I/O error'); END`); err != nil {
+		t.Fatalf("Failed to create trigger: %v", err)
+	}
+	lines = append(lines, strings.Replace(strings.Replace(lines[6], `"m6"`, `"m9"`, 1), "step 6", "step 9", 1))
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+		t.Fatalf("Failed to write transcript: %v", err)
+	}
+	if _, _, err := importer.ImportJSONLFile(path, projectInfo); err == nil || !strings.Contains(err.Error(), "disk I/O error") {
+		t.Errorf("Expected the failed batch to fail the import, got %v", err)
+	}
+}
+
+func TestImporter_ImportJSONLFileWithMultiRowInserts(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewSessionRepository(db, logger)
+	importer := NewImporter(repo, logger)
+
+	// More rows than fit in one statement, so every table is chunked
+	const count = 250
+	start := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	var lines []string
+	for n := 0; n < count; n++ {
+		lines = append(lines, fmt.Sprintf(`{"cwd":"/work/app","sessionId":"bulk","version":"1.0.%d","isSidechain":true,"userType":"external","requestId":"req%d","type":"assistant","message":{"role":"assistant","model":"claude-3-5-sonnet-20241022","content":[{"type":"tool_use","id":"tu%d","name":"Write","input":{"file_path":"/work/app/f%d.go","content":"x"}}],"usage":{"input_tokens":10,"output_tokens":5,"service_tier":"standard"}},"uuid":"m%d","timestamp":"%s"}`,
+			n, n, n, n, n, start.Add(time.Duration(n)*time.Second).Format(time.RFC3339)))
+	}
+	path := filepath.Join(t.TempDir(), "bulk.jsonl")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+		t.Fatalf("Failed to write transcript: %v", err)
+	}
+
+	if _, messages, err := importer.ImportJSONLFile(path, ProjectInfo{}); err != nil || messages != count {
+		t.Fatalf("Expected %d messages imported, got %d (%v)", count, messages, err)
+	}
+
+	var stored, usages, results int
+	db.Get(&stored, `SELECT COUNT(*) FROM messages WHERE session_id = 'bulk'`)
+	db.Get(&usages, `SELECT COUNT(*) FROM token_usage WHERE session_id = 'bulk' AND service_tier = 'standard' AND total_tokens = 15`)
+	db.Get(&results, `SELECT COUNT(*) FROM tool_results WHERE session_id = 'bulk' AND tool_name = 'Write'`)
+	if stored != count || usages != count || results != count {
+		t.Errorf("Expected %d rows in each table, got %d messages, %d token usages and %d tool results", count, stored, usages, results)
+	}
+
+	var message Message
+	if err := db.Get(&message, `SELECT * FROM messages WHERE id = 'm249'`); err != nil {
+		t.Fatalf("Failed to get message: %v", err)
+	}
+	if message.CWD != "/work/app" || message.Version != "1.0.249" || !message.IsSidechain || message.UserType != "external" ||
+		message.RequestID == nil || *message.RequestID != "req249" || !message.Timestamp.Equal(start.Add(249*time.Second)) {
+		t.Errorf("Expected every column stored, got %+v", message)
+	}
+
+	var filePath string
+	if err := db.Get(&filePath, `SELECT file_path FROM tool_results WHERE message_id = 'm7'`); err != nil || filePath != "f7.go" {
+		t.Errorf("Expected the workspace-relative path f7.go, got %q (%v)", filePath, err)
+	}
+}
@@ -3,8 +3,10 @@ package database
 import (
 	"context"
 	"crypto/sha256"
+	"database/sql"
 	"fmt"
 	"io"
+	"math"
 	"os"
 	"path/filepath"
 	"strings"
@@ -15,12 +17,17 @@ import (
 
 // IncrementalImporter handles smart importing of only changed files
 type IncrementalImporter struct {
-	repo   *SessionRepository
-	db     *Database
-	logger *logrus.Logger
-	ctx    context.Context
+	repo         *SessionRepository
+	db           *Database
+	logger       *logrus.Logger
+	ctx          context.Context
+	callback     UpdateCallback
+	lastProgress time.Time
 }
 
+// progressInterval is the least time between progress reports of a run
+const progressInterval = time.Second
+
 // NewIncrementalImporter creates a new incremental importer
 func NewIncrementalImporter(ctx context.Context, repo *SessionRepository, db *Database, logger *logrus.Logger) *IncrementalImporter {
 	return &IncrementalImporter{
@@ -31,6 +38,11 @@ func NewIncrementalImporter(ctx context.Context, repo *SessionRepository, db *Da
 	}
 }
 
+// SetUpdateCallback sets the callback import progress is reported to
+func (i *IncrementalImporter) SetUpdateCallback(callback UpdateCallback) {
+	i.callback = callback
+}
+
 // ImportClaudeDirectory performs an intelligent import of only changed files
 func (i *IncrementalImporter) ImportClaudeDirectory(claudeDir string, forceInitial bool) error {
 	projectsDir := filepath.Join(claudeDir, "projects")
@@ -43,6 +55,14 @@ func (i *IncrementalImporter) ImportClaudeDirectory(claudeDir string, forceIniti
 		runType = "initial"
 	}
 
+	// A run that never finished left its completed files journaled in
+	// file_watchers, so pick up from there instead of starting over
+	interrupted, err := i.interruptedRun()
+	if err != nil {
+		i.logger.WithError(err).Warn("Failed to check for an interrupted import, starting over")
+	}
+	resuming := interrupted != nil && runType == "initial" && !forceInitial
+
 	// Start import run tracking
 	importRun, err := i.startImportRun(runType)
 	if err != nil {
@@ -76,12 +96,33 @@ func (i *IncrementalImporter) ImportClaudeDirectory(claudeDir string, forceIniti
 	}
 
 	// Scan all files first to identify what needs processing
-	filesToProcess, totalFiles, err := i.identifyFilesToProcess(projectsDir, entries, runType == "initial")
+	filesToProcess, totalFiles, err := i.identifyFilesToProcess(projectsDir, entries, runType == "initial" && !resuming)
 	if err != nil {
 		i.finishImportRun(importRun.ID, "failed", fmt.Sprintf("failed to identify files: %v", err))
 		return fmt.Errorf("failed to identify files to process: %w", err)
 	}
 
+	progress := &ImportProgress{
+		RunID:        importRun.ID,
+		RunType:      runType,
+		Status:       "running",
+		FilesSkipped: totalFiles - len(filesToProcess),
+		FilesTotal:   len(filesToProcess),
+	}
+
+	if resuming {
+		recovered := totalFiles - len(filesToProcess)
+		progress.ResumedFromRunID = &interrupted.ID
+		progress.FilesRecovered = recovered
+		i.recordResume(importRun.ID, interrupted.ID, recovered)
+		i.logger.WithFields(logrus.Fields{
+			"interrupted_run": interrupted.ID,
+			"interrupted_at":  interrupted.StartTime.Format(time.RFC3339),
+			"files_recovered": recovered,
+			"files_remaining": len(filesToProcess),
+		}).Info("Resuming interrupted import")
+	}
+
 	i.logger.WithFields(logrus.Fields{
 		"total_files":         totalFiles,
 		"files_to_process":    len(filesToProcess),
@@ -93,6 +134,9 @@ func (i *IncrementalImporter) ImportClaudeDirectory(claudeDir string, forceIniti
 		i.logger.Info("No files need processing - all up to date")
 		importRunCompleted = true
 		i.finishImportRun(importRun.ID, "completed", "")
+		progress.Status = "completed"
+		progress.Percent = 100
+		i.reportProgress(progress, true)
 		return nil
 	}
 
@@ -100,6 +144,11 @@ func (i *IncrementalImporter) ImportClaudeDirectory(claudeDir string, forceIniti
 	totalSessions := 0
 	totalMessages := 0
 	startTime := time.Now()
+	totalMB, doneMB := 0.0, 0.0
+	for _, fileInfo := range filesToProcess {
+		totalMB += fileInfo.SizeMB
+	}
+	i.reportProgress(progress, true)
 
 	for idx, fileInfo := range filesToProcess {
 		// Check for cancellation
@@ -108,20 +157,32 @@ func (i *IncrementalImporter) ImportClaudeDirectory(claudeDir string, forceIniti
 			i.logger.Info("Import cancelled by context")
 			importRunCompleted = true
 			i.finishImportRun(importRun.ID, "cancelled", "cancelled by user")
+			progress.Status = "cancelled"
+			progress.ETASeconds = nil
+			i.reportProgress(progress, true)
 			return i.ctx.Err()
 		default:
 		}
 
 		// Import the file
 		sessions, messages, err := i.processFile(fileInfo)
+		doneMB += fileInfo.SizeMB
+		progress.FilesProcessed++
 		if err != nil {
 			i.logger.WithError(err).WithField("file", fileInfo.FilePath).Error("Failed to process file")
 			i.markFileError(fileInfo.FilePath, err.Error())
+			progress.FilesFailed++
+			progress.estimate(time.Since(startTime), doneMB, totalMB)
+			i.reportProgress(progress, false)
 			continue
 		}
 
 		totalSessions += sessions
 		totalMessages += messages
+		progress.Sessions = totalSessions
+		progress.Messages = totalMessages
+		progress.estimate(time.Since(startTime), doneMB, totalMB)
+		i.reportProgress(progress, false)
 
 		// Log progress every 10 files or large files
 		if (idx+1)%10 == 0 || fileInfo.SizeMB > 5 {
@@ -160,6 +221,12 @@ func (i *IncrementalImporter) ImportClaudeDirectory(claudeDir string, forceIniti
 	}
 
 	duration := time.Since(startTime)
+	progress.Status = "completed"
+	progress.Percent = 100
+	progress.ElapsedSeconds = duration.Seconds()
+	progress.ETASeconds = nil
+	i.reportProgress(progress, true)
+
 	i.logger.WithFields(logrus.Fields{
 		"files_processed":   len(filesToProcess),
 		"files_skipped":     totalFiles - len(filesToProcess),
@@ -172,6 +239,37 @@ func (i *IncrementalImporter) ImportClaudeDirectory(claudeDir string, forceIniti
 	return nil
 }
 
+// reportProgress sends a copy of progress to the update callback. Reports
+// while files are processed are sent at most once per progressInterval; the
+// first and last reports of a run are always sent.
+func (i *IncrementalImporter) reportProgress(progress *ImportProgress, force bool) {
+	if i.callback == nil {
+		return
+	}
+	if !force && time.Since(i.lastProgress) < progressInterval {
+		return
+	}
+	i.lastProgress = time.Now()
+	snapshot := *progress
+	i.callback.OnImportProgress(&snapshot)
+}
+
+// estimate fills in how far the import is and how long the rest will take,
+// weighting files by size since large transcripts take longest
+func (p *ImportProgress) estimate(elapsed time.Duration, doneMB, totalMB float64) {
+	p.ElapsedSeconds = elapsed.Seconds()
+	done, total := doneMB, totalMB
+	if total == 0 {
+		done, total = float64(p.FilesProcessed), float64(p.FilesTotal)
+	}
+	if total == 0 || done == 0 {
+		return
+	}
+	p.Percent = math.Round(done/total*1000) / 10
+	eta := elapsed.Seconds() * (total - done) / done
+	p.ETASeconds = &eta
+}
+
 // FileToProcess represents a file that needs to be imported
 type FileToProcess struct {
 	FilePath    string
@@ -187,7 +285,7 @@ func (i *IncrementalImporter) identifyFilesToProcess(projectsDir string, entries
 	totalFiles := 0
 
 	for _, entry := range entries {
-		if !entry.IsDir() {
+		if !isProjectDir(projectsDir, entry) {
 			continue
 		}
 
@@ -262,6 +360,15 @@ func (i *IncrementalImporter) fileNeedsProcessing(filePath string, modTime time.
 		return true, nil
 	}
 	
+	// A completed file whose mtime moved but whose content hashes the same,
+	// e.g. after a copy or touch, doesn't need importing again
+	if fw.ImportStatus == "completed" && size == fw.FileSize && modTime.After(*fw.LastProcessed) && fw.FileHash != nil {
+		if hash, err := i.calculateFileHash(filePath); err == nil && hash == *fw.FileHash {
+			i.logger.WithField("file", filePath).Debug("File does not need processing: content hash unchanged")
+			return false, nil
+		}
+	}
+
 	// If file was modified after last processing or size changed, process it
 	if modTime.After(*fw.LastProcessed) || size != fw.FileSize {
 		i.logger.WithFields(logrus.Fields{
@@ -304,14 +411,21 @@ func (i *IncrementalImporter) processFile(fileInfo FileToProcess) (int, int, err
 	i.markFileProcessing(fileInfo.FilePath, fileInfo.ModTime, int64(fileInfo.SizeMB*1024*1024))
 	
 	// Import the file using incremental batch operations
+	start := time.Now()
 	sessions, messages, err := batchImporter.ImportJSONLFileIncremental(fileInfo.FilePath, fileInfo.ProjectInfo)
 	if err != nil {
 		i.markFileError(fileInfo.FilePath, err.Error())
 		return 0, 0, err
 	}
+	i.db.observeImport("import", sessions, messages, start)
 
-	// Mark file as completed
-	i.markFileCompleted(fileInfo.FilePath, sessions, messages)
+	// Journal the file as completed, with its hash, so an interrupted run
+	// can be resumed from here
+	hash, err := i.calculateFileHash(fileInfo.FilePath)
+	if err != nil {
+		i.logger.WithError(err).WithField("file", fileInfo.FilePath).Debug("Failed to hash imported file")
+	}
+	i.markFileCompleted(fileInfo.FilePath, hash, sessions, messages)
 	
 	return sessions, messages, nil
 }
@@ -334,18 +448,25 @@ func (i *IncrementalImporter) markFileProcessing(filePath string, modTime time.T
 	}
 }
 
-// markFileCompleted marks a file as successfully processed
-func (i *IncrementalImporter) markFileCompleted(filePath string, sessions, messages int) {
+// markFileCompleted marks a file as successfully processed and records the
+// hash of the content that was imported
+func (i *IncrementalImporter) markFileCompleted(filePath, hash string, sessions, messages int) {
+	var hashPtr *string
+	if hash != "" {
+		hashPtr = &hash
+	}
+
 	_, err := i.db.Exec(`
 		UPDATE file_watchers 
 		SET import_status = 'completed',
 		    sessions_imported = ?,
 		    messages_imported = ?,
+		    file_hash = ?,
 		    last_processed = CURRENT_TIMESTAMP,
 		    last_error = NULL,
 		    updated_at = CURRENT_TIMESTAMP
 		WHERE file_path = ?
-	`, sessions, messages, filePath)
+	`, sessions, messages, hashPtr, filePath)
 	
 	if err != nil {
 		i.logger.WithError(err).WithField("file", filePath).Error("Failed to mark file as completed")
@@ -409,6 +530,54 @@ func (i *IncrementalImporter) finishImportRun(runID int, status, errorMsg string
 	}
 }
 
+// interruptedRun returns the most recent import run that started after the
+// last completed one and never finished, or nil if there isn't one. Runs the
+// server died during are still 'running' and are marked 'interrupted'; those
+// started since the database was opened belong to this process, such as a
+// rebuild, and are left running.
+func (i *IncrementalImporter) interruptedRun() (*ImportRun, error) {
+	_, err := i.db.Exec(`
+		UPDATE import_runs
+		SET status = 'interrupted',
+		    end_time = CURRENT_TIMESTAMP,
+		    error_message = 'Server stopped before the import finished'
+		WHERE status = 'running' AND start_time < ?
+	`, i.db.openedAt.Format("2006-01-02 15:04:05"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to mark interrupted imports: %w", err)
+	}
+
+	var run ImportRun
+	err = i.db.Get(&run, `
+		SELECT * FROM import_runs
+		WHERE status NOT IN ('completed', 'running')
+		AND id > COALESCE((SELECT MAX(id) FROM import_runs WHERE status = 'completed'), 0)
+		ORDER BY id DESC
+		LIMIT 1
+	`)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get interrupted import: %w", err)
+	}
+	return &run, nil
+}
+
+// recordResume records which interrupted run an import picked up from and
+// how many files it had already journaled
+func (i *IncrementalImporter) recordResume(runID, interruptedID, recovered int) {
+	_, err := i.db.Exec(`
+		UPDATE import_runs
+		SET resumed_from_run_id = ?, files_recovered = ?
+		WHERE id = ?
+	`, interruptedID, recovered, runID)
+
+	if err != nil {
+		i.logger.WithError(err).Error("Failed to record import resume")
+	}
+}
+
 // isFirstRun checks if this is the first time we're running an import
 func (i *IncrementalImporter) isFirstRun() (bool, error) {
 	var count int
@@ -0,0 +1,134 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// progressRecorder records the import progress reported to it
+type progressRecorder struct {
+	reports []*ImportProgress
+}
+
+func (r *progressRecorder) OnSessionUpdate(string, string, *Session) {}
+func (r *progressRecorder) OnActivityUpdate(*ActivityLogEntry)       {}
+func (r *progressRecorder) OnMetricsUpdate(string, *TokenUsage)      {}
+func (r *progressRecorder) OnImportProgress(progress *ImportProgress) {
+	r.reports = append(r.reports, progress)
+}
+
+func TestIncrementalImporter_ResumesInterruptedImport(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewSessionRepository(db, logger)
+
+	claudeDir := t.TempDir()
+	projectDir := filepath.Join(claudeDir, "projects", "-work-app")
+	if err := os.MkdirAll(projectDir, 0755); err != nil {
+		t.Fatalf("Failed to create project dir: %v", err)
+	}
+	for _, id := range []string{"first", "second"} {
+		line := fmt.Sprintf(`{"parentUuid":null,"cwd":"/work/app","sessionId":"%s","type":"user","message":{"role":"user","content":"hello"},"uuid":"%s-1","timestamp":"2025-01-01T10:00:00Z"}`+"\n", id, id)
+		if err := os.WriteFile(filepath.Join(projectDir, id+".jsonl"), []byte(line), 0644); err != nil {
+			t.Fatalf("Failed to write transcript: %v", err)
+		}
+	}
+
+	importer := NewIncrementalImporter(context.Background(), repo, db, logger)
+	if err := importer.ImportClaudeDirectory(claudeDir, false); err != nil {
+		t.Fatalf("Failed to import: %v", err)
+	}
+
+	// Simulate the server dying while the second file was being imported
+	second := filepath.Join(projectDir, "second.jsonl")
+	if _, err := db.Exec(`UPDATE import_runs SET status = 'running', start_time = datetime('now', '-1 hour'), end_time = NULL`); err != nil {
+		t.Fatalf("Failed to reset import run: %v", err)
+	}
+	if _, err := db.Exec(`UPDATE file_watchers SET import_status = 'processing', last_processed = NULL WHERE file_path = ?`, second); err != nil {
+		t.Fatalf("Failed to reset file journal: %v", err)
+	}
+
+	recorder := &progressRecorder{}
+	importer.SetUpdateCallback(recorder)
+	if err := importer.ImportClaudeDirectory(claudeDir, false); err != nil {
+		t.Fatalf("Failed to resume import: %v", err)
+	}
+
+	var runs []ImportRun
+	if err := db.Select(&runs, `SELECT * FROM import_runs ORDER BY id`); err != nil {
+		t.Fatalf("Failed to get import runs: %v", err)
+	}
+	if len(runs) != 2 || runs[0].Status != "interrupted" {
+		t.Fatalf("Expected the first run to be marked interrupted, got %+v", runs)
+	}
+	resumed := runs[1]
+	if resumed.Status != "completed" || resumed.ResumedFromRunID == nil || *resumed.ResumedFromRunID != runs[0].ID {
+		t.Errorf("Expected a completed run resumed from %d, got %+v", runs[0].ID, resumed)
+	}
+	if resumed.FilesRecovered != 1 || resumed.FilesProcessed != 1 {
+		t.Errorf("Expected 1 file recovered and 1 processed, got %d and %d", resumed.FilesRecovered, resumed.FilesProcessed)
+	}
+
+	// Progress reports carry the resume and end with the completed run
+	if len(recorder.reports) != 2 {
+		t.Fatalf("Expected a start and a final progress report, got %d", len(recorder.reports))
+	}
+	start, final := recorder.reports[0], recorder.reports[1]
+	if start.Status != "running" || start.ResumedFromRunID == nil || *start.ResumedFromRunID != runs[0].ID || start.FilesRecovered != 1 || start.FilesTotal != 1 {
+		t.Errorf("Expected the start report to describe the resume, got %+v", start)
+	}
+	if final.Status != "completed" || final.RunID != resumed.ID || final.FilesProcessed != 1 || final.Sessions != 1 || final.Percent != 100 || final.ETASeconds != nil {
+		t.Errorf("Expected the final report to complete the run, got %+v", final)
+	}
+
+	var hash *string
+	if err := db.Get(&hash, `SELECT file_hash FROM file_watchers WHERE file_path = ?`, second); err != nil || hash == nil {
+		t.Errorf("Expected the completed file to be journaled with its hash, got %v (%v)", hash, err)
+	}
+
+	// Touching a file without changing it doesn't trigger a re-import
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(second, later, later); err != nil {
+		t.Fatalf("Failed to touch transcript: %v", err)
+	}
+	info, _ := os.Stat(second)
+	needed, err := importer.fileNeedsProcessing(second, info.ModTime(), info.Size(), false)
+	if err != nil || needed {
+		t.Errorf("Expected an unchanged hash to skip the file, got %v (%v)", needed, err)
+	}
+}
+
+func TestIncrementalImporter_LeavesLiveRunsRunning(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewSessionRepository(db, logger)
+	importer := NewIncrementalImporter(context.Background(), repo, db, logger)
+
+	// A run from a process that is gone and one this process is still running
+	if _, err := db.Exec(`INSERT INTO import_runs (run_type, start_time, status) VALUES ('incremental', datetime('now', '-1 hour'), 'running')`); err != nil {
+		t.Fatalf("Failed to create stale run: %v", err)
+	}
+	live, err := importer.startImportRun("incremental")
+	if err != nil {
+		t.Fatalf("Failed to start import run: %v", err)
+	}
+
+	interrupted, err := importer.interruptedRun()
+	if err != nil {
+		t.Fatalf("Failed to get interrupted run: %v", err)
+	}
+	if interrupted == nil || interrupted.ID == live.ID {
+		t.Fatalf("Expected the stale run to be the interrupted one, got %+v", interrupted)
+	}
+
+	var status string
+	if err := db.Get(&status, `SELECT status FROM import_runs WHERE id = ?`, live.ID); err != nil || status != "running" {
+		t.Errorf("Expected the live run to stay running, got %q (%v)", status, err)
+	}
+}
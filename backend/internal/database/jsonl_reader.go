@@ -0,0 +1,136 @@
+package database
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+)
+
+// DefaultMaxLineBytes is the longest JSONL line read into memory whole
+const DefaultMaxLineBytes = 10 * 1024 * 1024
+
+// jsonlReader reads JSONL messages a line at a time. Lines up to maxLine bytes
+// are buffered and unmarshalled as usual; longer ones, typically carrying
+// large base64 images, are decoded as a stream so they are imported rather
+// than dropped.
+type jsonlReader struct {
+	reader  *bufio.Reader
+	maxLine int
+	line    int   // number of the line last read
+	offset  int64 // byte offset of the line last read
+	size    int64 // size of the line last read, including its newline
+}
+
+func newJSONLReader(r io.Reader, maxLine int) *jsonlReader {
+	if maxLine <= 0 {
+		maxLine = DefaultMaxLineBytes
+	}
+	return &jsonlReader{
+		reader:  bufio.NewReaderSize(r, 64*1024),
+		maxLine: maxLine,
+	}
+}
+
+// lineError is a line that couldn't be decoded. Reading can carry on past it.
+type lineError struct {
+	err error
+}
+
+func (e *lineError) Error() string { return e.err.Error() }
+
+func (e *lineError) Unwrap() error { return e.err }
+
+// isLineError reports whether err is confined to one line
+func isLineError(err error) bool {
+	var lineErr *lineError
+	return errors.As(err, &lineErr)
+}
+
+// Next decodes the next non-empty line into msg and reports whether the line
+// was longer than the limit. It returns io.EOF after the last line, and a
+// lineError when just this line couldn't be decoded.
+func (r *jsonlReader) Next(msg *JSONLMessage) (oversized bool, err error) {
+	for {
+		r.offset += r.size
+		r.size = 0
+		r.line++
+
+		var buf []byte
+		for {
+			chunk, err := r.reader.ReadSlice('\n')
+			buf = append(buf, chunk...)
+			r.size += int64(len(chunk))
+			if err == bufio.ErrBufferFull {
+				if len(buf) > r.maxLine {
+					return true, r.decodeOversized(buf, msg)
+				}
+				continue
+			}
+			if err == io.EOF && len(buf) == 0 {
+				return false, io.EOF
+			}
+			if err != nil && err != io.EOF {
+				return false, err
+			}
+			break
+		}
+
+		if len(bytes.TrimSpace(buf)) == 0 {
+			continue
+		}
+		if err := json.Unmarshal(buf, msg); err != nil {
+			return len(buf) > r.maxLine, &lineError{err: err}
+		}
+		return len(buf) > r.maxLine, nil
+	}
+}
+
+// decodeOversized decodes a line from the part already buffered and the rest
+// of the line still in the reader, then skips whatever the decoder left
+func (r *jsonlReader) decodeOversized(head []byte, msg *JSONLMessage) error {
+	rest := &lineRemainder{reader: r.reader}
+	decodeErr := json.NewDecoder(io.MultiReader(bytes.NewReader(head), rest)).Decode(msg)
+
+	if _, err := io.Copy(io.Discard, rest); err != nil {
+		return err
+	}
+	r.size += rest.read
+	if decodeErr != nil {
+		return &lineError{err: decodeErr}
+	}
+	return nil
+}
+
+// lineRemainder reads from a buffered reader up to and including the next
+// newline
+type lineRemainder struct {
+	reader *bufio.Reader
+	read   int64
+	done   bool
+}
+
+func (l *lineRemainder) Read(p []byte) (int, error) {
+	if l.done {
+		return 0, io.EOF
+	}
+
+	peeked, err := l.reader.Peek(min(len(p), l.reader.Buffered()+1))
+	if len(peeked) == 0 {
+		l.done = true
+		if err == nil || err == io.EOF {
+			return 0, io.EOF
+		}
+		return 0, err
+	}
+	if i := bytes.IndexByte(peeked, '\n'); i >= 0 {
+		peeked = peeked[:i+1]
+		l.done = true
+	}
+
+	n := copy(p, peeked)
+	l.reader.Discard(n)
+	l.read += int64(n)
+	return n, nil
+}
@@ -0,0 +1,98 @@
+package database
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func jsonlLine(id, content string) string {
+	return fmt.Sprintf(`{"sessionId":"s1","type":"user","message":{"role":"user","content":"%s"},"uuid":"%s","timestamp":"2025-01-01T10:00:00Z"}`, content, id) + "\n"
+}
+
+func TestJSONLReader_OversizedLines(t *testing.T) {
+	image := strings.Repeat("A", 200*1024)
+	broken := strings.TrimSuffix(jsonlLine("broken", image), "}\n") + "\n"
+	lines := []string{
+		jsonlLine("small", "hello"),
+		"\n",
+		jsonlLine("image", image),
+		broken,
+		jsonlLine("after", "still read"),
+	}
+	reader := newJSONLReader(strings.NewReader(strings.Join(lines, "")), 100*1024)
+
+	var msg JSONLMessage
+	if oversized, err := reader.Next(&msg); err != nil || oversized || msg.UUID != "small" {
+		t.Fatalf("Expected the small line, got %q oversized=%v (%v)", msg.UUID, oversized, err)
+	}
+
+	msg = JSONLMessage{}
+	oversized, err := reader.Next(&msg)
+	if err != nil || !oversized || msg.UUID != "image" || msg.Message.Content != image {
+		t.Fatalf("Expected the image line to be stream decoded, got %q oversized=%v (%v)", msg.UUID, oversized, err)
+	}
+	if want := int64(len(lines[0]) + len(lines[1])); reader.offset != want || reader.size != int64(len(lines[2])) {
+		t.Errorf("Expected offset %d and size %d, got %d and %d", want, len(lines[2]), reader.offset, reader.size)
+	}
+
+	oversized, err = reader.Next(&JSONLMessage{})
+	if !oversized || !isLineError(err) {
+		t.Fatalf("Expected a line error for the broken oversized line, got oversized=%v (%v)", oversized, err)
+	}
+
+	msg = JSONLMessage{}
+	if oversized, err := reader.Next(&msg); err != nil || oversized || msg.UUID != "after" {
+		t.Fatalf("Expected reading to carry on after the broken line, got %q oversized=%v (%v)", msg.UUID, oversized, err)
+	}
+	if _, err := reader.Next(&msg); err != io.EOF {
+		t.Errorf("Expected EOF, got %v", err)
+	}
+}
+
+func TestImporter_RecordsOversizedLines(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+	db.maxLineBytes = 100 * 1024
+
+	repo := NewSessionRepository(db, logger)
+	image := strings.Repeat("A", 200*1024)
+	broken := strings.TrimSuffix(jsonlLine("broken", image), "}\n") + "\n"
+	path := filepath.Join(t.TempDir(), "s1.jsonl")
+	if err := os.WriteFile(path, []byte(jsonlLine("image", image)+broken+jsonlLine("small", "hi")), 0644); err != nil {
+		t.Fatalf("Failed to write transcript: %v", err)
+	}
+
+	_, messages, err := NewImporter(repo, logger).ImportJSONLFile(path, ProjectInfo{})
+	if err != nil {
+		t.Fatalf("Failed to import: %v", err)
+	}
+	if messages != 2 {
+		t.Errorf("Expected the image and small messages, got %d", messages)
+	}
+
+	letters, err := repo.GetDeadLetters("", 10)
+	if err != nil {
+		t.Fatalf("Failed to get dead letters: %v", err)
+	}
+	if len(letters) != 2 {
+		t.Fatalf("Expected 2 dead letters, got %+v", letters)
+	}
+	outcomes := map[int64]string{}
+	for _, letter := range letters {
+		outcomes[letter.ByteOffset] = letter.Outcome
+	}
+	imageSize := int64(len(jsonlLine("image", image)))
+	if outcomes[0] != DeadLetterDecoded || outcomes[imageSize] != DeadLetterSkipped {
+		t.Errorf("Expected the image decoded and the broken line skipped, got %+v", letters)
+	}
+
+	// Importing again counts another occurrence instead of a new entry
+	NewImporter(repo, logger).ImportJSONLFile(path, ProjectInfo{})
+	if skipped, _ := repo.GetDeadLetters(DeadLetterSkipped, 10); len(skipped) != 1 || skipped[0].Occurrences != 2 {
+		t.Errorf("Expected one skipped line seen twice, got %+v", skipped)
+	}
+}
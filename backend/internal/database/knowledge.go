@@ -0,0 +1,202 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// StartKnowledgeExtractionRun records the start of an extraction run and
+// returns it with the time messages should be scanned from: the start of the
+// last completed run, or the zero time for a full scan
+func (r *SessionRepository) StartKnowledgeExtractionRun(fullScan bool) (*KnowledgeExtractionRun, time.Time, error) {
+	var since time.Time
+	if !fullScan {
+		err := r.db.Get(&since, `
+			SELECT start_time FROM knowledge_extraction_runs
+			WHERE status = 'completed'
+			ORDER BY start_time DESC LIMIT 1
+		`)
+		if err != nil && err != sql.ErrNoRows {
+			return nil, since, fmt.Errorf("failed to get last extraction run: %w", err)
+		}
+	}
+
+	run := &KnowledgeExtractionRun{
+		StartTime: time.Now().UTC(),
+		Status:    "running",
+		FullScan:  fullScan,
+	}
+	err := r.db.WriteOperation(func(tx *sqlx.Tx) error {
+		result, err := tx.Exec(`
+			INSERT INTO knowledge_extraction_runs (start_time, status, full_scan) VALUES (?, ?, ?)
+		`, run.StartTime, run.Status, run.FullScan)
+		if err != nil {
+			return err
+		}
+		id, err := result.LastInsertId()
+		run.ID = int(id)
+		return err
+	})
+	if err != nil {
+		return nil, since, fmt.Errorf("failed to record extraction run: %w", err)
+	}
+
+	return run, since, nil
+}
+
+// FinishKnowledgeExtractionRun records the outcome of an extraction run
+func (r *SessionRepository) FinishKnowledgeExtractionRun(run *KnowledgeExtractionRun) error {
+	now := time.Now().UTC()
+	run.EndTime = &now
+	return r.db.WriteOperation(func(tx *sqlx.Tx) error {
+		_, err := tx.NamedExec(`
+			UPDATE knowledge_extraction_runs SET
+				end_time = :end_time,
+				status = :status,
+				sessions_scanned = :sessions_scanned,
+				entries_found = :entries_found,
+				error_message = :error_message
+			WHERE id = :id
+		`, run)
+		return err
+	})
+}
+
+// GetLastKnowledgeExtractionRun returns the most recent extraction run, or nil if none has run
+func (r *SessionRepository) GetLastKnowledgeExtractionRun() (*KnowledgeExtractionRun, error) {
+	var run KnowledgeExtractionRun
+	err := r.db.Get(&run, `SELECT * FROM knowledge_extraction_runs ORDER BY id DESC LIMIT 1`)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get extraction run: %w", err)
+	}
+	return &run, nil
+}
+
+// GetSessionsWithNewMessages returns the IDs of sessions with messages imported after since
+func (r *SessionRepository) GetSessionsWithNewMessages(since time.Time) ([]string, error) {
+	var sessionIDs []string
+	err := r.db.Select(&sessionIDs, `
+		SELECT DISTINCT session_id FROM messages WHERE created_at >= ?
+	`, since.UTC().Format("2006-01-02 15:04:05"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sessions with new messages: %w", err)
+	}
+	return sessionIDs, nil
+}
+
+// GetTranscript returns the main-thread messages of a session in order
+func (r *SessionRepository) GetTranscript(sessionID string) ([]TranscriptMessage, error) {
+	var messages []TranscriptMessage
+	err := r.db.Select(&messages, `
+		SELECT id, session_id, COALESCE(role, '') AS role, COALESCE(content, '') AS content, timestamp
+		FROM messages
+		WHERE session_id = ? AND COALESCE(is_sidechain, FALSE) = FALSE
+		ORDER BY timestamp ASC
+	`, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transcript: %w", err)
+	}
+	return messages, nil
+}
+
+// SaveKnowledgeEntry adds a source to the entry with the same kind and
+// normalized key, creating the entry if it does not exist. The newest source
+// supplies the entry's body and project. Saving the same source twice has no effect.
+func (r *SessionRepository) SaveKnowledgeEntry(entry *KnowledgeEntry, source *KnowledgeSource) error {
+	return r.db.WriteOperation(func(tx *sqlx.Tx) error {
+		_, err := tx.Exec(`
+			INSERT INTO knowledge_entries (
+				id, kind, normalized_key, title, body, project_name, occurrences, first_seen, last_seen
+			) VALUES (?, ?, ?, ?, ?, ?, 0, ?, ?)
+			ON CONFLICT(kind, normalized_key) DO UPDATE SET
+				body = CASE WHEN excluded.last_seen >= knowledge_entries.last_seen
+					THEN COALESCE(excluded.body, knowledge_entries.body) ELSE knowledge_entries.body END,
+				project_name = CASE WHEN excluded.last_seen >= knowledge_entries.last_seen
+					THEN excluded.project_name ELSE knowledge_entries.project_name END,
+				first_seen = MIN(knowledge_entries.first_seen, excluded.first_seen),
+				last_seen = MAX(knowledge_entries.last_seen, excluded.last_seen),
+				updated_at = CURRENT_TIMESTAMP
+		`, uuid.New().String(), entry.Kind, entry.NormalizedKey, entry.Title, entry.Body,
+			source.ProjectName, source.Timestamp, source.Timestamp)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Get(&source.EntryID, `
+			SELECT id FROM knowledge_entries WHERE kind = ? AND normalized_key = ?
+		`, entry.Kind, entry.NormalizedKey); err != nil {
+			return err
+		}
+
+		if _, err := tx.NamedExec(`
+			INSERT OR IGNORE INTO knowledge_sources (entry_id, message_id, session_id, project_name, timestamp)
+			VALUES (:entry_id, :message_id, :session_id, :project_name, :timestamp)
+		`, source); err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(`
+			UPDATE knowledge_entries
+			SET occurrences = (SELECT COUNT(DISTINCT session_id) FROM knowledge_sources WHERE entry_id = ?)
+			WHERE id = ?
+		`, source.EntryID, source.EntryID)
+		return err
+	})
+}
+
+// SearchKnowledge returns knowledge entries matching a text query, most recurring first.
+// Empty filters match everything.
+func (r *SessionRepository) SearchKnowledge(query, kind, projectName string, minOccurrences, limit int) ([]KnowledgeEntry, error) {
+	sqlQuery := `SELECT * FROM knowledge_entries WHERE occurrences >= ?`
+	args := []interface{}{minOccurrences}
+	if query != "" {
+		sqlQuery += ` AND (title LIKE ? OR body LIKE ?)`
+		pattern := "%" + query + "%"
+		args = append(args, pattern, pattern)
+	}
+	if kind != "" {
+		sqlQuery += ` AND kind = ?`
+		args = append(args, kind)
+	}
+	if projectName != "" {
+		sqlQuery += ` AND id IN (SELECT entry_id FROM knowledge_sources WHERE project_name = ?)`
+		args = append(args, projectName)
+	}
+	sqlQuery += ` ORDER BY occurrences DESC, last_seen DESC LIMIT ?`
+	args = append(args, limit)
+
+	entries := []KnowledgeEntry{}
+	if err := r.db.Select(&entries, sqlQuery, args...); err != nil {
+		return nil, fmt.Errorf("failed to search knowledge: %w", err)
+	}
+	return entries, nil
+}
+
+// GetKnowledgeEntry returns a knowledge entry with the messages it was extracted from
+func (r *SessionRepository) GetKnowledgeEntry(id string) (*KnowledgeEntry, []KnowledgeSource, error) {
+	var entry KnowledgeEntry
+	err := r.db.Get(&entry, `SELECT * FROM knowledge_entries WHERE id = ?`, id)
+	if err == sql.ErrNoRows {
+		return nil, nil, fmt.Errorf("knowledge entry not found: %s", id)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get knowledge entry: %w", err)
+	}
+
+	sources := []KnowledgeSource{}
+	err = r.db.Select(&sources, `
+		SELECT * FROM knowledge_sources WHERE entry_id = ? ORDER BY timestamp DESC
+	`, id)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get knowledge sources: %w", err)
+	}
+
+	return &entry, sources, nil
+}
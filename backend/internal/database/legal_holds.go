@@ -0,0 +1,185 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// ErrLegalHold is returned when an operation would prune or archive a session under legal hold
+var ErrLegalHold = errors.New("session is under legal hold")
+
+// PlaceLegalHold puts a session under legal hold. A session can only have one active hold.
+func (r *SessionRepository) PlaceLegalHold(sessionID, reason, placedBy string) (*LegalHold, error) {
+	if _, err := r.GetSessionByID(sessionID); err != nil {
+		return nil, fmt.Errorf("session not found: %s", sessionID)
+	}
+	if existing, err := r.GetLegalHold(sessionID); err == nil {
+		return nil, fmt.Errorf("session is already under legal hold since %s: %s", existing.PlacedAt.Format(time.RFC3339), sessionID)
+	}
+
+	hold := &LegalHold{
+		ID:        uuid.New().String(),
+		SessionID: sessionID,
+		Reason:    reason,
+		PlacedBy:  placedBy,
+		PlacedAt:  time.Now().UTC(),
+	}
+	err := r.db.WriteOperation(func(tx *sqlx.Tx) error {
+		_, err := tx.NamedExec(`
+			INSERT INTO legal_holds (id, session_id, reason, placed_by, placed_at)
+			VALUES (:id, :session_id, :reason, :placed_by, :placed_at)
+		`, hold)
+		if err != nil {
+			return err
+		}
+		return logHoldActivity(tx, sessionID, "legal_hold_placed",
+			fmt.Sprintf("Legal hold placed by %s: %s", placedBy, reason), hold.PlacedAt)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to place legal hold: %w", err)
+	}
+	return hold, nil
+}
+
+// ReleaseLegalHold releases a session's active legal hold
+func (r *SessionRepository) ReleaseLegalHold(sessionID, releasedBy string) (*LegalHold, error) {
+	hold, err := r.GetLegalHold(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	hold.ReleasedBy = &releasedBy
+	hold.ReleasedAt = &now
+	err = r.db.WriteOperation(func(tx *sqlx.Tx) error {
+		_, err := tx.Exec(`
+			UPDATE legal_holds SET released_by = ?, released_at = ? WHERE id = ?
+		`, releasedBy, now, hold.ID)
+		if err != nil {
+			return err
+		}
+		return logHoldActivity(tx, sessionID, "legal_hold_released",
+			fmt.Sprintf("Legal hold released by %s", releasedBy), now)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to release legal hold: %w", err)
+	}
+	return hold, nil
+}
+
+// logHoldActivity records a legal hold change in the session's audit trail
+func logHoldActivity(tx *sqlx.Tx, sessionID, activityType, details string, at time.Time) error {
+	_, err := tx.Exec(`
+		INSERT INTO activity_log (session_id, activity_type, details, timestamp)
+		VALUES (?, ?, ?, ?)
+	`, sessionID, activityType, details, at)
+	return err
+}
+
+// GetLegalHold returns a session's active legal hold
+func (r *SessionRepository) GetLegalHold(sessionID string) (*LegalHold, error) {
+	var hold LegalHold
+	err := r.db.Get(&hold, `
+		SELECT * FROM legal_holds WHERE session_id = ? AND released_at IS NULL
+	`, sessionID)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("legal hold not found: %s", sessionID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get legal hold: %w", err)
+	}
+	return &hold, nil
+}
+
+// GetLegalHolds returns legal holds, newest first, including released ones if requested
+func (r *SessionRepository) GetLegalHolds(includeReleased bool) ([]LegalHold, error) {
+	query := `SELECT * FROM legal_holds`
+	if !includeReleased {
+		query += ` WHERE released_at IS NULL`
+	}
+	query += ` ORDER BY placed_at DESC`
+
+	holds := []LegalHold{}
+	if err := r.db.Select(&holds, query); err != nil {
+		return nil, fmt.Errorf("failed to get legal holds: %w", err)
+	}
+	return holds, nil
+}
+
+// CheckNotOnLegalHold returns ErrLegalHold if the session has an active hold.
+// Anything that prunes or archives sessions must call it first.
+func (r *SessionRepository) CheckNotOnLegalHold(sessionID string) error {
+	return checkNotOnLegalHold(r.db, sessionID)
+}
+
+// checkNotOnLegalHold is CheckNotOnLegalHold for q, which is a write
+// transaction when the hold must not be placed between the check and the
+// write it guards
+func checkNotOnLegalHold(q sqlx.Queryer, sessionID string) error {
+	var held bool
+	err := sqlx.Get(q, &held, `
+		SELECT EXISTS(SELECT 1 FROM legal_holds WHERE session_id = ? AND released_at IS NULL)
+	`, sessionID)
+	if err != nil {
+		return fmt.Errorf("failed to check legal hold: %w", err)
+	}
+	if held {
+		return fmt.Errorf("%w: %s", ErrLegalHold, sessionID)
+	}
+	return nil
+}
+
+// GetComplianceRecords gathers everything stored about a session for a compliance export
+func (r *SessionRepository) GetComplianceRecords(sessionID string) (*ComplianceRecords, error) {
+	session, err := r.GetSessionByID(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	records := &ComplianceRecords{Session: session}
+
+	if records.Messages, err = r.GetSessionMessages(sessionID); err != nil {
+		return nil, err
+	}
+	if records.Hashes, err = r.GetMessageHashes(sessionID); err != nil {
+		return nil, err
+	}
+
+	err = r.db.Select(&records.ToolResults, `
+		SELECT
+			id, message_id, session_id, COALESCE(tool_name, '') AS tool_name, file_path, absolute_path,
+			COALESCE(result_data, '') AS result_data, timestamp, created_at
+		FROM tool_results WHERE session_id = ? ORDER BY timestamp ASC
+	`, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tool results: %w", err)
+	}
+
+	err = r.db.Select(&records.Activity, `
+		SELECT id, session_id, activity_type, COALESCE(details, '') AS details, timestamp, created_at
+		FROM activity_log WHERE session_id = ? ORDER BY timestamp ASC
+	`, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get activity log: %w", err)
+	}
+
+	err = r.db.Select(&records.LegalHolds, `
+		SELECT * FROM legal_holds WHERE session_id = ? ORDER BY placed_at ASC
+	`, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get legal holds: %w", err)
+	}
+
+	if review, err := r.GetSessionReview(sessionID); err == nil {
+		records.Review = review
+	}
+	if records.Comments, err = r.GetSessionReviewComments(sessionID); err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
@@ -0,0 +1,77 @@
+package database
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSessionRepository_LegalHolds(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewSessionRepository(db, logger)
+
+	now := time.Now()
+	if err := repo.UpsertSession(&Session{
+		ID:           "held",
+		ProjectPath:  "/test/project",
+		ProjectName:  "project",
+		StartTime:    now.Add(-time.Hour),
+		LastActivity: now,
+		Status:       "completed",
+	}); err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	if err := repo.UpsertMessage(&Message{ID: "held-1", SessionID: "held", Role: "user", Content: `"Refactor the importer"`, Timestamp: now}); err != nil {
+		t.Fatalf("Failed to create message: %v", err)
+	}
+
+	if err := repo.CheckNotOnLegalHold("held"); err != nil {
+		t.Fatalf("Expected no hold yet, got %v", err)
+	}
+
+	hold, err := repo.PlaceLegalHold("held", "Litigation 2025-17", "counsel")
+	if err != nil {
+		t.Fatalf("Failed to place hold: %v", err)
+	}
+	if _, err := repo.PlaceLegalHold("held", "Again", "counsel"); err == nil {
+		t.Error("Expected error placing a second active hold")
+	}
+	if err := repo.CheckNotOnLegalHold("held"); !errors.Is(err, ErrLegalHold) {
+		t.Errorf("Expected ErrLegalHold, got %v", err)
+	}
+
+	records, err := repo.GetComplianceRecords("held")
+	if err != nil {
+		t.Fatalf("Failed to get compliance records: %v", err)
+	}
+	if len(records.Messages) != 1 || len(records.LegalHolds) != 1 || len(records.Activity) != 1 {
+		t.Errorf("Unexpected compliance records: %+v", records)
+	}
+
+	released, err := repo.ReleaseLegalHold("held", "counsel")
+	if err != nil {
+		t.Fatalf("Failed to release hold: %v", err)
+	}
+	if released.ID != hold.ID || released.ReleasedAt == nil {
+		t.Errorf("Unexpected released hold: %+v", released)
+	}
+	if err := repo.CheckNotOnLegalHold("held"); err != nil {
+		t.Errorf("Expected released hold not to block, got %v", err)
+	}
+
+	active, _ := repo.GetLegalHolds(false)
+	all, _ := repo.GetLegalHolds(true)
+	if len(active) != 0 || len(all) != 1 {
+		t.Errorf("Expected 0 active and 1 total hold, got %d and %d", len(active), len(all))
+	}
+
+	// A released hold can be replaced by a new one
+	if _, err := repo.PlaceLegalHold("held", "New matter", "counsel"); err != nil {
+		t.Errorf("Failed to place a new hold after release: %v", err)
+	}
+	if _, err := repo.PlaceLegalHold("missing", "Reason", "counsel"); err == nil {
+		t.Error("Expected error for unknown session")
+	}
+}
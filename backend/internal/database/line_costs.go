@@ -0,0 +1,80 @@
+package database
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// GetLineCosts returns each project's spend per UTC month of messages sent
+// in [from, to), with the lines added and removed by the file edits made in
+// the same period. Lines are counted from the edits' recorded parameters,
+// the same way GetFileHistory diffs them.
+func (r *SessionRepository) GetLineCosts(from, to time.Time) ([]LineCost, error) {
+	costs := []LineCost{}
+	err := r.db.Select(&costs, `
+		SELECT
+			s.project_name,
+			strftime('%Y-%m', m.timestamp) AS month,
+			COALESCE(SUM(tu.estimated_cost), 0.0) AS cost_usd
+		FROM token_usage tu
+		JOIN messages m ON m.id = tu.message_id
+		JOIN sessions s ON s.id = m.session_id
+		WHERE m.timestamp >= ? AND m.timestamp < ?
+		GROUP BY s.project_name, month
+	`, from.UTC(), to.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get project costs: %w", err)
+	}
+
+	var edits []struct {
+		ProjectName string    `db:"project_name"`
+		ToolName    string    `db:"tool_name"`
+		ResultData  string    `db:"result_data"`
+		Timestamp   time.Time `db:"timestamp"`
+	}
+	err = r.db.Select(&edits, `
+		SELECT s.project_name, tr.tool_name, COALESCE(tr.result_data, '') AS result_data, tr.timestamp
+		FROM tool_results tr
+		JOIN sessions s ON s.id = tr.session_id
+		WHERE tr.tool_name IN ('Edit', 'Write', 'MultiEdit')
+		AND tr.timestamp >= ? AND tr.timestamp < ?
+	`, from.UTC(), to.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get file edits: %w", err)
+	}
+
+	index := make(map[[2]string]int, len(costs))
+	for i, cost := range costs {
+		index[[2]string{cost.ProjectName, cost.Month}] = i
+	}
+	for _, edit := range edits {
+		key := [2]string{edit.ProjectName, edit.Timestamp.UTC().Format("2006-01")}
+		i, ok := index[key]
+		if !ok {
+			i = len(costs)
+			index[key] = i
+			costs = append(costs, LineCost{ProjectName: key[0], Month: key[1]})
+		}
+		costs[i].Edits++
+		for _, replacement := range editReplacements(edit.ToolName, edit.ResultData) {
+			added, removed := lineChanges(replacement[0], replacement[1])
+			costs[i].LinesAdded += added
+			costs[i].LinesRemoved += removed
+		}
+	}
+
+	for i := range costs {
+		if costs[i].LinesAdded > 0 {
+			perKLOC := costs[i].CostUSD / float64(costs[i].LinesAdded) * 1000
+			costs[i].CostPerKLOC = &perKLOC
+		}
+	}
+	sort.Slice(costs, func(i, j int) bool {
+		if costs[i].Month != costs[j].Month {
+			return costs[i].Month > costs[j].Month
+		}
+		return costs[i].ProjectName < costs[j].ProjectName
+	})
+	return costs, nil
+}
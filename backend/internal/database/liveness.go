@@ -0,0 +1,64 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// SetSessionLiveness records a session's state as reported by a hook and
+// applies it to the session, reporting whether the session's is_active
+// changed. A session that has not been imported yet takes the state when it
+// is.
+func (r *SessionRepository) SetSessionLiveness(liveness *SessionLiveness) (bool, error) {
+	changed := false
+	err := r.db.WriteOperation(func(tx *sqlx.Tx) error {
+		var wasActive bool
+		err := tx.Get(&wasActive, `SELECT is_active FROM sessions WHERE id = ?`, liveness.SessionID)
+		if err != nil && err != sql.ErrNoRows {
+			return fmt.Errorf("failed to get session state: %w", err)
+		}
+		exists := err == nil
+
+		if _, err := tx.Exec(`
+			INSERT INTO session_liveness (session_id, is_active, event, changed_at)
+			VALUES (?, ?, ?, ?)
+			ON CONFLICT(session_id) DO UPDATE SET
+				is_active = excluded.is_active,
+				event = excluded.event,
+				changed_at = excluded.changed_at
+		`, liveness.SessionID, liveness.IsActive, liveness.Event, liveness.ChangedAt); err != nil {
+			return fmt.Errorf("failed to record session liveness: %w", err)
+		}
+		if !exists {
+			return nil
+		}
+
+		status := "completed"
+		if liveness.IsActive {
+			status = "active"
+		}
+		if _, err := tx.Exec(`
+			UPDATE sessions SET is_active = ?, status = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?
+		`, liveness.IsActive, status, liveness.SessionID); err != nil {
+			return fmt.Errorf("failed to update session state: %w", err)
+		}
+		changed = wasActive != liveness.IsActive
+		return nil
+	})
+	return changed, err
+}
+
+// GetSessionLiveness returns a session's state as last reported by its hooks
+func (r *SessionRepository) GetSessionLiveness(sessionID string) (*SessionLiveness, error) {
+	var liveness SessionLiveness
+	err := r.db.Get(&liveness, `SELECT * FROM session_liveness WHERE session_id = ?`, sessionID)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("session liveness not found: %s", sessionID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session liveness: %w", err)
+	}
+	return &liveness, nil
+}
@@ -0,0 +1,73 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSessionRepository_SetSessionLiveness(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewSessionRepository(db, logger)
+
+	now := time.Now()
+	upsert := func(lastActivity time.Time, active bool) {
+		status := "completed"
+		if active {
+			status = "active"
+		}
+		if err := repo.UpsertSession(&Session{
+			ID:           "s1",
+			ProjectPath:  "/test/project",
+			ProjectName:  "project",
+			StartTime:    now.Add(-time.Hour),
+			LastActivity: lastActivity,
+			IsActive:     active,
+			Status:       status,
+		}); err != nil {
+			t.Fatalf("Failed to upsert session: %v", err)
+		}
+	}
+	isActive := func() bool {
+		session, err := repo.GetSessionByID("s1")
+		if err != nil {
+			t.Fatalf("Failed to get session: %v", err)
+		}
+		return session.IsActive
+	}
+
+	// A session started before its transcript is imported takes the reported state
+	if _, err := repo.SetSessionLiveness(&SessionLiveness{SessionID: "s1", IsActive: true, Event: "SessionStart", ChangedAt: now}); err != nil {
+		t.Fatalf("Failed to set liveness: %v", err)
+	}
+	upsert(now.Add(-10*time.Minute), false)
+	if !isActive() {
+		t.Error("Expected reported state to override the import's estimate")
+	}
+
+	changed, err := repo.SetSessionLiveness(&SessionLiveness{SessionID: "s1", IsActive: false, Event: "Stop", ChangedAt: now})
+	if err != nil || !changed {
+		t.Fatalf("Expected Stop to change the session's state, got %v (%v)", changed, err)
+	}
+	if changed, _ := repo.SetSessionLiveness(&SessionLiveness{SessionID: "s1", IsActive: false, Event: "SessionEnd", ChangedAt: now}); changed {
+		t.Error("Expected no change for a session already inactive")
+	}
+
+	// The final messages imported after Stop do not mark the session active again
+	upsert(now.Add(-time.Second), true)
+	if isActive() {
+		t.Error("Expected Stop to keep the session inactive")
+	}
+
+	// Activity well after the last hook event falls back to the estimate
+	upsert(now.Add(5*time.Minute), true)
+	if !isActive() {
+		t.Error("Expected a stale reported state to be ignored")
+	}
+
+	liveness, err := repo.GetSessionLiveness("s1")
+	if err != nil || liveness.Event != "SessionEnd" || liveness.IsActive {
+		t.Errorf("Unexpected liveness: %+v (%v)", liveness, err)
+	}
+}
@@ -0,0 +1,112 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// messageColumns selects every message column with NULLs replaced so rows scan into Message
+const messageColumns = `
+	id, session_id, parent_uuid, COALESCE(is_sidechain, FALSE) AS is_sidechain,
+	COALESCE(user_type, '') AS user_type, COALESCE(cwd, '') AS cwd,
+	COALESCE(version, '') AS version, COALESCE(type, '') AS type,
+	COALESCE(role, '') AS role, COALESCE(content, '') AS content,
+	request_id, timestamp, created_at`
+
+// GetSessionMessages returns every message of a session in order
+func (r *SessionRepository) GetSessionMessages(sessionID string) ([]Message, error) {
+	messages := []Message{}
+	err := r.db.Select(&messages, `
+		SELECT `+messageColumns+`
+		FROM messages WHERE session_id = ? ORDER BY timestamp ASC, id ASC
+	`, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get messages: %w", err)
+	}
+	return messages, nil
+}
+
+// GetSessionsWithUnhashedMessages returns up to limit sessions with messages
+// that have not been added to their hash chain
+func (r *SessionRepository) GetSessionsWithUnhashedMessages(limit int) ([]string, error) {
+	var sessionIDs []string
+	err := r.db.Select(&sessionIDs, `
+		SELECT DISTINCT m.session_id
+		FROM messages m
+		LEFT JOIN message_hashes mh ON mh.message_id = m.id
+		WHERE mh.message_id IS NULL
+		LIMIT ?
+	`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find unhashed messages: %w", err)
+	}
+	return sessionIDs, nil
+}
+
+// GetUnhashedMessages returns a session's messages that are not yet in its hash chain, in order
+func (r *SessionRepository) GetUnhashedMessages(sessionID string) ([]Message, error) {
+	messages := []Message{}
+	err := r.db.Select(&messages, `
+		SELECT `+messageColumns+`
+		FROM messages
+		WHERE session_id = ? AND id NOT IN (SELECT message_id FROM message_hashes WHERE session_id = ?)
+		ORDER BY timestamp ASC, id ASC
+	`, sessionID, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get unhashed messages: %w", err)
+	}
+	return messages, nil
+}
+
+// GetSessionHash returns the head of a session's hash chain
+func (r *SessionRepository) GetSessionHash(sessionID string) (*SessionHash, error) {
+	var head SessionHash
+	err := r.db.Get(&head, `SELECT * FROM session_hashes WHERE session_id = ?`, sessionID)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("session hash not found: %s", sessionID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session hash: %w", err)
+	}
+	return &head, nil
+}
+
+// GetMessageHashes returns a session's hash chain in order
+func (r *SessionRepository) GetMessageHashes(sessionID string) ([]MessageHash, error) {
+	hashes := []MessageHash{}
+	err := r.db.Select(&hashes, `
+		SELECT * FROM message_hashes WHERE session_id = ? ORDER BY seq ASC
+	`, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get message hashes: %w", err)
+	}
+	return hashes, nil
+}
+
+// AppendMessageHashes extends a session's hash chain and moves its head. It
+// fails if the chain was extended concurrently, since sequence numbers are unique.
+func (r *SessionRepository) AppendMessageHashes(hashes []MessageHash, head *SessionHash) error {
+	return r.db.WriteOperation(func(tx *sqlx.Tx) error {
+		for i := range hashes {
+			_, err := tx.NamedExec(`
+				INSERT INTO message_hashes (message_id, session_id, seq, content_hash, chain_hash)
+				VALUES (:message_id, :session_id, :seq, :content_hash, :chain_hash)
+			`, &hashes[i])
+			if err != nil {
+				return fmt.Errorf("failed to store hash of message %s: %w", hashes[i].MessageID, err)
+			}
+		}
+
+		_, err := tx.Exec(`
+			INSERT INTO session_hashes (session_id, message_count, head_hash)
+			VALUES (?, ?, ?)
+			ON CONFLICT(session_id) DO UPDATE SET
+				message_count = excluded.message_count,
+				head_hash = excluded.head_hash,
+				updated_at = CURRENT_TIMESTAMP
+		`, head.SessionID, head.MessageCount, head.HeadHash)
+		return err
+	})
+}
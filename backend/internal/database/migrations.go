@@ -0,0 +1,227 @@
+package database
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Migrations 001-003 and 007 added columns that applySchemaUpdates now adds
+// and 008 a view schema.sql created, so their versions aren't registered.
+// 008's view is what 011 reverts to, 011's what 012 reverts to, 012's
+// what 013 reverts to and 013's what 017 reverts to.
+//
+//go:embed migrations/005_fix_total_tokens.sql migrations/006_update_session_project_paths.sql migrations/008_update_session_summary_view.sql migrations/011_session_summary_view.sql migrations/012_session_summary_view.sql migrations/013_session_summary_view.sql
+var migrationFiles embed.FS
+
+// registeredMigrations returns the migrations applied on top of schema.sql.
+// New migrations are appended with the next version and must also be
+// reflected in schema.sql.
+func registeredMigrations() []Migration {
+	return []Migration{
+		{Version: 4, Name: "recalculate_token_costs", Up: recalculateTokenCosts},
+		{Version: 5, Name: "fix_total_tokens", Up: sqlMigration("migrations/005_fix_total_tokens.sql")},
+		{Version: 6, Name: "update_session_project_paths", Up: sqlMigration("migrations/006_update_session_project_paths.sql")},
+		{Version: 9, Name: "extract_tool_results", Up: extractToolResults, Down: removeExtractedToolResults},
+		{Version: 10, Name: "link_resumed_sessions", Up: linkAllResumedSessions, Down: unlinkResumedSessions},
+		{Version: 11, Name: "add_git_to_session_summary", Up: recreateSessionSummary, Down: sqlMigration("migrations/008_update_session_summary_view.sql")},
+		{Version: 12, Name: "add_cache_savings", Up: addCacheSavings, Down: sqlMigration("migrations/011_session_summary_view.sql")},
+		{Version: 13, Name: "add_archived_to_session_summary", Up: recreateSessionSummary, Down: sqlMigration("migrations/012_session_summary_view.sql")},
+		{Version: 14, Name: "create_projects", Up: createMissingProjects, Down: removeProjects},
+		{Version: 15, Name: "record_commands", Up: rescanToolCalls, Down: removeCommands},
+		{Version: 16, Name: "relative_tool_result_paths", Up: relativizeToolResultPaths, Down: restoreAbsoluteToolResultPaths},
+		{Version: 17, Name: "materialize_session_summary", Up: materializeSessionSummary, Down: sqlMigration("migrations/013_session_summary_view.sql")},
+		{Version: 18, Name: "derive_environment_git_remotes", Up: clearDetectedGitRemotes, Down: copyDetectedGitRemotes},
+	}
+}
+
+// lastManualMigration is the last of the data fixes that were run by hand
+// before the migrator. A database from before the migrator has had them or
+// never needed them, so they are recorded as applied instead of run again.
+const lastManualMigration = 6
+
+// manualMigrations returns the registered migrations up to
+// lastManualMigration
+func manualMigrations() []Migration {
+	var manual []Migration
+	for _, m := range registeredMigrations() {
+		if m.Version <= lastManualMigration {
+			manual = append(manual, m)
+		}
+	}
+	return manual
+}
+
+// sqlMigration runs an embedded SQL file
+func sqlMigration(name string) func(tx *sqlx.Tx) error {
+	return func(tx *sqlx.Tx) error {
+		query, err := migrationFiles.ReadFile(name)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		_, err = tx.Exec(string(query))
+		return err
+	}
+}
+
+// recalculateTokenCosts prices every token usage row from the pricing
+// table. It replaced a SQL file with its own copy of the prices.
+func recalculateTokenCosts(tx *sqlx.Tx) error {
+	_, err := repriceTokenUsage(tx)
+	return err
+}
+
+// extractedToolResultMarker tags the tool results extractToolResults adds, so
+// reverting it removes only those
+const extractedToolResultMarker = "extract_tool_results"
+
+// extractToolResults backfills tool_results with the file-modifying tool calls
+// found in assistant messages, replacing the placeholder 'unknown' rows
+// written by early importers
+func extractToolResults(tx *sqlx.Tx) error {
+	var messages []struct {
+		ID        string    `db:"id"`
+		SessionID string    `db:"session_id"`
+		Content   string    `db:"content"`
+		Timestamp time.Time `db:"timestamp"`
+	}
+	err := tx.Select(&messages, `
+		SELECT id, session_id, content, timestamp
+		FROM messages
+		WHERE role = 'assistant'
+		AND content LIKE '%"file_path"%'
+		AND (
+			content LIKE '%"name":"Edit"%'
+			OR content LIKE '%"name":"Write"%'
+			OR content LIKE '%"name":"MultiEdit"%'
+			OR content LIKE '%"name":"NotebookEdit"%'
+			OR content LIKE '%"name":"NotebookWrite"%'
+			OR content LIKE '%<invoke name=%'
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to get messages: %w", err)
+	}
+
+	for _, msg := range messages {
+		for _, call := range ExtractToolCallsFromMessage(msg.Content, msg.Timestamp) {
+			if !isFileModifyingTool(call.ToolName) || call.FilePath == "" {
+				continue
+			}
+
+			resultData, _ := json.Marshal(map[string]interface{}{
+				"tool_name":  call.ToolName,
+				"parameters": call.Parameters,
+				"extracted":  true,
+				"migration":  extractedToolResultMarker,
+			})
+			_, err := tx.Exec(`
+				INSERT INTO tool_results (message_id, session_id, tool_name, file_path, result_data, timestamp)
+				SELECT ?, ?, ?, ?, ?, ?
+				WHERE NOT EXISTS (
+					SELECT 1 FROM tool_results WHERE message_id = ? AND tool_name = ? AND file_path = ?
+				)
+			`, msg.ID, msg.SessionID, call.ToolName, call.FilePath, string(resultData), msg.Timestamp,
+				msg.ID, call.ToolName, call.FilePath)
+			if err != nil {
+				return fmt.Errorf("failed to insert tool result for message %s: %w", msg.ID, err)
+			}
+		}
+	}
+
+	_, err = tx.Exec(`DELETE FROM tool_results WHERE tool_name = 'unknown'`)
+	return err
+}
+
+// removeExtractedToolResults reverts extractToolResults. The 'unknown' rows
+// it replaced carried nothing worth restoring.
+func removeExtractedToolResults(tx *sqlx.Tx) error {
+	_, err := tx.Exec(`DELETE FROM tool_results WHERE result_data LIKE ?`, `%"migration":"`+extractedToolResultMarker+`"%`)
+	return err
+}
+
+// linkAllResumedSessions links the resume chains among sessions imported
+// before links were detected on import
+func linkAllResumedSessions(tx *sqlx.Tx) error {
+	_, err := linkResumedSessions(tx, nil)
+	return err
+}
+
+// unlinkResumedSessions reverts linkAllResumedSessions. Imports detect the
+// links again.
+func unlinkResumedSessions(tx *sqlx.Tx) error {
+	_, err := tx.Exec(`DELETE FROM session_links`)
+	return err
+}
+
+// rescanToolCalls forgets which sessions had their tool calls extracted, so
+// the next refresh records the commands of sessions scanned before commands
+// were
+func rescanToolCalls(tx *sqlx.Tx) error {
+	_, err := tx.Exec(`DELETE FROM tool_call_scans`)
+	return err
+}
+
+// removeCommands reverts rescanToolCalls. The table stays, as schema.sql
+// creates it.
+func removeCommands(tx *sqlx.Tx) error {
+	_, err := tx.Exec(`DELETE FROM commands`)
+	return err
+}
+
+// removeProjects reverts createMissingProjects, along with any renames and
+// merges since. The tables stay, as schema.sql creates them.
+func removeProjects(tx *sqlx.Tx) error {
+	if _, err := tx.Exec(`DELETE FROM project_aliases`); err != nil {
+		return err
+	}
+	_, err := tx.Exec(`DELETE FROM projects`)
+	return err
+}
+
+// addCacheSavings prices the cache savings of token usage imported before
+// they were stored and recreates the session_summary view with each
+// session's total
+func addCacheSavings(tx *sqlx.Tx) error {
+	if err := recalculateTokenCosts(tx); err != nil {
+		return err
+	}
+	return recreateSessionSummary(tx)
+}
+
+// clearDetectedGitRemotes drops the git remotes stored with detected
+// environments, which now take theirs from sessions.git_remote. Only remotes
+// a client reported are kept.
+func clearDetectedGitRemotes(tx *sqlx.Tx) error {
+	_, err := tx.Exec(`UPDATE session_environments SET git_remote = NULL WHERE source = 'detected' AND git_remote IS NOT NULL`)
+	return err
+}
+
+// copyDetectedGitRemotes reverts clearDetectedGitRemotes, storing each
+// detected environment's remote from its session again
+func copyDetectedGitRemotes(tx *sqlx.Tx) error {
+	_, err := tx.Exec(`
+		UPDATE session_environments
+		SET git_remote = (SELECT NULLIF(s.git_remote, '') FROM sessions s WHERE s.id = session_environments.session_id)
+		WHERE source = 'detected' AND git_remote IS NULL
+	`)
+	return err
+}
+
+// recreateSessionSummary drops the session_summary view and runs schema.sql
+// again, which recreated it from the schema of the time. Since 017 the
+// summaries are a table that schema.sql creates and 017 fills.
+func recreateSessionSummary(tx *sqlx.Tx) error {
+	schemaSQL, err := schemaFiles.ReadFile("schema.sql")
+	if err != nil {
+		return fmt.Errorf("failed to read schema file: %w", err)
+	}
+	if _, err := tx.Exec(`DROP VIEW IF EXISTS session_summary`); err != nil {
+		return err
+	}
+	_, err = tx.Exec(string(schemaSQL))
+	return err
+}
@@ -0,0 +1,184 @@
+package database
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Migration is a versioned change applied on top of schema.sql. schema.sql
+// always describes the latest schema, so new databases are stamped with every
+// migration instead of running them; migrations bring older databases up to
+// date. Down is nil for data fixes that can't be undone.
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(tx *sqlx.Tx) error
+	Down    func(tx *sqlx.Tx) error
+}
+
+// MigrationStatus is a registered migration and when it was applied
+type MigrationStatus struct {
+	Version    int        `json:"version"`
+	Name       string     `json:"name"`
+	Reversible bool       `json:"reversible"`
+	AppliedAt  *time.Time `json:"applied_at,omitempty"`
+}
+
+// appliedMigration is a row of schema_migrations
+type appliedMigration struct {
+	Version   int       `db:"version"`
+	Name      string    `db:"name"`
+	AppliedAt time.Time `db:"applied_at"`
+}
+
+// isNewDatabase reports whether schema.sql has never run against the
+// database. It must be called before migrate.
+func (db *Database) isNewDatabase() (bool, error) {
+	var tables int
+	err := db.Get(&tables, `SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'sessions'`)
+	if err != nil {
+		return false, fmt.Errorf("failed to check for existing schema: %w", err)
+	}
+	return tables == 0, nil
+}
+
+// predatesMigrator reports whether the database was created before
+// schema_migrations existed. It must be called before migrate.
+func (db *Database) predatesMigrator() (bool, error) {
+	var tables int
+	err := db.Get(&tables, `SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'schema_migrations'`)
+	if err != nil {
+		return false, fmt.Errorf("failed to check for migration history: %w", err)
+	}
+	return tables == 0, nil
+}
+
+// MigrateUp applies every pending migration in version order, each in its own
+// transaction, and returns the ones applied
+func (db *Database) MigrateUp() ([]Migration, error) {
+	return db.migrateUp(registeredMigrations())
+}
+
+// MigrateDown reverts the last steps applied migrations, newest first, and
+// returns the ones reverted. It stops at the first migration without a Down.
+func (db *Database) MigrateDown(steps int) ([]Migration, error) {
+	return db.migrateDown(registeredMigrations(), steps)
+}
+
+// MigrationStatus returns every registered migration and when it was applied
+func (db *Database) MigrationStatus() ([]MigrationStatus, error) {
+	return db.migrationStatus(registeredMigrations())
+}
+
+func (db *Database) appliedMigrations() (map[int]appliedMigration, error) {
+	var rows []appliedMigration
+	if err := db.Select(&rows, `SELECT version, name, applied_at FROM schema_migrations`); err != nil {
+		return nil, fmt.Errorf("failed to get applied migrations: %w", err)
+	}
+
+	applied := make(map[int]appliedMigration, len(rows))
+	for _, row := range rows {
+		applied[row.Version] = row
+	}
+	return applied, nil
+}
+
+// stampMigrations records migrations as applied without running them
+func (db *Database) stampMigrations(migrations []Migration) error {
+	return db.WriteOperation(func(tx *sqlx.Tx) error {
+		for _, m := range migrations {
+			if _, err := tx.Exec(`INSERT OR IGNORE INTO schema_migrations (version, name) VALUES (?, ?)`, m.Version, m.Name); err != nil {
+				return fmt.Errorf("failed to record migration %d: %w", m.Version, err)
+			}
+		}
+		return nil
+	})
+}
+
+func (db *Database) migrateUp(migrations []Migration) ([]Migration, error) {
+	applied, err := db.appliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	var ran []Migration
+	for _, m := range sortedMigrations(migrations) {
+		if _, ok := applied[m.Version]; ok {
+			continue
+		}
+
+		db.logger.WithField("version", m.Version).Infof("Applying migration %s", m.Name)
+		err := db.WriteOperation(func(tx *sqlx.Tx) error {
+			if err := m.Up(tx); err != nil {
+				return err
+			}
+			_, err := tx.Exec(`INSERT INTO schema_migrations (version, name) VALUES (?, ?)`, m.Version, m.Name)
+			return err
+		})
+		if err != nil {
+			return ran, fmt.Errorf("migration %d (%s) failed: %w", m.Version, m.Name, err)
+		}
+		ran = append(ran, m)
+	}
+	return ran, nil
+}
+
+func (db *Database) migrateDown(migrations []Migration, steps int) ([]Migration, error) {
+	applied, err := db.appliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	sorted := sortedMigrations(migrations)
+	var reverted []Migration
+	for i := len(sorted) - 1; i >= 0 && len(reverted) < steps; i-- {
+		m := sorted[i]
+		if _, ok := applied[m.Version]; !ok {
+			continue
+		}
+		if m.Down == nil {
+			return reverted, fmt.Errorf("migration %d (%s) cannot be reverted", m.Version, m.Name)
+		}
+
+		db.logger.WithField("version", m.Version).Infof("Reverting migration %s", m.Name)
+		err := db.WriteOperation(func(tx *sqlx.Tx) error {
+			if err := m.Down(tx); err != nil {
+				return err
+			}
+			_, err := tx.Exec(`DELETE FROM schema_migrations WHERE version = ?`, m.Version)
+			return err
+		})
+		if err != nil {
+			return reverted, fmt.Errorf("reverting migration %d (%s) failed: %w", m.Version, m.Name, err)
+		}
+		reverted = append(reverted, m)
+	}
+	return reverted, nil
+}
+
+func (db *Database) migrationStatus(migrations []Migration) ([]MigrationStatus, error) {
+	applied, err := db.appliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []MigrationStatus
+	for _, m := range sortedMigrations(migrations) {
+		status := MigrationStatus{Version: m.Version, Name: m.Name, Reversible: m.Down != nil}
+		if row, ok := applied[m.Version]; ok {
+			appliedAt := row.AppliedAt
+			status.AppliedAt = &appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+func sortedMigrations(migrations []Migration) []Migration {
+	sorted := append([]Migration(nil), migrations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+	return sorted
+}
@@ -0,0 +1,242 @@
+package database
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+func TestMigrator_NewDatabaseIsStamped(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	statuses, err := db.MigrationStatus()
+	if err != nil {
+		t.Fatalf("Failed to get migration status: %v", err)
+	}
+	if len(statuses) != len(registeredMigrations()) {
+		t.Fatalf("Expected %d migrations, got %+v", len(registeredMigrations()), statuses)
+	}
+	for _, status := range statuses {
+		if status.AppliedAt == nil {
+			t.Errorf("Expected migration %d to be recorded as applied", status.Version)
+		}
+	}
+
+	if ran, err := db.MigrateUp(); err != nil || len(ran) != 0 {
+		t.Errorf("Expected nothing pending, got %+v (%v)", ran, err)
+	}
+}
+
+func TestMigrator_UpAndDown(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	migrations := []Migration{
+		{
+			Version: 101,
+			Name:    "add_notes_column",
+			Up: func(tx *sqlx.Tx) error {
+				_, err := tx.Exec(`ALTER TABLE migration_test ADD COLUMN notes TEXT`)
+				return err
+			},
+			Down: func(tx *sqlx.Tx) error {
+				_, err := tx.Exec(`ALTER TABLE migration_test DROP COLUMN notes`)
+				return err
+			},
+		},
+		{
+			Version: 100,
+			Name:    "create_migration_test",
+			Up: func(tx *sqlx.Tx) error {
+				_, err := tx.Exec(`CREATE TABLE migration_test (id INTEGER PRIMARY KEY)`)
+				return err
+			},
+		},
+	}
+
+	ran, err := db.migrateUp(migrations)
+	if err != nil {
+		t.Fatalf("Failed to migrate up: %v", err)
+	}
+	if len(ran) != 2 || ran[0].Version != 100 || ran[1].Version != 101 {
+		t.Fatalf("Expected migrations 100 and 101 in order, got %+v", ran)
+	}
+	if _, err := db.Exec(`INSERT INTO migration_test (notes) VALUES ('ok')`); err != nil {
+		t.Fatalf("Expected the notes column to exist: %v", err)
+	}
+
+	reverted, err := db.migrateDown(migrations, 1)
+	if err != nil || len(reverted) != 1 || reverted[0].Version != 101 {
+		t.Fatalf("Expected migration 101 to be reverted, got %+v (%v)", reverted, err)
+	}
+	statuses, _ := db.migrationStatus(migrations)
+	if statuses[0].AppliedAt == nil || statuses[1].AppliedAt != nil {
+		t.Errorf("Expected only migration 100 applied, got %+v", statuses)
+	}
+
+	// Migrations without a Down stop the rollback
+	if _, err := db.migrateDown(migrations, 1); err == nil {
+		t.Error("Expected reverting migration 100 to fail")
+	}
+}
+
+func TestMigrator_UpgradesExistingDatabase(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	// Simulate a database from before the migrator
+	setup := []string{
+		`DELETE FROM schema_migrations`,
+		`INSERT INTO sessions (id, project_path, project_name, file_path, start_time, last_activity)
+			VALUES ('s1', '/work/app', 'app', '/tmp/s1.jsonl', '2025-01-01 10:00:00', '2025-01-01 10:05:00')`,
+		`INSERT INTO messages (id, session_id, role, content, timestamp)
+			VALUES ('m1', 's1', 'assistant', '[{"type":"tool_use","name":"Edit","input":{"file_path":"/work/app/main.go","old_string":"a","new_string":"b"}}]', '2025-01-01 10:01:00')`,
+		`INSERT INTO tool_results (message_id, session_id, tool_name, timestamp)
+			VALUES ('m1', 's1', 'unknown', '2025-01-01 10:01:00')`,
+	}
+	for _, query := range setup {
+		if _, err := db.Exec(query); err != nil {
+			t.Fatalf("Failed to set up data: %v", err)
+		}
+	}
+
+	ran, err := db.MigrateUp()
+	if err != nil || len(ran) != len(registeredMigrations()) {
+		t.Fatalf("Expected every migration to run, got %+v (%v)", ran, err)
+	}
+
+	var results []struct {
+		ToolName string `db:"tool_name"`
+		FilePath string `db:"file_path"`
+	}
+	if err := db.Select(&results, `SELECT tool_name, file_path FROM tool_results`); err != nil {
+		t.Fatalf("Failed to get tool results: %v", err)
+	}
+	if len(results) != 1 || results[0].ToolName != "Edit" || results[0].FilePath != "/work/app/main.go" {
+		t.Errorf("Expected the Edit call to replace the unknown result, got %+v", results)
+	}
+
+	// Revert back to before extract_tool_results
+	steps := 0
+	for _, m := range registeredMigrations() {
+		if m.Version >= 9 {
+			steps++
+		}
+	}
+	if _, err := db.MigrateDown(steps); err != nil {
+		t.Fatalf("Failed to migrate down: %v", err)
+	}
+	var remaining int
+	if err := db.Get(&remaining, `SELECT COUNT(*) FROM tool_results`); err != nil || remaining != 0 {
+		t.Errorf("Expected the extracted results to be removed, got %d (%v)", remaining, err)
+	}
+}
+
+func TestMigrator_UpgradesBaselineDatabase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "baseline.db")
+	schema, err := os.ReadFile("testdata/baseline_schema.sql")
+	if err != nil {
+		t.Fatalf("Failed to read baseline schema: %v", err)
+	}
+
+	// A database as the first release created it
+	baseline, err := sqlx.Open("sqlite3", path)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	setup := []string{
+		string(schema),
+		`INSERT INTO sessions (id, project_path, project_name, file_path, start_time, last_activity, model)
+			VALUES ('s1', '/work/app', 'app', '/tmp/s1.jsonl', '2025-01-01 10:00:00', '2025-01-01 10:05:00', 'claude-opus-4')`,
+		`INSERT INTO messages (id, session_id, role, content, cwd, timestamp)
+			VALUES ('m1', 's1', 'assistant', '"done"', '/work/app/cmd', '2025-01-01 10:01:00')`,
+		`INSERT INTO token_usage (message_id, session_id, input_tokens, output_tokens)
+			VALUES ('m1', 's1', 1000, 100)`,
+	}
+	for _, query := range setup {
+		if _, err := baseline.Exec(query); err != nil {
+			t.Fatalf("Failed to set up baseline database: %v", err)
+		}
+	}
+	baseline.Close()
+
+	db, err := NewDatabase(Config{DatabasePath: path, Logger: logger})
+	if err != nil {
+		t.Fatalf("Failed to open baseline database: %v", err)
+	}
+	defer db.Close()
+
+	statuses, err := db.MigrationStatus()
+	if err != nil {
+		t.Fatalf("Failed to get migration status: %v", err)
+	}
+	for _, status := range statuses {
+		if status.AppliedAt == nil {
+			t.Errorf("Expected migration %d to be applied", status.Version)
+		}
+	}
+
+	// The fixes that were run by hand aren't run again
+	var fixed struct {
+		ProjectPath string `db:"project_path"`
+		TotalTokens int    `db:"total_tokens"`
+	}
+	err = db.Get(&fixed, `SELECT s.project_path, tu.total_tokens FROM sessions s JOIN token_usage tu ON tu.session_id = s.id`)
+	if err != nil || fixed.ProjectPath != "/work/app" || fixed.TotalTokens != 0 {
+		t.Errorf("Expected the data left as it was, got %+v (%v)", fixed, err)
+	}
+
+	repo := NewSessionRepository(db, logger)
+	summary, err := repo.GetSessionByID("s1")
+	if err != nil || summary.Source != "import" {
+		t.Errorf("Expected the imported session summarized, got %+v (%v)", summary, err)
+	}
+	now := time.Now()
+	if err := repo.UpsertSession(&Session{ID: "s2", ProjectPath: "/work/app", ProjectName: "app", StartTime: now, LastActivity: now, Status: "active"}); err != nil {
+		t.Errorf("Failed to create session after upgrading: %v", err)
+	}
+}
+
+func TestMigrator_ClearDetectedGitRemotes(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewSessionRepository(db, logger)
+	now := time.Now()
+	for _, id := range []string{"detected", "reported"} {
+		if err := repo.UpsertSession(&Session{ID: id, ProjectPath: "/work/app", ProjectName: "app", GitRemote: "github.com/org/app", StartTime: now, LastActivity: now, Status: "completed"}); err != nil {
+			t.Fatalf("Failed to create session: %v", err)
+		}
+	}
+	setup := []string{
+		`INSERT INTO session_environments (session_id, git_remote, source) VALUES ('detected', 'github.com/org/stale', 'detected')`,
+		`INSERT INTO session_environments (session_id, git_remote, source) VALUES ('reported', 'gitlab.com/org/app', 'reported')`,
+	}
+	for _, query := range setup {
+		if _, err := db.Exec(query); err != nil {
+			t.Fatalf("Failed to set up environments: %v", err)
+		}
+	}
+
+	if err := db.WriteOperation(clearDetectedGitRemotes); err != nil {
+		t.Fatalf("Failed to clear git remotes: %v", err)
+	}
+	if env, _ := repo.GetSessionEnvironment("detected"); env.GitRemote == nil || *env.GitRemote != "github.com/org/app" {
+		t.Errorf("Expected the session's remote, got %v", env.GitRemote)
+	}
+	if env, _ := repo.GetSessionEnvironment("reported"); env.GitRemote == nil || *env.GitRemote != "gitlab.com/org/app" {
+		t.Errorf("Expected the reported remote kept, got %v", env.GitRemote)
+	}
+
+	if err := db.WriteOperation(copyDetectedGitRemotes); err != nil {
+		t.Fatalf("Failed to copy git remotes: %v", err)
+	}
+	var stored string
+	if err := db.Get(&stored, `SELECT git_remote FROM session_environments WHERE session_id = 'detected'`); err != nil || stored != "github.com/org/app" {
+		t.Errorf("Expected the session's remote stored again, got %q (%v)", stored, err)
+	}
+}
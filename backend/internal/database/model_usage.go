@@ -0,0 +1,99 @@
+package database
+
+import (
+	"fmt"
+	"time"
+)
+
+// GetSessionModelUsage returns the prompts, tool uses and token usage of
+// every session last active in [from, to) that used any tokens. Prompts are
+// user messages that aren't tool results; tool uses are assistant messages
+// calling a tool.
+func (r *SessionRepository) GetSessionModelUsage(from, to time.Time) ([]SessionModelUsage, error) {
+	usage := []SessionModelUsage{}
+	err := r.db.Select(&usage, `
+		SELECT
+			s.id AS session_id,
+			s.project_name,
+			COALESCE(s.model, '') AS model,
+			(
+				SELECT COUNT(*) FROM messages
+				WHERE session_id = s.id AND role = 'user' AND content NOT LIKE '%"tool_result"%'
+			) AS prompts,
+			(
+				SELECT COUNT(*) FROM messages
+				WHERE session_id = s.id AND role = 'assistant' AND content LIKE '%"tool_use"%'
+			) AS tool_uses,
+			SUM(tu.input_tokens) AS input_tokens,
+			SUM(tu.output_tokens) AS output_tokens,
+			SUM(tu.cache_creation_input_tokens) AS cache_creation_input_tokens,
+			SUM(tu.cache_read_input_tokens) AS cache_read_input_tokens,
+			COALESCE(SUM(tu.estimated_cost), 0.0) AS cost_usd,
+			COALESCE(s.duration_seconds, 0) AS duration_seconds
+		FROM sessions s
+		JOIN token_usage tu ON tu.session_id = s.id
+		WHERE s.last_activity >= ? AND s.last_activity < ?
+		GROUP BY s.id
+		ORDER BY s.last_activity DESC
+	`, from.UTC(), to.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session model usage: %w", err)
+	}
+	return usage, nil
+}
+
+// GetModelMonthUsage returns the tokens used with each model per UTC month of
+// messages sent in [from, to), ordered by month and model. A message's model
+// is its session's.
+func (r *SessionRepository) GetModelMonthUsage(from, to time.Time) ([]ModelMonthUsage, error) {
+	usage := []ModelMonthUsage{}
+	err := r.db.Select(&usage, `
+		SELECT
+			strftime('%Y-%m', m.timestamp) AS month,
+			COALESCE(s.model, '') AS model,
+			COUNT(DISTINCT s.id) AS sessions,
+			COALESCE(SUM(tu.input_tokens), 0) AS input_tokens,
+			COALESCE(SUM(tu.output_tokens), 0) AS output_tokens,
+			COALESCE(SUM(tu.cache_creation_input_tokens), 0) AS cache_creation_input_tokens,
+			COALESCE(SUM(tu.cache_read_input_tokens), 0) AS cache_read_input_tokens,
+			COALESCE(SUM(tu.estimated_cost), 0.0) AS cost_usd
+		FROM token_usage tu
+		JOIN messages m ON m.id = tu.message_id
+		JOIN sessions s ON s.id = m.session_id
+		WHERE m.timestamp >= ? AND m.timestamp < ?
+		GROUP BY month, s.model
+		ORDER BY month, model
+	`, from.UTC(), to.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get model usage by month: %w", err)
+	}
+	return usage, nil
+}
+
+// GetDailyUsage returns the tokens and cost of messages sent in [from, to)
+// per UTC day, project and model, leaving out archived sessions. Projects are
+// reported by display name, so merged project names count as one.
+func (r *SessionRepository) GetDailyUsage(from, to time.Time) ([]DailyUsage, error) {
+	usage := []DailyUsage{}
+	err := r.db.Select(&usage, `
+		SELECT
+			DATE(m.timestamp) AS day,
+			COALESCE(p.display_name, s.project_name) AS project,
+			COALESCE(s.model, '') AS model,
+			COALESCE(SUM(tu.total_tokens), 0) AS tokens,
+			COALESCE(SUM(tu.estimated_cost), 0.0) AS cost_usd
+		FROM token_usage tu
+		JOIN messages m ON m.id = tu.message_id
+		JOIN sessions s ON s.id = m.session_id
+		LEFT JOIN project_aliases pa ON pa.alias = s.project_name
+		LEFT JOIN projects p ON p.id = pa.project_id
+		WHERE m.timestamp >= ? AND m.timestamp < ?
+		AND s.archived_at IS NULL
+		GROUP BY day, project, s.model
+		ORDER BY day, project, model
+	`, from.UTC(), to.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get daily usage: %w", err)
+	}
+	return usage, nil
+}
@@ -0,0 +1,137 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSessionRepository_GetSessionModelUsage(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewSessionRepository(db, logger)
+
+	now := time.Now().UTC()
+	if err := repo.UpsertSession(&Session{ID: "s1", ProjectPath: "/work/docs", ProjectName: "docs", Model: "claude-sonnet-4", StartTime: now.Add(-time.Hour), LastActivity: now, Status: "completed"}); err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	for i, m := range []struct{ role, content string }{
+		{"user", `"What does this regex match?"`},
+		{"assistant", `[{"type":"text","text":"Dates"}]`},
+		{"assistant", `[{"type":"tool_use","name":"Read","input":{}}]`},
+		{"user", `[{"type":"tool_result","content":"ok"}]`},
+	} {
+		id := "s1-" + string(rune('a'+i))
+		if err := repo.UpsertMessage(&Message{ID: id, SessionID: "s1", Role: m.role, Content: m.content, Timestamp: now}); err != nil {
+			t.Fatalf("Failed to create message: %v", err)
+		}
+		if m.role == "assistant" {
+			if err := repo.UpsertTokenUsage(&TokenUsage{MessageID: id, SessionID: "s1", InputTokens: 100, OutputTokens: 50, TotalTokens: 150, EstimatedCost: 0.5}); err != nil {
+				t.Fatalf("Failed to create token usage: %v", err)
+			}
+		}
+	}
+
+	usage, err := repo.GetSessionModelUsage(now.Add(-24*time.Hour), now.Add(time.Minute))
+	if err != nil {
+		t.Fatalf("Failed to get model usage: %v", err)
+	}
+	if len(usage) != 1 {
+		t.Fatalf("Expected 1 session, got %+v", usage)
+	}
+	got := usage[0]
+	if got.Prompts != 1 || got.ToolUses != 1 || got.InputTokens != 200 || got.OutputTokens != 100 || got.CostUSD != 1.0 {
+		t.Errorf("Unexpected usage: %+v", got)
+	}
+}
+
+func TestSessionRepository_GetModelMonthUsage(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewSessionRepository(db, logger)
+
+	for _, s := range []struct {
+		id, model string
+		sent      time.Time
+	}{
+		{"opus-aug", "claude-opus-4", time.Date(2026, 8, 20, 12, 0, 0, 0, time.UTC)},
+		{"opus-sep", "claude-opus-4", time.Date(2026, 9, 2, 12, 0, 0, 0, time.UTC)},
+		{"sonnet-sep", "claude-sonnet-4", time.Date(2026, 9, 3, 12, 0, 0, 0, time.UTC)},
+	} {
+		if err := repo.UpsertSession(&Session{ID: s.id, ProjectPath: "/work/app", ProjectName: "app", Model: s.model, StartTime: s.sent, LastActivity: s.sent, Status: "completed"}); err != nil {
+			t.Fatalf("Failed to create session: %v", err)
+		}
+		if err := repo.UpsertMessage(&Message{ID: s.id + "-msg", SessionID: s.id, Role: "assistant", Content: `"done"`, Timestamp: s.sent}); err != nil {
+			t.Fatalf("Failed to create message: %v", err)
+		}
+		if err := repo.UpsertTokenUsage(&TokenUsage{MessageID: s.id + "-msg", SessionID: s.id, InputTokens: 1000, OutputTokens: 100, TotalTokens: 1100, EstimatedCost: 0.25}); err != nil {
+			t.Fatalf("Failed to create token usage: %v", err)
+		}
+	}
+
+	usage, err := repo.GetModelMonthUsage(time.Date(2026, 8, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Failed to get model usage by month: %v", err)
+	}
+	if len(usage) != 3 {
+		t.Fatalf("Expected 3 model months, got %+v", usage)
+	}
+	if usage[0].Month != "2026-08" || usage[1].Model != "claude-opus-4" || usage[2].Model != "claude-sonnet-4" {
+		t.Errorf("Expected usage by month then model, got %+v", usage)
+	}
+	if usage[1].Sessions != 1 || usage[1].InputTokens != 1000 || usage[1].CostUSD != 0.25 {
+		t.Errorf("Unexpected usage: %+v", usage[1])
+	}
+}
+
+func TestSessionRepository_GetDailyUsage(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewSessionRepository(db, logger)
+
+	for _, s := range []struct {
+		id, project, model string
+		sent               time.Time
+	}{
+		{"app-1", "app", "claude-opus-4", time.Date(2026, 9, 1, 9, 0, 0, 0, time.UTC)},
+		{"app-2", "app-worktree", "claude-opus-4", time.Date(2026, 9, 1, 15, 0, 0, 0, time.UTC)},
+		{"docs-1", "docs", "claude-sonnet-4", time.Date(2026, 9, 2, 9, 0, 0, 0, time.UTC)},
+		{"archived", "docs", "claude-sonnet-4", time.Date(2026, 9, 2, 10, 0, 0, 0, time.UTC)},
+	} {
+		if err := repo.UpsertSession(&Session{ID: s.id, ProjectPath: "/work/" + s.project, ProjectName: s.project, Model: s.model, StartTime: s.sent, LastActivity: s.sent, Status: "completed"}); err != nil {
+			t.Fatalf("Failed to create session: %v", err)
+		}
+		if err := repo.UpsertMessage(&Message{ID: s.id + "-msg", SessionID: s.id, Role: "assistant", Content: `"done"`, Timestamp: s.sent}); err != nil {
+			t.Fatalf("Failed to create message: %v", err)
+		}
+		if err := repo.UpsertTokenUsage(&TokenUsage{MessageID: s.id + "-msg", SessionID: s.id, TotalTokens: 1000, EstimatedCost: 0.25}); err != nil {
+			t.Fatalf("Failed to create token usage: %v", err)
+		}
+	}
+	if _, err := repo.ArchiveSession("archived"); err != nil {
+		t.Fatalf("Failed to archive session: %v", err)
+	}
+	projects, err := repo.GetProjects(false)
+	if err != nil {
+		t.Fatalf("Failed to get projects: %v", err)
+	}
+	if _, err := repo.MergeProjects(projects[0].ID, []int64{projects[1].ID}); err != nil {
+		t.Fatalf("Failed to merge projects: %v", err)
+	}
+
+	usage, err := repo.GetDailyUsage(time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 9, 3, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Failed to get daily usage: %v", err)
+	}
+	if len(usage) != 2 {
+		t.Fatalf("Expected a row per day, project and model, got %+v", usage)
+	}
+	if usage[0].Day != "2026-09-01" || usage[0].Project != "app" || usage[0].Tokens != 2000 || usage[0].CostUSD != 0.5 {
+		t.Errorf("Expected both app projects as one, got %+v", usage[0])
+	}
+	if usage[1].Day != "2026-09-02" || usage[1].Model != "claude-sonnet-4" || usage[1].Tokens != 1000 {
+		t.Errorf("Expected the unarchived docs session, got %+v", usage[1])
+	}
+}
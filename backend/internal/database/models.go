@@ -13,6 +13,7 @@ type Session struct {
 	FilePath       string    `db:"file_path" json:"file_path"`
 	GitBranch      string    `db:"git_branch" json:"git_branch"`
 	GitWorktree    string    `db:"git_worktree" json:"git_worktree"`
+	GitRemote      string    `db:"git_remote" json:"git_remote"`
 	StartTime      time.Time `db:"start_time" json:"start_time"`
 	LastActivity   time.Time `db:"last_activity" json:"last_activity"`
 	IsActive       bool      `db:"is_active" json:"is_active"`
@@ -21,6 +22,7 @@ type Session struct {
 	MessageCount   int       `db:"message_count" json:"message_count"`
 	DurationSeconds int64    `db:"duration_seconds" json:"duration_seconds"`
 	Source         string    `db:"source" json:"source"` // 'import' or 'ui'
+	ArchivedAt     *time.Time `db:"archived_at" json:"archived_at,omitempty"`
 	CreatedAt      time.Time `db:"created_at" json:"created_at"`
 	UpdatedAt      time.Time `db:"updated_at" json:"updated_at"`
 }
@@ -53,20 +55,87 @@ type TokenUsage struct {
 	CacheReadInputTokens     int       `db:"cache_read_input_tokens" json:"cache_read_input_tokens"`
 	TotalTokens              int       `db:"total_tokens" json:"total_tokens"`
 	ServiceTier              string    `db:"service_tier" json:"service_tier"`
+	Model                    string    `db:"model" json:"model,omitempty"` // model that wrote the message; the session's when empty
 	EstimatedCost            float64   `db:"estimated_cost" json:"estimated_cost"`
+	CacheSavings             float64   `db:"cache_savings" json:"cache_savings"` // fresh input cost of cached tokens minus their cost
 	CreatedAt                time.Time `db:"created_at" json:"created_at"`
 }
 
 // ToolResult represents tool usage results
 type ToolResult struct {
-	ID         int       `db:"id" json:"id"`
-	MessageID  string    `db:"message_id" json:"message_id"`
+	ID           int       `db:"id" json:"id"`
+	MessageID    string    `db:"message_id" json:"message_id"`
+	SessionID    string    `db:"session_id" json:"session_id"`
+	ToolName     string    `db:"tool_name" json:"tool_name"`
+	FilePath     *string   `db:"file_path" json:"file_path"` // relative to the working directory when inside it
+	AbsolutePath *string   `db:"absolute_path" json:"absolute_path,omitempty"`
+	ResultData   string    `db:"result_data" json:"result_data"` // JSON string
+	Timestamp    time.Time `db:"timestamp" json:"timestamp"`
+	CreatedAt    time.Time `db:"created_at" json:"created_at"`
+}
+
+// ToolCallRecord is a tool_use block in an assistant message. DurationMs is
+// the time until the message carrying its result, nil if there is none.
+type ToolCallRecord struct {
+	ID         int64     `db:"id" json:"id"`
 	SessionID  string    `db:"session_id" json:"session_id"`
+	MessageID  string    `db:"message_id" json:"message_id"`
+	ToolUseID  *string   `db:"tool_use_id" json:"tool_use_id,omitempty"`
 	ToolName   string    `db:"tool_name" json:"tool_name"`
-	FilePath   *string   `db:"file_path" json:"file_path"`
-	ResultData string    `db:"result_data" json:"result_data"` // JSON string
 	Timestamp  time.Time `db:"timestamp" json:"timestamp"`
-	CreatedAt  time.Time `db:"created_at" json:"created_at"`
+	DurationMs *int64    `db:"duration_ms" json:"duration_ms,omitempty"`
+	IsError    bool      `db:"is_error" json:"is_error"`
+}
+
+// Command is a shell command Claude ran with the Bash tool. ExitCode is nil
+// until its result arrives or when the result doesn't say. ProjectName is
+// only set when listing commands across sessions.
+type Command struct {
+	ID          int64     `db:"id" json:"id"`
+	SessionID   string    `db:"session_id" json:"session_id"`
+	ProjectName string    `db:"project_name" json:"project_name,omitempty"`
+	MessageID   string    `db:"message_id" json:"message_id"`
+	ToolUseID   *string   `db:"tool_use_id" json:"tool_use_id,omitempty"`
+	Command     string    `db:"command" json:"command"`
+	Description *string   `db:"description" json:"description,omitempty"`
+	Cwd         *string   `db:"cwd" json:"cwd,omitempty"`
+	ExitCode    *int      `db:"exit_code" json:"exit_code"`
+	Timestamp   time.Time `db:"timestamp" json:"timestamp"`
+}
+
+// ToolUsage is how often a tool was called and how long its results took.
+// The Under and Over fields count calls by duration.
+type ToolUsage struct {
+	Tool          string  `db:"tool" json:"tool"`
+	Calls         int64   `db:"calls" json:"calls"`
+	Sessions      int64   `db:"sessions" json:"sessions"`
+	Errors        int64   `db:"errors" json:"errors"`
+	Completed     int64   `db:"completed" json:"completed"` // calls with a result, which the durations cover
+	AvgDurationMs float64 `db:"avg_duration_ms" json:"avg_duration_ms"`
+	MaxDurationMs int64   `db:"max_duration_ms" json:"max_duration_ms"`
+	Under1s       int64   `db:"under_1s" json:"under_1s"`
+	Under5s       int64   `db:"under_5s" json:"under_5s"`
+	Under30s      int64   `db:"under_30s" json:"under_30s"`
+	Under2m       int64   `db:"under_2m" json:"under_2m"`
+	Under10m      int64   `db:"under_10m" json:"under_10m"`
+	Over10m       int64   `db:"over_10m" json:"over_10m"`
+}
+
+// ToolUsageGroup is a project's or session's tool calls, most called tool
+// first
+type ToolUsageGroup struct {
+	Key     string      `json:"key"`
+	Project string      `json:"project,omitempty"` // the session's project, for sessions
+	Calls   int64       `json:"calls"`
+	Errors  int64       `json:"errors"`
+	Tools   []ToolCount `json:"tools"`
+}
+
+// ToolCount is how often a tool was called within a group
+type ToolCount struct {
+	Tool   string `db:"tool" json:"tool"`
+	Calls  int64  `db:"calls" json:"calls"`
+	Errors int64  `db:"errors" json:"errors"`
 }
 
 // FileWatcher represents a monitored file with processing status
@@ -97,10 +166,39 @@ type ImportRun struct {
 	FilesSkipped     int        `db:"files_skipped" json:"files_skipped"`
 	SessionsImported int        `db:"sessions_imported" json:"sessions_imported"`
 	MessagesImported int        `db:"messages_imported" json:"messages_imported"`
+	ResumedFromRunID *int       `db:"resumed_from_run_id" json:"resumed_from_run_id,omitempty"`
+	FilesRecovered   int        `db:"files_recovered" json:"files_recovered"`
 	ErrorMessage     *string    `db:"error_message" json:"error_message"`
 	CreatedAt        time.Time  `db:"created_at" json:"created_at"`
 }
 
+// ImportProgress is a snapshot of a running import, sent to the update
+// callback as files are processed
+type ImportProgress struct {
+	RunID            int      `json:"run_id"`
+	RunType          string   `json:"run_type"`
+	Status           string   `json:"status"` // running, completed or cancelled
+	ResumedFromRunID *int     `json:"resumed_from_run_id,omitempty"`
+	FilesRecovered   int      `json:"files_recovered"` // already imported by the interrupted run
+	FilesSkipped     int      `json:"files_skipped"`   // up to date, including recovered files
+	FilesTotal       int      `json:"files_total"`     // files this run processes
+	FilesProcessed   int      `json:"files_processed"`
+	FilesFailed      int      `json:"files_failed"`
+	Sessions         int      `json:"sessions"`
+	Messages         int      `json:"messages"`
+	Percent          float64  `json:"percent"`
+	ElapsedSeconds   float64  `json:"elapsed_seconds"`
+	ETASeconds       *float64 `json:"eta_seconds,omitempty"` // estimated from bytes processed; unset until the first file is done
+}
+
+// FsckFinding is a kind of orphaned or duplicated row found by Fsck
+type FsckFinding struct {
+	Table   string `json:"table"`
+	Problem string `json:"problem"`
+	Rows    int    `json:"rows"`
+	Fixed   bool   `json:"fixed"`
+}
+
 // ActivityLogEntry represents an activity log entry
 type ActivityLogEntry struct {
 	ID           *int      `db:"id" json:"id"`
@@ -124,12 +222,17 @@ type SessionSummary struct {
 	MessageCount               int       `db:"message_count" json:"message_count"`
 	DurationSeconds            int64     `db:"duration_seconds" json:"duration_seconds"`
 	Source                     string    `db:"source" json:"source"`
+	ArchivedAt                 *time.Time `db:"archived_at" json:"archived_at,omitempty"`
+	GitBranch                  string    `db:"git_branch" json:"git_branch"`
+	GitWorktree                string    `db:"git_worktree" json:"git_worktree"`
+	GitRemote                  string    `db:"git_remote" json:"git_remote"`
 	TotalInputTokens           int       `db:"total_input_tokens" json:"total_input_tokens"`
 	TotalOutputTokens          int       `db:"total_output_tokens" json:"total_output_tokens"`
 	TotalCacheCreationTokens   int       `db:"total_cache_creation_tokens" json:"total_cache_creation_tokens"`
 	TotalCacheReadTokens       int       `db:"total_cache_read_tokens" json:"total_cache_read_tokens"`
 	TotalTokens                int       `db:"total_tokens" json:"total_tokens"`
 	TotalEstimatedCost         float64   `db:"total_estimated_cost" json:"total_estimated_cost"`
+	TotalCacheSavings          float64   `db:"total_cache_savings" json:"total_cache_savings"`
 	FilesModified              string    `db:"files_modified" json:"files_modified"` // JSON array as string
 }
 
@@ -152,6 +255,19 @@ type DailyMetric struct {
 	TotalTokens  int    `db:"total_tokens" json:"total_tokens"`
 }
 
+// MetricsSummaryAsOf is the metrics summary recomputed from the messages
+// sent before a point in time
+type MetricsSummaryAsOf struct {
+	TotalSessions          int            `db:"total_sessions"`
+	ActiveSessions         int            `db:"active_sessions"`
+	TotalMessages          int            `db:"total_messages"`
+	TotalTokens            int            `db:"total_tokens"`
+	EstimatedCost          float64        `db:"estimated_cost"`
+	AverageSessionDuration float64        `db:"average_session_duration"` // minutes
+	MostUsedModel          string         `db:"-"`
+	ModelUsage             map[string]int `db:"-"`
+}
+
 // DatabaseStats represents overall database statistics
 type DatabaseStats struct {
 	TotalSessions        int     `json:"total_sessions"`
@@ -161,4 +277,1052 @@ type DatabaseStats struct {
 	TotalEstimatedCost   float64 `json:"total_estimated_cost"`
 	AverageSessionDuration float64 `json:"average_session_duration"`
 	MostUsedModel        string  `json:"most_used_model"`
-}
\ No newline at end of file
+}
+// PromptTemplate represents a saved prompt that can be inserted into a UI chat
+type PromptTemplate struct {
+	ID          string     `db:"id" json:"id"`
+	Name        string     `db:"name" json:"name"`
+	Description *string    `db:"description" json:"description,omitempty"`
+	Content     string     `db:"content" json:"content"`
+	Category    *string    `db:"category" json:"category,omitempty"`
+	UsageCount  int        `db:"usage_count" json:"usage_count"`
+	LastUsedAt  *time.Time `db:"last_used_at" json:"last_used_at,omitempty"`
+	CreatedAt   time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt   time.Time  `db:"updated_at" json:"updated_at"`
+}
+
+// PromptTemplateStats represents usage statistics for a prompt template
+type PromptTemplateStats struct {
+	TemplateID     string     `db:"template_id" json:"template_id"`
+	Name           string     `db:"name" json:"name"`
+	UsageCount     int        `db:"usage_count" json:"usage_count"`
+	UniqueSessions int        `db:"unique_sessions" json:"unique_sessions"`
+	UsesLast7Days  int        `db:"uses_last_7_days" json:"uses_last_7_days"`
+	UsesLast30Days int        `db:"uses_last_30_days" json:"uses_last_30_days"`
+	LastUsedAt     *time.Time `db:"last_used_at" json:"last_used_at,omitempty"`
+}
+
+// SessionGroup links related sessions together, e.g. the sessions of a playbook run
+type SessionGroup struct {
+	ID        string    `db:"id" json:"id"`
+	Name      string    `db:"name" json:"name"`
+	Kind      string    `db:"kind" json:"kind"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
+
+// PlaybookRun represents a single execution of a playbook
+type PlaybookRun struct {
+	ID           string     `db:"id" json:"id"`
+	PlaybookName string     `db:"playbook_name" json:"playbook_name"`
+	ProjectPath  string     `db:"project_path" json:"project_path"`
+	GroupID      *string    `db:"group_id" json:"group_id,omitempty"`
+	ScheduleID   *string    `db:"schedule_id" json:"schedule_id,omitempty"`
+	Status       string     `db:"status" json:"status"`
+	Trigger      string     `db:"trigger_type" json:"trigger"`
+	TotalCostUSD float64    `db:"total_cost_usd" json:"total_cost_usd"`
+	Error        *string    `db:"error" json:"error,omitempty"`
+	Diff         *string    `db:"diff" json:"diff,omitempty"`
+	StartedAt    *time.Time `db:"started_at" json:"started_at,omitempty"`
+	CompletedAt  *time.Time `db:"completed_at" json:"completed_at,omitempty"`
+	CreatedAt    time.Time  `db:"created_at" json:"created_at"`
+}
+
+// PlaybookRunStep represents the outcome of one prompt within a playbook run
+type PlaybookRunStep struct {
+	ID              int64      `db:"id" json:"id"`
+	RunID           string     `db:"run_id" json:"run_id"`
+	StepIndex       int        `db:"step_index" json:"step_index"`
+	Name            string     `db:"name" json:"name"`
+	Prompt          string     `db:"prompt" json:"prompt"`
+	SessionID       *string    `db:"session_id" json:"session_id,omitempty"`
+	ClaudeSessionID *string    `db:"claude_session_id" json:"claude_session_id,omitempty"`
+	Status          string     `db:"status" json:"status"`
+	Response        *string    `db:"response" json:"response,omitempty"`
+	Proposal        *string    `db:"proposal" json:"proposal,omitempty"`
+	ReviewedBy      *string    `db:"reviewed_by" json:"reviewed_by,omitempty"`
+	ReviewedAt      *time.Time `db:"reviewed_at" json:"reviewed_at,omitempty"`
+	FailureReason   *string    `db:"failure_reason" json:"failure_reason,omitempty"`
+	CostUSD         float64    `db:"cost_usd" json:"cost_usd"`
+	DurationMs      int        `db:"duration_ms" json:"duration_ms"`
+	NumTurns        int        `db:"num_turns" json:"num_turns"`
+	StartedAt       *time.Time `db:"started_at" json:"started_at,omitempty"`
+	CompletedAt     *time.Time `db:"completed_at" json:"completed_at,omitempty"`
+}
+
+// PlaybookSchedule launches a playbook automatically on a cron schedule
+type PlaybookSchedule struct {
+	ID           string     `db:"id" json:"id"`
+	PlaybookName string     `db:"playbook_name" json:"playbook_name"`
+	CronExpr     string     `db:"cron_expr" json:"cron_expr"`
+	ProjectPath  *string    `db:"project_path" json:"project_path,omitempty"`
+	WebhookURL   *string    `db:"webhook_url" json:"webhook_url,omitempty"`
+	Enabled      bool       `db:"enabled" json:"enabled"`
+	LastRunID    *string    `db:"last_run_id" json:"last_run_id,omitempty"`
+	LastRunAt    *time.Time `db:"last_run_at" json:"last_run_at,omitempty"`
+	NextRunAt    *time.Time `db:"next_run_at" json:"next_run_at,omitempty"`
+	CreatedAt    time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt    time.Time  `db:"updated_at" json:"updated_at"`
+}
+
+// SessionQualityScore is a composite 0-100 score of how effective a session
+// was, with the signals it was computed from
+type SessionQualityScore struct {
+	SessionID      string    `db:"session_id" json:"session_id"`
+	Score          float64   `db:"score" json:"score"`
+	Efficiency     float64   `db:"efficiency" json:"efficiency"`
+	FilesChanged   int       `db:"files_changed" json:"files_changed"`
+	TokensSpent    int       `db:"tokens_spent" json:"tokens_spent"`
+	Interruptions  int       `db:"interruptions" json:"interruptions"`
+	ToolResults    int       `db:"tool_results" json:"tool_results"`
+	ToolErrors     int       `db:"tool_errors" json:"tool_errors"`
+	FeedbackRating *int      `db:"feedback_rating" json:"feedback_rating,omitempty"`
+	MessageCount   int       `db:"message_count" json:"-"`
+	ComputedAt     time.Time `db:"computed_at" json:"computed_at"`
+}
+
+// SessionFeedback is an explicit rating of a session
+type SessionFeedback struct {
+	SessionID string    `db:"session_id" json:"session_id"`
+	Rating    int       `db:"rating" json:"rating"`
+	Note      *string   `db:"note" json:"note,omitempty"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// Knowledge entry kinds
+const (
+	KnowledgeKindQA       = "qa"
+	KnowledgeKindDecision = "decision"
+)
+
+// KnowledgeEntry is a Q&A pair or decision extracted from session transcripts
+type KnowledgeEntry struct {
+	ID            string    `db:"id" json:"id"`
+	Kind          string    `db:"kind" json:"kind"`
+	NormalizedKey string    `db:"normalized_key" json:"-"`
+	Title         string    `db:"title" json:"title"`
+	Body          *string   `db:"body" json:"body,omitempty"`
+	ProjectName   *string   `db:"project_name" json:"project_name,omitempty"`
+	Occurrences   int       `db:"occurrences" json:"occurrences"`
+	FirstSeen     time.Time `db:"first_seen" json:"first_seen"`
+	LastSeen      time.Time `db:"last_seen" json:"last_seen"`
+	CreatedAt     time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt     time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// KnowledgeSource links a knowledge entry to a message it was extracted from
+type KnowledgeSource struct {
+	EntryID     string    `db:"entry_id" json:"-"`
+	MessageID   string    `db:"message_id" json:"message_id"`
+	SessionID   string    `db:"session_id" json:"session_id"`
+	ProjectName *string   `db:"project_name" json:"project_name,omitempty"`
+	Timestamp   time.Time `db:"timestamp" json:"timestamp"`
+}
+
+// KnowledgeExtractionRun records one pass of the knowledge extraction job
+type KnowledgeExtractionRun struct {
+	ID              int        `db:"id" json:"id"`
+	StartTime       time.Time  `db:"start_time" json:"start_time"`
+	EndTime         *time.Time `db:"end_time" json:"end_time,omitempty"`
+	Status          string     `db:"status" json:"status"`
+	FullScan        bool       `db:"full_scan" json:"full_scan"`
+	SessionsScanned int        `db:"sessions_scanned" json:"sessions_scanned"`
+	EntriesFound    int        `db:"entries_found" json:"entries_found"`
+	ErrorMessage    *string    `db:"error_message" json:"error_message,omitempty"`
+}
+
+// TranscriptMessage is the subset of a message needed to read a transcript
+type TranscriptMessage struct {
+	ID        string    `db:"id"`
+	SessionID string    `db:"session_id"`
+	Role      string    `db:"role"`
+	Content   string    `db:"content"`
+	Timestamp time.Time `db:"timestamp"`
+}
+
+// Session review statuses
+const (
+	ReviewStatusNeedsReview = "needs-review"
+	ReviewStatusReviewed    = "reviewed"
+	ReviewStatusFlagged     = "flagged"
+)
+
+// SessionReview is the review state of a session
+type SessionReview struct {
+	SessionID  string     `db:"session_id" json:"session_id"`
+	Status     string     `db:"status" json:"status"`
+	Reviewer   *string    `db:"reviewer" json:"reviewer,omitempty"`
+	UpdatedBy  *string    `db:"updated_by" json:"updated_by,omitempty"`
+	ReviewedAt *time.Time `db:"reviewed_at" json:"reviewed_at,omitempty"`
+	CreatedAt  time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt  time.Time  `db:"updated_at" json:"updated_at"`
+}
+
+// SessionReviewComment is a reviewer's comment on a session
+type SessionReviewComment struct {
+	ID        string    `db:"id" json:"id"`
+	SessionID string    `db:"session_id" json:"session_id"`
+	Author    string    `db:"author" json:"author"`
+	Body      string    `db:"body" json:"body"`
+	MessageID *string   `db:"message_id" json:"message_id,omitempty"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
+
+// ReviewQueueItem is a session in the review queue with enough context to triage it
+type ReviewQueueItem struct {
+	SessionID    string     `db:"session_id" json:"session_id"`
+	Status       string     `db:"status" json:"status"`
+	Reviewer     *string    `db:"reviewer" json:"reviewer,omitempty"`
+	UpdatedAt    *time.Time `db:"updated_at" json:"updated_at,omitempty"`
+	ProjectName  string     `db:"project_name" json:"project_name"`
+	ProjectPath  string     `db:"project_path" json:"project_path"`
+	GitBranch    *string    `db:"git_branch" json:"git_branch,omitempty"`
+	LastActivity time.Time  `db:"last_activity" json:"last_activity"`
+	MessageCount int        `db:"message_count" json:"message_count"`
+	FilesChanged int        `db:"files_changed" json:"files_changed"`
+	CommentCount int        `db:"comment_count" json:"comment_count"`
+}
+
+// LegalHold prevents a session from being pruned or archived while it is active
+type LegalHold struct {
+	ID         string     `db:"id" json:"id"`
+	SessionID  string     `db:"session_id" json:"session_id"`
+	Reason     string     `db:"reason" json:"reason"`
+	PlacedBy   string     `db:"placed_by" json:"placed_by"`
+	PlacedAt   time.Time  `db:"placed_at" json:"placed_at"`
+	ReleasedBy *string    `db:"released_by" json:"released_by,omitempty"`
+	ReleasedAt *time.Time `db:"released_at" json:"released_at,omitempty"`
+}
+
+// SessionEdit is a split or merge of sessions made by hand. A split moves a
+// session's messages from MessageID on into TargetSessionID; a merge moves
+// all of them and removes the session.
+type SessionEdit struct {
+	ID              int64      `db:"id" json:"id"`
+	Kind            string     `db:"kind" json:"kind"`
+	SessionID       string     `db:"session_id" json:"session_id"`
+	TargetSessionID string     `db:"target_session_id" json:"target_session_id"`
+	MessageID       *string    `db:"message_id" json:"message_id,omitempty"`
+	SplitAt         *time.Time `db:"split_at" json:"split_at,omitempty"`
+	Reason          *string    `db:"reason" json:"reason,omitempty"`
+	EditedBy        *string    `db:"edited_by" json:"edited_by,omitempty"`
+	CreatedAt       time.Time  `db:"created_at" json:"created_at"`
+}
+
+// ComplianceRecords is everything stored about a session, gathered for a compliance export
+type ComplianceRecords struct {
+	Session     *SessionSummary
+	Messages    []Message
+	Hashes      []MessageHash
+	ToolResults []ToolResult
+	Activity    []ActivityLogEntry
+	LegalHolds  []LegalHold
+	Review      *SessionReview
+	Comments    []SessionReviewComment
+}
+
+// ReproRecords is what is stored about a session that helps reproduce its
+// changes
+type ReproRecords struct {
+	Session     *SessionSummary
+	GitBranch   string
+	GitWorktree string
+	Messages    []Message
+	Environment *SessionEnvironment
+	Tags        []SessionTag
+}
+
+// TranscriptRecords is what a transcript export renders: the session, its
+// messages and the token usage of each
+type TranscriptRecords struct {
+	Session    *SessionSummary
+	Messages   []Message
+	TokenUsage []TokenUsage
+}
+
+// MessageHash is a message's content hash and its link in the session's hash chain
+type MessageHash struct {
+	MessageID   string    `db:"message_id" json:"message_id"`
+	SessionID   string    `db:"session_id" json:"session_id"`
+	Seq         int       `db:"seq" json:"seq"`
+	ContentHash string    `db:"content_hash" json:"content_hash"`
+	ChainHash   string    `db:"chain_hash" json:"chain_hash"`
+	CreatedAt   time.Time `db:"created_at" json:"created_at"`
+}
+
+// SessionHash is the head of a session's message hash chain
+type SessionHash struct {
+	SessionID    string    `db:"session_id" json:"session_id"`
+	MessageCount int       `db:"message_count" json:"message_count"`
+	HeadHash     string    `db:"head_hash" json:"head_hash"`
+	UpdatedAt    time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// SessionCost is a session's usage within a reporting period
+type SessionCost struct {
+	SessionID   string  `db:"session_id" json:"session_id"`
+	ProjectName string  `db:"project_name" json:"project_name"`
+	Messages    int     `db:"messages" json:"messages"`
+	Tokens      int     `db:"tokens" json:"tokens"`
+	CostUSD     float64 `db:"cost_usd" json:"cost_usd"`
+}
+
+// CostBreakdown is the token usage and cost of one group of GetCostBreakdown
+type CostBreakdown struct {
+	Name         string  `db:"name" json:"name"`
+	Sessions     int     `db:"sessions" json:"sessions"`
+	Messages     int     `db:"messages" json:"messages"`
+	TotalTokens  int     `db:"total_tokens" json:"total_tokens"`
+	CachedTokens int     `db:"cached_tokens" json:"cached_tokens"` // read from the cache
+	FreshTokens  int     `db:"fresh_tokens" json:"fresh_tokens"`   // input, output and cache writes
+	CostUSD      float64 `db:"cost_usd" json:"cost_usd"`
+	CacheSavings float64 `db:"cache_savings" json:"cache_savings"`
+}
+
+// SessionModelUsage is what a session asked of its model: how many prompts
+// it sent, how many tools it used and the tokens and cost that took
+type SessionModelUsage struct {
+	SessionID                string  `db:"session_id" json:"session_id"`
+	ProjectName              string  `db:"project_name" json:"project_name"`
+	Model                    string  `db:"model" json:"model"`
+	Prompts                  int     `db:"prompts" json:"prompts"`
+	ToolUses                 int     `db:"tool_uses" json:"tool_uses"`
+	InputTokens              int     `db:"input_tokens" json:"input_tokens"`
+	OutputTokens             int     `db:"output_tokens" json:"output_tokens"`
+	CacheCreationInputTokens int     `db:"cache_creation_input_tokens" json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int     `db:"cache_read_input_tokens" json:"cache_read_input_tokens"`
+	CostUSD                  float64 `db:"cost_usd" json:"cost_usd"`
+	DurationSeconds          int64   `db:"duration_seconds" json:"duration_seconds"`
+}
+
+// ModelMonthUsage is the tokens used with one model in a month and their
+// recorded cost
+type ModelMonthUsage struct {
+	Month                    string  `db:"month" json:"month"` // YYYY-MM, UTC
+	Model                    string  `db:"model" json:"model"`
+	Sessions                 int     `db:"sessions" json:"sessions"`
+	InputTokens              int     `db:"input_tokens" json:"input_tokens"`
+	OutputTokens             int     `db:"output_tokens" json:"output_tokens"`
+	CacheCreationInputTokens int     `db:"cache_creation_input_tokens" json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int     `db:"cache_read_input_tokens" json:"cache_read_input_tokens"`
+	CostUSD                  float64 `db:"cost_usd" json:"cost_usd"`
+}
+
+// DailyUsage is the tokens used and their cost in one project with one model
+// on a day
+type DailyUsage struct {
+	Day     string  `db:"day" json:"day"` // YYYY-MM-DD, UTC
+	Project string  `db:"project" json:"project"`
+	Model   string  `db:"model" json:"model"`
+	Tokens  int     `db:"tokens" json:"tokens"`
+	CostUSD float64 `db:"cost_usd" json:"cost_usd"`
+}
+
+// LineCost is a project's spend in a month alongside the lines its Edit,
+// Write and MultiEdit tool calls added and removed that month
+type LineCost struct {
+	ProjectName  string   `db:"project_name" json:"project_name"`
+	Month        string   `db:"month" json:"month"` // YYYY-MM, UTC
+	CostUSD      float64  `db:"cost_usd" json:"cost_usd"`
+	Edits        int      `json:"edits"`
+	LinesAdded   int      `json:"lines_added"`
+	LinesRemoved int      `json:"lines_removed"`
+	CostPerKLOC  *float64 `json:"cost_per_kloc"` // cost per 1,000 lines added; nil when none were
+}
+
+// Todo statuses
+const (
+	TodoStatusPending    = "pending"
+	TodoStatusInProgress = "in_progress"
+	TodoStatusCompleted  = "completed"
+)
+
+// SessionTodo is an item on a todo list Claude kept during a session. Agent
+// is the session itself or one of its subagents.
+type SessionTodo struct {
+	SessionID      string     `db:"session_id" json:"session_id"`
+	AgentID        string     `db:"agent_id" json:"agent_id"`
+	Position       int        `db:"position" json:"position"`
+	TodoID         *string    `db:"todo_id" json:"todo_id,omitempty"`
+	Content        string     `db:"content" json:"content"`
+	Status         string     `db:"status" json:"status"`
+	ActiveForm     *string    `db:"active_form" json:"active_form,omitempty"`
+	Priority       *string    `db:"priority" json:"priority,omitempty"`
+	FileModifiedAt *time.Time `db:"file_modified_at" json:"file_modified_at,omitempty"`
+	UpdatedAt      time.Time  `db:"updated_at" json:"updated_at"`
+	ProjectName    *string    `db:"project_name" json:"project_name,omitempty"`
+}
+
+// SettingsChange is a recorded version of the Claude settings file
+type SettingsChange struct {
+	ID             int64      `db:"id" json:"id"`
+	FilePath       string     `db:"file_path" json:"file_path"`
+	Checksum       string     `db:"checksum" json:"checksum"`
+	Content        string     `db:"content" json:"content"`
+	ChangedKeys    string     `db:"changed_keys" json:"-"`
+	Keys           []string   `db:"-" json:"changed_keys"`
+	FileModifiedAt *time.Time `db:"file_modified_at" json:"file_modified_at,omitempty"`
+	RecordedAt     time.Time  `db:"recorded_at" json:"recorded_at"`
+}
+
+// HookEvent is an event POSTed by a Claude Code hook. Payload is the hook
+// input exactly as received.
+type HookEvent struct {
+	ID             int64           `db:"id" json:"id"`
+	SessionID      string          `db:"session_id" json:"session_id"`
+	Event          string          `db:"event" json:"event"`
+	ToolName       *string         `db:"tool_name" json:"tool_name,omitempty"`
+	ToolUseID      *string         `db:"tool_use_id" json:"tool_use_id,omitempty"`
+	CWD            *string         `db:"cwd" json:"cwd,omitempty"`
+	TranscriptPath *string         `db:"transcript_path" json:"transcript_path,omitempty"`
+	Payload        string          `db:"payload" json:"-"`
+	Data           json.RawMessage `db:"-" json:"payload"`
+	ReceivedAt     time.Time       `db:"received_at" json:"received_at"`
+}
+
+// SessionLiveness is whether a session is running as last reported by its
+// hooks
+type SessionLiveness struct {
+	SessionID string    `db:"session_id" json:"session_id"`
+	IsActive  bool      `db:"is_active" json:"is_active"`
+	Event     string    `db:"event" json:"event"`
+	ChangedAt time.Time `db:"changed_at" json:"changed_at"`
+}
+
+// StatuslineUsage is the usage summarized on a Claude Code statusline
+type StatuslineUsage struct {
+	SessionTokens  int     `db:"session_tokens" json:"session_tokens"`
+	SessionCostUSD float64 `db:"session_cost_usd" json:"session_cost_usd"`
+	TodayTokens    int     `db:"today_tokens" json:"today_tokens"`
+	TodayCostUSD   float64 `db:"today_cost_usd" json:"today_cost_usd"`
+}
+
+// ProjectSummary is the aggregate view of a single project's sessions
+type ProjectSummary struct {
+	ProjectName    string              `db:"-" json:"project_name"`
+	TotalSessions  int                 `db:"total_sessions" json:"total_sessions"`
+	ActiveSessions int                 `db:"active_sessions" json:"active_sessions"`
+	TotalMessages  int                 `db:"-" json:"total_messages"`
+	TokenUsage     TokenUsageAggregate `db:"-" json:"token_usage"`
+	EstimatedCost  float64             `db:"-" json:"estimated_cost"`
+	MostUsedModel  string              `db:"-" json:"most_used_model"`
+	FirstActivity  time.Time           `db:"-" json:"first_activity"`
+	LastActivity   time.Time           `db:"-" json:"last_activity"`
+}
+
+// FileSession is a session that read or changed a file, for editors showing
+// which sessions touched the open file
+type FileSession struct {
+	SessionID   string    `db:"session_id" json:"session_id"`
+	ProjectName string    `db:"project_name" json:"project_name"`
+	GitBranch   *string   `db:"git_branch" json:"git_branch,omitempty"`
+	IsActive    bool      `db:"is_active" json:"is_active"`
+	Touches     int       `db:"touches" json:"touches"`
+	Tools       string    `db:"tools" json:"tools"`
+	LastTouched time.Time `db:"last_touched" json:"last_touched"`
+}
+
+// WorkspaceUsage is a workspace's usage since the start of the day and its
+// sessions' current activity
+type WorkspaceUsage struct {
+	TodaySessions  int        `db:"today_sessions" json:"today_sessions"`
+	TodayTokens    int        `db:"today_tokens" json:"today_tokens"`
+	TodayCostUSD   float64    `db:"today_cost_usd" json:"today_cost_usd"`
+	ActiveSessions int        `db:"active_sessions" json:"active_sessions"`
+	LastActivity   *time.Time `db:"last_activity" json:"last_activity,omitempty"`
+}
+
+// FileChange is a tool call that modified a file
+type FileChange struct {
+	FilePath     string    `db:"file_path" json:"file_path"`
+	AbsolutePath *string   `db:"absolute_path" json:"absolute_path,omitempty"`
+	SessionID    string    `db:"session_id" json:"session_id"`
+	ProjectName  string    `db:"project_name" json:"project_name"`
+	GitBranch    *string   `db:"git_branch" json:"git_branch,omitempty"`
+	MessageID    string    `db:"message_id" json:"message_id"`
+	ToolName     string    `db:"tool_name" json:"tool_name"`
+	Timestamp    time.Time `db:"timestamp" json:"timestamp"`
+}
+
+// FileEdit is one Edit, Write or MultiEdit tool call on a file with the
+// change it made as a unified diff. Edits only record the replaced text, so
+// diff line numbers count from the start of the edit, not the file; a Write
+// is shown as adding the whole content.
+type FileEdit struct {
+	FileChange
+	ResultData string `db:"result_data" json:"-"`
+	Diff       string `json:"diff"`
+}
+
+// QuickLookSession is a compact search result for launcher integrations
+type QuickLookSession struct {
+	ID           string    `db:"id" json:"id"`
+	ProjectName  string    `db:"project_name" json:"project_name"`
+	ProjectPath  string    `db:"project_path" json:"project_path"`
+	GitBranch    *string   `db:"git_branch" json:"git_branch,omitempty"`
+	LastActivity time.Time `db:"last_activity" json:"last_activity"`
+	IsActive     bool      `db:"is_active" json:"is_active"`
+	MessageCount int       `db:"message_count" json:"message_count"`
+	Prompt       string    `db:"prompt" json:"prompt"`
+	CostUSD      float64   `db:"cost_usd" json:"cost_usd"`
+}
+
+// PeriodUsage is the usage from messages sent within a period
+type PeriodUsage struct {
+	Sessions                 int              `db:"sessions" json:"sessions"`
+	Messages                 int              `db:"messages" json:"messages"`
+	InputTokens              int              `db:"input_tokens" json:"input_tokens"`
+	OutputTokens             int              `db:"output_tokens" json:"output_tokens"`
+	CacheCreationInputTokens int              `db:"cache_creation_input_tokens" json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int              `db:"cache_read_input_tokens" json:"cache_read_input_tokens"`
+	Tokens                   int              `db:"tokens" json:"tokens"`
+	CostUSD                  float64          `db:"cost_usd" json:"cost_usd"`
+	ByProject                []UsageAggregate `db:"-" json:"by_project"`
+	ByModel                  []UsageAggregate `db:"-" json:"by_model"`
+}
+
+// MonthlySnapshot is a month's usage aggregates frozen at month close. Data
+// holds the full aggregates as JSON; Checksum is its SHA-256.
+type MonthlySnapshot struct {
+	Month       string    `db:"month" json:"month"`
+	PeriodStart time.Time `db:"period_start" json:"period_start"`
+	PeriodEnd   time.Time `db:"period_end" json:"period_end"`
+	Sessions    int       `db:"sessions" json:"sessions"`
+	Messages    int       `db:"messages" json:"messages"`
+	Tokens      int       `db:"tokens" json:"tokens"`
+	CostUSD     float64   `db:"cost_usd" json:"cost_usd"`
+	Currency    string    `db:"currency" json:"currency"`
+	Data        string    `db:"data" json:"-"`
+	Checksum    string    `db:"checksum" json:"checksum"`
+	ClosedAt    time.Time `db:"closed_at" json:"closed_at"`
+}
+
+// Session tag sources
+const (
+	TagSourceManual = "manual"
+	TagSourceRule   = "rule"
+	TagSourceScript = "script"
+)
+
+// SessionTag is a label on a session
+type SessionTag struct {
+	SessionID string    `db:"session_id" json:"session_id"`
+	Tag       string    `db:"tag" json:"tag"`
+	Source    string    `db:"source" json:"source"`
+	Rule      *string   `db:"rule" json:"rule,omitempty"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
+
+// SessionNote is a free-form note attached to a session
+type SessionNote struct {
+	SessionID string    `db:"session_id" json:"session_id"`
+	Content   string    `db:"content" json:"content"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// ScriptField is a custom field a script computed for a session or, when
+// MessageID is set, for one of its messages
+type ScriptField struct {
+	SessionID string    `db:"session_id" json:"session_id"`
+	MessageID string    `db:"message_id" json:"message_id,omitempty"`
+	Name      string    `db:"name" json:"name"`
+	Value     string    `db:"value" json:"value"`
+	Script    string    `db:"script" json:"script"`
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// ScriptEvaluation records the outcome of a session's last evaluation
+// against the session scripts
+type ScriptEvaluation struct {
+	SessionID      string   `db:"session_id"`
+	ScriptsVersion string   `db:"scripts_version"`
+	MessageCount   int      `db:"message_count"`
+	Matched        []string `db:"-"` // notify scripts that were true
+}
+
+// PruneFilter selects the sessions a retention run prunes or archives. The
+// preview selects through the same filter as the run, so it shows exactly
+// what the run would affect. Active sessions are never selected.
+type PruneFilter struct {
+	Before  time.Time `json:"before"`            // last activity before this time
+	Project string    `json:"project,omitempty"` // exact project name
+	Tags    []string  `json:"tags,omitempty"`    // sessions must carry every tag
+}
+
+// PruneCandidate is a session a retention run would prune or archive, with
+// what is stored for it
+type PruneCandidate struct {
+	SessionID    string    `db:"session_id" json:"session_id"`
+	ProjectName  string    `db:"project_name" json:"project_name"`
+	StartTime    time.Time `db:"start_time" json:"start_time"`
+	LastActivity time.Time `db:"last_activity" json:"last_activity"`
+	Messages     int       `db:"messages" json:"messages"`
+	SizeBytes    int64     `db:"size_bytes" json:"size_bytes"` // stored message content and tool results
+	Tokens       int64     `db:"tokens" json:"tokens"`
+	CostUSD      float64   `db:"cost_usd" json:"cost_usd"`
+	LegalHold    bool      `db:"legal_hold" json:"legal_hold"`
+}
+
+// PruneCostMonth is the cost of the messages a retention run would remove
+// that were sent in one month
+type PruneCostMonth struct {
+	Month    string  `db:"month" json:"month"` // YYYY-MM
+	Messages int     `db:"messages" json:"messages"`
+	Tokens   int64   `db:"tokens" json:"tokens"`
+	CostUSD  float64 `db:"cost_usd" json:"cost_usd"`
+}
+
+// PrunePreview summarizes what a retention run with a filter would affect
+// without changing anything. Totals cover Sessions only: sessions under
+// legal hold are listed in Held and would be skipped.
+type PrunePreview struct {
+	Filter      PruneFilter      `json:"filter"`
+	Sessions    []PruneCandidate `json:"sessions"`
+	Held        []PruneCandidate `json:"held"`
+	Messages    int              `json:"messages"`
+	SizeBytes   int64            `json:"size_bytes"`
+	Tokens      int64            `json:"tokens"`
+	CostUSD     float64          `json:"cost_usd"`
+	CostHistory []PruneCostMonth `json:"cost_history"`
+}
+
+// TagSubject is the part of a session that tagging rules match against
+type TagSubject struct {
+	SessionID    string `db:"session_id"`
+	ProjectName  string `db:"project_name"`
+	ProjectPath  string `db:"project_path"`
+	GitBranch    string `db:"git_branch"`
+	MessageCount int    `db:"message_count"`
+	FirstMessage string `db:"-"`
+}
+
+// Environment sources
+const (
+	EnvironmentSourceDetected = "detected"
+	EnvironmentSourceReported = "reported"
+)
+
+// SessionEnvironment records the machine a session ran on. Fields that
+// could not be resolved are nil.
+type SessionEnvironment struct {
+	SessionID     string    `db:"session_id" json:"session_id"`
+	OS            *string   `db:"os" json:"os,omitempty"`
+	Terminal      *string   `db:"terminal" json:"terminal,omitempty"`
+	GitRemote     *string   `db:"git_remote" json:"git_remote,omitempty"`
+	CWD           *string   `db:"cwd" json:"cwd,omitempty"`
+	ClientVersion *string   `db:"client_version" json:"client_version,omitempty"`
+	Source        string    `db:"source" json:"source"`
+	CapturedAt    time.Time `db:"captured_at" json:"captured_at"`
+	UpdatedAt     time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// EnvironmentCount is the number of sessions sharing one environment value
+type EnvironmentCount struct {
+	Field    string `db:"field" json:"field"`
+	Value    string `db:"value" json:"value"`
+	Sessions int    `db:"sessions" json:"sessions"`
+}
+
+// PromptSignature is the MinHash signature of a session's opening prompt
+type PromptSignature struct {
+	SessionID   string    `db:"session_id" json:"session_id"`
+	ProjectName *string   `db:"project_name" json:"project_name,omitempty"`
+	Prompt      string    `db:"prompt" json:"prompt"`
+	Signature   string    `db:"signature" json:"-"`
+	StartedAt   time.Time `db:"started_at" json:"started_at"`
+	CreatedAt   time.Time `db:"created_at" json:"-"`
+}
+
+// UsageAggregate is the usage rolled up under one key, such as a project, model or day
+type UsageAggregate struct {
+	Key      string  `db:"key" json:"key"`
+	Sessions int     `db:"sessions" json:"sessions"`
+	Messages int     `db:"messages" json:"messages"`
+	Tokens   int     `db:"tokens" json:"tokens"`
+	CostUSD  float64 `db:"cost_usd" json:"cost_usd"`
+}
+
+// UsageSummary is the aggregate-only view of an instance's usage shared with
+// a federation parent. It never includes message content.
+type UsageSummary struct {
+	GeneratedAt    time.Time        `json:"generated_at"`
+	Days           int              `json:"days"`
+	TotalSessions  int              `json:"total_sessions"`
+	ActiveSessions int              `json:"active_sessions"`
+	TotalMessages  int              `json:"total_messages"`
+	TotalTokens    int              `json:"total_tokens"`
+	TotalCostUSD   float64          `json:"total_cost_usd"`
+	ByProject      []UsageAggregate `json:"by_project"`
+	ByModel        []UsageAggregate `json:"by_model"`
+	ByDay          []UsageAggregate `json:"by_day"`
+}
+
+// VersionUsage is the usage and error rates of messages written by one
+// Claude Code client version
+type VersionUsage struct {
+	Version       string  `db:"version" json:"version"`
+	Sessions      int     `db:"sessions" json:"sessions"`
+	Messages      int     `db:"messages" json:"messages"`
+	Tokens        int     `db:"tokens" json:"tokens"`
+	CostUSD       float64 `db:"cost_usd" json:"cost_usd"`
+	ToolResults   int     `db:"tool_results" json:"tool_results"`
+	ToolErrors    int     `db:"tool_errors" json:"tool_errors"`
+	Interruptions int     `db:"interruptions" json:"interruptions"`
+	ErrorRate     float64 `db:"-" json:"error_rate"`         // tool errors per tool result
+	TokensPerMsg  float64 `db:"-" json:"tokens_per_message"` // average tokens per message
+	FirstSeen     string  `db:"first_seen" json:"first_seen"`
+	LastSeen      string  `db:"last_seen" json:"last_seen"`
+}
+
+// SessionMetrics is the metrics-only view of a session used for exports. It
+// carries identifiers and counts but never message content or file paths.
+type SessionMetrics struct {
+	SessionID                string    `db:"session_id"`
+	ProjectName              string    `db:"project_name"`
+	Model                    string    `db:"model"`
+	StartTime                time.Time `db:"start_time"`
+	DurationSeconds          int64     `db:"duration_seconds"`
+	MessageCount             int       `db:"message_count"`
+	InputTokens              int       `db:"input_tokens"`
+	OutputTokens             int       `db:"output_tokens"`
+	CacheCreationInputTokens int       `db:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int       `db:"cache_read_input_tokens"`
+	TotalTokens              int       `db:"total_tokens"`
+	CostUSD                  float64   `db:"cost_usd"`
+	ToolCalls                int       `db:"tool_calls"`
+	FilesTouched             int       `db:"files_touched"`
+}
+
+// FederationSnapshot is the latest summary pulled from a team instance
+type FederationSnapshot struct {
+	InstanceName  string     `db:"instance_name" json:"instance_name"`
+	URL           string     `db:"url" json:"url"`
+	Status        string     `db:"status" json:"status"`
+	Error         *string    `db:"error" json:"error,omitempty"`
+	Summary       *string    `db:"summary" json:"-"`
+	LastSuccessAt *time.Time `db:"last_success_at" json:"last_success_at,omitempty"`
+	PolledAt      time.Time  `db:"polled_at" json:"polled_at"`
+}
+
+// SessionLineage is the resume chain a session belongs to
+type SessionLineage struct {
+	ParentSessionID *string        `json:"parent_session_id,omitempty"`
+	ChildSessionIDs []string       `json:"child_session_ids"`
+	Chain           []LineageEntry `json:"chain"`
+}
+
+// LineageEntry is one session in a resume chain. Depth is relative to the
+// session the chain was requested for: ancestors are negative, descendants
+// positive.
+type LineageEntry struct {
+	SessionID       string    `db:"session_id" json:"session_id"`
+	ParentSessionID *string   `db:"parent_session_id" json:"parent_session_id,omitempty"`
+	Depth           int       `db:"depth" json:"depth"`
+	StartTime       time.Time `db:"start_time" json:"start_time"`
+	LastActivity    time.Time `db:"last_activity" json:"last_activity"`
+	MessageCount    int       `db:"message_count" json:"message_count"`
+}
+
+// Thread is a session and every session resumed from it, read as one
+// transcript
+type Thread struct {
+	RootSessionID string          `json:"root_session_id"`
+	StartTime     time.Time       `json:"start_time"`
+	LastActivity  time.Time       `json:"last_activity"`
+	TotalCost     float64         `json:"total_cost"`
+	TotalTokens   int64           `json:"total_tokens"`
+	Sessions      []ThreadSession `json:"sessions"`
+	Messages      []ThreadMessage `json:"messages"`
+}
+
+// ThreadSession is one session of a thread, with the thread's cost up to the
+// end of the session
+type ThreadSession struct {
+	SessionID       string    `db:"session_id" json:"session_id"`
+	ParentSessionID *string   `db:"parent_session_id" json:"parent_session_id,omitempty"`
+	Depth           int       `db:"depth" json:"depth"`
+	StartTime       time.Time `db:"start_time" json:"start_time"`
+	LastActivity    time.Time `db:"last_activity" json:"last_activity"`
+	MessageCount    int       `db:"message_count" json:"message_count"`
+	Cost            float64   `db:"cost" json:"cost"`
+	Tokens          int64     `db:"tokens" json:"tokens"`
+	CumulativeCost  float64   `db:"-" json:"cumulative_cost"`
+}
+
+// ThreadMessage is a message of a thread with the thread's cost so far
+type ThreadMessage struct {
+	ID             string    `db:"id" json:"id"`
+	SessionID      string    `db:"session_id" json:"session_id"`
+	Role           string    `db:"role" json:"role"`
+	Content        string    `db:"content" json:"content"`
+	IsSidechain    bool      `db:"is_sidechain" json:"is_sidechain"`
+	Timestamp      time.Time `db:"timestamp" json:"timestamp"`
+	Cost           float64   `db:"cost" json:"cost"`
+	CumulativeCost float64   `db:"-" json:"cumulative_cost"`
+}
+
+// DeadLetter is a JSONL line an import couldn't read as usual
+type DeadLetter struct {
+	ID          int64     `db:"id" json:"id"`
+	FilePath    string    `db:"file_path" json:"file_path"`
+	ByteOffset  int64     `db:"byte_offset" json:"byte_offset"`
+	ByteSize    int64     `db:"byte_size" json:"byte_size"`
+	Reason      string    `db:"reason" json:"reason"`
+	Outcome     string    `db:"outcome" json:"outcome"`
+	Error       *string   `db:"error" json:"error,omitempty"`
+	Occurrences int       `db:"occurrences" json:"occurrences"`
+	FirstSeen   time.Time `db:"first_seen" json:"first_seen"`
+	LastSeen    time.Time `db:"last_seen" json:"last_seen"`
+}
+
+// Dead letter reasons and outcomes
+const (
+	DeadLetterOversizedLine = "oversized_line"
+	DeadLetterDecoded       = "decoded"
+	DeadLetterSkipped       = "skipped"
+)
+
+// Project is a project as reported: the sessions imported under any of its
+// aliases, the project names it is made up of, with their totals
+type Project struct {
+	ID            int64     `db:"id" json:"id"`
+	DisplayName   string    `db:"display_name" json:"display_name"`
+	CanonicalPath string    `db:"canonical_path" json:"canonical_path"`
+	Aliases       []string  `db:"-" json:"aliases"`
+	Sessions      int       `db:"sessions" json:"sessions"`
+	Messages      int       `db:"messages" json:"messages"`
+	TotalTokens   int       `db:"total_tokens" json:"total_tokens"`
+	TotalCost     float64   `db:"total_cost" json:"total_cost"`
+	LastActivity  string    `db:"last_activity" json:"last_activity,omitempty"`
+	CreatedAt     time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt     time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// Budget is a daily or monthly cost limit for one project, or all projects
+// when ProjectName is nil
+type Budget struct {
+	ID          string    `db:"id" json:"id"`
+	Name        string    `db:"name" json:"name"`
+	ProjectName *string   `db:"project_name" json:"project_name,omitempty"`
+	Period      string    `db:"period" json:"period"`
+	LimitUSD    float64   `db:"limit_usd" json:"limit_usd"`
+	CreatedAt   time.Time `db:"created_at" json:"created_at"`
+}
+
+// Webhook is a URL sent a JSON payload when one of the events it subscribes
+// to occurs
+type Webhook struct {
+	ID            string     `db:"id" json:"id"`
+	URL           string     `db:"url" json:"url"`
+	Events        []string   `db:"-" json:"events"`
+	EventList     string     `db:"events" json:"-"` // Events, comma-separated
+	Secret        string     `db:"secret" json:"-"`
+	HasSecret     bool       `db:"-" json:"has_secret"`
+	Description   *string    `db:"description" json:"description,omitempty"`
+	Enabled       bool       `db:"enabled" json:"enabled"`
+	LastStatus    *string    `db:"last_status" json:"last_status,omitempty"`
+	LastError     *string    `db:"last_error" json:"last_error,omitempty"`
+	LastAttemptAt *time.Time `db:"last_attempt_at" json:"last_attempt_at,omitempty"`
+	CreatedAt     time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt     time.Time  `db:"updated_at" json:"updated_at"`
+}
+
+// Dashboard is a saved layout of widgets, kept on the server so a team's
+// dashboards don't depend on which frontend shows them
+type Dashboard struct {
+	ID          string            `db:"id" json:"id"`
+	Name        string            `db:"name" json:"name"`
+	Description *string           `db:"description" json:"description,omitempty"`
+	Widgets     []DashboardWidget `db:"-" json:"widgets"`
+	WidgetsJSON string            `db:"widgets" json:"-"` // Widgets, as JSON
+	CreatedAt   time.Time         `db:"created_at" json:"created_at"`
+	UpdatedAt   time.Time         `db:"updated_at" json:"updated_at"`
+}
+
+// DashboardWidget is one widget on a dashboard: the API endpoint it shows,
+// with the query parameters to call it with, and where it sits
+type DashboardWidget struct {
+	ID       string            `json:"id"`
+	Title    string            `json:"title"`
+	Type     string            `json:"type"` // how the frontend draws it, such as line, bar, table or stat
+	Endpoint string            `json:"endpoint"`
+	Params   map[string]string `json:"params,omitempty"`
+	Layout   DashboardLayout   `json:"layout"`
+	Options  json.RawMessage   `json:"options,omitempty"` // frontend settings, stored as given
+}
+
+// DashboardLayout is a widget's position and size on the dashboard grid
+type DashboardLayout struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+	W int `json:"w"`
+	H int `json:"h"`
+}
+
+// Webhook delivery statuses
+const (
+	WebhookDelivered = "delivered"
+	WebhookFailed    = "failed"
+)
+
+// Budget periods
+const (
+	BudgetDaily   = "daily"
+	BudgetMonthly = "monthly"
+)
+
+// BudgetAlert records a budget crossing one of its thresholds in a period
+type BudgetAlert struct {
+	BudgetID    string    `db:"budget_id" json:"budget_id"`
+	PeriodStart time.Time `db:"period_start" json:"period_start"`
+	Threshold   int       `db:"threshold" json:"threshold"`
+	SpendUSD    float64   `db:"spend_usd" json:"spend_usd"`
+	TriggeredAt time.Time `db:"triggered_at" json:"triggered_at"`
+}
+
+// User is someone API keys are issued to
+type User struct {
+	ID        string    `db:"id" json:"id"`
+	Name      string    `db:"name" json:"name"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
+
+// Quota metrics
+const (
+	QuotaTokens = "tokens"
+	QuotaCost   = "cost"
+)
+
+// UserQuota is a user's soft monthly quota and the projects whose sessions
+// count against it. Users without a quota have zero limits.
+type UserQuota struct {
+	UserID         string     `db:"user_id" json:"user_id"`
+	UserName       string     `db:"user_name" json:"user_name"`
+	MonthlyTokens  int64      `db:"monthly_tokens" json:"monthly_tokens"`     // 0 for no token quota
+	MonthlyCostUSD float64    `db:"monthly_cost_usd" json:"monthly_cost_usd"` // 0 for no cost quota
+	Projects       []string   `db:"-" json:"projects"`
+	UpdatedAt      *time.Time `db:"updated_at" json:"updated_at,omitempty"`
+}
+
+// UserUsage is the tokens and cost counted against a user in a period
+type UserUsage struct {
+	UserID  string  `db:"user_id" json:"user_id"`
+	Tokens  int64   `db:"tokens" json:"tokens"`
+	CostUSD float64 `db:"cost_usd" json:"cost_usd"`
+}
+
+// QuotaAlert records a user's usage crossing a threshold of their quota for
+// one metric in one month
+type QuotaAlert struct {
+	UserID      string    `db:"user_id" json:"user_id"`
+	PeriodStart time.Time `db:"period_start" json:"period_start"`
+	Metric      string    `db:"metric" json:"metric"`
+	Threshold   int       `db:"threshold" json:"threshold"`
+	Used        float64   `db:"used" json:"used"`
+	TriggeredAt time.Time `db:"triggered_at" json:"triggered_at"`
+}
+
+// ExportJob is a heavy export generated in the background. Once it succeeds
+// its file is downloaded through a signed URL until ExpiresAt.
+type ExportJob struct {
+	ID          string     `db:"id" json:"id"`
+	Client      string     `db:"client" json:"-"`
+	Kind        string     `db:"kind" json:"kind"`
+	Dataset     *string    `db:"dataset" json:"dataset,omitempty"`
+	Format      string     `db:"format" json:"format"`
+	Days        int        `db:"days" json:"days,omitempty"`
+	Status      string     `db:"status" json:"status"`
+	FileName    *string    `db:"file_name" json:"file_name,omitempty"`
+	SizeBytes   int64      `db:"size_bytes" json:"size_bytes"`
+	Error       *string    `db:"error" json:"error,omitempty"`
+	StartedAt   *time.Time `db:"started_at" json:"started_at,omitempty"`
+	CompletedAt *time.Time `db:"completed_at" json:"completed_at,omitempty"`
+	ExpiresAt   *time.Time `db:"expires_at" json:"expires_at,omitempty"`
+	CreatedAt   time.Time  `db:"created_at" json:"created_at"`
+}
+
+// NotificationPreferences is how and when a user is told of the events the
+// notifier sends: through which channels, for which events, at what
+// thresholds and outside which quiet hours
+type NotificationPreferences struct {
+	UserID             string                `db:"user_id" json:"user_id"`
+	UserName           string                `db:"user_name" json:"user_name"`
+	Channels           []NotificationChannel `db:"-" json:"channels"`
+	ChannelsJSON       string                `db:"channels" json:"-"`                                // Channels, as JSON
+	Events             []string              `db:"-" json:"events"`                                  // empty for the events the config enables
+	EventsJSON         string                `db:"events" json:"-"`                                  // Events, as JSON
+	DailyCostThreshold float64               `db:"daily_cost_threshold" json:"daily_cost_threshold"` // USD; the config's when 0
+	IdleMinutes        int                   `db:"idle_minutes" json:"idle_minutes"`                 // the config's when 0
+	QuietHoursStart    string                `db:"quiet_hours_start" json:"quiet_hours_start"`       // HH:MM; no quiet hours when empty
+	QuietHoursEnd      string                `db:"quiet_hours_end" json:"quiet_hours_end"`
+	Timezone           string                `db:"timezone" json:"timezone"` // IANA name; the server's local time when empty
+	UpdatedAt          time.Time             `db:"updated_at" json:"updated_at"`
+}
+
+// NotificationChannel is somewhere a user's notifications are delivered: a
+// Slack or Discord webhook, an email address or a webhook taking JSON
+type NotificationChannel struct {
+	Type   string `json:"type"`             // slack, discord, email or webhook
+	Target string `json:"target,omitempty"` // URL or address; Slack and Discord use the config's webhook when empty
+}
+
+// APIKey is a key accepted by the API when auth is enabled. Only the hash of
+// the key is stored; Prefix identifies it in listings.
+type APIKey struct {
+	ID         string     `db:"id" json:"id"`
+	UserID     string     `db:"user_id" json:"user_id"`
+	UserName   string     `db:"user_name" json:"user_name"`
+	Name       string     `db:"name" json:"name"`
+	Prefix     string     `db:"prefix" json:"prefix"`
+	KeyHash    string     `db:"key_hash" json:"-"`
+	Scope      string     `db:"scope" json:"scope"`
+	CreatedAt  time.Time  `db:"created_at" json:"created_at"`
+	LastUsedAt *time.Time `db:"last_used_at" json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `db:"revoked_at" json:"revoked_at,omitempty"`
+}
+
+// APIUsage is the requests to one route in one UTC hour, as recorded by the
+// API usage middleware
+type APIUsage struct {
+	Hour         time.Time `db:"hour" json:"hour"`
+	Method       string    `db:"method" json:"method"`
+	Route        string    `db:"route" json:"route"` // route template, e.g. /api/v1/sessions/:id
+	Requests     int64     `db:"requests" json:"requests"`
+	ClientErrors int64     `db:"client_errors" json:"client_errors"` // 4xx responses
+	ServerErrors int64     `db:"server_errors" json:"server_errors"` // 5xx responses
+	TotalMs      float64   `db:"total_ms" json:"total_ms"`
+	MaxMs        float64   `db:"max_ms" json:"max_ms"`
+}
+
+// APIRouteUsage is a route's requests over a period
+type APIRouteUsage struct {
+	Method         string  `db:"method" json:"method"`
+	Route          string  `db:"route" json:"route"`
+	Requests       int64   `db:"requests" json:"requests"`
+	RequestsPerMin float64 `db:"-" json:"requests_per_minute"`
+	ClientErrors   int64   `db:"client_errors" json:"client_errors"`
+	ServerErrors   int64   `db:"server_errors" json:"server_errors"`
+	TotalMs        float64 `db:"total_ms" json:"total_ms"`
+	AvgMs          float64 `db:"avg_ms" json:"avg_ms"`
+	MaxMs          float64 `db:"max_ms" json:"max_ms"`
+	LastHour       string  `db:"last_hour" json:"last_hour"`
+}
+
+// API key scopes
+const (
+	ScopeRead  = "read"  // GET, HEAD and OPTIONS requests only
+	ScopeWrite = "write" // any request
+)
+
+// Playbook run and step statuses
+const (
+	RunStatusPending          = "pending"
+	RunStatusRunning          = "running"
+	RunStatusAwaitingApproval = "awaiting_approval"
+	RunStatusSucceeded        = "succeeded"
+	RunStatusFailed           = "failed"
+	RunStatusCancelled        = "cancelled"
+	RunStatusRejected         = "rejected"
+	RunStatusSkipped          = "skipped"
+)
@@ -0,0 +1,146 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// GetPeriodUsage returns usage from messages sent in [from, to), in total and
+// by project and model
+func (r *SessionRepository) GetPeriodUsage(from, to time.Time) (*PeriodUsage, error) {
+	// Token usage is pre-aggregated per message so joining it does not
+	// multiply message counts
+	periodMessages := `
+		FROM messages m
+		JOIN sessions s ON s.id = m.session_id
+		LEFT JOIN (
+			SELECT
+				message_id,
+				SUM(input_tokens) AS input_tokens,
+				SUM(output_tokens) AS output_tokens,
+				SUM(cache_creation_input_tokens) AS cache_creation_input_tokens,
+				SUM(cache_read_input_tokens) AS cache_read_input_tokens,
+				SUM(total_tokens) AS tokens,
+				SUM(estimated_cost) AS cost
+			FROM token_usage
+			GROUP BY message_id
+		) tu ON tu.message_id = m.id
+		WHERE m.timestamp >= ? AND m.timestamp < ?
+	`
+	from, to = from.UTC(), to.UTC()
+
+	var usage PeriodUsage
+	err := r.db.Get(&usage, `
+		SELECT
+			COUNT(DISTINCT m.session_id) AS sessions,
+			COUNT(DISTINCT m.id) AS messages,
+			COALESCE(SUM(tu.input_tokens), 0) AS input_tokens,
+			COALESCE(SUM(tu.output_tokens), 0) AS output_tokens,
+			COALESCE(SUM(tu.cache_creation_input_tokens), 0) AS cache_creation_input_tokens,
+			COALESCE(SUM(tu.cache_read_input_tokens), 0) AS cache_read_input_tokens,
+			COALESCE(SUM(tu.tokens), 0) AS tokens,
+			COALESCE(SUM(tu.cost), 0.0) AS cost_usd
+	`+periodMessages, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get period usage: %w", err)
+	}
+
+	usage.ByProject = []UsageAggregate{}
+	err = r.db.Select(&usage.ByProject, `
+		SELECT
+			s.project_name AS key,
+			COUNT(DISTINCT m.session_id) AS sessions,
+			COUNT(DISTINCT m.id) AS messages,
+			COALESCE(SUM(tu.tokens), 0) AS tokens,
+			COALESCE(SUM(tu.cost), 0.0) AS cost_usd
+	`+periodMessages+`
+		GROUP BY s.project_name
+		ORDER BY cost_usd DESC, key ASC
+	`, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get period usage by project: %w", err)
+	}
+
+	usage.ByModel = []UsageAggregate{}
+	err = r.db.Select(&usage.ByModel, `
+		SELECT
+			COALESCE(NULLIF(s.model, ''), 'unknown') AS key,
+			COUNT(DISTINCT m.session_id) AS sessions,
+			COUNT(DISTINCT m.id) AS messages,
+			COALESCE(SUM(tu.tokens), 0) AS tokens,
+			COALESCE(SUM(tu.cost), 0.0) AS cost_usd
+	`+periodMessages+`
+		GROUP BY key
+		ORDER BY cost_usd DESC, key ASC
+	`, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get period usage by model: %w", err)
+	}
+
+	return &usage, nil
+}
+
+// GetFirstMessageTime returns the timestamp of the earliest message, or nil
+// if nothing has been imported
+func (r *SessionRepository) GetFirstMessageTime() (*time.Time, error) {
+	var first time.Time
+	err := r.db.Get(&first, `SELECT timestamp FROM messages ORDER BY timestamp ASC LIMIT 1`)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get first message time: %w", err)
+	}
+	return &first, nil
+}
+
+// SaveMonthlySnapshot stores a closed month's snapshot. Snapshots are never
+// replaced, so closing a month twice fails.
+func (r *SessionRepository) SaveMonthlySnapshot(snapshot *MonthlySnapshot) error {
+	return r.db.WriteOperation(func(tx *sqlx.Tx) error {
+		var exists int
+		if err := tx.Get(&exists, `SELECT COUNT(*) FROM monthly_snapshots WHERE month = ?`, snapshot.Month); err != nil {
+			return err
+		}
+		if exists > 0 {
+			return fmt.Errorf("month %s is already closed", snapshot.Month)
+		}
+
+		_, err := tx.NamedExec(`
+			INSERT INTO monthly_snapshots (
+				month, period_start, period_end, sessions, messages, tokens,
+				cost_usd, currency, data, checksum, closed_at
+			) VALUES (
+				:month, :period_start, :period_end, :sessions, :messages, :tokens,
+				:cost_usd, :currency, :data, :checksum, :closed_at
+			)
+		`, snapshot)
+		return err
+	})
+}
+
+// GetMonthlySnapshot returns the snapshot taken when a month closed
+func (r *SessionRepository) GetMonthlySnapshot(month string) (*MonthlySnapshot, error) {
+	var snapshot MonthlySnapshot
+	err := r.db.Get(&snapshot, `SELECT * FROM monthly_snapshots WHERE month = ?`, month)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("monthly snapshot not found: %s", month)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get monthly snapshot: %w", err)
+	}
+	return &snapshot, nil
+}
+
+// GetMonthlySnapshots returns every closed month, most recent first
+func (r *SessionRepository) GetMonthlySnapshots() ([]MonthlySnapshot, error) {
+	snapshots := []MonthlySnapshot{}
+	err := r.db.Select(&snapshots, `SELECT * FROM monthly_snapshots ORDER BY month DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get monthly snapshots: %w", err)
+	}
+	return snapshots, nil
+}
@@ -0,0 +1,43 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// GetSessionNote returns the note attached to a session
+func (r *SessionRepository) GetSessionNote(sessionID string) (*SessionNote, error) {
+	var note SessionNote
+	err := r.db.Get(&note, `
+		SELECT session_id, content, created_at, updated_at
+		FROM session_notes WHERE session_id = ?
+	`, sessionID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("note not found: %s", sessionID)
+		}
+		return nil, fmt.Errorf("failed to get session note: %w", err)
+	}
+	return &note, nil
+}
+
+// SetSessionNote replaces the note attached to a session. Empty content
+// removes the note.
+func (r *SessionRepository) SetSessionNote(sessionID, content string) error {
+	return r.db.WriteOperation(func(tx *sqlx.Tx) error {
+		if content == "" {
+			_, err := tx.Exec(`DELETE FROM session_notes WHERE session_id = ?`, sessionID)
+			return err
+		}
+		_, err := tx.Exec(`
+			INSERT INTO session_notes (session_id, content)
+			VALUES (?, ?)
+			ON CONFLICT(session_id) DO UPDATE SET
+				content = excluded.content,
+				updated_at = CURRENT_TIMESTAMP
+		`, sessionID, content)
+		return err
+	})
+}
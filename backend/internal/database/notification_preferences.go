@@ -0,0 +1,151 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// SetNotificationPreferences replaces the named user's notification
+// preferences, filling in their user ID and update time
+func (r *SessionRepository) SetNotificationPreferences(userName string, prefs *NotificationPreferences) error {
+	prefs.UserName = userName
+	prefs.UpdatedAt = time.Now().UTC()
+	if err := prefs.encode(); err != nil {
+		return err
+	}
+
+	return r.db.WriteOperation(func(tx *sqlx.Tx) error {
+		err := tx.Get(&prefs.UserID, `SELECT id FROM users WHERE name = ?`, userName)
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("user not found: %s", userName)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get user: %w", err)
+		}
+		_, err = tx.NamedExec(`
+			INSERT INTO notification_preferences (user_id, channels, events, daily_cost_threshold, idle_minutes,
+				quiet_hours_start, quiet_hours_end, timezone, updated_at)
+			VALUES (:user_id, :channels, :events, :daily_cost_threshold, :idle_minutes,
+				:quiet_hours_start, :quiet_hours_end, :timezone, :updated_at)
+			ON CONFLICT(user_id) DO UPDATE SET
+				channels = excluded.channels,
+				events = excluded.events,
+				daily_cost_threshold = excluded.daily_cost_threshold,
+				idle_minutes = excluded.idle_minutes,
+				quiet_hours_start = excluded.quiet_hours_start,
+				quiet_hours_end = excluded.quiet_hours_end,
+				timezone = excluded.timezone,
+				updated_at = excluded.updated_at
+		`, prefs)
+		if err != nil {
+			return fmt.Errorf("failed to set notification preferences: %w", err)
+		}
+		return nil
+	})
+}
+
+// GetNotificationPreferences returns every user's notification preferences,
+// by user name
+func (r *SessionRepository) GetNotificationPreferences() ([]NotificationPreferences, error) {
+	prefs := []NotificationPreferences{}
+	err := r.db.Select(&prefs, `
+		SELECT p.*, u.name AS user_name
+		FROM notification_preferences p
+		JOIN users u ON u.id = p.user_id
+		ORDER BY u.name
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notification preferences: %w", err)
+	}
+	for i := range prefs {
+		if err := prefs[i].decode(); err != nil {
+			return nil, err
+		}
+	}
+	return prefs, nil
+}
+
+// GetUserNotificationPreferences returns the named user's notification
+// preferences, or nil if they haven't set any
+func (r *SessionRepository) GetUserNotificationPreferences(userName string) (*NotificationPreferences, error) {
+	var userID string
+	err := r.db.Get(&userID, `SELECT id FROM users WHERE name = ?`, userName)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("user not found: %s", userName)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	var prefs NotificationPreferences
+	err = r.db.Get(&prefs, `
+		SELECT p.*, u.name AS user_name
+		FROM notification_preferences p
+		JOIN users u ON u.id = p.user_id
+		WHERE p.user_id = ?
+	`, userID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notification preferences: %w", err)
+	}
+	if err := prefs.decode(); err != nil {
+		return nil, err
+	}
+	return &prefs, nil
+}
+
+// DeleteNotificationPreferences removes the named user's notification
+// preferences, so they are no longer notified
+func (r *SessionRepository) DeleteNotificationPreferences(userName string) error {
+	return r.db.WriteOperation(func(tx *sqlx.Tx) error {
+		result, err := tx.Exec(`
+			DELETE FROM notification_preferences
+			WHERE user_id = (SELECT id FROM users WHERE name = ?)
+		`, userName)
+		if err != nil {
+			return fmt.Errorf("failed to delete notification preferences: %w", err)
+		}
+		if rows, _ := result.RowsAffected(); rows == 0 {
+			return fmt.Errorf("notification preferences not found: %s", userName)
+		}
+		return nil
+	})
+}
+
+// encode fills the stored channels and events columns
+func (p *NotificationPreferences) encode() error {
+	if p.Channels == nil {
+		p.Channels = []NotificationChannel{}
+	}
+	if p.Events == nil {
+		p.Events = []string{}
+	}
+	channels, err := json.Marshal(p.Channels)
+	if err != nil {
+		return fmt.Errorf("failed to encode notification channels: %w", err)
+	}
+	events, err := json.Marshal(p.Events)
+	if err != nil {
+		return fmt.Errorf("failed to encode notification events: %w", err)
+	}
+	p.ChannelsJSON, p.EventsJSON = string(channels), string(events)
+	return nil
+}
+
+// decode fills Channels and Events from the stored columns
+func (p *NotificationPreferences) decode() error {
+	p.Channels, p.Events = []NotificationChannel{}, []string{}
+	if err := json.Unmarshal([]byte(p.ChannelsJSON), &p.Channels); err != nil {
+		return fmt.Errorf("failed to decode notification channels: %w", err)
+	}
+	if err := json.Unmarshal([]byte(p.EventsJSON), &p.Events); err != nil {
+		return fmt.Errorf("failed to decode notification events: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package database
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSessionRepository_NotificationPreferences(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewSessionRepository(db, logger)
+	if err := repo.CreateAPIKey("alice", &APIKey{Name: "laptop", Prefix: "csm_aaaa", KeyHash: "hash-a", Scope: ScopeWrite}); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+
+	prefs, err := repo.GetUserNotificationPreferences("alice")
+	if err != nil || prefs != nil {
+		t.Fatalf("Expected no preferences yet, got %+v (%v)", prefs, err)
+	}
+
+	prefs = &NotificationPreferences{
+		Channels:           []NotificationChannel{{Type: "slack"}, {Type: "email", Target: "alice@example.com"}},
+		DailyCostThreshold: 20,
+		QuietHoursStart:    "22:00",
+		QuietHoursEnd:      "07:00",
+		Timezone:           "Europe/London",
+	}
+	if err := repo.SetNotificationPreferences("alice", prefs); err != nil {
+		t.Fatalf("Failed to set preferences: %v", err)
+	}
+	if prefs.UserID == "" {
+		t.Error("Expected the user's ID filled in")
+	}
+
+	prefs.Events = []string{"daily_cost"}
+	prefs.IdleMinutes = 45
+	if err := repo.SetNotificationPreferences("alice", prefs); err != nil {
+		t.Fatalf("Failed to replace preferences: %v", err)
+	}
+
+	got, err := repo.GetUserNotificationPreferences("alice")
+	if err != nil || got == nil {
+		t.Fatalf("Expected preferences, got %+v (%v)", got, err)
+	}
+	if got.UserName != "alice" || len(got.Channels) != 2 || got.Channels[1].Target != "alice@example.com" ||
+		len(got.Events) != 1 || got.IdleMinutes != 45 || got.Timezone != "Europe/London" {
+		t.Errorf("Unexpected preferences: %+v", got)
+	}
+
+	all, err := repo.GetNotificationPreferences()
+	if err != nil || len(all) != 1 {
+		t.Fatalf("Expected one user's preferences, got %+v (%v)", all, err)
+	}
+
+	if err := repo.SetNotificationPreferences("bob", &NotificationPreferences{}); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("Expected an unknown user not found, got %v", err)
+	}
+	if _, err := repo.GetUserNotificationPreferences("bob"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("Expected an unknown user not found, got %v", err)
+	}
+
+	if err := repo.DeleteNotificationPreferences("alice"); err != nil {
+		t.Fatalf("Failed to delete preferences: %v", err)
+	}
+	if err := repo.DeleteNotificationPreferences("alice"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("Expected deleting twice not found, got %v", err)
+	}
+}
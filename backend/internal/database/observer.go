@@ -0,0 +1,68 @@
+package database
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Observer is told how long queries take, how much is imported and which file
+// changes the watcher sees, for monitoring. Its methods are called from many
+// goroutines.
+type Observer interface {
+	ObserveQuery(operation string, duration time.Duration)
+	ObserveImport(source string, sessions, messages int, duration time.Duration)
+	ObserveWatcherEvent(event string)
+}
+
+// SetObserver sets the observer queries, imports and watcher events are
+// reported to. It must be called before the database is shared.
+func (db *Database) SetObserver(observer Observer) {
+	db.observer = observer
+}
+
+// observeQuery reports a query of operation started at start
+func (db *Database) observeQuery(operation string, start time.Time) {
+	if db.observer != nil {
+		db.observer.ObserveQuery(operation, time.Since(start))
+	}
+}
+
+// observeImport reports a file imported by source, started at start
+func (db *Database) observeImport(source string, sessions, messages int, start time.Time) {
+	if db.observer != nil {
+		db.observer.ObserveImport(source, sessions, messages, time.Since(start))
+	}
+}
+
+// observeWatcherEvent reports a file change seen by the watcher
+func (db *Database) observeWatcherEvent(event string) {
+	if db.observer != nil {
+		db.observer.ObserveWatcherEvent(event)
+	}
+}
+
+// Select runs a query into dest on a read connection, timing it for the
+// observer
+func (db *Database) Select(dest interface{}, query string, args ...interface{}) error {
+	defer db.observeQuery("select", time.Now())
+	return db.reader.Select(dest, query, args...)
+}
+
+// Get runs a single row query into dest on a read connection, timing it for
+// the observer
+func (db *Database) Get(dest interface{}, query string, args ...interface{}) error {
+	defer db.observeQuery("get", time.Now())
+	return db.reader.Get(dest, query, args...)
+}
+
+// Query runs a query on a read connection, timing it for the observer
+func (db *Database) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	defer db.observeQuery("query", time.Now())
+	return db.reader.Query(query, args...)
+}
+
+// Exec runs a statement outside a transaction, timing it for the observer
+func (db *Database) Exec(query string, args ...interface{}) (sql.Result, error) {
+	defer db.observeQuery("exec", time.Now())
+	return db.DB.Exec(query, args...)
+}
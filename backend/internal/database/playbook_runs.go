@@ -0,0 +1,271 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// CreateSessionGroup creates a new group for linking related sessions
+func (r *SessionRepository) CreateSessionGroup(name, kind string) (*SessionGroup, error) {
+	group := &SessionGroup{
+		ID:        uuid.New().String(),
+		Name:      name,
+		Kind:      kind,
+		CreatedAt: time.Now(),
+	}
+
+	err := r.db.WriteOperation(func(tx *sqlx.Tx) error {
+		_, err := tx.NamedExec(`
+			INSERT INTO session_groups (id, name, kind, created_at)
+			VALUES (:id, :name, :kind, :created_at)
+		`, group)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return group, nil
+}
+
+// AddSessionToGroup links a session to a group at the given position
+func (r *SessionRepository) AddSessionToGroup(groupID, sessionID string, position int) error {
+	return r.db.WriteOperation(func(tx *sqlx.Tx) error {
+		_, err := tx.Exec(`
+			INSERT OR IGNORE INTO session_group_members (group_id, session_id, position)
+			VALUES (?, ?, ?)
+		`, groupID, sessionID, position)
+		return err
+	})
+}
+
+// GetSessionGroupSessionIDs returns the session IDs in a group in position order
+func (r *SessionRepository) GetSessionGroupSessionIDs(groupID string) ([]string, error) {
+	var ids []string
+	err := r.db.Select(&ids, `
+		SELECT session_id FROM session_group_members
+		WHERE group_id = ?
+		ORDER BY position ASC, added_at ASC
+	`, groupID)
+	return ids, err
+}
+
+// CreatePlaybookRun stores a new playbook run
+func (r *SessionRepository) CreatePlaybookRun(run *PlaybookRun) error {
+	if run.ID == "" {
+		run.ID = uuid.New().String()
+	}
+	if run.Status == "" {
+		run.Status = RunStatusPending
+	}
+	if run.Trigger == "" {
+		run.Trigger = "manual"
+	}
+	run.CreatedAt = time.Now()
+
+	return r.db.WriteOperation(func(tx *sqlx.Tx) error {
+		_, err := tx.NamedExec(`
+			INSERT INTO playbook_runs (
+				id, playbook_name, project_path, group_id, schedule_id, status, trigger_type,
+				total_cost_usd, error, diff, started_at, completed_at, created_at
+			) VALUES (
+				:id, :playbook_name, :project_path, :group_id, :schedule_id, :status, :trigger_type,
+				:total_cost_usd, :error, :diff, :started_at, :completed_at, :created_at
+			)
+		`, run)
+		return err
+	})
+}
+
+// UpdatePlaybookRun persists the mutable fields of a playbook run
+func (r *SessionRepository) UpdatePlaybookRun(run *PlaybookRun) error {
+	return r.db.WriteOperation(func(tx *sqlx.Tx) error {
+		_, err := tx.NamedExec(`
+			UPDATE playbook_runs
+			SET group_id = :group_id, status = :status, total_cost_usd = :total_cost_usd,
+				error = :error, diff = :diff, started_at = :started_at, completed_at = :completed_at
+			WHERE id = :id
+		`, run)
+		return err
+	})
+}
+
+// GetPlaybookRun returns a playbook run by ID
+func (r *SessionRepository) GetPlaybookRun(id string) (*PlaybookRun, error) {
+	var run PlaybookRun
+	err := r.db.Get(&run, `SELECT * FROM playbook_runs WHERE id = ?`, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("playbook run not found: %s", id)
+		}
+		return nil, err
+	}
+	return &run, nil
+}
+
+// GetPlaybookRuns returns the most recent playbook runs, optionally filtered by playbook name
+func (r *SessionRepository) GetPlaybookRuns(playbookName string, limit int) ([]PlaybookRun, error) {
+	var runs []PlaybookRun
+	query := `SELECT * FROM playbook_runs`
+	args := []interface{}{}
+	if playbookName != "" {
+		query += ` WHERE playbook_name = ?`
+		args = append(args, playbookName)
+	}
+	query += ` ORDER BY created_at DESC LIMIT ?`
+	args = append(args, limit)
+
+	err := r.db.Select(&runs, query, args...)
+	return runs, err
+}
+
+// GetPlaybookRunsByStatus returns playbook runs in the given status, oldest first
+func (r *SessionRepository) GetPlaybookRunsByStatus(status string) ([]PlaybookRun, error) {
+	var runs []PlaybookRun
+	err := r.db.Select(&runs, `
+		SELECT * FROM playbook_runs
+		WHERE status = ?
+		ORDER BY created_at ASC
+	`, status)
+	return runs, err
+}
+
+// CreatePlaybookRunStep stores a new step record and sets its ID
+func (r *SessionRepository) CreatePlaybookRunStep(step *PlaybookRunStep) error {
+	return r.db.WriteOperation(func(tx *sqlx.Tx) error {
+		result, err := tx.NamedExec(`
+			INSERT INTO playbook_run_steps (
+				run_id, step_index, name, prompt, session_id, claude_session_id, status,
+				response, proposal, reviewed_by, reviewed_at, failure_reason,
+				cost_usd, duration_ms, num_turns, started_at, completed_at
+			) VALUES (
+				:run_id, :step_index, :name, :prompt, :session_id, :claude_session_id, :status,
+				:response, :proposal, :reviewed_by, :reviewed_at, :failure_reason,
+				:cost_usd, :duration_ms, :num_turns, :started_at, :completed_at
+			)
+		`, step)
+		if err != nil {
+			return err
+		}
+		step.ID, err = result.LastInsertId()
+		return err
+	})
+}
+
+// UpdatePlaybookRunStep persists the mutable fields of a step record
+func (r *SessionRepository) UpdatePlaybookRunStep(step *PlaybookRunStep) error {
+	return r.db.WriteOperation(func(tx *sqlx.Tx) error {
+		_, err := tx.NamedExec(`
+			UPDATE playbook_run_steps
+			SET session_id = :session_id, claude_session_id = :claude_session_id, status = :status,
+				response = :response, proposal = :proposal, reviewed_by = :reviewed_by,
+				reviewed_at = :reviewed_at, failure_reason = :failure_reason, cost_usd = :cost_usd,
+				duration_ms = :duration_ms, num_turns = :num_turns,
+				started_at = :started_at, completed_at = :completed_at
+			WHERE id = :id
+		`, step)
+		return err
+	})
+}
+
+// GetPlaybookRunSteps returns all steps of a playbook run in order
+func (r *SessionRepository) GetPlaybookRunSteps(runID string) ([]PlaybookRunStep, error) {
+	var steps []PlaybookRunStep
+	err := r.db.Select(&steps, `
+		SELECT * FROM playbook_run_steps
+		WHERE run_id = ?
+		ORDER BY step_index ASC
+	`, runID)
+	return steps, err
+}
+
+// CreatePlaybookSchedule stores a new playbook schedule
+func (r *SessionRepository) CreatePlaybookSchedule(schedule *PlaybookSchedule) error {
+	now := time.Now()
+	schedule.ID = uuid.New().String()
+	schedule.CreatedAt = now
+	schedule.UpdatedAt = now
+
+	return r.db.WriteOperation(func(tx *sqlx.Tx) error {
+		_, err := tx.NamedExec(`
+			INSERT INTO playbook_schedules (
+				id, playbook_name, cron_expr, project_path, webhook_url, enabled,
+				last_run_id, last_run_at, next_run_at, created_at, updated_at
+			) VALUES (
+				:id, :playbook_name, :cron_expr, :project_path, :webhook_url, :enabled,
+				:last_run_id, :last_run_at, :next_run_at, :created_at, :updated_at
+			)
+		`, schedule)
+		return err
+	})
+}
+
+// UpdatePlaybookSchedule persists every field of a playbook schedule
+func (r *SessionRepository) UpdatePlaybookSchedule(schedule *PlaybookSchedule) error {
+	schedule.UpdatedAt = time.Now()
+	return r.db.WriteOperation(func(tx *sqlx.Tx) error {
+		result, err := tx.NamedExec(`
+			UPDATE playbook_schedules
+			SET playbook_name = :playbook_name, cron_expr = :cron_expr, project_path = :project_path,
+				webhook_url = :webhook_url, enabled = :enabled, last_run_id = :last_run_id,
+				last_run_at = :last_run_at, next_run_at = :next_run_at, updated_at = :updated_at
+			WHERE id = :id
+		`, schedule)
+		if err != nil {
+			return err
+		}
+		if rows, _ := result.RowsAffected(); rows == 0 {
+			return fmt.Errorf("playbook schedule not found: %s", schedule.ID)
+		}
+		return nil
+	})
+}
+
+// DeletePlaybookSchedule removes a playbook schedule
+func (r *SessionRepository) DeletePlaybookSchedule(id string) error {
+	return r.db.WriteOperation(func(tx *sqlx.Tx) error {
+		result, err := tx.Exec(`DELETE FROM playbook_schedules WHERE id = ?`, id)
+		if err != nil {
+			return err
+		}
+		if rows, _ := result.RowsAffected(); rows == 0 {
+			return fmt.Errorf("playbook schedule not found: %s", id)
+		}
+		return nil
+	})
+}
+
+// GetPlaybookSchedule returns a playbook schedule by ID
+func (r *SessionRepository) GetPlaybookSchedule(id string) (*PlaybookSchedule, error) {
+	var schedule PlaybookSchedule
+	err := r.db.Get(&schedule, `SELECT * FROM playbook_schedules WHERE id = ?`, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("playbook schedule not found: %s", id)
+		}
+		return nil, err
+	}
+	return &schedule, nil
+}
+
+// GetPlaybookSchedules returns all playbook schedules
+func (r *SessionRepository) GetPlaybookSchedules() ([]PlaybookSchedule, error) {
+	var schedules []PlaybookSchedule
+	err := r.db.Select(&schedules, `SELECT * FROM playbook_schedules ORDER BY playbook_name ASC, created_at ASC`)
+	return schedules, err
+}
+
+// GetDuePlaybookSchedules returns enabled schedules whose next run is at or before now
+func (r *SessionRepository) GetDuePlaybookSchedules(now time.Time) ([]PlaybookSchedule, error) {
+	var schedules []PlaybookSchedule
+	err := r.db.Select(&schedules, `
+		SELECT * FROM playbook_schedules
+		WHERE enabled = TRUE AND next_run_at IS NOT NULL AND next_run_at <= ?
+		ORDER BY next_run_at ASC
+	`, now)
+	return schedules, err
+}
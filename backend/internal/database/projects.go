@@ -0,0 +1,275 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ErrProjectAliasTaken is returned when a project would take an alias that
+// belongs to another project
+var ErrProjectAliasTaken = errors.New("alias belongs to another project")
+
+// ErrProjectHasSessions is returned when deleting a project sessions were
+// imported under
+var ErrProjectHasSessions = errors.New("project has sessions")
+
+// projectSelect sums each project's sessions over its aliases
+const projectSelect = `
+	SELECT
+		p.id, p.display_name, p.canonical_path, p.created_at, p.updated_at,
+		COUNT(s.id) AS sessions,
+		COALESCE(SUM(s.message_count), 0) AS messages,
+		COALESCE(SUM(tu.tokens), 0) AS total_tokens,
+		COALESCE(SUM(tu.cost), 0.0) AS total_cost,
+		COALESCE(MAX(s.last_activity), '') AS last_activity
+	FROM projects p
+	LEFT JOIN project_aliases pa ON pa.project_id = p.id
+	LEFT JOIN sessions s ON s.project_name = pa.alias AND (? OR s.archived_at IS NULL)
+	LEFT JOIN (
+		SELECT session_id, SUM(total_tokens) AS tokens, SUM(estimated_cost) AS cost
+		FROM token_usage
+		GROUP BY session_id
+	) tu ON tu.session_id = s.id
+`
+
+// GetProjects returns every project with its aliases and totals, by name
+func (r *SessionRepository) GetProjects(includeArchived bool) ([]Project, error) {
+	projects := []Project{}
+	err := r.db.Select(&projects, projectSelect+`
+		GROUP BY p.id
+		ORDER BY p.display_name COLLATE NOCASE, p.id
+	`, includeArchived)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get projects: %w", err)
+	}
+
+	var aliases []struct {
+		Alias     string `db:"alias"`
+		ProjectID int64  `db:"project_id"`
+	}
+	if err := r.db.Select(&aliases, `SELECT alias, project_id FROM project_aliases ORDER BY alias`); err != nil {
+		return nil, fmt.Errorf("failed to get project aliases: %w", err)
+	}
+	byProject := make(map[int64][]string)
+	for _, a := range aliases {
+		byProject[a.ProjectID] = append(byProject[a.ProjectID], a.Alias)
+	}
+	for i := range projects {
+		projects[i].Aliases = byProject[projects[i].ID]
+		if projects[i].Aliases == nil {
+			projects[i].Aliases = []string{}
+		}
+	}
+	return projects, nil
+}
+
+// GetProject returns a project by ID with its aliases and totals
+func (r *SessionRepository) GetProject(id int64, includeArchived bool) (*Project, error) {
+	var project Project
+	err := r.db.Get(&project, projectSelect+`
+		WHERE p.id = ?
+		GROUP BY p.id
+	`, includeArchived, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("project not found: %d", id)
+		}
+		return nil, fmt.Errorf("failed to get project: %w", err)
+	}
+
+	project.Aliases = []string{}
+	if err := r.db.Select(&project.Aliases, `SELECT alias FROM project_aliases WHERE project_id = ? ORDER BY alias`, id); err != nil {
+		return nil, fmt.Errorf("failed to get project aliases: %w", err)
+	}
+	return &project, nil
+}
+
+// CreateProject stores a new project with the given aliases, assigning its
+// ID. Sessions imported under an alias later belong to it; an alias that
+// already belongs to a project returns ErrProjectAliasTaken.
+func (r *SessionRepository) CreateProject(displayName, canonicalPath string, aliases []string) (*Project, error) {
+	var id int64
+	err := r.db.WriteOperation(func(tx *sqlx.Tx) error {
+		now := time.Now().UTC()
+		result, err := tx.Exec(`
+			INSERT INTO projects (display_name, canonical_path, created_at, updated_at)
+			VALUES (?, ?, ?, ?)
+		`, displayName, canonicalPath, now, now)
+		if err != nil {
+			return err
+		}
+		if id, err = result.LastInsertId(); err != nil {
+			return err
+		}
+		for _, alias := range normalizeAliases(aliases) {
+			var owner int64
+			err := tx.Get(&owner, `SELECT project_id FROM project_aliases WHERE alias = ?`, alias)
+			if err == nil {
+				return fmt.Errorf("%w: %s belongs to project %d", ErrProjectAliasTaken, alias, owner)
+			}
+			if err != sql.ErrNoRows {
+				return err
+			}
+			if _, err := tx.Exec(`INSERT INTO project_aliases (alias, project_id) VALUES (?, ?)`, alias, id); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create project: %w", err)
+	}
+	return r.GetProject(id, false)
+}
+
+// UpdateProject renames a project or changes its canonical path, leaving
+// nil fields as they are
+func (r *SessionRepository) UpdateProject(id int64, displayName, canonicalPath *string) (*Project, error) {
+	var updated int64
+	err := r.db.WriteOperation(func(tx *sqlx.Tx) error {
+		result, err := tx.Exec(`
+			UPDATE projects SET
+				display_name = COALESCE(?, display_name),
+				canonical_path = COALESCE(?, canonical_path),
+				updated_at = ?
+			WHERE id = ?
+		`, displayName, canonicalPath, time.Now().UTC(), id)
+		if err != nil {
+			return err
+		}
+		updated, err = result.RowsAffected()
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update project: %w", err)
+	}
+	if updated == 0 {
+		return nil, fmt.Errorf("project not found: %d", id)
+	}
+	return r.GetProject(id, false)
+}
+
+// MergeProjects moves the aliases of each source project to the target and
+// deletes the sources, so their sessions, including those imported later,
+// are reported under the target
+func (r *SessionRepository) MergeProjects(targetID int64, sourceIDs []int64) (*Project, error) {
+	err := r.db.WriteOperation(func(tx *sqlx.Tx) error {
+		for _, id := range append([]int64{targetID}, sourceIDs...) {
+			var exists bool
+			if err := tx.Get(&exists, `SELECT COUNT(*) > 0 FROM projects WHERE id = ?`, id); err != nil {
+				return err
+			}
+			if !exists {
+				return fmt.Errorf("project not found: %d", id)
+			}
+		}
+		for _, id := range sourceIDs {
+			if id == targetID {
+				continue
+			}
+			if _, err := tx.Exec(`UPDATE project_aliases SET project_id = ? WHERE project_id = ?`, targetID, id); err != nil {
+				return err
+			}
+			if _, err := tx.Exec(`DELETE FROM projects WHERE id = ?`, id); err != nil {
+				return err
+			}
+		}
+		_, err := tx.Exec(`UPDATE projects SET updated_at = ? WHERE id = ?`, time.Now().UTC(), targetID)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge projects: %w", err)
+	}
+	return r.GetProject(targetID, false)
+}
+
+// DeleteProject removes a project and its aliases. A project any session,
+// archived or not, was imported under returns ErrProjectHasSessions: merge
+// it instead.
+func (r *SessionRepository) DeleteProject(id int64) error {
+	var deleted int64
+	err := r.db.WriteOperation(func(tx *sqlx.Tx) error {
+		var sessions int
+		err := tx.Get(&sessions, `
+			SELECT COUNT(*) FROM sessions
+			WHERE project_name IN (SELECT alias FROM project_aliases WHERE project_id = ?)
+		`, id)
+		if err != nil {
+			return err
+		}
+		if sessions > 0 {
+			return fmt.Errorf("%w: %d sessions", ErrProjectHasSessions, sessions)
+		}
+		if _, err := tx.Exec(`DELETE FROM project_aliases WHERE project_id = ?`, id); err != nil {
+			return err
+		}
+		result, err := tx.Exec(`DELETE FROM projects WHERE id = ?`, id)
+		if err != nil {
+			return err
+		}
+		deleted, err = result.RowsAffected()
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete project: %w", err)
+	}
+	if deleted == 0 {
+		return fmt.Errorf("project not found: %d", id)
+	}
+	return nil
+}
+
+// createMissingProjects gives each project name sessions were imported
+// under without a project one of its own, with the path of its most recent
+// session. Triggers on sessions do the same for names imported later.
+func createMissingProjects(tx *sqlx.Tx) error {
+	var names []struct {
+		ProjectName  string `db:"project_name"`
+		ProjectPath  string `db:"project_path"`
+		LastActivity string `db:"last_activity"`
+	}
+	err := tx.Select(&names, `
+		SELECT project_name, project_path, MAX(last_activity) AS last_activity
+		FROM sessions
+		WHERE project_name NOT IN (SELECT alias FROM project_aliases)
+		GROUP BY project_name
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to get project names: %w", err)
+	}
+
+	for _, name := range names {
+		result, err := tx.Exec(`INSERT INTO projects (display_name, canonical_path) VALUES (?, ?)`, name.ProjectName, name.ProjectPath)
+		if err != nil {
+			return fmt.Errorf("failed to create project %s: %w", name.ProjectName, err)
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`INSERT INTO project_aliases (alias, project_id) VALUES (?, ?)`, name.ProjectName, id); err != nil {
+			return fmt.Errorf("failed to create project %s: %w", name.ProjectName, err)
+		}
+	}
+	return nil
+}
+
+// normalizeAliases trims aliases and drops empty and repeated ones
+func normalizeAliases(aliases []string) []string {
+	seen := make(map[string]bool, len(aliases))
+	normalized := []string{}
+	for _, alias := range aliases {
+		alias = strings.TrimSpace(alias)
+		if alias == "" || seen[alias] {
+			continue
+		}
+		seen[alias] = true
+		normalized = append(normalized, alias)
+	}
+	return normalized
+}
@@ -0,0 +1,128 @@
+package database
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+func TestSessionRepository_Projects(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewSessionRepository(db, logger)
+
+	now := time.Now().UTC()
+	sessions := []struct{ id, name, path string }{
+		{"s1", "my-app", "/work/my-app"},
+		{"s2", "my-app", "/work/my-app"},
+		{"s3", "my-app-worktree", "/work/my-app-worktree"},
+	}
+	for _, s := range sessions {
+		session := &Session{ID: s.id, ProjectPath: s.path, ProjectName: s.name, StartTime: now.Add(-time.Hour), LastActivity: now, Status: "completed", Model: "claude-sonnet-4"}
+		if err := repo.UpsertSession(session); err != nil {
+			t.Fatalf("Failed to create session: %v", err)
+		}
+		if err := repo.UpsertMessage(&Message{ID: s.id + "-msg", SessionID: s.id, Role: "assistant", Content: `"done"`, Timestamp: now}); err != nil {
+			t.Fatalf("Failed to create message: %v", err)
+		}
+		if err := repo.UpsertTokenUsage(&TokenUsage{MessageID: s.id + "-msg", SessionID: s.id, TotalTokens: 100, EstimatedCost: 1.0}); err != nil {
+			t.Fatalf("Failed to create token usage: %v", err)
+		}
+	}
+
+	projects, err := repo.GetProjects(false)
+	if err != nil {
+		t.Fatalf("Failed to get projects: %v", err)
+	}
+	if len(projects) != 2 {
+		t.Fatalf("Expected a project per project name, got %+v", projects)
+	}
+	app, worktree := projects[0], projects[1]
+	if app.DisplayName != "my-app" || app.CanonicalPath != "/work/my-app" || app.Sessions != 2 || app.TotalCost != 2.0 {
+		t.Errorf("Expected my-app with 2 sessions, got %+v", app)
+	}
+
+	renamed := "My App"
+	if _, err := repo.UpdateProject(app.ID, &renamed, nil); err != nil {
+		t.Fatalf("Failed to rename project: %v", err)
+	}
+
+	merged, err := repo.MergeProjects(app.ID, []int64{worktree.ID})
+	if err != nil {
+		t.Fatalf("Failed to merge projects: %v", err)
+	}
+	if merged.DisplayName != "My App" || merged.CanonicalPath != "/work/my-app" || merged.Sessions != 3 || merged.TotalTokens != 300 {
+		t.Errorf("Expected the renamed project with all 3 sessions, got %+v", merged)
+	}
+	if len(merged.Aliases) != 2 || merged.Aliases[0] != "my-app" || merged.Aliases[1] != "my-app-worktree" {
+		t.Errorf("Expected both aliases, got %v", merged.Aliases)
+	}
+	if _, err := repo.GetProject(worktree.ID, false); err == nil {
+		t.Error("Expected the merged project to be deleted")
+	}
+
+	// Sessions imported later under a merged alias stay with the target
+	if err := repo.UpsertSession(&Session{ID: "s4", ProjectPath: "/work/my-app-worktree", ProjectName: "my-app-worktree", StartTime: now, LastActivity: now, Status: "completed"}); err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	if projects, err := repo.GetProjects(false); err != nil || len(projects) != 1 || projects[0].Sessions != 4 {
+		t.Errorf("Expected one project with 4 sessions, got %+v (%v)", projects, err)
+	}
+
+	if _, err := repo.CreateProject("Other", "", []string{"my-app"}); !errors.Is(err, ErrProjectAliasTaken) {
+		t.Errorf("Expected a taken alias to be refused, got %v", err)
+	}
+	if err := repo.DeleteProject(app.ID); !errors.Is(err, ErrProjectHasSessions) {
+		t.Errorf("Expected a project with sessions not to be deleted, got %v", err)
+	}
+
+	planned, err := repo.CreateProject("Planned", "/work/planned", []string{"planned", " planned ", ""})
+	if err != nil {
+		t.Fatalf("Failed to create project: %v", err)
+	}
+	if len(planned.Aliases) != 1 || planned.Sessions != 0 {
+		t.Errorf("Expected one alias and no sessions, got %+v", planned)
+	}
+	if err := repo.DeleteProject(planned.ID); err != nil {
+		t.Errorf("Failed to delete project: %v", err)
+	}
+	if err := repo.DeleteProject(planned.ID); err == nil {
+		t.Error("Expected deleting a missing project to fail")
+	}
+}
+
+func TestCreateMissingProjects(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewSessionRepository(db, logger)
+
+	now := time.Now().UTC()
+	for _, id := range []string{"s1", "s2"} {
+		if err := repo.UpsertSession(&Session{ID: id, ProjectPath: "/work/" + id, ProjectName: id, StartTime: now, LastActivity: now, Status: "completed"}); err != nil {
+			t.Fatalf("Failed to create session: %v", err)
+		}
+	}
+
+	// Simulate sessions imported before projects
+	err := db.WriteOperation(func(tx *sqlx.Tx) error {
+		if err := removeProjects(tx); err != nil {
+			return err
+		}
+		return createMissingProjects(tx)
+	})
+	if err != nil {
+		t.Fatalf("Failed to create missing projects: %v", err)
+	}
+
+	projects, err := repo.GetProjects(false)
+	if err != nil {
+		t.Fatalf("Failed to get projects: %v", err)
+	}
+	if len(projects) != 2 || projects[0].DisplayName != "s1" || projects[0].CanonicalPath != "/work/s1" || projects[0].Sessions != 1 {
+		t.Errorf("Expected a project per session, got %+v", projects)
+	}
+}
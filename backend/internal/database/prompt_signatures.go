@@ -0,0 +1,82 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// openingMessageLimit bounds how many user messages are read to find a session's opening prompt
+const openingMessageLimit = 10
+
+// GetSessionsWithoutPromptSignature returns the IDs of sessions that have user
+// messages but no stored prompt signature, oldest first
+func (r *SessionRepository) GetSessionsWithoutPromptSignature(limit int) ([]string, error) {
+	var sessionIDs []string
+	err := r.db.Select(&sessionIDs, `
+		SELECT s.id
+		FROM sessions s
+		LEFT JOIN prompt_signatures ps ON ps.session_id = s.id
+		WHERE ps.session_id IS NULL
+		AND EXISTS (SELECT 1 FROM messages m WHERE m.session_id = s.id AND m.role = 'user')
+		ORDER BY s.start_time ASC
+		LIMIT ?
+	`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find sessions without prompt signatures: %w", err)
+	}
+	return sessionIDs, nil
+}
+
+// GetOpeningUserMessages returns the first main-thread user messages of a session in order
+func (r *SessionRepository) GetOpeningUserMessages(sessionID string) ([]TranscriptMessage, error) {
+	var messages []TranscriptMessage
+	err := r.db.Select(&messages, `
+		SELECT id, session_id, role, COALESCE(content, '') AS content, timestamp
+		FROM messages
+		WHERE session_id = ? AND role = 'user' AND COALESCE(is_sidechain, FALSE) = FALSE
+		ORDER BY timestamp ASC
+		LIMIT ?
+	`, sessionID, openingMessageLimit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get opening messages: %w", err)
+	}
+	return messages, nil
+}
+
+// SavePromptSignature stores the signature of a session's opening prompt
+func (r *SessionRepository) SavePromptSignature(signature *PromptSignature) error {
+	return r.db.WriteOperation(func(tx *sqlx.Tx) error {
+		_, err := tx.NamedExec(`
+			INSERT OR REPLACE INTO prompt_signatures (session_id, project_name, prompt, signature, started_at)
+			VALUES (:session_id, :project_name, :prompt, :signature, :started_at)
+		`, signature)
+		return err
+	})
+}
+
+// GetPromptSignature returns the stored prompt signature of a session
+func (r *SessionRepository) GetPromptSignature(sessionID string) (*PromptSignature, error) {
+	var signature PromptSignature
+	err := r.db.Get(&signature, `SELECT * FROM prompt_signatures WHERE session_id = ?`, sessionID)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("prompt signature not found: %s", sessionID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get prompt signature: %w", err)
+	}
+	return &signature, nil
+}
+
+// GetPromptSignatures returns every non-empty prompt signature
+func (r *SessionRepository) GetPromptSignatures() ([]PromptSignature, error) {
+	var signatures []PromptSignature
+	err := r.db.Select(&signatures, `
+		SELECT * FROM prompt_signatures WHERE signature != '' ORDER BY started_at DESC
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get prompt signatures: %w", err)
+	}
+	return signatures, nil
+}
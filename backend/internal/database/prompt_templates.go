@@ -0,0 +1,161 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// CreatePromptTemplate stores a new prompt template
+func (r *SessionRepository) CreatePromptTemplate(name, content string, description, category *string) (*PromptTemplate, error) {
+	now := time.Now()
+	template := &PromptTemplate{
+		ID:          uuid.New().String(),
+		Name:        name,
+		Description: description,
+		Content:     content,
+		Category:    category,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	err := r.db.WriteOperation(func(tx *sqlx.Tx) error {
+		_, err := tx.NamedExec(`
+			INSERT INTO prompt_templates (
+				id, name, description, content, category, usage_count, created_at, updated_at
+			) VALUES (
+				:id, :name, :description, :content, :category, 0, :created_at, :updated_at
+			)
+		`, template)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return template, nil
+}
+
+// GetPromptTemplates returns all prompt templates, optionally filtered by category
+func (r *SessionRepository) GetPromptTemplates(category string) ([]PromptTemplate, error) {
+	var templates []PromptTemplate
+	query := `SELECT * FROM prompt_templates`
+	args := []interface{}{}
+	if category != "" {
+		query += ` WHERE category = ?`
+		args = append(args, category)
+	}
+	query += ` ORDER BY usage_count DESC, name ASC`
+
+	err := r.db.Select(&templates, query, args...)
+	return templates, err
+}
+
+// GetPromptTemplateByID returns a single prompt template
+func (r *SessionRepository) GetPromptTemplateByID(id string) (*PromptTemplate, error) {
+	var template PromptTemplate
+	err := r.db.Get(&template, `SELECT * FROM prompt_templates WHERE id = ?`, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("prompt template not found: %s", id)
+		}
+		return nil, err
+	}
+	return &template, nil
+}
+
+// UpdatePromptTemplate updates the editable fields of a prompt template
+func (r *SessionRepository) UpdatePromptTemplate(template *PromptTemplate) error {
+	template.UpdatedAt = time.Now()
+	return r.db.WriteOperation(func(tx *sqlx.Tx) error {
+		result, err := tx.NamedExec(`
+			UPDATE prompt_templates
+			SET name = :name, description = :description, content = :content,
+				category = :category, updated_at = :updated_at
+			WHERE id = :id
+		`, template)
+		if err != nil {
+			return err
+		}
+		if rows, _ := result.RowsAffected(); rows == 0 {
+			return fmt.Errorf("prompt template not found: %s", template.ID)
+		}
+		return nil
+	})
+}
+
+// DeletePromptTemplate removes a prompt template and its usage history
+func (r *SessionRepository) DeletePromptTemplate(id string) error {
+	return r.db.WriteOperation(func(tx *sqlx.Tx) error {
+		if _, err := tx.Exec(`DELETE FROM prompt_template_usage WHERE template_id = ?`, id); err != nil {
+			return err
+		}
+		result, err := tx.Exec(`DELETE FROM prompt_templates WHERE id = ?`, id)
+		if err != nil {
+			return err
+		}
+		if rows, _ := result.RowsAffected(); rows == 0 {
+			return fmt.Errorf("prompt template not found: %s", id)
+		}
+		return nil
+	})
+}
+
+// RecordPromptTemplateUsage records that a template was inserted into a chat
+// and returns the updated template. sessionID may be empty.
+func (r *SessionRepository) RecordPromptTemplateUsage(id, sessionID string) (*PromptTemplate, error) {
+	var session interface{}
+	if sessionID != "" {
+		session = sessionID
+	}
+
+	err := r.db.WriteOperation(func(tx *sqlx.Tx) error {
+		now := time.Now()
+		result, err := tx.Exec(`
+			UPDATE prompt_templates
+			SET usage_count = usage_count + 1, last_used_at = ?
+			WHERE id = ?
+		`, now, id)
+		if err != nil {
+			return err
+		}
+		if rows, _ := result.RowsAffected(); rows == 0 {
+			return fmt.Errorf("prompt template not found: %s", id)
+		}
+
+		_, err = tx.Exec(`
+			INSERT INTO prompt_template_usage (template_id, session_id, used_at)
+			VALUES (?, ?, ?)
+		`, id, session, now)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return r.GetPromptTemplateByID(id)
+}
+
+// GetPromptTemplateStats returns usage statistics for every prompt template
+func (r *SessionRepository) GetPromptTemplateStats() ([]PromptTemplateStats, error) {
+	var stats []PromptTemplateStats
+	query := `
+		SELECT
+			pt.id as template_id,
+			pt.name,
+			pt.usage_count,
+			COUNT(DISTINCT ptu.session_id) as unique_sessions,
+			COUNT(CASE WHEN ptu.used_at >= datetime('now', '-7 days') THEN 1 END) as uses_last_7_days,
+			COUNT(CASE WHEN ptu.used_at >= datetime('now', '-30 days') THEN 1 END) as uses_last_30_days,
+			pt.last_used_at
+		FROM prompt_templates pt
+		LEFT JOIN prompt_template_usage ptu ON pt.id = ptu.template_id
+		GROUP BY pt.id
+		ORDER BY pt.usage_count DESC, pt.name ASC
+	`
+	err := r.db.Select(&stats, query)
+	return stats, err
+}
@@ -0,0 +1,81 @@
+package database
+
+import (
+	"testing"
+)
+
+func TestSessionRepository_PromptTemplates(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewSessionRepository(db, logger)
+
+	review, err := repo.CreatePromptTemplate("Review diff", "Review this diff for bugs", nil, stringPtr("review"))
+	if err != nil {
+		t.Fatalf("Failed to create template: %v", err)
+	}
+	if _, err := repo.CreatePromptTemplate("Write tests", "Write tests for", nil, stringPtr("testing")); err != nil {
+		t.Fatalf("Failed to create template: %v", err)
+	}
+
+	t.Run("duplicate name is rejected", func(t *testing.T) {
+		if _, err := repo.CreatePromptTemplate("Review diff", "other", nil, nil); err == nil {
+			t.Error("Expected error for duplicate template name")
+		}
+	})
+
+	t.Run("filter by category", func(t *testing.T) {
+		templates, err := repo.GetPromptTemplates("review")
+		if err != nil {
+			t.Fatalf("Failed to list templates: %v", err)
+		}
+		if len(templates) != 1 || templates[0].ID != review.ID {
+			t.Errorf("Expected only the review template, got %+v", templates)
+		}
+	})
+
+	t.Run("usage is recorded", func(t *testing.T) {
+		for _, sessionID := range []string{"session-a", "session-a", "session-b"} {
+			if _, err := repo.RecordPromptTemplateUsage(review.ID, sessionID); err != nil {
+				t.Fatalf("Failed to record usage: %v", err)
+			}
+		}
+
+		updated, err := repo.GetPromptTemplateByID(review.ID)
+		if err != nil {
+			t.Fatalf("Failed to get template: %v", err)
+		}
+		if updated.UsageCount != 3 {
+			t.Errorf("Expected usage count 3, got %d", updated.UsageCount)
+		}
+		if updated.LastUsedAt == nil {
+			t.Error("Expected last_used_at to be set")
+		}
+
+		stats, err := repo.GetPromptTemplateStats()
+		if err != nil {
+			t.Fatalf("Failed to get stats: %v", err)
+		}
+		if len(stats) != 2 {
+			t.Fatalf("Expected stats for 2 templates, got %d", len(stats))
+		}
+		if stats[0].TemplateID != review.ID || stats[0].UniqueSessions != 2 || stats[0].UsesLast7Days != 3 {
+			t.Errorf("Unexpected stats for review template: %+v", stats[0])
+		}
+	})
+
+	t.Run("unknown template", func(t *testing.T) {
+		if _, err := repo.RecordPromptTemplateUsage("missing", ""); err == nil {
+			t.Error("Expected error for unknown template")
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		if err := repo.DeletePromptTemplate(review.ID); err != nil {
+			t.Fatalf("Failed to delete template: %v", err)
+		}
+		if _, err := repo.GetPromptTemplateByID(review.ID); err == nil {
+			t.Error("Expected template to be gone")
+		}
+	})
+}
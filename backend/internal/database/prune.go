@@ -0,0 +1,234 @@
+package database
+
+import (
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// pruneSessionsQuery builds a query selecting the IDs of the sessions a
+// filter selects, including those under legal hold
+func pruneSessionsQuery(filter PruneFilter) (string, []interface{}, error) {
+	query := `
+		SELECT s.id
+		FROM sessions s
+		WHERE s.last_activity < ?
+		AND s.is_active = FALSE
+		AND (? = '' OR s.project_name = ?)
+	`
+	args := []interface{}{filter.Before.UTC(), filter.Project, filter.Project}
+	if len(filter.Tags) > 0 {
+		tagged, tagArgs, err := taggedSessionsQuery(filter.Tags)
+		if err != nil {
+			return "", nil, err
+		}
+		query += ` AND s.id IN (` + tagged + `)`
+		args = append(args, tagArgs...)
+	}
+	return query, args, nil
+}
+
+// GetPruneCandidates returns the sessions a retention run with filter would
+// prune or archive, oldest first. Sessions under legal hold are included
+// with LegalHold set; the run must skip them.
+func (r *SessionRepository) GetPruneCandidates(filter PruneFilter) ([]PruneCandidate, error) {
+	selected, args, err := pruneSessionsQuery(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := []PruneCandidate{}
+	err = r.db.Select(&candidates, r.db.Rebind(`
+		SELECT
+			s.id AS session_id,
+			s.project_name,
+			s.start_time,
+			s.last_activity,
+			(SELECT COUNT(*) FROM messages m WHERE m.session_id = s.id) AS messages,
+			(SELECT COALESCE(SUM(LENGTH(CAST(m.content AS BLOB))), 0) FROM messages m WHERE m.session_id = s.id)
+				+ (SELECT COALESCE(SUM(LENGTH(CAST(tr.result_data AS BLOB))), 0) FROM tool_results tr WHERE tr.session_id = s.id) AS size_bytes,
+			(SELECT COALESCE(SUM(tu.total_tokens), 0) FROM token_usage tu WHERE tu.session_id = s.id) AS tokens,
+			(SELECT COALESCE(SUM(tu.estimated_cost), 0.0) FROM token_usage tu WHERE tu.session_id = s.id) AS cost_usd,
+			EXISTS(SELECT 1 FROM legal_holds h WHERE h.session_id = s.id AND h.released_at IS NULL) AS legal_hold
+		FROM sessions s
+		WHERE s.id IN (`+selected+`)
+		ORDER BY s.last_activity ASC
+	`), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get prune candidates: %w", err)
+	}
+	return candidates, nil
+}
+
+// getPruneCostHistory returns the cost of the messages a retention run with
+// filter would remove by the month they were sent, skipping sessions under
+// legal hold
+func (r *SessionRepository) getPruneCostHistory(filter PruneFilter) ([]PruneCostMonth, error) {
+	selected, args, err := pruneSessionsQuery(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	history := []PruneCostMonth{}
+	err = r.db.Select(&history, r.db.Rebind(`
+		SELECT
+			strftime('%Y-%m', m.timestamp) AS month,
+			COUNT(DISTINCT m.id) AS messages,
+			COALESCE(SUM(tu.total_tokens), 0) AS tokens,
+			COALESCE(SUM(tu.estimated_cost), 0.0) AS cost_usd
+		FROM messages m
+		LEFT JOIN token_usage tu ON tu.message_id = m.id
+		WHERE m.session_id IN (`+selected+`)
+		AND m.session_id NOT IN (SELECT session_id FROM legal_holds WHERE released_at IS NULL)
+		GROUP BY month
+		ORDER BY month ASC
+	`), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get prune cost history: %w", err)
+	}
+	return history, nil
+}
+
+// PreviewPrune summarizes what a retention run with filter would prune or
+// archive without changing anything
+func (r *SessionRepository) PreviewPrune(filter PruneFilter) (*PrunePreview, error) {
+	candidates, err := r.GetPruneCandidates(filter)
+	if err != nil {
+		return nil, err
+	}
+	history, err := r.getPruneCostHistory(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	preview := &PrunePreview{
+		Filter:      filter,
+		Sessions:    []PruneCandidate{},
+		Held:        []PruneCandidate{},
+		CostHistory: history,
+	}
+	for _, candidate := range candidates {
+		if candidate.LegalHold {
+			preview.Held = append(preview.Held, candidate)
+			continue
+		}
+		preview.Sessions = append(preview.Sessions, candidate)
+		preview.Messages += candidate.Messages
+		preview.SizeBytes += candidate.SizeBytes
+		preview.Tokens += candidate.Tokens
+		preview.CostUSD += candidate.CostUSD
+	}
+	return preview, nil
+}
+
+// deleteBatchSize limits how many sessions are deleted per statement, keeping
+// under SQLite's limit on query parameters
+const deleteBatchSize = 500
+
+// DeleteSession removes a session with its messages, token usage, tool
+// results, activity log entries and per-session data in one transaction.
+// Sessions under legal hold are refused with ErrLegalHold. Integrity hashes,
+// hook events and todos are kept, as fsck keeps them.
+func (r *SessionRepository) DeleteSession(sessionID string) error {
+	return r.db.WriteOperation(func(tx *sqlx.Tx) error {
+		deleted, err := deleteSessions(tx, []string{sessionID}, "")
+		if err != nil {
+			return fmt.Errorf("failed to delete session: %w", err)
+		}
+		if len(deleted) > 0 {
+			return nil
+		}
+		if err := checkNotOnLegalHold(tx, sessionID); err != nil {
+			return err
+		}
+		return fmt.Errorf("session not found: %s", sessionID)
+	})
+}
+
+// Prune deletes the sessions filter selects, skipping those under legal
+// hold, and returns what was deleted in the form of PreviewPrune. A session
+// that was held or became active again after the preview is skipped too,
+// and left out of the totals; CostHistory is as previewed.
+func (r *SessionRepository) Prune(filter PruneFilter) (*PrunePreview, error) {
+	preview, err := r.PreviewPrune(filter)
+	if err != nil {
+		return nil, err
+	}
+	if len(preview.Sessions) == 0 {
+		return preview, nil
+	}
+
+	sessionIDs := make([]string, len(preview.Sessions))
+	for i, candidate := range preview.Sessions {
+		sessionIDs[i] = candidate.SessionID
+	}
+	deleted := make(map[string]bool, len(sessionIDs))
+	err = r.db.WriteOperation(func(tx *sqlx.Tx) error {
+		for start := 0; start < len(sessionIDs); start += deleteBatchSize {
+			end := min(start+deleteBatchSize, len(sessionIDs))
+			ids, err := deleteSessions(tx, sessionIDs[start:end], `AND last_activity < ? AND is_active = FALSE`, filter.Before.UTC())
+			if err != nil {
+				return err
+			}
+			for _, id := range ids {
+				deleted[id] = true
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to prune sessions: %w", err)
+	}
+
+	pruned := preview.Sessions[:0]
+	for _, candidate := range preview.Sessions {
+		if deleted[candidate.SessionID] {
+			pruned = append(pruned, candidate)
+			continue
+		}
+		preview.Messages -= candidate.Messages
+		preview.SizeBytes -= candidate.SizeBytes
+		preview.Tokens -= candidate.Tokens
+		preview.CostUSD -= candidate.CostUSD
+	}
+	preview.Sessions = pruned
+	return preview, nil
+}
+
+// deleteSessions deletes those of sessionIDs that aren't under legal hold
+// and also match condition, a clause on sessions taking args, along with
+// everything stored for them. Children go before parents so it doesn't rely
+// on foreign keys being on. It returns the IDs of the sessions deleted.
+func deleteSessions(tx *sqlx.Tx, sessionIDs []string, condition string, args ...interface{}) ([]string, error) {
+	selected := `SELECT id FROM sessions WHERE id IN (?) AND id ` + notHeld + ` ` + condition
+	statements := []string{
+		`DELETE FROM tool_results WHERE session_id IN (` + selected + `)`,
+		`DELETE FROM token_usage WHERE session_id IN (` + selected + `)`,
+		`DELETE FROM messages WHERE session_id IN (` + selected + `)`,
+		`DELETE FROM activity_log WHERE session_id IN (` + selected + `)`,
+		`DELETE FROM session_links WHERE parent_session_id IN (` + selected + `)`,
+	}
+	for _, table := range perSessionTables {
+		statements = append(statements, `DELETE FROM `+table+` WHERE session_id IN (`+selected+`)`)
+	}
+	inArgs := append([]interface{}{sessionIDs}, args...)
+	for _, statement := range statements {
+		query, queryArgs, err := sqlx.In(statement, inArgs...)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := tx.Exec(tx.Rebind(query), queryArgs...); err != nil {
+			return nil, fmt.Errorf("failed to run %q: %w", statement, err)
+		}
+	}
+
+	query, queryArgs, err := sqlx.In(`DELETE FROM sessions WHERE id IN (?) AND id `+notHeld+` `+condition+` RETURNING id`, inArgs...)
+	if err != nil {
+		return nil, err
+	}
+	deleted := []string{}
+	if err := tx.Select(&deleted, tx.Rebind(query), queryArgs...); err != nil {
+		return nil, fmt.Errorf("failed to delete sessions: %w", err)
+	}
+	return deleted, nil
+}
@@ -0,0 +1,159 @@
+package database
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+func TestSessionRepository_PreviewPrune(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewSessionRepository(db, logger)
+
+	now := time.Now().UTC()
+	old := time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC)
+	for _, s := range []struct {
+		id, project  string
+		lastActivity time.Time
+		active       bool
+	}{
+		{"old-app", "app", old, false},
+		{"old-held", "app", old, false},
+		{"old-other", "other", old, false},
+		{"recent", "app", now, false},
+		{"old-active", "app", old, true},
+	} {
+		if err := repo.UpsertSession(&Session{ID: s.id, ProjectPath: "/work/" + s.project, ProjectName: s.project, StartTime: s.lastActivity.Add(-time.Hour), LastActivity: s.lastActivity, IsActive: s.active, Status: "completed"}); err != nil {
+			t.Fatalf("Failed to create session: %v", err)
+		}
+		for i, sent := range []time.Time{s.lastActivity.AddDate(0, -1, 0), s.lastActivity} {
+			id := s.id + "-" + string(rune('a'+i))
+			if err := repo.UpsertMessage(&Message{ID: id, SessionID: s.id, Role: "assistant", Content: `"done"`, Timestamp: sent}); err != nil {
+				t.Fatalf("Failed to create message: %v", err)
+			}
+			if err := repo.UpsertTokenUsage(&TokenUsage{MessageID: id, SessionID: s.id, TotalTokens: 100, EstimatedCost: 0.5}); err != nil {
+				t.Fatalf("Failed to create token usage: %v", err)
+			}
+		}
+	}
+	if _, err := repo.PlaceLegalHold("old-held", "Litigation", "legal@example.com"); err != nil {
+		t.Fatalf("Failed to place legal hold: %v", err)
+	}
+
+	preview, err := repo.PreviewPrune(PruneFilter{Before: now.AddDate(0, 0, -30), Project: "app"})
+	if err != nil {
+		t.Fatalf("Failed to preview prune: %v", err)
+	}
+	if len(preview.Sessions) != 1 || preview.Sessions[0].SessionID != "old-app" {
+		t.Fatalf("Expected only the old inactive app session, got %+v", preview.Sessions)
+	}
+	if len(preview.Held) != 1 || preview.Held[0].SessionID != "old-held" {
+		t.Errorf("Expected the held session to be listed separately, got %+v", preview.Held)
+	}
+	if preview.Messages != 2 || preview.Tokens != 200 || preview.CostUSD != 1.0 || preview.SizeBytes != 12 {
+		t.Errorf("Expected totals for the old app session only, got %+v", preview)
+	}
+	if len(preview.CostHistory) != 2 || preview.CostHistory[0].Month != "2023-12" || preview.CostHistory[1].CostUSD != 0.5 {
+		t.Errorf("Expected cost by month, got %+v", preview.CostHistory)
+	}
+
+	// Tags narrow the same selection
+	if err := repo.AddSessionTags("old-other", []string{"experiment"}); err != nil {
+		t.Fatalf("Failed to tag session: %v", err)
+	}
+	preview, err = repo.PreviewPrune(PruneFilter{Before: now.AddDate(0, 0, -30), Tags: []string{"experiment"}})
+	if err != nil {
+		t.Fatalf("Failed to preview prune: %v", err)
+	}
+	if len(preview.Sessions) != 1 || preview.Sessions[0].SessionID != "old-other" {
+		t.Errorf("Expected only the tagged session, got %+v", preview.Sessions)
+	}
+}
+
+func TestSessionRepository_DeleteSession(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewSessionRepository(db, logger)
+
+	old := time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC)
+	for _, id := range []string{"doomed", "held", "old"} {
+		if err := repo.UpsertSession(&Session{ID: id, ProjectPath: "/work/app", ProjectName: "app", StartTime: old.Add(-time.Hour), LastActivity: old, Status: "completed"}); err != nil {
+			t.Fatalf("Failed to create session: %v", err)
+		}
+		if err := repo.UpsertMessage(&Message{ID: id + "-msg", SessionID: id, Role: "assistant", Content: `"done"`, Timestamp: old}); err != nil {
+			t.Fatalf("Failed to create message: %v", err)
+		}
+		if err := repo.UpsertTokenUsage(&TokenUsage{MessageID: id + "-msg", SessionID: id, TotalTokens: 100}); err != nil {
+			t.Fatalf("Failed to create token usage: %v", err)
+		}
+		sessionID := id
+		if err := repo.LogActivity(&ActivityLogEntry{SessionID: &sessionID, ActivityType: "session_created", Timestamp: old}); err != nil {
+			t.Fatalf("Failed to log activity: %v", err)
+		}
+	}
+	if _, err := repo.PlaceLegalHold("held", "Litigation", "legal@example.com"); err != nil {
+		t.Fatalf("Failed to place legal hold: %v", err)
+	}
+
+	if err := repo.DeleteSession("doomed"); err != nil {
+		t.Fatalf("Failed to delete session: %v", err)
+	}
+	for _, table := range []string{"sessions", "messages", "token_usage", "activity_log"} {
+		column := "session_id"
+		if table == "sessions" {
+			column = "id"
+		}
+		var count int
+		if err := db.Get(&count, `SELECT COUNT(*) FROM `+table+` WHERE `+column+` = 'doomed'`); err != nil {
+			t.Fatalf("Failed to count %s: %v", table, err)
+		}
+		if count != 0 {
+			t.Errorf("Expected no %s rows left for the deleted session, got %d", table, count)
+		}
+	}
+
+	if err := repo.DeleteSession("held"); !errors.Is(err, ErrLegalHold) {
+		t.Errorf("Expected a held session to be refused, got %v", err)
+	}
+	if err := repo.DeleteSession("missing"); err == nil {
+		t.Error("Expected deleting an unknown session to fail")
+	}
+
+	// The deletes themselves skip held sessions and those the condition
+	// leaves out, whatever was checked before
+	err := db.WriteOperation(func(tx *sqlx.Tx) error {
+		deleted, err := deleteSessions(tx, []string{"held", "old"}, `AND last_activity > ?`, old)
+		if err == nil && len(deleted) != 0 {
+			t.Errorf("Expected nothing deleted, got %v", deleted)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Failed to delete sessions: %v", err)
+	}
+	var messages int
+	if err := db.Get(&messages, `SELECT COUNT(*) FROM messages WHERE session_id IN ('held', 'old')`); err != nil || messages != 2 {
+		t.Errorf("Expected the messages kept, got %d (%v)", messages, err)
+	}
+
+	// Prune removes what the preview selects and keeps held sessions
+	pruned, err := repo.Prune(PruneFilter{Before: old.AddDate(0, 0, 1)})
+	if err != nil {
+		t.Fatalf("Failed to prune: %v", err)
+	}
+	if len(pruned.Sessions) != 1 || pruned.Sessions[0].SessionID != "old" || len(pruned.Held) != 1 {
+		t.Errorf("Expected only the unheld session to be pruned, got %+v", pruned)
+	}
+	var remaining []string
+	if err := db.Select(&remaining, `SELECT id FROM sessions`); err != nil {
+		t.Fatalf("Failed to list sessions: %v", err)
+	}
+	if len(remaining) != 1 || remaining[0] != "held" {
+		t.Errorf("Expected only the held session to remain, got %v", remaining)
+	}
+}
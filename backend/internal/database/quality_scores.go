@@ -0,0 +1,216 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Weights of each signal in the composite quality score. When a session has
+// no feedback the remaining weights are scaled up to fill its share.
+const (
+	qualityWeightEfficiency    = 0.4
+	qualityWeightInterruptions = 0.2
+	qualityWeightErrors        = 0.2
+	qualityWeightFeedback      = 0.2
+)
+
+// efficiencyTokenUnit is the token spend one changed file is measured against;
+// one file per unit gives an efficiency of 0.5
+const efficiencyTokenUnit = 100000
+
+// scoreBatchSize limits how many sessions are scored per query
+const scoreBatchSize = 200
+
+// computeQualityScore fills in the efficiency and composite score of a session from its signals
+func computeQualityScore(score *SessionQualityScore) {
+	// Efficiency saturates towards 1 as more files change per token spent
+	switch {
+	case score.TokensSpent > 0:
+		filesPerUnit := float64(score.FilesChanged) * efficiencyTokenUnit / float64(score.TokensSpent)
+		score.Efficiency = filesPerUnit / (filesPerUnit + 1)
+	case score.FilesChanged > 0:
+		score.Efficiency = 1
+	default:
+		score.Efficiency = 0
+	}
+
+	interruptions := 1 / (1 + float64(score.Interruptions))
+	errors := 1.0
+	if score.ToolResults > 0 {
+		errors = 1 - math.Min(1, float64(score.ToolErrors)/float64(score.ToolResults))
+	}
+
+	total := qualityWeightEfficiency*score.Efficiency +
+		qualityWeightInterruptions*interruptions +
+		qualityWeightErrors*errors
+	weights := qualityWeightEfficiency + qualityWeightInterruptions + qualityWeightErrors
+	if score.FeedbackRating != nil {
+		total += qualityWeightFeedback * float64(*score.FeedbackRating-1) / 4
+		weights += qualityWeightFeedback
+	}
+
+	score.Score = math.Round(1000*total/weights) / 10
+	score.Efficiency = math.Round(1000*score.Efficiency) / 1000
+}
+
+// RefreshSessionQualityScores recomputes the score of every session whose
+// messages or feedback changed since it was last scored and returns how many
+// sessions were scored
+func (r *SessionRepository) RefreshSessionQualityScores() (int, error) {
+	var sessionIDs []string
+	err := r.db.Select(&sessionIDs, `
+		SELECT s.id
+		FROM sessions s
+		LEFT JOIN session_scores ss ON ss.session_id = s.id
+		LEFT JOIN session_feedback f ON f.session_id = s.id
+		WHERE s.message_count > 0
+		AND (
+			ss.session_id IS NULL
+			OR ss.message_count != s.message_count
+			OR f.updated_at > ss.computed_at
+			OR (f.session_id IS NULL AND ss.feedback_rating IS NOT NULL)
+		)
+	`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to find sessions to score: %w", err)
+	}
+
+	for start := 0; start < len(sessionIDs); start += scoreBatchSize {
+		end := start + scoreBatchSize
+		if end > len(sessionIDs) {
+			end = len(sessionIDs)
+		}
+		if err := r.scoreSessions(sessionIDs[start:end]); err != nil {
+			return start, err
+		}
+	}
+
+	return len(sessionIDs), nil
+}
+
+// scoreSessions computes and stores the scores of the given sessions
+func (r *SessionRepository) scoreSessions(sessionIDs []string) error {
+	query, args, err := sqlx.In(`
+		SELECT
+			s.id AS session_id,
+			s.message_count,
+			COALESCE((
+				SELECT SUM(input_tokens + output_tokens + cache_creation_input_tokens)
+				FROM token_usage WHERE session_id = s.id
+			), 0) AS tokens_spent,
+			(
+				SELECT COUNT(DISTINCT file_path) FROM tool_results
+				WHERE session_id = s.id AND file_path IS NOT NULL
+				AND tool_name IN ('Edit', 'Write', 'MultiEdit', 'NotebookEdit', 'NotebookWrite')
+			) AS files_changed,
+			(
+				SELECT COUNT(*) FROM messages
+				WHERE session_id = s.id AND role = 'user' AND content LIKE '%[Request interrupted%'
+			) AS interruptions,
+			(
+				SELECT COUNT(*) FROM messages
+				WHERE session_id = s.id AND content LIKE '%"tool_result"%'
+			) AS tool_results,
+			(
+				SELECT COUNT(*) FROM messages
+				WHERE session_id = s.id AND content LIKE '%"is_error":true%'
+			) AS tool_errors,
+			f.rating AS feedback_rating
+		FROM sessions s
+		LEFT JOIN session_feedback f ON f.session_id = s.id
+		WHERE s.id IN (?)
+	`, sessionIDs)
+	if err != nil {
+		return err
+	}
+
+	var scores []SessionQualityScore
+	if err := r.db.Select(&scores, r.db.Rebind(query), args...); err != nil {
+		return fmt.Errorf("failed to get session quality signals: %w", err)
+	}
+
+	now := time.Now().UTC()
+	for i := range scores {
+		computeQualityScore(&scores[i])
+		scores[i].ComputedAt = now
+	}
+
+	return r.db.WriteOperation(func(tx *sqlx.Tx) error {
+		for i := range scores {
+			_, err := tx.NamedExec(`
+				INSERT OR REPLACE INTO session_scores (
+					session_id, score, efficiency, files_changed, tokens_spent, interruptions,
+					tool_results, tool_errors, feedback_rating, message_count, computed_at
+				) VALUES (
+					:session_id, :score, :efficiency, :files_changed, :tokens_spent, :interruptions,
+					:tool_results, :tool_errors, :feedback_rating, :message_count, :computed_at
+				)
+			`, &scores[i])
+			if err != nil {
+				return fmt.Errorf("failed to store score for session %s: %w", scores[i].SessionID, err)
+			}
+		}
+		return nil
+	})
+}
+
+// GetSessionQualityScores returns every stored session score keyed by session ID
+func (r *SessionRepository) GetSessionQualityScores() (map[string]*SessionQualityScore, error) {
+	var scores []*SessionQualityScore
+	if err := r.db.Select(&scores, `SELECT * FROM session_scores`); err != nil {
+		return nil, fmt.Errorf("failed to get session scores: %w", err)
+	}
+
+	byID := make(map[string]*SessionQualityScore, len(scores))
+	for _, score := range scores {
+		byID[score.SessionID] = score
+	}
+	return byID, nil
+}
+
+// GetSessionQualityScore returns the stored score of a session
+func (r *SessionRepository) GetSessionQualityScore(sessionID string) (*SessionQualityScore, error) {
+	var score SessionQualityScore
+	err := r.db.Get(&score, `SELECT * FROM session_scores WHERE session_id = ?`, sessionID)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("session score not found: %s", sessionID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session score: %w", err)
+	}
+	return &score, nil
+}
+
+// SetSessionFeedback records a 1-5 rating of a session and rescores it
+func (r *SessionRepository) SetSessionFeedback(sessionID string, rating int, note *string) (*SessionQualityScore, error) {
+	if rating < 1 || rating > 5 {
+		return nil, fmt.Errorf("rating must be between 1 and 5")
+	}
+	if _, err := r.GetSessionByID(sessionID); err != nil {
+		return nil, fmt.Errorf("session not found: %s", sessionID)
+	}
+
+	err := r.db.WriteOperation(func(tx *sqlx.Tx) error {
+		_, err := tx.Exec(`
+			INSERT INTO session_feedback (session_id, rating, note)
+			VALUES (?, ?, ?)
+			ON CONFLICT(session_id) DO UPDATE SET
+				rating = excluded.rating,
+				note = excluded.note,
+				updated_at = CURRENT_TIMESTAMP
+		`, sessionID, rating, note)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to save session feedback: %w", err)
+	}
+
+	if err := r.scoreSessions([]string{sessionID}); err != nil {
+		return nil, err
+	}
+	return r.GetSessionQualityScore(sessionID)
+}
@@ -0,0 +1,130 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestComputeQualityScore(t *testing.T) {
+	rating := func(r int) *int { return &r }
+
+	tests := []struct {
+		name  string
+		input SessionQualityScore
+		want  float64
+	}{
+		{"nothing happened", SessionQualityScore{}, 50},
+		{"one file per 100k tokens", SessionQualityScore{FilesChanged: 1, TokensSpent: 100000}, 75},
+		{"interrupted twice", SessionQualityScore{FilesChanged: 1, TokensSpent: 100000, Interruptions: 2}, 58.3},
+		{"half the tools failed", SessionQualityScore{FilesChanged: 1, TokensSpent: 100000, ToolResults: 4, ToolErrors: 2}, 62.5},
+		{"rated 5", SessionQualityScore{FilesChanged: 1, TokensSpent: 100000, FeedbackRating: rating(5)}, 80},
+		{"rated 1", SessionQualityScore{FilesChanged: 1, TokensSpent: 100000, FeedbackRating: rating(1)}, 60},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			score := tt.input
+			computeQualityScore(&score)
+			if score.Score != tt.want {
+				t.Errorf("Score = %v, want %v", score.Score, tt.want)
+			}
+		})
+	}
+}
+
+func TestSessionRepository_QualityScores(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewSessionRepository(db, logger)
+
+	now := time.Now()
+	for _, id := range []string{"good", "bad"} {
+		if err := repo.UpsertSession(&Session{
+			ID:           id,
+			ProjectPath:  "/test/project",
+			ProjectName:  "project",
+			StartTime:    now.Add(-time.Hour),
+			LastActivity: now,
+			Status:       "completed",
+			MessageCount: 2,
+		}); err != nil {
+			t.Fatalf("Failed to create session: %v", err)
+		}
+	}
+
+	messages := []*Message{
+		{ID: "good-1", SessionID: "good", Role: "assistant", Content: `[{"type":"tool_use","name":"Edit"}]`},
+		{ID: "good-2", SessionID: "good", Role: "user", Content: `[{"type":"tool_result","content":"ok"}]`},
+		{ID: "bad-1", SessionID: "bad", Role: "user", Content: `[{"type":"tool_result","is_error":true}]`},
+		{ID: "bad-2", SessionID: "bad", Role: "user", Content: `[Request interrupted by user]`},
+	}
+	for _, message := range messages {
+		message.Timestamp = now
+		if err := repo.UpsertMessage(message); err != nil {
+			t.Fatalf("Failed to create message: %v", err)
+		}
+	}
+	if err := repo.UpsertToolResult(&ToolResult{
+		MessageID: "good-1",
+		SessionID: "good",
+		ToolName:  "Edit",
+		FilePath:  stringPtr("/test/project/main.go"),
+		Timestamp: now,
+	}); err != nil {
+		t.Fatalf("Failed to create tool result: %v", err)
+	}
+	for _, usage := range []*TokenUsage{
+		{MessageID: "good-1", SessionID: "good", InputTokens: 10000, OutputTokens: 10000},
+		{MessageID: "bad-1", SessionID: "bad", InputTokens: 10000, OutputTokens: 10000},
+	} {
+		if err := repo.UpsertTokenUsage(usage); err != nil {
+			t.Fatalf("Failed to create token usage: %v", err)
+		}
+	}
+
+	scored, err := repo.RefreshSessionQualityScores()
+	if err != nil {
+		t.Fatalf("Failed to refresh scores: %v", err)
+	}
+	if scored != 2 {
+		t.Errorf("Expected 2 sessions scored, got %d", scored)
+	}
+
+	// Unchanged sessions are not rescored
+	if scored, _ := repo.RefreshSessionQualityScores(); scored != 0 {
+		t.Errorf("Expected no sessions to be rescored, got %d", scored)
+	}
+
+	scores, err := repo.GetSessionQualityScores()
+	if err != nil {
+		t.Fatalf("Failed to get scores: %v", err)
+	}
+	good, bad := scores["good"], scores["bad"]
+	if good == nil || bad == nil {
+		t.Fatalf("Expected both sessions to be scored, got %v", scores)
+	}
+	if good.FilesChanged != 1 || bad.Interruptions != 1 || bad.ToolErrors != 1 {
+		t.Errorf("Unexpected signals: good %+v, bad %+v", good, bad)
+	}
+	if good.Score <= bad.Score {
+		t.Errorf("Expected good session (%v) to outscore bad session (%v)", good.Score, bad.Score)
+	}
+
+	t.Run("feedback rescores the session", func(t *testing.T) {
+		updated, err := repo.SetSessionFeedback("bad", 5, nil)
+		if err != nil {
+			t.Fatalf("Failed to set feedback: %v", err)
+		}
+		if updated.FeedbackRating == nil || *updated.FeedbackRating != 5 || updated.Score <= bad.Score {
+			t.Errorf("Expected rating to raise the score, got %+v", updated)
+		}
+
+		if _, err := repo.SetSessionFeedback("bad", 6, nil); err == nil {
+			t.Error("Expected error for out of range rating")
+		}
+		if _, err := repo.SetSessionFeedback("missing", 3, nil); err == nil {
+			t.Error("Expected error for unknown session")
+		}
+	})
+}
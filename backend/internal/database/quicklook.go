@@ -0,0 +1,61 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+)
+
+// QuickLookSessions returns the most recently active sessions matching every
+// word of query in their project name, path, branch, opening prompt or tags,
+// or whose ID starts with the word. It reads session metadata rather than
+// message content so launchers get results in well under 100ms. An empty
+// query returns the most recent sessions.
+func (r *SessionRepository) QuickLookSessions(query string, limit int) ([]QuickLookSession, error) {
+	var conditions []string
+	var args []interface{}
+	for _, word := range strings.Fields(strings.ToLower(query)) {
+		pattern := "%" + escapeLike(word) + "%"
+		conditions = append(conditions, `(
+			LOWER(s.project_name) LIKE ? ESCAPE '\'
+			OR LOWER(s.project_path) LIKE ? ESCAPE '\'
+			OR LOWER(COALESCE(s.git_branch, '')) LIKE ? ESCAPE '\'
+			OR LOWER(COALESCE(p.prompt, '')) LIKE ? ESCAPE '\'
+			OR s.id LIKE ? ESCAPE '\'
+			OR EXISTS (SELECT 1 FROM session_tags t WHERE t.session_id = s.id AND LOWER(t.tag) = ?)
+		)`)
+		args = append(args, pattern, pattern, pattern, pattern, escapeLike(word)+"%", word)
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	sessions := []QuickLookSession{}
+	err := r.db.Select(&sessions, `
+		SELECT
+			s.id,
+			s.project_name,
+			s.project_path,
+			s.git_branch,
+			s.last_activity,
+			s.is_active,
+			s.message_count,
+			COALESCE(p.prompt, '') AS prompt,
+			(SELECT COALESCE(SUM(tu.estimated_cost), 0.0) FROM token_usage tu WHERE tu.session_id = s.id) AS cost_usd
+		FROM sessions s
+		LEFT JOIN prompt_signatures p ON p.session_id = s.id
+		`+where+`
+		ORDER BY s.last_activity DESC
+		LIMIT ?
+	`, append(args, limit)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search sessions: %w", err)
+	}
+	return sessions, nil
+}
+
+// escapeLike escapes LIKE wildcards so s matches literally with ESCAPE '\'
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
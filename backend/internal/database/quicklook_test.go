@@ -0,0 +1,92 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSessionRepository_QuickLookSessions(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewSessionRepository(db, logger)
+
+	now := time.Now()
+	for i, s := range []struct {
+		id, project, branch, prompt string
+	}{
+		{"aaa111", "payments", "", "Add retries to the webhook handler"},
+		{"bbb222", "payments", "fix/login_flow", "Fix the flaky login test"},
+		{"ccc333", "web", "", "Speed up the 100% CPU build"},
+	} {
+		if err := repo.UpsertSession(&Session{
+			ID:           s.id,
+			ProjectPath:  "/work/" + s.project,
+			ProjectName:  s.project,
+			GitBranch:    s.branch,
+			StartTime:    now,
+			LastActivity: now.Add(time.Duration(i) * time.Minute),
+			Status:       "completed",
+		}); err != nil {
+			t.Fatalf("Failed to create session: %v", err)
+		}
+		if err := repo.SavePromptSignature(&PromptSignature{SessionID: s.id, Prompt: s.prompt, StartedAt: now}); err != nil {
+			t.Fatalf("Failed to save prompt: %v", err)
+		}
+	}
+	if err := repo.UpsertMessage(&Message{ID: "m1", SessionID: "aaa111", Role: "assistant", Timestamp: now}); err != nil {
+		t.Fatalf("Failed to create message: %v", err)
+	}
+	if err := repo.UpsertTokenUsage(&TokenUsage{MessageID: "m1", SessionID: "aaa111", TotalTokens: 100, EstimatedCost: 0.25}); err != nil {
+		t.Fatalf("Failed to create token usage: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO session_tags (session_id, tag) VALUES ('ccc333', 'Perf')`); err != nil {
+		t.Fatalf("Failed to tag session: %v", err)
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"", []string{"ccc333", "bbb222", "aaa111"}},
+		{"payments", []string{"bbb222", "aaa111"}},
+		{"PAYMENTS webhook", []string{"aaa111"}},
+		{"login_flow", []string{"bbb222"}},
+		{"login%flow", nil},
+		{"100%", []string{"ccc333"}},
+		{"perf", []string{"ccc333"}},
+		{"bbb", []string{"bbb222"}},
+		{"b222", nil},
+	}
+	for _, tt := range tests {
+		sessions, err := repo.QuickLookSessions(tt.query, 5)
+		if err != nil {
+			t.Fatalf("QuickLookSessions(%q) failed: %v", tt.query, err)
+		}
+		var got []string
+		for _, s := range sessions {
+			got = append(got, s.ID)
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("QuickLookSessions(%q) = %v, want %v", tt.query, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("QuickLookSessions(%q) = %v, want %v", tt.query, got, tt.want)
+				break
+			}
+		}
+	}
+
+	sessions, err := repo.QuickLookSessions("webhook", 5)
+	if err != nil || len(sessions) != 1 {
+		t.Fatalf("Expected one result, got %+v (%v)", sessions, err)
+	}
+	if sessions[0].Prompt != "Add retries to the webhook handler" || sessions[0].CostUSD != 0.25 {
+		t.Errorf("Unexpected result: %+v", sessions[0])
+	}
+	if limited, _ := repo.QuickLookSessions("", 1); len(limited) != 1 {
+		t.Errorf("Expected the limit to apply, got %d results", len(limited))
+	}
+}
@@ -0,0 +1,218 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// SetUserQuota replaces the named user's quota and the projects whose
+// sessions count against them. A project can only belong to one user.
+func (r *SessionRepository) SetUserQuota(userName string, quota *UserQuota) error {
+	now := time.Now().UTC()
+	quota.UserName = userName
+	quota.UpdatedAt = &now
+	if quota.Projects == nil {
+		quota.Projects = []string{}
+	}
+
+	return r.db.WriteOperation(func(tx *sqlx.Tx) error {
+		userID, err := userIDByName(tx, userName)
+		if err != nil {
+			return err
+		}
+		quota.UserID = userID
+
+		_, err = tx.NamedExec(`
+			INSERT INTO user_quotas (user_id, monthly_tokens, monthly_cost_usd, updated_at)
+			VALUES (:user_id, :monthly_tokens, :monthly_cost_usd, :updated_at)
+			ON CONFLICT(user_id) DO UPDATE SET
+				monthly_tokens = excluded.monthly_tokens,
+				monthly_cost_usd = excluded.monthly_cost_usd,
+				updated_at = excluded.updated_at
+		`, quota)
+		if err != nil {
+			return fmt.Errorf("failed to set quota: %w", err)
+		}
+
+		if _, err := tx.Exec(`DELETE FROM project_owners WHERE user_id = ?`, userID); err != nil {
+			return fmt.Errorf("failed to clear project owners: %w", err)
+		}
+		for _, project := range quota.Projects {
+			var owner string
+			err := tx.Get(&owner, `
+				SELECT u.name FROM project_owners p JOIN users u ON u.id = p.user_id
+				WHERE p.project_name = ?
+			`, project)
+			if err == nil {
+				return fmt.Errorf("project already has an owner: %s belongs to %s", project, owner)
+			}
+			if err != sql.ErrNoRows {
+				return fmt.Errorf("failed to get project owner: %w", err)
+			}
+			if _, err := tx.Exec(`INSERT INTO project_owners (project_name, user_id) VALUES (?, ?)`, project, userID); err != nil {
+				return fmt.Errorf("failed to set project owner: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// GetUserQuotas returns every user's quota, by name, with zero limits for
+// users without one
+func (r *SessionRepository) GetUserQuotas() ([]UserQuota, error) {
+	quotas := []UserQuota{}
+	err := r.db.Select(&quotas, `
+		SELECT u.id AS user_id, u.name AS user_name,
+		       COALESCE(q.monthly_tokens, 0) AS monthly_tokens,
+		       COALESCE(q.monthly_cost_usd, 0) AS monthly_cost_usd,
+		       q.updated_at
+		FROM users u
+		LEFT JOIN user_quotas q ON q.user_id = u.id
+		ORDER BY u.name
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get quotas: %w", err)
+	}
+
+	var owners []struct {
+		ProjectName string `db:"project_name"`
+		UserID      string `db:"user_id"`
+	}
+	if err := r.db.Select(&owners, `SELECT project_name, user_id FROM project_owners ORDER BY project_name`); err != nil {
+		return nil, fmt.Errorf("failed to get project owners: %w", err)
+	}
+	projects := make(map[string][]string)
+	for _, owner := range owners {
+		projects[owner.UserID] = append(projects[owner.UserID], owner.ProjectName)
+	}
+	for i := range quotas {
+		quotas[i].Projects = projects[quotas[i].UserID]
+		if quotas[i].Projects == nil {
+			quotas[i].Projects = []string{}
+		}
+	}
+	return quotas, nil
+}
+
+// DeleteUserQuota removes the named user's quota and project ownership
+func (r *SessionRepository) DeleteUserQuota(userName string) error {
+	return r.db.WriteOperation(func(tx *sqlx.Tx) error {
+		userID, err := userIDByName(tx, userName)
+		if err != nil {
+			return err
+		}
+		result, err := tx.Exec(`DELETE FROM user_quotas WHERE user_id = ?`, userID)
+		if err != nil {
+			return fmt.Errorf("failed to delete quota: %w", err)
+		}
+		if rows, _ := result.RowsAffected(); rows == 0 {
+			return fmt.Errorf("quota not found: %s", userName)
+		}
+		if _, err := tx.Exec(`DELETE FROM project_owners WHERE user_id = ?`, userID); err != nil {
+			return fmt.Errorf("failed to clear project owners: %w", err)
+		}
+		return nil
+	})
+}
+
+// SetSessionOwner counts a session's usage against the named user rather
+// than its project's owner, or against its project's owner again when
+// userName is empty
+func (r *SessionRepository) SetSessionOwner(sessionID, userName string) error {
+	return r.db.WriteOperation(func(tx *sqlx.Tx) error {
+		var exists bool
+		if err := tx.Get(&exists, `SELECT EXISTS(SELECT 1 FROM sessions WHERE id = ?)`, sessionID); err != nil {
+			return fmt.Errorf("failed to get session: %w", err)
+		}
+		if !exists {
+			return fmt.Errorf("session not found: %s", sessionID)
+		}
+
+		if userName == "" {
+			if _, err := tx.Exec(`DELETE FROM session_owners WHERE session_id = ?`, sessionID); err != nil {
+				return fmt.Errorf("failed to clear session owner: %w", err)
+			}
+			return nil
+		}
+		userID, err := userIDByName(tx, userName)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(`
+			INSERT INTO session_owners (session_id, user_id, assigned_at) VALUES (?, ?, ?)
+			ON CONFLICT(session_id) DO UPDATE SET user_id = excluded.user_id, assigned_at = excluded.assigned_at
+		`, sessionID, userID, time.Now().UTC())
+		if err != nil {
+			return fmt.Errorf("failed to set session owner: %w", err)
+		}
+		return nil
+	})
+}
+
+// GetUsageByUser returns the tokens and cost of messages sent in [from, to)
+// by the user they count against: the session's owner, or else its
+// project's. Usage nobody owns is left out.
+func (r *SessionRepository) GetUsageByUser(from, to time.Time) (map[string]UserUsage, error) {
+	var rows []UserUsage
+	err := r.db.Select(&rows, `
+		SELECT COALESCE(so.user_id, po.user_id) AS user_id,
+		       COALESCE(SUM(tu.total_tokens), 0) AS tokens,
+		       COALESCE(SUM(tu.estimated_cost), 0.0) AS cost_usd
+		FROM messages m
+		JOIN token_usage tu ON tu.message_id = m.id
+		JOIN sessions s ON s.id = m.session_id
+		LEFT JOIN session_owners so ON so.session_id = s.id
+		LEFT JOIN project_owners po ON po.project_name = s.project_name
+		WHERE m.timestamp >= ? AND m.timestamp < ?
+		  AND COALESCE(so.user_id, po.user_id) IS NOT NULL
+		GROUP BY COALESCE(so.user_id, po.user_id)
+	`, from.UTC(), to.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get usage by user: %w", err)
+	}
+
+	usage := make(map[string]UserUsage, len(rows))
+	for _, row := range rows {
+		usage[row.UserID] = row
+	}
+	return usage, nil
+}
+
+// RecordQuotaAlert stores an alert unless the user already crossed the same
+// threshold of the same metric in the same month, reporting whether it was
+// new
+func (r *SessionRepository) RecordQuotaAlert(alert *QuotaAlert) (bool, error) {
+	alert.PeriodStart = alert.PeriodStart.UTC()
+	var inserted int64
+	err := r.db.WriteOperation(func(tx *sqlx.Tx) error {
+		result, err := tx.NamedExec(`
+			INSERT OR IGNORE INTO quota_alerts (user_id, period_start, metric, threshold, used, triggered_at)
+			VALUES (:user_id, :period_start, :metric, :threshold, :used, :triggered_at)
+		`, alert)
+		if err != nil {
+			return err
+		}
+		inserted, err = result.RowsAffected()
+		return err
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to record quota alert: %w", err)
+	}
+	return inserted > 0, nil
+}
+
+// userIDByName returns the ID of the named user
+func userIDByName(tx *sqlx.Tx, userName string) (string, error) {
+	var userID string
+	err := tx.Get(&userID, `SELECT id FROM users WHERE name = ?`, userName)
+	if err == sql.ErrNoRows {
+		return "", fmt.Errorf("user not found: %s", userName)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get user: %w", err)
+	}
+	return userID, nil
+}
@@ -0,0 +1,100 @@
+package database
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSessionRepository_Quotas(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewSessionRepository(db, logger)
+	for _, user := range []string{"alice", "bob"} {
+		if err := repo.CreateAPIKey(user, &APIKey{Name: "laptop", Prefix: "csm_" + user, KeyHash: "hash-" + user, Scope: ScopeWrite}); err != nil {
+			t.Fatalf("Failed to create key: %v", err)
+		}
+	}
+
+	web, err := repo.CreateUISession("/work/web", "web", "claude-sonnet-4-20250514")
+	if err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	other, err := repo.CreateUISession("/work/web", "web", "claude-sonnet-4-20250514")
+	if err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	at := time.Now().UTC().Add(-time.Hour)
+	for i, session := range []*Session{web, other} {
+		_, err := repo.RecordChatTurn(&ChatTurn{
+			SessionID: session.ID,
+			PromptID:  session.ID + "-prompt",
+			ReplyID:   session.ID + "-reply",
+			Prompt:    "Go",
+			Reply:     "Done",
+			Usage:     &TokenUsage{InputTokens: 1000 * (i + 1), OutputTokens: 100},
+			SentAt:    at,
+			RepliedAt: at.Add(time.Second),
+		})
+		if err != nil {
+			t.Fatalf("Failed to record chat turn: %v", err)
+		}
+	}
+
+	quota := &UserQuota{MonthlyTokens: 10000, MonthlyCostUSD: 5, Projects: []string{"web"}}
+	if err := repo.SetUserQuota("alice", quota); err != nil {
+		t.Fatalf("Failed to set quota: %v", err)
+	}
+	if err := repo.SetUserQuota("bob", &UserQuota{Projects: []string{"web"}}); err == nil || !strings.Contains(err.Error(), "belongs to alice") {
+		t.Errorf("Expected alice's project refused, got %v", err)
+	}
+	if err := repo.SetSessionOwner(other.ID, "bob"); err != nil {
+		t.Fatalf("Failed to set session owner: %v", err)
+	}
+
+	usage, err := repo.GetUsageByUser(at.Add(-time.Hour), at.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to get usage: %v", err)
+	}
+	if usage[quota.UserID].Tokens != 1100 || usage[quota.UserID].CostUSD <= 0 {
+		t.Errorf("Expected alice to own the first session's usage, got %+v", usage[quota.UserID])
+	}
+	quotas, err := repo.GetUserQuotas()
+	if err != nil || len(quotas) != 2 {
+		t.Fatalf("Expected both users' quotas, got %+v (%v)", quotas, err)
+	}
+	if bob := quotas[1]; bob.UserName != "bob" || usage[bob.UserID].Tokens != 2100 || bob.MonthlyTokens != 0 || bob.UpdatedAt != nil {
+		t.Errorf("Expected bob without a quota owning the second session, got %+v and %+v", bob, usage[bob.UserID])
+	}
+	if alice := quotas[0]; alice.MonthlyCostUSD != 5 || len(alice.Projects) != 1 {
+		t.Errorf("Unexpected quota for alice: %+v", alice)
+	}
+
+	// Cleared, the session counts against its project's owner again
+	if err := repo.SetSessionOwner(other.ID, ""); err != nil {
+		t.Fatalf("Failed to clear session owner: %v", err)
+	}
+	usage, _ = repo.GetUsageByUser(at.Add(-time.Hour), at.Add(time.Hour))
+	if usage[quota.UserID].Tokens != 3200 {
+		t.Errorf("Expected alice to own both sessions' usage, got %+v", usage[quota.UserID])
+	}
+
+	alert := &QuotaAlert{UserID: quota.UserID, PeriodStart: at, Metric: QuotaTokens, Threshold: 80, Used: 8000, TriggeredAt: at}
+	if isNew, err := repo.RecordQuotaAlert(alert); err != nil || !isNew {
+		t.Fatalf("Expected a new alert, got %v (%v)", isNew, err)
+	}
+	if isNew, _ := repo.RecordQuotaAlert(alert); isNew {
+		t.Error("Expected the same alert not recorded twice")
+	}
+
+	if err := repo.DeleteUserQuota("alice"); err != nil {
+		t.Fatalf("Failed to delete quota: %v", err)
+	}
+	if err := repo.DeleteUserQuota("alice"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("Expected deleting twice not found, got %v", err)
+	}
+	if err := repo.SetSessionOwner("missing", "alice"); err == nil || !strings.Contains(err.Error(), "session not found") {
+		t.Errorf("Expected a missing session not found, got %v", err)
+	}
+}
@@ -24,22 +24,11 @@ func (r *ReadOptimizedRepository) GetSessionTokenTimelineOptimized(sessionID str
 	
 	// Execute in read-only transaction
 	err := r.executeInReadTransaction(func(tx *sqlx.Tx) error {
-		// Determine the time format based on granularity
-		var timeFormat string
-		switch granularity {
-		case "minute":
-			timeFormat = "%Y-%m-%d %H:%M:00"
-		case "hour":
-			timeFormat = "%Y-%m-%d %H:00:00"
-		case "day":
-			timeFormat = "%Y-%m-%d 00:00:00"
-		default:
-			timeFormat = "%Y-%m-%d %H:%M:00"
-		}
+		bucket := timelineBucketSQL("m.timestamp", granularity, "minute")
 
-		query := `
+		query := fmt.Sprintf(`
 			SELECT 
-				strftime(?, m.timestamp) as timestamp,
+				%[1]s as timestamp,
 				COALESCE(SUM(tu.input_tokens), 0) as input_tokens,
 				COALESCE(SUM(tu.output_tokens), 0) as output_tokens,
 				COALESCE(SUM(tu.cache_creation_input_tokens), 0) as cache_creation_tokens,
@@ -51,11 +40,11 @@ func (r *ReadOptimizedRepository) GetSessionTokenTimelineOptimized(sessionID str
 			LEFT JOIN token_usage tu ON m.id = tu.message_id
 			WHERE m.session_id = ?
 			AND m.timestamp >= datetime('now', '-' || ? || ' hours')
-			GROUP BY strftime(?, m.timestamp)
+			GROUP BY %[1]s
 			ORDER BY timestamp ASC
-		`
+		`, bucket)
 
-		return tx.Select(&entries, query, timeFormat, sessionID, hours, timeFormat)
+		return tx.Select(&entries, query, sessionID, hours)
 	})
 	
 	return entries, err
@@ -63,24 +52,26 @@ func (r *ReadOptimizedRepository) GetSessionTokenTimelineOptimized(sessionID str
 
 // GetTokenTimelineOptimized returns overall token usage timeline using read-only transaction
 func (r *ReadOptimizedRepository) GetTokenTimelineOptimized(hours int, granularity string) ([]TokenTimelineEntry, error) {
+	return r.GetTokenTimelineAsOf(hours, granularity, time.Now())
+}
+
+// GetTokenTimelineAsOf returns the token usage timeline of the hours before asOf
+func (r *ReadOptimizedRepository) GetTokenTimelineAsOf(hours int, granularity string, asOf time.Time) ([]TokenTimelineEntry, error) {
+	return r.GetTokenTimelineBetween(asOf.Add(-time.Duration(hours)*time.Hour), asOf, granularity, "")
+}
+
+// GetTokenTimelineBetween returns the token usage timeline of messages sent
+// in [from, to), in UTC buckets. A project, by display name or any of its
+// aliases, limits it to that project's sessions.
+func (r *ReadOptimizedRepository) GetTokenTimelineBetween(from, to time.Time, granularity, projectName string) ([]TokenTimelineEntry, error) {
 	var entries []TokenTimelineEntry
-	
+
 	err := r.executeInReadTransaction(func(tx *sqlx.Tx) error {
-		var timeFormat string
-		switch granularity {
-		case "minute":
-			timeFormat = "%Y-%m-%d %H:%M:00"
-		case "hour":
-			timeFormat = "%Y-%m-%d %H:00:00"
-		case "day":
-			timeFormat = "%Y-%m-%d 00:00:00"
-		default:
-			timeFormat = "%Y-%m-%d %H:00:00"
-		}
+		bucket := timelineBucketSQL("m.timestamp", granularity, "hour")
 
-		query := `
+		query := fmt.Sprintf(`
 			SELECT 
-				strftime(?, m.timestamp) as timestamp,
+				%[1]s as timestamp,
 				COALESCE(SUM(tu.input_tokens), 0) as input_tokens,
 				COALESCE(SUM(tu.output_tokens), 0) as output_tokens,
 				COALESCE(SUM(tu.cache_creation_input_tokens), 0) as cache_creation_tokens,
@@ -90,38 +81,70 @@ func (r *ReadOptimizedRepository) GetTokenTimelineOptimized(hours int, granulari
 				COUNT(DISTINCT m.id) as message_count
 			FROM messages m
 			LEFT JOIN token_usage tu ON m.id = tu.message_id
-			WHERE m.timestamp >= datetime('now', '-' || ? || ' hours')
-			GROUP BY strftime(?, m.timestamp)
+			WHERE m.timestamp >= ? AND m.timestamp < ?
+			AND (? = '' OR m.session_id IN (
+				SELECT s.id FROM sessions s
+				JOIN project_aliases pa ON pa.alias = s.project_name
+				WHERE pa.project_id IN (
+					SELECT project_id FROM project_aliases WHERE alias = ?
+					UNION SELECT id FROM projects WHERE display_name = ?
+				)
+			))
+			GROUP BY %[1]s
 			ORDER BY timestamp ASC
-		`
+		`, bucket)
 
-		return tx.Select(&entries, query, timeFormat, hours, timeFormat)
+		return tx.Select(&entries, query, from.UTC(), to.UTC(), projectName, projectName, projectName)
 	})
 	
 	return entries, err
 }
 
-// GetAllSessionsOptimized returns all sessions with summary information using read-only transaction
-func (r *ReadOptimizedRepository) GetAllSessionsOptimized() ([]*SessionSummary, error) {
+// GetAllSessionsOptimized returns a page of sessions with summary information,
+// ordered by last activity, and the total number of sessions using a read-only
+// transaction. A limit of 0 or less returns every session from offset on.
+// Archived sessions are left out unless includeArchived is set.
+func (r *ReadOptimizedRepository) GetAllSessionsOptimized(limit, offset int, ascending, includeArchived bool) ([]*SessionSummary, int, error) {
 	var sessions []*SessionSummary
-	
+	var total int
+
+	order := "DESC"
+	if ascending {
+		order = "ASC"
+	}
+	if limit <= 0 {
+		limit = -1 // SQLite treats a negative LIMIT as no limit
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
 	err := r.executeInReadTransaction(func(tx *sqlx.Tx) error {
-		return tx.Select(&sessions, "SELECT * FROM session_summary ORDER BY last_activity DESC")
+		if err := tx.Get(&total, "SELECT COUNT(*) FROM sessions WHERE (? OR archived_at IS NULL)", includeArchived); err != nil {
+			return err
+		}
+		return tx.Select(&sessions, fmt.Sprintf(`
+			SELECT * FROM session_summaries
+			WHERE (? OR archived_at IS NULL)
+			ORDER BY last_activity %s, id
+			LIMIT ? OFFSET ?
+		`, order), includeArchived, limit, offset)
 	})
-	
-	return sessions, err
+
+	return sessions, total, err
 }
 
-// GetActiveSessionsOptimized returns currently active sessions using read-only transaction
-func (r *ReadOptimizedRepository) GetActiveSessionsOptimized() ([]*SessionSummary, error) {
+// GetActiveSessionsOptimized returns currently active sessions using read-only
+// transaction, leaving out archived sessions unless includeArchived is set
+func (r *ReadOptimizedRepository) GetActiveSessionsOptimized(includeArchived bool) ([]*SessionSummary, error) {
 	var sessions []*SessionSummary
 	
 	err := r.executeInReadTransaction(func(tx *sqlx.Tx) error {
 		return tx.Select(&sessions, `
-			SELECT * FROM session_summary 
-			WHERE is_active = 1 
+			SELECT * FROM session_summaries 
+			WHERE is_active = 1 AND (? OR archived_at IS NULL)
 			ORDER BY last_activity DESC
-		`)
+		`, includeArchived)
 	})
 	
 	return sessions, err
@@ -129,15 +152,12 @@ func (r *ReadOptimizedRepository) GetActiveSessionsOptimized() ([]*SessionSummar
 
 // executeInReadTransaction executes a function within a transaction optimized for reads
 func (r *ReadOptimizedRepository) executeInReadTransaction(fn func(*sqlx.Tx) error) error {
-	tx, err := r.db.Beginx()
+	tx, err := r.db.BeginRead()
 	if err != nil {
 		return fmt.Errorf("failed to begin read transaction: %w", err)
 	}
 	defer tx.Rollback()
 
-	// Note: We don't use PRAGMA query_only because it affects the entire connection,
-	// not just the transaction, which can cause "readonly database" errors elsewhere
-
 	if err := fn(tx); err != nil {
 		return err
 	}
@@ -150,7 +170,7 @@ func (r *ReadOptimizedRepository) GetSessionByIDOptimized(sessionID string) (*Se
 	var session SessionSummary
 	
 	err := r.executeInReadTransaction(func(tx *sqlx.Tx) error {
-		err := tx.Get(&session, "SELECT * FROM session_summary WHERE id = ?", sessionID)
+		err := tx.Get(&session, "SELECT * FROM session_summaries WHERE id = ?", sessionID)
 		if err == sql.ErrNoRows {
 			return fmt.Errorf("session not found: %s", sessionID)
 		}
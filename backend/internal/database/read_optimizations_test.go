@@ -0,0 +1,189 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadOptimizedRepository_GetTokenTimelineBetween(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewSessionRepository(db, logger)
+	readOptimized := NewReadOptimizedRepository(db)
+
+	hour := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	sessions := []struct {
+		id, project string
+		at          time.Time
+	}{
+		{"s1", "my-app", hour.Add(10 * time.Minute)},
+		{"s2", "my-app-worktree", hour.Add(20 * time.Minute)},
+		{"s3", "other", hour.Add(70 * time.Minute)},
+	}
+	for _, s := range sessions {
+		if err := repo.UpsertSession(&Session{ID: s.id, ProjectPath: "/work/" + s.project, ProjectName: s.project, StartTime: s.at, LastActivity: s.at, Status: "completed"}); err != nil {
+			t.Fatalf("Failed to create session: %v", err)
+		}
+		if err := repo.UpsertMessage(&Message{ID: s.id + "-msg", SessionID: s.id, Role: "assistant", Content: `"done"`, Timestamp: s.at}); err != nil {
+			t.Fatalf("Failed to create message: %v", err)
+		}
+		if err := repo.UpsertTokenUsage(&TokenUsage{MessageID: s.id + "-msg", SessionID: s.id, InputTokens: 10, OutputTokens: 20, TotalTokens: 30, EstimatedCost: 0.5}); err != nil {
+			t.Fatalf("Failed to create token usage: %v", err)
+		}
+	}
+
+	timeline, err := readOptimized.GetTokenTimelineBetween(hour, hour.Add(2*time.Hour), "hour", "")
+	if err != nil {
+		t.Fatalf("Failed to get timeline: %v", err)
+	}
+	if len(timeline) != 2 || timeline[0].Timestamp != "2026-10-01 12:00:00" || timeline[0].MessageCount != 2 || timeline[0].EstimatedCost != 1.0 {
+		t.Errorf("Expected two hourly buckets, got %+v", timeline)
+	}
+
+	// A project includes the sessions of the projects merged into it
+	projects, err := repo.GetProjects(false)
+	if err != nil {
+		t.Fatalf("Failed to get projects: %v", err)
+	}
+	if _, err := repo.MergeProjects(projects[0].ID, []int64{projects[1].ID}); err != nil {
+		t.Fatalf("Failed to merge projects: %v", err)
+	}
+	for _, name := range []string{"my-app", "my-app-worktree"} {
+		timeline, err := readOptimized.GetTokenTimelineBetween(hour, hour.Add(2*time.Hour), "hour", name)
+		if err != nil {
+			t.Fatalf("Failed to get timeline: %v", err)
+		}
+		if len(timeline) != 1 || timeline[0].TotalTokens != 60 {
+			t.Errorf("Expected both sessions of the merged project for %s, got %+v", name, timeline)
+		}
+	}
+}
+
+func TestReadOptimizedRepository_TimelineWindows(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewSessionRepository(db, logger)
+	readOptimized := NewReadOptimizedRepository(db)
+
+	start := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC) // a Thursday
+	if err := repo.UpsertSession(&Session{ID: "s1", ProjectPath: "/work/app", ProjectName: "app", StartTime: start, LastActivity: start, Status: "completed"}); err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	for i, at := range []time.Time{
+		start.Add(10 * time.Minute),
+		start.Add(20 * time.Minute),
+		start.Add(70 * time.Minute),
+		time.Date(2026, 10, 5, 11, 0, 0, 0, time.FixedZone("CEST", 2*60*60)), // Monday 09:00 UTC
+		time.Date(2026, 11, 2, 9, 0, 0, 0, time.UTC),
+	} {
+		id := fmt.Sprintf("m%d", i)
+		if err := repo.UpsertMessage(&Message{ID: id, SessionID: "s1", Role: "assistant", Content: `"done"`, Timestamp: at}); err != nil {
+			t.Fatalf("Failed to create message: %v", err)
+		}
+		if err := repo.UpsertTokenUsage(&TokenUsage{MessageID: id, SessionID: "s1", InputTokens: 10, TotalTokens: 10}); err != nil {
+			t.Fatalf("Failed to create token usage: %v", err)
+		}
+	}
+
+	tests := []struct {
+		granularity string
+		want        []string
+	}{
+		{"15m", []string{"2026-10-01 12:00:00", "2026-10-01 12:15:00", "2026-10-01 13:00:00", "2026-10-05 09:00:00", "2026-11-02 09:00:00"}},
+		{"6h", []string{"2026-10-01 12:00:00", "2026-10-05 06:00:00", "2026-11-02 06:00:00"}},
+		{"week", []string{"2026-09-28 00:00:00", "2026-10-05 00:00:00", "2026-11-02 00:00:00"}},
+		{"month", []string{"2026-10-01 00:00:00", "2026-11-01 00:00:00"}},
+		{"fortnight", []string{"2026-10-01 12:00:00", "2026-10-01 13:00:00", "2026-10-05 09:00:00", "2026-11-02 09:00:00"}}, // falls back to hourly
+	}
+	for _, tt := range tests {
+		timeline, err := readOptimized.GetTokenTimelineBetween(start, start.AddDate(0, 2, 0), tt.granularity, "")
+		if err != nil {
+			t.Fatalf("Failed to get %s timeline: %v", tt.granularity, err)
+		}
+		var got []string
+		for _, entry := range timeline {
+			got = append(got, entry.Timestamp)
+		}
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("%s: expected buckets %v, got %v", tt.granularity, tt.want, got)
+		}
+	}
+}
+
+func TestIsTimelineGranularity(t *testing.T) {
+	for granularity, want := range map[string]bool{
+		"minute": true,
+		"week":   true,
+		"month":  true,
+		"15m":    true,
+		"1h30m":  true,
+		"2d":     true,
+		"30s":    false,
+		"90s":    false,
+		"0m":     false,
+		"-1h":    false,
+		"400d":   false,
+		"year":   false,
+		"":       false,
+	} {
+		if got := IsTimelineGranularity(granularity); got != want {
+			t.Errorf("IsTimelineGranularity(%q) = %v, want %v", granularity, got, want)
+		}
+	}
+}
+
+func TestFillTimeline(t *testing.T) {
+	from := time.Date(2026, 10, 1, 10, 30, 0, 0, time.UTC)
+	entries := []TokenTimelineEntry{
+		{Timestamp: "2026-10-01 12:00:00", TotalTokens: 30, MessageCount: 2},
+		{Timestamp: "2026-10-01 13:00:00", TotalTokens: 10, MessageCount: 1},
+	}
+
+	filled, err := FillTimeline(entries, from, from.Add(3*time.Hour+40*time.Minute), "hour", "hour")
+	if err != nil {
+		t.Fatalf("Failed to fill timeline: %v", err)
+	}
+	var labels []string
+	for _, entry := range filled {
+		labels = append(labels, entry.Timestamp)
+	}
+	want := "2026-10-01 10:00:00,2026-10-01 11:00:00,2026-10-01 12:00:00,2026-10-01 13:00:00,2026-10-01 14:00:00"
+	if strings.Join(labels, ",") != want {
+		t.Errorf("Expected buckets %s, got %v", want, labels)
+	}
+	if filled[1].MessageCount != 0 || filled[2].TotalTokens != 30 {
+		t.Errorf("Expected empty buckets at zero and the rest kept, got %+v", filled)
+	}
+
+	tests := []struct {
+		granularity string
+		to          time.Time
+		want        string
+	}{
+		{"week", from.AddDate(0, 0, 12), "2026-09-28 00:00:00,2026-10-05 00:00:00,2026-10-12 00:00:00"},
+		{"month", from.AddDate(0, 0, 12), "2026-10-01 00:00:00"},
+		{"7h", from.Add(7 * time.Hour), "2026-10-01 07:00:00,2026-10-01 14:00:00"}, // aligned to the Unix epoch
+	}
+	for _, tt := range tests {
+		filled, err := FillTimeline(nil, from, tt.to, tt.granularity, "hour")
+		if err != nil {
+			t.Fatalf("Failed to fill %s timeline: %v", tt.granularity, err)
+		}
+		labels = labels[:0]
+		for _, entry := range filled {
+			labels = append(labels, entry.Timestamp)
+		}
+		if strings.Join(labels, ",") != tt.want {
+			t.Errorf("%s: expected buckets %s, got %v", tt.granularity, tt.want, labels)
+		}
+	}
+
+	if _, err := FillTimeline(nil, from, from.AddDate(0, 0, 30), "minute", "hour"); !errors.Is(err, ErrTooManyTimelineBuckets) {
+		t.Errorf("Expected too many buckets, got %v", err)
+	}
+}
@@ -0,0 +1,79 @@
+package database
+
+import (
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// notHeld selects the sessions not under an active legal hold
+const notHeld = `NOT IN (SELECT session_id FROM legal_holds WHERE released_at IS NULL)`
+
+// RebuildWipe is what WipeDerivedData removed
+type RebuildWipe struct {
+	SessionsDeleted int64 `json:"sessions_deleted"`
+	SessionsKept    int64 `json:"sessions_kept"` // emptied but kept for their chats and groups
+	MessagesDeleted int64 `json:"messages_deleted"`
+}
+
+// WipeDerivedData deletes everything imports derive from transcripts, the
+// sessions with their messages, token usage and tool results, the activity
+// log, the tool calls and commands found in messages, and the import
+// journal, so the next import reads every transcript again. Tags, notes,
+// reviews and other annotations are kept and apply again as sessions are
+// re-imported under the same ids. Sessions that chats or session groups
+// refer to are emptied rather than deleted, as deleting them would cascade to
+// those. Sessions under an active legal hold are left alone.
+func (r *SessionRepository) WipeDerivedData() (*RebuildWipe, error) {
+	wipe := &RebuildWipe{}
+	err := r.db.WriteOperation(func(tx *sqlx.Tx) error {
+		// Messages last, so deleting them has nothing left to cascade to
+		statements := []string{
+			`DELETE FROM tool_results WHERE session_id ` + notHeld,
+			`DELETE FROM token_usage WHERE session_id ` + notHeld,
+			`DELETE FROM activity_log WHERE session_id IS NULL OR session_id ` + notHeld,
+			`DELETE FROM tool_calls WHERE session_id ` + notHeld,
+			`DELETE FROM tool_call_scans WHERE session_id ` + notHeld,
+			`DELETE FROM commands WHERE session_id ` + notHeld,
+			`DELETE FROM file_watchers`,
+		}
+		for _, statement := range statements {
+			if _, err := tx.Exec(statement); err != nil {
+				return fmt.Errorf("failed to run %q: %w", statement, err)
+			}
+		}
+
+		result, err := tx.Exec(`DELETE FROM messages WHERE session_id ` + notHeld)
+		if err != nil {
+			return fmt.Errorf("failed to delete messages: %w", err)
+		}
+		if wipe.MessagesDeleted, err = result.RowsAffected(); err != nil {
+			return err
+		}
+
+		result, err = tx.Exec(`
+			DELETE FROM sessions
+			WHERE id ` + notHeld + `
+			AND id NOT IN (SELECT session_id FROM chat_sessions)
+			AND id NOT IN (SELECT session_id FROM session_group_members)`)
+		if err != nil {
+			return fmt.Errorf("failed to delete sessions: %w", err)
+		}
+		if wipe.SessionsDeleted, err = result.RowsAffected(); err != nil {
+			return err
+		}
+
+		result, err = tx.Exec(`
+			UPDATE sessions SET message_count = 0, duration_seconds = 0, is_active = FALSE, status = 'completed'
+			WHERE id ` + notHeld)
+		if err != nil {
+			return fmt.Errorf("failed to empty sessions: %w", err)
+		}
+		wipe.SessionsKept, err = result.RowsAffected()
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to wipe derived data: %w", err)
+	}
+	return wipe, nil
+}
@@ -0,0 +1,75 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSessionRepository_WipeDerivedData(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewSessionRepository(db, logger)
+	now := time.Now().UTC().Truncate(time.Second)
+	for _, id := range []string{"plain", "chatted", "held"} {
+		if err := repo.UpsertSession(&Session{ID: id, ProjectPath: "/work/app", ProjectName: "app", StartTime: now, LastActivity: now, Status: "active", IsActive: true, MessageCount: 1}); err != nil {
+			t.Fatalf("Failed to create session: %v", err)
+		}
+		if err := repo.UpsertMessage(&Message{ID: id + "-m1", SessionID: id, Role: "assistant", Content: "hi", Timestamp: now}); err != nil {
+			t.Fatalf("Failed to create message: %v", err)
+		}
+		if err := repo.UpsertTokenUsage(&TokenUsage{MessageID: id + "-m1", SessionID: id, InputTokens: 10, TotalTokens: 10}); err != nil {
+			t.Fatalf("Failed to create token usage: %v", err)
+		}
+	}
+	setup := []string{
+		`INSERT INTO chat_sessions (id, session_id) VALUES ('chat-1', 'chatted')`,
+		`INSERT INTO legal_holds (id, session_id, reason, placed_by, placed_at) VALUES ('hold-1', 'held', 'audit', 'alice', CURRENT_TIMESTAMP)`,
+		`INSERT INTO session_tags (session_id, tag) VALUES ('plain', 'keep-me')`,
+		`INSERT INTO activity_log (session_id, activity_type, timestamp) VALUES ('plain', 'session_created', CURRENT_TIMESTAMP), ('held', 'session_created', CURRENT_TIMESTAMP)`,
+		`INSERT INTO file_watchers (file_path, last_modified) VALUES ('/tmp/plain.jsonl', CURRENT_TIMESTAMP)`,
+	}
+	for _, query := range setup {
+		if _, err := db.Exec(query); err != nil {
+			t.Fatalf("Failed to set up data: %v", err)
+		}
+	}
+
+	wipe, err := repo.WipeDerivedData()
+	if err != nil {
+		t.Fatalf("Failed to wipe derived data: %v", err)
+	}
+	if wipe.SessionsDeleted != 1 || wipe.SessionsKept != 1 || wipe.MessagesDeleted != 2 {
+		t.Errorf("Expected 1 session deleted, 1 emptied and 2 messages deleted, got %+v", wipe)
+	}
+
+	if _, err := repo.GetSessionByID("plain"); err == nil {
+		t.Error("Expected the plain session to be deleted")
+	}
+	chatted, err := repo.GetSessionByID("chatted")
+	if err != nil {
+		t.Fatalf("Expected the chatted session to be kept: %v", err)
+	}
+	if chatted.MessageCount != 0 || chatted.TotalTokens != 0 || chatted.IsActive {
+		t.Errorf("Expected the chatted session emptied, got %+v", chatted)
+	}
+	held, err := repo.GetSessionByID("held")
+	if err != nil || held.TotalTokens != 10 || held.MessageCount != 1 {
+		t.Errorf("Expected the held session untouched, got %+v (%v)", held, err)
+	}
+
+	counts := map[string]int{
+		`SELECT COUNT(*) FROM chat_sessions`: 1,
+		`SELECT COUNT(*) FROM session_tags`:  1,
+		`SELECT COUNT(*) FROM activity_log`:  1,
+		`SELECT COUNT(*) FROM file_watchers`: 0,
+		`SELECT COUNT(*) FROM messages`:      1,
+		`SELECT COUNT(*) FROM token_usage`:   1,
+	}
+	for query, want := range counts {
+		var got int
+		if err := db.Get(&got, query); err != nil || got != want {
+			t.Errorf("%s: expected %d, got %d (%v)", query, want, got, err)
+		}
+	}
+}
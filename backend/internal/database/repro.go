@@ -0,0 +1,43 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+)
+
+// GetReproRecords returns the session, its git branch, transcript, captured
+// environment and tags
+func (r *SessionRepository) GetReproRecords(sessionID string) (*ReproRecords, error) {
+	session, err := r.GetSessionByID(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	records := &ReproRecords{Session: session}
+
+	var git struct {
+		Branch   string `db:"git_branch"`
+		Worktree string `db:"git_worktree"`
+	}
+	err = r.db.Get(&git, `
+		SELECT COALESCE(git_branch, '') AS git_branch, COALESCE(git_worktree, '') AS git_worktree
+		FROM sessions WHERE id = ?
+	`, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session git branch: %w", err)
+	}
+	records.GitBranch, records.GitWorktree = git.Branch, git.Worktree
+
+	if records.Messages, err = r.GetSessionMessages(sessionID); err != nil {
+		return nil, err
+	}
+	if records.Environment, err = r.GetSessionEnvironment(sessionID); err != nil {
+		if !strings.Contains(err.Error(), "not found") {
+			return nil, err
+		}
+		records.Environment = nil
+	}
+	if records.Tags, err = r.GetSessionTags(sessionID); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
@@ -0,0 +1,207 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// ReviewQueueUnreviewed selects sessions that changed files but have no review yet
+const ReviewQueueUnreviewed = "unreviewed"
+
+// IsValidReviewStatus reports whether status is a review status a session can be set to
+func IsValidReviewStatus(status string) bool {
+	switch status {
+	case ReviewStatusNeedsReview, ReviewStatusReviewed, ReviewStatusFlagged:
+		return true
+	}
+	return false
+}
+
+// GetSessionReview returns the review state of a session
+func (r *SessionRepository) GetSessionReview(sessionID string) (*SessionReview, error) {
+	var review SessionReview
+	err := r.db.Get(&review, `SELECT * FROM session_reviews WHERE session_id = ?`, sessionID)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("session review not found: %s", sessionID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session review: %w", err)
+	}
+	return &review, nil
+}
+
+// SetSessionReview sets the review status of a session and, when reviewer is
+// not nil, assigns it. An empty reviewer clears the assignment.
+func (r *SessionRepository) SetSessionReview(sessionID, status string, reviewer, updatedBy *string) (*SessionReview, error) {
+	if !IsValidReviewStatus(status) {
+		return nil, fmt.Errorf("invalid review status: %s", status)
+	}
+	if _, err := r.GetSessionByID(sessionID); err != nil {
+		return nil, fmt.Errorf("session not found: %s", sessionID)
+	}
+
+	var reviewedAt *time.Time
+	if status != ReviewStatusNeedsReview {
+		now := time.Now().UTC()
+		reviewedAt = &now
+	}
+
+	err := r.db.WriteOperation(func(tx *sqlx.Tx) error {
+		_, err := tx.Exec(`
+			INSERT INTO session_reviews (session_id, status, reviewer, updated_by, reviewed_at)
+			VALUES (?, ?, NULLIF(?, ''), ?, ?)
+			ON CONFLICT(session_id) DO UPDATE SET
+				status = excluded.status,
+				reviewer = CASE WHEN ? THEN excluded.reviewer ELSE session_reviews.reviewer END,
+				updated_by = excluded.updated_by,
+				reviewed_at = COALESCE(excluded.reviewed_at, session_reviews.reviewed_at),
+				updated_at = CURRENT_TIMESTAMP
+		`, sessionID, status, stringOrEmpty(reviewer), updatedBy, reviewedAt, reviewer != nil)
+		if err != nil {
+			return err
+		}
+
+		details := fmt.Sprintf("Session marked %s", status)
+		if updatedBy != nil && *updatedBy != "" {
+			details += " by " + *updatedBy
+		}
+		if reviewer != nil && *reviewer != "" {
+			details += ", assigned to " + *reviewer
+		}
+		_, err = tx.Exec(`
+			INSERT INTO activity_log (session_id, activity_type, details, timestamp)
+			VALUES (?, ?, ?, ?)
+		`, sessionID, "session_review_"+status, details, time.Now().UTC())
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to save session review: %w", err)
+	}
+
+	return r.GetSessionReview(sessionID)
+}
+
+// stringOrEmpty dereferences s, treating nil as empty
+func stringOrEmpty(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// AddSessionReviewComment adds a reviewer comment to a session. A session
+// without a review is put in the needs-review state.
+func (r *SessionRepository) AddSessionReviewComment(comment *SessionReviewComment) error {
+	if _, err := r.GetSessionByID(comment.SessionID); err != nil {
+		return fmt.Errorf("session not found: %s", comment.SessionID)
+	}
+
+	comment.ID = uuid.New().String()
+	comment.CreatedAt = time.Now().UTC()
+	return r.db.WriteOperation(func(tx *sqlx.Tx) error {
+		_, err := tx.NamedExec(`
+			INSERT INTO session_review_comments (id, session_id, author, body, message_id, created_at)
+			VALUES (:id, :session_id, :author, :body, :message_id, :created_at)
+		`, comment)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(`
+			INSERT OR IGNORE INTO session_reviews (session_id, status, updated_by) VALUES (?, ?, ?)
+		`, comment.SessionID, ReviewStatusNeedsReview, comment.Author)
+		return err
+	})
+}
+
+// GetSessionReviewComments returns a session's review comments, oldest first
+func (r *SessionRepository) GetSessionReviewComments(sessionID string) ([]SessionReviewComment, error) {
+	comments := []SessionReviewComment{}
+	err := r.db.Select(&comments, `
+		SELECT * FROM session_review_comments WHERE session_id = ? ORDER BY created_at ASC
+	`, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get review comments: %w", err)
+	}
+	return comments, nil
+}
+
+// GetReviewQueue returns sessions in a review status, optionally assigned to
+// one reviewer, oldest change first so nothing waits indefinitely. The
+// unreviewed status instead returns sessions that changed files but have
+// never been reviewed, most recent first.
+func (r *SessionRepository) GetReviewQueue(status, reviewer string, limit int) ([]ReviewQueueItem, error) {
+	query := `
+		SELECT
+			s.id AS session_id,
+			COALESCE(sr.status, 'unreviewed') AS status,
+			sr.reviewer,
+			sr.updated_at,
+			s.project_name,
+			s.project_path,
+			s.git_branch,
+			s.last_activity,
+			s.message_count,
+			(
+				SELECT COUNT(DISTINCT file_path) FROM tool_results
+				WHERE session_id = s.id AND file_path IS NOT NULL
+				AND tool_name IN ('Edit', 'Write', 'MultiEdit', 'NotebookEdit', 'NotebookWrite')
+			) AS files_changed,
+			(SELECT COUNT(*) FROM session_review_comments WHERE session_id = s.id) AS comment_count
+		FROM sessions s
+		LEFT JOIN session_reviews sr ON sr.session_id = s.id
+	`
+	var args []interface{}
+	if status == ReviewQueueUnreviewed {
+		query += `
+			WHERE sr.session_id IS NULL
+			AND EXISTS (
+				SELECT 1 FROM tool_results
+				WHERE session_id = s.id AND file_path IS NOT NULL
+				AND tool_name IN ('Edit', 'Write', 'MultiEdit', 'NotebookEdit', 'NotebookWrite')
+			)
+			ORDER BY s.last_activity DESC
+		`
+	} else {
+		query += ` WHERE sr.status = ?`
+		args = append(args, status)
+		if reviewer != "" {
+			query += ` AND sr.reviewer = ?`
+			args = append(args, reviewer)
+		}
+		query += ` ORDER BY sr.updated_at ASC`
+	}
+	query += ` LIMIT ?`
+	args = append(args, limit)
+
+	items := []ReviewQueueItem{}
+	if err := r.db.Select(&items, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to get review queue: %w", err)
+	}
+	return items, nil
+}
+
+// GetReviewCounts returns the number of sessions in each review status
+func (r *SessionRepository) GetReviewCounts() (map[string]int, error) {
+	var rows []struct {
+		Status string `db:"status"`
+		Count  int    `db:"count"`
+	}
+	if err := r.db.Select(&rows, `SELECT status, COUNT(*) AS count FROM session_reviews GROUP BY status`); err != nil {
+		return nil, fmt.Errorf("failed to get review counts: %w", err)
+	}
+
+	counts := map[string]int{
+		ReviewStatusNeedsReview: 0,
+		ReviewStatusReviewed:    0,
+		ReviewStatusFlagged:     0,
+	}
+	for _, row := range rows {
+		counts[row.Status] = row.Count
+	}
+	return counts, nil
+}
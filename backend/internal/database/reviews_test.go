@@ -0,0 +1,108 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSessionRepository_Reviews(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewSessionRepository(db, logger)
+
+	now := time.Now()
+	for _, id := range []string{"changed", "read-only"} {
+		if err := repo.UpsertSession(&Session{
+			ID:           id,
+			ProjectPath:  "/test/project",
+			ProjectName:  "project",
+			StartTime:    now.Add(-time.Hour),
+			LastActivity: now,
+			Status:       "completed",
+		}); err != nil {
+			t.Fatalf("Failed to create session: %v", err)
+		}
+	}
+	if err := repo.UpsertMessage(&Message{ID: "changed-1", SessionID: "changed", Role: "assistant", Timestamp: now}); err != nil {
+		t.Fatalf("Failed to create message: %v", err)
+	}
+	if err := repo.UpsertToolResult(&ToolResult{
+		MessageID: "changed-1",
+		SessionID: "changed",
+		ToolName:  "Write",
+		FilePath:  stringPtr("/test/project/main.go"),
+		Timestamp: now,
+	}); err != nil {
+		t.Fatalf("Failed to create tool result: %v", err)
+	}
+
+	// Sessions that changed files are unreviewed until someone reviews them
+	queue, err := repo.GetReviewQueue(ReviewQueueUnreviewed, "", 10)
+	if err != nil {
+		t.Fatalf("Failed to get review queue: %v", err)
+	}
+	if len(queue) != 1 || queue[0].SessionID != "changed" || queue[0].FilesChanged != 1 {
+		t.Fatalf("Expected only the session that changed files, got %+v", queue)
+	}
+
+	review, err := repo.SetSessionReview("changed", ReviewStatusNeedsReview, stringPtr("alice"), stringPtr("lead"))
+	if err != nil {
+		t.Fatalf("Failed to set review: %v", err)
+	}
+	if review.Reviewer == nil || *review.Reviewer != "alice" || review.ReviewedAt != nil {
+		t.Errorf("Unexpected review: %+v", review)
+	}
+
+	if err := repo.AddSessionReviewComment(&SessionReviewComment{
+		SessionID: "changed",
+		Author:    "alice",
+		Body:      "Missing tests for main.go",
+		MessageID: stringPtr("changed-1"),
+	}); err != nil {
+		t.Fatalf("Failed to add comment: %v", err)
+	}
+
+	queue, err = repo.GetReviewQueue(ReviewStatusNeedsReview, "alice", 10)
+	if err != nil {
+		t.Fatalf("Failed to get review queue: %v", err)
+	}
+	if len(queue) != 1 || queue[0].CommentCount != 1 {
+		t.Fatalf("Expected the assigned session with its comment, got %+v", queue)
+	}
+	if queue, _ := repo.GetReviewQueue(ReviewStatusNeedsReview, "bob", 10); len(queue) != 0 {
+		t.Errorf("Expected no sessions assigned to bob, got %+v", queue)
+	}
+
+	// Changing the status without a reviewer keeps the assignment
+	review, err = repo.SetSessionReview("changed", ReviewStatusFlagged, nil, stringPtr("alice"))
+	if err != nil {
+		t.Fatalf("Failed to flag session: %v", err)
+	}
+	if review.Status != ReviewStatusFlagged || review.Reviewer == nil || review.ReviewedAt == nil {
+		t.Errorf("Unexpected review after flagging: %+v", review)
+	}
+
+	counts, err := repo.GetReviewCounts()
+	if err != nil {
+		t.Fatalf("Failed to get review counts: %v", err)
+	}
+	if counts[ReviewStatusFlagged] != 1 || counts[ReviewStatusNeedsReview] != 0 {
+		t.Errorf("Unexpected counts: %v", counts)
+	}
+
+	// Commenting on an unreviewed session queues it for review
+	if err := repo.AddSessionReviewComment(&SessionReviewComment{SessionID: "read-only", Author: "bob", Body: "Worth a look"}); err != nil {
+		t.Fatalf("Failed to add comment: %v", err)
+	}
+	if review, err := repo.GetSessionReview("read-only"); err != nil || review.Status != ReviewStatusNeedsReview {
+		t.Errorf("Expected commented session to need review, got %+v (%v)", review, err)
+	}
+
+	if _, err := repo.SetSessionReview("changed", "approved", nil, nil); err == nil {
+		t.Error("Expected error for invalid status")
+	}
+	if _, err := repo.SetSessionReview("missing", ReviewStatusReviewed, nil, nil); err == nil {
+		t.Error("Expected error for unknown session")
+	}
+}
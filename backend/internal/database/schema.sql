@@ -8,7 +8,8 @@ CREATE TABLE IF NOT EXISTS sessions (
     project_name TEXT NOT NULL,
     file_path TEXT NOT NULL, -- Original JSONL file path
     git_branch TEXT,
-    git_worktree TEXT,
+    git_worktree TEXT, -- top level of the working tree the session ran in
+    git_remote TEXT DEFAULT '', -- origin remote, without credentials
     start_time DATETIME NOT NULL,
     last_activity DATETIME NOT NULL,
     is_active BOOLEAN DEFAULT FALSE,
@@ -16,6 +17,8 @@ CREATE TABLE IF NOT EXISTS sessions (
     model TEXT,
     message_count INTEGER DEFAULT 0,
     duration_seconds INTEGER DEFAULT 0,
+    source TEXT DEFAULT 'import', -- import, ui
+    archived_at DATETIME, -- set while archived, hiding the session from default listings and metrics
     created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
     updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
 );
@@ -49,7 +52,9 @@ CREATE TABLE IF NOT EXISTS token_usage (
     cache_read_input_tokens INTEGER DEFAULT 0,
     total_tokens INTEGER DEFAULT 0,
     service_tier TEXT,
+    model TEXT DEFAULT '', -- model that wrote the message, as priced; the session's when empty
     estimated_cost REAL DEFAULT 0.0,
+    cache_savings REAL DEFAULT 0.0, -- fresh input cost of the cached tokens minus their cost
     created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
     FOREIGN KEY (message_id) REFERENCES messages(id) ON DELETE CASCADE,
     FOREIGN KEY (session_id) REFERENCES sessions(id) ON DELETE CASCADE
@@ -61,7 +66,8 @@ CREATE TABLE IF NOT EXISTS tool_results (
     message_id TEXT NOT NULL,
     session_id TEXT NOT NULL,
     tool_name TEXT,
-    file_path TEXT,
+    file_path TEXT, -- relative to the session's working directory when inside it
+    absolute_path TEXT, -- the path as Claude gave it, when absolute
     result_data TEXT, -- JSON string of full tool result
     timestamp DATETIME NOT NULL,
     created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
@@ -69,6 +75,46 @@ CREATE TABLE IF NOT EXISTS tool_results (
     FOREIGN KEY (session_id) REFERENCES sessions(id) ON DELETE CASCADE
 );
 
+-- Tool calls table - every tool_use block in assistant messages, whatever the tool, with how
+-- long its result took. Rebuilt from a session's messages whenever its message count changes.
+CREATE TABLE IF NOT EXISTS tool_calls (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    session_id TEXT NOT NULL,
+    message_id TEXT NOT NULL,
+    tool_use_id TEXT, -- ID of the tool_use block, which its tool_result refers to
+    tool_name TEXT NOT NULL,
+    timestamp DATETIME NOT NULL, -- when the tool was called
+    duration_ms INTEGER, -- until the message carrying its result; NULL without one
+    is_error BOOLEAN NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS idx_tool_calls_session ON tool_calls(session_id);
+CREATE INDEX IF NOT EXISTS idx_tool_calls_timestamp ON tool_calls(timestamp);
+
+-- Tool call scans table - each session's message count when its tool calls were last extracted
+CREATE TABLE IF NOT EXISTS tool_call_scans (
+    session_id TEXT PRIMARY KEY,
+    message_count INTEGER NOT NULL,
+    scanned_at DATETIME NOT NULL
+);
+
+-- Commands table - shell commands Claude ran with the Bash tool, with the exit code its result
+-- reported. Rebuilt with a session's tool calls.
+CREATE TABLE IF NOT EXISTS commands (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    session_id TEXT NOT NULL,
+    message_id TEXT NOT NULL,
+    tool_use_id TEXT,
+    command TEXT NOT NULL,
+    description TEXT, -- what Claude said the command does
+    cwd TEXT, -- working directory of the session when the command ran
+    exit_code INTEGER, -- NULL until a result arrives, or when it doesn't say
+    timestamp DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_commands_session ON commands(session_id);
+CREATE INDEX IF NOT EXISTS idx_commands_timestamp ON commands(timestamp);
+
 -- File watchers table - tracks which files we're monitoring and their processing status
 CREATE TABLE IF NOT EXISTS file_watchers (
     id INTEGER PRIMARY KEY AUTOINCREMENT,
@@ -91,11 +137,13 @@ CREATE TABLE IF NOT EXISTS import_runs (
     run_type TEXT NOT NULL, -- 'initial', 'incremental', 'manual'
     start_time DATETIME NOT NULL,
     end_time DATETIME,
-    status TEXT DEFAULT 'running', -- running, completed, failed, cancelled
+    status TEXT DEFAULT 'running', -- running, completed, failed, cancelled, interrupted
     files_processed INTEGER DEFAULT 0,
     files_skipped INTEGER DEFAULT 0,
     sessions_imported INTEGER DEFAULT 0,
     messages_imported INTEGER DEFAULT 0,
+    resumed_from_run_id INTEGER, -- interrupted run this one picked up from
+    files_recovered INTEGER DEFAULT 0, -- files the interrupted run had journaled as done
     error_message TEXT,
     created_at DATETIME DEFAULT CURRENT_TIMESTAMP
 );
@@ -135,47 +183,185 @@ CREATE INDEX IF NOT EXISTS idx_activity_log_session_id ON activity_log(session_i
 CREATE INDEX IF NOT EXISTS idx_activity_log_timestamp ON activity_log(timestamp DESC);
 CREATE INDEX IF NOT EXISTS idx_activity_log_type ON activity_log(activity_type);
 
--- Views for common queries
-CREATE VIEW IF NOT EXISTS session_summary AS
-SELECT 
-    s.id,
-    s.project_name,
-    s.project_path,
-    s.start_time,
-    s.last_activity,
-    s.is_active,
-    s.status,
-    s.model,
-    s.message_count,
-    s.duration_seconds,
-    COALESCE(tu.total_input_tokens, 0) as total_input_tokens,
-    COALESCE(tu.total_output_tokens, 0) as total_output_tokens,
-    COALESCE(tu.total_cache_creation_tokens, 0) as total_cache_creation_tokens,
-    COALESCE(tu.total_cache_read_tokens, 0) as total_cache_read_tokens,
-    COALESCE(tu.total_tokens, 0) as total_tokens,
-    COALESCE(tu.total_cost, 0.0) as total_estimated_cost,
-    COALESCE(fr.modified_files, '[]') as files_modified
-FROM sessions s
-LEFT JOIN (
-    SELECT 
-        session_id,
-        SUM(input_tokens) as total_input_tokens,
-        SUM(output_tokens) as total_output_tokens,
-        SUM(cache_creation_input_tokens) as total_cache_creation_tokens,
-        SUM(cache_read_input_tokens) as total_cache_read_tokens,
-        SUM(total_tokens) as total_tokens,
-        SUM(estimated_cost) as total_cost
-    FROM token_usage 
-    GROUP BY session_id
-) tu ON s.id = tu.session_id
-LEFT JOIN (
-    SELECT 
-        session_id,
-        JSON_GROUP_ARRAY(DISTINCT file_path) as modified_files
-    FROM tool_results 
-    WHERE file_path IS NOT NULL
-    GROUP BY session_id
-) fr ON s.id = fr.session_id;
+-- Session summaries table - each session with its token totals and the files
+-- it modified. Kept up to date by the triggers below as sessions, token usage
+-- and tool results are written, so listings don't aggregate on every read.
+-- RebuildSessionSummaries recomputes it.
+CREATE TABLE IF NOT EXISTS session_summaries (
+    id TEXT PRIMARY KEY,
+    project_name TEXT NOT NULL,
+    project_path TEXT NOT NULL,
+    start_time DATETIME NOT NULL,
+    last_activity DATETIME NOT NULL,
+    is_active BOOLEAN DEFAULT FALSE,
+    status TEXT DEFAULT 'completed',
+    model TEXT,
+    message_count INTEGER DEFAULT 0,
+    duration_seconds INTEGER DEFAULT 0,
+    source TEXT DEFAULT 'import',
+    archived_at DATETIME,
+    git_branch TEXT NOT NULL DEFAULT '',
+    git_worktree TEXT NOT NULL DEFAULT '',
+    git_remote TEXT NOT NULL DEFAULT '',
+    total_input_tokens INTEGER NOT NULL DEFAULT 0,
+    total_output_tokens INTEGER NOT NULL DEFAULT 0,
+    total_cache_creation_tokens INTEGER NOT NULL DEFAULT 0,
+    total_cache_read_tokens INTEGER NOT NULL DEFAULT 0,
+    total_tokens INTEGER NOT NULL DEFAULT 0,
+    total_estimated_cost REAL NOT NULL DEFAULT 0.0,
+    total_cache_savings REAL NOT NULL DEFAULT 0.0,
+    files_modified TEXT NOT NULL DEFAULT '[]' -- JSON array of distinct modified file paths
+);
+
+CREATE INDEX IF NOT EXISTS idx_session_summaries_last_activity ON session_summaries(last_activity DESC);
+
+-- Session columns are read back from sessions rather than taken from NEW, as
+-- the hook liveness triggers may update the row again
+CREATE TRIGGER IF NOT EXISTS session_summaries_session_insert
+AFTER INSERT ON sessions
+BEGIN
+    INSERT OR REPLACE INTO session_summaries (
+        id, project_name, project_path, start_time, last_activity, is_active, status,
+        model, message_count, duration_seconds, source, archived_at,
+        git_branch, git_worktree, git_remote,
+        total_input_tokens, total_output_tokens, total_cache_creation_tokens,
+        total_cache_read_tokens, total_tokens, total_estimated_cost, total_cache_savings,
+        files_modified
+    )
+    SELECT
+        s.id, s.project_name, s.project_path, s.start_time, s.last_activity, s.is_active, s.status,
+        s.model, s.message_count, s.duration_seconds, s.source, s.archived_at,
+        COALESCE(s.git_branch, ''), COALESCE(s.git_worktree, ''), COALESCE(s.git_remote, ''),
+        COALESCE(tu.input_tokens, 0), COALESCE(tu.output_tokens, 0), COALESCE(tu.cache_creation_tokens, 0),
+        COALESCE(tu.cache_read_tokens, 0), COALESCE(tu.total_tokens, 0), COALESCE(tu.cost, 0.0), COALESCE(tu.cache_savings, 0.0),
+        fr.files
+    FROM sessions s,
+    (
+        SELECT
+            SUM(input_tokens) as input_tokens,
+            SUM(output_tokens) as output_tokens,
+            SUM(cache_creation_input_tokens) as cache_creation_tokens,
+            SUM(cache_read_input_tokens) as cache_read_tokens,
+            SUM(total_tokens) as total_tokens,
+            SUM(estimated_cost) as cost,
+            SUM(cache_savings) as cache_savings
+        FROM token_usage WHERE session_id = NEW.id
+    ) tu,
+    (
+        SELECT JSON_GROUP_ARRAY(DISTINCT file_path) as files
+        FROM tool_results WHERE session_id = NEW.id AND file_path IS NOT NULL
+    ) fr
+    WHERE s.id = NEW.id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS session_summaries_session_update
+AFTER UPDATE ON sessions
+BEGIN
+    UPDATE session_summaries SET (
+        id, project_name, project_path, start_time, last_activity, is_active, status,
+        model, message_count, duration_seconds, source, archived_at,
+        git_branch, git_worktree, git_remote
+    ) = (
+        SELECT
+            id, project_name, project_path, start_time, last_activity, is_active, status,
+            model, message_count, duration_seconds, source, archived_at,
+            COALESCE(git_branch, ''), COALESCE(git_worktree, ''), COALESCE(git_remote, '')
+        FROM sessions WHERE id = NEW.id
+    )
+    WHERE id = OLD.id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS session_summaries_session_delete
+AFTER DELETE ON sessions
+BEGIN
+    DELETE FROM session_summaries WHERE id = OLD.id;
+END;
+
+-- Token totals are adjusted by each row rather than summed again, so imports
+-- stay linear in the size of the session
+CREATE TRIGGER IF NOT EXISTS session_summaries_token_usage_insert
+AFTER INSERT ON token_usage
+BEGIN
+    UPDATE session_summaries SET
+        total_input_tokens = total_input_tokens + COALESCE(NEW.input_tokens, 0),
+        total_output_tokens = total_output_tokens + COALESCE(NEW.output_tokens, 0),
+        total_cache_creation_tokens = total_cache_creation_tokens + COALESCE(NEW.cache_creation_input_tokens, 0),
+        total_cache_read_tokens = total_cache_read_tokens + COALESCE(NEW.cache_read_input_tokens, 0),
+        total_tokens = total_tokens + COALESCE(NEW.total_tokens, 0),
+        total_estimated_cost = total_estimated_cost + COALESCE(NEW.estimated_cost, 0.0),
+        total_cache_savings = total_cache_savings + COALESCE(NEW.cache_savings, 0.0)
+    WHERE id = NEW.session_id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS session_summaries_token_usage_update
+AFTER UPDATE ON token_usage
+BEGIN
+    UPDATE session_summaries SET
+        total_input_tokens = total_input_tokens - COALESCE(OLD.input_tokens, 0),
+        total_output_tokens = total_output_tokens - COALESCE(OLD.output_tokens, 0),
+        total_cache_creation_tokens = total_cache_creation_tokens - COALESCE(OLD.cache_creation_input_tokens, 0),
+        total_cache_read_tokens = total_cache_read_tokens - COALESCE(OLD.cache_read_input_tokens, 0),
+        total_tokens = total_tokens - COALESCE(OLD.total_tokens, 0),
+        total_estimated_cost = total_estimated_cost - COALESCE(OLD.estimated_cost, 0.0),
+        total_cache_savings = total_cache_savings - COALESCE(OLD.cache_savings, 0.0)
+    WHERE id = OLD.session_id;
+    UPDATE session_summaries SET
+        total_input_tokens = total_input_tokens + COALESCE(NEW.input_tokens, 0),
+        total_output_tokens = total_output_tokens + COALESCE(NEW.output_tokens, 0),
+        total_cache_creation_tokens = total_cache_creation_tokens + COALESCE(NEW.cache_creation_input_tokens, 0),
+        total_cache_read_tokens = total_cache_read_tokens + COALESCE(NEW.cache_read_input_tokens, 0),
+        total_tokens = total_tokens + COALESCE(NEW.total_tokens, 0),
+        total_estimated_cost = total_estimated_cost + COALESCE(NEW.estimated_cost, 0.0),
+        total_cache_savings = total_cache_savings + COALESCE(NEW.cache_savings, 0.0)
+    WHERE id = NEW.session_id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS session_summaries_token_usage_delete
+AFTER DELETE ON token_usage
+BEGIN
+    UPDATE session_summaries SET
+        total_input_tokens = total_input_tokens - COALESCE(OLD.input_tokens, 0),
+        total_output_tokens = total_output_tokens - COALESCE(OLD.output_tokens, 0),
+        total_cache_creation_tokens = total_cache_creation_tokens - COALESCE(OLD.cache_creation_input_tokens, 0),
+        total_cache_read_tokens = total_cache_read_tokens - COALESCE(OLD.cache_read_input_tokens, 0),
+        total_tokens = total_tokens - COALESCE(OLD.total_tokens, 0),
+        total_estimated_cost = total_estimated_cost - COALESCE(OLD.estimated_cost, 0.0),
+        total_cache_savings = total_cache_savings - COALESCE(OLD.cache_savings, 0.0)
+    WHERE id = OLD.session_id;
+END;
+
+-- A session's files are listed again whenever one of its file paths changes
+CREATE TRIGGER IF NOT EXISTS session_summaries_tool_results_insert
+AFTER INSERT ON tool_results
+WHEN NEW.file_path IS NOT NULL
+BEGIN
+    UPDATE session_summaries SET files_modified = (
+        SELECT JSON_GROUP_ARRAY(DISTINCT file_path) FROM tool_results
+        WHERE session_id = NEW.session_id AND file_path IS NOT NULL
+    )
+    WHERE id = NEW.session_id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS session_summaries_tool_results_update
+AFTER UPDATE OF session_id, file_path ON tool_results
+BEGIN
+    UPDATE session_summaries SET files_modified = (
+        SELECT JSON_GROUP_ARRAY(DISTINCT file_path) FROM tool_results
+        WHERE session_id = session_summaries.id AND file_path IS NOT NULL
+    )
+    WHERE id IN (OLD.session_id, NEW.session_id);
+END;
+
+CREATE TRIGGER IF NOT EXISTS session_summaries_tool_results_delete
+AFTER DELETE ON tool_results
+WHEN OLD.file_path IS NOT NULL
+BEGIN
+    UPDATE session_summaries SET files_modified = (
+        SELECT JSON_GROUP_ARRAY(DISTINCT file_path) FROM tool_results
+        WHERE session_id = OLD.session_id AND file_path IS NOT NULL
+    )
+    WHERE id = OLD.session_id;
+END;
 
 -- Chat sessions table - tracks active chat sessions with Claude CLI
 CREATE TABLE IF NOT EXISTS chat_sessions (
@@ -185,6 +371,7 @@ CREATE TABLE IF NOT EXISTS chat_sessions (
     status TEXT NOT NULL DEFAULT 'active', -- active, inactive, terminated, error
     started_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
     last_activity DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    claude_session_id TEXT, -- the Claude session the chat resumes
     FOREIGN KEY (session_id) REFERENCES sessions(id) ON DELETE CASCADE
 );
 
@@ -208,6 +395,685 @@ CREATE INDEX IF NOT EXISTS idx_chat_messages_chat_session_id ON chat_messages(ch
 CREATE INDEX IF NOT EXISTS idx_chat_messages_timestamp ON chat_messages(timestamp DESC);
 CREATE INDEX IF NOT EXISTS idx_chat_messages_type ON chat_messages(type);
 
+-- Prompt templates table - saved prompts that can be inserted when starting a UI chat
+CREATE TABLE IF NOT EXISTS prompt_templates (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE,
+    description TEXT,
+    content TEXT NOT NULL,
+    category TEXT,
+    usage_count INTEGER DEFAULT 0,
+    last_used_at DATETIME,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Prompt template usage table - one row per time a template is used
+CREATE TABLE IF NOT EXISTS prompt_template_usage (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    template_id TEXT NOT NULL,
+    session_id TEXT,
+    used_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (template_id) REFERENCES prompt_templates(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_prompt_templates_category ON prompt_templates(category);
+CREATE INDEX IF NOT EXISTS idx_prompt_template_usage_template_id ON prompt_template_usage(template_id);
+CREATE INDEX IF NOT EXISTS idx_prompt_template_usage_used_at ON prompt_template_usage(used_at DESC);
+
+-- Session groups table - links sessions that belong together (e.g. a playbook run)
+CREATE TABLE IF NOT EXISTS session_groups (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    kind TEXT NOT NULL DEFAULT 'manual', -- manual, playbook
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS session_group_members (
+    group_id TEXT NOT NULL,
+    session_id TEXT NOT NULL,
+    position INTEGER DEFAULT 0,
+    added_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (group_id, session_id),
+    FOREIGN KEY (group_id) REFERENCES session_groups(id) ON DELETE CASCADE,
+    FOREIGN KEY (session_id) REFERENCES sessions(id) ON DELETE CASCADE
+);
+
+-- Playbook runs table - one row per execution of a playbook
+CREATE TABLE IF NOT EXISTS playbook_runs (
+    id TEXT PRIMARY KEY,
+    playbook_name TEXT NOT NULL,
+    project_path TEXT NOT NULL,
+    group_id TEXT,
+    schedule_id TEXT, -- set when launched from a playbook schedule
+    status TEXT NOT NULL DEFAULT 'pending', -- pending, running, awaiting_approval, succeeded, failed, cancelled, rejected
+    trigger_type TEXT NOT NULL DEFAULT 'manual', -- manual, schedule
+    total_cost_usd REAL DEFAULT 0.0,
+    error TEXT,
+    diff TEXT, -- git diff of the project after the run
+    started_at DATETIME,
+    completed_at DATETIME,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (group_id) REFERENCES session_groups(id) ON DELETE SET NULL
+);
+
+-- Playbook run steps table - the outcome of each prompt in a run
+CREATE TABLE IF NOT EXISTS playbook_run_steps (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    run_id TEXT NOT NULL,
+    step_index INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    prompt TEXT NOT NULL,
+    session_id TEXT,
+    claude_session_id TEXT,
+    status TEXT NOT NULL DEFAULT 'pending', -- pending, running, awaiting_approval, succeeded, failed, skipped, rejected
+    response TEXT,
+    proposal TEXT, -- changes proposed by a step that requires approval
+    reviewed_by TEXT,
+    reviewed_at DATETIME,
+    failure_reason TEXT,
+    cost_usd REAL DEFAULT 0.0,
+    duration_ms INTEGER DEFAULT 0,
+    num_turns INTEGER DEFAULT 0,
+    started_at DATETIME,
+    completed_at DATETIME,
+    FOREIGN KEY (run_id) REFERENCES playbook_runs(id) ON DELETE CASCADE
+);
+
+-- Playbook schedules table - playbooks launched automatically on a cron schedule
+CREATE TABLE IF NOT EXISTS playbook_schedules (
+    id TEXT PRIMARY KEY,
+    playbook_name TEXT NOT NULL,
+    cron_expr TEXT NOT NULL,
+    project_path TEXT, -- overrides the playbook's project_path when set
+    webhook_url TEXT, -- notified with the run result when set
+    enabled BOOLEAN DEFAULT TRUE,
+    last_run_id TEXT,
+    last_run_at DATETIME,
+    next_run_at DATETIME,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_playbook_schedules_next_run ON playbook_schedules(enabled, next_run_at);
+CREATE INDEX IF NOT EXISTS idx_session_group_members_session_id ON session_group_members(session_id);
+CREATE INDEX IF NOT EXISTS idx_playbook_runs_status ON playbook_runs(status);
+CREATE INDEX IF NOT EXISTS idx_playbook_runs_created_at ON playbook_runs(created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_playbook_run_steps_run_id ON playbook_run_steps(run_id, step_index);
+
+-- Session scores table - composite quality score per session, recomputed when
+-- the session's message count or feedback changes
+CREATE TABLE IF NOT EXISTS session_scores (
+    session_id TEXT PRIMARY KEY,
+    score REAL NOT NULL, -- 0-100
+    efficiency REAL NOT NULL, -- 0-1, files changed relative to tokens spent
+    files_changed INTEGER DEFAULT 0,
+    tokens_spent INTEGER DEFAULT 0, -- input + output + cache creation tokens
+    interruptions INTEGER DEFAULT 0,
+    tool_results INTEGER DEFAULT 0,
+    tool_errors INTEGER DEFAULT 0,
+    feedback_rating INTEGER,
+    message_count INTEGER DEFAULT 0, -- message count the score was computed from
+    computed_at DATETIME NOT NULL
+);
+
+-- Session feedback table - explicit 1-5 ratings of how effective a session was
+CREATE TABLE IF NOT EXISTS session_feedback (
+    session_id TEXT PRIMARY KEY,
+    rating INTEGER NOT NULL CHECK (rating BETWEEN 1 AND 5),
+    note TEXT,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_session_scores_score ON session_scores(score DESC);
+
+-- Knowledge entries table - recurring Q&A pairs and decisions extracted from transcripts
+CREATE TABLE IF NOT EXISTS knowledge_entries (
+    id TEXT PRIMARY KEY,
+    kind TEXT NOT NULL, -- qa, decision
+    normalized_key TEXT NOT NULL, -- normalized question or decision text used to group repeats
+    title TEXT NOT NULL, -- the question or decision as first written
+    body TEXT, -- the most recent answer, or the decision's surrounding context
+    project_name TEXT, -- project of the most recent source
+    occurrences INTEGER DEFAULT 0, -- distinct sessions the entry was found in
+    first_seen DATETIME NOT NULL,
+    last_seen DATETIME NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(kind, normalized_key)
+);
+
+-- Knowledge sources table - links each knowledge entry to the messages it came from
+CREATE TABLE IF NOT EXISTS knowledge_sources (
+    entry_id TEXT NOT NULL,
+    message_id TEXT NOT NULL,
+    session_id TEXT NOT NULL,
+    project_name TEXT,
+    timestamp DATETIME NOT NULL,
+    PRIMARY KEY (entry_id, message_id),
+    FOREIGN KEY (entry_id) REFERENCES knowledge_entries(id) ON DELETE CASCADE
+);
+
+-- Knowledge extraction runs table - each run scans messages imported since the last completed run
+CREATE TABLE IF NOT EXISTS knowledge_extraction_runs (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    start_time DATETIME NOT NULL,
+    end_time DATETIME,
+    status TEXT DEFAULT 'running', -- running, completed, failed
+    full_scan BOOLEAN DEFAULT FALSE,
+    sessions_scanned INTEGER DEFAULT 0,
+    entries_found INTEGER DEFAULT 0,
+    error_message TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_knowledge_entries_occurrences ON knowledge_entries(occurrences DESC);
+CREATE INDEX IF NOT EXISTS idx_knowledge_sources_session_id ON knowledge_sources(session_id);
+CREATE INDEX IF NOT EXISTS idx_messages_created_at ON messages(created_at);
+
+-- Prompt signatures table - MinHash signature of each session's opening prompt for near-duplicate detection
+CREATE TABLE IF NOT EXISTS prompt_signatures (
+    session_id TEXT PRIMARY KEY,
+    project_name TEXT,
+    prompt TEXT NOT NULL, -- opening prompt, empty when the session has no text prompt
+    signature TEXT NOT NULL, -- hex-encoded MinHash values, empty when there is no prompt
+    started_at DATETIME NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_prompt_signatures_started_at ON prompt_signatures(started_at);
+
+-- Session reviews table - review state of a session's transcript and changes
+CREATE TABLE IF NOT EXISTS session_reviews (
+    session_id TEXT PRIMARY KEY,
+    status TEXT NOT NULL, -- needs-review, reviewed, flagged
+    reviewer TEXT, -- assigned reviewer
+    updated_by TEXT, -- who last changed the status or assignment
+    reviewed_at DATETIME, -- when the session was last marked reviewed or flagged
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Session review comments table - reviewer comments, optionally anchored to a message
+CREATE TABLE IF NOT EXISTS session_review_comments (
+    id TEXT PRIMARY KEY,
+    session_id TEXT NOT NULL,
+    author TEXT NOT NULL,
+    body TEXT NOT NULL,
+    message_id TEXT,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_session_reviews_status ON session_reviews(status, updated_at);
+CREATE INDEX IF NOT EXISTS idx_session_review_comments_session_id ON session_review_comments(session_id, created_at);
+
+-- Legal holds table - sessions that must not be pruned or archived; a hold is active until released
+CREATE TABLE IF NOT EXISTS legal_holds (
+    id TEXT PRIMARY KEY,
+    session_id TEXT NOT NULL,
+    reason TEXT NOT NULL,
+    placed_by TEXT NOT NULL,
+    placed_at DATETIME NOT NULL,
+    released_by TEXT,
+    released_at DATETIME
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_legal_holds_active ON legal_holds(session_id) WHERE released_at IS NULL;
+
+-- Message hashes table - content hash of each message chained to the previous message of its session
+CREATE TABLE IF NOT EXISTS message_hashes (
+    message_id TEXT PRIMARY KEY,
+    session_id TEXT NOT NULL,
+    seq INTEGER NOT NULL, -- position in the session's chain, from 1
+    content_hash TEXT NOT NULL, -- SHA-256 of the message's canonical form
+    chain_hash TEXT NOT NULL, -- SHA-256 of the previous chain hash and this content hash
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(session_id, seq)
+);
+
+-- Session hashes table - head of each session's message hash chain
+CREATE TABLE IF NOT EXISTS session_hashes (
+    session_id TEXT PRIMARY KEY,
+    message_count INTEGER NOT NULL,
+    head_hash TEXT NOT NULL,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Session environments table - where each session ran, detected at import or reported by the client
+CREATE TABLE IF NOT EXISTS session_environments (
+    session_id TEXT PRIMARY KEY,
+    os TEXT, -- darwin, linux, windows
+    terminal TEXT,
+    git_remote TEXT, -- host/path of the origin remote as reported by the client; sessions.git_remote when NULL
+    cwd TEXT,
+    client_version TEXT,
+    source TEXT NOT NULL DEFAULT 'detected', -- detected, reported
+    captured_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_session_environments_git_remote ON session_environments(git_remote);
+
+-- Session tags table - labels on sessions, applied by hand or by auto-tagging rules
+CREATE TABLE IF NOT EXISTS session_tags (
+    session_id TEXT NOT NULL,
+    tag TEXT NOT NULL,
+    source TEXT NOT NULL DEFAULT 'manual', -- manual, rule, script
+    rule TEXT, -- name of the rule or script that applied the tag
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (session_id, tag)
+);
+
+CREATE INDEX IF NOT EXISTS idx_session_tags_tag ON session_tags(tag);
+
+-- Session notes table - free-form notes attached to sessions by hand
+CREATE TABLE IF NOT EXISTS session_notes (
+    session_id TEXT PRIMARY KEY,
+    content TEXT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Tag rule evaluations table - which version of the tagging rules each session was last evaluated against
+CREATE TABLE IF NOT EXISTS tag_rule_evaluations (
+    session_id TEXT PRIMARY KEY,
+    rules_version TEXT NOT NULL, -- hash of the configured rules
+    has_prompt BOOLEAN NOT NULL DEFAULT FALSE, -- whether the opening prompt had been imported
+    message_count INTEGER NOT NULL DEFAULT 0,
+    evaluated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Monthly snapshots table - usage aggregates frozen when a month closes, so re-imports and
+-- cost recalculations cannot change numbers already reported to finance
+CREATE TABLE IF NOT EXISTS monthly_snapshots (
+    month TEXT PRIMARY KEY, -- YYYY-MM
+    period_start DATETIME NOT NULL,
+    period_end DATETIME NOT NULL, -- exclusive
+    sessions INTEGER NOT NULL DEFAULT 0,
+    messages INTEGER NOT NULL DEFAULT 0,
+    tokens INTEGER NOT NULL DEFAULT 0,
+    cost_usd REAL NOT NULL DEFAULT 0.0,
+    currency TEXT,
+    data TEXT NOT NULL, -- JSON of the full aggregates
+    checksum TEXT NOT NULL, -- SHA-256 of data
+    closed_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TRIGGER IF NOT EXISTS monthly_snapshots_immutable_update
+BEFORE UPDATE ON monthly_snapshots
+BEGIN
+    SELECT RAISE(ABORT, 'monthly snapshots are immutable');
+END;
+
+CREATE TRIGGER IF NOT EXISTS monthly_snapshots_immutable_delete
+BEFORE DELETE ON monthly_snapshots
+BEGIN
+    SELECT RAISE(ABORT, 'monthly snapshots are immutable');
+END;
+
+-- Session todos table - the todo lists Claude keeps in ~/.claude/todos, one
+-- row per item, replaced whenever the list file changes
+CREATE TABLE IF NOT EXISTS session_todos (
+    session_id TEXT NOT NULL,
+    agent_id TEXT NOT NULL,
+    position INTEGER NOT NULL,
+    todo_id TEXT,
+    content TEXT NOT NULL,
+    status TEXT NOT NULL, -- 'pending', 'in_progress' or 'completed'
+    active_form TEXT,
+    priority TEXT,
+    file_modified_at DATETIME,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (session_id, agent_id, position)
+);
+
+CREATE INDEX IF NOT EXISTS idx_session_todos_status ON session_todos(status);
+
+-- Settings changes table - each distinct version of the Claude settings file
+CREATE TABLE IF NOT EXISTS settings_changes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    file_path TEXT NOT NULL,
+    checksum TEXT NOT NULL, -- SHA-256 of the file as read
+    content TEXT NOT NULL, -- settings with env values redacted
+    changed_keys TEXT NOT NULL DEFAULT '[]', -- JSON array of top-level keys changed from the previous version
+    file_modified_at DATETIME,
+    recorded_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_settings_changes_file ON settings_changes(file_path, id);
+
+-- Hook events table - events POSTed by Claude Code hooks as they happen
+CREATE TABLE IF NOT EXISTS hook_events (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    session_id TEXT NOT NULL,
+    event TEXT NOT NULL, -- hook_event_name, e.g. 'PreToolUse', 'PostToolUse', 'Stop'
+    tool_name TEXT,
+    tool_use_id TEXT,
+    cwd TEXT,
+    transcript_path TEXT,
+    payload TEXT NOT NULL, -- the hook input as received
+    received_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_hook_events_session ON hook_events(session_id, id);
+CREATE INDEX IF NOT EXISTS idx_hook_events_event ON hook_events(event, id);
+
+-- Session liveness table - whether a session is running, as last reported by its hooks
+CREATE TABLE IF NOT EXISTS session_liveness (
+    session_id TEXT PRIMARY KEY,
+    is_active BOOLEAN NOT NULL,
+    event TEXT NOT NULL, -- the hook event that set it
+    changed_at DATETIME NOT NULL
+);
+
+-- Imports estimate is_active from how recent the last message is. While a
+-- session's hooks have reported its state since its last message (allowing a
+-- minute for the transcript to be written first), the reported state wins.
+CREATE TRIGGER IF NOT EXISTS sessions_hook_liveness_insert
+AFTER INSERT ON sessions
+WHEN EXISTS (
+    SELECT 1 FROM session_liveness l
+    WHERE l.session_id = NEW.id
+    AND julianday(l.changed_at) >= julianday(NEW.last_activity) - 1.0 / 1440
+)
+BEGIN
+    UPDATE sessions SET
+        is_active = (SELECT is_active FROM session_liveness WHERE session_id = NEW.id),
+        status = CASE WHEN (SELECT is_active FROM session_liveness WHERE session_id = NEW.id) THEN 'active' ELSE 'completed' END
+    WHERE id = NEW.id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS sessions_hook_liveness_update
+AFTER UPDATE OF is_active, status, last_activity ON sessions
+WHEN EXISTS (
+    SELECT 1 FROM session_liveness l
+    WHERE l.session_id = NEW.id
+    AND julianday(l.changed_at) >= julianday(NEW.last_activity) - 1.0 / 1440
+)
+BEGIN
+    UPDATE sessions SET
+        is_active = (SELECT is_active FROM session_liveness WHERE session_id = NEW.id),
+        status = CASE WHEN (SELECT is_active FROM session_liveness WHERE session_id = NEW.id) THEN 'active' ELSE 'completed' END
+    WHERE id = NEW.id;
+END;
+
+-- Federation snapshots table - latest usage aggregates pulled from each team instance
+CREATE TABLE IF NOT EXISTS federation_snapshots (
+    instance_name TEXT PRIMARY KEY,
+    url TEXT NOT NULL,
+    status TEXT NOT NULL, -- ok, error
+    error TEXT,
+    summary TEXT, -- JSON UsageSummary from the last successful poll
+    last_success_at DATETIME,
+    polled_at DATETIME NOT NULL
+);
+
+-- Import dead letters table - JSONL lines an import couldn't read as usual:
+-- lines over the line size limit, decoded as a stream or skipped if even that failed
+CREATE TABLE IF NOT EXISTS import_dead_letters (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    file_path TEXT NOT NULL,
+    byte_offset INTEGER NOT NULL, -- where the line starts in the file
+    byte_size INTEGER NOT NULL,
+    reason TEXT NOT NULL, -- oversized_line
+    outcome TEXT NOT NULL, -- decoded, skipped
+    error TEXT,
+    occurrences INTEGER NOT NULL DEFAULT 1, -- times an import has hit the line
+    first_seen DATETIME DEFAULT CURRENT_TIMESTAMP,
+    last_seen DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(file_path, byte_offset)
+);
+
+-- Session links table - resumed sessions and the session they continue, detected
+-- from a message whose parentUuid is a message of the earlier session
+CREATE TABLE IF NOT EXISTS session_links (
+    session_id TEXT PRIMARY KEY, -- the resumed session
+    parent_session_id TEXT NOT NULL,
+    message_id TEXT NOT NULL, -- first message of session_id continuing the parent
+    parent_message_id TEXT NOT NULL,
+    linked_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_session_links_parent ON session_links(parent_session_id);
+
+-- Session edits table - splits and merges made by hand, applied again after each
+-- import so re-importing a transcript doesn't undo them
+CREATE TABLE IF NOT EXISTS session_edits (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    kind TEXT NOT NULL, -- split or merge
+    session_id TEXT NOT NULL, -- the session messages are moved out of
+    target_session_id TEXT NOT NULL, -- the session they are moved into
+    message_id TEXT, -- split: the first message moved
+    split_at DATETIME, -- split: the timestamp of message_id; later messages move too
+    reason TEXT,
+    edited_by TEXT,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_session_edits_session ON session_edits(session_id);
+CREATE INDEX IF NOT EXISTS idx_messages_parent_uuid ON messages(parent_uuid);
+
+-- Budgets table - daily or monthly cost limits for one project or all of them
+CREATE TABLE IF NOT EXISTS budgets (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    project_name TEXT, -- NULL for all projects
+    period TEXT NOT NULL CHECK (period IN ('daily', 'monthly')),
+    limit_usd REAL NOT NULL,
+    created_at DATETIME NOT NULL
+);
+
+-- Budget alerts table - each threshold a budget crossed, at most once per period
+CREATE TABLE IF NOT EXISTS budget_alerts (
+    budget_id TEXT NOT NULL,
+    period_start DATETIME NOT NULL,
+    threshold INTEGER NOT NULL, -- percent of the limit
+    spend_usd REAL NOT NULL,
+    triggered_at DATETIME NOT NULL,
+    PRIMARY KEY (budget_id, period_start, threshold),
+    FOREIGN KEY (budget_id) REFERENCES budgets(id) ON DELETE CASCADE
+);
+
+-- Webhooks table - URLs sent a JSON payload when the events they subscribe to occur
+CREATE TABLE IF NOT EXISTS webhooks (
+    id TEXT PRIMARY KEY,
+    url TEXT NOT NULL,
+    events TEXT NOT NULL, -- comma-separated event names
+    secret TEXT NOT NULL DEFAULT '', -- signs payloads with HMAC-SHA256 when set
+    description TEXT,
+    enabled BOOLEAN NOT NULL DEFAULT 1,
+    last_status TEXT, -- delivered or failed, after retries
+    last_error TEXT,
+    last_attempt_at DATETIME,
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL
+);
+
+-- Dashboards table - saved layouts of widgets, each charting an API endpoint
+CREATE TABLE IF NOT EXISTS dashboards (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE,
+    description TEXT,
+    widgets TEXT NOT NULL, -- JSON array of widgets
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL
+);
+
+-- Script fields table - custom fields computed by scripts; message_id is empty for session fields
+CREATE TABLE IF NOT EXISTS script_fields (
+    session_id TEXT NOT NULL,
+    message_id TEXT NOT NULL DEFAULT '',
+    name TEXT NOT NULL,
+    value TEXT NOT NULL,
+    script TEXT NOT NULL, -- name of the script that computed the field
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (session_id, message_id, name)
+);
+
+-- Script evaluations table - which version of the session scripts each session was last evaluated against
+CREATE TABLE IF NOT EXISTS script_evaluations (
+    session_id TEXT PRIMARY KEY,
+    scripts_version TEXT NOT NULL, -- hash of the configured session scripts
+    message_count INTEGER NOT NULL DEFAULT 0,
+    matched TEXT NOT NULL DEFAULT '', -- comma-separated notify scripts that were true
+    evaluated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Users table - people API keys are issued to
+CREATE TABLE IF NOT EXISTS users (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE,
+    created_at DATETIME NOT NULL
+);
+
+-- API keys table - keys accepted by the API when auth is enabled; only a hash of each key is kept
+CREATE TABLE IF NOT EXISTS api_keys (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL,
+    name TEXT NOT NULL,
+    prefix TEXT NOT NULL, -- first characters of the key, to tell keys apart
+    key_hash TEXT NOT NULL UNIQUE, -- hex SHA-256 of the key
+    scope TEXT NOT NULL CHECK (scope IN ('read', 'write')),
+    created_at DATETIME NOT NULL,
+    last_used_at DATETIME,
+    revoked_at DATETIME,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_user ON api_keys(user_id);
+
+-- Notification preferences table - how and when each user is told of idle sessions, the
+-- day's cost and errors; zero thresholds fall back to the notifications config
+CREATE TABLE IF NOT EXISTS notification_preferences (
+    user_id TEXT PRIMARY KEY,
+    channels TEXT NOT NULL, -- JSON array of {type, target}
+    events TEXT NOT NULL, -- JSON array of event names; empty for those the config enables
+    daily_cost_threshold REAL NOT NULL DEFAULT 0,
+    idle_minutes INTEGER NOT NULL DEFAULT 0,
+    quiet_hours_start TEXT NOT NULL DEFAULT '', -- HH:MM, in timezone
+    quiet_hours_end TEXT NOT NULL DEFAULT '',
+    timezone TEXT NOT NULL DEFAULT '', -- IANA name; the server's local time when empty
+    updated_at DATETIME NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- User quotas table - soft monthly token and cost quotas for team members sharing a
+-- subscription; crossing them raises warnings but blocks nothing
+CREATE TABLE IF NOT EXISTS user_quotas (
+    user_id TEXT PRIMARY KEY,
+    monthly_tokens INTEGER NOT NULL DEFAULT 0, -- 0 for no token quota
+    monthly_cost_usd REAL NOT NULL DEFAULT 0, -- 0 for no cost quota
+    updated_at DATETIME NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Project owners table - the user the usage of a project's sessions counts against
+CREATE TABLE IF NOT EXISTS project_owners (
+    project_name TEXT PRIMARY KEY, -- sessions.project_name
+    user_id TEXT NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Session owners table - the user a session's usage counts against, over its project's owner
+CREATE TABLE IF NOT EXISTS session_owners (
+    session_id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL,
+    assigned_at DATETIME NOT NULL,
+    FOREIGN KEY (session_id) REFERENCES sessions(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Quota alerts table - each threshold of a user's quota crossed, at most once per month
+CREATE TABLE IF NOT EXISTS quota_alerts (
+    user_id TEXT NOT NULL,
+    period_start DATETIME NOT NULL,
+    metric TEXT NOT NULL CHECK (metric IN ('tokens', 'cost')),
+    threshold INTEGER NOT NULL, -- percent of the quota
+    used REAL NOT NULL,
+    triggered_at DATETIME NOT NULL,
+    PRIMARY KEY (user_id, period_start, metric, threshold),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Export jobs table - heavy exports generated in the background, downloaded through signed
+-- expiring URLs once they succeed and deleted with their files when they expire
+CREATE TABLE IF NOT EXISTS export_jobs (
+    id TEXT PRIMARY KEY,
+    client TEXT NOT NULL, -- key:<api key id> or ip:<address> of whoever started it
+    kind TEXT NOT NULL, -- backup, analytics
+    dataset TEXT, -- analytics dataset
+    format TEXT NOT NULL, -- sqlite, csv, parquet
+    days INTEGER NOT NULL DEFAULT 0, -- analytics rows from this many days back; 0 for all
+    status TEXT NOT NULL DEFAULT 'pending', -- pending, running, succeeded, failed
+    file_name TEXT,
+    size_bytes INTEGER NOT NULL DEFAULT 0,
+    error TEXT,
+    started_at DATETIME,
+    completed_at DATETIME,
+    expires_at DATETIME, -- when the file is deleted
+    created_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_export_jobs_client ON export_jobs(client, created_at);
+
+-- API usage table - requests to this server per UTC hour and route template, for finding
+-- the dashboard widgets and integrations that call it most
+CREATE TABLE IF NOT EXISTS api_usage (
+    hour DATETIME NOT NULL, -- start of the UTC hour
+    method TEXT NOT NULL,
+    route TEXT NOT NULL, -- route template, e.g. /api/v1/sessions/:id
+    requests INTEGER NOT NULL DEFAULT 0,
+    client_errors INTEGER NOT NULL DEFAULT 0, -- 4xx responses
+    server_errors INTEGER NOT NULL DEFAULT 0, -- 5xx responses
+    total_ms REAL NOT NULL DEFAULT 0,
+    max_ms REAL NOT NULL DEFAULT 0,
+    PRIMARY KEY (hour, method, route)
+);
+
+-- Projects table - projects as reported, each made up of the project names sessions were
+-- imported under (its aliases). Each new project name gets a project of its own, which can
+-- be renamed or merged into another.
+CREATE TABLE IF NOT EXISTS projects (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    display_name TEXT NOT NULL,
+    canonical_path TEXT NOT NULL DEFAULT '',
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Project aliases table - the project each imported project name belongs to
+CREATE TABLE IF NOT EXISTS project_aliases (
+    alias TEXT PRIMARY KEY, -- sessions.project_name
+    project_id INTEGER NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_project_aliases_project ON project_aliases(project_id);
+
+CREATE TRIGGER IF NOT EXISTS sessions_project_insert
+AFTER INSERT ON sessions
+WHEN NOT EXISTS (SELECT 1 FROM project_aliases WHERE alias = NEW.project_name)
+BEGIN
+    INSERT INTO projects (display_name, canonical_path) VALUES (NEW.project_name, NEW.project_path);
+    INSERT INTO project_aliases (alias, project_id) VALUES (NEW.project_name, last_insert_rowid());
+END;
+
+CREATE TRIGGER IF NOT EXISTS sessions_project_update
+AFTER UPDATE OF project_name ON sessions
+WHEN NOT EXISTS (SELECT 1 FROM project_aliases WHERE alias = NEW.project_name)
+BEGIN
+    INSERT INTO projects (display_name, canonical_path) VALUES (NEW.project_name, NEW.project_path);
+    INSERT INTO project_aliases (alias, project_id) VALUES (NEW.project_name, last_insert_rowid());
+END;
+
+-- Schema migrations table - versions applied by the migrator on top of this schema
+CREATE TABLE IF NOT EXISTS schema_migrations (
+    version INTEGER PRIMARY KEY,
+    name TEXT NOT NULL,
+    applied_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
 -- Daily metrics view
 CREATE VIEW IF NOT EXISTS daily_metrics AS
 SELECT 
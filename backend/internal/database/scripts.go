@@ -0,0 +1,198 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// MessageHook inspects each imported message before it is stored
+type MessageHook func(message *Message) *MessageHookResult
+
+// MessageHookResult is what a message hook decided for a message
+type MessageHookResult struct {
+	Drop   bool          // skip storing the message
+	Fields []ScriptField // custom fields computed for the message
+	Tags   []SessionTag  // tags for the message's session
+}
+
+// SetMessageHook sets the hook run on every imported message. It must be set
+// before imports start.
+func (r *SessionRepository) SetMessageHook(hook MessageHook) {
+	r.messageHook = hook
+}
+
+// evaluateMessageHook runs the message hook on a message. Messages carrying
+// token usage are never dropped, so spend stays accurate.
+func (r *SessionRepository) evaluateMessageHook(message *Message, hasUsage bool) *MessageHookResult {
+	if r.messageHook == nil {
+		return nil
+	}
+	result := r.messageHook(message)
+	if result != nil && result.Drop && hasUsage {
+		result.Drop = false
+	}
+	return result
+}
+
+// runMessageHook runs the message hook and stores the fields and tags it
+// returned, reporting whether the message should be stored
+func (r *SessionRepository) runMessageHook(tx *sqlx.Tx, message *Message, hasUsage bool) (bool, error) {
+	result := r.evaluateMessageHook(message, hasUsage)
+	if result == nil {
+		return true, nil
+	}
+	if result.Drop {
+		return false, nil
+	}
+	return true, saveScriptResults(tx, result.Fields, result.Tags)
+}
+
+// UpsertImportedMessage runs the message hook on an imported message and
+// stores the message unless the hook drops it, reporting whether it was stored
+func (r *SessionRepository) UpsertImportedMessage(message *Message, hasUsage bool) (bool, error) {
+	stored := false
+	err := r.db.Transaction(func(tx *sqlx.Tx) error {
+		keep, err := r.runMessageHook(tx, message, hasUsage)
+		if err != nil || !keep {
+			return err
+		}
+		stored = true
+		return upsertMessage(tx, message)
+	})
+	return stored, err
+}
+
+// saveMessageHookResults stores the fields and tags of hook results collected
+// during a batch import
+func (r *SessionRepository) saveMessageHookResults(results []*MessageHookResult) error {
+	if len(results) == 0 {
+		return nil
+	}
+	return r.db.WriteOperation(func(tx *sqlx.Tx) error {
+		for _, result := range results {
+			if err := saveScriptResults(tx, result.Fields, result.Tags); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// saveScriptResults stores script fields, replacing earlier values, and adds
+// script tags. Tags a session already carries keep their source.
+func saveScriptResults(tx *sqlx.Tx, fields []ScriptField, tags []SessionTag) error {
+	for _, field := range fields {
+		if _, err := tx.Exec(`
+			INSERT INTO script_fields (session_id, message_id, name, value, script)
+			VALUES (?, ?, ?, ?, ?)
+			ON CONFLICT(session_id, message_id, name) DO UPDATE SET
+				value = excluded.value,
+				script = excluded.script,
+				updated_at = CURRENT_TIMESTAMP
+		`, field.SessionID, field.MessageID, field.Name, field.Value, field.Script); err != nil {
+			return fmt.Errorf("failed to save script field %s: %w", field.Name, err)
+		}
+	}
+	for _, tag := range tags {
+		if _, err := tx.Exec(`
+			INSERT OR IGNORE INTO session_tags (session_id, tag, source, rule)
+			VALUES (?, ?, ?, ?)
+		`, tag.SessionID, tag.Tag, TagSourceScript, tag.Rule); err != nil {
+			return fmt.Errorf("failed to apply tag %s: %w", tag.Tag, err)
+		}
+	}
+	return nil
+}
+
+// GetSessionsPendingScripts returns up to limit sessions that have not been
+// evaluated against the given version of the session scripts or have grown
+// since. If sessionID is set only that session is considered.
+func (r *SessionRepository) GetSessionsPendingScripts(version, sessionID string, limit int) ([]string, error) {
+	var ids []string
+	err := r.db.Select(&ids, `
+		SELECT s.id
+		FROM sessions s
+		LEFT JOIN script_evaluations e ON e.session_id = s.id
+		WHERE (? = '' OR s.id = ?)
+		AND (
+			e.session_id IS NULL
+			OR e.scripts_version != ?
+			OR COALESCE(s.message_count, 0) > e.message_count
+		)
+		ORDER BY s.start_time DESC
+		LIMIT ?
+	`, sessionID, sessionID, version, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find sessions pending scripts: %w", err)
+	}
+	return ids, nil
+}
+
+// GetScriptEvaluation returns the outcome of a session's last evaluation
+// against the session scripts
+func (r *SessionRepository) GetScriptEvaluation(sessionID string) (*ScriptEvaluation, error) {
+	var row struct {
+		ScriptEvaluation
+		Matched string `db:"matched"`
+	}
+	err := r.db.Get(&row, `
+		SELECT session_id, scripts_version, message_count, matched
+		FROM script_evaluations WHERE session_id = ?
+	`, sessionID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("script evaluation not found: %s", sessionID)
+		}
+		return nil, fmt.Errorf("failed to get script evaluation: %w", err)
+	}
+	evaluation := row.ScriptEvaluation
+	if row.Matched != "" {
+		evaluation.Matched = strings.Split(row.Matched, ",")
+	}
+	return &evaluation, nil
+}
+
+// ApplySessionScripts replaces a session's script fields with fields, adds
+// tags and records the evaluation. Message fields are left as they are.
+func (r *SessionRepository) ApplySessionScripts(evaluation *ScriptEvaluation, fields []ScriptField, tags []SessionTag) error {
+	return r.db.WriteOperation(func(tx *sqlx.Tx) error {
+		if _, err := tx.Exec(`
+			DELETE FROM script_fields WHERE session_id = ? AND message_id = ''
+		`, evaluation.SessionID); err != nil {
+			return fmt.Errorf("failed to clear script fields: %w", err)
+		}
+		if err := saveScriptResults(tx, fields, tags); err != nil {
+			return err
+		}
+
+		_, err := tx.Exec(`
+			INSERT INTO script_evaluations (session_id, scripts_version, message_count, matched)
+			VALUES (?, ?, ?, ?)
+			ON CONFLICT(session_id) DO UPDATE SET
+				scripts_version = excluded.scripts_version,
+				message_count = excluded.message_count,
+				matched = excluded.matched,
+				evaluated_at = CURRENT_TIMESTAMP
+		`, evaluation.SessionID, evaluation.ScriptsVersion, evaluation.MessageCount, strings.Join(evaluation.Matched, ","))
+		if err != nil {
+			return fmt.Errorf("failed to record script evaluation: %w", err)
+		}
+		return nil
+	})
+}
+
+// GetScriptFields returns the custom fields scripts computed for a session
+// and its messages, session fields first
+func (r *SessionRepository) GetScriptFields(sessionID string) ([]ScriptField, error) {
+	fields := []ScriptField{}
+	err := r.db.Select(&fields, `
+		SELECT * FROM script_fields WHERE session_id = ? ORDER BY message_id ASC, name ASC
+	`, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get script fields: %w", err)
+	}
+	return fields, nil
+}
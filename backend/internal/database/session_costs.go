@@ -0,0 +1,31 @@
+package database
+
+import (
+	"fmt"
+	"time"
+)
+
+// GetSessionCosts returns each session's token usage from messages sent in
+// [from, to), so usage is charged to the period it happened in even when a
+// session spans several
+func (r *SessionRepository) GetSessionCosts(from, to time.Time) ([]SessionCost, error) {
+	costs := []SessionCost{}
+	err := r.db.Select(&costs, `
+		SELECT
+			m.session_id,
+			s.project_name,
+			COUNT(DISTINCT m.id) AS messages,
+			COALESCE(SUM(tu.total_tokens), 0) AS tokens,
+			COALESCE(SUM(tu.estimated_cost), 0.0) AS cost_usd
+		FROM token_usage tu
+		JOIN messages m ON m.id = tu.message_id
+		JOIN sessions s ON s.id = m.session_id
+		WHERE m.timestamp >= ? AND m.timestamp < ?
+		GROUP BY m.session_id, s.project_name
+		ORDER BY m.session_id ASC
+	`, from.UTC(), to.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session costs: %w", err)
+	}
+	return costs, nil
+}
@@ -0,0 +1,45 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSessionRepository_GetSessionCosts(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewSessionRepository(db, logger)
+
+	start := time.Date(2026, 8, 31, 12, 0, 0, 0, time.UTC)
+	if err := repo.UpsertSession(&Session{
+		ID:           "spanning",
+		ProjectPath:  "/test/project",
+		ProjectName:  "project",
+		StartTime:    start,
+		LastActivity: start.Add(48 * time.Hour),
+		Status:       "completed",
+	}); err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+
+	// One message in August and two in September
+	for i, ts := range []time.Time{start, start.Add(24 * time.Hour), start.Add(36 * time.Hour)} {
+		id := string(rune('a' + i))
+		if err := repo.UpsertMessage(&Message{ID: id, SessionID: "spanning", Role: "assistant", Timestamp: ts}); err != nil {
+			t.Fatalf("Failed to create message: %v", err)
+		}
+		if err := repo.UpsertTokenUsage(&TokenUsage{MessageID: id, SessionID: "spanning", TotalTokens: 100, EstimatedCost: 0.5}); err != nil {
+			t.Fatalf("Failed to create token usage: %v", err)
+		}
+	}
+
+	september := time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)
+	costs, err := repo.GetSessionCosts(september, september.AddDate(0, 1, 0))
+	if err != nil {
+		t.Fatalf("Failed to get session costs: %v", err)
+	}
+	if len(costs) != 1 || costs[0].Messages != 2 || costs[0].Tokens != 200 || costs[0].CostUSD != 1.0 {
+		t.Errorf("Expected only September usage, got %+v", costs)
+	}
+}
@@ -0,0 +1,411 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// Kinds of session edit
+const (
+	SessionEditSplit = "split"
+	SessionEditMerge = "merge"
+)
+
+// ErrInvalidSessionEdit is returned for a split or merge that can't be made,
+// such as splitting at a session's first message
+var ErrInvalidSessionEdit = errors.New("invalid session edit")
+
+// editedMessages selects the messages an edit moves, given its ID: every
+// message of the session for a merge, and those from the split message on
+// for a split
+const editedMessages = `
+	SELECT m.id FROM messages m
+	JOIN session_edits e ON e.id = ?
+	WHERE m.session_id = e.session_id
+	AND (e.kind = 'merge' OR m.timestamp > e.split_at OR (m.timestamp = e.split_at AND m.id >= e.message_id))`
+
+// editedMessageTables hold rows of a message that move with it
+var editedMessageTables = []string{
+	"token_usage",
+	"tool_results",
+	"tool_calls",
+	"commands",
+	"knowledge_sources",
+	"script_fields",
+	"session_review_comments",
+}
+
+// mergedSessionTables hold per-session data a merge carries over to the
+// session merged into, unless it has its own
+var mergedSessionTables = []string{
+	"session_tags",
+	"session_notes",
+	"session_feedback",
+	"session_reviews",
+	"session_review_comments",
+	"session_group_members",
+	"session_environments",
+	"chat_sessions",
+	"prompt_template_usage",
+	"activity_log",
+	"script_fields",
+}
+
+// editedSessionCaches hold data derived from a session's messages, cleared
+// for the sessions an edit changes so it is computed again
+var editedSessionCaches = []string{
+	"tool_call_scans",
+	"session_scores",
+	"prompt_signatures",
+	"tag_rule_evaluations",
+	"script_evaluations",
+	"message_hashes",
+	"session_hashes",
+	"session_links",
+}
+
+// SplitSession splits a session into two at a message: the message and every
+// later one move to a new session, which carries on from the original if it
+// was active. It returns the edit, whose TargetSessionID is the new session.
+func (r *SessionRepository) SplitSession(sessionID, messageID, reason, editedBy string) (*SessionEdit, error) {
+	if _, err := r.GetSessionByID(sessionID); err != nil {
+		return nil, err
+	}
+	if err := r.CheckNotOnLegalHold(sessionID); err != nil {
+		return nil, err
+	}
+
+	var message Message
+	err := r.db.Get(&message, `SELECT `+messageColumns+` FROM messages WHERE id = ? AND session_id = ?`, messageID, sessionID)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("message not found: %s", messageID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get message: %w", err)
+	}
+	var first bool
+	err = r.db.Get(&first, `
+		SELECT NOT EXISTS(
+			SELECT 1 FROM messages m JOIN messages s ON s.id = ?
+			WHERE m.session_id = s.session_id
+			AND (m.timestamp < s.timestamp OR (m.timestamp = s.timestamp AND m.id < s.id))
+		)
+	`, messageID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check message position: %w", err)
+	}
+	if first {
+		return nil, fmt.Errorf("%w: message %s is the first of the session", ErrInvalidSessionEdit, messageID)
+	}
+
+	// split_at is copied from the message so it compares exactly as stored
+	return r.addSessionEdit(`
+		INSERT INTO session_edits (kind, session_id, target_session_id, message_id, split_at, reason, edited_by)
+		SELECT 'split', session_id, ?, id, timestamp, NULLIF(?, ''), NULLIF(?, '')
+		FROM messages WHERE id = ?
+	`, sessionID, uuid.NewString(), reason, editedBy, messageID)
+}
+
+// MergeSessions moves every message of sourceID into targetID, along with
+// its tags, notes and reviews where targetID has none, and removes sourceID,
+// for a task that was split over two sessions, such as when Claude crashed
+// and restarted. It returns the edit.
+func (r *SessionRepository) MergeSessions(targetID, sourceID, reason, editedBy string) (*SessionEdit, error) {
+	if targetID == sourceID {
+		return nil, fmt.Errorf("%w: a session can't be merged into itself", ErrInvalidSessionEdit)
+	}
+	for _, sessionID := range []string{targetID, sourceID} {
+		if _, err := r.GetSessionByID(sessionID); err != nil {
+			return nil, err
+		}
+		if err := r.CheckNotOnLegalHold(sessionID); err != nil {
+			return nil, err
+		}
+	}
+
+	return r.addSessionEdit(`
+		INSERT INTO session_edits (kind, session_id, target_session_id, reason, edited_by)
+		VALUES ('merge', ?, ?, NULLIF(?, ''), NULLIF(?, ''))
+	`, sourceID, sourceID, targetID, reason, editedBy)
+}
+
+// addSessionEdit records an edit of sessionID with insert and applies it
+func (r *SessionRepository) addSessionEdit(insert, sessionID string, args ...interface{}) (*SessionEdit, error) {
+	var edit SessionEdit
+	err := r.db.WriteOperation(func(tx *sqlx.Tx) error {
+		result, err := tx.Exec(insert, args...)
+		if err != nil {
+			return fmt.Errorf("failed to record session edit: %w", err)
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+		if err := applySessionEdits(tx, []string{sessionID}); err != nil {
+			return err
+		}
+		return tx.Get(&edit, `SELECT * FROM session_edits WHERE id = ?`, id)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &edit, nil
+}
+
+// GetSessionEdits returns the edits that moved messages out of or into a
+// session, or every edit when sessionID is empty, most recent first
+func (r *SessionRepository) GetSessionEdits(sessionID string) ([]SessionEdit, error) {
+	edits := []SessionEdit{}
+	err := r.db.Select(&edits, `
+		SELECT * FROM session_edits
+		WHERE ? = '' OR session_id = ? OR target_session_id = ?
+		ORDER BY id DESC
+	`, sessionID, sessionID, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session edits: %w", err)
+	}
+	return edits, nil
+}
+
+// ReapplySessionEdits applies the edits of the given sessions again, once
+// they have been imported. An import writes messages back to the session
+// their transcript names, so without this a split or merge would be undone
+// whenever its sessions' transcripts change.
+func (r *SessionRepository) ReapplySessionEdits(sessionIDs []string) error {
+	if len(sessionIDs) == 0 {
+		return nil
+	}
+	err := r.db.WriteOperation(func(tx *sqlx.Tx) error {
+		return applySessionEdits(tx, sessionIDs)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to reapply session edits: %w", err)
+	}
+	return nil
+}
+
+// applySessionEdits applies the edits of the given sessions, and of the
+// sessions those edits moved messages into, in the order they were made.
+// Applying an edit again moves only messages imported since.
+func applySessionEdits(tx *sqlx.Tx, sessionIDs []string) error {
+	edits := make(map[int64]SessionEdit)
+	seen := make(map[string]bool)
+	for frontier := sessionIDs; len(frontier) > 0; {
+		for _, sessionID := range frontier {
+			seen[sessionID] = true
+		}
+		query, args, err := sqlx.In(`SELECT * FROM session_edits WHERE session_id IN (?)`, frontier)
+		if err != nil {
+			return err
+		}
+		var found []SessionEdit
+		if err := tx.Select(&found, tx.Rebind(query), args...); err != nil {
+			return fmt.Errorf("failed to get session edits: %w", err)
+		}
+		frontier = nil
+		for _, edit := range found {
+			edits[edit.ID] = edit
+			if !seen[edit.TargetSessionID] {
+				seen[edit.TargetSessionID] = true
+				frontier = append(frontier, edit.TargetSessionID)
+			}
+		}
+	}
+	if len(edits) == 0 {
+		return nil
+	}
+
+	ordered := make([]SessionEdit, 0, len(edits))
+	for _, edit := range edits {
+		ordered = append(ordered, edit)
+	}
+	sort.Slice(ordered, func(i, j int) bool { return ordered[i].ID < ordered[j].ID })
+
+	changed := make(map[string]bool)
+	for _, edit := range ordered {
+		applied, err := applySessionEdit(tx, edit)
+		if err != nil {
+			return fmt.Errorf("failed to apply %s of session %s: %w", edit.Kind, edit.SessionID, err)
+		}
+		if applied {
+			changed[edit.SessionID] = true
+			changed[edit.TargetSessionID] = true
+		}
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+
+	ids := make([]string, 0, len(changed))
+	for sessionID := range changed {
+		ids = append(ids, sessionID)
+	}
+	return recalculateEditedSessions(tx, ids)
+}
+
+// applySessionEdit moves the messages an edit selects and reports whether
+// anything changed. A merge whose sessions are gone, or whose session has
+// been put under legal hold since, is skipped.
+func applySessionEdit(tx *sqlx.Tx, edit SessionEdit) (bool, error) {
+	var sourceExists, targetExists, held bool
+	err := tx.QueryRowx(`
+		SELECT
+			EXISTS(SELECT 1 FROM sessions WHERE id = ?),
+			EXISTS(SELECT 1 FROM sessions WHERE id = ?),
+			EXISTS(SELECT 1 FROM legal_holds WHERE session_id = ? AND released_at IS NULL)
+	`, edit.SessionID, edit.TargetSessionID, edit.SessionID).Scan(&sourceExists, &targetExists, &held)
+	if err != nil {
+		return false, err
+	}
+	if !sourceExists || held {
+		return false, nil
+	}
+
+	if !targetExists {
+		if edit.Kind == SessionEditMerge {
+			return false, nil
+		}
+		// The split-off session is created, or created again after an
+		// import recreated what it was split from
+		_, err := tx.Exec(`
+			INSERT INTO sessions (
+				id, project_path, project_name, file_path, git_branch, git_worktree, git_remote,
+				start_time, last_activity, is_active, status, model, message_count, duration_seconds
+			)
+			SELECT ?, project_path, project_name, file_path, git_branch, git_worktree, git_remote,
+				start_time, last_activity, FALSE, 'completed', model, 0, 0
+			FROM sessions WHERE id = ?
+		`, edit.TargetSessionID, edit.SessionID)
+		if err != nil {
+			return false, fmt.Errorf("failed to create split session: %w", err)
+		}
+	}
+
+	// Rows belonging to the messages move before the messages themselves
+	for _, table := range editedMessageTables {
+		_, err := tx.Exec(`UPDATE `+table+` SET session_id = ? WHERE message_id IN (`+editedMessages+`)`, edit.TargetSessionID, edit.ID)
+		if err != nil {
+			return false, fmt.Errorf("failed to move %s: %w", table, err)
+		}
+	}
+	if edit.Kind == SessionEditSplit {
+		_, err := tx.Exec(`
+			UPDATE activity_log SET session_id = ?
+			WHERE session_id = ? AND timestamp >= (SELECT split_at FROM session_edits WHERE id = ?)
+		`, edit.TargetSessionID, edit.SessionID, edit.ID)
+		if err != nil {
+			return false, fmt.Errorf("failed to move activity: %w", err)
+		}
+	}
+	result, err := tx.Exec(`UPDATE messages SET session_id = ? WHERE id IN (`+editedMessages+`)`, edit.TargetSessionID, edit.ID)
+	if err != nil {
+		return false, fmt.Errorf("failed to move messages: %w", err)
+	}
+	moved, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	if edit.Kind == SessionEditMerge {
+		return true, mergeSessionInto(tx, edit.SessionID, edit.TargetSessionID)
+	}
+	if moved > 0 {
+		// The messages moved are the latest, so whether the session is
+		// active moves with them
+		_, err := tx.Exec(`
+			UPDATE sessions SET
+				is_active = (SELECT is_active FROM sessions WHERE id = ?),
+				status = (SELECT status FROM sessions WHERE id = ?)
+			WHERE id = ?
+		`, edit.SessionID, edit.SessionID, edit.TargetSessionID)
+		if err != nil {
+			return false, err
+		}
+		if _, err := tx.Exec(`UPDATE sessions SET is_active = FALSE, status = 'completed' WHERE id = ?`, edit.SessionID); err != nil {
+			return false, err
+		}
+	}
+	return moved > 0, nil
+}
+
+// mergeSessionInto carries sourceID's per-session data over to targetID,
+// keeping targetID's where both have some, and deletes sourceID
+func mergeSessionInto(tx *sqlx.Tx, sourceID, targetID string) error {
+	for _, table := range mergedSessionTables {
+		// Rows that would clash with targetID's stay and are deleted below
+		if _, err := tx.Exec(`UPDATE OR IGNORE `+table+` SET session_id = ? WHERE session_id = ?`, targetID, sourceID); err != nil {
+			return fmt.Errorf("failed to merge %s: %w", table, err)
+		}
+	}
+	_, err := tx.Exec(`
+		UPDATE sessions SET
+			is_active = is_active OR (SELECT is_active FROM sessions WHERE id = ?),
+			status = CASE WHEN is_active OR (SELECT is_active FROM sessions WHERE id = ?) THEN 'active' ELSE 'completed' END
+		WHERE id = ?
+	`, sourceID, sourceID, targetID)
+	if err != nil {
+		return err
+	}
+	deleted, err := deleteSessions(tx, []string{sourceID}, "")
+	if err != nil || len(deleted) > 0 {
+		return err
+	}
+	// A hold placed since the merge was checked
+	return checkNotOnLegalHold(tx, sourceID)
+}
+
+// recalculateEditedSessions clears what was derived from the messages of
+// sessions an edit changed, recalculates their times and message counts and
+// links them to the sessions they resume again
+func recalculateEditedSessions(tx *sqlx.Tx, sessionIDs []string) error {
+	statements := []string{`DELETE FROM session_links WHERE parent_session_id IN (?)`}
+	for _, table := range editedSessionCaches {
+		statements = append(statements, `DELETE FROM `+table+` WHERE session_id IN (?)`)
+	}
+	for _, statement := range statements {
+		query, args, err := sqlx.In(statement, sessionIDs)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(tx.Rebind(query), args...); err != nil {
+			return fmt.Errorf("failed to run %q: %w", statement, err)
+		}
+	}
+
+	for _, sessionID := range sessionIDs {
+		var start, end time.Time
+		err := tx.Get(&start, `SELECT timestamp FROM messages WHERE session_id = ? ORDER BY timestamp ASC LIMIT 1`, sessionID)
+		if err == sql.ErrNoRows {
+			continue // merged away
+		}
+		if err == nil {
+			err = tx.Get(&end, `SELECT timestamp FROM messages WHERE session_id = ? ORDER BY timestamp DESC LIMIT 1`, sessionID)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get times of session %s: %w", sessionID, err)
+		}
+		_, err = tx.Exec(`
+			UPDATE sessions SET
+				start_time = ?,
+				last_activity = ?,
+				duration_seconds = ?,
+				message_count = (SELECT COUNT(*) FROM messages WHERE session_id = ?),
+				updated_at = CURRENT_TIMESTAMP
+			WHERE id = ?
+		`, start, end, int64(end.Sub(start).Seconds()), sessionID, sessionID)
+		if err != nil {
+			return fmt.Errorf("failed to recalculate session %s: %w", sessionID, err)
+		}
+	}
+
+	if _, err := linkResumedSessions(tx, sessionIDs); err != nil {
+		return fmt.Errorf("failed to link resumed sessions: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestSessionRepository_SplitAndMergeSessions(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewSessionRepository(db, logger)
+	start := time.Now().UTC().Add(-time.Hour).Truncate(time.Second)
+	addMessage := func(sessionID, id string, at time.Duration) {
+		t.Helper()
+		if err := repo.UpsertMessage(&Message{ID: id, SessionID: sessionID, Role: "assistant", Content: "hi", Timestamp: start.Add(at)}); err != nil {
+			t.Fatalf("Failed to create message: %v", err)
+		}
+		if err := repo.UpsertTokenUsage(&TokenUsage{MessageID: id, SessionID: sessionID, TotalTokens: 10, EstimatedCost: 0.5}); err != nil {
+			t.Fatalf("Failed to create token usage: %v", err)
+		}
+	}
+	for _, id := range []string{"s1", "s2"} {
+		if err := repo.UpsertSession(&Session{ID: id, ProjectPath: "/work/app", ProjectName: "app", StartTime: start, LastActivity: start, IsActive: id == "s1", Status: "active", MessageCount: 4}); err != nil {
+			t.Fatalf("Failed to create session: %v", err)
+		}
+	}
+	for i := 0; i < 4; i++ {
+		addMessage("s1", fmt.Sprintf("m%d", i+1), time.Duration(i)*time.Minute)
+	}
+	addMessage("s2", "n1", 10*time.Minute)
+
+	if _, err := repo.SplitSession("s1", "m1", "", ""); !errors.Is(err, ErrInvalidSessionEdit) {
+		t.Errorf("Expected splitting at the first message to fail, got %v", err)
+	}
+	if _, err := repo.SplitSession("s1", "n1", "", ""); err == nil {
+		t.Error("Expected splitting at another session's message to fail")
+	}
+
+	split, err := repo.SplitSession("s1", "m3", "two tasks", "alice")
+	if err != nil {
+		t.Fatalf("Failed to split session: %v", err)
+	}
+	head, err := repo.GetSessionByID("s1")
+	if err != nil {
+		t.Fatalf("Failed to get split session: %v", err)
+	}
+	tail, err := repo.GetSessionByID(split.TargetSessionID)
+	if err != nil {
+		t.Fatalf("Failed to get new session: %v", err)
+	}
+	if head.MessageCount != 2 || tail.MessageCount != 2 {
+		t.Errorf("Expected 2 messages each, got %d and %d", head.MessageCount, tail.MessageCount)
+	}
+	if !tail.StartTime.Equal(start.Add(2*time.Minute)) || !head.LastActivity.Equal(start.Add(time.Minute)) {
+		t.Errorf("Expected times recalculated, got head ending %v and tail starting %v", head.LastActivity, tail.StartTime)
+	}
+	if head.IsActive || !tail.IsActive {
+		t.Errorf("Expected the new session to carry on as active, got %v and %v", head.IsActive, tail.IsActive)
+	}
+	if tail.TotalTokens != 20 {
+		t.Errorf("Expected the tail's token usage to move with it, got %d", tail.TotalTokens)
+	}
+
+	// Importing the transcript again writes the messages back to s1, and a
+	// new message arrives after the split
+	addMessage("s1", "m3", 2*time.Minute)
+	addMessage("s1", "m5", 4*time.Minute)
+	if err := repo.ReapplySessionEdits([]string{"s1"}); err != nil {
+		t.Fatalf("Failed to reapply session edits: %v", err)
+	}
+	head, _ = repo.GetSessionByID("s1")
+	tail, _ = repo.GetSessionByID(split.TargetSessionID)
+	if head.MessageCount != 2 || tail.MessageCount != 3 {
+		t.Errorf("Expected the split to survive re-import, got %d and %d messages", head.MessageCount, tail.MessageCount)
+	}
+
+	merge, err := repo.MergeSessions(split.TargetSessionID, "s2", "", "")
+	if err != nil {
+		t.Fatalf("Failed to merge sessions: %v", err)
+	}
+	if merge.Kind != SessionEditMerge {
+		t.Errorf("Expected a merge, got %s", merge.Kind)
+	}
+	if _, err := repo.GetSessionByID("s2"); err == nil {
+		t.Error("Expected the merged session to be removed")
+	}
+	merged, _ := repo.GetSessionByID(split.TargetSessionID)
+	if merged.MessageCount != 4 || !merged.LastActivity.Equal(start.Add(10*time.Minute)) {
+		t.Errorf("Expected the merged session to hold 4 messages until the last, got %d until %v", merged.MessageCount, merged.LastActivity)
+	}
+
+	// s2's transcript imported again
+	if err := repo.UpsertSession(&Session{ID: "s2", ProjectPath: "/work/app", ProjectName: "app", StartTime: start, LastActivity: start, Status: "completed"}); err != nil {
+		t.Fatalf("Failed to recreate session: %v", err)
+	}
+	addMessage("s2", "n1", 10*time.Minute)
+	if err := repo.ReapplySessionEdits([]string{"s2"}); err != nil {
+		t.Fatalf("Failed to reapply session edits: %v", err)
+	}
+	if _, err := repo.GetSessionByID("s2"); err == nil {
+		t.Error("Expected the merge to survive re-import")
+	}
+
+	edits, err := repo.GetSessionEdits(split.TargetSessionID)
+	if err != nil || len(edits) != 2 {
+		t.Errorf("Expected both edits, got %d (%v)", len(edits), err)
+	}
+	if _, err := repo.MergeSessions("s1", "s1", "", ""); !errors.Is(err, ErrInvalidSessionEdit) {
+		t.Errorf("Expected merging a session into itself to fail, got %v", err)
+	}
+}
@@ -0,0 +1,122 @@
+package database
+
+import (
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// maxLineageDepth bounds how far a resume chain is followed either way
+const maxLineageDepth = 100
+
+// LinkResumedSessions records which of the given sessions resume an earlier
+// session, or are resumed by a later one. Claude writes a resumed
+// conversation under a new session ID whose first message's parentUuid is
+// the last message of the session it continues. The parent must have started
+// before the child, which keeps chains acyclic. It returns the number of new
+// links.
+func (r *SessionRepository) LinkResumedSessions(sessionIDs []string) (int, error) {
+	if len(sessionIDs) == 0 {
+		return 0, nil
+	}
+
+	var linked int64
+	err := r.db.WriteOperation(func(tx *sqlx.Tx) error {
+		var err error
+		linked, err = linkResumedSessions(tx, sessionIDs)
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to link resumed sessions: %w", err)
+	}
+	return int(linked), nil
+}
+
+// linkResumedSessions links the given sessions, or every session when
+// sessionIDs is nil
+func linkResumedSessions(tx *sqlx.Tx, sessionIDs []string) (int64, error) {
+	query := `
+		INSERT OR IGNORE INTO session_links (session_id, parent_session_id, message_id, parent_message_id)
+		SELECT m.session_id, p.session_id, m.id, p.id
+		FROM messages m
+		JOIN messages p ON p.id = m.parent_uuid
+		JOIN sessions cs ON cs.id = m.session_id
+		JOIN sessions ps ON ps.id = p.session_id
+		WHERE p.session_id != m.session_id
+		AND ps.start_time < cs.start_time`
+	var args []interface{}
+	if sessionIDs != nil {
+		var err error
+		query, args, err = sqlx.In(query+`
+		AND (m.session_id IN (?) OR p.session_id IN (?))`, sessionIDs, sessionIDs)
+		if err != nil {
+			return 0, err
+		}
+	}
+	// The earliest continuing message wins
+	query += `
+		ORDER BY m.timestamp ASC`
+
+	result, err := tx.Exec(query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// GetSessionLineage returns the resume chain through a session: its
+// ancestors oldest first, the session itself, then the sessions resumed from
+// it. It returns nil when the session was neither resumed nor resumes another.
+func (r *SessionRepository) GetSessionLineage(sessionID string) (*SessionLineage, error) {
+	var chain []LineageEntry
+	err := r.db.Select(&chain, `
+		WITH RECURSIVE
+		up(id, depth) AS (
+			SELECT ?, 0
+			UNION
+			SELECT l.parent_session_id, up.depth - 1
+			FROM session_links l JOIN up ON l.session_id = up.id
+			WHERE up.depth > ?
+		),
+		down(id, depth) AS (
+			SELECT ?, 0
+			UNION
+			SELECT l.session_id, down.depth + 1
+			FROM session_links l JOIN down ON l.parent_session_id = down.id
+			WHERE down.depth < ?
+		),
+		chain(id, depth) AS (
+			SELECT id, depth FROM up
+			UNION
+			SELECT id, depth FROM down
+		)
+		SELECT
+			s.id AS session_id,
+			l.parent_session_id,
+			c.depth,
+			s.start_time,
+			s.last_activity,
+			s.message_count
+		FROM chain c
+		JOIN sessions s ON s.id = c.id
+		LEFT JOIN session_links l ON l.session_id = s.id
+		ORDER BY c.depth, s.start_time
+	`, sessionID, -maxLineageDepth, sessionID, maxLineageDepth)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session lineage: %w", err)
+	}
+	if len(chain) <= 1 {
+		return nil, nil
+	}
+
+	lineage := &SessionLineage{ChildSessionIDs: []string{}, Chain: chain}
+	for _, entry := range chain {
+		if entry.SessionID == sessionID {
+			lineage.ParentSessionID = entry.ParentSessionID
+		}
+		if entry.ParentSessionID != nil && *entry.ParentSessionID == sessionID {
+			lineage.ChildSessionIDs = append(lineage.ChildSessionIDs, entry.SessionID)
+		}
+	}
+	return lineage, nil
+}
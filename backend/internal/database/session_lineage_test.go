@@ -0,0 +1,79 @@
+package database
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSessionLineage_LinksResumedSessions(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewSessionRepository(db, logger)
+	dir := t.TempDir()
+	writeSession := func(sessionID, parentUUID, hour string, uuids ...string) string {
+		var lines []string
+		parent := "null"
+		if parentUUID != "" {
+			parent = `"` + parentUUID + `"`
+		}
+		for n, uuid := range uuids {
+			lines = append(lines, fmt.Sprintf(`{"parentUuid":%s,"cwd":"/work/app","sessionId":"%s","type":"user","message":{"role":"user","content":"hi"},"uuid":"%s","timestamp":"2025-01-01T%s:0%d:00Z"}`,
+				parent, sessionID, uuid, hour, n))
+			parent = `"` + uuid + `"`
+		}
+		path := filepath.Join(dir, sessionID+".jsonl")
+		if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+			t.Fatalf("Failed to write transcript: %v", err)
+		}
+		return path
+	}
+
+	// The resumed sessions are imported before the session they continue
+	paths := []string{
+		writeSession("third", "b2", "12", "c1"),
+		writeSession("second", "a2", "11", "b1", "b2"),
+		writeSession("first", "", "10", "a1", "a2"),
+		writeSession("branch", "a2", "13", "d1"),
+	}
+	importer := NewImporter(repo, logger)
+	for _, path := range paths {
+		if _, _, err := importer.ImportJSONLFile(path, ProjectInfo{}); err != nil {
+			t.Fatalf("Failed to import %s: %v", path, err)
+		}
+	}
+
+	lineage, err := repo.GetSessionLineage("second")
+	if err != nil || lineage == nil {
+		t.Fatalf("Expected a lineage for the resumed session, got %v (%v)", lineage, err)
+	}
+	if lineage.ParentSessionID == nil || *lineage.ParentSessionID != "first" {
+		t.Errorf("Expected parent first, got %v", lineage.ParentSessionID)
+	}
+	if len(lineage.ChildSessionIDs) != 1 || lineage.ChildSessionIDs[0] != "third" {
+		t.Errorf("Expected child third, got %v", lineage.ChildSessionIDs)
+	}
+	var chain []string
+	for _, entry := range lineage.Chain {
+		chain = append(chain, fmt.Sprintf("%s:%d", entry.SessionID, entry.Depth))
+	}
+	if got := strings.Join(chain, ","); got != "first:-1,second:0,third:1" {
+		t.Errorf("Unexpected chain %s", got)
+	}
+
+	root, _ := repo.GetSessionLineage("first")
+	if root == nil || root.ParentSessionID != nil || strings.Join(root.ChildSessionIDs, ",") != "second,branch" {
+		t.Errorf("Expected first to be resumed by second and branch, got %+v", root)
+	}
+
+	// Sessions that were never resumed have no lineage
+	if _, _, err := importer.ImportJSONLFile(writeSession("alone", "", "14", "e1"), ProjectInfo{}); err != nil {
+		t.Fatalf("Failed to import: %v", err)
+	}
+	if lineage, err := repo.GetSessionLineage("alone"); err != nil || lineage != nil {
+		t.Errorf("Expected no lineage, got %+v (%v)", lineage, err)
+	}
+}
@@ -14,8 +14,9 @@ import (
 
 // SessionRepository provides database operations for sessions
 type SessionRepository struct {
-	db     *Database
-	logger *logrus.Logger
+	db          *Database
+	logger      *logrus.Logger
+	messageHook MessageHook
 }
 
 // GetDB returns the underlying database connection
@@ -34,7 +35,7 @@ func NewSessionRepository(db *Database, logger *logrus.Logger) *SessionRepositor
 // GetAllSessions returns all sessions with summary information
 func (r *SessionRepository) GetAllSessions() ([]*SessionSummary, error) {
 	var sessions []*SessionSummary
-	err := r.db.Select(&sessions, "SELECT * FROM session_summary ORDER BY last_activity DESC")
+	err := r.db.Select(&sessions, "SELECT * FROM session_summaries ORDER BY last_activity DESC")
 	if err != nil {
 		return nil, fmt.Errorf("failed to get all sessions: %w", err)
 	}
@@ -44,7 +45,7 @@ func (r *SessionRepository) GetAllSessions() ([]*SessionSummary, error) {
 // GetSessionByID returns a specific session by ID
 func (r *SessionRepository) GetSessionByID(sessionID string) (*SessionSummary, error) {
 	var session SessionSummary
-	err := r.db.Get(&session, "SELECT * FROM session_summary WHERE id = ?", sessionID)
+	err := r.db.Get(&session, "SELECT * FROM session_summaries WHERE id = ?", sessionID)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("session not found: %s", sessionID)
@@ -54,22 +55,23 @@ func (r *SessionRepository) GetSessionByID(sessionID string) (*SessionSummary, e
 	return &session, nil
 }
 
-// GetActiveSessions returns currently active sessions
+// GetActiveSessions returns currently active sessions that aren't archived
 func (r *SessionRepository) GetActiveSessions() ([]*SessionSummary, error) {
 	var sessions []*SessionSummary
 	err := r.db.Select(&sessions,
-		"SELECT * FROM session_summary WHERE is_active = true ORDER BY last_activity DESC")
+		"SELECT * FROM session_summaries WHERE is_active = true AND archived_at IS NULL ORDER BY last_activity DESC")
 	if err != nil {
 		return nil, fmt.Errorf("failed to get active sessions: %w", err)
 	}
 	return sessions, nil
 }
 
-// GetRecentSessions returns the N most recent sessions
-func (r *SessionRepository) GetRecentSessions(limit int) ([]*SessionSummary, error) {
+// GetRecentSessions returns the N most recent sessions, leaving out archived
+// sessions unless includeArchived is set
+func (r *SessionRepository) GetRecentSessions(limit int, includeArchived bool) ([]*SessionSummary, error) {
 	var sessions []*SessionSummary
 	err := r.db.Select(&sessions,
-		"SELECT * FROM session_summary ORDER BY last_activity DESC LIMIT ?", limit)
+		"SELECT * FROM session_summaries WHERE (? OR archived_at IS NULL) ORDER BY last_activity DESC LIMIT ?", includeArchived, limit)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get recent sessions: %w", err)
 	}
@@ -82,7 +84,7 @@ func (r *SessionRepository) SearchSessions(query string) ([]*SessionSummary, err
 	var sessions []*SessionSummary
 
 	searchSQL := `
-		SELECT DISTINCT s.* FROM session_summary s
+		SELECT DISTINCT s.* FROM session_summaries s
 		LEFT JOIN messages m ON s.id = m.session_id
 		WHERE LOWER(s.project_name) LIKE ? 
 		   OR LOWER(m.content) LIKE ?
@@ -99,9 +101,9 @@ func (r *SessionRepository) SearchSessions(query string) ([]*SessionSummary, err
 }
 
 // GetTotalSessions returns the total number of sessions
-func (r *SessionRepository) GetTotalSessions() (int, error) {
+func (r *SessionRepository) GetTotalSessions(includeArchived bool) (int, error) {
 	var count int
-	err := r.db.Get(&count, "SELECT COUNT(*) FROM sessions")
+	err := r.db.Get(&count, "SELECT COUNT(*) FROM sessions WHERE (? OR archived_at IS NULL)", includeArchived)
 	if err != nil {
 		return 0, fmt.Errorf("failed to get total sessions: %w", err)
 	}
@@ -109,9 +111,9 @@ func (r *SessionRepository) GetTotalSessions() (int, error) {
 }
 
 // GetActiveSessionsCount returns the number of active sessions
-func (r *SessionRepository) GetActiveSessionsCount() (int, error) {
+func (r *SessionRepository) GetActiveSessionsCount(includeArchived bool) (int, error) {
 	var count int
-	err := r.db.Get(&count, "SELECT COUNT(*) FROM sessions WHERE is_active = true")
+	err := r.db.Get(&count, "SELECT COUNT(*) FROM sessions WHERE is_active = true AND (? OR archived_at IS NULL)", includeArchived)
 	if err != nil {
 		return 0, fmt.Errorf("failed to get active sessions count: %w", err)
 	}
@@ -119,9 +121,12 @@ func (r *SessionRepository) GetActiveSessionsCount() (int, error) {
 }
 
 // GetTotalMessages returns the total number of messages
-func (r *SessionRepository) GetTotalMessages() (int, error) {
+func (r *SessionRepository) GetTotalMessages(includeArchived bool) (int, error) {
 	var count int
-	err := r.db.Get(&count, "SELECT COUNT(*) FROM messages")
+	err := r.db.Get(&count, `
+		SELECT COUNT(*) FROM messages
+		WHERE (? OR session_id NOT IN (SELECT id FROM sessions WHERE archived_at IS NOT NULL))
+	`, includeArchived)
 	if err != nil {
 		return 0, fmt.Errorf("failed to get total messages: %w", err)
 	}
@@ -129,7 +134,7 @@ func (r *SessionRepository) GetTotalMessages() (int, error) {
 }
 
 // GetOverallTokenUsage returns aggregated token usage
-func (r *SessionRepository) GetOverallTokenUsage() (*TokenUsageAggregate, error) {
+func (r *SessionRepository) GetOverallTokenUsage(includeArchived bool) (*TokenUsageAggregate, error) {
 	var usage TokenUsageAggregate
 	err := r.db.Get(&usage, `
 		SELECT 
@@ -140,7 +145,8 @@ func (r *SessionRepository) GetOverallTokenUsage() (*TokenUsageAggregate, error)
 			COALESCE(SUM(total_tokens), 0) as total_tokens,
 			COALESCE(SUM(estimated_cost), 0.0) as estimated_cost
 		FROM token_usage
-	`)
+		WHERE (? OR session_id NOT IN (SELECT id FROM sessions WHERE archived_at IS NOT NULL))
+	`, includeArchived)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get overall token usage: %w", err)
 	}
@@ -148,9 +154,12 @@ func (r *SessionRepository) GetOverallTokenUsage() (*TokenUsageAggregate, error)
 }
 
 // GetEstimatedCost returns total estimated cost
-func (r *SessionRepository) GetEstimatedCost() (float64, error) {
+func (r *SessionRepository) GetEstimatedCost(includeArchived bool) (float64, error) {
 	var cost float64
-	err := r.db.Get(&cost, "SELECT COALESCE(SUM(estimated_cost), 0.0) FROM token_usage")
+	err := r.db.Get(&cost, `
+		SELECT COALESCE(SUM(estimated_cost), 0.0) FROM token_usage
+		WHERE (? OR session_id NOT IN (SELECT id FROM sessions WHERE archived_at IS NOT NULL))
+	`, includeArchived)
 	if err != nil {
 		return 0, fmt.Errorf("failed to get estimated cost: %w", err)
 	}
@@ -158,13 +167,13 @@ func (r *SessionRepository) GetEstimatedCost() (float64, error) {
 }
 
 // GetAverageSessionDuration returns average session duration in minutes
-func (r *SessionRepository) GetAverageSessionDuration() (float64, error) {
+func (r *SessionRepository) GetAverageSessionDuration(includeArchived bool) (float64, error) {
 	var duration float64
 	err := r.db.Get(&duration, `
 		SELECT COALESCE(AVG(duration_seconds / 60.0), 0.0) 
 		FROM sessions 
-		WHERE duration_seconds > 0
-	`)
+		WHERE duration_seconds > 0 AND (? OR archived_at IS NULL)
+	`, includeArchived)
 	if err != nil {
 		return 0, fmt.Errorf("failed to get average duration: %w", err)
 	}
@@ -172,16 +181,16 @@ func (r *SessionRepository) GetAverageSessionDuration() (float64, error) {
 }
 
 // GetMostUsedModel returns the most frequently used model
-func (r *SessionRepository) GetMostUsedModel() (string, error) {
+func (r *SessionRepository) GetMostUsedModel(includeArchived bool) (string, error) {
 	var model string
 	err := r.db.Get(&model, `
 		SELECT COALESCE(model, 'unknown') 
 		FROM sessions 
-		WHERE model IS NOT NULL AND model != ''
+		WHERE model IS NOT NULL AND model != '' AND (? OR archived_at IS NULL)
 		GROUP BY model 
 		ORDER BY COUNT(*) DESC 
 		LIMIT 1
-	`)
+	`, includeArchived)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return "unknown", nil
@@ -192,14 +201,14 @@ func (r *SessionRepository) GetMostUsedModel() (string, error) {
 }
 
 // GetModelUsage returns usage count by model
-func (r *SessionRepository) GetModelUsage() (map[string]int, error) {
+func (r *SessionRepository) GetModelUsage(includeArchived bool) (map[string]int, error) {
 	rows, err := r.db.Query(`
 		SELECT model, COUNT(*) as count 
 		FROM sessions 
-		WHERE model IS NOT NULL AND model != ''
+		WHERE model IS NOT NULL AND model != '' AND (? OR archived_at IS NULL)
 		GROUP BY model 
 		ORDER BY count DESC
-	`)
+	`, includeArchived)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get model usage: %w", err)
 	}
@@ -240,18 +249,24 @@ func (r *SessionRepository) GetDailyMetrics(days int) ([]*DailyMetric, error) {
 
 // GetPeakHours returns peak usage hours
 func (r *SessionRepository) GetPeakHours() ([]map[string]interface{}, error) {
+	return r.GetPeakHoursAsOf(time.Now())
+}
+
+// GetPeakHoursAsOf returns peak usage hours of the 30 days before asOf
+func (r *SessionRepository) GetPeakHoursAsOf(asOf time.Time) ([]map[string]interface{}, error) {
+	asOf = asOf.UTC()
 	rows, err := r.db.Query(`
 		SELECT 
 			strftime('%H', timestamp) as hour,
 			COUNT(*) as message_count,
 			COUNT(DISTINCT DATE(timestamp)) as unique_days
 		FROM messages 
-		WHERE timestamp >= datetime('now', '-30 days')
+		WHERE timestamp >= ? AND timestamp < ?
 		GROUP BY strftime('%H', timestamp)
 		HAVING message_count > 10
 		ORDER BY message_count DESC
 		LIMIT 4
-	`)
+	`, asOf.AddDate(0, 0, -30), asOf)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get peak hours: %w", err)
 	}
@@ -594,69 +609,149 @@ type TokenUsageAggregate struct {
 // UpsertSession creates or updates a session
 func (r *SessionRepository) UpsertSession(session *Session) error {
 	return r.db.Transaction(func(tx *sqlx.Tx) error {
-		_, err := tx.NamedExec(`
-			INSERT OR REPLACE INTO sessions (
-				id, project_path, project_name, file_path, git_branch, git_worktree,
-				start_time, last_activity, is_active, status, model, message_count,
-				duration_seconds, updated_at
-			) VALUES (
-				:id, :project_path, :project_name, :file_path, :git_branch, :git_worktree,
-				:start_time, :last_activity, :is_active, :status, :model, :message_count,
-				:duration_seconds, CURRENT_TIMESTAMP
-			)
-		`, session)
-		return err
+		return upsertSession(tx, session)
 	})
 }
 
 // UpsertMessage creates or updates a message
 func (r *SessionRepository) UpsertMessage(message *Message) error {
 	return r.db.Transaction(func(tx *sqlx.Tx) error {
-		_, err := tx.NamedExec(`
-			INSERT OR REPLACE INTO messages (
-				id, session_id, parent_uuid, is_sidechain, user_type, cwd, version,
-				type, role, content, request_id, timestamp
-			) VALUES (
-				:id, :session_id, :parent_uuid, :is_sidechain, :user_type, :cwd, :version,
-				:type, :role, :content, :request_id, :timestamp
-			)
-		`, message)
-		return err
+		return upsertMessage(tx, message)
 	})
 }
 
 // UpsertTokenUsage creates or updates token usage
 func (r *SessionRepository) UpsertTokenUsage(usage *TokenUsage) error {
 	return r.db.Transaction(func(tx *sqlx.Tx) error {
-		_, err := tx.NamedExec(`
-			INSERT OR REPLACE INTO token_usage (
-				message_id, session_id, input_tokens, output_tokens,
-				cache_creation_input_tokens, cache_read_input_tokens, total_tokens,
-				service_tier, estimated_cost
-			) VALUES (
-				:message_id, :session_id, :input_tokens, :output_tokens,
-				:cache_creation_input_tokens, :cache_read_input_tokens, :total_tokens,
-				:service_tier, :estimated_cost
-			)
-		`, usage)
-		return err
+		return upsertTokenUsage(tx, usage)
 	})
 }
 
 // UpsertToolResult creates or updates a tool result
 func (r *SessionRepository) UpsertToolResult(result *ToolResult) error {
 	return r.db.Transaction(func(tx *sqlx.Tx) error {
-		_, err := tx.NamedExec(`
-			INSERT OR REPLACE INTO tool_results (
-				message_id, session_id, tool_name, file_path, result_data, timestamp
-			) VALUES (
-				:message_id, :session_id, :tool_name, :file_path, :result_data, :timestamp
-			)
-		`, result)
-		return err
+		return upsertToolResult(tx, result)
 	})
 }
 
+// sessionUpsertSet updates an existing session row in place on re-import.
+// The row is never replaced: replacing deletes it, which cascades to the
+// session's messages and resets columns the import doesn't write, such as
+// created_at and source. Git details and the model are only overwritten
+// with non-empty values, and the message count never goes backwards.
+const sessionUpsertSet = `
+		ON CONFLICT(id) DO UPDATE SET
+			project_path = excluded.project_path,
+			project_name = excluded.project_name,
+			file_path = excluded.file_path,
+			git_branch = COALESCE(NULLIF(excluded.git_branch, ''), sessions.git_branch),
+			git_worktree = COALESCE(NULLIF(excluded.git_worktree, ''), sessions.git_worktree),
+			git_remote = COALESCE(NULLIF(excluded.git_remote, ''), sessions.git_remote),
+			start_time = excluded.start_time,
+			last_activity = excluded.last_activity,
+			is_active = excluded.is_active,
+			status = excluded.status,
+			model = COALESCE(NULLIF(excluded.model, ''), sessions.model),
+			message_count = MAX(sessions.message_count, excluded.message_count),
+			duration_seconds = excluded.duration_seconds,
+			updated_at = CURRENT_TIMESTAMP`
+
+// upsertSession creates a session or updates its row in place, keeping the
+// messages that reference it and the columns imports don't manage
+func upsertSession(tx *sqlx.Tx, session *Session) error {
+	_, err := tx.NamedExec(`
+		INSERT INTO sessions (
+			id, project_path, project_name, file_path, git_branch, git_worktree, git_remote,
+			start_time, last_activity, is_active, status, model, message_count,
+			duration_seconds, updated_at
+		) VALUES (
+			:id, :project_path, :project_name, :file_path, :git_branch, :git_worktree, :git_remote,
+			:start_time, :last_activity, :is_active, :status, :model, :message_count,
+			:duration_seconds, CURRENT_TIMESTAMP
+		)`+sessionUpsertSet, session)
+	return err
+}
+
+const messageUpsert = `
+	INSERT OR REPLACE INTO messages (
+		id, session_id, parent_uuid, is_sidechain, user_type, cwd, version,
+		type, role, content, request_id, timestamp
+	) VALUES (
+		:id, :session_id, :parent_uuid, :is_sidechain, :user_type, :cwd, :version,
+		:type, :role, :content, :request_id, :timestamp
+	)`
+
+const tokenUsageUpsert = `
+	INSERT OR REPLACE INTO token_usage (
+		message_id, session_id, input_tokens, output_tokens,
+		cache_creation_input_tokens, cache_read_input_tokens, total_tokens,
+		service_tier, model, estimated_cost, cache_savings
+	) VALUES (
+		:message_id, :session_id, :input_tokens, :output_tokens,
+		:cache_creation_input_tokens, :cache_read_input_tokens, :total_tokens,
+		:service_tier, :model, :estimated_cost, :cache_savings
+	)`
+
+const toolResultUpsert = `
+	INSERT OR REPLACE INTO tool_results (
+		message_id, session_id, tool_name, file_path, absolute_path, result_data, timestamp
+	) VALUES (
+		:message_id, :session_id, :tool_name, :file_path, :absolute_path, :result_data, :timestamp
+	)`
+
+func upsertMessage(tx *sqlx.Tx, message *Message) error {
+	_, err := tx.NamedExec(messageUpsert, message)
+	return err
+}
+
+func upsertTokenUsage(tx *sqlx.Tx, usage *TokenUsage) error {
+	_, err := tx.NamedExec(tokenUsageUpsert, usage)
+	return err
+}
+
+func upsertToolResult(tx *sqlx.Tx, result *ToolResult) error {
+	_, err := tx.NamedExec(toolResultUpsert, result)
+	return err
+}
+
+// maxBulkVariables keeps multi-row statements under SQLite's historical
+// limit of 999 bound parameters
+const maxBulkVariables = 999
+
+func upsertMessages(tx *sqlx.Tx, messages []Message) error {
+	return bulkExec(tx, messageUpsert, 12, len(messages), func(from, to int) interface{} {
+		return messages[from:to]
+	})
+}
+
+func upsertTokenUsages(tx *sqlx.Tx, usages []TokenUsage) error {
+	return bulkExec(tx, tokenUsageUpsert, 10, len(usages), func(from, to int) interface{} {
+		return usages[from:to]
+	})
+}
+
+func upsertToolResults(tx *sqlx.Tx, results []ToolResult) error {
+	return bulkExec(tx, toolResultUpsert, 7, len(results), func(from, to int) interface{} {
+		return results[from:to]
+	})
+}
+
+// bulkExec runs a named insert over rows in as few multi-row statements as
+// the parameter limit allows. chunk returns the slice of rows [from, to).
+func bulkExec(tx *sqlx.Tx, query string, columns, rows int, chunk func(from, to int) interface{}) error {
+	perStatement := maxBulkVariables / columns
+	for from := 0; from < rows; from += perStatement {
+		to := from + perStatement
+		if to > rows {
+			to = rows
+		}
+		if _, err := tx.NamedExec(query, chunk(from, to)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // LogActivity logs an activity entry
 func (r *SessionRepository) LogActivity(entry *ActivityLogEntry) error {
 	_, err := r.db.NamedExec(`
@@ -669,6 +764,7 @@ func (r *SessionRepository) LogActivity(entry *ActivityLogEntry) error {
 // RecentFile represents a recently modified file
 type RecentFile struct {
 	FilePath     string  `db:"file_path" json:"file_path"`
+	AbsolutePath *string `db:"absolute_path" json:"absolute_path,omitempty"`
 	LastModified string  `db:"last_modified" json:"last_modified"`
 	SessionID    string  `db:"session_id" json:"session_id"`
 	SessionTitle string  `db:"session_title" json:"session_title"`
@@ -682,6 +778,7 @@ type RecentFile struct {
 // ProjectRecentFile represents a file modified within a specific project
 type ProjectRecentFile struct {
 	FilePath           string              `db:"file_path" json:"file_path"`
+	AbsolutePath       *string             `db:"absolute_path" json:"absolute_path,omitempty"` // where it was last modified
 	LastModified       string              `db:"last_modified" json:"last_modified"`
 	TotalModifications int                 `db:"total_modifications" json:"total_modifications"`
 	ToolsUsed          string              `db:"tools_used" json:"tools_used"` // Comma-separated list
@@ -700,9 +797,12 @@ func (r *SessionRepository) GetRecentFiles(limit, offset int) ([]RecentFile, int
 	// Count total recent files
 	var total int
 	err := r.db.Get(&total, `
-		SELECT COUNT(DISTINCT file_path) 
-		FROM tool_results 
-		WHERE file_path IS NOT NULL
+		SELECT COUNT(*) FROM (
+			SELECT 1
+			FROM tool_results
+			WHERE file_path IS NOT NULL
+			GROUP BY file_path, session_id
+		)
 	`)
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to count recent files: %w", err)
@@ -714,6 +814,7 @@ func (r *SessionRepository) GetRecentFiles(limit, offset int) ([]RecentFile, int
 		WITH recent_files AS (
 			SELECT 
 				tr.file_path,
+				tr.absolute_path,
 				MAX(tr.timestamp) as last_modified,
 				tr.session_id,
 				tr.tool_name,
@@ -729,6 +830,7 @@ func (r *SessionRepository) GetRecentFiles(limit, offset int) ([]RecentFile, int
 		)
 		SELECT 
 			file_path,
+			absolute_path,
 			last_modified,
 			session_id,
 			session_title,
@@ -763,22 +865,11 @@ type TokenTimelineEntry struct {
 
 // GetTokenTimeline returns overall token usage over time with configurable granularity
 func (r *SessionRepository) GetTokenTimeline(hours int, granularity string) ([]TokenTimelineEntry, error) {
-	// Determine the time format based on granularity
-	var timeFormat string
-	switch granularity {
-	case "minute":
-		timeFormat = "%Y-%m-%d %H:%M:00"
-	case "hour":
-		timeFormat = "%Y-%m-%d %H:00:00"
-	case "day":
-		timeFormat = "%Y-%m-%d 00:00:00"
-	default:
-		timeFormat = "%Y-%m-%d %H:00:00" // Default to hourly
-	}
+	bucket := timelineBucketSQL("m.timestamp", granularity, "hour")
 
-	query := `
+	query := fmt.Sprintf(`
 		SELECT 
-			strftime(?, m.timestamp) as timestamp,
+			%[1]s as timestamp,
 			SUM(tu.input_tokens) as input_tokens,
 			SUM(tu.output_tokens) as output_tokens,
 			SUM(tu.cache_creation_input_tokens) as cache_creation_tokens,
@@ -789,33 +880,22 @@ func (r *SessionRepository) GetTokenTimeline(hours int, granularity string) ([]T
 		FROM messages m
 		JOIN token_usage tu ON m.id = tu.message_id
 		WHERE m.timestamp >= datetime('now', '-' || ? || ' hours')
-		GROUP BY strftime(?, m.timestamp)
+		GROUP BY %[1]s
 		ORDER BY timestamp ASC
-	`
+	`, bucket)
 
 	var entries []TokenTimelineEntry
-	err := r.db.Select(&entries, query, timeFormat, hours, timeFormat)
+	err := r.db.Select(&entries, query, hours)
 	return entries, err
 }
 
 // GetSessionTokenTimeline returns token usage over time for a specific session
 func (r *SessionRepository) GetSessionTokenTimeline(sessionID string, hours int, granularity string) ([]TokenTimelineEntry, error) {
-	// Determine the time format based on granularity
-	var timeFormat string
-	switch granularity {
-	case "minute":
-		timeFormat = "%Y-%m-%d %H:%M:00"
-	case "hour":
-		timeFormat = "%Y-%m-%d %H:00:00"
-	case "day":
-		timeFormat = "%Y-%m-%d 00:00:00"
-	default:
-		timeFormat = "%Y-%m-%d %H:%M:00" // Default to minute for session view
-	}
+	bucket := timelineBucketSQL("m.timestamp", granularity, "minute")
 
-	query := `
+	query := fmt.Sprintf(`
 		SELECT 
-			strftime(?, m.timestamp) as timestamp,
+			%[1]s as timestamp,
 			COALESCE(SUM(tu.input_tokens), 0) as input_tokens,
 			COALESCE(SUM(tu.output_tokens), 0) as output_tokens,
 			COALESCE(SUM(tu.cache_creation_input_tokens), 0) as cache_creation_tokens,
@@ -827,33 +907,22 @@ func (r *SessionRepository) GetSessionTokenTimeline(sessionID string, hours int,
 		LEFT JOIN token_usage tu ON m.id = tu.message_id
 		WHERE m.session_id = ?
 		AND m.timestamp >= datetime('now', '-' || ? || ' hours')
-		GROUP BY strftime(?, m.timestamp)
+		GROUP BY %[1]s
 		ORDER BY timestamp ASC
-	`
+	`, bucket)
 
 	var entries []TokenTimelineEntry
-	err := r.db.Select(&entries, query, timeFormat, sessionID, hours, timeFormat)
+	err := r.db.Select(&entries, query, sessionID, hours)
 	return entries, err
 }
 
 // GetProjectTokenTimeline returns token usage over time for a specific project
 func (r *SessionRepository) GetProjectTokenTimeline(projectName string, hours int, granularity string) ([]TokenTimelineEntry, error) {
-	// Determine the time format based on granularity
-	var timeFormat string
-	switch granularity {
-	case "minute":
-		timeFormat = "%Y-%m-%d %H:%M:00"
-	case "hour":
-		timeFormat = "%Y-%m-%d %H:00:00"
-	case "day":
-		timeFormat = "%Y-%m-%d 00:00:00"
-	default:
-		timeFormat = "%Y-%m-%d %H:00:00" // Default to hourly
-	}
+	bucket := timelineBucketSQL("m.timestamp", granularity, "hour")
 
-	query := `
+	query := fmt.Sprintf(`
 		SELECT 
-			strftime(?, m.timestamp) as timestamp,
+			%[1]s as timestamp,
 			SUM(tu.input_tokens) as input_tokens,
 			SUM(tu.output_tokens) as output_tokens,
 			SUM(tu.cache_creation_input_tokens) as cache_creation_tokens,
@@ -865,12 +934,12 @@ func (r *SessionRepository) GetProjectTokenTimeline(projectName string, hours in
 		JOIN token_usage tu ON m.id = tu.message_id
 		JOIN sessions s ON m.session_id = s.id
 		WHERE s.project_name = ? AND m.timestamp >= datetime('now', '-' || ? || ' hours')
-		GROUP BY strftime(?, m.timestamp)
+		GROUP BY %[1]s
 		ORDER BY timestamp ASC
-	`
+	`, bucket)
 
 	var entries []TokenTimelineEntry
-	err := r.db.Select(&entries, query, timeFormat, projectName, hours, timeFormat)
+	err := r.db.Select(&entries, query, projectName, hours)
 	return entries, err
 }
 
@@ -881,6 +950,7 @@ func (r *SessionRepository) GetProjectRecentFiles(projectName string, limit int,
 		WITH project_files AS (
 			SELECT 
 				tr.file_path,
+				tr.absolute_path,
 				MAX(tr.timestamp) as last_modified,
 				COUNT(*) as total_modifications,
 				GROUP_CONCAT(DISTINCT tr.tool_name) as tools_used,
@@ -904,6 +974,7 @@ func (r *SessionRepository) GetProjectRecentFiles(projectName string, limit int,
 		)
 		SELECT 
 			file_path,
+			absolute_path,
 			last_modified,
 			total_modifications,
 			tools_used,
@@ -929,6 +1000,7 @@ func (r *SessionRepository) GetProjectRecentFiles(projectName string, limit int,
 
 		err := rows.Scan(
 			&file.FilePath,
+			&file.AbsolutePath,
 			&file.LastModified,
 			&file.TotalModifications,
 			&file.ToolsUsed,
@@ -965,6 +1037,85 @@ func (r *SessionRepository) GetProjectRecentFiles(projectName string, limit int,
 	return files, nil
 }
 
+// GetProjectSummary returns the aggregate figures for a single project's
+// dashboard: sessions, messages, token usage by type, cost, the most used
+// model and when the project was first and last active
+func (r *SessionRepository) GetProjectSummary(projectName string) (*ProjectSummary, error) {
+	summary := ProjectSummary{ProjectName: projectName, MostUsedModel: "unknown"}
+
+	err := r.db.Get(&summary, `
+		SELECT
+			COUNT(*) as total_sessions,
+			COALESCE(SUM(CASE WHEN is_active THEN 1 ELSE 0 END), 0) as active_sessions
+		FROM sessions
+		WHERE project_name = ?
+	`, projectName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get project sessions: %w", err)
+	}
+	if summary.TotalSessions == 0 {
+		return nil, fmt.Errorf("project not found: %s", projectName)
+	}
+
+	err = r.db.Get(&summary.TotalMessages, `
+		SELECT COUNT(*)
+		FROM messages m
+		JOIN sessions s ON m.session_id = s.id
+		WHERE s.project_name = ?
+	`, projectName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get project messages: %w", err)
+	}
+
+	err = r.db.Get(&summary.TokenUsage, `
+		SELECT 
+			COALESCE(SUM(tu.input_tokens), 0) as input_tokens,
+			COALESCE(SUM(tu.output_tokens), 0) as output_tokens,
+			COALESCE(SUM(tu.cache_creation_input_tokens), 0) as cache_creation_input_tokens,
+			COALESCE(SUM(tu.cache_read_input_tokens), 0) as cache_read_input_tokens,
+			COALESCE(SUM(tu.total_tokens), 0) as total_tokens,
+			COALESCE(SUM(tu.estimated_cost), 0.0) as estimated_cost
+		FROM token_usage tu
+		JOIN sessions s ON tu.session_id = s.id
+		WHERE s.project_name = ?
+	`, projectName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get project token usage: %w", err)
+	}
+	summary.EstimatedCost = summary.TokenUsage.EstimatedCost
+
+	err = r.db.Get(&summary.MostUsedModel, `
+		SELECT model
+		FROM sessions
+		WHERE project_name = ? AND model IS NOT NULL AND model != ''
+		GROUP BY model
+		ORDER BY COUNT(*) DESC
+		LIMIT 1
+	`, projectName)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to get project most used model: %w", err)
+	}
+
+	// Selected directly rather than with MIN/MAX so SQLite returns DATETIMEs
+	var firstActivity, lastActivity time.Time
+	err = r.db.Get(&firstActivity, `
+		SELECT start_time FROM sessions WHERE project_name = ? ORDER BY start_time ASC LIMIT 1
+	`, projectName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get project first activity: %w", err)
+	}
+	err = r.db.Get(&lastActivity, `
+		SELECT last_activity FROM sessions WHERE project_name = ? ORDER BY last_activity DESC LIMIT 1
+	`, projectName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get project last activity: %w", err)
+	}
+	summary.FirstActivity = firstActivity
+	summary.LastActivity = lastActivity
+
+	return &summary, nil
+}
+
 // CreateUISession creates a new UI-initiated session
 func (r *SessionRepository) CreateUISession(projectPath, projectName, model string) (*Session, error) {
 	now := time.Now()
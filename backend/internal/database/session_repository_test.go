@@ -228,4 +228,154 @@ func TestSessionRepository_GetProjectRecentFiles(t *testing.T) {
 // Helper function to create string pointer
 func stringPtr(s string) *string {
 	return &s
-}
\ No newline at end of file
+}
+func TestSessionRepository_GetProjectSummary(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewSessionRepository(db, logger)
+
+	start := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	for i, s := range []struct {
+		id, project, model string
+		active             bool
+	}{
+		{"s1", "api", "claude-opus-4", false},
+		{"s2", "api", "claude-sonnet-4", true},
+		{"s3", "api", "claude-sonnet-4", false},
+		{"s4", "web", "claude-opus-4", true},
+	} {
+		if err := repo.UpsertSession(&Session{
+			ID:           s.id,
+			ProjectPath:  "/work/" + s.project,
+			ProjectName:  s.project,
+			StartTime:    start.Add(time.Duration(i) * time.Hour),
+			LastActivity: start.Add(time.Duration(i)*time.Hour + 30*time.Minute),
+			IsActive:     s.active,
+			Status:       "completed",
+			Model:        s.model,
+		}); err != nil {
+			t.Fatalf("Failed to create session: %v", err)
+		}
+		if err := repo.UpsertMessage(&Message{ID: s.id + "-1", SessionID: s.id, Role: "assistant", Timestamp: start}); err != nil {
+			t.Fatalf("Failed to create message: %v", err)
+		}
+		if err := repo.UpsertTokenUsage(&TokenUsage{
+			MessageID:            s.id + "-1",
+			SessionID:            s.id,
+			InputTokens:          10,
+			OutputTokens:         20,
+			CacheReadInputTokens: 5,
+			TotalTokens:          35,
+			EstimatedCost:        0.5,
+		}); err != nil {
+			t.Fatalf("Failed to create token usage: %v", err)
+		}
+	}
+
+	summary, err := repo.GetProjectSummary("api")
+	if err != nil {
+		t.Fatalf("Failed to get project summary: %v", err)
+	}
+	if summary.TotalSessions != 3 || summary.ActiveSessions != 1 || summary.TotalMessages != 3 {
+		t.Errorf("Unexpected counts: %+v", summary)
+	}
+	if summary.TokenUsage.InputTokens != 30 || summary.TokenUsage.OutputTokens != 60 ||
+		summary.TokenUsage.CacheReadInputTokens != 15 || summary.TokenUsage.TotalTokens != 105 {
+		t.Errorf("Unexpected token usage: %+v", summary.TokenUsage)
+	}
+	if summary.EstimatedCost != 1.5 || summary.MostUsedModel != "claude-sonnet-4" {
+		t.Errorf("Unexpected cost or model: %+v", summary)
+	}
+	if !summary.FirstActivity.Equal(start) || !summary.LastActivity.Equal(start.Add(2*time.Hour+30*time.Minute)) {
+		t.Errorf("Unexpected activity range: %v - %v", summary.FirstActivity, summary.LastActivity)
+	}
+
+	if _, err := repo.GetProjectSummary("missing"); err == nil {
+		t.Error("Expected an error for an unknown project")
+	}
+}
+
+func TestReadOptimizedRepository_GetAllSessionsOptimized(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewSessionRepository(db, logger)
+	readOptimized := NewReadOptimizedRepository(db)
+
+	now := time.Now()
+	for i, id := range []string{"oldest", "middle", "newest"} {
+		if err := repo.UpsertSession(&Session{
+			ID:           id,
+			ProjectPath:  "/work/app",
+			ProjectName:  "app",
+			StartTime:    now,
+			LastActivity: now.Add(time.Duration(i) * time.Hour),
+			Status:       "completed",
+		}); err != nil {
+			t.Fatalf("Failed to create session: %v", err)
+		}
+	}
+
+	page, total, err := readOptimized.GetAllSessionsOptimized(2, 1, false, false)
+	if err != nil {
+		t.Fatalf("Failed to get sessions: %v", err)
+	}
+	if total != 3 || len(page) != 2 || page[0].ID != "middle" || page[1].ID != "oldest" {
+		t.Errorf("Expected middle then oldest of 3, got %d %+v", total, page)
+	}
+
+	all, _, err := readOptimized.GetAllSessionsOptimized(0, 0, true, false)
+	if err != nil || len(all) != 3 || all[0].ID != "oldest" {
+		t.Errorf("Expected every session oldest first, got %+v (%v)", all, err)
+	}
+}
+
+func TestSessionRepository_UpsertSessionPreservesRow(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewSessionRepository(db, logger)
+
+	created, err := repo.CreateUISession("/work/app", "app", "claude-3-5-sonnet-20241022")
+	if err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	if _, err := db.Exec(`UPDATE sessions SET created_at = '2024-01-01 00:00:00', git_branch = 'main' WHERE id = ?`, created.ID); err != nil {
+		t.Fatalf("Failed to backdate session: %v", err)
+	}
+	now := time.Now()
+	if err := repo.UpsertMessage(&Message{ID: "m1", SessionID: created.ID, Role: "user", Content: `"hello"`, Timestamp: now}); err != nil {
+		t.Fatalf("Failed to create message: %v", err)
+	}
+	if err := repo.UpsertMessage(&Message{ID: "m2", SessionID: created.ID, Role: "assistant", Content: `"hi"`, Timestamp: now}); err != nil {
+		t.Fatalf("Failed to create message: %v", err)
+	}
+	if err := repo.UpsertSession(&Session{ID: created.ID, ProjectPath: "/work/app", ProjectName: "app", StartTime: now, LastActivity: now, Status: "completed", MessageCount: 2}); err != nil {
+		t.Fatalf("Failed to upsert session: %v", err)
+	}
+
+	// A re-import that saw fewer messages, no model and no branch
+	if err := repo.UpsertSession(&Session{ID: created.ID, ProjectPath: "/work/app", ProjectName: "app", StartTime: now, LastActivity: now, Status: "completed", MessageCount: 1}); err != nil {
+		t.Fatalf("Failed to upsert session: %v", err)
+	}
+
+	var row struct {
+		Source       string `db:"source"`
+		CreatedAt    string `db:"created_at"`
+		GitBranch    string `db:"git_branch"`
+		Model        string `db:"model"`
+		MessageCount int    `db:"message_count"`
+	}
+	if err := db.Get(&row, `SELECT source, strftime('%Y-%m-%d', created_at) AS created_at, git_branch, model, message_count FROM sessions WHERE id = ?`, created.ID); err != nil {
+		t.Fatalf("Failed to get session: %v", err)
+	}
+	if row.Source != "ui" || row.CreatedAt != "2024-01-01" || row.GitBranch != "main" || row.Model != "claude-3-5-sonnet-20241022" || row.MessageCount != 2 {
+		t.Errorf("Expected the row to be updated in place, got %+v", row)
+	}
+
+	var messages int
+	if err := db.Get(&messages, `SELECT COUNT(*) FROM messages WHERE session_id = ?`, created.ID); err != nil || messages != 2 {
+		t.Errorf("Expected the session's messages to be kept, got %d (%v)", messages, err)
+	}
+}
@@ -0,0 +1,87 @@
+package database
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Session statuses
+const (
+	SessionStatusActive    = "active"
+	SessionStatusIdle      = "idle"
+	SessionStatusCompleted = "completed"
+)
+
+// SessionStatusChange is a session moving from one status to another
+type SessionStatusChange struct {
+	SessionID    string    `json:"session_id"`
+	From         string    `json:"from"`
+	To           string    `json:"to"`
+	LastActivity time.Time `json:"last_activity"`
+}
+
+// TransitionSessionStatuses moves active sessions last active before
+// idleBefore to idle, and active or idle sessions last active before
+// completedBefore to completed, and returns the changes. A session its hooks
+// last reported as running, such as one in a long tool call, isn't made idle,
+// and is only completed once it has been running since before completedBefore
+// with no activity.
+func (r *SessionRepository) TransitionSessionStatuses(idleBefore, completedBefore time.Time) ([]SessionStatusChange, error) {
+	changes := []SessionStatusChange{}
+	err := r.db.WriteOperation(func(tx *sqlx.Tx) error {
+		var sessions []struct {
+			ID           string     `db:"id"`
+			Status       string     `db:"status"`
+			LastActivity time.Time  `db:"last_activity"`
+			Live         *bool      `db:"live"`
+			LiveSince    *time.Time `db:"live_since"`
+		}
+		if err := tx.Select(&sessions, `
+			SELECT s.id, s.status, s.last_activity, l.is_active AS live, l.changed_at AS live_since
+			FROM sessions s
+			LEFT JOIN session_liveness l ON l.session_id = s.id
+			WHERE s.status IN (?, ?)
+		`, SessionStatusActive, SessionStatusIdle); err != nil {
+			return fmt.Errorf("failed to get sessions to transition: %w", err)
+		}
+
+		for _, session := range sessions {
+			lastActivity := session.LastActivity
+			live := session.Live != nil && *session.Live
+			if live && session.LiveSince != nil && session.LiveSince.After(lastActivity) {
+				lastActivity = *session.LiveSince
+			}
+
+			status := session.Status
+			switch {
+			case lastActivity.Before(completedBefore):
+				status = SessionStatusCompleted
+			case lastActivity.Before(idleBefore) && !live:
+				status = SessionStatusIdle
+			}
+			if status == session.Status {
+				continue
+			}
+
+			if _, err := tx.Exec(`
+				UPDATE sessions SET status = ?, is_active = FALSE, updated_at = CURRENT_TIMESTAMP
+				WHERE id = ? AND status = ?
+			`, status, session.ID, session.Status); err != nil {
+				return fmt.Errorf("failed to update session status: %w", err)
+			}
+			changes = append(changes, SessionStatusChange{
+				SessionID:    session.ID,
+				From:         session.Status,
+				To:           status,
+				LastActivity: session.LastActivity,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
@@ -0,0 +1,78 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSessionRepository_TransitionSessionStatuses(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewSessionRepository(db, logger)
+
+	now := time.Now()
+	for _, session := range []struct {
+		id           string
+		status       string
+		lastActivity time.Time
+	}{
+		{"working", SessionStatusActive, now.Add(-time.Minute)},
+		{"paused", SessionStatusActive, now.Add(-10 * time.Minute)},
+		{"abandoned", SessionStatusActive, now.Add(-time.Hour)},
+		{"idle", SessionStatusIdle, now.Add(-time.Hour)},
+		{"done", SessionStatusCompleted, now.Add(-time.Hour)},
+		{"long-tool", SessionStatusActive, now.Add(-10 * time.Minute)},
+	} {
+		if err := repo.UpsertSession(&Session{
+			ID:           session.id,
+			ProjectPath:  "/test/project",
+			ProjectName:  "project",
+			StartTime:    now.Add(-2 * time.Hour),
+			LastActivity: session.lastActivity,
+			IsActive:     session.status == SessionStatusActive,
+			Status:       session.status,
+		}); err != nil {
+			t.Fatalf("Failed to upsert session: %v", err)
+		}
+	}
+	// Hooks report the long tool call still running
+	if _, err := repo.SetSessionLiveness(&SessionLiveness{SessionID: "long-tool", IsActive: true, Event: "PreToolUse", ChangedAt: now.Add(-10 * time.Minute)}); err != nil {
+		t.Fatalf("Failed to set liveness: %v", err)
+	}
+
+	changes, err := repo.TransitionSessionStatuses(now.Add(-5*time.Minute), now.Add(-30*time.Minute))
+	if err != nil {
+		t.Fatalf("Failed to transition sessions: %v", err)
+	}
+	got := map[string]string{}
+	for _, change := range changes {
+		got[change.SessionID] = change.From + "->" + change.To
+	}
+	want := map[string]string{
+		"paused":    "active->idle",
+		"abandoned": "active->completed",
+		"idle":      "idle->completed",
+	}
+	if len(got) != len(want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+	for id, transition := range want {
+		if got[id] != transition {
+			t.Errorf("Expected %s to go %s, got %q", id, transition, got[id])
+		}
+	}
+
+	paused, err := repo.GetSessionByID("paused")
+	if err != nil {
+		t.Fatalf("Failed to get session: %v", err)
+	}
+	if paused.Status != SessionStatusIdle || paused.IsActive {
+		t.Errorf("Expected an inactive idle session, got %s (active %v)", paused.Status, paused.IsActive)
+	}
+
+	// Running again changes nothing
+	if changes, err := repo.TransitionSessionStatuses(now.Add(-5*time.Minute), now.Add(-30*time.Minute)); err != nil || len(changes) != 0 {
+		t.Errorf("Expected no further changes, got %v (%v)", changes, err)
+	}
+}
@@ -0,0 +1,88 @@
+package database
+
+import (
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// rebuildSessionSummariesSQL recomputes every row of session_summaries from
+// sessions, token_usage and tool_results, as the triggers maintaining it do
+// one session at a time
+const rebuildSessionSummariesSQL = `
+	DELETE FROM session_summaries;
+
+	INSERT INTO session_summaries (
+		id, project_name, project_path, start_time, last_activity, is_active, status,
+		model, message_count, duration_seconds, source, archived_at,
+		git_branch, git_worktree, git_remote,
+		total_input_tokens, total_output_tokens, total_cache_creation_tokens,
+		total_cache_read_tokens, total_tokens, total_estimated_cost, total_cache_savings,
+		files_modified
+	)
+	SELECT
+		s.id, s.project_name, s.project_path, s.start_time, s.last_activity, s.is_active, s.status,
+		s.model, s.message_count, s.duration_seconds, s.source, s.archived_at,
+		COALESCE(s.git_branch, ''), COALESCE(s.git_worktree, ''), COALESCE(s.git_remote, ''),
+		COALESCE(tu.input_tokens, 0), COALESCE(tu.output_tokens, 0), COALESCE(tu.cache_creation_tokens, 0),
+		COALESCE(tu.cache_read_tokens, 0), COALESCE(tu.total_tokens, 0), COALESCE(tu.cost, 0.0), COALESCE(tu.cache_savings, 0.0),
+		COALESCE(fr.files, '[]')
+	FROM sessions s
+	LEFT JOIN (
+		SELECT
+			session_id,
+			SUM(input_tokens) as input_tokens,
+			SUM(output_tokens) as output_tokens,
+			SUM(cache_creation_input_tokens) as cache_creation_tokens,
+			SUM(cache_read_input_tokens) as cache_read_tokens,
+			SUM(total_tokens) as total_tokens,
+			SUM(estimated_cost) as cost,
+			SUM(cache_savings) as cache_savings
+		FROM token_usage
+		GROUP BY session_id
+	) tu ON s.id = tu.session_id
+	LEFT JOIN (
+		SELECT session_id, JSON_GROUP_ARRAY(DISTINCT file_path) as files
+		FROM tool_results
+		WHERE file_path IS NOT NULL
+		GROUP BY session_id
+	) fr ON s.id = fr.session_id;
+`
+
+// rebuildSessionSummaries recomputes session_summaries, returning how many
+// sessions it holds
+func rebuildSessionSummaries(tx *sqlx.Tx) (int, error) {
+	if _, err := tx.Exec(rebuildSessionSummariesSQL); err != nil {
+		return 0, fmt.Errorf("failed to rebuild session summaries: %w", err)
+	}
+	var count int
+	if err := tx.Get(&count, `SELECT COUNT(*) FROM session_summaries`); err != nil {
+		return 0, fmt.Errorf("failed to count session summaries: %w", err)
+	}
+	return count, nil
+}
+
+// RebuildSessionSummaries recomputes every session's summary, its token
+// totals and modified files, from the rows they are derived from. Triggers
+// keep the summaries current, so this is only needed to repair them, for
+// example after rows were written with the triggers missing.
+func (db *Database) RebuildSessionSummaries() (int, error) {
+	var count int
+	err := db.WriteOperation(func(tx *sqlx.Tx) error {
+		var err error
+		count, err = rebuildSessionSummaries(tx)
+		return err
+	})
+	return count, err
+}
+
+// materializeSessionSummary replaces the session_summary view, which
+// aggregated token usage and tool results on every read, with the
+// session_summaries table schema.sql has created
+func materializeSessionSummary(tx *sqlx.Tx) error {
+	if _, err := tx.Exec(`DROP VIEW IF EXISTS session_summary`); err != nil {
+		return err
+	}
+	_, err := rebuildSessionSummaries(tx)
+	return err
+}
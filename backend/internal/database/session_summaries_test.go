@@ -0,0 +1,144 @@
+package database
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSessionSummaries_FollowWrites(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewSessionRepository(db, logger)
+	importer := NewImporter(repo, logger)
+	importer.SetBatchSize(3)
+
+	// summaries lists every summary with its files sorted and its cost
+	// rounded, as the triggers add up costs in a different order than a rebuild
+	summaries := func() []string {
+		t.Helper()
+		var rows []string
+		err := db.Select(&rows, `
+			SELECT id || ' ' || project_name || ' ' || message_count || ' ' || is_active || ' ' ||
+				total_input_tokens || ' ' || total_output_tokens || ' ' || total_tokens || ' ' ||
+				ROUND(total_estimated_cost, 9) || ' ' ||
+				(SELECT JSON_GROUP_ARRAY(value) FROM (SELECT value FROM json_each(files_modified) ORDER BY value))
+			FROM session_summaries ORDER BY id`)
+		if err != nil {
+			t.Fatalf("Failed to list session summaries: %v", err)
+		}
+		return rows
+	}
+	// consistent checks the maintained summaries match a rebuild
+	consistent := func(step string) {
+		t.Helper()
+		maintained := summaries()
+		if _, err := db.RebuildSessionSummaries(); err != nil {
+			t.Fatalf("Failed to rebuild session summaries: %v", err)
+		}
+		if rebuilt := summaries(); !reflect.DeepEqual(maintained, rebuilt) {
+			t.Errorf("After %s expected the summaries to match a rebuild:\n got %v\nwant %v", step, maintained, rebuilt)
+		}
+	}
+
+	start := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	var lines []string
+	for n := 0; n < 8; n++ {
+		lines = append(lines, fmt.Sprintf(`{"cwd":"/work/app","sessionId":"s1","type":"assistant","message":{"role":"assistant","model":"claude-3-5-sonnet-20241022","content":[{"type":"tool_use","id":"tu%d","name":"Write","input":{"file_path":"/work/app/f%d.go","content":"x"}}],"usage":{"input_tokens":10,"output_tokens":5}},"uuid":"m%d","timestamp":"%s"}`,
+			n, n%3, n, start.Add(time.Duration(n)*time.Minute).Format(time.RFC3339)))
+	}
+	path := filepath.Join(t.TempDir(), "s1.jsonl")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+		t.Fatalf("Failed to write transcript: %v", err)
+	}
+	if _, _, err := importer.ImportJSONLFile(path, ProjectInfo{}); err != nil {
+		t.Fatalf("Failed to import: %v", err)
+	}
+
+	session, err := repo.GetSessionByID("s1")
+	if err != nil {
+		t.Fatalf("Failed to get session: %v", err)
+	}
+	if session.TotalInputTokens != 80 || session.TotalOutputTokens != 40 || session.MessageCount != 8 {
+		t.Errorf("Expected the imported totals in the summary, got %+v", session)
+	}
+	if files, _ := session.GetFilesModifiedList(); len(files) != 3 {
+		t.Errorf("Expected the three modified files, got %s", session.FilesModified)
+	}
+	consistent("an import")
+
+	// Importing again replaces each message, which cascades to its token
+	// usage and tool results
+	if _, _, err := importer.ImportJSONLFile(path, ProjectInfo{}); err != nil {
+		t.Fatalf("Failed to import again: %v", err)
+	}
+	if session, _ := repo.GetSessionByID("s1"); session.TotalInputTokens != 80 {
+		t.Errorf("Expected re-importing not to count usage twice, got %d input tokens", session.TotalInputTokens)
+	}
+	consistent("a re-import")
+
+	if _, err := repo.SplitSession("s1", "m5", "", ""); err != nil {
+		t.Fatalf("Failed to split session: %v", err)
+	}
+	consistent("a split")
+
+	if _, err := db.Exec(`UPDATE token_usage SET estimated_cost = estimated_cost * 2, total_tokens = 1 WHERE message_id IN ('m1', 'm6')`); err != nil {
+		t.Fatalf("Failed to reprice token usage: %v", err)
+	}
+	if _, err := db.Exec(`UPDATE sessions SET project_name = 'renamed' WHERE id = 's1'`); err != nil {
+		t.Fatalf("Failed to rename project: %v", err)
+	}
+	consistent("updates")
+
+	if _, err := db.Exec(`DELETE FROM tool_results WHERE message_id = 'm0'`); err != nil {
+		t.Fatalf("Failed to delete tool result: %v", err)
+	}
+	consistent("deleting a tool result")
+
+	if err := repo.DeleteSession("s1"); err != nil {
+		t.Fatalf("Failed to delete session: %v", err)
+	}
+	if _, err := repo.GetSessionByID("s1"); err == nil {
+		t.Error("Expected the deleted session's summary to be gone")
+	}
+	consistent("a delete")
+}
+
+func TestSessionSummaries_Rebuild(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewSessionRepository(db, logger)
+	now := time.Now().UTC()
+	if err := repo.UpsertSession(&Session{ID: "s1", ProjectPath: "/work/app", ProjectName: "app", StartTime: now, LastActivity: now, Status: "completed"}); err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	if err := repo.UpsertMessage(&Message{ID: "m1", SessionID: "s1", Role: "assistant", Content: "hi", Timestamp: now}); err != nil {
+		t.Fatalf("Failed to create message: %v", err)
+	}
+	if err := repo.UpsertTokenUsage(&TokenUsage{MessageID: "m1", SessionID: "s1", InputTokens: 7, TotalTokens: 7, EstimatedCost: 0.25}); err != nil {
+		t.Fatalf("Failed to create token usage: %v", err)
+	}
+
+	// Drift the summary as a write with the triggers missing would
+	if _, err := db.Exec(`UPDATE session_summaries SET total_input_tokens = 0, total_estimated_cost = 0, project_name = 'stale'`); err != nil {
+		t.Fatalf("Failed to drift summary: %v", err)
+	}
+
+	count, err := db.RebuildSessionSummaries()
+	if err != nil || count != 1 {
+		t.Fatalf("Expected 1 summary rebuilt, got %d (%v)", count, err)
+	}
+	session, err := repo.GetSessionByID("s1")
+	if err != nil {
+		t.Fatalf("Failed to get session: %v", err)
+	}
+	if session.TotalInputTokens != 7 || session.TotalEstimatedCost != 0.25 || session.ProjectName != "app" || session.FilesModified != "[]" {
+		t.Errorf("Expected the summary recomputed, got %+v", session)
+	}
+}
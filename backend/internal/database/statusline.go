@@ -0,0 +1,38 @@
+package database
+
+import (
+	"fmt"
+	"time"
+)
+
+// GetStatuslineUsage returns a session's usage so far and the usage from
+// messages sent since the start of the day. It reads only indexed rows so
+// statusline scripts can poll it every few seconds.
+func (r *SessionRepository) GetStatuslineUsage(sessionID string, dayStart time.Time) (*StatuslineUsage, error) {
+	var usage StatuslineUsage
+	if sessionID != "" {
+		err := r.db.Get(&usage, `
+			SELECT
+				COALESCE(SUM(total_tokens), 0) AS session_tokens,
+				COALESCE(SUM(estimated_cost), 0.0) AS session_cost_usd
+			FROM token_usage
+			WHERE session_id = ?
+		`, sessionID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get session usage: %w", err)
+		}
+	}
+
+	err := r.db.Get(&usage, `
+		SELECT
+			COALESCE(SUM(tu.total_tokens), 0) AS today_tokens,
+			COALESCE(SUM(tu.estimated_cost), 0.0) AS today_cost_usd
+		FROM messages m
+		JOIN token_usage tu ON tu.message_id = m.id
+		WHERE m.timestamp >= ?
+	`, dayStart)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get today's usage: %w", err)
+	}
+	return &usage, nil
+}
@@ -0,0 +1,58 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSessionRepository_GetStatuslineUsage(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewSessionRepository(db, logger)
+
+	now := time.Now()
+	dayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	for _, id := range []string{"s1", "s2"} {
+		if err := repo.UpsertSession(&Session{
+			ID:           id,
+			ProjectPath:  "/test/project",
+			ProjectName:  "project",
+			StartTime:    dayStart.Add(-time.Hour),
+			LastActivity: dayStart.Add(time.Minute),
+			Status:       "completed",
+		}); err != nil {
+			t.Fatalf("Failed to create session: %v", err)
+		}
+	}
+
+	// s1 has one message before today and one today; s2 has one today
+	for _, m := range []struct {
+		id, session string
+		at          time.Time
+		cost        float64
+	}{
+		{"a", "s1", dayStart.Add(-time.Minute), 1.0},
+		{"b", "s1", dayStart.Add(time.Second), 2.0},
+		{"c", "s2", dayStart.Add(time.Second), 4.0},
+	} {
+		if err := repo.UpsertMessage(&Message{ID: m.id, SessionID: m.session, Role: "assistant", Timestamp: m.at}); err != nil {
+			t.Fatalf("Failed to create message: %v", err)
+		}
+		if err := repo.UpsertTokenUsage(&TokenUsage{MessageID: m.id, SessionID: m.session, TotalTokens: 100, EstimatedCost: m.cost}); err != nil {
+			t.Fatalf("Failed to create token usage: %v", err)
+		}
+	}
+
+	usage, err := repo.GetStatuslineUsage("s1", dayStart)
+	if err != nil {
+		t.Fatalf("Failed to get statusline usage: %v", err)
+	}
+	if usage.SessionTokens != 200 || usage.SessionCostUSD != 3.0 || usage.TodayTokens != 200 || usage.TodayCostUSD != 6.0 {
+		t.Errorf("Unexpected usage: %+v", usage)
+	}
+
+	if usage, _ := repo.GetStatuslineUsage("", dayStart); usage.SessionTokens != 0 || usage.TodayCostUSD != 6.0 {
+		t.Errorf("Expected only today's usage without a session, got %+v", usage)
+	}
+}
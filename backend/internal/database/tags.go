@@ -0,0 +1,223 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// GetSessionsPendingTagRules returns up to limit sessions that have not been
+// evaluated against the given version of the tagging rules, or were
+// evaluated before their opening prompt was imported and have grown since.
+// If sessionID is set only that session is considered.
+func (r *SessionRepository) GetSessionsPendingTagRules(version, sessionID string, limit int) ([]TagSubject, error) {
+	var subjects []TagSubject
+	err := r.db.Select(&subjects, `
+		SELECT
+			s.id AS session_id,
+			s.project_name,
+			s.project_path,
+			COALESCE(s.git_branch, '') AS git_branch,
+			COALESCE(s.message_count, 0) AS message_count
+		FROM sessions s
+		LEFT JOIN tag_rule_evaluations e ON e.session_id = s.id
+		WHERE (? = '' OR s.id = ?)
+		AND (
+			e.session_id IS NULL
+			OR e.rules_version != ?
+			OR (e.has_prompt = FALSE AND COALESCE(s.message_count, 0) > e.message_count)
+		)
+		ORDER BY s.start_time DESC
+		LIMIT ?
+	`, sessionID, sessionID, version, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find sessions pending tag rules: %w", err)
+	}
+	return subjects, nil
+}
+
+// ApplyRuleTags replaces a session's rule-applied tags with tags and records
+// that it was evaluated against version. Tags already applied by hand are
+// left as they are.
+func (r *SessionRepository) ApplyRuleTags(subject *TagSubject, version string, tags []SessionTag) error {
+	return r.db.WriteOperation(func(tx *sqlx.Tx) error {
+		if _, err := tx.Exec(`
+			DELETE FROM session_tags WHERE session_id = ? AND source = ?
+		`, subject.SessionID, TagSourceRule); err != nil {
+			return fmt.Errorf("failed to clear rule tags: %w", err)
+		}
+
+		for _, tag := range tags {
+			if _, err := tx.Exec(`
+				INSERT OR IGNORE INTO session_tags (session_id, tag, source, rule)
+				VALUES (?, ?, ?, ?)
+			`, subject.SessionID, tag.Tag, TagSourceRule, tag.Rule); err != nil {
+				return fmt.Errorf("failed to apply tag %s: %w", tag.Tag, err)
+			}
+		}
+
+		_, err := tx.Exec(`
+			INSERT INTO tag_rule_evaluations (session_id, rules_version, has_prompt, message_count)
+			VALUES (?, ?, ?, ?)
+			ON CONFLICT(session_id) DO UPDATE SET
+				rules_version = excluded.rules_version,
+				has_prompt = excluded.has_prompt,
+				message_count = excluded.message_count,
+				evaluated_at = CURRENT_TIMESTAMP
+		`, subject.SessionID, version, subject.FirstMessage != "", subject.MessageCount)
+		return err
+	})
+}
+
+// ResetTagRuleEvaluations marks every session as needing evaluation against
+// the tagging rules
+func (r *SessionRepository) ResetTagRuleEvaluations() error {
+	return r.db.WriteOperation(func(tx *sqlx.Tx) error {
+		_, err := tx.Exec(`DELETE FROM tag_rule_evaluations`)
+		return err
+	})
+}
+
+// GetSessionTags returns a session's tags in alphabetical order
+func (r *SessionRepository) GetSessionTags(sessionID string) ([]SessionTag, error) {
+	tags := []SessionTag{}
+	err := r.db.Select(&tags, `
+		SELECT * FROM session_tags WHERE session_id = ? ORDER BY tag ASC
+	`, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session tags: %w", err)
+	}
+	return tags, nil
+}
+
+// GetRuleTagCounts returns how many sessions each tagging rule has tagged
+func (r *SessionRepository) GetRuleTagCounts() (map[string]int, error) {
+	var rows []struct {
+		Rule     string `db:"rule"`
+		Sessions int    `db:"sessions"`
+	}
+	err := r.db.Select(&rows, `
+		SELECT rule, COUNT(*) AS sessions
+		FROM session_tags
+		WHERE source = ? AND rule IS NOT NULL
+		GROUP BY rule
+	`, TagSourceRule)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rule tag counts: %w", err)
+	}
+
+	counts := make(map[string]int, len(rows))
+	for _, row := range rows {
+		counts[row.Rule] = row.Sessions
+	}
+	return counts, nil
+}
+
+// GetSessionTagMap returns every session's tags keyed by session ID
+func (r *SessionRepository) GetSessionTagMap() (map[string][]string, error) {
+	var rows []SessionTag
+	if err := r.db.Select(&rows, `SELECT * FROM session_tags ORDER BY session_id, tag`); err != nil {
+		return nil, fmt.Errorf("failed to get session tags: %w", err)
+	}
+
+	tags := make(map[string][]string)
+	for _, row := range rows {
+		tags[row.SessionID] = append(tags[row.SessionID], row.Tag)
+	}
+	return tags, nil
+}
+
+// AddSessionTags tags a session by hand. A tag a rule already applied becomes
+// a manual tag, so rule changes no longer remove it.
+func (r *SessionRepository) AddSessionTags(sessionID string, tags []string) error {
+	return r.db.WriteOperation(func(tx *sqlx.Tx) error {
+		for _, tag := range tags {
+			if _, err := tx.Exec(`
+				INSERT INTO session_tags (session_id, tag, source)
+				VALUES (?, ?, ?)
+				ON CONFLICT(session_id, tag) DO UPDATE SET
+					source = excluded.source,
+					rule = NULL
+			`, sessionID, tag, TagSourceManual); err != nil {
+				return fmt.Errorf("failed to add tag %s: %w", tag, err)
+			}
+		}
+		return nil
+	})
+}
+
+// RemoveSessionTags removes tags from a session, reporting how many it had.
+// A rule-applied tag is applied again if the rules change or the session is
+// re-evaluated.
+func (r *SessionRepository) RemoveSessionTags(sessionID string, tags []string) (int, error) {
+	removed := 0
+	err := r.db.WriteOperation(func(tx *sqlx.Tx) error {
+		for _, tag := range tags {
+			result, err := tx.Exec(`
+				DELETE FROM session_tags WHERE session_id = ? AND tag = ?
+			`, sessionID, tag)
+			if err != nil {
+				return fmt.Errorf("failed to remove tag %s: %w", tag, err)
+			}
+			n, err := result.RowsAffected()
+			if err != nil {
+				return err
+			}
+			removed += int(n)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return removed, nil
+}
+
+// GetSessionIDsWithTags returns the IDs of the sessions carrying every one of
+// tags, matched case-insensitively
+func (r *SessionRepository) GetSessionIDsWithTags(tags []string) (map[string]bool, error) {
+	if len(tags) == 0 {
+		return map[string]bool{}, nil
+	}
+
+	query, args, err := taggedSessionsQuery(tags)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []string
+	if err := r.db.Select(&ids, r.db.Rebind(query), args...); err != nil {
+		return nil, fmt.Errorf("failed to get tagged sessions: %w", err)
+	}
+
+	matched := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		matched[id] = true
+	}
+	return matched, nil
+}
+
+// taggedSessionsQuery builds a query selecting the IDs of sessions carrying
+// every tag, ignoring case
+func taggedSessionsQuery(tags []string) (string, []interface{}, error) {
+	seen := make(map[string]bool, len(tags))
+	var lowered []string
+	for _, tag := range tags {
+		if tag = strings.ToLower(tag); !seen[tag] {
+			seen[tag] = true
+			lowered = append(lowered, tag)
+		}
+	}
+	query, args, err := sqlx.In(`
+		SELECT session_id
+		FROM session_tags
+		WHERE LOWER(tag) IN (?)
+		GROUP BY session_id
+		HAVING COUNT(DISTINCT LOWER(tag)) = ?
+	`, lowered, len(lowered))
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to build tag filter: %w", err)
+	}
+	return query, args, nil
+}
@@ -0,0 +1,86 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSessionRepository_ManualTags(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewSessionRepository(db, logger)
+
+	now := time.Now()
+	for _, id := range []string{"s1", "s2"} {
+		if err := repo.UpsertSession(&Session{ID: id, ProjectPath: "/work/app", ProjectName: "app", StartTime: now, LastActivity: now, Status: "completed"}); err != nil {
+			t.Fatalf("Failed to create session: %v", err)
+		}
+	}
+
+	rule := "incidents"
+	if err := repo.ApplyRuleTags(&TagSubject{SessionID: "s1"}, "v1", []SessionTag{{Tag: "prod-incident", Rule: &rule}}); err != nil {
+		t.Fatalf("Failed to apply rule tags: %v", err)
+	}
+	if err := repo.AddSessionTags("s1", []string{"prod-incident", "refactor"}); err != nil {
+		t.Fatalf("Failed to add tags: %v", err)
+	}
+	if err := repo.AddSessionTags("s2", []string{"refactor"}); err != nil {
+		t.Fatalf("Failed to add tags: %v", err)
+	}
+
+	// Tagging by hand takes a rule's tag over, so rule changes keep it
+	if err := repo.ApplyRuleTags(&TagSubject{SessionID: "s1"}, "v2", nil); err != nil {
+		t.Fatalf("Failed to apply rule tags: %v", err)
+	}
+	tags, err := repo.GetSessionTags("s1")
+	if err != nil {
+		t.Fatalf("Failed to get tags: %v", err)
+	}
+	if len(tags) != 2 || tags[0].Tag != "prod-incident" || tags[0].Source != TagSourceManual || tags[0].Rule != nil {
+		t.Errorf("Expected both tags to be manual, got %+v", tags)
+	}
+
+	ids, err := repo.GetSessionIDsWithTags([]string{"REFACTOR", "prod-incident"})
+	if err != nil {
+		t.Fatalf("Failed to filter by tags: %v", err)
+	}
+	if len(ids) != 1 || !ids["s1"] {
+		t.Errorf("Expected only s1 to carry both tags, got %v", ids)
+	}
+
+	removed, err := repo.RemoveSessionTags("s1", []string{"refactor", "missing"})
+	if err != nil || removed != 1 {
+		t.Fatalf("Expected one tag removed, got %d, %v", removed, err)
+	}
+	if ids, _ := repo.GetSessionIDsWithTags([]string{"refactor"}); len(ids) != 1 || !ids["s2"] {
+		t.Errorf("Expected only s2 to be tagged refactor, got %v", ids)
+	}
+}
+
+func TestSessionRepository_SessionNote(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewSessionRepository(db, logger)
+
+	if _, err := repo.GetSessionNote("s1"); err == nil {
+		t.Fatal("Expected no note")
+	}
+	for _, content := range []string{"First draft", "Rolled back in #412"} {
+		if err := repo.SetSessionNote("s1", content); err != nil {
+			t.Fatalf("Failed to set note: %v", err)
+		}
+	}
+	note, err := repo.GetSessionNote("s1")
+	if err != nil || note.Content != "Rolled back in #412" {
+		t.Fatalf("Expected the replaced note, got %+v, %v", note, err)
+	}
+
+	if err := repo.SetSessionNote("s1", ""); err != nil {
+		t.Fatalf("Failed to clear note: %v", err)
+	}
+	if _, err := repo.GetSessionNote("s1"); err == nil {
+		t.Error("Expected an empty note to remove it")
+	}
+}
@@ -0,0 +1,57 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// GetMessagesAfter returns up to limit of a session's messages that come
+// after a cursor, in order: after the message afterID if set, otherwise
+// after since if set. Messages are ordered by timestamp and then ID, so
+// messages sharing the cursor's timestamp aren't skipped. With no cursor it
+// returns the last limit messages, for a tail to start from.
+func (r *SessionRepository) GetMessagesAfter(sessionID, afterID string, since time.Time, limit int) ([]Message, error) {
+	messages := []Message{}
+	var err error
+	switch {
+	case afterID != "":
+		var after time.Time
+		err = r.db.Get(&after, `SELECT timestamp FROM messages WHERE id = ? AND session_id = ?`, afterID, sessionID)
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("message not found: %s", afterID)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to find cursor message: %w", err)
+		}
+		err = r.db.Select(&messages, `
+			SELECT `+messageColumns+`
+			FROM messages
+			WHERE session_id = ? AND (timestamp > ? OR (timestamp = ? AND id > ?))
+			ORDER BY timestamp ASC, id ASC
+			LIMIT ?
+		`, sessionID, after, after, afterID, limit)
+	case !since.IsZero():
+		err = r.db.Select(&messages, `
+			SELECT `+messageColumns+`
+			FROM messages
+			WHERE session_id = ? AND timestamp > ?
+			ORDER BY timestamp ASC, id ASC
+			LIMIT ?
+		`, sessionID, since.UTC(), limit)
+	default:
+		err = r.db.Select(&messages, `
+			SELECT * FROM (
+				SELECT `+messageColumns+`
+				FROM messages
+				WHERE session_id = ?
+				ORDER BY timestamp DESC, id DESC
+				LIMIT ?
+			) ORDER BY timestamp ASC, id ASC
+		`, sessionID, limit)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get messages: %w", err)
+	}
+	return messages, nil
+}
@@ -0,0 +1,59 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSessionRepository_GetMessagesAfter(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewSessionRepository(db, logger)
+	start := time.Now().UTC().Add(-time.Hour).Truncate(time.Second)
+	if err := repo.UpsertSession(&Session{ID: "s1", ProjectPath: "/work/app", ProjectName: "app", StartTime: start, LastActivity: start, Status: "active"}); err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	// m2 and m3 share a timestamp, so they are ordered by ID
+	for _, m := range []struct {
+		id string
+		at time.Duration
+	}{{"m1", 0}, {"m3", time.Second}, {"m2", time.Second}, {"m4", 2 * time.Second}} {
+		if err := repo.UpsertMessage(&Message{ID: m.id, SessionID: "s1", Role: "user", Content: "hi", Timestamp: start.Add(m.at)}); err != nil {
+			t.Fatalf("Failed to create message: %v", err)
+		}
+	}
+
+	ids := func(messages []Message) string {
+		var s string
+		for _, m := range messages {
+			s += m.ID
+		}
+		return s
+	}
+
+	last, err := repo.GetMessagesAfter("s1", "", time.Time{}, 2)
+	if err != nil {
+		t.Fatalf("Failed to get last messages: %v", err)
+	}
+	if got := ids(last); got != "m3m4" {
+		t.Errorf("Expected the last 2 messages in order, got %s", got)
+	}
+
+	after, err := repo.GetMessagesAfter("s1", "m2", time.Time{}, 10)
+	if err != nil {
+		t.Fatalf("Failed to get messages after m2: %v", err)
+	}
+	if got := ids(after); got != "m3m4" {
+		t.Errorf("Expected the messages after m2, including m3 at the same time, got %s", got)
+	}
+
+	since, _ := repo.GetMessagesAfter("s1", "", start, 10)
+	if got := ids(since); got != "m2m3m4" {
+		t.Errorf("Expected the messages after the first, got %s", got)
+	}
+
+	if _, err := repo.GetMessagesAfter("s1", "missing", time.Time{}, 10); err == nil {
+		t.Error("Expected an unknown cursor to fail")
+	}
+}
@@ -0,0 +1,110 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// GetThread stitches a session and every session resumed from it into one
+// transcript. Sessions are ordered by their distance from the root, then by
+// start time, and each session's messages follow in order, so a thread reads
+// from the first session to the last with the cost accumulating throughout.
+func (r *SessionRepository) GetThread(rootSessionID string) (*Thread, error) {
+	var exists bool
+	err := r.db.Get(&exists, `SELECT 1 FROM sessions WHERE id = ?`, rootSessionID)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("session not found: %s", rootSessionID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+
+	var sessions []ThreadSession
+	err = r.db.Select(&sessions, `
+		WITH RECURSIVE down(id, depth) AS (
+			SELECT ?, 0
+			UNION
+			SELECT l.session_id, down.depth + 1
+			FROM session_links l JOIN down ON l.parent_session_id = down.id
+			WHERE down.depth < ?
+		)
+		SELECT
+			s.id AS session_id,
+			CASE WHEN d.depth > 0 THEN l.parent_session_id END AS parent_session_id,
+			d.depth,
+			s.start_time,
+			s.last_activity,
+			s.message_count,
+			COALESCE(tu.cost, 0) AS cost,
+			COALESCE(tu.tokens, 0) AS tokens
+		FROM down d
+		JOIN sessions s ON s.id = d.id
+		LEFT JOIN session_links l ON l.session_id = s.id
+		LEFT JOIN (
+			SELECT session_id, SUM(estimated_cost) AS cost, SUM(total_tokens) AS tokens
+			FROM token_usage GROUP BY session_id
+		) tu ON tu.session_id = s.id
+		ORDER BY d.depth, s.start_time
+	`, rootSessionID, maxLineageDepth)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get thread sessions: %w", err)
+	}
+
+	sessionIDs := make([]string, len(sessions))
+	for i, session := range sessions {
+		sessionIDs[i] = session.SessionID
+	}
+	query, args, err := sqlx.In(`
+		SELECT
+			m.id,
+			m.session_id,
+			COALESCE(m.role, '') AS role,
+			COALESCE(m.content, '') AS content,
+			COALESCE(m.is_sidechain, FALSE) AS is_sidechain,
+			m.timestamp,
+			COALESCE((SELECT SUM(estimated_cost) FROM token_usage WHERE message_id = m.id), 0) AS cost
+		FROM messages m
+		WHERE m.session_id IN (?)
+		ORDER BY m.timestamp ASC
+	`, sessionIDs)
+	if err != nil {
+		return nil, err
+	}
+	var messages []ThreadMessage
+	if err := r.db.Select(&messages, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to get thread messages: %w", err)
+	}
+
+	bySession := make(map[string][]ThreadMessage, len(sessions))
+	for _, msg := range messages {
+		bySession[msg.SessionID] = append(bySession[msg.SessionID], msg)
+	}
+
+	thread := &Thread{
+		RootSessionID: rootSessionID,
+		StartTime:     sessions[0].StartTime,
+		LastActivity:  sessions[0].LastActivity,
+		Sessions:      sessions,
+		Messages:      make([]ThreadMessage, 0, len(messages)),
+	}
+	for i := range thread.Sessions {
+		session := &thread.Sessions[i]
+		for _, msg := range bySession[session.SessionID] {
+			thread.TotalCost += msg.Cost
+			msg.CumulativeCost = thread.TotalCost
+			thread.Messages = append(thread.Messages, msg)
+		}
+		session.CumulativeCost = thread.TotalCost
+		thread.TotalTokens += session.Tokens
+
+		if session.StartTime.Before(thread.StartTime) {
+			thread.StartTime = session.StartTime
+		}
+		if session.LastActivity.After(thread.LastActivity) {
+			thread.LastActivity = session.LastActivity
+		}
+	}
+	return thread, nil
+}
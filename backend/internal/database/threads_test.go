@@ -0,0 +1,74 @@
+package database
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGetThread(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewSessionRepository(db, logger)
+	dir := t.TempDir()
+	parents := map[string][]string{
+		// day two resumes day one from its last message
+		"day1": {`null`, `"a1"`},
+		"day2": {`"a2"`},
+	}
+	uuids := map[string][]string{"day1": {"a1", "a2"}, "day2": {"b1"}}
+	days := map[string]string{"day1": "01", "day2": "02"}
+	for _, sessionID := range []string{"day2", "day1"} {
+		var lines []string
+		for n, uuid := range uuids[sessionID] {
+			lines = append(lines, fmt.Sprintf(`{"parentUuid":%s,"cwd":"/work/app","sessionId":"%s","type":"assistant","message":{"role":"assistant","model":"claude-3-5-sonnet-20241022","content":"step %s","usage":{"input_tokens":1000000,"output_tokens":0}},"uuid":"%s","timestamp":"2025-01-%sT10:0%d:00Z"}`,
+				parents[sessionID][n], sessionID, uuid, uuid, days[sessionID], n))
+		}
+		path := filepath.Join(dir, sessionID+".jsonl")
+		if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+			t.Fatalf("Failed to write transcript: %v", err)
+		}
+		if _, _, err := NewImporter(repo, logger).ImportJSONLFile(path, ProjectInfo{}); err != nil {
+			t.Fatalf("Failed to import: %v", err)
+		}
+	}
+
+	thread, err := repo.GetThread("day1")
+	if err != nil {
+		t.Fatalf("Failed to get thread: %v", err)
+	}
+	if len(thread.Sessions) != 2 || thread.Sessions[0].SessionID != "day1" || thread.Sessions[1].SessionID != "day2" {
+		t.Fatalf("Expected day1 then day2, got %+v", thread.Sessions)
+	}
+	var order []string
+	for _, msg := range thread.Messages {
+		order = append(order, msg.ID)
+	}
+	if got := strings.Join(order, ","); got != "a1,a2,b1" {
+		t.Errorf("Expected the messages in thread order, got %s", got)
+	}
+
+	// Each message costs $3 at Sonnet input pricing
+	last := thread.Messages[len(thread.Messages)-1]
+	if math.Abs(last.CumulativeCost-9) > 1e-9 || math.Abs(thread.TotalCost-9) > 1e-9 {
+		t.Errorf("Expected a cumulative cost of 9, got %v and %v", last.CumulativeCost, thread.TotalCost)
+	}
+	if math.Abs(thread.Sessions[0].CumulativeCost-6) > 1e-9 {
+		t.Errorf("Expected day1 to end at a cost of 6, got %v", thread.Sessions[0].CumulativeCost)
+	}
+	if thread.TotalTokens != 3000000 {
+		t.Errorf("Expected 3000000 tokens, got %d", thread.TotalTokens)
+	}
+
+	// A thread can start from a resumed session
+	if thread, err := repo.GetThread("day2"); err != nil || len(thread.Sessions) != 1 || thread.Sessions[0].ParentSessionID != nil {
+		t.Errorf("Expected day2 alone, got %+v (%v)", thread, err)
+	}
+	if _, err := repo.GetThread("missing"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("Expected not found, got %v", err)
+	}
+}
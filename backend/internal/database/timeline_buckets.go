@@ -0,0 +1,148 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxTimelineWindow bounds windows so a timeline always has a bucket per
+// few months at least
+const maxTimelineWindow = 366 * 24 * time.Hour
+
+// MaxTimelineBuckets bounds how many buckets a gap-filled timeline may have
+const MaxTimelineBuckets = 10000
+
+// ErrTooManyTimelineBuckets is returned when filling a timeline's gaps would
+// produce more than MaxTimelineBuckets buckets
+var ErrTooManyTimelineBuckets = errors.New("too many timeline buckets")
+
+// IsTimelineGranularity reports whether granularity is a timeline bucket:
+// minute, hour, day, week (ISO, starting Monday), month, or a fixed window of
+// whole minutes such as 15m, 6h or 2d
+func IsTimelineGranularity(granularity string) bool {
+	switch granularity {
+	case "minute", "hour", "day", "week", "month":
+		return true
+	}
+	_, ok := parseTimelineWindow(granularity)
+	return ok
+}
+
+// parseTimelineWindow parses a window such as 15m, 6h, 1h30m or 2d
+func parseTimelineWindow(granularity string) (time.Duration, bool) {
+	var window time.Duration
+	if days, ok := strings.CutSuffix(granularity, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, false
+		}
+		window = time.Duration(n) * 24 * time.Hour
+	} else {
+		var err error
+		if window, err = time.ParseDuration(granularity); err != nil {
+			return 0, false
+		}
+	}
+	if window < time.Minute || window > maxTimelineWindow || window%time.Minute != 0 {
+		return 0, false
+	}
+	return window, true
+}
+
+// timelineBucketSQL returns an SQL expression truncating the timestamp column
+// to the UTC start of its bucket, formatted like 2006-01-02 15:04:00. Windows
+// are aligned to the Unix epoch, so 6h buckets start at 00:00, 06:00, 12:00
+// and 18:00. An unknown granularity uses fallback.
+func timelineBucketSQL(column, granularity, fallback string) string {
+	if !IsTimelineGranularity(granularity) {
+		granularity = fallback
+	}
+	switch granularity {
+	case "minute":
+		return fmt.Sprintf("strftime('%%Y-%%m-%%d %%H:%%M:00', %s)", column)
+	case "hour":
+		return fmt.Sprintf("strftime('%%Y-%%m-%%d %%H:00:00', %s)", column)
+	case "day":
+		return fmt.Sprintf("strftime('%%Y-%%m-%%d 00:00:00', %s)", column)
+	case "week":
+		return fmt.Sprintf("strftime('%%Y-%%m-%%d 00:00:00', %s, '-6 days', 'weekday 1')", column)
+	case "month":
+		return fmt.Sprintf("strftime('%%Y-%%m-01 00:00:00', %s)", column)
+	}
+	seconds := timelineWindowSeconds(granularity)
+	return fmt.Sprintf("strftime('%%Y-%%m-%%d %%H:%%M:%%S', CAST(strftime('%%s', %s) AS INTEGER) / %d * %d, 'unixepoch')", column, seconds, seconds)
+}
+
+// timelineWindowSeconds returns the length of a valid window granularity
+func timelineWindowSeconds(granularity string) int64 {
+	window, _ := parseTimelineWindow(granularity)
+	return int64(window / time.Second)
+}
+
+// FillTimeline returns a continuous series of the buckets overlapping
+// [from, to), taking each bucket's figures from entries and leaving buckets
+// without messages at zero. Labels match those of the timeline queries.
+func FillTimeline(entries []TokenTimelineEntry, from, to time.Time, granularity, fallback string) ([]TokenTimelineEntry, error) {
+	if !IsTimelineGranularity(granularity) {
+		granularity = fallback
+	}
+	byBucket := make(map[string]TokenTimelineEntry, len(entries))
+	for _, entry := range entries {
+		byBucket[entry.Timestamp] = entry
+	}
+
+	var filled []TokenTimelineEntry
+	for bucket := timelineBucketStart(from.UTC(), granularity); bucket.Before(to); bucket = nextTimelineBucket(bucket, granularity) {
+		if len(filled) == MaxTimelineBuckets {
+			return nil, fmt.Errorf("%w: more than %d %s buckets", ErrTooManyTimelineBuckets, MaxTimelineBuckets, granularity)
+		}
+		label := bucket.Format("2006-01-02 15:04:05")
+		entry, ok := byBucket[label]
+		if !ok {
+			entry = TokenTimelineEntry{Timestamp: label}
+		}
+		filled = append(filled, entry)
+	}
+	return filled, nil
+}
+
+// timelineBucketStart truncates a UTC time to the start of its bucket, as
+// timelineBucketSQL does
+func timelineBucketStart(t time.Time, granularity string) time.Time {
+	switch granularity {
+	case "minute":
+		return t.Truncate(time.Minute)
+	case "hour":
+		return t.Truncate(time.Hour)
+	case "day":
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	case "week":
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	case "month":
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+	seconds := timelineWindowSeconds(granularity)
+	unix := t.Unix()
+	return time.Unix(unix-unix%seconds, 0).UTC()
+}
+
+// nextTimelineBucket returns the start of the bucket after the one starting at t
+func nextTimelineBucket(t time.Time, granularity string) time.Time {
+	switch granularity {
+	case "minute":
+		return t.Add(time.Minute)
+	case "hour":
+		return t.Add(time.Hour)
+	case "day":
+		return t.AddDate(0, 0, 1)
+	case "week":
+		return t.AddDate(0, 0, 7)
+	case "month":
+		return t.AddDate(0, 1, 0)
+	}
+	return t.Add(time.Duration(timelineWindowSeconds(granularity)) * time.Second)
+}
@@ -0,0 +1,65 @@
+package database
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// RecalculateTokenCosts reprices every stored token usage row from the shared
+// pricing table, so a price change applies to past sessions too. Cache
+// savings are repriced with the cost. Frozen monthly snapshots are left as
+// they were. It returns the number of rows whose cost or savings changed.
+func (r *SessionRepository) RecalculateTokenCosts() (int, error) {
+	var changed int
+	err := r.db.WriteOperation(func(tx *sqlx.Tx) error {
+		var err error
+		changed, err = repriceTokenUsage(tx)
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to recalculate token costs: %w", err)
+	}
+
+	r.logger.WithField("changed", changed).Info("Recalculated token costs")
+	return changed, nil
+}
+
+// repriceTokenUsage updates the cost and cache savings of every token usage
+// row with the model that wrote its message, or its session's for rows
+// imported before that was stored, returning how many changed
+func repriceTokenUsage(tx *sqlx.Tx) (int, error) {
+	var rows []TokenUsage
+	if err := tx.Select(&rows, `
+		SELECT tu.id, tu.message_id, tu.session_id, tu.input_tokens, tu.output_tokens,
+			tu.cache_creation_input_tokens, tu.cache_read_input_tokens, tu.total_tokens,
+			COALESCE(tu.estimated_cost, 0) as estimated_cost,
+			COALESCE(tu.cache_savings, 0) as cache_savings,
+			COALESCE(NULLIF(tu.model, ''), s.model, '') as model
+		FROM token_usage tu
+		JOIN sessions s ON s.id = tu.session_id
+	`); err != nil {
+		return 0, err
+	}
+
+	stmt, err := tx.Preparex(`UPDATE token_usage SET estimated_cost = ?, cache_savings = ? WHERE id = ?`)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	changed := 0
+	for _, row := range rows {
+		cost := estimateTokenCost(&row, row.Model)
+		savings := estimateCacheSavings(&row, row.Model)
+		if math.Abs(cost-row.EstimatedCost) < 1e-12 && math.Abs(savings-row.CacheSavings) < 1e-12 {
+			continue
+		}
+		if _, err := stmt.Exec(cost, savings, row.ID); err != nil {
+			return 0, err
+		}
+		changed++
+	}
+	return changed, nil
+}
@@ -0,0 +1,115 @@
+package database
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ksred/claude-session-manager/internal/pricing"
+)
+
+func TestRecalculateTokenCosts(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewSessionRepository(db, logger)
+	path := filepath.Join(t.TempDir(), "priced.jsonl")
+	line := `{"parentUuid":null,"cwd":"/work/app","sessionId":"priced","type":"assistant","message":{"role":"assistant","model":"claude-sonnet-4-20250514","content":"hi","usage":{"input_tokens":1000000,"output_tokens":0}},"uuid":"p1","timestamp":"2025-01-01T10:00:00Z"}`
+	if err := os.WriteFile(path, []byte(line+"\n"), 0644); err != nil {
+		t.Fatalf("Failed to write transcript: %v", err)
+	}
+	if _, _, err := NewImporter(repo, logger).ImportJSONLFile(path, ProjectInfo{}); err != nil {
+		t.Fatalf("Failed to import: %v", err)
+	}
+
+	cost := func() float64 {
+		var cost float64
+		if err := db.Get(&cost, `SELECT estimated_cost FROM token_usage WHERE message_id = 'p1'`); err != nil {
+			t.Fatalf("Failed to read cost: %v", err)
+		}
+		return cost
+	}
+	if got := cost(); math.Abs(got-3) > 1e-9 {
+		t.Fatalf("Expected $3 at the built-in Sonnet price, got %v", got)
+	}
+
+	if err := pricing.Default().SetOverrides(pricing.PriceList{Models: []pricing.Price{{Model: "claude-sonnet-4", Input: 6}}}); err != nil {
+		t.Fatalf("Failed to set prices: %v", err)
+	}
+	defer pricing.Default().SetOverrides(pricing.PriceList{})
+
+	changed, err := repo.RecalculateTokenCosts()
+	if err != nil {
+		t.Fatalf("Failed to recalculate: %v", err)
+	}
+	if changed != 1 || math.Abs(cost()-6) > 1e-9 {
+		t.Errorf("Expected one row repriced to $6, got %d and %v", changed, cost())
+	}
+
+	if changed, _ := repo.RecalculateTokenCosts(); changed != 0 {
+		t.Errorf("Expected nothing to change on a second run, got %d", changed)
+	}
+}
+
+func TestImportStoresCacheSavings(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewSessionRepository(db, logger)
+	path := filepath.Join(t.TempDir(), "cached.jsonl")
+	lines := `{"cwd":"/work/app","sessionId":"cached","type":"assistant","message":{"role":"assistant","model":"claude-sonnet-4-20250514","content":"hi","usage":{"input_tokens":10,"output_tokens":0,"cache_creation_input_tokens":1000000}},"uuid":"c1","timestamp":"2025-01-01T10:00:00Z"}
+{"cwd":"/work/app","sessionId":"cached","type":"assistant","message":{"role":"assistant","model":"claude-sonnet-4-20250514","content":"again","usage":{"input_tokens":10,"output_tokens":0,"cache_read_input_tokens":2000000}},"uuid":"c2","timestamp":"2025-01-01T10:01:00Z"}
+`
+	if err := os.WriteFile(path, []byte(lines), 0644); err != nil {
+		t.Fatalf("Failed to write transcript: %v", err)
+	}
+	if _, _, err := NewImporter(repo, logger).ImportJSONLFile(path, ProjectInfo{}); err != nil {
+		t.Fatalf("Failed to import: %v", err)
+	}
+
+	// Writing a million tokens costs $0.75 more than sending them fresh at
+	// Sonnet prices; reading two million saves $5.40
+	summary, err := repo.GetSessionByID("cached")
+	if err != nil {
+		t.Fatalf("Failed to get session: %v", err)
+	}
+	if math.Abs(summary.TotalCacheSavings-4.65) > 1e-9 {
+		t.Errorf("Expected $4.65 saved by the session's cache use, got %v", summary.TotalCacheSavings)
+	}
+
+	response, err := NewAPIAdapter(repo).SessionSummaryToSessionResponse(summary)
+	if err != nil || math.Abs(response.CacheSavings-4.65) > 1e-9 {
+		t.Errorf("Expected the savings in the session response, got %+v (%v)", response, err)
+	}
+}
+
+func TestRecalculateTokenCosts_MixedModels(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewSessionRepository(db, logger)
+	path := filepath.Join(t.TempDir(), "mixed.jsonl")
+	lines := `{"cwd":"/work/app","sessionId":"mixed","type":"assistant","message":{"role":"assistant","model":"claude-opus-4-20250514","content":"plan","usage":{"input_tokens":1000000,"output_tokens":0}},"uuid":"o1","timestamp":"2025-01-01T10:00:00Z"}
+{"cwd":"/work/app","sessionId":"mixed","type":"assistant","isSidechain":true,"message":{"role":"assistant","model":"claude-3-5-haiku-20241022","content":"search","usage":{"input_tokens":1000000,"output_tokens":0}},"uuid":"h1","timestamp":"2025-01-01T10:01:00Z"}
+`
+	if err := os.WriteFile(path, []byte(lines), 0644); err != nil {
+		t.Fatalf("Failed to write transcript: %v", err)
+	}
+	if _, _, err := NewImporter(repo, logger).ImportJSONLFile(path, ProjectInfo{}); err != nil {
+		t.Fatalf("Failed to import: %v", err)
+	}
+
+	var models []string
+	if err := db.Select(&models, `SELECT model FROM token_usage ORDER BY message_id DESC`); err != nil {
+		t.Fatalf("Failed to read models: %v", err)
+	}
+	if len(models) != 2 || models[0] != "claude-opus-4-20250514" || models[1] != "claude-3-5-haiku-20241022" {
+		t.Errorf("Expected each message's model stored, got %v", models)
+	}
+
+	// Each message keeps the price of its own model
+	if changed, err := repo.RecalculateTokenCosts(); err != nil || changed != 0 {
+		t.Errorf("Expected nothing repriced with unchanged prices, got %d (%v)", changed, err)
+	}
+}
@@ -0,0 +1,310 @@
+package database
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// toolCallBatchSize limits how many sessions have their tool calls extracted
+// per transaction
+const toolCallBatchSize = 50
+
+// ToolUsageFilter selects the tool calls analytics cover: those made from
+// Since, if set, in sessions of Project (a display name or any of its
+// aliases) or the one session SessionID. Archived sessions are left out.
+type ToolUsageFilter struct {
+	Since     time.Time
+	Project   string
+	SessionID string
+}
+
+// where returns the conditions on tool_calls tc joined to sessions s and
+// their arguments
+func (f ToolUsageFilter) where() (string, []interface{}) {
+	where := `WHERE s.archived_at IS NULL`
+	var args []interface{}
+	if !f.Since.IsZero() {
+		where += ` AND tc.timestamp >= ?`
+		args = append(args, f.Since.UTC())
+	}
+	if f.Project != "" {
+		where += ` AND s.project_name IN (
+			SELECT alias FROM project_aliases WHERE project_id IN (
+				SELECT project_id FROM project_aliases WHERE alias = ?
+				UNION SELECT id FROM projects WHERE display_name = ?
+			)
+			UNION SELECT ?
+		)`
+		args = append(args, f.Project, f.Project, f.Project)
+	}
+	if f.SessionID != "" {
+		where += ` AND tc.session_id = ?`
+		args = append(args, f.SessionID)
+	}
+	return where, args
+}
+
+// RefreshToolCalls extracts the tool calls of every session whose message
+// count changed since it was last scanned and returns how many sessions were
+// scanned
+func (r *SessionRepository) RefreshToolCalls() (int, error) {
+	var sessionIDs []string
+	err := r.db.Select(&sessionIDs, `
+		SELECT s.id
+		FROM sessions s
+		LEFT JOIN tool_call_scans tcs ON tcs.session_id = s.id
+		WHERE tcs.session_id IS NULL OR tcs.message_count != s.message_count
+	`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to find sessions to scan for tool calls: %w", err)
+	}
+
+	for start := 0; start < len(sessionIDs); start += toolCallBatchSize {
+		end := min(start+toolCallBatchSize, len(sessionIDs))
+		err := r.db.WriteOperation(func(tx *sqlx.Tx) error {
+			for _, sessionID := range sessionIDs[start:end] {
+				if err := extractToolCalls(tx, sessionID); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return start, fmt.Errorf("failed to extract tool calls: %w", err)
+		}
+	}
+	return len(sessionIDs), nil
+}
+
+// RefreshSessionToolCalls extracts one session's tool calls and commands if
+// its message count changed since it was last scanned, so reads of a live
+// session needn't wait for the next refresh
+func (r *SessionRepository) RefreshSessionToolCalls(sessionID string) error {
+	var stale bool
+	err := r.db.Get(&stale, `
+		SELECT COUNT(*) > 0
+		FROM sessions s
+		LEFT JOIN tool_call_scans tcs ON tcs.session_id = s.id
+		WHERE s.id = ? AND (tcs.session_id IS NULL OR tcs.message_count != s.message_count)
+	`, sessionID)
+	if err != nil || !stale {
+		return err
+	}
+	return r.db.WriteOperation(func(tx *sqlx.Tx) error {
+		if err := extractToolCalls(tx, sessionID); err != nil {
+			return fmt.Errorf("failed to extract tool calls: %w", err)
+		}
+		return nil
+	})
+}
+
+// extractToolCalls replaces a session's tool calls and commands with those in
+// its messages, timing each call from its assistant message to the message
+// carrying its result
+func extractToolCalls(tx *sqlx.Tx, sessionID string) error {
+	var messageCount int
+	if err := tx.Get(&messageCount, `SELECT message_count FROM sessions WHERE id = ?`, sessionID); err != nil {
+		return err
+	}
+
+	var messages []struct {
+		ID        string    `db:"id"`
+		Role      string    `db:"role"`
+		Content   string    `db:"content"`
+		Cwd       *string   `db:"cwd"`
+		Timestamp time.Time `db:"timestamp"`
+	}
+	err := tx.Select(&messages, `
+		SELECT id, role, COALESCE(content, '') AS content, NULLIF(cwd, '') AS cwd, timestamp
+		FROM messages
+		WHERE session_id = ?
+		AND (content LIKE '%"tool_use"%' OR content LIKE '%"tool_result"%' OR content LIKE '%<invoke name=%')
+		ORDER BY timestamp
+	`, sessionID)
+	if err != nil {
+		return err
+	}
+
+	var calls []*ToolCallRecord
+	var commands []*Command
+	pending := make(map[string]*ToolCallRecord)
+	pendingCommands := make(map[string]*Command)
+	for _, msg := range messages {
+		if msg.Role == "assistant" {
+			for _, call := range ExtractToolCallsFromMessage(msg.Content, msg.Timestamp) {
+				record := &ToolCallRecord{
+					SessionID: sessionID,
+					MessageID: msg.ID,
+					ToolName:  call.ToolName,
+					Timestamp: msg.Timestamp,
+				}
+				if call.ID != "" {
+					id := call.ID
+					record.ToolUseID = &id
+					pending[id] = record
+				}
+				calls = append(calls, record)
+
+				if command := bashCommand(call); command != nil {
+					command.SessionID = sessionID
+					command.MessageID = msg.ID
+					command.ToolUseID = record.ToolUseID
+					command.Cwd = msg.Cwd
+					command.Timestamp = msg.Timestamp
+					if call.ID != "" {
+						pendingCommands[call.ID] = command
+					}
+					commands = append(commands, command)
+				}
+			}
+			continue
+		}
+		for _, ref := range ExtractToolResultRefs(msg.Content) {
+			if command, ok := pendingCommands[ref.ToolUseID]; ok {
+				command.ExitCode = commandExitCode(ref)
+				delete(pendingCommands, ref.ToolUseID)
+			}
+			record, ok := pending[ref.ToolUseID]
+			if !ok {
+				continue
+			}
+			duration := msg.Timestamp.Sub(record.Timestamp).Milliseconds()
+			if duration < 0 {
+				duration = 0
+			}
+			record.DurationMs = &duration
+			record.IsError = ref.IsError
+			delete(pending, ref.ToolUseID)
+		}
+	}
+
+	if _, err := tx.Exec(`DELETE FROM tool_calls WHERE session_id = ?`, sessionID); err != nil {
+		return err
+	}
+	for _, call := range calls {
+		_, err := tx.NamedExec(`
+			INSERT INTO tool_calls (session_id, message_id, tool_use_id, tool_name, timestamp, duration_ms, is_error)
+			VALUES (:session_id, :message_id, :tool_use_id, :tool_name, :timestamp, :duration_ms, :is_error)
+		`, call)
+		if err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(`DELETE FROM commands WHERE session_id = ?`, sessionID); err != nil {
+		return err
+	}
+	for _, command := range commands {
+		_, err := tx.NamedExec(`
+			INSERT INTO commands (session_id, message_id, tool_use_id, command, description, cwd, exit_code, timestamp)
+			VALUES (:session_id, :message_id, :tool_use_id, :command, :description, :cwd, :exit_code, :timestamp)
+		`, command)
+		if err != nil {
+			return err
+		}
+	}
+	_, err = tx.Exec(`
+		INSERT INTO tool_call_scans (session_id, message_count, scanned_at)
+		VALUES (?, ?, ?)
+		ON CONFLICT(session_id) DO UPDATE SET
+			message_count = excluded.message_count,
+			scanned_at = excluded.scanned_at
+	`, sessionID, messageCount, time.Now().UTC())
+	return err
+}
+
+// GetToolUsage returns how often each tool was called and how long its
+// results took, most called first
+func (r *SessionRepository) GetToolUsage(filter ToolUsageFilter) ([]ToolUsage, error) {
+	where, args := filter.where()
+	usage := []ToolUsage{}
+	err := r.db.Select(&usage, `
+		SELECT
+			tc.tool_name AS tool,
+			COUNT(*) AS calls,
+			COUNT(DISTINCT tc.session_id) AS sessions,
+			COALESCE(SUM(tc.is_error), 0) AS errors,
+			COUNT(tc.duration_ms) AS completed,
+			COALESCE(AVG(tc.duration_ms), 0.0) AS avg_duration_ms,
+			COALESCE(MAX(tc.duration_ms), 0) AS max_duration_ms,
+			COUNT(CASE WHEN tc.duration_ms < 1000 THEN 1 END) AS under_1s,
+			COUNT(CASE WHEN tc.duration_ms >= 1000 AND tc.duration_ms < 5000 THEN 1 END) AS under_5s,
+			COUNT(CASE WHEN tc.duration_ms >= 5000 AND tc.duration_ms < 30000 THEN 1 END) AS under_30s,
+			COUNT(CASE WHEN tc.duration_ms >= 30000 AND tc.duration_ms < 120000 THEN 1 END) AS under_2m,
+			COUNT(CASE WHEN tc.duration_ms >= 120000 AND tc.duration_ms < 600000 THEN 1 END) AS under_10m,
+			COUNT(CASE WHEN tc.duration_ms >= 600000 THEN 1 END) AS over_10m
+		FROM tool_calls tc
+		JOIN sessions s ON s.id = tc.session_id
+		`+where+`
+		GROUP BY tc.tool_name
+		ORDER BY calls DESC, tool
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tool usage: %w", err)
+	}
+	return usage, nil
+}
+
+// GetToolUsageByProject returns each project's tool calls, most calls first,
+// up to limit projects
+func (r *SessionRepository) GetToolUsageByProject(filter ToolUsageFilter, limit int) ([]ToolUsageGroup, error) {
+	return r.getToolUsageGroups(filter, `COALESCE(p.display_name, s.project_name)`, `''`, limit)
+}
+
+// GetToolUsageBySession returns each session's tool calls with its project,
+// most calls first, up to limit sessions
+func (r *SessionRepository) GetToolUsageBySession(filter ToolUsageFilter, limit int) ([]ToolUsageGroup, error) {
+	return r.getToolUsageGroups(filter, `s.id`, `COALESCE(p.display_name, s.project_name)`, limit)
+}
+
+// getToolUsageGroups counts tool calls by key and tool and groups them by key
+func (r *SessionRepository) getToolUsageGroups(filter ToolUsageFilter, key, project string, limit int) ([]ToolUsageGroup, error) {
+	where, args := filter.where()
+	var rows []struct {
+		Key     string `db:"key"`
+		Project string `db:"project"`
+		ToolCount
+	}
+	err := r.db.Select(&rows, `
+		SELECT
+			`+key+` AS key,
+			`+project+` AS project,
+			tc.tool_name AS tool,
+			COUNT(*) AS calls,
+			COALESCE(SUM(tc.is_error), 0) AS errors
+		FROM tool_calls tc
+		JOIN sessions s ON s.id = tc.session_id
+		LEFT JOIN project_aliases pa ON pa.alias = s.project_name
+		LEFT JOIN projects p ON p.id = pa.project_id
+		`+where+`
+		GROUP BY `+key+`, tc.tool_name
+		ORDER BY calls DESC, tool
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tool usage groups: %w", err)
+	}
+
+	groups := []ToolUsageGroup{}
+	index := make(map[string]int)
+	for _, row := range rows {
+		i, ok := index[row.Key]
+		if !ok {
+			i = len(groups)
+			index[row.Key] = i
+			groups = append(groups, ToolUsageGroup{Key: row.Key, Project: row.Project, Tools: []ToolCount{}})
+		}
+		groups[i].Calls += row.Calls
+		groups[i].Errors += row.Errors
+		groups[i].Tools = append(groups[i].Tools, row.ToolCount)
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		return groups[i].Calls > groups[j].Calls
+	})
+	if limit > 0 && len(groups) > limit {
+		groups = groups[:limit]
+	}
+	return groups, nil
+}
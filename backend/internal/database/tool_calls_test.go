@@ -0,0 +1,90 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSessionRepository_ToolCalls(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewSessionRepository(db, logger)
+	start := time.Now().UTC().Add(-time.Hour).Truncate(time.Second)
+
+	messages := []struct {
+		session, id, role, content string
+		at                         time.Duration
+	}{
+		{"s1", "m1", "assistant", `[{"type":"text","text":"Looking"},{"type":"tool_use","id":"tu1","name":"Bash","input":{"command":"go test ./..."}},{"type":"tool_use","id":"tu2","name":"Read","input":{"file_path":"/work/app/main.go"}}]`, 0},
+		{"s1", "m2", "user", `[{"type":"tool_result","tool_use_id":"tu1","content":"FAIL","is_error":true}]`, 40 * time.Second},
+		{"s1", "m3", "user", `[{"type":"tool_result","tool_use_id":"tu2","content":"package main"}]`, 500 * time.Millisecond},
+		{"s1", "m4", "assistant", `[{"type":"tool_use","id":"tu3","name":"Bash","input":{"command":"ls"}}]`, time.Minute},
+		{"s2", "m5", "assistant", `[{"type":"tool_use","id":"tu4","name":"Edit","input":{"file_path":"/work/docs/README.md"}}]`, 0},
+		{"s2", "m6", "user", `[{"type":"tool_result","tool_use_id":"tu4","content":"ok"}]`, 2 * time.Second},
+	}
+	counts := map[string]int{"s1": 4, "s2": 2}
+	for _, project := range []struct{ session, name string }{{"s1", "app"}, {"s2", "docs"}} {
+		if err := repo.UpsertSession(&Session{ID: project.session, ProjectPath: "/work/" + project.name, ProjectName: project.name, StartTime: start, LastActivity: start, Status: "completed", MessageCount: counts[project.session]}); err != nil {
+			t.Fatalf("Failed to create session: %v", err)
+		}
+	}
+	for _, m := range messages {
+		if err := repo.UpsertMessage(&Message{ID: m.id, SessionID: m.session, Role: m.role, Content: m.content, Timestamp: start.Add(m.at)}); err != nil {
+			t.Fatalf("Failed to create message: %v", err)
+		}
+	}
+
+	scanned, err := repo.RefreshToolCalls()
+	if err != nil {
+		t.Fatalf("Failed to extract tool calls: %v", err)
+	}
+	if scanned != 2 {
+		t.Errorf("Expected 2 sessions scanned, got %d", scanned)
+	}
+	if scanned, _ := repo.RefreshToolCalls(); scanned != 0 {
+		t.Errorf("Expected unchanged sessions to be skipped, got %d", scanned)
+	}
+
+	tools, err := repo.GetToolUsage(ToolUsageFilter{})
+	if err != nil {
+		t.Fatalf("Failed to get tool usage: %v", err)
+	}
+	if len(tools) != 3 || tools[0].Tool != "Bash" {
+		t.Fatalf("Expected Bash, Edit and Read, most called first, got %+v", tools)
+	}
+	if bash := tools[0]; bash.Calls != 2 || bash.Errors != 1 || bash.Completed != 1 || bash.MaxDurationMs != 40000 || bash.Under2m != 1 {
+		t.Errorf("Expected one failed 40s Bash call and one without a result, got %+v", bash)
+	}
+
+	sessions, err := repo.GetToolUsageBySession(ToolUsageFilter{}, 10)
+	if err != nil {
+		t.Fatalf("Failed to get tool usage by session: %v", err)
+	}
+	if len(sessions) != 2 || sessions[0].Key != "s1" || sessions[0].Project != "app" || sessions[0].Calls != 3 || len(sessions[0].Tools) != 2 {
+		t.Errorf("Expected s1 first with 3 calls of 2 tools, got %+v", sessions)
+	}
+
+	projects, err := repo.GetToolUsageByProject(ToolUsageFilter{Project: "docs"}, 10)
+	if err != nil {
+		t.Fatalf("Failed to get tool usage by project: %v", err)
+	}
+	if len(projects) != 1 || projects[0].Key != "docs" || projects[0].Tools[0].Tool != "Edit" {
+		t.Errorf("Expected only docs, got %+v", projects)
+	}
+
+	// A session is rescanned when its message count changes
+	if err := repo.UpsertMessage(&Message{ID: "m7", SessionID: "s1", Role: "user", Content: `[{"type":"tool_result","tool_use_id":"tu3","content":""}]`, Timestamp: start.Add(61 * time.Second)}); err != nil {
+		t.Fatalf("Failed to create message: %v", err)
+	}
+	if err := repo.UpsertSession(&Session{ID: "s1", ProjectPath: "/work/app", ProjectName: "app", StartTime: start, LastActivity: start, Status: "completed", MessageCount: 5}); err != nil {
+		t.Fatalf("Failed to update session: %v", err)
+	}
+	if scanned, _ := repo.RefreshToolCalls(); scanned != 1 {
+		t.Errorf("Expected the changed session to be rescanned, got %d", scanned)
+	}
+	tools, _ = repo.GetToolUsage(ToolUsageFilter{SessionID: "s1"})
+	if len(tools) != 2 || tools[0].Completed != 2 || tools[0].Under5s != 1 {
+		t.Errorf("Expected both Bash calls completed, got %+v", tools)
+	}
+}
@@ -8,6 +8,7 @@ import (
 
 // ToolCall represents a parsed tool invocation from a message
 type ToolCall struct {
+	ID         string // tool_use block ID, which its tool_result refers to; empty for other formats
 	ToolName   string
 	FilePath   string
 	Parameters map[string]interface{}
@@ -60,6 +61,7 @@ func parseJSONToolCall(data interface{}, timestamp time.Time) *ToolCall {
 	}
 
 	// Check for different tool call formats
+	var id string
 	var toolName string
 	var filePath string
 	var params map[string]interface{}
@@ -69,6 +71,7 @@ func parseJSONToolCall(data interface{}, timestamp time.Time) *ToolCall {
 		if name, ok := obj["name"].(string); ok {
 			toolName = name
 		}
+		id, _ = obj["id"].(string)
 		if input, ok := obj["input"].(map[string]interface{}); ok {
 			params = input
 			if fp, ok := input["file_path"].(string); ok {
@@ -111,6 +114,7 @@ func parseJSONToolCall(data interface{}, timestamp time.Time) *ToolCall {
 	}
 
 	return &ToolCall{
+		ID:         id,
 		ToolName:   toolName,
 		FilePath:   filePath,
 		Parameters: params,
@@ -118,6 +122,63 @@ func parseJSONToolCall(data interface{}, timestamp time.Time) *ToolCall {
 	}
 }
 
+// ToolResultRef is a tool_result block, naming the tool_use it answers, with
+// its text
+type ToolResultRef struct {
+	ToolUseID string
+	IsError   bool
+	Content   string
+}
+
+// ExtractToolResultRefs returns the tool_result blocks in message content
+// stored as a JSON array of content blocks
+func ExtractToolResultRefs(content string) []ToolResultRef {
+	var blocks []struct {
+		Type      string          `json:"type"`
+		ToolUseID string          `json:"tool_use_id"`
+		IsError   bool            `json:"is_error"`
+		Content   json.RawMessage `json:"content"`
+	}
+	if err := json.Unmarshal([]byte(content), &blocks); err != nil {
+		return nil
+	}
+
+	var refs []ToolResultRef
+	for _, block := range blocks {
+		if block.Type == "tool_result" && block.ToolUseID != "" {
+			refs = append(refs, ToolResultRef{
+				ToolUseID: block.ToolUseID,
+				IsError:   block.IsError,
+				Content:   toolResultText(block.Content),
+			})
+		}
+	}
+	return refs
+}
+
+// toolResultText returns the text of a tool_result's content, which is a
+// string or an array of text blocks
+func toolResultText(content json.RawMessage) string {
+	var text string
+	if err := json.Unmarshal(content, &text); err == nil {
+		return text
+	}
+	var blocks []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(content, &blocks); err != nil {
+		return ""
+	}
+	var parts []string
+	for _, block := range blocks {
+		if block.Type == "text" {
+			parts = append(parts, block.Text)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
 // extractFromInvokeTags extracts tool calls from <invoke> tags
 func extractFromInvokeTags(content string, timestamp time.Time) []ToolCall {
 	var toolCalls []ToolCall
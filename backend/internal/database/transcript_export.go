@@ -0,0 +1,57 @@
+package database
+
+import (
+	"fmt"
+
+	"github.com/ksred/claude-session-manager/internal/chat"
+)
+
+// ChatTranscriptRecords is a session with the messages of the chats run
+// against it from the UI
+type ChatTranscriptRecords struct {
+	Session  *SessionSummary
+	Messages []*chat.ChatMessage
+}
+
+// GetTranscriptRecords returns a session with its messages and their token
+// usage for exporting
+func (r *SessionRepository) GetTranscriptRecords(sessionID string) (*TranscriptRecords, error) {
+	session, err := r.GetSessionByID(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	records := &TranscriptRecords{Session: session}
+
+	if records.Messages, err = r.GetSessionMessages(sessionID); err != nil {
+		return nil, err
+	}
+
+	err = r.db.Select(&records.TokenUsage, `
+		SELECT
+			id, message_id, session_id, input_tokens, output_tokens,
+			cache_creation_input_tokens, cache_read_input_tokens, total_tokens,
+			COALESCE(service_tier, '') AS service_tier, estimated_cost, created_at
+		FROM token_usage WHERE session_id = ?
+	`, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get token usage: %w", err)
+	}
+
+	return records, nil
+}
+
+// GetChatTranscriptRecords returns a session with the messages of every chat
+// run against it, including chats that have since ended
+func (r *SessionRepository) GetChatTranscriptRecords(sessionID string) (*ChatTranscriptRecords, error) {
+	session, err := r.GetSessionByID(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	messages, err := chat.NewRepository(r.db.DB).GetSessionChatMessages(sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chat messages: %w", err)
+	}
+
+	return &ChatTranscriptRecords{Session: session, Messages: messages}, nil
+}
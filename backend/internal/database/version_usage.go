@@ -0,0 +1,49 @@
+package database
+
+import (
+	"fmt"
+	"math"
+)
+
+// GetVersionUsage returns usage and error rates grouped by the client
+// version recorded on each message over the last days days, most recently
+// seen version first, so a regression after an upgrade shows up next to the
+// version before it
+func (r *SessionRepository) GetVersionUsage(days int) ([]VersionUsage, error) {
+	usage := []VersionUsage{}
+	err := r.db.Select(&usage, `
+		SELECT
+			COALESCE(NULLIF(m.version, ''), 'unknown') AS version,
+			COUNT(DISTINCT m.session_id) AS sessions,
+			COUNT(*) AS messages,
+			COALESCE(SUM(tu.tokens), 0) AS tokens,
+			COALESCE(SUM(tu.cost), 0.0) AS cost_usd,
+			SUM(CASE WHEN m.content LIKE '%"tool_result"%' THEN 1 ELSE 0 END) AS tool_results,
+			SUM(CASE WHEN m.content LIKE '%"is_error":true%' THEN 1 ELSE 0 END) AS tool_errors,
+			SUM(CASE WHEN m.role = 'user' AND m.content LIKE '%[Request interrupted%' THEN 1 ELSE 0 END) AS interruptions,
+			DATE(MIN(m.timestamp)) AS first_seen,
+			DATE(MAX(m.timestamp)) AS last_seen
+		FROM messages m
+		LEFT JOIN (
+			SELECT message_id, SUM(total_tokens) AS tokens, SUM(estimated_cost) AS cost
+			FROM token_usage
+			GROUP BY message_id
+		) tu ON tu.message_id = m.id
+		WHERE m.timestamp >= datetime('now', ?)
+		GROUP BY 1
+		ORDER BY MAX(m.timestamp) DESC
+	`, fmt.Sprintf("-%d days", days))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get usage by version: %w", err)
+	}
+
+	for i := range usage {
+		if usage[i].ToolResults > 0 {
+			usage[i].ErrorRate = math.Round(float64(usage[i].ToolErrors)/float64(usage[i].ToolResults)*1000) / 1000
+		}
+		if usage[i].Messages > 0 {
+			usage[i].TokensPerMsg = math.Round(float64(usage[i].Tokens)/float64(usage[i].Messages)*10) / 10
+		}
+	}
+	return usage, nil
+}
@@ -0,0 +1,71 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSessionRepository_GetVersionUsage(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewSessionRepository(db, logger)
+
+	now := time.Now()
+	for _, id := range []string{"old", "new"} {
+		if err := repo.UpsertSession(&Session{
+			ID:           id,
+			ProjectPath:  "/test/project",
+			ProjectName:  "project",
+			StartTime:    now.Add(-time.Hour),
+			LastActivity: now,
+			Status:       "completed",
+		}); err != nil {
+			t.Fatalf("Failed to create session: %v", err)
+		}
+	}
+
+	messages := []*Message{
+		{ID: "old-1", SessionID: "old", Version: "1.0.0", Role: "assistant", Content: `[{"type":"tool_use","name":"Bash"}]`, Timestamp: now.Add(-2 * time.Hour)},
+		{ID: "old-2", SessionID: "old", Version: "1.0.0", Role: "user", Content: `[{"type":"tool_result","content":"ok"}]`, Timestamp: now.Add(-2 * time.Hour)},
+		{ID: "new-1", SessionID: "new", Version: "1.1.0", Role: "assistant", Content: `[{"type":"tool_use","name":"Bash"}]`, Timestamp: now},
+		{ID: "new-2", SessionID: "new", Version: "1.1.0", Role: "user", Content: `[{"type":"tool_result","is_error":true}]`, Timestamp: now},
+		{ID: "new-3", SessionID: "new", Version: "1.1.0", Role: "user", Content: `[{"type":"tool_result","content":"ok"}]`, Timestamp: now},
+		{ID: "new-4", SessionID: "new", Role: "user", Content: `"no version"`, Timestamp: now.Add(-time.Hour)},
+	}
+	for _, message := range messages {
+		if err := repo.UpsertMessage(message); err != nil {
+			t.Fatalf("Failed to create message: %v", err)
+		}
+	}
+	for _, usage := range []*TokenUsage{
+		{MessageID: "old-1", SessionID: "old", TotalTokens: 100, EstimatedCost: 0.01},
+		{MessageID: "new-1", SessionID: "new", TotalTokens: 300, EstimatedCost: 0.03},
+	} {
+		if err := repo.UpsertTokenUsage(usage); err != nil {
+			t.Fatalf("Failed to create token usage: %v", err)
+		}
+	}
+
+	usage, err := repo.GetVersionUsage(30)
+	if err != nil {
+		t.Fatalf("Failed to get version usage: %v", err)
+	}
+	if len(usage) != 3 {
+		t.Fatalf("Expected 3 versions, got %d: %+v", len(usage), usage)
+	}
+
+	latest := usage[0]
+	if latest.Version != "1.1.0" || latest.Messages != 3 || latest.Tokens != 300 {
+		t.Errorf("Unexpected latest version usage: %+v", latest)
+	}
+	if latest.ToolResults != 2 || latest.ToolErrors != 1 || latest.ErrorRate != 0.5 {
+		t.Errorf("Expected half of 1.1.0's tool results to fail, got %+v", latest)
+	}
+	if latest.TokensPerMsg != 100 {
+		t.Errorf("Expected 100 tokens per message, got %v", latest.TokensPerMsg)
+	}
+	if usage[1].Version != "unknown" || usage[2].Version != "1.0.0" || usage[2].ErrorRate != 0 {
+		t.Errorf("Unexpected version order or rates: %+v", usage)
+	}
+}
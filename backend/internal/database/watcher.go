@@ -1,11 +1,11 @@
 package database
 
 import (
-	"bufio"
 	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
@@ -29,13 +29,16 @@ type ClaudeFileWatcher struct {
 	doneCh              chan struct{}
 	updateCallback      UpdateCallback
 	started             bool
+	watchMode           string
+	pollInterval        time.Duration
 }
 
-// UpdateCallback is called when sessions are updated
+// UpdateCallback is called when sessions are updated and as imports progress
 type UpdateCallback interface {
 	OnSessionUpdate(updateType string, sessionID string, session *Session)
 	OnActivityUpdate(activity *ActivityLogEntry)
 	OnMetricsUpdate(sessionID string, usage *TokenUsage)
+	OnImportProgress(progress *ImportProgress)
 }
 
 // NewFileWatcher creates a new file watcher
@@ -57,6 +60,8 @@ func NewFileWatcher(claudeDir string, repo *SessionRepository, logger *logrus.Lo
 		watcher:             watcher,
 		stopCh:              make(chan struct{}),
 		doneCh:              make(chan struct{}),
+		watchMode:           WatchModeAuto,
+		pollInterval:        5 * time.Second,
 	}
 
 	return fw, nil
@@ -77,12 +82,38 @@ func (fw *ClaudeFileWatcher) Start(ctx context.Context) error {
 	fw.started = true
 	fw.mu.Unlock()
 
-	// Add the projects directory to watch
 	projectsDir := filepath.Join(fw.claudeDir, "projects")
+
+	mode := fw.watchMode
+	if mode == WatchModeAuto {
+		mode = WatchModeFsnotify
+		if reason := pollReason(projectsDir); reason != "" {
+			fw.logger.WithField("reason", reason).Info("fsnotify is unreliable here, polling for changes instead")
+			mode = WatchModePoll
+		}
+	}
+
+	if mode == WatchModePoll {
+		// Scan before importing so files written meanwhile show up as changed
+		baseline := fw.scanFiles()
+		fw.importClaudeData()
+		fw.logger.WithFields(logrus.Fields{
+			"directory": projectsDir,
+			"interval":  fw.pollInterval,
+		}).Info("Started polling file watcher")
+		go fw.pollChanges(ctx, baseline)
+		return nil
+	}
+
+	// Add the projects directory to watch
 	if err := fw.addDirectoryRecursively(projectsDir); err != nil {
 		return fmt.Errorf("failed to add directory to watcher: %w", err)
 	}
 
+	// Watch the todo lists and the settings file, which live outside the
+	// projects directory
+	fw.watchClaudeData()
+
 	fw.logger.WithField("directory", projectsDir).Info("Started file watcher")
 
 	// Start the event processing goroutine
@@ -164,6 +195,15 @@ func (fw *ClaudeFileWatcher) processEvents(ctx context.Context) {
 				return
 			}
 
+			// Todo lists and settings are small and rewritten as a whole, so
+			// they are re-read on every change without debouncing
+			if fw.isClaudeDataFile(event.Name) {
+				if event.Op&(fsnotify.Create|fsnotify.Write) != 0 {
+					fw.processClaudeDataFile(event.Name)
+				}
+				continue
+			}
+
 			// Only process JSONL files
 			if !strings.HasSuffix(event.Name, ".jsonl") {
 				continue
@@ -204,8 +244,72 @@ func (fw *ClaudeFileWatcher) processEvents(ctx context.Context) {
 	}
 }
 
+// watchClaudeData imports the current todo lists and settings and adds them
+// to the watcher
+func (fw *ClaudeFileWatcher) watchClaudeData() {
+	fw.importClaudeData()
+
+	// The Claude directory itself is watched for the settings file; fsnotify
+	// does not recurse, so this adds no other subdirectories
+	for _, dir := range []string{TodosDir(fw.claudeDir), fw.claudeDir} {
+		if _, err := os.Stat(dir); err != nil {
+			continue
+		}
+		if err := fw.watcher.Add(dir); err != nil {
+			fw.logger.WithError(err).WithField("path", dir).Warn("Failed to add directory to watcher")
+		}
+	}
+}
+
+// importClaudeData imports the current todo lists and settings
+func (fw *ClaudeFileWatcher) importClaudeData() {
+	lists, recorded, err := fw.repo.ImportClaudeData(fw.claudeDir)
+	if err != nil {
+		fw.logger.WithError(err).Warn("Failed to import todo lists and settings")
+	} else {
+		fw.logger.WithFields(logrus.Fields{
+			"todo_lists":        lists,
+			"settings_recorded": recorded,
+		}).Debug("Imported todo lists and settings")
+	}
+}
+
+// isClaudeDataFile reports whether a path is a todo list or the settings file
+func (fw *ClaudeFileWatcher) isClaudeDataFile(path string) bool {
+	if path == SettingsFile(fw.claudeDir) {
+		return true
+	}
+	_, _, ok := ParseTodoFileName(path)
+	return ok && filepath.Dir(path) == TodosDir(fw.claudeDir)
+}
+
+// processClaudeDataFile imports a changed todo list or records a changed
+// settings file
+func (fw *ClaudeFileWatcher) processClaudeDataFile(path string) {
+	if path == SettingsFile(fw.claudeDir) {
+		recorded, err := fw.repo.RecordSettingsFile(path)
+		if err != nil {
+			fw.logger.WithError(err).WithField("file", path).Warn("Failed to record settings change")
+		} else if recorded {
+			fw.logger.WithField("file", path).Info("Recorded settings change")
+		}
+		return
+	}
+
+	todos, err := fw.repo.ImportTodoFile(path)
+	if err != nil {
+		fw.logger.WithError(err).WithField("file", path).Debug("Failed to import todo list")
+		return
+	}
+	fw.logger.WithFields(logrus.Fields{
+		"file":  path,
+		"todos": todos,
+	}).Debug("Imported todo list")
+}
+
 // handleFileCreate handles file creation events
 func (fw *ClaudeFileWatcher) handleFileCreate(filePath string) {
+	fw.repo.db.observeWatcherEvent("create")
 	// For new files, wait a bit to ensure they're fully written
 	time.Sleep(100 * time.Millisecond)
 	fw.processJSONLFile(filePath)
@@ -213,12 +317,14 @@ func (fw *ClaudeFileWatcher) handleFileCreate(filePath string) {
 
 // handleFileWrite handles file modification events
 func (fw *ClaudeFileWatcher) handleFileWrite(filePath string) {
+	fw.repo.db.observeWatcherEvent("write")
 	// For modified files, use the incremental importer
 	fw.processFileWithIncrementalImporter(filePath)
 }
 
 // handleFileRemove handles file removal events
 func (fw *ClaudeFileWatcher) handleFileRemove(filePath string) {
+	fw.repo.db.observeWatcherEvent("remove")
 	// When a file is removed, we could optionally mark sessions as inactive
 	// For now, we'll leave the data in the database
 	fw.logger.WithField("file", filePath).Info("JSONL file removed")
@@ -236,11 +342,13 @@ func (fw *ClaudeFileWatcher) processFileWithIncrementalImporter(filePath string)
 	batchImporter := NewBatchImporter(fw.repo, fw.logger)
 	
 	// Use incremental import that won't delete existing data
+	start := time.Now()
 	sessions, messages, err := batchImporter.ImportJSONLFileIncremental(filePath, projectInfo)
 	if err != nil {
 		fw.logger.WithError(err).WithField("file", filePath).Error("Failed to process JSONL file incrementally")
 		return
 	}
+	fw.repo.db.observeImport("watcher", sessions, messages, start)
 
 	fw.logger.WithFields(logrus.Fields{
 		"file":         filePath,
@@ -261,8 +369,9 @@ func (fw *ClaudeFileWatcher) processFileWithIncrementalImporter(filePath string)
 				ProjectPath:     sessionSummary.ProjectPath,
 				ProjectName:     sessionSummary.ProjectName,
 				FilePath:        filePath, // Use the actual file path
-				GitBranch:       "",       // SessionSummary doesn't have this field
-				GitWorktree:     "",       // SessionSummary doesn't have this field
+				GitBranch:       sessionSummary.GitBranch,
+				GitWorktree:     sessionSummary.GitWorktree,
+				GitRemote:       sessionSummary.GitRemote,
 				StartTime:       sessionSummary.StartTime,
 				LastActivity:    sessionSummary.LastActivity,
 				IsActive:        sessionSummary.IsActive,
@@ -284,11 +393,13 @@ func (fw *ClaudeFileWatcher) processJSONLFile(filePath string) {
 	// Extract project info from file path
 	projectInfo := fw.extractProjectInfo(filePath)
 	
+	start := time.Now()
 	sessions, messages, err := fw.importer.ImportJSONLFile(filePath, projectInfo)
 	if err != nil {
 		fw.logger.WithError(err).WithField("file", filePath).Error("Failed to process JSONL file")
 		return
 	}
+	fw.repo.db.observeImport("watcher", sessions, messages, start)
 
 	fw.logger.WithFields(logrus.Fields{
 		"file":     filePath,
@@ -369,23 +480,27 @@ func (fw *ClaudeFileWatcher) processJSONLFileIncremental(filePath string) {
 		return
 	}
 
-	// Process new lines with larger buffer for long lines
-	scanner := bufio.NewScanner(file)
-	// Set max token size to 10MB (default is 64KB)
-	buf := make([]byte, 0, 64*1024)
-	scanner.Buffer(buf, 10*1024*1024) // 10MB max token size
+	// Process new lines, stream decoding any over the line size limit
+	reader := newJSONLReader(file, fw.repo.db.maxLineBytes)
 	
 	newMessages := 0
 	projectInfo := fw.extractProjectInfo(filePath)
+	sessionIDs := make(map[string]bool)
 	
-	for scanner.Scan() {
-		line := scanner.Text()
-		if strings.TrimSpace(line) == "" {
-			continue
-		}
-
+	for {
 		var msg JSONLMessage
-		if err := json.Unmarshal([]byte(line), &msg); err != nil {
+		oversized, err := reader.Next(&msg)
+		if err == io.EOF {
+			break
+		}
+		if err != nil && !isLineError(err) {
+			fw.logger.WithError(err).WithField("file", filePath).Error("Error scanning file")
+			return
+		}
+		if oversized {
+			fw.repo.recordOversizedLine(filePath, lastProcessed+reader.offset, reader.size, err)
+		}
+		if err != nil {
 			fw.logger.WithError(err).WithField("file", filePath).Debug("Failed to parse message line, skipping")
 			continue
 		}
@@ -397,24 +512,28 @@ func (fw *ClaudeFileWatcher) processJSONLFileIncremental(filePath string) {
 		}
 
 		newMessages++
-	}
-
-	if err := scanner.Err(); err != nil {
-		fw.logger.WithError(err).WithField("file", filePath).Error("Error scanning file")
-		return
+		sessionIDs[msg.SessionID] = true
 	}
 
 	// Update the last processed position
-	currentPos, err := file.Seek(0, 1) // Current position
-	if err == nil {
-		fw.updateLastProcessedPosition(filePath, currentPos, fileInfo.Size())
-	}
+	fw.updateLastProcessedPosition(filePath, lastProcessed+reader.offset+reader.size, fileInfo.Size())
 
 	if newMessages > 0 {
 		fw.logger.WithFields(logrus.Fields{
 			"file":         filePath,
 			"new_messages": newMessages,
 		}).Debug("Processed new messages incrementally")
+
+		ids := make([]string, 0, len(sessionIDs))
+		for sessionID := range sessionIDs {
+			ids = append(ids, sessionID)
+		}
+		if _, err := fw.repo.LinkResumedSessions(ids); err != nil {
+			fw.logger.WithError(err).WithField("file", filePath).Warn("Failed to link resumed sessions")
+		}
+		if err := fw.repo.ReapplySessionEdits(ids); err != nil {
+			fw.logger.WithError(err).WithField("file", filePath).Warn("Failed to reapply session edits")
+		}
 	}
 }
 
@@ -494,9 +613,14 @@ func (fw *ClaudeFileWatcher) processSingleMessage(msg JSONLMessage, projectInfo
 		Timestamp:   msg.Timestamp,
 	}
 
-	if err := fw.repo.UpsertMessage(dbMessage); err != nil {
+	stored, err := fw.repo.UpsertImportedMessage(dbMessage, msg.Message.Usage != nil)
+	if err != nil {
 		return fmt.Errorf("failed to upsert message: %w", err)
 	}
+	if !stored {
+		// Dropped by a script
+		return nil
+	}
 
 	// Log activity for user messages
 	if msg.Message.Role == "user" {
@@ -547,7 +671,9 @@ func (fw *ClaudeFileWatcher) processSingleMessage(msg JSONLMessage, projectInfo
 		
 		usage.TotalTokens = usage.InputTokens + usage.OutputTokens + 
 			usage.CacheCreationInputTokens + usage.CacheReadInputTokens
-		usage.EstimatedCost = fw.importer.calculateTokenCost(usage, session.Model)
+		usage.Model = session.Model
+		usage.EstimatedCost = estimateTokenCost(usage, usage.Model)
+		usage.CacheSavings = estimateCacheSavings(usage, usage.Model)
 
 		if err := fw.repo.UpsertTokenUsage(usage); err != nil {
 			return fmt.Errorf("failed to upsert token usage: %w", err)
@@ -586,6 +712,7 @@ func (fw *ClaudeFileWatcher) processSingleMessage(msg JSONLMessage, projectInfo
 			ResultData: string(resultBytes),
 			Timestamp:  msg.Timestamp,
 		}
+		toolResult.setWorkspacePath(msg.CWD)
 
 		if err := fw.repo.UpsertToolResult(toolResult); err != nil {
 			return fmt.Errorf("failed to upsert tool result: %w", err)
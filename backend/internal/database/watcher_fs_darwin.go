@@ -0,0 +1,32 @@
+package database
+
+import "syscall"
+
+// remoteFileSystemTypes are the file system type names of network file
+// systems, where FSEvents misses changes made by other machines
+var remoteFileSystemTypes = map[string]bool{
+	"nfs":     true,
+	"smbfs":   true,
+	"afpfs":   true,
+	"webdav":  true,
+	"cifs":    true,
+	"macfuse": true,
+	"osxfuse": true,
+}
+
+// remoteFileSystem reports whether path is on a network file system, and
+// which
+func remoteFileSystem(path string) (string, bool) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return "", false
+	}
+	name := make([]byte, 0, len(stat.Fstypename))
+	for _, c := range stat.Fstypename {
+		if c == 0 {
+			break
+		}
+		name = append(name, byte(c))
+	}
+	return string(name), remoteFileSystemTypes[string(name)]
+}
@@ -0,0 +1,28 @@
+package database
+
+import "syscall"
+
+// remoteFileSystemTypes are the statfs magic numbers of network and virtual
+// file systems where inotify misses changes made by other machines or the host
+var remoteFileSystemTypes = map[uint32]string{
+	0x6969:     "nfs",
+	0x517b:     "smb",
+	0xff534d42: "cifs",
+	0xfe534d42: "smb2",
+	0x65735546: "fuse",
+	0x01021997: "9p",
+	0x6a656a63: "virtiofs",
+	0x5346414f: "afs",
+	0x73757245: "coda",
+}
+
+// remoteFileSystem reports whether path is on a network or virtual file
+// system, and which
+func remoteFileSystem(path string) (string, bool) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return "", false
+	}
+	fsType, ok := remoteFileSystemTypes[uint32(stat.Type)]
+	return fsType, ok
+}
@@ -0,0 +1,10 @@
+//go:build !linux && !darwin
+
+package database
+
+// remoteFileSystem reports whether path is on a network file system. It
+// can't be detected on this platform, so polling must be configured with
+// watch_mode: poll.
+func remoteFileSystem(path string) (string, bool) {
+	return "", false
+}
@@ -0,0 +1,197 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Watch modes. WatchModeAuto polls when the projects directory is a symlink
+// or on a network file system, where fsnotify misses changes, and uses
+// fsnotify otherwise.
+const (
+	WatchModeAuto     = "auto"
+	WatchModeFsnotify = "fsnotify"
+	WatchModePoll     = "poll"
+)
+
+// minPollInterval bounds how often the Claude directory is scanned
+const minPollInterval = time.Second
+
+// polledFile is the size and modification time a poll compares
+type polledFile struct {
+	size    int64
+	modTime time.Time
+}
+
+// SetWatchMode sets how changes are detected and, when polling, how often the
+// Claude directory is scanned. It must be called before Start; an empty mode
+// is WatchModeAuto.
+func (fw *ClaudeFileWatcher) SetWatchMode(mode string, pollInterval time.Duration) {
+	if mode == "" {
+		mode = WatchModeAuto
+	}
+	if pollInterval < minPollInterval {
+		pollInterval = minPollInterval
+	}
+	fw.watchMode = mode
+	fw.pollInterval = pollInterval
+}
+
+// pollReason returns why the projects directory should be polled, or an empty
+// string when fsnotify can be relied on
+func pollReason(projectsDir string) string {
+	if info, err := os.Lstat(projectsDir); err == nil && info.Mode()&os.ModeSymlink != 0 {
+		return "projects directory is a symlink"
+	}
+
+	// fsnotify doesn't follow symlinked project directories either
+	if entries, err := os.ReadDir(projectsDir); err == nil {
+		for _, entry := range entries {
+			if entry.Type()&os.ModeSymlink != 0 {
+				return fmt.Sprintf("project directory %s is a symlink", entry.Name())
+			}
+		}
+	}
+
+	if fsType, remote := remoteFileSystem(projectsDir); remote {
+		return fmt.Sprintf("projects directory is on a %s file system", fsType)
+	}
+	return ""
+}
+
+// isProjectDir reports whether a projects directory entry is a directory,
+// following symlinks so symlinked project directories are imported too
+func isProjectDir(projectsDir string, entry os.DirEntry) bool {
+	if entry.IsDir() {
+		return true
+	}
+	if entry.Type()&os.ModeSymlink == 0 {
+		return false
+	}
+	info, err := os.Stat(filepath.Join(projectsDir, entry.Name()))
+	return err == nil && info.IsDir()
+}
+
+// pollChanges scans the Claude directory every poll interval and processes
+// the files whose size or modification time changed since the last scan,
+// starting from the baseline taken before the startup import
+func (fw *ClaudeFileWatcher) pollChanges(ctx context.Context, previous map[string]polledFile) {
+	defer close(fw.doneCh)
+
+	ticker := time.NewTicker(fw.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-fw.stopCh:
+			return
+		case <-ticker.C:
+		}
+
+		current := fw.scanFiles()
+		created, changed, removed := diffPolledFiles(previous, current)
+		previous = current
+
+		for _, path := range append(created, changed...) {
+			if fw.isClaudeDataFile(path) {
+				fw.processClaudeDataFile(path)
+			}
+		}
+		for _, path := range created {
+			if strings.HasSuffix(path, ".jsonl") {
+				fw.logger.WithField("file", path).Debug("Processing polled file creation")
+				fw.handleFileCreate(path)
+			}
+		}
+		for _, path := range changed {
+			if strings.HasSuffix(path, ".jsonl") {
+				fw.logger.WithField("file", path).Debug("Processing polled file change")
+				fw.handleFileWrite(path)
+			}
+		}
+		for _, path := range removed {
+			if strings.HasSuffix(path, ".jsonl") {
+				fw.handleFileRemove(path)
+			}
+		}
+	}
+}
+
+// scanFiles returns the transcripts under the projects directory, following
+// symlinks, and the todo lists and settings file
+func (fw *ClaudeFileWatcher) scanFiles() map[string]polledFile {
+	files := make(map[string]polledFile)
+	visited := make(map[string]bool)
+
+	var walk func(dir string)
+	walk = func(dir string) {
+		// Guard against symlink cycles by the directory's real path
+		real, err := filepath.EvalSymlinks(dir)
+		if err != nil || visited[real] {
+			return
+		}
+		visited[real] = true
+
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			fw.logger.WithError(err).WithField("path", dir).Debug("Error reading directory, skipping")
+			return
+		}
+		for _, entry := range entries {
+			path := filepath.Join(dir, entry.Name())
+			info, err := os.Stat(path)
+			if err != nil {
+				continue
+			}
+			if info.IsDir() {
+				walk(path)
+			} else if strings.HasSuffix(path, ".jsonl") {
+				files[path] = polledFile{size: info.Size(), modTime: info.ModTime()}
+			}
+		}
+	}
+	walk(filepath.Join(fw.claudeDir, "projects"))
+
+	if entries, err := os.ReadDir(TodosDir(fw.claudeDir)); err == nil {
+		for _, entry := range entries {
+			path := filepath.Join(TodosDir(fw.claudeDir), entry.Name())
+			if !fw.isClaudeDataFile(path) {
+				continue
+			}
+			if info, err := os.Stat(path); err == nil {
+				files[path] = polledFile{size: info.Size(), modTime: info.ModTime()}
+			}
+		}
+	}
+	if info, err := os.Stat(SettingsFile(fw.claudeDir)); err == nil {
+		files[SettingsFile(fw.claudeDir)] = polledFile{size: info.Size(), modTime: info.ModTime()}
+	}
+
+	return files
+}
+
+// diffPolledFiles compares two scans, returning the files that appeared,
+// changed size or modification time, and disappeared
+func diffPolledFiles(previous, current map[string]polledFile) (created, changed, removed []string) {
+	for path, file := range current {
+		prev, ok := previous[path]
+		switch {
+		case !ok:
+			created = append(created, path)
+		case file.size != prev.size || !file.modTime.Equal(prev.modTime):
+			changed = append(changed, path)
+		}
+	}
+	for path := range previous {
+		if _, ok := current[path]; !ok {
+			removed = append(removed, path)
+		}
+	}
+	return created, changed, removed
+}
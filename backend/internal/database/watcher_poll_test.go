@@ -0,0 +1,110 @@
+package database
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestPollingWatcher_ScanFollowsSymlinks(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	// A project directory kept elsewhere, e.g. on a mounted home, and
+	// symlinked into the projects directory
+	claudeDir := t.TempDir()
+	remote := t.TempDir()
+	projectsDir := filepath.Join(claudeDir, "projects")
+	if err := os.MkdirAll(filepath.Join(projectsDir, "-work-local"), 0755); err != nil {
+		t.Fatalf("Failed to create projects directory: %v", err)
+	}
+	if err := os.Symlink(remote, filepath.Join(projectsDir, "-work-remote")); err != nil {
+		t.Fatalf("Failed to symlink project directory: %v", err)
+	}
+	// A cycle must not be followed forever
+	if err := os.Symlink(projectsDir, filepath.Join(remote, "loop")); err != nil {
+		t.Fatalf("Failed to create symlink cycle: %v", err)
+	}
+	for _, path := range []string{
+		filepath.Join(projectsDir, "-work-local", "a.jsonl"),
+		filepath.Join(remote, "b.jsonl"),
+		filepath.Join(remote, "notes.txt"),
+	} {
+		if err := os.WriteFile(path, []byte("{}\n"), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", path, err)
+		}
+	}
+
+	if reason := pollReason(projectsDir); reason == "" {
+		t.Error("Expected a symlinked project directory to need polling")
+	}
+	entries, _ := os.ReadDir(projectsDir)
+	for _, entry := range entries {
+		if !isProjectDir(projectsDir, entry) {
+			t.Errorf("Expected %s to be a project directory", entry.Name())
+		}
+	}
+
+	fw, err := NewFileWatcher(claudeDir, NewSessionRepository(db, logger), logger)
+	if err != nil {
+		t.Fatalf("Failed to create watcher: %v", err)
+	}
+	defer fw.watcher.Close()
+
+	files := fw.scanFiles()
+	var paths []string
+	for path := range files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	want := []string{
+		filepath.Join(projectsDir, "-work-local", "a.jsonl"),
+		filepath.Join(projectsDir, "-work-remote", "b.jsonl"),
+	}
+	if len(paths) != len(want) || paths[0] != want[0] || paths[1] != want[1] {
+		t.Errorf("Expected transcripts under their projects directory paths %v, got %v", want, paths)
+	}
+}
+
+func TestPollingWatcher_NoSymlinks(t *testing.T) {
+	projectsDir := filepath.Join(t.TempDir(), "projects")
+	if err := os.MkdirAll(filepath.Join(projectsDir, "-work-local"), 0755); err != nil {
+		t.Fatalf("Failed to create projects directory: %v", err)
+	}
+	if _, remote := remoteFileSystem(projectsDir); remote {
+		t.Skip("Temporary directory is on a network file system")
+	}
+	if reason := pollReason(projectsDir); reason != "" {
+		t.Errorf("Expected fsnotify for a local directory, got %q", reason)
+	}
+}
+
+func TestDiffPolledFiles(t *testing.T) {
+	now := time.Now()
+	previous := map[string]polledFile{
+		"same":    {size: 10, modTime: now},
+		"grown":   {size: 10, modTime: now},
+		"touched": {size: 10, modTime: now},
+		"gone":    {size: 10, modTime: now},
+	}
+	current := map[string]polledFile{
+		"same":    {size: 10, modTime: now},
+		"grown":   {size: 20, modTime: now},
+		"touched": {size: 10, modTime: now.Add(time.Second)},
+		"new":     {size: 5, modTime: now},
+	}
+
+	created, changed, removed := diffPolledFiles(previous, current)
+	sort.Strings(changed)
+	if len(created) != 1 || created[0] != "new" {
+		t.Errorf("Expected new to be created, got %v", created)
+	}
+	if len(changed) != 2 || changed[0] != "grown" || changed[1] != "touched" {
+		t.Errorf("Expected grown and touched to change, got %v", changed)
+	}
+	if len(removed) != 1 || removed[0] != "gone" {
+		t.Errorf("Expected gone to be removed, got %v", removed)
+	}
+}
@@ -0,0 +1,127 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// CreateWebhook stores a new webhook, assigning its ID and timestamps
+func (r *SessionRepository) CreateWebhook(webhook *Webhook) error {
+	now := time.Now().UTC()
+	webhook.ID = uuid.New().String()
+	webhook.CreatedAt = now
+	webhook.UpdatedAt = now
+	webhook.EventList = strings.Join(webhook.Events, ",")
+	webhook.HasSecret = webhook.Secret != ""
+
+	err := r.db.WriteOperation(func(tx *sqlx.Tx) error {
+		_, err := tx.NamedExec(`
+			INSERT INTO webhooks (id, url, events, secret, description, enabled, created_at, updated_at)
+			VALUES (:id, :url, :events, :secret, :description, :enabled, :created_at, :updated_at)
+		`, webhook)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create webhook: %w", err)
+	}
+	return nil
+}
+
+// UpdateWebhook replaces a webhook's URL, events, secret, description and
+// whether it is enabled
+func (r *SessionRepository) UpdateWebhook(webhook *Webhook) error {
+	webhook.UpdatedAt = time.Now().UTC()
+	webhook.EventList = strings.Join(webhook.Events, ",")
+	webhook.HasSecret = webhook.Secret != ""
+
+	return r.db.WriteOperation(func(tx *sqlx.Tx) error {
+		result, err := tx.NamedExec(`
+			UPDATE webhooks
+			SET url = :url, events = :events, secret = :secret, description = :description,
+				enabled = :enabled, updated_at = :updated_at
+			WHERE id = :id
+		`, webhook)
+		if err != nil {
+			return fmt.Errorf("failed to update webhook: %w", err)
+		}
+		if rows, _ := result.RowsAffected(); rows == 0 {
+			return fmt.Errorf("webhook not found: %s", webhook.ID)
+		}
+		return nil
+	})
+}
+
+// GetWebhooks returns every webhook, oldest first
+func (r *SessionRepository) GetWebhooks() ([]Webhook, error) {
+	webhooks := []Webhook{}
+	if err := r.db.Select(&webhooks, `SELECT * FROM webhooks ORDER BY created_at, id`); err != nil {
+		return nil, fmt.Errorf("failed to get webhooks: %w", err)
+	}
+	for i := range webhooks {
+		webhooks[i].splitEvents()
+	}
+	return webhooks, nil
+}
+
+// GetWebhook returns a webhook by ID
+func (r *SessionRepository) GetWebhook(id string) (*Webhook, error) {
+	var webhook Webhook
+	if err := r.db.Get(&webhook, `SELECT * FROM webhooks WHERE id = ?`, id); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("webhook not found: %s", id)
+		}
+		return nil, fmt.Errorf("failed to get webhook: %w", err)
+	}
+	webhook.splitEvents()
+	return &webhook, nil
+}
+
+// DeleteWebhook removes a webhook
+func (r *SessionRepository) DeleteWebhook(id string) error {
+	return r.db.WriteOperation(func(tx *sqlx.Tx) error {
+		result, err := tx.Exec(`DELETE FROM webhooks WHERE id = ?`, id)
+		if err != nil {
+			return fmt.Errorf("failed to delete webhook: %w", err)
+		}
+		if rows, _ := result.RowsAffected(); rows == 0 {
+			return fmt.Errorf("webhook not found: %s", id)
+		}
+		return nil
+	})
+}
+
+// RecordWebhookDelivery records the outcome of the latest delivery to a
+// webhook; deliveryErr is nil when it succeeded
+func (r *SessionRepository) RecordWebhookDelivery(id string, deliveryErr error, at time.Time) error {
+	status := WebhookDelivered
+	var message *string
+	if deliveryErr != nil {
+		status = WebhookFailed
+		text := deliveryErr.Error()
+		message = &text
+	}
+
+	return r.db.WriteOperation(func(tx *sqlx.Tx) error {
+		_, err := tx.Exec(`
+			UPDATE webhooks SET last_status = ?, last_error = ?, last_attempt_at = ? WHERE id = ?
+		`, status, message, at.UTC(), id)
+		if err != nil {
+			return fmt.Errorf("failed to record webhook delivery: %w", err)
+		}
+		return nil
+	})
+}
+
+// splitEvents fills Events and HasSecret from the stored columns
+func (w *Webhook) splitEvents() {
+	w.Events = []string{}
+	if w.EventList != "" {
+		w.Events = strings.Split(w.EventList, ",")
+	}
+	w.HasSecret = w.Secret != ""
+}
@@ -0,0 +1,55 @@
+package database
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSessionRepository_Webhooks(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewSessionRepository(db, logger)
+
+	webhook := &Webhook{URL: "https://example.com/hook", Events: []string{"session_created", "budget_exceeded"}, Secret: "s3cret", Enabled: true}
+	if err := repo.CreateWebhook(webhook); err != nil {
+		t.Fatalf("Failed to create webhook: %v", err)
+	}
+
+	webhooks, err := repo.GetWebhooks()
+	if err != nil {
+		t.Fatalf("Failed to get webhooks: %v", err)
+	}
+	if len(webhooks) != 1 || len(webhooks[0].Events) != 2 || webhooks[0].Events[1] != "budget_exceeded" || !webhooks[0].HasSecret {
+		t.Fatalf("Expected the webhook with its events, got %+v", webhooks)
+	}
+
+	webhook.Events = []string{"error_detected"}
+	webhook.Secret = ""
+	webhook.Enabled = false
+	if err := repo.UpdateWebhook(webhook); err != nil {
+		t.Fatalf("Failed to update webhook: %v", err)
+	}
+	if err := repo.RecordWebhookDelivery(webhook.ID, errors.New("webhook returned status 500"), time.Now()); err != nil {
+		t.Fatalf("Failed to record delivery: %v", err)
+	}
+
+	got, err := repo.GetWebhook(webhook.ID)
+	if err != nil {
+		t.Fatalf("Failed to get webhook: %v", err)
+	}
+	if got.Enabled || got.HasSecret || len(got.Events) != 1 || got.Events[0] != "error_detected" {
+		t.Errorf("Expected the updated webhook, got %+v", got)
+	}
+	if got.LastStatus == nil || *got.LastStatus != WebhookFailed || got.LastError == nil || got.LastAttemptAt == nil {
+		t.Errorf("Expected the failed delivery recorded, got %+v", got)
+	}
+
+	if err := repo.DeleteWebhook(webhook.ID); err != nil {
+		t.Fatalf("Failed to delete webhook: %v", err)
+	}
+	if err := repo.DeleteWebhook(webhook.ID); err == nil {
+		t.Error("Expected deleting a missing webhook to fail")
+	}
+}
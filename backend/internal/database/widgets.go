@@ -0,0 +1,55 @@
+package database
+
+import (
+	"fmt"
+	"time"
+)
+
+// widgetUsage sums the usage of messages sent in [from, to) by sessions that
+// aren't archived
+const widgetUsage = `
+	FROM messages m
+	JOIN sessions s ON s.id = m.session_id
+	LEFT JOIN token_usage tu ON tu.message_id = m.id
+	WHERE m.timestamp >= ? AND m.timestamp < ?
+	AND s.archived_at IS NULL
+`
+
+// GetUsageBetween returns the usage of messages sent in [from, to), leaving
+// out archived sessions
+func (r *SessionRepository) GetUsageBetween(from, to time.Time) (*UsageAggregate, error) {
+	var usage UsageAggregate
+	err := r.db.Get(&usage, `
+		SELECT
+			'' AS key,
+			COUNT(DISTINCT m.session_id) AS sessions,
+			COUNT(DISTINCT m.id) AS messages,
+			COALESCE(SUM(tu.total_tokens), 0) AS tokens,
+			COALESCE(SUM(tu.estimated_cost), 0.0) AS cost_usd
+	`+widgetUsage, from.UTC(), to.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get usage: %w", err)
+	}
+	return &usage, nil
+}
+
+// GetUsageByProjectBetween returns each project's usage from messages sent
+// in [from, to), most expensive first, leaving out archived sessions
+func (r *SessionRepository) GetUsageByProjectBetween(from, to time.Time) ([]UsageAggregate, error) {
+	usage := []UsageAggregate{}
+	err := r.db.Select(&usage, `
+		SELECT
+			s.project_name AS key,
+			COUNT(DISTINCT m.session_id) AS sessions,
+			COUNT(DISTINCT m.id) AS messages,
+			COALESCE(SUM(tu.total_tokens), 0) AS tokens,
+			COALESCE(SUM(tu.estimated_cost), 0.0) AS cost_usd
+	`+widgetUsage+`
+		GROUP BY s.project_name
+		ORDER BY cost_usd DESC, key
+	`, from.UTC(), to.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get usage by project: %w", err)
+	}
+	return usage, nil
+}
@@ -0,0 +1,65 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSessionRepository_GetUsageBetween(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewSessionRepository(db, logger)
+
+	now := time.Now().UTC()
+	sessions := []struct {
+		id, project string
+		at          time.Time
+		cost        float64
+	}{
+		{"api-1", "api", now.Add(-time.Hour), 2.0},
+		{"api-2", "api", now.Add(-2 * time.Hour), 1.0},
+		{"web-1", "web", now.Add(-time.Hour), 4.0},
+		{"old-1", "web", now.Add(-48 * time.Hour), 8.0},
+		{"archived", "api", now.Add(-time.Hour), 16.0},
+	}
+	for _, s := range sessions {
+		session := &Session{ID: s.id, ProjectPath: "/work/" + s.project, ProjectName: s.project, StartTime: s.at, LastActivity: s.at, Status: "completed", Model: "claude-sonnet-4"}
+		if err := repo.UpsertSession(session); err != nil {
+			t.Fatalf("Failed to create session: %v", err)
+		}
+		if err := repo.UpsertMessage(&Message{ID: s.id + "-msg", SessionID: s.id, Role: "assistant", Content: `"done"`, Timestamp: s.at}); err != nil {
+			t.Fatalf("Failed to create message: %v", err)
+		}
+		if err := repo.UpsertTokenUsage(&TokenUsage{MessageID: s.id + "-msg", SessionID: s.id, TotalTokens: 100, EstimatedCost: s.cost}); err != nil {
+			t.Fatalf("Failed to create token usage: %v", err)
+		}
+	}
+	if _, err := repo.ArchiveSession("archived"); err != nil {
+		t.Fatalf("Failed to archive session: %v", err)
+	}
+
+	from := now.Add(-24 * time.Hour)
+	usage, err := repo.GetUsageBetween(from, now)
+	if err != nil {
+		t.Fatalf("Failed to get usage: %v", err)
+	}
+	if usage.Sessions != 3 || usage.Messages != 3 || usage.Tokens != 300 || usage.CostUSD != 7.0 {
+		t.Errorf("Expected 3 unarchived sessions costing $7 in the last day, got %+v", usage)
+	}
+
+	projects, err := repo.GetUsageByProjectBetween(from, now)
+	if err != nil {
+		t.Fatalf("Failed to get usage by project: %v", err)
+	}
+	if len(projects) != 2 {
+		t.Fatalf("Expected 2 projects, got %+v", projects)
+	}
+	if projects[0].Key != "web" || projects[0].CostUSD != 4.0 || projects[1].Key != "api" || projects[1].Sessions != 2 {
+		t.Errorf("Expected web then api by cost, got %+v", projects)
+	}
+
+	if empty, err := repo.GetUsageBetween(now.Add(time.Hour), now.Add(2*time.Hour)); err != nil || empty.Sessions != 0 || empty.CostUSD != 0 {
+		t.Errorf("Expected no usage in the future, got %+v (%v)", empty, err)
+	}
+}
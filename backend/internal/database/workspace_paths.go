@@ -0,0 +1,66 @@
+package database
+
+import (
+	"path"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// workspacePath returns filePath relative to the working directory cwd when
+// it lies inside it, so a file's history survives the repository moving,
+// along with the absolute path to keep alongside. Paths outside cwd keep
+// their absolute path in both; relative paths have no absolute path.
+func workspacePath(filePath, cwd string) (string, *string) {
+	if !strings.HasPrefix(filePath, "/") {
+		return filePath, nil
+	}
+	absolute := filePath
+	cwd = strings.TrimRight(cwd, "/")
+	if cwd != "" && strings.HasPrefix(filePath, cwd+"/") {
+		return path.Clean(strings.TrimPrefix(filePath, cwd+"/")), &absolute
+	}
+	return filePath, &absolute
+}
+
+// setWorkspacePath makes a tool result's file path relative to the working
+// directory it ran in
+func (tr *ToolResult) setWorkspacePath(cwd string) {
+	if tr.FilePath == nil || *tr.FilePath == "" {
+		return
+	}
+	relative, absolute := workspacePath(*tr.FilePath, cwd)
+	tr.FilePath = &relative
+	tr.AbsolutePath = absolute
+}
+
+// relativizeToolResultPaths makes the file paths of tool results imported
+// before paths were relative to the working directory of their message, and
+// keeps the absolute path. substr is compared rather than LIKE so working
+// directories containing % or _ match literally.
+func relativizeToolResultPaths(tx *sqlx.Tx) error {
+	_, err := tx.Exec(`
+		UPDATE tool_results
+		SET absolute_path = file_path,
+			file_path = COALESCE((
+				SELECT substr(tool_results.file_path, length(rtrim(m.cwd, '/')) + 2)
+				FROM messages m
+				WHERE m.id = tool_results.message_id
+				AND rtrim(m.cwd, '/') != ''
+				AND substr(tool_results.file_path, 1, length(rtrim(m.cwd, '/')) + 1) = rtrim(m.cwd, '/') || '/'
+			), file_path)
+		WHERE absolute_path IS NULL AND substr(file_path, 1, 1) = '/'
+	`)
+	return err
+}
+
+// restoreAbsoluteToolResultPaths reverts relativizeToolResultPaths. The
+// column stays, as applySchemaUpdates adds it.
+func restoreAbsoluteToolResultPaths(tx *sqlx.Tx) error {
+	_, err := tx.Exec(`
+		UPDATE tool_results
+		SET file_path = absolute_path, absolute_path = NULL
+		WHERE absolute_path IS NOT NULL
+	`)
+	return err
+}
@@ -0,0 +1,76 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWorkspacePath(t *testing.T) {
+	tests := []struct {
+		filePath, cwd, relative, absolute string
+	}{
+		{"/work/app/src/main.go", "/work/app", "src/main.go", "/work/app/src/main.go"},
+		{"/work/app/src/main.go", "/work/app/", "src/main.go", "/work/app/src/main.go"},
+		{"/work/app_old/main.go", "/work/app", "/work/app_old/main.go", "/work/app_old/main.go"},
+		{"/etc/hosts", "/work/app", "/etc/hosts", "/etc/hosts"},
+		{"/work/app/main.go", "", "/work/app/main.go", "/work/app/main.go"},
+		{"src/main.go", "/work/app", "src/main.go", ""},
+	}
+	for _, tt := range tests {
+		relative, absolute := workspacePath(tt.filePath, tt.cwd)
+		if relative != tt.relative {
+			t.Errorf("workspacePath(%q, %q): expected %q, got %q", tt.filePath, tt.cwd, tt.relative, relative)
+		}
+		got := ""
+		if absolute != nil {
+			got = *absolute
+		}
+		if got != tt.absolute {
+			t.Errorf("workspacePath(%q, %q): expected absolute %q, got %q", tt.filePath, tt.cwd, tt.absolute, got)
+		}
+	}
+}
+
+func TestSessionRepository_MovedWorkspaceFiles(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewSessionRepository(db, logger)
+	now := time.Now()
+	if err := repo.UpsertSession(&Session{ID: "s1", ProjectPath: "/old/home/app", ProjectName: "app", StartTime: now, LastActivity: now, Status: "completed"}); err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	if err := repo.UpsertMessage(&Message{ID: "m1", SessionID: "s1", Role: "assistant", CWD: "/old/home/app", Timestamp: now}); err != nil {
+		t.Fatalf("Failed to create message: %v", err)
+	}
+	result := &ToolResult{MessageID: "m1", SessionID: "s1", ToolName: "Edit", FilePath: stringPtr("/old/home/app/src/main.go"), ResultData: "{}", Timestamp: now}
+	result.setWorkspacePath("/old/home/app")
+	if err := repo.UpsertToolResult(result); err != nil {
+		t.Fatalf("Failed to create tool result: %v", err)
+	}
+
+	// The repository has since moved
+	moved := "/new/place/app/src/main.go"
+	changes, total, err := repo.GetFileChanges(moved, false, 10)
+	if err != nil {
+		t.Fatalf("Failed to get file changes: %v", err)
+	}
+	if total != 1 || len(changes) != 1 {
+		t.Fatalf("Expected the change made before the move, got %d", total)
+	}
+	if changes[0].AbsolutePath == nil || *changes[0].AbsolutePath != "/old/home/app/src/main.go" {
+		t.Errorf("Expected the absolute path to be kept, got %v", changes[0].AbsolutePath)
+	}
+
+	sessions, err := repo.GetFileSessions(moved, 10)
+	if err != nil {
+		t.Fatalf("Failed to get file sessions: %v", err)
+	}
+	if len(sessions) != 1 {
+		t.Errorf("Expected 1 session for the moved file, got %d", len(sessions))
+	}
+
+	if changes, _, _ := repo.GetFileChanges("/new/place/other/src/main.go", false, 10); len(changes) != 0 {
+		t.Errorf("Expected a file of another project not to match, got %d", len(changes))
+	}
+}
@@ -0,0 +1,293 @@
+// Package demo generates synthetic Claude Code transcripts for demo mode:
+// a history of finished sessions across a handful of made-up projects, then
+// live sessions appended to a line at a time at a realistic pace. The server
+// imports and watches them like real transcripts, so the dashboard, analytics
+// and WebSocket updates all work without any real session data.
+package demo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ksred/claude-session-manager/internal/config"
+)
+
+// Seed makes every demo generate the same projects, prompts and sessions, so
+// screenshots are repeatable
+const Seed = 42
+
+// version is the Claude Code version written to synthetic transcripts
+const version = "1.0.51"
+
+// Configure points cfg at the demo Claude home directory and turns off
+// everything that reaches outside it: telemetry, federation, MQTT and plugins
+func Configure(cfg *config.Config, home string) {
+	cfg.Claude.HomeDirectory = home
+	cfg.Claude.ProjectsPath = filepath.Join(home, "projects")
+	cfg.Playbooks.Directory = filepath.Join(home, "playbooks")
+	cfg.Features.EnableWebSocket = true
+	cfg.Features.EnableFileWatcher = true
+	cfg.Telemetry.Enabled = false
+	cfg.Federation.Enabled = false
+	cfg.MQTT.Enabled = false
+	cfg.Plugins = nil
+}
+
+// project is a made-up project with the prompts and files its sessions use.
+// Names have no hyphens, which Claude's directory encoding would split.
+type project struct {
+	name    string
+	branch  string
+	prompts []string
+	files   []string
+}
+
+var projects = []project{
+	{"storefront", "main", []string{
+		"Add a wishlist button to the product page",
+		"The checkout total is off by one cent for some carts, can you find out why?",
+		"Write tests for the cart reducer",
+	}, []string{"src/pages/Product.tsx", "src/store/cart.ts", "src/store/cart.test.ts", "src/lib/money.ts"}},
+	{"payments", "feature/refunds", []string{
+		"Implement partial refunds in the refunds service",
+		"Why is the webhook handler retrying forever on 409s?",
+		"Add idempotency keys to POST /charges",
+	}, []string{"internal/refunds/service.go", "internal/webhooks/handler.go", "internal/charges/routes.go", "migrations/0042_refunds.sql"}},
+	{"pipeline", "main", []string{
+		"Speed up the feature extraction step, it takes 40 minutes",
+		"Add a data validation stage before training",
+	}, []string{"pipeline/features.py", "pipeline/validate.py", "pipeline/train.py", "tests/test_features.py"}},
+	{"docs", "main", []string{
+		"Fix the broken links reported by the link checker",
+		"Write a getting started guide for the CLI",
+	}, []string{"content/getting-started.md", "content/cli/index.md", "astro.config.mjs"}},
+	{"mobile", "release/2.4", []string{
+		"The settings screen crashes on Android 12, here is the stack trace",
+		"Add pull to refresh to the activity feed",
+	}, []string{"app/screens/Settings.tsx", "app/screens/Feed.tsx", "app/hooks/useRefresh.ts"}},
+}
+
+var models = []string{"claude-sonnet-4-20250514", "claude-sonnet-4-20250514", "claude-opus-4-20250514", "claude-3-5-haiku-20241022"}
+
+var replies = []string{
+	"Found it. The fix is small, updating it now.",
+	"That's done. The tests pass locally.",
+	"I've made the change and checked the other call sites.",
+	"Here's what was going on, and the change I made to fix it.",
+}
+
+// Generator writes synthetic transcripts under a Claude home directory
+type Generator struct {
+	home string
+
+	mu  sync.Mutex
+	rng *rand.Rand
+}
+
+// NewGenerator creates a generator writing under home, which is created if
+// needed
+func NewGenerator(home string) *Generator {
+	return &Generator{home: home, rng: rand.New(rand.NewSource(Seed))}
+}
+
+// session is a transcript being written
+type session struct {
+	id      string
+	project project
+	model   string
+	path    string
+	parent  *string
+	turns   int
+	pending []map[string]interface{} // lines of the current turn not yet written
+}
+
+// History writes days of finished sessions ending at now, a few a day, and
+// returns how many it wrote
+func (g *Generator) History(days int, now time.Time) (int, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	count := 0
+	for day := days; day >= 1; day-- {
+		for n := 1 + g.rng.Intn(4); n > 0; n-- {
+			at := now.AddDate(0, 0, -day).Add(time.Duration(8+g.rng.Intn(11))*time.Hour + time.Duration(g.rng.Intn(60))*time.Minute)
+			s, err := g.newSession()
+			if err != nil {
+				return count, err
+			}
+			for turn := 0; turn < s.turns; turn++ {
+				for _, line := range g.turn(s) {
+					if err := g.write(s, line, at); err != nil {
+						return count, err
+					}
+					at = at.Add(time.Duration(5+g.rng.Intn(90)) * time.Second)
+				}
+			}
+			count++
+		}
+	}
+	return count, nil
+}
+
+// Replay keeps live sessions going, writing one line to one of them about
+// every pace and starting a new session when one finishes, until ctx is
+// cancelled
+func (g *Generator) Replay(ctx context.Context, live int, pace time.Duration) error {
+	sessions := make([]*session, live)
+	for {
+		g.mu.Lock()
+		i := g.rng.Intn(live)
+		wait := pace/2 + time.Duration(g.rng.Int63n(int64(pace)))
+		var err error
+		if sessions[i] == nil || (sessions[i].turns == 0 && len(sessions[i].pending) == 0) {
+			sessions[i], err = g.newSession()
+		}
+		if err == nil {
+			s := sessions[i]
+			if len(s.pending) == 0 {
+				s.pending = g.turn(s)
+				s.turns--
+			}
+			err = g.write(s, s.pending[0], time.Now().UTC())
+			s.pending = s.pending[1:]
+		}
+		g.mu.Unlock()
+		if err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(wait):
+		}
+	}
+}
+
+// newSession starts a session in a random project
+func (g *Generator) newSession() (*session, error) {
+	p := projects[g.rng.Intn(len(projects))]
+	id, err := uuid.NewRandomFromReader(g.rng)
+	if err != nil {
+		return nil, err
+	}
+	dir := filepath.Join(g.home, "projects", "-home-demo-"+p.name)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create demo project directory: %w", err)
+	}
+	return &session{
+		id:      id.String(),
+		project: p,
+		model:   models[g.rng.Intn(len(models))],
+		path:    filepath.Join(dir, id.String()+".jsonl"),
+		turns:   2 + g.rng.Intn(6),
+	}, nil
+}
+
+// turn returns the lines of one exchange: a prompt, a tool call and its
+// result, and the answer
+func (g *Generator) turn(s *session) []map[string]interface{} {
+	p := s.project
+	file := p.files[g.rng.Intn(len(p.files))]
+	toolID := "toolu_" + g.token(24)
+
+	var name string
+	var input map[string]interface{}
+	var result string
+	switch g.rng.Intn(5) {
+	case 0:
+		name, input, result = "Read", map[string]interface{}{"file_path": "/home/demo/" + p.name + "/" + file}, "(file contents)"
+	case 1:
+		name, input, result = "Edit", map[string]interface{}{"file_path": "/home/demo/" + p.name + "/" + file, "old_string": "TODO", "new_string": "done"}, "The file has been updated."
+	case 2:
+		name, input, result = "Grep", map[string]interface{}{"pattern": strings.Split(filepath.Base(file), ".")[0]}, "Found 3 files"
+	case 3:
+		name, input, result = "Bash", map[string]interface{}{"command": "make test"}, "ok  \tall tests passed"
+	default:
+		name, input, result = "Write", map[string]interface{}{"file_path": "/home/demo/" + p.name + "/" + file, "content": "// generated"}, "File created successfully"
+	}
+
+	return []map[string]interface{}{
+		{"type": "user", "message": map[string]interface{}{"role": "user", "content": p.prompts[g.rng.Intn(len(p.prompts))]}},
+		{"type": "assistant", "message": g.assistant(s, []interface{}{
+			map[string]interface{}{"type": "text", "text": "Let me take a look."},
+			map[string]interface{}{"type": "tool_use", "id": toolID, "name": name, "input": input},
+		})},
+		{"type": "user", "message": map[string]interface{}{"role": "user", "content": []interface{}{
+			map[string]interface{}{"type": "tool_result", "tool_use_id": toolID, "content": result},
+		}}},
+		{"type": "assistant", "message": g.assistant(s, []interface{}{
+			map[string]interface{}{"type": "text", "text": replies[g.rng.Intn(len(replies))]},
+		})},
+	}
+}
+
+// assistant returns an assistant message with plausible token usage
+func (g *Generator) assistant(s *session, content []interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"id":      "msg_" + g.token(24),
+		"role":    "assistant",
+		"model":   s.model,
+		"content": content,
+		"usage": map[string]interface{}{
+			"input_tokens":                4 + g.rng.Intn(400),
+			"output_tokens":               80 + g.rng.Intn(1200),
+			"cache_creation_input_tokens": g.rng.Intn(6000),
+			"cache_read_input_tokens":     8000 + g.rng.Intn(50000),
+			"service_tier":                "standard",
+		},
+	}
+}
+
+// write appends line to the session's transcript as sent at
+func (g *Generator) write(s *session, line map[string]interface{}, at time.Time) error {
+	id, err := uuid.NewRandomFromReader(g.rng)
+	if err != nil {
+		return err
+	}
+	uid := id.String()
+
+	line["parentUuid"] = s.parent
+	line["isSidechain"] = false
+	line["userType"] = "external"
+	line["cwd"] = "/home/demo/" + s.project.name
+	line["sessionId"] = s.id
+	line["version"] = version
+	line["gitBranch"] = s.project.branch
+	line["uuid"] = uid
+	line["timestamp"] = at.UTC().Format(time.RFC3339Nano)
+	if line["type"] == "assistant" {
+		line["requestId"] = "req_" + g.token(24)
+	}
+	s.parent = &uid
+
+	data, err := json.Marshal(line)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open demo transcript: %w", err)
+	}
+	defer f.Close()
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
+// token returns n random alphanumeric characters
+func (g *Generator) token(n int) string {
+	const chars = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = chars[g.rng.Intn(len(chars))]
+	}
+	return string(b)
+}
@@ -0,0 +1,68 @@
+package demo
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ksred/claude-session-manager/internal/claude"
+)
+
+func TestGenerator_History(t *testing.T) {
+	home := t.TempDir()
+	now := time.Now().UTC()
+
+	count, err := NewGenerator(home).History(3, now)
+	if err != nil {
+		t.Fatalf("Failed to generate history: %v", err)
+	}
+	files, _ := filepath.Glob(filepath.Join(home, "projects", "*", "*.jsonl"))
+	if count < 3 || len(files) != count {
+		t.Fatalf("Expected at least one session a day and a file per session, got %d sessions and %d files", count, len(files))
+	}
+
+	for _, file := range files {
+		session, err := claude.ParseSessionFile(file)
+		if err != nil {
+			t.Fatalf("Failed to parse %s: %v", file, err)
+		}
+		if len(session.Messages) < 8 || session.TokensUsed.OutputTokens == 0 {
+			t.Errorf("Expected at least two turns with token usage, got %d messages and %+v", len(session.Messages), session.TokensUsed)
+		}
+		if session.StartTime.Before(now.AddDate(0, 0, -4)) || session.LastActivity.After(now) {
+			t.Errorf("Expected %s within the last 3 days, got %v to %v", session.ProjectName, session.StartTime, session.LastActivity)
+		}
+	}
+
+	// The same seed generates the same sessions
+	again := t.TempDir()
+	NewGenerator(again).History(3, now)
+	for _, file := range files {
+		rel, _ := filepath.Rel(home, file)
+		if matches, _ := filepath.Glob(filepath.Join(again, rel)); len(matches) != 1 {
+			t.Errorf("Expected %s to be generated again", rel)
+		}
+	}
+}
+
+func TestGenerator_Replay(t *testing.T) {
+	home := t.TempDir()
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	if err := NewGenerator(home).Replay(ctx, 2, 5*time.Millisecond); err != nil {
+		t.Fatalf("Failed to replay: %v", err)
+	}
+	files, _ := filepath.Glob(filepath.Join(home, "projects", "*", "*.jsonl"))
+	if len(files) == 0 {
+		t.Fatalf("Expected live sessions to be written, got %d files", len(files))
+	}
+	session, err := claude.ParseSessionFile(files[0])
+	if err != nil {
+		t.Fatalf("Failed to parse %s: %v", files[0], err)
+	}
+	if len(session.Messages) == 0 || time.Since(session.LastActivity) > time.Minute {
+		t.Errorf("Expected recent messages, got %d last at %v", len(session.Messages), session.LastActivity)
+	}
+}
@@ -0,0 +1,127 @@
+// Package environment captures the machine each session ran on: the
+// operating system, and the terminal and anything else a client reports.
+// The git remote is the one detected when the session was imported.
+package environment
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ksred/claude-session-manager/internal/database"
+	"github.com/ksred/claude-session-manager/internal/gitinfo"
+	"github.com/sirupsen/logrus"
+)
+
+// gitTimeout bounds each git lookup so an unresponsive mount cannot stall capture
+const gitTimeout = 5 * time.Second
+
+// windowsPath matches a drive-letter path such as C:\Users\me
+var windowsPath = regexp.MustCompile(`^[A-Za-z]:[\\/]`)
+
+// DetectOS infers the operating system a session ran on from its working
+// directory. Sessions are read from a shared Claude directory that may be
+// mounted elsewhere (for example into a container), so the path is a better
+// guide than the server's own OS. It returns "" when the path is ambiguous.
+func DetectOS(cwd string) string {
+	switch {
+	case windowsPath.MatchString(cwd) || strings.HasPrefix(cwd, `\\`):
+		return "windows"
+	case strings.HasPrefix(cwd, "/Users/") || strings.HasPrefix(cwd, "/Volumes/") || strings.HasPrefix(cwd, "/private/"):
+		return "darwin"
+	case strings.HasPrefix(cwd, "/home/") || strings.HasPrefix(cwd, "/root/") || cwd == "/root" || strings.HasPrefix(cwd, "/mnt/"):
+		return "linux"
+	}
+	return ""
+}
+
+// GitRemote returns the sanitized origin remote of the repository at dir,
+// or "" if dir no longer exists or has no origin
+func GitRemote(ctx context.Context, dir string) string {
+	return gitinfo.SanitizeRemote(git(ctx, dir, "config", "--get", "remote.origin.url"))
+}
+
+// git runs a git command in dir and returns its trimmed output, or "" if dir
+// is not a repository or the command fails
+func git(ctx context.Context, dir string, args ...string) string {
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return ""
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, gitTimeout)
+	defer cancel()
+	output, err := exec.CommandContext(ctx, "git", append([]string{"-C", dir}, args...)...).Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(output))
+}
+
+// RepoRoot returns the top level of the repository containing dir, or "" if
+// dir is not in one
+func RepoRoot(ctx context.Context, dir string) string {
+	return git(ctx, dir, "rev-parse", "--show-toplevel")
+}
+
+// CommitBefore returns the last commit on the current branch of the
+// repository at dir made before at, or "" if there is none
+func CommitBefore(ctx context.Context, dir string, at time.Time) string {
+	return git(ctx, dir, "rev-list", "-1", "--before="+strconv.FormatInt(at.Unix(), 10), "HEAD")
+}
+
+// TrackedFiles returns the paths, relative to the repository root, of the
+// files tracked at commit
+func TrackedFiles(ctx context.Context, dir, commit string) map[string]bool {
+	files := make(map[string]bool)
+	for _, path := range strings.Split(git(ctx, dir, "ls-tree", "-r", "--name-only", commit), "\n") {
+		if path != "" {
+			files[path] = true
+		}
+	}
+	return files
+}
+
+// Capturer records the environment of newly imported sessions
+type Capturer struct {
+	repo   *database.SessionRepository
+	logger *logrus.Logger
+}
+
+// NewCapturer creates a new environment capturer
+func NewCapturer(repo *database.SessionRepository, logger *logrus.Logger) *Capturer {
+	return &Capturer{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+// CapturePending captures the environment of up to limit sessions that have
+// none and returns how many were captured
+func (c *Capturer) CapturePending(limit int) (int, error) {
+	pending, err := c.repo.GetSessionsWithoutEnvironment(limit)
+	if err != nil {
+		return 0, err
+	}
+
+	captured := 0
+	for i := range pending {
+		env := &pending[i]
+		if env.CWD != nil {
+			if detected := DetectOS(*env.CWD); detected != "" {
+				env.OS = &detected
+			}
+		}
+		env.Source = database.EnvironmentSourceDetected
+
+		if err := c.repo.SaveSessionEnvironment(env); err != nil {
+			c.logger.WithError(err).WithField("session_id", env.SessionID).Warn("Failed to save session environment")
+			continue
+		}
+		captured++
+	}
+	return captured, nil
+}
@@ -0,0 +1,142 @@
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/ksred/claude-session-manager/internal/database"
+)
+
+// csvFlushRows is how many CSV rows are written between flushes, so a large
+// export reaches the client as it is produced
+const csvFlushRows = 1000
+
+// AnalyticsWriter writes the rows of an analytics export dataset, as
+// streamed by SessionRepository.StreamAnalyticsExport, in a file format
+type AnalyticsWriter interface {
+	WriteRow(row []interface{}) error
+	// Close finishes the file without closing the underlying writer
+	Close() error
+}
+
+// IsAnalyticsFormat reports whether format is csv or parquet
+func IsAnalyticsFormat(format string) bool {
+	return format == "csv" || format == "parquet"
+}
+
+// AnalyticsContentType returns the MIME type of an analytics export format
+func AnalyticsContentType(format string) string {
+	if format == "parquet" {
+		return "application/vnd.apache.parquet"
+	}
+	return "text/csv"
+}
+
+// NewAnalyticsWriter starts an analytics export with the given columns in
+// format, csv or parquet, on w
+func NewAnalyticsWriter(format string, w io.Writer, columns []database.AnalyticsExportColumn) (AnalyticsWriter, error) {
+	switch format {
+	case "csv":
+		return NewCSVWriter(w, columns)
+	case "parquet":
+		return NewParquetWriter(w, columns)
+	}
+	return nil, fmt.Errorf("unknown export format: %s", format)
+}
+
+// CSVWriter writes rows as CSV with a header of column names. Null values
+// are empty and times are RFC 3339 in UTC.
+type CSVWriter struct {
+	writer  *csv.Writer
+	columns []database.AnalyticsExportColumn
+	record  []string
+	pending int
+}
+
+// NewCSVWriter writes the header row for columns to w
+func NewCSVWriter(w io.Writer, columns []database.AnalyticsExportColumn) (*CSVWriter, error) {
+	cw := &CSVWriter{writer: csv.NewWriter(w), columns: columns, record: make([]string, len(columns))}
+	for i, column := range columns {
+		cw.record[i] = column.Name
+	}
+	if err := cw.writer.Write(cw.record); err != nil {
+		return nil, err
+	}
+	return cw, nil
+}
+
+// WriteRow writes a row with a value, or nil, for each column
+func (cw *CSVWriter) WriteRow(row []interface{}) error {
+	if len(row) != len(cw.columns) {
+		return fmt.Errorf("row has %d values for %d columns", len(row), len(cw.columns))
+	}
+	for i, column := range cw.columns {
+		if err := checkValue(column, row[i]); err != nil {
+			return err
+		}
+		cw.record[i] = csvValue(row[i])
+	}
+	if err := cw.writer.Write(cw.record); err != nil {
+		return err
+	}
+	cw.pending++
+	if cw.pending >= csvFlushRows {
+		cw.pending = 0
+		cw.writer.Flush()
+		return cw.writer.Error()
+	}
+	return nil
+}
+
+// Close flushes the rows written so far
+func (cw *CSVWriter) Close() error {
+	cw.writer.Flush()
+	return cw.writer.Error()
+}
+
+// csvValue formats a checked value for a CSV field
+func csvValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano)
+	}
+	return fmt.Sprint(value)
+}
+
+// checkValue checks that value, unless nil, has the Go type of its column's
+// kind
+func checkValue(column database.AnalyticsExportColumn, value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	var ok bool
+	switch column.Kind {
+	case database.ExportString:
+		_, ok = value.(string)
+	case database.ExportInt:
+		_, ok = value.(int64)
+	case database.ExportFloat:
+		_, ok = value.(float64)
+	case database.ExportBool:
+		_, ok = value.(bool)
+	case database.ExportTime:
+		_, ok = value.(time.Time)
+	}
+	if !ok {
+		return fmt.Errorf("unexpected %T value for %s column %s", value, column.Kind, column.Name)
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package export
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ksred/claude-session-manager/internal/database"
+)
+
+var analyticsTestColumns = []database.AnalyticsExportColumn{
+	{Name: "id", Kind: database.ExportInt},
+	{Name: "session_id", Kind: database.ExportString},
+	{Name: "cost", Kind: database.ExportFloat},
+	{Name: "archived", Kind: database.ExportBool},
+	{Name: "timestamp", Kind: database.ExportTime},
+}
+
+var analyticsTestRows = [][]interface{}{
+	{int64(1), "s1", 0.25, false, time.Date(2026, 9, 1, 12, 0, 0, 0, time.UTC)},
+	{int64(2), nil, nil, true, nil},
+	{int64(3), "s, \"quoted\"", 1.5, nil, time.Date(2026, 9, 2, 8, 30, 0, 0, time.UTC)},
+}
+
+func TestCSVWriter(t *testing.T) {
+	var buf bytes.Buffer
+	writer, err := NewAnalyticsWriter("csv", &buf, analyticsTestColumns)
+	if err != nil {
+		t.Fatalf("Failed to create writer: %v", err)
+	}
+	for _, row := range analyticsTestRows {
+		if err := writer.WriteRow(row); err != nil {
+			t.Fatalf("Failed to write row: %v", err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Failed to close writer: %v", err)
+	}
+
+	expected := "id,session_id,cost,archived,timestamp\n" +
+		"1,s1,0.25,false,2026-09-01T12:00:00Z\n" +
+		"2,,,true,\n" +
+		"3,\"s, \"\"quoted\"\"\",1.5,,2026-09-02T08:30:00Z\n"
+	if buf.String() != expected {
+		t.Errorf("Unexpected CSV:\n%s", buf.String())
+	}
+
+	if err := writer.WriteRow([]interface{}{"1", "s1", 0.0, false, nil}); err == nil {
+		t.Error("Expected a string in an int column to be rejected")
+	}
+}
+
+func TestParquetWriter(t *testing.T) {
+	var buf bytes.Buffer
+	writer, err := NewAnalyticsWriter("parquet", &buf, analyticsTestColumns)
+	if err != nil {
+		t.Fatalf("Failed to create writer: %v", err)
+	}
+	for _, row := range analyticsTestRows {
+		if err := writer.WriteRow(row); err != nil {
+			t.Fatalf("Failed to write row: %v", err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Failed to close writer: %v", err)
+	}
+
+	data := buf.Bytes()
+	if !bytes.HasPrefix(data, []byte("PAR1")) || !bytes.HasSuffix(data, []byte("PAR1")) {
+		t.Fatal("Expected the file to start and end with PAR1")
+	}
+	footerLen := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	if footerLen <= 0 || footerLen > len(data)-12 {
+		t.Fatalf("Unexpected footer length %d in a %d byte file", footerLen, len(data))
+	}
+	footer := string(data[len(data)-8-footerLen : len(data)-8])
+	for _, column := range analyticsTestColumns {
+		if !strings.Contains(footer, column.Name) {
+			t.Errorf("Expected the footer to describe column %s", column.Name)
+		}
+	}
+
+	// The first column chunk follows the magic: a page header, then the
+	// definition levels (all defined) and the three PLAIN int64 ids
+	page := data[4:]
+	levels := []byte{0x03, 0x07} // one bit-packed group of 8 levels
+	start := bytes.Index(page, append([]byte{byte(len(levels)), 0, 0, 0}, levels...))
+	if start < 0 {
+		t.Fatal("Expected the id column's definition levels")
+	}
+	values := page[start+4+len(levels):]
+	for i := 0; i < 3; i++ {
+		if id := binary.LittleEndian.Uint64(values[i*8:]); id != uint64(i+1) {
+			t.Errorf("Expected id %d, got %d", i+1, id)
+		}
+	}
+	// Nulls are left out of the values, so the cost column's levels mark
+	// the second row null and are followed by just two doubles
+	costs := []byte{2, 0, 0, 0, 0x03, 0x05}
+	costs = binary.LittleEndian.AppendUint64(costs, math.Float64bits(0.25))
+	costs = binary.LittleEndian.AppendUint64(costs, math.Float64bits(1.5))
+	if !bytes.Contains(page, costs) {
+		t.Error("Expected the cost column's levels and non-null values")
+	}
+
+	if _, err := NewAnalyticsWriter("xlsx", &buf, analyticsTestColumns); err == nil {
+		t.Error("Expected an unknown format to fail")
+	}
+}
+
+func TestParquetWriter_RowGroups(t *testing.T) {
+	var buf bytes.Buffer
+	writer, err := NewParquetWriter(&buf, analyticsTestColumns[:1])
+	if err != nil {
+		t.Fatalf("Failed to create writer: %v", err)
+	}
+	for i := 0; i < ParquetRowGroupSize+1; i++ {
+		if err := writer.WriteRow([]interface{}{int64(i)}); err != nil {
+			t.Fatalf("Failed to write row: %v", err)
+		}
+	}
+	if len(writer.rowGroups) != 1 || len(writer.rows) != 1 {
+		t.Fatalf("Expected a full row group written and one row buffered, got %d groups and %d rows", len(writer.rowGroups), len(writer.rows))
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Failed to close writer: %v", err)
+	}
+	if len(writer.rowGroups) != 2 || writer.numRows != ParquetRowGroupSize+1 {
+		t.Errorf("Expected 2 row groups of %d rows, got %d groups of %d", ParquetRowGroupSize+1, len(writer.rowGroups), writer.numRows)
+	}
+}
@@ -0,0 +1,157 @@
+// Package export produces datasets of session data for use outside the manager
+package export
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/ksred/claude-session-manager/internal/database"
+)
+
+// AnonymizedSchemaVersion is bumped whenever the anonymized dataset's fields change
+const AnonymizedSchemaVersion = 1
+
+// AnonymizedSession is a metrics-only session record. Identifiers are keyed
+// hashes and the start time is truncated to the hour.
+type AnonymizedSession struct {
+	Session                  string  `json:"session"`
+	Project                  string  `json:"project"`
+	Model                    string  `json:"model"`
+	StartDate                string  `json:"start_date"`
+	StartHour                int     `json:"start_hour"`
+	DurationSeconds          int64   `json:"duration_seconds"`
+	MessageCount             int     `json:"message_count"`
+	InputTokens              int     `json:"input_tokens"`
+	OutputTokens             int     `json:"output_tokens"`
+	CacheCreationInputTokens int     `json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int     `json:"cache_read_input_tokens"`
+	TotalTokens              int     `json:"total_tokens"`
+	CostUSD                  float64 `json:"cost_usd"`
+	ToolCalls                int     `json:"tool_calls"`
+	FilesTouched             int     `json:"files_touched"`
+}
+
+// AnonymizedDataset is the JSON form of an anonymized export
+type AnonymizedDataset struct {
+	SchemaVersion int                 `json:"schema_version"`
+	GeneratedAt   time.Time           `json:"generated_at"`
+	Since         time.Time           `json:"since"`
+	StableHashes  bool                `json:"stable_hashes"`
+	Sessions      []AnonymizedSession `json:"sessions"`
+}
+
+// Anonymizer hashes identifiers with a secret key so they cannot be reversed
+// by hashing guessed project names
+type Anonymizer struct {
+	key    []byte
+	stable bool
+}
+
+// NewAnonymizer creates an anonymizer keyed by salt. With an empty salt a
+// random key is used, so hashes cannot be linked across exports.
+func NewAnonymizer(salt string) (*Anonymizer, error) {
+	if salt != "" {
+		return &Anonymizer{key: []byte(salt), stable: true}, nil
+	}
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	return &Anonymizer{key: key}, nil
+}
+
+// Hash returns a short keyed hash of value
+func (a *Anonymizer) Hash(value string) string {
+	mac := hmac.New(sha256.New, a.key)
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))[:16]
+}
+
+// Dataset anonymizes session metrics into a shareable dataset
+func (a *Anonymizer) Dataset(metrics []database.SessionMetrics, since time.Time) *AnonymizedDataset {
+	dataset := &AnonymizedDataset{
+		SchemaVersion: AnonymizedSchemaVersion,
+		GeneratedAt:   time.Now().UTC(),
+		Since:         since.UTC(),
+		StableHashes:  a.stable,
+		Sessions:      make([]AnonymizedSession, 0, len(metrics)),
+	}
+
+	for _, m := range metrics {
+		start := m.StartTime.UTC()
+		dataset.Sessions = append(dataset.Sessions, AnonymizedSession{
+			Session:                  a.Hash("session:" + m.SessionID),
+			Project:                  a.Hash("project:" + m.ProjectName),
+			Model:                    m.Model,
+			StartDate:                start.Format("2006-01-02"),
+			StartHour:                start.Hour(),
+			DurationSeconds:          m.DurationSeconds,
+			MessageCount:             m.MessageCount,
+			InputTokens:              m.InputTokens,
+			OutputTokens:             m.OutputTokens,
+			CacheCreationInputTokens: m.CacheCreationInputTokens,
+			CacheReadInputTokens:     m.CacheReadInputTokens,
+			TotalTokens:              m.TotalTokens,
+			CostUSD:                  m.CostUSD,
+			ToolCalls:                m.ToolCalls,
+			FilesTouched:             m.FilesTouched,
+		})
+	}
+
+	return dataset
+}
+
+// WriteJSON writes the dataset as a single JSON document
+func (d *AnonymizedDataset) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(d)
+}
+
+// anonymizedCSVHeader lists the CSV columns in the same order as the JSON fields
+var anonymizedCSVHeader = []string{
+	"session", "project", "model", "start_date", "start_hour", "duration_seconds",
+	"message_count", "input_tokens", "output_tokens", "cache_creation_input_tokens",
+	"cache_read_input_tokens", "total_tokens", "cost_usd", "tool_calls", "files_touched",
+}
+
+// WriteCSV writes the dataset's sessions as CSV with a header row
+func (d *AnonymizedDataset) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(anonymizedCSVHeader); err != nil {
+		return err
+	}
+
+	for _, s := range d.Sessions {
+		record := []string{
+			s.Session,
+			s.Project,
+			s.Model,
+			s.StartDate,
+			strconv.Itoa(s.StartHour),
+			strconv.FormatInt(s.DurationSeconds, 10),
+			strconv.Itoa(s.MessageCount),
+			strconv.Itoa(s.InputTokens),
+			strconv.Itoa(s.OutputTokens),
+			strconv.Itoa(s.CacheCreationInputTokens),
+			strconv.Itoa(s.CacheReadInputTokens),
+			strconv.Itoa(s.TotalTokens),
+			strconv.FormatFloat(s.CostUSD, 'f', 6, 64),
+			strconv.Itoa(s.ToolCalls),
+			strconv.Itoa(s.FilesTouched),
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
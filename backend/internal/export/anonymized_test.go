@@ -0,0 +1,94 @@
+package export
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ksred/claude-session-manager/internal/database"
+)
+
+var testMetrics = []database.SessionMetrics{
+	{
+		SessionID:   "session-1",
+		ProjectName: "secret-project",
+		Model:       "claude-sonnet",
+		StartTime:   time.Date(2025, 3, 14, 10, 42, 7, 0, time.UTC),
+		TotalTokens: 1200,
+		CostUSD:     0.42,
+		ToolCalls:   3,
+	},
+	{
+		SessionID:   "session-2",
+		ProjectName: "secret-project",
+		Model:       "claude-opus",
+		StartTime:   time.Date(2025, 3, 15, 23, 5, 0, 0, time.UTC),
+	},
+}
+
+func TestAnonymizer_Dataset(t *testing.T) {
+	anonymizer, err := NewAnonymizer("salt")
+	if err != nil {
+		t.Fatalf("Failed to create anonymizer: %v", err)
+	}
+	dataset := anonymizer.Dataset(testMetrics, time.Time{})
+
+	if !dataset.StableHashes || len(dataset.Sessions) != 2 {
+		t.Fatalf("Unexpected dataset: %+v", dataset)
+	}
+	first := dataset.Sessions[0]
+	if first.Project != dataset.Sessions[1].Project {
+		t.Error("Expected sessions of the same project to share a project hash")
+	}
+	if first.Session == dataset.Sessions[1].Session {
+		t.Error("Expected sessions to have distinct hashes")
+	}
+	if first.StartDate != "2025-03-14" || first.StartHour != 10 {
+		t.Errorf("Expected start time truncated to the hour, got %s %d", first.StartDate, first.StartHour)
+	}
+
+	var buf bytes.Buffer
+	if err := dataset.WriteJSON(&buf); err != nil {
+		t.Fatalf("Failed to write JSON: %v", err)
+	}
+	for _, leaked := range []string{"secret-project", "session-1", "10:42"} {
+		if strings.Contains(buf.String(), leaked) {
+			t.Errorf("Export leaked %q", leaked)
+		}
+	}
+
+	// The same salt gives the same hashes; no salt gives unlinkable ones
+	again, _ := NewAnonymizer("salt")
+	if again.Hash("project:secret-project") != anonymizer.Hash("project:secret-project") {
+		t.Error("Expected hashes to be stable for the same salt")
+	}
+	random, _ := NewAnonymizer("")
+	if random.Hash("project:secret-project") == anonymizer.Hash("project:secret-project") {
+		t.Error("Expected a random key to give different hashes")
+	}
+}
+
+func TestAnonymizedDataset_WriteCSV(t *testing.T) {
+	anonymizer, err := NewAnonymizer("salt")
+	if err != nil {
+		t.Fatalf("Failed to create anonymizer: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := anonymizer.Dataset(testMetrics, time.Time{}).WriteCSV(&buf); err != nil {
+		t.Fatalf("Failed to write CSV: %v", err)
+	}
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("Failed to read CSV: %v", err)
+	}
+	if len(records) != 3 || len(records[0]) != len(anonymizedCSVHeader) {
+		t.Fatalf("Expected header and 2 rows of %d columns, got %v", len(anonymizedCSVHeader), records)
+	}
+	if records[1][12] != "0.420000" {
+		t.Errorf("Expected cost column 0.420000, got %q", records[1][12])
+	}
+}
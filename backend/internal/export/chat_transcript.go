@@ -0,0 +1,188 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/ksred/claude-session-manager/internal/chat"
+	"github.com/ksred/claude-session-manager/internal/database"
+)
+
+// ChatTranscriptSchemaVersion is bumped whenever the JSON chat transcript's
+// fields change
+const ChatTranscriptSchemaVersion = 1
+
+// ChatTurn is a prompt sent from the UI and Claude's reply, with the
+// figures the CLI reported for the run that produced the reply
+type ChatTurn struct {
+	Number       int        `json:"number"`
+	Prompt       string     `json:"prompt"`
+	PromptedAt   *time.Time `json:"prompted_at,omitempty"`
+	Response     string     `json:"response"`
+	RespondedAt  *time.Time `json:"responded_at,omitempty"`
+	Reported     bool       `json:"reported"` // whether the CLI reported the figures below
+	DurationMs   int64      `json:"duration_ms"`
+	NumTurns     int        `json:"num_turns"`
+	TotalCostUSD float64    `json:"total_cost_usd"`
+}
+
+// ChatTranscript is the conversation of the chats run against a session from
+// the UI, with the CLI's duration, turn and cost figures added up
+type ChatTranscript struct {
+	SchemaVersion   int                      `json:"schema_version"`
+	GeneratedAt     time.Time                `json:"generated_at"`
+	Session         *database.SessionSummary `json:"session"`
+	Turns           []ChatTurn               `json:"turns"`
+	TotalDurationMs int64                    `json:"total_duration_ms"`
+	TotalNumTurns   int                      `json:"total_num_turns"`
+	TotalCostUSD    float64                  `json:"total_cost_usd"`
+	Unreported      int                      `json:"unreported"` // replies without CLI figures, left out of the totals
+}
+
+// BuildChatTranscript pairs each chat reply with the prompt before it. A
+// prompt that got no reply, or a reply without a prompt, is a turn of its own.
+func BuildChatTranscript(records *database.ChatTranscriptRecords) *ChatTranscript {
+	transcript := &ChatTranscript{
+		SchemaVersion: ChatTranscriptSchemaVersion,
+		GeneratedAt:   time.Now().UTC(),
+		Session:       records.Session,
+		Turns:         []ChatTurn{},
+	}
+
+	var pending *ChatTurn
+	flush := func() {
+		if pending != nil {
+			pending.Number = len(transcript.Turns) + 1
+			transcript.Turns = append(transcript.Turns, *pending)
+			pending = nil
+		}
+	}
+	for _, message := range records.Messages {
+		timestamp := message.Timestamp
+		switch message.Type {
+		case chat.MessageTypeUser:
+			flush()
+			pending = &ChatTurn{Prompt: message.Content, PromptedAt: &timestamp}
+		case chat.MessageTypeClaude:
+			if pending == nil {
+				pending = &ChatTurn{}
+			}
+			pending.Response = message.Content
+			pending.RespondedAt = &timestamp
+			if _, ok := message.Metadata["duration_ms"]; ok {
+				pending.Reported = true
+				pending.DurationMs = int64(metadataNumber(message.Metadata, "duration_ms"))
+				pending.NumTurns = int(metadataNumber(message.Metadata, "num_turns"))
+				pending.TotalCostUSD = metadataNumber(message.Metadata, "total_cost_usd")
+				transcript.TotalDurationMs += pending.DurationMs
+				transcript.TotalNumTurns += pending.NumTurns
+				transcript.TotalCostUSD += pending.TotalCostUSD
+			} else {
+				transcript.Unreported++
+			}
+			flush()
+		}
+	}
+	flush()
+	return transcript
+}
+
+// metadataNumber reads a number from decoded message metadata
+func metadataNumber(metadata map[string]interface{}, key string) float64 {
+	switch v := metadata[key].(type) {
+	case float64:
+		return v
+	case int:
+		return float64(v)
+	case int64:
+		return float64(v)
+	}
+	return 0
+}
+
+// WriteJSON writes the chat transcript as indented JSON
+func (t *ChatTranscript) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(t)
+}
+
+// WriteMarkdown writes the chat transcript as a Markdown document, each
+// reply headed by its duration, CLI turns and cost and a table of them all
+// in the footer
+func (t *ChatTranscript) WriteMarkdown(w io.Writer) error {
+	var b strings.Builder
+	session := t.Session
+	fmt.Fprintf(&b, "# Chat: %s\n\n", transcriptTitle(session))
+	fmt.Fprintf(&b, "- **Session:** `%s`\n", session.ID)
+	fmt.Fprintf(&b, "- **Project:** `%s`\n", session.ProjectPath)
+	if len(t.Turns) > 0 {
+		if at := turnTime(t.Turns[0]); at != nil {
+			fmt.Fprintf(&b, "- **First prompt:** %s\n", formatTimestamp(*at))
+		}
+	}
+	fmt.Fprintf(&b, "- **Turns:** %d\n", len(t.Turns))
+
+	for _, turn := range t.Turns {
+		fmt.Fprintf(&b, "\n---\n\n## Turn %d", turn.Number)
+		if at := turnTime(turn); at != nil {
+			fmt.Fprintf(&b, " · %s", formatTimestamp(*at))
+		}
+		b.WriteString("\n")
+
+		if turn.PromptedAt != nil {
+			b.WriteString("\n**Prompt**\n\n")
+			b.WriteString(strings.TrimSuffix(turn.Prompt, "\n") + "\n")
+		}
+		if turn.RespondedAt == nil {
+			b.WriteString("\n*No response.*\n")
+			continue
+		}
+		b.WriteString("\n**Response**")
+		if turn.Reported {
+			fmt.Fprintf(&b, " · %s", chatTurnFigures(turn))
+		}
+		b.WriteString("\n\n")
+		b.WriteString(strings.TrimSuffix(turn.Response, "\n") + "\n")
+	}
+
+	b.WriteString("\n---\n\n## Cost\n\n")
+	b.WriteString("| Turn | Duration | CLI turns | Cost |\n")
+	b.WriteString("|---:|---:|---:|---:|\n")
+	for _, turn := range t.Turns {
+		if !turn.Reported {
+			continue
+		}
+		fmt.Fprintf(&b, "| %d | %s | %d | %s |\n", turn.Number, formatDurationMs(turn.DurationMs), turn.NumTurns, formatCost(turn.TotalCostUSD))
+	}
+	fmt.Fprintf(&b, "| **Total** | **%s** | **%d** | **%s** |\n", formatDurationMs(t.TotalDurationMs), t.TotalNumTurns, formatCost(t.TotalCostUSD))
+	if t.Unreported > 0 {
+		fmt.Fprintf(&b, "\nResponses the CLI reported no figures for, not counted above: %d\n", t.Unreported)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// turnTime is when a turn started: its prompt, or its reply when it has none
+func turnTime(turn ChatTurn) *time.Time {
+	if turn.PromptedAt != nil {
+		return turn.PromptedAt
+	}
+	return turn.RespondedAt
+}
+
+func chatTurnFigures(turn ChatTurn) string {
+	label := "CLI turns"
+	if turn.NumTurns == 1 {
+		label = "CLI turn"
+	}
+	return fmt.Sprintf("%s · %d %s · %s", formatDurationMs(turn.DurationMs), turn.NumTurns, label, formatCost(turn.TotalCostUSD))
+}
+
+func formatDurationMs(ms int64) string {
+	return (time.Duration(ms) * time.Millisecond).Round(100 * time.Millisecond).String()
+}
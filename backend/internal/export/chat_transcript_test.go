@@ -0,0 +1,65 @@
+package export
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ksred/claude-session-manager/internal/chat"
+	"github.com/ksred/claude-session-manager/internal/database"
+)
+
+func TestBuildChatTranscript(t *testing.T) {
+	now := time.Date(2025, 3, 14, 10, 0, 0, 0, time.UTC)
+	records := &database.ChatTranscriptRecords{
+		Session: &database.SessionSummary{ID: "session-1", ProjectName: "project", ProjectPath: "/repo", StartTime: now, LastActivity: now},
+		Messages: []*chat.ChatMessage{
+			{Type: chat.MessageTypeUser, Content: "Add a test", Timestamp: now},
+			{Type: chat.MessageTypeClaude, Content: "Added one", Timestamp: now.Add(12 * time.Second), Metadata: map[string]interface{}{
+				"source": "cli_output", "duration_ms": float64(12340), "num_turns": float64(3), "total_cost_usd": 0.0123,
+			}},
+			{Type: chat.MessageTypeUser, Content: "Run it", Timestamp: now.Add(time.Minute)},
+			{Type: chat.MessageTypeClaude, Content: "It passes", Timestamp: now.Add(2 * time.Minute), Metadata: map[string]interface{}{
+				"source": "cli_output", "duration_ms": float64(1000), "num_turns": float64(1), "total_cost_usd": 0.002,
+			}},
+			{Type: chat.MessageTypeClaude, Content: "raw output", Timestamp: now.Add(3 * time.Minute), Metadata: map[string]interface{}{"source": "cli_output"}},
+			{Type: chat.MessageTypeUser, Content: "Commit it", Timestamp: now.Add(4 * time.Minute)},
+		},
+	}
+
+	transcript := BuildChatTranscript(records)
+	if len(transcript.Turns) != 4 {
+		t.Fatalf("Expected 4 turns, got %+v", transcript.Turns)
+	}
+	if turn := transcript.Turns[0]; turn.Prompt != "Add a test" || turn.Response != "Added one" || turn.NumTurns != 3 || turn.DurationMs != 12340 {
+		t.Errorf("Expected the first prompt paired with its reply, got %+v", turn)
+	}
+	if turn := transcript.Turns[2]; turn.PromptedAt != nil || turn.Reported {
+		t.Errorf("Expected an unprompted, unreported reply, got %+v", turn)
+	}
+	if turn := transcript.Turns[3]; turn.RespondedAt != nil || turn.Prompt != "Commit it" {
+		t.Errorf("Expected an unanswered prompt, got %+v", turn)
+	}
+	if transcript.TotalDurationMs != 13340 || transcript.TotalNumTurns != 4 || transcript.Unreported != 1 {
+		t.Errorf("Expected totals of the reported replies, got %+v", transcript)
+	}
+
+	var buf bytes.Buffer
+	if err := transcript.WriteMarkdown(&buf); err != nil {
+		t.Fatalf("Failed to write Markdown: %v", err)
+	}
+	markdown := buf.String()
+	for _, want := range []string{
+		"# Chat: project",
+		"**Response** · 12.3s · 3 CLI turns · $0.0123",
+		"**Response** · 1s · 1 CLI turn · $0.0020",
+		"*No response.*",
+		"| **Total** | **13.3s** | **4** | **$0.0143** |",
+		"Responses the CLI reported no figures for, not counted above: 1",
+	} {
+		if !strings.Contains(markdown, want) {
+			t.Errorf("Expected the Markdown to contain %q:\n%s", want, markdown)
+		}
+	}
+}
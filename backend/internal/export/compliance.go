@@ -0,0 +1,240 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ksred/claude-session-manager/internal/database"
+)
+
+// ComplianceSchemaVersion is bumped whenever the compliance bundle's layout changes
+const ComplianceSchemaVersion = 1
+
+// FileChange is a single file modification made by a tool call in a session
+type FileChange struct {
+	MessageID string    `json:"message_id"`
+	Timestamp time.Time `json:"timestamp"`
+	ToolName  string    `json:"tool_name"`
+	FilePath  string    `json:"file_path"`
+	OldString string    `json:"old_string,omitempty"`
+	NewString string    `json:"new_string,omitempty"`
+	Content   string    `json:"content,omitempty"` // full file written by Write
+}
+
+// BundleFile is an entry in a compliance bundle with its SHA-256 checksum
+type BundleFile struct {
+	Name   string `json:"name"`
+	Size   int    `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// ComplianceManifest describes a compliance bundle and checksums its contents
+type ComplianceManifest struct {
+	SchemaVersion int                      `json:"schema_version"`
+	GeneratedAt   time.Time                `json:"generated_at"`
+	Session       *database.SessionSummary `json:"session"`
+	OnLegalHold   bool                     `json:"on_legal_hold"`
+	MessageCount  int                      `json:"message_count"`
+	FileChanges   int                      `json:"file_changes"`
+	Files         []BundleFile             `json:"files"`
+}
+
+// FileChanges extracts the file modifications made by Edit, MultiEdit and
+// Write tool calls from a session's messages, in order
+func FileChanges(messages []database.Message) []FileChange {
+	var changes []FileChange
+	for _, message := range messages {
+		if message.Role != "assistant" {
+			continue
+		}
+		for _, call := range database.ExtractToolCallsFromMessage(message.Content, message.Timestamp) {
+			if call.FilePath == "" {
+				continue
+			}
+			change := FileChange{
+				MessageID: message.ID,
+				Timestamp: message.Timestamp,
+				ToolName:  call.ToolName,
+				FilePath:  call.FilePath,
+			}
+			switch call.ToolName {
+			case "Edit":
+				change.OldString, _ = call.Parameters["old_string"].(string)
+				change.NewString, _ = call.Parameters["new_string"].(string)
+				changes = append(changes, change)
+			case "MultiEdit":
+				edits, _ := call.Parameters["edits"].([]interface{})
+				for _, item := range edits {
+					edit, ok := item.(map[string]interface{})
+					if !ok {
+						continue
+					}
+					each := change
+					each.OldString, _ = edit["old_string"].(string)
+					each.NewString, _ = edit["new_string"].(string)
+					changes = append(changes, each)
+				}
+			case "Write":
+				change.Content, _ = call.Parameters["content"].(string)
+				changes = append(changes, change)
+			}
+		}
+	}
+	return changes
+}
+
+// RenderDiffs renders file changes as a readable patch. Edits show the
+// replaced and replacement text; writes show the full new file.
+func RenderDiffs(changes []FileChange) string {
+	var b strings.Builder
+	prefixLines := func(prefix, text string) {
+		if text == "" {
+			return
+		}
+		for _, line := range strings.Split(strings.TrimSuffix(text, "\n"), "\n") {
+			b.WriteString(prefix + line + "\n")
+		}
+	}
+
+	for _, change := range changes {
+		fmt.Fprintf(&b, "# %s %s (message %s)\n", change.Timestamp.UTC().Format(time.RFC3339), change.ToolName, change.MessageID)
+		fmt.Fprintf(&b, "--- a/%s\n+++ b/%s\n", strings.TrimPrefix(change.FilePath, "/"), strings.TrimPrefix(change.FilePath, "/"))
+		if change.ToolName == "Write" {
+			prefixLines("+", change.Content)
+		} else {
+			b.WriteString("@@\n")
+			prefixLines("-", change.OldString)
+			prefixLines("+", change.NewString)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// WriteComplianceBundle writes a zip archive of everything stored about a
+// session: the transcript, tool results, file diffs, audit log and message
+// hash chain, a manifest and a sha256sum-compatible checksums file covering
+// every other entry
+func WriteComplianceBundle(w io.Writer, records *database.ComplianceRecords) error {
+	changes := FileChanges(records.Messages)
+
+	var transcript bytes.Buffer
+	for _, message := range records.Messages {
+		line, err := json.Marshal(message)
+		if err != nil {
+			return err
+		}
+		transcript.Write(line)
+		transcript.WriteByte('\n')
+	}
+	var toolResults bytes.Buffer
+	for _, result := range records.ToolResults {
+		line, err := json.Marshal(result)
+		if err != nil {
+			return err
+		}
+		toolResults.Write(line)
+		toolResults.WriteByte('\n')
+	}
+
+	fileChanges, err := json.MarshalIndent(changes, "", "  ")
+	if err != nil {
+		return err
+	}
+	auditLog, err := json.MarshalIndent(map[string]interface{}{
+		"activity":        records.Activity,
+		"legal_holds":     records.LegalHolds,
+		"review":          records.Review,
+		"review_comments": records.Comments,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	contents := map[string][]byte{
+		"transcript.jsonl":   transcript.Bytes(),
+		"tool_results.jsonl": toolResults.Bytes(),
+		"file_changes.json":  fileChanges,
+		"diffs.patch":        []byte(RenderDiffs(changes)),
+		"audit_log.json":     auditLog,
+	}
+
+	// The hash chain lets the transcript be proven unmodified since import
+	if len(records.Hashes) > 0 {
+		chain, err := json.MarshalIndent(map[string]interface{}{
+			"algorithm": "sha256",
+			"head_hash": records.Hashes[len(records.Hashes)-1].ChainHash,
+			"messages":  records.Hashes,
+		}, "", "  ")
+		if err != nil {
+			return err
+		}
+		contents["integrity.json"] = chain
+	}
+
+	names := make([]string, 0, len(contents))
+	for name := range contents {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	onHold := false
+	for _, hold := range records.LegalHolds {
+		if hold.ReleasedAt == nil {
+			onHold = true
+		}
+	}
+	manifest := ComplianceManifest{
+		SchemaVersion: ComplianceSchemaVersion,
+		GeneratedAt:   time.Now().UTC(),
+		Session:       records.Session,
+		OnLegalHold:   onHold,
+		MessageCount:  len(records.Messages),
+		FileChanges:   len(changes),
+	}
+	var checksums strings.Builder
+	for _, name := range names {
+		sum := sha256.Sum256(contents[name])
+		file := BundleFile{Name: name, Size: len(contents[name]), SHA256: hex.EncodeToString(sum[:])}
+		manifest.Files = append(manifest.Files, file)
+		fmt.Fprintf(&checksums, "%s  %s\n", file.SHA256, name)
+	}
+	manifestJSON, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	manifestSum := sha256.Sum256(manifestJSON)
+	fmt.Fprintf(&checksums, "%s  %s\n", hex.EncodeToString(manifestSum[:]), "manifest.json")
+
+	entries := append(names, "manifest.json", "checksums.sha256")
+	contents["manifest.json"] = manifestJSON
+	contents["checksums.sha256"] = []byte(checksums.String())
+	return writeZip(w, entries, contents, manifest.GeneratedAt)
+}
+
+// writeZip writes the named entries of contents to a zip archive in order
+func writeZip(w io.Writer, names []string, contents map[string][]byte, modified time.Time) error {
+	archive := zip.NewWriter(w)
+	for _, name := range names {
+		entry, err := archive.CreateHeader(&zip.FileHeader{
+			Name:     name,
+			Method:   zip.Deflate,
+			Modified: modified,
+		})
+		if err != nil {
+			return err
+		}
+		if _, err := entry.Write(contents[name]); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
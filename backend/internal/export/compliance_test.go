@@ -0,0 +1,75 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ksred/claude-session-manager/internal/database"
+)
+
+func TestWriteComplianceBundle(t *testing.T) {
+	now := time.Date(2025, 3, 14, 10, 0, 0, 0, time.UTC)
+	records := &database.ComplianceRecords{
+		Session: &database.SessionSummary{ID: "session-1", ProjectName: "project"},
+		Messages: []database.Message{
+			{ID: "m1", SessionID: "session-1", Role: "user", Content: `"Rename the flag"`, Timestamp: now},
+			{ID: "m2", SessionID: "session-1", Role: "assistant", Timestamp: now, Content: `[
+				{"type":"tool_use","name":"Edit","input":{"file_path":"/repo/main.go","old_string":"verbose","new_string":"debug"}},
+				{"type":"tool_use","name":"Write","input":{"file_path":"/repo/NOTES.md","content":"renamed\n"}}
+			]`},
+		},
+		LegalHolds: []database.LegalHold{{ID: "hold-1", SessionID: "session-1", Reason: "Audit", PlacedBy: "counsel", PlacedAt: now}},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteComplianceBundle(&buf, records); err != nil {
+		t.Fatalf("Failed to write bundle: %v", err)
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("Failed to read bundle: %v", err)
+	}
+	files := make(map[string][]byte)
+	for _, entry := range archive.File {
+		reader, err := entry.Open()
+		if err != nil {
+			t.Fatalf("Failed to open %s: %v", entry.Name, err)
+		}
+		files[entry.Name], _ = io.ReadAll(reader)
+		reader.Close()
+	}
+
+	for _, name := range []string{"transcript.jsonl", "tool_results.jsonl", "file_changes.json", "diffs.patch", "audit_log.json", "manifest.json", "checksums.sha256"} {
+		if _, exists := files[name]; !exists {
+			t.Errorf("Bundle is missing %s", name)
+		}
+	}
+
+	// Every checksum line matches the file it names
+	lines := strings.Split(strings.TrimSpace(string(files["checksums.sha256"])), "\n")
+	if len(lines) != 6 {
+		t.Fatalf("Expected checksums for 6 files, got %v", lines)
+	}
+	for _, line := range lines {
+		parts := strings.SplitN(line, "  ", 2)
+		sum := sha256.Sum256(files[parts[1]])
+		if hex.EncodeToString(sum[:]) != parts[0] {
+			t.Errorf("Checksum mismatch for %s", parts[1])
+		}
+	}
+
+	diffs := string(files["diffs.patch"])
+	if !strings.Contains(diffs, "-verbose\n+debug") || !strings.Contains(diffs, "+++ b/repo/NOTES.md\n+renamed") {
+		t.Errorf("Unexpected diffs:\n%s", diffs)
+	}
+	if !strings.Contains(string(files["manifest.json"]), `"on_legal_hold": true`) {
+		t.Errorf("Expected manifest to record the active hold:\n%s", files["manifest.json"])
+	}
+}
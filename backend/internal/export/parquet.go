@@ -0,0 +1,384 @@
+package export
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	"github.com/ksred/claude-session-manager/internal/database"
+)
+
+// parquetMagic starts and ends every Parquet file
+const parquetMagic = "PAR1"
+
+// ParquetRowGroupSize is how many rows are buffered before a row group is
+// written, bounding memory while streaming
+const ParquetRowGroupSize = 10000
+
+// Parquet physical types, repetition types, converted types, encodings and
+// page types, as numbered in parquet.thrift
+const (
+	parquetBoolean   = 0
+	parquetInt64     = 2
+	parquetDouble    = 5
+	parquetByteArray = 6
+
+	parquetOptional = 1
+
+	parquetUTF8            = 0
+	parquetTimestampMillis = 9
+
+	parquetPlain = 0
+	parquetRLE   = 3
+
+	parquetDataPage = 0
+)
+
+// ParquetWriter writes rows as an uncompressed Parquet file with every column
+// optional and PLAIN encoded. Rows are written a row group at a time, so
+// only the file's footer and the current row group are held in memory.
+type ParquetWriter struct {
+	w         *countingWriter
+	columns   []database.AnalyticsExportColumn
+	rows      [][]interface{}
+	rowGroups []parquetRowGroup
+	numRows   int64
+}
+
+// parquetRowGroup is the footer's record of a written row group
+type parquetRowGroup struct {
+	columns   []parquetColumnChunk
+	totalSize int64
+	numRows   int64
+}
+
+// parquetColumnChunk is the footer's record of a column in a row group
+type parquetColumnChunk struct {
+	offset    int64
+	size      int64
+	numValues int64
+}
+
+// countingWriter tracks the offset reached in the file
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// NewParquetWriter starts a Parquet file with the given columns on w
+func NewParquetWriter(w io.Writer, columns []database.AnalyticsExportColumn) (*ParquetWriter, error) {
+	pw := &ParquetWriter{w: &countingWriter{w: w}, columns: columns}
+	if _, err := io.WriteString(pw.w, parquetMagic); err != nil {
+		return nil, err
+	}
+	return pw, nil
+}
+
+// WriteRow adds a row with a value, or nil, for each column
+func (pw *ParquetWriter) WriteRow(row []interface{}) error {
+	if len(row) != len(pw.columns) {
+		return fmt.Errorf("row has %d values for %d columns", len(row), len(pw.columns))
+	}
+	for i, column := range pw.columns {
+		if err := checkValue(column, row[i]); err != nil {
+			return err
+		}
+	}
+	// Rows are buffered until the row group is written, so the caller's
+	// slice is copied in case it is reused
+	pw.rows = append(pw.rows, append([]interface{}(nil), row...))
+	if len(pw.rows) >= ParquetRowGroupSize {
+		return pw.flush()
+	}
+	return nil
+}
+
+// Close writes the last row group and the footer. It doesn't close the
+// underlying writer.
+func (pw *ParquetWriter) Close() error {
+	if err := pw.flush(); err != nil {
+		return err
+	}
+	footer := pw.footer()
+	if _, err := pw.w.Write(footer); err != nil {
+		return err
+	}
+	if err := binary.Write(pw.w, binary.LittleEndian, uint32(len(footer))); err != nil {
+		return err
+	}
+	_, err := io.WriteString(pw.w, parquetMagic)
+	return err
+}
+
+// flush writes the buffered rows as a row group of one page per column
+func (pw *ParquetWriter) flush() error {
+	if len(pw.rows) == 0 {
+		return nil
+	}
+	group := parquetRowGroup{numRows: int64(len(pw.rows))}
+	for i, column := range pw.columns {
+		page, err := parquetPage(column, pw.rows, i)
+		if err != nil {
+			return fmt.Errorf("failed to encode column %s: %w", column.Name, err)
+		}
+		header := parquetPageHeader(len(pw.rows), len(page))
+
+		chunk := parquetColumnChunk{offset: pw.w.n, size: int64(len(header) + len(page)), numValues: int64(len(pw.rows))}
+		if _, err := pw.w.Write(header); err != nil {
+			return err
+		}
+		if _, err := pw.w.Write(page); err != nil {
+			return err
+		}
+		group.columns = append(group.columns, chunk)
+		group.totalSize += chunk.size
+	}
+	pw.rowGroups = append(pw.rowGroups, group)
+	pw.numRows += group.numRows
+	pw.rows = pw.rows[:0]
+	return nil
+}
+
+// parquetPage encodes a column of rows as a v1 data page: definition levels
+// marking which values are null, then the PLAIN encoded values that aren't
+func parquetPage(column database.AnalyticsExportColumn, rows [][]interface{}, index int) ([]byte, error) {
+	// Definition levels are one bit each, written as a single bit-packed
+	// run of the RLE/bit-packing hybrid encoding
+	groups := (len(rows) + 7) / 8
+	levels := make([]byte, 0, groups+binary.MaxVarintLen32)
+	levels = binary.AppendUvarint(levels, uint64(groups<<1|1))
+	packed := make([]byte, groups)
+	for i, row := range rows {
+		if row[index] != nil {
+			packed[i/8] |= 1 << (i % 8)
+		}
+	}
+	levels = append(levels, packed...)
+
+	var page bytes.Buffer
+	binary.Write(&page, binary.LittleEndian, uint32(len(levels)))
+	page.Write(levels)
+
+	var booleans []bool
+	for _, row := range rows {
+		value := row[index]
+		if value == nil {
+			continue
+		}
+		switch v := value.(type) {
+		case int64:
+			binary.Write(&page, binary.LittleEndian, v)
+		case float64:
+			binary.Write(&page, binary.LittleEndian, math.Float64bits(v))
+		case string:
+			binary.Write(&page, binary.LittleEndian, uint32(len(v)))
+			page.WriteString(v)
+		case time.Time:
+			binary.Write(&page, binary.LittleEndian, v.UnixMilli())
+		case bool:
+			booleans = append(booleans, v)
+		default:
+			return nil, fmt.Errorf("unsupported value %T", value)
+		}
+	}
+	if column.Kind == database.ExportBool {
+		packed := make([]byte, (len(booleans)+7)/8)
+		for i, v := range booleans {
+			if v {
+				packed[i/8] |= 1 << (i % 8)
+			}
+		}
+		page.Write(packed)
+	}
+	return page.Bytes(), nil
+}
+
+// parquetPageHeader encodes the PageHeader of an uncompressed data page
+func parquetPageHeader(numValues, size int) []byte {
+	t := &thriftWriter{}
+	t.i32(1, parquetDataPage)
+	t.i32(2, int32(size))
+	t.i32(3, int32(size))
+	t.beginStruct(5)
+	t.i32(1, int32(numValues))
+	t.i32(2, parquetPlain)
+	t.i32(3, parquetRLE)
+	t.i32(4, parquetRLE)
+	t.endStruct()
+	t.stop()
+	return t.buf.Bytes()
+}
+
+// footer encodes the FileMetaData describing the schema and row groups
+func (pw *ParquetWriter) footer() []byte {
+	t := &thriftWriter{}
+	t.i32(1, 1)
+
+	t.beginList(2, thriftStruct, len(pw.columns)+1)
+	t.beginElement()
+	t.binary(4, "schema")
+	t.i32(5, int32(len(pw.columns)))
+	t.endElement()
+	for _, column := range pw.columns {
+		physical, converted := parquetTypes(column.Kind)
+		t.beginElement()
+		t.i32(1, physical)
+		t.i32(3, parquetOptional)
+		t.binary(4, column.Name)
+		if converted >= 0 {
+			t.i32(6, converted)
+		}
+		t.endElement()
+	}
+
+	t.i64(3, pw.numRows)
+
+	t.beginList(4, thriftStruct, len(pw.rowGroups))
+	for _, group := range pw.rowGroups {
+		t.beginElement()
+		t.beginList(1, thriftStruct, len(group.columns))
+		for i, chunk := range group.columns {
+			column := pw.columns[i]
+			physical, _ := parquetTypes(column.Kind)
+			t.beginElement()
+			t.i64(2, chunk.offset)
+			t.beginStruct(3)
+			t.i32(1, physical)
+			t.beginList(2, thriftI32, 2)
+			t.listI32(parquetPlain)
+			t.listI32(parquetRLE)
+			t.beginList(3, thriftBinary, 1)
+			t.listBinary(column.Name)
+			t.i32(4, 0) // uncompressed
+			t.i64(5, chunk.numValues)
+			t.i64(6, chunk.size)
+			t.i64(7, chunk.size)
+			t.i64(9, chunk.offset)
+			t.endStruct()
+			t.endElement()
+		}
+		t.i64(2, group.totalSize)
+		t.i64(3, group.numRows)
+		t.endElement()
+	}
+
+	t.binary(6, "claude-session-manager")
+	t.stop()
+	return t.buf.Bytes()
+}
+
+// parquetTypes returns the physical and converted type of a column kind;
+// the converted type is -1 when there is none
+func parquetTypes(kind string) (int32, int32) {
+	switch kind {
+	case database.ExportInt:
+		return parquetInt64, -1
+	case database.ExportFloat:
+		return parquetDouble, -1
+	case database.ExportBool:
+		return parquetBoolean, -1
+	case database.ExportTime:
+		return parquetInt64, parquetTimestampMillis
+	}
+	return parquetByteArray, parquetUTF8
+}
+
+// Thrift compact protocol type ids
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thriftWriter encodes the Thrift compact protocol, just as far as Parquet
+// metadata needs it
+type thriftWriter struct {
+	buf       bytes.Buffer
+	lastField []int16
+	field     int16
+}
+
+func (t *thriftWriter) fieldHeader(id int16, typ byte) {
+	if delta := id - t.field; delta > 0 && delta <= 15 {
+		t.buf.WriteByte(byte(delta)<<4 | typ)
+	} else {
+		t.buf.WriteByte(typ)
+		t.varint(int64(id))
+	}
+	t.field = id
+}
+
+func (t *thriftWriter) varint(v int64) {
+	t.buf.Write(binary.AppendUvarint(nil, uint64((v<<1)^(v>>63))))
+}
+
+func (t *thriftWriter) i32(id int16, v int32) {
+	t.fieldHeader(id, thriftI32)
+	t.varint(int64(v))
+}
+
+func (t *thriftWriter) i64(id int16, v int64) {
+	t.fieldHeader(id, thriftI64)
+	t.varint(v)
+}
+
+func (t *thriftWriter) binary(id int16, v string) {
+	t.fieldHeader(id, thriftBinary)
+	t.listBinary(v)
+}
+
+func (t *thriftWriter) beginStruct(id int16) {
+	t.fieldHeader(id, thriftStruct)
+	t.beginElement()
+}
+
+func (t *thriftWriter) endStruct() {
+	t.endElement()
+}
+
+// beginElement starts a struct inside a list, or the body of a struct field
+func (t *thriftWriter) beginElement() {
+	t.lastField = append(t.lastField, t.field)
+	t.field = 0
+}
+
+// endElement ends the struct begun by beginElement
+func (t *thriftWriter) endElement() {
+	t.stop()
+	t.field = t.lastField[len(t.lastField)-1]
+	t.lastField = t.lastField[:len(t.lastField)-1]
+}
+
+func (t *thriftWriter) beginList(id int16, elementType byte, size int) {
+	t.fieldHeader(id, thriftList)
+	if size < 15 {
+		t.buf.WriteByte(byte(size)<<4 | elementType)
+		return
+	}
+	t.buf.WriteByte(0xF0 | elementType)
+	t.buf.Write(binary.AppendUvarint(nil, uint64(size)))
+}
+
+func (t *thriftWriter) listI32(v int32) {
+	t.varint(int64(v))
+}
+
+func (t *thriftWriter) listBinary(v string) {
+	t.buf.Write(binary.AppendUvarint(nil, uint64(len(v))))
+	t.buf.WriteString(v)
+}
+
+func (t *thriftWriter) stop() {
+	t.buf.WriteByte(0)
+}
@@ -0,0 +1,248 @@
+package export
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ksred/claude-session-manager/internal/database"
+	"github.com/ksred/claude-session-manager/internal/knowledge"
+)
+
+// ReproSchemaVersion is bumped whenever the repro bundle's layout changes
+const ReproSchemaVersion = 1
+
+// ReproGit is the repository state a session ran against
+type ReproGit struct {
+	Remote     string `json:"remote,omitempty"`
+	Branch     string `json:"branch,omitempty"`
+	Worktree   string `json:"worktree,omitempty"`
+	Root       string `json:"root,omitempty"`
+	BaseCommit string `json:"base_commit,omitempty"` // last commit before the session started
+	EndCommit  string `json:"end_commit,omitempty"`  // last commit before its last activity
+	// Tracked holds the paths, relative to Root, tracked at BaseCommit
+	Tracked map[string]bool `json:"-"`
+}
+
+// ReproPrompt is a prompt the user sent during the session
+type ReproPrompt struct {
+	Index     int       `json:"index"`
+	MessageID string    `json:"message_id"`
+	Timestamp time.Time `json:"timestamp"`
+	Text      string    `json:"text"`
+}
+
+// ReproFile is a file the session changed
+type ReproFile struct {
+	Path          string   `json:"path"`
+	RelativePath  string   `json:"relative_path,omitempty"`
+	ExistedAtBase *bool    `json:"existed_at_base,omitempty"` // unknown without the base commit
+	Changes       int      `json:"changes"`
+	Tools         []string `json:"tools"`
+}
+
+// ReproMetadata describes the session and the setup it ran with
+type ReproMetadata struct {
+	SchemaVersion  int                          `json:"schema_version"`
+	GeneratedAt    time.Time                    `json:"generated_at"`
+	Session        *database.SessionSummary     `json:"session"`
+	Model          string                       `json:"model"`
+	ClaudeVersions []string                     `json:"claude_versions"`
+	WorkingDir     string                       `json:"working_dir,omitempty"`
+	Git            *ReproGit                    `json:"git"`
+	Environment    *database.SessionEnvironment `json:"environment,omitempty"`
+	Tags           []database.SessionTag        `json:"tags"`
+	Prompts        int                          `json:"prompts"`
+	Files          int                          `json:"files"`
+}
+
+// ReproPrompts returns the text the user typed during a session, in order,
+// skipping tool results and command output
+func ReproPrompts(messages []database.Message) []ReproPrompt {
+	prompts := []ReproPrompt{}
+	for _, message := range messages {
+		if message.Role != "user" {
+			continue
+		}
+		text := knowledge.ExtractText(message.Content)
+		if text == "" {
+			continue
+		}
+		prompts = append(prompts, ReproPrompt{
+			Index:     len(prompts) + 1,
+			MessageID: message.ID,
+			Timestamp: message.Timestamp,
+			Text:      text,
+		})
+	}
+	return prompts
+}
+
+// ReproFiles lists the files changed by a session, relative to the
+// repository root where known, and whether each existed at the base commit
+func ReproFiles(changes []FileChange, git *ReproGit) []ReproFile {
+	byPath := make(map[string]*ReproFile)
+	var paths []string
+	for _, change := range changes {
+		file, ok := byPath[change.FilePath]
+		if !ok {
+			file = &ReproFile{Path: change.FilePath, Tools: []string{}}
+			if git != nil && git.Root != "" && strings.HasPrefix(change.FilePath, git.Root+"/") {
+				file.RelativePath = strings.TrimPrefix(change.FilePath, git.Root+"/")
+				if git.BaseCommit != "" {
+					existed := git.Tracked[file.RelativePath]
+					file.ExistedAtBase = &existed
+				}
+			}
+			byPath[change.FilePath] = file
+			paths = append(paths, change.FilePath)
+		}
+		file.Changes++
+		if !containsString(file.Tools, change.ToolName) {
+			file.Tools = append(file.Tools, change.ToolName)
+		}
+	}
+
+	sort.Strings(paths)
+	files := make([]ReproFile, 0, len(paths))
+	for _, path := range paths {
+		files = append(files, *byPath[path])
+	}
+	return files
+}
+
+// WriteReproBundle writes a zip archive for reproducing a session's changes:
+// its prompts in order, the files it changed and whether they existed at the
+// commit it started from, the changes it made, the model, client and
+// repository metadata, a README explaining how to replay it and a
+// sha256sum-compatible checksums file
+func WriteReproBundle(w io.Writer, records *database.ReproRecords, git *ReproGit) error {
+	if git == nil {
+		git = &ReproGit{}
+	}
+	git.Branch, git.Worktree = records.GitBranch, records.GitWorktree
+
+	prompts := ReproPrompts(records.Messages)
+	changes := FileChanges(records.Messages)
+	files := ReproFiles(changes, git)
+
+	metadata := ReproMetadata{
+		SchemaVersion:  ReproSchemaVersion,
+		GeneratedAt:    time.Now().UTC(),
+		Session:        records.Session,
+		Model:          records.Session.Model,
+		ClaudeVersions: []string{},
+		Git:            git,
+		Environment:    records.Environment,
+		Tags:           records.Tags,
+		Prompts:        len(prompts),
+		Files:          len(files),
+	}
+	for _, message := range records.Messages {
+		if message.Version != "" && !containsString(metadata.ClaudeVersions, message.Version) {
+			metadata.ClaudeVersions = append(metadata.ClaudeVersions, message.Version)
+		}
+		if metadata.WorkingDir == "" && message.CWD != "" {
+			metadata.WorkingDir = message.CWD
+		}
+	}
+	if metadata.Tags == nil {
+		metadata.Tags = []database.SessionTag{}
+	}
+
+	var promptsJSONL bytes.Buffer
+	var promptsMarkdown strings.Builder
+	for _, prompt := range prompts {
+		line, err := json.Marshal(prompt)
+		if err != nil {
+			return err
+		}
+		promptsJSONL.Write(line)
+		promptsJSONL.WriteByte('\n')
+		fmt.Fprintf(&promptsMarkdown, "## Prompt %d (%s)\n\n%s\n\n", prompt.Index, prompt.Timestamp.UTC().Format(time.RFC3339), prompt.Text)
+	}
+
+	metadataJSON, err := json.MarshalIndent(metadata, "", "  ")
+	if err != nil {
+		return err
+	}
+	filesJSON, err := json.MarshalIndent(files, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	contents := map[string][]byte{
+		"README.md":      []byte(reproReadme(&metadata)),
+		"metadata.json":  metadataJSON,
+		"prompts.jsonl":  promptsJSONL.Bytes(),
+		"prompts.md":     []byte(promptsMarkdown.String()),
+		"files.json":     filesJSON,
+		"expected.patch": []byte(RenderDiffs(changes)),
+	}
+	names := make([]string, 0, len(contents)+1)
+	for name := range contents {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var checksums strings.Builder
+	for _, name := range names {
+		sum := sha256.Sum256(contents[name])
+		fmt.Fprintf(&checksums, "%s  %s\n", hex.EncodeToString(sum[:]), name)
+	}
+	contents["checksums.sha256"] = []byte(checksums.String())
+	names = append(names, "checksums.sha256")
+
+	return writeZip(w, names, contents, metadata.GeneratedAt)
+}
+
+// reproReadme explains how to replay the session from the bundle
+func reproReadme(metadata *ReproMetadata) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Reproducing session %s\n\n", metadata.Session.ID)
+	fmt.Fprintf(&b, "Project: %s\n", metadata.Session.ProjectName)
+	fmt.Fprintf(&b, "Model: %s\n", metadata.Model)
+	if len(metadata.ClaudeVersions) > 0 {
+		fmt.Fprintf(&b, "Claude Code version: %s\n", strings.Join(metadata.ClaudeVersions, ", "))
+	}
+	fmt.Fprintf(&b, "Started: %s\n\n", metadata.Session.StartTime.UTC().Format(time.RFC3339))
+
+	b.WriteString("## Steps\n\n")
+	step := 1
+	if metadata.Git.Remote != "" {
+		fmt.Fprintf(&b, "%d. Clone %s.\n", step, metadata.Git.Remote)
+		step++
+	}
+	switch {
+	case metadata.Git.BaseCommit != "":
+		fmt.Fprintf(&b, "%d. Check out %s, the last commit before the session started", step, metadata.Git.BaseCommit)
+		if metadata.Git.Branch != "" {
+			fmt.Fprintf(&b, " (on %s)", metadata.Git.Branch)
+		}
+		b.WriteString(".\n")
+		step++
+	case metadata.Git.Branch != "":
+		fmt.Fprintf(&b, "%d. Check out %s as it was at the start time above; the exact commit could not be determined.\n", step, metadata.Git.Branch)
+		step++
+	}
+	fmt.Fprintf(&b, "%d. Start Claude Code with the model above and send the prompts in prompts.md in order.\n", step)
+	fmt.Fprintf(&b, "%d. Compare the result against expected.patch. files.json lists each file changed and whether it existed at the base commit.\n\n", step+1)
+
+	b.WriteString("Verify the bundle with `sha256sum -c checksums.sha256`.\n")
+	return b.String()
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,79 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ksred/claude-session-manager/internal/database"
+)
+
+func TestWriteReproBundle(t *testing.T) {
+	now := time.Date(2025, 3, 14, 10, 0, 0, 0, time.UTC)
+	records := &database.ReproRecords{
+		Session:   &database.SessionSummary{ID: "session-1", ProjectName: "project", Model: "claude-sonnet", StartTime: now},
+		GitBranch: "feature/flags",
+		Messages: []database.Message{
+			{ID: "m1", Role: "user", Content: `"Rename the flag"`, Version: "1.0.51", CWD: "/repo", Timestamp: now},
+			{ID: "m2", Role: "assistant", Version: "1.0.51", Timestamp: now, Content: `[
+				{"type":"tool_use","name":"Edit","input":{"file_path":"/repo/main.go","old_string":"verbose","new_string":"debug"}},
+				{"type":"tool_use","name":"Write","input":{"file_path":"/repo/NOTES.md","content":"renamed\n"}}
+			]`},
+			{ID: "m3", Role: "user", Content: `[{"type":"tool_result","content":"ok"}]`, Timestamp: now},
+			{ID: "m4", Role: "user", Content: `[{"type":"text","text":"Now update the docs"}]`, Timestamp: now.Add(time.Minute)},
+		},
+	}
+	git := &ReproGit{
+		Remote:     "github.com/org/repo",
+		Root:       "/repo",
+		BaseCommit: "abc123",
+		Tracked:    map[string]bool{"main.go": true},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteReproBundle(&buf, records, git); err != nil {
+		t.Fatalf("Failed to write bundle: %v", err)
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("Failed to read bundle: %v", err)
+	}
+	files := make(map[string]string)
+	for _, entry := range archive.File {
+		reader, err := entry.Open()
+		if err != nil {
+			t.Fatalf("Failed to open %s: %v", entry.Name, err)
+		}
+		data, _ := io.ReadAll(reader)
+		files[entry.Name] = string(data)
+		reader.Close()
+	}
+
+	for _, name := range []string{"README.md", "metadata.json", "prompts.jsonl", "prompts.md", "files.json", "expected.patch", "checksums.sha256"} {
+		if _, exists := files[name]; !exists {
+			t.Errorf("Bundle is missing %s", name)
+		}
+	}
+
+	// Tool results are not prompts
+	if lines := strings.Split(strings.TrimSpace(files["prompts.jsonl"]), "\n"); len(lines) != 2 || !strings.Contains(lines[1], "Now update the docs") {
+		t.Errorf("Unexpected prompts: %v", lines)
+	}
+	if !strings.Contains(files["README.md"], "Check out abc123") || !strings.Contains(files["README.md"], "Clone github.com/org/repo") {
+		t.Errorf("Unexpected README:\n%s", files["README.md"])
+	}
+	if !strings.Contains(files["metadata.json"], `"claude_versions": [
+    "1.0.51"
+  ]`) || !strings.Contains(files["metadata.json"], `"branch": "feature/flags"`) {
+		t.Errorf("Unexpected metadata:\n%s", files["metadata.json"])
+	}
+
+	changed := ReproFiles(FileChanges(records.Messages), git)
+	if len(changed) != 2 || changed[0].RelativePath != "NOTES.md" || *changed[0].ExistedAtBase || !*changed[1].ExistedAtBase {
+		t.Errorf("Unexpected files: %+v", changed)
+	}
+}
@@ -0,0 +1,419 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ksred/claude-session-manager/internal/database"
+)
+
+// TranscriptSchemaVersion is bumped whenever the JSON transcript's fields change
+const TranscriptSchemaVersion = 1
+
+// Transcript export formats
+const (
+	TranscriptJSON     = "json"
+	TranscriptMarkdown = "markdown"
+	TranscriptHTML     = "html"
+)
+
+// maxToolOutput is how much of a tool's input or result the Markdown and HTML
+// transcripts show; the JSON transcript keeps everything
+const maxToolOutput = 4000
+
+// TranscriptBlock is one content block of a message
+type TranscriptBlock struct {
+	Type      string          `json:"type"` // text, thinking, tool_use, tool_result, or the block's own type
+	Text      string          `json:"text,omitempty"`
+	ToolUseID string          `json:"tool_use_id,omitempty"`
+	ToolName  string          `json:"tool_name,omitempty"`
+	Input     json.RawMessage `json:"input,omitempty"`
+	IsError   bool            `json:"is_error,omitempty"`
+}
+
+// TranscriptEntry is a message in conversation order with its token usage
+type TranscriptEntry struct {
+	ID          string               `json:"id"`
+	ParentUUID  *string              `json:"parent_uuid,omitempty"`
+	Role        string               `json:"role"`
+	Timestamp   time.Time            `json:"timestamp"`
+	IsSidechain bool                 `json:"is_sidechain,omitempty"`
+	Blocks      []TranscriptBlock    `json:"blocks"`
+	Usage       *database.TokenUsage `json:"usage,omitempty"`
+	Cost        float64              `json:"cost"`
+}
+
+// Transcript is a session's full conversation
+type Transcript struct {
+	SchemaVersion int                      `json:"schema_version"`
+	GeneratedAt   time.Time                `json:"generated_at"`
+	Session       *database.SessionSummary `json:"session"`
+	TotalTokens   int                      `json:"total_tokens"`
+	TotalCost     float64                  `json:"total_cost"`
+	Messages      []TranscriptEntry        `json:"messages"`
+}
+
+// OrderMessages orders messages by conversation rather than by timestamp
+// alone: each message follows its parent, with replies to the same parent
+// and messages whose parent isn't in the session ordered by timestamp
+func OrderMessages(messages []database.Message) []database.Message {
+	byID := make(map[string]bool, len(messages))
+	for _, message := range messages {
+		byID[message.ID] = true
+	}
+
+	sorted := append([]database.Message(nil), messages...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Timestamp.Before(sorted[j].Timestamp) })
+
+	children := make(map[string][]database.Message)
+	var roots []database.Message
+	for _, message := range sorted {
+		if message.ParentUUID != nil && byID[*message.ParentUUID] && *message.ParentUUID != message.ID {
+			children[*message.ParentUUID] = append(children[*message.ParentUUID], message)
+		} else {
+			roots = append(roots, message)
+		}
+	}
+
+	ordered := make([]database.Message, 0, len(messages))
+	visited := make(map[string]bool, len(messages))
+	var walk func(message database.Message)
+	walk = func(message database.Message) {
+		if visited[message.ID] {
+			return
+		}
+		visited[message.ID] = true
+		ordered = append(ordered, message)
+		for _, child := range children[message.ID] {
+			walk(child)
+		}
+	}
+	for _, root := range roots {
+		walk(root)
+	}
+	// Messages caught in a parent cycle have no root to be reached from
+	for _, message := range sorted {
+		walk(message)
+	}
+	return ordered
+}
+
+// BuildTranscript orders a session's messages by conversation and splits
+// their content into blocks, naming the tool each tool result answers
+func BuildTranscript(records *database.TranscriptRecords) *Transcript {
+	usage := make(map[string]database.TokenUsage, len(records.TokenUsage))
+	for _, u := range records.TokenUsage {
+		usage[u.MessageID] = u
+	}
+
+	transcript := &Transcript{
+		SchemaVersion: TranscriptSchemaVersion,
+		GeneratedAt:   time.Now().UTC(),
+		Session:       records.Session,
+		Messages:      []TranscriptEntry{},
+	}
+	toolNames := make(map[string]string)
+	for _, message := range OrderMessages(records.Messages) {
+		entry := TranscriptEntry{
+			ID:          message.ID,
+			ParentUUID:  message.ParentUUID,
+			Role:        message.Role,
+			Timestamp:   message.Timestamp,
+			IsSidechain: message.IsSidechain,
+			Blocks:      parseBlocks(message.Content),
+		}
+		if entry.Role == "" {
+			entry.Role = message.Type
+		}
+		for i, block := range entry.Blocks {
+			switch block.Type {
+			case "tool_use":
+				toolNames[block.ToolUseID] = block.ToolName
+			case "tool_result":
+				entry.Blocks[i].ToolName = toolNames[block.ToolUseID]
+			}
+		}
+		if u, ok := usage[message.ID]; ok {
+			entry.Usage = &u
+			entry.Cost = u.EstimatedCost
+			transcript.TotalTokens += u.TotalTokens
+			transcript.TotalCost += u.EstimatedCost
+		}
+		transcript.Messages = append(transcript.Messages, entry)
+	}
+	return transcript
+}
+
+// parseBlocks splits stored message content, either a JSON string or a JSON
+// array of content blocks, into transcript blocks
+func parseBlocks(content string) []TranscriptBlock {
+	var text string
+	if err := json.Unmarshal([]byte(content), &text); err == nil {
+		if text == "" {
+			return []TranscriptBlock{}
+		}
+		return []TranscriptBlock{{Type: "text", Text: text}}
+	}
+
+	var items []map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(content), &items); err != nil {
+		if content == "" || content == "{}" || content == "null" {
+			return []TranscriptBlock{}
+		}
+		return []TranscriptBlock{{Type: "text", Text: content}}
+	}
+
+	blocks := make([]TranscriptBlock, 0, len(items))
+	for _, item := range items {
+		block := TranscriptBlock{Type: rawString(item["type"])}
+		switch block.Type {
+		case "text":
+			block.Text = rawString(item["text"])
+		case "thinking":
+			block.Text = rawString(item["thinking"])
+		case "tool_use":
+			block.ToolUseID = rawString(item["id"])
+			block.ToolName = rawString(item["name"])
+			block.Input = item["input"]
+		case "tool_result":
+			block.ToolUseID = rawString(item["tool_use_id"])
+			block.Text = resultText(item["content"])
+			json.Unmarshal(item["is_error"], &block.IsError)
+		case "image":
+			block.Text = "[image]"
+		default:
+			raw, _ := json.Marshal(item)
+			block.Text = string(raw)
+		}
+		blocks = append(blocks, block)
+	}
+	return blocks
+}
+
+func rawString(raw json.RawMessage) string {
+	var s string
+	json.Unmarshal(raw, &s)
+	return s
+}
+
+// resultText flattens a tool result's content, a string or a list of text
+// and image blocks
+func resultText(raw json.RawMessage) string {
+	if len(raw) == 0 {
+		return ""
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	var items []map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &items); err != nil {
+		return string(raw)
+	}
+	var parts []string
+	for _, item := range items {
+		switch rawString(item["type"]) {
+		case "text":
+			parts = append(parts, rawString(item["text"]))
+		case "image":
+			parts = append(parts, "[image]")
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// WriteJSON writes the transcript as indented JSON
+func (t *Transcript) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(t)
+}
+
+// WriteMarkdown writes the transcript as a Markdown document, with tool
+// calls and results in code blocks
+func (t *Transcript) WriteMarkdown(w io.Writer) error {
+	var b strings.Builder
+	session := t.Session
+	fmt.Fprintf(&b, "# %s\n\n", transcriptTitle(session))
+	fmt.Fprintf(&b, "- **Session:** `%s`\n", session.ID)
+	fmt.Fprintf(&b, "- **Project:** `%s`\n", session.ProjectPath)
+	if session.Model != "" {
+		fmt.Fprintf(&b, "- **Model:** %s\n", session.Model)
+	}
+	fmt.Fprintf(&b, "- **Started:** %s\n", formatTimestamp(session.StartTime))
+	fmt.Fprintf(&b, "- **Last activity:** %s\n", formatTimestamp(session.LastActivity))
+	fmt.Fprintf(&b, "- **Messages:** %d\n", len(t.Messages))
+	fmt.Fprintf(&b, "- **Tokens:** %d\n", t.TotalTokens)
+	fmt.Fprintf(&b, "- **Cost:** %s\n", formatCost(t.TotalCost))
+
+	for _, entry := range t.Messages {
+		fmt.Fprintf(&b, "\n---\n\n## %s · %s", roleLabel(entry), formatTimestamp(entry.Timestamp))
+		if entry.Usage != nil {
+			fmt.Fprintf(&b, " · %d tokens · %s", entry.Usage.TotalTokens, formatCost(entry.Cost))
+		}
+		b.WriteString("\n")
+
+		for _, block := range entry.Blocks {
+			b.WriteString("\n")
+			switch block.Type {
+			case "text":
+				b.WriteString(block.Text + "\n")
+			case "thinking":
+				for _, line := range strings.Split(block.Text, "\n") {
+					b.WriteString("> " + line + "\n")
+				}
+			case "tool_use":
+				fmt.Fprintf(&b, "**Tool call: %s**\n\n", block.ToolName)
+				writeFenced(&b, "json", truncateOutput(prettyJSON(block.Input)))
+			case "tool_result":
+				label := "Tool result"
+				if block.ToolName != "" {
+					label += ": " + block.ToolName
+				}
+				if block.IsError {
+					label += " (error)"
+				}
+				fmt.Fprintf(&b, "**%s**\n\n", label)
+				writeFenced(&b, "", truncateOutput(block.Text))
+			default:
+				fmt.Fprintf(&b, "*%s:* %s\n", block.Type, block.Text)
+			}
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeFenced writes text in a code fence longer than any backtick run in it
+func writeFenced(b *strings.Builder, lang, text string) {
+	fence := "```"
+	for strings.Contains(text, fence) {
+		fence += "`"
+	}
+	fmt.Fprintf(b, "%s%s\n%s\n%s\n", fence, lang, strings.TrimSuffix(text, "\n"), fence)
+}
+
+// WriteHTML writes the transcript as a standalone HTML page, with tool calls
+// and results collapsed
+func (t *Transcript) WriteHTML(w io.Writer) error {
+	return transcriptTemplate.Execute(w, t)
+}
+
+var transcriptTemplate = template.Must(template.New("transcript").Funcs(template.FuncMap{
+	"title":     transcriptTitle,
+	"role":      roleLabel,
+	"timestamp": formatTimestamp,
+	"cost":      formatCost,
+	"json":      func(raw json.RawMessage) string { return truncateOutput(prettyJSON(raw)) },
+	"output":    truncateOutput,
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{title .Session}}</title>
+<style>
+body { font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", sans-serif; max-width: 960px; margin: 2rem auto; padding: 0 1rem; color: #1f2328; }
+dl { display: grid; grid-template-columns: max-content auto; gap: .25rem 1rem; }
+dt { font-weight: 600; }
+.message { border: 1px solid #d0d7de; border-radius: 6px; margin: 1rem 0; padding: .75rem 1rem; }
+.message.user { background: #f6f8fa; }
+.message.sidechain { margin-left: 2rem; }
+.meta { color: #656d76; font-size: .85rem; margin-bottom: .5rem; }
+.text { white-space: pre-wrap; }
+.thinking { white-space: pre-wrap; color: #656d76; border-left: 3px solid #d0d7de; padding-left: .75rem; }
+pre { background: #f6f8fa; padding: .5rem; overflow-x: auto; white-space: pre-wrap; }
+details { margin: .5rem 0; }
+summary { cursor: pointer; font-weight: 600; }
+.error summary { color: #cf222e; }
+</style>
+</head>
+<body>
+<h1>{{title .Session}}</h1>
+<dl>
+<dt>Session</dt><dd><code>{{.Session.ID}}</code></dd>
+<dt>Project</dt><dd><code>{{.Session.ProjectPath}}</code></dd>
+{{- if .Session.Model}}
+<dt>Model</dt><dd>{{.Session.Model}}</dd>
+{{- end}}
+<dt>Started</dt><dd>{{timestamp .Session.StartTime}}</dd>
+<dt>Last activity</dt><dd>{{timestamp .Session.LastActivity}}</dd>
+<dt>Messages</dt><dd>{{len .Messages}}</dd>
+<dt>Tokens</dt><dd>{{.TotalTokens}}</dd>
+<dt>Cost</dt><dd>{{cost .TotalCost}}</dd>
+</dl>
+{{- range .Messages}}
+<div class="message {{.Role}}{{if .IsSidechain}} sidechain{{end}}" id="{{.ID}}">
+<div class="meta">{{role .}} · {{timestamp .Timestamp}}{{if .Usage}} · {{.Usage.TotalTokens}} tokens · {{cost .Cost}}{{end}}</div>
+{{- range .Blocks}}
+{{- if eq .Type "text"}}
+<div class="text">{{.Text}}</div>
+{{- else if eq .Type "thinking"}}
+<div class="thinking">{{.Text}}</div>
+{{- else if eq .Type "tool_use"}}
+<details><summary>Tool call: {{.ToolName}}</summary><pre>{{json .Input}}</pre></details>
+{{- else if eq .Type "tool_result"}}
+<details{{if .IsError}} class="error"{{end}}><summary>Tool result{{if .ToolName}}: {{.ToolName}}{{end}}{{if .IsError}} (error){{end}}</summary><pre>{{output .Text}}</pre></details>
+{{- else}}
+<div class="text"><em>{{.Type}}:</em> {{.Text}}</div>
+{{- end}}
+{{- end}}
+</div>
+{{- end}}
+</body>
+</html>
+`))
+
+func transcriptTitle(session *database.SessionSummary) string {
+	return fmt.Sprintf("%s · %s", session.ProjectName, formatTimestamp(session.StartTime))
+}
+
+func roleLabel(entry TranscriptEntry) string {
+	label := "Assistant"
+	if entry.Role == "user" {
+		label = "User"
+		// Tool results come back to the model as user messages
+		if len(entry.Blocks) > 0 && entry.Blocks[0].Type == "tool_result" {
+			label = "Tool"
+		}
+	} else if entry.Role != "assistant" && entry.Role != "" {
+		label = strings.ToUpper(entry.Role[:1]) + entry.Role[1:]
+	}
+	if entry.IsSidechain {
+		label += " (subagent)"
+	}
+	return label
+}
+
+func formatTimestamp(t time.Time) string {
+	return t.UTC().Format("2006-01-02 15:04:05 UTC")
+}
+
+func formatCost(cost float64) string {
+	return fmt.Sprintf("$%.4f", cost)
+}
+
+func prettyJSON(raw json.RawMessage) string {
+	if len(raw) == 0 {
+		return "{}"
+	}
+	var out bytes.Buffer
+	if err := json.Indent(&out, raw, "", "  "); err != nil {
+		return string(raw)
+	}
+	return out.String()
+}
+
+func truncateOutput(text string) string {
+	runes := []rune(text)
+	if len(runes) <= maxToolOutput {
+		return text
+	}
+	return fmt.Sprintf("%s\n… (%d more characters)", string(runes[:maxToolOutput]), len(runes)-maxToolOutput)
+}
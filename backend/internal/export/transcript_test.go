@@ -0,0 +1,97 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ksred/claude-session-manager/internal/database"
+)
+
+func transcriptRecords() *database.TranscriptRecords {
+	now := time.Date(2025, 3, 14, 10, 0, 0, 0, time.UTC)
+	parent := func(id string) *string { return &id }
+	return &database.TranscriptRecords{
+		Session: &database.SessionSummary{ID: "session-1", ProjectName: "project", ProjectPath: "/repo", StartTime: now, LastActivity: now},
+		// The tool result is stamped before the call that produced it
+		Messages: []database.Message{
+			{ID: "m1", Role: "user", Content: `"Show <main.go>"`, Timestamp: now},
+			{ID: "m3", ParentUUID: parent("m2"), Role: "user", Timestamp: now.Add(time.Second), Content: `[
+				{"type":"tool_result","tool_use_id":"toolu_1","content":[{"type":"text","text":"package main\n` + "```" + `"}]}
+			]`},
+			{ID: "m2", ParentUUID: parent("m1"), Role: "assistant", Timestamp: now.Add(2 * time.Second), Content: `[
+				{"type":"text","text":"Reading it"},
+				{"type":"tool_use","id":"toolu_1","name":"Read","input":{"file_path":"/repo/main.go"}}
+			]`},
+			{ID: "m4", ParentUUID: parent("missing"), Role: "assistant", Content: `"Done"`, Timestamp: now.Add(3 * time.Second)},
+		},
+		TokenUsage: []database.TokenUsage{
+			{MessageID: "m2", TotalTokens: 120, EstimatedCost: 0.01},
+			{MessageID: "m4", TotalTokens: 30, EstimatedCost: 0.002},
+		},
+	}
+}
+
+func TestBuildTranscript(t *testing.T) {
+	transcript := BuildTranscript(transcriptRecords())
+
+	var ids []string
+	for _, entry := range transcript.Messages {
+		ids = append(ids, entry.ID)
+	}
+	if strings.Join(ids, ",") != "m1,m2,m3,m4" {
+		t.Errorf("Expected messages in conversation order, got %v", ids)
+	}
+	if transcript.TotalTokens != 150 || transcript.Messages[1].Cost != 0.01 {
+		t.Errorf("Expected 150 tokens and per-message cost, got %d and %v", transcript.TotalTokens, transcript.Messages[1].Cost)
+	}
+
+	result := transcript.Messages[2].Blocks[0]
+	if result.Type != "tool_result" || result.ToolName != "Read" || !strings.HasPrefix(result.Text, "package main") {
+		t.Errorf("Expected the Read tool result, got %+v", result)
+	}
+
+	var buf bytes.Buffer
+	if err := transcript.WriteJSON(&buf); err != nil {
+		t.Fatalf("Failed to write JSON: %v", err)
+	}
+	var decoded Transcript
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil || len(decoded.Messages) != 4 {
+		t.Errorf("Expected a JSON transcript with 4 messages, got %v", err)
+	}
+}
+
+func TestTranscript_WriteMarkdown(t *testing.T) {
+	var buf bytes.Buffer
+	if err := BuildTranscript(transcriptRecords()).WriteMarkdown(&buf); err != nil {
+		t.Fatalf("Failed to write Markdown: %v", err)
+	}
+	markdown := buf.String()
+
+	for _, want := range []string{"**Tool call: Read**", `"file_path": "/repo/main.go"`, "**Tool result: Read**", "## Tool ·", "120 tokens · $0.0100"} {
+		if !strings.Contains(markdown, want) {
+			t.Errorf("Expected Markdown to contain %q:\n%s", want, markdown)
+		}
+	}
+	// The result's own fence must not close the code block
+	if !strings.Contains(markdown, "````\npackage main\n```\n````") {
+		t.Errorf("Expected a longer fence around output containing backticks:\n%s", markdown)
+	}
+}
+
+func TestTranscript_WriteHTML(t *testing.T) {
+	var buf bytes.Buffer
+	if err := BuildTranscript(transcriptRecords()).WriteHTML(&buf); err != nil {
+		t.Fatalf("Failed to write HTML: %v", err)
+	}
+	page := buf.String()
+
+	if strings.Contains(page, "<main.go>") || !strings.Contains(page, "Show &lt;main.go&gt;") {
+		t.Error("Expected message content to be escaped")
+	}
+	if !strings.Contains(page, "<summary>Tool call: Read</summary>") {
+		t.Error("Expected the tool call in a collapsible section")
+	}
+}
@@ -0,0 +1,410 @@
+// Package exportjobs generates heavy exports, such as database backups and
+// analytics datasets, in the background so they don't tie up API workers or
+// time out behind proxies. A finished export is downloaded through a signed
+// URL that stops working after a few minutes, and its file is deleted once
+// the export expires. Each client may start a limited number an hour.
+package exportjobs
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ksred/claude-session-manager/internal/config"
+	"github.com/ksred/claude-session-manager/internal/database"
+	"github.com/ksred/claude-session-manager/internal/export"
+	"github.com/sirupsen/logrus"
+)
+
+// Export kinds
+const (
+	KindBackup    = "backup"    // a copy of the SQLite database
+	KindAnalytics = "analytics" // a token_usage, sessions or tool_results dataset
+)
+
+// FormatSQLite is the format of backups
+const FormatSQLite = "sqlite"
+
+// DownloadPath is where finished exports are downloaded from. Requests under
+// it carry a signature instead of an API key.
+const DownloadPath = "/api/v1/downloads/exports"
+
+// pruneInterval is how often expired exports are deleted
+const pruneInterval = time.Hour
+
+var (
+	// ErrInvalidRequest is returned for exports that can't be generated
+	ErrInvalidRequest = errors.New("invalid export")
+	// ErrLimitExceeded is returned when a client has started its exports
+	// for the hour
+	ErrLimitExceeded = errors.New("export limit exceeded")
+	// ErrInvalidSignature is returned for download URLs that weren't signed
+	// by this server
+	ErrInvalidSignature = errors.New("invalid download signature")
+	// ErrURLExpired is returned for download URLs past their expiry
+	ErrURLExpired = errors.New("download url expired")
+)
+
+// Request describes an export to generate. Format defaults to sqlite for
+// backups and csv for analytics; Days limits analytics to rows from that many
+// days back.
+type Request struct {
+	Kind    string `json:"kind"`
+	Dataset string `json:"dataset,omitempty"`
+	Format  string `json:"format,omitempty"`
+	Days    int    `json:"days,omitempty"`
+}
+
+// Job is an export job with a freshly signed download URL once it has
+// succeeded
+type Job struct {
+	database.ExportJob
+	DownloadURL  string     `json:"download_url,omitempty"`
+	URLExpiresAt *time.Time `json:"url_expires_at,omitempty"`
+}
+
+// Usage is how many exports a client started in the last hour, against the
+// limit, and how much space its kept exports take
+type Usage struct {
+	Started   int        `json:"started"`
+	Limit     int        `json:"limit"`              // 0 for no limit
+	Remaining *int       `json:"remaining"`          // nil without a limit
+	ResetAt   *time.Time `json:"reset_at,omitempty"` // when the oldest export counted stops counting
+	SizeBytes int64      `json:"size_bytes"`
+}
+
+// Runner generates exports, a few at a time, and signs their download URLs
+type Runner struct {
+	repo      *database.SessionRepository
+	dir       string
+	key       []byte
+	urlTTL    time.Duration
+	retention time.Duration
+	perHour   int
+	slots     chan struct{}
+	logger    *logrus.Logger
+	now       func() time.Time
+
+	mu sync.Mutex // held while metering so concurrent requests can't share the last export
+}
+
+// NewRunner creates a runner writing exports to cfg's directory, or to dir
+// when it isn't set. Exports a previous run left unfinished are failed, as
+// nothing is generating them any more.
+func NewRunner(cfg config.ExportsConfig, dir string, repo *database.SessionRepository, logger *logrus.Logger) (*Runner, error) {
+	if cfg.Directory != "" {
+		dir = cfg.Directory
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create exports directory: %w", err)
+	}
+
+	key := []byte(cfg.SigningKey)
+	if len(key) == 0 {
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, fmt.Errorf("failed to create signing key: %w", err)
+		}
+	}
+
+	r := &Runner{
+		repo:      repo,
+		dir:       dir,
+		key:       key,
+		urlTTL:    time.Duration(cfg.URLTTL) * time.Minute,
+		retention: time.Duration(cfg.Retention) * time.Hour,
+		perHour:   cfg.PerHour,
+		slots:     make(chan struct{}, max(cfg.Workers, 1)),
+		logger:    logger,
+		now:       time.Now,
+	}
+	if r.urlTTL <= 0 {
+		r.urlTTL = 15 * time.Minute
+	}
+	if r.retention <= 0 {
+		r.retention = 24 * time.Hour
+	}
+
+	if failed, err := repo.FailUnfinishedExportJobs("interrupted by a restart", r.now().Add(r.retention)); err != nil {
+		return nil, err
+	} else if failed > 0 {
+		logger.WithField("exports", failed).Warn("Failed exports left unfinished by a restart")
+	}
+	return r, nil
+}
+
+// Submit starts generating an export for client, returning its pending job
+func (r *Runner) Submit(client string, req Request) (*Job, error) {
+	job, err := newJob(client, req)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	usage, err := r.Usage(client)
+	if err != nil {
+		return nil, err
+	}
+	if usage.Remaining != nil && *usage.Remaining == 0 {
+		return nil, fmt.Errorf("%w: %d an hour", ErrLimitExceeded, r.perHour)
+	}
+	if err := r.repo.CreateExportJob(job); err != nil {
+		return nil, err
+	}
+
+	view := r.Job(job)
+	go r.run(job)
+	return &view, nil
+}
+
+// newJob validates req, filling in its defaults
+func newJob(client string, req Request) (*database.ExportJob, error) {
+	job := &database.ExportJob{Client: client, Kind: req.Kind, Format: req.Format}
+	switch req.Kind {
+	case KindBackup:
+		if job.Format == "" {
+			job.Format = FormatSQLite
+		}
+		if job.Format != FormatSQLite || req.Dataset != "" || req.Days != 0 {
+			return nil, fmt.Errorf("%w: backups are sqlite and take no dataset or days", ErrInvalidRequest)
+		}
+	case KindAnalytics:
+		if _, ok := database.AnalyticsExportColumns(req.Dataset); !ok {
+			return nil, fmt.Errorf("%w: dataset must be token_usage, sessions or tool_results", ErrInvalidRequest)
+		}
+		if job.Format == "" {
+			job.Format = "csv"
+		}
+		if !export.IsAnalyticsFormat(job.Format) {
+			return nil, fmt.Errorf("%w: format must be csv or parquet", ErrInvalidRequest)
+		}
+		if req.Days < 0 {
+			return nil, fmt.Errorf("%w: days must not be negative", ErrInvalidRequest)
+		}
+		job.Dataset = &req.Dataset
+		job.Days = req.Days
+	default:
+		return nil, fmt.Errorf("%w: kind must be %s or %s", ErrInvalidRequest, KindBackup, KindAnalytics)
+	}
+	return job, nil
+}
+
+// run generates a job's file once a worker is free and records the outcome
+func (r *Runner) run(job *database.ExportJob) {
+	r.slots <- struct{}{}
+	defer func() { <-r.slots }()
+
+	started := r.now().UTC()
+	job.Status = database.RunStatusRunning
+	job.StartedAt = &started
+	if err := r.repo.UpdateExportJob(job); err != nil {
+		r.logger.WithError(err).WithField("export", job.ID).Error("Failed to start export")
+	}
+
+	err := r.generate(job)
+	completed := r.now().UTC()
+	expires := completed.Add(r.retention)
+	job.CompletedAt = &completed
+	job.ExpiresAt = &expires
+	if err != nil {
+		message := err.Error()
+		job.Status = database.RunStatusFailed
+		job.Error = &message
+		job.FileName = nil
+		r.logger.WithError(err).WithField("export", job.ID).Error("Failed to generate export")
+	} else {
+		job.Status = database.RunStatusSucceeded
+		r.logger.WithFields(logrus.Fields{
+			"export": job.ID,
+			"kind":   job.Kind,
+			"bytes":  job.SizeBytes,
+		}).Info("Generated export")
+	}
+	if err := r.repo.UpdateExportJob(job); err != nil {
+		r.logger.WithError(err).WithField("export", job.ID).Error("Failed to record export")
+	}
+}
+
+// generate writes a job's file, removing what was written if it fails
+func (r *Runner) generate(job *database.ExportJob) error {
+	date := job.CreatedAt.Format("20060102")
+	var fileName string
+	if job.Kind == KindBackup {
+		fileName = fmt.Sprintf("claude-sessions-%s.db", date)
+	} else {
+		fileName = fmt.Sprintf("claude-%s-%s.%s", strings.ReplaceAll(*job.Dataset, "_", "-"), date, job.Format)
+	}
+	job.FileName = &fileName
+	path := r.path(job)
+
+	var err error
+	if job.Kind == KindBackup {
+		err = r.repo.BackupTo(path)
+	} else {
+		err = r.writeAnalytics(job, path)
+	}
+	if err == nil {
+		var info os.FileInfo
+		if info, err = os.Stat(path); err == nil {
+			job.SizeBytes = info.Size()
+		}
+	}
+	if err != nil {
+		os.Remove(path)
+	}
+	return err
+}
+
+// writeAnalytics writes a job's analytics dataset to path
+func (r *Runner) writeAnalytics(job *database.ExportJob, path string) error {
+	columns, _ := database.AnalyticsExportColumns(*job.Dataset)
+	var since time.Time
+	if job.Days > 0 {
+		since = job.CreatedAt.AddDate(0, 0, -job.Days)
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create export file: %w", err)
+	}
+	writer, err := export.NewAnalyticsWriter(job.Format, file, columns)
+	if err == nil {
+		err = r.repo.StreamAnalyticsExport(*job.Dataset, since, writer.WriteRow)
+		if closeErr := writer.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// path returns where a job's file is kept, named by its ID so names chosen
+// for downloads never collide
+func (r *Runner) path(job *database.ExportJob) string {
+	return filepath.Join(r.dir, job.ID+filepath.Ext(*job.FileName))
+}
+
+// Job returns an export job as the API shows it, with a download URL signed
+// now if it has succeeded
+func (r *Runner) Job(job *database.ExportJob) Job {
+	view := Job{ExportJob: *job}
+	if job.Status != database.RunStatusSucceeded {
+		return view
+	}
+
+	expires := r.now().Add(r.urlTTL).Truncate(time.Second)
+	if job.ExpiresAt != nil && job.ExpiresAt.Before(expires) {
+		expires = *job.ExpiresAt
+	}
+	view.DownloadURL = fmt.Sprintf("%s/%s?expires=%d&signature=%s", DownloadPath, job.ID, expires.Unix(), r.sign(job.ID, expires.Unix()))
+	view.URLExpiresAt = &expires
+	return view
+}
+
+// Open checks a download URL's signature and expiry, returning the job it
+// is for and the path of its file
+func (r *Runner) Open(id, expires, signature string) (*database.ExportJob, string, error) {
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || !hmac.Equal([]byte(signature), []byte(r.sign(id, unix))) {
+		return nil, "", ErrInvalidSignature
+	}
+	if r.now().Unix() >= unix {
+		return nil, "", ErrURLExpired
+	}
+
+	job, err := r.repo.GetExportJob(id)
+	if err != nil {
+		return nil, "", err
+	}
+	if job.Status != database.RunStatusSucceeded || job.FileName == nil {
+		return nil, "", fmt.Errorf("export not found: %s", id)
+	}
+	return job, r.path(job), nil
+}
+
+// sign returns the hex HMAC-SHA256 of a job ID and a URL's expiry
+func (r *Runner) sign(id string, expires int64) string {
+	mac := hmac.New(sha256.New, r.key)
+	mac.Write([]byte(id + "." + strconv.FormatInt(expires, 10)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Usage returns how many exports client started in the last hour and how
+// much space its kept exports take
+func (r *Runner) Usage(client string) (*Usage, error) {
+	jobs, err := r.repo.GetExportJobs(client)
+	if err != nil {
+		return nil, err
+	}
+
+	since := r.now().Add(-time.Hour)
+	usage := &Usage{Limit: r.perHour}
+	for _, job := range jobs {
+		usage.SizeBytes += job.SizeBytes
+		if !job.CreatedAt.After(since) {
+			continue
+		}
+		usage.Started++
+		if reset := job.CreatedAt.Add(time.Hour); usage.ResetAt == nil || reset.Before(*usage.ResetAt) {
+			usage.ResetAt = &reset
+		}
+	}
+	if r.perHour > 0 {
+		remaining := max(r.perHour-usage.Started, 0)
+		usage.Remaining = &remaining
+	}
+	return usage, nil
+}
+
+// Prune deletes the exports that have expired and their files, returning
+// how many there were
+func (r *Runner) Prune() (int, error) {
+	jobs, err := r.repo.GetExpiredExportJobs(r.now())
+	if err != nil {
+		return 0, err
+	}
+	for _, job := range jobs {
+		if job.FileName != nil {
+			if err := os.Remove(r.path(&job)); err != nil && !os.IsNotExist(err) {
+				return 0, fmt.Errorf("failed to delete export file: %w", err)
+			}
+		}
+		if err := r.repo.DeleteExportJob(job.ID); err != nil {
+			return 0, err
+		}
+	}
+	return len(jobs), nil
+}
+
+// Start deletes expired exports now and every hour until ctx is cancelled
+func (r *Runner) Start(ctx context.Context) {
+	ticker := time.NewTicker(pruneInterval)
+	defer ticker.Stop()
+
+	for {
+		if pruned, err := r.Prune(); err != nil {
+			r.logger.WithError(err).Warn("Failed to delete expired exports")
+		} else if pruned > 0 {
+			r.logger.WithField("exports", pruned).Debug("Deleted expired exports")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
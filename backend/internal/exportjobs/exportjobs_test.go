@@ -0,0 +1,145 @@
+package exportjobs
+
+import (
+	"errors"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ksred/claude-session-manager/internal/config"
+	"github.com/ksred/claude-session-manager/internal/database"
+	"github.com/sirupsen/logrus"
+)
+
+func setupTestRepo(t *testing.T) *database.SessionRepository {
+	tmpFile, err := os.CreateTemp("", "test-exports-*.db")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	tmpFile.Close()
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	db, err := database.NewDatabase(database.Config{DatabasePath: tmpFile.Name(), Logger: logger})
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	t.Cleanup(func() {
+		db.Close()
+		os.Remove(tmpFile.Name())
+	})
+
+	return database.NewSessionRepository(db, logger)
+}
+
+// waitFor returns a job once it has finished
+func waitFor(t *testing.T, repo *database.SessionRepository, id string) *database.ExportJob {
+	t.Helper()
+	for i := 0; i < 200; i++ {
+		job, err := repo.GetExportJob(id)
+		if err != nil {
+			t.Fatalf("Failed to get export job: %v", err)
+		}
+		if job.Status == database.RunStatusSucceeded || job.Status == database.RunStatusFailed {
+			return job
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("Export %s didn't finish", id)
+	return nil
+}
+
+func TestRunner(t *testing.T) {
+	repo := setupTestRepo(t)
+	at := time.Now().Add(-time.Hour)
+	if err := repo.UpsertSession(&database.Session{ID: "s1", ProjectPath: "/work/web", ProjectName: "web", StartTime: at, LastActivity: at, Status: "active"}); err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	interrupted := &database.ExportJob{Client: "key:1", Kind: KindBackup, Format: FormatSQLite}
+	if err := repo.CreateExportJob(interrupted); err != nil {
+		t.Fatalf("Failed to create export job: %v", err)
+	}
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	runner, err := NewRunner(config.ExportsConfig{URLTTL: 5, PerHour: 3}, t.TempDir(), repo, logger)
+	if err != nil {
+		t.Fatalf("Failed to create runner: %v", err)
+	}
+	if job, _ := repo.GetExportJob(interrupted.ID); job.Status != database.RunStatusFailed {
+		t.Errorf("Expected the interrupted export failed, got %+v", job)
+	}
+
+	if _, err := runner.Submit("key:1", Request{Kind: KindAnalytics, Dataset: "messages"}); !errors.Is(err, ErrInvalidRequest) {
+		t.Errorf("Expected an unknown dataset refused, got %v", err)
+	}
+	if _, err := runner.Submit("key:1", Request{Kind: KindBackup, Format: "csv"}); !errors.Is(err, ErrInvalidRequest) {
+		t.Errorf("Expected a csv backup refused, got %v", err)
+	}
+
+	submitted, err := runner.Submit("key:1", Request{Kind: KindAnalytics, Dataset: "sessions"})
+	if err != nil || submitted.Status != database.RunStatusPending || submitted.DownloadURL != "" {
+		t.Fatalf("Expected a pending export without a URL, got %+v (%v)", submitted, err)
+	}
+	job := waitFor(t, repo, submitted.ID)
+	if job.Status != database.RunStatusSucceeded || job.Format != "csv" || job.SizeBytes == 0 || !strings.HasSuffix(*job.FileName, ".csv") {
+		t.Fatalf("Expected a succeeded csv export, got %+v", job)
+	}
+
+	view := runner.Job(job)
+	if !strings.HasPrefix(view.DownloadURL, DownloadPath+"/"+job.ID+"?") || view.URLExpiresAt == nil {
+		t.Fatalf("Expected a signed download URL, got %+v", view)
+	}
+	download, _ := url.Parse(view.DownloadURL)
+	expires, signature := download.Query().Get("expires"), download.Query().Get("signature")
+	opened, file, err := runner.Open(path.Base(download.Path), expires, signature)
+	if err != nil || opened.ID != job.ID {
+		t.Fatalf("Failed to open export: %v", err)
+	}
+	if content, _ := os.ReadFile(file); !strings.Contains(string(content), "s1") {
+		t.Errorf("Expected the session in the export, got %q", content)
+	}
+	if _, _, err := runner.Open(job.ID, expires, strings.Repeat("0", len(signature))); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Expected a forged signature refused, got %v", err)
+	}
+	if _, _, err := runner.Open(interrupted.ID, expires, signature); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Expected a signature for another export refused, got %v", err)
+	}
+	runner.now = func() time.Time { return time.Now().Add(6 * time.Minute) }
+	if _, _, err := runner.Open(job.ID, expires, signature); !errors.Is(err, ErrURLExpired) {
+		t.Errorf("Expected the URL expired after its TTL, got %v", err)
+	}
+	runner.now = time.Now
+
+	backup, err := runner.Submit("key:1", Request{Kind: KindBackup})
+	if err != nil {
+		t.Fatalf("Failed to submit backup: %v", err)
+	}
+	if job := waitFor(t, repo, backup.ID); job.Status != database.RunStatusSucceeded || !strings.HasSuffix(*job.FileName, ".db") {
+		t.Fatalf("Expected a succeeded backup, got %+v", job)
+	}
+
+	// The interrupted export counts too
+	if _, err := runner.Submit("key:1", Request{Kind: KindBackup}); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("Expected the hourly limit reached, got %v", err)
+	}
+	usage, err := runner.Usage("key:1")
+	if err != nil || usage.Started != 3 || *usage.Remaining != 0 || usage.ResetAt == nil || usage.SizeBytes == 0 {
+		t.Errorf("Expected the limit used up, got %+v (%v)", usage, err)
+	}
+	if usage, _ := runner.Usage("ip:10.0.0.1"); usage.Started != 0 || *usage.Remaining != 3 {
+		t.Errorf("Expected other clients metered separately, got %+v", usage)
+	}
+
+	runner.now = func() time.Time { return time.Now().Add(25 * time.Hour) }
+	pruned, err := runner.Prune()
+	if err != nil || pruned != 3 {
+		t.Fatalf("Expected every export pruned after a day, got %d (%v)", pruned, err)
+	}
+	if _, err := os.Stat(file); !os.IsNotExist(err) {
+		t.Errorf("Expected the export file deleted, got %v", err)
+	}
+}
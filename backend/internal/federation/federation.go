@@ -0,0 +1,262 @@
+// Package federation implements the org-wide rollup mode, where a parent
+// instance pulls usage aggregates from team instances and combines them.
+package federation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ksred/claude-session-manager/internal/config"
+	"github.com/ksred/claude-session-manager/internal/database"
+	"github.com/sirupsen/logrus"
+)
+
+// SummaryPath is the endpoint every instance serves its usage aggregates on
+const SummaryPath = "/api/v1/federation/summary"
+
+// Snapshot statuses
+const (
+	StatusOK    = "ok"
+	StatusError = "error"
+)
+
+// Poller periodically pulls usage summaries from team instances and stores
+// the latest one for each
+type Poller struct {
+	repo      *database.SessionRepository
+	instances []config.FederationInstance
+	days      int
+	interval  time.Duration
+	client    *http.Client
+	logger    *logrus.Logger
+}
+
+// NewPoller creates a new federation poller
+func NewPoller(repo *database.SessionRepository, cfg config.FederationConfig, logger *logrus.Logger) *Poller {
+	interval := time.Duration(cfg.PollInterval) * time.Second
+	if interval <= 0 {
+		interval = 5 * time.Minute
+	}
+	days := cfg.Days
+	if days <= 0 {
+		days = 30
+	}
+	return &Poller{
+		repo:      repo,
+		instances: cfg.Instances,
+		days:      days,
+		interval:  interval,
+		client:    &http.Client{Timeout: 30 * time.Second},
+		logger:    logger,
+	}
+}
+
+// Start polls every instance immediately and then on each interval until ctx is cancelled
+func (p *Poller) Start(ctx context.Context) {
+	p.PollAll(ctx)
+
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			p.PollAll(ctx)
+		}
+	}
+}
+
+// PollAll pulls a summary from every configured instance concurrently and
+// waits for them to finish
+func (p *Poller) PollAll(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, instance := range p.instances {
+		wg.Add(1)
+		go func(instance config.FederationInstance) {
+			defer wg.Done()
+			p.poll(ctx, instance)
+		}(instance)
+	}
+	wg.Wait()
+}
+
+// poll pulls one instance's summary and records the result
+func (p *Poller) poll(ctx context.Context, instance config.FederationInstance) {
+	now := time.Now().UTC()
+	snapshot := &database.FederationSnapshot{
+		InstanceName: instance.Name,
+		URL:          instance.URL,
+		Status:       StatusOK,
+		PolledAt:     now,
+	}
+
+	summary, err := p.fetch(ctx, instance)
+	if err != nil {
+		msg := err.Error()
+		snapshot.Status = StatusError
+		snapshot.Error = &msg
+		p.logger.WithError(err).WithField("instance", instance.Name).Warn("Failed to poll federated instance")
+	} else {
+		encoded := string(summary)
+		snapshot.Summary = &encoded
+		snapshot.LastSuccessAt = &now
+	}
+
+	if err := p.repo.SaveFederationSnapshot(snapshot); err != nil {
+		p.logger.WithError(err).WithField("instance", instance.Name).Error("Failed to save federation snapshot")
+	}
+}
+
+// fetch requests an instance's summary, with its API key if it has one, and
+// returns it after checking that it decodes
+func (p *Poller) fetch(ctx context.Context, instance config.FederationInstance) ([]byte, error) {
+	endpoint := strings.TrimRight(instance.URL, "/") + SummaryPath + "?" + url.Values{
+		"days": []string{fmt.Sprint(p.days)},
+	}.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	if instance.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+instance.APIKey)
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d from %s", resp.StatusCode, endpoint)
+	}
+
+	var summary database.UsageSummary
+	if err := json.NewDecoder(resp.Body).Decode(&summary); err != nil {
+		return nil, fmt.Errorf("invalid summary from %s: %w", endpoint, err)
+	}
+	return json.Marshal(summary)
+}
+
+// InstanceStatus reports the health and latest totals of one polled instance
+type InstanceStatus struct {
+	database.FederationSnapshot
+	Summary *database.UsageSummary `json:"summary,omitempty"`
+}
+
+// ProjectUsage is a project's usage on a particular instance
+type ProjectUsage struct {
+	Instance string `json:"instance"`
+	database.UsageAggregate
+}
+
+// OrgSummary combines the latest summaries of every instance
+type OrgSummary struct {
+	GeneratedAt    time.Time                 `json:"generated_at"`
+	Instances      []InstanceStatus          `json:"instances"`
+	TotalSessions  int                       `json:"total_sessions"`
+	ActiveSessions int                       `json:"active_sessions"`
+	TotalMessages  int                       `json:"total_messages"`
+	TotalTokens    int                       `json:"total_tokens"`
+	TotalCostUSD   float64                   `json:"total_cost_usd"`
+	ByInstance     []database.UsageAggregate `json:"by_instance"`
+	ByModel        []database.UsageAggregate `json:"by_model"`
+	ByDay          []database.UsageAggregate `json:"by_day"`
+	ByProject      []ProjectUsage            `json:"by_project"`
+}
+
+// Instances decodes the stored snapshots of every polled instance
+func Instances(snapshots []database.FederationSnapshot) []InstanceStatus {
+	statuses := make([]InstanceStatus, 0, len(snapshots))
+	for _, snapshot := range snapshots {
+		status := InstanceStatus{FederationSnapshot: snapshot}
+		if snapshot.Summary != nil {
+			var summary database.UsageSummary
+			if err := json.Unmarshal([]byte(*snapshot.Summary), &summary); err == nil {
+				status.Summary = &summary
+			}
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// Rollup combines instance summaries into organization-wide totals. Instances
+// whose last poll failed contribute their last successful summary.
+func Rollup(instances []InstanceStatus) *OrgSummary {
+	org := &OrgSummary{
+		GeneratedAt: time.Now().UTC(),
+		Instances:   instances,
+		ByInstance:  []database.UsageAggregate{},
+		ByProject:   []ProjectUsage{},
+	}
+	byModel := map[string]*database.UsageAggregate{}
+	byDay := map[string]*database.UsageAggregate{}
+
+	for _, instance := range instances {
+		summary := instance.Summary
+		if summary == nil {
+			continue
+		}
+
+		org.TotalSessions += summary.TotalSessions
+		org.ActiveSessions += summary.ActiveSessions
+		org.TotalMessages += summary.TotalMessages
+		org.TotalTokens += summary.TotalTokens
+		org.TotalCostUSD += summary.TotalCostUSD
+
+		org.ByInstance = append(org.ByInstance, database.UsageAggregate{
+			Key:      instance.InstanceName,
+			Sessions: summary.TotalSessions,
+			Messages: summary.TotalMessages,
+			Tokens:   summary.TotalTokens,
+			CostUSD:  summary.TotalCostUSD,
+		})
+		for _, project := range summary.ByProject {
+			org.ByProject = append(org.ByProject, ProjectUsage{Instance: instance.InstanceName, UsageAggregate: project})
+		}
+		merge(byModel, summary.ByModel)
+		merge(byDay, summary.ByDay)
+	}
+
+	org.ByModel = flatten(byModel)
+	sort.Slice(org.ByModel, func(i, j int) bool { return org.ByModel[i].CostUSD > org.ByModel[j].CostUSD })
+	org.ByDay = flatten(byDay)
+	sort.Slice(org.ByDay, func(i, j int) bool { return org.ByDay[i].Key < org.ByDay[j].Key })
+	sort.Slice(org.ByProject, func(i, j int) bool { return org.ByProject[i].CostUSD > org.ByProject[j].CostUSD })
+
+	return org
+}
+
+// merge adds aggregates into totals keyed by aggregate key
+func merge(totals map[string]*database.UsageAggregate, aggregates []database.UsageAggregate) {
+	for _, aggregate := range aggregates {
+		total, exists := totals[aggregate.Key]
+		if !exists {
+			total = &database.UsageAggregate{Key: aggregate.Key}
+			totals[aggregate.Key] = total
+		}
+		total.Sessions += aggregate.Sessions
+		total.Messages += aggregate.Messages
+		total.Tokens += aggregate.Tokens
+		total.CostUSD += aggregate.CostUSD
+	}
+}
+
+// flatten returns the values of a keyed aggregate map
+func flatten(totals map[string]*database.UsageAggregate) []database.UsageAggregate {
+	aggregates := make([]database.UsageAggregate, 0, len(totals))
+	for _, total := range totals {
+		aggregates = append(aggregates, *total)
+	}
+	return aggregates
+}
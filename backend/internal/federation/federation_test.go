@@ -0,0 +1,144 @@
+package federation
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/ksred/claude-session-manager/internal/config"
+	"github.com/ksred/claude-session-manager/internal/database"
+	"github.com/sirupsen/logrus"
+)
+
+func setupTestRepo(t *testing.T) *database.SessionRepository {
+	tmpFile, err := os.CreateTemp("", "test-federation-*.db")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	tmpFile.Close()
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.WarnLevel)
+	db, err := database.NewDatabase(database.Config{DatabasePath: tmpFile.Name(), Logger: logger})
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	t.Cleanup(func() {
+		db.Close()
+		os.Remove(tmpFile.Name())
+	})
+
+	return database.NewSessionRepository(db, logger)
+}
+
+// summaryServer serves a fixed usage summary on the federation endpoint
+func summaryServer(t *testing.T, summary database.UsageSummary) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != SummaryPath {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(summary)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestPoller_SendsAPIKey(t *testing.T) {
+	repo := setupTestRepo(t)
+
+	summary := summaryServer(t, database.UsageSummary{TotalSessions: 1})
+	secured := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer csm_team" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		summary.Config.Handler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(secured.Close)
+
+	poller := NewPoller(repo, config.FederationConfig{
+		Instances: []config.FederationInstance{
+			{Name: "with-key", URL: secured.URL, APIKey: "csm_team"},
+			{Name: "without-key", URL: secured.URL},
+		},
+	}, logrus.New())
+	poller.PollAll(context.Background())
+
+	snapshots, err := repo.GetFederationSnapshots()
+	if err != nil {
+		t.Fatalf("Failed to get snapshots: %v", err)
+	}
+	instances := Instances(snapshots)
+	if len(instances) != 2 {
+		t.Fatalf("Expected 2 instances, got %d", len(instances))
+	}
+	for _, instance := range instances {
+		want := StatusOK
+		if instance.InstanceName == "without-key" {
+			want = StatusError
+		}
+		if instance.Status != want {
+			t.Errorf("Expected %s to be %s, got %+v", instance.InstanceName, want, instance)
+		}
+	}
+}
+
+func TestPoller_Rollup(t *testing.T) {
+	repo := setupTestRepo(t)
+
+	teamA := summaryServer(t, database.UsageSummary{
+		TotalSessions: 2,
+		TotalTokens:   300,
+		TotalCostUSD:  3.0,
+		ByProject:     []database.UsageAggregate{{Key: "api", Sessions: 2, CostUSD: 3.0}},
+		ByModel:       []database.UsageAggregate{{Key: "claude-sonnet", Sessions: 2, CostUSD: 3.0}},
+		ByDay:         []database.UsageAggregate{{Key: "2025-03-02", CostUSD: 3.0}},
+	})
+	teamB := summaryServer(t, database.UsageSummary{
+		TotalSessions: 1,
+		TotalTokens:   100,
+		TotalCostUSD:  1.0,
+		ByProject:     []database.UsageAggregate{{Key: "web", Sessions: 1, CostUSD: 1.0}},
+		ByModel:       []database.UsageAggregate{{Key: "claude-sonnet", Sessions: 1, CostUSD: 1.0}},
+		ByDay:         []database.UsageAggregate{{Key: "2025-03-01", CostUSD: 1.0}},
+	})
+
+	poller := NewPoller(repo, config.FederationConfig{
+		Instances: []config.FederationInstance{
+			{Name: "team-a", URL: teamA.URL},
+			{Name: "team-b", URL: teamB.URL + "/"},
+			{Name: "team-c", URL: "http://127.0.0.1:1"},
+		},
+	}, logrus.New())
+	poller.PollAll(context.Background())
+
+	snapshots, err := repo.GetFederationSnapshots()
+	if err != nil {
+		t.Fatalf("Failed to get snapshots: %v", err)
+	}
+	instances := Instances(snapshots)
+	if len(instances) != 3 {
+		t.Fatalf("Expected 3 instances, got %d", len(instances))
+	}
+	if instances[2].Status != StatusError || instances[2].Summary != nil {
+		t.Errorf("Expected unreachable instance to be an error without a summary, got %+v", instances[2])
+	}
+
+	org := Rollup(instances)
+	if org.TotalSessions != 3 || org.TotalTokens != 400 || org.TotalCostUSD != 4.0 {
+		t.Errorf("Unexpected org totals: %+v", org)
+	}
+	if len(org.ByModel) != 1 || org.ByModel[0].Sessions != 3 {
+		t.Errorf("Expected models to be merged across instances, got %+v", org.ByModel)
+	}
+	if len(org.ByDay) != 2 || org.ByDay[0].Key != "2025-03-01" {
+		t.Errorf("Expected days in ascending order, got %+v", org.ByDay)
+	}
+	if len(org.ByProject) != 2 || org.ByProject[0].Instance != "team-a" {
+		t.Errorf("Expected projects ordered by cost with their instance, got %+v", org.ByProject)
+	}
+}
@@ -0,0 +1,309 @@
+// Package forecast projects daily token usage and cost forward from their
+// recent history, overall and per project and model, with a linear trend or
+// a moving average and 95% prediction bounds.
+package forecast
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/ksred/claude-session-manager/internal/database"
+)
+
+// Forecast methods
+const (
+	MethodLinear        = "linear"
+	MethodMovingAverage = "moving_average"
+)
+
+// MovingAverageWindow is the number of most recent days a moving average
+// is taken over
+const MovingAverageWindow = 7
+
+// Confidence is the coverage of the prediction bounds
+const Confidence = 0.95
+
+// z95 is the normal quantile of a two-sided 95% interval
+const z95 = 1.96
+
+// dayLayout is how days are written
+const dayLayout = "2006-01-02"
+
+// Point is the projected value of one day
+type Point struct {
+	Date  string  `json:"date"`
+	Value float64 `json:"value"`
+	Lower float64 `json:"lower"`
+	Upper float64 `json:"upper"`
+}
+
+// Projection is a daily series projected over the horizon
+type Projection struct {
+	HistoryTotal float64 `json:"history_total"`
+	DailyAverage float64 `json:"daily_average"` // over the history
+	// TrendPerDay is how much daily usage grows each day under a linear
+	// trend; a moving average has none
+	TrendPerDay float64 `json:"trend_per_day"`
+	Projected   float64 `json:"projected"` // over the horizon
+	// Lower and Upper sum the daily bounds, so they are wider than a bound
+	// on the total would be
+	Lower float64 `json:"lower"`
+	Upper float64 `json:"upper"`
+	Days  []Point `json:"days"`
+}
+
+// Series is the projected tokens and cost of everything, a project or a
+// model
+type Series struct {
+	Key     string     `json:"key,omitempty"`
+	Tokens  Projection `json:"tokens"`
+	CostUSD Projection `json:"cost_usd"`
+}
+
+// Report is the usage projected from the days in [HistoryFrom, HistoryTo)
+// over the HorizonDays from HistoryTo
+type Report struct {
+	Method      string    `json:"method"`
+	HistoryFrom time.Time `json:"history_from"`
+	HistoryTo   time.Time `json:"history_to"`
+	HorizonDays int       `json:"horizon_days"`
+	Confidence  float64   `json:"confidence"`
+	Total       Series    `json:"total"`
+	Projects    []Series  `json:"projects"`
+	Models      []Series  `json:"models"`
+	Caveats     []string  `json:"caveats"`
+	GeneratedAt time.Time `json:"generated_at"`
+}
+
+// Caveats explain what a forecast doesn't account for; they are returned
+// with every report
+var Caveats = []string{
+	"Days are UTC days; days without usage count as zero.",
+	"Bounds assume day-to-day variation is normal and stays as it was. Bursty usage makes them too narrow.",
+	"A linear trend continues indefinitely, so long horizons over a short history can overshoot; projections are never below zero.",
+	"A message's model is its session's, so sessions that switched models are counted under the model they ended on.",
+}
+
+// ValidMethod reports whether method is a forecast method
+func ValidMethod(method string) bool {
+	return method == MethodLinear || method == MethodMovingAverage
+}
+
+// Forecast projects usage, the tokens and cost per day, project and model
+// in [from, to), over the horizon days from to. from and to are UTC
+// midnights. Only the limit projects and models with the highest history
+// cost are projected on their own.
+func Forecast(usage []database.DailyUsage, method string, from, to time.Time, horizon, limit int) Report {
+	from, to = from.UTC(), to.UTC()
+	days := int(to.Sub(from).Hours() / 24)
+	report := Report{
+		Method:      method,
+		HistoryFrom: from,
+		HistoryTo:   to,
+		HorizonDays: horizon,
+		Confidence:  Confidence,
+		Projects:    []Series{},
+		Models:      []Series{},
+		Caveats:     Caveats,
+		GeneratedAt: time.Now().UTC(),
+	}
+
+	total := newHistory(days)
+	projects := make(map[string]*history)
+	models := make(map[string]*history)
+	for _, row := range usage {
+		day, err := time.Parse(dayLayout, row.Day)
+		if err != nil {
+			continue
+		}
+		i := int(day.Sub(from).Hours() / 24)
+		if i < 0 || i >= days {
+			continue
+		}
+		total.add(i, row)
+		if projects[row.Project] == nil {
+			projects[row.Project] = newHistory(days)
+		}
+		projects[row.Project].add(i, row)
+		if models[row.Model] == nil {
+			models[row.Model] = newHistory(days)
+		}
+		models[row.Model].add(i, row)
+	}
+
+	report.Total = total.project("", method, to, horizon)
+	report.Projects = projectTop(projects, method, to, horizon, limit)
+	report.Models = projectTop(models, method, to, horizon, limit)
+	return report
+}
+
+// history is a series of daily tokens and cost
+type history struct {
+	tokens []float64
+	cost   []float64
+}
+
+func newHistory(days int) *history {
+	return &history{tokens: make([]float64, days), cost: make([]float64, days)}
+}
+
+func (h *history) add(day int, row database.DailyUsage) {
+	h.tokens[day] += float64(row.Tokens)
+	h.cost[day] += row.CostUSD
+}
+
+func (h *history) project(key, method string, start time.Time, horizon int) Series {
+	return Series{
+		Key:     key,
+		Tokens:  Project(h.tokens, method, start, horizon).round(0),
+		CostUSD: Project(h.cost, method, start, horizon).round(6),
+	}
+}
+
+// projectTop projects the limit series with the highest history cost,
+// highest first
+func projectTop(histories map[string]*history, method string, start time.Time, horizon, limit int) []Series {
+	keys := make([]string, 0, len(histories))
+	totals := make(map[string]float64, len(histories))
+	for key, h := range histories {
+		keys = append(keys, key)
+		for _, cost := range h.cost {
+			totals[key] += cost
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if totals[keys[i]] != totals[keys[j]] {
+			return totals[keys[i]] > totals[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	if limit > 0 && len(keys) > limit {
+		keys = keys[:limit]
+	}
+
+	series := []Series{}
+	for _, key := range keys {
+		series = append(series, histories[key].project(key, method, start, horizon))
+	}
+	return series
+}
+
+// Project fits history, one value per consecutive day ending the day before
+// start, and projects it over the horizon days from start. A linear trend
+// needs three days of history; with fewer, the moving average is used.
+func Project(history []float64, method string, start time.Time, horizon int) Projection {
+	n := len(history)
+	projection := Projection{Days: []Point{}}
+	for _, value := range history {
+		projection.HistoryTotal += value
+	}
+	if n > 0 {
+		projection.DailyAverage = projection.HistoryTotal / float64(n)
+	}
+
+	predict := movingAverage(history)
+	if method == MethodLinear && n >= 3 {
+		var slope float64
+		predict, slope = linearTrend(history)
+		projection.TrendPerDay = slope
+	}
+
+	for h := 0; h < horizon; h++ {
+		value, spread := predict(n + h)
+		point := Point{
+			Date:  start.AddDate(0, 0, h).Format(dayLayout),
+			Value: math.Max(value, 0),
+			Lower: math.Max(value-spread, 0),
+			Upper: math.Max(value+spread, 0),
+		}
+		projection.Projected += point.Value
+		projection.Lower += point.Lower
+		projection.Upper += point.Upper
+		projection.Days = append(projection.Days, point)
+	}
+	return projection
+}
+
+// predictor returns the value projected for day x, counted from the first
+// day of history, and the half-width of its prediction interval
+type predictor func(x int) (value, spread float64)
+
+// linearTrend fits a least squares line to history
+func linearTrend(history []float64) (predictor, float64) {
+	n := float64(len(history))
+	var meanX, meanY float64
+	for x, y := range history {
+		meanX += float64(x)
+		meanY += y
+	}
+	meanX /= n
+	meanY /= n
+
+	var sxx, sxy float64
+	for x, y := range history {
+		sxx += (float64(x) - meanX) * (float64(x) - meanX)
+		sxy += (float64(x) - meanX) * (y - meanY)
+	}
+	slope := sxy / sxx
+	intercept := meanY - slope*meanX
+
+	var residuals float64
+	for x, y := range history {
+		r := y - (intercept + slope*float64(x))
+		residuals += r * r
+	}
+	stdErr := math.Sqrt(residuals / (n - 2))
+
+	return func(x int) (float64, float64) {
+		d := float64(x) - meanX
+		return intercept + slope*float64(x), z95 * stdErr * math.Sqrt(1+1/n+d*d/sxx)
+	}, slope
+}
+
+// movingAverage projects the mean of the last MovingAverageWindow days
+func movingAverage(history []float64) predictor {
+	window := history
+	if len(window) > MovingAverageWindow {
+		window = window[len(window)-MovingAverageWindow:]
+	}
+	w := float64(len(window))
+	if w == 0 {
+		return func(int) (float64, float64) { return 0, 0 }
+	}
+
+	var mean float64
+	for _, y := range window {
+		mean += y
+	}
+	mean /= w
+
+	var spread float64
+	if w > 1 {
+		var variance float64
+		for _, y := range window {
+			variance += (y - mean) * (y - mean)
+		}
+		spread = z95 * math.Sqrt(variance/(w-1)) * math.Sqrt(1+1/w)
+	}
+	return func(int) (float64, float64) { return mean, spread }
+}
+
+// round rounds every figure of the projection to the given decimal places
+func (p Projection) round(places int) Projection {
+	scale := math.Pow(10, float64(places))
+	r := func(value float64) float64 { return math.Round(value*scale) / scale }
+
+	p.HistoryTotal = r(p.HistoryTotal)
+	p.DailyAverage = r(p.DailyAverage)
+	p.TrendPerDay = r(p.TrendPerDay)
+	p.Projected = r(p.Projected)
+	p.Lower = r(p.Lower)
+	p.Upper = r(p.Upper)
+	for i := range p.Days {
+		p.Days[i].Value = r(p.Days[i].Value)
+		p.Days[i].Lower = r(p.Days[i].Lower)
+		p.Days[i].Upper = r(p.Days[i].Upper)
+	}
+	return p
+}
@@ -0,0 +1,90 @@
+package forecast
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ksred/claude-session-manager/internal/database"
+)
+
+func TestProject_Linear(t *testing.T) {
+	start := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	// A perfect line grows by 2 a day with no spread
+	projection := Project([]float64{1, 3, 5, 7}, MethodLinear, start, 3)
+
+	if projection.TrendPerDay != 2 || projection.HistoryTotal != 16 || projection.DailyAverage != 4 {
+		t.Errorf("Expected a trend of 2 a day, got %+v", projection)
+	}
+	if len(projection.Days) != 3 || projection.Days[0].Date != "2026-10-01" || projection.Days[0].Value != 9 || projection.Days[2].Value != 13 {
+		t.Fatalf("Expected 9, 11, 13, got %+v", projection.Days)
+	}
+	if projection.Projected != 33 || projection.Lower != 33 || projection.Upper != 33 {
+		t.Errorf("Expected 33 with no spread, got %+v", projection)
+	}
+
+	// A noisy series has bounds that widen further out
+	noisy := Project([]float64{10, 14, 9, 15, 11, 16}, MethodLinear, start, 2)
+	first, second := noisy.Days[0], noisy.Days[1]
+	if !(first.Lower < first.Value && first.Value < first.Upper) || second.Upper-second.Lower <= first.Upper-first.Lower {
+		t.Errorf("Expected widening bounds around the trend, got %+v", noisy.Days)
+	}
+
+	// A falling trend stops at zero
+	falling := Project([]float64{30, 20, 10}, MethodLinear, start, 3)
+	if falling.Days[2].Value != 0 || falling.Days[2].Lower != 0 {
+		t.Errorf("Expected projections no lower than zero, got %+v", falling.Days)
+	}
+}
+
+func TestProject_MovingAverage(t *testing.T) {
+	start := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	history := []float64{100, 100, 2, 4, 2, 4, 2, 4, 2}
+	projection := Project(history, MethodMovingAverage, start, 7)
+
+	// Only the last 7 days count
+	if projection.Days[0].Value != 20.0/7 || projection.TrendPerDay != 0 {
+		t.Errorf("Expected the mean of the last week, got %+v", projection.Days[0])
+	}
+	if projection.Days[0].Lower != projection.Days[6].Lower {
+		t.Errorf("Expected constant bounds, got %+v", projection.Days)
+	}
+
+	// Linear falls back to the average without enough history
+	if short := Project([]float64{5, 7}, MethodLinear, start, 1); short.Days[0].Value != 6 || short.TrendPerDay != 0 {
+		t.Errorf("Expected the average of 2 days, got %+v", short)
+	}
+	if empty := Project(nil, MethodLinear, start, 2); empty.Projected != 0 || len(empty.Days) != 2 {
+		t.Errorf("Expected zeros without history, got %+v", empty)
+	}
+}
+
+func TestForecast(t *testing.T) {
+	from := time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, 4)
+	usage := []database.DailyUsage{
+		{Day: "2026-09-01", Project: "app", Model: "claude-opus-4", Tokens: 1000, CostUSD: 1},
+		{Day: "2026-09-02", Project: "app", Model: "claude-opus-4", Tokens: 2000, CostUSD: 2},
+		{Day: "2026-09-03", Project: "docs", Model: "claude-sonnet-4", Tokens: 500, CostUSD: 0.1},
+		{Day: "2026-09-04", Project: "app", Model: "claude-opus-4", Tokens: 4000, CostUSD: 4},
+		{Day: "2026-09-04", Project: "scratch", Model: "claude-haiku", Tokens: 10, CostUSD: 0.01},
+		{Day: "2026-09-05", Project: "app", Model: "claude-opus-4", Tokens: 9999, CostUSD: 9},
+	}
+
+	report := Forecast(usage, MethodMovingAverage, from, to, 7, 2)
+
+	if report.Total.Tokens.HistoryTotal != 7510 || report.Total.CostUSD.HistoryTotal != 7.11 {
+		t.Errorf("Expected the usage inside the history only, got %+v", report.Total)
+	}
+	if len(report.Projects) != 2 || report.Projects[0].Key != "app" || report.Projects[1].Key != "docs" {
+		t.Errorf("Expected the 2 costliest projects, got %+v", report.Projects)
+	}
+	if len(report.Models) != 2 || report.Models[0].Key != "claude-opus-4" {
+		t.Errorf("Expected the 2 costliest models, got %+v", report.Models)
+	}
+	if app := report.Projects[0]; app.Tokens.Days[0].Value != 1750 || app.CostUSD.Projected != 12.25 {
+		t.Errorf("Expected app to average 1750 tokens and $1.75 a day, got %+v", app)
+	}
+	if report.Total.Tokens.Days[0].Date != "2026-09-05" || len(report.Total.Tokens.Days) != 7 {
+		t.Errorf("Expected 7 days from the end of the history, got %+v", report.Total.Tokens.Days)
+	}
+}
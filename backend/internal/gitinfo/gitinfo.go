@@ -0,0 +1,181 @@
+// Package gitinfo reads the branch, working tree and origin remote of the git
+// repository a session ran in. It parses the .git directory rather than
+// running git, so imports can look up every session cheaply, and handles
+// linked worktrees and submodules, whose .git is a file pointing elsewhere.
+package gitinfo
+
+import (
+	"bufio"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Info is what a working directory's repository reveals. Fields that could
+// not be resolved are empty.
+type Info struct {
+	Branch   string // checked out branch, empty when HEAD is detached
+	Worktree string // top level of the working tree containing the directory
+	Remote   string // origin remote, sanitized by SanitizeRemote
+}
+
+// scpRemote matches scp-style remotes such as git@github.com:org/repo.git
+var scpRemote = regexp.MustCompile(`^(?:[^@/]+@)?([^:/]+):(.+)$`)
+
+// windowsPath matches a drive-letter path such as C:\Users\me
+var windowsPath = regexp.MustCompile(`^[A-Za-z]:[\\/]`)
+
+// SanitizeRemote reduces a git remote URL to host/path, dropping the scheme,
+// any user name, password or token, query string and trailing .git, so
+// remotes cloned over HTTPS and SSH compare equal. It returns "" for remotes
+// it cannot parse.
+func SanitizeRemote(remote string) string {
+	remote = strings.TrimSpace(remote)
+	if remote == "" {
+		return ""
+	}
+
+	var host, path string
+	if strings.Contains(remote, "://") {
+		parsed, err := url.Parse(remote)
+		if err != nil || parsed.Host == "" {
+			return ""
+		}
+		host, path = parsed.Hostname(), parsed.Path
+	} else if match := scpRemote.FindStringSubmatch(remote); match != nil && !windowsPath.MatchString(remote) {
+		host, path = match[1], match[2]
+	} else {
+		// Local path remotes identify nothing beyond this machine
+		return ""
+	}
+
+	path = strings.TrimSuffix(strings.Trim(path, "/"), ".git")
+	if path == "" {
+		return strings.ToLower(host)
+	}
+	return strings.ToLower(host) + "/" + path
+}
+
+// Detect returns the repository information of dir. A directory that no
+// longer exists, or that isn't in a repository, returns an empty Info rather
+// than guessing from its parents.
+func Detect(dir string) Info {
+	if dir == "" {
+		return Info{}
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return Info{}
+	}
+
+	worktree, gitDir := findGitDir(filepath.Clean(dir))
+	if gitDir == "" {
+		return Info{}
+	}
+
+	return Info{
+		Branch:   readBranch(gitDir),
+		Worktree: worktree,
+		Remote:   SanitizeRemote(readOriginURL(commonDir(gitDir))),
+	}
+}
+
+// findGitDir walks up from dir to the first directory with a .git entry and
+// returns it with the git directory it refers to
+func findGitDir(dir string) (worktree, gitDir string) {
+	for {
+		dotGit := filepath.Join(dir, ".git")
+		if info, err := os.Stat(dotGit); err == nil {
+			if info.IsDir() {
+				return dir, dotGit
+			}
+			// Linked worktrees and submodules have a .git file reading
+			// "gitdir: <path>"
+			if target := readGitFile(dotGit); target != "" {
+				if !filepath.IsAbs(target) {
+					target = filepath.Join(dir, target)
+				}
+				return dir, filepath.Clean(target)
+			}
+			return "", ""
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", ""
+		}
+		dir = parent
+	}
+}
+
+// readGitFile returns the git directory a .git file points to
+func readGitFile(path string) string {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	target, ok := strings.CutPrefix(strings.TrimSpace(string(content)), "gitdir:")
+	if !ok {
+		return ""
+	}
+	return strings.TrimSpace(target)
+}
+
+// commonDir returns the directory holding the config shared by all of a
+// repository's worktrees
+func commonDir(gitDir string) string {
+	content, err := os.ReadFile(filepath.Join(gitDir, "commondir"))
+	if err != nil {
+		return gitDir
+	}
+	common := strings.TrimSpace(string(content))
+	if !filepath.IsAbs(common) {
+		common = filepath.Join(gitDir, common)
+	}
+	return filepath.Clean(common)
+}
+
+// readBranch returns the branch HEAD points to, or "" when it is detached
+func readBranch(gitDir string) string {
+	content, err := os.ReadFile(filepath.Join(gitDir, "HEAD"))
+	if err != nil {
+		return ""
+	}
+	ref, ok := strings.CutPrefix(strings.TrimSpace(string(content)), "ref:")
+	if !ok {
+		return ""
+	}
+	return strings.TrimPrefix(strings.TrimSpace(ref), "refs/heads/")
+}
+
+// readOriginURL returns the url of the origin remote in the repository
+// config, or "" if there is none
+func readOriginURL(gitDir string) string {
+	file, err := os.Open(filepath.Join(gitDir, "config"))
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	inOrigin := false
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		if line[0] == '[' {
+			inOrigin = strings.EqualFold(strings.Join(strings.Fields(strings.Trim(line, "[]")), " "), `remote "origin"`)
+			continue
+		}
+		if !inOrigin {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if ok && strings.EqualFold(strings.TrimSpace(key), "url") {
+			return strings.Trim(strings.TrimSpace(value), `"`)
+		}
+	}
+	return ""
+}
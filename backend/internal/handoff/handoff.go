@@ -0,0 +1,464 @@
+// Package handoff moves a session between machines. A bundle carries the
+// session's transcript lines, its metadata and the Claude session ID its UI
+// chat resumes, so a conversation started on one machine can be continued
+// from another. Importing a newer bundle of the same session fast-forwards
+// the transcript, and one that has diverged from this machine's copy is
+// refused unless forced.
+package handoff
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ksred/claude-session-manager/internal/chat"
+	"github.com/ksred/claude-session-manager/internal/database"
+	"github.com/sirupsen/logrus"
+)
+
+// Version is the bundle format version
+const Version = 1
+
+// Transcript statuses on import
+const (
+	StatusCreated   = "created"   // not on this machine before
+	StatusUnchanged = "unchanged" // this machine already had every line
+	StatusUpdated   = "updated"   // lines added after this machine's copy
+	StatusReplaced  = "replaced"  // this machine's diverged copy overwritten, with a backup
+	StatusConflict  = "conflict"  // diverged from this machine's copy
+)
+
+// ErrInvalidBundle is returned for a bundle that can't be imported
+var ErrInvalidBundle = errors.New("invalid handoff bundle")
+
+// Bundle is a session's state as exported from one machine
+type Bundle struct {
+	Version    int       `json:"version"`
+	ExportedAt time.Time `json:"exported_at"`
+	Host       string    `json:"host"`
+	Session    Session   `json:"session"`
+
+	// ChatClaudeSessionID is the Claude session the session's UI chat
+	// resumes, when it has one
+	ChatClaudeSessionID string `json:"chat_claude_session_id,omitempty"`
+
+	// Transcripts are the session's transcript, and the chat's when it is
+	// on the exporting machine
+	Transcripts []Transcript `json:"transcripts"`
+}
+
+// Session is the metadata of a handed off session
+type Session struct {
+	ID           string    `json:"id"`
+	ProjectName  string    `json:"project_name"`
+	ProjectPath  string    `json:"project_path"`
+	GitBranch    string    `json:"git_branch,omitempty"`
+	GitRemote    string    `json:"git_remote,omitempty"`
+	Model        string    `json:"model,omitempty"`
+	StartTime    time.Time `json:"start_time"`
+	LastActivity time.Time `json:"last_activity"`
+	MessageCount int       `json:"message_count"`
+}
+
+// Transcript is the JSONL lines of one Claude session
+type Transcript struct {
+	SessionID string   `json:"session_id"`
+	Lines     []string `json:"lines"`
+	SHA256    string   `json:"sha256"` // hex digest of the lines joined with newlines
+}
+
+// ImportOptions control where and how a bundle is imported
+type ImportOptions struct {
+	ProjectPath string // the project's checkout on this machine; the bundle's when empty
+	Force       bool   // replace diverged transcripts, backing up this machine's copies
+}
+
+// ImportResult is what importing a bundle did
+type ImportResult struct {
+	SessionID           string             `json:"session_id"`
+	ProjectPath         string             `json:"project_path"`
+	ChatClaudeSessionID string             `json:"chat_claude_session_id,omitempty"`
+	Messages            int                `json:"messages"`
+	Transcripts         []TranscriptResult `json:"transcripts"`
+}
+
+// TranscriptResult is what importing a bundle did to one transcript
+type TranscriptResult struct {
+	SessionID string `json:"session_id"`
+	Path      string `json:"path"`
+	Status    string `json:"status"`
+	Lines     int    `json:"lines"`            // in the bundle
+	Local     int    `json:"local"`            // on this machine before the import
+	Backup    string `json:"backup,omitempty"` // where a replaced copy was moved
+	Reason    string `json:"reason,omitempty"` // why it conflicts
+}
+
+// ConflictError is returned when a bundle's transcripts have diverged from
+// this machine's copies
+type ConflictError struct {
+	Transcripts []TranscriptResult
+}
+
+func (e *ConflictError) Error() string {
+	reasons := make([]string, 0, len(e.Transcripts))
+	for _, transcript := range e.Transcripts {
+		reasons = append(reasons, fmt.Sprintf("%s: %s", transcript.SessionID, transcript.Reason))
+	}
+	return "handoff conflicts with this machine's transcripts: " + strings.Join(reasons, "; ")
+}
+
+// Store is where sessions are read from
+type Store interface {
+	GetSessionByID(sessionID string) (*database.SessionSummary, error)
+	GetSessionFilePath(sessionID string) (string, error)
+}
+
+// ChatStore is where UI chats are kept
+type ChatStore interface {
+	GetChatSessionBySessionID(sessionID string) (*chat.ChatSession, error)
+	CreateChatSession(sessionID string, processID int) (*chat.ChatSession, error)
+	UpdateChatSessionClaudeID(id string, claudeSessionID string) error
+}
+
+// Importer imports a transcript into the database
+type Importer interface {
+	ImportJSONLFile(filePath string, projectInfo database.ProjectInfo) (int, int, error)
+}
+
+// Handoff exports and imports bundles
+type Handoff struct {
+	store     Store
+	chats     ChatStore
+	importer  Importer
+	claudeDir string
+	logger    *logrus.Logger
+}
+
+// New creates a handoff writing imported transcripts under claudeDir, where
+// Claude Code looks for sessions to resume
+func New(store Store, chats ChatStore, importer Importer, claudeDir string, logger *logrus.Logger) *Handoff {
+	return &Handoff{
+		store:     store,
+		chats:     chats,
+		importer:  importer,
+		claudeDir: claudeDir,
+		logger:    logger,
+	}
+}
+
+// Export bundles a session's transcript, metadata and chat
+func (h *Handoff) Export(sessionID string) (*Bundle, error) {
+	summary, err := h.store.GetSessionByID(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	path, err := h.store.GetSessionFilePath(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	lines, err := readTranscript(path, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	host, _ := os.Hostname()
+	bundle := &Bundle{
+		Version:    Version,
+		ExportedAt: time.Now().UTC(),
+		Host:       host,
+		Session: Session{
+			ID:           summary.ID,
+			ProjectName:  summary.ProjectName,
+			ProjectPath:  summary.ProjectPath,
+			GitBranch:    summary.GitBranch,
+			GitRemote:    summary.GitRemote,
+			Model:        summary.Model,
+			StartTime:    summary.StartTime,
+			LastActivity: summary.LastActivity,
+			MessageCount: summary.MessageCount,
+		},
+		Transcripts: []Transcript{newTranscript(sessionID, lines)},
+	}
+
+	chatSession, err := h.chats.GetChatSessionBySessionID(sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chat session: %w", err)
+	}
+	if chatSession != nil && chatSession.ClaudeSessionID != nil && *chatSession.ClaudeSessionID != "" {
+		claudeID := *chatSession.ClaudeSessionID
+		bundle.ChatClaudeSessionID = claudeID
+		// The chat runs in the project, so its transcript sits beside the session's
+		chatLines, err := readTranscript(filepath.Join(filepath.Dir(path), claudeID+".jsonl"), claudeID)
+		if err == nil && claudeID != sessionID {
+			bundle.Transcripts = append(bundle.Transcripts, newTranscript(claudeID, chatLines))
+		} else if err != nil {
+			h.logger.WithError(err).WithField("session_id", sessionID).Debug("Chat transcript not bundled")
+		}
+	}
+	return bundle, nil
+}
+
+// Import writes a bundle's transcripts where Claude Code will resume them
+// from in the project's checkout on this machine, imports them, and points
+// the session's chat at the bundle's Claude session. Nothing is written if a
+// transcript has diverged from this machine's copy, unless opts.Force is set.
+func (h *Handoff) Import(bundle *Bundle, opts ImportOptions) (*ImportResult, error) {
+	if err := validate(bundle); err != nil {
+		return nil, err
+	}
+	projectPath := opts.ProjectPath
+	if projectPath == "" {
+		projectPath = bundle.Session.ProjectPath
+	}
+	if !filepath.IsAbs(projectPath) {
+		return nil, fmt.Errorf("%w: project path must be absolute: %q", ErrInvalidBundle, projectPath)
+	}
+	projectDir := ProjectDir(h.claudeDir, projectPath)
+
+	// Compare every transcript before writing any
+	results := make([]TranscriptResult, len(bundle.Transcripts))
+	var conflicts []TranscriptResult
+	for i, transcript := range bundle.Transcripts {
+		path := filepath.Join(projectDir, transcript.SessionID+".jsonl")
+		if local, err := h.store.GetSessionFilePath(transcript.SessionID); err == nil && fileExists(local) {
+			path = local
+		}
+		local, err := readTranscript(path, transcript.SessionID)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		results[i] = compare(transcript, local)
+		results[i].Path = path
+		if results[i].Status == StatusConflict {
+			conflicts = append(conflicts, results[i])
+		}
+	}
+	if len(conflicts) > 0 && !opts.Force {
+		return nil, &ConflictError{Transcripts: conflicts}
+	}
+
+	result := &ImportResult{
+		SessionID:           bundle.Session.ID,
+		ProjectPath:         projectPath,
+		ChatClaudeSessionID: bundle.ChatClaudeSessionID,
+	}
+	for i, transcript := range bundle.Transcripts {
+		status := &results[i]
+		if status.Status == StatusConflict {
+			backup := fmt.Sprintf("%s.%d.bak", status.Path, time.Now().Unix())
+			if err := os.Rename(status.Path, backup); err != nil {
+				return nil, fmt.Errorf("failed to back up transcript: %w", err)
+			}
+			status.Status, status.Backup, status.Reason = StatusReplaced, backup, ""
+		}
+		if status.Status != StatusUnchanged {
+			if err := writeTranscript(status.Path, transcript.Lines); err != nil {
+				return nil, err
+			}
+		}
+
+		dir := filepath.Dir(status.Path)
+		_, messages, err := h.importer.ImportJSONLFile(status.Path, database.ProjectInfo{
+			ProjectPath: projectPath,
+			ProjectName: bundle.Session.ProjectName,
+			FilePath:    filepath.Base(dir),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to import transcript %s: %w", transcript.SessionID, err)
+		}
+		result.Messages += messages
+	}
+	result.Transcripts = results
+
+	if bundle.ChatClaudeSessionID != "" {
+		if err := h.linkChat(bundle.Session.ID, bundle.ChatClaudeSessionID); err != nil {
+			return nil, err
+		}
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"session_id":   bundle.Session.ID,
+		"host":         bundle.Host,
+		"project_path": projectPath,
+		"messages":     result.Messages,
+	}).Info("Imported session handoff")
+	return result, nil
+}
+
+// linkChat points the session's chat at a Claude session, so the next chat
+// message resumes it
+func (h *Handoff) linkChat(sessionID, claudeSessionID string) error {
+	chatSession, err := h.chats.GetChatSessionBySessionID(sessionID)
+	if err != nil {
+		return fmt.Errorf("failed to get chat session: %w", err)
+	}
+	if chatSession == nil {
+		if chatSession, err = h.chats.CreateChatSession(sessionID, 0); err != nil {
+			return fmt.Errorf("failed to create chat session: %w", err)
+		}
+	}
+	if err := h.chats.UpdateChatSessionClaudeID(chatSession.ID, claudeSessionID); err != nil {
+		return fmt.Errorf("failed to update chat session: %w", err)
+	}
+	return nil
+}
+
+// projectDirChars are the characters Claude Code replaces with hyphens when
+// naming a project's directory after its path
+var projectDirChars = regexp.MustCompile(`[^A-Za-z0-9]`)
+
+// ProjectDir returns the directory under claudeDir Claude Code keeps the
+// transcripts of sessions run in projectPath
+func ProjectDir(claudeDir, projectPath string) string {
+	return filepath.Join(claudeDir, "projects", projectDirChars.ReplaceAllString(projectPath, "-"))
+}
+
+// validate checks a bundle is one this version can import and that its
+// transcripts arrived intact
+func validate(bundle *Bundle) error {
+	if bundle.Version != Version {
+		return fmt.Errorf("%w: unsupported version %d", ErrInvalidBundle, bundle.Version)
+	}
+	if !validID(bundle.Session.ID) {
+		return fmt.Errorf("%w: invalid session id %q", ErrInvalidBundle, bundle.Session.ID)
+	}
+	if bundle.ChatClaudeSessionID != "" && !validID(bundle.ChatClaudeSessionID) {
+		return fmt.Errorf("%w: invalid chat session id %q", ErrInvalidBundle, bundle.ChatClaudeSessionID)
+	}
+	if len(bundle.Transcripts) == 0 || bundle.Transcripts[0].SessionID != bundle.Session.ID {
+		return fmt.Errorf("%w: the session's transcript is missing", ErrInvalidBundle)
+	}
+	for _, transcript := range bundle.Transcripts {
+		if !validID(transcript.SessionID) {
+			return fmt.Errorf("%w: invalid transcript session id %q", ErrInvalidBundle, transcript.SessionID)
+		}
+		if digest(transcript.Lines) != transcript.SHA256 {
+			return fmt.Errorf("%w: transcript %s does not match its checksum", ErrInvalidBundle, transcript.SessionID)
+		}
+	}
+	return nil
+}
+
+// validID reports whether a session ID is safe to name a file after
+func validID(id string) bool {
+	return id != "" && id != "." && id != ".." && !strings.ContainsAny(id, `/\`)
+}
+
+// compare works out what importing a transcript over this machine's copy
+// would do
+func compare(transcript Transcript, local []string) TranscriptResult {
+	result := TranscriptResult{
+		SessionID: transcript.SessionID,
+		Lines:     len(transcript.Lines),
+		Local:     len(local),
+	}
+	common := 0
+	for common < len(local) && common < len(transcript.Lines) && local[common] == transcript.Lines[common] {
+		common++
+	}
+
+	switch {
+	case len(local) == 0:
+		result.Status = StatusCreated
+	case common == len(local) && common == len(transcript.Lines):
+		result.Status = StatusUnchanged
+	case common == len(local):
+		result.Status = StatusUpdated
+	case common == len(transcript.Lines):
+		result.Status = StatusConflict
+		result.Reason = fmt.Sprintf("this machine has %d lines the bundle doesn't", len(local)-common)
+	default:
+		result.Status = StatusConflict
+		result.Reason = fmt.Sprintf("the transcripts diverge after line %d", common)
+	}
+	return result
+}
+
+func newTranscript(sessionID string, lines []string) Transcript {
+	return Transcript{SessionID: sessionID, Lines: lines, SHA256: digest(lines)}
+}
+
+func digest(lines []string) string {
+	sum := sha256.Sum256([]byte(strings.Join(lines, "\n")))
+	return hex.EncodeToString(sum[:])
+}
+
+// readTranscript returns the lines of a transcript file that belong to a
+// session, leaving out blank lines and those another session wrote
+func readTranscript(path, sessionID string) ([]string, error) {
+	if path == "" {
+		return nil, fmt.Errorf("session transcript not found: %s", sessionID)
+	}
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("session transcript not found: %s: %w", sessionID, err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open transcript: %w", err)
+	}
+	defer file.Close()
+
+	lines := []string{}
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadString('\n')
+		if line = strings.TrimRight(line, "\r\n"); strings.TrimSpace(line) != "" {
+			var entry struct {
+				SessionID string `json:"sessionId"`
+			}
+			if json.Unmarshal([]byte(line), &entry) != nil || entry.SessionID == "" || entry.SessionID == sessionID {
+				lines = append(lines, line)
+			}
+		}
+		if err == io.EOF {
+			return lines, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read transcript: %w", err)
+		}
+	}
+}
+
+// writeTranscript replaces a transcript file with lines, through a temporary
+// file so Claude Code and the file watcher never see it half written
+func writeTranscript(path string, lines []string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create project directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".handoff-*")
+	if err != nil {
+		return fmt.Errorf("failed to write transcript: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	writer := bufio.NewWriter(tmp)
+	for _, line := range lines {
+		writer.WriteString(line)
+		writer.WriteByte('\n')
+	}
+	if err := writer.Flush(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write transcript: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write transcript: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write transcript: %w", err)
+	}
+	return nil
+}
+
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
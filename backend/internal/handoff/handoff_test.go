@@ -0,0 +1,217 @@
+package handoff
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ksred/claude-session-manager/internal/chat"
+	"github.com/ksred/claude-session-manager/internal/database"
+	"github.com/sirupsen/logrus"
+)
+
+// machine is a database and Claude directory standing in for one computer
+type machine struct {
+	repo      *database.SessionRepository
+	chats     *chat.Repository
+	claudeDir string
+	handoff   *Handoff
+}
+
+func newMachine(t *testing.T) *machine {
+	t.Helper()
+	logger := logrus.New()
+	logger.SetLevel(logrus.WarnLevel)
+
+	dir := t.TempDir()
+	db, err := database.NewDatabase(database.Config{DatabasePath: filepath.Join(dir, "sessions.db"), Logger: logger})
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	repo := database.NewSessionRepository(db, logger)
+	chats := chat.NewRepositoryWithWriteOp(db.DB, db.WriteOperation)
+	claudeDir := filepath.Join(dir, ".claude")
+	return &machine{
+		repo:      repo,
+		chats:     chats,
+		claudeDir: claudeDir,
+		handoff:   New(repo, chats, database.NewImporter(repo, logger), claudeDir, logger),
+	}
+}
+
+func transcriptLine(sessionID string, n int) string {
+	return fmt.Sprintf(`{"cwd":"/work/app","sessionId":"%s","type":"user","message":{"role":"user","content":"step %d"},"uuid":"%s-m%d","timestamp":"%s"}`,
+		sessionID, n, sessionID, n, time.Date(2026, 10, 15, 12, n, 0, 0, time.UTC).Format(time.RFC3339))
+}
+
+// record writes lines to a session's transcript on m and imports it
+func (m *machine) record(t *testing.T, sessionID string, lines ...string) string {
+	t.Helper()
+	path := filepath.Join(ProjectDir(m.claudeDir, "/work/app"), sessionID+".jsonl")
+	if err := writeTranscript(path, lines); err != nil {
+		t.Fatalf("Failed to write transcript: %v", err)
+	}
+	importer := database.NewImporter(m.repo, logrus.New())
+	if _, _, err := importer.ImportJSONLFile(path, database.ProjectInfo{ProjectPath: "/work/app", ProjectName: "app"}); err != nil {
+		t.Fatalf("Failed to import transcript: %v", err)
+	}
+	return path
+}
+
+func TestHandoff_RoundTrip(t *testing.T) {
+	desktop, laptop := newMachine(t), newMachine(t)
+	lines := []string{transcriptLine("s1", 1), transcriptLine("s1", 2)}
+	desktop.record(t, "s1", lines...)
+	desktop.record(t, "claude-1", transcriptLine("claude-1", 1))
+
+	chatSession, err := desktop.chats.CreateChatSession("s1", 0)
+	if err != nil {
+		t.Fatalf("Failed to create chat session: %v", err)
+	}
+	if err := desktop.chats.UpdateChatSessionClaudeID(chatSession.ID, "claude-1"); err != nil {
+		t.Fatalf("Failed to set Claude session: %v", err)
+	}
+
+	bundle, err := desktop.handoff.Export("s1")
+	if err != nil {
+		t.Fatalf("Failed to export: %v", err)
+	}
+	if bundle.Session.ProjectPath != "/work/app" || bundle.ChatClaudeSessionID != "claude-1" || len(bundle.Transcripts) != 2 {
+		t.Fatalf("Expected the session and its chat bundled, got %+v", bundle)
+	}
+
+	result, err := laptop.handoff.Import(bundle, ImportOptions{})
+	if err != nil {
+		t.Fatalf("Failed to import: %v", err)
+	}
+	for _, transcript := range result.Transcripts {
+		if transcript.Status != StatusCreated {
+			t.Errorf("Expected %s created, got %s", transcript.SessionID, transcript.Status)
+		}
+	}
+	data, err := os.ReadFile(filepath.Join(ProjectDir(laptop.claudeDir, "/work/app"), "s1.jsonl"))
+	if err != nil || string(data) != strings.Join(lines, "\n")+"\n" {
+		t.Errorf("Expected the transcript written where Claude Code resumes it, got %q (%v)", data, err)
+	}
+	if _, err := laptop.repo.GetSessionByID("s1"); err != nil {
+		t.Errorf("Expected the session imported: %v", err)
+	}
+	laptopChat, err := laptop.chats.GetChatSessionBySessionID("s1")
+	if err != nil || laptopChat == nil || laptopChat.ClaudeSessionID == nil || *laptopChat.ClaudeSessionID != "claude-1" {
+		t.Errorf("Expected the chat to resume claude-1, got %+v (%v)", laptopChat, err)
+	}
+
+	// The same bundle again changes nothing
+	result, err = laptop.handoff.Import(bundle, ImportOptions{})
+	if err != nil {
+		t.Fatalf("Failed to re-import: %v", err)
+	}
+	if result.Transcripts[0].Status != StatusUnchanged {
+		t.Errorf("Expected unchanged, got %s", result.Transcripts[0].Status)
+	}
+}
+
+func TestHandoff_Conflicts(t *testing.T) {
+	desktop, laptop := newMachine(t), newMachine(t)
+	desktop.record(t, "s1", transcriptLine("s1", 1))
+	bundle, err := desktop.handoff.Export("s1")
+	if err != nil {
+		t.Fatalf("Failed to export: %v", err)
+	}
+	if _, err := laptop.handoff.Import(bundle, ImportOptions{}); err != nil {
+		t.Fatalf("Failed to import: %v", err)
+	}
+
+	// The conversation continues on the desktop, so the laptop fast-forwards
+	desktop.record(t, "s1", transcriptLine("s1", 1), transcriptLine("s1", 2))
+	bundle, err = desktop.handoff.Export("s1")
+	if err != nil {
+		t.Fatalf("Failed to export: %v", err)
+	}
+	result, err := laptop.handoff.Import(bundle, ImportOptions{})
+	if err != nil {
+		t.Fatalf("Failed to import: %v", err)
+	}
+	if result.Transcripts[0].Status != StatusUpdated || result.Transcripts[0].Local != 1 {
+		t.Errorf("Expected updated from 1 line, got %+v", result.Transcripts[0])
+	}
+
+	// Both machines then carry on separately
+	laptopPath := laptop.record(t, "s1", transcriptLine("s1", 1), transcriptLine("s1", 2), transcriptLine("s1", 4))
+	desktop.record(t, "s1", transcriptLine("s1", 1), transcriptLine("s1", 2), transcriptLine("s1", 3))
+	bundle, err = desktop.handoff.Export("s1")
+	if err != nil {
+		t.Fatalf("Failed to export: %v", err)
+	}
+
+	_, err = laptop.handoff.Import(bundle, ImportOptions{})
+	var conflict *ConflictError
+	if !errors.As(err, &conflict) || len(conflict.Transcripts) != 1 || !strings.Contains(conflict.Transcripts[0].Reason, "after line 2") {
+		t.Fatalf("Expected a conflict after line 2, got %v", err)
+	}
+	if data, _ := os.ReadFile(laptopPath); !strings.Contains(string(data), "step 4") {
+		t.Error("Expected the laptop's transcript left alone on conflict")
+	}
+
+	result, err = laptop.handoff.Import(bundle, ImportOptions{Force: true})
+	if err != nil {
+		t.Fatalf("Failed to force import: %v", err)
+	}
+	replaced := result.Transcripts[0]
+	if replaced.Status != StatusReplaced || replaced.Backup == "" {
+		t.Fatalf("Expected replaced with a backup, got %+v", replaced)
+	}
+	if data, _ := os.ReadFile(replaced.Backup); !strings.Contains(string(data), "step 4") {
+		t.Error("Expected the laptop's transcript backed up")
+	}
+	if data, _ := os.ReadFile(laptopPath); !strings.Contains(string(data), "step 3") {
+		t.Error("Expected the desktop's transcript written")
+	}
+}
+
+func TestHandoff_ImportInvalid(t *testing.T) {
+	desktop, laptop := newMachine(t), newMachine(t)
+	desktop.record(t, "s1", transcriptLine("s1", 1))
+	bundle, err := desktop.handoff.Export("s1")
+	if err != nil {
+		t.Fatalf("Failed to export: %v", err)
+	}
+
+	tampered := *bundle
+	tampered.Transcripts = []Transcript{{SessionID: "s1", Lines: []string{"{}"}, SHA256: bundle.Transcripts[0].SHA256}}
+	if _, err := laptop.handoff.Import(&tampered, ImportOptions{}); !errors.Is(err, ErrInvalidBundle) {
+		t.Errorf("Expected a checksum mismatch rejected, got %v", err)
+	}
+
+	escaping := *bundle
+	escaping.Session.ID = "../s1"
+	if _, err := laptop.handoff.Import(&escaping, ImportOptions{}); !errors.Is(err, ErrInvalidBundle) {
+		t.Errorf("Expected a path in the session id rejected, got %v", err)
+	}
+
+	if _, err := laptop.handoff.Import(bundle, ImportOptions{ProjectPath: "work/app"}); !errors.Is(err, ErrInvalidBundle) {
+		t.Errorf("Expected a relative project path rejected, got %v", err)
+	}
+}
+
+func TestReadTranscript_SkipsOtherSessions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "s1.jsonl")
+	content := transcriptLine("s1", 1) + "\n\n" + transcriptLine("s2", 2) + "\n" + `{"type":"summary"}` + "\n" + transcriptLine("s1", 3)
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("Failed to write transcript: %v", err)
+	}
+
+	lines, err := readTranscript(path, "s1")
+	if err != nil {
+		t.Fatalf("Failed to read transcript: %v", err)
+	}
+	if len(lines) != 3 || strings.Contains(strings.Join(lines, "\n"), `"s2"`) {
+		t.Errorf("Expected s1's lines and the summary, got %v", lines)
+	}
+}
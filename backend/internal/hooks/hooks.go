@@ -0,0 +1,164 @@
+// Package hooks parses the events Claude Code hooks POST to the server and
+// generates the hook script and settings that send them, so tool use is
+// captured as it happens rather than when the transcript is next read.
+package hooks
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ksred/claude-session-manager/internal/database"
+)
+
+// Events are the hook events a generated configuration can subscribe to
+var Events = []string{
+	"PreToolUse", "PostToolUse", "Stop", "SubagentStop",
+	"UserPromptSubmit", "Notification", "SessionStart", "SessionEnd", "PreCompact",
+}
+
+// DefaultEvents are the events subscribed to when none are requested
+var DefaultEvents = []string{"SessionStart", "PreToolUse", "PostToolUse", "Stop", "SessionEnd"}
+
+// toolEvents are the events that are matched against a tool name
+var toolEvents = map[string]bool{"PreToolUse": true, "PostToolUse": true}
+
+// Input is the part of a hook's input the server indexes. The full input is
+// stored as received.
+type Input struct {
+	SessionID      string `json:"session_id"`
+	TranscriptPath string `json:"transcript_path"`
+	CWD            string `json:"cwd"`
+	HookEventName  string `json:"hook_event_name"`
+	ToolName       string `json:"tool_name"`
+	ToolUseID      string `json:"tool_use_id"`
+}
+
+// Liveness returns whether an event shows its session is running, and false
+// for ok if the event says nothing about it. Stop fires when Claude finishes
+// responding, so the session is idle until the next prompt.
+func Liveness(event string) (active bool, ok bool) {
+	switch event {
+	case "SessionStart", "UserPromptSubmit", "PreToolUse", "PostToolUse", "SubagentStop", "PreCompact":
+		return true, true
+	case "Stop", "SessionEnd":
+		return false, true
+	default:
+		return false, false
+	}
+}
+
+// IsEvent reports whether name is a hook event
+func IsEvent(name string) bool {
+	for _, event := range Events {
+		if event == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Parse turns a hook's input into an event to store
+func Parse(payload []byte, received time.Time) (*database.HookEvent, error) {
+	var input Input
+	if err := json.Unmarshal(payload, &input); err != nil {
+		return nil, fmt.Errorf("hook input must be a JSON object: %w", err)
+	}
+	if input.SessionID == "" {
+		return nil, fmt.Errorf("hook input has no session_id")
+	}
+	if input.HookEventName == "" {
+		return nil, fmt.Errorf("hook input has no hook_event_name")
+	}
+
+	optional := func(value string) *string {
+		if value == "" {
+			return nil
+		}
+		return &value
+	}
+	return &database.HookEvent{
+		SessionID:      input.SessionID,
+		Event:          input.HookEventName,
+		ToolName:       optional(input.ToolName),
+		ToolUseID:      optional(input.ToolUseID),
+		CWD:            optional(input.CWD),
+		TranscriptPath: optional(input.TranscriptPath),
+		Payload:        string(payload),
+		Data:           json.RawMessage(payload),
+		ReceivedAt:     received.UTC(),
+	}, nil
+}
+
+// Script returns a shell script that forwards a hook's input to endpoint,
+// sending the API key in CSM_API_KEY if it is set. It never fails or blocks
+// the hook for long, so Claude carries on if the server is down, but reports
+// errors, such as a rejected key, on stderr.
+func Script(endpoint string) string {
+	return `#!/bin/sh
+# Forwards Claude Code hook input to Claude Session Manager.
+# Generated by GET /api/v1/hooks/script
+# Set CSM_API_KEY to a write key if the server has auth enabled.
+curl --silent --show-error --fail --max-time 2 \
+  --header 'Content-Type: application/json' \
+  ` + authHeader + ` \
+  --data-binary @- \
+  ` + shellQuote(endpoint) + ` >/dev/null
+exit 0
+`
+}
+
+// SummaryScript returns a hook script that forwards input to endpoint like
+// Script and, when a session ends, prints its summary from sessionsURL (the
+// server's /api/v1/sessions/ URL) to the terminal Claude ran in
+func SummaryScript(endpoint, sessionsURL string) string {
+	return `#!/bin/sh
+# Forwards Claude Code hook input to Claude Session Manager and prints a
+# summary of the session to the terminal when it ends.
+# Generated by GET /api/v1/hooks/script?summary=true
+# Set CSM_API_KEY to a write key if the server has auth enabled.
+input=$(cat)
+printf '%s' "$input" | curl --silent --show-error --fail --max-time 2 \
+  --header 'Content-Type: application/json' \
+  ` + authHeader + ` \
+  --data-binary @- \
+  ` + shellQuote(endpoint) + ` >/dev/null
+case "$input" in
+  *'"hook_event_name":"SessionEnd"'*|*'"hook_event_name": "SessionEnd"'*)
+    session_id=$(printf '%s' "$input" | sed -n 's/.*"session_id" *: *"\([A-Za-z0-9_-]*\)".*/\1/p')
+    if [ -n "$session_id" ]; then
+      curl --silent --fail --max-time 2 ` + authHeader + ` ` + shellQuote(sessionsURL) + `"$session_id/wrap-up?format=text" 2>/dev/null >/dev/tty
+    fi
+    ;;
+esac
+exit 0
+`
+}
+
+// authHeader expands to curl's Authorization header option when CSM_API_KEY
+// is set and to nothing otherwise
+const authHeader = `${CSM_API_KEY:+--header "Authorization: Bearer $CSM_API_KEY"}`
+
+// shellQuote quotes a value for a POSIX shell
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// Settings returns the hooks section of a Claude settings file that runs
+// command on each of events
+func Settings(command string, events []string) map[string]interface{} {
+	hooks := make(map[string]interface{}, len(events))
+	for _, event := range events {
+		matcher := map[string]interface{}{
+			"hooks": []map[string]interface{}{
+				{"type": "command", "command": command, "timeout": 5},
+			},
+		}
+		if toolEvents[event] {
+			matcher["matcher"] = "*"
+		}
+		hooks[event] = []interface{}{matcher}
+	}
+	return map[string]interface{}{"hooks": hooks}
+}
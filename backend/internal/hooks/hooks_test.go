@@ -0,0 +1,111 @@
+package hooks
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ksred/claude-session-manager/internal/database"
+	"github.com/sirupsen/logrus"
+)
+
+func setupTestRepo(t *testing.T) *database.SessionRepository {
+	tmpFile, err := os.CreateTemp("", "test-hooks-*.db")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	tmpFile.Close()
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.WarnLevel)
+	db, err := database.NewDatabase(database.Config{DatabasePath: tmpFile.Name(), Logger: logger})
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	t.Cleanup(func() {
+		db.Close()
+		os.Remove(tmpFile.Name())
+	})
+
+	return database.NewSessionRepository(db, logger)
+}
+
+func TestParse(t *testing.T) {
+	repo := setupTestRepo(t)
+
+	payload := `{"session_id":"s1","transcript_path":"/home/me/.claude/projects/p/s1.jsonl","cwd":"/home/me/p",` +
+		`"hook_event_name":"PreToolUse","tool_name":"Bash","tool_use_id":"toolu_1","tool_input":{"command":"go test ./..."}}`
+	event, err := Parse([]byte(payload), time.Now())
+	if err != nil {
+		t.Fatalf("Failed to parse hook input: %v", err)
+	}
+	if event.Event != "PreToolUse" || *event.ToolName != "Bash" || *event.ToolUseID != "toolu_1" || event.Payload != payload {
+		t.Errorf("Unexpected event: %+v", event)
+	}
+	if err := repo.SaveHookEvent(event); err != nil || event.ID == 0 {
+		t.Fatalf("Failed to save hook event: %v", err)
+	}
+
+	stop, err := Parse([]byte(`{"session_id":"s1","hook_event_name":"Stop","stop_hook_active":false}`), time.Now())
+	if err != nil || stop.ToolName != nil {
+		t.Fatalf("Unexpected stop event: %+v (%v)", stop, err)
+	}
+	if err := repo.SaveHookEvent(stop); err != nil {
+		t.Fatalf("Failed to save hook event: %v", err)
+	}
+
+	events, err := repo.GetHookEvents("s1", "", 10)
+	if err != nil || len(events) != 2 || events[0].Event != "Stop" {
+		t.Fatalf("Expected 2 events, most recent first, got %+v (%v)", events, err)
+	}
+	if string(events[1].Data) != payload {
+		t.Errorf("Expected payload to be kept as received, got %s", events[1].Data)
+	}
+	if events, _ := repo.GetHookEvents("", "PreToolUse", 10); len(events) != 1 {
+		t.Errorf("Expected 1 PreToolUse event, got %d", len(events))
+	}
+
+	for _, input := range []string{`not json`, `{"hook_event_name":"Stop"}`, `{"session_id":"s1"}`} {
+		if _, err := Parse([]byte(input), time.Now()); err == nil {
+			t.Errorf("Expected error for %s", input)
+		}
+	}
+}
+
+func TestScriptAndSettings(t *testing.T) {
+	script := Script("http://localhost:8080/api/v1/hooks/events")
+	if !strings.Contains(script, "'http://localhost:8080/api/v1/hooks/events'") || !strings.HasSuffix(script, "exit 0\n") {
+		t.Errorf("Unexpected script:\n%s", script)
+	}
+	if !strings.Contains(script, authHeader) || strings.Contains(script, "2>&1") {
+		t.Errorf("Expected the script to send CSM_API_KEY and report errors, got:\n%s", script)
+	}
+
+	script = SummaryScript("http://localhost:8080/api/v1/hooks/events", "http://localhost:8080/api/v1/sessions/")
+	if !strings.Contains(script, `'http://localhost:8080/api/v1/sessions/'"$session_id/wrap-up?format=text"`) || !strings.HasSuffix(script, "exit 0\n") {
+		t.Errorf("Unexpected summary script:\n%s", script)
+	}
+
+	settings := Settings("/hook.sh", []string{"PreToolUse", "Stop"})
+	hooks := settings["hooks"].(map[string]interface{})
+	pre := hooks["PreToolUse"].([]interface{})[0].(map[string]interface{})
+	stop := hooks["Stop"].([]interface{})[0].(map[string]interface{})
+	if pre["matcher"] != "*" {
+		t.Errorf("Expected tool events to match every tool, got %v", pre)
+	}
+	if _, ok := stop["matcher"]; ok {
+		t.Errorf("Expected no matcher for Stop, got %v", stop)
+	}
+}
+
+func TestLiveness(t *testing.T) {
+	for event, want := range map[string]bool{"SessionStart": true, "PreToolUse": true, "Stop": false, "SessionEnd": false} {
+		if active, ok := Liveness(event); !ok || active != want {
+			t.Errorf("Liveness(%s) = %v, %v; want %v", event, active, ok, want)
+		}
+	}
+	if _, ok := Liveness("Notification"); ok {
+		t.Error("Expected Notification to say nothing about liveness")
+	}
+}
@@ -0,0 +1,280 @@
+// Package integrity makes stored transcripts tamper-evident. Each message's
+// canonical form is hashed and chained to the previous message of its
+// session, so changing, removing or reordering any message after it was
+// sealed changes every later link and the session's head hash.
+package integrity
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/ksred/claude-session-manager/internal/database"
+	"github.com/sirupsen/logrus"
+)
+
+// GenesisHash is the chain hash before a session's first message
+var GenesisHash = strings.Repeat("0", 64)
+
+// Mismatch reasons
+const (
+	ReasonModified    = "modified"     // message content differs from when it was sealed
+	ReasonMissing     = "missing"      // sealed message no longer exists
+	ReasonChainBroken = "chain_broken" // stored chain hash or sequence does not follow from the previous link
+	ReasonHeadChanged = "head_changed" // session head does not match the end of the chain
+	ReasonNotInChain  = "not_in_chain" // message was never sealed
+)
+
+// canonicalMessage is the part of a message covered by its content hash.
+// created_at is excluded because it changes when a file is re-imported.
+type canonicalMessage struct {
+	ID          string  `json:"id"`
+	SessionID   string  `json:"session_id"`
+	ParentUUID  *string `json:"parent_uuid"`
+	IsSidechain bool    `json:"is_sidechain"`
+	UserType    string  `json:"user_type"`
+	CWD         string  `json:"cwd"`
+	Version     string  `json:"version"`
+	Type        string  `json:"type"`
+	Role        string  `json:"role"`
+	Content     string  `json:"content"`
+	RequestID   *string `json:"request_id"`
+	Timestamp   string  `json:"timestamp"`
+}
+
+// ContentHash returns the SHA-256 of a message's canonical JSON form
+func ContentHash(message database.Message) string {
+	data, _ := json.Marshal(canonicalMessage{
+		ID:          message.ID,
+		SessionID:   message.SessionID,
+		ParentUUID:  message.ParentUUID,
+		IsSidechain: message.IsSidechain,
+		UserType:    message.UserType,
+		CWD:         message.CWD,
+		Version:     message.Version,
+		Type:        message.Type,
+		Role:        message.Role,
+		Content:     message.Content,
+		RequestID:   message.RequestID,
+		Timestamp:   message.Timestamp.UTC().Format(time.RFC3339Nano),
+	})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// ChainHash links a content hash to the previous chain hash
+func ChainHash(previous, contentHash string) string {
+	sum := sha256.Sum256([]byte(previous + contentHash))
+	return hex.EncodeToString(sum[:])
+}
+
+// Mismatch is a message that failed verification
+type Mismatch struct {
+	MessageID string `json:"message_id"`
+	Seq       int    `json:"seq,omitempty"`
+	Reason    string `json:"reason"`
+}
+
+// Report is the result of verifying messages against a session's hash chain
+type Report struct {
+	SessionID  string     `json:"session_id"`
+	Valid      bool       `json:"valid"`
+	Verified   int        `json:"verified"`           // messages whose content matched the chain
+	Unsealed   int        `json:"unsealed,omitempty"` // stored messages imported since the chain was last extended
+	HeadHash   string     `json:"head_hash"`          // chain hash after the last verified message
+	Mismatches []Mismatch `json:"mismatches"`
+	VerifiedAt time.Time  `json:"verified_at"`
+}
+
+// walkChain checks that each link follows from the previous one and returns
+// the mismatches and the hash at the end of the chain
+func walkChain(hashes []database.MessageHash) ([]Mismatch, string) {
+	var mismatches []Mismatch
+	previous := GenesisHash
+	for i, link := range hashes {
+		if link.Seq != i+1 || ChainHash(previous, link.ContentHash) != link.ChainHash {
+			mismatches = append(mismatches, Mismatch{MessageID: link.MessageID, Seq: link.Seq, Reason: ReasonChainBroken})
+		}
+		previous = link.ChainHash
+	}
+	return mismatches, previous
+}
+
+// VerifyStored checks a session's stored messages against its hash chain and
+// head. Messages imported since the chain was last extended are counted as
+// unsealed rather than failures.
+func VerifyStored(sessionID string, messages []database.Message, hashes []database.MessageHash, head *database.SessionHash) *Report {
+	report := &Report{SessionID: sessionID, VerifiedAt: time.Now().UTC()}
+
+	mismatches, end := walkChain(hashes)
+	if head != nil && (head.HeadHash != end || head.MessageCount != len(hashes)) {
+		mismatches = append(mismatches, Mismatch{Reason: ReasonHeadChanged})
+	}
+
+	byID := make(map[string]database.Message, len(messages))
+	for _, message := range messages {
+		byID[message.ID] = message
+	}
+	for _, link := range hashes {
+		message, exists := byID[link.MessageID]
+		switch {
+		case !exists:
+			mismatches = append(mismatches, Mismatch{MessageID: link.MessageID, Seq: link.Seq, Reason: ReasonMissing})
+		case ContentHash(message) != link.ContentHash:
+			mismatches = append(mismatches, Mismatch{MessageID: link.MessageID, Seq: link.Seq, Reason: ReasonModified})
+		default:
+			report.Verified++
+		}
+		delete(byID, link.MessageID)
+	}
+
+	report.Unsealed = len(byID)
+	report.HeadHash = end
+	report.Mismatches = append([]Mismatch{}, mismatches...)
+	report.Valid = len(mismatches) == 0
+	return report
+}
+
+// VerifyTranscript checks an exported transcript against a session's hash
+// chain. The transcript is valid if its messages are exactly the first
+// messages of the chain, unmodified; HeadHash is then the chain hash at the
+// transcript's last message, which matches the head recorded when it was exported.
+func VerifyTranscript(sessionID string, messages []database.Message, hashes []database.MessageHash) *Report {
+	report := &Report{SessionID: sessionID, HeadHash: GenesisHash, VerifiedAt: time.Now().UTC()}
+
+	mismatches, _ := walkChain(hashes)
+	byID := make(map[string]database.MessageHash, len(hashes))
+	for _, link := range hashes {
+		byID[link.MessageID] = link
+	}
+
+	covered := make(map[int]bool)
+	for _, message := range messages {
+		link, exists := byID[message.ID]
+		switch {
+		case !exists:
+			mismatches = append(mismatches, Mismatch{MessageID: message.ID, Reason: ReasonNotInChain})
+		case ContentHash(message) != link.ContentHash:
+			mismatches = append(mismatches, Mismatch{MessageID: message.ID, Seq: link.Seq, Reason: ReasonModified})
+		default:
+			covered[link.Seq] = true
+			report.Verified++
+		}
+	}
+
+	// The verified messages must be a gap-free prefix of the chain
+	for i := 0; i < report.Verified; i++ {
+		if i >= len(hashes) || !covered[i+1] {
+			link := database.MessageHash{Seq: i + 1}
+			if i < len(hashes) {
+				link = hashes[i]
+			}
+			mismatches = append(mismatches, Mismatch{MessageID: link.MessageID, Seq: link.Seq, Reason: ReasonMissing})
+			break
+		}
+		report.HeadHash = hashes[i].ChainHash
+	}
+
+	report.Mismatches = append([]Mismatch{}, mismatches...)
+	report.Valid = len(mismatches) == 0
+	return report
+}
+
+// Sealer extends sessions' hash chains with newly imported messages
+type Sealer struct {
+	repo   *database.SessionRepository
+	logger *logrus.Logger
+}
+
+// NewSealer creates a new transcript sealer
+func NewSealer(repo *database.SessionRepository, logger *logrus.Logger) *Sealer {
+	return &Sealer{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+// SealSession appends a session's unsealed messages to its hash chain in
+// timestamp order and returns how many were sealed
+func (s *Sealer) SealSession(sessionID string) (int, error) {
+	messages, err := s.repo.GetUnhashedMessages(sessionID)
+	if err != nil || len(messages) == 0 {
+		return 0, err
+	}
+
+	head := &database.SessionHash{SessionID: sessionID, HeadHash: GenesisHash}
+	if existing, err := s.repo.GetSessionHash(sessionID); err == nil {
+		head = existing
+	}
+
+	hashes := make([]database.MessageHash, 0, len(messages))
+	for _, message := range messages {
+		contentHash := ContentHash(message)
+		head.HeadHash = ChainHash(head.HeadHash, contentHash)
+		head.MessageCount++
+		hashes = append(hashes, database.MessageHash{
+			MessageID:   message.ID,
+			SessionID:   sessionID,
+			Seq:         head.MessageCount,
+			ContentHash: contentHash,
+			ChainHash:   head.HeadHash,
+		})
+	}
+
+	if err := s.repo.AppendMessageHashes(hashes, head); err != nil {
+		return 0, err
+	}
+	return len(hashes), nil
+}
+
+// SealPending seals up to limit sessions with unsealed messages and returns
+// how many messages were sealed
+func (s *Sealer) SealPending(limit int) (int, error) {
+	sessionIDs, err := s.repo.GetSessionsWithUnhashedMessages(limit)
+	if err != nil {
+		return 0, err
+	}
+
+	sealed := 0
+	for _, sessionID := range sessionIDs {
+		count, err := s.SealSession(sessionID)
+		if err != nil {
+			s.logger.WithError(err).WithField("session_id", sessionID).Warn("Failed to seal session messages")
+			continue
+		}
+		sealed += count
+	}
+	return sealed, nil
+}
+
+// VerifySession checks a session's stored messages against its hash chain
+func (s *Sealer) VerifySession(sessionID string) (*Report, error) {
+	if _, err := s.repo.GetSessionByID(sessionID); err != nil {
+		return nil, err
+	}
+	messages, err := s.repo.GetSessionMessages(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	hashes, err := s.repo.GetMessageHashes(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	var head *database.SessionHash
+	if existing, err := s.repo.GetSessionHash(sessionID); err == nil {
+		head = existing
+	}
+
+	return VerifyStored(sessionID, messages, hashes, head), nil
+}
+
+// VerifyTranscript checks an exported transcript against a session's stored hash chain
+func (s *Sealer) VerifyTranscript(sessionID string, messages []database.Message) (*Report, error) {
+	hashes, err := s.repo.GetMessageHashes(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return VerifyTranscript(sessionID, messages, hashes), nil
+}
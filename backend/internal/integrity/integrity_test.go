@@ -0,0 +1,127 @@
+package integrity
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ksred/claude-session-manager/internal/database"
+	"github.com/sirupsen/logrus"
+)
+
+func setupTestRepo(t *testing.T) *database.SessionRepository {
+	tmpFile, err := os.CreateTemp("", "test-integrity-*.db")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	tmpFile.Close()
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.WarnLevel)
+	db, err := database.NewDatabase(database.Config{DatabasePath: tmpFile.Name(), Logger: logger})
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	t.Cleanup(func() {
+		db.Close()
+		os.Remove(tmpFile.Name())
+	})
+
+	return database.NewSessionRepository(db, logger)
+}
+
+func TestSealer(t *testing.T) {
+	repo := setupTestRepo(t)
+	sealer := NewSealer(repo, logrus.New())
+
+	now := time.Now()
+	if err := repo.UpsertSession(&database.Session{
+		ID:           "session-1",
+		ProjectPath:  "/test/project",
+		ProjectName:  "project",
+		StartTime:    now,
+		LastActivity: now,
+		Status:       "completed",
+	}); err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	addMessage := func(i int, content string) *database.Message {
+		message := &database.Message{
+			ID:        fmt.Sprintf("m%d", i),
+			SessionID: "session-1",
+			Role:      "user",
+			Content:   content,
+			Timestamp: now.Add(time.Duration(i) * time.Second),
+		}
+		if err := repo.UpsertMessage(message); err != nil {
+			t.Fatalf("Failed to create message: %v", err)
+		}
+		return message
+	}
+	for i := 1; i <= 3; i++ {
+		addMessage(i, fmt.Sprintf(`"message %d"`, i))
+	}
+
+	sealed, err := sealer.SealPending(10)
+	if err != nil || sealed != 3 {
+		t.Fatalf("Expected 3 messages sealed, got %d (%v)", sealed, err)
+	}
+
+	// Messages imported later extend the chain rather than invalidating it
+	addMessage(4, `"message 4"`)
+	report, err := sealer.VerifySession("session-1")
+	if err != nil {
+		t.Fatalf("Failed to verify session: %v", err)
+	}
+	if !report.Valid || report.Verified != 3 || report.Unsealed != 1 {
+		t.Errorf("Unexpected report before sealing the new message: %+v", report)
+	}
+	if sealed, _ := sealer.SealSession("session-1"); sealed != 1 {
+		t.Errorf("Expected 1 message sealed, got %d", sealed)
+	}
+
+	head, err := repo.GetSessionHash("session-1")
+	if err != nil || head.MessageCount != 4 {
+		t.Fatalf("Expected head covering 4 messages, got %+v (%v)", head, err)
+	}
+
+	// An exported prefix of the transcript verifies against the chain
+	messages, _ := repo.GetSessionMessages("session-1")
+	exported, _ := json.Marshal(messages[:2])
+	var transcript []database.Message
+	if err := json.Unmarshal(exported, &transcript); err != nil {
+		t.Fatalf("Failed to decode transcript: %v", err)
+	}
+	hashes, _ := repo.GetMessageHashes("session-1")
+	report, _ = sealer.VerifyTranscript("session-1", transcript)
+	if !report.Valid || report.Verified != 2 || report.HeadHash != hashes[1].ChainHash {
+		t.Errorf("Expected exported prefix to verify, got %+v", report)
+	}
+
+	// Edits, gaps and additions in an exported transcript are detected
+	edited := append([]database.Message{}, transcript...)
+	edited[1].Content = `"message 2, edited"`
+	if report, _ := sealer.VerifyTranscript("session-1", edited); report.Valid || report.Mismatches[0].Reason != ReasonModified {
+		t.Errorf("Expected edited transcript to fail, got %+v", report)
+	}
+	if report, _ := sealer.VerifyTranscript("session-1", transcript[1:]); report.Valid || report.Mismatches[0].Reason != ReasonMissing {
+		t.Errorf("Expected transcript with a gap to fail, got %+v", report)
+	}
+	extra := append(append([]database.Message{}, transcript...), database.Message{ID: "forged", SessionID: "session-1"})
+	if report, _ := sealer.VerifyTranscript("session-1", extra); report.Valid || report.Mismatches[0].Reason != ReasonNotInChain {
+		t.Errorf("Expected transcript with a forged message to fail, got %+v", report)
+	}
+
+	// Modifying a stored message after it was sealed is detected
+	addMessage(2, `"message 2, rewritten"`)
+	report, _ = sealer.VerifySession("session-1")
+	if report.Valid || len(report.Mismatches) != 1 || report.Mismatches[0].MessageID != "m2" || report.Mismatches[0].Reason != ReasonModified {
+		t.Errorf("Expected modified message to be reported, got %+v", report)
+	}
+
+	if _, err := sealer.VerifySession("missing"); err == nil {
+		t.Error("Expected error for unknown session")
+	}
+}
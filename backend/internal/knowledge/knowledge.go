@@ -0,0 +1,285 @@
+// Package knowledge extracts recurring questions and answers and recorded
+// decisions from session transcripts into a searchable knowledge table.
+package knowledge
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ksred/claude-session-manager/internal/database"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// maxQuestionLength skips long prompts, which are instructions rather than questions
+	maxQuestionLength = 500
+	// maxBodyLength truncates stored answers and decision context
+	maxBodyLength = 2000
+	// maxDecisionLength bounds the sentence a decision is recorded as
+	maxDecisionLength = 300
+)
+
+// questionPrefixes mark a user message as a question even without a question mark
+var questionPrefixes = []string{
+	"how ", "what ", "why ", "where ", "when ", "which ", "who ",
+	"can ", "could ", "should ", "is ", "are ", "does ", "do ",
+}
+
+// decisionPhrases mark a sentence as recording a decision
+var decisionPhrases = []string{
+	"we decided", "i decided", "decided to", "the decision is", "let's go with",
+	"we'll go with", "we will go with", "we'll use", "we will use", "going with",
+	"settled on", "agreed to",
+}
+
+var (
+	sentenceBoundary = regexp.MustCompile(`[.!?\n]+\s*`)
+	nonWord          = regexp.MustCompile(`[^\p{L}\p{N}]+`)
+)
+
+// Candidate is a Q&A pair or decision found in a single transcript
+type Candidate struct {
+	Kind      string
+	Key       string
+	Title     string
+	Body      string
+	MessageID string
+	Timestamp time.Time
+}
+
+// Normalize reduces text to lowercase words separated by single spaces so
+// that rephrasings differing only in case or punctuation group together
+func Normalize(text string) string {
+	return strings.TrimSpace(nonWord.ReplaceAllString(strings.ToLower(text), " "))
+}
+
+// ExtractText returns the human-written text of stored message content,
+// skipping tool calls, tool results and command output
+func ExtractText(content string) string {
+	var decoded interface{}
+	if err := json.Unmarshal([]byte(content), &decoded); err != nil {
+		decoded = content
+	}
+
+	var text string
+	switch v := decoded.(type) {
+	case string:
+		text = v
+	case []interface{}:
+		var parts []string
+		for _, item := range v {
+			block, ok := item.(map[string]interface{})
+			if !ok || block["type"] != "text" {
+				continue
+			}
+			if s, ok := block["text"].(string); ok {
+				parts = append(parts, s)
+			}
+		}
+		text = strings.Join(parts, "\n")
+	}
+
+	text = strings.TrimSpace(text)
+	if strings.HasPrefix(text, "Caveat:") || strings.HasPrefix(text, "<command-") ||
+		strings.HasPrefix(text, "<local-command-") || strings.HasPrefix(text, "[Request interrupted") {
+		return ""
+	}
+	return text
+}
+
+// isQuestion reports whether a user message reads as a question
+func isQuestion(text string) bool {
+	if text == "" || utf8.RuneCountInString(text) > maxQuestionLength || strings.Contains(text, "```") {
+		return false
+	}
+	if strings.HasSuffix(text, "?") {
+		return true
+	}
+	lower := strings.ToLower(text)
+	for _, prefix := range questionPrefixes {
+		if strings.HasPrefix(lower, prefix) {
+			return !strings.Contains(text, "\n")
+		}
+	}
+	return false
+}
+
+// decisions returns the sentences of text that record a decision
+func decisions(text string) []string {
+	var found []string
+	for _, sentence := range sentenceBoundary.Split(text, -1) {
+		sentence = strings.TrimSpace(sentence)
+		if sentence == "" || utf8.RuneCountInString(sentence) > maxDecisionLength {
+			continue
+		}
+		lower := strings.ToLower(sentence)
+		for _, phrase := range decisionPhrases {
+			if strings.Contains(lower, phrase) {
+				found = append(found, sentence)
+				break
+			}
+		}
+	}
+	return found
+}
+
+// truncate shortens text to at most n runes
+func truncate(text string, n int) string {
+	if utf8.RuneCountInString(text) <= n {
+		return text
+	}
+	runes := []rune(text)
+	return string(runes[:n]) + "…"
+}
+
+// Extract finds Q&A pairs and decisions in a transcript. A question is a short
+// user message paired with the assistant text that follows it, up to the next
+// user message. A decision is a sentence in any message that records one.
+func Extract(messages []database.TranscriptMessage) []Candidate {
+	var candidates []Candidate
+	var question *Candidate
+	var answer []string
+
+	flush := func() {
+		if question != nil && len(answer) > 0 {
+			question.Body = truncate(strings.Join(answer, "\n\n"), maxBodyLength)
+			candidates = append(candidates, *question)
+		}
+		question, answer = nil, nil
+	}
+
+	for _, message := range messages {
+		text := ExtractText(message.Content)
+		if text == "" {
+			continue
+		}
+
+		if message.Role == "user" {
+			flush()
+			if isQuestion(text) {
+				question = &Candidate{
+					Kind:      database.KnowledgeKindQA,
+					Key:       Normalize(text),
+					Title:     text,
+					MessageID: message.ID,
+					Timestamp: message.Timestamp,
+				}
+			}
+		} else if question != nil {
+			answer = append(answer, text)
+		}
+
+		for _, decision := range decisions(text) {
+			candidates = append(candidates, Candidate{
+				Kind:      database.KnowledgeKindDecision,
+				Key:       Normalize(decision),
+				Title:     decision,
+				Body:      truncate(text, maxBodyLength),
+				MessageID: message.ID,
+				Timestamp: message.Timestamp,
+			})
+		}
+	}
+	flush()
+
+	return candidates
+}
+
+// Extractor scans newly imported transcripts and saves what it finds
+type Extractor struct {
+	repo   *database.SessionRepository
+	logger *logrus.Logger
+	mu     sync.Mutex
+}
+
+// NewExtractor creates a new knowledge extractor
+func NewExtractor(repo *database.SessionRepository, logger *logrus.Logger) *Extractor {
+	return &Extractor{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+// Run scans every session with messages imported since the last completed
+// run, or every session when full is set, and records the run. Only one run
+// happens at a time.
+func (e *Extractor) Run(ctx context.Context, full bool) (*database.KnowledgeExtractionRun, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	run, since, err := e.repo.StartKnowledgeExtractionRun(full)
+	if err != nil {
+		return nil, err
+	}
+
+	err = e.scan(ctx, run, since)
+	run.Status = "completed"
+	if err != nil {
+		run.Status = "failed"
+		message := err.Error()
+		run.ErrorMessage = &message
+	}
+	if finishErr := e.repo.FinishKnowledgeExtractionRun(run); finishErr != nil {
+		e.logger.WithError(finishErr).Error("Failed to record knowledge extraction run")
+	}
+
+	return run, err
+}
+
+// scan extracts knowledge from each session with messages imported since the given time
+func (e *Extractor) scan(ctx context.Context, run *database.KnowledgeExtractionRun, since time.Time) error {
+	sessionIDs, err := e.repo.GetSessionsWithNewMessages(since)
+	if err != nil {
+		return err
+	}
+
+	for _, sessionID := range sessionIDs {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		var projectName *string
+		if session, err := e.repo.GetSessionByID(sessionID); err == nil && session.ProjectName != "" {
+			projectName = &session.ProjectName
+		}
+
+		messages, err := e.repo.GetTranscript(sessionID)
+		if err != nil {
+			return err
+		}
+
+		for _, candidate := range Extract(messages) {
+			entry := &database.KnowledgeEntry{
+				Kind:          candidate.Kind,
+				NormalizedKey: candidate.Key,
+				Title:         candidate.Title,
+			}
+			if candidate.Body != "" {
+				body := candidate.Body
+				entry.Body = &body
+			}
+			source := &database.KnowledgeSource{
+				MessageID:   candidate.MessageID,
+				SessionID:   sessionID,
+				ProjectName: projectName,
+				Timestamp:   candidate.Timestamp,
+			}
+			if err := e.repo.SaveKnowledgeEntry(entry, source); err != nil {
+				return err
+			}
+			run.EntriesFound++
+		}
+		run.SessionsScanned++
+	}
+
+	e.logger.WithFields(logrus.Fields{
+		"sessions": run.SessionsScanned,
+		"entries":  run.EntriesFound,
+	}).Info("Extracted knowledge from transcripts")
+	return nil
+}
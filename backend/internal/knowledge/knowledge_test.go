@@ -0,0 +1,145 @@
+package knowledge
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ksred/claude-session-manager/internal/database"
+	"github.com/sirupsen/logrus"
+)
+
+func setupTestRepo(t *testing.T) *database.SessionRepository {
+	tmpFile, err := os.CreateTemp("", "test-knowledge-*.db")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	tmpFile.Close()
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.WarnLevel)
+	db, err := database.NewDatabase(database.Config{DatabasePath: tmpFile.Name(), Logger: logger})
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	t.Cleanup(func() {
+		db.Close()
+		os.Remove(tmpFile.Name())
+	})
+
+	return database.NewSessionRepository(db, logger)
+}
+
+func TestExtractText(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"plain string", `"How do I run the tests?"`, "How do I run the tests?"},
+		{"text blocks", `[{"type":"text","text":"First"},{"type":"tool_use","name":"Bash"},{"type":"text","text":"Second"}]`, "First\nSecond"},
+		{"tool result only", `[{"type":"tool_result","content":"ok"}]`, ""},
+		{"command output", `"<command-name>/clear</command-name>"`, ""},
+		{"interruption", `[{"type":"text","text":"[Request interrupted by user]"}]`, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExtractText(tt.content); got != tt.want {
+				t.Errorf("ExtractText() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtract(t *testing.T) {
+	now := time.Now()
+	messages := []database.TranscriptMessage{
+		{ID: "1", Role: "user", Content: `"How do I run the tests?"`, Timestamp: now},
+		{ID: "2", Role: "assistant", Content: `[{"type":"text","text":"Run make test."}]`, Timestamp: now},
+		{ID: "3", Role: "user", Content: `"Add a flag for verbose output"`, Timestamp: now},
+		{ID: "4", Role: "assistant", Content: `[{"type":"text","text":"Done. We decided to use logrus for all logging. It matches the rest of the code."}]`, Timestamp: now},
+		{ID: "5", Role: "user", Content: `"Why is CI failing?"`, Timestamp: now},
+	}
+
+	candidates := Extract(messages)
+	if len(candidates) != 2 {
+		t.Fatalf("Expected a Q&A pair and a decision, got %+v", candidates)
+	}
+
+	qa, decision := candidates[0], candidates[1]
+	if qa.Kind != database.KnowledgeKindQA || qa.Key != "how do i run the tests" || qa.Body != "Run make test." || qa.MessageID != "1" {
+		t.Errorf("Unexpected Q&A pair: %+v", qa)
+	}
+	if decision.Kind != database.KnowledgeKindDecision || decision.Title != "We decided to use logrus for all logging" || decision.MessageID != "4" {
+		t.Errorf("Unexpected decision: %+v", decision)
+	}
+}
+
+func TestExtractor_Run(t *testing.T) {
+	repo := setupTestRepo(t)
+
+	now := time.Now()
+	for i, id := range []string{"session-1", "session-2"} {
+		if err := repo.UpsertSession(&database.Session{
+			ID:           id,
+			ProjectPath:  "/test/project",
+			ProjectName:  "project",
+			StartTime:    now.Add(-time.Hour),
+			LastActivity: now,
+			Status:       "completed",
+		}); err != nil {
+			t.Fatalf("Failed to create session: %v", err)
+		}
+
+		question := `"How do I run the tests?"`
+		if i == 1 {
+			question = `"how do I run the tests"`
+		}
+		for j, message := range []*database.Message{
+			{ID: id + "-q", Role: "user", Content: question},
+			{ID: id + "-a", Role: "assistant", Content: `[{"type":"text","text":"Run make test."}]`},
+		} {
+			message.SessionID = id
+			message.Timestamp = now.Add(time.Duration(i*10+j) * time.Second)
+			if err := repo.UpsertMessage(message); err != nil {
+				t.Fatalf("Failed to create message: %v", err)
+			}
+		}
+	}
+
+	extractor := NewExtractor(repo, logrus.New())
+	run, err := extractor.Run(context.Background(), false)
+	if err != nil {
+		t.Fatalf("Failed to run extraction: %v", err)
+	}
+	if run.Status != "completed" || run.SessionsScanned != 2 {
+		t.Errorf("Unexpected run: %+v", run)
+	}
+
+	// Rescanning the same messages does not count them twice
+	if _, err := extractor.Run(context.Background(), true); err != nil {
+		t.Fatalf("Failed to rerun extraction: %v", err)
+	}
+
+	entries, err := repo.SearchKnowledge("tests", database.KnowledgeKindQA, "project", 2, 10)
+	if err != nil {
+		t.Fatalf("Failed to search knowledge: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Occurrences != 2 {
+		t.Fatalf("Expected one entry seen in 2 sessions, got %+v", entries)
+	}
+
+	entry, sources, err := repo.GetKnowledgeEntry(entries[0].ID)
+	if err != nil {
+		t.Fatalf("Failed to get knowledge entry: %v", err)
+	}
+	if entry.Title != "How do I run the tests?" || len(sources) != 2 || sources[0].SessionID != "session-2" {
+		t.Errorf("Unexpected entry %+v with sources %+v", entry, sources)
+	}
+
+	if _, _, err := repo.GetKnowledgeEntry("missing"); err == nil {
+		t.Error("Expected error for unknown entry")
+	}
+}
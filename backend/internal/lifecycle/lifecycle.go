@@ -0,0 +1,89 @@
+// Package lifecycle re-evaluates session statuses as time passes. Imports set
+// a session's status from how recent its last message was, so without it a
+// session imported moments after activity would stay active forever; the
+// manager moves sessions from active to idle to completed as they go quiet.
+package lifecycle
+
+import (
+	"context"
+	"time"
+
+	"github.com/ksred/claude-session-manager/internal/config"
+	"github.com/ksred/claude-session-manager/internal/database"
+	"github.com/sirupsen/logrus"
+)
+
+// Store is the storage session statuses are kept in
+type Store interface {
+	TransitionSessionStatuses(idleBefore, completedBefore time.Time) ([]database.SessionStatusChange, error)
+}
+
+// Manager transitions session statuses on an interval
+type Manager struct {
+	store         Store
+	idleAfter     time.Duration
+	completeAfter time.Duration
+	interval      time.Duration
+	onChange      func(database.SessionStatusChange)
+	logger        *logrus.Logger
+	now           func() time.Time
+}
+
+// NewManager creates a manager for the thresholds in cfg
+func NewManager(store Store, cfg config.SessionStatusConfig, logger *logrus.Logger) *Manager {
+	return &Manager{
+		store:         store,
+		idleAfter:     time.Duration(cfg.IdleAfter) * time.Minute,
+		completeAfter: time.Duration(cfg.CompleteAfter) * time.Minute,
+		interval:      time.Duration(cfg.Interval) * time.Second,
+		logger:        logger,
+		now:           time.Now,
+	}
+}
+
+// OnChange sets the function called with each status change
+func (m *Manager) OnChange(fn func(database.SessionStatusChange)) {
+	m.onChange = fn
+}
+
+// Run transitions the sessions that have gone quiet for long enough and
+// returns the changes
+func (m *Manager) Run() ([]database.SessionStatusChange, error) {
+	now := m.now()
+	changes, err := m.store.TransitionSessionStatuses(now.Add(-m.idleAfter), now.Add(-m.completeAfter))
+	if err != nil {
+		return nil, err
+	}
+	if len(changes) > 0 {
+		m.logger.WithField("sessions", len(changes)).Debug("Transitioned session statuses")
+	}
+	if m.onChange != nil {
+		for _, change := range changes {
+			m.onChange(change)
+		}
+	}
+	return changes, nil
+}
+
+// Start transitions sessions immediately, catching up on those left active
+// when the server last stopped, and then on each interval until ctx is
+// cancelled
+func (m *Manager) Start(ctx context.Context) {
+	if _, err := m.Run(); err != nil {
+		m.logger.WithError(err).Error("Failed to transition session statuses")
+	}
+
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := m.Run(); err != nil {
+				m.logger.WithError(err).Error("Failed to transition session statuses")
+			}
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package lifecycle
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ksred/claude-session-manager/internal/config"
+	"github.com/ksred/claude-session-manager/internal/database"
+	"github.com/sirupsen/logrus"
+)
+
+type fakeStore struct {
+	idleBefore, completedBefore time.Time
+}
+
+func (s *fakeStore) TransitionSessionStatuses(idleBefore, completedBefore time.Time) ([]database.SessionStatusChange, error) {
+	s.idleBefore, s.completedBefore = idleBefore, completedBefore
+	return []database.SessionStatusChange{{SessionID: "s1", From: database.SessionStatusActive, To: database.SessionStatusIdle}}, nil
+}
+
+func TestManager_Run(t *testing.T) {
+	store := &fakeStore{}
+	manager := NewManager(store, config.SessionStatusConfig{Enabled: true, IdleAfter: 5, CompleteAfter: 30, Interval: 30}, logrus.New())
+	now := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+	manager.now = func() time.Time { return now }
+
+	var notified []database.SessionStatusChange
+	manager.OnChange(func(change database.SessionStatusChange) {
+		notified = append(notified, change)
+	})
+
+	changes, err := manager.Run()
+	if err != nil {
+		t.Fatalf("Failed to run manager: %v", err)
+	}
+	if !store.idleBefore.Equal(now.Add(-5*time.Minute)) || !store.completedBefore.Equal(now.Add(-30*time.Minute)) {
+		t.Errorf("Expected thresholds of 5 and 30 minutes ago, got %v and %v", store.idleBefore, store.completedBefore)
+	}
+	if len(changes) != 1 || len(notified) != 1 || notified[0].SessionID != "s1" {
+		t.Errorf("Expected the change returned and notified, got %v and %v", changes, notified)
+	}
+}
@@ -0,0 +1,294 @@
+// Package locale picks the locale API responses are described in and formats
+// costs and token counts for clients that don't format numbers themselves.
+package locale
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultLocale is used when neither the config nor the request names a
+// known locale
+const DefaultLocale = "en-US"
+
+// numberFormat is how a locale writes numbers and where it puts the
+// currency symbol. Spaces are non-breaking, so an amount is never wrapped
+// across lines.
+type numberFormat struct {
+	decimal       string
+	group         string
+	symbolAfter   bool // "12,50 €" rather than "€12.50"
+	symbolSpacing bool // a space between the symbol and the amount
+}
+
+// formats are the locales numbers can be formatted for. Each language's
+// first locale listed is the one its bare language tag, such as "de",
+// resolves to.
+var formats = map[string]numberFormat{
+	"en-US": {decimal: ".", group: ","},
+	"en-GB": {decimal: ".", group: ","},
+	"en-AU": {decimal: ".", group: ","},
+	"en-CA": {decimal: ".", group: ","},
+	"de-DE": {decimal: ",", group: ".", symbolAfter: true, symbolSpacing: true},
+	"de-CH": {decimal: ".", group: "’", symbolSpacing: true},
+	"fr-FR": {decimal: ",", group: "\u202f", symbolAfter: true, symbolSpacing: true},
+	"es-ES": {decimal: ",", group: ".", symbolAfter: true, symbolSpacing: true},
+	"it-IT": {decimal: ",", group: ".", symbolAfter: true, symbolSpacing: true},
+	"nl-NL": {decimal: ",", group: ".", symbolSpacing: true},
+	"pt-BR": {decimal: ",", group: ".", symbolSpacing: true},
+	"sv-SE": {decimal: ",", group: "\u00a0", symbolAfter: true, symbolSpacing: true},
+	"pl-PL": {decimal: ",", group: "\u00a0", symbolAfter: true, symbolSpacing: true},
+	"ja-JP": {decimal: ".", group: ","},
+	"zh-CN": {decimal: ".", group: ","},
+	"ko-KR": {decimal: ".", group: ","},
+}
+
+// languageDefaults maps a bare language to the locale it resolves to
+var languageDefaults = map[string]string{
+	"en": "en-US",
+	"de": "de-DE",
+	"fr": "fr-FR",
+	"es": "es-ES",
+	"it": "it-IT",
+	"nl": "nl-NL",
+	"pt": "pt-BR",
+	"sv": "sv-SE",
+	"pl": "pl-PL",
+	"ja": "ja-JP",
+	"zh": "zh-CN",
+	"ko": "ko-KR",
+}
+
+// currencySymbols are the symbols of common currencies; others are written
+// with their ISO 4217 code
+var currencySymbols = map[string]string{
+	"USD": "$",
+	"EUR": "€",
+	"GBP": "£",
+	"JPY": "¥",
+	"CNY": "¥",
+	"KRW": "₩",
+	"INR": "₹",
+	"BRL": "R$",
+}
+
+// currencyDecimals lists the currencies that aren't written with two
+// decimal places
+var currencyDecimals = map[string]int{
+	"JPY": 0,
+	"KRW": 0,
+}
+
+// Supported returns the locales numbers can be formatted for, sorted
+func Supported() []string {
+	tags := make([]string, 0, len(formats))
+	for tag := range formats {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// Resolve returns the supported locale closest to tag: the same locale,
+// ignoring case and "_" for "-", or else the default locale of its
+// language. ok is false when the language isn't supported either.
+func Resolve(tag string) (string, bool) {
+	tag = strings.ReplaceAll(strings.TrimSpace(tag), "_", "-")
+	language, region, _ := strings.Cut(tag, "-")
+	language = strings.ToLower(language)
+	if region != "" {
+		candidate := language + "-" + strings.ToUpper(region)
+		if _, ok := formats[candidate]; ok {
+			return candidate, true
+		}
+	}
+	if candidate, ok := languageDefaults[language]; ok {
+		return candidate, true
+	}
+	return "", false
+}
+
+// Negotiate picks the locale for a request from its Accept-Language header,
+// taking the most preferred language that is supported. fallback, the
+// configured locale, is used when none is, and is preferred over another
+// locale of the same language, so "en" keeps an en-GB configuration.
+func Negotiate(acceptLanguage, fallback string) string {
+	if resolved, ok := Resolve(fallback); ok {
+		fallback = resolved
+	} else {
+		fallback = DefaultLocale
+	}
+
+	type preference struct {
+		tag     string
+		quality float64
+	}
+	var preferences []preference
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if tag == "" {
+			continue
+		}
+		quality := 1.0
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(q, 64)
+			if err != nil {
+				continue
+			}
+			quality = parsed
+		}
+		if quality > 0 {
+			preferences = append(preferences, preference{tag: tag, quality: quality})
+		}
+	}
+	sort.SliceStable(preferences, func(i, j int) bool {
+		return preferences[i].quality > preferences[j].quality
+	})
+
+	for _, p := range preferences {
+		if p.tag == "*" {
+			return fallback
+		}
+		language, region, _ := strings.Cut(strings.ReplaceAll(p.tag, "_", "-"), "-")
+		if region == "" && strings.EqualFold(language, strings.SplitN(fallback, "-", 2)[0]) {
+			return fallback
+		}
+		if resolved, ok := Resolve(p.tag); ok {
+			return resolved
+		}
+	}
+	return fallback
+}
+
+// Metadata describes how a response's figures should be formatted, so
+// clients present costs and token counts the same way
+type Metadata struct {
+	Locale           string `json:"locale"`
+	Currency         string `json:"currency"`
+	CurrencySymbol   string `json:"currency_symbol"`
+	CurrencyDecimals int    `json:"currency_decimals"`
+	SymbolPosition   string `json:"symbol_position"` // before or after the amount
+	DecimalSeparator string `json:"decimal_separator"`
+	GroupSeparator   string `json:"group_separator"`
+	Units            Units  `json:"units"`
+}
+
+// Units names the units figures in responses are given in
+type Units struct {
+	Cost     string `json:"cost"`
+	Tokens   string `json:"tokens"`
+	Duration string `json:"duration"`
+}
+
+// Formatter formats figures for a locale and currency
+type Formatter struct {
+	locale   string
+	currency string
+	format   numberFormat
+}
+
+// NewFormatter returns a formatter for the supported locale closest to tag,
+// or the default locale, writing costs in currency (USD when empty)
+func NewFormatter(tag, currency string) *Formatter {
+	resolved, ok := Resolve(tag)
+	if !ok {
+		resolved = DefaultLocale
+	}
+	if currency == "" {
+		currency = "USD"
+	}
+	return &Formatter{locale: resolved, currency: strings.ToUpper(currency), format: formats[resolved]}
+}
+
+// Locale returns the locale the formatter uses
+func (f *Formatter) Locale() string {
+	return f.locale
+}
+
+// Metadata describes the formatter's conventions
+func (f *Formatter) Metadata() Metadata {
+	position := "before"
+	if f.format.symbolAfter {
+		position = "after"
+	}
+	return Metadata{
+		Locale:           f.locale,
+		Currency:         f.currency,
+		CurrencySymbol:   f.symbol(),
+		CurrencyDecimals: f.decimals(),
+		SymbolPosition:   position,
+		DecimalSeparator: f.format.decimal,
+		GroupSeparator:   f.format.group,
+		Units: Units{
+			Cost:     f.currency,
+			Tokens:   "tokens",
+			Duration: "minutes",
+		},
+	}
+}
+
+// Cost formats an amount with the currency symbol, such as "$1,234.50" or
+// "1.234,50 €"
+func (f *Formatter) Cost(amount float64) string {
+	number := f.Number(math.Abs(amount), f.decimals())
+	space := ""
+	if f.format.symbolSpacing || f.symbol() == f.currency {
+		space = "\u00a0"
+	}
+	var formatted string
+	if f.format.symbolAfter {
+		formatted = number + space + f.symbol()
+	} else {
+		formatted = f.symbol() + space + number
+	}
+	if amount < 0 && number != f.Number(0, f.decimals()) {
+		formatted = "-" + formatted
+	}
+	return formatted
+}
+
+// Tokens formats a token count with grouped thousands, such as "1,234,567"
+func (f *Formatter) Tokens(count int64) string {
+	return f.Number(float64(count), 0)
+}
+
+// Number formats a number with the given decimal places and the locale's
+// separators
+func (f *Formatter) Number(value float64, decimals int) string {
+	formatted := strconv.FormatFloat(math.Abs(value), 'f', decimals, 64)
+	whole, fraction, _ := strings.Cut(formatted, ".")
+
+	var grouped strings.Builder
+	for i, digit := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			grouped.WriteString(f.format.group)
+		}
+		grouped.WriteRune(digit)
+	}
+	result := grouped.String()
+	if fraction != "" {
+		result += f.format.decimal + fraction
+	}
+	if value < 0 && strings.Trim(formatted, "0.") != "" {
+		result = "-" + result
+	}
+	return result
+}
+
+// symbol returns the currency's symbol, or its code without one
+func (f *Formatter) symbol() string {
+	if symbol, ok := currencySymbols[f.currency]; ok {
+		return symbol
+	}
+	return f.currency
+}
+
+// decimals returns the decimal places costs are written with
+func (f *Formatter) decimals() int {
+	if decimals, ok := currencyDecimals[f.currency]; ok {
+		return decimals
+	}
+	return 2
+}
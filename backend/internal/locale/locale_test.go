@@ -0,0 +1,59 @@
+package locale
+
+import "testing"
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		acceptLanguage, fallback, expected string
+	}{
+		{"", "en-US", "en-US"},
+		{"fr-CH, fr;q=0.9, en;q=0.8", "en-US", "fr-FR"},
+		{"de-CH", "en-US", "de-CH"},
+		{"xx-YY, ja;q=0.5", "en-US", "ja-JP"},
+		{"en", "en-GB", "en-GB"},
+		{"en-AU", "en-GB", "en-AU"},
+		{"en;q=0.2, pt_br;q=0.8", "en-US", "pt-BR"},
+		{"de;q=0, *", "nl-NL", "nl-NL"},
+		{"xx", "unknown", DefaultLocale},
+	}
+	for _, test := range tests {
+		if got := Negotiate(test.acceptLanguage, test.fallback); got != test.expected {
+			t.Errorf("Negotiate(%q, %q) = %q, expected %q", test.acceptLanguage, test.fallback, got, test.expected)
+		}
+	}
+}
+
+func TestFormatter(t *testing.T) {
+	tests := []struct {
+		locale, currency string
+		cost             float64
+		tokens           int64
+		expectedCost     string
+		expectedTokens   string
+	}{
+		{"en-US", "", 1234.5, 1234567, "$1,234.50", "1,234,567"},
+		{"de-DE", "EUR", 1234.5, 1234567, "1.234,50\u00a0€", "1.234.567"},
+		{"fr-FR", "USD", 0.126, 999, "0,13\u00a0$", "999"},
+		{"en-GB", "CHF", 12, 1000, "CHF\u00a012.00", "1,000"},
+		{"ja-JP", "JPY", 1500.4, 0, "¥1,500", "0"},
+		{"en-US", "USD", -3.456, 0, "-$3.46", "0"},
+		{"en-US", "USD", -0.001, 0, "$0.00", "0"},
+	}
+	for _, test := range tests {
+		formatter := NewFormatter(test.locale, test.currency)
+		if got := formatter.Cost(test.cost); got != test.expectedCost {
+			t.Errorf("%s %s Cost(%v) = %q, expected %q", test.locale, test.currency, test.cost, got, test.expectedCost)
+		}
+		if got := formatter.Tokens(test.tokens); got != test.expectedTokens {
+			t.Errorf("%s Tokens(%d) = %q, expected %q", test.locale, test.tokens, got, test.expectedTokens)
+		}
+	}
+
+	meta := NewFormatter("de", "eur").Metadata()
+	if meta.Locale != "de-DE" || meta.Currency != "EUR" || meta.CurrencySymbol != "€" || meta.SymbolPosition != "after" || meta.DecimalSeparator != "," || meta.Units.Cost != "EUR" {
+		t.Errorf("Unexpected metadata: %+v", meta)
+	}
+	if got := NewFormatter("tlh", "").Locale(); got != DefaultLocale {
+		t.Errorf("Expected an unsupported locale to fall back to %s, got %s", DefaultLocale, got)
+	}
+}
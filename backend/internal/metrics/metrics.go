@@ -0,0 +1,226 @@
+// Package metrics exposes the session manager's own health and the usage it
+// has imported in the Prometheus text format: HTTP request latencies,
+// WebSocket clients, chat processes, import throughput, file watcher events, database query
+// timings and token and cost totals.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/claude-session-manager/internal/chat"
+	"github.com/ksred/claude-session-manager/internal/database"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/sirupsen/logrus"
+)
+
+// namespace prefixes every metric name
+const namespace = "claude_session_manager"
+
+// Metrics records the server's metrics in its own registry. It implements
+// database.Observer.
+type Metrics struct {
+	registry *prometheus.Registry
+	logger   *logrus.Logger
+
+	requestDuration *prometheus.HistogramVec
+	queryDuration   *prometheus.HistogramVec
+	importDuration  *prometheus.HistogramVec
+	importFiles     *prometheus.CounterVec
+	importSessions  *prometheus.CounterVec
+	importMessages  *prometheus.CounterVec
+	watcherEvents   *prometheus.CounterVec
+}
+
+// New creates metrics registered with Go runtime and process metrics
+func New(logger *logrus.Logger) *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		logger:   logger,
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latencies by method, route template and status code.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		queryDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "db_query_duration_seconds",
+			Help:      "Database query timings by operation (select, get, exec or transaction).",
+			Buckets:   []float64{.0005, .001, .005, .01, .025, .05, .1, .25, .5, 1, 5},
+		}, []string{"operation"}),
+		importDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "import_file_duration_seconds",
+			Help:      "Time to import a session file by source (import or watcher).",
+			Buckets:   []float64{.01, .05, .1, .5, 1, 5, 10, 30, 60},
+		}, []string{"source"}),
+		importFiles: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "import_files_total",
+			Help:      "Session files imported by source.",
+		}, []string{"source"}),
+		importSessions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "import_sessions_total",
+			Help:      "Sessions imported by source.",
+		}, []string{"source"}),
+		importMessages: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "import_messages_total",
+			Help:      "Messages imported by source.",
+		}, []string{"source"}),
+		watcherEvents: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "watcher_events_total",
+			Help:      "Session file changes seen by the file watcher by event (create, write or remove).",
+		}, []string{"event"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requestDuration,
+		m.queryDuration,
+		m.importDuration,
+		m.importFiles,
+		m.importSessions,
+		m.importMessages,
+		m.watcherEvents,
+	)
+	return m
+}
+
+// Handler serves the metrics in the Prometheus text format
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// Middleware times each request by its route template, so IDs don't create
+// a series each. Requests matching no route are recorded as "other".
+func (m *Metrics) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "other"
+		}
+		m.requestDuration.WithLabelValues(c.Request.Method, route, strconv.Itoa(c.Writer.Status())).
+			Observe(time.Since(start).Seconds())
+	}
+}
+
+// RegisterWebSocketClients reports the number of connected WebSocket clients
+// as returned by count
+func (m *Metrics) RegisterWebSocketClients(count func() int) {
+	m.registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "websocket_clients",
+		Help:      "Connected WebSocket clients.",
+	}, func() float64 {
+		return float64(count())
+	}))
+}
+
+// RegisterChatProcesses reports the live Claude CLI chat processes and how
+// many have been started and stopped, as returned by stats
+func (m *Metrics) RegisterChatProcesses(stats func() chat.ProcessStats) {
+	m.registry.MustRegister(&chatCollector{stats: stats})
+}
+
+// RegisterUsage reports the sessions, messages, tokens and cost in db, read
+// when metrics are scraped
+func (m *Metrics) RegisterUsage(db *database.Database) {
+	m.registry.MustRegister(&usageCollector{db: db, logger: m.logger})
+}
+
+// ObserveQuery records a database query timing
+func (m *Metrics) ObserveQuery(operation string, duration time.Duration) {
+	m.queryDuration.WithLabelValues(operation).Observe(duration.Seconds())
+}
+
+// ObserveImport records an imported session file
+func (m *Metrics) ObserveImport(source string, sessions, messages int, duration time.Duration) {
+	m.importFiles.WithLabelValues(source).Inc()
+	m.importSessions.WithLabelValues(source).Add(float64(sessions))
+	m.importMessages.WithLabelValues(source).Add(float64(messages))
+	m.importDuration.WithLabelValues(source).Observe(duration.Seconds())
+}
+
+// ObserveWatcherEvent records a file change seen by the watcher
+func (m *Metrics) ObserveWatcherEvent(event string) {
+	m.watcherEvents.WithLabelValues(event).Inc()
+}
+
+var (
+	sessionsDesc = prometheus.NewDesc(namespace+"_sessions", "Sessions in the database.", nil, nil)
+	activeDesc   = prometheus.NewDesc(namespace+"_active_sessions", "Active sessions.", nil, nil)
+	messagesDesc = prometheus.NewDesc(namespace+"_messages", "Messages in the database.", nil, nil)
+	tokensDesc   = prometheus.NewDesc(namespace+"_tokens_total", "Tokens used across all sessions.", nil, nil)
+	costDesc     = prometheus.NewDesc(namespace+"_cost_usd_total", "Estimated cost in USD across all sessions.", nil, nil)
+)
+
+// usageCollector reads the usage totals from the database on each scrape.
+// Token and cost totals only fall when sessions are pruned, which Prometheus
+// treats as a counter reset.
+type usageCollector struct {
+	db     *database.Database
+	logger *logrus.Logger
+}
+
+// Describe implements prometheus.Collector
+func (u *usageCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- sessionsDesc
+	ch <- activeDesc
+	ch <- messagesDesc
+	ch <- tokensDesc
+	ch <- costDesc
+}
+
+// Collect implements prometheus.Collector
+func (u *usageCollector) Collect(ch chan<- prometheus.Metric) {
+	stats, err := u.db.GetStats()
+	if err != nil {
+		u.logger.WithError(err).Warn("Failed to read usage totals for metrics")
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(sessionsDesc, prometheus.GaugeValue, float64(stats.TotalSessions))
+	ch <- prometheus.MustNewConstMetric(activeDesc, prometheus.GaugeValue, float64(stats.ActiveSessions))
+	ch <- prometheus.MustNewConstMetric(messagesDesc, prometheus.GaugeValue, float64(stats.TotalMessages))
+	ch <- prometheus.MustNewConstMetric(tokensDesc, prometheus.CounterValue, float64(stats.TotalTokens))
+	ch <- prometheus.MustNewConstMetric(costDesc, prometheus.CounterValue, stats.TotalEstimatedCost)
+}
+
+var (
+	chatProcessesDesc = prometheus.NewDesc(namespace+"_chat_processes", "Live Claude CLI chat processes.", nil, nil)
+	chatStartedDesc   = prometheus.NewDesc(namespace+"_chat_processes_started_total", "Claude CLI chat processes started.", nil, nil)
+	chatStoppedDesc   = prometheus.NewDesc(namespace+"_chat_processes_stopped_total", "Claude CLI chat processes stopped, by reason.", []string{"reason"}, nil)
+)
+
+// chatCollector reads the chat process counts on each scrape
+type chatCollector struct {
+	stats func() chat.ProcessStats
+}
+
+// Describe implements prometheus.Collector
+func (c *chatCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- chatProcessesDesc
+	ch <- chatStartedDesc
+	ch <- chatStoppedDesc
+}
+
+// Collect implements prometheus.Collector
+func (c *chatCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.stats()
+	ch <- prometheus.MustNewConstMetric(chatProcessesDesc, prometheus.GaugeValue, float64(stats.Live))
+	ch <- prometheus.MustNewConstMetric(chatStartedDesc, prometheus.CounterValue, float64(stats.Started))
+	for _, reason := range []string{chat.StopReasonEnded, chat.StopReasonInactive, chat.StopReasonShutdown} {
+		ch <- prometheus.MustNewConstMetric(chatStoppedDesc, prometheus.CounterValue, float64(stats.Stopped[reason]), reason)
+	}
+}
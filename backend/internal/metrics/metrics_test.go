@@ -0,0 +1,87 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/claude-session-manager/internal/chat"
+	"github.com/ksred/claude-session-manager/internal/database"
+	"github.com/sirupsen/logrus"
+)
+
+func TestMetrics(t *testing.T) {
+	tmpFile, err := os.CreateTemp("", "test-metrics-*.db")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	tmpFile.Close()
+	defer os.Remove(tmpFile.Name())
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.WarnLevel)
+	db, err := database.NewDatabase(database.Config{DatabasePath: tmpFile.Name(), Logger: logger})
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer db.Close()
+
+	m := New(logger)
+	m.RegisterUsage(db)
+	m.RegisterWebSocketClients(func() int { return 3 })
+	m.RegisterChatProcesses(func() chat.ProcessStats {
+		return chat.ProcessStats{Live: 2, Started: 5, Stopped: map[string]int64{chat.StopReasonInactive: 3}}
+	})
+	db.SetObserver(m)
+
+	repo := database.NewSessionRepository(db, logger)
+	now := time.Now()
+	if err := repo.UpsertSession(&database.Session{ID: "s1", ProjectPath: "/work/app", ProjectName: "app", StartTime: now, LastActivity: now, Status: "completed"}); err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	if err := repo.UpsertMessage(&database.Message{ID: "m1", SessionID: "s1", Role: "assistant", Content: `"done"`, Timestamp: now}); err != nil {
+		t.Fatalf("Failed to create message: %v", err)
+	}
+	if err := repo.UpsertTokenUsage(&database.TokenUsage{MessageID: "m1", SessionID: "s1", TotalTokens: 1500, EstimatedCost: 0.25}); err != nil {
+		t.Fatalf("Failed to create token usage: %v", err)
+	}
+	m.ObserveImport("watcher", 1, 12, 40*time.Millisecond)
+	m.ObserveWatcherEvent("write")
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(m.Middleware())
+	router.GET("/api/v1/sessions/:id", func(c *gin.Context) { c.Status(http.StatusNotFound) })
+	router.GET("/metrics", gin.WrapH(m.Handler()))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/v1/sessions/abc", nil))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", w.Code)
+	}
+	body := w.Body.String()
+
+	for _, want := range []string{
+		`claude_session_manager_http_request_duration_seconds_count{method="GET",route="/api/v1/sessions/:id",status="404"} 1`,
+		`claude_session_manager_websocket_clients 3`,
+		`claude_session_manager_chat_processes 2`,
+		`claude_session_manager_chat_processes_started_total 5`,
+		`claude_session_manager_chat_processes_stopped_total{reason="inactive"} 3`,
+		`claude_session_manager_chat_processes_stopped_total{reason="shutdown"} 0`,
+		`claude_session_manager_import_messages_total{source="watcher"} 12`,
+		`claude_session_manager_watcher_events_total{event="write"} 1`,
+		`claude_session_manager_db_query_duration_seconds_count{operation="transaction"}`,
+		`claude_session_manager_sessions 1`,
+		`claude_session_manager_tokens_total 1500`,
+		`claude_session_manager_cost_usd_total 0.25`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected metrics to contain %q", want)
+		}
+	}
+}
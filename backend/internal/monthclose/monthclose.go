@@ -0,0 +1,203 @@
+// Package monthclose freezes each month's usage aggregates once the month
+// ends, so re-imports and cost recalculations cannot change numbers that
+// have already been reported to finance.
+package monthclose
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ksred/claude-session-manager/internal/costcenter"
+	"github.com/ksred/claude-session-manager/internal/database"
+	"github.com/sirupsen/logrus"
+)
+
+// GracePeriod is how long after a month ends it is closed automatically,
+// leaving time for sessions that ran over midnight to be imported
+const GracePeriod = 6 * time.Hour
+
+var (
+	// ErrMonthOpen is returned when closing a month that has not ended
+	ErrMonthOpen = errors.New("month has not ended")
+	// ErrAlreadyClosed is returned when closing a month that has a snapshot
+	ErrAlreadyClosed = errors.New("month is already closed")
+)
+
+// Data is the full set of aggregates frozen in a snapshot
+type Data struct {
+	Month       string                `json:"month"`
+	From        time.Time             `json:"from"`
+	To          time.Time             `json:"to"`
+	Currency    string                `json:"currency"`
+	Usage       *database.PeriodUsage `json:"usage"`
+	CostCenters *costcenter.Report    `json:"cost_centers"`
+}
+
+// Bounds returns the start and exclusive end of a YYYY-MM month in UTC
+func Bounds(month string) (time.Time, time.Time, error) {
+	start, err := time.Parse("2006-01", month)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("month must be YYYY-MM")
+	}
+	return start, start.AddDate(0, 1, 0), nil
+}
+
+// Checksum returns the SHA-256 of a snapshot's data
+func Checksum(data string) string {
+	sum := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(sum[:])
+}
+
+// Verify reports whether a snapshot's data still matches its checksum
+func Verify(snapshot *database.MonthlySnapshot) bool {
+	return Checksum(snapshot.Data) == snapshot.Checksum
+}
+
+// Decode returns the aggregates frozen in a snapshot
+func Decode(snapshot *database.MonthlySnapshot) (*Data, error) {
+	var data Data
+	if err := json.Unmarshal([]byte(snapshot.Data), &data); err != nil {
+		return nil, fmt.Errorf("failed to decode snapshot %s: %w", snapshot.Month, err)
+	}
+	return &data, nil
+}
+
+// Closer takes the snapshot of each month once it has ended
+type Closer struct {
+	repo      *database.SessionRepository
+	allocator *costcenter.Allocator
+	currency  string
+	logger    *logrus.Logger
+	now       func() time.Time
+	mu        sync.Mutex
+}
+
+// NewCloser creates a closer that allocates each month to the configured
+// cost centers
+func NewCloser(repo *database.SessionRepository, allocator *costcenter.Allocator, currency string, logger *logrus.Logger) *Closer {
+	return &Closer{
+		repo:      repo,
+		allocator: allocator,
+		currency:  currency,
+		logger:    logger,
+		now:       time.Now,
+	}
+}
+
+// CloseMonth snapshots a month that has ended. A month is only ever closed
+// once; closing it again returns ErrAlreadyClosed.
+func (c *Closer) CloseMonth(month string) (*database.MonthlySnapshot, error) {
+	from, to, err := Bounds(month)
+	if err != nil {
+		return nil, err
+	}
+	if c.now().Before(to) {
+		return nil, ErrMonthOpen
+	}
+
+	// The watcher-driven refresh and a manual close would otherwise race to
+	// take the same snapshot
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, err := c.repo.GetMonthlySnapshot(month); err == nil {
+		return nil, ErrAlreadyClosed
+	} else if !strings.Contains(err.Error(), "not found") {
+		return nil, err
+	}
+
+	return c.close(month, from, to)
+}
+
+// ClosePending snapshots every month since the first imported message that
+// ended more than GracePeriod ago and has not been closed, and returns how
+// many were closed
+func (c *Closer) ClosePending() (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	first, err := c.repo.GetFirstMessageTime()
+	if err != nil || first == nil {
+		return 0, err
+	}
+
+	snapshots, err := c.repo.GetMonthlySnapshots()
+	if err != nil {
+		return 0, err
+	}
+	closed := make(map[string]bool, len(snapshots))
+	for _, snapshot := range snapshots {
+		closed[snapshot.Month] = true
+	}
+
+	count := 0
+	now := c.now()
+	start := first.UTC()
+	for from := time.Date(start.Year(), start.Month(), 1, 0, 0, 0, 0, time.UTC); ; from = from.AddDate(0, 1, 0) {
+		to := from.AddDate(0, 1, 0)
+		if now.Before(to.Add(GracePeriod)) {
+			break
+		}
+		month := from.Format("2006-01")
+		if closed[month] {
+			continue
+		}
+		if _, err := c.close(month, from, to); err != nil {
+			return count, err
+		}
+		c.logger.WithField("month", month).Info("Closed month")
+		count++
+	}
+	return count, nil
+}
+
+func (c *Closer) close(month string, from, to time.Time) (*database.MonthlySnapshot, error) {
+	usage, err := c.repo.GetPeriodUsage(from, to)
+	if err != nil {
+		return nil, err
+	}
+	costs, err := c.repo.GetSessionCosts(from, to)
+	if err != nil {
+		return nil, err
+	}
+	tags, err := c.repo.GetSessionTagMap()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(&Data{
+		Month:       month,
+		From:        from,
+		To:          to,
+		Currency:    c.currency,
+		Usage:       usage,
+		CostCenters: c.allocator.Allocate(costs, tags, from, to, c.currency),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+
+	snapshot := &database.MonthlySnapshot{
+		Month:       month,
+		PeriodStart: from,
+		PeriodEnd:   to,
+		Sessions:    usage.Sessions,
+		Messages:    usage.Messages,
+		Tokens:      usage.Tokens,
+		CostUSD:     usage.CostUSD,
+		Currency:    c.currency,
+		Data:        string(data),
+		Checksum:    Checksum(string(data)),
+		ClosedAt:    c.now().UTC(),
+	}
+	if err := c.repo.SaveMonthlySnapshot(snapshot); err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
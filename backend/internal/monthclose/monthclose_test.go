@@ -0,0 +1,122 @@
+package monthclose
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ksred/claude-session-manager/internal/config"
+	"github.com/ksred/claude-session-manager/internal/costcenter"
+	"github.com/ksred/claude-session-manager/internal/database"
+	"github.com/sirupsen/logrus"
+)
+
+func setupTestRepo(t *testing.T) *database.SessionRepository {
+	tmpFile, err := os.CreateTemp("", "test-monthclose-*.db")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	tmpFile.Close()
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.WarnLevel)
+	db, err := database.NewDatabase(database.Config{DatabasePath: tmpFile.Name(), Logger: logger})
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	t.Cleanup(func() {
+		db.Close()
+		os.Remove(tmpFile.Name())
+	})
+
+	return database.NewSessionRepository(db, logger)
+}
+
+func TestCloser(t *testing.T) {
+	repo := setupTestRepo(t)
+	logger := logrus.New()
+	logger.SetLevel(logrus.WarnLevel)
+
+	start := time.Date(2026, 8, 31, 12, 0, 0, 0, time.UTC)
+	if err := repo.UpsertSession(&database.Session{
+		ID:           "spanning",
+		ProjectPath:  "/home/me/payments",
+		ProjectName:  "payments",
+		StartTime:    start,
+		LastActivity: start.Add(36 * time.Hour),
+		Status:       "completed",
+	}); err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	// One message in August and two in September
+	for i, ts := range []time.Time{start, start.Add(24 * time.Hour), start.Add(36 * time.Hour)} {
+		id := string(rune('a' + i))
+		if err := repo.UpsertMessage(&database.Message{ID: id, SessionID: "spanning", Role: "assistant", Timestamp: ts}); err != nil {
+			t.Fatalf("Failed to create message: %v", err)
+		}
+		if err := repo.UpsertTokenUsage(&database.TokenUsage{MessageID: id, SessionID: "spanning", TotalTokens: 100, EstimatedCost: 0.5}); err != nil {
+			t.Fatalf("Failed to create token usage: %v", err)
+		}
+	}
+
+	allocator := costcenter.NewAllocator(config.CostCentersConfig{
+		Centers: []config.CostCenter{{Name: "payments", Code: "CC-100", Projects: []string{"payments"}}},
+	})
+	closer := NewCloser(repo, allocator, "USD", logger)
+
+	// Early on October 1st only August has passed the grace period
+	closer.now = func() time.Time { return time.Date(2026, 10, 1, 3, 0, 0, 0, time.UTC) }
+	closed, err := closer.ClosePending()
+	if err != nil || closed != 1 {
+		t.Fatalf("Expected 1 month closed, got %d (%v)", closed, err)
+	}
+	august, err := repo.GetMonthlySnapshot("2026-08")
+	if err != nil {
+		t.Fatalf("Failed to get snapshot: %v", err)
+	}
+	if august.Messages != 1 || august.Tokens != 100 || august.CostUSD != 0.5 || !Verify(august) {
+		t.Errorf("Unexpected August snapshot: %+v", august)
+	}
+
+	if _, err := closer.CloseMonth("2026-10"); !errors.Is(err, ErrMonthOpen) {
+		t.Errorf("Expected open month to be refused, got %v", err)
+	}
+	september, err := closer.CloseMonth("2026-09")
+	if err != nil {
+		t.Fatalf("Failed to close September: %v", err)
+	}
+	if _, err := closer.CloseMonth("2026-09"); !errors.Is(err, ErrAlreadyClosed) {
+		t.Errorf("Expected second close to be refused, got %v", err)
+	}
+
+	data, err := Decode(september)
+	if err != nil {
+		t.Fatalf("Failed to decode snapshot: %v", err)
+	}
+	if data.Usage.Messages != 2 || len(data.Usage.ByProject) != 1 || data.Usage.ByProject[0].Key != "payments" {
+		t.Errorf("Unexpected September usage: %+v", data.Usage)
+	}
+	if len(data.CostCenters.CostCenters) == 0 || data.CostCenters.CostCenters[0].Name != "payments" || data.CostCenters.CostCenters[0].CostUSD != 1.0 {
+		t.Errorf("Unexpected September cost centers: %+v", data.CostCenters.CostCenters)
+	}
+
+	// Recalculated costs do not change a closed month
+	if _, err := repo.GetDB().Exec(`UPDATE token_usage SET estimated_cost = estimated_cost * 10`); err != nil {
+		t.Fatalf("Failed to recalculate costs: %v", err)
+	}
+	if again, _ := repo.GetMonthlySnapshot("2026-09"); again.CostUSD != 1.0 || again.Checksum != september.Checksum {
+		t.Errorf("Expected closed month to be unchanged, got %+v", again)
+	}
+
+	// Snapshots cannot be edited or removed
+	if _, err := repo.GetDB().Exec(`UPDATE monthly_snapshots SET cost_usd = 0`); err == nil {
+		t.Error("Expected snapshot update to be refused")
+	}
+	if _, err := repo.GetDB().Exec(`DELETE FROM monthly_snapshots`); err == nil {
+		t.Error("Expected snapshot delete to be refused")
+	}
+	if snapshots, _ := repo.GetMonthlySnapshots(); len(snapshots) != 2 || snapshots[0].Month != "2026-09" {
+		t.Errorf("Expected 2 snapshots, most recent first, got %+v", snapshots)
+	}
+}
@@ -0,0 +1,256 @@
+// Package mqtt publishes the active session count, today's cost and budget
+// status to an MQTT broker for home dashboards, with Home Assistant discovery
+// so the sensors appear on their own. It speaks just enough MQTT 3.1.1 to
+// connect and publish at QoS 0.
+package mqtt
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Control packet types, shifted into the high nibble of the first byte
+const (
+	packetConnect    = 1 << 4
+	packetConnack    = 2 << 4
+	packetPublish    = 3 << 4
+	packetDisconnect = 14 << 4
+)
+
+// Connect flags
+const (
+	flagCleanSession = 0x02
+	flagPassword     = 0x40
+	flagUsername     = 0x80
+)
+
+// writeTimeout bounds each write to the broker
+const writeTimeout = 10 * time.Second
+
+// connackErrors describe the CONNACK return codes that refuse a connection
+var connackErrors = map[byte]string{
+	1: "unacceptable protocol version",
+	2: "client identifier rejected",
+	3: "server unavailable",
+	4: "bad user name or password",
+	5: "not authorized",
+}
+
+// Options are how a client connects to its broker
+type Options struct {
+	Broker    string // host:port, tcp://host:port, or ssl://, tls:// or mqtts:// for TLS
+	ClientID  string
+	Username  string
+	Password  string
+	KeepAlive time.Duration // how long the broker waits for a packet before dropping the client; 0 never
+}
+
+// Client is a connection to a broker that publishes at QoS 0
+type Client struct {
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+// Dial connects to the broker and waits for it to accept the connection
+func Dial(ctx context.Context, opts Options) (*Client, error) {
+	network, address, useTLS := parseBroker(opts.Broker)
+
+	var conn net.Conn
+	var err error
+	if useTLS {
+		dialer := &tls.Dialer{Config: &tls.Config{MinVersion: tls.VersionTLS12}}
+		conn, err = dialer.DialContext(ctx, network, address)
+	} else {
+		var dialer net.Dialer
+		conn, err = dialer.DialContext(ctx, network, address)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to mqtt broker: %w", err)
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	} else {
+		conn.SetDeadline(time.Now().Add(writeTimeout))
+	}
+	if _, err := conn.Write(connectPacket(opts)); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to send mqtt connect: %w", err)
+	}
+	packetType, body, err := readPacket(conn)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to read mqtt connack: %w", err)
+	}
+	if packetType != packetConnack || len(body) != 2 {
+		conn.Close()
+		return nil, fmt.Errorf("unexpected mqtt packet %#x waiting for connack", packetType)
+	}
+	if code := body[1]; code != 0 {
+		conn.Close()
+		if reason, ok := connackErrors[code]; ok {
+			return nil, fmt.Errorf("mqtt broker refused connection: %s", reason)
+		}
+		return nil, fmt.Errorf("mqtt broker refused connection: code %d", code)
+	}
+	conn.SetDeadline(time.Time{})
+
+	// Nothing is subscribed, so the broker only sends ping responses, which
+	// are drained so they can't fill the socket's buffer
+	go io.Copy(io.Discard, conn)
+
+	return &Client{conn: conn}, nil
+}
+
+// Publish sends payload to topic at QoS 0. Retained messages are kept by the
+// broker and sent to anyone who subscribes later.
+func (c *Client) Publish(topic string, payload []byte, retain bool) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn == nil {
+		return errors.New("mqtt client is closed")
+	}
+	c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	if _, err := c.conn.Write(publishPacket(topic, payload, retain)); err != nil {
+		return fmt.Errorf("failed to publish to %s: %w", topic, err)
+	}
+	return nil
+}
+
+// Close disconnects cleanly from the broker and closes the connection
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn == nil {
+		return nil
+	}
+	c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	c.conn.Write([]byte{packetDisconnect, 0})
+	err := c.conn.Close()
+	c.conn = nil
+	return err
+}
+
+// parseBroker returns the network and address to dial for broker and whether
+// it uses TLS, defaulting to port 1883, or 8883 for TLS
+func parseBroker(broker string) (string, string, bool) {
+	useTLS := false
+	if scheme, rest, ok := strings.Cut(broker, "://"); ok {
+		broker = rest
+		switch scheme {
+		case "ssl", "tls", "mqtts":
+			useTLS = true
+		}
+	}
+	if _, _, err := net.SplitHostPort(broker); err != nil {
+		port := "1883"
+		if useTLS {
+			port = "8883"
+		}
+		broker = net.JoinHostPort(broker, port)
+	}
+	return "tcp", broker, useTLS
+}
+
+// connectPacket returns the CONNECT packet for opts, always starting a clean
+// session
+func connectPacket(opts Options) []byte {
+	flags := byte(flagCleanSession)
+	if opts.Username != "" {
+		flags |= flagUsername
+		if opts.Password != "" {
+			flags |= flagPassword
+		}
+	}
+	keepAlive := opts.KeepAlive / time.Second
+	if keepAlive > 65535 {
+		keepAlive = 65535
+	}
+
+	var body []byte
+	body = appendString(body, "MQTT")
+	body = append(body, 4, flags) // protocol level 4 is MQTT 3.1.1
+	body = binary.BigEndian.AppendUint16(body, uint16(keepAlive))
+	body = appendString(body, opts.ClientID)
+	if flags&flagUsername != 0 {
+		body = appendString(body, opts.Username)
+	}
+	if flags&flagPassword != 0 {
+		body = appendString(body, opts.Password)
+	}
+	return packet(packetConnect, body)
+}
+
+// publishPacket returns a QoS 0 PUBLISH packet
+func publishPacket(topic string, payload []byte, retain bool) []byte {
+	header := byte(packetPublish)
+	if retain {
+		header |= 0x01
+	}
+	body := appendString(nil, topic)
+	body = append(body, payload...)
+	return packet(header, body)
+}
+
+// packet prefixes body with the fixed header and its remaining length
+func packet(header byte, body []byte) []byte {
+	out := []byte{header}
+	length := len(body)
+	for {
+		digit := byte(length % 128)
+		length /= 128
+		if length > 0 {
+			digit |= 0x80
+		}
+		out = append(out, digit)
+		if length == 0 {
+			break
+		}
+	}
+	return append(out, body...)
+}
+
+// appendString appends s as a length-prefixed UTF-8 string
+func appendString(b []byte, s string) []byte {
+	b = binary.BigEndian.AppendUint16(b, uint16(len(s)))
+	return append(b, s...)
+}
+
+// readPacket reads one packet, returning its type, with the flags masked
+// off, and its body
+func readPacket(r io.Reader) (byte, []byte, error) {
+	var header [1]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return 0, nil, err
+	}
+
+	length, multiplier := 0, 1
+	for i := 0; ; i++ {
+		if i == 4 {
+			return 0, nil, errors.New("malformed mqtt remaining length")
+		}
+		var digit [1]byte
+		if _, err := io.ReadFull(r, digit[:]); err != nil {
+			return 0, nil, err
+		}
+		length += int(digit[0]&0x7f) * multiplier
+		if digit[0]&0x80 == 0 {
+			break
+		}
+		multiplier *= 128
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return 0, nil, err
+	}
+	return header[0] & 0xf0, body, nil
+}
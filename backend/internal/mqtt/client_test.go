@@ -0,0 +1,120 @@
+package mqtt
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeBroker accepts one client, answers its CONNECT with code and sends
+// every later packet to the returned channel
+func fakeBroker(t *testing.T, code byte) (string, <-chan []byte, <-chan []byte) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	connects := make(chan []byte, 1)
+	packets := make(chan []byte, 16)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		defer close(packets)
+
+		packetType, body, err := readPacket(conn)
+		if err != nil || packetType != packetConnect {
+			return
+		}
+		connects <- body
+		conn.Write([]byte{packetConnack, 2, 0, code})
+
+		for {
+			packetType, body, err := readPacket(conn)
+			if err != nil {
+				return
+			}
+			packets <- append([]byte{packetType}, body...)
+		}
+	}()
+	return listener.Addr().String(), connects, packets
+}
+
+func TestParseBroker(t *testing.T) {
+	tests := []struct {
+		broker  string
+		address string
+		tls     bool
+	}{
+		{"localhost", "localhost:1883", false},
+		{"tcp://broker.lan:1884", "broker.lan:1884", false},
+		{"mqtts://broker.example.com", "broker.example.com:8883", true},
+		{"ssl://10.0.0.2:8884", "10.0.0.2:8884", true},
+	}
+	for _, tt := range tests {
+		if _, address, useTLS := parseBroker(tt.broker); address != tt.address || useTLS != tt.tls {
+			t.Errorf("parseBroker(%q) = %s, %v, want %s, %v", tt.broker, address, useTLS, tt.address, tt.tls)
+		}
+	}
+}
+
+func TestPacket_RemainingLength(t *testing.T) {
+	for _, size := range []int{0, 127, 128, 16383, 16384, 300000} {
+		encoded := packet(packetPublish, make([]byte, size))
+		packetType, body, err := readPacket(bytes.NewReader(encoded))
+		if err != nil || packetType != packetPublish || len(body) != size {
+			t.Errorf("Expected a %d byte body to round trip, got %d bytes, err %v", size, len(body), err)
+		}
+	}
+}
+
+func TestClient_Publish(t *testing.T) {
+	address, connects, packets := fakeBroker(t, 0)
+
+	client, err := Dial(context.Background(), Options{Broker: address, ClientID: "csm", Username: "home", Password: "secret", KeepAlive: 3 * time.Minute})
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+
+	connect := <-connects
+	flags, keepAlive := connect[7], binary.BigEndian.Uint16(connect[8:10])
+	if string(connect[2:6]) != "MQTT" || connect[6] != 4 || flags != flagCleanSession|flagUsername|flagPassword || keepAlive != 180 {
+		t.Errorf("Unexpected CONNECT header %v", connect[:10])
+	}
+	if !strings.HasSuffix(string(connect), "\x00\x03csm\x00\x04home\x00\x06secret") {
+		t.Errorf("Expected the client ID and credentials in the CONNECT payload, got %q", connect[10:])
+	}
+
+	if err := client.Publish("csm/active_sessions", []byte("3"), true); err != nil {
+		t.Fatalf("Failed to publish: %v", err)
+	}
+	published := <-packets
+	if published[0] != packetPublish || string(published[1:]) != "\x00\x13csm/active_sessions3" {
+		t.Errorf("Unexpected PUBLISH %q", published)
+	}
+
+	client.Close()
+	if disconnect := <-packets; disconnect[0] != packetDisconnect {
+		t.Errorf("Expected a DISCONNECT, got %v", disconnect)
+	}
+	if err := client.Publish("csm/active_sessions", []byte("3"), true); err == nil {
+		t.Error("Expected publishing on a closed client to fail")
+	}
+}
+
+func TestDial_Refused(t *testing.T) {
+	address, _, _ := fakeBroker(t, 4)
+
+	_, err := Dial(context.Background(), Options{Broker: address, ClientID: "csm"})
+	if err == nil || !strings.Contains(err.Error(), "bad user name or password") {
+		t.Errorf("Expected the refusal reason, got %v", err)
+	}
+}
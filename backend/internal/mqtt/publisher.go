@@ -0,0 +1,225 @@
+package mqtt
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/ksred/claude-session-manager/internal/budget"
+	"github.com/ksred/claude-session-manager/internal/config"
+	"github.com/ksred/claude-session-manager/internal/database"
+	"github.com/sirupsen/logrus"
+)
+
+// StateNone is the budget state published when no budgets are set
+const StateNone = "none"
+
+// Message is a payload to publish to a topic
+type Message struct {
+	Topic   string
+	Payload []byte
+}
+
+// BudgetState is one budget's spend in its current period
+type BudgetState struct {
+	Name        string  `json:"name"`
+	ProjectName *string `json:"project_name,omitempty"`
+	Period      string  `json:"period"`
+	State       string  `json:"state"`
+	Percent     float64 `json:"percent"`
+	SpendUSD    float64 `json:"spend_usd"`
+	LimitUSD    float64 `json:"limit_usd"`
+}
+
+// BudgetSummary is published to the budget topic. State is the worst state
+// of any budget.
+type BudgetSummary struct {
+	State   string        `json:"state"`
+	Budgets []BudgetState `json:"budgets"`
+}
+
+// Publisher publishes the current figures every interval
+type Publisher struct {
+	cfg     config.MQTTConfig
+	repo    *database.SessionRepository
+	budgets *budget.Monitor
+	logger  *logrus.Logger
+	now     func() time.Time
+
+	client *Client
+}
+
+// NewPublisher creates a publisher for the figures in repo and the budgets
+// checked by budgets. Callers check cfg.Enabled first.
+func NewPublisher(cfg config.MQTTConfig, repo *database.SessionRepository, budgets *budget.Monitor, logger *logrus.Logger) *Publisher {
+	return &Publisher{
+		cfg:     cfg,
+		repo:    repo,
+		budgets: budgets,
+		logger:  logger,
+		now:     time.Now,
+	}
+}
+
+// Messages returns the current figures: the active session count, today's
+// cost in USD since local midnight and the budget summary
+func (p *Publisher) Messages() ([]Message, error) {
+	active, err := p.repo.GetActiveSessionsCount(false)
+	if err != nil {
+		return nil, err
+	}
+
+	now := p.now()
+	dayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	today, err := p.repo.GetUsageBetween(dayStart, now)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses, err := p.budgets.Status()
+	if err != nil {
+		return nil, err
+	}
+	summary, err := json.Marshal(Summarize(statuses))
+	if err != nil {
+		return nil, err
+	}
+
+	return []Message{
+		{Topic: p.cfg.ActiveSessionsTopic, Payload: []byte(strconv.Itoa(active))},
+		{Topic: p.cfg.CostTodayTopic, Payload: []byte(strconv.FormatFloat(math.Round(today.CostUSD*100)/100, 'f', 2, 64))},
+		{Topic: p.cfg.BudgetTopic, Payload: summary},
+	}, nil
+}
+
+// Summarize returns the budget summary of statuses
+func Summarize(statuses []budget.Status) BudgetSummary {
+	summary := BudgetSummary{State: StateNone, Budgets: []BudgetState{}}
+	rank := map[string]int{StateNone: 0, budget.StateOK: 1, budget.StateWarning: 2, budget.StateExceeded: 3}
+	for _, status := range statuses {
+		summary.Budgets = append(summary.Budgets, BudgetState{
+			Name:        status.Budget.Name,
+			ProjectName: status.Budget.ProjectName,
+			Period:      status.Budget.Period,
+			State:       status.State,
+			Percent:     status.Percent,
+			SpendUSD:    status.SpendUSD,
+			LimitUSD:    status.Budget.LimitUSD,
+		})
+		if rank[status.State] > rank[summary.State] {
+			summary.State = status.State
+		}
+	}
+	return summary
+}
+
+// DiscoveryMessages returns the Home Assistant discovery configs for the
+// three sensors, or nothing when discovery is off
+func (p *Publisher) DiscoveryMessages() []Message {
+	if p.cfg.DiscoveryPrefix == "" {
+		return nil
+	}
+
+	device := map[string]interface{}{
+		"identifiers": []string{p.cfg.ClientID},
+		"name":        "Claude Session Manager",
+	}
+	sensors := []struct {
+		object string
+		config map[string]interface{}
+	}{
+		{"active_sessions", map[string]interface{}{
+			"name":        "Active sessions",
+			"state_topic": p.cfg.ActiveSessionsTopic,
+			"state_class": "measurement",
+			"icon":        "mdi:console",
+		}},
+		{"cost_today", map[string]interface{}{
+			"name":                "Cost today",
+			"state_topic":         p.cfg.CostTodayTopic,
+			"device_class":        "monetary",
+			"unit_of_measurement": "USD",
+			"icon":                "mdi:cash",
+		}},
+		{"budget", map[string]interface{}{
+			"name":                  "Budget status",
+			"state_topic":           p.cfg.BudgetTopic,
+			"value_template":        "{{ value_json.state }}",
+			"json_attributes_topic": p.cfg.BudgetTopic,
+			"icon":                  "mdi:wallet",
+		}},
+	}
+
+	messages := make([]Message, 0, len(sensors))
+	for _, sensor := range sensors {
+		sensor.config["unique_id"] = p.cfg.ClientID + "_" + sensor.object
+		sensor.config["device"] = device
+		payload, _ := json.Marshal(sensor.config)
+		messages = append(messages, Message{
+			Topic:   p.cfg.DiscoveryPrefix + "/sensor/" + p.cfg.ClientID + "/" + sensor.object + "/config",
+			Payload: payload,
+		})
+	}
+	return messages
+}
+
+// Publish publishes the current figures, connecting first if needed and
+// sending the discovery configs on each new connection. A failed publish
+// drops the connection so the next one reconnects.
+func (p *Publisher) Publish(ctx context.Context) error {
+	messages, err := p.Messages()
+	if err != nil {
+		return err
+	}
+
+	if p.client == nil {
+		dialCtx, cancel := context.WithTimeout(ctx, writeTimeout)
+		client, err := Dial(dialCtx, Options{
+			Broker:    p.cfg.Broker,
+			ClientID:  p.cfg.ClientID,
+			Username:  p.cfg.Username,
+			Password:  p.cfg.Password,
+			KeepAlive: 3 * time.Duration(p.cfg.Interval) * time.Second,
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+		p.client = client
+		messages = append(p.DiscoveryMessages(), messages...)
+	}
+
+	for _, message := range messages {
+		if err := p.client.Publish(message.Topic, message.Payload, true); err != nil {
+			p.client.Close()
+			p.client = nil
+			return err
+		}
+	}
+	return nil
+}
+
+// Start publishes now and every interval until ctx is cancelled, then
+// disconnects
+func (p *Publisher) Start(ctx context.Context) {
+	ticker := time.NewTicker(time.Duration(p.cfg.Interval) * time.Second)
+	defer ticker.Stop()
+	defer func() {
+		if p.client != nil {
+			p.client.Close()
+		}
+	}()
+
+	for {
+		if err := p.Publish(ctx); err != nil && ctx.Err() == nil {
+			p.logger.WithError(err).Warn("Failed to publish to MQTT")
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package mqtt
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ksred/claude-session-manager/internal/budget"
+	"github.com/ksred/claude-session-manager/internal/config"
+	"github.com/ksred/claude-session-manager/internal/database"
+	"github.com/sirupsen/logrus"
+)
+
+func setupTestRepo(t *testing.T) *database.SessionRepository {
+	tmpFile, err := os.CreateTemp("", "test-mqtt-*.db")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	tmpFile.Close()
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	db, err := database.NewDatabase(database.Config{DatabasePath: tmpFile.Name(), Logger: logger})
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	t.Cleanup(func() {
+		db.Close()
+		os.Remove(tmpFile.Name())
+	})
+
+	return database.NewSessionRepository(db, logger)
+}
+
+func TestSummarize(t *testing.T) {
+	if summary := Summarize(nil); summary.State != StateNone || len(summary.Budgets) != 0 {
+		t.Errorf("Expected no budgets, got %+v", summary)
+	}
+
+	summary := Summarize([]budget.Status{
+		{Budget: database.Budget{Name: "daily"}, State: budget.StateOK},
+		{Budget: database.Budget{Name: "monthly"}, State: budget.StateWarning},
+	})
+	if summary.State != budget.StateWarning || len(summary.Budgets) != 2 {
+		t.Errorf("Expected the worst state, got %+v", summary)
+	}
+}
+
+func TestPublisher_Publish(t *testing.T) {
+	repo := setupTestRepo(t)
+	logger := logrus.New()
+	now := time.Now()
+
+	if err := repo.UpsertSession(&database.Session{ID: "s1", ProjectPath: "/work/app", ProjectName: "app", StartTime: now, LastActivity: now, IsActive: true, Status: "active"}); err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	if err := repo.UpsertMessage(&database.Message{ID: "m1", SessionID: "s1", Role: "assistant", Timestamp: now}); err != nil {
+		t.Fatalf("Failed to create message: %v", err)
+	}
+	if err := repo.UpsertTokenUsage(&database.TokenUsage{MessageID: "m1", SessionID: "s1", TotalTokens: 100, EstimatedCost: 1.234}); err != nil {
+		t.Fatalf("Failed to create token usage: %v", err)
+	}
+	if err := repo.CreateBudget(&database.Budget{Name: "daily", Period: database.BudgetDaily, LimitUSD: 1}); err != nil {
+		t.Fatalf("Failed to create budget: %v", err)
+	}
+
+	address, _, packets := fakeBroker(t, 0)
+	cfg := config.DefaultConfig().MQTT
+	cfg.Enabled = true
+	cfg.Broker = address
+	publisher := NewPublisher(cfg, repo, budget.NewMonitor(repo, logger), logger)
+
+	if err := publisher.Publish(context.Background()); err != nil {
+		t.Fatalf("Failed to publish: %v", err)
+	}
+
+	published := make(map[string]string)
+	for i := 0; i < 6; i++ {
+		body := <-packets
+		topicLength := int(body[1])<<8 | int(body[2])
+		published[string(body[3:3+topicLength])] = string(body[3+topicLength:])
+	}
+
+	if published[cfg.ActiveSessionsTopic] != "1" || published[cfg.CostTodayTopic] != "1.23" {
+		t.Errorf("Expected 1 active session costing 1.23, got %v", published)
+	}
+	var summary BudgetSummary
+	if err := json.Unmarshal([]byte(published[cfg.BudgetTopic]), &summary); err != nil || summary.State != budget.StateExceeded {
+		t.Errorf("Expected the budget to be exceeded, got %s", published[cfg.BudgetTopic])
+	}
+	discovery := published["homeassistant/sensor/claude-session-manager/budget/config"]
+	if !strings.Contains(discovery, `"value_template":"{{ value_json.state }}"`) {
+		t.Errorf("Expected a discovery config for the budget sensor, got %v", published)
+	}
+
+	// Discovery configs are only sent when connecting
+	if err := publisher.Publish(context.Background()); err != nil {
+		t.Fatalf("Failed to publish: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		<-packets
+	}
+	select {
+	case body := <-packets:
+		t.Errorf("Expected only the figures, got %q", body)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -0,0 +1,414 @@
+// Package notifications posts high-signal events to Slack and Discord
+// incoming webhooks: a session left idle with uncommitted changes, the day's
+// cost reaching a threshold and a session that errored. Each kind of event
+// can be turned off on its own. Users with notification preferences are also
+// sent the events they choose, through their own channels (including email
+// and plain webhooks), at their own thresholds and outside their quiet hours.
+package notifications
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ksred/claude-session-manager/internal/chat"
+	"github.com/ksred/claude-session-manager/internal/config"
+	"github.com/ksred/claude-session-manager/internal/database"
+	"github.com/sirupsen/logrus"
+)
+
+// Notification events
+const (
+	EventSessionIdle  = "session_idle"
+	EventDailyCost    = "daily_cost"
+	EventSessionError = "session_error"
+	EventTest         = "test"
+)
+
+// recentSessions is how many of the most recently active sessions are
+// checked for being idle
+const recentSessions = 100
+
+// errorCooldown is how long after notifying of a session's error further
+// errors in it are not sent, so a failing run doesn't flood the channel
+const errorCooldown = 10 * time.Minute
+
+// queueSize is how many errors can wait to be sent before new ones are
+// dropped
+const queueSize = 64
+
+// Notification is a message posted to every configured sender
+type Notification struct {
+	Event string
+	Title string
+	Text  string
+}
+
+// Store is where sessions and the day's usage are read from
+type Store interface {
+	GetSessionByID(sessionID string) (*database.SessionSummary, error)
+	GetRecentSessions(limit int, includeArchived bool) ([]*database.SessionSummary, error)
+	GetUsageBetween(from, to time.Time) (*database.UsageAggregate, error)
+	GetNotificationPreferences() ([]database.NotificationPreferences, error)
+}
+
+// sessionError is a failure reported by a session, waiting to be sent
+type sessionError struct {
+	sessionID string
+	detail    string
+}
+
+// Notifier checks for idle sessions and the day's cost on an interval, and
+// sends errors as they are reported through Notify
+type Notifier struct {
+	cfg     config.NotificationsConfig
+	smtp    config.SMTPConfig
+	store   Store
+	senders []*Sender
+	queue   chan sessionError
+	started time.Time
+	logger  *logrus.Logger
+
+	// uncommitted returns how many files in a working tree have uncommitted
+	// changes
+	uncommitted func(dir string) int
+
+	mu         sync.Mutex
+	users      []recipient          // users with notification preferences
+	idle       map[string]time.Time // recipient and session to the last activity it was checked as idle after
+	costDays   map[string]string    // recipient to the day its cost notification was last sent
+	lastErrors map[string]time.Time // session to when its last error was sent
+}
+
+// NewNotifier creates a notifier sending to the Slack and Discord webhooks
+// in cfg
+func NewNotifier(cfg config.NotificationsConfig, store Store, logger *logrus.Logger) *Notifier {
+	n := &Notifier{
+		cfg:         cfg,
+		store:       store,
+		queue:       make(chan sessionError, queueSize),
+		started:     time.Now(),
+		logger:      logger,
+		uncommitted: uncommittedFiles,
+		idle:        make(map[string]time.Time),
+		costDays:    make(map[string]string),
+		lastErrors:  make(map[string]time.Time),
+	}
+	if cfg.SlackWebhookURL != "" {
+		n.senders = append(n.senders, Slack(cfg.SlackWebhookURL))
+	}
+	if cfg.DiscordWebhookURL != "" {
+		n.senders = append(n.senders, Discord(cfg.DiscordWebhookURL))
+	}
+	return n
+}
+
+// Enabled reports whether a Slack or Discord webhook is configured
+func (n *Notifier) Enabled() bool {
+	return len(n.senders) > 0
+}
+
+// SetSMTP sets the mail server notifications to users' email channels are
+// sent through
+func (n *Notifier) SetSMTP(smtpCfg config.SMTPConfig) {
+	n.smtp = smtpCfg
+}
+
+// Notify is called with every update broadcast to WebSocket clients and
+// queues failed hook events and chat errors if anyone is to be told of them.
+// The error is dropped if the queue is full.
+func (n *Notifier) Notify(updateType string, data interface{}) {
+	wanted := false
+	for _, r := range n.recipients() {
+		wanted = wanted || r.wants(EventSessionError)
+	}
+	if !wanted {
+		return
+	}
+
+	var failure sessionError
+	switch updateType {
+	case "hook_event":
+		event, ok := data.(*database.HookEvent)
+		if !ok || !event.Failed() {
+			return
+		}
+		failure = sessionError{sessionID: event.SessionID, detail: event.Event + " failed"}
+		if event.ToolName != nil {
+			failure.detail = fmt.Sprintf("%s failed for %s", event.Event, *event.ToolName)
+		}
+	case chat.WSMsgChatError:
+		message, ok := data.(chat.WebSocketMessage)
+		if !ok {
+			return
+		}
+		failure = sessionError{sessionID: message.SessionID, detail: message.Content}
+	default:
+		return
+	}
+
+	select {
+	case n.queue <- failure:
+	default:
+		n.logger.WithField("session_id", failure.sessionID).Warn("Notification queue full, dropping error")
+	}
+}
+
+// Start checks for idle sessions and the day's cost every interval and sends
+// queued errors until ctx is cancelled
+func (n *Notifier) Start(ctx context.Context) {
+	if err := n.LoadPreferences(); err != nil {
+		n.logger.WithError(err).Warn("Failed to load notification preferences")
+	}
+
+	ticker := time.NewTicker(time.Duration(n.cfg.Interval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n.Check(ctx, time.Now())
+		case failure := <-n.queue:
+			now := time.Now()
+			if notification, ok := n.errorNotification(failure, now); ok {
+				for _, r := range n.recipients() {
+					if r.wants(EventSessionError) && !r.quietAt(now) {
+						n.send(ctx, r, notification)
+					}
+				}
+			}
+		}
+	}
+}
+
+// Check sends each recipient a notification for each session that has
+// become idle with uncommitted changes, and for the day's cost once it
+// reaches their threshold. Recipients in their quiet hours are skipped, so
+// they are told once the quiet hours end.
+func (n *Notifier) Check(ctx context.Context, now time.Time) {
+	if err := n.LoadPreferences(); err != nil {
+		n.logger.WithError(err).Warn("Failed to load notification preferences")
+	}
+
+	var sessions []*database.SessionSummary
+	var sessionsErr error
+	loaded := false
+	changes := make(map[string]int) // working tree to its uncommitted files, counted once per check
+	for _, r := range n.recipients() {
+		if r.quietAt(now) {
+			continue
+		}
+		if r.wants(EventSessionIdle) {
+			if !loaded {
+				sessions, sessionsErr = n.store.GetRecentSessions(recentSessions, false)
+				loaded = true
+				if sessionsErr != nil {
+					n.logger.WithError(sessionsErr).Warn("Failed to check for idle sessions")
+				}
+			}
+			if sessionsErr == nil {
+				for _, notification := range n.idleSessions(now, r, sessions, changes) {
+					n.send(ctx, r, notification)
+				}
+			}
+		}
+		if r.wants(EventDailyCost) {
+			notification, ok, err := n.dailyCost(now, r)
+			if err != nil {
+				n.logger.WithError(err).Warn("Failed to check the day's cost")
+			}
+			if ok {
+				n.send(ctx, r, notification)
+			}
+		}
+	}
+}
+
+// idleSessions returns a notification for each of the sessions that has
+// been idle for the recipient's minutes and has uncommitted changes. A
+// session is only checked once per idle spell for each recipient, and not at
+// all if it went idle before the notifier started.
+func (n *Notifier) idleSessions(now time.Time, r recipient, sessions []*database.SessionSummary, changes map[string]int) []Notification {
+	threshold := time.Duration(r.idleMinutes) * time.Minute
+	notifications := []Notification{}
+	for _, session := range sessions {
+		idleAt := session.LastActivity.Add(threshold)
+		if now.Before(idleAt) || idleAt.Before(n.started) {
+			continue
+		}
+
+		key := r.userID + "/" + session.ID
+		n.mu.Lock()
+		checked := n.idle[key].Equal(session.LastActivity)
+		n.idle[key] = session.LastActivity
+		n.mu.Unlock()
+		if checked {
+			continue
+		}
+
+		dir := session.GitWorktree
+		if dir == "" {
+			dir = session.ProjectPath
+		}
+		count, ok := changes[dir]
+		if !ok {
+			count = n.uncommitted(dir)
+			changes[dir] = count
+		}
+		if count == 0 {
+			continue
+		}
+
+		text := fmt.Sprintf("%s has been idle for %s with %s",
+			describeSession(session), formatIdle(now.Sub(session.LastActivity)), plural(count, "uncommitted file"))
+		if session.GitBranch != "" {
+			text += " on " + session.GitBranch
+		}
+		notifications = append(notifications, Notification{
+			Event: EventSessionIdle,
+			Title: "Session idle with uncommitted changes",
+			Text:  text + ".",
+		})
+	}
+	return notifications
+}
+
+// dailyCost returns a notification if the day's cost, in the recipient's
+// timezone, has reached their threshold and none has been sent them today
+func (n *Notifier) dailyCost(now time.Time, r recipient) (Notification, bool, error) {
+	local := now.In(r.location)
+	day := local.Format("2006-01-02")
+	n.mu.Lock()
+	sent := n.costDays[r.userID] == day
+	n.mu.Unlock()
+	if sent {
+		return Notification{}, false, nil
+	}
+
+	dayStart := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, r.location)
+	usage, err := n.store.GetUsageBetween(dayStart, now)
+	if err != nil {
+		return Notification{}, false, err
+	}
+	if usage.CostUSD < r.costThreshold {
+		return Notification{}, false, nil
+	}
+
+	n.mu.Lock()
+	n.costDays[r.userID] = day
+	n.mu.Unlock()
+	return Notification{
+		Event: EventDailyCost,
+		Title: fmt.Sprintf("Daily cost exceeded $%.2f", r.costThreshold),
+		Text:  fmt.Sprintf("Today's cost is $%.2f across %s.", usage.CostUSD, plural(usage.Sessions, "session")),
+	}, true, nil
+}
+
+// errorNotification returns the notification for a session's error, unless
+// one was sent for the session within the cooldown
+func (n *Notifier) errorNotification(failure sessionError, now time.Time) (Notification, bool) {
+	n.mu.Lock()
+	last, ok := n.lastErrors[failure.sessionID]
+	cooling := ok && now.Sub(last) < errorCooldown
+	if !cooling {
+		n.lastErrors[failure.sessionID] = now
+	}
+	n.mu.Unlock()
+	if cooling {
+		return Notification{}, false
+	}
+
+	subject := "Session " + shortID(failure.sessionID)
+	if session, err := n.store.GetSessionByID(failure.sessionID); err == nil {
+		subject = describeSession(session)
+	}
+	return Notification{
+		Event: EventSessionError,
+		Title: "Claude session errored",
+		Text:  fmt.Sprintf("%s: %s", subject, failure.detail),
+	}, true
+}
+
+// Test sends a test notification to every sender straight away
+func (n *Notifier) Test(ctx context.Context) error {
+	if !n.Enabled() {
+		return fmt.Errorf("no slack or discord webhook url is configured")
+	}
+	return n.send(ctx, recipient{senders: n.senders}, Notification{
+		Event: EventTest,
+		Title: "Test notification",
+		Text:  "Claude Session Manager notifications are set up.",
+	})
+}
+
+// send delivers a notification to each of a recipient's senders, logging
+// and returning the failures
+func (n *Notifier) send(ctx context.Context, r recipient, notification Notification) error {
+	var errs []error
+	for _, sender := range r.senders {
+		if err := sender.Send(ctx, notification); err != nil {
+			fields := logrus.Fields{
+				"sender": sender.Name,
+				"event":  notification.Event,
+			}
+			if r.userName != "" {
+				fields["user"] = r.userName
+			}
+			n.logger.WithError(err).WithFields(fields).Warn("Failed to send notification")
+			errs = append(errs, fmt.Errorf("%s: %w", sender.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// describeSession names a session by its project and short ID
+func describeSession(session *database.SessionSummary) string {
+	return fmt.Sprintf("%s (session %s)", session.ProjectName, shortID(session.ID))
+}
+
+func shortID(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
+
+// formatIdle formats how long a session has been idle to the minute, such as
+// "45 minutes" or "2h 5m"
+func formatIdle(d time.Duration) string {
+	minutes := int(d.Round(time.Minute) / time.Minute)
+	if minutes < 60 {
+		return plural(minutes, "minute")
+	}
+	return fmt.Sprintf("%dh %dm", minutes/60, minutes%60)
+}
+
+func plural(count int, noun string) string {
+	if count == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", count, noun)
+}
+
+// uncommittedFiles returns how many files git reports as changed or
+// untracked in dir, or 0 if it isn't a repository
+func uncommittedFiles(dir string) int {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	output, err := exec.CommandContext(ctx, "git", "-C", dir, "status", "--porcelain").Output()
+	if err != nil {
+		return 0
+	}
+	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+	if len(lines) == 1 && lines[0] == "" {
+		return 0
+	}
+	return len(lines)
+}
@@ -0,0 +1,257 @@
+package notifications
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/smtp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ksred/claude-session-manager/internal/chat"
+	"github.com/ksred/claude-session-manager/internal/config"
+	"github.com/ksred/claude-session-manager/internal/database"
+	"github.com/sirupsen/logrus"
+)
+
+type fakeStore struct {
+	sessions []*database.SessionSummary
+	costUSD  float64
+	prefs    []database.NotificationPreferences
+}
+
+func (s *fakeStore) GetSessionByID(sessionID string) (*database.SessionSummary, error) {
+	for _, session := range s.sessions {
+		if session.ID == sessionID {
+			return session, nil
+		}
+	}
+	return nil, fmt.Errorf("session not found: %s", sessionID)
+}
+
+func (s *fakeStore) GetRecentSessions(limit int, includeArchived bool) ([]*database.SessionSummary, error) {
+	return s.sessions, nil
+}
+
+func (s *fakeStore) GetUsageBetween(from, to time.Time) (*database.UsageAggregate, error) {
+	return &database.UsageAggregate{Sessions: 2, CostUSD: s.costUSD}, nil
+}
+
+func (s *fakeStore) GetNotificationPreferences() ([]database.NotificationPreferences, error) {
+	return s.prefs, nil
+}
+
+func TestNotifier_Check(t *testing.T) {
+	var posted []map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		posted = append(posted, body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	now := time.Now()
+	store := &fakeStore{
+		costUSD: 12.5,
+		sessions: []*database.SessionSummary{
+			{ID: "abcdef123456", ProjectName: "app", ProjectPath: "/work/app", GitBranch: "main", LastActivity: now.Add(-45 * time.Minute)},
+			{ID: "clean", ProjectName: "lib", ProjectPath: "/work/lib", LastActivity: now.Add(-45 * time.Minute)},
+			{ID: "busy", ProjectName: "app", ProjectPath: "/work/app", LastActivity: now.Add(-5 * time.Minute)},
+		},
+	}
+	notifier := NewNotifier(config.NotificationsConfig{
+		DiscordWebhookURL: server.URL,
+		Interval:          60,
+		SessionIdle:       config.IdleNotificationConfig{Enabled: true, Minutes: 30},
+		DailyCost:         config.CostNotificationConfig{Enabled: true, Threshold: 10},
+	}, store, logrus.New())
+	notifier.started = now.Add(-time.Hour)
+	notifier.uncommitted = func(dir string) int {
+		if dir == "/work/app" {
+			return 3
+		}
+		return 0
+	}
+
+	notifier.Check(context.Background(), now)
+	if len(posted) != 2 {
+		t.Fatalf("Expected an idle and a cost notification, got %v", posted)
+	}
+	if want := "**Session idle with uncommitted changes**\napp (session abcdef12) has been idle for 45 minutes with 3 uncommitted files on main."; posted[0]["content"] != want {
+		t.Errorf("Expected %q, got %q", want, posted[0]["content"])
+	}
+	if !strings.Contains(posted[1]["content"], "Today's cost is $12.50 across 2 sessions.") {
+		t.Errorf("Expected the day's cost, got %q", posted[1]["content"])
+	}
+
+	// Neither is sent again until the session is used or the day changes
+	notifier.Check(context.Background(), now.Add(time.Minute))
+	if len(posted) != 2 {
+		t.Errorf("Expected no repeated notifications, got %v", posted[2:])
+	}
+
+	// Sessions that went idle before the notifier started are left alone
+	late := NewNotifier(config.NotificationsConfig{
+		SlackWebhookURL: server.URL,
+		SessionIdle:     config.IdleNotificationConfig{Enabled: true, Minutes: 30},
+	}, store, logrus.New())
+	late.uncommitted = notifier.uncommitted
+	late.Check(context.Background(), now)
+	if len(posted) != 2 {
+		t.Errorf("Expected sessions idle since before startup skipped, got %v", posted[2:])
+	}
+}
+
+func TestNotifier_Errors(t *testing.T) {
+	store := &fakeStore{sessions: []*database.SessionSummary{{ID: "s1", ProjectName: "app"}}}
+	notifier := NewNotifier(config.NotificationsConfig{
+		SlackWebhookURL: "https://hooks.slack.com/services/T0/B0/x",
+		SessionError:    config.ErrorNotificationConfig{Enabled: true},
+	}, store, logrus.New())
+
+	tool := "Bash"
+	notifier.Notify("hook_event", &database.HookEvent{SessionID: "s1", Event: "PostToolUse", ToolName: &tool, Data: json.RawMessage(`{"tool_response":{"is_error":true}}`)})
+	notifier.Notify("hook_event", &database.HookEvent{SessionID: "s1", Event: "PostToolUse", Data: json.RawMessage(`{"tool_response":"ok"}`)})
+	notifier.Notify(chat.WSMsgChatError, chat.WebSocketMessage{SessionID: "s1", Content: "Failed to start chat session"})
+	if len(notifier.queue) != 2 {
+		t.Fatalf("Expected the two failures queued, got %d", len(notifier.queue))
+	}
+
+	now := time.Now()
+	notification, ok := notifier.errorNotification(<-notifier.queue, now)
+	if !ok || notification.Text != "app (session s1): PostToolUse failed for Bash" {
+		t.Errorf("Expected the failed tool named, got %+v", notification)
+	}
+	if _, ok := notifier.errorNotification(<-notifier.queue, now.Add(time.Minute)); ok {
+		t.Error("Expected a second error within the cooldown dropped")
+	}
+
+	notifier.cfg.SessionError.Enabled = false
+	notifier.Notify(chat.WSMsgChatError, chat.WebSocketMessage{SessionID: "s1"})
+	if len(notifier.queue) != 0 {
+		t.Error("Expected no errors queued when turned off")
+	}
+}
+
+func TestSlack(t *testing.T) {
+	var body map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&body)
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	if err := Slack(server.URL).Send(context.Background(), Notification{Title: "Title", Text: "Text"}); err != nil {
+		t.Fatalf("Failed to send: %v", err)
+	}
+	if body["text"] != "*Title*\nText" {
+		t.Errorf("Expected Slack mrkdwn text, got %v", body)
+	}
+}
+
+func TestNotifier_Preferences(t *testing.T) {
+	posted := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		posted[r.URL.Path]++
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	now := time.Date(2026, 3, 2, 23, 30, 0, 0, time.UTC)
+	store := &fakeStore{
+		costUSD: 12.5,
+		prefs: []database.NotificationPreferences{
+			// Wants the cost from $10, but is in quiet hours in London
+			{UserID: "u1", UserName: "alice", Channels: []database.NotificationChannel{{Type: "webhook", Target: server.URL + "/alice"}},
+				Events: []string{EventDailyCost}, DailyCostThreshold: 10, QuietHoursStart: "23:00", QuietHoursEnd: "23:45", Timezone: "Europe/London"},
+			// Wants the cost from $5 and errors, at any time
+			{UserID: "u2", UserName: "bob", Channels: []database.NotificationChannel{{Type: "slack"}},
+				Events: []string{EventDailyCost, EventSessionError}, DailyCostThreshold: 5},
+		},
+	}
+	notifier := NewNotifier(config.NotificationsConfig{
+		SlackWebhookURL: server.URL + "/slack",
+		DailyCost:       config.CostNotificationConfig{Enabled: true, Threshold: 20},
+	}, store, logrus.New())
+
+	notifier.Check(context.Background(), now)
+	if posted["/slack"] != 1 || posted["/alice"] != 0 {
+		t.Fatalf("Expected only bob told of the cost, got %v", posted)
+	}
+
+	// Alice is told once her quiet hours end; bob isn't told twice
+	notifier.Check(context.Background(), now.Add(20*time.Minute))
+	if posted["/slack"] != 1 || posted["/alice"] != 1 {
+		t.Errorf("Expected alice told after her quiet hours, got %v", posted)
+	}
+
+	// Errors are queued because bob wants them, although the config doesn't
+	notifier.Notify(chat.WSMsgChatError, chat.WebSocketMessage{SessionID: "s1", Content: "boom"})
+	if len(notifier.queue) != 1 {
+		t.Errorf("Expected the error queued for bob, got %d", len(notifier.queue))
+	}
+
+	store.prefs = nil
+	notifier.LoadPreferences()
+	notifier.Notify(chat.WSMsgChatError, chat.WebSocketMessage{SessionID: "s1", Content: "boom"})
+	if len(notifier.queue) != 1 {
+		t.Errorf("Expected no errors queued once nobody wants them, got %d", len(notifier.queue))
+	}
+}
+
+func TestNotifier_ValidatePreferences(t *testing.T) {
+	notifier := NewNotifier(config.NotificationsConfig{
+		SlackWebhookURL: "https://hooks.slack.com/services/T0/B0/x",
+		SessionIdle:     config.IdleNotificationConfig{Minutes: 30},
+	}, &fakeStore{}, logrus.New())
+
+	valid := &database.NotificationPreferences{
+		Channels:        []database.NotificationChannel{{Type: "slack"}, {Type: "webhook", Target: "https://example.com/hook"}},
+		Events:          []string{EventSessionIdle},
+		QuietHoursStart: "22:00",
+		QuietHoursEnd:   "07:00",
+		Timezone:        "America/New_York",
+	}
+	if err := notifier.ValidatePreferences(valid); err != nil {
+		t.Errorf("Expected valid preferences, got %v", err)
+	}
+
+	for name, prefs := range map[string]*database.NotificationPreferences{
+		"discord without a webhook": {Channels: []database.NotificationChannel{{Type: "discord"}}},
+		"email without smtp":        {Channels: []database.NotificationChannel{{Type: "email", Target: "alice@example.com"}}},
+		"webhook without a url":     {Channels: []database.NotificationChannel{{Type: "webhook", Target: "example.com"}}},
+		"unknown channel":           {Channels: []database.NotificationChannel{{Type: "pager"}}},
+		"unknown event":             {Events: []string{"test"}},
+		"negative threshold":        {DailyCostThreshold: -1},
+		"cost without a threshold":  {Events: []string{EventDailyCost}},
+		"half of the quiet hours":   {QuietHoursStart: "22:00"},
+		"empty quiet hours":         {QuietHoursStart: "22:00", QuietHoursEnd: "22:00"},
+		"unknown timezone":          {Timezone: "Mars/Olympus"},
+	} {
+		if err := notifier.ValidatePreferences(prefs); err == nil {
+			t.Errorf("Expected %s rejected", name)
+		}
+	}
+}
+
+func TestEmail(t *testing.T) {
+	var to []string
+	var message string
+	sendMail = func(addr string, a smtp.Auth, from string, recipients []string, msg []byte) error {
+		to, message = recipients, string(msg)
+		return nil
+	}
+	defer func() { sendMail = smtp.SendMail }()
+
+	sender := Email(config.SMTPConfig{Host: "smtp.example.com", Port: 587, From: "csm@example.com"}, "alice@example.com")
+	if err := sender.Send(context.Background(), Notification{Title: "Title", Text: "Text"}); err != nil {
+		t.Fatalf("Failed to send: %v", err)
+	}
+	if len(to) != 1 || to[0] != "alice@example.com" || !strings.Contains(message, "Subject: Title\r\n") || !strings.HasSuffix(message, "\r\n\r\nText\r\n") {
+		t.Errorf("Unexpected email to %v: %q", to, message)
+	}
+}
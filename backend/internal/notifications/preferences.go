@@ -0,0 +1,292 @@
+package notifications
+
+import (
+	"context"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"time"
+
+	"github.com/ksred/claude-session-manager/internal/database"
+)
+
+// Notification channels a user can choose
+const (
+	ChannelSlack   = "slack"
+	ChannelDiscord = "discord"
+	ChannelEmail   = "email"
+	ChannelWebhook = "webhook"
+)
+
+// Events are the events a user can choose to be notified of
+var Events = []string{EventSessionIdle, EventDailyCost, EventSessionError}
+
+// recipient is who notifications go to: the channels in the config, or a
+// user with notification preferences
+type recipient struct {
+	userID        string // empty for the config's channels
+	userName      string
+	senders       []*Sender
+	events        map[string]bool
+	idleMinutes   int
+	costThreshold float64
+	location      *time.Location // where the day's cost and quiet hours are reckoned
+	quiet         *quietHours    // nil for none
+}
+
+// wants reports whether the recipient is sent an event at all
+func (r recipient) wants(event string) bool {
+	return len(r.senders) > 0 && r.events[event]
+}
+
+// quietAt reports whether t falls in the recipient's quiet hours
+func (r recipient) quietAt(t time.Time) bool {
+	return r.quiet != nil && r.quiet.contains(t.In(r.location))
+}
+
+// quietHours is a daily spell, in minutes after midnight, that may run past
+// midnight
+type quietHours struct {
+	start, end int
+}
+
+func (q *quietHours) contains(t time.Time) bool {
+	minute := t.Hour()*60 + t.Minute()
+	if q.start < q.end {
+		return minute >= q.start && minute < q.end
+	}
+	return minute >= q.start || minute < q.end
+}
+
+// LoadPreferences reads every user's notification preferences, which govern
+// the notifications sent from then on. Check calls it on each interval, and
+// it should be called after preferences change.
+func (n *Notifier) LoadPreferences() error {
+	prefs, err := n.store.GetNotificationPreferences()
+	if err != nil {
+		return err
+	}
+
+	users := make([]recipient, 0, len(prefs))
+	for i := range prefs {
+		users = append(users, n.userRecipient(&prefs[i], true))
+	}
+	n.mu.Lock()
+	n.users = users
+	n.mu.Unlock()
+	return nil
+}
+
+// ValidatePreferences checks that each of a user's channels can be
+// delivered to and that their events, thresholds, quiet hours and timezone
+// are valid
+func (n *Notifier) ValidatePreferences(prefs *database.NotificationPreferences) error {
+	for _, channel := range prefs.Channels {
+		if _, err := n.sender(channel); err != nil {
+			return err
+		}
+	}
+	for _, event := range prefs.Events {
+		if !isEvent(event) {
+			return fmt.Errorf("unknown event %q (expected %s, %s or %s)", event, EventSessionIdle, EventDailyCost, EventSessionError)
+		}
+	}
+	if prefs.DailyCostThreshold < 0 {
+		return fmt.Errorf("daily_cost_threshold must not be negative")
+	}
+	if prefs.IdleMinutes < 0 {
+		return fmt.Errorf("idle_minutes must not be negative")
+	}
+	for _, event := range prefs.Events {
+		if event == EventDailyCost && prefs.DailyCostThreshold == 0 && n.cfg.DailyCost.Threshold <= 0 {
+			return fmt.Errorf("daily_cost_threshold is needed as notifications.daily_cost.threshold isn't set")
+		}
+		if event == EventSessionIdle && prefs.IdleMinutes == 0 && n.cfg.SessionIdle.Minutes <= 0 {
+			return fmt.Errorf("idle_minutes is needed as notifications.session_idle.minutes isn't set")
+		}
+	}
+	if _, err := location(prefs.Timezone); err != nil {
+		return err
+	}
+	_, err := parseQuietHours(prefs.QuietHoursStart, prefs.QuietHoursEnd)
+	return err
+}
+
+// TestPreferences sends a test notification to each of a user's channels
+// straight away, quiet hours or not
+func (n *Notifier) TestPreferences(ctx context.Context, prefs *database.NotificationPreferences) error {
+	r := n.userRecipient(prefs, false)
+	if len(r.senders) == 0 {
+		return fmt.Errorf("no notification channels are set")
+	}
+	return n.send(ctx, r, Notification{
+		Event: EventTest,
+		Title: "Test notification",
+		Text:  fmt.Sprintf("Claude Session Manager notifications are set up for %s.", prefs.UserName),
+	})
+}
+
+// recipients returns the config's channels followed by each user with
+// notification preferences
+func (n *Notifier) recipients() []recipient {
+	configured := recipient{
+		senders: n.senders,
+		events: map[string]bool{
+			EventSessionIdle:  n.cfg.SessionIdle.Enabled,
+			EventDailyCost:    n.cfg.DailyCost.Enabled,
+			EventSessionError: n.cfg.SessionError.Enabled,
+		},
+		idleMinutes:   n.cfg.SessionIdle.Minutes,
+		costThreshold: n.cfg.DailyCost.Threshold,
+		location:      time.Local,
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return append([]recipient{configured}, n.users...)
+}
+
+// userRecipient returns the recipient a user's preferences describe. Once
+// stored, preferences may no longer be valid if the config has changed, so
+// channels and quiet hours that aren't are logged and left out.
+func (n *Notifier) userRecipient(prefs *database.NotificationPreferences, stored bool) recipient {
+	r := recipient{
+		userID:        prefs.UserID,
+		userName:      prefs.UserName,
+		events:        make(map[string]bool),
+		idleMinutes:   prefs.IdleMinutes,
+		costThreshold: prefs.DailyCostThreshold,
+		location:      time.Local,
+	}
+	warn := func(err error) {
+		if stored {
+			n.logger.WithError(err).WithField("user", prefs.UserName).Warn("Ignoring invalid notification preference")
+		}
+	}
+
+	for _, channel := range prefs.Channels {
+		sender, err := n.sender(channel)
+		if err != nil {
+			warn(err)
+			continue
+		}
+		r.senders = append(r.senders, sender)
+	}
+
+	if len(prefs.Events) == 0 {
+		r.events[EventSessionIdle] = n.cfg.SessionIdle.Enabled
+		r.events[EventDailyCost] = n.cfg.DailyCost.Enabled
+		r.events[EventSessionError] = n.cfg.SessionError.Enabled
+	}
+	for _, event := range prefs.Events {
+		r.events[event] = true
+	}
+	if r.idleMinutes == 0 {
+		r.idleMinutes = n.cfg.SessionIdle.Minutes
+	}
+	if r.costThreshold == 0 {
+		r.costThreshold = n.cfg.DailyCost.Threshold
+	}
+
+	if loc, err := location(prefs.Timezone); err != nil {
+		warn(err)
+	} else {
+		r.location = loc
+	}
+	if quiet, err := parseQuietHours(prefs.QuietHoursStart, prefs.QuietHoursEnd); err != nil {
+		warn(err)
+	} else {
+		r.quiet = quiet
+	}
+	return r
+}
+
+// sender returns the sender for a user's channel. Slack and Discord
+// channels without a target use the config's webhook.
+func (n *Notifier) sender(channel database.NotificationChannel) (*Sender, error) {
+	target := channel.Target
+	switch channel.Type {
+	case ChannelSlack, ChannelDiscord:
+		if target == "" && channel.Type == ChannelSlack {
+			target = n.cfg.SlackWebhookURL
+		}
+		if target == "" && channel.Type == ChannelDiscord {
+			target = n.cfg.DiscordWebhookURL
+		}
+		if target == "" {
+			return nil, fmt.Errorf("%s channel needs a target as notifications.%s_webhook_url isn't set", channel.Type, channel.Type)
+		}
+		if err := checkURL(channel.Type, target); err != nil {
+			return nil, err
+		}
+		if channel.Type == ChannelSlack {
+			return Slack(target), nil
+		}
+		return Discord(target), nil
+	case ChannelWebhook:
+		if err := checkURL(channel.Type, target); err != nil {
+			return nil, err
+		}
+		return Webhook(target), nil
+	case ChannelEmail:
+		if _, err := mail.ParseAddress(target); err != nil {
+			return nil, fmt.Errorf("email channel needs a valid address: %w", err)
+		}
+		if n.smtp.Host == "" || n.smtp.From == "" {
+			return nil, fmt.Errorf("email channel needs reports.smtp to have a host and from address")
+		}
+		return Email(n.smtp, target), nil
+	default:
+		return nil, fmt.Errorf("unknown channel %q (expected %s, %s, %s or %s)", channel.Type, ChannelSlack, ChannelDiscord, ChannelEmail, ChannelWebhook)
+	}
+}
+
+func checkURL(channel, target string) error {
+	parsed, err := url.Parse(target)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("%s channel needs an http or https URL", channel)
+	}
+	return nil
+}
+
+func isEvent(name string) bool {
+	for _, event := range Events {
+		if event == name {
+			return true
+		}
+	}
+	return false
+}
+
+// location loads an IANA timezone, or the server's when name is empty
+func location(name string) (*time.Location, error) {
+	if name == "" {
+		return time.Local, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown timezone %q", name)
+	}
+	return loc, nil
+}
+
+// parseQuietHours parses HH:MM start and end times, returning nil when both
+// are empty
+func parseQuietHours(start, end string) (*quietHours, error) {
+	if start == "" && end == "" {
+		return nil, nil
+	}
+	from, err := time.Parse("15:04", start)
+	if err != nil {
+		return nil, fmt.Errorf("quiet_hours_start must be a time such as 22:00")
+	}
+	to, err := time.Parse("15:04", end)
+	if err != nil {
+		return nil, fmt.Errorf("quiet_hours_end must be a time such as 07:00")
+	}
+	quiet := &quietHours{start: from.Hour()*60 + from.Minute(), end: to.Hour()*60 + to.Minute()}
+	if quiet.start == quiet.end {
+		return nil, fmt.Errorf("quiet hours must not start and end at the same time")
+	}
+	return quiet, nil
+}
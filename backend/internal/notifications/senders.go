@@ -0,0 +1,119 @@
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strconv"
+	"time"
+
+	"github.com/ksred/claude-session-manager/internal/config"
+)
+
+// discordContentLimit is the most characters Discord accepts in a message
+const discordContentLimit = 2000
+
+// sendMail is smtp.SendMail, replaced in tests
+var sendMail = smtp.SendMail
+
+// Sender delivers notifications to a chat service's incoming webhook, a
+// webhook taking JSON or an email address
+type Sender struct {
+	Name string
+	send func(ctx context.Context, n Notification) error
+}
+
+// Slack returns a sender for a Slack incoming webhook URL
+func Slack(url string) *Sender {
+	return &Sender{
+		Name: "slack",
+		send: post("slack", url, func(n Notification) interface{} {
+			return map[string]string{"text": fmt.Sprintf("*%s*\n%s", n.Title, n.Text)}
+		}),
+	}
+}
+
+// Discord returns a sender for a Discord webhook URL
+func Discord(url string) *Sender {
+	return &Sender{
+		Name: "discord",
+		send: post("discord", url, func(n Notification) interface{} {
+			content := []rune(fmt.Sprintf("**%s**\n%s", n.Title, n.Text))
+			if len(content) > discordContentLimit {
+				content = append(content[:discordContentLimit-1], '…')
+			}
+			return map[string]string{"content": string(content)}
+		}),
+	}
+}
+
+// Webhook returns a sender posting each notification's event, title and
+// text as JSON to a URL
+func Webhook(url string) *Sender {
+	return &Sender{
+		Name: "webhook",
+		send: post("webhook", url, func(n Notification) interface{} {
+			return map[string]string{"event": n.Event, "title": n.Title, "text": n.Text}
+		}),
+	}
+}
+
+// Email returns a sender emailing notifications to an address through an
+// SMTP server
+func Email(smtpCfg config.SMTPConfig, to string) *Sender {
+	return &Sender{
+		Name: "email",
+		send: func(ctx context.Context, n Notification) error {
+			var auth smtp.Auth
+			if smtpCfg.Username != "" {
+				auth = smtp.PlainAuth("", smtpCfg.Username, smtpCfg.Password, smtpCfg.Host)
+			}
+			message := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\nMIME-Version: 1.0\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n%s\r\n",
+				smtpCfg.From, to, mime.QEncoding.Encode("utf-8", n.Title), n.Text)
+			addr := net.JoinHostPort(smtpCfg.Host, strconv.Itoa(smtpCfg.Port))
+			if err := sendMail(addr, auth, smtpCfg.From, []string{to}, []byte(message)); err != nil {
+				return fmt.Errorf("failed to send email: %w", err)
+			}
+			return nil
+		},
+	}
+}
+
+// Send delivers a notification once
+func (s *Sender) Send(ctx context.Context, n Notification) error {
+	return s.send(ctx, n)
+}
+
+// post returns a send function posting the JSON body built for each
+// notification to a URL
+func post(name, url string, body func(Notification) interface{}) func(context.Context, Notification) error {
+	client := &http.Client{Timeout: 10 * time.Second}
+	return func(ctx context.Context, n Notification) error {
+		data, err := json.Marshal(body(n))
+		if err != nil {
+			return fmt.Errorf("failed to encode notification: %w", err)
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+		if err != nil {
+			return fmt.Errorf("failed to create notification request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return fmt.Errorf("failed to post notification: %w", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode >= 300 {
+			return fmt.Errorf("%s returned status %d", name, resp.StatusCode)
+		}
+		return nil
+	}
+}
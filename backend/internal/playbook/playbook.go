@@ -0,0 +1,191 @@
+package playbook
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Playbook is a YAML-defined sequence of prompts executed against a project
+type Playbook struct {
+	Name        string `yaml:"name" json:"name"`
+	Description string `yaml:"description" json:"description,omitempty"`
+	ProjectPath string `yaml:"project_path" json:"project_path"`
+	ProjectName string `yaml:"project_name" json:"project_name,omitempty"`
+	Model       string `yaml:"model" json:"model,omitempty"`
+	Steps       []Step `yaml:"steps" json:"steps"`
+	// RequireApproval holds every step's changes until a human approves them
+	RequireApproval bool `yaml:"require_approval" json:"require_approval,omitempty"`
+
+	// FilePath is the file the playbook was loaded from, if any
+	FilePath string `yaml:"-" json:"file_path,omitempty"`
+}
+
+// Step is a single prompt within a playbook
+type Step struct {
+	Name   string `yaml:"name" json:"name"`
+	Prompt string `yaml:"prompt" json:"prompt"`
+	// NewSession starts a fresh conversation instead of resuming the previous step's
+	NewSession bool `yaml:"new_session" json:"new_session,omitempty"`
+	// ContinueOnFailure keeps the run going when this step's success criteria fail
+	ContinueOnFailure bool `yaml:"continue_on_failure" json:"continue_on_failure,omitempty"`
+	// RequireApproval runs the step in plan mode and holds its changes until approved
+	RequireApproval bool `yaml:"require_approval" json:"require_approval,omitempty"`
+	// Timeout overrides the runner's step timeout, in seconds
+	Timeout int             `yaml:"timeout" json:"timeout,omitempty"`
+	Success SuccessCriteria `yaml:"success" json:"success"`
+}
+
+// SuccessCriteria decides whether a step's response counts as a success.
+// All configured checks must pass; an empty criteria always passes.
+type SuccessCriteria struct {
+	Contains    []string `yaml:"contains" json:"contains,omitempty"`
+	NotContains []string `yaml:"not_contains" json:"not_contains,omitempty"`
+	Matches     string   `yaml:"matches" json:"matches,omitempty"`
+	MaxCostUSD  float64  `yaml:"max_cost_usd" json:"max_cost_usd,omitempty"`
+}
+
+// Parse parses and validates a playbook from YAML
+func Parse(data []byte) (*Playbook, error) {
+	var pb Playbook
+	if err := yaml.Unmarshal(data, &pb); err != nil {
+		return nil, fmt.Errorf("failed to parse playbook: %w", err)
+	}
+	if err := pb.Validate(); err != nil {
+		return nil, err
+	}
+	return &pb, nil
+}
+
+// LoadFile loads a playbook from a YAML file
+func LoadFile(path string) (*Playbook, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read playbook %s: %w", path, err)
+	}
+
+	pb, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	pb.FilePath = path
+	return pb, nil
+}
+
+// LoadDir loads every *.yaml and *.yml playbook in a directory, sorted by name.
+// A missing directory yields no playbooks rather than an error.
+func LoadDir(dir string) ([]*Playbook, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []*Playbook{}, nil
+		}
+		return nil, fmt.Errorf("failed to read playbook directory: %w", err)
+	}
+
+	playbooks := []*Playbook{}
+	seen := make(map[string]string)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		if ext != ".yaml" && ext != ".yml" {
+			continue
+		}
+
+		pb, err := LoadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		if other, exists := seen[pb.Name]; exists {
+			return nil, fmt.Errorf("duplicate playbook name %q in %s and %s", pb.Name, other, pb.FilePath)
+		}
+		seen[pb.Name] = pb.FilePath
+		playbooks = append(playbooks, pb)
+	}
+
+	sort.Slice(playbooks, func(i, j int) bool {
+		return playbooks[i].Name < playbooks[j].Name
+	})
+	return playbooks, nil
+}
+
+// Find loads the playbook with the given name from a directory
+func Find(dir, name string) (*Playbook, error) {
+	playbooks, err := LoadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, pb := range playbooks {
+		if pb.Name == name {
+			return pb, nil
+		}
+	}
+	return nil, fmt.Errorf("playbook not found: %s", name)
+}
+
+// Validate checks that the playbook is runnable
+func (p *Playbook) Validate() error {
+	if p.Name == "" {
+		return fmt.Errorf("playbook name is required")
+	}
+	if p.ProjectPath == "" {
+		return fmt.Errorf("playbook %s: project_path is required", p.Name)
+	}
+	if len(p.Steps) == 0 {
+		return fmt.Errorf("playbook %s: at least one step is required", p.Name)
+	}
+
+	for i := range p.Steps {
+		step := &p.Steps[i]
+		if step.Name == "" {
+			step.Name = fmt.Sprintf("step-%d", i+1)
+		}
+		if strings.TrimSpace(step.Prompt) == "" {
+			return fmt.Errorf("playbook %s: step %s has an empty prompt", p.Name, step.Name)
+		}
+		if step.Success.Matches != "" {
+			if _, err := regexp.Compile(step.Success.Matches); err != nil {
+				return fmt.Errorf("playbook %s: step %s has an invalid matches pattern: %w", p.Name, step.Name, err)
+			}
+		}
+	}
+
+	if p.ProjectName == "" {
+		p.ProjectName = filepath.Base(p.ProjectPath)
+	}
+	return nil
+}
+
+// Evaluate checks a step response against the criteria, returning a reason on failure
+func (c SuccessCriteria) Evaluate(response string, costUSD float64) (bool, string) {
+	for _, want := range c.Contains {
+		if !strings.Contains(response, want) {
+			return false, fmt.Sprintf("response does not contain %q", want)
+		}
+	}
+	for _, unwanted := range c.NotContains {
+		if strings.Contains(response, unwanted) {
+			return false, fmt.Sprintf("response contains %q", unwanted)
+		}
+	}
+	if c.Matches != "" {
+		re, err := regexp.Compile(c.Matches)
+		if err != nil {
+			return false, fmt.Sprintf("invalid matches pattern: %v", err)
+		}
+		if !re.MatchString(response) {
+			return false, fmt.Sprintf("response does not match %q", c.Matches)
+		}
+	}
+	if c.MaxCostUSD > 0 && costUSD > c.MaxCostUSD {
+		return false, fmt.Sprintf("step cost $%.4f exceeds limit of $%.4f", costUSD, c.MaxCostUSD)
+	}
+	return true, ""
+}
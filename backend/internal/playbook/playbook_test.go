@@ -0,0 +1,312 @@
+package playbook
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ksred/claude-session-manager/internal/chat"
+	"github.com/ksred/claude-session-manager/internal/database"
+	"github.com/sirupsen/logrus"
+)
+
+const testPlaybook = `
+name: upgrade-deps
+project_path: /tmp/project
+steps:
+  - name: upgrade
+    prompt: Upgrade all dependencies
+    success:
+      contains: ["upgraded"]
+  - prompt: Run the tests
+    success:
+      not_contains: ["FAIL"]
+      max_cost_usd: 1.0
+`
+
+func TestParse(t *testing.T) {
+	pb, err := Parse([]byte(testPlaybook))
+	if err != nil {
+		t.Fatalf("Failed to parse playbook: %v", err)
+	}
+
+	if pb.ProjectName != "project" {
+		t.Errorf("Expected project name to default to 'project', got %q", pb.ProjectName)
+	}
+	if len(pb.Steps) != 2 {
+		t.Fatalf("Expected 2 steps, got %d", len(pb.Steps))
+	}
+	if pb.Steps[1].Name != "step-2" {
+		t.Errorf("Expected unnamed step to default to 'step-2', got %q", pb.Steps[1].Name)
+	}
+
+	invalid := []string{
+		"project_path: /tmp\nsteps: [{prompt: hi}]",
+		"name: x\nsteps: [{prompt: hi}]",
+		"name: x\nproject_path: /tmp",
+		"name: x\nproject_path: /tmp\nsteps: [{prompt: hi, success: {matches: '('}}]",
+	}
+	for _, doc := range invalid {
+		if _, err := Parse([]byte(doc)); err == nil {
+			t.Errorf("Expected error parsing %q", doc)
+		}
+	}
+}
+
+func TestSuccessCriteria_Evaluate(t *testing.T) {
+	tests := []struct {
+		name     string
+		criteria SuccessCriteria
+		response string
+		cost     float64
+		want     bool
+	}{
+		{"empty criteria", SuccessCriteria{}, "anything", 0, true},
+		{"contains", SuccessCriteria{Contains: []string{"done"}}, "all done", 0, true},
+		{"missing text", SuccessCriteria{Contains: []string{"done"}}, "still going", 0, false},
+		{"unwanted text", SuccessCriteria{NotContains: []string{"FAIL"}}, "1 FAIL", 0, false},
+		{"regex", SuccessCriteria{Matches: `\d+ passed`}, "12 passed", 0, true},
+		{"over budget", SuccessCriteria{MaxCostUSD: 0.5}, "ok", 0.75, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, reason := tt.criteria.Evaluate(tt.response, tt.cost)
+			if got != tt.want {
+				t.Errorf("Evaluate() = %v (%s), want %v", got, reason, tt.want)
+			}
+		})
+	}
+}
+
+// fakeExecutor returns canned responses in order and records the resume IDs and permission modes it was given
+type fakeExecutor struct {
+	responses []string
+	resumed   []string
+	modes     []string
+}
+
+func (f *fakeExecutor) RunPrompt(ctx context.Context, projectPath, claudeSessionID, prompt string, opts chat.PromptOptions) (*chat.ClaudeResponse, error) {
+	f.resumed = append(f.resumed, claudeSessionID)
+	f.modes = append(f.modes, opts.PermissionMode)
+	if len(f.responses) == 0 {
+		return nil, fmt.Errorf("no more responses")
+	}
+	result := f.responses[0]
+	f.responses = f.responses[1:]
+	return &chat.ClaudeResponse{
+		Result:       result,
+		SessionID:    "claude-session",
+		TotalCostUSD: 0.1,
+	}, nil
+}
+
+func setupTestRepo(t *testing.T) *database.SessionRepository {
+	tmpFile, err := os.CreateTemp("", "test-playbook-*.db")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	tmpFile.Close()
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.WarnLevel)
+	db, err := database.NewDatabase(database.Config{DatabasePath: tmpFile.Name(), Logger: logger})
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	t.Cleanup(func() {
+		db.Close()
+		os.Remove(tmpFile.Name())
+	})
+
+	return database.NewSessionRepository(db, logger)
+}
+
+func TestRunner_Execute(t *testing.T) {
+	repo := setupTestRepo(t)
+	pb, err := Parse([]byte(testPlaybook))
+	if err != nil {
+		t.Fatalf("Failed to parse playbook: %v", err)
+	}
+
+	t.Run("all steps succeed", func(t *testing.T) {
+		executor := &fakeExecutor{responses: []string{"deps upgraded", "all tests passed"}}
+		runner := NewRunner(repo, executor, logrus.New(), time.Minute)
+
+		run := &database.PlaybookRun{PlaybookName: pb.Name, ProjectPath: pb.ProjectPath}
+		if err := repo.CreatePlaybookRun(run); err != nil {
+			t.Fatalf("Failed to create run: %v", err)
+		}
+		if err := runner.Execute(context.Background(), pb, run); err != nil {
+			t.Fatalf("Expected run to succeed: %v", err)
+		}
+
+		stored, err := repo.GetPlaybookRun(run.ID)
+		if err != nil {
+			t.Fatalf("Failed to get run: %v", err)
+		}
+		if stored.Status != database.RunStatusSucceeded {
+			t.Errorf("Expected status succeeded, got %s", stored.Status)
+		}
+		if stored.TotalCostUSD < 0.19 || stored.TotalCostUSD > 0.21 {
+			t.Errorf("Expected total cost 0.2, got %f", stored.TotalCostUSD)
+		}
+
+		// The second step resumes the conversation started by the first
+		if len(executor.resumed) != 2 || executor.resumed[0] != "" || executor.resumed[1] != "claude-session" {
+			t.Errorf("Unexpected resume IDs: %v", executor.resumed)
+		}
+
+		sessionIDs, err := repo.GetSessionGroupSessionIDs(*stored.GroupID)
+		if err != nil {
+			t.Fatalf("Failed to get group sessions: %v", err)
+		}
+		if len(sessionIDs) != 1 {
+			t.Errorf("Expected 1 linked session, got %d", len(sessionIDs))
+		}
+	})
+
+	t.Run("failed criteria skips remaining steps", func(t *testing.T) {
+		executor := &fakeExecutor{responses: []string{"nothing to do"}}
+		runner := NewRunner(repo, executor, logrus.New(), time.Minute)
+
+		run := &database.PlaybookRun{PlaybookName: pb.Name, ProjectPath: pb.ProjectPath}
+		if err := repo.CreatePlaybookRun(run); err != nil {
+			t.Fatalf("Failed to create run: %v", err)
+		}
+		if err := runner.Execute(context.Background(), pb, run); err == nil {
+			t.Fatal("Expected run to fail")
+		}
+
+		steps, err := repo.GetPlaybookRunSteps(run.ID)
+		if err != nil {
+			t.Fatalf("Failed to get steps: %v", err)
+		}
+		if len(steps) != 2 {
+			t.Fatalf("Expected 2 step records, got %d", len(steps))
+		}
+		if steps[0].Status != database.RunStatusFailed || steps[0].FailureReason == nil {
+			t.Errorf("Expected first step to fail with a reason, got %+v", steps[0])
+		}
+		if steps[1].Status != database.RunStatusSkipped {
+			t.Errorf("Expected second step to be skipped, got %s", steps[1].Status)
+		}
+	})
+}
+
+func TestRunner_Approval(t *testing.T) {
+	repo := setupTestRepo(t)
+	pb, err := Parse([]byte(testPlaybook))
+	if err != nil {
+		t.Fatalf("Failed to parse playbook: %v", err)
+	}
+	pb.Steps[0].RequireApproval = true
+
+	newRun := func(t *testing.T, executor *fakeExecutor) (*Runner, *database.PlaybookRun) {
+		runner := NewRunner(repo, executor, logrus.New(), time.Minute)
+		run := &database.PlaybookRun{PlaybookName: pb.Name, ProjectPath: pb.ProjectPath}
+		if err := repo.CreatePlaybookRun(run); err != nil {
+			t.Fatalf("Failed to create run: %v", err)
+		}
+		if err := runner.Execute(context.Background(), pb, run); err != errAwaitingApproval {
+			t.Fatalf("Expected run to await approval, got %v", err)
+		}
+		return runner, run
+	}
+
+	t.Run("approved run continues", func(t *testing.T) {
+		executor := &fakeExecutor{responses: []string{"I will upgrade deps", "deps upgraded", "all tests passed"}}
+		runner, run := newRun(t, executor)
+
+		_, step, err := runner.PendingApproval(run.ID)
+		if err != nil {
+			t.Fatalf("Expected a pending step: %v", err)
+		}
+		if step.Proposal == nil || *step.Proposal != "I will upgrade deps" {
+			t.Errorf("Expected proposal to be recorded, got %v", step.Proposal)
+		}
+		if executor.modes[0] != chat.PermissionModePlan {
+			t.Errorf("Expected proposal to run in plan mode, got %q", executor.modes[0])
+		}
+
+		done := make(chan *database.PlaybookRun, 1)
+		if _, err := runner.Approve(context.Background(), pb, run.ID, "alice", func(r *database.PlaybookRun) { done <- r }); err != nil {
+			t.Fatalf("Failed to approve run: %v", err)
+		}
+
+		select {
+		case finished := <-done:
+			if finished.Status != database.RunStatusSucceeded {
+				t.Errorf("Expected status succeeded, got %s", finished.Status)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timed out waiting for approved run")
+		}
+
+		// The approval resumes the conversation the proposal was made in
+		if len(executor.resumed) != 3 || executor.resumed[1] != "claude-session" || executor.modes[1] != "" {
+			t.Errorf("Unexpected calls after approval: resumed %v, modes %v", executor.resumed, executor.modes)
+		}
+
+		steps, err := repo.GetPlaybookRunSteps(run.ID)
+		if err != nil {
+			t.Fatalf("Failed to get steps: %v", err)
+		}
+		if len(steps) != 2 || steps[0].ReviewedBy == nil || *steps[0].ReviewedBy != "alice" {
+			t.Errorf("Expected first step to record its reviewer, got %+v", steps)
+		}
+	})
+
+	t.Run("rejected run stops", func(t *testing.T) {
+		executor := &fakeExecutor{responses: []string{"I will delete everything"}}
+		runner, run := newRun(t, executor)
+
+		rejected, err := runner.Reject(run.ID, "bob", "too risky")
+		if err != nil {
+			t.Fatalf("Failed to reject run: %v", err)
+		}
+		if rejected.Status != database.RunStatusRejected || rejected.CompletedAt == nil {
+			t.Errorf("Expected completed rejected run, got %+v", rejected)
+		}
+
+		if _, err := runner.Approve(context.Background(), pb, run.ID, "bob", nil); err == nil {
+			t.Error("Expected approving a rejected run to fail")
+		}
+	})
+}
+
+func TestCronSchedule_Next(t *testing.T) {
+	base := time.Date(2025, 3, 14, 10, 17, 30, 0, time.UTC) // a Friday
+
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"*/15 * * * *", time.Date(2025, 3, 14, 10, 30, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2025, 3, 14, 11, 0, 0, 0, time.UTC)},
+		{"@nightly", time.Date(2025, 3, 15, 2, 0, 0, 0, time.UTC)},
+		{"0 9 * * 1-5", time.Date(2025, 3, 17, 9, 0, 0, 0, time.UTC)},
+		{"30 8 1 * *", time.Date(2025, 4, 1, 8, 30, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2025, 3, 16, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			schedule, err := ParseCron(tt.expr)
+			if err != nil {
+				t.Fatalf("Failed to parse %q: %v", tt.expr, err)
+			}
+			if got := schedule.Next(base); !got.Equal(tt.want) {
+				t.Errorf("Next() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	for _, expr := range []string{"* * * *", "60 * * * *", "*/0 * * * *", "a b c d e"} {
+		if _, err := ParseCron(expr); err == nil {
+			t.Errorf("Expected error parsing %q", expr)
+		}
+	}
+}
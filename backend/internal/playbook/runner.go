@@ -0,0 +1,498 @@
+package playbook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/ksred/claude-session-manager/internal/chat"
+	"github.com/ksred/claude-session-manager/internal/database"
+	"github.com/sirupsen/logrus"
+)
+
+// Executor runs a single prompt against a project, resuming the given Claude
+// conversation when claudeSessionID is set. chat.CLIManager implements it.
+type Executor interface {
+	RunPrompt(ctx context.Context, projectPath, claudeSessionID, prompt string, opts chat.PromptOptions) (*chat.ClaudeResponse, error)
+}
+
+// ApplyApprovedPrompt is sent, resuming the step's conversation, once a
+// proposal has been approved
+const ApplyApprovedPrompt = "The proposed changes have been approved. Apply them now exactly as proposed."
+
+// errAwaitingApproval stops step execution when a step's proposal needs review
+var errAwaitingApproval = errors.New("awaiting approval")
+
+// Runner executes playbooks and records their results as a linked session group
+type Runner struct {
+	repo        *database.SessionRepository
+	executor    Executor
+	logger      *logrus.Logger
+	stepTimeout time.Duration
+
+	// Cancel functions for runs in progress, keyed by run ID
+	active map[string]context.CancelFunc
+	mutex  sync.Mutex
+}
+
+// NewRunner creates a new playbook runner
+func NewRunner(repo *database.SessionRepository, executor Executor, logger *logrus.Logger, stepTimeout time.Duration) *Runner {
+	if stepTimeout <= 0 {
+		stepTimeout = 10 * time.Minute
+	}
+	return &Runner{
+		repo:        repo,
+		executor:    executor,
+		logger:      logger,
+		stepTimeout: stepTimeout,
+		active:      make(map[string]context.CancelFunc),
+	}
+}
+
+// Start records a new run of the playbook and executes it in the background
+func (r *Runner) Start(ctx context.Context, pb *Playbook, trigger string) (*database.PlaybookRun, error) {
+	return r.Launch(ctx, pb, &database.PlaybookRun{Trigger: trigger}, nil)
+}
+
+// Launch records run, which may carry a trigger and schedule ID, and executes
+// it in the background. onComplete is called with the run record whenever
+// execution stops: on completion, failure, or when a step awaits approval.
+func (r *Runner) Launch(ctx context.Context, pb *Playbook, run *database.PlaybookRun, onComplete func(*database.PlaybookRun)) (*database.PlaybookRun, error) {
+	run.PlaybookName = pb.Name
+	run.ProjectPath = pb.ProjectPath
+	if err := r.repo.CreatePlaybookRun(run); err != nil {
+		return nil, fmt.Errorf("failed to create playbook run: %w", err)
+	}
+
+	r.goRun(ctx, run, onComplete, func(runCtx context.Context) error {
+		return r.Execute(runCtx, pb, run)
+	})
+
+	return run, nil
+}
+
+// goRun executes fn in the background with a cancellable context registered under the run ID
+func (r *Runner) goRun(ctx context.Context, run *database.PlaybookRun, onComplete func(*database.PlaybookRun), fn func(context.Context) error) {
+	runCtx, cancel := context.WithCancel(ctx)
+	r.mutex.Lock()
+	r.active[run.ID] = cancel
+	r.mutex.Unlock()
+
+	go func() {
+		defer func() {
+			r.mutex.Lock()
+			delete(r.active, run.ID)
+			r.mutex.Unlock()
+			cancel()
+		}()
+		if err := fn(runCtx); err != nil && !errors.Is(err, errAwaitingApproval) {
+			r.logger.WithError(err).WithField("run_id", run.ID).Warn("Playbook run did not succeed")
+		}
+		if onComplete != nil {
+			onComplete(run)
+		}
+	}()
+}
+
+// Cancel stops a run that is in progress
+func (r *Runner) Cancel(runID string) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	cancel, exists := r.active[runID]
+	if exists {
+		cancel()
+	}
+	return exists
+}
+
+// Execute runs every step of the playbook for an already-created run record.
+// It returns early, leaving the run awaiting approval, when a step's
+// proposal needs review.
+func (r *Runner) Execute(ctx context.Context, pb *Playbook, run *database.PlaybookRun) error {
+	now := time.Now()
+	run.Status = database.RunStatusRunning
+	run.StartedAt = &now
+
+	group, err := r.repo.CreateSessionGroup(fmt.Sprintf("playbook: %s", pb.Name), "playbook")
+	if err != nil {
+		return r.finish(pb, run, fmt.Errorf("failed to create session group: %w", err))
+	}
+	run.GroupID = &group.ID
+	if err := r.repo.UpdatePlaybookRun(run); err != nil {
+		r.logger.WithError(err).WithField("run_id", run.ID).Error("Failed to mark playbook run as running")
+	}
+
+	r.logger.WithFields(logrus.Fields{
+		"run_id":   run.ID,
+		"playbook": pb.Name,
+	}).Info("Starting playbook run")
+
+	return r.finish(pb, run, r.executeSteps(ctx, pb, run, 0, &conversation{}))
+}
+
+// conversation tracks the session a run's steps are currently writing to
+type conversation struct {
+	sessionID       string
+	claudeSessionID string
+	sessions        int
+}
+
+// executeSteps runs the playbook's steps starting at index from
+func (r *Runner) executeSteps(ctx context.Context, pb *Playbook, run *database.PlaybookRun, from int, conv *conversation) error {
+	logger := r.logger.WithFields(logrus.Fields{
+		"run_id":   run.ID,
+		"playbook": pb.Name,
+	})
+
+	var runErr error
+	for i := from; i < len(pb.Steps); i++ {
+		step := pb.Steps[i]
+		record := &database.PlaybookRunStep{
+			RunID:     run.ID,
+			StepIndex: i,
+			Name:      step.Name,
+			Prompt:    step.Prompt,
+			Status:    database.RunStatusPending,
+		}
+		if err := r.repo.CreatePlaybookRunStep(record); err != nil {
+			return fmt.Errorf("failed to record step %s: %w", step.Name, err)
+		}
+
+		// A step is skipped once an earlier step has failed the run
+		if runErr != nil {
+			record.Status = database.RunStatusSkipped
+			r.saveStep(logger, record)
+			continue
+		}
+
+		if ctx.Err() != nil {
+			runErr = ctx.Err()
+			record.Status = database.RunStatusCancelled
+			r.saveStep(logger, record)
+			continue
+		}
+
+		// Each conversation gets its own UI session linked into the run's group
+		if conv.sessionID == "" || step.NewSession {
+			session, err := r.repo.CreateUISession(pb.ProjectPath, pb.ProjectName, pb.Model)
+			if err != nil {
+				return fmt.Errorf("failed to create session for step %s: %w", step.Name, err)
+			}
+			if run.GroupID != nil {
+				if err := r.repo.AddSessionToGroup(*run.GroupID, session.ID, conv.sessions); err != nil {
+					logger.WithError(err).Error("Failed to add session to playbook group")
+				}
+			}
+			conv.sessions++
+			conv.sessionID = session.ID
+			conv.claudeSessionID = ""
+		}
+
+		// Steps that need approval only propose their changes; the run stops
+		// here until Approve or Reject is called
+		if pb.RequireApproval || step.RequireApproval {
+			err := r.proposeStep(ctx, pb, step, record, conv)
+			run.TotalCostUSD += record.CostUSD
+			r.saveStep(logger, record)
+			if err != nil {
+				return err
+			}
+			return errAwaitingApproval
+		}
+
+		err := r.runStep(ctx, pb.ProjectPath, step.Prompt, step, record, conv, chat.PromptOptions{})
+		run.TotalCostUSD += record.CostUSD
+		r.saveStep(logger, record)
+		if err != nil {
+			if ctx.Err() != nil {
+				runErr = ctx.Err()
+			} else if !step.ContinueOnFailure {
+				runErr = err
+			}
+		}
+	}
+
+	return runErr
+}
+
+// runStep executes a prompt for a step and fills in its record, evaluating the step's success criteria
+func (r *Runner) runStep(ctx context.Context, projectPath, prompt string, step Step, record *database.PlaybookRunStep, conv *conversation, opts chat.PromptOptions) error {
+	response, err := r.executePrompt(ctx, projectPath, prompt, step, record, conv, opts)
+	if err != nil {
+		reason := err.Error()
+		record.Status = database.RunStatusFailed
+		record.FailureReason = &reason
+		return err
+	}
+
+	if ok, reason := step.Success.Evaluate(response.Result, record.CostUSD); !ok {
+		record.Status = database.RunStatusFailed
+		record.FailureReason = &reason
+		return fmt.Errorf("step %s failed: %s", step.Name, reason)
+	}
+
+	record.Status = database.RunStatusSucceeded
+	return nil
+}
+
+// proposeStep runs a step in plan mode so Claude describes its changes without applying them
+func (r *Runner) proposeStep(ctx context.Context, pb *Playbook, step Step, record *database.PlaybookRunStep, conv *conversation) error {
+	response, err := r.executePrompt(ctx, pb.ProjectPath, step.Prompt, step, record, conv, chat.PromptOptions{
+		PermissionMode: chat.PermissionModePlan,
+	})
+	if err != nil {
+		reason := err.Error()
+		record.Status = database.RunStatusFailed
+		record.FailureReason = &reason
+		return err
+	}
+
+	record.Proposal = &response.Result
+	record.Response = nil
+	record.CompletedAt = nil
+	record.Status = database.RunStatusAwaitingApproval
+	return nil
+}
+
+// executePrompt runs a prompt within the step's timeout and records the response on the step.
+// Cost and turns accumulate so a proposal followed by its application is reported as one step.
+func (r *Runner) executePrompt(ctx context.Context, projectPath, prompt string, step Step, record *database.PlaybookRunStep, conv *conversation, opts chat.PromptOptions) (*chat.ClaudeResponse, error) {
+	timeout := r.stepTimeout
+	if step.Timeout > 0 {
+		timeout = time.Duration(step.Timeout) * time.Second
+	}
+	stepCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	started := time.Now()
+	record.SessionID = &conv.sessionID
+	if record.StartedAt == nil {
+		record.StartedAt = &started
+	}
+	record.Status = database.RunStatusRunning
+	r.saveStep(r.logger.WithField("run_id", record.RunID), record)
+
+	opts.SessionID = conv.sessionID
+	response, err := r.executor.RunPrompt(stepCtx, projectPath, conv.claudeSessionID, prompt, opts)
+
+	completed := time.Now()
+	record.CompletedAt = &completed
+	record.DurationMs += int(completed.Sub(started).Milliseconds())
+
+	if response != nil {
+		record.Response = &response.Result
+		record.CostUSD += response.TotalCostUSD
+		record.NumTurns += response.NumTurns
+		if response.SessionID != "" {
+			record.ClaudeSessionID = &response.SessionID
+			conv.claudeSessionID = response.SessionID
+		}
+	}
+
+	return response, err
+}
+
+// PendingApproval returns the step of a run that is waiting for review
+func (r *Runner) PendingApproval(runID string) (*database.PlaybookRun, *database.PlaybookRunStep, error) {
+	run, err := r.repo.GetPlaybookRun(runID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if run.Status != database.RunStatusAwaitingApproval {
+		return run, nil, fmt.Errorf("playbook run %s is not awaiting approval (status %s)", runID, run.Status)
+	}
+
+	steps, err := r.repo.GetPlaybookRunSteps(runID)
+	if err != nil {
+		return run, nil, err
+	}
+	for i := len(steps) - 1; i >= 0; i-- {
+		if steps[i].Status == database.RunStatusAwaitingApproval {
+			return run, &steps[i], nil
+		}
+	}
+	return run, nil, fmt.Errorf("playbook run %s has no step awaiting approval", runID)
+}
+
+// Approve applies the pending proposal of a run and continues the playbook in
+// the background. pb must be the playbook the run was started from.
+func (r *Runner) Approve(ctx context.Context, pb *Playbook, runID, reviewer string, onComplete func(*database.PlaybookRun)) (*database.PlaybookRun, error) {
+	run, record, err := r.PendingApproval(runID)
+	if err != nil {
+		return nil, err
+	}
+	if record.StepIndex >= len(pb.Steps) || pb.Steps[record.StepIndex].Name != record.Name {
+		return nil, fmt.Errorf("playbook %s has changed since run %s started", pb.Name, runID)
+	}
+
+	// Steps resume against the project the run was started for
+	if pb.ProjectPath != run.ProjectPath {
+		pb.ProjectPath = run.ProjectPath
+		pb.ProjectName = ""
+		if err := pb.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+	record.ReviewedBy = &reviewer
+	record.ReviewedAt = &now
+	run.Status = database.RunStatusRunning
+	if err := r.repo.UpdatePlaybookRun(run); err != nil {
+		return nil, fmt.Errorf("failed to update playbook run: %w", err)
+	}
+
+	conv := &conversation{}
+	if record.SessionID != nil {
+		conv.sessionID = *record.SessionID
+	}
+	if record.ClaudeSessionID != nil {
+		conv.claudeSessionID = *record.ClaudeSessionID
+	}
+	if run.GroupID != nil {
+		if ids, err := r.repo.GetSessionGroupSessionIDs(*run.GroupID); err == nil {
+			conv.sessions = len(ids)
+		}
+	}
+
+	r.logger.WithFields(logrus.Fields{
+		"run_id":   run.ID,
+		"step":     record.Name,
+		"reviewer": reviewer,
+	}).Info("Playbook step approved")
+
+	r.goRun(ctx, run, onComplete, func(runCtx context.Context) error {
+		step := pb.Steps[record.StepIndex]
+		costBefore := record.CostUSD
+		err := r.runStep(runCtx, pb.ProjectPath, ApplyApprovedPrompt, step, record, conv, chat.PromptOptions{})
+		run.TotalCostUSD += record.CostUSD - costBefore
+		r.saveStep(r.logger.WithField("run_id", run.ID), record)
+
+		if err != nil && (runCtx.Err() != nil || !step.ContinueOnFailure) {
+			if runCtx.Err() != nil {
+				err = runCtx.Err()
+			}
+			return r.finish(pb, run, err)
+		}
+		return r.finish(pb, run, r.executeSteps(runCtx, pb, run, record.StepIndex+1, conv))
+	})
+
+	return run, nil
+}
+
+// Reject discards the pending proposal of a run and ends the run
+func (r *Runner) Reject(runID, reviewer, reason string) (*database.PlaybookRun, error) {
+	run, record, err := r.PendingApproval(runID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	record.Status = database.RunStatusRejected
+	record.ReviewedBy = &reviewer
+	record.ReviewedAt = &now
+	if reason != "" {
+		record.FailureReason = &reason
+	}
+	r.saveStep(r.logger.WithField("run_id", run.ID), record)
+
+	msg := fmt.Sprintf("step %s rejected by %s", record.Name, reviewer)
+	if reason != "" {
+		msg += ": " + reason
+	}
+	run.Status = database.RunStatusRejected
+	run.Error = &msg
+	run.CompletedAt = &now
+	if err := r.repo.UpdatePlaybookRun(run); err != nil {
+		return nil, fmt.Errorf("failed to update playbook run: %w", err)
+	}
+
+	r.logActivity(run, now)
+	return run, nil
+}
+
+// maxDiffBytes caps the diff stored for a run
+const maxDiffBytes = 256 * 1024
+
+// captureDiff returns the uncommitted git diff of a project, or an empty
+// string if the project is not a git repository
+func captureDiff(projectPath string) string {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	output, err := exec.CommandContext(ctx, "git", "-C", projectPath, "diff", "HEAD").Output()
+	if err != nil {
+		return ""
+	}
+	if len(output) > maxDiffBytes {
+		return string(output[:maxDiffBytes]) + "\n... diff truncated ...\n"
+	}
+	return string(output)
+}
+
+// saveStep persists a step record, logging rather than failing the run on error
+func (r *Runner) saveStep(logger *logrus.Entry, record *database.PlaybookRunStep) {
+	if err := r.repo.UpdatePlaybookRunStep(record); err != nil {
+		logger.WithError(err).WithField("step", record.Name).Error("Failed to update playbook step")
+	}
+}
+
+// finish records the state of a run once execution stops and returns runErr.
+// A run that is waiting for approval is left open.
+func (r *Runner) finish(pb *Playbook, run *database.PlaybookRun, runErr error) error {
+	now := time.Now()
+
+	switch {
+	case errors.Is(runErr, errAwaitingApproval):
+		run.Status = database.RunStatusAwaitingApproval
+	case runErr == nil:
+		run.Status = database.RunStatusSucceeded
+	case errors.Is(runErr, context.Canceled):
+		run.Status = database.RunStatusCancelled
+	default:
+		run.Status = database.RunStatusFailed
+	}
+
+	if run.Status != database.RunStatusAwaitingApproval {
+		run.CompletedAt = &now
+		if runErr != nil {
+			msg := runErr.Error()
+			run.Error = &msg
+		}
+
+		// Record what the run changed so it can be reviewed and reported
+		if diff := captureDiff(pb.ProjectPath); diff != "" {
+			run.Diff = &diff
+		}
+	}
+
+	if err := r.repo.UpdatePlaybookRun(run); err != nil {
+		r.logger.WithError(err).WithField("run_id", run.ID).Error("Failed to update playbook run")
+	}
+
+	r.logActivity(run, now)
+
+	r.logger.WithFields(logrus.Fields{
+		"run_id":   run.ID,
+		"playbook": run.PlaybookName,
+		"status":   run.Status,
+		"cost_usd": run.TotalCostUSD,
+	}).Info("Playbook run stopped")
+
+	return runErr
+}
+
+// logActivity surfaces a run's current status in the normal activity feed
+func (r *Runner) logActivity(run *database.PlaybookRun, at time.Time) {
+	if err := r.repo.LogActivity(&database.ActivityLogEntry{
+		ActivityType: "playbook_run_" + run.Status,
+		Details:      fmt.Sprintf("Playbook %s %s (run %s, $%.4f)", run.PlaybookName, run.Status, run.ID, run.TotalCostUSD),
+		Timestamp:    at,
+	}); err != nil {
+		r.logger.WithError(err).WithField("run_id", run.ID).Error("Failed to log playbook run activity")
+	}
+}
@@ -0,0 +1,233 @@
+package playbook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/ksred/claude-session-manager/internal/database"
+	"github.com/sirupsen/logrus"
+)
+
+// Scheduler launches playbooks whose cron schedule has come due and
+// notifies the schedule's webhook when each run finishes
+type Scheduler struct {
+	repo      *database.SessionRepository
+	runner    *Runner
+	directory string
+	logger    *logrus.Logger
+	interval  time.Duration
+	client    *http.Client
+}
+
+// WebhookPayload is the JSON body posted to a schedule's webhook after a run
+// finishes or stops to wait for approval
+type WebhookPayload struct {
+	Event        string                     `json:"event"`
+	ScheduleID   string                     `json:"schedule_id"`
+	PlaybookName string                     `json:"playbook_name"`
+	Run          *database.PlaybookRun      `json:"run"`
+	Steps        []database.PlaybookRunStep `json:"steps"`
+	Timestamp    time.Time                  `json:"timestamp"`
+}
+
+// NewScheduler creates a new playbook scheduler
+func NewScheduler(repo *database.SessionRepository, runner *Runner, directory string, logger *logrus.Logger) *Scheduler {
+	return &Scheduler{
+		repo:      repo,
+		runner:    runner,
+		directory: directory,
+		logger:    logger,
+		interval:  30 * time.Second,
+		client:    &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// NextRun computes the next run time of a cron expression after the given time, in UTC
+func NextRun(cronExpr string, after time.Time) (*time.Time, error) {
+	schedule, err := ParseCron(cronExpr)
+	if err != nil {
+		return nil, err
+	}
+	next := schedule.Next(after.UTC())
+	if next.IsZero() {
+		return nil, fmt.Errorf("cron expression %q never fires", cronExpr)
+	}
+	return &next, nil
+}
+
+// Start checks for due schedules until ctx is cancelled
+func (s *Scheduler) Start(ctx context.Context) {
+	s.initializeNextRuns()
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.tick(ctx, time.Now().UTC())
+		}
+	}
+}
+
+// initializeNextRuns fills in next_run_at for enabled schedules that lack one
+func (s *Scheduler) initializeNextRuns() {
+	schedules, err := s.repo.GetPlaybookSchedules()
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to load playbook schedules")
+		return
+	}
+
+	for i := range schedules {
+		schedule := &schedules[i]
+		if !schedule.Enabled || schedule.NextRunAt != nil {
+			continue
+		}
+		next, err := NextRun(schedule.CronExpr, time.Now())
+		if err != nil {
+			s.logger.WithError(err).WithField("schedule_id", schedule.ID).Warn("Invalid playbook schedule")
+			continue
+		}
+		schedule.NextRunAt = next
+		if err := s.repo.UpdatePlaybookSchedule(schedule); err != nil {
+			s.logger.WithError(err).WithField("schedule_id", schedule.ID).Error("Failed to update playbook schedule")
+		}
+	}
+}
+
+// tick launches every schedule that is due at now
+func (s *Scheduler) tick(ctx context.Context, now time.Time) {
+	schedules, err := s.repo.GetDuePlaybookSchedules(now)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to load due playbook schedules")
+		return
+	}
+
+	for i := range schedules {
+		schedule := &schedules[i]
+		if _, err := s.Trigger(ctx, schedule, "schedule"); err != nil {
+			s.logger.WithError(err).WithFields(logrus.Fields{
+				"schedule_id": schedule.ID,
+				"playbook":    schedule.PlaybookName,
+			}).Error("Failed to launch scheduled playbook")
+		}
+
+		// Advance the schedule even if the launch failed so a broken playbook
+		// does not retry every tick
+		next, err := NextRun(schedule.CronExpr, now)
+		if err != nil {
+			s.logger.WithError(err).WithField("schedule_id", schedule.ID).Warn("Disabling invalid playbook schedule")
+			schedule.Enabled = false
+			schedule.NextRunAt = nil
+		} else {
+			schedule.NextRunAt = next
+		}
+		if err := s.repo.UpdatePlaybookSchedule(schedule); err != nil {
+			s.logger.WithError(err).WithField("schedule_id", schedule.ID).Error("Failed to update playbook schedule")
+		}
+	}
+}
+
+// Trigger launches the schedule's playbook immediately and records it as the last run.
+// The caller is responsible for persisting next_run_at.
+func (s *Scheduler) Trigger(ctx context.Context, schedule *database.PlaybookSchedule, trigger string) (*database.PlaybookRun, error) {
+	pb, err := Find(s.directory, schedule.PlaybookName)
+	if err != nil {
+		return nil, err
+	}
+	if schedule.ProjectPath != nil && *schedule.ProjectPath != "" {
+		pb.ProjectPath = *schedule.ProjectPath
+		pb.ProjectName = ""
+		if err := pb.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	scheduleID := schedule.ID
+	run, err := s.runner.Launch(ctx, pb, &database.PlaybookRun{
+		Trigger:    trigger,
+		ScheduleID: &scheduleID,
+	}, s.CompletionHandler)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	schedule.LastRunID = &run.ID
+	schedule.LastRunAt = &now
+	if err := s.repo.UpdatePlaybookSchedule(schedule); err != nil {
+		s.logger.WithError(err).WithField("schedule_id", schedule.ID).Error("Failed to record scheduled run")
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"schedule_id": schedule.ID,
+		"playbook":    schedule.PlaybookName,
+		"run_id":      run.ID,
+	}).Info("Launched scheduled playbook")
+
+	return run, nil
+}
+
+// CompletionHandler notifies the webhook of the schedule that launched run, if
+// any. It is passed to the runner whenever a scheduled run starts or resumes.
+func (s *Scheduler) CompletionHandler(run *database.PlaybookRun) {
+	if run.ScheduleID == nil {
+		return
+	}
+	schedule, err := s.repo.GetPlaybookSchedule(*run.ScheduleID)
+	if err != nil {
+		s.logger.WithError(err).WithField("run_id", run.ID).Warn("Failed to load schedule for webhook")
+		return
+	}
+	if schedule.WebhookURL != nil && *schedule.WebhookURL != "" {
+		s.notify(*schedule.WebhookURL, schedule.ID, run)
+	}
+}
+
+// notify posts a stopped run to a webhook, logging rather than failing on error
+func (s *Scheduler) notify(url, scheduleID string, run *database.PlaybookRun) {
+	steps, err := s.repo.GetPlaybookRunSteps(run.ID)
+	if err != nil {
+		s.logger.WithError(err).WithField("run_id", run.ID).Error("Failed to load steps for webhook")
+	}
+
+	event := "playbook.run.completed"
+	if run.Status == database.RunStatusAwaitingApproval {
+		event = "playbook.run.awaiting_approval"
+	}
+
+	payload := WebhookPayload{
+		Event:        event,
+		ScheduleID:   scheduleID,
+		PlaybookName: run.PlaybookName,
+		Run:          run,
+		Steps:        steps,
+		Timestamp:    time.Now(),
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to encode webhook payload")
+		return
+	}
+
+	resp, err := s.client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		s.logger.WithError(err).WithField("run_id", run.ID).Warn("Failed to deliver playbook webhook")
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		s.logger.WithFields(logrus.Fields{
+			"run_id": run.ID,
+			"status": resp.StatusCode,
+		}).Warn("Playbook webhook returned an error status")
+	}
+}
@@ -0,0 +1,240 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/ksred/claude-session-manager/internal/config"
+	"github.com/ksred/claude-session-manager/internal/export"
+	"github.com/ksred/claude-session-manager/internal/rpc"
+	"github.com/sirupsen/logrus"
+)
+
+// Info describes a configured plugin and what it registered
+type Info struct {
+	Name     string    `json:"name"`
+	Command  string    `json:"command"`
+	Running  bool      `json:"running"`
+	Error    string    `json:"error,omitempty"`
+	Manifest *Manifest `json:"manifest,omitempty"`
+}
+
+// Manager starts the configured plugins and routes calls and events to them.
+// A plugin that has exited is restarted on its next call.
+type Manager struct {
+	ctx     context.Context
+	configs []config.PluginConfig
+	host    *rpc.Server
+	logger  *logrus.Logger
+
+	mu      sync.Mutex
+	plugins map[string]*Plugin
+	errors  map[string]error
+	events  chan event
+}
+
+// event is a broadcast queued for the plugins' notifiers
+type event struct {
+	name string
+	data interface{}
+}
+
+// eventQueueSize bounds the events waiting for plugins; more are dropped so
+// a slow plugin never holds up a broadcast
+const eventQueueSize = 256
+
+// NewManager creates a manager for the configured plugins, answering their
+// requests from host. Plugins are stopped when ctx is cancelled.
+func NewManager(ctx context.Context, configs []config.PluginConfig, host *rpc.Server, logger *logrus.Logger) *Manager {
+	return &Manager{
+		ctx:     ctx,
+		configs: configs,
+		host:    host,
+		logger:  logger,
+		plugins: make(map[string]*Plugin),
+		errors:  make(map[string]error),
+		events:  make(chan event, eventQueueSize),
+	}
+}
+
+// Start launches every configured plugin and delivers events to them until
+// the manager's context is cancelled. Plugins that fail to start are logged
+// and retried on their next call.
+func (m *Manager) Start() {
+	go m.deliverEvents()
+
+	for _, cfg := range m.configs {
+		if p, err := m.get(cfg.Name); err != nil {
+			m.logger.WithError(err).WithField("plugin", cfg.Name).Error("Failed to start plugin")
+		} else {
+			manifest := p.Manifest()
+			m.logger.WithFields(logrus.Fields{
+				"plugin":    cfg.Name,
+				"analytics": len(manifest.Analytics),
+				"exporters": len(manifest.Exporters),
+				"notifiers": len(manifest.Notifiers),
+			}).Info("Started plugin")
+		}
+	}
+}
+
+// List returns every configured plugin, in config order
+func (m *Manager) List() []Info {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	infos := make([]Info, 0, len(m.configs))
+	for _, cfg := range m.configs {
+		info := Info{Name: cfg.Name, Command: cfg.Command}
+		if p, ok := m.plugins[cfg.Name]; ok {
+			manifest := p.Manifest()
+			info.Manifest = &manifest
+			info.Running = !p.Exited()
+		}
+		if err := m.errors[cfg.Name]; err != nil && !info.Running {
+			info.Error = err.Error()
+		}
+		infos = append(infos, info)
+	}
+	return infos
+}
+
+// RunAnalytics runs a plugin's analytic with the given query parameters and
+// returns its result
+func (m *Manager) RunAnalytics(ctx context.Context, pluginName, name string, params map[string]string) (interface{}, error) {
+	p, err := m.get(pluginName)
+	if err != nil {
+		return nil, err
+	}
+	found := false
+	for _, analytic := range p.Manifest().Analytics {
+		found = found || analytic.Name == name
+	}
+	if !found {
+		return nil, fmt.Errorf("analytic not found: %s/%s", pluginName, name)
+	}
+
+	var result interface{}
+	if err := p.Call(ctx, "analytics/run", AnalyticsParams{Name: name, Params: params}, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Exporter returns the exporter registered as name by the named plugin
+func (m *Manager) Exporter(pluginName, name string) (Exporter, error) {
+	p, err := m.get(pluginName)
+	if err != nil {
+		return Exporter{}, err
+	}
+	for _, exporter := range p.Manifest().Exporters {
+		if exporter.Name == name {
+			if exporter.ContentType == "" {
+				exporter.ContentType = "text/plain; charset=utf-8"
+			}
+			if exporter.Extension == "" {
+				exporter.Extension = "txt"
+			}
+			return exporter, nil
+		}
+	}
+	return Exporter{}, fmt.Errorf("exporter not found: %s/%s", pluginName, name)
+}
+
+// Export renders a transcript with a plugin's exporter
+func (m *Manager) Export(ctx context.Context, pluginName, name string, transcript *export.Transcript) (string, error) {
+	p, err := m.get(pluginName)
+	if err != nil {
+		return "", err
+	}
+	var result ExportResult
+	if err := p.Call(ctx, "export/run", ExportParams{Name: name, Transcript: transcript}, &result); err != nil {
+		return "", err
+	}
+	return result.Content, nil
+}
+
+// Notify queues an event for every plugin with a notifier subscribed to it.
+// The event is dropped if the queue is full.
+func (m *Manager) Notify(name string, data interface{}) {
+	select {
+	case m.events <- event{name: name, data: data}:
+	default:
+		m.logger.WithField("event", name).Debug("Plugin event queue full, dropping event")
+	}
+}
+
+// deliverEvents sends queued events to the running plugins subscribed to
+// them. Plugins are not restarted for events.
+func (m *Manager) deliverEvents() {
+	for {
+		var e event
+		select {
+		case <-m.ctx.Done():
+			return
+		case e = <-m.events:
+		}
+
+		m.mu.Lock()
+		plugins := make([]*Plugin, 0, len(m.plugins))
+		for _, p := range m.plugins {
+			plugins = append(plugins, p)
+		}
+		m.mu.Unlock()
+
+		for _, p := range plugins {
+			for _, notifier := range p.Manifest().Notifiers {
+				if !subscribed(notifier, e.name) {
+					continue
+				}
+				if err := p.Notify("notify", NotifyParams{Notifier: notifier.Name, Event: e.name, Data: e.data}); err != nil {
+					m.logger.WithError(err).WithField("plugin", p.Name()).Debug("Failed to notify plugin")
+				}
+			}
+		}
+	}
+}
+
+// Close stops every plugin
+func (m *Manager) Close() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for name, p := range m.plugins {
+		p.Close()
+		delete(m.plugins, name)
+	}
+}
+
+// get returns a running plugin, starting it if it isn't running
+func (m *Manager) get(name string) (*Plugin, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if p, ok := m.plugins[name]; ok && !p.Exited() {
+		return p, nil
+	}
+	for _, cfg := range m.configs {
+		if cfg.Name != name {
+			continue
+		}
+		p, err := Start(m.ctx, cfg, m.host, m.logger)
+		if err != nil {
+			m.errors[name] = err
+			return nil, err
+		}
+		delete(m.errors, name)
+		m.plugins[name] = p
+		return p, nil
+	}
+	return nil, fmt.Errorf("plugin not found: %s", name)
+}
+
+func subscribed(notifier Notifier, event string) bool {
+	for _, e := range notifier.Events {
+		if e == "*" || e == event {
+			return true
+		}
+	}
+	return false
+}
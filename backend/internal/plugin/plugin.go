@@ -0,0 +1,349 @@
+// Package plugin runs subprocess plugins that add analytics endpoints,
+// session exporters and event notifiers without forking the session
+// manager. Plugins speak JSON-RPC 2.0 over stdin/stdout with the same
+// Content-Length framing as the rpc command, in both directions: the server
+// calls the plugin's methods, and the plugin may call the rpc methods
+// (sessions/list, metrics/summary and so on) to read session data.
+//
+// The server calls these plugin methods:
+//
+//   - initialize, returning the plugin's Manifest
+//   - analytics/run, with AnalyticsParams, returning any JSON value
+//   - export/run, with ExportParams, returning an ExportResult
+//   - notify, a notification with NotifyParams for each subscribed event
+//   - shutdown, then the exit notification, when the server stops
+package plugin
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ksred/claude-session-manager/internal/config"
+	"github.com/ksred/claude-session-manager/internal/export"
+	"github.com/ksred/claude-session-manager/internal/rpc"
+	"github.com/sirupsen/logrus"
+)
+
+// defaultTimeout bounds a call when the plugin config sets no timeout
+const defaultTimeout = 30 * time.Second
+
+// Manifest is what a plugin registers in its initialize result
+type Manifest struct {
+	Name      string     `json:"name"`
+	Version   string     `json:"version,omitempty"`
+	Analytics []Analytic `json:"analytics,omitempty"`
+	Exporters []Exporter `json:"exporters,omitempty"`
+	Notifiers []Notifier `json:"notifiers,omitempty"`
+}
+
+// Analytic is an analytics endpoint served at
+// /api/v1/plugins/{plugin}/analytics/{name}
+type Analytic struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+// Exporter is a session export format served at
+// /api/v1/sessions/{id}/export/plugin/{plugin}/{name}
+type Exporter struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	ContentType string `json:"content_type,omitempty"` // text/plain when unset
+	Extension   string `json:"extension,omitempty"`    // txt when unset
+}
+
+// Notifier subscribes the plugin to events broadcast to WebSocket clients,
+// such as budget_alert or session_wrap_up. "*" subscribes to every event.
+type Notifier struct {
+	Name   string   `json:"name"`
+	Events []string `json:"events"`
+}
+
+// AnalyticsParams are the params of analytics/run
+type AnalyticsParams struct {
+	Name   string            `json:"name"`
+	Params map[string]string `json:"params"` // the request's query parameters
+}
+
+// ExportParams are the params of export/run
+type ExportParams struct {
+	Name       string             `json:"name"`
+	Transcript *export.Transcript `json:"transcript"`
+}
+
+// ExportResult is the result of export/run
+type ExportResult struct {
+	Content string `json:"content"`
+}
+
+// NotifyParams are the params of notify
+type NotifyParams struct {
+	Notifier string      `json:"notifier"`
+	Event    string      `json:"event"`
+	Data     interface{} `json:"data"`
+}
+
+// message is any JSON-RPC message read from a plugin: a response to one of
+// the server's calls, or a request or notification of its own
+type message struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *rpc.Error      `json:"error,omitempty"`
+}
+
+// Plugin is a running plugin process
+type Plugin struct {
+	config   config.PluginConfig
+	manifest Manifest
+	timeout  time.Duration
+	host     *rpc.Server
+	logger   *logrus.Logger
+
+	cmd        *exec.Cmd
+	stdin      io.WriteCloser
+	writeMu    sync.Mutex
+	stderrDone chan struct{}
+
+	mu      sync.Mutex
+	nextID  int64
+	pending map[int64]chan message
+	done    chan struct{}
+	exitErr error
+}
+
+// Start launches a plugin and waits for its manifest. The process runs until
+// Close is called or ctx is cancelled.
+func Start(ctx context.Context, cfg config.PluginConfig, host *rpc.Server, logger *logrus.Logger) (*Plugin, error) {
+	cmd := exec.CommandContext(ctx, cfg.Command, cfg.Args...)
+	cmd.Env = append(os.Environ(), cfg.Env...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start plugin %s: %w", cfg.Name, err)
+	}
+
+	p := &Plugin{
+		config:     cfg,
+		timeout:    defaultTimeout,
+		host:       host,
+		logger:     logger,
+		cmd:        cmd,
+		stdin:      stdin,
+		pending:    make(map[int64]chan message),
+		done:       make(chan struct{}),
+		stderrDone: make(chan struct{}),
+	}
+	if cfg.Timeout > 0 {
+		p.timeout = time.Duration(cfg.Timeout) * time.Second
+	}
+
+	go p.logStderr(stderr)
+	go p.readLoop(stdout)
+
+	var manifest Manifest
+	err = p.Call(ctx, "initialize", map[string]interface{}{
+		"serverInfo": map[string]string{"name": "claude-session-manager"},
+		"methods":    host.Methods(),
+	}, &manifest)
+	if err != nil {
+		p.Close()
+		return nil, fmt.Errorf("failed to initialize plugin %s: %w", cfg.Name, err)
+	}
+	p.manifest = manifest
+	return p, nil
+}
+
+// Name returns the plugin's configured name
+func (p *Plugin) Name() string {
+	return p.config.Name
+}
+
+// Manifest returns what the plugin registered
+func (p *Plugin) Manifest() Manifest {
+	return p.manifest
+}
+
+// Exited reports whether the plugin process has stopped
+func (p *Plugin) Exited() bool {
+	select {
+	case <-p.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// Call calls a plugin method and decodes its result into result, which may
+// be nil to discard it
+func (p *Plugin) Call(ctx context.Context, method string, params, result interface{}) error {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	p.mu.Lock()
+	if p.Exited() {
+		p.mu.Unlock()
+		return p.exitError()
+	}
+	p.nextID++
+	id := p.nextID
+	reply := make(chan message, 1)
+	p.pending[id] = reply
+	p.mu.Unlock()
+
+	defer func() {
+		p.mu.Lock()
+		delete(p.pending, id)
+		p.mu.Unlock()
+	}()
+
+	if err := p.send(map[string]interface{}{
+		"jsonrpc": rpc.Version,
+		"id":      id,
+		"method":  method,
+		"params":  params,
+	}); err != nil {
+		return err
+	}
+
+	select {
+	case msg := <-reply:
+		if msg.Error != nil {
+			return msg.Error
+		}
+		if result == nil || len(msg.Result) == 0 {
+			return nil
+		}
+		if err := json.Unmarshal(msg.Result, result); err != nil {
+			return fmt.Errorf("invalid %s result from plugin %s: %w", method, p.config.Name, err)
+		}
+		return nil
+	case <-p.done:
+		return p.exitError()
+	case <-ctx.Done():
+		return fmt.Errorf("plugin %s did not answer %s: %w", p.config.Name, method, ctx.Err())
+	}
+}
+
+// Notify sends a notification, which the plugin doesn't answer
+func (p *Plugin) Notify(method string, params interface{}) error {
+	if p.Exited() {
+		return p.exitError()
+	}
+	return p.send(map[string]interface{}{
+		"jsonrpc": rpc.Version,
+		"method":  method,
+		"params":  params,
+	})
+}
+
+// Close asks the plugin to shut down and kills it if it hasn't exited after
+// a few seconds
+func (p *Plugin) Close() {
+	if !p.Exited() {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		p.Call(ctx, "shutdown", nil, nil)
+		cancel()
+		p.Notify("exit", nil)
+	}
+	p.stdin.Close()
+
+	select {
+	case <-p.done:
+	case <-time.After(3 * time.Second):
+		p.cmd.Process.Kill()
+		<-p.done
+	}
+}
+
+func (p *Plugin) send(msg interface{}) error {
+	p.writeMu.Lock()
+	defer p.writeMu.Unlock()
+	return rpc.WriteMessage(p.stdin, msg)
+}
+
+func (p *Plugin) exitError() error {
+	if p.exitErr != nil {
+		return fmt.Errorf("plugin %s exited: %w", p.config.Name, p.exitErr)
+	}
+	return fmt.Errorf("plugin %s exited", p.config.Name)
+}
+
+// readLoop routes responses to their callers and answers the plugin's own
+// requests from the rpc server until the plugin closes stdout
+func (p *Plugin) readLoop(stdout io.Reader) {
+	reader := bufio.NewReader(stdout)
+	for {
+		body, err := rpc.ReadMessage(reader)
+		if err != nil {
+			if err != io.EOF {
+				p.logger.WithError(err).WithField("plugin", p.config.Name).Warn("Failed to read from plugin")
+			}
+			break
+		}
+
+		var msg message
+		if err := json.Unmarshal(body, &msg); err != nil {
+			p.logger.WithError(err).WithField("plugin", p.config.Name).Warn("Plugin sent invalid JSON")
+			continue
+		}
+
+		if msg.Method != "" {
+			resp, ok := p.host.Handle(rpc.Request{JSONRPC: msg.JSONRPC, ID: msg.ID, Method: msg.Method, Params: msg.Params})
+			if ok {
+				if err := p.send(resp); err != nil {
+					p.logger.WithError(err).WithField("plugin", p.config.Name).Warn("Failed to answer plugin request")
+				}
+			}
+			continue
+		}
+
+		id, err := strconv.ParseInt(string(msg.ID), 10, 64)
+		if err != nil {
+			continue
+		}
+		p.mu.Lock()
+		reply, ok := p.pending[id]
+		p.mu.Unlock()
+		if ok {
+			reply <- msg
+		}
+	}
+
+	// Wait closes the pipes, so stderr must be drained first
+	<-p.stderrDone
+	err := p.cmd.Wait()
+	p.mu.Lock()
+	p.exitErr = err
+	close(p.done)
+	p.mu.Unlock()
+}
+
+// logStderr logs each line the plugin writes to stderr
+func (p *Plugin) logStderr(stderr io.Reader) {
+	defer close(p.stderrDone)
+	scanner := bufio.NewScanner(stderr)
+	for scanner.Scan() {
+		p.logger.WithField("plugin", p.config.Name).Info(scanner.Text())
+	}
+}
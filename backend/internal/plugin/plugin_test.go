@@ -0,0 +1,183 @@
+package plugin
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ksred/claude-session-manager/internal/config"
+	"github.com/ksred/claude-session-manager/internal/database"
+	"github.com/ksred/claude-session-manager/internal/export"
+	"github.com/ksred/claude-session-manager/internal/rpc"
+	"github.com/sirupsen/logrus"
+)
+
+// TestHelperPlugin is not a real test: run with PLUGIN_HELPER=1, the test
+// binary acts as a plugin registering one of each extension
+func TestHelperPlugin(t *testing.T) {
+	if os.Getenv("PLUGIN_HELPER") != "1" {
+		return
+	}
+
+	reader := bufio.NewReader(os.Stdin)
+	var lastEvent string
+	nextID := 0
+	write := func(v interface{}) { rpc.WriteMessage(os.Stdout, v) }
+	read := func() message {
+		body, err := rpc.ReadMessage(reader)
+		if err != nil {
+			os.Exit(0)
+		}
+		var msg message
+		json.Unmarshal(body, &msg)
+		return msg
+	}
+
+	for {
+		msg := read()
+		var result interface{}
+		switch msg.Method {
+		case "initialize":
+			result = Manifest{
+				Name:      "helper",
+				Analytics: []Analytic{{Name: "sessions"}, {Name: "last_event"}},
+				Exporters: []Exporter{{Name: "shout", ContentType: "text/plain"}},
+				Notifiers: []Notifier{{Name: "alerts", Events: []string{"budget_alert"}}},
+			}
+		case "analytics/run":
+			var params AnalyticsParams
+			json.Unmarshal(msg.Params, &params)
+			if params.Name == "last_event" {
+				result = lastEvent
+				break
+			}
+			// Read session data back from the server
+			nextID++
+			write(map[string]interface{}{"jsonrpc": "2.0", "id": nextID, "method": "metrics/summary"})
+			summary := read()
+			result = map[string]interface{}{"summary": summary.Result, "project": params.Params["project"]}
+		case "export/run":
+			var params ExportParams
+			json.Unmarshal(msg.Params, &params)
+			result = ExportResult{Content: strings.ToUpper(params.Transcript.Session.ProjectName)}
+		case "notify":
+			var params NotifyParams
+			json.Unmarshal(msg.Params, &params)
+			lastEvent = params.Notifier + ":" + params.Event
+			continue
+		case "shutdown":
+		case "exit":
+			os.Exit(0)
+		}
+		write(map[string]interface{}{"jsonrpc": "2.0", "id": msg.ID, "result": result})
+	}
+}
+
+func newTestManager(t *testing.T) *Manager {
+	tmpFile, err := os.CreateTemp("", "test-plugin-*.db")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	tmpFile.Close()
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	db, err := database.NewDatabase(database.Config{DatabasePath: tmpFile.Name(), Logger: logger})
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	host := rpc.NewServer(database.NewSessionRepository(db, logger), rpc.Options{Currency: "USD"}, logger)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	manager := NewManager(ctx, []config.PluginConfig{{
+		Name:    "helper",
+		Command: os.Args[0],
+		Args:    []string{"-test.run=TestHelperPlugin"},
+		Env:     []string{"PLUGIN_HELPER=1"},
+		Timeout: 10,
+	}}, host, logger)
+	t.Cleanup(func() {
+		manager.Close()
+		cancel()
+		db.Close()
+		os.Remove(tmpFile.Name())
+	})
+	return manager
+}
+
+func TestManager(t *testing.T) {
+	manager := newTestManager(t)
+	manager.Start()
+	ctx := context.Background()
+
+	plugins := manager.List()
+	if len(plugins) != 1 || !plugins[0].Running || plugins[0].Manifest.Name != "helper" {
+		t.Fatalf("Expected the helper plugin to be running, got %+v", plugins)
+	}
+
+	result, err := manager.RunAnalytics(ctx, "helper", "sessions", map[string]string{"project": "payments"})
+	if err != nil {
+		t.Fatalf("Failed to run analytic: %v", err)
+	}
+	body := result.(map[string]interface{})
+	summary, ok := body["summary"].(map[string]interface{})
+	if !ok || summary["total_sessions"] != float64(0) || body["project"] != "payments" {
+		t.Errorf("Expected the plugin to read metrics from the server, got %v", result)
+	}
+
+	if _, err := manager.RunAnalytics(ctx, "helper", "missing", nil); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("Expected an unknown analytic to be not found, got %v", err)
+	}
+	if _, err := manager.RunAnalytics(ctx, "missing", "sessions", nil); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("Expected an unknown plugin to be not found, got %v", err)
+	}
+
+	exporter, err := manager.Exporter("helper", "shout")
+	if err != nil || exporter.Extension != "txt" {
+		t.Fatalf("Expected the shout exporter with the default extension, got %+v, %v", exporter, err)
+	}
+	content, err := manager.Export(ctx, "helper", "shout", &export.Transcript{Session: &database.SessionSummary{ProjectName: "payments"}})
+	if err != nil || content != "PAYMENTS" {
+		t.Errorf("Expected the exported content, got %q, %v", content, err)
+	}
+
+	// Only subscribed events reach the notifier
+	manager.Notify("session_update", nil)
+	manager.Notify("budget_alert", map[string]string{"name": "daily"})
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		result, err := manager.RunAnalytics(ctx, "helper", "last_event", nil)
+		if err != nil {
+			t.Fatalf("Failed to run analytic: %v", err)
+		}
+		if result == "alerts:budget_alert" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the budget alert to be delivered, got %v", result)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestManager_RestartsExitedPlugin(t *testing.T) {
+	manager := newTestManager(t)
+	manager.Start()
+
+	first, err := manager.get("helper")
+	if err != nil {
+		t.Fatalf("Failed to get plugin: %v", err)
+	}
+	first.Close()
+
+	if _, err := manager.RunAnalytics(context.Background(), "helper", "last_event", nil); err != nil {
+		t.Fatalf("Expected the plugin to be restarted, got %v", err)
+	}
+	if second, _ := manager.get("helper"); second == first {
+		t.Error("Expected a new plugin process")
+	}
+}
@@ -0,0 +1,326 @@
+// Package pricing holds the per-model token prices every cost estimate is
+// computed from. Prices start from a built-in list, which a remote price list
+// can replace, and are overridden by those in the config file or set through
+// the API.
+package pricing
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ksred/claude-session-manager/internal/config"
+)
+
+// Where a price came from
+const (
+	SourceBuiltIn = "built-in"
+	SourceRemote  = "remote"
+	SourceConfig  = "config"
+	SourceAPI     = "api"
+	SourceWhatIf  = "what-if"
+)
+
+// Price is a model's price in USD per million tokens. Model matches the exact
+// model name or, failing that, any model name containing it.
+type Price struct {
+	Model      string  `json:"model"`
+	Input      float64 `json:"input"`
+	Output     float64 `json:"output"`
+	CacheWrite float64 `json:"cache_write"`
+	CacheRead  float64 `json:"cache_read"`
+	Source     string  `json:"source,omitempty"`
+}
+
+// Usage is the token counts a cost is computed from
+type Usage struct {
+	InputTokens              int
+	OutputTokens             int
+	CacheCreationInputTokens int
+	CacheReadInputTokens     int
+}
+
+// Cost returns the cost of usage at this price
+func (p Price) Cost(usage Usage) float64 {
+	cost := float64(usage.InputTokens) * p.Input
+	cost += float64(usage.OutputTokens) * p.Output
+	cost += float64(usage.CacheCreationInputTokens) * p.CacheWrite
+	cost += float64(usage.CacheReadInputTokens) * p.CacheRead
+	return cost / 1_000_000
+}
+
+// CacheSavings returns what the cached part of usage would have cost as
+// fresh input at this price minus what it did cost. Cache writes are priced
+// above input, so usage that writes more than it reads saves a negative
+// amount.
+func (p Price) CacheSavings(usage Usage) float64 {
+	cached := float64(usage.CacheCreationInputTokens + usage.CacheReadInputTokens)
+	actual := float64(usage.CacheCreationInputTokens)*p.CacheWrite + float64(usage.CacheReadInputTokens)*p.CacheRead
+	return (cached*p.Input - actual) / 1_000_000
+}
+
+func (p Price) validate() error {
+	if p.Input < 0 || p.Output < 0 || p.CacheWrite < 0 || p.CacheRead < 0 {
+		return fmt.Errorf("invalid price for model %s", p.Model)
+	}
+	return nil
+}
+
+// PriceList is a set of model prices with the price of models none of them
+// match. It is the body of GET and PUT /api/v1/pricing and the format of the
+// remote price list.
+type PriceList struct {
+	Models    []Price    `json:"models"`
+	Default   *Price     `json:"default,omitempty"`
+	Currency  string     `json:"currency,omitempty"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+// Validate checks every price is named and none is negative
+func (l PriceList) Validate() error {
+	for _, price := range l.Models {
+		if price.Model == "" {
+			return fmt.Errorf("pricing models require a model name")
+		}
+		if err := price.validate(); err != nil {
+			return err
+		}
+	}
+	if l.Default != nil {
+		if err := l.Default.validate(); err != nil {
+			return fmt.Errorf("invalid default model price")
+		}
+	}
+	return nil
+}
+
+// BuiltIn returns the prices shipped with this release, per million tokens
+// with 5 minute cache writes
+func BuiltIn() PriceList {
+	sonnet := Price{Input: 3.0, Output: 15.0, CacheWrite: 3.75, CacheRead: 0.30}
+	opus := Price{Input: 15.0, Output: 75.0, CacheWrite: 18.75, CacheRead: 1.50}
+	named := func(model string, price Price) Price {
+		price.Model = model
+		return price
+	}
+	return PriceList{
+		Models: []Price{
+			named("claude-opus-4", opus),
+			named("claude-sonnet-4", sonnet),
+			named("claude-3-opus", opus),
+			named("claude-3-7-sonnet", sonnet),
+			named("claude-3-5-sonnet", sonnet),
+			named("claude-3-sonnet", sonnet),
+			{Model: "claude-3-5-haiku", Input: 0.80, Output: 4.0, CacheWrite: 1.0, CacheRead: 0.08},
+			{Model: "claude-3-haiku", Input: 0.25, Output: 1.25, CacheWrite: 0.30, CacheRead: 0.03},
+		},
+		Default: &sonnet,
+	}
+}
+
+// Table looks up the price of a model. Overrides, from the config file or
+// the API, take precedence over the base list, built-in or remote.
+type Table struct {
+	mu              sync.RWMutex
+	whatIf          []Price // set only on copies made by With
+	base            []Price
+	baseDefault     Price
+	overrides       []Price
+	overrideDefault *Price
+	currency        string
+	updatedAt       time.Time
+}
+
+// NewTable creates a table with the built-in prices
+func NewTable() *Table {
+	builtIn := BuiltIn()
+	t := &Table{currency: "USD", updatedAt: time.Now().UTC()}
+	t.base = withSource(builtIn.Models, SourceBuiltIn)
+	t.baseDefault = *builtIn.Default
+	t.baseDefault.Source = SourceBuiltIn
+	return t
+}
+
+// Configure applies the prices and currency from the config file as overrides
+func (t *Table) Configure(cfg config.PricingConfig) {
+	overrides := PriceList{Currency: cfg.Currency}
+	for _, model := range cfg.Models {
+		overrides.Models = append(overrides.Models, fromConfig(model))
+	}
+	if cfg.Default != nil {
+		price := fromConfig(*cfg.Default)
+		overrides.Default = &price
+	}
+	t.setOverrides(overrides, SourceConfig)
+}
+
+// SetOverrides replaces the overrides with prices set through the API
+func (t *Table) SetOverrides(list PriceList) error {
+	if err := list.Validate(); err != nil {
+		return err
+	}
+	t.setOverrides(list, SourceAPI)
+	return nil
+}
+
+func (t *Table) setOverrides(list PriceList, source string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.overrides = withSource(list.Models, source)
+	t.overrideDefault = nil
+	if list.Default != nil {
+		price := *list.Default
+		price.Source = source
+		t.overrideDefault = &price
+	}
+	if list.Currency != "" {
+		t.currency = list.Currency
+	}
+	t.updatedAt = time.Now().UTC()
+}
+
+// SetBase replaces the built-in prices, keeping the built-in price of unknown
+// models when list has no default
+func (t *Table) SetBase(list PriceList, source string) error {
+	if err := list.Validate(); err != nil {
+		return err
+	}
+	if len(list.Models) == 0 {
+		return fmt.Errorf("price list has no models")
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.base = withSource(list.Models, source)
+	if list.Default != nil {
+		t.baseDefault = *list.Default
+		t.baseDefault.Source = source
+	}
+	t.updatedAt = time.Now().UTC()
+	return nil
+}
+
+// Lookup returns the price of model: an exact match, else the longest model
+// name contained in it, else the default. Dots and dashes are treated alike,
+// so claude-3-5-sonnet also prices claude-3.5-sonnet.
+func (t *Table) Lookup(model string) Price {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	name := normalize(model)
+	for _, prices := range [][]Price{t.whatIf, t.overrides, t.base} {
+		var best *Price
+		for i, price := range prices {
+			pattern := normalize(price.Model)
+			if pattern == name {
+				return price
+			}
+			if pattern != "" && strings.Contains(name, pattern) && (best == nil || len(pattern) > len(normalize(best.Model))) {
+				best = &prices[i]
+			}
+		}
+		if best != nil {
+			return *best
+		}
+	}
+	if t.overrideDefault != nil {
+		return *t.overrideDefault
+	}
+	return t.baseDefault
+}
+
+// With returns a copy of the table in which prices take precedence over every
+// other price, for estimating costs under hypothetical prices without
+// changing the prices in effect
+func (t *Table) With(prices []Price) *Table {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return &Table{
+		whatIf:          withSource(prices, SourceWhatIf),
+		base:            t.base,
+		baseDefault:     t.baseDefault,
+		overrides:       t.overrides,
+		overrideDefault: t.overrideDefault,
+		currency:        t.currency,
+		updatedAt:       t.updatedAt,
+	}
+}
+
+// Cost returns the cost of usage by model
+func (t *Table) Cost(model string, usage Usage) float64 {
+	return t.Lookup(model).Cost(usage)
+}
+
+// CacheSavings returns the cache savings of usage by model
+func (t *Table) CacheSavings(model string, usage Usage) float64 {
+	return t.Lookup(model).CacheSavings(usage)
+}
+
+// List returns the prices in effect, overrides replacing base prices for the
+// same model, sorted by model
+func (t *Table) List() PriceList {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	byModel := make(map[string]Price)
+	for _, prices := range [][]Price{t.base, t.overrides} {
+		for _, price := range prices {
+			byModel[normalize(price.Model)] = price
+		}
+	}
+	models := make([]Price, 0, len(byModel))
+	for _, price := range byModel {
+		models = append(models, price)
+	}
+	sort.Slice(models, func(i, j int) bool { return models[i].Model < models[j].Model })
+
+	defaultPrice := t.baseDefault
+	if t.overrideDefault != nil {
+		defaultPrice = *t.overrideDefault
+	}
+	updatedAt := t.updatedAt
+	return PriceList{Models: models, Default: &defaultPrice, Currency: t.currency, UpdatedAt: &updatedAt}
+}
+
+func fromConfig(price config.ModelPrice) Price {
+	return Price{
+		Model:      price.Model,
+		Input:      price.Input,
+		Output:     price.Output,
+		CacheWrite: price.CacheWrite,
+		CacheRead:  price.CacheRead,
+	}
+}
+
+func withSource(prices []Price, source string) []Price {
+	out := make([]Price, len(prices))
+	for i, price := range prices {
+		price.Source = source
+		out[i] = price
+	}
+	return out
+}
+
+func normalize(model string) string {
+	return strings.ReplaceAll(strings.ToLower(model), ".", "-")
+}
+
+// shared is the table every cost estimate uses
+var shared = NewTable()
+
+// Default returns the table every cost estimate uses
+func Default() *Table {
+	return shared
+}
+
+// Cost returns the cost of usage by model from the shared table
+func Cost(model string, usage Usage) float64 {
+	return shared.Cost(model, usage)
+}
+
+// CacheSavings returns the cache savings of usage by model from the shared
+// table
+func CacheSavings(model string, usage Usage) float64 {
+	return shared.CacheSavings(model, usage)
+}
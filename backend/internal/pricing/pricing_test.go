@@ -0,0 +1,114 @@
+package pricing
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ksred/claude-session-manager/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+func TestTable_Lookup(t *testing.T) {
+	table := NewTable()
+
+	tests := []struct {
+		model string
+		want  string
+	}{
+		{"claude-opus-4-20250514", "claude-opus-4"},
+		{"claude-3.5-sonnet", "claude-3-5-sonnet"},
+		{"claude-3-5-haiku-20241022", "claude-3-5-haiku"},
+		{"claude-3-haiku-20240307", "claude-3-haiku"},
+		{"some-future-model", ""},
+	}
+	for _, tt := range tests {
+		if got := table.Lookup(tt.model); got.Model != tt.want {
+			t.Errorf("Lookup(%q) = %q, want %q", tt.model, got.Model, tt.want)
+		}
+	}
+
+	usage := Usage{InputTokens: 1_000_000, OutputTokens: 1_000_000, CacheCreationInputTokens: 1_000_000, CacheReadInputTokens: 1_000_000}
+	if cost := table.Cost("claude-opus-4-1", usage); math.Abs(cost-110.25) > 1e-9 {
+		t.Errorf("Expected $110.25 for a million of each at Opus prices, got %v", cost)
+	}
+	// Two million cached tokens at $15 fresh, less $18.75 written and $1.50 read
+	if savings := table.CacheSavings("claude-opus-4-1", usage); math.Abs(savings-9.75) > 1e-9 {
+		t.Errorf("Expected $9.75 saved by the cache at Opus prices, got %v", savings)
+	}
+}
+
+func TestTable_Overrides(t *testing.T) {
+	table := NewTable()
+	table.Configure(config.PricingConfig{
+		Currency: "EUR",
+		Models:   []config.ModelPrice{{Model: "claude-sonnet-4", Input: 2, Output: 10}},
+		Default:  &config.ModelPrice{Input: 1, Output: 1},
+	})
+
+	if price := table.Lookup("claude-sonnet-4-20250514"); price.Input != 2 || price.Source != SourceConfig {
+		t.Errorf("Expected the config price, got %+v", price)
+	}
+	if price := table.Lookup("unknown"); price.Input != 1 || price.Source != SourceConfig {
+		t.Errorf("Expected the config default, got %+v", price)
+	}
+
+	// A remote list replaces the built-in prices but not the overrides
+	if err := table.SetBase(PriceList{Models: []Price{{Model: "claude-sonnet-4", Input: 9}, {Model: "claude-next", Input: 4}}}, SourceRemote); err != nil {
+		t.Fatalf("Failed to set base prices: %v", err)
+	}
+	if price := table.Lookup("claude-sonnet-4"); price.Input != 2 {
+		t.Errorf("Expected the config price to win over the remote one, got %+v", price)
+	}
+	if price := table.Lookup("claude-next-1"); price.Input != 4 || price.Source != SourceRemote {
+		t.Errorf("Expected the remote price, got %+v", price)
+	}
+
+	list := table.List()
+	if list.Currency != "EUR" || len(list.Models) != 2 {
+		t.Errorf("Expected 2 models in EUR, got %+v", list)
+	}
+
+	if err := table.SetOverrides(PriceList{Models: []Price{{Model: "claude-next", Input: -1}}}); err == nil {
+		t.Error("Expected negative prices to be rejected")
+	}
+}
+
+func TestTable_With(t *testing.T) {
+	table := NewTable()
+	table.Configure(config.PricingConfig{
+		Models: []config.ModelPrice{{Model: "claude-sonnet-4", Input: 2, Output: 10}},
+	})
+
+	whatIf := table.With([]Price{{Model: "claude-sonnet", Input: 1, Output: 5}})
+	if price := whatIf.Lookup("claude-sonnet-4-20250514"); price.Input != 1 || price.Source != SourceWhatIf {
+		t.Errorf("Expected the hypothetical price to win over the config price, got %+v", price)
+	}
+	if price := whatIf.Lookup("claude-opus-4"); price.Input != 15 {
+		t.Errorf("Expected other models to keep their prices, got %+v", price)
+	}
+	if price := table.Lookup("claude-sonnet-4-20250514"); price.Input != 2 {
+		t.Errorf("Expected the original table to be unchanged, got %+v", price)
+	}
+}
+
+func TestFetcher_Fetch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"models":[{"model":"claude-next","input":4,"output":20}]}`))
+	}))
+	defer server.Close()
+
+	table := NewTable()
+	fetcher := NewFetcher(table, server.URL, time.Hour, logrus.New())
+	fetcher.refresh(context.Background())
+
+	if price := table.Lookup("claude-next"); price.Output != 20 || price.Source != SourceRemote {
+		t.Errorf("Expected the fetched price, got %+v", price)
+	}
+	if price := table.Lookup("claude-opus-4"); price.Model != "" {
+		t.Errorf("Expected the remote list to replace the built-in prices, got %+v", price)
+	}
+}
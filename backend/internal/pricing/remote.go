@@ -0,0 +1,88 @@
+package pricing
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// maxRemoteSize bounds the remote price list read into memory
+const maxRemoteSize = 1 << 20
+
+// Fetcher keeps a table's base prices in step with a remote price list
+type Fetcher struct {
+	table    *Table
+	url      string
+	interval time.Duration
+	client   *http.Client
+	logger   *logrus.Logger
+}
+
+// NewFetcher creates a fetcher replacing table's base prices with the list at
+// url every interval
+func NewFetcher(table *Table, url string, interval time.Duration, logger *logrus.Logger) *Fetcher {
+	return &Fetcher{
+		table:    table,
+		url:      url,
+		interval: interval,
+		client:   &http.Client{Timeout: 30 * time.Second},
+		logger:   logger,
+	}
+}
+
+// Start fetches the price list immediately and then on each interval until
+// ctx is cancelled. The last good prices are kept when a fetch fails.
+func (f *Fetcher) Start(ctx context.Context) {
+	f.refresh(ctx)
+
+	ticker := time.NewTicker(f.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			f.refresh(ctx)
+		}
+	}
+}
+
+func (f *Fetcher) refresh(ctx context.Context) {
+	list, err := f.Fetch(ctx)
+	if err == nil {
+		err = f.table.SetBase(*list, SourceRemote)
+	}
+	if err != nil {
+		f.logger.WithError(err).WithField("url", f.url).Warn("Failed to refresh remote pricing")
+		return
+	}
+	f.logger.WithField("models", len(list.Models)).Info("Refreshed remote pricing")
+}
+
+// Fetch downloads and decodes the remote price list
+func (f *Fetcher) Fetch(ctx context.Context) (*PriceList, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, f.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("price list returned %s", resp.Status)
+	}
+
+	var list PriceList
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxRemoteSize)).Decode(&list); err != nil {
+		return nil, fmt.Errorf("failed to decode price list: %w", err)
+	}
+	return &list, nil
+}
@@ -0,0 +1,201 @@
+// Package quota tracks each team member's usage against their soft monthly
+// token and cost quotas, for teams splitting a shared Claude subscription.
+// Usage counts against a session's owner, or else its project's. Crossing a
+// quota blocks nothing; it raises a warning the first time each threshold is
+// crossed in a month.
+package quota
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ksred/claude-session-manager/internal/database"
+	"github.com/sirupsen/logrus"
+)
+
+// Thresholds are the percentages of a quota that raise a warning
+var Thresholds = []int{80, 100}
+
+// Quota states
+const (
+	StateOK        = "ok"
+	StateWarning   = "warning"
+	StateExceeded  = "exceeded"
+	StateUnlimited = "unlimited" // no quota is set
+)
+
+// Status is a user's usage this month against their quota
+type Status struct {
+	database.UserQuota
+	PeriodStart      time.Time `json:"period_start"`
+	PeriodEnd        time.Time `json:"period_end"`
+	UsedTokens       int64     `json:"used_tokens"`
+	UsedCostUSD      float64   `json:"used_cost_usd"`
+	RemainingTokens  *int64    `json:"remaining_tokens"`   // nil without a token quota
+	RemainingCostUSD *float64  `json:"remaining_cost_usd"` // nil without a cost quota
+	Percent          float64   `json:"percent"`            // of whichever quota is nearer its limit
+	State            string    `json:"state"`
+}
+
+// Alert is a user's usage crossing a threshold of one of their quotas, sent
+// to WebSocket clients as quota_warning
+type Alert struct {
+	UserID      string    `json:"user_id"`
+	UserName    string    `json:"user_name"`
+	Metric      string    `json:"metric"` // tokens or cost
+	PeriodStart time.Time `json:"period_start"`
+	Threshold   int       `json:"threshold"`
+	Used        float64   `json:"used"`
+	Limit       float64   `json:"limit"`
+	TriggeredAt time.Time `json:"triggered_at"`
+}
+
+// Monitor checks users' quotas against their usage and raises alerts
+type Monitor struct {
+	repo   *database.SessionRepository
+	logger *logrus.Logger
+	now    func() time.Time
+
+	mu      sync.Mutex
+	onAlert func(Alert)
+}
+
+// NewMonitor creates a monitor for the quotas stored in repo. Months start
+// at local midnight on the first.
+func NewMonitor(repo *database.SessionRepository, logger *logrus.Logger) *Monitor {
+	return &Monitor{
+		repo:   repo,
+		logger: logger,
+		now:    time.Now,
+	}
+}
+
+// OnAlert sets the function called for each new alert
+func (m *Monitor) OnAlert(fn func(Alert)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onAlert = fn
+}
+
+// Status returns every user's usage this month against their quota, by
+// name. Users without a quota are listed with their usage as unlimited.
+func (m *Monitor) Status() ([]Status, error) {
+	quotas, err := m.repo.GetUserQuotas()
+	if err != nil {
+		return nil, err
+	}
+
+	now := m.now()
+	start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	end := start.AddDate(0, 1, 0)
+	usage, err := m.repo.GetUsageByUser(start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(quotas))
+	for _, q := range quotas {
+		used := usage[q.UserID]
+		status := Status{
+			UserQuota:   q,
+			PeriodStart: start,
+			PeriodEnd:   end,
+			UsedTokens:  used.Tokens,
+			UsedCostUSD: used.CostUSD,
+			State:       StateUnlimited,
+		}
+		if q.MonthlyTokens > 0 {
+			remaining := max(q.MonthlyTokens-used.Tokens, 0)
+			status.RemainingTokens = &remaining
+			status.Percent = float64(used.Tokens) / float64(q.MonthlyTokens) * 100
+		}
+		if q.MonthlyCostUSD > 0 {
+			remaining := max(q.MonthlyCostUSD-used.CostUSD, 0)
+			status.RemainingCostUSD = &remaining
+			status.Percent = max(status.Percent, used.CostUSD/q.MonthlyCostUSD*100)
+		}
+		if q.MonthlyTokens > 0 || q.MonthlyCostUSD > 0 {
+			switch {
+			case status.Percent >= float64(Thresholds[len(Thresholds)-1]):
+				status.State = StateExceeded
+			case status.Percent >= float64(Thresholds[0]):
+				status.State = StateWarning
+			default:
+				status.State = StateOK
+			}
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// Check raises an alert for each threshold of each quota a user has crossed
+// this month that they hadn't already been warned of, returning the new
+// alerts
+func (m *Monitor) Check() ([]Alert, error) {
+	statuses, err := m.Status()
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	onAlert := m.onAlert
+	m.mu.Unlock()
+
+	var alerts []Alert
+	for _, status := range statuses {
+		metrics := []struct {
+			name        string
+			used, limit float64
+		}{
+			{database.QuotaTokens, float64(status.UsedTokens), float64(status.MonthlyTokens)},
+			{database.QuotaCost, status.UsedCostUSD, status.MonthlyCostUSD},
+		}
+		for _, metric := range metrics {
+			if metric.limit <= 0 {
+				continue
+			}
+			for _, threshold := range Thresholds {
+				if metric.used/metric.limit*100 < float64(threshold) {
+					break
+				}
+				alert := Alert{
+					UserID:      status.UserID,
+					UserName:    status.UserName,
+					Metric:      metric.name,
+					PeriodStart: status.PeriodStart,
+					Threshold:   threshold,
+					Used:        metric.used,
+					Limit:       metric.limit,
+					TriggeredAt: m.now().UTC(),
+				}
+				isNew, err := m.repo.RecordQuotaAlert(&database.QuotaAlert{
+					UserID:      alert.UserID,
+					PeriodStart: alert.PeriodStart,
+					Metric:      alert.Metric,
+					Threshold:   threshold,
+					Used:        alert.Used,
+					TriggeredAt: alert.TriggeredAt,
+				})
+				if err != nil {
+					return alerts, err
+				}
+				if !isNew {
+					continue
+				}
+
+				m.logger.WithFields(logrus.Fields{
+					"user":      alert.UserName,
+					"metric":    alert.Metric,
+					"threshold": threshold,
+					"used":      alert.Used,
+				}).Warn("Quota threshold crossed")
+				alerts = append(alerts, alert)
+				if onAlert != nil {
+					onAlert(alert)
+				}
+			}
+		}
+	}
+	return alerts, nil
+}
@@ -0,0 +1,105 @@
+package quota
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ksred/claude-session-manager/internal/database"
+	"github.com/sirupsen/logrus"
+)
+
+func setupTestRepo(t *testing.T) *database.SessionRepository {
+	tmpFile, err := os.CreateTemp("", "test-quota-*.db")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	tmpFile.Close()
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	db, err := database.NewDatabase(database.Config{DatabasePath: tmpFile.Name(), Logger: logger})
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	t.Cleanup(func() {
+		db.Close()
+		os.Remove(tmpFile.Name())
+	})
+
+	return database.NewSessionRepository(db, logger)
+}
+
+func addUsage(t *testing.T, repo *database.SessionRepository, sessionID, project string, at time.Time, tokens int, cost float64) {
+	t.Helper()
+	if err := repo.UpsertSession(&database.Session{ID: sessionID, ProjectPath: "/work/" + project, ProjectName: project, StartTime: at, LastActivity: at, Status: "active"}); err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	messageID := sessionID + "-message"
+	if err := repo.UpsertMessage(&database.Message{ID: messageID, SessionID: sessionID, Role: "assistant", Timestamp: at}); err != nil {
+		t.Fatalf("Failed to create message: %v", err)
+	}
+	if err := repo.UpsertTokenUsage(&database.TokenUsage{MessageID: messageID, SessionID: sessionID, TotalTokens: tokens, EstimatedCost: cost}); err != nil {
+		t.Fatalf("Failed to create token usage: %v", err)
+	}
+}
+
+func TestMonitor(t *testing.T) {
+	repo := setupTestRepo(t)
+	for _, user := range []string{"alice", "bob", "carol"} {
+		if err := repo.CreateAPIKey(user, &database.APIKey{Name: "laptop", Prefix: "csm_" + user, KeyHash: "hash-" + user, Scope: database.ScopeWrite}); err != nil {
+			t.Fatalf("Failed to create key: %v", err)
+		}
+	}
+
+	now := time.Date(2026, 2, 14, 15, 30, 0, 0, time.UTC)
+	addUsage(t, repo, "web-1", "web", now.Add(-time.Hour), 9000, 2)
+	addUsage(t, repo, "api-1", "api", now.Add(-time.Hour), 1000, 12)
+	addUsage(t, repo, "web-old", "web", now.AddDate(0, -1, 0), 50000, 50) // last month
+
+	if err := repo.SetUserQuota("alice", &database.UserQuota{MonthlyTokens: 10000, Projects: []string{"web"}}); err != nil {
+		t.Fatalf("Failed to set quota: %v", err)
+	}
+	if err := repo.SetUserQuota("bob", &database.UserQuota{MonthlyTokens: 100000, MonthlyCostUSD: 10, Projects: []string{"api"}}); err != nil {
+		t.Fatalf("Failed to set quota: %v", err)
+	}
+
+	monitor := NewMonitor(repo, logrus.New())
+	monitor.now = func() time.Time { return now }
+	var raised []Alert
+	monitor.OnAlert(func(alert Alert) { raised = append(raised, alert) })
+
+	statuses, err := monitor.Status()
+	if err != nil || len(statuses) != 3 {
+		t.Fatalf("Expected a status for each user, got %+v (%v)", statuses, err)
+	}
+	alice, bob, carol := statuses[0], statuses[1], statuses[2]
+	if alice.State != StateWarning || alice.UsedTokens != 9000 || *alice.RemainingTokens != 1000 || alice.RemainingCostUSD != nil {
+		t.Errorf("Expected alice warned with 1000 tokens left, got %+v", alice)
+	}
+	if bob.State != StateExceeded || *bob.RemainingCostUSD != 0 || bob.Percent != 120 {
+		t.Errorf("Expected bob over his cost quota, got %+v", bob)
+	}
+	if carol.State != StateUnlimited || carol.RemainingTokens != nil || carol.UsedTokens != 0 {
+		t.Errorf("Expected carol unlimited, got %+v", carol)
+	}
+
+	alerts, err := monitor.Check()
+	if err != nil {
+		t.Fatalf("Failed to check quotas: %v", err)
+	}
+	// alice's tokens at 80%, bob's cost at 80% and 100%
+	if len(alerts) != 3 || len(raised) != 3 {
+		t.Fatalf("Expected three alerts, got %+v", alerts)
+	}
+	if alerts[0].UserName != "alice" || alerts[0].Metric != database.QuotaTokens || alerts[0].Threshold != 80 {
+		t.Errorf("Unexpected first alert: %+v", alerts[0])
+	}
+	if alerts[2].UserName != "bob" || alerts[2].Metric != database.QuotaCost || alerts[2].Threshold != 100 || alerts[2].Limit != 10 {
+		t.Errorf("Unexpected last alert: %+v", alerts[2])
+	}
+
+	if alerts, _ := monitor.Check(); len(alerts) != 0 {
+		t.Errorf("Expected no repeated alerts within the month, got %+v", alerts)
+	}
+}
@@ -0,0 +1,105 @@
+package reports
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ksred/claude-session-manager/internal/config"
+)
+
+// WebhookPayload is the JSON body posted to a report's webhook
+type WebhookPayload struct {
+	Event     string    `json:"event"` // always report
+	Report    *Report   `json:"report"`
+	Format    string    `json:"format"`
+	Body      string    `json:"body"` // the report rendered in Format
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// deliver sends a rendered report to each of its destinations, reporting
+// rather than stopping at failures
+func (s *Scheduler) deliver(cfg config.ReportConfig, report *Report, format, body string) []Delivery {
+	deliveries := []Delivery{}
+	if len(cfg.Email) > 0 {
+		delivery := Delivery{Channel: "email", Target: strings.Join(cfg.Email, ", ")}
+		if err := s.email(cfg.Email, report, format, body); err != nil {
+			delivery.Error = err.Error()
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	if cfg.WebhookURL != "" {
+		delivery := Delivery{Channel: "webhook", Target: cfg.WebhookURL}
+		if err := s.post(cfg.WebhookURL, report, format, body); err != nil {
+			delivery.Error = err.Error()
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries
+}
+
+// email sends a report through the configured SMTP server
+func (s *Scheduler) email(to []string, report *Report, format, body string) error {
+	smtpCfg := s.cfg.SMTP
+	if smtpCfg.Host == "" || smtpCfg.From == "" {
+		return fmt.Errorf("reports.smtp has no host or from address")
+	}
+	var auth smtp.Auth
+	if smtpCfg.Username != "" {
+		auth = smtp.PlainAuth("", smtpCfg.Username, smtpCfg.Password, smtpCfg.Host)
+	}
+	addr := net.JoinHostPort(smtpCfg.Host, strconv.Itoa(smtpCfg.Port))
+	if err := s.sendMail(addr, auth, smtpCfg.From, to, message(smtpCfg.From, to, Title(report), format, body)); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	return nil
+}
+
+// message builds an email of one HTML or plain text part
+func message(from string, to []string, subject, format, body string) []byte {
+	contentType := "text/plain; charset=utf-8"
+	if format == FormatHTML {
+		contentType = "text/html; charset=utf-8"
+	}
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&b, "Content-Type: %s\r\n", contentType)
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(strings.ReplaceAll(body, "\r\n", "\n"), "\n", "\r\n"))
+	return b.Bytes()
+}
+
+// post sends a report to a webhook as JSON
+func (s *Scheduler) post(url string, report *Report, format, body string) error {
+	payload, err := json.Marshal(WebhookPayload{
+		Event:     "report",
+		Report:    report,
+		Format:    format,
+		Body:      body,
+		Timestamp: s.now().UTC(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+
+	resp, err := s.client.Post(url, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to post webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
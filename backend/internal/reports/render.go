@@ -0,0 +1,162 @@
+package reports
+
+import (
+	"fmt"
+	"html/template"
+	"strings"
+	"time"
+
+	"github.com/ksred/claude-session-manager/internal/locale"
+)
+
+// Render writes a report as Markdown or HTML, with costs and token counts
+// formatted by formatter
+func Render(report *Report, format string, formatter *locale.Formatter) (string, error) {
+	switch format {
+	case FormatMarkdown:
+		return renderMarkdown(report, formatter), nil
+	case FormatHTML:
+		var b strings.Builder
+		tmpl := template.Must(reportTemplate.Clone()).Funcs(templateFuncs(formatter))
+		if err := tmpl.Execute(&b, report); err != nil {
+			return "", fmt.Errorf("failed to render report: %w", err)
+		}
+		return b.String(), nil
+	}
+	return "", fmt.Errorf("unknown report format: %s", format)
+}
+
+// Title names a report and its period, such as "Weekly report: 2026-10-05 to
+// 2026-10-11"
+func Title(report *Report) string {
+	return fmt.Sprintf("%s report: %s", strings.ToUpper(report.Period[:1])+report.Period[1:], periodLabel(report))
+}
+
+// periodLabel is the days a report covers, inclusive
+func periodLabel(report *Report) string {
+	first := report.From.Format("2006-01-02")
+	last := report.To.Add(-time.Nanosecond).Format("2006-01-02")
+	if first == last {
+		return first
+	}
+	return first + " to " + last
+}
+
+// renderMarkdown writes a report as a Markdown document with tables
+func renderMarkdown(report *Report, f *locale.Formatter) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", Title(report))
+	fmt.Fprintf(&b, "- **Sessions:** %s\n", f.Tokens(int64(report.Sessions)))
+	fmt.Fprintf(&b, "- **Messages:** %s\n", f.Tokens(int64(report.Messages)))
+	fmt.Fprintf(&b, "- **Tokens:** %s\n", f.Tokens(int64(report.Tokens)))
+	fmt.Fprintf(&b, "- **Cost:** %s\n", f.Cost(report.Cost))
+	fmt.Fprintf(&b, "- **Cache savings:** %s\n", f.Cost(report.CacheSavings))
+
+	if len(report.TopProjects) > 0 {
+		fmt.Fprintf(&b, "\n## Top projects (%d of %d)\n\n", len(report.TopProjects), report.Projects)
+		b.WriteString("| Project | Sessions | Tokens | Cost | Cache savings |\n")
+		b.WriteString("|---|---:|---:|---:|---:|\n")
+		for _, project := range report.TopProjects {
+			fmt.Fprintf(&b, "| %s | %s | %s | %s | %s |\n", markdownCell(project.Name), f.Tokens(int64(project.Sessions)),
+				f.Tokens(int64(project.TotalTokens)), f.Cost(project.CostUSD), f.Cost(project.CacheSavings))
+		}
+	}
+
+	if len(report.Models) > 0 {
+		b.WriteString("\n## Models\n\n")
+		b.WriteString("| Model | Sessions | Tokens | Cost |\n")
+		b.WriteString("|---|---:|---:|---:|\n")
+		for _, model := range report.Models {
+			fmt.Fprintf(&b, "| %s | %s | %s | %s |\n", markdownCell(model.Name), f.Tokens(int64(model.Sessions)),
+				f.Tokens(int64(model.TotalTokens)), f.Cost(model.CostUSD))
+		}
+	}
+
+	if report.Period == Weekly && len(report.Days) > 0 {
+		b.WriteString("\n## Days (UTC)\n\n")
+		b.WriteString("| Day | Sessions | Tokens | Cost |\n")
+		b.WriteString("|---|---:|---:|---:|\n")
+		for _, day := range report.Days {
+			fmt.Fprintf(&b, "| %s | %s | %s | %s |\n", day.Name, f.Tokens(int64(day.Sessions)),
+				f.Tokens(int64(day.TotalTokens)), f.Cost(day.CostUSD))
+		}
+	}
+
+	if report.Sessions == 0 {
+		b.WriteString("\nNo sessions used tokens in this period.\n")
+	}
+	return b.String()
+}
+
+// markdownCell escapes the characters that would break a table cell
+func markdownCell(text string) string {
+	text = strings.ReplaceAll(text, "|", `\|`)
+	return strings.ReplaceAll(text, "\n", " ")
+}
+
+// templateFuncs binds the report template's formatting to a formatter
+func templateFuncs(f *locale.Formatter) template.FuncMap {
+	return template.FuncMap{
+		"title":  Title,
+		"cost":   f.Cost,
+		"count":  func(n int) string { return f.Tokens(int64(n)) },
+		"weekly": func(report *Report) bool { return report.Period == Weekly },
+	}
+}
+
+var reportTemplate = template.Must(template.New("report").Funcs(templateFuncs(locale.NewFormatter(locale.DefaultLocale, ""))).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{title .}}</title>
+<style>
+body { font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", sans-serif; max-width: 760px; margin: 2rem auto; padding: 0 1rem; color: #1f2328; }
+dl { display: grid; grid-template-columns: max-content auto; gap: .25rem 1rem; }
+dt { font-weight: 600; }
+table { border-collapse: collapse; width: 100%; margin: .5rem 0 1.5rem; }
+th, td { border-bottom: 1px solid #d0d7de; padding: .35rem .5rem; text-align: left; }
+td.n, th.n { text-align: right; }
+</style>
+</head>
+<body>
+<h1>{{title .}}</h1>
+<dl>
+<dt>Sessions</dt><dd>{{count .Sessions}}</dd>
+<dt>Messages</dt><dd>{{count .Messages}}</dd>
+<dt>Tokens</dt><dd>{{count .Tokens}}</dd>
+<dt>Cost</dt><dd>{{cost .Cost}}</dd>
+<dt>Cache savings</dt><dd>{{cost .CacheSavings}}</dd>
+</dl>
+{{- if .TopProjects}}
+<h2>Top projects ({{len .TopProjects}} of {{.Projects}})</h2>
+<table>
+<tr><th>Project</th><th class="n">Sessions</th><th class="n">Tokens</th><th class="n">Cost</th><th class="n">Cache savings</th></tr>
+{{- range .TopProjects}}
+<tr><td>{{.Name}}</td><td class="n">{{count .Sessions}}</td><td class="n">{{count .TotalTokens}}</td><td class="n">{{cost .CostUSD}}</td><td class="n">{{cost .CacheSavings}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+{{- if .Models}}
+<h2>Models</h2>
+<table>
+<tr><th>Model</th><th class="n">Sessions</th><th class="n">Tokens</th><th class="n">Cost</th></tr>
+{{- range .Models}}
+<tr><td>{{.Name}}</td><td class="n">{{count .Sessions}}</td><td class="n">{{count .TotalTokens}}</td><td class="n">{{cost .CostUSD}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+{{- if and (weekly .) .Days}}
+<h2>Days (UTC)</h2>
+<table>
+<tr><th>Day</th><th class="n">Sessions</th><th class="n">Tokens</th><th class="n">Cost</th></tr>
+{{- range .Days}}
+<tr><td>{{.Name}}</td><td class="n">{{count .Sessions}}</td><td class="n">{{count .TotalTokens}}</td><td class="n">{{cost .CostUSD}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+{{- if eq .Sessions 0}}
+<p>No sessions used tokens in this period.</p>
+{{- end}}
+</body>
+</html>
+`))
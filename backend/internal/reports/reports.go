@@ -0,0 +1,283 @@
+// Package reports builds the scheduled summaries of sessions, costs, top
+// projects and cache savings, renders them as Markdown or HTML and delivers
+// them by email or to a webhook.
+package reports
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/smtp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ksred/claude-session-manager/internal/config"
+	"github.com/ksred/claude-session-manager/internal/database"
+	"github.com/ksred/claude-session-manager/internal/locale"
+	"github.com/sirupsen/logrus"
+)
+
+// Report periods
+const (
+	Daily  = "daily"
+	Weekly = "weekly"
+)
+
+// Report formats
+const (
+	FormatMarkdown = "markdown"
+	FormatHTML     = "html"
+)
+
+// defaultTopProjects is how many projects a report lists when its
+// configuration doesn't say
+const defaultTopProjects = 5
+
+// ErrUnknownReport is returned when running a report that isn't configured
+var ErrUnknownReport = errors.New("report is not configured")
+
+// Store is where report figures are read from
+type Store interface {
+	GetCostBreakdown(groupBy string, filter database.CostBreakdownFilter) ([]database.CostBreakdown, error)
+}
+
+// Report summarises the sessions and spend of a period
+type Report struct {
+	Name         string                   `json:"name"`
+	Period       string                   `json:"period"`
+	From         time.Time                `json:"from"`
+	To           time.Time                `json:"to"`
+	Currency     string                   `json:"currency"`
+	Sessions     int                      `json:"sessions"`
+	Messages     int                      `json:"messages"`
+	Tokens       int                      `json:"tokens"`
+	Cost         float64                  `json:"cost"`
+	CacheSavings float64                  `json:"cache_savings"`
+	Projects     int                      `json:"projects"` // projects with usage, of which TopProjects are the most expensive
+	TopProjects  []database.CostBreakdown `json:"top_projects"`
+	Models       []database.CostBreakdown `json:"models"`
+	Days         []database.CostBreakdown `json:"days"`
+	GeneratedAt  time.Time                `json:"generated_at"`
+}
+
+// Delivery is the outcome of sending a report to one destination
+type Delivery struct {
+	Channel string `json:"channel"` // email or webhook
+	Target  string `json:"target"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Result is a report that was run, its rendered body and where it was sent
+type Result struct {
+	Report     *Report    `json:"report"`
+	Format     string     `json:"format"`
+	Body       string     `json:"body"`
+	Deliveries []Delivery `json:"deliveries"`
+}
+
+// Bounds returns the period a report run at now covers, in now's location:
+// the previous day for daily reports and the previous seven days for weekly
+// ones, ending at midnight
+func Bounds(period string, now time.Time) (time.Time, time.Time) {
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	if period == Weekly {
+		return to.AddDate(0, 0, -7), to
+	}
+	return to.AddDate(0, 0, -1), to
+}
+
+// Next returns when a report is next due after t, in t's location
+func Next(cfg config.ReportConfig, t time.Time) time.Time {
+	next := time.Date(t.Year(), t.Month(), t.Day(), cfg.Hour, 0, 0, 0, t.Location())
+	for !next.After(t) || (cfg.Period == Weekly && next.Weekday() != weekday(cfg.Weekday)) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
+
+// weekday parses a configured weekday, Monday when empty
+func weekday(name string) time.Weekday {
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if strings.EqualFold(day.String(), name) {
+			return day
+		}
+	}
+	return time.Monday
+}
+
+// Scheduler runs the configured reports when they are due
+type Scheduler struct {
+	store     Store
+	cfg       config.ReportsConfig
+	formatter *locale.Formatter
+	client    *http.Client
+	sendMail  func(addr string, auth smtp.Auth, from string, to []string, msg []byte) error
+	logger    *logrus.Logger
+	now       func() time.Time
+}
+
+// NewScheduler creates a scheduler for the reports in cfg, writing figures
+// with formatter
+func NewScheduler(store Store, cfg config.ReportsConfig, formatter *locale.Formatter, logger *logrus.Logger) *Scheduler {
+	return &Scheduler{
+		store:     store,
+		cfg:       cfg,
+		formatter: formatter,
+		client:    &http.Client{Timeout: 30 * time.Second},
+		sendMail:  smtp.SendMail,
+		logger:    logger,
+		now:       time.Now,
+	}
+}
+
+// Build gathers the figures of a report for the period ending at the last
+// midnight before now
+func (s *Scheduler) Build(cfg config.ReportConfig, now time.Time) (*Report, error) {
+	from, to := Bounds(cfg.Period, now)
+	filter := database.CostBreakdownFilter{From: from, To: to}
+
+	projects, err := s.store.GetCostBreakdown("project", filter)
+	if err != nil {
+		return nil, err
+	}
+	models, err := s.store.GetCostBreakdown("model", filter)
+	if err != nil {
+		return nil, err
+	}
+	days, err := s.store.GetCostBreakdown("day", filter)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(days, func(i, j int) bool { return days[i].Name < days[j].Name })
+
+	report := &Report{
+		Name:        cfg.Name,
+		Period:      cfg.Period,
+		From:        from,
+		To:          to,
+		Currency:    s.formatter.Metadata().Currency,
+		Projects:    len(projects),
+		Models:      models,
+		Days:        days,
+		GeneratedAt: s.now().UTC(),
+	}
+	// A session belongs to one project, so project rows add up to the totals
+	for _, project := range projects {
+		report.Sessions += project.Sessions
+		report.Messages += project.Messages
+		report.Tokens += project.TotalTokens
+		report.Cost += project.CostUSD
+		report.CacheSavings += project.CacheSavings
+	}
+	top := cfg.TopProjects
+	if top <= 0 {
+		top = defaultTopProjects
+	}
+	if len(projects) > top {
+		projects = projects[:top]
+	}
+	report.TopProjects = projects
+	return report, nil
+}
+
+// Run builds and renders the named report, or every report when name is
+// empty, for the period ending at the last midnight. Reports are delivered
+// unless deliver is false.
+func (s *Scheduler) Run(name string, deliver bool) ([]Result, error) {
+	var schedules []config.ReportConfig
+	for _, cfg := range s.cfg.Schedules {
+		if name == "" || cfg.Name == name {
+			schedules = append(schedules, cfg)
+		}
+	}
+	if len(schedules) == 0 {
+		return nil, ErrUnknownReport
+	}
+
+	results := make([]Result, 0, len(schedules))
+	for _, cfg := range schedules {
+		result, err := s.run(cfg, s.now(), deliver)
+		if err != nil {
+			return nil, fmt.Errorf("failed to run report %s: %w", cfg.Name, err)
+		}
+		results = append(results, *result)
+	}
+	return results, nil
+}
+
+// run builds, renders and optionally delivers one report
+func (s *Scheduler) run(cfg config.ReportConfig, now time.Time, deliver bool) (*Result, error) {
+	report, err := s.Build(cfg, now)
+	if err != nil {
+		return nil, err
+	}
+	format := cfg.Format
+	if format == "" {
+		format = FormatMarkdown
+	}
+	body, err := Render(report, format, s.formatter)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{Report: report, Format: format, Body: body, Deliveries: []Delivery{}}
+	if deliver {
+		result.Deliveries = s.deliver(cfg, report, format, body)
+	}
+	return result, nil
+}
+
+// Start sends each report when it is due until ctx is cancelled. Reports
+// that fell due while the server was stopped are not sent.
+func (s *Scheduler) Start(ctx context.Context) {
+	if len(s.cfg.Schedules) == 0 {
+		return
+	}
+	for {
+		now := s.now()
+		var due time.Time
+		for _, cfg := range s.cfg.Schedules {
+			if next := Next(cfg, now); due.IsZero() || next.Before(due) {
+				due = next
+			}
+		}
+
+		timer := time.NewTimer(due.Sub(now))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		for _, cfg := range s.cfg.Schedules {
+			if !Next(cfg, due.Add(-time.Second)).Equal(due) {
+				continue
+			}
+			result, err := s.run(cfg, due, true)
+			if err != nil {
+				s.logger.WithError(err).WithField("report", cfg.Name).Error("Failed to run scheduled report")
+				continue
+			}
+			s.logDeliveries(result)
+		}
+	}
+}
+
+// logDeliveries records where a scheduled report was sent
+func (s *Scheduler) logDeliveries(result *Result) {
+	for _, delivery := range result.Deliveries {
+		fields := logrus.Fields{
+			"report":  result.Report.Name,
+			"channel": delivery.Channel,
+			"target":  delivery.Target,
+		}
+		if delivery.Error != "" {
+			s.logger.WithFields(fields).WithField("error", delivery.Error).Warn("Failed to deliver report")
+		} else {
+			s.logger.WithFields(fields).Info("Delivered report")
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package reports
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/smtp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ksred/claude-session-manager/internal/config"
+	"github.com/ksred/claude-session-manager/internal/database"
+	"github.com/ksred/claude-session-manager/internal/locale"
+	"github.com/sirupsen/logrus"
+)
+
+type fakeStore struct {
+	filters []database.CostBreakdownFilter
+}
+
+func (s *fakeStore) GetCostBreakdown(groupBy string, filter database.CostBreakdownFilter) ([]database.CostBreakdown, error) {
+	s.filters = append(s.filters, filter)
+	switch groupBy {
+	case "project":
+		return []database.CostBreakdown{
+			{Name: "api", Sessions: 3, Messages: 40, TotalTokens: 120000, CostUSD: 2.5, CacheSavings: 0.75},
+			{Name: "web|app", Sessions: 2, Messages: 10, TotalTokens: 30000, CostUSD: 1, CacheSavings: 0.25},
+			{Name: "docs", Sessions: 1, Messages: 2, TotalTokens: 1000, CostUSD: 0.05},
+		}, nil
+	case "model":
+		return []database.CostBreakdown{{Name: "claude-sonnet", Sessions: 6, TotalTokens: 151000, CostUSD: 3.55}}, nil
+	}
+	return []database.CostBreakdown{
+		{Name: "2026-10-13", Sessions: 2, TotalTokens: 1000, CostUSD: 0.05},
+		{Name: "2026-10-12", Sessions: 4, TotalTokens: 150000, CostUSD: 3.5},
+	}, nil
+}
+
+func TestBoundsAndNext(t *testing.T) {
+	now := time.Date(2026, 10, 15, 9, 30, 0, 0, time.UTC) // a Thursday
+
+	from, to := Bounds(Daily, now)
+	if !from.Equal(time.Date(2026, 10, 14, 0, 0, 0, 0, time.UTC)) || !to.Equal(time.Date(2026, 10, 15, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected the previous day, got %v to %v", from, to)
+	}
+	if from, _ := Bounds(Weekly, now); !from.Equal(time.Date(2026, 10, 8, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected the previous seven days, got %v", from)
+	}
+
+	daily := config.ReportConfig{Period: Daily, Hour: 9}
+	if next := Next(daily, now); !next.Equal(time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected tomorrow at 9, got %v", next)
+	}
+	if next := Next(daily, now.Add(-time.Hour)); !next.Equal(time.Date(2026, 10, 15, 9, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected today at 9, got %v", next)
+	}
+	weekly := config.ReportConfig{Period: Weekly, Weekday: "Monday", Hour: 8}
+	if next := Next(weekly, now); !next.Equal(time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected next Monday at 8, got %v", next)
+	}
+}
+
+func TestScheduler_Build(t *testing.T) {
+	store := &fakeStore{}
+	scheduler := NewScheduler(store, config.ReportsConfig{}, locale.NewFormatter("en-US", "USD"), logrus.New())
+	now := time.Date(2026, 10, 15, 9, 0, 0, 0, time.UTC)
+
+	report, err := scheduler.Build(config.ReportConfig{Name: "weekly", Period: Weekly, TopProjects: 2}, now)
+	if err != nil {
+		t.Fatalf("Failed to build report: %v", err)
+	}
+	if report.Sessions != 6 || report.Messages != 52 || report.Tokens != 151000 || report.Projects != 3 {
+		t.Errorf("Expected the project rows added up, got %+v", report)
+	}
+	if report.Cost != 3.55 || report.CacheSavings != 1 {
+		t.Errorf("Expected cost 3.55 and savings 1, got %v and %v", report.Cost, report.CacheSavings)
+	}
+	if len(report.TopProjects) != 2 || report.TopProjects[0].Name != "api" {
+		t.Errorf("Expected the two most expensive projects, got %+v", report.TopProjects)
+	}
+	if report.Days[0].Name != "2026-10-12" {
+		t.Errorf("Expected days in order, got %+v", report.Days)
+	}
+	if !store.filters[0].From.Equal(time.Date(2026, 10, 8, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected the previous week to be queried, got %+v", store.filters[0])
+	}
+
+	markdown, err := Render(report, FormatMarkdown, scheduler.formatter)
+	if err != nil {
+		t.Fatalf("Failed to render Markdown: %v", err)
+	}
+	for _, want := range []string{"# Weekly report: 2026-10-08 to 2026-10-14", "- **Cost:** $3.55", "| web\\|app | 2 | 30,000 | $1.00 | $0.25 |", "## Days (UTC)"} {
+		if !strings.Contains(markdown, want) {
+			t.Errorf("Expected the Markdown to contain %q:\n%s", want, markdown)
+		}
+	}
+
+	html, err := Render(report, FormatHTML, locale.NewFormatter("de-DE", "EUR"))
+	if err != nil {
+		t.Fatalf("Failed to render HTML: %v", err)
+	}
+	if !strings.Contains(html, "<td>web|app</td>") || !strings.Contains(html, "3,55\u00a0€") {
+		t.Errorf("Expected the HTML tables in the formatter's locale:\n%s", html)
+	}
+}
+
+func TestScheduler_Run(t *testing.T) {
+	var payload WebhookPayload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&payload)
+	}))
+	defer server.Close()
+
+	cfg := config.ReportsConfig{
+		SMTP: config.SMTPConfig{Host: "mail.example.com", Port: 587, From: "reports@example.com"},
+		Schedules: []config.ReportConfig{
+			{Name: "daily", Period: Daily, Format: FormatHTML, Email: []string{"team@example.com"}, WebhookURL: server.URL},
+			{Name: "weekly", Period: Weekly, WebhookURL: server.URL + "/missing"},
+		},
+	}
+	scheduler := NewScheduler(&fakeStore{}, cfg, locale.NewFormatter("en-US", "USD"), logrus.New())
+	var sent struct {
+		addr string
+		to   []string
+		msg  string
+	}
+	scheduler.sendMail = func(addr string, auth smtp.Auth, from string, to []string, msg []byte) error {
+		sent.addr, sent.to, sent.msg = addr, to, string(msg)
+		return nil
+	}
+
+	results, err := scheduler.Run("daily", true)
+	if err != nil {
+		t.Fatalf("Failed to run report: %v", err)
+	}
+	if len(results) != 1 || len(results[0].Deliveries) != 2 {
+		t.Fatalf("Expected the daily report emailed and posted, got %+v", results)
+	}
+	for _, delivery := range results[0].Deliveries {
+		if delivery.Error != "" {
+			t.Errorf("Expected %s delivery to succeed, got %s", delivery.Channel, delivery.Error)
+		}
+	}
+	if sent.addr != "mail.example.com:587" || !strings.Contains(sent.msg, "Content-Type: text/html") || !strings.Contains(sent.msg, "Daily report") {
+		t.Errorf("Unexpected email to %s:\n%s", sent.addr, sent.msg)
+	}
+	if payload.Event != "report" || payload.Report.Name != "daily" || payload.Format != FormatHTML {
+		t.Errorf("Unexpected webhook payload: %+v", payload)
+	}
+
+	results, err = scheduler.Run("", false)
+	if err != nil || len(results) != 2 || len(results[1].Deliveries) != 0 {
+		t.Errorf("Expected every report rendered without delivery, got %+v, %v", results, err)
+	}
+	if _, err := scheduler.Run("monthly", true); err != ErrUnknownReport {
+		t.Errorf("Expected an unknown report to fail, got %v", err)
+	}
+}
@@ -0,0 +1,82 @@
+// Package retention applies the configured retention policy, deleting
+// sessions that have been inactive for longer than the policy keeps them.
+package retention
+
+import (
+	"context"
+	"time"
+
+	"github.com/ksred/claude-session-manager/internal/config"
+	"github.com/ksred/claude-session-manager/internal/database"
+	"github.com/sirupsen/logrus"
+)
+
+// Store is the storage sessions are pruned from
+type Store interface {
+	Prune(filter database.PruneFilter) (*database.PrunePreview, error)
+}
+
+// Janitor prunes expired sessions on an interval
+type Janitor struct {
+	store    Store
+	maxAge   time.Duration
+	interval time.Duration
+	logger   *logrus.Logger
+	now      func() time.Time
+}
+
+// NewJanitor creates a janitor for the retention policy in cfg
+func NewJanitor(store Store, cfg config.RetentionConfig, logger *logrus.Logger) *Janitor {
+	return &Janitor{
+		store:    store,
+		maxAge:   time.Duration(cfg.MaxAgeDays) * 24 * time.Hour,
+		interval: time.Duration(cfg.Interval) * time.Hour,
+		logger:   logger,
+		now:      time.Now,
+	}
+}
+
+// Filter returns the sessions a run now would prune: those inactive since
+// before the retention period
+func (j *Janitor) Filter() database.PruneFilter {
+	return database.PruneFilter{Before: j.now().UTC().Add(-j.maxAge)}
+}
+
+// Run prunes the sessions that have expired, skipping those under legal
+// hold, and returns what was pruned
+func (j *Janitor) Run() (*database.PrunePreview, error) {
+	pruned, err := j.store.Prune(j.Filter())
+	if err != nil {
+		return nil, err
+	}
+	if len(pruned.Sessions) > 0 || len(pruned.Held) > 0 {
+		j.logger.WithFields(logrus.Fields{
+			"sessions":   len(pruned.Sessions),
+			"messages":   pruned.Messages,
+			"size_bytes": pruned.SizeBytes,
+			"held":       len(pruned.Held),
+		}).Info("Pruned sessions past the retention period")
+	}
+	return pruned, nil
+}
+
+// Start prunes immediately and then on each interval until ctx is cancelled
+func (j *Janitor) Start(ctx context.Context) {
+	if _, err := j.Run(); err != nil {
+		j.logger.WithError(err).Error("Failed to prune expired sessions")
+	}
+
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := j.Run(); err != nil {
+				j.logger.WithError(err).Error("Failed to prune expired sessions")
+			}
+		}
+	}
+}
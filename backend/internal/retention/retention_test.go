@@ -0,0 +1,41 @@
+package retention
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ksred/claude-session-manager/internal/config"
+	"github.com/ksred/claude-session-manager/internal/database"
+	"github.com/sirupsen/logrus"
+)
+
+type fakeStore struct {
+	filters []database.PruneFilter
+}
+
+func (s *fakeStore) Prune(filter database.PruneFilter) (*database.PrunePreview, error) {
+	s.filters = append(s.filters, filter)
+	return &database.PrunePreview{
+		Filter:   filter,
+		Sessions: []database.PruneCandidate{{SessionID: "old"}},
+		Held:     []database.PruneCandidate{},
+	}, nil
+}
+
+func TestJanitor_Run(t *testing.T) {
+	store := &fakeStore{}
+	janitor := NewJanitor(store, config.RetentionConfig{Enabled: true, MaxAgeDays: 30, Interval: 24}, logrus.New())
+	now := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+	janitor.now = func() time.Time { return now }
+
+	pruned, err := janitor.Run()
+	if err != nil {
+		t.Fatalf("Failed to run janitor: %v", err)
+	}
+	if len(pruned.Sessions) != 1 {
+		t.Errorf("Expected the pruned sessions, got %+v", pruned)
+	}
+	if len(store.filters) != 1 || !store.filters[0].Before.Equal(now.AddDate(0, 0, -30)) {
+		t.Errorf("Expected sessions inactive for 30 days to be pruned, got %+v", store.filters)
+	}
+}
@@ -0,0 +1,199 @@
+// Package routing finds past sessions a cheaper model would likely have
+// handled just as well and estimates what running them on it would have
+// saved, grouped by project so whole workflows can be switched.
+package routing
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/ksred/claude-session-manager/internal/database"
+	"github.com/ksred/claude-session-manager/internal/pricing"
+)
+
+// DefaultTarget is the model sessions are repriced at when none is given
+const DefaultTarget = "claude-3-5-haiku"
+
+// Heuristics a session must meet to be flagged. Sessions that used no
+// tools, sent few prompts and got short answers are simple question and
+// answer exchanges, which smaller models handle well.
+type Heuristics struct {
+	MaxPrompts      int `json:"max_prompts"`
+	MaxToolUses     int `json:"max_tool_uses"`
+	MaxOutputTokens int `json:"max_output_tokens"`
+}
+
+// DefaultHeuristics are deliberately conservative so recommendations are
+// likely to hold
+var DefaultHeuristics = Heuristics{
+	MaxPrompts:      3,
+	MaxToolUses:     0,
+	MaxOutputTokens: 4000,
+}
+
+// Confidence levels of a recommendation
+const (
+	ConfidenceHigh   = "high"   // a single short prompt and answer
+	ConfidenceMedium = "medium" // a short exchange
+)
+
+// Recommendation is a session that would likely have sufficed on the target
+// model
+type Recommendation struct {
+	SessionID     string   `json:"session_id"`
+	ProjectName   string   `json:"project_name"`
+	Model         string   `json:"model"`
+	Prompts       int      `json:"prompts"`
+	ToolUses      int      `json:"tool_uses"`
+	OutputTokens  int      `json:"output_tokens"`
+	CostUSD       float64  `json:"cost_usd"`
+	TargetCostUSD float64  `json:"target_cost_usd"`
+	SavingsUSD    float64  `json:"savings_usd"`
+	Confidence    string   `json:"confidence"`
+	Reasons       []string `json:"reasons"`
+}
+
+// Workflow is one project's share of the potential savings
+type Workflow struct {
+	ProjectName string  `json:"project_name"`
+	Sessions    int     `json:"sessions"`
+	Candidates  int     `json:"candidates"`
+	Share       float64 `json:"share"` // fraction of the project's sessions flagged
+	CostUSD     float64 `json:"cost_usd"`
+	SavingsUSD  float64 `json:"savings_usd"`
+}
+
+// Report is the routing recommendations for a period
+type Report struct {
+	From             time.Time        `json:"from"`
+	To               time.Time        `json:"to"`
+	TargetModel      string           `json:"target_model"`
+	Heuristics       Heuristics       `json:"heuristics"`
+	SessionsAnalyzed int              `json:"sessions_analyzed"`
+	Candidates       int              `json:"candidates"`
+	CostUSD          float64          `json:"cost_usd"`
+	SavingsUSD       float64          `json:"savings_usd"`
+	Workflows        []Workflow       `json:"workflows"`
+	Sessions         []Recommendation `json:"sessions"`
+	GeneratedAt      time.Time        `json:"generated_at"`
+}
+
+// Analyze flags the sessions meeting the heuristics that would have cost
+// less on the target model, keeping the limit with the largest savings.
+// Sessions already on a model no more expensive than the target are skipped.
+func Analyze(usage []database.SessionModelUsage, table *pricing.Table, target string, heuristics Heuristics, from, to time.Time, limit int) Report {
+	targetPrice := table.Lookup(target)
+	report := Report{
+		From:        from,
+		To:          to,
+		TargetModel: target,
+		Heuristics:  heuristics,
+		Workflows:   []Workflow{},
+		Sessions:    []Recommendation{},
+		GeneratedAt: time.Now().UTC(),
+	}
+
+	workflows := make(map[string]*Workflow)
+	for _, session := range usage {
+		report.SessionsAnalyzed++
+		report.CostUSD += session.CostUSD
+
+		workflow, ok := workflows[session.ProjectName]
+		if !ok {
+			workflow = &Workflow{ProjectName: session.ProjectName}
+			workflows[session.ProjectName] = workflow
+		}
+		workflow.Sessions++
+		workflow.CostUSD += session.CostUSD
+
+		recommendation, ok := recommend(session, table, targetPrice, heuristics)
+		if !ok {
+			continue
+		}
+		report.Candidates++
+		report.SavingsUSD += recommendation.SavingsUSD
+		workflow.Candidates++
+		workflow.SavingsUSD += recommendation.SavingsUSD
+		report.Sessions = append(report.Sessions, recommendation)
+	}
+
+	for _, workflow := range workflows {
+		if workflow.Candidates == 0 {
+			continue
+		}
+		workflow.Share = round(float64(workflow.Candidates)/float64(workflow.Sessions), 3)
+		workflow.SavingsUSD = round(workflow.SavingsUSD, 6)
+		report.Workflows = append(report.Workflows, *workflow)
+	}
+	sort.Slice(report.Workflows, func(i, j int) bool {
+		return report.Workflows[i].SavingsUSD > report.Workflows[j].SavingsUSD
+	})
+	sort.Slice(report.Sessions, func(i, j int) bool {
+		return report.Sessions[i].SavingsUSD > report.Sessions[j].SavingsUSD
+	})
+	if limit > 0 && len(report.Sessions) > limit {
+		report.Sessions = report.Sessions[:limit]
+	}
+	report.SavingsUSD = round(report.SavingsUSD, 6)
+	return report
+}
+
+// recommend checks a session against the heuristics and prices it on the
+// target model
+func recommend(session database.SessionModelUsage, table *pricing.Table, target pricing.Price, heuristics Heuristics) (Recommendation, bool) {
+	if session.Prompts == 0 || session.Prompts > heuristics.MaxPrompts ||
+		session.ToolUses > heuristics.MaxToolUses ||
+		session.OutputTokens > heuristics.MaxOutputTokens {
+		return Recommendation{}, false
+	}
+	if current := table.Lookup(session.Model); current.Input <= target.Input && current.Output <= target.Output {
+		return Recommendation{}, false
+	}
+
+	targetCost := target.Cost(pricing.Usage{
+		InputTokens:              session.InputTokens,
+		OutputTokens:             session.OutputTokens,
+		CacheCreationInputTokens: session.CacheCreationInputTokens,
+		CacheReadInputTokens:     session.CacheReadInputTokens,
+	})
+	savings := session.CostUSD - targetCost
+	if savings <= 0 {
+		return Recommendation{}, false
+	}
+
+	reasons := []string{
+		fmt.Sprintf("%d prompt(s)", session.Prompts),
+		fmt.Sprintf("%d output tokens", session.OutputTokens),
+	}
+	if session.ToolUses == 0 {
+		reasons = append(reasons, "no tool use")
+	} else {
+		reasons = append(reasons, fmt.Sprintf("%d tool use(s)", session.ToolUses))
+	}
+
+	confidence := ConfidenceMedium
+	if session.Prompts == 1 && session.ToolUses == 0 && session.OutputTokens <= heuristics.MaxOutputTokens/4 {
+		confidence = ConfidenceHigh
+	}
+
+	return Recommendation{
+		SessionID:     session.SessionID,
+		ProjectName:   session.ProjectName,
+		Model:         session.Model,
+		Prompts:       session.Prompts,
+		ToolUses:      session.ToolUses,
+		OutputTokens:  session.OutputTokens,
+		CostUSD:       session.CostUSD,
+		TargetCostUSD: round(targetCost, 6),
+		SavingsUSD:    round(savings, 6),
+		Confidence:    confidence,
+		Reasons:       reasons,
+	}, true
+}
+
+func round(value float64, places int) float64 {
+	scale := math.Pow(10, float64(places))
+	return math.Round(value*scale) / scale
+}
@@ -0,0 +1,65 @@
+package routing
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ksred/claude-session-manager/internal/database"
+	"github.com/ksred/claude-session-manager/internal/pricing"
+)
+
+func TestAnalyze(t *testing.T) {
+	table := pricing.NewTable()
+	sonnet := func(id, project string, prompts, tools, input, output int) database.SessionModelUsage {
+		usage := database.SessionModelUsage{
+			SessionID:    id,
+			ProjectName:  project,
+			Model:        "claude-sonnet-4-20250514",
+			Prompts:      prompts,
+			ToolUses:     tools,
+			InputTokens:  input,
+			OutputTokens: output,
+		}
+		usage.CostUSD = table.Cost(usage.Model, pricing.Usage{InputTokens: input, OutputTokens: output})
+		return usage
+	}
+
+	usage := []database.SessionModelUsage{
+		sonnet("quick", "docs", 1, 0, 2000, 500),
+		sonnet("chat", "docs", 3, 0, 20000, 3000),
+		sonnet("refactor", "docs", 1, 12, 50000, 2000),
+		sonnet("long", "api", 10, 0, 50000, 3000),
+		sonnet("verbose", "api", 1, 0, 5000, 9000),
+		{SessionID: "haiku", ProjectName: "api", Model: "claude-3-5-haiku-20241022", Prompts: 1, InputTokens: 100, OutputTokens: 100, CostUSD: 0.001},
+	}
+
+	from := time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)
+	report := Analyze(usage, table, DefaultTarget, DefaultHeuristics, from, from.AddDate(0, 1, 0), 10)
+
+	if report.SessionsAnalyzed != 6 || report.Candidates != 2 {
+		t.Fatalf("Expected 2 of 6 sessions flagged, got %d of %d: %+v", report.Candidates, report.SessionsAnalyzed, report.Sessions)
+	}
+	if report.Sessions[0].SessionID != "chat" || report.Sessions[1].SessionID != "quick" {
+		t.Errorf("Expected sessions by savings, got %+v", report.Sessions)
+	}
+	if report.Sessions[1].Confidence != ConfidenceHigh || report.Sessions[0].Confidence != ConfidenceMedium {
+		t.Errorf("Unexpected confidence: %+v", report.Sessions)
+	}
+
+	// 2000 input and 500 output tokens cost $0.0135 on Sonnet and $0.0036 on Haiku
+	if got := report.Sessions[1].SavingsUSD; got != 0.0099 {
+		t.Errorf("Expected savings of 0.0099, got %v", got)
+	}
+
+	if len(report.Workflows) != 1 || report.Workflows[0].ProjectName != "docs" || report.Workflows[0].Sessions != 3 || report.Workflows[0].Candidates != 2 {
+		t.Errorf("Expected only docs as a workflow to switch, got %+v", report.Workflows)
+	}
+	if report.Workflows[0].Share != 0.667 {
+		t.Errorf("Expected 2 of 3 docs sessions flagged, got %v", report.Workflows[0].Share)
+	}
+
+	limited := Analyze(usage, table, DefaultTarget, DefaultHeuristics, from, from.AddDate(0, 1, 0), 1)
+	if len(limited.Sessions) != 1 || limited.Candidates != 2 || limited.SavingsUSD != report.SavingsUSD {
+		t.Errorf("Expected the limit to apply only to the sessions listed, got %+v", limited)
+	}
+}
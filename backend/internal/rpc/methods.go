@@ -0,0 +1,220 @@
+package rpc
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/ksred/claude-session-manager/internal/database"
+)
+
+// defaultLimit is used when a list method is called without a limit
+const defaultLimit = 20
+
+type limitParams struct {
+	Limit int `json:"limit"`
+}
+
+func (p limitParams) limit() int {
+	if p.Limit <= 0 {
+		return defaultLimit
+	}
+	if p.Limit > 100 {
+		return 100
+	}
+	return p.Limit
+}
+
+type sessionParams struct {
+	ID string `json:"id"`
+}
+
+type todosParams struct {
+	SessionID string `json:"session_id"`
+	Status    string `json:"status"`
+	Limit     int    `json:"limit"`
+}
+
+type statuslineParams struct {
+	SessionID string `json:"session_id"`
+}
+
+// MetricsSummary is the result of metrics/summary
+type MetricsSummary struct {
+	TotalSessions          int            `json:"total_sessions"`
+	ActiveSessions         int            `json:"active_sessions"`
+	TotalMessages          int            `json:"total_messages"`
+	TotalTokensUsed        int            `json:"total_tokens_used"`
+	TotalEstimatedCost     float64        `json:"total_estimated_cost"`
+	AverageSessionDuration float64        `json:"average_session_duration_minutes"`
+	MostUsedModel          string         `json:"most_used_model"`
+	ModelUsage             map[string]int `json:"model_usage"`
+}
+
+// Statusline is the result of statusline
+type Statusline struct {
+	SessionID       string   `json:"session_id,omitempty"`
+	SessionTokens   int      `json:"session_tokens"`
+	SessionCostUSD  float64  `json:"session_cost_usd"`
+	TodayTokens     int      `json:"today_tokens"`
+	TodayCostUSD    float64  `json:"today_cost_usd"`
+	Currency        string   `json:"currency"`
+	DailyBudget     *float64 `json:"daily_budget,omitempty"`
+	BudgetRemaining *float64 `json:"budget_remaining,omitempty"`
+}
+
+func (s *Server) initialize(params json.RawMessage) (interface{}, error) {
+	return map[string]interface{}{
+		"serverInfo": map[string]string{
+			"name":    "claude-session-manager",
+			"version": "1.0.0",
+		},
+		"methods": s.Methods(),
+	}, nil
+}
+
+func (s *Server) shutdownMethod(params json.RawMessage) (interface{}, error) {
+	s.shutdown = true
+	return nil, nil
+}
+
+func (s *Server) listSessions(params json.RawMessage) (interface{}, error) {
+	var p limitParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	summaries, err := s.repo.GetRecentSessions(p.limit(), false)
+	if err != nil {
+		return nil, err
+	}
+	return s.toResponses(summaries)
+}
+
+func (s *Server) activeSessions(params json.RawMessage) (interface{}, error) {
+	summaries, err := s.repo.GetActiveSessions()
+	if err != nil {
+		return nil, err
+	}
+	return s.toResponses(summaries)
+}
+
+func (s *Server) getSession(params json.RawMessage) (interface{}, error) {
+	var p sessionParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if p.ID == "" {
+		return nil, &Error{Code: CodeInvalidParams, Message: "id is required"}
+	}
+	summary, err := s.repo.GetSessionByID(p.ID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return nil, &Error{Code: CodeNotFound, Message: "Session not found"}
+		}
+		return nil, err
+	}
+	return s.adapter.SessionSummaryToSessionResponse(summary)
+}
+
+func (s *Server) sessionTodos(params json.RawMessage) (interface{}, error) {
+	var p todosParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if p.Status != "" && p.Status != database.TodoStatusPending &&
+		p.Status != database.TodoStatusInProgress && p.Status != database.TodoStatusCompleted {
+		return nil, &Error{Code: CodeInvalidParams, Message: "status must be pending, in_progress or completed"}
+	}
+	return s.repo.GetTodos(p.SessionID, p.Status, limitParams{Limit: p.Limit}.limit())
+}
+
+func (s *Server) metricsSummary(params json.RawMessage) (interface{}, error) {
+	var summary MetricsSummary
+	var err error
+	if summary.TotalSessions, err = s.repo.GetTotalSessions(false); err != nil {
+		return nil, err
+	}
+	if summary.ActiveSessions, err = s.repo.GetActiveSessionsCount(false); err != nil {
+		return nil, err
+	}
+	if summary.TotalMessages, err = s.repo.GetTotalMessages(false); err != nil {
+		return nil, err
+	}
+	tokenUsage, err := s.repo.GetOverallTokenUsage(false)
+	if err != nil {
+		return nil, err
+	}
+	summary.TotalTokensUsed = tokenUsage.TotalTokens
+	if summary.TotalEstimatedCost, err = s.repo.GetEstimatedCost(false); err != nil {
+		return nil, err
+	}
+	if summary.AverageSessionDuration, err = s.repo.GetAverageSessionDuration(false); err != nil {
+		return nil, err
+	}
+	if summary.MostUsedModel, err = s.repo.GetMostUsedModel(false); err != nil {
+		return nil, err
+	}
+	if summary.ModelUsage, err = s.repo.GetModelUsage(false); err != nil {
+		return nil, err
+	}
+	return summary, nil
+}
+
+func (s *Server) recentActivity(params json.RawMessage) (interface{}, error) {
+	var p limitParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	entries, err := s.repo.GetRecentActivity(p.limit())
+	if err != nil {
+		return nil, err
+	}
+	activity := make([]database.ActivityEntry, 0, len(entries))
+	for _, entry := range entries {
+		activity = append(activity, s.adapter.ActivityLogEntryToAPIActivityEntry(entry))
+	}
+	return activity, nil
+}
+
+func (s *Server) statusline(params json.RawMessage) (interface{}, error) {
+	var p statuslineParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	dayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	usage, err := s.repo.GetStatuslineUsage(p.SessionID, dayStart)
+	if err != nil {
+		return nil, err
+	}
+
+	result := Statusline{
+		SessionID:      p.SessionID,
+		SessionTokens:  usage.SessionTokens,
+		SessionCostUSD: usage.SessionCostUSD,
+		TodayTokens:    usage.TodayTokens,
+		TodayCostUSD:   usage.TodayCostUSD,
+		Currency:       s.options.Currency,
+	}
+	if s.options.DailyBudget > 0 {
+		budget := s.options.DailyBudget
+		remaining := budget - usage.TodayCostUSD
+		result.DailyBudget = &budget
+		result.BudgetRemaining = &remaining
+	}
+	return result, nil
+}
+
+// toResponses converts summaries to the session objects the HTTP API returns
+func (s *Server) toResponses(summaries []*database.SessionSummary) ([]*database.SessionResponse, error) {
+	responses := make([]*database.SessionResponse, 0, len(summaries))
+	for _, summary := range summaries {
+		response, err := s.adapter.SessionSummaryToSessionResponse(summary)
+		if err != nil {
+			return nil, err
+		}
+		responses = append(responses, response)
+	}
+	return responses, nil
+}
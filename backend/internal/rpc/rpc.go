@@ -0,0 +1,245 @@
+// Package rpc serves the core session queries as JSON-RPC 2.0 over stdio,
+// framed with Content-Length headers the way language servers are, so editor
+// plugins can embed session stats without running the HTTP server.
+package rpc
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ksred/claude-session-manager/internal/database"
+	"github.com/sirupsen/logrus"
+)
+
+// Version is the JSON-RPC version spoken by the server
+const Version = "2.0"
+
+// maxMessageSize bounds the body of a single request
+const maxMessageSize = 10 << 20
+
+// Standard JSON-RPC error codes, plus CodeNotFound for a missing session
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+	CodeNotFound       = -32001
+)
+
+// Request is a JSON-RPC request or, without an ID, a notification
+type Request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// Response is a JSON-RPC response carrying either a result or an error
+type Response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+// Error is a JSON-RPC error object
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("rpc error %d: %s", e.Code, e.Message)
+}
+
+// Options configures the figures the server reports
+type Options struct {
+	// DailyBudget is reported by the statusline method; 0 leaves it out
+	DailyBudget float64
+	Currency    string
+}
+
+type method func(params json.RawMessage) (interface{}, error)
+
+// Server answers JSON-RPC requests from the session repository
+type Server struct {
+	repo     *database.SessionRepository
+	adapter  *database.APIAdapter
+	options  Options
+	logger   *logrus.Logger
+	methods  map[string]method
+	writeMu  sync.Mutex
+	shutdown bool
+}
+
+// NewServer creates a new JSON-RPC server
+func NewServer(repo *database.SessionRepository, options Options, logger *logrus.Logger) *Server {
+	s := &Server{
+		repo:    repo,
+		adapter: database.NewAPIAdapter(repo),
+		options: options,
+		logger:  logger,
+	}
+	s.methods = map[string]method{
+		"initialize":      s.initialize,
+		"shutdown":        s.shutdownMethod,
+		"sessions/list":   s.listSessions,
+		"sessions/active": s.activeSessions,
+		"sessions/get":    s.getSession,
+		"sessions/todos":  s.sessionTodos,
+		"metrics/summary": s.metricsSummary,
+		"activity/recent": s.recentActivity,
+		"statusline":      s.statusline,
+	}
+	return s
+}
+
+// Methods returns the names of the methods the server answers
+func (s *Server) Methods() []string {
+	names := make([]string, 0, len(s.methods))
+	for name := range s.methods {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Serve reads requests from r and writes responses to w until r is closed or
+// an exit notification is received. Requests are answered in order.
+func (s *Server) Serve(r io.Reader, w io.Writer) error {
+	reader := bufio.NewReader(r)
+	for {
+		body, err := ReadMessage(reader)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var req Request
+		if err := json.Unmarshal(body, &req); err != nil {
+			s.write(w, Response{JSONRPC: Version, ID: json.RawMessage("null"), Error: &Error{Code: CodeParseError, Message: "Parse error"}})
+			continue
+		}
+		if req.Method == "exit" {
+			return nil
+		}
+
+		resp, ok := s.Handle(req)
+		if ok {
+			if err := s.write(w, resp); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// Handle answers a single request. ok is false for notifications, which get
+// no response.
+func (s *Server) Handle(req Request) (resp Response, ok bool) {
+	notification := len(req.ID) == 0
+	resp = Response{JSONRPC: Version, ID: req.ID}
+	if notification {
+		resp.ID = json.RawMessage("null")
+	}
+
+	if req.JSONRPC != Version || req.Method == "" {
+		resp.Error = &Error{Code: CodeInvalidRequest, Message: "Invalid request"}
+		return resp, !notification
+	}
+
+	m, exists := s.methods[req.Method]
+	if !exists {
+		resp.Error = &Error{Code: CodeMethodNotFound, Message: "Method not found: " + req.Method}
+		return resp, !notification
+	}
+	if s.shutdown && req.Method != "shutdown" {
+		resp.Error = &Error{Code: CodeInvalidRequest, Message: "Server is shutting down"}
+		return resp, !notification
+	}
+
+	result, err := m(req.Params)
+	if err != nil {
+		if rpcErr, isRPC := err.(*Error); isRPC {
+			resp.Error = rpcErr
+		} else {
+			s.logger.WithError(err).WithField("method", req.Method).Error("Failed to handle RPC request")
+			resp.Error = &Error{Code: CodeInternalError, Message: "Internal error"}
+		}
+		return resp, !notification
+	}
+	if result == nil {
+		// A null result must still be sent, so it can't be omitted
+		result = json.RawMessage("null")
+	}
+	resp.Result = result
+	return resp, !notification
+}
+
+// ReadMessage reads one Content-Length framed message
+func ReadMessage(r *bufio.Reader) ([]byte, error) {
+	headers, err := textproto.NewReader(r).ReadMIMEHeader()
+	if err != nil {
+		if err == io.EOF && len(headers) == 0 {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("failed to read message header: %w", err)
+	}
+
+	length, err := strconv.Atoi(strings.TrimSpace(headers.Get("Content-Length")))
+	if err != nil || length < 0 {
+		return nil, fmt.Errorf("invalid Content-Length header: %q", headers.Get("Content-Length"))
+	}
+	if length > maxMessageSize {
+		return nil, fmt.Errorf("message of %d bytes exceeds the %d byte limit", length, maxMessageSize)
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, fmt.Errorf("failed to read message body: %w", err)
+	}
+	return body, nil
+}
+
+// write sends a response with its Content-Length header
+func (s *Server) write(w io.Writer, resp Response) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	return WriteMessage(w, resp)
+}
+
+// WriteMessage encodes v as JSON and writes it with its Content-Length
+// header. Callers serialize concurrent writes to the same writer.
+func WriteMessage(w io.Writer, v interface{}) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode message: %w", err)
+	}
+	if _, err := fmt.Fprintf(w, "Content-Length: %d\r\n\r\n", len(body)); err != nil {
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+	if _, err := w.Write(body); err != nil {
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+	return nil
+}
+
+// decodeParams unmarshals params into v, treating missing params as empty
+func decodeParams(params json.RawMessage, v interface{}) error {
+	if len(params) == 0 || string(params) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(params, v); err != nil {
+		return &Error{Code: CodeInvalidParams, Message: "Invalid params: " + err.Error()}
+	}
+	return nil
+}
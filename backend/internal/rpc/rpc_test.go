@@ -0,0 +1,146 @@
+package rpc
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ksred/claude-session-manager/internal/database"
+	"github.com/sirupsen/logrus"
+)
+
+func setupTestRepo(t *testing.T) *database.SessionRepository {
+	tmpFile, err := os.CreateTemp("", "test-rpc-*.db")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	tmpFile.Close()
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.WarnLevel)
+	db, err := database.NewDatabase(database.Config{DatabasePath: tmpFile.Name(), Logger: logger})
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	t.Cleanup(func() {
+		db.Close()
+		os.Remove(tmpFile.Name())
+	})
+
+	return database.NewSessionRepository(db, logger)
+}
+
+// frame encodes messages the way an editor client sends them
+func frame(messages ...string) *bytes.Buffer {
+	var buf bytes.Buffer
+	for _, message := range messages {
+		fmt.Fprintf(&buf, "Content-Length: %d\r\n\r\n%s", len(message), message)
+	}
+	return &buf
+}
+
+func readResponses(t *testing.T, out *bytes.Buffer) []Response {
+	reader := bufio.NewReader(out)
+	var responses []Response
+	for {
+		body, err := ReadMessage(reader)
+		if err != nil {
+			break
+		}
+		var resp Response
+		if err := json.Unmarshal(body, &resp); err != nil {
+			t.Fatalf("Failed to decode response %s: %v", body, err)
+		}
+		responses = append(responses, resp)
+	}
+	return responses
+}
+
+func TestServe(t *testing.T) {
+	repo := setupTestRepo(t)
+	now := time.Now()
+	if err := repo.UpsertSession(&database.Session{
+		ID:           "session-1",
+		ProjectPath:  "/test/project",
+		ProjectName:  "project",
+		StartTime:    now,
+		LastActivity: now,
+		Status:       "active",
+	}); err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+
+	server := NewServer(repo, Options{DailyBudget: 5, Currency: "USD"}, logrus.New())
+	in := frame(
+		`{"jsonrpc":"2.0","id":1,"method":"initialize"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"sessions/get","params":{"id":"session-1"}}`,
+		`{"jsonrpc":"2.0","id":3,"method":"sessions/get","params":{"id":"missing"}}`,
+		`{"jsonrpc":"2.0","method":"initialized"}`,
+		`{"jsonrpc":"2.0","id":4,"method":"no/such"}`,
+		`not json`,
+		`{"jsonrpc":"2.0","id":"s","method":"statusline","params":{"session_id":"session-1"}}`,
+		`{"jsonrpc":"2.0","id":5,"method":"sessions/list","params":{"limit":"ten"}}`,
+		`{"jsonrpc":"2.0","method":"exit"}`,
+		`{"jsonrpc":"2.0","id":6,"method":"initialize"}`,
+	)
+	var out bytes.Buffer
+	if err := server.Serve(in, &out); err != nil {
+		t.Fatalf("Serve failed: %v", err)
+	}
+
+	responses := readResponses(t, &out)
+	if len(responses) != 7 {
+		t.Fatalf("Expected 7 responses (none for notifications or after exit), got %d: %+v", len(responses), responses)
+	}
+
+	if responses[0].Error != nil || !strings.Contains(fmt.Sprint(responses[0].Result), "sessions/list") {
+		t.Errorf("Expected initialize to list methods, got %+v", responses[0])
+	}
+	session := responses[1].Result.(map[string]interface{})
+	if string(responses[1].ID) != "2" || session["id"] != "session-1" || session["project_name"] != "project" {
+		t.Errorf("Unexpected session response: %+v", responses[1])
+	}
+	if responses[2].Error == nil || responses[2].Error.Code != CodeNotFound {
+		t.Errorf("Expected not found error, got %+v", responses[2])
+	}
+	if responses[3].Error == nil || responses[3].Error.Code != CodeMethodNotFound {
+		t.Errorf("Expected method not found error, got %+v", responses[3])
+	}
+	if responses[4].Error == nil || responses[4].Error.Code != CodeParseError || string(responses[4].ID) != "null" {
+		t.Errorf("Expected parse error, got %+v", responses[4])
+	}
+	statusline := responses[5].Result.(map[string]interface{})
+	if string(responses[5].ID) != `"s"` || statusline["daily_budget"] != 5.0 || statusline["session_id"] != "session-1" {
+		t.Errorf("Unexpected statusline response: %+v", responses[5])
+	}
+	if responses[6].Error == nil || responses[6].Error.Code != CodeInvalidParams {
+		t.Errorf("Expected invalid params error, got %+v", responses[6])
+	}
+}
+
+func TestHandleShutdown(t *testing.T) {
+	server := NewServer(setupTestRepo(t), Options{}, logrus.New())
+
+	resp, ok := server.Handle(Request{JSONRPC: Version, ID: json.RawMessage("1"), Method: "shutdown"})
+	if !ok || resp.Error != nil {
+		t.Fatalf("Expected shutdown to succeed, got %+v", resp)
+	}
+	body, _ := json.Marshal(resp)
+	if !strings.Contains(string(body), `"result":null`) {
+		t.Errorf("Expected a null result to be sent, got %s", body)
+	}
+
+	resp, _ = server.Handle(Request{JSONRPC: Version, ID: json.RawMessage("2"), Method: "metrics/summary"})
+	if resp.Error == nil || resp.Error.Code != CodeInvalidRequest {
+		t.Errorf("Expected requests after shutdown to be rejected, got %+v", resp)
+	}
+
+	if _, ok := server.Handle(Request{JSONRPC: "1.0", Method: "initialize"}); ok {
+		t.Error("Expected no response to a notification")
+	}
+}
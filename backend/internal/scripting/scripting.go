@@ -0,0 +1,404 @@
+// Package scripting runs user-configured CEL expressions against imported
+// messages and sessions to compute custom fields, drop noise, tag sessions
+// and raise notifications. CEL has no access to the file system, network or
+// clock, and every evaluation is bounded by a cost limit and a timeout.
+package scripting
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"github.com/google/cel-go/ext"
+	"github.com/ksred/claude-session-manager/internal/config"
+	"github.com/ksred/claude-session-manager/internal/database"
+	"github.com/ksred/claude-session-manager/internal/knowledge"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// Defaults used when the configuration leaves the limits unset
+const (
+	DefaultCostLimit = 100000
+	DefaultTimeout   = 50 * time.Millisecond
+)
+
+// Metrics counts a script's evaluations since the server started
+type Metrics struct {
+	Evaluations int64      `json:"evaluations"`
+	Matches     int64      `json:"matches"` // evaluations that were true or set a field
+	Errors      int64      `json:"errors"`
+	TotalTimeMs float64    `json:"total_time_ms"`
+	LastError   string     `json:"last_error,omitempty"`
+	LastErrorAt *time.Time `json:"last_error_at,omitempty"`
+}
+
+// Script is a compiled script with its metrics
+type Script struct {
+	config.Script
+	program cel.Program
+	metrics Metrics
+	mu      sync.Mutex
+}
+
+// Metrics returns a copy of the script's metrics
+func (s *Script) Metrics() Metrics {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.metrics
+}
+
+// eval evaluates the script against vars and records the outcome. A nil
+// result means the script did not match: it was false, null, an empty string
+// or failed.
+func (s *Script) eval(vars map[string]interface{}, timeout time.Duration) (ref.Val, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	start := time.Now()
+	out, _, err := s.program.ContextEval(ctx, vars)
+	elapsed := time.Since(start)
+
+	if err == nil && s.Action != "field" && out.Type() != types.BoolType {
+		err = fmt.Errorf("script %s returned %s, expected bool", s.Name, out.Type().TypeName())
+	}
+	matched := err == nil && out != types.NullValue && out != types.False && out != types.String("")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.metrics.Evaluations++
+	s.metrics.TotalTimeMs += float64(elapsed.Microseconds()) / 1000
+	if err != nil {
+		now := time.Now()
+		s.metrics.Errors++
+		s.metrics.LastError = err.Error()
+		s.metrics.LastErrorAt = &now
+		return nil, err
+	}
+	if !matched {
+		return nil, nil
+	}
+	s.metrics.Matches++
+	return out, nil
+}
+
+// Compile compiles scripts, failing on the first that does not compile
+func Compile(cfg config.ScriptingConfig) ([]*Script, error) {
+	costLimit := cfg.CostLimit
+	if costLimit == 0 {
+		costLimit = DefaultCostLimit
+	}
+
+	envs := make(map[string]*cel.Env)
+	for _, on := range []string{"message", "session"} {
+		env, err := cel.NewEnv(
+			cel.Variable(on, cel.MapType(cel.StringType, cel.DynType)),
+			ext.Strings(),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create script environment: %w", err)
+		}
+		envs[on] = env
+	}
+
+	scripts := make([]*Script, 0, len(cfg.Scripts))
+	for _, script := range cfg.Scripts {
+		env, ok := envs[script.On]
+		if !ok {
+			return nil, fmt.Errorf("script %s must run on message or session, got %q", script.Name, script.On)
+		}
+		ast, issues := env.Compile(script.Expression)
+		if issues.Err() != nil {
+			return nil, fmt.Errorf("invalid expression in script %s: %w", script.Name, issues.Err())
+		}
+		program, err := env.Program(ast, cel.CostLimit(costLimit), cel.InterruptCheckFrequency(100))
+		if err != nil {
+			return nil, fmt.Errorf("invalid expression in script %s: %w", script.Name, err)
+		}
+		scripts = append(scripts, &Script{Script: script, program: program})
+	}
+	return scripts, nil
+}
+
+// Version identifies a set of scripts, so sessions are re-evaluated when the
+// configured session scripts change
+func Version(scripts []config.Script) string {
+	data, _ := json.Marshal(scripts)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
+
+// MessageVars returns the variables message scripts see as message
+func MessageVars(message *database.Message) map[string]interface{} {
+	return map[string]interface{}{
+		"message": map[string]interface{}{
+			"id":           message.ID,
+			"session_id":   message.SessionID,
+			"type":         message.Type,
+			"role":         message.Role,
+			"text":         knowledge.ExtractText(message.Content),
+			"content":      message.Content,
+			"cwd":          message.CWD,
+			"is_sidechain": message.IsSidechain,
+			"timestamp":    message.Timestamp,
+		},
+	}
+}
+
+// SessionVars returns the variables session scripts see as session
+func SessionVars(session *database.SessionSummary, tags []database.SessionTag) map[string]interface{} {
+	names := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		names = append(names, tag.Tag)
+	}
+	return map[string]interface{}{
+		"session": map[string]interface{}{
+			"id":               session.ID,
+			"project_name":     session.ProjectName,
+			"project_path":     session.ProjectPath,
+			"model":            session.Model,
+			"status":           session.Status,
+			"is_active":        session.IsActive,
+			"message_count":    session.MessageCount,
+			"duration_seconds": session.DurationSeconds,
+			"total_tokens":     session.TotalTokens,
+			"total_cost":       session.TotalEstimatedCost,
+			"start_time":       session.StartTime,
+			"last_activity":    session.LastActivity,
+			"tags":             names,
+		},
+	}
+}
+
+// fieldValue renders a script result as the text stored in a custom field
+func fieldValue(out ref.Val) (string, error) {
+	switch v := out.Value().(type) {
+	case string:
+		return v, nil
+	case time.Time:
+		return v.UTC().Format(time.RFC3339), nil
+	case bool, int64, uint64, float64:
+		return fmt.Sprint(v), nil
+	}
+	native, err := out.ConvertToNative(reflect.TypeOf(&structpb.Value{}))
+	if err != nil {
+		return "", fmt.Errorf("unsupported result type %s", out.Type().TypeName())
+	}
+	data, err := protojson.Marshal(native.(*structpb.Value))
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// Engine runs the configured scripts
+type Engine struct {
+	repo     *database.SessionRepository
+	messages []*Script
+	sessions []*Script
+	version  string
+	timeout  time.Duration
+	notifier func(eventType string, data interface{})
+	logger   *logrus.Logger
+	mu       sync.Mutex
+}
+
+// NewEngine compiles the configured scripts
+func NewEngine(repo *database.SessionRepository, cfg config.ScriptingConfig, logger *logrus.Logger) (*Engine, error) {
+	scripts, err := Compile(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &Engine{
+		repo:    repo,
+		timeout: time.Duration(cfg.Timeout) * time.Millisecond,
+		logger:  logger,
+	}
+	if e.timeout == 0 {
+		e.timeout = DefaultTimeout
+	}
+
+	var sessionScripts []config.Script
+	for _, script := range scripts {
+		if script.On == "message" {
+			e.messages = append(e.messages, script)
+			continue
+		}
+		e.sessions = append(e.sessions, script)
+		sessionScripts = append(sessionScripts, script.Script)
+	}
+	e.version = Version(sessionScripts)
+	return e, nil
+}
+
+// SetNotifier sets the function notify scripts broadcast events through
+func (e *Engine) SetNotifier(notifier func(eventType string, data interface{})) {
+	e.notifier = notifier
+}
+
+// Scripts returns the configured scripts in the order they run
+func (e *Engine) Scripts() []*Script {
+	scripts := make([]*Script, 0, len(e.messages)+len(e.sessions))
+	scripts = append(scripts, e.messages...)
+	return append(scripts, e.sessions...)
+}
+
+// HasMessageScripts reports whether any script runs on messages
+func (e *Engine) HasMessageScripts() bool {
+	return len(e.messages) > 0
+}
+
+// ProcessMessage runs the message scripts on an imported message. It is used
+// as the repository's message hook, so it must not touch the database.
+func (e *Engine) ProcessMessage(message *database.Message) *database.MessageHookResult {
+	vars := MessageVars(message)
+	result := &database.MessageHookResult{}
+	for _, script := range e.messages {
+		out, err := script.eval(vars, e.timeout)
+		if err != nil {
+			e.logger.WithError(err).WithField("script", script.Name).Debug("Message script failed")
+			continue
+		}
+		if out == nil {
+			continue
+		}
+
+		switch script.Action {
+		case "drop":
+			return &database.MessageHookResult{Drop: true}
+		case "tag":
+			name := script.Name
+			result.Tags = append(result.Tags, database.SessionTag{SessionID: message.SessionID, Tag: script.Tag, Rule: &name})
+		case "field":
+			value, err := fieldValue(out)
+			if err != nil {
+				e.logger.WithError(err).WithField("script", script.Name).Debug("Message script returned an unsupported value")
+				continue
+			}
+			result.Fields = append(result.Fields, database.ScriptField{
+				SessionID: message.SessionID,
+				MessageID: message.ID,
+				Name:      script.Field,
+				Value:     value,
+				Script:    script.Name,
+			})
+		}
+	}
+	return result
+}
+
+// ApplySession evaluates a single session if it is pending and reports
+// whether it was evaluated. Notify scripts that became true broadcast an
+// event.
+func (e *Engine) ApplySession(sessionID string) (bool, error) {
+	applied, err := e.apply(sessionID, 1, true)
+	return applied > 0, err
+}
+
+// ApplyPending evaluates up to limit pending sessions and returns how many
+// were evaluated. No events are broadcast, so sessions imported in bulk do
+// not raise a notification each.
+func (e *Engine) ApplyPending(limit int) (int, error) {
+	return e.apply("", limit, false)
+}
+
+func (e *Engine) apply(sessionID string, limit int, notify bool) (int, error) {
+	if len(e.sessions) == 0 {
+		return 0, nil
+	}
+
+	// Evaluations of the same session from the watcher and the background
+	// refresh would otherwise race to replace its fields
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	ids, err := e.repo.GetSessionsPendingScripts(e.version, sessionID, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	applied := 0
+	for _, id := range ids {
+		if err := e.evaluateSession(id, notify); err != nil {
+			e.logger.WithError(err).WithField("session_id", id).Warn("Failed to run session scripts")
+			continue
+		}
+		applied++
+	}
+	return applied, nil
+}
+
+func (e *Engine) evaluateSession(sessionID string, notify bool) error {
+	session, err := e.repo.GetSessionByID(sessionID)
+	if err != nil {
+		return err
+	}
+	tags, err := e.repo.GetSessionTags(sessionID)
+	if err != nil {
+		return err
+	}
+	previous := make(map[string]bool)
+	if last, err := e.repo.GetScriptEvaluation(sessionID); err == nil {
+		for _, name := range last.Matched {
+			previous[name] = true
+		}
+	}
+
+	vars := SessionVars(session, tags)
+	evaluation := &database.ScriptEvaluation{
+		SessionID:      sessionID,
+		ScriptsVersion: e.version,
+		MessageCount:   session.MessageCount,
+	}
+	var fields []database.ScriptField
+	var newTags []database.SessionTag
+	for _, script := range e.sessions {
+		out, err := script.eval(vars, e.timeout)
+		if err != nil {
+			e.logger.WithError(err).WithField("script", script.Name).Debug("Session script failed")
+			continue
+		}
+		if out == nil {
+			continue
+		}
+
+		switch script.Action {
+		case "tag":
+			name := script.Name
+			newTags = append(newTags, database.SessionTag{SessionID: sessionID, Tag: script.Tag, Rule: &name})
+		case "field":
+			value, err := fieldValue(out)
+			if err != nil {
+				e.logger.WithError(err).WithField("script", script.Name).Debug("Session script returned an unsupported value")
+				continue
+			}
+			fields = append(fields, database.ScriptField{
+				SessionID: sessionID,
+				Name:      script.Field,
+				Value:     value,
+				Script:    script.Name,
+			})
+		case "notify":
+			evaluation.Matched = append(evaluation.Matched, script.Name)
+			if notify && !previous[script.Name] && e.notifier != nil {
+				e.notifier("script_match", map[string]interface{}{
+					"script":       script.Name,
+					"session_id":   sessionID,
+					"project_name": session.ProjectName,
+				})
+			}
+		}
+	}
+
+	return e.repo.ApplySessionScripts(evaluation, fields, newTags)
+}
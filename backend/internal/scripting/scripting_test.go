@@ -0,0 +1,188 @@
+package scripting
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ksred/claude-session-manager/internal/config"
+	"github.com/ksred/claude-session-manager/internal/database"
+	"github.com/sirupsen/logrus"
+)
+
+func setupTestRepo(t *testing.T) *database.SessionRepository {
+	tmpFile, err := os.CreateTemp("", "test-scripting-*.db")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	tmpFile.Close()
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.WarnLevel)
+	db, err := database.NewDatabase(database.Config{DatabasePath: tmpFile.Name(), Logger: logger})
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	t.Cleanup(func() {
+		db.Close()
+		os.Remove(tmpFile.Name())
+	})
+
+	return database.NewSessionRepository(db, logger)
+}
+
+func TestCompile(t *testing.T) {
+	if _, err := Compile(config.ScriptingConfig{Scripts: []config.Script{
+		{Name: "broken", On: "message", Expression: "message.role ==", Action: "drop"},
+	}}); err == nil || !strings.Contains(err.Error(), "broken") {
+		t.Errorf("Expected an invalid expression to fail, got %v", err)
+	}
+	if _, err := Compile(config.ScriptingConfig{Scripts: []config.Script{
+		{Name: "wrong-variable", On: "message", Expression: "session.message_count > 1", Action: "drop"},
+	}}); err == nil {
+		t.Error("Expected message scripts not to see the session")
+	}
+}
+
+func TestEngine_ProcessMessage(t *testing.T) {
+	engine, err := NewEngine(nil, config.ScriptingConfig{
+		CostLimit: 1000,
+		Scripts: []config.Script{
+			{Name: "noise", On: "message", Expression: `message.role == "user" && message.text == ""`, Action: "drop"},
+			{Name: "ticket", On: "message", Expression: `message.text.matches("[A-Z]+-[0-9]+") ? message.text.split(" ")[0] : ""`, Action: "field", Field: "ticket"},
+			{Name: "outage", On: "message", Expression: `message.text.lowerAscii().contains("outage")`, Action: "tag", Tag: "prod-incident"},
+			{Name: "not-bool", On: "message", Expression: `message.role`, Action: "tag", Tag: "never"},
+			{Name: "expensive", On: "message", Expression: `[1, 2, 3, 4, 5].all(x, [1, 2, 3, 4, 5].all(y, [1, 2, 3, 4, 5].all(z, x + y + z > 0)))`, Action: "tag", Tag: "never"},
+		},
+	}, logrus.New())
+	if err != nil {
+		t.Fatalf("Failed to create engine: %v", err)
+	}
+
+	result := engine.ProcessMessage(&database.Message{ID: "m1", SessionID: "s1", Role: "user", Content: `"<command-name>/clear</command-name>"`})
+	if !result.Drop {
+		t.Errorf("Expected command output to be dropped, got %+v", result)
+	}
+
+	result = engine.ProcessMessage(&database.Message{ID: "m2", SessionID: "s1", Role: "user", Content: `"PAY-123 Outage in checkout"`})
+	if result.Drop || len(result.Fields) != 1 || result.Fields[0].Value != "PAY-123" || result.Fields[0].MessageID != "m2" {
+		t.Errorf("Expected the ticket field, got %+v", result)
+	}
+	if len(result.Tags) != 1 || result.Tags[0].Tag != "prod-incident" || *result.Tags[0].Rule != "outage" {
+		t.Errorf("Expected the outage tag, got %+v", result.Tags)
+	}
+
+	metrics := make(map[string]Metrics)
+	for _, script := range engine.Scripts() {
+		metrics[script.Name] = script.Metrics()
+	}
+	if m := metrics["ticket"]; m.Evaluations != 1 || m.Matches != 1 || m.Errors != 0 {
+		t.Errorf("Expected the dropped message to stop evaluation, got %+v", m)
+	}
+	if m := metrics["not-bool"]; m.Errors != 1 || !strings.Contains(m.LastError, "expected bool") {
+		t.Errorf("Expected a non-bool result to be an error, got %+v", m)
+	}
+	if m := metrics["expensive"]; m.Errors != 1 || !strings.Contains(m.LastError, "cost limit") {
+		t.Errorf("Expected the cost limit to stop the script, got %+v", m)
+	}
+}
+
+func TestEngine_MessageHook(t *testing.T) {
+	repo := setupTestRepo(t)
+	engine, err := NewEngine(repo, config.ScriptingConfig{Scripts: []config.Script{
+		{Name: "noise", On: "message", Expression: `message.text == ""`, Action: "drop"},
+		{Name: "length", On: "message", Expression: `size(message.text)`, Action: "field", Field: "length"},
+	}}, logrus.New())
+	if err != nil {
+		t.Fatalf("Failed to create engine: %v", err)
+	}
+	repo.SetMessageHook(engine.ProcessMessage)
+
+	now := time.Now()
+	if err := repo.UpsertSession(&database.Session{ID: "s1", ProjectPath: "/work/app", ProjectName: "app", StartTime: now, LastActivity: now, Status: "completed"}); err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+
+	for _, message := range []struct {
+		id, content string
+		hasUsage    bool
+		stored      bool
+	}{
+		{"m1", `"Fix the build"`, false, true},
+		{"m2", `""`, false, false},
+		// Messages with token usage are kept so spend stays accurate
+		{"m3", `""`, true, true},
+	} {
+		stored, err := repo.UpsertImportedMessage(&database.Message{ID: message.id, SessionID: "s1", Role: "user", Content: message.content, Timestamp: now}, message.hasUsage)
+		if err != nil {
+			t.Fatalf("Failed to import message: %v", err)
+		}
+		if stored != message.stored {
+			t.Errorf("Expected message %s stored=%v, got %v", message.id, message.stored, stored)
+		}
+	}
+
+	fields, err := repo.GetScriptFields("s1")
+	if err != nil {
+		t.Fatalf("Failed to get fields: %v", err)
+	}
+	if len(fields) != 1 || fields[0].MessageID != "m1" || fields[0].Value != "13" {
+		t.Errorf("Expected the length of the stored message, got %+v", fields)
+	}
+}
+
+func TestEngine_ApplySession(t *testing.T) {
+	repo := setupTestRepo(t)
+	engine, err := NewEngine(repo, config.ScriptingConfig{Scripts: []config.Script{
+		{Name: "size", On: "session", Expression: `session.message_count > 10 ? "large" : "small"`, Action: "field", Field: "size"},
+		{Name: "marathon", On: "session", Expression: `session.message_count > 10`, Action: "tag", Tag: "marathon"},
+		{Name: "long-running", On: "session", Expression: `session.message_count > 10`, Action: "notify"},
+	}}, logrus.New())
+	if err != nil {
+		t.Fatalf("Failed to create engine: %v", err)
+	}
+	var events []map[string]interface{}
+	engine.SetNotifier(func(eventType string, data interface{}) {
+		if eventType == "script_match" {
+			events = append(events, data.(map[string]interface{}))
+		}
+	})
+
+	now := time.Now()
+	setMessageCount := func(count int) {
+		if err := repo.UpsertSession(&database.Session{ID: "s1", ProjectPath: "/work/app", ProjectName: "app", StartTime: now, LastActivity: now, Status: "active", MessageCount: count}); err != nil {
+			t.Fatalf("Failed to save session: %v", err)
+		}
+	}
+	setMessageCount(2)
+
+	// Sessions imported in bulk are evaluated without notifications
+	if applied, err := engine.ApplyPending(10); err != nil || applied != 1 {
+		t.Fatalf("Expected one session evaluated, got %d, %v", applied, err)
+	}
+	if applied, _ := engine.ApplySession("s1"); applied {
+		t.Error("Expected an unchanged session not to be re-evaluated")
+	}
+
+	setMessageCount(12)
+	if applied, err := engine.ApplySession("s1"); err != nil || !applied {
+		t.Fatalf("Expected the grown session to be re-evaluated, got %v, %v", applied, err)
+	}
+	setMessageCount(13)
+	if _, err := engine.ApplySession("s1"); err != nil {
+		t.Fatalf("Failed to apply scripts: %v", err)
+	}
+	if len(events) != 1 || events[0]["script"] != "long-running" || events[0]["session_id"] != "s1" {
+		t.Errorf("Expected one notification when the script became true, got %v", events)
+	}
+
+	fields, err := repo.GetScriptFields("s1")
+	if err != nil || len(fields) != 1 || fields[0].Value != "large" {
+		t.Errorf("Expected the size field to be replaced, got %+v, %v", fields, err)
+	}
+	tags, err := repo.GetSessionTags("s1")
+	if err != nil || len(tags) != 1 || tags[0].Source != database.TagSourceScript || *tags[0].Rule != "marathon" {
+		t.Errorf("Expected the script tag, got %+v, %v", tags, err)
+	}
+}
@@ -0,0 +1,292 @@
+// Package similarity detects sessions whose opening prompt closely matches a
+// past session's, using MinHash signatures of word shingles.
+package similarity
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ksred/claude-session-manager/internal/database"
+	"github.com/ksred/claude-session-manager/internal/knowledge"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// NumHashes is the length of a MinHash signature
+	NumHashes = 64
+	// ShingleSize is the number of consecutive words in a shingle
+	ShingleSize = 3
+	// DefaultThreshold is the estimated Jaccard similarity at which prompts count as near-duplicates
+	DefaultThreshold = 0.6
+	// maxPromptLength truncates stored prompts
+	maxPromptLength = 1000
+)
+
+// Signature is the MinHash signature of a prompt's shingles
+type Signature []uint64
+
+// Shingles returns the distinct word n-grams of normalized text. Text shorter
+// than a shingle becomes a single shingle.
+func Shingles(text string) []string {
+	words := strings.Fields(knowledge.Normalize(text))
+	if len(words) == 0 {
+		return nil
+	}
+	if len(words) < ShingleSize {
+		return []string{strings.Join(words, " ")}
+	}
+
+	seen := make(map[string]bool)
+	var shingles []string
+	for i := 0; i+ShingleSize <= len(words); i++ {
+		shingle := strings.Join(words[i:i+ShingleSize], " ")
+		if !seen[shingle] {
+			seen[shingle] = true
+			shingles = append(shingles, shingle)
+		}
+	}
+	return shingles
+}
+
+// splitmix64 scrambles x, giving an independent hash function for each seed
+func splitmix64(x uint64) uint64 {
+	x += 0x9e3779b97f4a7c15
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
+
+// Compute returns the MinHash signature of text, or nil if it has no words
+func Compute(text string) Signature {
+	shingles := Shingles(text)
+	if len(shingles) == 0 {
+		return nil
+	}
+
+	signature := make(Signature, NumHashes)
+	for i := range signature {
+		signature[i] = ^uint64(0)
+	}
+	for _, shingle := range shingles {
+		h := fnv.New64a()
+		h.Write([]byte(shingle))
+		base := h.Sum64()
+		for i := range signature {
+			if v := splitmix64(base ^ splitmix64(uint64(i))); v < signature[i] {
+				signature[i] = v
+			}
+		}
+	}
+	return signature
+}
+
+// Similarity estimates the Jaccard similarity of the shingle sets behind two signatures
+func Similarity(a, b Signature) float64 {
+	if len(a) == 0 || len(a) != len(b) {
+		return 0
+	}
+	matches := 0
+	for i := range a {
+		if a[i] == b[i] {
+			matches++
+		}
+	}
+	return float64(matches) / float64(len(a))
+}
+
+// Encode returns the signature as a hex string for storage
+func (s Signature) Encode() string {
+	buf := make([]byte, 8*len(s))
+	for i, v := range s {
+		binary.BigEndian.PutUint64(buf[8*i:], v)
+	}
+	return hex.EncodeToString(buf)
+}
+
+// Decode parses a signature stored by Encode. An empty string decodes to nil.
+func Decode(encoded string) (Signature, error) {
+	buf, err := hex.DecodeString(encoded)
+	if err != nil || len(buf)%8 != 0 {
+		return nil, fmt.Errorf("invalid signature encoding")
+	}
+	var signature Signature
+	for i := 0; i < len(buf); i += 8 {
+		signature = append(signature, binary.BigEndian.Uint64(buf[i:]))
+	}
+	return signature, nil
+}
+
+// OpeningPrompt returns the text of the first user message that has any,
+// skipping command output and tool results
+func OpeningPrompt(messages []database.TranscriptMessage) string {
+	for _, message := range messages {
+		if text := knowledge.ExtractText(message.Content); text != "" {
+			return text
+		}
+	}
+	return ""
+}
+
+// Match is a past session whose opening prompt resembles another prompt
+type Match struct {
+	SessionID   string    `json:"session_id"`
+	ProjectName *string   `json:"project_name,omitempty"`
+	Prompt      string    `json:"prompt"`
+	StartedAt   time.Time `json:"started_at"`
+	Similarity  float64   `json:"similarity"`
+}
+
+// Detector indexes the opening prompts of sessions and finds near-duplicates
+type Detector struct {
+	repo   *database.SessionRepository
+	logger *logrus.Logger
+}
+
+// NewDetector creates a new near-duplicate prompt detector
+func NewDetector(repo *database.SessionRepository, logger *logrus.Logger) *Detector {
+	return &Detector{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+// Index stores the signature of a session's opening prompt if it has not been
+// stored yet. It returns the signature, whether it was newly stored, and nil
+// without error when the session has no user messages yet.
+func (d *Detector) Index(sessionID string) (*database.PromptSignature, bool, error) {
+	if existing, err := d.repo.GetPromptSignature(sessionID); err == nil {
+		return existing, false, nil
+	}
+
+	messages, err := d.repo.GetOpeningUserMessages(sessionID)
+	if err != nil || len(messages) == 0 {
+		return nil, false, err
+	}
+	session, err := d.repo.GetSessionByID(sessionID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	prompt := OpeningPrompt(messages)
+	signature := &database.PromptSignature{
+		SessionID: sessionID,
+		Prompt:    truncate(prompt, maxPromptLength),
+		Signature: Compute(prompt).Encode(),
+		StartedAt: session.StartTime,
+	}
+	if session.ProjectName != "" {
+		signature.ProjectName = &session.ProjectName
+	}
+	if err := d.repo.SavePromptSignature(signature); err != nil {
+		return nil, false, fmt.Errorf("failed to save prompt signature: %w", err)
+	}
+	return signature, true, nil
+}
+
+// IndexPending stores signatures for up to limit sessions that do not have
+// one yet and returns how many were stored
+func (d *Detector) IndexPending(limit int) (int, error) {
+	sessionIDs, err := d.repo.GetSessionsWithoutPromptSignature(limit)
+	if err != nil {
+		return 0, err
+	}
+
+	indexed := 0
+	for _, sessionID := range sessionIDs {
+		if _, created, err := d.Index(sessionID); err != nil {
+			d.logger.WithError(err).WithField("session_id", sessionID).Warn("Failed to index opening prompt")
+		} else if created {
+			indexed++
+		}
+	}
+	return indexed, nil
+}
+
+// FindSimilar returns sessions started before the given one whose opening
+// prompt has at least the threshold similarity to its opening prompt, most
+// similar first
+func (d *Detector) FindSimilar(sessionID string, threshold float64, limit int) (*database.PromptSignature, []Match, error) {
+	target, _, err := d.Index(sessionID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if target == nil {
+		return nil, nil, fmt.Errorf("opening prompt not found: %s", sessionID)
+	}
+	if target.Signature == "" {
+		return target, []Match{}, nil
+	}
+
+	signature, err := Decode(target.Signature)
+	if err != nil {
+		return nil, nil, err
+	}
+	matches, err := d.match(signature, threshold, limit, func(candidate *database.PromptSignature) bool {
+		return candidate.SessionID != sessionID && candidate.StartedAt.Before(target.StartedAt)
+	})
+	return target, matches, err
+}
+
+// FindSimilarToPrompt returns sessions whose opening prompt has at least the
+// threshold similarity to prompt, most similar first
+func (d *Detector) FindSimilarToPrompt(prompt string, threshold float64, limit int) ([]Match, error) {
+	signature := Compute(prompt)
+	if signature == nil {
+		return []Match{}, nil
+	}
+	return d.match(signature, threshold, limit, func(*database.PromptSignature) bool { return true })
+}
+
+// match compares signature against every stored signature that passes include
+func (d *Detector) match(signature Signature, threshold float64, limit int, include func(*database.PromptSignature) bool) ([]Match, error) {
+	candidates, err := d.repo.GetPromptSignatures()
+	if err != nil {
+		return nil, err
+	}
+
+	matches := []Match{}
+	for i := range candidates {
+		candidate := &candidates[i]
+		if !include(candidate) {
+			continue
+		}
+		other, err := Decode(candidate.Signature)
+		if err != nil {
+			continue
+		}
+		if similarity := Similarity(signature, other); similarity >= threshold {
+			matches = append(matches, Match{
+				SessionID:   candidate.SessionID,
+				ProjectName: candidate.ProjectName,
+				Prompt:      candidate.Prompt,
+				StartedAt:   candidate.StartedAt,
+				Similarity:  similarity,
+			})
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].Similarity != matches[j].Similarity {
+			return matches[i].Similarity > matches[j].Similarity
+		}
+		return matches[i].StartedAt.After(matches[j].StartedAt)
+	})
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches, nil
+}
+
+// truncate shortens text to at most n runes
+func truncate(text string, n int) string {
+	runes := []rune(text)
+	if len(runes) <= n {
+		return text
+	}
+	return string(runes[:n]) + "…"
+}
@@ -0,0 +1,123 @@
+package similarity
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ksred/claude-session-manager/internal/database"
+	"github.com/sirupsen/logrus"
+)
+
+func setupTestRepo(t *testing.T) *database.SessionRepository {
+	tmpFile, err := os.CreateTemp("", "test-similarity-*.db")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	tmpFile.Close()
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.WarnLevel)
+	db, err := database.NewDatabase(database.Config{DatabasePath: tmpFile.Name(), Logger: logger})
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	t.Cleanup(func() {
+		db.Close()
+		os.Remove(tmpFile.Name())
+	})
+
+	return database.NewSessionRepository(db, logger)
+}
+
+func TestSignature(t *testing.T) {
+	prompt := "Add pagination to the sessions list endpoint and update the tests"
+	signature := Compute(prompt)
+	if len(signature) != NumHashes {
+		t.Fatalf("Expected %d hashes, got %d", NumHashes, len(signature))
+	}
+
+	decoded, err := Decode(signature.Encode())
+	if err != nil || Similarity(signature, decoded) != 1 {
+		t.Fatalf("Expected signature to round-trip, got %v (%v)", decoded, err)
+	}
+
+	if s := Similarity(signature, Compute("add pagination to the sessions list endpoint, and update tests!")); s < DefaultThreshold {
+		t.Errorf("Expected rephrased prompt to be a near-duplicate, got %v", s)
+	}
+	if s := Similarity(signature, Compute("Why does the websocket hub drop messages under load")); s >= DefaultThreshold {
+		t.Errorf("Expected unrelated prompt not to match, got %v", s)
+	}
+	if Compute("  ?! ") != nil {
+		t.Error("Expected no signature for text without words")
+	}
+}
+
+func TestDetector_FindSimilar(t *testing.T) {
+	repo := setupTestRepo(t)
+
+	now := time.Now()
+	prompts := map[string]string{
+		"old":       `"Add pagination to the sessions list endpoint and update the tests"`,
+		"unrelated": `[{"type":"text","text":"Why does the websocket hub drop messages under load"}]`,
+		"new":       `"Please add pagination to the sessions list endpoint and update the tests"`,
+	}
+	for i, id := range []string{"old", "unrelated", "new"} {
+		start := now.Add(time.Duration(i-3) * time.Hour)
+		if err := repo.UpsertSession(&database.Session{
+			ID:           id,
+			ProjectPath:  "/test/project",
+			ProjectName:  "project",
+			StartTime:    start,
+			LastActivity: start,
+			Status:       "completed",
+		}); err != nil {
+			t.Fatalf("Failed to create session: %v", err)
+		}
+		for j, content := range []string{`"<command-name>/clear</command-name>"`, prompts[id]} {
+			if err := repo.UpsertMessage(&database.Message{
+				ID:        id + "-" + string(rune('a'+j)),
+				SessionID: id,
+				Role:      "user",
+				Content:   content,
+				Timestamp: start.Add(time.Duration(j) * time.Second),
+			}); err != nil {
+				t.Fatalf("Failed to create message: %v", err)
+			}
+		}
+	}
+
+	detector := NewDetector(repo, logrus.New())
+	indexed, err := detector.IndexPending(10)
+	if err != nil || indexed != 3 {
+		t.Fatalf("Expected 3 sessions indexed, got %d (%v)", indexed, err)
+	}
+	if _, created, _ := detector.Index("new"); created {
+		t.Error("Expected an indexed session not to be indexed again")
+	}
+
+	target, matches, err := detector.FindSimilar("new", DefaultThreshold, 5)
+	if err != nil {
+		t.Fatalf("Failed to find similar sessions: %v", err)
+	}
+	if target.Prompt != "Please add pagination to the sessions list endpoint and update the tests" {
+		t.Errorf("Expected command output to be skipped, got prompt %q", target.Prompt)
+	}
+	if len(matches) != 1 || matches[0].SessionID != "old" {
+		t.Errorf("Expected only the old session to match, got %+v", matches)
+	}
+
+	// Only earlier sessions count as past sessions
+	if _, matches, _ := detector.FindSimilar("old", DefaultThreshold, 5); len(matches) != 0 {
+		t.Errorf("Expected no earlier matches for the oldest session, got %+v", matches)
+	}
+
+	matches, err = detector.FindSimilarToPrompt("add pagination to the sessions list endpoint", 0.3, 5)
+	if err != nil || len(matches) != 2 {
+		t.Errorf("Expected both pagination sessions to match, got %+v (%v)", matches, err)
+	}
+
+	if _, _, err := detector.FindSimilar("missing", DefaultThreshold, 5); err == nil {
+		t.Error("Expected error for unknown session")
+	}
+}
@@ -0,0 +1,330 @@
+// Package simulation reprices past usage under alternative assumptions: other
+// models, other prices or a subscription plan instead of paying per token,
+// reported in any currency at a given exchange rate. Usage itself isn't
+// changed, so each scenario answers what the same work would have cost.
+package simulation
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ksred/claude-session-manager/internal/database"
+	"github.com/ksred/claude-session-manager/internal/pricing"
+)
+
+// MaxScenarios is the most scenarios one simulation compares
+const MaxScenarios = 10
+
+// PlanAPI pays for every token at the table's prices
+const PlanAPI = "api"
+
+// Plans are the subscription plans known by name, with their monthly fee per
+// seat in USD. Usage on a subscription is covered by the fee.
+var Plans = map[string]float64{
+	"pro":     20,
+	"max-5x":  100,
+	"max-20x": 200,
+}
+
+// Plan is how usage is paid for. Name is api, a name in Plans or any other
+// name with its MonthlyFee; a MonthlyFee given for a known plan replaces its
+// fee.
+type Plan struct {
+	Name       string  `json:"name"`
+	MonthlyFee float64 `json:"monthly_fee,omitempty"` // USD per seat
+	Seats      int     `json:"seats,omitempty"`       // default 1
+}
+
+// Scenario is one set of assumptions to reprice usage under
+type Scenario struct {
+	Name string `json:"name"`
+	// ModelMapping reprices the usage of each model containing a key as the
+	// model it maps to; the longest matching key wins
+	ModelMapping map[string]string `json:"model_mapping,omitempty"`
+	// Pricing takes precedence over the prices in effect, as USD per million
+	// tokens matched the same way
+	Pricing []pricing.Price `json:"pricing,omitempty"`
+	// Plan defaults to api
+	Plan *Plan `json:"plan,omitempty"`
+	// Currency is what totals are reported in, at ExchangeRate units per
+	// USD; USD needs no rate
+	Currency     string  `json:"currency,omitempty"`
+	ExchangeRate float64 `json:"exchange_rate,omitempty"`
+}
+
+// Validate checks the scenario can be simulated and fills in its defaults
+func (s *Scenario) Validate() error {
+	if s.Plan == nil {
+		s.Plan = &Plan{Name: PlanAPI}
+	}
+	s.Plan.Name = strings.ToLower(strings.TrimSpace(s.Plan.Name))
+	if s.Plan.Name == "" {
+		s.Plan.Name = PlanAPI
+	}
+	if s.Plan.MonthlyFee < 0 {
+		return fmt.Errorf("plan monthly_fee must not be negative")
+	}
+	if s.Plan.Name != PlanAPI && s.Plan.MonthlyFee == 0 {
+		fee, ok := Plans[s.Plan.Name]
+		if !ok {
+			return fmt.Errorf("unknown plan %q: use api, pro, max-5x, max-20x or give a monthly_fee", s.Plan.Name)
+		}
+		s.Plan.MonthlyFee = fee
+	}
+	if s.Plan.Seats < 0 {
+		return fmt.Errorf("plan seats must not be negative")
+	}
+	if s.Plan.Seats == 0 {
+		s.Plan.Seats = 1
+	}
+
+	if err := (pricing.PriceList{Models: s.Pricing}).Validate(); err != nil {
+		return err
+	}
+	for from, to := range s.ModelMapping {
+		if from == "" || to == "" {
+			return fmt.Errorf("model_mapping needs a model on both sides")
+		}
+	}
+
+	s.Currency = strings.ToUpper(strings.TrimSpace(s.Currency))
+	if s.Currency == "" {
+		s.Currency = "USD"
+	}
+	if s.ExchangeRate < 0 {
+		return fmt.Errorf("exchange_rate must not be negative")
+	}
+	if s.ExchangeRate == 0 {
+		if s.Currency != "USD" {
+			return fmt.Errorf("exchange_rate is required to report in %s", s.Currency)
+		}
+		s.ExchangeRate = 1
+	}
+	return nil
+}
+
+// mapModel returns the model usage of model is repriced as
+func (s *Scenario) mapModel(model string) string {
+	name := strings.ToLower(model)
+	best, mapped := "", model
+	for from, to := range s.ModelMapping {
+		key := strings.ToLower(from)
+		if strings.Contains(name, key) && len(key) > len(best) {
+			best, mapped = key, to
+		}
+	}
+	return mapped
+}
+
+// ModelResult is the usage of one model under a scenario
+type ModelResult struct {
+	Model        string  `json:"model"`
+	PricedAs     string  `json:"priced_as"`
+	InputTokens  int     `json:"input_tokens"`
+	OutputTokens int     `json:"output_tokens"`
+	CacheTokens  int     `json:"cache_tokens"` // written and read
+	TokenCostUSD float64 `json:"token_cost_usd"`
+}
+
+// MonthResult is a month of a scenario
+type MonthResult struct {
+	Month        string  `json:"month"`
+	TokenCostUSD float64 `json:"token_cost_usd"`
+	PlanFeesUSD  float64 `json:"plan_fees_usd"`
+	TotalUSD     float64 `json:"total_usd"`
+	Total        float64 `json:"total"` // in the scenario's currency
+}
+
+// Result is what the usage would have cost under a scenario
+type Result struct {
+	Name         string  `json:"name"`
+	Plan         Plan    `json:"plan"`
+	Currency     string  `json:"currency"`
+	ExchangeRate float64 `json:"exchange_rate"`
+	// TokenCostUSD is the usage priced per token under the scenario's models
+	// and prices. On a subscription it is only what the usage is worth.
+	TokenCostUSD float64 `json:"token_cost_usd"`
+	PlanFeesUSD  float64 `json:"plan_fees_usd"`
+	TotalUSD     float64 `json:"total_usd"`
+	Total        float64 `json:"total"` // in the scenario's currency
+	// DifferenceUSD is TotalUSD less the baseline's; negative is a saving
+	DifferenceUSD     float64       `json:"difference_usd"`
+	DifferencePercent *float64      `json:"difference_percent"` // nil when the baseline cost nothing
+	Months            []MonthResult `json:"months"`
+	Models            []ModelResult `json:"models"`
+}
+
+// Report compares the scenarios with the baseline: the same usage at the
+// prices in effect, paid per token
+type Report struct {
+	From            time.Time `json:"from"`
+	To              time.Time `json:"to"`
+	RecordedCostUSD float64   `json:"recorded_cost_usd"` // cost stored at import
+	Baseline        Result    `json:"baseline"`
+	Scenarios       []Result  `json:"scenarios"`
+	Caveats         []string  `json:"caveats"`
+	GeneratedAt     time.Time `json:"generated_at"`
+}
+
+// Caveats explain what a simulation doesn't account for; they are returned
+// with every report
+var Caveats = []string{
+	"Usage is what was actually sent: a different model might have needed more or fewer tokens, turns or retries for the same work.",
+	"Subscription plans are priced by their fee alone. Their usage limits aren't modeled, so usage beyond what a plan allows is shown as covered.",
+	"Plan fees are prorated by the days of each month in the window.",
+	"A message's model is its session's, so sessions that switched models are priced at the model they ended on.",
+	"Exchange rates are applied as given, one rate for the whole window.",
+}
+
+// Simulate reprices usage, the tokens used per model and month in [from, to),
+// under each scenario. Scenarios must have been validated.
+func Simulate(usage []database.ModelMonthUsage, table *pricing.Table, scenarios []Scenario, from, to time.Time) Report {
+	report := Report{
+		From:        from,
+		To:          to,
+		Scenarios:   []Result{},
+		Caveats:     Caveats,
+		GeneratedAt: time.Now().UTC(),
+	}
+	for _, row := range usage {
+		report.RecordedCostUSD += row.CostUSD
+	}
+	report.RecordedCostUSD = round(report.RecordedCostUSD, 6)
+
+	baseline := Scenario{Name: "baseline"}
+	baseline.Validate()
+	report.Baseline = simulate(usage, table, baseline, from, to)
+
+	for _, scenario := range scenarios {
+		result := simulate(usage, table, scenario, from, to)
+		result.DifferenceUSD = round(result.TotalUSD-report.Baseline.TotalUSD, 6)
+		if report.Baseline.TotalUSD > 0 {
+			percent := round(result.DifferenceUSD/report.Baseline.TotalUSD*100, 1)
+			result.DifferencePercent = &percent
+		}
+		report.Scenarios = append(report.Scenarios, result)
+	}
+	return report
+}
+
+func simulate(usage []database.ModelMonthUsage, table *pricing.Table, scenario Scenario, from, to time.Time) Result {
+	if len(scenario.Pricing) > 0 {
+		table = table.With(scenario.Pricing)
+	}
+	result := Result{
+		Name:         scenario.Name,
+		Plan:         *scenario.Plan,
+		Currency:     scenario.Currency,
+		ExchangeRate: scenario.ExchangeRate,
+		Months:       []MonthResult{},
+		Models:       []ModelResult{},
+	}
+
+	shares := monthShares(from, to)
+	months := make(map[string]*MonthResult, len(shares))
+	for month := range shares {
+		months[month] = &MonthResult{Month: month}
+	}
+	models := make(map[string]*ModelResult)
+	for _, row := range usage {
+		pricedAs := scenario.mapModel(row.Model)
+		cost := table.Cost(pricedAs, pricing.Usage{
+			InputTokens:              row.InputTokens,
+			OutputTokens:             row.OutputTokens,
+			CacheCreationInputTokens: row.CacheCreationInputTokens,
+			CacheReadInputTokens:     row.CacheReadInputTokens,
+		})
+
+		month, ok := months[row.Month]
+		if !ok {
+			month = &MonthResult{Month: row.Month}
+			months[row.Month] = month
+		}
+		month.TokenCostUSD += cost
+
+		model, ok := models[row.Model]
+		if !ok {
+			model = &ModelResult{Model: row.Model, PricedAs: pricedAs}
+			models[row.Model] = model
+		}
+		model.InputTokens += row.InputTokens
+		model.OutputTokens += row.OutputTokens
+		model.CacheTokens += row.CacheCreationInputTokens + row.CacheReadInputTokens
+		model.TokenCostUSD += cost
+	}
+
+	if scenario.Plan.Name != PlanAPI {
+		for month, share := range shares {
+			months[month].PlanFeesUSD = scenario.Plan.MonthlyFee * float64(scenario.Plan.Seats) * share
+		}
+	}
+
+	for _, month := range months {
+		result.TokenCostUSD += month.TokenCostUSD
+		result.PlanFeesUSD += month.PlanFeesUSD
+		month.TotalUSD = month.TokenCostUSD
+		if scenario.Plan.Name != PlanAPI {
+			month.TotalUSD = month.PlanFeesUSD
+		}
+		month.Total = round(month.TotalUSD*scenario.ExchangeRate, 2)
+		month.TokenCostUSD = round(month.TokenCostUSD, 6)
+		month.PlanFeesUSD = round(month.PlanFeesUSD, 6)
+		month.TotalUSD = round(month.TotalUSD, 6)
+		result.Months = append(result.Months, *month)
+	}
+	sort.Slice(result.Months, func(i, j int) bool {
+		return result.Months[i].Month < result.Months[j].Month
+	})
+
+	for _, model := range models {
+		model.TokenCostUSD = round(model.TokenCostUSD, 6)
+		result.Models = append(result.Models, *model)
+	}
+	sort.Slice(result.Models, func(i, j int) bool {
+		return result.Models[i].TokenCostUSD > result.Models[j].TokenCostUSD
+	})
+
+	result.TotalUSD = result.TokenCostUSD
+	if scenario.Plan.Name != PlanAPI {
+		result.TotalUSD = result.PlanFeesUSD
+	}
+	result.Total = round(result.TotalUSD*scenario.ExchangeRate, 2)
+	result.TokenCostUSD = round(result.TokenCostUSD, 6)
+	result.PlanFeesUSD = round(result.PlanFeesUSD, 6)
+	result.TotalUSD = round(result.TotalUSD, 6)
+	return result
+}
+
+// monthShares returns the fraction of each UTC month that [from, to) covers
+func monthShares(from, to time.Time) map[string]float64 {
+	shares := make(map[string]float64)
+	from, to = from.UTC(), to.UTC()
+	for start := time.Date(from.Year(), from.Month(), 1, 0, 0, 0, 0, time.UTC); start.Before(to); start = start.AddDate(0, 1, 0) {
+		end := start.AddDate(0, 1, 0)
+		covered := minTime(end, to).Sub(maxTime(start, from))
+		shares[start.Format("2006-01")] = covered.Hours() / end.Sub(start).Hours()
+	}
+	return shares
+}
+
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+func round(value float64, places int) float64 {
+	scale := math.Pow(10, float64(places))
+	return math.Round(value*scale) / scale
+}
@@ -0,0 +1,89 @@
+package simulation
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ksred/claude-session-manager/internal/database"
+	"github.com/ksred/claude-session-manager/internal/pricing"
+)
+
+func TestSimulate(t *testing.T) {
+	usage := []database.ModelMonthUsage{
+		{Month: "2026-09", Model: "claude-sonnet-4-20250514", Sessions: 4, InputTokens: 1_000_000, OutputTokens: 1_000_000, CostUSD: 17.5},
+	}
+	scenarios := []Scenario{
+		{Name: "haiku", ModelMapping: map[string]string{"sonnet": "claude-3-5-haiku"}},
+		{Name: "max", Plan: &Plan{Name: "max-5x"}},
+		{Name: "discount in euros", Pricing: []pricing.Price{{Model: "claude-sonnet-4", Input: 1, Output: 5}}, Currency: "eur", ExchangeRate: 0.9},
+	}
+	for i := range scenarios {
+		if err := scenarios[i].Validate(); err != nil {
+			t.Fatalf("Failed to validate %s: %v", scenarios[i].Name, err)
+		}
+	}
+
+	from := time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)
+	report := Simulate(usage, pricing.NewTable(), scenarios, from, from.AddDate(0, 1, 0))
+
+	if report.RecordedCostUSD != 17.5 || report.Baseline.TotalUSD != 18 {
+		t.Fatalf("Expected $17.50 recorded and $18 at current Sonnet prices, got %+v", report)
+	}
+	if len(report.Scenarios) != 3 {
+		t.Fatalf("Expected 3 scenarios, got %d", len(report.Scenarios))
+	}
+
+	haiku := report.Scenarios[0]
+	if haiku.TotalUSD != 4.8 || haiku.Models[0].PricedAs != "claude-3-5-haiku" {
+		t.Errorf("Expected the usage priced as Haiku, got %+v", haiku)
+	}
+	if haiku.DifferenceUSD != -13.2 || haiku.DifferencePercent == nil || *haiku.DifferencePercent != -73.3 {
+		t.Errorf("Expected a $13.20 saving, got %+v", haiku)
+	}
+
+	subscription := report.Scenarios[1]
+	if subscription.PlanFeesUSD != 100 || subscription.TotalUSD != 100 || subscription.TokenCostUSD != 18 {
+		t.Errorf("Expected a month of the Max fee, got %+v", subscription)
+	}
+
+	euros := report.Scenarios[2]
+	if euros.Currency != "EUR" || euros.TotalUSD != 6 || euros.Total != 5.4 || euros.Months[0].Total != 5.4 {
+		t.Errorf("Expected $6 reported as 5.40 EUR, got %+v", euros)
+	}
+}
+
+func TestSimulate_ProratesPlanFees(t *testing.T) {
+	scenario := Scenario{Plan: &Plan{Name: "team", MonthlyFee: 30, Seats: 2}}
+	if err := scenario.Validate(); err != nil {
+		t.Fatalf("Failed to validate: %v", err)
+	}
+
+	// Half of September and all of October
+	from := time.Date(2026, 9, 16, 0, 0, 0, 0, time.UTC)
+	report := Simulate(nil, pricing.NewTable(), []Scenario{scenario}, from, time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC))
+
+	result := report.Scenarios[0]
+	if len(result.Months) != 2 || result.Months[0].PlanFeesUSD != 30 || result.Months[1].PlanFeesUSD != 60 {
+		t.Errorf("Expected fees prorated by month, got %+v", result.Months)
+	}
+	if result.TotalUSD != 90 || result.DifferencePercent != nil {
+		t.Errorf("Expected $90 against a baseline of nothing, got %+v", result)
+	}
+}
+
+func TestScenario_Validate(t *testing.T) {
+	tests := []struct {
+		name     string
+		scenario Scenario
+	}{
+		{"unknown plan", Scenario{Plan: &Plan{Name: "enterprise"}}},
+		{"currency without rate", Scenario{Currency: "GBP"}},
+		{"negative price", Scenario{Pricing: []pricing.Price{{Model: "claude-opus-4", Input: -1}}}},
+		{"empty mapping", Scenario{ModelMapping: map[string]string{"opus": ""}}},
+	}
+	for _, tt := range tests {
+		if err := tt.scenario.Validate(); err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+// Package tagging applies configured auto-tagging rules to sessions as they
+// are imported, so sessions are categorized without anyone remembering to
+// tag them.
+package tagging
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sync"
+
+	"github.com/ksred/claude-session-manager/internal/config"
+	"github.com/ksred/claude-session-manager/internal/database"
+	"github.com/ksred/claude-session-manager/internal/similarity"
+	"github.com/sirupsen/logrus"
+)
+
+// Rule is a compiled tagging rule
+type Rule struct {
+	config.TagRule
+	project      *regexp.Regexp
+	path         *regexp.Regexp
+	branch       *regexp.Regexp
+	firstMessage *regexp.Regexp
+}
+
+// Compile compiles tagging rules, failing on the first invalid pattern
+func Compile(rules []config.TagRule) ([]Rule, error) {
+	compiled := make([]Rule, 0, len(rules))
+	for _, rule := range rules {
+		c := Rule{TagRule: rule}
+		for _, field := range []struct {
+			pattern string
+			target  **regexp.Regexp
+		}{
+			{rule.Project, &c.project},
+			{rule.Path, &c.path},
+			{rule.Branch, &c.branch},
+			{rule.FirstMessage, &c.firstMessage},
+		} {
+			if field.pattern == "" {
+				continue
+			}
+			re, err := regexp.Compile(field.pattern)
+			if err != nil {
+				return nil, fmt.Errorf("invalid pattern in tagging rule %s: %w", rule.Name, err)
+			}
+			*field.target = re
+		}
+		compiled = append(compiled, c)
+	}
+	return compiled, nil
+}
+
+// Matches reports whether a session matches every pattern the rule sets
+func (r *Rule) Matches(subject *database.TagSubject) bool {
+	matches := func(re *regexp.Regexp, value string) bool {
+		return re == nil || re.MatchString(value)
+	}
+	return matches(r.project, subject.ProjectName) &&
+		matches(r.path, subject.ProjectPath) &&
+		matches(r.branch, subject.GitBranch) &&
+		matches(r.firstMessage, subject.FirstMessage)
+}
+
+// Version identifies a set of rules, so sessions are re-evaluated when the
+// configured rules change
+func Version(rules []config.TagRule) string {
+	data, _ := json.Marshal(rules)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
+
+// Tagger applies tagging rules to sessions that have not been evaluated
+// against the current rules
+type Tagger struct {
+	repo    *database.SessionRepository
+	rules   []Rule
+	version string
+	logger  *logrus.Logger
+	mu      sync.Mutex
+}
+
+// NewTagger creates a tagger for the configured rules
+func NewTagger(repo *database.SessionRepository, rules []config.TagRule, logger *logrus.Logger) (*Tagger, error) {
+	compiled, err := Compile(rules)
+	if err != nil {
+		return nil, err
+	}
+	return &Tagger{
+		repo:    repo,
+		rules:   compiled,
+		version: Version(rules),
+		logger:  logger,
+	}, nil
+}
+
+// Rules returns the configured rules
+func (t *Tagger) Rules() []config.TagRule {
+	rules := make([]config.TagRule, len(t.rules))
+	for i, rule := range t.rules {
+		rules[i] = rule.TagRule
+	}
+	return rules
+}
+
+// Evaluate returns the tags the rules apply to a session
+func (t *Tagger) Evaluate(subject *database.TagSubject) []database.SessionTag {
+	var tags []database.SessionTag
+	for i := range t.rules {
+		rule := &t.rules[i]
+		if rule.Matches(subject) {
+			name := rule.Name
+			tags = append(tags, database.SessionTag{SessionID: subject.SessionID, Tag: rule.Tag, Rule: &name})
+		}
+	}
+	return tags
+}
+
+// ApplySession evaluates a single session if it is pending and reports
+// whether it was evaluated
+func (t *Tagger) ApplySession(sessionID string) (bool, error) {
+	applied, err := t.apply(sessionID, 1)
+	return applied > 0, err
+}
+
+// ApplyPending evaluates up to limit pending sessions and returns how many
+// were evaluated
+func (t *Tagger) ApplyPending(limit int) (int, error) {
+	return t.apply("", limit)
+}
+
+// Reapply re-evaluates every session against the rules and returns how many
+// were evaluated
+func (t *Tagger) Reapply() (int, error) {
+	if err := t.repo.ResetTagRuleEvaluations(); err != nil {
+		return 0, err
+	}
+
+	total := 0
+	for {
+		applied, err := t.ApplyPending(500)
+		total += applied
+		if err != nil || applied == 0 {
+			return total, err
+		}
+	}
+}
+
+func (t *Tagger) apply(sessionID string, limit int) (int, error) {
+	// Evaluations of the same session from the watcher and the background
+	// refresh would otherwise race to replace its rule tags
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	subjects, err := t.repo.GetSessionsPendingTagRules(t.version, sessionID, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	applied := 0
+	for i := range subjects {
+		subject := &subjects[i]
+		messages, err := t.repo.GetOpeningUserMessages(subject.SessionID)
+		if err != nil {
+			t.logger.WithError(err).WithField("session_id", subject.SessionID).Warn("Failed to get opening prompt for tagging")
+			continue
+		}
+		subject.FirstMessage = similarity.OpeningPrompt(messages)
+
+		if err := t.repo.ApplyRuleTags(subject, t.version, t.Evaluate(subject)); err != nil {
+			t.logger.WithError(err).WithField("session_id", subject.SessionID).Warn("Failed to apply tagging rules")
+			continue
+		}
+		applied++
+	}
+	return applied, nil
+}
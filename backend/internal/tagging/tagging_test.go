@@ -0,0 +1,161 @@
+package tagging
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ksred/claude-session-manager/internal/config"
+	"github.com/ksred/claude-session-manager/internal/database"
+	"github.com/sirupsen/logrus"
+)
+
+func setupTestRepo(t *testing.T) *database.SessionRepository {
+	tmpFile, err := os.CreateTemp("", "test-tagging-*.db")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	tmpFile.Close()
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.WarnLevel)
+	db, err := database.NewDatabase(database.Config{DatabasePath: tmpFile.Name(), Logger: logger})
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	t.Cleanup(func() {
+		db.Close()
+		os.Remove(tmpFile.Name())
+	})
+
+	return database.NewSessionRepository(db, logger)
+}
+
+func TestRuleMatches(t *testing.T) {
+	rules, err := Compile([]config.TagRule{
+		{Name: "incidents", Tag: "prod-incident", FirstMessage: `(?i)\boutage\b`},
+		{Name: "payments-spikes", Tag: "experiment", Path: "/payments/", Branch: "^spike/"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to compile rules: %v", err)
+	}
+
+	subject := &database.TagSubject{
+		ProjectName:  "payments",
+		ProjectPath:  "/home/me/payments/",
+		GitBranch:    "spike/ledger",
+		FirstMessage: "We have an OUTAGE in checkout",
+	}
+	if !rules[0].Matches(subject) || !rules[1].Matches(subject) {
+		t.Error("Expected both rules to match")
+	}
+
+	// Every pattern a rule sets must match
+	subject.GitBranch = "main"
+	if rules[1].Matches(subject) {
+		t.Error("Expected rule to require its branch pattern")
+	}
+
+	if _, err := Compile([]config.TagRule{{Name: "bad", Tag: "bad", Project: "("}}); err == nil {
+		t.Error("Expected error for invalid pattern")
+	}
+}
+
+func TestTagger(t *testing.T) {
+	repo := setupTestRepo(t)
+	logger := logrus.New()
+
+	now := time.Now()
+	createSession := func(id, path string, messageCount int) {
+		if err := repo.UpsertSession(&database.Session{
+			ID:           id,
+			ProjectPath:  path,
+			ProjectName:  id,
+			StartTime:    now,
+			LastActivity: now,
+			Status:       "completed",
+			MessageCount: messageCount,
+		}); err != nil {
+			t.Fatalf("Failed to create session: %v", err)
+		}
+	}
+	addMessage := func(id, sessionID, content string) {
+		if err := repo.UpsertMessage(&database.Message{
+			ID:        id,
+			SessionID: sessionID,
+			Role:      "user",
+			Content:   content,
+			Timestamp: now,
+		}); err != nil {
+			t.Fatalf("Failed to create message: %v", err)
+		}
+	}
+
+	createSession("incident", "/home/me/checkout", 1)
+	addMessage("incident-1", "incident", `"checkout outage after deploy"`)
+	createSession("refactor", "/home/me/payments", 1)
+	addMessage("refactor-1", "refactor", `"tidy up the ledger code"`)
+	createSession("empty", "/home/me/checkout", 0)
+
+	rules := []config.TagRule{
+		{Name: "incidents", Tag: "prod-incident", FirstMessage: `(?i)\boutage\b`},
+		{Name: "payments", Tag: "payments", Path: "/payments$"},
+	}
+	tagger, err := NewTagger(repo, rules, logger)
+	if err != nil {
+		t.Fatalf("Failed to create tagger: %v", err)
+	}
+
+	// A tag applied by hand survives rule evaluation
+	if _, err := repo.GetDB().Exec(`INSERT INTO session_tags (session_id, tag) VALUES ('refactor', 'cleanup')`); err != nil {
+		t.Fatalf("Failed to add manual tag: %v", err)
+	}
+
+	evaluated, err := tagger.ApplyPending(10)
+	if err != nil || evaluated != 3 {
+		t.Fatalf("Expected 3 sessions evaluated, got %d (%v)", evaluated, err)
+	}
+	if evaluated, _ := tagger.ApplyPending(10); evaluated != 0 {
+		t.Errorf("Expected no sessions to be re-evaluated, got %d", evaluated)
+	}
+
+	tags, _ := repo.GetSessionTags("incident")
+	if len(tags) != 1 || tags[0].Tag != "prod-incident" || tags[0].Source != database.TagSourceRule || *tags[0].Rule != "incidents" {
+		t.Errorf("Unexpected incident tags: %+v", tags)
+	}
+	tags, _ = repo.GetSessionTags("refactor")
+	if len(tags) != 2 || tags[0].Tag != "cleanup" || tags[0].Source != database.TagSourceManual || tags[1].Tag != "payments" {
+		t.Errorf("Unexpected refactor tags: %+v", tags)
+	}
+
+	// A session evaluated before its opening prompt arrived is evaluated again once it grows
+	createSession("empty", "/home/me/checkout", 1)
+	addMessage("empty-1", "empty", `"Is the outage over?"`)
+	if applied, err := tagger.ApplySession("empty"); err != nil || !applied {
+		t.Fatalf("Expected session to be re-evaluated, got %v (%v)", applied, err)
+	}
+	if tags, _ := repo.GetSessionTags("empty"); len(tags) != 1 || tags[0].Tag != "prod-incident" {
+		t.Errorf("Expected late opening prompt to be tagged, got %+v", tags)
+	}
+
+	// Changing the rules re-evaluates every session and drops tags from removed rules
+	tagger, err = NewTagger(repo, rules[1:], logger)
+	if err != nil {
+		t.Fatalf("Failed to create tagger: %v", err)
+	}
+	if evaluated, _ := tagger.ApplyPending(10); evaluated != 3 {
+		t.Errorf("Expected all sessions re-evaluated after a rule change, got %d", evaluated)
+	}
+	if tags, _ := repo.GetSessionTags("incident"); len(tags) != 0 {
+		t.Errorf("Expected removed rule's tag to be dropped, got %+v", tags)
+	}
+
+	counts, err := repo.GetRuleTagCounts()
+	if err != nil || counts["payments"] != 1 || counts["incidents"] != 0 {
+		t.Errorf("Unexpected rule counts: %v (%v)", counts, err)
+	}
+
+	if evaluated, err := tagger.Reapply(); err != nil || evaluated != 3 {
+		t.Errorf("Expected Reapply to evaluate 3 sessions, got %d (%v)", evaluated, err)
+	}
+}
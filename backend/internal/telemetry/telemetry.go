@@ -0,0 +1,211 @@
+// Package telemetry sends opt-in, anonymous usage reports about the session
+// manager itself: its version, platform, a database size bucket and how many
+// times each API route was called. Reports never include session content,
+// project names, paths, IDs or addresses.
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/claude-session-manager/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+// Version is the session manager's version, set at build time with
+// -ldflags "-X github.com/ksred/claude-session-manager/internal/telemetry.Version=1.2.3"
+var Version = "dev"
+
+// Report is the payload posted to the telemetry endpoint
+type Report struct {
+	Version      string           `json:"version"`
+	OS           string           `json:"os"`
+	Arch         string           `json:"arch"`
+	DBSizeBucket string           `json:"db_size_bucket"`
+	APICalls     map[string]int64 `json:"api_calls"` // keyed by method and route template, e.g. "GET /api/v1/sessions/:id"
+	PeriodStart  time.Time        `json:"period_start"`
+	PeriodEnd    time.Time        `json:"period_end"`
+}
+
+// Disabled returns why telemetry is off, or an empty string when cfg turns it
+// on. DO_NOT_TRACK turns it off regardless of the configuration.
+func Disabled(cfg config.TelemetryConfig) string {
+	switch {
+	case os.Getenv("DO_NOT_TRACK") != "" && os.Getenv("DO_NOT_TRACK") != "0":
+		return "DO_NOT_TRACK is set"
+	case !cfg.Enabled:
+		return "telemetry.enabled is false"
+	case cfg.Endpoint == "":
+		return "telemetry.endpoint is empty"
+	}
+	return ""
+}
+
+// SizeBucket returns the coarse size range a database of size bytes falls in
+func SizeBucket(size int64) string {
+	const mb = 1 << 20
+	switch {
+	case size < 10*mb:
+		return "<10MB"
+	case size < 100*mb:
+		return "10MB-100MB"
+	case size < 1024*mb:
+		return "100MB-1GB"
+	default:
+		return ">1GB"
+	}
+}
+
+// DBSizeBucket returns the size bucket of the database at path, counting its
+// write-ahead log, or "unknown" when it can't be read
+func DBSizeBucket(path string) string {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "unknown"
+	}
+	size := info.Size()
+	if wal, err := os.Stat(path + "-wal"); err == nil {
+		size += wal.Size()
+	}
+	return SizeBucket(size)
+}
+
+// Reporter counts API calls and periodically posts them to the endpoint
+type Reporter struct {
+	endpoint string
+	interval time.Duration
+	dbPath   string
+	client   *http.Client
+	logger   *logrus.Logger
+	now      func() time.Time
+
+	mu          sync.Mutex
+	calls       map[string]int64
+	periodStart time.Time
+}
+
+// NewReporter creates a reporter posting to the configured endpoint every
+// interval. Callers check Disabled first.
+func NewReporter(cfg config.TelemetryConfig, dbPath string, logger *logrus.Logger) *Reporter {
+	return &Reporter{
+		endpoint:    cfg.Endpoint,
+		interval:    time.Duration(cfg.Interval) * time.Hour,
+		dbPath:      dbPath,
+		client:      &http.Client{Timeout: 30 * time.Second},
+		logger:      logger,
+		now:         time.Now,
+		calls:       make(map[string]int64),
+		periodStart: time.Now().UTC(),
+	}
+}
+
+// Middleware counts each request by its route template, so IDs and query
+// strings are never recorded. Requests matching no route count as "other".
+func (r *Reporter) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "other"
+		}
+		key := c.Request.Method + " " + route
+
+		r.mu.Lock()
+		r.calls[key]++
+		r.mu.Unlock()
+	}
+}
+
+// Snapshot returns the report that would be sent now
+func (r *Reporter) Snapshot() Report {
+	r.mu.Lock()
+	calls := make(map[string]int64, len(r.calls))
+	for key, count := range r.calls {
+		calls[key] = count
+	}
+	start := r.periodStart
+	r.mu.Unlock()
+
+	report := NewReport(r.dbPath)
+	report.APICalls = calls
+	report.PeriodStart = start
+	report.PeriodEnd = r.now().UTC()
+	return report
+}
+
+// NewReport returns a report of the database at dbPath with no API calls,
+// for a period starting and ending now
+func NewReport(dbPath string) Report {
+	now := time.Now().UTC()
+	return Report{
+		Version:      Version,
+		OS:           runtime.GOOS,
+		Arch:         runtime.GOARCH,
+		DBSizeBucket: DBSizeBucket(dbPath),
+		APICalls:     map[string]int64{},
+		PeriodStart:  now,
+		PeriodEnd:    now,
+	}
+}
+
+// Send posts the current report and starts a new period. Counts are kept for
+// the next attempt when the post fails.
+func (r *Reporter) Send(ctx context.Context) error {
+	report := r.Snapshot()
+	body, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("telemetry endpoint returned %s", resp.Status)
+	}
+
+	// Subtract what was sent rather than clearing, keeping calls counted
+	// while the post was in flight
+	r.mu.Lock()
+	for key, count := range report.APICalls {
+		if r.calls[key] -= count; r.calls[key] <= 0 {
+			delete(r.calls, key)
+		}
+	}
+	r.periodStart = report.PeriodEnd
+	r.mu.Unlock()
+	return nil
+}
+
+// Start sends a report every interval until ctx is cancelled
+func (r *Reporter) Start(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.Send(ctx); err != nil && ctx.Err() == nil {
+				r.logger.WithError(err).Debug("Failed to send telemetry")
+			}
+		}
+	}
+}
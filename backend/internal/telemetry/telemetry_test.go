@@ -0,0 +1,90 @@
+package telemetry
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/claude-session-manager/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+func TestDisabled(t *testing.T) {
+	t.Setenv("DO_NOT_TRACK", "")
+	enabled := config.TelemetryConfig{Enabled: true, Endpoint: "https://telemetry.example.com", Interval: 24}
+
+	if reason := Disabled(enabled); reason != "" {
+		t.Errorf("Expected telemetry to be enabled, got %q", reason)
+	}
+	if reason := Disabled(config.TelemetryConfig{Endpoint: enabled.Endpoint}); reason == "" {
+		t.Error("Expected telemetry to be off unless enabled")
+	}
+
+	t.Setenv("DO_NOT_TRACK", "1")
+	if reason := Disabled(enabled); reason != "DO_NOT_TRACK is set" {
+		t.Errorf("Expected DO_NOT_TRACK to turn telemetry off, got %q", reason)
+	}
+}
+
+func TestSizeBucket(t *testing.T) {
+	tests := map[int64]string{
+		0:         "<10MB",
+		50 << 20:  "10MB-100MB",
+		500 << 20: "100MB-1GB",
+		2 << 30:   ">1GB",
+	}
+	for size, want := range tests {
+		if got := SizeBucket(size); got != want {
+			t.Errorf("SizeBucket(%d) = %q, want %q", size, got, want)
+		}
+	}
+}
+
+func TestReporter_Send(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var received Report
+	status := http.StatusOK
+	endpoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = Report{}
+		json.NewDecoder(r.Body).Decode(&received)
+		w.WriteHeader(status)
+	}))
+	defer endpoint.Close()
+
+	reporter := NewReporter(config.TelemetryConfig{Enabled: true, Endpoint: endpoint.URL, Interval: 24}, "", logrus.New())
+	router := gin.New()
+	router.Use(reporter.Middleware())
+	router.GET("/api/v1/sessions/:id", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	for _, path := range []string{"/api/v1/sessions/abc", "/api/v1/sessions/def?secret=1", "/nope"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	// A failed post keeps the counts for the next attempt
+	status = http.StatusInternalServerError
+	if err := reporter.Send(context.Background()); err == nil {
+		t.Fatal("Expected an error from a failing endpoint")
+	}
+
+	status = http.StatusOK
+	if err := reporter.Send(context.Background()); err != nil {
+		t.Fatalf("Failed to send report: %v", err)
+	}
+	if received.APICalls["GET /api/v1/sessions/:id"] != 2 || received.APICalls["GET other"] != 1 || len(received.APICalls) != 2 {
+		t.Errorf("Expected calls counted by route template, got %v", received.APICalls)
+	}
+	if received.Version != Version || received.DBSizeBucket != "unknown" {
+		t.Errorf("Unexpected report %+v", received)
+	}
+
+	if err := reporter.Send(context.Background()); err != nil {
+		t.Fatalf("Failed to send report: %v", err)
+	}
+	if len(received.APICalls) != 0 {
+		t.Errorf("Expected counts to restart after a report, got %v", received.APICalls)
+	}
+}
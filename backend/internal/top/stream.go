@@ -0,0 +1,157 @@
+package top
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/ksred/claude-session-manager/internal/database"
+)
+
+// sessionEvents are the WebSocket update types that carry a session
+var sessionEvents = map[string]bool{
+	"session_new":     true,
+	"session_created": true,
+	"session_update":  true,
+	"session_updated": true,
+}
+
+type streamMessage struct {
+	Type   string          `json:"type"`
+	Data   json.RawMessage `json:"data"`
+	Events []struct {
+		Type string          `json:"type"`
+		Data json.RawMessage `json:"data"`
+	} `json:"events"`
+}
+
+type sessionUpdate struct {
+	Session *database.SessionResponse `json:"session"`
+}
+
+// ParseSessions returns the sessions carried by a WebSocket message, including
+// those inside a batched_updates message
+func ParseSessions(payload []byte) ([]database.SessionResponse, error) {
+	var msg streamMessage
+	if err := json.Unmarshal(payload, &msg); err != nil {
+		return nil, err
+	}
+
+	var sessions []database.SessionResponse
+	collect := func(eventType string, data json.RawMessage) {
+		if !sessionEvents[eventType] || len(data) == 0 {
+			return
+		}
+		var update sessionUpdate
+		if err := json.Unmarshal(data, &update); err == nil && update.Session != nil && update.Session.ID != "" {
+			sessions = append(sessions, *update.Session)
+		}
+	}
+
+	collect(msg.Type, msg.Data)
+	for _, event := range msg.Events {
+		collect(event.Type, event.Data)
+	}
+	return sessions, nil
+}
+
+// Client reads session updates from a running server
+type Client struct {
+	baseURL string
+	apiKey  string
+	http    *http.Client
+}
+
+// NewClient creates a client for the server at baseURL, e.g.
+// http://localhost:8080, that sends apiKey if it isn't empty
+func NewClient(baseURL, apiKey string) *Client {
+	return &Client{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		apiKey:  apiKey,
+		http:    &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// header returns the headers that authenticate the client's requests
+func (c *Client) header() http.Header {
+	header := http.Header{}
+	if c.apiKey != "" {
+		header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+	return header
+}
+
+// ActiveSessions returns the sessions the server considers active
+func (c *Client) ActiveSessions(ctx context.Context) ([]database.SessionResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/api/v1/sessions/active", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header = c.header()
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("server returned %s", resp.Status)
+	}
+
+	var body struct {
+		Sessions []database.SessionResponse `json:"sessions"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to decode active sessions: %w", err)
+	}
+	return body.Sessions, nil
+}
+
+// Stream connects to the server's WebSocket and calls onSession for every
+// session update until the connection drops or ctx is done
+func (c *Client) Stream(ctx context.Context, onSession func(database.SessionResponse)) error {
+	wsURL, err := url.Parse(c.baseURL + "/api/v1/ws")
+	if err != nil {
+		return err
+	}
+	// Pin the WebSocket contract version the decoding below is written against
+	wsURL.RawQuery = url.Values{"version": {"1"}}.Encode()
+	switch wsURL.Scheme {
+	case "https":
+		wsURL.Scheme = "wss"
+	default:
+		wsURL.Scheme = "ws"
+	}
+
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, wsURL.String(), c.header())
+	if err != nil {
+		return fmt.Errorf("failed to connect to %s: %w", wsURL, err)
+	}
+	defer conn.Close()
+
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	for {
+		_, payload, err := conn.ReadMessage()
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+		sessions, err := ParseSessions(payload)
+		if err != nil {
+			continue
+		}
+		for _, session := range sessions {
+			onSession(session)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+// Package top tracks the token burn of active sessions from the server's
+// WebSocket stream for the `top` command
+package top
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ksred/claude-session-manager/internal/database"
+)
+
+// Sort orders for the table
+const (
+	SortTokens   = "tokens"
+	SortCost     = "cost"
+	SortActivity = "activity"
+)
+
+// sortOrders is the order the s key cycles through
+var sortOrders = []string{SortTokens, SortCost, SortActivity}
+
+// NextSort returns the sort order after current
+func NextSort(current string) string {
+	for i, order := range sortOrders {
+		if order == current {
+			return sortOrders[(i+1)%len(sortOrders)]
+		}
+	}
+	return sortOrders[0]
+}
+
+// sample is a session's running totals when an update arrived
+type sample struct {
+	at     time.Time
+	tokens int
+	cost   float64
+}
+
+type trackedSession struct {
+	session database.SessionResponse
+	samples []sample
+}
+
+// Row is one session in the table
+type Row struct {
+	SessionID    string
+	ProjectName  string
+	Model        string
+	TokensPerMin float64
+	CostPerMin   float64
+	TotalTokens  int
+	TotalCost    float64
+	LastActivity time.Time
+}
+
+// Tracker keeps the recent totals of each session it's told about and
+// derives burn rates over a sliding window
+type Tracker struct {
+	mu       sync.Mutex
+	window   time.Duration
+	sessions map[string]*trackedSession
+}
+
+// NewTracker creates a tracker measuring rates over window. Sessions without
+// activity in the window are considered idle.
+func NewTracker(window time.Duration) *Tracker {
+	return &Tracker{
+		window:   window,
+		sessions: make(map[string]*trackedSession),
+	}
+}
+
+// Observe records a session's totals as of at
+func (t *Tracker) Observe(session database.SessionResponse, at time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	tracked, ok := t.sessions[session.ID]
+	if !ok {
+		tracked = &trackedSession{}
+		t.sessions[session.ID] = tracked
+	}
+	tracked.session = session
+	tracked.samples = append(tracked.samples, sample{
+		at:     at,
+		tokens: session.TokensUsed.TotalTokens,
+		cost:   session.TokensUsed.EstimatedCost,
+	})
+
+	// Keep the newest sample at or before the window start as the baseline
+	cutoff := at.Add(-t.window)
+	drop := 0
+	for drop+1 < len(tracked.samples) && !tracked.samples[drop+1].at.After(cutoff) {
+		drop++
+	}
+	tracked.samples = tracked.samples[drop:]
+}
+
+// Rows returns the sessions active within the window as of now, in order
+func (t *Tracker) Rows(now time.Time, order string) []Row {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var rows []Row
+	for id, tracked := range t.sessions {
+		session := tracked.session
+		if now.Sub(session.UpdatedAt) > t.window {
+			continue
+		}
+
+		row := Row{
+			SessionID:    id,
+			ProjectName:  session.ProjectName,
+			Model:        session.Model,
+			TotalTokens:  session.TokensUsed.TotalTokens,
+			TotalCost:    session.TokensUsed.EstimatedCost,
+			LastActivity: session.UpdatedAt,
+		}
+		first, last := tracked.samples[0], tracked.samples[len(tracked.samples)-1]
+		if minutes := now.Sub(first.at).Minutes(); minutes > 0 && len(tracked.samples) > 1 {
+			row.TokensPerMin = float64(last.tokens-first.tokens) / minutes
+			row.CostPerMin = (last.cost - first.cost) / minutes
+		}
+		rows = append(rows, row)
+	}
+
+	sort.Slice(rows, func(i, j int) bool {
+		switch order {
+		case SortCost:
+			if rows[i].CostPerMin != rows[j].CostPerMin {
+				return rows[i].CostPerMin > rows[j].CostPerMin
+			}
+		case SortActivity:
+		default:
+			if rows[i].TokensPerMin != rows[j].TokensPerMin {
+				return rows[i].TokensPerMin > rows[j].TokensPerMin
+			}
+		}
+		return rows[i].LastActivity.After(rows[j].LastActivity)
+	})
+	return rows
+}
+
+// Render draws the table as of now with a status line above it
+func Render(rows []Row, now time.Time, order, status string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "claude-session-manager top - %s - %d active - sorted by %s - %s\n",
+		now.Format("15:04:05"), len(rows), order, status)
+	b.WriteString("q quit  s sort\n\n")
+	fmt.Fprintf(&b, "%-10s %-24s %-18s %11s %9s %12s %10s %9s\n",
+		"SESSION", "PROJECT", "MODEL", "TOKENS/MIN", "COST/MIN", "TOKENS", "COST", "LAST")
+
+	var tokensPerMin, costPerMin float64
+	for _, row := range rows {
+		fmt.Fprintf(&b, "%-10s %-24s %-18s %11.0f %9s %12d %10s %9s\n",
+			truncate(row.SessionID, 10),
+			truncate(row.ProjectName, 24),
+			truncate(row.Model, 18),
+			row.TokensPerMin,
+			fmt.Sprintf("$%.3f", row.CostPerMin),
+			row.TotalTokens,
+			fmt.Sprintf("$%.2f", row.TotalCost),
+			formatAgo(now.Sub(row.LastActivity)),
+		)
+		tokensPerMin += row.TokensPerMin
+		costPerMin += row.CostPerMin
+	}
+	if len(rows) == 0 {
+		b.WriteString("\nNo active sessions\n")
+	} else {
+		fmt.Fprintf(&b, "\nTotal burn: %.0f tokens/min, $%.3f/min\n", tokensPerMin, costPerMin)
+	}
+	return b.String()
+}
+
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-1]) + "…"
+}
+
+func formatAgo(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds ago", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", int(d.Minutes()))
+	default:
+		return fmt.Sprintf("%dh ago", int(d.Hours()))
+	}
+}
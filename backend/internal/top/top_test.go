@@ -0,0 +1,118 @@
+package top
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/ksred/claude-session-manager/internal/claude"
+	"github.com/ksred/claude-session-manager/internal/database"
+)
+
+func session(id string, tokens int, cost float64, lastActivity time.Time) database.SessionResponse {
+	return database.SessionResponse{
+		ID:          id,
+		ProjectName: "app",
+		UpdatedAt:   lastActivity,
+		TokensUsed:  claude.TokenUsage{TotalTokens: tokens, EstimatedCost: cost},
+	}
+}
+
+func TestTracker_Rates(t *testing.T) {
+	start := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	tracker := NewTracker(5 * time.Minute)
+
+	tracker.Observe(session("busy", 1000, 1.0, start), start)
+	tracker.Observe(session("busy", 3000, 1.5, start.Add(2*time.Minute)), start.Add(2*time.Minute))
+	tracker.Observe(session("quiet", 500, 0.1, start), start)
+	tracker.Observe(session("quiet", 700, 0.9, start.Add(time.Minute)), start.Add(time.Minute))
+	tracker.Observe(session("idle", 100, 0.1, start.Add(-time.Hour)), start)
+
+	now := start.Add(2 * time.Minute)
+	rows := tracker.Rows(now, SortTokens)
+	if len(rows) != 2 || rows[0].SessionID != "busy" || rows[1].SessionID != "quiet" {
+		t.Fatalf("Expected busy then quiet without the idle session, got %+v", rows)
+	}
+	if rows[0].TokensPerMin != 1000 || math.Abs(rows[0].CostPerMin-0.25) > 1e-9 {
+		t.Errorf("Expected 1000 tokens/min and $0.25/min, got %v and %v", rows[0].TokensPerMin, rows[0].CostPerMin)
+	}
+
+	if rows := tracker.Rows(now, SortCost); rows[0].SessionID != "quiet" {
+		t.Errorf("Expected quiet first by cost, got %+v", rows)
+	}
+
+	// Samples older than the window stop counting towards the rate
+	later := start.Add(10 * time.Minute)
+	tracker.Observe(session("busy", 3600, 1.5, later), later)
+	rows = tracker.Rows(later, SortTokens)
+	if len(rows) != 1 || math.Abs(rows[0].TokensPerMin-600.0/8) > 1e-9 {
+		t.Errorf("Expected the rate since the last sample before the window, got %+v", rows)
+	}
+}
+
+func TestParseSessions(t *testing.T) {
+	direct := `{"type":"session_update","data":{"session_id":"a","session":{"id":"a","tokens_used":{"total_tokens":10}}}}`
+	batched := `{"type":"batched_updates","events":[
+		{"type":"session_update","data":{"session_id":"b","session":{"id":"b"}}},
+		{"type":"activity_update","data":{"session_id":"c"}},
+		{"type":"session_new","data":{"session_id":"d","session":{"id":"d"}}}
+	]}`
+
+	sessions, err := ParseSessions([]byte(direct))
+	if err != nil || len(sessions) != 1 || sessions[0].TokensUsed.TotalTokens != 10 {
+		t.Errorf("Expected session a, got %+v (%v)", sessions, err)
+	}
+
+	sessions, err = ParseSessions([]byte(batched))
+	var ids []string
+	for _, s := range sessions {
+		ids = append(ids, s.ID)
+	}
+	if err != nil || strings.Join(ids, ",") != "b,d" {
+		t.Errorf("Expected sessions b and d, got %v (%v)", ids, err)
+	}
+}
+
+func TestClient_SendsAPIKey(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer csm_key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path == "/api/v1/ws" {
+			conn, err := upgrader.Upgrade(w, r, nil)
+			if err != nil {
+				return
+			}
+			conn.WriteMessage(websocket.TextMessage, []byte(`{"type":"session_update","data":{"session_id":"b","session":{"id":"b"}}}`))
+			conn.Close()
+			return
+		}
+		w.Write([]byte(`{"sessions":[{"id":"a"}]}`))
+	}))
+	defer server.Close()
+
+	if _, err := NewClient(server.URL, "").ActiveSessions(context.Background()); err == nil {
+		t.Error("Expected a client without a key to be refused")
+	}
+
+	client := NewClient(server.URL, "csm_key")
+	sessions, err := client.ActiveSessions(context.Background())
+	if err != nil || len(sessions) != 1 || sessions[0].ID != "a" {
+		t.Errorf("Expected session a, got %+v (%v)", sessions, err)
+	}
+
+	var streamed []string
+	client.Stream(context.Background(), func(session database.SessionResponse) {
+		streamed = append(streamed, session.ID)
+	})
+	if strings.Join(streamed, ",") != "b" {
+		t.Errorf("Expected session b streamed, got %v", streamed)
+	}
+}
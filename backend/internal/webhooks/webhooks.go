@@ -0,0 +1,288 @@
+// Package webhooks posts JSON payloads to user-configured URLs when sessions
+// are created or completed, a budget is exceeded or an error is detected,
+// signing them with HMAC-SHA256 and retrying failed deliveries with backoff.
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ksred/claude-session-manager/internal/budget"
+	"github.com/ksred/claude-session-manager/internal/database"
+	"github.com/sirupsen/logrus"
+)
+
+// Webhook events
+const (
+	EventSessionCreated   = "session_created"
+	EventSessionCompleted = "session_completed"
+	EventBudgetExceeded   = "budget_exceeded"
+	EventErrorDetected    = "error_detected"
+
+	// EventPing is sent by Test and can't be subscribed to
+	EventPing = "ping"
+)
+
+// Events are the events a webhook can subscribe to
+var Events = []string{EventSessionCreated, EventSessionCompleted, EventBudgetExceeded, EventErrorDetected}
+
+// SignatureHeader carries "sha256=" and the hex HMAC-SHA256 of the body,
+// keyed with the webhook's secret, when it has one
+const SignatureHeader = "X-Webhook-Signature"
+
+// queueSize is how many events can wait for delivery before new ones are
+// dropped
+const queueSize = 256
+
+// IsEvent reports whether name is an event a webhook can subscribe to
+func IsEvent(name string) bool {
+	for _, event := range Events {
+		if event == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Store is where webhooks are read from and their deliveries recorded
+type Store interface {
+	GetWebhooks() ([]database.Webhook, error)
+	RecordWebhookDelivery(id string, deliveryErr error, at time.Time) error
+}
+
+// Payload is the JSON body posted to a webhook. Retries send the same ID.
+type Payload struct {
+	ID        string      `json:"id"`
+	Event     string      `json:"event"`
+	Timestamp time.Time   `json:"timestamp"`
+	Data      interface{} `json:"data"`
+}
+
+// Dispatcher queues events and delivers them to the webhooks subscribed to
+// them
+type Dispatcher struct {
+	store       Store
+	client      *http.Client
+	queue       chan Payload
+	maxAttempts int
+	backoff     time.Duration // before the first retry, doubling after each
+	logger      *logrus.Logger
+	wg          sync.WaitGroup
+}
+
+// NewDispatcher creates a dispatcher for the webhooks in store
+func NewDispatcher(store Store, logger *logrus.Logger) *Dispatcher {
+	return &Dispatcher{
+		store:       store,
+		client:      &http.Client{Timeout: 10 * time.Second},
+		queue:       make(chan Payload, queueSize),
+		maxAttempts: 5,
+		backoff:     2 * time.Second,
+		logger:      logger,
+	}
+}
+
+// Notify is called with every update broadcast to WebSocket clients and
+// queues the ones that are webhook events. The event is dropped if the queue
+// is full.
+func (d *Dispatcher) Notify(updateType string, data interface{}) {
+	event, ok := eventFor(updateType, data)
+	if !ok {
+		return
+	}
+	payload := Payload{
+		ID:        uuid.New().String(),
+		Event:     event,
+		Timestamp: time.Now().UTC(),
+		Data:      data,
+	}
+	select {
+	case d.queue <- payload:
+	default:
+		d.logger.WithField("event", event).Warn("Webhook queue full, dropping event")
+	}
+}
+
+// eventFor maps a WebSocket update to the webhook event it is, if any
+func eventFor(updateType string, data interface{}) (string, bool) {
+	switch updateType {
+	case "session_created":
+		return EventSessionCreated, true
+	case "session_wrap_up":
+		return EventSessionCompleted, true
+	case "budget_alert":
+		alert, ok := data.(budget.Alert)
+		return EventBudgetExceeded, ok && alert.Threshold >= 100
+	case "hook_event":
+		event, ok := data.(*database.HookEvent)
+		return EventErrorDetected, ok && event.Failed()
+	case "chat:error":
+		return EventErrorDetected, true
+	}
+	return "", false
+}
+
+// Start delivers queued events until ctx is cancelled, then waits for the
+// deliveries in progress to give up
+func (d *Dispatcher) Start(ctx context.Context) {
+	defer d.wg.Wait()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case payload := <-d.queue:
+			d.dispatch(ctx, payload)
+		}
+	}
+}
+
+// dispatch sends a payload to each enabled webhook subscribed to its event,
+// concurrently so a slow endpoint doesn't hold up the others
+func (d *Dispatcher) dispatch(ctx context.Context, payload Payload) {
+	webhooks, err := d.store.GetWebhooks()
+	if err != nil {
+		d.logger.WithError(err).Error("Failed to get webhooks")
+		return
+	}
+	for _, webhook := range webhooks {
+		if !webhook.Enabled || !subscribed(webhook, payload.Event) {
+			continue
+		}
+		d.wg.Add(1)
+		go func(webhook database.Webhook) {
+			defer d.wg.Done()
+			d.Deliver(ctx, webhook, payload)
+		}(webhook)
+	}
+}
+
+func subscribed(webhook database.Webhook, event string) bool {
+	for _, name := range webhook.Events {
+		if name == event {
+			return true
+		}
+	}
+	return false
+}
+
+// Test sends a ping to a webhook straight away, without retrying, and
+// returns the outcome
+func (d *Dispatcher) Test(ctx context.Context, webhook database.Webhook) error {
+	payload := Payload{
+		ID:        uuid.New().String(),
+		Event:     EventPing,
+		Timestamp: time.Now().UTC(),
+		Data:      map[string]interface{}{"webhook_id": webhook.ID},
+	}
+	err := d.send(ctx, webhook, payload)
+	d.record(webhook, err)
+	return err
+}
+
+// Deliver posts a payload to a webhook, retrying network errors, 429s and
+// 5xx responses with exponential backoff, and records the outcome
+func (d *Dispatcher) Deliver(ctx context.Context, webhook database.Webhook, payload Payload) error {
+	var err error
+	wait := d.backoff
+	for attempt := 1; attempt <= d.maxAttempts; attempt++ {
+		if err = d.send(ctx, webhook, payload); err == nil || !retryable(err) || attempt == d.maxAttempts {
+			break
+		}
+		d.logger.WithError(err).WithFields(logrus.Fields{
+			"webhook": webhook.ID,
+			"event":   payload.Event,
+			"attempt": attempt,
+		}).Debug("Webhook delivery failed, retrying")
+
+		select {
+		case <-ctx.Done():
+			err = ctx.Err()
+		case <-time.After(wait):
+			wait *= 2
+			continue
+		}
+		break
+	}
+
+	if err != nil {
+		d.logger.WithError(err).WithFields(logrus.Fields{
+			"webhook": webhook.ID,
+			"url":     webhook.URL,
+			"event":   payload.Event,
+		}).Warn("Failed to deliver webhook")
+	}
+	d.record(webhook, err)
+	return err
+}
+
+// record stores the outcome of a delivery on the webhook
+func (d *Dispatcher) record(webhook database.Webhook, deliveryErr error) {
+	if err := d.store.RecordWebhookDelivery(webhook.ID, deliveryErr, time.Now()); err != nil {
+		d.logger.WithError(err).WithField("webhook", webhook.ID).Error("Failed to record webhook delivery")
+	}
+}
+
+// statusError is a response a webhook endpoint rejected a payload with
+type statusError struct {
+	code int
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("webhook returned status %d", e.code)
+}
+
+// retryable reports whether a failed delivery may succeed if sent again
+func retryable(err error) bool {
+	if status, ok := err.(*statusError); ok {
+		return status.code == http.StatusTooManyRequests || status.code >= 500
+	}
+	return true
+}
+
+// send posts a payload to a webhook once
+func (d *Dispatcher) send(ctx context.Context, webhook database.Webhook, payload Payload) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "claude-session-manager")
+	req.Header.Set("X-Webhook-Event", payload.Event)
+	req.Header.Set("X-Webhook-ID", payload.ID)
+	if webhook.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign(webhook.Secret, body))
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return &statusError{code: resp.StatusCode}
+	}
+	return nil
+}
+
+// Sign returns the signature header value of a payload body: "sha256=" and
+// the hex HMAC-SHA256 of body keyed with secret
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
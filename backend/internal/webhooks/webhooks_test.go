@@ -0,0 +1,159 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ksred/claude-session-manager/internal/budget"
+	"github.com/ksred/claude-session-manager/internal/database"
+	"github.com/sirupsen/logrus"
+)
+
+type fakeStore struct {
+	mu         sync.Mutex
+	webhooks   []database.Webhook
+	deliveries map[string]error
+}
+
+func (s *fakeStore) GetWebhooks() ([]database.Webhook, error) {
+	return s.webhooks, nil
+}
+
+func (s *fakeStore) RecordWebhookDelivery(id string, deliveryErr error, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deliveries[id] = deliveryErr
+	return nil
+}
+
+func TestEventFor(t *testing.T) {
+	failed := &database.HookEvent{Event: "PostToolUse", Data: json.RawMessage(`{"tool_response":{"is_error":true}}`)}
+	succeeded := &database.HookEvent{Event: "PostToolUse", Data: json.RawMessage(`{"tool_response":"ok"}`)}
+
+	tests := []struct {
+		updateType string
+		data       interface{}
+		event      string
+		ok         bool
+	}{
+		{"session_created", nil, EventSessionCreated, true},
+		{"session_update", nil, "", false},
+		{"session_wrap_up", nil, EventSessionCompleted, true},
+		{"budget_alert", budget.Alert{Threshold: 100}, EventBudgetExceeded, true},
+		{"budget_alert", budget.Alert{Threshold: 80}, EventBudgetExceeded, false},
+		{"hook_event", failed, EventErrorDetected, true},
+		{"hook_event", succeeded, EventErrorDetected, false},
+		{"hook_event", &database.HookEvent{Event: "StopFailure", Data: json.RawMessage(`{}`)}, EventErrorDetected, true},
+		{"chat:error", nil, EventErrorDetected, true},
+	}
+	for _, tt := range tests {
+		event, ok := eventFor(tt.updateType, tt.data)
+		if ok != tt.ok || (ok && event != tt.event) {
+			t.Errorf("eventFor(%s, %v) = %s, %v; want %s, %v", tt.updateType, tt.data, event, ok, tt.event, tt.ok)
+		}
+	}
+}
+
+func TestDispatcher_Deliver(t *testing.T) {
+	var mu sync.Mutex
+	var calls int
+	var signature string
+	var received Payload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		body, _ := io.ReadAll(r.Body)
+		signature = r.Header.Get(SignatureHeader)
+		json.Unmarshal(body, &received)
+		if calls < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer server.Close()
+
+	store := &fakeStore{deliveries: map[string]error{}}
+	dispatcher := NewDispatcher(store, logrus.New())
+	dispatcher.backoff = time.Millisecond
+
+	webhook := database.Webhook{ID: "w1", URL: server.URL, Secret: "s3cret", Events: Events, Enabled: true}
+	payload := Payload{ID: "p1", Event: EventSessionCreated, Data: map[string]string{"session_id": "s1"}}
+	if err := dispatcher.Deliver(context.Background(), webhook, payload); err != nil {
+		t.Fatalf("Expected delivery to succeed after retries, got %v", err)
+	}
+	if calls != 3 || received.ID != "p1" {
+		t.Errorf("Expected three attempts of the same payload, got %d of %+v", calls, received)
+	}
+	body, _ := json.Marshal(payload)
+	if signature != Sign("s3cret", body) {
+		t.Errorf("Expected the body signed with the secret, got %s", signature)
+	}
+	if err, ok := store.deliveries["w1"]; !ok || err != nil {
+		t.Errorf("Expected the delivery recorded as successful, got %v", err)
+	}
+
+	// Client errors aren't retried
+	rejecting := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls++
+		mu.Unlock()
+		w.WriteHeader(http.StatusGone)
+	}))
+	defer rejecting.Close()
+	calls = 0
+	webhook.URL = rejecting.URL
+	if err := dispatcher.Deliver(context.Background(), webhook, payload); err == nil || calls != 1 {
+		t.Errorf("Expected one failed attempt, got %d and %v", calls, err)
+	}
+	if store.deliveries["w1"] == nil {
+		t.Error("Expected the failure recorded")
+	}
+}
+
+func TestDispatcher_Start(t *testing.T) {
+	delivered := make(chan Payload, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload Payload
+		json.NewDecoder(r.Body).Decode(&payload)
+		delivered <- payload
+	}))
+	defer server.Close()
+
+	store := &fakeStore{
+		deliveries: map[string]error{},
+		webhooks: []database.Webhook{
+			{ID: "budgets", URL: server.URL, Events: []string{EventBudgetExceeded}, Enabled: true},
+			{ID: "disabled", URL: server.URL, Events: Events},
+		},
+	}
+	dispatcher := NewDispatcher(store, logrus.New())
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		dispatcher.Start(ctx)
+		close(done)
+	}()
+
+	dispatcher.Notify("session_created", nil)
+	dispatcher.Notify("budget_alert", budget.Alert{Name: "monthly", Threshold: 100})
+
+	select {
+	case payload := <-delivered:
+		if payload.Event != EventBudgetExceeded {
+			t.Errorf("Expected only the budget event delivered, got %s", payload.Event)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for the webhook")
+	}
+	cancel()
+	<-done
+	if len(delivered) != 0 {
+		t.Errorf("Expected one delivery, got %d more", len(delivered))
+	}
+}
@@ -33,7 +33,10 @@ const FEATURES = {
 
 export const AppContent: React.FC = () => {
   const queryClient = useQueryClient();
-  const [selectedSessionId, setSelectedSessionId] = useState<string | null>(null);
+  // Launcher deep links open the dashboard at /?session={id}
+  const [selectedSessionId, setSelectedSessionId] = useState<string | null>(
+    () => new URLSearchParams(window.location.search).get('session')
+  );
   const [selectedProjectId, setSelectedProjectId] = useState<string | null>(null);
   const [currentView, setCurrentView] = useState<'session' | 'project' | 'analytics'>('session');
   const [timeRange, setTimeRange] = useState(168);
@@ -4,7 +4,9 @@ import { WebSocketMessage } from '../types/api';
 import { sessionKeys } from './useSessionData';
 import { createDebouncedInvalidator } from '../utils/debounce';
 
-const WS_URL = `${window.location.protocol === 'https:' ? 'wss:' : 'ws:'}//${window.location.host}/api/v1/ws`;
+// WebSocket contract version the message handlers below are written against
+const WS_CONTRACT_VERSION = 1;
+const WS_URL = `${window.location.protocol === 'https:' ? 'wss:' : 'ws:'}//${window.location.host}/api/v1/ws?version=${WS_CONTRACT_VERSION}`;
 const RECONNECT_INTERVAL = 3000;
 const MAX_RECONNECT_ATTEMPTS = 5;
 const INVALIDATION_DELAY = 5000; // 5 seconds debounce for all invalidations
@@ -425,6 +427,10 @@ export const useWebSocket = () => {
               console.log('✅ WebSocket subscription confirmed');
               break;
               
+            case 'hello:ack':
+              // Contract version confirmed
+              break;
+              
             // Chat message types
             case 'chat:session:start':
             case 'chat:session:end':
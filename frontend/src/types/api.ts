@@ -32,6 +32,7 @@ export interface HealthResponse {
 
 export interface WebSocketMessage {
   type: string;
+  version?: number;
   session_id?: string;
   data: any;
   timestamp: string;